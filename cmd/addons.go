@@ -168,6 +168,11 @@ func runAddonsBootstrap(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("--repo flag is required")
 	}
 
+	stackName, err := getStackFromArgs(nil, 0)
+	if err != nil {
+		return err
+	}
+
 	fmt.Println()
 	color.Cyan("📋 Bootstrap Configuration:")
 	fmt.Printf("  • Repository: %s\n", gitopsRepo)
@@ -226,7 +231,12 @@ func runAddonsBootstrap(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("kubeconfig not found in stack outputs")
 	}
 
-	kubeconfigPath := fmt.Sprintf("%v", kubeconfigOutput.Value)
+	// kubectl needs the kubeconfig on disk; it is removed when the command exits
+	kubeconfigPath, err := createTempFile("kubeconfig-*", []byte(fmt.Sprintf("%v", kubeconfigOutput.Value)))
+	if err != nil {
+		s.Stop()
+		return err
+	}
 	s.Stop()
 
 	color.Green("✅ Cluster found")
@@ -237,12 +247,11 @@ func runAddonsBootstrap(cmd *cobra.Command, args []string) error {
 	s.Suffix = " Cloning GitOps repository..."
 	s.Start()
 
-	tempDir, err := os.MkdirTemp("", "gitops-*")
+	tempDir, err := createTempDir("gitops-*")
 	if err != nil {
 		s.Stop()
-		return fmt.Errorf("failed to create temp dir: %w", err)
+		return err
 	}
-	defer os.RemoveAll(tempDir)
 
 	gitopsConfig := &addons.GitOpsConfig{
 		RepoURL:    gitopsRepo,
@@ -302,6 +311,11 @@ func runAddonsBootstrap(cmd *cobra.Command, args []string) error {
 func runAddonsList(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	stackName, err := getStackFromArgs(nil, 0)
+	if err != nil {
+		return err
+	}
+
 	printHeader("📦 Installed Addons")
 
 	s := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+// stackConfigStore reads and writes the config of a stack
+type stackConfigStore interface {
+	GetAllConfig(ctx context.Context) (auto.ConfigMap, error)
+	SetConfig(ctx context.Context, key string, val auto.ConfigValue) error
+}
+
+// ensureAdminPasswords fills in the admin passwords the cluster config leaves
+// empty. A password generated by an earlier deploy is read back from the
+// stack config; otherwise a new one is generated and kept there as a secret,
+// so it stays the same from one deploy to the next.
+func ensureAdminPasswords(ctx context.Context, store stackConfigStore, cfg *config.ClusterConfig) error {
+	var missing []config.AdminPassword
+	for _, password := range cfg.AdminPasswords() {
+		if *password.Value == "" {
+			missing = append(missing, password)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	stored, err := store.GetAllConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read stack config: %w", err)
+	}
+
+	for _, password := range missing {
+		if value := storedConfigValue(stored, password.Key); value != "" {
+			*password.Value = value
+			continue
+		}
+
+		generated, err := config.GeneratePassword()
+		if err != nil {
+			return err
+		}
+		if err := store.SetConfig(ctx, password.Key, auto.ConfigValue{Value: generated, Secret: true}); err != nil {
+			return fmt.Errorf("failed to store the generated %s password: %w", password.Name, err)
+		}
+		*password.Value = generated
+		printInfo(fmt.Sprintf("🔑 Generated a %s password (stack output: %s)", password.Name, password.Output))
+	}
+	return nil
+}
+
+// storedConfigValue returns the value of a stack config key, which the
+// config map holds qualified with the project name
+func storedConfigValue(stored auto.ConfigMap, key string) string {
+	for name, value := range stored {
+		if name == key || strings.HasSuffix(name, ":"+key) {
+			return value.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+type fakeConfigStore struct {
+	config auto.ConfigMap
+}
+
+func (f *fakeConfigStore) GetAllConfig(ctx context.Context) (auto.ConfigMap, error) {
+	return f.config, nil
+}
+
+func (f *fakeConfigStore) SetConfig(ctx context.Context, key string, val auto.ConfigValue) error {
+	f.config["sloth-kubernetes:"+key] = val
+	return nil
+}
+
+func TestEnsureAdminPasswords(t *testing.T) {
+	store := &fakeConfigStore{config: auto.ConfigMap{
+		"sloth-kubernetes:" + config.PulumiKeyGrafanaAdminPassword: {Value: "Stored-Grafana-42", Secret: true},
+	}}
+	cfg := &config.ClusterConfig{
+		Monitoring: config.MonitoringConfig{Grafana: &config.GrafanaConfig{Enabled: true}},
+		Addons:     config.AddonsConfig{ArgoCD: &config.ArgoCDConfig{Enabled: true, AdminPassword: "Configured-Argo-42"}},
+		Security:   config.SecurityConfig{Bastion: &config.BastionConfig{Enabled: true}},
+	}
+
+	if err := ensureAdminPasswords(context.Background(), store, cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := cfg.Monitoring.Grafana.AdminPassword; got != "Stored-Grafana-42" {
+		t.Errorf("Grafana password = %q, want the stored one", got)
+	}
+	if got := cfg.Addons.ArgoCD.AdminPassword; got != "Configured-Argo-42" {
+		t.Errorf("ArgoCD password = %q, want the configured one", got)
+	}
+	if _, ok := store.config["sloth-kubernetes:"+config.PulumiKeyArgoCDAdminPassword]; ok {
+		t.Error("a configured password should not be stored")
+	}
+
+	generated := cfg.Security.Bastion.SaltAPIPassword
+	if err := config.ValidatePasswordStrength(generated); err != nil {
+		t.Errorf("generated Salt API password %q is not strong: %v", generated, err)
+	}
+	stored := store.config["sloth-kubernetes:"+config.PulumiKeySaltAPIPassword]
+	if stored.Value != generated || !stored.Secret {
+		t.Errorf("stored Salt API password = %+v, want %q as a secret", stored, generated)
+	}
+
+	// The next deploy reuses the generated password
+	cfg.Security.Bastion.SaltAPIPassword = ""
+	if err := ensureAdminPasswords(context.Background(), store, cfg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Security.Bastion.SaltAPIPassword != generated {
+		t.Errorf("Salt API password changed between deploys")
+	}
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// apiDialTimeout bounds the reachability check of the API endpoint
+const apiDialTimeout = 5 * time.Second
+
+// apiDialer opens a TCP connection to the API endpoint. It is a variable so
+// tests can simulate reachability without a network.
+var apiDialer = func(address string, timeout time.Duration) error {
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
+
+// kubectlLocalCommands are kubectl subcommands that never talk to the API
+var kubectlLocalCommands = map[string]bool{
+	"completion": true,
+	"config":     true,
+	"help":       true,
+	"kustomize":  true,
+	"options":    true,
+	"plugin":     true,
+}
+
+// helmLocalCommands are helm subcommands that never talk to the API
+var helmLocalCommands = map[string]bool{
+	"completion": true,
+	"create":     true,
+	"dependency": true,
+	"env":        true,
+	"help":       true,
+	"lint":       true,
+	"package":    true,
+	"plugin":     true,
+	"pull":       true,
+	"repo":       true,
+	"search":     true,
+	"show":       true,
+	"template":   true,
+	"verify":     true,
+	"version":    true,
+}
+
+// kubeconfigFile is the part of a kubeconfig needed to find the API server
+type kubeconfigFile struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Clusters []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server string `yaml:"server"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+}
+
+// needsAPIServer reports whether a kubectl or helm invocation talks to the
+// API, i.e. its subcommand is not one of the local ones
+func needsAPIServer(args []string, local map[string]bool) bool {
+	if len(args) == 0 {
+		return false
+	}
+	for _, arg := range args {
+		if arg == "-h" || arg == "--help" {
+			return false
+		}
+	}
+	return !local[args[0]]
+}
+
+// kubeconfigFromArgs returns the value of a --kubeconfig flag in args
+func kubeconfigFromArgs(args []string) string {
+	for i, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "--kubeconfig="); ok {
+			return value
+		}
+		if arg == "--kubeconfig" && i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// kubeconfigServer returns the API server of the current context in the
+// first readable file of a KUBECONFIG-style path list
+func kubeconfigServer(paths string) (string, error) {
+	for _, path := range filepath.SplitList(paths) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+
+		var kubeconfig kubeconfigFile
+		if err := yaml.Unmarshal(data, &kubeconfig); err != nil {
+			return "", fmt.Errorf("failed to parse kubeconfig %s: %w", path, err)
+		}
+
+		cluster := ""
+		for _, context := range kubeconfig.Contexts {
+			if context.Name == kubeconfig.CurrentContext {
+				cluster = context.Context.Cluster
+			}
+		}
+		for _, c := range kubeconfig.Clusters {
+			if c.Name == cluster || (cluster == "" && len(kubeconfig.Clusters) == 1) {
+				return c.Cluster.Server, nil
+			}
+		}
+		return "", nil
+	}
+	return "", nil
+}
+
+// checkAPIEndpoint verifies that the API server is reachable from this
+// machine. Kubeconfigs generated for the cluster point at addresses that are
+// often only reachable over the VPN, so the error says how to connect instead
+// of leaving the user with a connection timeout.
+func checkAPIEndpoint(server, stack string) error {
+	endpoint, err := url.Parse(server)
+	if err != nil || endpoint.Host == "" {
+		return nil
+	}
+
+	address := endpoint.Host
+	if endpoint.Port() == "" {
+		address = net.JoinHostPort(endpoint.Hostname(), "443")
+	}
+
+	if err := apiDialer(address, apiDialTimeout); err != nil {
+		if stack == "" {
+			stack = "<stack>"
+		}
+		return fmt.Errorf("API endpoint %s is unreachable — are you connected to the VPN? Run 'sloth-kubernetes vpn join %s'", address, stack)
+	}
+	return nil
+}
+
+// ensureAPIReachable runs checkAPIEndpoint against the kubeconfig a local
+// kubectl or helm invocation will use. A kubeconfig that cannot be read is
+// left for the tool itself to report.
+func ensureAPIReachable(args []string, local map[string]bool, kubeconfigPath string) error {
+	if !needsAPIServer(args, local) {
+		return nil
+	}
+	if path := kubeconfigFromArgs(args); path != "" {
+		kubeconfigPath = path
+	}
+	if kubeconfigPath == "" {
+		return nil
+	}
+
+	server, err := kubeconfigServer(kubeconfigPath)
+	if err != nil || server == "" {
+		return nil
+	}
+
+	stack, _ := getStackFromArgs(nil, 0)
+	return checkAPIEndpoint(server, stack)
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: production
+contexts:
+- name: staging
+  context:
+    cluster: staging
+- name: production
+  context:
+    cluster: production
+clusters:
+- name: staging
+  cluster:
+    server: https://10.8.0.20:6443
+- name: production
+  cluster:
+    server: https://10.8.0.10:6443
+`
+
+func stubAPIDialer(t *testing.T, fn func(address string, timeout time.Duration) error) {
+	t.Helper()
+	original := apiDialer
+	apiDialer = fn
+	t.Cleanup(func() { apiDialer = original })
+}
+
+func writeTestKubeconfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(path, []byte(testKubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestKubeconfigServer(t *testing.T) {
+	path := writeTestKubeconfig(t)
+
+	server, err := kubeconfigServer(filepath.Join(t.TempDir(), "missing") + string(filepath.ListSeparator) + path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if server != "https://10.8.0.10:6443" {
+		t.Errorf("Expected the current context's server, got %q", server)
+	}
+}
+
+func TestCheckAPIEndpoint_Unreachable(t *testing.T) {
+	var dialed string
+	stubAPIDialer(t, func(address string, timeout time.Duration) error {
+		dialed = address
+		return errors.New("i/o timeout")
+	})
+
+	err := checkAPIEndpoint("https://10.8.0.10:6443", "production")
+	if dialed != "10.8.0.10:6443" {
+		t.Errorf("Expected to dial the API endpoint, dialed %q", dialed)
+	}
+	want := "API endpoint 10.8.0.10:6443 is unreachable — are you connected to the VPN? Run 'sloth-kubernetes vpn join production'"
+	if err == nil || err.Error() != want {
+		t.Errorf("Expected %q, got %v", want, err)
+	}
+
+	err = checkAPIEndpoint("https://api.example.com", "")
+	if dialed != "api.example.com:443" {
+		t.Errorf("Expected the default HTTPS port, dialed %q", dialed)
+	}
+	if err == nil || !strings.Contains(err.Error(), "vpn join <stack>") {
+		t.Errorf("Expected a placeholder stack in the hint, got %v", err)
+	}
+}
+
+func TestCheckAPIEndpoint_Reachable(t *testing.T) {
+	stubAPIDialer(t, func(address string, timeout time.Duration) error { return nil })
+
+	if err := checkAPIEndpoint("https://10.8.0.10:6443", "production"); err != nil {
+		t.Errorf("Expected no error for a reachable endpoint, got %v", err)
+	}
+}
+
+func TestEnsureAPIReachable(t *testing.T) {
+	path := writeTestKubeconfig(t)
+	dials := 0
+	stubAPIDialer(t, func(address string, timeout time.Duration) error {
+		dials++
+		return errors.New("connection refused")
+	})
+
+	if err := ensureAPIReachable([]string{"config", "view"}, kubectlLocalCommands, path); err != nil {
+		t.Errorf("Expected local commands to skip the check, got %v", err)
+	}
+	if err := ensureAPIReachable([]string{"get", "pods", "--help"}, kubectlLocalCommands, path); err != nil {
+		t.Errorf("Expected help to skip the check, got %v", err)
+	}
+	if dials != 0 {
+		t.Errorf("Expected no dial for local commands, got %d", dials)
+	}
+
+	err := ensureAPIReachable([]string{"get", "nodes", "--kubeconfig=" + path}, kubectlLocalCommands, "")
+	if err == nil || !strings.Contains(err.Error(), "10.8.0.10:6443 is unreachable") {
+		t.Errorf("Expected the unreachable endpoint from --kubeconfig, got %v", err)
+	}
+}
+
+func TestKubeconfigFromArgs(t *testing.T) {
+	if got := kubeconfigFromArgs([]string{"get", "nodes", "--kubeconfig", "./kc"}); got != "./kc" {
+		t.Errorf("Expected ./kc, got %q", got)
+	}
+	if got := kubeconfigFromArgs([]string{"--kubeconfig=./kc", "get", "nodes"}); got != "./kc" {
+		t.Errorf("Expected ./kc, got %q", got)
+	}
+	if got := kubeconfigFromArgs([]string{"get", "nodes"}); got != "" {
+		t.Errorf("Expected no kubeconfig, got %q", got)
+	}
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// artifactDir is the --output-dir flag: where commands write generated files
+// such as client configs and kubeconfigs. Empty means the per-stack default.
+var artifactDir string
+
+// stackArtifactDir returns the directory generated files of stack are written
+// to, creating it if needed. It defaults to ~/.sloth-kubernetes/<stack>. The
+// files can hold secrets, so a new directory is only accessible by the user.
+func stackArtifactDir(stack string) (string, error) {
+	dir := artifactDir
+	if dir == "" || strings.HasPrefix(dir, "~/") {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %w", err)
+		}
+		if dir == "" {
+			dir = filepath.Join(homeDir, ".sloth-kubernetes", stack)
+		} else {
+			dir = filepath.Join(homeDir, dir[2:])
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create output directory: %w", err)
+	}
+	return dir, nil
+}
+
+// artifactPath returns the path of the generated file name of stack
+func artifactPath(stack, name string) (string, error) {
+	dir, err := stackArtifactDir(stack)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name), nil
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func stubArtifactDir(t *testing.T, dir string) {
+	t.Helper()
+	original := artifactDir
+	artifactDir = dir
+	t.Cleanup(func() { artifactDir = original })
+}
+
+func TestArtifactPath_OutputDir(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "artifacts")
+	stubArtifactDir(t, dir)
+
+	path, err := artifactPath("production", "wg0-client.conf")
+	if err != nil {
+		t.Fatalf("artifactPath() error = %v", err)
+	}
+	if path != filepath.Join(dir, "wg0-client.conf") {
+		t.Errorf("artifactPath() = %q, want a file in %q", path, dir)
+	}
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		t.Fatalf("output directory not created: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		t.Errorf("output directory permissions = %o, want 700", perm)
+	}
+}
+
+func TestArtifactPath_DefaultsToStackDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	stubArtifactDir(t, "")
+
+	path, err := artifactPath("production", "kubeconfig")
+	if err != nil {
+		t.Fatalf("artifactPath() error = %v", err)
+	}
+	want := filepath.Join(home, ".sloth-kubernetes", "production", "kubeconfig")
+	if path != want {
+		t.Errorf("artifactPath() = %q, want %q", path, want)
+	}
+
+	info, err := os.Stat(filepath.Dir(want))
+	if err != nil {
+		t.Fatalf("stack directory not created: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0700 {
+		t.Errorf("stack directory permissions = %o, want 700", perm)
+	}
+}
+
+func TestArtifactPath_ExpandsHome(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	stubArtifactDir(t, "~/clusters/prod")
+
+	path, err := artifactPath("production", "kubeconfig")
+	if err != nil {
+		t.Fatalf("artifactPath() error = %v", err)
+	}
+	if want := filepath.Join(home, "clusters", "prod", "kubeconfig"); path != want {
+		t.Errorf("artifactPath() = %q, want %q", path, want)
+	}
+}
@@ -0,0 +1,535 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/spf13/cobra"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+// kubeAPIPort is the port the RKE2 kube-apiserver listens on
+const kubeAPIPort = 6443
+
+// minAllowedIPsPrefix is the shortest AllowedIPs prefix a VPN peer may have.
+// The mesh routes 10.0.0.0/8, anything broader sends foreign traffic into the VPN.
+const minAllowedIPsPrefix = 8
+
+// Audit controls
+const (
+	controlSSHPassword       = "ssh-password-auth"
+	controlFail2ban          = "fail2ban"
+	controlFirewall          = "firewall-default-deny"
+	controlSecretsEncryption = "secrets-encryption"
+	controlAPIExposure       = "api-not-public"
+	controlVPNAllowedIPs     = "vpn-allowed-ips"
+)
+
+// auditControls lists the controls in report order with their remediation
+var auditControls = []struct {
+	Name        string
+	Remediation string
+}{
+	{controlSSHPassword, "Set 'PasswordAuthentication no' in /etc/ssh/sshd_config and reload sshd"},
+	{controlFail2ban, "Install fail2ban and enable it: systemctl enable --now fail2ban"},
+	{controlFirewall, "Deny incoming traffic by default: ufw default deny incoming && ufw --force enable"},
+	{controlSecretsEncryption, "Set kubernetes.encryptSecrets: true and redeploy, or run 'k3s secrets-encrypt enable' ('rke2 secrets-encrypt enable' on RKE2) on the servers"},
+	{controlAPIExposure, fmt.Sprintf("Block port %d from the internet in the provider firewall and reach the API over the VPN or bastion", kubeAPIPort)},
+	{controlVPNAllowedIPs, fmt.Sprintf("Narrow the peer's AllowedIPs to /%d or longer prefixes and rerun 'vpn rebuild'", minAllowedIPsPrefix)},
+}
+
+// auditResult is the outcome of one control on one target
+type auditResult struct {
+	Control string
+	Target  string
+	Pass    bool
+	Detail  string
+}
+
+// auditFacts is the hardening state read from a host by auditProbeScript
+type auditFacts struct {
+	PasswordAuth string
+	Fail2ban     string
+	UFWDefault   string
+	InputPolicy  string
+	// SecretsEncryption is the parsed `secrets-encrypt status` of a server,
+	// nil when the status could not be read
+	SecretsEncryption *secretsEncryptStatus
+	AllowedIPs        map[string][]string // peer public key -> CIDRs
+}
+
+// auditProbeScript prints the host facts as key=value lines, one line per
+// WireGuard peer for allowed_ips and per line of `secrets-encrypt status`
+// for secrets_status. The status is read from the RKE2 or K3s binary, since
+// K3s servers get --secrets-encryption on the command line, not in a config.
+const auditProbeScript = `echo "password_auth=$(sudo sshd -T 2>/dev/null | awk '$1=="passwordauthentication"{print $2}')"
+echo "fail2ban=$(systemctl is-active fail2ban 2>/dev/null)"
+echo "ufw_default=$(sudo ufw status verbose 2>/dev/null | awk -F': ' '/^Default:/{print $2}')"
+echo "input_policy=$(sudo iptables -S INPUT 2>/dev/null | awk '$1=="-P"{print $3}')"
+sudo bash -s 2>/dev/null << 'SECRETSEOF' | sed 's/^/secrets_status=/'
+` + secretsEncryptScriptPrefix + `$BIN secrets-encrypt status
+SECRETSEOF
+sudo wg show wg0 allowed-ips 2>/dev/null | sed 's/^/allowed_ips=/'
+true
+`
+
+// apiPortOpen reports whether addr accepts TCP connections from this machine.
+// It is a variable so tests can simulate exposure without a network.
+var apiPortOpen = func(addr string) bool {
+	conn, err := net.DialTimeout("tcp", addr, 3*time.Second)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit [stack-name]",
+	Short: "Audit the security hardening of a running cluster",
+	Long: `Audit the hardening of a deployed stack without changing anything:
+  • SSH password authentication disabled on nodes and bastion
+  • fail2ban active on nodes and bastion
+  • Host firewall denies incoming traffic by default
+  • Secrets encryption enabled on servers (RKE2 or K3s)
+  • Kubernetes API not reachable from the internet (only via VPN/bastion)
+  • No VPN peer with an AllowedIPs broader than /8
+
+Each control passes only if it passes on every host. The report ends with a
+score and remediation hints for failed controls. Unlike 'doctor', which checks
+that nodes can reach what they need, 'audit' checks how the cluster is hardened.`,
+	Example: `  # Audit a stack
+  sloth-kubernetes audit production
+
+  # Also check the cluster configuration
+  sloth-kubernetes audit production --config cluster.yaml`,
+	RunE: runAudit,
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	var cfg *config.ClusterConfig
+	if cfgFile != "" {
+		cfg, err = config.LoadFromYAML(cfgFile)
+		if err != nil {
+			return fmt.Errorf("failed to load config file: %w", err)
+		}
+	}
+
+	printHeader(fmt.Sprintf("🛡️  Security Audit - Stack: %s", stack))
+
+	// Create workspace with S3 support
+	workspace, err := createWorkspaceWithS3Support(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	// Use fully qualified stack name for S3 backend
+	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
+	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
+	if err != nil {
+		return fmt.Errorf("failed to select stack '%s': %w", stack, err)
+	}
+
+	// Get outputs
+	outputs, err := s.Outputs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get stack outputs: %w", err)
+	}
+
+	// Parse nodes
+	nodes, err := ParseNodeOutputs(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to parse nodes: %w", err)
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("no nodes found in stack")
+	}
+
+	access := newNodeSSHAccess(stack, outputs)
+
+	fmt.Println()
+	printInfo(fmt.Sprintf("Auditing %d nodes...", len(nodes)))
+	fmt.Println()
+
+	var results []auditResult
+	for _, node := range nodes {
+		results = append(results, auditHost(node, access, isControlPlaneNode(node))...)
+	}
+
+	if bastion, ok := bastionAuditTarget(outputs); ok {
+		// The bastion itself is always reached directly
+		results = append(results, auditHost(bastion, nodeSSHAccess{Stack: access.Stack, KeyPath: access.KeyPath}, false)...)
+	}
+
+	results = append(results, auditAPIExposure(nodes, outputs)...)
+	if cfg != nil {
+		results = append(results, auditConfig(cfg)...)
+	}
+
+	printAuditReport(results)
+
+	passed, total := auditScore(results)
+	if passed < total {
+		return fmt.Errorf("%d of %d controls failed", total-passed, total)
+	}
+	return nil
+}
+
+// bastionAuditTarget returns the bastion as a host to audit, if the stack has one
+func bastionAuditTarget(outputs auto.OutputMap) (NodeInfo, bool) {
+	enabled, ip := getBastionInfo(outputs)
+	if !enabled || ip == "" {
+		return NodeInfo{}, false
+	}
+
+	bastion := NodeInfo{Name: "bastion", PublicIP: ip}
+	if output, ok := outputs["bastion"]; ok {
+		if bastionMap, ok := output.Value.(map[string]interface{}); ok {
+			if name, ok := bastionMap["name"].(string); ok && name != "" {
+				bastion.Name = name
+			}
+			if provider, ok := bastionMap["provider"].(string); ok {
+				bastion.Provider = provider
+			}
+		}
+	}
+	return bastion, true
+}
+
+// auditHost reads the hardening facts of a host and evaluates its controls.
+// Every control fails when the host cannot be reached.
+func auditHost(node NodeInfo, access nodeSSHAccess, server bool) []auditResult {
+	output, err := access.runScript(node, 10, auditProbeScript)
+	if err != nil {
+		var results []auditResult
+		for _, control := range hostControls(server) {
+			results = append(results, auditResult{Control: control, Target: node.Name, Detail: fmt.Sprintf("unreachable: %v", err)})
+		}
+		return results
+	}
+
+	return evaluateHostFacts(node.Name, parseAuditFacts(string(output)), server)
+}
+
+// hostControls returns the controls checked on a host
+func hostControls(server bool) []string {
+	controls := []string{controlSSHPassword, controlFail2ban, controlFirewall}
+	if server {
+		controls = append(controls, controlSecretsEncryption)
+	}
+	return append(controls, controlVPNAllowedIPs)
+}
+
+// parseAuditFacts parses the output of auditProbeScript
+func parseAuditFacts(output string) auditFacts {
+	facts := auditFacts{AllowedIPs: make(map[string][]string)}
+	var secretsStatus []string
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "password_auth":
+			facts.PasswordAuth = value
+		case "fail2ban":
+			facts.Fail2ban = value
+		case "ufw_default":
+			facts.UFWDefault = value
+		case "input_policy":
+			facts.InputPolicy = value
+		case "secrets_status":
+			secretsStatus = append(secretsStatus, value)
+		case "allowed_ips":
+			// <public key>\t<cidr> <cidr>...
+			fields := strings.Fields(value)
+			if len(fields) == 0 {
+				continue
+			}
+			for _, cidr := range fields[1:] {
+				if cidr != "(none)" {
+					facts.AllowedIPs[fields[0]] = append(facts.AllowedIPs[fields[0]], cidr)
+				}
+			}
+		}
+	}
+	if len(secretsStatus) > 0 {
+		status := parseSecretsEncryptStatus(strings.Join(secretsStatus, "\n"))
+		facts.SecretsEncryption = &status
+	}
+	return facts
+}
+
+// evaluateHostFacts judges the host controls against the facts read from a host
+func evaluateHostFacts(target string, facts auditFacts, server bool) []auditResult {
+	results := []auditResult{
+		evaluateSSHPassword(target, facts),
+		evaluateFail2ban(target, facts),
+		evaluateFirewall(target, facts),
+	}
+	if server {
+		results = append(results, evaluateSecretsEncryption(target, facts))
+	}
+	return append(results, evaluateAllowedIPs(target, facts.AllowedIPs))
+}
+
+func evaluateSecretsEncryption(target string, facts auditFacts) auditResult {
+	result := auditResult{Control: controlSecretsEncryption, Target: target}
+	switch {
+	case facts.SecretsEncryption == nil:
+		result.Detail = "could not read secrets-encrypt status"
+	case facts.SecretsEncryption.Enabled:
+		result.Pass = true
+	default:
+		result.Detail = "secrets-encrypt status reports encryption disabled"
+	}
+	return result
+}
+
+func evaluateSSHPassword(target string, facts auditFacts) auditResult {
+	result := auditResult{Control: controlSSHPassword, Target: target}
+	switch facts.PasswordAuth {
+	case "no":
+		result.Pass = true
+	case "":
+		result.Detail = "could not read the effective sshd configuration"
+	default:
+		result.Detail = "PasswordAuthentication " + facts.PasswordAuth
+	}
+	return result
+}
+
+func evaluateFail2ban(target string, facts auditFacts) auditResult {
+	result := auditResult{Control: controlFail2ban, Target: target, Pass: facts.Fail2ban == "active"}
+	if !result.Pass {
+		state := facts.Fail2ban
+		if state == "" {
+			state = "not installed"
+		}
+		result.Detail = "fail2ban " + state
+	}
+	return result
+}
+
+// evaluateFirewall accepts a UFW default of deny/reject for incoming traffic,
+// or a DROP policy on the iptables INPUT chain
+func evaluateFirewall(target string, facts auditFacts) auditResult {
+	result := auditResult{Control: controlFirewall, Target: target}
+
+	ufwIncoming := ""
+	for _, part := range strings.Split(facts.UFWDefault, ",") {
+		if strings.Contains(part, "(incoming)") {
+			ufwIncoming = strings.Fields(part)[0]
+		}
+	}
+
+	switch {
+	case ufwIncoming == "deny" || ufwIncoming == "reject":
+		result.Pass = true
+		result.Detail = "ufw " + ufwIncoming + " (incoming)"
+	case facts.InputPolicy == "DROP":
+		result.Pass = true
+		result.Detail = "iptables INPUT policy DROP"
+	case ufwIncoming != "":
+		result.Detail = "ufw " + ufwIncoming + " (incoming)"
+	case facts.InputPolicy != "":
+		result.Detail = "iptables INPUT policy " + facts.InputPolicy
+	default:
+		result.Detail = "no host firewall found"
+	}
+	return result
+}
+
+// evaluateAllowedIPs fails when a peer routes a prefix broader than
+// minAllowedIPsPrefix
+func evaluateAllowedIPs(target string, allowedIPs map[string][]string) auditResult {
+	result := auditResult{Control: controlVPNAllowedIPs, Target: target, Pass: true}
+
+	keys := make([]string, 0, len(allowedIPs))
+	for key := range allowedIPs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var broad []string
+	for _, key := range keys {
+		for _, cidr := range allowedIPs[key] {
+			_, network, err := net.ParseCIDR(cidr)
+			if err != nil {
+				continue
+			}
+			if ones, _ := network.Mask.Size(); ones < minAllowedIPsPrefix {
+				broad = append(broad, fmt.Sprintf("%s… %s", shortKey(key), cidr))
+			}
+		}
+	}
+
+	if len(broad) > 0 {
+		result.Pass = false
+		result.Detail = "broad AllowedIPs: " + strings.Join(broad, ", ")
+	}
+	return result
+}
+
+// auditAPIExposure checks from this machine that the Kubernetes API is not
+// reachable on the public addresses of the control plane or the API endpoint
+func auditAPIExposure(nodes []NodeInfo, outputs auto.OutputMap) []auditResult {
+	var addresses []string
+	seen := make(map[string]bool)
+	add := func(host string) {
+		ip := net.ParseIP(host)
+		if host == "" || seen[host] || (ip != nil && (ip.IsPrivate() || ip.IsLoopback())) {
+			return
+		}
+		seen[host] = true
+		addresses = append(addresses, host)
+	}
+
+	for _, node := range nodes {
+		if isControlPlaneNode(node) {
+			add(node.PublicIP)
+		}
+	}
+	if endpoint, ok := outputs["apiEndpoint"]; ok {
+		if value, ok := endpoint.Value.(string); ok {
+			add(apiEndpointHost(value))
+		}
+	}
+
+	var results []auditResult
+	for _, host := range addresses {
+		addr := net.JoinHostPort(host, strconv.Itoa(kubeAPIPort))
+		result := auditResult{Control: controlAPIExposure, Target: host, Pass: !apiPortOpen(addr)}
+		if !result.Pass {
+			result.Detail = addr + " reachable from this machine"
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// apiEndpointHost returns the host of an API endpoint given as a URL,
+// host:port or bare host
+func apiEndpointHost(endpoint string) string {
+	if strings.Contains(endpoint, "://") {
+		if u, err := url.Parse(endpoint); err == nil {
+			return u.Hostname()
+		}
+		return ""
+	}
+	if host, _, err := net.SplitHostPort(endpoint); err == nil {
+		return host
+	}
+	return endpoint
+}
+
+// auditConfig checks the controls that are visible in the cluster configuration
+func auditConfig(cfg *config.ClusterConfig) []auditResult {
+	result := auditResult{Control: controlSecretsEncryption, Target: "config"}
+	if cfg.Kubernetes.SecretsEncryptionEnabled() {
+		result.Pass = true
+	} else {
+		result.Detail = "neither kubernetes.encryptSecrets nor kubernetes.rke2.secretsEncryption is enabled"
+	}
+	return []auditResult{result}
+}
+
+// auditScore returns how many of the audited controls passed. A control
+// passes only if it passed on every target.
+func auditScore(results []auditResult) (int, int) {
+	passed, total := 0, 0
+	for _, control := range auditControls {
+		audited, ok := false, true
+		for _, r := range results {
+			if r.Control == control.Name {
+				audited = true
+				ok = ok && r.Pass
+			}
+		}
+		if !audited {
+			continue
+		}
+		total++
+		if ok {
+			passed++
+		}
+	}
+	return passed, total
+}
+
+// printAuditReport prints the results per control, the score and the
+// remediation of failed controls
+func printAuditReport(results []auditResult) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CONTROL\tTARGET\tSTATUS\tDETAIL")
+	var failed []int
+	for i, control := range auditControls {
+		controlFailed := false
+		for _, r := range results {
+			if r.Control != control.Name {
+				continue
+			}
+			status := color.GreenString("pass")
+			if !r.Pass {
+				status = color.RedString("FAIL")
+				controlFailed = true
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Control, r.Target, status, r.Detail)
+		}
+		if controlFailed {
+			failed = append(failed, i)
+		}
+	}
+	w.Flush()
+
+	passed, total := auditScore(results)
+	score := 0
+	if total > 0 {
+		score = passed * 100 / total
+	}
+
+	fmt.Println()
+	summary := fmt.Sprintf("Score: %d/%d controls passed (%d%%)", passed, total, score)
+	if passed == total {
+		printSuccess(summary)
+		return
+	}
+	color.Yellow(summary)
+
+	fmt.Println()
+	color.Cyan("Remediation:")
+	for _, i := range failed {
+		fmt.Printf("  • %s: %s\n", auditControls[i].Name, auditControls[i].Remediation)
+	}
+}
+
+// shortKey abbreviates a WireGuard public key for display
+func shortKey(key string) string {
+	if len(key) > 8 {
+		return key[:8]
+	}
+	return key
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+const hardenedProbeOutput = `password_auth=no
+fail2ban=active
+ufw_default=deny (incoming), allow (outgoing), deny (routed)
+input_policy=ACCEPT
+secrets_status=Encryption Status: Enabled
+secrets_status=Current Rotation Stage: start
+secrets_status=Server Encryption Hashes: All hashes match
+allowed_ips=abcdEFGH1234abcdEFGH1234abcdEFGH1234abcdEF0=	10.8.0.2/32 10.0.0.0/8
+allowed_ips=zyxwVUTS9876zyxwVUTS9876zyxwVUTS9876zyxwVU0=	10.8.0.3/32
+`
+
+func findAuditResult(t *testing.T, results []auditResult, control string) auditResult {
+	t.Helper()
+	for _, r := range results {
+		if r.Control == control {
+			return r
+		}
+	}
+	t.Fatalf("No result for control %s in %+v", control, results)
+	return auditResult{}
+}
+
+func TestParseAuditFacts(t *testing.T) {
+	facts := parseAuditFacts(hardenedProbeOutput)
+
+	if facts.PasswordAuth != "no" || facts.Fail2ban != "active" || facts.SecretsEncryption == nil || !facts.SecretsEncryption.Enabled {
+		t.Errorf("Unexpected facts: %+v", facts)
+	}
+	if facts.UFWDefault != "deny (incoming), allow (outgoing), deny (routed)" {
+		t.Errorf("Unexpected ufw default: %q", facts.UFWDefault)
+	}
+
+	cidrs := facts.AllowedIPs["abcdEFGH1234abcdEFGH1234abcdEFGH1234abcdEF0="]
+	if len(cidrs) != 2 || cidrs[1] != "10.0.0.0/8" {
+		t.Errorf("Expected peer key with its '=' padding and two CIDRs, got %v", facts.AllowedIPs)
+	}
+}
+
+func TestEvaluateHostFacts_Hardened(t *testing.T) {
+	results := evaluateHostFacts("master-1", parseAuditFacts(hardenedProbeOutput), true)
+	if len(results) != 5 {
+		t.Fatalf("Expected 5 host controls on a server, got %d", len(results))
+	}
+	for _, r := range results {
+		if !r.Pass {
+			t.Errorf("Expected %s to pass, got %+v", r.Control, r)
+		}
+	}
+
+	if agent := evaluateHostFacts("worker-1", parseAuditFacts(hardenedProbeOutput), false); len(agent) != 4 {
+		t.Errorf("Secrets encryption should only be checked on servers, got %+v", agent)
+	}
+}
+
+func TestEvaluateHostFacts_Weak(t *testing.T) {
+	output := `password_auth=yes
+fail2ban=inactive
+ufw_default=
+input_policy=ACCEPT
+secrets_status=Encryption Status: Disabled
+allowed_ips=abcdEFGH1234=	0.0.0.0/0
+`
+	results := evaluateHostFacts("master-1", parseAuditFacts(output), true)
+	for _, r := range results {
+		if r.Pass {
+			t.Errorf("Expected %s to fail, got %+v", r.Control, r)
+		}
+	}
+
+	if r := findAuditResult(t, results, controlSSHPassword); r.Detail != "PasswordAuthentication yes" {
+		t.Errorf("Unexpected ssh detail: %q", r.Detail)
+	}
+	if r := findAuditResult(t, results, controlFirewall); r.Detail != "iptables INPUT policy ACCEPT" {
+		t.Errorf("Unexpected firewall detail: %q", r.Detail)
+	}
+	if r := findAuditResult(t, results, controlVPNAllowedIPs); !strings.Contains(r.Detail, "abcdEFGH… 0.0.0.0/0") {
+		t.Errorf("Unexpected allowed IPs detail: %q", r.Detail)
+	}
+}
+
+func TestEvaluateFirewall(t *testing.T) {
+	tests := []struct {
+		facts auditFacts
+		pass  bool
+	}{
+		{auditFacts{UFWDefault: "reject (incoming), allow (outgoing)"}, true},
+		{auditFacts{UFWDefault: "allow (incoming), allow (outgoing)"}, false},
+		{auditFacts{InputPolicy: "DROP"}, true},
+		{auditFacts{}, false},
+	}
+	for _, tt := range tests {
+		if got := evaluateFirewall("node", tt.facts); got.Pass != tt.pass {
+			t.Errorf("evaluateFirewall(%+v) = %+v, want pass=%v", tt.facts, got, tt.pass)
+		}
+	}
+}
+
+func TestEvaluateAllowedIPs(t *testing.T) {
+	if r := evaluateAllowedIPs("node", map[string][]string{"key": {"10.0.0.0/8", "192.168.1.0/24"}}); !r.Pass {
+		t.Errorf("Expected /8 and longer prefixes to pass, got %+v", r)
+	}
+	if r := evaluateAllowedIPs("node", map[string][]string{"key": {"128.0.0.0/1"}}); r.Pass {
+		t.Error("Expected /1 to fail")
+	}
+	if r := evaluateAllowedIPs("node", nil); !r.Pass {
+		t.Error("Expected a host without peers to pass")
+	}
+}
+
+func TestAuditHost_Unreachable(t *testing.T) {
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	results := auditHost(NodeInfo{Name: "worker-1", PublicIP: "203.0.113.20"}, nodeSSHAccess{KeyPath: "/tmp/key.pem"}, false)
+	if len(results) != 4 {
+		t.Fatalf("Expected every agent control to fail, got %+v", results)
+	}
+	for _, r := range results {
+		if r.Pass || !strings.Contains(r.Detail, "connection refused") {
+			t.Errorf("Expected unreachable failure, got %+v", r)
+		}
+	}
+}
+
+func TestAuditAPIExposure(t *testing.T) {
+	original := apiPortOpen
+	var probed []string
+	apiPortOpen = func(addr string) bool {
+		probed = append(probed, addr)
+		return addr == "203.0.113.10:6443"
+	}
+	t.Cleanup(func() { apiPortOpen = original })
+
+	nodes := []NodeInfo{
+		{Name: "master-1", PublicIP: "203.0.113.10", Roles: []string{"master"}},
+		{Name: "master-2", PublicIP: "203.0.113.11", Roles: []string{"master"}},
+		{Name: "worker-1", PublicIP: "203.0.113.20", Roles: []string{"worker"}},
+	}
+	outputs := auto.OutputMap{"apiEndpoint": {Value: "https://10.8.0.1:6443"}}
+
+	results := auditAPIExposure(nodes, outputs)
+	if len(probed) != 2 {
+		t.Errorf("Expected only public control-plane addresses to be probed, got %v", probed)
+	}
+	if len(results) != 2 || results[0].Pass || !results[1].Pass {
+		t.Errorf("Expected master-1 exposed and master-2 closed, got %+v", results)
+	}
+}
+
+func TestAPIEndpointHost(t *testing.T) {
+	tests := map[string]string{
+		"https://api.example.com:6443": "api.example.com",
+		"203.0.113.10:6443":            "203.0.113.10",
+		"203.0.113.10":                 "203.0.113.10",
+	}
+	for endpoint, want := range tests {
+		if got := apiEndpointHost(endpoint); got != want {
+			t.Errorf("apiEndpointHost(%q) = %q, want %q", endpoint, got, want)
+		}
+	}
+}
+
+func TestBastionAuditTarget(t *testing.T) {
+	if _, ok := bastionAuditTarget(auto.OutputMap{}); ok {
+		t.Error("Expected no bastion without outputs")
+	}
+
+	outputs := auto.OutputMap{
+		"bastion_enabled": {Value: true},
+		"bastion":         {Value: map[string]interface{}{"name": "prod-bastion", "public_ip": "198.51.100.5", "provider": "azure"}},
+	}
+	bastion, ok := bastionAuditTarget(outputs)
+	if !ok || bastion.Name != "prod-bastion" || bastion.PublicIP != "198.51.100.5" || bastion.Provider != "azure" {
+		t.Errorf("Unexpected bastion target: %+v", bastion)
+	}
+}
+
+func TestAuditConfig(t *testing.T) {
+	cfg := &config.ClusterConfig{}
+	if r := auditConfig(cfg)[0]; r.Pass {
+		t.Error("Expected missing RKE2 config to fail secrets encryption")
+	}
+	cfg.Kubernetes.RKE2 = &config.RKE2Config{SecretsEncryption: true}
+	if r := auditConfig(cfg)[0]; !r.Pass {
+		t.Errorf("Expected secrets encryption to pass, got %+v", r)
+	}
+}
+
+func TestAuditScore(t *testing.T) {
+	results := []auditResult{
+		{Control: controlSSHPassword, Target: "a", Pass: true},
+		{Control: controlSSHPassword, Target: "b", Pass: false},
+		{Control: controlFail2ban, Target: "a", Pass: true},
+		{Control: controlAPIExposure, Target: "203.0.113.10", Pass: true},
+	}
+	passed, total := auditScore(results)
+	if passed != 2 || total != 3 {
+		t.Errorf("Expected 2/3 controls passed, got %d/%d", passed, total)
+	}
+}
+
+// TestEvaluateHostFacts_SecretsEncryptionUnknown tests that a server whose
+// secrets-encrypt status cannot be read fails the control
+func TestEvaluateHostFacts_SecretsEncryptionUnknown(t *testing.T) {
+	output := strings.ReplaceAll(hardenedProbeOutput, "secrets_status=", "ignored=")
+	r := findAuditResult(t, evaluateHostFacts("master-1", parseAuditFacts(output), true), controlSecretsEncryption)
+	if r.Pass || r.Detail != "could not read secrets-encrypt status" {
+		t.Errorf("Expected unknown status to fail, got %+v", r)
+	}
+
+	if !strings.Contains(auditProbeScript, secretsEncryptScriptPrefix+"$BIN secrets-encrypt status") {
+		t.Error("Probe should read the status from the RKE2 or K3s binary")
+	}
+}
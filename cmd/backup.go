@@ -0,0 +1,537 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+// etcdRestorePollInterval and etcdRestorePollAttempts bound the wait for a
+// server to answer API requests after a restore. They are variables so
+// tests do not sleep.
+var (
+	etcdRestorePollInterval = 10 * time.Second
+	etcdRestorePollAttempts = 60
+)
+
+// etcdSnapshotNamePattern matches snapshot names that are safe to pass to
+// the etcd-snapshot and server commands
+var etcdSnapshotNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// etcdSnapshotBucketPattern matches S3 buckets with an optional folder
+var etcdSnapshotBucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*(/[A-Za-z0-9._/-]*)?$`)
+
+// etcdSnapshotSavedPattern matches the log line etcd-snapshot save prints
+// for each snapshot it takes
+var etcdSnapshotSavedPattern = regexp.MustCompile(`Snapshot (\S+) saved`)
+
+var (
+	backupSnapshotName string
+	backupS3Bucket     string
+	backupRestoreName  string
+)
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Take, list and restore etcd snapshots",
+	Long: `Take, list and restore etcd snapshots of the cluster using the snapshot
+support built into RKE2. Scheduled snapshots are configured with
+kubernetes.rke2.snapshotScheduleCron and snapshotRetention.
+
+When kubernetes.etcd.snapshot.s3Bucket is set in the configuration given with
+--config, or --s3-bucket is given, snapshots are uploaded to and listed from
+that bucket too. The bucket may include a folder (bucket/folder). Credentials
+are taken from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_REGION.`,
+}
+
+var backupEtcdCmd = &cobra.Command{
+	Use:   "etcd [stack-name]",
+	Short: "Take an etcd snapshot now",
+	Long: `Take an on-demand etcd snapshot on a reachable control-plane node over SSH.
+The snapshot is kept on the node and, when an S3 bucket is configured,
+uploaded to it.`,
+	Example: `  # Snapshot production before an upgrade
+  sloth-kubernetes backup etcd production --name pre-upgrade
+
+  # Upload the snapshot to S3
+  sloth-kubernetes backup etcd production --config cluster.yaml
+  sloth-kubernetes backup etcd production --s3-bucket my-backups/production`,
+	RunE: runBackupEtcd,
+}
+
+var backupListCmd = &cobra.Command{
+	Use:   "list [stack-name]",
+	Short: "List etcd snapshots",
+	Long: `List the etcd snapshots kept on every control-plane node and, when an S3
+bucket is configured, the ones uploaded to it. Newest snapshots come first.`,
+	Example: `  # List snapshots
+  sloth-kubernetes backup list production`,
+	RunE: runBackupList,
+}
+
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore [stack-name]",
+	Short: "Restore etcd from a snapshot",
+	Long: `Restore the cluster state from an etcd snapshot. RKE2 is stopped on every
+control-plane node, the snapshot is restored on the node that holds it (or on
+the first node when it is only in S3) with a cluster reset, and the other
+control-plane nodes rejoin with an empty etcd data directory.
+
+Everything written to the cluster after the snapshot was taken is lost. All
+control-plane nodes must be reachable before the restore starts.`,
+	Example: `  # Restore a snapshot listed by 'backup list'
+  sloth-kubernetes backup restore production --name on-demand-master-1-1700000000`,
+	RunE: runBackupRestore,
+}
+
+func init() {
+	rootCmd.AddCommand(backupCmd)
+	backupCmd.AddCommand(backupEtcdCmd)
+	backupCmd.AddCommand(backupListCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+
+	for _, c := range []*cobra.Command{backupEtcdCmd, backupListCmd, backupRestoreCmd} {
+		c.Flags().StringVar(&backupS3Bucket, "s3-bucket", "", "S3 bucket (and folder) of the snapshots (default: kubernetes.etcd.snapshot.s3Bucket)")
+	}
+	backupEtcdCmd.Flags().StringVar(&backupSnapshotName, "name", "on-demand", "Snapshot name prefix; the node name and a timestamp are appended")
+	backupRestoreCmd.Flags().StringVar(&backupRestoreName, "name", "", "Name of the snapshot to restore")
+	_ = backupRestoreCmd.MarkFlagRequired("name")
+}
+
+// etcdSnapshotS3 is the S3 location of the snapshots
+type etcdSnapshotS3 struct {
+	Bucket string
+	Folder string
+}
+
+// parseEtcdSnapshotBucket parses a bucket with an optional folder, e.g.
+// my-backups/production
+func parseEtcdSnapshotBucket(value string) (*etcdSnapshotS3, error) {
+	value = strings.Trim(value, "/")
+	if !etcdSnapshotBucketPattern.MatchString(value) {
+		return nil, fmt.Errorf("invalid S3 bucket %q: expected bucket or bucket/folder", value)
+	}
+	bucket, folder, _ := strings.Cut(value, "/")
+	return &etcdSnapshotS3{Bucket: bucket, Folder: folder}, nil
+}
+
+// etcdSnapshotBucket returns the S3 location of the snapshots from
+// --s3-bucket or the configuration given with --config, or nil when
+// snapshots are only kept on the nodes
+func etcdSnapshotBucket() (*etcdSnapshotS3, error) {
+	bucket := backupS3Bucket
+	if bucket == "" && cfgFile != "" {
+		cfg, err := config.LoadFromYAML(cfgFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load config file: %w", err)
+		}
+		if snapshot := cfg.Kubernetes.Etcd.Snapshot; snapshot != nil {
+			bucket = snapshot.S3Bucket
+		}
+	}
+	if bucket == "" {
+		return nil, nil
+	}
+	return parseEtcdSnapshotBucket(bucket)
+}
+
+// flags returns the S3 flags of the etcd-snapshot command, or of the server
+// command with prefix "etcd-"
+func (s *etcdSnapshotS3) flags(prefix string) string {
+	if s == nil {
+		return ""
+	}
+	flags := fmt.Sprintf(" --%[1]ss3 --%[1]ss3-bucket=%[2]s", prefix, s.Bucket)
+	if s.Folder != "" {
+		flags += fmt.Sprintf(" --%ss3-folder=%s", prefix, s.Folder)
+	}
+	return flags
+}
+
+// etcdBackupScriptPrefix selects the RKE2 or K3s binary, server unit, data
+// directory and kubectl, and exports the S3 credentials from the local
+// environment when s3 is set. The credentials are sent on stdin, so they
+// don't show up in the process list of the node.
+func etcdBackupScriptPrefix(s3 *etcdSnapshotS3) string {
+	var b strings.Builder
+	b.WriteString(`set -e
+export PATH="$PATH:/usr/local/bin:/var/lib/rancher/rke2/bin"
+if command -v rke2 >/dev/null 2>&1; then
+  BIN=rke2; SVC=rke2-server; DATA=/var/lib/rancher/rke2
+  KUBECTL="/var/lib/rancher/rke2/bin/kubectl --kubeconfig /etc/rancher/rke2/rke2.yaml"
+else
+  BIN=k3s; SVC=k3s; DATA=/var/lib/rancher/k3s
+  KUBECTL="k3s kubectl"
+fi
+`)
+	if s3 != nil {
+		for _, name := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_REGION"} {
+			if value := os.Getenv(name); value != "" {
+				fmt.Fprintf(&b, "export %s='%s'\n", name, strings.ReplaceAll(value, "'", `'\''`))
+			}
+		}
+	}
+	return b.String()
+}
+
+// etcdSnapshotSaveScript takes a snapshot named prefix-<node>-<timestamp>
+func etcdSnapshotSaveScript(prefix string, s3 *etcdSnapshotS3) string {
+	return etcdBackupScriptPrefix(s3) + fmt.Sprintf("$BIN etcd-snapshot save --name %s%s 2>&1\n", prefix, s3.flags(""))
+}
+
+// etcdSnapshotListScript lists the snapshots on the node and in S3
+func etcdSnapshotListScript(s3 *etcdSnapshotS3) string {
+	script := etcdBackupScriptPrefix(nil) + "$BIN etcd-snapshot ls 2>/dev/null\n"
+	if s3 != nil {
+		script += etcdBackupScriptPrefix(s3) + fmt.Sprintf("$BIN etcd-snapshot ls%s 2>/dev/null\n", s3.flags(""))
+	}
+	return script
+}
+
+// etcdServerReadyLoop waits until the API server of the node answers
+const etcdServerReadyLoop = `for i in $(seq 1 %d); do
+  if $KUBECTL get --raw /readyz >/dev/null 2>&1; then
+    echo "READY"
+    exit 0
+  fi
+  sleep %d
+done
+echo "server did not become ready"
+exit 1
+`
+
+// etcdStopServerScript stops the server so etcd is no longer written to
+func etcdStopServerScript() string {
+	return etcdBackupScriptPrefix(nil) + `systemctl stop "$SVC"
+echo "STOPPED"
+`
+}
+
+// etcdClusterResetScript restores snapshot with a cluster reset and starts
+// the server again. Snapshots that are only in S3 are downloaded from s3.
+func etcdClusterResetScript(snapshot etcdSnapshot, s3 *etcdSnapshotS3) string {
+	restorePath := strings.TrimPrefix(snapshot.Location, "file://")
+	flags := ""
+	if snapshot.inS3() {
+		restorePath = snapshot.Name
+		flags = s3.flags("etcd-")
+	}
+	return etcdBackupScriptPrefix(s3) + fmt.Sprintf(`set -o pipefail
+$BIN server --cluster-reset --cluster-reset-restore-path=%s%s 2>&1 | tail -n 5
+systemctl start "$SVC"
+`, restorePath, flags) + fmt.Sprintf(etcdServerReadyLoop, etcdRestorePollAttempts, int(etcdRestorePollInterval.Seconds()))
+}
+
+// etcdRejoinServerScript clears the etcd data of a server so it rejoins
+// the restored cluster, and starts it
+func etcdRejoinServerScript() string {
+	return etcdBackupScriptPrefix(nil) + `rm -rf "$DATA/server/db/etcd"
+systemctl start "$SVC"
+` + fmt.Sprintf(etcdServerReadyLoop, etcdRestorePollAttempts, int(etcdRestorePollInterval.Seconds()))
+}
+
+// etcdSnapshot is a snapshot listed by etcd-snapshot ls
+type etcdSnapshot struct {
+	Node     string
+	Name     string
+	Location string
+	Size     string
+	Created  string
+}
+
+// inS3 reports whether the snapshot is the S3 copy
+func (s etcdSnapshot) inS3() bool {
+	return strings.HasPrefix(s.Location, "s3://")
+}
+
+// parseEtcdSnapshotSaved returns the names of the snapshots reported by
+// etcd-snapshot save
+func parseEtcdSnapshotSaved(output string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, match := range etcdSnapshotSavedPattern.FindAllStringSubmatch(output, -1) {
+		name := strings.TrimSuffix(match[1], ".")
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseEtcdSnapshotList parses the output of etcd-snapshot ls, e.g.
+//
+//	Name                            Location                                               Size    Created
+//	on-demand-master-1-1700000000   file:///var/lib/rancher/rke2/server/db/snapshots/...   5242912 2023-11-14T22:13:20Z
+func parseEtcdSnapshotList(node, output string) []etcdSnapshot {
+	var snapshots []etcdSnapshot
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] == "Name" || !strings.Contains(fields[1], "://") {
+			continue
+		}
+		snapshots = append(snapshots, etcdSnapshot{
+			Node:     node,
+			Name:     fields[0],
+			Location: fields[1],
+			Size:     fields[2],
+			Created:  fields[3],
+		})
+	}
+	return snapshots
+}
+
+// listEtcdSnapshots lists the snapshots of every server, newest first. S3
+// snapshots are listed once, by the first server that can read them.
+// Servers that can't be reached are returned by name.
+func listEtcdSnapshots(servers []NodeInfo, access nodeSSHAccess, s3 *etcdSnapshotS3) ([]etcdSnapshot, []string) {
+	var snapshots []etcdSnapshot
+	var unreachable []string
+	seenS3 := make(map[string]bool)
+	for _, server := range servers {
+		output, err := runEtcdBackupScript(server, access, etcdSnapshotListScript(s3))
+		if err != nil {
+			unreachable = append(unreachable, server.Name)
+			continue
+		}
+		for _, snapshot := range parseEtcdSnapshotList(server.Name, output) {
+			if snapshot.inS3() {
+				if seenS3[snapshot.Location] {
+					continue
+				}
+				seenS3[snapshot.Location] = true
+				snapshot.Node = "s3"
+			}
+			snapshots = append(snapshots, snapshot)
+		}
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].Created > snapshots[j].Created })
+	return snapshots, unreachable
+}
+
+// findEtcdSnapshot returns the copy of the named snapshot to restore: one
+// kept on a node, which needs no download, or else the S3 copy
+func findEtcdSnapshot(snapshots []etcdSnapshot, name string) (etcdSnapshot, bool) {
+	var found etcdSnapshot
+	ok := false
+	for _, snapshot := range snapshots {
+		if snapshot.Name != name {
+			continue
+		}
+		if !snapshot.inS3() {
+			return snapshot, true
+		}
+		found, ok = snapshot, true
+	}
+	return found, ok
+}
+
+// runEtcdBackupScript runs script as root on a server
+func runEtcdBackupScript(node NodeInfo, access nodeSSHAccess, script string) (string, error) {
+	user := getSSHUserForNode(node.Provider)
+	output, err := sshRunner(access.args(node, user, 10, "sudo", "bash", "-s"), script)
+	if err != nil {
+		return string(output), fmt.Errorf("%s: %v (output: %s)", node.Name, err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// restoreEtcdSnapshot stops every server, restores snapshot on the server
+// that holds it (the first one for S3 snapshots) and has the others rejoin
+func restoreEtcdSnapshot(servers []NodeInfo, access nodeSSHAccess, snapshot etcdSnapshot, s3 *etcdSnapshotS3) error {
+	leader := servers[0]
+	for _, server := range servers {
+		if server.Name == snapshot.Node {
+			leader = server
+		}
+	}
+
+	for _, server := range servers {
+		printInfo(fmt.Sprintf("  Stopping RKE2 on %s...", server.Name))
+		if _, err := runEtcdBackupScript(server, access, etcdStopServerScript()); err != nil {
+			return fmt.Errorf("failed to stop RKE2 on %s", err)
+		}
+	}
+
+	printInfo(fmt.Sprintf("  Restoring %s on %s...", snapshot.Name, leader.Name))
+	output, err := runEtcdBackupScript(leader, access, etcdClusterResetScript(snapshot, s3))
+	if err != nil || !strings.Contains(output, "READY") {
+		return fmt.Errorf("failed to restore the snapshot on %s: %v (output: %s)", leader.Name, err, strings.TrimSpace(output))
+	}
+	printSuccess(fmt.Sprintf("  ✓ %s restored and running", leader.Name))
+
+	for _, server := range servers {
+		if server.Name == leader.Name {
+			continue
+		}
+		printInfo(fmt.Sprintf("  Rejoining %s...", server.Name))
+		output, err := runEtcdBackupScript(server, access, etcdRejoinServerScript())
+		if err != nil || !strings.Contains(output, "READY") {
+			return fmt.Errorf("failed to rejoin %s to the restored cluster: %v (output: %s)", server.Name, err, strings.TrimSpace(output))
+		}
+		printSuccess(fmt.Sprintf("  ✓ %s rejoined", server.Name))
+	}
+	return nil
+}
+
+func runBackupEtcd(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+	if !etcdSnapshotNamePattern.MatchString(backupSnapshotName) {
+		return fmt.Errorf("invalid snapshot name %q: use letters, digits, '.', '_' and '-'", backupSnapshotName)
+	}
+
+	printHeader(fmt.Sprintf("💾 etcd Snapshot - Stack: %s", stack))
+
+	s3, err := etcdSnapshotBucket()
+	if err != nil {
+		return err
+	}
+	servers, access, err := loadControlPlane(ctx, stack)
+	if err != nil {
+		return err
+	}
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
+	}
+	server, err := findReachableNode(servers, access)
+	if err != nil {
+		return err
+	}
+
+	target := "the node"
+	if s3 != nil {
+		target = fmt.Sprintf("the node and s3://%s/%s", s3.Bucket, s3.Folder)
+	}
+	printInfo(fmt.Sprintf("Taking snapshot on %s, kept on %s...", server.Name, strings.TrimSuffix(target, "/")))
+
+	output, err := runEtcdBackupScript(server, access, etcdSnapshotSaveScript(backupSnapshotName, s3))
+	if err != nil {
+		return fmt.Errorf("etcd snapshot failed on %s", err)
+	}
+	names := parseEtcdSnapshotSaved(output)
+	if len(names) == 0 {
+		return fmt.Errorf("etcd snapshot on %s did not report a saved snapshot (output: %s)", server.Name, strings.TrimSpace(output))
+	}
+
+	fmt.Println()
+	for _, name := range names {
+		printSuccess(fmt.Sprintf("Snapshot %s saved", name))
+	}
+	printInfo(fmt.Sprintf("Restore it with: sloth-kubernetes backup restore %s --name %s", stack, names[0]))
+	return nil
+}
+
+func runBackupList(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("💾 etcd Snapshots - Stack: %s", stack))
+
+	s3, err := etcdSnapshotBucket()
+	if err != nil {
+		return err
+	}
+	servers, access, err := loadControlPlane(ctx, stack)
+	if err != nil {
+		return err
+	}
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
+	}
+
+	snapshots, unreachable := listEtcdSnapshots(servers, access, s3)
+	for _, name := range unreachable {
+		printWarning(fmt.Sprintf("Could not list the snapshots of %s", name))
+	}
+	if len(unreachable) == len(servers) {
+		return errs.Mark(fmt.Errorf("no control-plane node of stack '%s' could be reached", stack), errs.ErrNodeUnreachable)
+	}
+
+	fmt.Println()
+	if len(snapshots) == 0 {
+		printInfo("No etcd snapshots found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tNODE\tSIZE\tCREATED\tLOCATION")
+	for _, s := range snapshots {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", s.Name, s.Node, s.Size, s.Created, s.Location)
+	}
+	w.Flush()
+	fmt.Println()
+	return nil
+}
+
+func runBackupRestore(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+	if !etcdSnapshotNamePattern.MatchString(backupRestoreName) {
+		return fmt.Errorf("invalid snapshot name %q", backupRestoreName)
+	}
+
+	printHeader(fmt.Sprintf("♻️  etcd Restore - Stack: %s", stack))
+
+	s3, err := etcdSnapshotBucket()
+	if err != nil {
+		return err
+	}
+	servers, access, err := loadControlPlane(ctx, stack)
+	if err != nil {
+		return err
+	}
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
+	}
+
+	// Every server is stopped during the restore, so nothing is changed
+	// unless all of them are reachable
+	snapshots, unreachable := listEtcdSnapshots(servers, access, s3)
+	if len(unreachable) > 0 {
+		return errs.Mark(fmt.Errorf("cannot restore without reaching every control-plane node; failed to reach: %s", strings.Join(unreachable, ", ")), errs.ErrNodeUnreachable)
+	}
+	snapshot, ok := findEtcdSnapshot(snapshots, backupRestoreName)
+	if !ok {
+		return fmt.Errorf("snapshot %s not found; run 'sloth-kubernetes backup list %s' to see the available snapshots", backupRestoreName, stack)
+	}
+
+	fmt.Println()
+	printInfo(fmt.Sprintf("Snapshot: %s (%s, created %s)", snapshot.Name, snapshot.Location, snapshot.Created))
+	printInfo(fmt.Sprintf("Control plane: %d server(s), all stopped during the restore", len(servers)))
+	color.Yellow("⚠️  Everything written to the cluster after the snapshot was taken will be lost")
+	if !autoApprove && !confirm("Restore etcd from this snapshot?") {
+		printWarning("Restore cancelled")
+		return nil
+	}
+
+	fmt.Println()
+	if err := restoreEtcdSnapshot(servers, access, snapshot, s3); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	printSuccess(fmt.Sprintf("Cluster restored from %s", snapshot.Name))
+	return nil
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const etcdSnapshotListOutput = `Name                              Location                                                                          Size    Created
+on-demand-master-1-1700000000     file:///var/lib/rancher/rke2/server/db/snapshots/on-demand-master-1-1700000000     5242912 2023-11-14T22:13:20Z
+etcd-snapshot-master-1-1700100000 file:///var/lib/rancher/rke2/server/db/snapshots/etcd-snapshot-master-1-1700100000 5242912 2023-11-16T02:00:00Z
+`
+
+func TestParseEtcdSnapshotBucket(t *testing.T) {
+	s3, err := parseEtcdSnapshotBucket("my-backups/production/etcd/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if s3.Bucket != "my-backups" || s3.Folder != "production/etcd" {
+		t.Errorf("Unexpected location: %+v", s3)
+	}
+	if got := s3.flags("etcd-"); got != " --etcd-s3 --etcd-s3-bucket=my-backups --etcd-s3-folder=production/etcd" {
+		t.Errorf("Unexpected server flags: %q", got)
+	}
+
+	s3, err = parseEtcdSnapshotBucket("my-backups")
+	if err != nil || s3.Folder != "" || s3.flags("") != " --s3 --s3-bucket=my-backups" {
+		t.Errorf("Unexpected location %+v (err %v)", s3, err)
+	}
+
+	for _, bucket := range []string{"", "My_Bucket", "bucket; rm -rf /", "bucket/$(id)"} {
+		if _, err := parseEtcdSnapshotBucket(bucket); err == nil {
+			t.Errorf("Expected %q to be rejected", bucket)
+		}
+	}
+}
+
+func TestEtcdSnapshotSaveScript(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "it's-secret")
+	t.Setenv("AWS_REGION", "")
+
+	script := etcdSnapshotSaveScript("pre-upgrade", &etcdSnapshotS3{Bucket: "my-backups"})
+	for _, want := range []string{
+		"BIN=rke2; SVC=rke2-server",
+		"export AWS_ACCESS_KEY_ID='AKIAEXAMPLE'",
+		`export AWS_SECRET_ACCESS_KEY='it'\''s-secret'`,
+		"$BIN etcd-snapshot save --name pre-upgrade --s3 --s3-bucket=my-backups",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q, got:\n%s", want, script)
+		}
+	}
+	if strings.Contains(script, "AWS_REGION") {
+		t.Errorf("Expected unset variables to be left out, got:\n%s", script)
+	}
+
+	script = etcdSnapshotSaveScript("pre-upgrade", nil)
+	if strings.Contains(script, "AWS_") || strings.Contains(script, "--s3") {
+		t.Errorf("Expected no S3 settings without a bucket, got:\n%s", script)
+	}
+}
+
+func TestParseEtcdSnapshotSaved(t *testing.T) {
+	output := `time="2023-11-14T22:13:20Z" level=info msg="Saving etcd snapshot to /var/lib/rancher/rke2/server/db/snapshots/pre-upgrade-master-1-1700000000"
+time="2023-11-14T22:13:20Z" level=info msg="Snapshot pre-upgrade-master-1-1700000000 saved."
+time="2023-11-14T22:13:21Z" level=info msg="Snapshot pre-upgrade-master-1-1700000000 saved."
+`
+	names := parseEtcdSnapshotSaved(output)
+	if len(names) != 1 || names[0] != "pre-upgrade-master-1-1700000000" {
+		t.Errorf("Unexpected snapshot names: %v", names)
+	}
+	if names := parseEtcdSnapshotSaved("FATA[0000] etcd database not found"); len(names) != 0 {
+		t.Errorf("Expected no snapshot names, got %v", names)
+	}
+}
+
+func TestParseEtcdSnapshotList(t *testing.T) {
+	snapshots := parseEtcdSnapshotList("master-1", etcdSnapshotListOutput+"level=warning msg=\"Unable to initialize S3\"\n")
+	if len(snapshots) != 2 {
+		t.Fatalf("Expected 2 snapshots, got %+v", snapshots)
+	}
+	s := snapshots[0]
+	if s.Node != "master-1" || s.Name != "on-demand-master-1-1700000000" || s.Size != "5242912" || s.Created != "2023-11-14T22:13:20Z" || s.inS3() {
+		t.Errorf("Unexpected snapshot: %+v", s)
+	}
+}
+
+func TestListEtcdSnapshots(t *testing.T) {
+	servers := []NodeInfo{
+		{Name: "master-1", PublicIP: "203.0.113.10", Provider: "digitalocean", Roles: []string{"master"}},
+		{Name: "master-2", PublicIP: "203.0.113.11", Provider: "digitalocean", Roles: []string{"master"}},
+		{Name: "master-3", PublicIP: "203.0.113.12", Provider: "digitalocean", Roles: []string{"master"}},
+	}
+	s3Line := "on-demand-master-1-1700000000 s3://my-backups/on-demand-master-1-1700000000 5242912 2023-11-14T22:13:20Z\n"
+
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		switch sshTarget(args) {
+		case "root@203.0.113.10":
+			return []byte(etcdSnapshotListOutput + s3Line), nil
+		case "root@203.0.113.11":
+			return []byte(s3Line), nil
+		}
+		return nil, errors.New("connection timed out")
+	})
+
+	snapshots, unreachable := listEtcdSnapshots(servers, nodeSSHAccess{}, &etcdSnapshotS3{Bucket: "my-backups"})
+	if len(unreachable) != 1 || unreachable[0] != "master-3" {
+		t.Errorf("Expected master-3 unreachable, got %v", unreachable)
+	}
+	if len(snapshots) != 3 {
+		t.Fatalf("Expected the S3 snapshot listed once, got %+v", snapshots)
+	}
+	if snapshots[0].Name != "etcd-snapshot-master-1-1700100000" {
+		t.Errorf("Expected the newest snapshot first, got %+v", snapshots[0])
+	}
+
+	snapshot, ok := findEtcdSnapshot(snapshots, "on-demand-master-1-1700000000")
+	if !ok || snapshot.inS3() || snapshot.Node != "master-1" {
+		t.Errorf("Expected the local copy to be preferred, got %+v", snapshot)
+	}
+	if _, ok := findEtcdSnapshot(snapshots, "missing"); ok {
+		t.Error("Expected a missing snapshot not to be found")
+	}
+}
+
+func TestRestoreEtcdSnapshot(t *testing.T) {
+	servers := []NodeInfo{
+		{Name: "master-1", PublicIP: "203.0.113.10", Provider: "digitalocean", Roles: []string{"master"}},
+		{Name: "master-2", PublicIP: "203.0.113.11", Provider: "digitalocean", Roles: []string{"master"}},
+	}
+	snapshot := etcdSnapshot{
+		Node:     "master-2",
+		Name:     "on-demand-master-2-1700000000",
+		Location: "file:///var/lib/rancher/rke2/server/db/snapshots/on-demand-master-2-1700000000",
+	}
+
+	var steps []string
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		target := sshTarget(args)
+		switch {
+		case strings.Contains(stdin, "systemctl stop"):
+			steps = append(steps, "stop "+target)
+			return []byte("STOPPED\n"), nil
+		case strings.Contains(stdin, "--cluster-reset-restore-path=/var/lib/rancher/rke2/server/db/snapshots/on-demand-master-2-1700000000 2>&1"):
+			steps = append(steps, "reset "+target)
+			return []byte("READY\n"), nil
+		case strings.Contains(stdin, `rm -rf "$DATA/server/db/etcd"`):
+			steps = append(steps, "rejoin "+target)
+			return []byte("READY\n"), nil
+		}
+		t.Errorf("Unexpected script for %s:\n%s", target, stdin)
+		return nil, errors.New("unexpected script")
+	})
+
+	if err := restoreEtcdSnapshot(servers, nodeSSHAccess{}, snapshot, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := "stop root@203.0.113.10,stop root@203.0.113.11,reset root@203.0.113.11,rejoin root@203.0.113.10"
+	if got := strings.Join(steps, ","); got != want {
+		t.Errorf("Unexpected restore steps:\n got: %s\nwant: %s", got, want)
+	}
+}
+
+func TestRestoreEtcdSnapshot_FromS3(t *testing.T) {
+	servers := []NodeInfo{
+		{Name: "master-1", PublicIP: "203.0.113.10", Provider: "digitalocean", Roles: []string{"master"}},
+	}
+	snapshot := etcdSnapshot{Node: "s3", Name: "nightly", Location: "s3://my-backups/etcd/nightly"}
+
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		if strings.Contains(stdin, "--cluster-reset") {
+			if !strings.Contains(stdin, "--cluster-reset-restore-path=nightly --etcd-s3 --etcd-s3-bucket=my-backups --etcd-s3-folder=etcd") {
+				t.Errorf("Expected an S3 restore, got:\n%s", stdin)
+			}
+			return []byte("server did not become ready\n"), errors.New("exit status 1")
+		}
+		return []byte("STOPPED\n"), nil
+	})
+
+	err := restoreEtcdSnapshot(servers, nodeSSHAccess{}, snapshot, &etcdSnapshotS3{Bucket: "my-backups", Folder: "etcd"})
+	if err == nil || !strings.Contains(err.Error(), "failed to restore the snapshot on master-1") {
+		t.Errorf("Expected a restore failure, got %v", err)
+	}
+}
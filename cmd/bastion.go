@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/spf13/cobra"
+
+	"github.com/chalkan3/sloth-kubernetes/internal/common"
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+// publicIPServiceURL answers with the caller's public IP as plain text
+const publicIPServiceURL = "https://api.ipify.org"
+
+var (
+	bastionAllowIP      string
+	bastionAllowSSHPort int
+)
+
+var bastionCmd = &cobra.Command{
+	Use:   "bastion",
+	Short: "Manage the bastion host",
+	Long:  `Manage the bastion host that fronts SSH access to the cluster nodes`,
+}
+
+var bastionAllowIPCmd = &cobra.Command{
+	Use:   "allow-ip [stack-name]",
+	Short: "Allow SSH to the bastion from your current public IP",
+	Long: `Detect your current public IP and allow SSH to the bastion from it, replacing
+the rule added by an earlier allow-ip. Use this when your IP has changed since
+the cluster was deployed with --allow-my-ip. You must still be able to reach
+the bastion, e.g. over the VPN or from another allowed address.`,
+	Example: `  # Allow SSH from this machine's public IP
+  sloth-kubernetes bastion allow-ip production
+
+  # Allow a specific address instead of detecting it
+  sloth-kubernetes bastion allow-ip production --ip 203.0.113.7`,
+	RunE: runBastionAllowIP,
+}
+
+func init() {
+	rootCmd.AddCommand(bastionCmd)
+	bastionCmd.AddCommand(bastionAllowIPCmd)
+
+	bastionAllowIPCmd.Flags().StringVar(&bastionAllowIP, "ip", "", "IP to allow (default: detect this machine's public IP)")
+	bastionAllowIPCmd.Flags().IntVar(&bastionAllowSSHPort, "ssh-port", 22, "SSH port of the bastion")
+}
+
+// detectPublicIP returns the public IP this machine reaches the internet
+// from. It is a variable so tests can avoid the network.
+var detectPublicIP = func(ctx context.Context) (string, error) {
+	client, err := common.NewHTTPClient(10 * time.Second)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, publicIPServiceURL, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to detect public IP: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to detect public IP: %s returned %s", publicIPServiceURL, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64))
+	if err != nil {
+		return "", fmt.Errorf("failed to detect public IP: %w", err)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// operatorCIDR returns ip as a host CIDR, detecting this machine's public IP
+// when ip is empty
+func operatorCIDR(ctx context.Context, ip string) (string, error) {
+	if ip == "" {
+		detected, err := detectPublicIP(ctx)
+		if err != nil {
+			return "", err
+		}
+		ip = detected
+	}
+	return config.HostCIDR(ip)
+}
+
+// applyBastionAllowMyIP adds the operator's IP to the bastion's allowed CIDRs
+// when requested by flag or config, then warns if SSH is still open to all
+func applyBastionAllowMyIP(ctx context.Context, cfg *config.ClusterConfig, allowMyIP bool) error {
+	bastion := cfg.Security.Bastion
+	if bastion == nil || !bastion.Enabled {
+		return nil
+	}
+
+	if allowMyIP || bastion.AllowMyIP {
+		cidr, err := operatorCIDR(ctx, "")
+		if err != nil {
+			return fmt.Errorf("--allow-my-ip: %w", err)
+		}
+		bastion.OperatorCIDR = cidr
+		if bastion.AddAllowedCIDR(cidr) {
+			printInfo(fmt.Sprintf("🔒 Bastion SSH allowed from your IP: %s", cidr))
+		}
+	}
+
+	if bastion.OpenToAnywhere() {
+		fmt.Println()
+		color.Red("⚠️  WARNING: the bastion accepts SSH from ANY address (allowedCIDRs is empty or 0.0.0.0/0)")
+		color.Red("   Use --allow-my-ip or set security.bastion.allowedCIDRs to lock it down")
+		fmt.Println()
+	}
+	return nil
+}
+
+// operatorRuleScript allows SSH from cidr on port and removes the operator
+// rules added for other addresses by deploy --allow-my-ip or allow-ip. The new rule goes in first so
+// the operator is never locked out halfway.
+func operatorRuleScript(cidr string, port int) string {
+	ip := strings.SplitN(cidr, "/", 2)[0]
+	return fmt.Sprintf(`set -e
+ufw allow from %[1]s to any port %[2]d proto tcp comment '%[3]s'
+for n in $(ufw status numbered | grep '%[3]s' | grep -v -w -F '%[4]s' | sed -E 's/^\[ *([0-9]+)\].*/\1/' | sort -rn); do
+  ufw --force delete "$n"
+done
+echo SUCCESS
+`, cidr, port, config.BastionOperatorRuleComment, ip)
+}
+
+func runBastionAllowIP(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("🔒 Bastion Allow IP - Stack: %s", stack))
+
+	// Create workspace with S3 support
+	workspace, err := createWorkspaceWithS3Support(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	// Use fully qualified stack name for S3 backend
+	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
+	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
+	if err != nil {
+		return fmt.Errorf("failed to select stack '%s': %w", stack, err)
+	}
+
+	outputs, err := s.Outputs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get stack outputs: %w", err)
+	}
+
+	bastion, ok := bastionAuditTarget(outputs)
+	if !ok {
+		return fmt.Errorf("stack '%s' has no bastion", stack)
+	}
+
+	cidr, err := operatorCIDR(ctx, bastionAllowIP)
+	if err != nil {
+		return err
+	}
+	printInfo(fmt.Sprintf("Allowing SSH to %s from %s", bastion.PublicIP, cidr))
+
+	// The bastion itself is always reached directly, as its provider's user
+	access := nodeSSHAccess{Stack: stack, KeyPath: GetSSHKeyPath(stack)}
+	user := getSSHUserForNode(bastion.Provider)
+	output, err := sshRunner(access.args(bastion, user, 10, "sudo", "bash", "-s"), operatorRuleScript(cidr, bastionAllowSSHPort))
+	if err != nil || !strings.Contains(string(output), "SUCCESS") {
+		return fmt.Errorf("failed to update bastion firewall: %v (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+
+	printSuccess(fmt.Sprintf("Bastion SSH allowed from %s", cidr))
+	return nil
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+func stubDetectPublicIP(t *testing.T, ip string, err error) {
+	t.Helper()
+	original := detectPublicIP
+	detectPublicIP = func(ctx context.Context) (string, error) { return ip, err }
+	t.Cleanup(func() { detectPublicIP = original })
+}
+
+func TestApplyBastionAllowMyIP(t *testing.T) {
+	stubDetectPublicIP(t, "203.0.113.7\n", nil)
+
+	cfg := &config.ClusterConfig{}
+	cfg.Security.Bastion = &config.BastionConfig{Enabled: true, AllowedCIDRs: []string{"198.51.100.0/24"}}
+
+	if err := applyBastionAllowMyIP(context.Background(), cfg, true); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	bastion := cfg.Security.Bastion
+	if strings.Join(bastion.AllowedCIDRs, ",") != "198.51.100.0/24,203.0.113.7/32" {
+		t.Errorf("Expected the operator IP appended, got %v", bastion.AllowedCIDRs)
+	}
+	if bastion.OperatorCIDR != "203.0.113.7/32" {
+		t.Errorf("Expected the operator CIDR recorded, got %q", bastion.OperatorCIDR)
+	}
+}
+
+func TestApplyBastionAllowMyIP_ConfigToggle(t *testing.T) {
+	stubDetectPublicIP(t, "203.0.113.7", nil)
+
+	cfg := &config.ClusterConfig{}
+	cfg.Security.Bastion = &config.BastionConfig{Enabled: true, AllowMyIP: true}
+	if err := applyBastionAllowMyIP(context.Background(), cfg, false); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cfg.Security.Bastion.OpenToAnywhere() {
+		t.Errorf("Expected the bastion to be locked down, got %v", cfg.Security.Bastion.AllowedCIDRs)
+	}
+}
+
+func TestApplyBastionAllowMyIP_DetectionFails(t *testing.T) {
+	stubDetectPublicIP(t, "", errors.New("network unreachable"))
+
+	cfg := &config.ClusterConfig{}
+	cfg.Security.Bastion = &config.BastionConfig{Enabled: true}
+	if err := applyBastionAllowMyIP(context.Background(), cfg, true); err == nil {
+		t.Error("Expected an error when the IP cannot be detected")
+	}
+
+	// Without the option nothing is detected
+	if err := applyBastionAllowMyIP(context.Background(), cfg, false); err != nil {
+		t.Errorf("Unexpected error without --allow-my-ip: %v", err)
+	}
+}
+
+func TestOperatorRuleScript(t *testing.T) {
+	script := operatorRuleScript("203.0.113.7/32", 2222)
+
+	allow := "ufw allow from 203.0.113.7/32 to any port 2222 proto tcp comment 'sloth-operator'"
+	if !strings.Contains(script, allow) {
+		t.Errorf("Expected rule %q in script:\n%s", allow, script)
+	}
+	if strings.Index(script, allow) > strings.Index(script, "ufw --force delete") {
+		t.Error("The new rule must be added before old ones are deleted")
+	}
+	if !strings.Contains(script, "grep -v -w -F '203.0.113.7'") {
+		t.Error("Expected the new address to be kept when deleting old rules")
+	}
+}
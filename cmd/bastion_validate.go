@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// bastionValidationAttempts and bastionValidationBackoff bound the retries of
+// the bastion SSH check after deploy, so a racing sshd reload or lagging key
+// propagation does not fail the deployment. The backoff doubles per attempt.
+// They are variables so tests do not sleep.
+var (
+	bastionValidationAttempts = 5
+	bastionValidationBackoff  = 5 * time.Second
+)
+
+// bastionValidationScript confirms SSH and the bastion's security services
+// are up
+const bastionValidationScript = `echo "✅ SSH connection successful!"
+echo "  • Hostname: $(hostname)"
+echo "  • Uptime: $(uptime -p)"
+echo "  • SSH service: $(systemctl is-active sshd)"
+echo "  • UFW status: $(ufw status | head -1)"
+echo "  • fail2ban status: $(systemctl is-active fail2ban)"
+`
+
+// sshFailureKind categorizes why an ssh invocation failed
+type sshFailureKind string
+
+const (
+	sshFailureAuth    sshFailureKind = "auth"
+	sshFailureHostKey sshFailureKind = "host-key"
+	sshFailureRefused sshFailureKind = "connection-refused"
+	sshFailureReset   sshFailureKind = "connection-reset"
+	sshFailureTimeout sshFailureKind = "timeout"
+	sshFailureCommand sshFailureKind = "command"
+	sshFailureUnknown sshFailureKind = "unknown"
+)
+
+// hint returns what the user should check for this kind of failure
+func (k sshFailureKind) hint() string {
+	switch k {
+	case sshFailureAuth:
+		return "the SSH key was rejected; check the key is authorized for the bastion user"
+	case sshFailureHostKey:
+		return "the bastion's host key does not match the recorded one; check nobody is intercepting the connection"
+	case sshFailureRefused:
+		return "nothing is accepting connections on port 22; check sshd is running on the bastion"
+	case sshFailureReset:
+		return "the connection was reset; sshd may still be restarting"
+	case sshFailureTimeout:
+		return "the bastion did not answer in time; check the firewall, allowed CIDRs and that the host is up"
+	case sshFailureCommand:
+		return "SSH works but the validation script failed on the bastion"
+	default:
+		return "unexpected SSH error"
+	}
+}
+
+// classifySSHFailure maps a failed ssh invocation to a failure kind from its
+// error and output. ssh exits with 255 on its own errors; any other exit
+// status comes from the remote command.
+func classifySSHFailure(output []byte, err error) sshFailureKind {
+	if err == nil {
+		return ""
+	}
+
+	msg := string(output) + " " + err.Error()
+	switch {
+	case hostKeyChanged(output):
+		return sshFailureHostKey
+	case strings.Contains(msg, "Permission denied"):
+		return sshFailureAuth
+	case strings.Contains(msg, "Connection refused"):
+		return sshFailureRefused
+	case strings.Contains(msg, "Connection reset"), strings.Contains(msg, "Connection closed"), strings.Contains(msg, "kex_exchange_identification"):
+		return sshFailureReset
+	case strings.Contains(msg, "timed out"):
+		return sshFailureTimeout
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() != 255 {
+		return sshFailureCommand
+	}
+	return sshFailureUnknown
+}
+
+// validateBastionSSH runs the validation script on the bastion over the same
+// ssh options, host key checks and jump hosts as every other bastion
+// connection, retrying transient failures with exponential backoff
+func validateBastionSSH(access nodeSSHAccess) (string, error) {
+	var lastErr error
+	var lastKind sshFailureKind
+
+	backoff := bastionValidationBackoff
+	for attempt := 1; attempt <= bastionValidationAttempts; attempt++ {
+		output, err := sshRunner(access.bastionArgs(15, "sudo", "bash", "-s"), bastionValidationScript)
+		if err == nil {
+			return string(output), nil
+		}
+
+		lastErr = fmt.Errorf("%w: %s", err, strings.TrimSpace(string(output)))
+		lastKind = classifySSHFailure(output, err)
+		printWarning(fmt.Sprintf("Bastion SSH validation attempt %d/%d failed (%s): %v", attempt, bastionValidationAttempts, lastKind, lastErr))
+
+		// A changed host key will not fix itself
+		if lastKind == sshFailureHostKey {
+			break
+		}
+		if attempt < bastionValidationAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+
+	return "", fmt.Errorf("bastion SSH validation failed (%s: %s): %w", lastKind, lastKind.hint(), lastErr)
+}
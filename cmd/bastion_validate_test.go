@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// stubBastionValidation makes the bastion validation retry without sleeping
+func stubBastionValidation(t *testing.T, attempts int) {
+	t.Helper()
+	originalAttempts, originalBackoff := bastionValidationAttempts, bastionValidationBackoff
+	bastionValidationAttempts = attempts
+	bastionValidationBackoff = 0
+	t.Cleanup(func() {
+		bastionValidationAttempts = originalAttempts
+		bastionValidationBackoff = originalBackoff
+	})
+}
+
+func TestValidateBastionSSH_RetriesThroughJumpHosts(t *testing.T) {
+	stubBastionValidation(t, 5)
+	verifyHostKeys = true
+	t.Cleanup(func() { verifyHostKeys = false })
+	t.Setenv("HOME", t.TempDir())
+
+	var calls [][]string
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		calls = append(calls, args)
+		if len(calls) < 3 {
+			return []byte("kex_exchange_identification: Connection closed by remote host"), errors.New("exit status 255")
+		}
+		return []byte("✅ SSH connection successful!"), nil
+	})
+
+	access := nodeSSHAccess{
+		Stack:          "prod",
+		KeyPath:        "/tmp/key",
+		BastionEnabled: true,
+		BastionIP:      "203.0.113.5",
+		BastionUser:    "ubuntu",
+		JumpHosts:      []string{"ops@jump.example.com"},
+	}
+	output, err := validateBastionSSH(access)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(output, "SSH connection successful") {
+		t.Errorf("Expected validation output, got %q", output)
+	}
+	if len(calls) != 3 {
+		t.Fatalf("Expected 3 attempts, got %d", len(calls))
+	}
+
+	joined := strings.Join(calls[0], " ")
+	for _, want := range []string{"-J ops@jump.example.com", "StrictHostKeyChecking=accept-new", "UserKnownHostsFile=", "ubuntu@203.0.113.5"} {
+		if !strings.Contains(joined, want) {
+			t.Errorf("Expected %q in ssh args, got %v", want, calls[0])
+		}
+	}
+	if strings.Contains(joined, "UserKnownHostsFile=/dev/null") {
+		t.Errorf("Expected host keys to be recorded, got %v", calls[0])
+	}
+}
+
+func TestValidateBastionSSH_StopsOnChangedHostKey(t *testing.T) {
+	stubBastionValidation(t, 5)
+
+	calls := 0
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		calls++
+		return []byte("@@@ WARNING: REMOTE HOST IDENTIFICATION HAS CHANGED! @@@\nHost key verification failed."), errors.New("exit status 255")
+	})
+
+	_, err := validateBastionSSH(nodeSSHAccess{Stack: "prod", BastionEnabled: true, BastionIP: "203.0.113.5"})
+	if err == nil {
+		t.Fatal("Expected error for a changed host key")
+	}
+	if calls != 1 {
+		t.Errorf("Expected no retry after a changed host key, got %d attempts", calls)
+	}
+	if !strings.Contains(err.Error(), string(sshFailureHostKey)) {
+		t.Errorf("Expected host key failure in error, got: %v", err)
+	}
+}
+
+func TestClassifySSHFailure(t *testing.T) {
+	exit255 := errors.New("exit status 255")
+	tests := []struct {
+		output string
+		kind   sshFailureKind
+	}{
+		{"ubuntu@203.0.113.5: Permission denied (publickey).", sshFailureAuth},
+		{"ssh: connect to host 203.0.113.5 port 22: Connection refused", sshFailureRefused},
+		{"Connection reset by 203.0.113.5 port 22", sshFailureReset},
+		{"ssh: connect to host 203.0.113.5 port 22: Connection timed out", sshFailureTimeout},
+		{"Host key verification failed.", sshFailureHostKey},
+		{"something odd", sshFailureUnknown},
+	}
+
+	for _, tt := range tests {
+		if kind := classifySSHFailure([]byte(tt.output), exit255); kind != tt.kind {
+			t.Errorf("classifySSHFailure(%q) = %s, want %s", tt.output, kind, tt.kind)
+		}
+	}
+}
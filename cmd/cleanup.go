@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// tempPaths holds the temporary files and directories of the running
+// command, removed when it exits. Files meant to outlive the command, such as
+// client configs and kubeconfigs saved for the user, are never added.
+var tempPaths = &cleanupRegistry{}
+
+// interruptsHandled is set while a command handles interrupts itself, so the
+// cleanup handler leaves the process running
+var interruptsHandled atomic.Int32
+
+// cleanupRegistry is a list of paths to remove
+type cleanupRegistry struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+// Add registers path for removal
+func (r *cleanupRegistry) Add(path string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.paths = append(r.paths, path)
+}
+
+// Run removes the registered paths, newest first, and empties the registry
+func (r *cleanupRegistry) Run() {
+	r.mu.Lock()
+	paths := r.paths
+	r.paths = nil
+	r.mu.Unlock()
+
+	for i := len(paths) - 1; i >= 0; i-- {
+		if err := os.RemoveAll(paths[i]); err != nil && verbose {
+			fmt.Fprintf(os.Stderr, "warning: failed to remove %s: %v\n", paths[i], err)
+		}
+	}
+}
+
+// createTempFile writes data to a new file only the user can read, removed
+// when the command exits. It is the place for secrets tools need on disk.
+func createTempFile(pattern string, data []byte) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tempPaths.Add(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return f.Name(), nil
+}
+
+// createTempDir creates a directory removed when the command exits
+func createTempDir(pattern string) (string, error) {
+	dir, err := os.MkdirTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp dir: %w", err)
+	}
+	tempPaths.Add(dir)
+	return dir, nil
+}
+
+// runWithCleanup runs fn and removes the temporary paths once it returns, or
+// when the process is interrupted while no command handles interrupts itself
+func runWithCleanup(fn func() error) error {
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for {
+			select {
+			case <-signals:
+				if interruptsHandled.Load() > 0 {
+					continue
+				}
+				tempPaths.Run()
+				os.Exit(130)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	defer func() {
+		signal.Stop(signals)
+		close(done)
+		tempPaths.Run()
+	}()
+	return fn()
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunWithCleanup_RemovesTempPaths(t *testing.T) {
+	var file, dir string
+	err := runWithCleanup(func() error {
+		var err error
+		if file, err = createTempFile("cleanup-test-*", []byte("secret")); err != nil {
+			return err
+		}
+		if dir, err = createTempDir("cleanup-test-*"); err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, "nested"), []byte("data"), 0600); err != nil {
+			return err
+		}
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("temp file mode = %v, want 0600", info.Mode().Perm())
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, path := range []string{file, dir} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed after the command returns, got %v", path, err)
+		}
+	}
+}
+
+func TestRunWithCleanup_KeepsUnregisteredFiles(t *testing.T) {
+	kept := filepath.Join(t.TempDir(), "client.conf")
+	err := runWithCleanup(func() error {
+		if _, err := createTempFile("cleanup-test-*", nil); err != nil {
+			return err
+		}
+		return os.WriteFile(kept, []byte("persisted"), 0600)
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("a file not registered should be kept: %v", err)
+	}
+}
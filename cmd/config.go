@@ -1,9 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"strings"
 
 	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
 	"github.com/spf13/cobra"
 
 	"github.com/chalkan3/sloth-kubernetes/pkg/config"
@@ -12,6 +16,14 @@ import (
 var (
 	outputPath string
 	format     string
+
+	// Convert command flags
+	convertFrom   string
+	convertTo     string
+	convertOutput string
+
+	// Schema command flags
+	schemaOutput string
 )
 
 var configCmd = &cobra.Command{
@@ -45,12 +57,117 @@ You can use environment variables in the YAML using ${VAR_NAME} syntax.`,
 	RunE: runGenerate,
 }
 
+var convertCmd = &cobra.Command{
+	Use:   "convert",
+	Short: "Convert between Pulumi config and YAML configuration",
+	Long: `Convert cluster settings between the two configuration styles.
+
+  pulumi -> yaml: reads the stack's Pulumi config (provider tokens, WireGuard
+                  server, RKE2 token) and writes an equivalent cluster YAML file
+  yaml -> pulumi: reads a cluster YAML file and sets the matching Pulumi config
+                  values on the stack
+
+The converted output is validated before the command succeeds.`,
+	Example: `  # Export a stack's Pulumi config as cluster YAML
+  sloth-kubernetes config convert --from pulumi --to yaml --stack production -o cluster.yaml
+
+  # Push a cluster YAML file into a stack's Pulumi config
+  sloth-kubernetes config convert --from yaml --to pulumi --stack production -c cluster.yaml`,
+	RunE: runConvert,
+}
+
+var schemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Generate a JSON Schema for cluster configuration files",
+	Long: `Generate a draft-07 JSON Schema for cluster configuration files in the
+ClusterConfig layout (metadata, providers, network, nodePools, ... at the top
+level). The schema is generated from the config types, so it always matches
+this version of sloth-kubernetes.
+
+Point your editor's YAML language server at it for completion and
+validation, or validate configs in CI.`,
+	Example: `  # Write the schema to a file
+  sloth-kubernetes config schema --output cluster.schema.json
+
+  # Use it from a config file (YAML language server)
+  # yaml-language-server: $schema=./cluster.schema.json`,
+	RunE: runSchema,
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate <file>",
+	Short: "Check a cluster configuration file and report every problem",
+	Long: `Load a cluster configuration file in the ClusterConfig layout, with
+defaults applied, and run every validator over it. Unlike the deploy-time
+checks, which stop at the first error, all problems are reported at once,
+each with the YAML path of the offending field:
+
+  • overlapping or invalid CIDRs
+  • enabled providers without an API token
+  • missing or even control plane counts
+  • WireGuard enabled without a server endpoint or key
+  • unsupported bastion, node pool, or ingress settings
+
+The command exits non-zero when any problem is found.`,
+	Example: `  # Check a config before deploying
+  sloth-kubernetes config validate cluster.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runConfigValidate,
+}
+
 func init() {
 	rootCmd.AddCommand(configCmd)
 	configCmd.AddCommand(generateCmd)
+	configCmd.AddCommand(convertCmd)
+	configCmd.AddCommand(schemaCmd)
+	configCmd.AddCommand(configValidateCmd)
 
 	generateCmd.Flags().StringVarP(&outputPath, "output", "o", "cluster-config.yaml", "Output file path")
 	generateCmd.Flags().StringVar(&format, "format", "full", "Config format: full|minimal")
+
+	convertCmd.Flags().StringVar(&convertFrom, "from", "", "Source format: pulumi|yaml")
+	convertCmd.Flags().StringVar(&convertTo, "to", "", "Target format: pulumi|yaml")
+	convertCmd.Flags().StringVarP(&convertOutput, "output", "o", "cluster-config.yaml", "Output file path (when converting to yaml)")
+
+	schemaCmd.Flags().StringVarP(&schemaOutput, "output", "o", "", "Output file path (default: stdout)")
+}
+
+func runSchema(cmd *cobra.Command, args []string) error {
+	schema, err := config.GenerateJSONSchema()
+	if err != nil {
+		return fmt.Errorf("failed to generate schema: %w", err)
+	}
+
+	if schemaOutput == "" {
+		fmt.Println(string(schema))
+		return nil
+	}
+
+	if err := os.WriteFile(schemaOutput, append(schema, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write schema: %w", err)
+	}
+	printSuccess(fmt.Sprintf("JSON Schema written to %s", schemaOutput))
+	return nil
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) error {
+	loader := config.NewLoader(args[0])
+	cfg, err := loader.Parse()
+	if err != nil {
+		return err
+	}
+
+	diags := loader.Diagnose(cfg)
+	if len(diags) == 0 {
+		printSuccess(fmt.Sprintf("%s is valid", args[0]))
+		return nil
+	}
+
+	color.Red("❌ %s has %d problem(s):", args[0], len(diags))
+	for _, d := range diags {
+		fmt.Printf("  • %s\n", d)
+	}
+	return fmt.Errorf("configuration %s is invalid: %d problem(s) found", args[0], len(diags))
 }
 
 func runGenerate(cmd *cobra.Command, args []string) error {
@@ -81,6 +198,128 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func runConvert(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	pulumiToYAML := convertFrom == "pulumi" && convertTo == "yaml"
+	yamlToPulumi := convertFrom == "yaml" && convertTo == "pulumi"
+	if !pulumiToYAML && !yamlToPulumi {
+		return fmt.Errorf("unsupported conversion %q -> %q (use --from pulumi --to yaml or --from yaml --to pulumi)", convertFrom, convertTo)
+	}
+
+	stackName, err := getStackFromArgs(nil, 0)
+	if err != nil {
+		return err
+	}
+
+	if pulumiToYAML {
+		return convertPulumiToYAML(ctx, stackName)
+	}
+	return convertYAMLToPulumi(ctx, stackName)
+}
+
+// convertPulumiToYAML writes the stack's Pulumi config as a cluster YAML file
+func convertPulumiToYAML(ctx context.Context, stackName string) error {
+	printHeader(fmt.Sprintf("🔄 Converting Pulumi config to YAML - Stack: %s", stackName))
+
+	stack, err := selectConvertStack(ctx, stackName)
+	if err != nil {
+		return err
+	}
+
+	stackConfig, err := stack.GetAllConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read stack config: %w", err)
+	}
+
+	cfg, err := config.BuildFromPulumiValues(pulumiValuesFromStackConfig(stackConfig))
+	if err != nil {
+		return fmt.Errorf("failed to build configuration: %w", err)
+	}
+
+	if err := config.SaveToYAML(cfg, convertOutput); err != nil {
+		return fmt.Errorf("failed to save config: %w", err)
+	}
+
+	// Make sure the YAML loads cleanly through the regular loader
+	if _, err := config.NewLoader(convertOutput).Load(); err != nil {
+		return fmt.Errorf("converted config at %s does not load: %w", convertOutput, err)
+	}
+
+	printSuccess(fmt.Sprintf("Configuration saved to %s", convertOutput))
+	printWarning("The file contains provider tokens in plain text - replace them with ${VAR} references before committing it")
+
+	return nil
+}
+
+// convertYAMLToPulumi sets the stack's Pulumi config from a cluster YAML file
+func convertYAMLToPulumi(ctx context.Context, stackName string) error {
+	configPath := cfgFile
+	if configPath == "" {
+		configPath = "cluster-config.yaml"
+	}
+
+	printHeader(fmt.Sprintf("🔄 Converting %s to Pulumi config - Stack: %s", configPath, stackName))
+
+	cfg, err := config.NewLoader(configPath).Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	values := config.PulumiValuesFromConfig(cfg)
+
+	// Make sure the values are enough for a Pulumi-config deployment before touching the stack
+	if _, err := config.BuildFromPulumiValues(values); err != nil {
+		return fmt.Errorf("config cannot be expressed as Pulumi config: %w", err)
+	}
+
+	stack, err := selectConvertStack(ctx, stackName)
+	if err != nil {
+		return err
+	}
+
+	configs := make(auto.ConfigMap, len(values))
+	for key, value := range values {
+		configs[key] = auto.ConfigValue{Value: value, Secret: config.IsSecretPulumiKey(key)}
+	}
+
+	if err := stack.SetAllConfig(ctx, configs); err != nil {
+		return fmt.Errorf("failed to set stack config: %w", err)
+	}
+
+	printSuccess(fmt.Sprintf("Set %d Pulumi config values on stack %s", len(configs), stackName))
+	return nil
+}
+
+// selectConvertStack selects the named stack on the S3-aware workspace
+func selectConvertStack(ctx context.Context, stackName string) (auto.Stack, error) {
+	workspace, err := createWorkspaceWithS3Support(ctx)
+	if err != nil {
+		return auto.Stack{}, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stackName)
+	stack, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
+	if err != nil {
+		return auto.Stack{}, fmt.Errorf("failed to select stack '%s': %w", stackName, err)
+	}
+
+	return stack, nil
+}
+
+// pulumiValuesFromStackConfig strips the project namespace from stack config keys
+// ("sloth-kubernetes:linodeToken" -> "linodeToken")
+func pulumiValuesFromStackConfig(stackConfig auto.ConfigMap) map[string]string {
+	values := make(map[string]string, len(stackConfig))
+	for key, value := range stackConfig {
+		if idx := strings.Index(key, ":"); idx >= 0 {
+			key = key[idx+1:]
+		}
+		values[key] = value.Value
+	}
+	return values
+}
+
 func generateMinimalConfig() *config.KubernetesStyleConfig {
 	return &config.KubernetesStyleConfig{
 		APIVersion: "kubernetes-create.io/v1",
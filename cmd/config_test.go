@@ -1,9 +1,14 @@
 package cmd
 
 import (
+	"encoding/json"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+
 	"github.com/chalkan3/sloth-kubernetes/pkg/config"
 )
 
@@ -421,3 +426,94 @@ func TestConfigGeneration(t *testing.T) {
 		})
 	}
 }
+
+// TestPulumiValuesFromStackConfig tests stripping the project namespace from stack config keys
+func TestPulumiValuesFromStackConfig(t *testing.T) {
+	values := pulumiValuesFromStackConfig(auto.ConfigMap{
+		"sloth-kubernetes:linodeToken": {Value: "linode-token", Secret: true},
+		"digitaloceanToken":            {Value: "do-token"},
+	})
+
+	if values["linodeToken"] != "linode-token" {
+		t.Errorf("Expected namespaced key to be stripped, got %v", values)
+	}
+	if values["digitaloceanToken"] != "do-token" {
+		t.Errorf("Expected plain key to be kept, got %v", values)
+	}
+}
+
+// TestConvertedPulumiConfigLoads tests that a config built from Pulumi values loads through the YAML loader
+func TestConvertedPulumiConfigLoads(t *testing.T) {
+	cfg, err := config.BuildFromPulumiValues(map[string]string{
+		"digitaloceanToken":        "do-token",
+		"linodeToken":              "linode-token",
+		"wireguardServerEndpoint":  "203.0.113.1:51820",
+		"wireguardServerPublicKey": "server-public-key",
+	})
+	if err != nil {
+		t.Fatalf("BuildFromPulumiValues failed: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "cluster.yaml")
+	if err := config.SaveToYAML(cfg, path); err != nil {
+		t.Fatalf("SaveToYAML failed: %v", err)
+	}
+
+	loaded, err := config.NewLoader(path).Load()
+	if err != nil {
+		t.Fatalf("Converted config should load cleanly: %v", err)
+	}
+
+	if loaded.Network.WireGuard == nil || loaded.Network.WireGuard.ServerEndpoint != "203.0.113.1:51820" {
+		t.Error("WireGuard endpoint should survive the conversion")
+	}
+	if len(loaded.NodePools) != len(cfg.NodePools) {
+		t.Errorf("Expected %d node pools, got %d", len(cfg.NodePools), len(loaded.NodePools))
+	}
+}
+
+// TestRunSchema tests that config schema writes the generated schema
+func TestRunSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cluster.schema.json")
+	schemaOutput = path
+	defer func() { schemaOutput = "" }()
+
+	if err := runSchema(schemaCmd, nil); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Expected the schema file: %v", err)
+	}
+	var schema map[string]interface{}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Expected valid JSON: %v", err)
+	}
+	if schema["$schema"] != config.JSONSchemaDraft {
+		t.Errorf("Unexpected $schema %v", schema["$schema"])
+	}
+}
+
+func TestRunConfigValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cluster.yaml")
+	data := `metadata:
+  name: prod
+providers:
+  digitalocean:
+    enabled: true
+nodePools:
+  masters:
+    provider: digitalocean
+    count: 2
+    roles: [master]
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := runConfigValidate(configValidateCmd, []string{path})
+	if err == nil || !strings.Contains(err.Error(), "2 problem(s)") {
+		t.Errorf("Expected the missing token and even master count, got %v", err)
+	}
+}
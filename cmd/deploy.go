@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
@@ -10,6 +11,7 @@ import (
 	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optpreview"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
 	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
@@ -30,6 +32,11 @@ var (
 	wireguardEndpoint string
 	wireguardPubKey   string
 	dryRun            bool
+	onlyRole          string
+	deployAllowMyIP   bool
+
+	deployReadyTimeout      time.Duration
+	deployReadyPollInterval time.Duration
 )
 
 var deployCmd = &cobra.Command{
@@ -55,7 +62,16 @@ Each stack maintains its own state file, enabling cluster updates and parallel d
   sloth-kubernetes deploy production --config prod.yaml
 
   # Preview without applying
-  sloth-kubernetes deploy my-cluster --config test.yaml --dry-run`,
+  sloth-kubernetes deploy my-cluster --config test.yaml --dry-run
+
+  # Re-deploy only worker nodes, leaving the control plane untouched
+  sloth-kubernetes deploy production --config prod.yaml --only-role worker
+
+  # Remove the lock left by a crashed deploy first
+  sloth-kubernetes deploy production --config prod.yaml --force-unlock
+
+  # Only allow bastion SSH from this machine's public IP
+  sloth-kubernetes deploy production --config prod.yaml --allow-my-ip`,
 	RunE: runDeploy,
 }
 
@@ -66,7 +82,11 @@ func init() {
 	deployCmd.Flags().StringVar(&linodeToken, "linode-token", "", "Linode API token")
 	deployCmd.Flags().StringVar(&wireguardEndpoint, "wireguard-endpoint", "", "WireGuard server endpoint (e.g., 1.2.3.4:51820)")
 	deployCmd.Flags().StringVar(&wireguardPubKey, "wireguard-pubkey", "", "WireGuard server public key")
-	deployCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Preview changes without applying")
+	deployCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the execution plan and preview changes without applying")
+	deployCmd.Flags().StringVar(&onlyRole, "only-role", "", "Restrict the deployment to already-deployed nodes of one role: worker|master")
+	deployCmd.Flags().BoolVar(&deployAllowMyIP, "allow-my-ip", false, "Restrict bastion SSH to this machine's public IP (adds <ip>/32 to allowedCIDRs)")
+	deployCmd.Flags().DurationVar(&deployReadyTimeout, "ready-timeout", 0, "How long to wait for the Kubernetes API and every node to be Ready (default: kubernetes.readyTimeout or 10m)")
+	deployCmd.Flags().DurationVar(&deployReadyPollInterval, "ready-poll-interval", 0, "How often to check Kubernetes readiness (default: kubernetes.readyPollInterval or 10s)")
 }
 
 func runDeploy(cmd *cobra.Command, args []string) error {
@@ -76,17 +96,17 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 	// before any Pulumi API calls that might initialize AWS SDK
 	_ = common.LoadSavedConfig()
 
-	// Parse stack name from args (first positional argument)
-	if len(args) > 0 {
-		stackName = args[0]
-		printInfo(fmt.Sprintf("📦 Using stack: %s", stackName))
-	} else {
-		// Use default stack name if not provided
-		if stackName == "" {
-			stackName = "production"
-		}
-		printInfo(fmt.Sprintf("📦 Using default stack: %s", stackName))
+	// Resolve stack from args, --stack or the configured default
+	resolvedStack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
 	}
+	stackName = resolvedStack
+
+	// With JSON output stdout carries nothing but events
+	defer eventsOnlyStdout()()
+
+	printInfo(fmt.Sprintf("📦 Using stack: %s", stackName))
 
 	// Print header
 	printHeader("🚀 Kubernetes Multi-Cloud Deployment")
@@ -104,6 +124,31 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 	s.Stop()
 	printSuccess("Configuration loaded")
 
+	// Readiness flags override the config
+	applyKubernetesReadyFlags(cmd, cfg)
+
+	// Lock the bastion down to the deployer when asked to
+	if err := applyBastionAllowMyIP(ctx, cfg, deployAllowMyIP); err != nil {
+		return err
+	}
+
+	// Resolve the node set for --only-role before doing anything else
+	var roleNodeNames []string
+	if onlyRole != "" {
+		if err := config.ValidateRoleFilter(onlyRole); err != nil {
+			return err
+		}
+		onlyRole = config.NormalizeRole(onlyRole)
+		roleNodeNames, err = config.NodeNamesWithRole(cfg, onlyRole)
+		if err != nil {
+			return err
+		}
+		if len(roleNodeNames) == 0 {
+			return fmt.Errorf("no %s nodes in configuration", onlyRole)
+		}
+		printInfo(fmt.Sprintf("🎯 Restricting deployment to %d %s node(s): %s", len(roleNodeNames), onlyRole, strings.Join(roleNodeNames, ", ")))
+	}
+
 	// Comprehensive validation before deployment
 	fmt.Println()
 	printHeader("🔍 Pre-Deployment Validation")
@@ -144,13 +189,27 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 			fmt.Println()
 			if !autoApprove {
 				if !confirm("Continue anyway?") {
-					return fmt.Errorf("deployment cancelled due to API validation failure")
+					return fmt.Errorf("deployment cancelled due to API validation failure: %w", err)
 				}
 			}
 		} else {
 			s.Stop()
 			color.Green("✅ API tokens verified with providers")
 		}
+
+		s.Suffix = " Validating node images with cloud providers..."
+		s.Start()
+		imageWarnings, err := validation.ValidateNodeImages(cfg, validation.NewImageCatalog())
+		s.Stop()
+		for _, warning := range imageWarnings {
+			color.Yellow("⚠️  Warning: %s", warning)
+		}
+		if err != nil {
+			color.Red("❌ Node image validation failed")
+			fmt.Println()
+			return err
+		}
+		color.Green("✅ Node images are available in their regions")
 	}
 
 	// Step 4: Validate node pools
@@ -201,6 +260,30 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 	s.Stop()
 	color.Green("✅ Resource sizes validated")
 
+	// Step 8: Validate disabled RKE2 components
+	if err := config.ValidateDisabledComponents(cfg); err != nil {
+		color.Red("❌ RKE2 component validation failed")
+		fmt.Println()
+		return err
+	}
+	_, componentWarnings := config.CheckDisabledComponents(cfg)
+	for _, warning := range componentWarnings {
+		color.Yellow("⚠️  %s", warning)
+	}
+	color.Green("✅ RKE2 components validated")
+
+	// Step 9: Validate admission plugins
+	if err := config.ValidateAdmissionConfig(&cfg.Kubernetes.Admission); err != nil {
+		color.Red("❌ Admission plugin validation failed")
+		fmt.Println()
+		return err
+	}
+	_, admissionWarnings := config.CheckAdmissionConfig(&cfg.Kubernetes.Admission)
+	for _, warning := range admissionWarnings {
+		color.Yellow("⚠️  %s", warning)
+	}
+	color.Green("✅ Admission plugins validated")
+
 	fmt.Println()
 	color.Green("✅ All pre-deployment validations passed!")
 	fmt.Println()
@@ -208,6 +291,11 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 	// Print summary
 	printDeploymentSummary(cfg)
 
+	// Show what the orchestrator will do before Pulumi previews resources
+	if dryRun {
+		printDeployPlan(deployPhasePlan(cfg))
+	}
+
 	// Confirm deployment
 	if !autoApprove && !dryRun {
 		if !confirm("Do you want to proceed with deployment?") {
@@ -216,7 +304,136 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	// Create Pulumi program
+	// Setup Pulumi Automation API stack
+	fmt.Println()
+	printInfo("🔧 Setting up Pulumi stack...")
+
+	stack, err := selectClusterStack(ctx, stackName, cfg)
+	if err != nil {
+		return err
+	}
+
+	printSuccess("Pulumi stack configured")
+
+	// Clear the lock a crashed run left behind
+	if forceUnlock {
+		if err := unlockStack(ctx, stack, stackName); err != nil {
+			return err
+		}
+	}
+
+	// Refresh stack
+	fmt.Println()
+	printInfo("🔄 Refreshing stack state...")
+	_, err = stack.Refresh(ctx)
+	if err != nil {
+		return stackLockedError(err, "refresh stack", stackName)
+	}
+
+	// Worker pools whose size changed are replaced node by node instead of
+	// being resized in place
+	if err := replaceResizedPools(ctx, stack, stackName, cfg, dryRun); err != nil {
+		return err
+	}
+
+	// Target only the resources owned by the selected role's nodes
+	var previewOpts []optpreview.Option
+	upOpts := []optup.Option{optup.ProgressStreams(pulumiProgress())}
+	if onlyRole != "" {
+		targets, err := roleTargetURNs(ctx, stack, roleNodeNames)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			return fmt.Errorf("no deployed resources found for %s nodes - run a full deploy first", onlyRole)
+		}
+
+		previewOpts = append(previewOpts, optpreview.Target(targets))
+		upOpts = append(upOpts, optup.Target(targets))
+	}
+
+	if dryRun {
+		// Preview mode
+		fmt.Println()
+		printInfo("📋 Previewing changes (dry-run mode)...")
+
+		prev, err := stack.Preview(ctx, previewOpts...)
+		if err != nil {
+			return stackLockedError(err, "preview", stackName)
+		}
+
+		printPreviewSummary(prev)
+		return nil
+	}
+
+	// Deploy!
+	fmt.Println()
+	printHeader("🚀 Deploying cluster...")
+	fmt.Println()
+
+	var res auto.UpResult
+	if onlyRole == config.RoleMaster {
+		// Masters are updated one at a time so etcd never loses quorum
+		res, err = rollMasters(ctx, stack, stackName, cfg, roleNodeNames)
+		if err != nil {
+			return err
+		}
+	} else {
+		res, err = stack.Up(ctx, upOpts...)
+		if err != nil {
+			return stackLockedError(err, "deploy", stackName)
+		}
+	}
+
+	// A created WireGuard server is only identified once provisioned
+	if cfg.Network.WireGuard != nil && cfg.Network.WireGuard.Create {
+		if err := storeWireGuardServerConfig(ctx, stack, res.Outputs); err != nil {
+			return err
+		}
+	}
+
+	// The bastion must answer over SSH before the cluster is reported ready
+	access := newNodeSSHAccess(stackName, res.Outputs)
+	if onlyRole == "" && access.viaBastion() {
+		fmt.Println()
+		printInfo("🔍 Validating bastion SSH connectivity...")
+		output, err := validateBastionSSH(access)
+		if err != nil {
+			return err
+		}
+		fmt.Print(output)
+		printSuccess("Bastion is ready")
+	}
+
+	// Requested encryption at rest must actually be active on the servers
+	if cfg.Kubernetes.SecretsEncryptionEnabled() {
+		fmt.Println()
+		printInfo("🔐 Verifying secrets encryption on the control plane...")
+		nodes, err := ParseNodeOutputs(res.Outputs)
+		if err != nil {
+			return fmt.Errorf("failed to parse nodes: %w", err)
+		}
+		if err := verifySecretsEncryption(controlPlaneNodes(nodes), access); err != nil {
+			return fmt.Errorf("secrets encryption was requested but could not be verified: %w", err)
+		}
+		printSuccess("Secrets encryption is active")
+	}
+
+	// Print success
+	fmt.Println()
+	printSuccess("✅ Cluster deployed successfully!")
+	fmt.Println()
+
+	// Print outputs
+	printClusterOutputs(res.Outputs)
+
+	return nil
+}
+
+// selectClusterStack creates or selects the stack of the cluster with the
+// program deploying cfg, and sets its config. Commands reapplying part of the
+// cluster, such as firewall reconcile, run the same program as deploy.
+func selectClusterStack(ctx context.Context, stackName string, cfg *config.ClusterConfig) (auto.Stack, error) {
 	program := func(ctx *pulumi.Context) error {
 		// Phase 1: Create VPCs if configured
 		ctx.Log.Info("📊 Phase 1: VPC Creation", nil)
@@ -244,6 +461,14 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 		ctx.Export("sshPrivateKey", clusterOrch.SSHPrivateKey)
 		ctx.Export("apiEndpoint", clusterOrch.APIEndpoint)
 
+		// Admin passwords of the enabled services, generated ones included
+		for _, password := range cfg.AdminPasswords() {
+			ctx.Export(password.Output, pulumi.ToSecret(pulumi.String(*password.Value)))
+		}
+		if b := cfg.Security.Bastion; b != nil && b.Enabled && b.SaltEnabled() {
+			ctx.Export("salt_api_user", pulumi.String(b.SaltUser()))
+		}
+
 		// Export VPC information
 		for provider, vpcResult := range vpcs {
 			ctx.Export(fmt.Sprintf("vpc_%s_id", provider), vpcResult.ID)
@@ -255,10 +480,6 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 		return nil
 	}
 
-	// Setup Pulumi Automation API stack
-	fmt.Println()
-	printInfo("🔧 Setting up Pulumi stack...")
-
 	// Create workspace with backend URL from environment
 	// Note: LoadSavedConfig() already set all environment variables at line 74
 	// For S3 backend, we need to set the project name
@@ -271,43 +492,16 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 		}),
 	}
 
-	// Collect all AWS/S3 environment variables to pass to Pulumi subprocess
-	envVars := make(map[string]string)
-	awsEnvKeys := []string{
-		"AWS_ACCESS_KEY_ID",
-		"AWS_SECRET_ACCESS_KEY",
-		"AWS_REGION",
-		"AWS_S3_ENDPOINT",
-		"AWS_S3_USE_PATH_STYLE",
-		"AWS_S3_FORCE_PATH_STYLE",
-		"PULUMI_BACKEND_URL",
-		"PULUMI_CONFIG_PASSPHRASE",
-	}
-	for _, key := range awsEnvKeys {
-		if val := os.Getenv(key); val != "" {
-			envVars[key] = val
-		}
-	}
-
-	// Add environment variables to workspace options
-	// This ensures Pulumi subprocess gets the S3 credentials
-	if len(envVars) > 0 {
-		workspaceOpts = append(workspaceOpts, auto.EnvVars(envVars))
-	}
-
-	// If PULUMI_BACKEND_URL is set, use it
-	if backendURL := os.Getenv("PULUMI_BACKEND_URL"); backendURL != "" {
-		workspaceOpts = append(workspaceOpts, auto.SecretsProvider("passphrase"))
-		// Set PULUMI_CONFIG_PASSPHRASE if not set
-		if os.Getenv("PULUMI_CONFIG_PASSPHRASE") == "" {
-			os.Setenv("PULUMI_CONFIG_PASSPHRASE", "")
-			envVars["PULUMI_CONFIG_PASSPHRASE"] = ""
-		}
+	// Pass the S3 credentials and secrets provider to the Pulumi subprocess
+	backendOpts, err := newStateBackendOptions()
+	if err != nil {
+		return auto.Stack{}, err
 	}
+	workspaceOpts = append(workspaceOpts, backendOpts...)
 
 	ws, err := auto.NewLocalWorkspace(ctx, workspaceOpts...)
 	if err != nil {
-		return fmt.Errorf("failed to create workspace: %w", err)
+		return auto.Stack{}, fmt.Errorf("failed to create workspace: %w", err)
 	}
 
 	// For S3 backend, we need to use fully qualified stack name: organization/project/stack
@@ -316,62 +510,233 @@ func runDeploy(cmd *cobra.Command, args []string) error {
 
 	stack, err := auto.UpsertStack(ctx, fullyQualifiedStackName, ws)
 	if err != nil {
-		return fmt.Errorf("failed to create or select stack: %w", err)
+		return auto.Stack{}, fmt.Errorf("failed to create or select stack: %w", err)
 	}
 
 	// Set configuration
 	if err := setStackConfig(ctx, stack, cfg); err != nil {
-		return fmt.Errorf("failed to set stack config: %w", err)
+		return auto.Stack{}, fmt.Errorf("failed to set stack config: %w", err)
 	}
 
-	printSuccess("Pulumi stack configured")
+	// Admin passwords left empty are generated once and kept in the stack
+	if err := ensureAdminPasswords(ctx, &stack, cfg); err != nil {
+		return auto.Stack{}, err
+	}
 
-	// Refresh stack
-	fmt.Println()
-	printInfo("🔄 Refreshing stack state...")
-	_, err = stack.Refresh(ctx)
+	return stack, nil
+}
+
+// stackResource is a resource of the exported stack state
+type stackResource struct {
+	URN                  string              `json:"urn"`
+	Type                 string              `json:"type"`
+	Parent               string              `json:"parent"`
+	Dependencies         []string            `json:"dependencies"`
+	PropertyDependencies map[string][]string `json:"propertyDependencies"`
+	DeletedWith          string              `json:"deletedWith"`
+}
+
+// dependsOnAny reports whether the resource is parented to, deleted with or
+// takes an input from any of urns
+func (r stackResource) dependsOnAny(urns map[string]bool) bool {
+	if urns[r.Parent] || urns[r.DeletedWith] {
+		return true
+	}
+	for _, dep := range r.Dependencies {
+		if urns[dep] {
+			return true
+		}
+	}
+	for _, deps := range r.PropertyDependencies {
+		for _, dep := range deps {
+			if urns[dep] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// realNodeType is the component every cluster node's resources are parented to
+const realNodeType = "kubernetes-create:compute:RealNode"
+
+// roleTargetURNs returns the URNs of the deployed resources owned by the named nodes
+func roleTargetURNs(ctx context.Context, stack auto.Stack, nodeNames []string) ([]string, error) {
+	resources, err := exportStackResources(ctx, stack)
 	if err != nil {
-		return fmt.Errorf("failed to refresh stack: %w", err)
+		return nil, err
 	}
+	return nodeResourceURNs(resources, nodeNames), nil
+}
 
-	if dryRun {
-		// Preview mode
-		fmt.Println()
-		printInfo("📋 Previewing changes (dry-run mode)...")
+// exportStackResources returns the resources of the stack state
+func exportStackResources(ctx context.Context, stack auto.Stack) ([]stackResource, error) {
+	deployment, err := stack.Export(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export stack: %w", err)
+	}
 
-		prev, err := stack.Preview(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to preview: %w", err)
+	var deploymentData struct {
+		Resources []stackResource `json:"resources"`
+	}
+	if err := json.Unmarshal(deployment.Deployment, &deploymentData); err != nil {
+		return nil, fmt.Errorf("failed to parse deployment: %w", err)
+	}
+	return deploymentData.Resources, nil
+}
+
+// remoteCommandType is the type of the commands run on hosts over SSH
+const remoteCommandType = "command:remote:Command"
+
+// nodeResourceURNs returns the URNs of the RealNode components of the named
+// nodes, of every resource parented under them and of the remote commands
+// that connect to them, such as their K3s install and configuration. Other
+// resources that merely read a node's outputs, like another node's install
+// joining through the first master, are left out.
+func nodeResourceURNs(resources []stackResource, nodeNames []string) []string {
+	owned := make(map[string]bool)
+	var urns []string
+
+	for _, resource := range resources {
+		if resource.Type != realNodeType {
+			continue
+		}
+		name := resource.URN
+		if idx := strings.LastIndex(name, "::"); idx >= 0 {
+			name = name[idx+2:]
 		}
+		for _, node := range nodeNames {
+			// RealNode components are named <deployment>-<node> or <deployment>-<pool>-<node>
+			if name == node || strings.HasSuffix(name, "-"+node) {
+				owned[resource.URN] = true
+				urns = append(urns, resource.URN)
+				break
+			}
+		}
+	}
 
-		printPreviewSummary(prev)
-		return nil
+	// Children are collected until no new descendant turns up, so the order
+	// of the state does not matter
+	for found := len(urns) > 0; found; {
+		found = false
+		for _, resource := range resources {
+			if !owned[resource.URN] && owned[resource.Parent] {
+				owned[resource.URN] = true
+				urns = append(urns, resource.URN)
+				found = true
+			}
+		}
 	}
 
-	// Deploy!
-	fmt.Println()
-	printHeader("🚀 Deploying cluster...")
-	fmt.Println()
+	// Commands are run on the host their connection points at
+	for _, resource := range resources {
+		if owned[resource.URN] || resource.Type != remoteCommandType {
+			continue
+		}
+		for _, dep := range resource.PropertyDependencies["connection"] {
+			if owned[dep] {
+				urns = append(urns, resource.URN)
+				break
+			}
+		}
+	}
 
-	// Setup progress streams
-	stdoutStreamer := optup.ProgressStreams(os.Stdout)
+	return urns
+}
 
-	res, err := stack.Up(ctx, stdoutStreamer)
+// rollMasters updates the named masters one at a time. The next master is
+// only touched once the previous one answers /readyz and its node is Ready
+// again, so at most one etcd member is ever down.
+func rollMasters(ctx context.Context, stack auto.Stack, stackName string, cfg *config.ClusterConfig, masters []string) (auto.UpResult, error) {
+	timeout, interval, err := cfg.Kubernetes.KubernetesReadyWait()
 	if err != nil {
-		return fmt.Errorf("failed to deploy: %w", err)
+		return auto.UpResult{}, err
 	}
 
-	// Print success
-	fmt.Println()
-	printSuccess("✅ Cluster deployed successfully!")
-	fmt.Println()
+	var res auto.UpResult
+	for i, master := range masters {
+		targets, err := roleTargetURNs(ctx, stack, []string{master})
+		if err != nil {
+			return res, err
+		}
+		if len(targets) == 0 {
+			printWarning(fmt.Sprintf("No deployed resources found for %s, skipping it", master))
+			continue
+		}
 
-	// Print outputs
-	printClusterOutputs(res.Outputs)
+		printInfo(fmt.Sprintf("🛡️  Updating master %d/%d: %s", i+1, len(masters), master))
+		res, err = stack.Up(ctx, optup.Target(targets), optup.ProgressStreams(pulumiProgress()))
+		if err != nil {
+			return res, stackLockedError(err, "deploy", stackName)
+		}
 
+		printInfo(fmt.Sprintf("⏳ Waiting for %s to be Ready before the next master...", master))
+		if err := waitForMasterReady(stackName, res.Outputs, master, timeout, interval); err != nil {
+			return res, fmt.Errorf("stopped before the next master to keep quorum: %w", err)
+		}
+		printSuccess(fmt.Sprintf("%s is Ready", master))
+	}
+	return res, nil
+}
+
+// waitForMasterReady waits on master until its API server answers /readyz
+// and the node reports Ready
+func waitForMasterReady(stackName string, outputs auto.OutputMap, master string, timeout, interval time.Duration) error {
+	nodes, err := ParseNodeOutputs(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to parse nodes: %w", err)
+	}
+	var node *NodeInfo
+	for i := range nodes {
+		if nodes[i].Name == master {
+			node = &nodes[i]
+			break
+		}
+	}
+	if node == nil {
+		return fmt.Errorf("master %s not found in stack outputs", master)
+	}
+
+	attempts := int(timeout / interval)
+	if attempts < 1 {
+		attempts = 1
+	}
+	access := newNodeSSHAccess(stackName, outputs)
+	user := getSSHUserForNode(node.Provider)
+	output, err := sshRunner(access.args(*node, user, 10, "sudo", "bash", "-s"), masterReadyScript(master, attempts, int(interval.Seconds())))
+	if err != nil || !strings.Contains(string(output), "READY") {
+		return fmt.Errorf("%s did not become Ready: %v (output: %s)", master, err, strings.TrimSpace(string(output)))
+	}
 	return nil
 }
 
+// masterReadyScript polls the local API server until it is ready and the
+// node named node is Ready
+func masterReadyScript(node string, attempts, intervalSeconds int) string {
+	return etcdBackupScriptPrefix(nil) + fmt.Sprintf(`for i in $(seq 1 %d); do
+  if $KUBECTL get --raw /readyz >/dev/null 2>&1 && \
+    [ "$($KUBECTL get node %s -o jsonpath='{.status.conditions[?(@.type=="Ready")].status}' 2>/dev/null)" = "True" ]; then
+    echo "READY"
+    exit 0
+  fi
+  sleep %d
+done
+echo "node did not become Ready"
+exit 1
+`, attempts, node, intervalSeconds)
+}
+
+// applyKubernetesReadyFlags overrides the Kubernetes readiness timeout and
+// poll interval of cfg with the flags that were given
+func applyKubernetesReadyFlags(cmd *cobra.Command, cfg *config.ClusterConfig) {
+	if cmd.Flags().Changed("ready-timeout") {
+		cfg.Kubernetes.ReadyTimeout = deployReadyTimeout.String()
+	}
+	if cmd.Flags().Changed("ready-poll-interval") {
+		cfg.Kubernetes.ReadyPollInterval = deployReadyPollInterval.String()
+	}
+}
+
 func loadConfiguration() (*config.ClusterConfig, error) {
 	var cfg *config.ClusterConfig
 	var err error
@@ -510,15 +875,57 @@ func loadConfiguration() (*config.ClusterConfig, error) {
 func setStackConfig(ctx context.Context, stack auto.Stack, cfg *config.ClusterConfig) error {
 	// Set configuration values for Pulumi
 	configs := map[string]auto.ConfigValue{
-		"digitaloceanToken":        {Value: cfg.Providers.DigitalOcean.Token, Secret: true},
-		"linodeToken":              {Value: cfg.Providers.Linode.Token, Secret: true},
-		"wireguardServerEndpoint":  {Value: cfg.Network.WireGuard.ServerEndpoint},
-		"wireguardServerPublicKey": {Value: cfg.Network.WireGuard.ServerPublicKey},
+		config.PulumiKeyDigitalOceanToken: {Value: cfg.Providers.DigitalOcean.Token, Secret: true},
+		config.PulumiKeyLinodeToken:       {Value: cfg.Providers.Linode.Token, Secret: true},
+	}
+
+	// A server created by the deployment has no endpoint or key yet; they are
+	// stored from the stack outputs once it is provisioned
+	wg := cfg.Network.WireGuard
+	if wg.ServerEndpoint != "" {
+		configs[config.PulumiKeyWireGuardServerEndpoint] = auto.ConfigValue{Value: wg.ServerEndpoint}
+	}
+	if wg.ServerPublicKey != "" {
+		configs[config.PulumiKeyWireGuardServerPublicKey] = auto.ConfigValue{Value: wg.ServerPublicKey}
+	}
+	if wg.Create {
+		configs[config.PulumiKeyWireGuardCreate] = auto.ConfigValue{Value: "true"}
 	}
 
 	return stack.SetAllConfig(ctx, configs)
 }
 
+// wireGuardServerConfig returns the stack config holding the endpoint and
+// public key of a WireGuard server created by the deployment, read from the
+// stack outputs. It is empty until both are known.
+func wireGuardServerConfig(outputs auto.OutputMap) auto.ConfigMap {
+	endpoint, _ := outputs["wireguard_server_endpoint"].Value.(string)
+	publicKey, _ := outputs["wireguard_server_public_key"].Value.(string)
+	if endpoint == "" || publicKey == "" {
+		return nil
+	}
+	return auto.ConfigMap{
+		config.PulumiKeyWireGuardServerEndpoint:  {Value: endpoint},
+		config.PulumiKeyWireGuardServerPublicKey: {Value: publicKey},
+	}
+}
+
+// storeWireGuardServerConfig saves the created WireGuard server's endpoint and
+// public key in the stack config, so later operations read them from the
+// stack instead of requiring them as input
+func storeWireGuardServerConfig(ctx context.Context, stack auto.Stack, outputs auto.OutputMap) error {
+	configs := wireGuardServerConfig(outputs)
+	if configs == nil {
+		printWarning("WireGuard server endpoint/public key not found in stack outputs - not stored in stack config")
+		return nil
+	}
+	if err := stack.SetAllConfig(ctx, configs); err != nil {
+		return fmt.Errorf("failed to store WireGuard server config: %w", err)
+	}
+	printInfo(fmt.Sprintf("🔐 WireGuard server %s stored in stack config", configs[config.PulumiKeyWireGuardServerEndpoint].Value))
+	return nil
+}
+
 func getEnvOrFlag(envKey, flagValue string) string {
 	if flagValue != "" {
 		return flagValue
@@ -811,24 +1218,6 @@ func printPreviewSummary(prev auto.PreviewResult) {
 	fmt.Println()
 }
 
-func printHeader(text string) {
-	fmt.Println()
-	color.New(color.Bold, color.FgCyan).Println(text)
-	fmt.Println()
-}
-
-func printSuccess(text string) {
-	color.Green("✓ " + text)
-}
-
-func printInfo(text string) {
-	color.Cyan(text)
-}
-
-func printWarning(text string) {
-	color.Yellow(text)
-}
-
 func confirm(question string) bool {
 	fmt.Printf("\n%s (y/N): ", color.YellowString("❓ "+question))
 	var response string
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+// deployPhase is one phase of the deployment and what it acts on
+type deployPhase struct {
+	Name    string
+	Details []string
+}
+
+// providerNodeCount counts the nodes a provider runs per role
+type providerNodeCount struct {
+	Masters int
+	Workers int
+}
+
+// deployPhasePlan returns the phases a deployment of cfg runs, in the order
+// the orchestrator runs them. It is built from the config alone, so it
+// neither creates anything nor calls a provider.
+func deployPhasePlan(cfg *config.ClusterConfig) []deployPhase {
+	var phases []deployPhase
+	add := func(name string, details ...string) {
+		phases = append(phases, deployPhase{Name: name, Details: details})
+	}
+
+	if vpcs := plannedVPCs(&cfg.Providers); len(vpcs) > 0 {
+		add("Create VPCs", vpcs...)
+	}
+
+	add("Generate SSH keys")
+
+	if bastion := cfg.Security.Bastion; bastion != nil && bastion.Enabled {
+		add("Provision bastion host", fmt.Sprintf("%s %s in %s", bastion.Provider, bastion.Size, bastion.Region))
+	}
+
+	counts := plannedNodeCounts(cfg)
+	providers := make([]string, 0, len(counts))
+	masters, workers := 0, 0
+	for provider, count := range counts {
+		providers = append(providers, provider)
+		masters += count.Masters
+		workers += count.Workers
+	}
+	sort.Strings(providers)
+
+	var nodeDetails []string
+	for _, provider := range providers {
+		count := counts[provider]
+		nodeDetails = append(nodeDetails, fmt.Sprintf("%s: %d node(s) (%d masters + %d workers)",
+			provider, count.Masters+count.Workers, count.Masters, count.Workers))
+	}
+	add(fmt.Sprintf("Create %d node(s)", masters+workers), nodeDetails...)
+
+	add("Validate cloud-init")
+
+	sysctls, modules := config.EffectiveSystemTuning(cfg.Kubernetes.SystemTuning)
+	add("Apply system tuning", fmt.Sprintf("%d sysctl(s), %d kernel module(s)", len(sysctls), len(modules)))
+
+	if wg := cfg.Network.WireGuard; wg != nil && (wg.Enabled || wg.Create) {
+		if wg.HubMode() {
+			add("Join nodes to the WireGuard server", fmt.Sprintf("server %s, subnet %s", wg.ServerEndpoint, wg.Subnet()))
+		} else {
+			details := []string{fmt.Sprintf("%d peer(s), subnet %s", masters+workers, wg.Subnet())}
+			if cfg.Network.HybridMode() {
+				details = append(details, "hybrid: peers in the same VPC connect over the VPC")
+			}
+			add("Configure WireGuard mesh", details...)
+		}
+		add("Verify VPN connectivity")
+	}
+
+	k8sDetails := []string{fmt.Sprintf("%d server(s), %d agent(s)", masters, workers)}
+	if cfg.Kubernetes.SecretsEncryptionEnabled() {
+		k8sDetails = append(k8sDetails, "secrets encryption at rest")
+	}
+	add(fmt.Sprintf("Install K3s %s", cfg.Kubernetes.Version), k8sDetails...)
+
+	if cfg.Kubernetes.NodeLocalDNSEnabled() {
+		add("Install node-local DNS cache", cfg.Kubernetes.NodeLocalDNS.Address())
+	}
+
+	if domain := cfg.Network.DNS.Domain; domain != "" && domain != "example.com" {
+		add("Create DNS records", domain)
+	}
+
+	if argocd := cfg.Addons.ArgoCD; argocd != nil && argocd.Enabled {
+		var details []string
+		if argocd.Version != "" {
+			details = append(details, "version "+argocd.Version)
+		}
+		if argocd.GitOpsRepoURL != "" {
+			details = append(details, "repo "+argocd.GitOpsRepoURL)
+		}
+		add("Install ArgoCD", details...)
+	}
+
+	if wg := cfg.Network.WireGuard; wg != nil && wg.Create {
+		add("Store the WireGuard server config")
+	}
+	if cfg.Kubernetes.SecretsEncryptionEnabled() {
+		add("Verify secrets encryption")
+	}
+
+	return phases
+}
+
+// plannedVPCs describes the VPCs the deployment creates or attaches to
+func plannedVPCs(providers *config.ProvidersConfig) []string {
+	var vpcs []string
+	describe := func(provider string, vpc *config.VPCConfig) {
+		switch {
+		case vpc == nil:
+		case vpc.Create:
+			vpcs = append(vpcs, fmt.Sprintf("%s: create %s (%s)", provider, vpc.Name, vpc.CIDR))
+		case vpc.ID != "":
+			vpcs = append(vpcs, fmt.Sprintf("%s: use existing %s", provider, vpc.ID))
+		}
+	}
+
+	if do := providers.DigitalOcean; do != nil && do.Enabled {
+		describe("digitalocean", do.VPC)
+	}
+	if linode := providers.Linode; linode != nil && linode.Enabled {
+		describe("linode", linode.VPC)
+	}
+	return vpcs
+}
+
+// plannedNodeCounts counts the configured nodes per provider and role
+func plannedNodeCounts(cfg *config.ClusterConfig) map[string]providerNodeCount {
+	counts := make(map[string]providerNodeCount)
+	add := func(provider string, roles []string, n int) {
+		count := counts[provider]
+		if config.HasRole(roles, config.RoleMaster) {
+			count.Masters += n
+		} else {
+			count.Workers += n
+		}
+		counts[provider] = count
+	}
+
+	for _, node := range cfg.Nodes {
+		add(node.Provider, node.Roles, 1)
+	}
+	for _, pool := range cfg.NodePools {
+		add(pool.Provider, pool.Roles, pool.Count)
+	}
+	return counts
+}
+
+// printDeployPlan prints the phases of a deployment
+func printDeployPlan(phases []deployPhase) {
+	color.Cyan("📋 Execution Plan:")
+	for i, phase := range phases {
+		fmt.Printf("  %2d. %s\n", i+1, phase.Name)
+		if len(phase.Details) > 0 {
+			fmt.Printf("      → %s\n", strings.Join(phase.Details, "\n      → "))
+		}
+	}
+	fmt.Println()
+}
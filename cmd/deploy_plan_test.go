@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+func planTestConfig() *config.ClusterConfig {
+	return &config.ClusterConfig{
+		Providers: config.ProvidersConfig{
+			DigitalOcean: &config.DigitalOceanProvider{Enabled: true},
+			Linode:       &config.LinodeProvider{Enabled: true},
+		},
+		Network: config.NetworkConfig{
+			WireGuard: &config.WireGuardConfig{Enabled: true, Create: true},
+		},
+		Kubernetes: config.KubernetesConfig{Version: "v1.29.0+k3s1"},
+		NodePools: map[string]config.NodePool{
+			"masters":        {Provider: "digitalocean", Count: 3, Roles: []string{"master"}},
+			"do-workers":     {Provider: "digitalocean", Count: 2, Roles: []string{"worker"}},
+			"linode-workers": {Provider: "linode", Count: 4, Roles: []string{"worker"}},
+		},
+	}
+}
+
+func planPhaseNames(phases []deployPhase) []string {
+	names := make([]string, len(phases))
+	for i, phase := range phases {
+		names[i] = phase.Name
+	}
+	return names
+}
+
+func findPlanPhase(phases []deployPhase, prefix string) *deployPhase {
+	for i := range phases {
+		if strings.HasPrefix(phases[i].Name, prefix) {
+			return &phases[i]
+		}
+	}
+	return nil
+}
+
+func TestDeployPhasePlan_Order(t *testing.T) {
+	names := planPhaseNames(deployPhasePlan(planTestConfig()))
+	expected := []string{
+		"Generate SSH keys",
+		"Create 9 node(s)",
+		"Validate cloud-init",
+		"Apply system tuning",
+		"Configure WireGuard mesh",
+		"Verify VPN connectivity",
+		"Install K3s v1.29.0+k3s1",
+		"Store the WireGuard server config",
+	}
+	if strings.Join(names, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected phases %v, got %v", expected, names)
+	}
+}
+
+func TestDeployPhasePlan_NodeCounts(t *testing.T) {
+	phases := deployPhasePlan(planTestConfig())
+
+	nodes := findPlanPhase(phases, "Create 9 node(s)")
+	if nodes == nil {
+		t.Fatalf("Expected a node phase, got %v", planPhaseNames(phases))
+	}
+	expected := []string{
+		"digitalocean: 5 node(s) (3 masters + 2 workers)",
+		"linode: 4 node(s) (0 masters + 4 workers)",
+	}
+	if strings.Join(nodes.Details, "|") != strings.Join(expected, "|") {
+		t.Errorf("Expected per-provider counts %v, got %v", expected, nodes.Details)
+	}
+
+	k3s := findPlanPhase(phases, "Install K3s")
+	if k3s == nil || k3s.Details[0] != "3 server(s), 6 agent(s)" {
+		t.Errorf("Expected 3 servers and 6 agents, got %+v", k3s)
+	}
+}
+
+func TestDeployPhasePlan_WireGuardDisabled(t *testing.T) {
+	cfg := planTestConfig()
+	cfg.Network.WireGuard = &config.WireGuardConfig{Enabled: false}
+
+	phases := deployPhasePlan(cfg)
+	for _, prefix := range []string{"Configure WireGuard", "Join nodes to the WireGuard", "Verify VPN", "Store the WireGuard"} {
+		if phase := findPlanPhase(phases, prefix); phase != nil {
+			t.Errorf("Expected no %q phase with WireGuard disabled, got %v", prefix, planPhaseNames(phases))
+		}
+	}
+}
+
+func TestDeployPhasePlan_HubMode(t *testing.T) {
+	cfg := planTestConfig()
+	cfg.Network.WireGuard = &config.WireGuardConfig{Enabled: true, ServerEndpoint: "vpn.example.com:51820"}
+
+	phases := deployPhasePlan(cfg)
+	if findPlanPhase(phases, "Join nodes to the WireGuard server") == nil || findPlanPhase(phases, "Configure WireGuard mesh") != nil {
+		t.Errorf("Expected nodes to join the existing server, got %v", planPhaseNames(phases))
+	}
+	if findPlanPhase(phases, "Verify VPN connectivity") == nil {
+		t.Errorf("Expected VPN verification in hub mode, got %v", planPhaseNames(phases))
+	}
+	if findPlanPhase(phases, "Store the WireGuard server config") != nil {
+		t.Errorf("Expected no server config to store for an existing server, got %v", planPhaseNames(phases))
+	}
+}
+
+func TestDeployPhasePlan_Toggles(t *testing.T) {
+	cfg := planTestConfig()
+	cfg.Providers.DigitalOcean.VPC = &config.VPCConfig{Create: true, Name: "k8s-vpc", CIDR: "10.10.0.0/16"}
+	cfg.Security.Bastion = &config.BastionConfig{Enabled: true, Provider: "digitalocean", Region: "nyc3", Size: "s-1vcpu-1gb"}
+	cfg.Kubernetes.EncryptSecrets = true
+	cfg.Kubernetes.NodeLocalDNS = &config.NodeLocalDNSConfig{Enabled: true}
+	cfg.Network.DNS.Domain = "cluster.dev"
+	cfg.Addons.ArgoCD = &config.ArgoCDConfig{Enabled: true, Version: "v2.9.0", GitOpsRepoURL: "https://github.com/org/gitops"}
+
+	phases := deployPhasePlan(cfg)
+	names := planPhaseNames(phases)
+	if names[0] != "Create VPCs" || names[1] != "Generate SSH keys" || names[2] != "Provision bastion host" {
+		t.Errorf("Expected VPCs, SSH keys and the bastion first, got %v", names)
+	}
+	if vpcs := findPlanPhase(phases, "Create VPCs"); vpcs.Details[0] != "digitalocean: create k8s-vpc (10.10.0.0/16)" {
+		t.Errorf("Expected the VPC to create, got %v", vpcs.Details)
+	}
+
+	for _, prefix := range []string{"Install node-local DNS cache", "Create DNS records", "Install ArgoCD", "Verify secrets encryption"} {
+		if findPlanPhase(phases, prefix) == nil {
+			t.Errorf("Expected a %q phase, got %v", prefix, names)
+		}
+	}
+	if argocd := findPlanPhase(phases, "Install ArgoCD"); strings.Join(argocd.Details, "|") != "version v2.9.0|repo https://github.com/org/gitops" {
+		t.Errorf("Expected the ArgoCD version and repo, got %v", argocd.Details)
+	}
+	if k3s := findPlanPhase(phases, "Install K3s"); len(k3s.Details) != 2 || k3s.Details[1] != "secrets encryption at rest" {
+		t.Errorf("Expected secrets encryption in the K3s phase, got %v", k3s.Details)
+	}
+}
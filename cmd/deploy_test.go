@@ -5,6 +5,8 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+
 	"github.com/chalkan3/sloth-kubernetes/pkg/config"
 )
 
@@ -741,3 +743,91 @@ func TestOutputParsing(t *testing.T) {
 		})
 	}
 }
+
+// TestNodeResourceURNs tests selecting the resources owned by a role's nodes
+// from the stack state
+func TestNodeResourceURNs(t *testing.T) {
+	prefix := "urn:pulumi:production::sloth-kubernetes::"
+	worker1 := prefix + "kubernetes-create:compute:RealNode::kubernetes-cluster-nodes-workers-workers-1"
+	worker2 := prefix + "kubernetes-create:compute:RealNode::kubernetes-cluster-nodes-workers-2"
+	worker1Droplet := prefix + "kubernetes-create:compute:RealNode$digitalocean:index/droplet:Droplet::kubernetes-cluster-nodes-workers-workers-1"
+	masterDroplet := prefix + "kubernetes-create:compute:RealNode$digitalocean:index/droplet:Droplet::kubernetes-cluster-nodes-masters-masters-1"
+	workerInstall := prefix + "kubernetes-create:compute:K3sCluster$command:remote:Command::kubernetes-cluster-k3s-worker-0-install"
+	resources := []stackResource{
+		{URN: worker1, Type: "kubernetes-create:compute:RealNode"},
+		{URN: worker1Droplet, Type: "digitalocean:index/droplet:Droplet", Parent: worker1},
+		{URN: worker2, Type: "kubernetes-create:compute:RealNode"},
+		{URN: prefix + "kubernetes-create:compute:RealNode$azure-native:network:PublicIPAddress::workers-2-pip", Type: "azure-native:network:PublicIPAddress", Parent: worker2},
+		{URN: prefix + "kubernetes-create:compute:RealNode::kubernetes-cluster-nodes-workers-workers-10", Type: "kubernetes-create:compute:RealNode"},
+		{URN: prefix + "kubernetes-create:compute:RealNode::kubernetes-cluster-nodes-masters-masters-1", Type: "kubernetes-create:compute:RealNode"},
+		{URN: masterDroplet, Type: "digitalocean:index/droplet:Droplet", Parent: prefix + "kubernetes-create:compute:RealNode::kubernetes-cluster-nodes-masters-masters-1"},
+		// Runs on worker 1 and joins through the first master
+		{URN: workerInstall, Type: "command:remote:Command", PropertyDependencies: map[string][]string{
+			"connection": {worker1Droplet},
+			"create":     {worker1Droplet, masterDroplet},
+		}},
+		// Runs on the master and only reads a worker's address
+		{URN: prefix + "kubernetes-create:compute:K3sCluster$command:remote:Command::kubernetes-cluster-k3s-master-0-install", Type: "command:remote:Command", PropertyDependencies: map[string][]string{
+			"connection": {masterDroplet},
+			"create":     {worker1Droplet},
+		}},
+		// Does not connect to the workers
+		{URN: prefix + "kubernetes-create:network:WireGuardMesh$command:remote:Command::workers-1-wg-config", Type: "command:remote:Command"},
+		{URN: prefix + "kubernetes-create:security:SSHKeys::kubernetes-cluster-ssh-keys", Type: "kubernetes-create:security:SSHKeys"},
+	}
+
+	matched := nodeResourceURNs(resources, []string{"workers-1", "workers-2"})
+	if len(matched) != 5 {
+		t.Fatalf("Expected 5 worker URNs, got %d: %v", len(matched), matched)
+	}
+	if matched[len(matched)-1] != workerInstall {
+		t.Errorf("Expected the worker's K3s install to be targeted, got %v", matched)
+	}
+
+	for _, urn := range matched {
+		if strings.Contains(urn, "masters") || strings.Contains(urn, "master-0") || strings.Contains(urn, "workers-10") || strings.Contains(urn, "ssh-keys") || strings.Contains(urn, "wg-config") {
+			t.Errorf("Unexpected URN selected: %s", urn)
+		}
+	}
+}
+
+// TestMasterReadyScript tests the readiness gate between masters
+func TestMasterReadyScript(t *testing.T) {
+	script := masterReadyScript("masters-1", 60, 10)
+	for _, want := range []string{"seq 1 60", "get --raw /readyz", "get node masters-1", `@.type=="Ready"`, "sleep 10", "READY"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q:\n%s", want, script)
+		}
+	}
+}
+
+// TestDeployOnlyRoleFlag tests the --only-role flag is registered
+func TestDeployOnlyRoleFlag(t *testing.T) {
+	flag := deployCmd.Flags().Lookup("only-role")
+	if flag == nil {
+		t.Fatal("only-role flag should exist")
+	}
+	if flag.DefValue != "" {
+		t.Errorf("Expected empty default, got %q", flag.DefValue)
+	}
+}
+
+// TestWireGuardServerConfig tests that a created server's identity is stored
+// only once both the endpoint and public key are in the outputs
+func TestWireGuardServerConfig(t *testing.T) {
+	outputs := auto.OutputMap{
+		"wireguard_server_endpoint": {Value: "203.0.113.10:51820"},
+	}
+	if configs := wireGuardServerConfig(outputs); configs != nil {
+		t.Errorf("Expected nothing stored without a public key, got %v", configs)
+	}
+
+	outputs["wireguard_server_public_key"] = auto.OutputValue{Value: "server-public-key"}
+	configs := wireGuardServerConfig(outputs)
+	if configs[config.PulumiKeyWireGuardServerEndpoint].Value != "203.0.113.10:51820" {
+		t.Errorf("Unexpected endpoint config: %v", configs)
+	}
+	if configs[config.PulumiKeyWireGuardServerPublicKey].Value != "server-public-key" {
+		t.Errorf("Unexpected public key config: %v", configs)
+	}
+}
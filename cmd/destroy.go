@@ -26,7 +26,10 @@ WARNING: This action cannot be undone!`,
   kubernetes-create destroy
 
   # Force destroy without confirmation
-  kubernetes-create destroy --yes --force`,
+  kubernetes-create destroy --yes --force
+
+  # Remove the lock left by a crashed run first
+  kubernetes-create destroy --force-unlock`,
 	RunE: runDestroy,
 }
 
@@ -38,10 +41,10 @@ func init() {
 func runDestroy(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
-	// Use stack name from args if provided, otherwise use global stackName
-	targetStack := stackName
-	if len(args) > 0 {
-		targetStack = args[0]
+	// Resolve stack from args, --stack or the configured default
+	targetStack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
 	}
 
 	// Print warning header
@@ -93,6 +96,13 @@ func runDestroy(cmd *cobra.Command, args []string) error {
 	s.Stop()
 	printSuccess("Connected to stack")
 
+	// Clear the lock a crashed run left behind
+	if forceUnlock {
+		if err := unlockStack(ctx, stack, targetStack); err != nil {
+			return err
+		}
+	}
+
 	// STEP 1: Logout from Salt (if logged in)
 	fmt.Println()
 	printHeader("🔓 Cleaning up Salt session...")
@@ -124,9 +134,9 @@ func runDestroy(cmd *cobra.Command, args []string) error {
 	printHeader("🔥 Destroying cluster...")
 	fmt.Println()
 
-	_, err = stack.Destroy(ctx, optdestroy.ProgressStreams(os.Stdout))
+	_, err = stack.Destroy(ctx, optdestroy.ProgressStreams(pulumiProgress()))
 	if err != nil {
-		return fmt.Errorf("failed to destroy: %w", err)
+		return stackLockedError(err, "destroy", targetStack)
 	}
 
 	// Success
@@ -0,0 +1,229 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/spf13/cobra"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+var (
+	doctorRegistry    string
+	doctorArtifactURL string
+)
+
+// doctorCheck is the outcome of one check on one node
+type doctorCheck struct {
+	Node   string
+	Check  string
+	OK     bool
+	Detail string
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor [stack-name]",
+	Short: "Check that cluster nodes can reach what they need",
+	Long: `Run connectivity checks from every node of a deployed stack:
+  • SSH access (directly or through the bastion)
+  • The system default registry (rke2.systemDefaultRegistry), when configured
+  • The air-gap artifact mirror (rke2.airGap), when enabled
+
+Registry settings are read from --config, or given with --registry/--artifact-url.`,
+	Example: `  # Check nodes against the registry configured in cluster.yaml
+  sloth-kubernetes doctor production --config cluster.yaml
+
+  # Check a registry without a config file
+  sloth-kubernetes doctor production --registry registry.internal:5000`,
+	RunE: runDoctor,
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	doctorCmd.Flags().StringVar(&doctorRegistry, "registry", "", "Registry to check (overrides rke2.systemDefaultRegistry)")
+	doctorCmd.Flags().StringVar(&doctorArtifactURL, "artifact-url", "", "Air-gap artifact mirror to check (overrides rke2.airGap.artifactUrl)")
+}
+
+func runDoctor(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	registry, installScriptURL, err := resolveDoctorTargets()
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("🩺 Doctor - Stack: %s", stack))
+
+	// Create workspace with S3 support
+	workspace, err := createWorkspaceWithS3Support(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	// Use fully qualified stack name for S3 backend
+	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
+	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
+	if err != nil {
+		return fmt.Errorf("failed to select stack '%s': %w", stack, err)
+	}
+
+	// Get outputs
+	outputs, err := s.Outputs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get stack outputs: %w", err)
+	}
+
+	// Parse nodes
+	nodes, err := ParseNodeOutputs(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	access := newNodeSSHAccess(stack, outputs)
+
+	fmt.Println()
+	if registry == "" {
+		printInfo("No system default registry configured - skipping registry checks")
+	}
+	printInfo(fmt.Sprintf("Running checks on %d nodes...", len(nodes)))
+	fmt.Println()
+
+	var checks []doctorCheck
+	for _, node := range nodes {
+		checks = append(checks, runDoctorChecks(node, access, registry, installScriptURL)...)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tCHECK\tSTATUS\tDETAIL")
+	failed := 0
+	for _, check := range checks {
+		status := color.GreenString("ok")
+		if !check.OK {
+			status = color.RedString("FAIL")
+			failed++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", check.Node, check.Check, status, check.Detail)
+	}
+	w.Flush()
+
+	fmt.Println()
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(checks))
+	}
+
+	printSuccess("All checks passed")
+	return nil
+}
+
+// resolveDoctorTargets returns the registry and install script URL to check,
+// from the flags first, then the --config file
+func resolveDoctorTargets() (string, string, error) {
+	var rke2 *config.RKE2Config
+	if cfgFile != "" {
+		cfg, err := config.LoadFromYAML(cfgFile)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to load config file: %w", err)
+		}
+		rke2 = cfg.Kubernetes.RKE2
+	}
+
+	registry := doctorRegistry
+	if registry == "" && rke2 != nil {
+		registry = rke2.SystemDefaultRegistry
+	}
+
+	installScriptURL := rke2.AirGapInstallScriptURL()
+	if doctorArtifactURL != "" {
+		mirror := &config.RKE2Config{AirGap: &config.AirGapConfig{Enabled: true, ArtifactURL: doctorArtifactURL}}
+		installScriptURL = mirror.AirGapInstallScriptURL()
+	}
+
+	return registry, installScriptURL, nil
+}
+
+// runDoctorChecks runs the SSH, registry and mirror checks on a node.
+// Registry and mirror checks are skipped when the node is unreachable.
+func runDoctorChecks(node NodeInfo, access nodeSSHAccess, registry, installScriptURL string) []doctorCheck {
+	if _, err := access.run(node, 10, "true"); err != nil {
+		return []doctorCheck{{Node: node.Name, Check: "ssh", Detail: err.Error()}}
+	}
+	checks := []doctorCheck{{Node: node.Name, Check: "ssh", OK: true}}
+
+	if registry != "" {
+		checks = append(checks, probeFromNode(node, access, "registry", registryProbeURL(registry), registryReachable))
+	}
+	if installScriptURL != "" {
+		checks = append(checks, probeFromNode(node, access, "airgap-mirror", installScriptURL, mirrorReachable))
+	}
+
+	return checks
+}
+
+// probeFromNode requests url from the node and judges the HTTP status code
+func probeFromNode(node NodeInfo, access nodeSSHAccess, name, url string, reachable func(int) bool) doctorCheck {
+	check := doctorCheck{Node: node.Name, Check: name}
+
+	output, err := access.run(node, 10, httpProbeCommand(url))
+	if err != nil {
+		check.Detail = fmt.Sprintf("%s: %v", url, err)
+		return check
+	}
+
+	code, err := parseHTTPProbeStatus(string(output))
+	if err != nil {
+		check.Detail = fmt.Sprintf("%s: %v", url, err)
+		return check
+	}
+
+	check.OK = reachable(code)
+	check.Detail = fmt.Sprintf("%s -> HTTP %d", url, code)
+	return check
+}
+
+// registryProbeURL returns the registry API endpoint for a registry reference
+// such as registry.example.com:5000/mirror
+func registryProbeURL(registry string) string {
+	host := strings.SplitN(registry, "/", 2)[0]
+	return fmt.Sprintf("https://%s/v2/", host)
+}
+
+// httpProbeCommand prints only the HTTP status code of a request to url
+// (000 when the connection fails)
+func httpProbeCommand(url string) string {
+	return fmt.Sprintf("curl -sk -o /dev/null -w '%%{http_code}' --max-time 10 '%s'; true", url)
+}
+
+// parseHTTPProbeStatus parses the status code printed by httpProbeCommand
+func parseHTTPProbeStatus(output string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(output))
+	if err != nil {
+		return 0, fmt.Errorf("unexpected probe output %q", strings.TrimSpace(output))
+	}
+	if code == 0 {
+		return 0, fmt.Errorf("connection failed")
+	}
+	return code, nil
+}
+
+// registryReachable reports whether a /v2/ response comes from a registry.
+// 401 is expected from registries that require authentication.
+func registryReachable(code int) bool {
+	return code == 200 || code == 401
+}
+
+// mirrorReachable reports whether the mirror served the file
+func mirrorReachable(code int) bool {
+	return code >= 200 && code < 400
+}
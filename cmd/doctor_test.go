@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRegistryProbeURL(t *testing.T) {
+	tests := map[string]string{
+		"registry.internal":            "https://registry.internal/v2/",
+		"registry.internal:5000":       "https://registry.internal:5000/v2/",
+		"registry.internal:5000/proxy": "https://registry.internal:5000/v2/",
+	}
+	for registry, want := range tests {
+		if got := registryProbeURL(registry); got != want {
+			t.Errorf("registryProbeURL(%q) = %q, want %q", registry, got, want)
+		}
+	}
+}
+
+func TestParseHTTPProbeStatus(t *testing.T) {
+	if code, err := parseHTTPProbeStatus("401\n"); err != nil || code != 401 {
+		t.Errorf("Expected 401, got %d (%v)", code, err)
+	}
+	if _, err := parseHTTPProbeStatus("000"); err == nil || !strings.Contains(err.Error(), "connection failed") {
+		t.Errorf("Expected connection failure for 000, got %v", err)
+	}
+	if _, err := parseHTTPProbeStatus("curl: not found"); err == nil {
+		t.Error("Expected error for non-numeric output")
+	}
+}
+
+func TestRunDoctorChecks(t *testing.T) {
+	node := NodeInfo{Name: "worker-1", PublicIP: "203.0.113.20"}
+
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		remote := args[len(args)-1]
+		switch {
+		case remote == "true":
+			return nil, nil
+		case strings.Contains(remote, "https://registry.internal:5000/v2/"):
+			return []byte("401"), nil
+		case strings.Contains(remote, "https://mirror.internal/rke2/install.sh"):
+			return []byte("000"), nil
+		}
+		return nil, errors.New("unexpected command: " + remote)
+	})
+
+	checks := runDoctorChecks(node, nodeSSHAccess{KeyPath: "/tmp/key.pem"}, "registry.internal:5000", "https://mirror.internal/rke2/install.sh")
+	if len(checks) != 3 {
+		t.Fatalf("Expected 3 checks, got %d: %+v", len(checks), checks)
+	}
+
+	if !checks[0].OK || checks[0].Check != "ssh" {
+		t.Errorf("Expected ssh check to pass, got %+v", checks[0])
+	}
+	if !checks[1].OK || checks[1].Check != "registry" {
+		t.Errorf("Expected registry check to accept 401, got %+v", checks[1])
+	}
+	if checks[2].OK || !strings.Contains(checks[2].Detail, "connection failed") {
+		t.Errorf("Expected mirror check to fail, got %+v", checks[2])
+	}
+}
+
+func TestRunDoctorChecks_UnreachableNodeSkipsProbes(t *testing.T) {
+	calls := 0
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		calls++
+		return nil, errors.New("connection timed out")
+	})
+
+	checks := runDoctorChecks(NodeInfo{Name: "worker-1", PublicIP: "203.0.113.20"}, nodeSSHAccess{KeyPath: "/tmp/key.pem"}, "registry.internal:5000", "")
+	if len(checks) != 1 || checks[0].OK {
+		t.Errorf("Expected a single failed ssh check, got %+v", checks)
+	}
+	if calls != 1 {
+		t.Errorf("Expected probes to be skipped, got %d ssh calls", calls)
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+// Exit codes of the CLI, so scripts can react to a failure class without
+// parsing messages
+const (
+	exitOK              = 0
+	exitFailure         = 1
+	exitStackNotFound   = 3
+	exitNodeUnreachable = 4
+	exitProviderAuth    = 5
+	exitMeshDegraded    = 6
+	exitQuorumRisk      = 7
+)
+
+// exitCodes maps error classes to exit codes in precedence order, for errors
+// carrying more than one class
+var exitCodes = []struct {
+	class error
+	code  int
+}{
+	{errs.ErrStackNotFound, exitStackNotFound},
+	{errs.ErrProviderAuth, exitProviderAuth},
+	{errs.ErrQuorumRisk, exitQuorumRisk},
+	{errs.ErrMeshDegraded, exitMeshDegraded},
+	{errs.ErrNodeUnreachable, exitNodeUnreachable},
+}
+
+// exitCode returns the exit code for the error a command returned
+func exitCode(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	for _, c := range exitCodes {
+		if errors.Is(err, c.class) {
+			return c.code
+		}
+	}
+	return exitFailure
+}
+
+// selectStackError wraps a failure to select stack, classifying a stack that
+// does not exist as errs.ErrStackNotFound
+func selectStackError(err error, stack string) error {
+	if auto.IsSelectStack404Error(err) {
+		err = errs.Mark(err, errs.ErrStackNotFound)
+	}
+	return fmt.Errorf("failed to select stack '%s': %w", stack, err)
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"success", nil, exitOK},
+		{"unclassified", errors.New("boom"), exitFailure},
+		{"stack not found", fmt.Errorf("failed to select stack 'prod': %w", errs.ErrStackNotFound), exitStackNotFound},
+		{"node unreachable", errs.Unreachable("master-1", errors.New("timeout")), exitNodeUnreachable},
+		{"provider auth", errs.Mark(errors.New("401"), errs.ErrProviderAuth), exitProviderAuth},
+		{"mesh degraded", fmt.Errorf("vpn test: %w", errs.ErrMeshDegraded), exitMeshDegraded},
+		{"quorum risk", errs.Mark(errors.New("even masters"), errs.ErrQuorumRisk), exitQuorumRisk},
+		{"degraded mesh of unreachable nodes", errs.Mark(errs.Unreachable("worker-1", nil), errs.ErrMeshDegraded), exitMeshDegraded},
+	}
+
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("%s: exitCode() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSelectStackError(t *testing.T) {
+	err := selectStackError(errors.New("failed to read backend"), "production")
+	if errors.Is(err, errs.ErrStackNotFound) {
+		t.Errorf("Expected a backend failure not to be classified as a missing stack, got %v", err)
+	}
+	if err.Error() != "failed to select stack 'production': failed to read backend" {
+		t.Errorf("Unexpected message: %q", err.Error())
+	}
+}
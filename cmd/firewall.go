@@ -0,0 +1,398 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optrefresh"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/spf13/cobra"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+	"github.com/chalkan3/sloth-kubernetes/pkg/network"
+	"github.com/chalkan3/sloth-kubernetes/pkg/security"
+)
+
+var firewallReconcileDryRun bool
+
+var firewallCmd = &cobra.Command{
+	Use:   "firewall",
+	Short: "Manage the cluster firewalls",
+	Long:  `Manage the cloud provider firewalls and the OS firewall (ufw) of the cluster nodes`,
+}
+
+var firewallReconcileCmd = &cobra.Command{
+	Use:   "reconcile [stack-name]",
+	Short: "Reapply the configured firewall rules after drift",
+	Long: `Recompute the firewall rules from the cluster config and reapply them, undoing
+edits made in the cloud console or with ufw on the nodes.
+
+Cloud provider firewalls are refreshed from the provider and updated back to
+the rules of the config. On each node, ufw port rules missing from the node are
+added and port rules the config does not have are removed. Rules on the SSH
+port and rules without a port (interfaces, whole networks) are never removed,
+so reconciling cannot lock you out.`,
+	Example: `  # Show the drift without changing anything
+  sloth-kubernetes firewall reconcile production --dry-run
+
+  # Reapply the rules
+  sloth-kubernetes firewall reconcile production`,
+	RunE: runFirewallReconcile,
+}
+
+func init() {
+	rootCmd.AddCommand(firewallCmd)
+	firewallCmd.AddCommand(firewallReconcileCmd)
+
+	firewallReconcileCmd.Flags().BoolVar(&firewallReconcileDryRun, "dry-run", false, "Report the drift without changing anything")
+}
+
+// ufwShowAddedScript prints the rules added to ufw, as ufw commands
+const ufwShowAddedScript = `sudo ufw show added`
+
+// ufwPortRule matches the ufw commands of port rules, with or without a
+// source and a comment
+var ufwPortRule = regexp.MustCompile(`^ufw allow (?:from (\S+) to any port (\S+) proto (\S+)|(\S+)/(\S+))(?: comment '.*')?$`)
+
+// ufwSSHPort is the port whose rules reconcile never removes
+const ufwSSHPort = "22"
+
+// ufwReconcilePlan is what reconcile changes in the ufw rules of a node
+type ufwReconcilePlan struct {
+	Add    []string // Configured rules missing on the node
+	Remove []string // Port rules on the node the config does not have
+	Kept   []string // SSH rules the config does not have, kept to avoid a lockout
+}
+
+// Empty reports whether the node has no drift to undo
+func (p ufwReconcilePlan) Empty() bool {
+	return len(p.Add) == 0 && len(p.Remove) == 0
+}
+
+// ufwRuleKey identifies a ufw port rule by what it allows, leaving out the
+// comment. ok is false for rules that are not port rules.
+func ufwRuleKey(rule string) (key, port string, ok bool) {
+	m := ufwPortRule.FindStringSubmatch(strings.Join(strings.Fields(rule), " "))
+	if m == nil {
+		return "", "", false
+	}
+	if m[1] != "" {
+		return fmt.Sprintf("%s %s/%s", m[1], m[2], m[3]), m[2], true
+	}
+	return fmt.Sprintf("0.0.0.0/0 %s/%s", m[4], m[5]), m[4], true
+}
+
+// planUFWReconcile compares the rules on a node, as printed by ufw show
+// added, with the configured ones
+func planUFWReconcile(current, intended []string) ufwReconcilePlan {
+	var plan ufwReconcilePlan
+
+	currentKeys := make(map[string]bool)
+	for _, rule := range current {
+		if key, _, ok := ufwRuleKey(rule); ok {
+			currentKeys[key] = true
+		}
+	}
+
+	intendedKeys := make(map[string]bool)
+	for _, rule := range intended {
+		key, _, ok := ufwRuleKey(rule)
+		if !ok || intendedKeys[key] {
+			continue
+		}
+		intendedKeys[key] = true
+		if !currentKeys[key] {
+			plan.Add = append(plan.Add, rule)
+		}
+	}
+
+	for _, rule := range current {
+		key, port, ok := ufwRuleKey(rule)
+		if !ok || intendedKeys[key] {
+			continue
+		}
+		if port == ufwSSHPort {
+			plan.Kept = append(plan.Kept, rule)
+			continue
+		}
+		plan.Remove = append(plan.Remove, rule)
+	}
+	return plan
+}
+
+// parseUFWShowAdded returns the rules printed by ufw show added
+func parseUFWShowAdded(output string) []string {
+	var rules []string
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "ufw ") {
+			rules = append(rules, line)
+		}
+	}
+	return rules
+}
+
+// script returns the shell script applying the plan. Rules are added before
+// any is removed so traffic the config allows is never cut off.
+func (p ufwReconcilePlan) script() string {
+	var b strings.Builder
+	b.WriteString("set -e\n")
+	for _, rule := range p.Add {
+		b.WriteString("sudo " + rule + "\n")
+	}
+	for _, rule := range p.Remove {
+		b.WriteString("sudo ufw delete " + strings.TrimPrefix(rule, "ufw ") + "\n")
+	}
+	b.WriteString("echo SUCCESS\n")
+	return b.String()
+}
+
+func runFirewallReconcile(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("🛡️  Firewall Reconcile - Stack: %s", stack))
+
+	cfg, err := loadConfiguration()
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	s, err := selectClusterStack(ctx, stack, cfg)
+	if err != nil {
+		return err
+	}
+
+	outputs, err := stackOutputsWithRetry(ctx, s)
+	if err != nil {
+		return err
+	}
+	nodes, err := ParseNodeOutputs(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	// Cloud provider firewalls
+	fmt.Println()
+	printInfo("☁️  Cloud provider firewalls")
+	if err := reconcileCloudFirewalls(ctx, s, stack); err != nil {
+		return err
+	}
+
+	// OS firewalls of the nodes
+	fmt.Println()
+	printInfo("🖥️  Node firewalls (ufw)")
+	if len(nodes) == 0 {
+		printInfo("  No nodes in the stack")
+		return nil
+	}
+
+	access := newNodeSSHAccess(stack, outputs)
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
+	}
+
+	intended := make(map[string][]string)
+	plans := make(map[string]ufwReconcilePlan)
+	var drifted []NodeInfo
+	var unreadable []string
+	for _, node := range nodes {
+		rules, ok := intended[node.Provider]
+		if !ok {
+			ruleSet := network.NewFirewallRuleSet(stack, &cfg.Network, node.Provider, providerNetworkCIDR(outputs, node.Provider))
+			rules = security.UFWRules(ruleSet)
+			intended[node.Provider] = rules
+		}
+
+		output, err := access.runScript(node, 10, ufwShowAddedScript)
+		if err != nil {
+			unreadable = append(unreadable, fmt.Sprintf("%s (%v)", node.Name, err))
+			continue
+		}
+
+		plan := planUFWReconcile(parseUFWShowAdded(string(output)), rules)
+		printUFWReconcilePlan(node.Name, plan)
+		if !plan.Empty() {
+			plans[node.Name] = plan
+			drifted = append(drifted, node)
+		}
+	}
+
+	// Nodes that could not be read are reported once the others are done
+	var unreadableErr error
+	if len(unreadable) > 0 {
+		unreadableErr = errs.Mark(fmt.Errorf("could not read the ufw rules of: %s", strings.Join(unreadable, ", ")), errs.ErrNodeUnreachable)
+	}
+
+	if len(drifted) == 0 {
+		fmt.Println()
+		printSuccess("Node firewalls match the config")
+		return unreadableErr
+	}
+	if firewallReconcileDryRun {
+		fmt.Println()
+		printInfo(fmt.Sprintf("Dry run: %d node(s) would be reconciled", len(drifted)))
+		return unreadableErr
+	}
+	if !autoApprove && !confirm(fmt.Sprintf("Apply the changes to %d node(s)?", len(drifted))) {
+		printWarning("Reconcile cancelled")
+		return nil
+	}
+
+	// Ctrl-C stops before the next node
+	tracker := newNodeChangeTracker()
+	defer tracker.Stop()
+
+	failed := 0
+	for _, node := range drifted {
+		if tracker.Interrupted() {
+			break
+		}
+		tracker.Begin(node.Name)
+
+		output, err := access.runScript(node, 10, plans[node.Name].script())
+		if err != nil && tracker.Interrupted() {
+			break
+		}
+		ok := err == nil && strings.Contains(string(output), "SUCCESS")
+		tracker.Done(ok)
+		if !ok {
+			color.Yellow(fmt.Sprintf("  ⚠️  Failed to reconcile %s: %v (output: %s)", node.Name, err, strings.TrimSpace(string(output))))
+			failed++
+			continue
+		}
+		printSuccess(fmt.Sprintf("%s reconciled", node.Name))
+	}
+
+	if tracker.Interrupted() {
+		names := make([]string, len(drifted))
+		for i, node := range drifted {
+			names[i] = node.Name
+		}
+		tracker.Report(names, fmt.Sprintf("sloth-kubernetes firewall reconcile %s", stack))
+		return tracker.interruptedError("firewall reconcile")
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to reconcile the firewall of %d node(s)", failed)
+	}
+
+	fmt.Println()
+	printSuccess("Firewalls reconciled")
+	return unreadableErr
+}
+
+// providerNetworkCIDR returns the CIDR of the VPC of a provider from the stack
+// outputs, or "" when the stack has none
+func providerNetworkCIDR(outputs auto.OutputMap, provider string) string {
+	if output, ok := outputs[fmt.Sprintf("vpc_%s_cidr", provider)]; ok {
+		if cidr, ok := output.Value.(string); ok {
+			return cidr
+		}
+	}
+	return ""
+}
+
+// printUFWReconcilePlan prints the drift found on a node
+func printUFWReconcilePlan(nodeName string, plan ufwReconcilePlan) {
+	if plan.Empty() && len(plan.Kept) == 0 {
+		fmt.Printf("  ✓ %s: in sync\n", nodeName)
+		return
+	}
+	fmt.Printf("  • %s:\n", nodeName)
+	for _, rule := range plan.Add {
+		color.Green("      + %s", rule)
+	}
+	for _, rule := range plan.Remove {
+		color.Red("      - %s", rule)
+	}
+	for _, rule := range plan.Kept {
+		color.Yellow("      = %s (SSH rule not in the config, kept)", rule)
+	}
+}
+
+// reconcileCloudFirewalls refreshes the firewall resources of the stack from
+// the providers and updates them back to the rules of the config
+func reconcileCloudFirewalls(ctx context.Context, s auto.Stack, stack string) error {
+	urns, err := firewallURNs(ctx, s)
+	if err != nil {
+		return err
+	}
+	if len(urns) == 0 {
+		printInfo("  No cloud firewalls in the stack")
+		return nil
+	}
+
+	prev, err := s.PreviewRefresh(ctx, optrefresh.Target(urns))
+	if err != nil {
+		return stackLockedError(err, "preview refresh", stack)
+	}
+	drifted := prev.ChangeSummary["update"] + prev.ChangeSummary["delete"]
+	if drifted == 0 {
+		printSuccess(fmt.Sprintf("%d cloud firewall(s) match the config", len(urns)))
+		return nil
+	}
+	color.Yellow(fmt.Sprintf("  ⚠️  %d of %d cloud firewall(s) drifted from the config", drifted, len(urns)))
+	if firewallReconcileDryRun {
+		return nil
+	}
+	if !autoApprove && !confirm("Reapply the cloud firewalls?") {
+		printWarning("Cloud firewalls left as they are")
+		return nil
+	}
+
+	if _, err := s.Refresh(ctx, optrefresh.Target(urns)); err != nil {
+		return stackLockedError(err, "refresh", stack)
+	}
+	res, err := s.Up(ctx, optup.Target(urns), optup.ProgressStreams(pulumiProgress()))
+	if err != nil {
+		return stackLockedError(err, "reapply firewalls", stack)
+	}
+
+	updated := 0
+	if res.Summary.ResourceChanges != nil {
+		changes := *res.Summary.ResourceChanges
+		updated = changes["update"] + changes["create"] + changes["replace"]
+	}
+	printSuccess(fmt.Sprintf("Reapplied %d cloud firewall(s)", updated))
+	return nil
+}
+
+// firewallURNs returns the URNs of the cloud provider firewalls of a stack
+func firewallURNs(ctx context.Context, s auto.Stack) ([]string, error) {
+	deployment, err := s.Export(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export stack: %w", err)
+	}
+
+	var deploymentData struct {
+		Resources []struct {
+			URN  string `json:"urn"`
+			Type string `json:"type"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(deployment.Deployment, &deploymentData); err != nil {
+		return nil, fmt.Errorf("failed to parse deployment: %w", err)
+	}
+
+	var urns []string
+	for _, resource := range deploymentData.Resources {
+		if isFirewallResourceType(resource.Type) {
+			urns = append(urns, resource.URN)
+		}
+	}
+	return urns, nil
+}
+
+// isFirewallResourceType reports whether a Pulumi resource type is a cloud
+// provider firewall, e.g. digitalocean:index/firewall:Firewall
+func isFirewallResourceType(resourceType string) bool {
+	return strings.HasSuffix(resourceType, "/firewall:Firewall")
+}
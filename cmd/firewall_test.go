@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanUFWReconcile(t *testing.T) {
+	current := parseUFWShowAdded(`Added user rules (see 'ufw status' for running firewall):
+ufw allow 6443/tcp
+ufw allow from 10.8.0.0/24 to any port 22 proto tcp comment 'old comment'
+ufw allow 22/tcp
+ufw allow 9999/tcp
+ufw allow in on lo
+`)
+
+	intended := []string{
+		"ufw allow 6443/tcp comment 'Kubernetes API'",
+		"ufw allow from 10.8.0.0/24 to any port 22 proto tcp comment 'SSH over VPN'",
+		"ufw allow 51820/udp comment 'WireGuard'",
+	}
+
+	plan := planUFWReconcile(current, intended)
+
+	if want := []string{"ufw allow 51820/udp comment 'WireGuard'"}; !reflect.DeepEqual(plan.Add, want) {
+		t.Errorf("Add = %v, want %v", plan.Add, want)
+	}
+	if want := []string{"ufw allow 9999/tcp"}; !reflect.DeepEqual(plan.Remove, want) {
+		t.Errorf("Remove = %v, want %v", plan.Remove, want)
+	}
+	if want := []string{"ufw allow 22/tcp"}; !reflect.DeepEqual(plan.Kept, want) {
+		t.Errorf("Kept = %v, want %v", plan.Kept, want)
+	}
+
+	script := plan.script()
+	add := strings.Index(script, "sudo ufw allow 51820/udp")
+	remove := strings.Index(script, "sudo ufw delete allow 9999/tcp")
+	if add < 0 || remove < 0 || add > remove {
+		t.Errorf("script should add rules before removing stale ones:\n%s", script)
+	}
+	if strings.Contains(script, "delete allow 22/tcp") {
+		t.Errorf("script removes an SSH rule:\n%s", script)
+	}
+}
+
+func TestPlanUFWReconcile_NoDrift(t *testing.T) {
+	rules := []string{"ufw allow 6443/tcp", "ufw allow from 10.0.0.0/16 to any port 1:65535 proto udp"}
+	if plan := planUFWReconcile(rules, rules); !plan.Empty() {
+		t.Errorf("plan = %+v, want empty", plan)
+	}
+}
@@ -66,6 +66,11 @@ func runHelm(cmd *cobra.Command, args []string) error {
 		os.Setenv("KUBECONFIG", kubeconfigPath)
 	}
 
+	// Fail fast with a hint when the API is only reachable over the VPN
+	if err := ensureAPIReachable(args, helmLocalCommands, kubeconfigPath); err != nil {
+		return err
+	}
+
 	// Create and execute helm command
 	helmExec := exec.Command(helmBinary, args...)
 	helmExec.Stdin = os.Stdin
@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// verifyHostKeys is the --verify-host-keys flag. By default host keys are
+// accepted and forgotten, which keeps bootstrap free of prompts but verifies
+// nothing; with the flag they are recorded on first connect to a per-stack
+// known_hosts file and checked on every later connection.
+var verifyHostKeys bool
+
+// knownHostsPath returns the known_hosts file of stack,
+// ~/.sloth-kubernetes/<stack>/known_hosts
+func knownHostsPath(stack string) (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".sloth-kubernetes", stack, "known_hosts"), nil
+}
+
+// knownHostsOption returns the ssh UserKnownHostsFile option for stack. With
+// host key verification on, the stack's known_hosts directory is created so
+// ssh can record new keys in it.
+func knownHostsOption(stack string) string {
+	if !verifyHostKeys {
+		return "UserKnownHostsFile=/dev/null"
+	}
+
+	path, err := knownHostsPath(stack)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(path), 0700)
+	}
+	if err != nil {
+		color.Red("⚠️  Cannot use a known_hosts file for stack '%s', host keys will NOT be verified: %v", stack, err)
+		return "UserKnownHostsFile=/dev/null"
+	}
+	return "UserKnownHostsFile=" + path
+}
+
+// hostKeyChanged reports whether ssh refused a connection because the host
+// key differs from the recorded one
+func hostKeyChanged(output []byte) bool {
+	return bytes.Contains(output, []byte("REMOTE HOST IDENTIFICATION HAS CHANGED")) ||
+		bytes.Contains(output, []byte("Host key verification failed"))
+}
+
+// warnHostKeyChanged prints a loud warning when an ssh invocation with args
+// was refused over a changed host key
+func warnHostKeyChanged(args []string, output []byte) {
+	if !verifyHostKeys || !hostKeyChanged(output) {
+		return
+	}
+
+	target, knownHosts := "", ""
+	for _, arg := range args {
+		if value, ok := strings.CutPrefix(arg, "UserKnownHostsFile="); ok {
+			knownHosts = value
+		} else if strings.Contains(arg, "@") && !strings.Contains(arg, "=") {
+			target = arg[strings.Index(arg, "@")+1:]
+		}
+	}
+
+	fmt.Println()
+	color.New(color.Bold, color.FgRed).Printf("⚠️  HOST KEY OF %s HAS CHANGED — possible man-in-the-middle attack!\n", target)
+	color.Red("The connection was refused. If the node was rebuilt, remove its old key with:")
+	color.Red("  ssh-keygen -R %s -f %s", target, knownHosts)
+}
@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func stubVerifyHostKeys(t *testing.T, verify bool) {
+	t.Helper()
+	original := verifyHostKeys
+	verifyHostKeys = verify
+	t.Cleanup(func() { verifyHostKeys = original })
+}
+
+func TestKnownHostsPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	path, err := knownHostsPath("production")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := filepath.Join(home, ".sloth-kubernetes", "production", "known_hosts"); path != want {
+		t.Errorf("Expected %s, got %s", want, path)
+	}
+}
+
+func TestNodeSSHArgs_VerifyHostKeys(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	stubVerifyHostKeys(t, true)
+
+	knownHosts := filepath.Join(home, ".sloth-kubernetes", "production", "known_hosts")
+	node := NodeInfo{Name: "master-1", PublicIP: "203.0.113.10", WireGuardIP: "10.8.0.10"}
+
+	access := nodeSSHAccess{Stack: "production", KeyPath: "/tmp/key.pem"}
+	args := access.args(node, "root", 10, "hostname")
+	if !slices.Contains(args, "UserKnownHostsFile="+knownHosts) || !slices.Contains(args, "StrictHostKeyChecking=accept-new") {
+		t.Errorf("Expected host keys to be recorded in %s, got %v", knownHosts, args)
+	}
+	if slices.Contains(args, "UserKnownHostsFile=/dev/null") || slices.Contains(args, "-q") {
+		t.Errorf("Expected no discarded host keys and no -q in verify mode, got %v", args)
+	}
+	if info, err := os.Stat(filepath.Dir(knownHosts)); err != nil || !info.IsDir() {
+		t.Errorf("Expected the known_hosts directory to be created, got %v", err)
+	}
+
+	access.BastionEnabled, access.BastionIP = true, "203.0.113.1"
+	args = access.args(node, "root", 10, "hostname")
+	proxy := args[slices.IndexFunc(args, func(arg string) bool { return strings.HasPrefix(arg, "ProxyCommand=") })]
+	if !strings.Contains(proxy, "-o UserKnownHostsFile="+knownHosts) {
+		t.Errorf("Expected the bastion hop to verify host keys too, got %s", proxy)
+	}
+}
+
+func TestNodeSSHArgs_DefaultDiscardsHostKeys(t *testing.T) {
+	stubVerifyHostKeys(t, false)
+
+	access := nodeSSHAccess{Stack: "production", KeyPath: "/tmp/key.pem"}
+	args := access.args(NodeInfo{Name: "master-1", PublicIP: "203.0.113.10"}, "root", 10)
+	if !slices.Contains(args, "UserKnownHostsFile=/dev/null") || args[0] != "-q" {
+		t.Errorf("Expected the permissive default, got %v", args)
+	}
+}
+
+func TestHostKeyChanged(t *testing.T) {
+	output := []byte("@@@@@@@@@@@\n@    WARNING: REMOTE HOST IDENTIFICATION HAS CHANGED!     @\nHost key verification failed.\n")
+	if !hostKeyChanged(output) {
+		t.Error("Expected a changed host key to be detected")
+	}
+	if hostKeyChanged([]byte("ssh: connect to host 203.0.113.10 port 22: Connection refused")) {
+		t.Error("Expected a refused connection not to be a changed host key")
+	}
+}
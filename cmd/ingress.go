@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/health"
+	"github.com/chalkan3/sloth-kubernetes/pkg/ingress"
+)
+
+// ingressScalePollInterval and ingressScalePollAttempts bound the wait for
+// the controller to settle at the new replica count. They are variables so
+// tests do not sleep.
+var (
+	ingressScalePollInterval = 10 * time.Second
+	ingressScalePollAttempts = 60
+)
+
+var ingressReplicas int
+
+var ingressCmd = &cobra.Command{
+	Use:   "ingress",
+	Short: "Manage the NGINX Ingress Controller",
+	Long:  `Manage the NGINX Ingress Controller installed on the cluster.`,
+}
+
+var ingressScaleCmd = &cobra.Command{
+	Use:   "scale [stack-name]",
+	Short: "Scale the ingress controller and wait for it to become ready",
+	Long: `Change the number of NGINX Ingress Controller replicas without a redeploy.
+The controller deployment is patched from a control-plane node and the command
+waits until the new replicas are Ready and the removed ones have terminated.
+When the controller is autoscaled, the autoscaler minimum follows the new
+replica count so it does not undo the change.
+
+Production clusters (environment "production" in --config, or a stack named
+production or prod) cannot be scaled below 1 replica.`,
+	Example: `  # Run three ingress controller replicas
+  sloth-kubernetes ingress scale production --replicas 3
+
+  # Scale without confirmation
+  sloth-kubernetes ingress scale staging --replicas 1 --yes`,
+	RunE: runIngressScale,
+}
+
+func init() {
+	rootCmd.AddCommand(ingressCmd)
+	ingressCmd.AddCommand(ingressScaleCmd)
+
+	ingressScaleCmd.Flags().IntVar(&ingressReplicas, "replicas", -1, "Number of ingress controller replicas")
+	_ = ingressScaleCmd.MarkFlagRequired("replicas")
+}
+
+// kubectlScriptPrefix points kubectl at the RKE2 or K3s admin kubeconfig
+const kubectlScriptPrefix = `set -e
+export PATH="$PATH:/usr/local/bin:/var/lib/rancher/rke2/bin"
+if [ -f /etc/rancher/rke2/rke2.yaml ]; then export KUBECONFIG=/etc/rancher/rke2/rke2.yaml; else export KUBECONFIG=/etc/rancher/k3s/k3s.yaml; fi
+`
+
+// ingressControllerState is the controller state read by ingressStateScript
+type ingressControllerState struct {
+	Deployment    string
+	Autoscaler    string
+	AutoscalerMax int
+	Replicas      int
+	Ready         int
+	Pods          int
+	Healthy       bool
+}
+
+// ingressStateScript prints the controller deployment, its autoscaler, its
+// replica counts and the health checker's ingress readiness markers
+func ingressStateScript() string {
+	return kubectlScriptPrefix + fmt.Sprintf(`NS=%[1]s
+SELECTOR=%[2]s
+DEPLOY=$(kubectl -n "$NS" get deploy -l "$SELECTOR" -o name | head -n1)
+if [ -z "$DEPLOY" ]; then
+  echo "ingress controller deployment not found"
+  exit 1
+fi
+echo "DEPLOYMENT:$DEPLOY"
+HPA=$(kubectl -n "$NS" get hpa -l "$SELECTOR" -o name 2>/dev/null | head -n1)
+if [ -n "$HPA" ]; then
+  echo "AUTOSCALER:$HPA:$(kubectl -n "$NS" get "$HPA" -o jsonpath='{.spec.maxReplicas}')"
+fi
+echo "REPLICAS:$(kubectl -n "$NS" get "$DEPLOY" -o jsonpath='{.spec.replicas}')"
+echo "READY:$(kubectl -n "$NS" get "$DEPLOY" -o jsonpath='{.status.readyReplicas}')"
+echo "PODS:$(kubectl -n "$NS" get pods -l "$SELECTOR" --no-headers 2>/dev/null | wc -l)"
+set +e
+%[3]s`, ingress.ControllerNamespace, ingress.ControllerSelector, health.IngressCheckScript)
+}
+
+// parseIngressControllerState parses the output of ingressStateScript
+func parseIngressControllerState(output string) ingressControllerState {
+	state := ingressControllerState{Healthy: health.IngressReady(output)}
+	atoi := func(value string) int {
+		n, _ := strconv.Atoi(strings.TrimSpace(value))
+		return n
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+
+		switch key {
+		case "DEPLOYMENT":
+			state.Deployment = value
+		case "AUTOSCALER":
+			if i := strings.LastIndex(value, ":"); i >= 0 {
+				state.Autoscaler = value[:i]
+				state.AutoscalerMax = atoi(value[i+1:])
+			}
+		case "REPLICAS":
+			state.Replicas = atoi(value)
+		case "READY":
+			state.Ready = atoi(value)
+		case "PODS":
+			state.Pods = atoi(value)
+		}
+	}
+	return state
+}
+
+// settled reports whether the controller runs exactly replicas ready pods,
+// i.e. new replicas are Ready and removed ones have terminated
+func (s ingressControllerState) settled(replicas int) bool {
+	return s.Healthy && s.Replicas == replicas && s.Ready == replicas && s.Pods == replicas
+}
+
+// ingressScaleScript patches the controller deployment, and its autoscaler
+// when there is one. An autoscaler does not act on a deployment scaled to
+// zero, so it is left alone in that case.
+func ingressScaleScript(state ingressControllerState, replicas int) string {
+	script := kubectlScriptPrefix + fmt.Sprintf("kubectl -n %s patch %s --type merge -p '%s'\n",
+		ingress.ControllerNamespace, state.Deployment, ingress.ScalePatch(replicas))
+	if state.Autoscaler != "" && replicas > 0 {
+		script += fmt.Sprintf("kubectl -n %s patch %s --type merge -p '%s'\n",
+			ingress.ControllerNamespace, state.Autoscaler, ingress.AutoscalerPatch(replicas, state.AutoscalerMax))
+	}
+	return script
+}
+
+// productionCluster reports whether the stack runs a production cluster,
+// from the config environment when a config is given and the stack name
+// otherwise
+func productionCluster(stack string, cfg *config.ClusterConfig) bool {
+	if cfg != nil && cfg.Metadata.Environment != "" {
+		return strings.EqualFold(cfg.Metadata.Environment, "production")
+	}
+	switch strings.ToLower(stack) {
+	case "production", "prod":
+		return true
+	}
+	return false
+}
+
+// runKubectlScript runs a kubectl script as root on a server
+func runKubectlScript(node NodeInfo, access nodeSSHAccess, script string) (string, error) {
+	user := getSSHUserForNode(node.Provider)
+	output, err := sshRunner(access.args(node, user, 10, "sudo", "bash", "-s"), script)
+	if err != nil {
+		return "", fmt.Errorf("kubectl failed on %s: %v (output: %s)", node.Name, err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// readIngressControllerState reads the controller state from a server
+func readIngressControllerState(node NodeInfo, access nodeSSHAccess) (ingressControllerState, error) {
+	output, err := runKubectlScript(node, access, ingressStateScript())
+	if err != nil {
+		return ingressControllerState{}, err
+	}
+	return parseIngressControllerState(output), nil
+}
+
+// waitForIngressReplicas polls the controller until it settles at replicas
+func waitForIngressReplicas(node NodeInfo, access nodeSSHAccess, replicas int) error {
+	var state ingressControllerState
+	for attempt := 0; attempt < ingressScalePollAttempts; attempt++ {
+		var err error
+		state, err = readIngressControllerState(node, access)
+		if err == nil && state.settled(replicas) {
+			return nil
+		}
+		time.Sleep(ingressScalePollInterval)
+	}
+	return fmt.Errorf("ingress controller did not settle at %d replicas (ready: %d, pods: %d)", replicas, state.Ready, state.Pods)
+}
+
+func runIngressScale(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	var cfg *config.ClusterConfig
+	if cfgFile != "" {
+		cfg, err = config.LoadFromYAML(cfgFile)
+		if err != nil {
+			return fmt.Errorf("failed to load config file: %w", err)
+		}
+	}
+	if err := ingress.ValidateReplicas(ingressReplicas, productionCluster(stack, cfg)); err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("🌐 Scale Ingress Controller - Stack: %s", stack))
+
+	servers, access, err := loadControlPlane(ctx, stack)
+	if err != nil {
+		return err
+	}
+	server, err := findReachableNode(servers, access)
+	if err != nil {
+		return err
+	}
+
+	state, err := readIngressControllerState(server, access)
+	if err != nil {
+		return err
+	}
+	if state.Deployment == "" {
+		return fmt.Errorf("ingress controller deployment not found on %s", server.Name)
+	}
+
+	fmt.Println()
+	printInfo(fmt.Sprintf("Controller: %s, %d → %d replica(s)", state.Deployment, state.Replicas, ingressReplicas))
+	if state.Autoscaler != "" && ingressReplicas > 0 {
+		printInfo(fmt.Sprintf("Autoscaler %s minimum will be set to %d", state.Autoscaler, ingressReplicas))
+	}
+	if !autoApprove && !confirm("Scale the ingress controller?") {
+		printWarning("Scale cancelled")
+		return nil
+	}
+
+	if _, err := runKubectlScript(server, access, ingressScaleScript(state, ingressReplicas)); err != nil {
+		return err
+	}
+
+	printInfo("Waiting for the ingress controller to become ready...")
+	if err := waitForIngressReplicas(server, access, ingressReplicas); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	printSuccess(fmt.Sprintf("Ingress controller running %d ready replica(s)", ingressReplicas))
+	return nil
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+const ingressStateScaling = `DEPLOYMENT:deployment.apps/nginx-ingress-ingress-nginx-controller
+AUTOSCALER:horizontalpodautoscaler.autoscaling/nginx-ingress-ingress-nginx-controller:4
+REPLICAS:3
+READY:2
+PODS:4
+NGINX:SERVICE:OK
+NGINX:PODS:OK
+`
+
+func stubIngressScalePolling(t *testing.T) {
+	t.Helper()
+	interval, attempts := ingressScalePollInterval, ingressScalePollAttempts
+	ingressScalePollInterval, ingressScalePollAttempts = 0, 3
+	t.Cleanup(func() {
+		ingressScalePollInterval, ingressScalePollAttempts = interval, attempts
+	})
+}
+
+func TestParseIngressControllerState(t *testing.T) {
+	state := parseIngressControllerState(ingressStateScaling)
+	if state.Deployment != "deployment.apps/nginx-ingress-ingress-nginx-controller" ||
+		state.Autoscaler != "horizontalpodautoscaler.autoscaling/nginx-ingress-ingress-nginx-controller" ||
+		state.AutoscalerMax != 4 || state.Replicas != 3 || state.Ready != 2 || state.Pods != 4 || !state.Healthy {
+		t.Errorf("Unexpected state: %+v", state)
+	}
+	if state.settled(3) {
+		t.Error("Expected a rollout with unready and terminating pods not to be settled")
+	}
+
+	state = parseIngressControllerState("DEPLOYMENT:deployment.apps/controller\nREPLICAS:0\nREADY:\nPODS:0\nNGINX:SERVICE:OK\nNGINX:PODS:OK\n")
+	if !state.settled(0) {
+		t.Errorf("Expected a controller scaled to zero to be settled, got %+v", state)
+	}
+}
+
+func TestIngressScaleScript(t *testing.T) {
+	state := parseIngressControllerState(ingressStateScaling)
+
+	script := ingressScaleScript(state, 6)
+	for _, want := range []string{
+		`kubectl -n ingress-nginx patch deployment.apps/nginx-ingress-ingress-nginx-controller --type merge -p '{"spec":{"replicas":6}}'`,
+		`patch horizontalpodautoscaler.autoscaling/nginx-ingress-ingress-nginx-controller --type merge -p '{"spec":{"maxReplicas":6,"minReplicas":6}}'`,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q, got:\n%s", want, script)
+		}
+	}
+
+	if script := ingressScaleScript(state, 0); strings.Contains(script, "horizontalpodautoscaler") {
+		t.Errorf("Expected the autoscaler to be left alone when scaling to zero, got:\n%s", script)
+	}
+}
+
+func TestProductionCluster(t *testing.T) {
+	if !productionCluster("prod", nil) {
+		t.Error("Expected the prod stack to be production")
+	}
+	if productionCluster("staging", nil) {
+		t.Error("Expected the staging stack not to be production")
+	}
+	cfg := &config.ClusterConfig{Metadata: config.Metadata{Environment: "production"}}
+	if !productionCluster("blue", cfg) {
+		t.Error("Expected the config environment to mark the cluster as production")
+	}
+	cfg.Metadata.Environment = "development"
+	if productionCluster("production", cfg) {
+		t.Error("Expected the config environment to take precedence over the stack name")
+	}
+}
+
+func TestWaitForIngressReplicas(t *testing.T) {
+	stubIngressScalePolling(t)
+	node := NodeInfo{Name: "master-1", Provider: "digitalocean", PublicIP: "203.0.113.10", Roles: []string{"master"}}
+
+	polls := 0
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		polls++
+		if polls < 2 {
+			return []byte(ingressStateScaling), nil
+		}
+		return []byte(strings.NewReplacer("READY:2", "READY:3", "PODS:4", "PODS:3").Replace(ingressStateScaling)), nil
+	})
+
+	if err := waitForIngressReplicas(node, nodeSSHAccess{}, 3); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if polls != 2 {
+		t.Errorf("Expected to poll until the old pod terminated, polled %d times", polls)
+	}
+
+	if err := waitForIngressReplicas(node, nodeSSHAccess{}, 5); err == nil {
+		t.Error("Expected an error when the controller never settles")
+	}
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/fatih/color"
+	"golang.org/x/term"
+)
+
+// nodeChangeTracker records which nodes a mutating command has changed so an
+// interrupted run can report or undo them. The first Ctrl-C only stops new node
+// operations from starting; a second one cancels the command context, killing
+// in-flight SSH sessions. Nodes may be changed concurrently.
+type nodeChangeTracker struct {
+	mu          sync.Mutex
+	modified    []string
+	inFlight    []string
+	interrupted bool
+
+	signals   chan os.Signal
+	done      chan struct{}
+	termState *term.State
+}
+
+// newNodeChangeTracker starts handling SIGINT/SIGTERM until Stop is called
+func newNodeChangeTracker() *nodeChangeTracker {
+	t := &nodeChangeTracker{
+		signals: make(chan os.Signal, 2),
+		done:    make(chan struct{}),
+	}
+
+	// Saved so a prompt interrupted mid-input does not leave the terminal raw
+	if fd := int(os.Stdin.Fd()); term.IsTerminal(fd) {
+		t.termState, _ = term.GetState(fd)
+	}
+
+	interruptsHandled.Add(1)
+	signal.Notify(t.signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for {
+			select {
+			case <-t.signals:
+				t.interrupt()
+			case <-t.done:
+				return
+			}
+		}
+	}()
+	return t
+}
+
+// interrupt handles one interrupt signal
+func (t *nodeChangeTracker) interrupt() {
+	t.mu.Lock()
+	first := !t.interrupted
+	t.interrupted = true
+	t.mu.Unlock()
+
+	fmt.Println()
+	if first {
+		color.Yellow("⚠️  Interrupt received - finishing the current node(s), no new nodes will be changed (Ctrl-C again to abort now)")
+		return
+	}
+	color.Yellow("⚠️  Aborting in-flight operations")
+	if cancelCommand != nil {
+		cancelCommand()
+	}
+}
+
+// Stop ends signal handling and restores the terminal
+func (t *nodeChangeTracker) Stop() {
+	signal.Stop(t.signals)
+	close(t.done)
+	interruptsHandled.Add(-1)
+	if t.termState != nil {
+		_ = term.Restore(int(os.Stdin.Fd()), t.termState)
+	}
+}
+
+// Interrupted reports whether the command was asked to stop
+func (t *nodeChangeTracker) Interrupted() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.interrupted
+}
+
+// Begin marks node as being changed. The change counts as possibly applied
+// until Done says otherwise.
+func (t *nodeChangeTracker) Begin(node string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.inFlight = append(t.inFlight, node)
+}
+
+// Done ends the change of the node begun last, recording whether it applied
+func (t *nodeChangeTracker) Done(changed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.inFlight) == 0 {
+		return
+	}
+	t.finish(t.inFlight[len(t.inFlight)-1], changed)
+}
+
+// DoneNode ends the change of node, for nodes changed concurrently
+func (t *nodeChangeTracker) DoneNode(node string, changed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.finish(node, changed)
+}
+
+// finish moves node out of the in-flight nodes; t.mu must be held
+func (t *nodeChangeTracker) finish(node string, changed bool) {
+	if !containsName(t.inFlight, node) {
+		return
+	}
+	t.inFlight = removeName(t.inFlight, node)
+	if changed {
+		t.modified = append(t.modified, node)
+	}
+}
+
+// Modified returns the nodes whose change was applied
+func (t *nodeChangeTracker) Modified() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.modified...)
+}
+
+// Touched returns the modified nodes plus the nodes whose change was cut
+// short, i.e. every node that may differ from its state before the command
+func (t *nodeChangeTracker) Touched() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append(append([]string(nil), t.modified...), t.inFlight...)
+}
+
+// Report prints which of nodes were changed, which were cut short and which
+// were left alone, with a hint on how to reconcile them
+func (t *nodeChangeTracker) Report(nodes []string, hint string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	touched := make(map[string]bool)
+	for _, name := range t.modified {
+		touched[name] = true
+	}
+	for _, name := range t.inFlight {
+		touched[name] = true
+	}
+
+	var untouched []string
+	for _, name := range nodes {
+		if !touched[name] {
+			untouched = append(untouched, name)
+		}
+	}
+
+	fmt.Println()
+	color.Yellow("⚠️  Interrupted before all nodes were updated:")
+	if len(t.modified) > 0 {
+		fmt.Printf("  Modified:     %s\n", strings.Join(t.modified, ", "))
+	} else {
+		fmt.Println("  Modified:     none")
+	}
+	if len(t.inFlight) > 0 {
+		fmt.Printf("  Unknown:      %s (interrupted mid-change)\n", strings.Join(t.inFlight, ", "))
+	}
+	if len(untouched) > 0 {
+		fmt.Printf("  Not modified: %s\n", strings.Join(untouched, ", "))
+	}
+	if hint != "" {
+		fmt.Printf("  Reconcile:    %s\n", hint)
+	}
+}
+
+// interruptedError is returned by commands stopped by an interrupt
+func (t *nodeChangeTracker) interruptedError(action string) error {
+	return fmt.Errorf("%s interrupted after modifying %d node(s)", action, len(t.Modified()))
+}
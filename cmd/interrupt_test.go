@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNodeChangeTracker_RecordsChanges(t *testing.T) {
+	tracker := newNodeChangeTracker()
+	defer tracker.Stop()
+
+	tracker.Begin("master-1")
+	tracker.Done(true)
+	tracker.Begin("master-2")
+	tracker.Done(false)
+	tracker.Begin("worker-1")
+
+	if got := tracker.Modified(); len(got) != 1 || got[0] != "master-1" {
+		t.Errorf("Expected only master-1 modified, got %v", got)
+	}
+	if got := tracker.Touched(); len(got) != 2 || got[1] != "worker-1" {
+		t.Errorf("Expected the in-flight node to count as touched, got %v", got)
+	}
+	if tracker.Interrupted() {
+		t.Error("Tracker should not start interrupted")
+	}
+}
+
+func TestNodeChangeTracker_Interrupt(t *testing.T) {
+	originalCtx, originalCancel := commandCtx, cancelCommand
+	commandCtx, cancelCommand = context.WithCancel(context.Background())
+	t.Cleanup(func() { commandCtx, cancelCommand = originalCtx, originalCancel })
+
+	tracker := newNodeChangeTracker()
+	defer tracker.Stop()
+
+	tracker.interrupt()
+	if !tracker.Interrupted() {
+		t.Fatal("Expected tracker to be interrupted")
+	}
+	if commandContext().Err() != nil {
+		t.Error("First interrupt should let in-flight operations finish")
+	}
+
+	tracker.interrupt()
+	if !errors.Is(commandContext().Err(), context.Canceled) {
+		t.Error("Second interrupt should cancel in-flight operations")
+	}
+}
+
+func TestAbortVPNJoin_ReportsWithoutAtomic(t *testing.T) {
+	originalAtomic, originalIP := vpnJoinAtomic, vpnJoinIP
+	vpnJoinAtomic, vpnJoinIP = false, "10.8.0.100"
+	t.Cleanup(func() { vpnJoinAtomic, vpnJoinIP = originalAtomic, originalIP })
+
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		t.Errorf("No node should be contacted without --atomic: %v", args)
+		return nil, nil
+	})
+
+	tracker := newNodeChangeTracker()
+	defer tracker.Stop()
+	tracker.Begin("master-1")
+	tracker.Done(true)
+
+	stubOperationStatusDir(t)
+	status := &operationStatus{Operation: vpnJoinOperation, Stack: "production"}
+	status.markSucceeded("master-1")
+	status.markFailed("worker-1")
+
+	nodes := []NodeInfo{{Name: "master-1"}, {Name: "worker-1"}}
+	err := abortVPNJoin(tracker, status, nodes, nodeSSHAccess{KeyPath: "/tmp/key.pem"}, "PUBKEY=")
+	if err == nil || !strings.Contains(err.Error(), "interrupted after modifying 1 node(s)") {
+		t.Errorf("Expected interrupted error, got %v", err)
+	}
+
+	saved, err := loadOperationStatus("production", vpnJoinOperation)
+	if err != nil || saved == nil {
+		t.Fatalf("Expected the join status to be saved for --resume-failed, got %v, %v", saved, err)
+	}
+	if strings.Join(saved.Failed, ",") != "worker-1" {
+		t.Errorf("Expected worker-1 to be resumable, got %v", saved.Failed)
+	}
+}
+
+func TestAbortVPNJoin_AtomicRollsBack(t *testing.T) {
+	originalAtomic := vpnJoinAtomic
+	vpnJoinAtomic = true
+	t.Cleanup(func() { vpnJoinAtomic = originalAtomic })
+
+	var contacted []string
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		remote := args[len(args)-1]
+		if !strings.Contains(remote, "key='PUBKEY='") || !strings.Contains(remote, "wg syncconf wg0") {
+			t.Errorf("Unexpected command: %s", remote)
+		}
+		contacted = append(contacted, sshTarget(args))
+		return []byte("SUCCESS\n"), nil
+	})
+
+	tracker := newNodeChangeTracker()
+	defer tracker.Stop()
+	tracker.Begin("master-1")
+	tracker.Done(true)
+	tracker.Begin("worker-1") // cut short by the interrupt
+
+	nodes := []NodeInfo{
+		{Name: "master-1", PublicIP: "203.0.113.10"},
+		{Name: "worker-1", PublicIP: "203.0.113.20"},
+		{Name: "worker-2", PublicIP: "203.0.113.21"},
+	}
+	stubOperationStatusDir(t)
+	status := &operationStatus{Operation: vpnJoinOperation, Stack: "production", Succeeded: []string{"master-1"}}
+	err := abortVPNJoin(tracker, status, nodes, nodeSSHAccess{KeyPath: "/tmp/key.pem"}, "PUBKEY=")
+	if err == nil || !strings.Contains(err.Error(), "rolled back") {
+		t.Errorf("Expected rolled back error, got %v", err)
+	}
+
+	want := []string{"root@203.0.113.10", "root@203.0.113.20"}
+	if strings.Join(contacted, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected rollback on %v, got %v", want, contacted)
+	}
+	if saved, _ := loadOperationStatus("production", vpnJoinOperation); saved != nil {
+		t.Errorf("Expected no join status after a full rollback, got %+v", saved)
+	}
+}
+
+func TestRollbackPeerAdd_ReportsFailures(t *testing.T) {
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		if sshTarget(args) == "root@203.0.113.20" {
+			return nil, errors.New("connection refused")
+		}
+		return []byte("SUCCESS"), nil
+	})
+
+	nodes := []NodeInfo{
+		{Name: "master-1", PublicIP: "203.0.113.10"},
+		{Name: "worker-1", PublicIP: "203.0.113.20"},
+	}
+	failed := rollbackPeerAdd([]string{"master-1", "worker-1", "gone-1"}, nodes, nodeSSHAccess{KeyPath: "/tmp/key.pem"}, "PUBKEY=")
+	if strings.Join(failed, ",") != "worker-1,gone-1" {
+		t.Errorf("Expected worker-1 and unknown gone-1 to fail, got %v", failed)
+	}
+}
@@ -0,0 +1,272 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/spf13/cobra"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+// Kubernetes distributions a stack may run, named by their binary
+const (
+	distributionRKE2 = "rke2"
+	distributionK3s  = "k3s"
+)
+
+// joinDetailsScript prints the distribution of a control-plane node, its
+// join token and its `<binary> --version` output, one per line
+var joinDetailsScript = etcdBackupScriptPrefix(nil) + `TOKEN=$(cat "$DATA/server/node-token")
+echo "$BIN"
+echo "$TOKEN"
+$BIN --version 2>/dev/null | head -1 || true
+`
+
+// joinDetails are what a node needs to join the cluster
+type joinDetails struct {
+	Distribution string // rke2 or k3s
+	Token        string
+	Version      string // Empty when it cannot be determined
+}
+
+// maskedToken replaces the join token unless --show-token is given
+const maskedToken = "[secret]"
+
+var (
+	joinRole      string
+	joinShowToken bool
+	joinServer    string
+)
+
+var joinCommandCmd = &cobra.Command{
+	Use:   "join-command [stack-name]",
+	Short: "Print the command to join a node by hand",
+	Long: `Print the one-liner that installs RKE2 or K3s, whichever the cluster runs,
+on a new machine and joins it to the cluster. The join token, server address
+and version are read from a control-plane node of the deployed stack.
+
+The token is masked unless --show-token is given. When --config points to a
+cluster with rke2.airGap enabled, the command installs from the mirror.`,
+	Example: `  # Join a worker
+  sloth-kubernetes join-command production
+
+  # Join an additional control-plane node, with the real token
+  sloth-kubernetes join-command production --role server --show-token`,
+	RunE: runJoinCommand,
+}
+
+func init() {
+	rootCmd.AddCommand(joinCommandCmd)
+
+	joinCommandCmd.Flags().StringVar(&joinRole, "role", "worker", "Role of the new node: worker or server")
+	joinCommandCmd.Flags().BoolVar(&joinShowToken, "show-token", false, "Print the join token instead of masking it")
+	joinCommandCmd.Flags().StringVar(&joinServer, "server", "", "Server address the node registers with (default: VPN IP of the control-plane node)")
+}
+
+func runJoinCommand(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	isServer, err := parseJoinRole(joinRole)
+	if err != nil {
+		return err
+	}
+
+	k8s := &config.KubernetesConfig{}
+	var security *config.SecurityConfig
+	if cfgFile != "" {
+		cfg, err := config.LoadFromYAML(cfgFile)
+		if err != nil {
+			return fmt.Errorf("failed to load config file: %w", err)
+		}
+		k8s = &cfg.Kubernetes
+		security = &cfg.Security
+	}
+	rke2 := &config.RKE2Config{}
+	if k8s.RKE2 != nil {
+		rke2 = k8s.RKE2
+	}
+
+	// Create workspace with S3 support
+	workspace, err := createWorkspaceWithS3Support(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	// Use fully qualified stack name for S3 backend
+	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
+	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
+	if err != nil {
+		return fmt.Errorf("failed to select stack '%s': %w", stack, err)
+	}
+
+	// Get outputs
+	outputs, err := s.Outputs(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get stack outputs: %w", err)
+	}
+
+	// Parse nodes
+	nodes, err := ParseNodeOutputs(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	access := newNodeSSHAccess(stack, outputs)
+
+	var controlPlanes []NodeInfo
+	for _, node := range nodes {
+		if isControlPlaneNode(node) {
+			controlPlanes = append(controlPlanes, node)
+		}
+	}
+	if len(controlPlanes) == 0 {
+		return fmt.Errorf("no control-plane node found in stack '%s'", stack)
+	}
+
+	server, err := findReachableNode(controlPlanes, access)
+	if err != nil {
+		return err
+	}
+
+	details, err := fetchJoinDetails(server, access)
+	if err != nil {
+		return err
+	}
+	if rke2.Version == "" {
+		rke2.Version = details.Version
+	}
+	token := details.Token
+
+	serverAddr := joinServer
+	if serverAddr == "" {
+		serverAddr = joinServerAddress(server)
+	}
+
+	if !joinShowToken {
+		token = maskedToken
+	}
+
+	if verbose {
+		printInfo(fmt.Sprintf("Read join details from %s", server.Name))
+	}
+	if details.Distribution == distributionK3s {
+		joinK8s := *k8s
+		joinK8s.RKE2 = rke2
+		command, err := buildK3sJoinCommand(&joinK8s, security, isServer, serverAddr, token)
+		if err != nil {
+			return err
+		}
+		fmt.Println(command)
+	} else {
+		fmt.Println(buildRKE2JoinCommand(rke2, security, isServer, serverAddr, token))
+	}
+
+	if !joinShowToken {
+		printInfo("Token masked - rerun with --show-token to print it")
+	}
+	return nil
+}
+
+// parseJoinRole reports whether role asks for a server
+func parseJoinRole(role string) (bool, error) {
+	switch role {
+	case "worker", "agent":
+		return false, nil
+	case "server", "master", "controlplane":
+		return true, nil
+	}
+	return false, fmt.Errorf("invalid --role %q: must be worker or server", role)
+}
+
+// fetchJoinDetails reads the distribution, join token and installed version
+// from a control-plane node
+func fetchJoinDetails(node NodeInfo, access nodeSSHAccess) (joinDetails, error) {
+	output, err := access.run(node, 10, joinDetailsScript)
+	if err != nil {
+		return joinDetails{}, fmt.Errorf("failed to read join token from %s: %w", node.Name, err)
+	}
+
+	lines := strings.SplitN(string(output), "\n", 3)
+	for len(lines) < 3 {
+		lines = append(lines, "")
+	}
+	details := joinDetails{
+		Distribution: strings.TrimSpace(lines[0]),
+		Token:        strings.TrimSpace(lines[1]),
+		Version:      parseRKE2Version(lines[2]),
+	}
+	if details.Distribution != distributionRKE2 && details.Distribution != distributionK3s {
+		return joinDetails{}, fmt.Errorf("unexpected distribution %q on %s", details.Distribution, node.Name)
+	}
+	if details.Token == "" {
+		return joinDetails{}, fmt.Errorf("join token on %s is empty", node.Name)
+	}
+	return details, nil
+}
+
+// parseRKE2Version extracts the version from `rke2 --version` or
+// `k3s --version` output, e.g. "rke2 version v1.28.5+rke2r1 (...)"
+func parseRKE2Version(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && (fields[0] == distributionRKE2 || fields[0] == distributionK3s) && fields[1] == "version" {
+			return fields[2]
+		}
+	}
+	return ""
+}
+
+// joinServerAddress returns the address new nodes register with. The VPN IP is
+// preferred since the cluster's own nodes join over WireGuard.
+func joinServerAddress(node NodeInfo) string {
+	if node.WireGuardIP != "" {
+		return node.WireGuardIP
+	}
+	if node.PrivateIP != "" {
+		return node.PrivateIP
+	}
+	return node.PublicIP
+}
+
+// buildRKE2JoinCommand writes the RKE2 config pointing at server, installs RKE2
+// for the role and starts its service
+func buildRKE2JoinCommand(rke2 *config.RKE2Config, security *config.SecurityConfig, isServer bool, server, token string) string {
+	service := "rke2-agent"
+	if isServer {
+		service = "rke2-server"
+	}
+
+	return fmt.Sprintf(
+		"mkdir -p /etc/rancher/rke2 && printf 'server: https://%s:9345\\ntoken: %s\\n' > /etc/rancher/rke2/config.yaml && %s && systemctl enable --now %s.service",
+		server, token, config.GetRKE2InstallCommand(rke2, isServer, security), service,
+	)
+}
+
+// buildK3sJoinCommand installs K3s for the role, joining server. Servers get
+// the server flags and admission configuration file of the cluster config.
+func buildK3sJoinCommand(k8s *config.KubernetesConfig, security *config.SecurityConfig, isServer bool, server, token string) (string, error) {
+	exec := "agent --flannel-iface=wg0" + config.K3sKubeletArgs(k8s)
+	admissionSetup := ""
+	if isServer {
+		exec = "server --flannel-iface=wg0 --write-kubeconfig-mode=644 --disable=traefik" + config.K3sKubeletArgs(k8s) + config.K3sServerArgs(k8s)
+
+		var err error
+		if admissionSetup, err = config.WriteAdmissionConfigCommand(&k8s.Admission, config.K3sAdmissionConfigPath); err != nil {
+			return "", err
+		}
+	}
+
+	env := fmt.Sprintf(`K3S_URL=https://%s:6443 K3S_TOKEN='%s' INSTALL_K3S_EXEC="%s"`, server, token, exec)
+	install := config.K3sInstallCommand(k8s.RKE2, security, env)
+	if admissionSetup == "" {
+		return install, nil
+	}
+	return admissionSetup + " && " + install, nil
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+func TestParseJoinRole(t *testing.T) {
+	if isServer, err := parseJoinRole("worker"); err != nil || isServer {
+		t.Errorf("Expected worker to be an agent, got %v (%v)", isServer, err)
+	}
+	if isServer, err := parseJoinRole("server"); err != nil || !isServer {
+		t.Errorf("Expected server role, got %v (%v)", isServer, err)
+	}
+	if _, err := parseJoinRole("etcd"); err == nil {
+		t.Error("Expected error for unknown role")
+	}
+}
+
+func TestParseRKE2Version(t *testing.T) {
+	output := "rke2 version v1.28.5+rke2r1 (5a3b5a3b)\ngo version go1.20.12 X:boringcrypto\n"
+	if got := parseRKE2Version(output); got != "v1.28.5+rke2r1" {
+		t.Errorf("Expected v1.28.5+rke2r1, got %q", got)
+	}
+	if got := parseRKE2Version("bash: rke2: command not found"); got != "" {
+		t.Errorf("Expected empty version, got %q", got)
+	}
+}
+
+func TestJoinServerAddress(t *testing.T) {
+	node := NodeInfo{PublicIP: "203.0.113.10", PrivateIP: "10.0.0.10", WireGuardIP: "10.8.0.10"}
+	if got := joinServerAddress(node); got != "10.8.0.10" {
+		t.Errorf("Expected VPN IP, got %q", got)
+	}
+	node.WireGuardIP = ""
+	if got := joinServerAddress(node); got != "10.0.0.10" {
+		t.Errorf("Expected private IP, got %q", got)
+	}
+}
+
+func TestBuildRKE2JoinCommand(t *testing.T) {
+	agent := buildRKE2JoinCommand(&config.RKE2Config{Version: "v1.28.5+rke2r1"}, nil, false, "10.8.0.10", "K10abc::server:xyz")
+
+	expected := []string{
+		"printf 'server: https://10.8.0.10:9345\\ntoken: K10abc::server:xyz\\n' > /etc/rancher/rke2/config.yaml",
+		"curl -sfL https://raw.githubusercontent.com/rancher/rke2/v1.28.5+rke2r1/install.sh -o /tmp/rke2-install.sh",
+		"INSTALL_RKE2_TYPE=agent INSTALL_RKE2_VERSION=v1.28.5+rke2r1 sh /tmp/rke2-install.sh",
+		"systemctl enable --now rke2-agent.service",
+	}
+	for _, want := range expected {
+		if !strings.Contains(agent, want) {
+			t.Errorf("Expected join command to contain %q\nGot: %s", want, agent)
+		}
+	}
+	if strings.Index(agent, "config.yaml") > strings.Index(agent, "rke2-install.sh") {
+		t.Error("Expected the config to be written before RKE2 is installed")
+	}
+
+	server := buildRKE2JoinCommand(&config.RKE2Config{}, nil, true, "10.8.0.10", maskedToken)
+	if !strings.Contains(server, "INSTALL_RKE2_TYPE=server") || !strings.Contains(server, "rke2-server.service") {
+		t.Errorf("Expected server install, got: %s", server)
+	}
+	if !strings.Contains(server, "token: [secret]") {
+		t.Errorf("Expected masked token, got: %s", server)
+	}
+}
+
+func TestFetchJoinDetails(t *testing.T) {
+	node := NodeInfo{Name: "master-1", PublicIP: "203.0.113.10"}
+
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		if args[len(args)-1] != joinDetailsScript {
+			return nil, errors.New("unexpected command")
+		}
+		return []byte("k3s\nK10abc::server:xyz\nk3s version v1.30.4+k3s1 (98262b5d)\n"), nil
+	})
+
+	details, err := fetchJoinDetails(node, nodeSSHAccess{KeyPath: "/tmp/key.pem"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if details.Distribution != distributionK3s || details.Token != "K10abc::server:xyz" || details.Version != "v1.30.4+k3s1" {
+		t.Errorf("Unexpected details: %+v", details)
+	}
+	if !strings.Contains(joinDetailsScript, `cat "$DATA/server/node-token"`) {
+		t.Error("Expected the token to be read from the data directory of the distribution")
+	}
+}
+
+func TestFetchJoinDetails_MissingToken(t *testing.T) {
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		return []byte("cat: /var/lib/rancher/k3s/server/node-token: No such file or directory"), errors.New("exit status 1")
+	})
+
+	_, err := fetchJoinDetails(NodeInfo{Name: "master-1"}, nodeSSHAccess{KeyPath: "/tmp/key.pem"})
+	if err == nil || !strings.Contains(err.Error(), "master-1") {
+		t.Errorf("Expected token read error naming the node, got %v", err)
+	}
+}
+
+func TestBuildK3sJoinCommand(t *testing.T) {
+	k8s := &config.KubernetesConfig{EncryptSecrets: true, RKE2: &config.RKE2Config{Version: "v1.30.4+k3s1"}}
+
+	agent, err := buildK3sJoinCommand(k8s, nil, false, "10.8.0.10", "K10abc::server:xyz")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := `INSTALL_K3S_VERSION=v1.30.4+k3s1 K3S_URL=https://10.8.0.10:6443 K3S_TOKEN='K10abc::server:xyz' INSTALL_K3S_EXEC="agent --flannel-iface=wg0" sh /tmp/k3s-install.sh`
+	if !strings.Contains(agent, want) {
+		t.Errorf("Expected join command to contain %q\nGot: %s", want, agent)
+	}
+
+	server, err := buildK3sJoinCommand(k8s, nil, true, "10.8.0.10", maskedToken)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(server, `INSTALL_K3S_EXEC="server `) || !strings.Contains(server, "--secrets-encryption") {
+		t.Errorf("Expected a server install with the server flags, got: %s", server)
+	}
+	if strings.Contains(server, "rke2") {
+		t.Errorf("Expected no RKE2 install on a K3s cluster, got: %s", server)
+	}
+}
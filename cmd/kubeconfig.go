@@ -3,26 +3,55 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"net"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
 	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
 )
 
 var (
-	outputFile string
-	merge      bool
+	outputFile            string
+	merge                 bool
+	kubeconfigContextName string
+	kubeconfigServerURL   string
 )
 
+// localWireGuardAllowedIPs lists the allowed IPs of the WireGuard interfaces
+// up on this machine, as printed by wg show all allowed-ips. It is a variable
+// so tests can simulate the mesh.
+var localWireGuardAllowedIPs = func() (string, error) {
+	if osType := detectOS(); osType == "windows" {
+		out, err := localWGCommand(osType, "show", "all", "allowed-ips").Output()
+		return string(out), err
+	}
+	out, err := exec.Command("sh", "-c", "wg show all allowed-ips 2>/dev/null || sudo -n wg show all allowed-ips").Output()
+	return string(out), err
+}
+
 var kubeconfigCmd = &cobra.Command{
 	Use:   "kubeconfig",
 	Short: "Get kubeconfig for kubectl access",
 	Long: `Retrieve the kubeconfig file for accessing the Kubernetes cluster.
-The kubeconfig can be printed to stdout or saved to a file.`,
+The kubeconfig can be printed to stdout, saved to a file, or merged into an
+existing kubeconfig.
+
+The API server is rewritten to an address reachable from outside the cluster:
+the cluster's API endpoint, or when there is none or the cluster sits behind
+a bastion, the WireGuard IP of the first control plane node. A warning is
+printed when that address is only reachable over the VPN and this machine is
+not on the mesh.
+
+With --merge, the cluster, user and context are added to ~/.kube/config (or
+the first file of $KUBECONFIG, or --output), replacing entries of the same
+name, and the context becomes the current one.`,
 	Example: `  # Print to stdout
   kubernetes-create kubeconfig
 
@@ -30,19 +59,269 @@ The kubeconfig can be printed to stdout or saved to a file.`,
   kubernetes-create kubeconfig -o ~/.kube/config
 
   # Save to default location
-  kubernetes-create kubeconfig -o ~/.kube/config`,
+  kubernetes-create kubeconfig -o ~/.kube/config
+
+  # Save to the generated files directory
+  kubernetes-create kubeconfig --output-dir ~/clusters/production
+
+  # Name the context, cluster and user "prod-eu" instead of the stack name
+  kubernetes-create kubeconfig --context-name prod-eu -o ~/.kube/prod-eu
+
+  # Add the cluster to ~/.kube/config as context "production"
+  kubernetes-create kubeconfig --stack production --merge`,
 	RunE: runKubeconfig,
 }
 
 func init() {
 	rootCmd.AddCommand(kubeconfigCmd)
 	kubeconfigCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file path (default: stdout)")
-	kubeconfigCmd.Flags().BoolVar(&merge, "merge", false, "Merge with existing kubeconfig (not implemented yet)")
+	kubeconfigCmd.Flags().BoolVar(&merge, "merge", false, "Merge into ~/.kube/config ($KUBECONFIG, or --output when given) and switch to the context")
+	kubeconfigCmd.Flags().StringVar(&kubeconfigContextName, "context-name", "", "Name of the context, cluster and user in the kubeconfig (default: the stack name)")
+	kubeconfigCmd.Flags().StringVar(&kubeconfigServerURL, "server", "", "API server URL to write into the kubeconfig (default: detected from the stack)")
+}
+
+// kubeconfigAPIServer returns the API server a kubeconfig should point at
+// from outside the cluster: the API endpoint when the cluster has one and no
+// bastion, else the WireGuard IP of the first control plane node. It returns
+// "" when neither is known, leaving the server unchanged.
+func kubeconfigAPIServer(outputs auto.OutputMap, nodes []NodeInfo) string {
+	bastionEnabled, _ := getBastionInfo(outputs)
+	if endpoint, ok := outputs["apiEndpoint"]; ok && !bastionEnabled {
+		if value, ok := endpoint.Value.(string); ok && value != "" {
+			return value
+		}
+	}
+	for _, node := range controlPlaneNodes(nodes) {
+		if node.WireGuardIP != "" {
+			return fmt.Sprintf("https://%s:6443", node.WireGuardIP)
+		}
+	}
+	return ""
+}
+
+// setKubeconfigServer points every cluster of a kubeconfig at server
+func setKubeconfigServer(kubeconfig, server string) (string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(kubeconfig), &doc); err != nil {
+		return "", fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return "", fmt.Errorf("kubeconfig is not a YAML mapping")
+	}
+
+	if clusters := mappingValue(doc.Content[0], "clusters"); clusters != nil {
+		for _, entry := range clusters.Content {
+			cluster := mappingValue(entry, "cluster")
+			if cluster == nil {
+				continue
+			}
+			if field := mappingValue(cluster, "server"); field != nil {
+				field.Value = server
+			}
+		}
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal kubeconfig: %w", err)
+	}
+	return string(out), nil
+}
+
+// mergeKubeconfig adds the clusters, users and contexts of incoming to
+// existing, replacing entries of the same name, and makes the current context
+// of incoming the current one. Everything else in existing is kept.
+func mergeKubeconfig(existing, incoming string) (string, error) {
+	var in yaml.Node
+	if err := yaml.Unmarshal([]byte(incoming), &in); err != nil {
+		return "", fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	if len(in.Content) == 0 || in.Content[0].Kind != yaml.MappingNode {
+		return "", fmt.Errorf("kubeconfig is not a YAML mapping")
+	}
+	if strings.TrimSpace(existing) == "" {
+		return incoming, nil
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(existing), &doc); err != nil {
+		return "", fmt.Errorf("failed to parse existing kubeconfig: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return "", fmt.Errorf("existing kubeconfig is not a YAML mapping")
+	}
+	root := doc.Content[0]
+
+	for _, key := range []string{"clusters", "users", "contexts"} {
+		entries := mappingValue(in.Content[0], key)
+		if entries == nil || entries.Kind != yaml.SequenceNode {
+			continue
+		}
+		list := mappingValue(root, key)
+		if list == nil || list.Kind != yaml.SequenceNode {
+			list = &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+			setMappingValue(root, key, list)
+		}
+
+		replaced := make(map[string]bool)
+		for _, entry := range entries.Content {
+			if name := mappingValue(entry, "name"); name != nil {
+				replaced[name.Value] = true
+			}
+		}
+		kept := list.Content[:0]
+		for _, entry := range list.Content {
+			if name := mappingValue(entry, "name"); name == nil || !replaced[name.Value] {
+				kept = append(kept, entry)
+			}
+		}
+		list.Content = append(kept, entries.Content...)
+		// Lists left empty are often written inline as []
+		list.Style = 0
+	}
+
+	if current := mappingValue(in.Content[0], "current-context"); current != nil && current.Value != "" {
+		setMappingValue(root, "current-context", &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: current.Value})
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal kubeconfig: %w", err)
+	}
+	return string(out), nil
+}
+
+// setMappingValue sets key in a YAML mapping, adding it when missing
+func setMappingValue(mapping *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content[i+1] = value
+			return
+		}
+	}
+	mapping.Content = append(mapping.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, value)
+}
+
+// defaultKubeconfigPath returns the kubeconfig kubectl uses by default: the
+// first file of $KUBECONFIG, else ~/.kube/config
+func defaultKubeconfigPath() (string, error) {
+	if paths := filepath.SplitList(os.Getenv("KUBECONFIG")); len(paths) > 0 && paths[0] != "" {
+		return paths[0], nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(home, ".kube", "config"), nil
+}
+
+// meshOnlyServer returns the host of server when it is the WireGuard IP of a
+// node, i.e. only reachable over the VPN, and "" otherwise
+func meshOnlyServer(server string, nodes []NodeInfo) string {
+	host := apiEndpointHost(server)
+	for _, node := range nodes {
+		if host != "" && node.WireGuardIP == host {
+			return host
+		}
+	}
+	return ""
+}
+
+// onWireGuardMesh reports whether a WireGuard interface up on this machine
+// routes to host
+func onWireGuardMesh(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	output, err := localWireGuardAllowedIPs()
+	if err != nil {
+		return false
+	}
+	for _, field := range strings.Fields(output) {
+		if _, cidr, err := net.ParseCIDR(field); err == nil && cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// renameKubeconfig gives the clusters, contexts and users of a kubeconfig the
+// given name, so kubeconfigs of several clusters can be merged without
+// colliding on generic names like "default". References between them and the
+// current context follow the rename; everything else is kept as it is.
+// When the kubeconfig holds several entries of a kind, each keeps its old
+// name as a suffix.
+func renameKubeconfig(kubeconfig, name string) (string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(kubeconfig), &doc); err != nil {
+		return "", fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return "", fmt.Errorf("kubeconfig is not a YAML mapping")
+	}
+	root := doc.Content[0]
+
+	clusters := renameKubeconfigEntries(mappingValue(root, "clusters"), name)
+	users := renameKubeconfigEntries(mappingValue(root, "users"), name)
+	contexts := renameKubeconfigEntries(mappingValue(root, "contexts"), name)
+	if len(clusters) == 0 || len(contexts) == 0 {
+		return "", fmt.Errorf("kubeconfig has no clusters or contexts")
+	}
+
+	for _, entry := range mappingValue(root, "contexts").Content {
+		context := mappingValue(entry, "context")
+		if context == nil {
+			continue
+		}
+		if ref := mappingValue(context, "cluster"); ref != nil && clusters[ref.Value] != "" {
+			ref.Value = clusters[ref.Value]
+		}
+		if ref := mappingValue(context, "user"); ref != nil && users[ref.Value] != "" {
+			ref.Value = users[ref.Value]
+		}
+	}
+	if current := mappingValue(root, "current-context"); current != nil && contexts[current.Value] != "" {
+		current.Value = contexts[current.Value]
+	}
+
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal kubeconfig: %w", err)
+	}
+	return string(out), nil
+}
+
+// renameKubeconfigEntries renames the named entries of a kubeconfig list and
+// returns the new name of each old one
+func renameKubeconfigEntries(list *yaml.Node, name string) map[string]string {
+	renamed := make(map[string]string)
+	if list == nil || list.Kind != yaml.SequenceNode {
+		return renamed
+	}
+	for _, entry := range list.Content {
+		field := mappingValue(entry, "name")
+		if field == nil {
+			continue
+		}
+		newName := name
+		if len(list.Content) > 1 {
+			newName = name + "-" + field.Value
+		}
+		renamed[field.Value] = newName
+		field.Value = newName
+	}
+	return renamed
 }
 
 func runKubeconfig(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	stackName, err := getStackFromArgs(nil, 0)
+	if err != nil {
+		return err
+	}
+
 	s := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
 	s.Suffix = " Retrieving kubeconfig..."
 	s.Start()
@@ -59,7 +338,7 @@ func runKubeconfig(cmd *cobra.Command, args []string) error {
 	stack, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
 	if err != nil {
 		s.Stop()
-		return fmt.Errorf("failed to select stack: %w", err)
+		return selectStackError(err, stackName)
 	}
 
 	// Get outputs
@@ -77,7 +356,48 @@ func runKubeconfig(cmd *cobra.Command, args []string) error {
 
 	s.Stop()
 
-	kubeConfigStr := fmt.Sprintf("%v", kubeConfigOutput.Value)
+	contextName := kubeconfigContextName
+	if contextName == "" {
+		contextName = stackName
+	}
+	kubeConfigStr, err := renameKubeconfig(fmt.Sprintf("%v", kubeConfigOutput.Value), contextName)
+	if err != nil {
+		return err
+	}
+
+	nodes, _ := ParseNodeOutputs(outputs)
+	server := kubeconfigServerURL
+	if server == "" {
+		server = kubeconfigAPIServer(outputs, nodes)
+	}
+	if server != "" {
+		kubeConfigStr, err = setKubeconfigServer(kubeConfigStr, server)
+		if err != nil {
+			return err
+		}
+	}
+	if host := meshOnlyServer(server, nodes); host != "" && !onWireGuardMesh(host) {
+		fmt.Fprintln(os.Stderr, color.YellowString("⚠️  The API server %s is only reachable over the VPN and this machine is not on the mesh", host))
+		fmt.Fprintln(os.Stderr, color.YellowString("   Join it with: sloth-kubernetes vpn join %s", stackName))
+	}
+
+	if merge {
+		target := outputFile
+		if target == "" {
+			if target, err = defaultKubeconfigPath(); err != nil {
+				return err
+			}
+		}
+		return mergeKubeconfigFile(target, kubeConfigStr, contextName)
+	}
+
+	// An explicit --output-dir saves the kubeconfig there instead of printing it
+	if outputFile == "" && cmd.Flags().Changed("output-dir") {
+		outputFile, err = artifactPath(stackName, "kubeconfig")
+		if err != nil {
+			return err
+		}
+	}
 
 	// Output to file or stdout
 	if outputFile != "" {
@@ -110,3 +430,31 @@ func runKubeconfig(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// mergeKubeconfigFile merges kubeconfig into the kubeconfig file at path,
+// creating it when missing
+func mergeKubeconfigFile(path, kubeconfig, contextName string) error {
+	if strings.HasPrefix(path, "~/") {
+		home, _ := os.UserHomeDir()
+		path = filepath.Join(home, path[2:])
+	}
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read kubeconfig: %w", err)
+	}
+
+	merged, err := mergeKubeconfig(string(existing), kubeconfig)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(merged), 0600); err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+
+	printSuccess(fmt.Sprintf("Context %s merged into %s and set as current", contextName, path))
+	return nil
+}
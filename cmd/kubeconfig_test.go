@@ -0,0 +1,251 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"gopkg.in/yaml.v3"
+)
+
+const rke2Kubeconfig = `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: Q0EK
+    server: https://10.8.0.10:6443
+  name: default
+contexts:
+- context:
+    cluster: default
+    user: default
+  name: default
+current-context: default
+kind: Config
+preferences: {}
+users:
+- name: default
+  user:
+    client-certificate-data: Q0VSVAo=
+    client-key-data: S0VZCg==
+`
+
+func TestRenameKubeconfig(t *testing.T) {
+	renamed, err := renameKubeconfig(rke2Kubeconfig, "production")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var kubeconfig struct {
+		CurrentContext string `yaml:"current-context"`
+		Clusters       []struct {
+			Name    string `yaml:"name"`
+			Cluster struct {
+				Server string `yaml:"server"`
+				CA     string `yaml:"certificate-authority-data"`
+			} `yaml:"cluster"`
+		} `yaml:"clusters"`
+		Contexts []struct {
+			Name    string `yaml:"name"`
+			Context struct {
+				Cluster string `yaml:"cluster"`
+				User    string `yaml:"user"`
+			} `yaml:"context"`
+		} `yaml:"contexts"`
+		Users []struct {
+			Name string `yaml:"name"`
+			User struct {
+				Key string `yaml:"client-key-data"`
+			} `yaml:"user"`
+		} `yaml:"users"`
+	}
+	if err := yaml.Unmarshal([]byte(renamed), &kubeconfig); err != nil {
+		t.Fatalf("renamed kubeconfig is not valid YAML: %v", err)
+	}
+
+	if kubeconfig.CurrentContext != "production" {
+		t.Errorf("current-context = %q, want production", kubeconfig.CurrentContext)
+	}
+	if len(kubeconfig.Clusters) != 1 || kubeconfig.Clusters[0].Name != "production" {
+		t.Errorf("clusters = %+v, want one named production", kubeconfig.Clusters)
+	}
+	if len(kubeconfig.Users) != 1 || kubeconfig.Users[0].Name != "production" {
+		t.Errorf("users = %+v, want one named production", kubeconfig.Users)
+	}
+	if len(kubeconfig.Contexts) != 1 {
+		t.Fatalf("contexts = %+v, want one", kubeconfig.Contexts)
+	}
+	context := kubeconfig.Contexts[0]
+	if context.Name != "production" || context.Context.Cluster != "production" || context.Context.User != "production" {
+		t.Errorf("context = %+v, want production everywhere", context)
+	}
+
+	if kubeconfig.Clusters[0].Cluster.Server != "https://10.8.0.10:6443" || kubeconfig.Clusters[0].Cluster.CA != "Q0EK" {
+		t.Errorf("cluster details changed: %+v", kubeconfig.Clusters[0].Cluster)
+	}
+	if kubeconfig.Users[0].User.Key != "S0VZCg==" {
+		t.Errorf("user credentials changed: %+v", kubeconfig.Users[0].User)
+	}
+	if strings.Contains(renamed, "default") {
+		t.Errorf("renamed kubeconfig still mentions default:\n%s", renamed)
+	}
+
+	// The current context resolves to the cluster's API server
+	path := filepath.Join(t.TempDir(), "kubeconfig")
+	if err := os.WriteFile(path, []byte(renamed), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if server, err := kubeconfigServer(path); err != nil || server != "https://10.8.0.10:6443" {
+		t.Errorf("kubeconfigServer() = %q, %v", server, err)
+	}
+}
+
+func TestRenameKubeconfig_SeveralClusters(t *testing.T) {
+	kubeconfig := `clusters:
+- name: a
+  cluster: {server: https://a:6443}
+- name: b
+  cluster: {server: https://b:6443}
+contexts:
+- name: ctx
+  context: {cluster: b, user: admin}
+current-context: ctx
+users:
+- name: admin
+`
+	renamed, err := renameKubeconfig(kubeconfig, "prod")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, want := range []string{"name: prod-a", "name: prod-b", "cluster: prod-b", "user: prod", "current-context: prod"} {
+		if !strings.Contains(renamed, want) {
+			t.Errorf("expected %q in:\n%s", want, renamed)
+		}
+	}
+}
+
+func TestRenameKubeconfig_Invalid(t *testing.T) {
+	if _, err := renameKubeconfig("not: [valid", "prod"); err == nil {
+		t.Error("expected an error for invalid YAML")
+	}
+	if _, err := renameKubeconfig("kind: Config\n", "prod"); err == nil {
+		t.Error("expected an error for a kubeconfig without clusters")
+	}
+}
+
+func TestKubeconfigAPIServer(t *testing.T) {
+	nodes := []NodeInfo{
+		{Name: "worker-1", WireGuardIP: "10.8.0.20", Roles: []string{"worker"}},
+		{Name: "master-2", WireGuardIP: "10.8.0.11", Roles: []string{"master"}},
+		{Name: "master-1", WireGuardIP: "10.8.0.10", Roles: []string{"master"}},
+	}
+	endpoint := auto.OutputMap{"apiEndpoint": {Value: "https://api.example.com:6443"}}
+
+	if server := kubeconfigAPIServer(endpoint, nodes); server != "https://api.example.com:6443" {
+		t.Errorf("Expected the API endpoint, got %q", server)
+	}
+
+	endpoint["bastion_enabled"] = auto.OutputValue{Value: true}
+	if server := kubeconfigAPIServer(endpoint, nodes); server != "https://10.8.0.10:6443" {
+		t.Errorf("Expected the first master's VPN IP behind a bastion, got %q", server)
+	}
+
+	if server := kubeconfigAPIServer(auto.OutputMap{}, nil); server != "" {
+		t.Errorf("Expected no server without nodes, got %q", server)
+	}
+}
+
+func TestSetKubeconfigServer(t *testing.T) {
+	updated, err := setKubeconfigServer(rke2Kubeconfig, "https://api.example.com:6443")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(updated, "server: https://api.example.com:6443") || strings.Contains(updated, "10.8.0.10") {
+		t.Errorf("Expected the server to be replaced:\n%s", updated)
+	}
+	if !strings.Contains(updated, "certificate-authority-data: Q0EK") {
+		t.Errorf("Expected the rest of the kubeconfig to be kept:\n%s", updated)
+	}
+}
+
+func TestMergeKubeconfig(t *testing.T) {
+	existing := `apiVersion: v1
+kind: Config
+clusters:
+- name: staging
+  cluster: {server: https://staging:6443}
+- name: prod
+  cluster: {server: https://old:6443}
+contexts:
+- name: staging
+  context: {cluster: staging, user: staging}
+- name: prod
+  context: {cluster: prod, user: prod}
+current-context: staging
+users:
+- name: staging
+- name: prod
+preferences: {}
+`
+	incoming, err := renameKubeconfig(rke2Kubeconfig, "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	merged, err := mergeKubeconfig(existing, incoming)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var kubeconfig kubeconfigFile
+	if err := yaml.Unmarshal([]byte(merged), &kubeconfig); err != nil {
+		t.Fatal(err)
+	}
+	if kubeconfig.CurrentContext != "prod" {
+		t.Errorf("Expected the merged context to be current, got %q", kubeconfig.CurrentContext)
+	}
+	if len(kubeconfig.Clusters) != 2 || len(kubeconfig.Contexts) != 2 {
+		t.Errorf("Expected prod to be replaced rather than duplicated:\n%s", merged)
+	}
+	if strings.Contains(merged, "https://old:6443") || !strings.Contains(merged, "https://staging:6443") {
+		t.Errorf("Expected only the prod cluster to change:\n%s", merged)
+	}
+}
+
+func TestMergeKubeconfigFile_New(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kube", "config")
+	if err := mergeKubeconfigFile(path, rke2Kubeconfig, "default"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != rke2Kubeconfig {
+		t.Errorf("Expected the kubeconfig written as is, got %q (%v)", data, err)
+	}
+}
+
+func TestOnWireGuardMesh(t *testing.T) {
+	defer func(orig func() (string, error)) { localWireGuardAllowedIPs = orig }(localWireGuardAllowedIPs)
+	nodes := []NodeInfo{{Name: "master-1", WireGuardIP: "10.8.0.10"}}
+
+	host := meshOnlyServer("https://10.8.0.10:6443", nodes)
+	if host != "10.8.0.10" {
+		t.Fatalf("Expected a VPN-only server, got %q", host)
+	}
+	if meshOnlyServer("https://api.example.com:6443", nodes) != "" {
+		t.Error("Expected a public endpoint not to need the VPN")
+	}
+
+	localWireGuardAllowedIPs = func() (string, error) {
+		return "wg0\tc2VydmVy=\t10.8.0.0/24\n", nil
+	}
+	if !onWireGuardMesh(host) {
+		t.Error("Expected an interface routing the VPN subnet to count as on the mesh")
+	}
+
+	localWireGuardAllowedIPs = func() (string, error) { return "", fmt.Errorf("wg: command not found") }
+	if onWireGuardMesh(host) {
+		t.Error("Expected no mesh without WireGuard")
+	}
+}
@@ -61,6 +61,11 @@ func runKubectl(cmd *cobra.Command, args []string) error {
 		os.Setenv("KUBECONFIG", kubeconfigPath)
 	}
 
+	// Fail fast with a hint when the API is only reachable over the VPN
+	if err := ensureAPIReachable(args, kubectlLocalCommands, kubeconfigPath); err != nil {
+		return err
+	}
+
 	// Create the root kubectl command with all subcommands
 	kubectlRootCmd := kubectlcmd.NewDefaultKubectlCommand()
 	kubectlRootCmd.SetArgs(args)
@@ -25,7 +25,12 @@ The backend URL is stored in ~/.sloth-kubernetes/config for future use.
 Example:
   sloth-kubernetes login s3://s3.lady-guica.chalkan3.com.br
   sloth-kubernetes login --bucket s3.lady-guica.chalkan3.com.br
-  sloth-kubernetes login s3://bucket --access-key-id YOUR_KEY --secret-access-key YOUR_SECRET --region us-east-1`,
+  sloth-kubernetes login s3://bucket --access-key-id YOUR_KEY --secret-access-key YOUR_SECRET --region us-east-1
+
+Stack secrets are encrypted with PULUMI_CONFIG_PASSPHRASE unless a cloud KMS
+key is configured with --secrets-provider, so a team does not share a passphrase:
+  sloth-kubernetes login s3://bucket --secrets-provider "awskms://alias/sloth?region=us-east-1"
+  sloth-kubernetes login s3://bucket --secrets-provider gcpkms://projects/p/locations/global/keyRings/r/cryptoKeys/k`,
 	RunE: runLogin,
 }
 
@@ -35,6 +40,7 @@ var (
 	loginSecretAccessKey string
 	loginRegion          string
 	loginEndpoint        string
+	loginSecretsProvider string
 )
 
 func init() {
@@ -44,6 +50,7 @@ func init() {
 	loginCmd.Flags().StringVar(&loginSecretAccessKey, "secret-access-key", "", "AWS Secret Access Key")
 	loginCmd.Flags().StringVar(&loginRegion, "region", "", "AWS Region (e.g., us-east-1)")
 	loginCmd.Flags().StringVar(&loginEndpoint, "endpoint", "", "S3 endpoint URL for S3-compatible storage")
+	loginCmd.Flags().StringVar(&loginSecretsProvider, "secrets-provider", "", "Secrets provider of stacks: passphrase (default), awskms://<key> or gcpkms://<key>")
 }
 
 func runLogin(cmd *cobra.Command, args []string) error {
@@ -57,6 +64,12 @@ func runLogin(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("usage: sloth-kubernetes login [s3://bucket-name]\nExample: sloth-kubernetes login s3://s3.lady-guica.chalkan3.com.br")
 	}
 
+	if loginSecretsProvider != "" {
+		if err := validateSecretsProvider(loginSecretsProvider); err != nil {
+			return err
+		}
+	}
+
 	// Normalize bucket URL - ensure it starts with s3://
 	if !strings.HasPrefix(bucketURL, "s3://") {
 		bucketURL = "s3://" + bucketURL
@@ -97,6 +110,12 @@ func runLogin(cmd *cobra.Command, args []string) error {
 		fmt.Printf("✓ AWS Region configured: %s\n", loginRegion)
 	}
 
+	if loginSecretsProvider != "" {
+		os.Setenv(secretsProviderKey, loginSecretsProvider)
+		config[secretsProviderKey] = loginSecretsProvider
+		fmt.Printf("✓ Secrets provider configured: %s\n", loginSecretsProvider)
+	}
+
 	// For S3-compatible storage (MinIO), construct backend URL with query parameters
 	// Pulumi expects: s3://bucket?endpoint=endpoint-url&s3ForcePathStyle=true&region=region
 	if loginEndpoint != "" {
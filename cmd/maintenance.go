@@ -0,0 +1,350 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/ingress"
+)
+
+// maintenanceReplicasAnnotation records the replica count of a workload
+// scaled down by `maintenance on --scale-down`, so `maintenance off` can
+// restore it
+const maintenanceReplicasAnnotation = "sloth-kubernetes.io/maintenance-replicas"
+
+// maintenanceCriticalNamespaces are never scaled down: they run the cluster
+// itself and the add-ons needed to bring workloads back
+var maintenanceCriticalNamespaces = []string{
+	"kube-system",
+	"kube-public",
+	"kube-node-lease",
+	ingress.ControllerNamespace,
+	"cert-manager",
+	"argocd",
+}
+
+var (
+	maintenanceRole           string
+	maintenanceScaleDown      bool
+	maintenanceKeepNamespaces []string
+)
+
+var maintenanceCmd = &cobra.Command{
+	Use:   "maintenance",
+	Short: "Put the whole cluster in or out of maintenance mode",
+	Long: `Cordon or uncordon every node of a cluster at once, e.g. before a provider
+maintenance window or a bulk upgrade.`,
+}
+
+var maintenanceOnCmd = &cobra.Command{
+	Use:   "on [stack-name]",
+	Short: "Cordon all nodes and report the workloads they run",
+	Long: `Cordon every node of the cluster so no new pods are scheduled, from a
+control-plane node over SSH. The workloads running on the nodes are listed
+before anything changes.
+
+With --scale-down, deployments and statefulsets outside the system namespaces
+(kube-system, ingress-nginx, cert-manager, argocd, ...) are scaled to zero.
+Their replica count is kept in an annotation and restored by 'maintenance off'.`,
+	Example: `  # Cordon every node of production
+  sloth-kubernetes maintenance on production
+
+  # Cordon only the workers and scale application workloads down
+  sloth-kubernetes maintenance on production --role worker --scale-down
+
+  # Keep the monitoring namespace running too
+  sloth-kubernetes maintenance on production --scale-down --keep-namespace monitoring`,
+	RunE: runMaintenanceOn,
+}
+
+var maintenanceOffCmd = &cobra.Command{
+	Use:   "off [stack-name]",
+	Short: "Uncordon all nodes and restore scaled-down workloads",
+	Long: `Uncordon every node of the cluster and scale the workloads 'maintenance on
+--scale-down' stopped back to their previous replica count.`,
+	Example: `  # Leave maintenance mode
+  sloth-kubernetes maintenance off production`,
+	RunE: runMaintenanceOff,
+}
+
+func init() {
+	rootCmd.AddCommand(maintenanceCmd)
+	maintenanceCmd.AddCommand(maintenanceOnCmd)
+	maintenanceCmd.AddCommand(maintenanceOffCmd)
+
+	for _, c := range []*cobra.Command{maintenanceOnCmd, maintenanceOffCmd} {
+		c.Flags().StringVar(&maintenanceRole, "role", "", "Only act on nodes of one role: worker|master")
+	}
+	maintenanceOnCmd.Flags().BoolVar(&maintenanceScaleDown, "scale-down", false, "Scale deployments and statefulsets outside the system namespaces to zero")
+	maintenanceOnCmd.Flags().StringSliceVar(&maintenanceKeepNamespaces, "keep-namespace", nil, "Namespace not to scale down, in addition to the system namespaces (repeatable)")
+}
+
+// maintenanceWorkload is a workload with pods running on the nodes put in
+// maintenance
+type maintenanceWorkload struct {
+	Namespace string
+	Kind      string
+	Name      string
+	Pods      int
+}
+
+// kubeNodeName returns the name a node registers with in Kubernetes: nodes
+// are given their stack name as hostname and join under `hostname -s`
+func kubeNodeName(node NodeInfo) string {
+	name, _, _ := strings.Cut(node.Name, ".")
+	return strings.ToLower(name)
+}
+
+// maintenanceNodes returns the Kubernetes names of the nodes with role, or of
+// all nodes when role is empty, sorted
+func maintenanceNodes(nodes []NodeInfo, role string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, node := range nodes {
+		if role != "" && !config.HasRole(node.Roles, role) {
+			continue
+		}
+		name := kubeNodeName(node)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// cordonScript cordons nodes, or uncordons them when cordon is false
+func cordonScript(nodes []string, cordon bool) string {
+	action := "cordon"
+	if !cordon {
+		action = "uncordon"
+	}
+	return kubectlScriptPrefix + fmt.Sprintf("kubectl %s '%s'\n", action, strings.Join(nodes, "' '"))
+}
+
+// runningPodsScript lists the running pods with their owner and node
+const runningPodsScript = kubectlScriptPrefix + `kubectl get pods -A --field-selector=status.phase=Running -o jsonpath='{range .items[*]}{.metadata.namespace}{"\t"}{.metadata.name}{"\t"}{.metadata.ownerReferences[0].kind}{"\t"}{.metadata.ownerReferences[0].name}{"\t"}{.spec.nodeName}{"\n"}{end}'
+`
+
+// parseMaintenanceWorkloads groups the pods of runningPodsScript output that
+// run on nodes by workload. DaemonSet pods are skipped: they belong to the
+// nodes and are not rescheduled elsewhere. Pods of a ReplicaSet are reported
+// under their Deployment.
+func parseMaintenanceWorkloads(output string, nodes []string) []maintenanceWorkload {
+	onNodes := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		onNodes[node] = true
+	}
+
+	byKey := make(map[string]*maintenanceWorkload)
+	var keys []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		if len(fields) != 5 || !onNodes[fields[4]] {
+			continue
+		}
+		namespace, pod, kind, name := fields[0], fields[1], fields[2], fields[3]
+
+		switch kind {
+		case "DaemonSet":
+			continue
+		case "ReplicaSet":
+			if i := strings.LastIndex(name, "-"); i > 0 {
+				kind, name = "Deployment", name[:i]
+			}
+		case "":
+			kind, name = "Pod", pod
+		}
+
+		key := namespace + "/" + kind + "/" + name
+		if byKey[key] == nil {
+			byKey[key] = &maintenanceWorkload{Namespace: namespace, Kind: kind, Name: name}
+			keys = append(keys, key)
+		}
+		byKey[key].Pods++
+	}
+
+	sort.Strings(keys)
+	workloads := make([]maintenanceWorkload, 0, len(keys))
+	for _, key := range keys {
+		workloads = append(workloads, *byKey[key])
+	}
+	return workloads
+}
+
+// maintenanceKeptNamespaces returns the namespaces scale-down leaves alone
+func maintenanceKeptNamespaces(extra []string) []string {
+	kept := append([]string(nil), maintenanceCriticalNamespaces...)
+	for _, ns := range extra {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			kept = append(kept, ns)
+		}
+	}
+	return kept
+}
+
+// scaleDownScript scales the deployments and statefulsets of every namespace
+// but kept to zero, recording their replica count in an annotation
+func scaleDownScript(kept []string) string {
+	return kubectlScriptPrefix + fmt.Sprintf(`KEEP=" %[1]s "
+for NS in $(kubectl get ns -o jsonpath='{.items[*].metadata.name}'); do
+  case "$KEEP" in *" $NS "*) continue ;; esac
+  for OBJ in $(kubectl -n "$NS" get deploy,statefulset -o name); do
+    REPLICAS=$(kubectl -n "$NS" get "$OBJ" -o jsonpath='{.spec.replicas}')
+    [ "${REPLICAS:-0}" -gt 0 ] || continue
+    kubectl -n "$NS" annotate "$OBJ" --overwrite "%[2]s=$REPLICAS" >/dev/null
+    kubectl -n "$NS" scale "$OBJ" --replicas=0 >/dev/null
+    echo "SCALED:$NS/$OBJ:$REPLICAS"
+  done
+done
+`, strings.Join(kept, " "), maintenanceReplicasAnnotation)
+}
+
+// restoreScript scales the workloads scaleDownScript stopped back up and
+// removes the annotation
+func restoreScript() string {
+	jsonpathKey := strings.ReplaceAll(maintenanceReplicasAnnotation, ".", `\.`)
+	return kubectlScriptPrefix + fmt.Sprintf(`kubectl get deploy,statefulset -A -o jsonpath='{range .items[*]}{.metadata.namespace}{" "}{.kind}{" "}{.metadata.name}{" "}{.metadata.annotations.%[1]s}{"\n"}{end}' |
+while read -r NS KIND NAME REPLICAS; do
+  [ -n "$REPLICAS" ] || continue
+  kubectl -n "$NS" scale "$KIND/$NAME" --replicas="$REPLICAS" >/dev/null
+  kubectl -n "$NS" annotate "$KIND/$NAME" "%[2]s-" >/dev/null
+  echo "RESTORED:$NS/$KIND/$NAME:$REPLICAS"
+done
+`, jsonpathKey, maintenanceReplicasAnnotation)
+}
+
+// printScriptResults prints the lines of output starting with prefix
+func printScriptResults(output, prefix, verb string) int {
+	count := 0
+	for _, line := range strings.Split(output, "\n") {
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), prefix); ok {
+			if i := strings.LastIndex(rest, ":"); i >= 0 {
+				fmt.Printf("  • %s %s (%s replica(s))\n", verb, rest[:i], rest[i+1:])
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// loadMaintenanceTarget loads the stack nodes and returns a reachable
+// control-plane node with the Kubernetes names of the selected nodes
+func loadMaintenanceTarget(ctx context.Context, args []string) (NodeInfo, nodeSSHAccess, []string, error) {
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return NodeInfo{}, nodeSSHAccess{}, nil, err
+	}
+
+	role := ""
+	if maintenanceRole != "" {
+		if err := config.ValidateRoleFilter(maintenanceRole); err != nil {
+			return NodeInfo{}, nodeSSHAccess{}, nil, err
+		}
+		role = config.NormalizeRole(maintenanceRole)
+	}
+
+	printHeader(fmt.Sprintf("🛠️  Maintenance Mode - Stack: %s", stack))
+
+	nodes, access, err := loadStackNodes(ctx, stack)
+	if err != nil {
+		return NodeInfo{}, nodeSSHAccess{}, nil, err
+	}
+	selected := maintenanceNodes(nodes, role)
+	if len(selected) == 0 {
+		return NodeInfo{}, nodeSSHAccess{}, nil, fmt.Errorf("no %s nodes found in stack '%s'", strings.TrimSpace(role+" "), stack)
+	}
+
+	server, err := findReachableNode(controlPlaneNodes(nodes), access)
+	if err != nil {
+		return NodeInfo{}, nodeSSHAccess{}, nil, err
+	}
+	return server, access, selected, nil
+}
+
+func runMaintenanceOn(cmd *cobra.Command, args []string) error {
+	server, access, nodes, err := loadMaintenanceTarget(context.Background(), args)
+	if err != nil {
+		return err
+	}
+
+	output, err := runKubectlScript(server, access, runningPodsScript)
+	if err != nil {
+		return err
+	}
+	workloads := parseMaintenanceWorkloads(output, nodes)
+
+	kept := make(map[string]bool)
+	for _, ns := range maintenanceKeptNamespaces(maintenanceKeepNamespaces) {
+		kept[ns] = true
+	}
+
+	fmt.Println()
+	printInfo(fmt.Sprintf("Nodes to cordon (%d): %s", len(nodes), strings.Join(nodes, ", ")))
+	fmt.Println()
+	if len(workloads) == 0 {
+		printInfo("No workloads are running on these nodes")
+	} else {
+		color.Cyan("Workloads running on these nodes:")
+		for _, w := range workloads {
+			note := ""
+			if maintenanceScaleDown && !kept[w.Namespace] && (w.Kind == "Deployment" || w.Kind == "StatefulSet") {
+				note = " → scaled down"
+			}
+			fmt.Printf("  • %s/%s %s: %d pod(s)%s\n", w.Namespace, strings.ToLower(w.Kind), w.Name, w.Pods, note)
+		}
+	}
+	fmt.Println()
+
+	if !autoApprove && !confirm("Put the cluster in maintenance mode?") {
+		printWarning("Maintenance cancelled")
+		return nil
+	}
+
+	if _, err := runKubectlScript(server, access, cordonScript(nodes, true)); err != nil {
+		return err
+	}
+	printSuccess(fmt.Sprintf("Cordoned %d node(s)", len(nodes)))
+
+	if maintenanceScaleDown {
+		output, err := runKubectlScript(server, access, scaleDownScript(maintenanceKeptNamespaces(maintenanceKeepNamespaces)))
+		if err != nil {
+			return err
+		}
+		scaled := printScriptResults(output, "SCALED:", "scaled down")
+		printSuccess(fmt.Sprintf("Scaled %d workload(s) to zero", scaled))
+	}
+
+	fmt.Println()
+	printInfo("Run 'sloth-kubernetes maintenance off' when the maintenance is over")
+	return nil
+}
+
+func runMaintenanceOff(cmd *cobra.Command, args []string) error {
+	server, access, nodes, err := loadMaintenanceTarget(context.Background(), args)
+	if err != nil {
+		return err
+	}
+
+	if _, err := runKubectlScript(server, access, cordonScript(nodes, false)); err != nil {
+		return err
+	}
+	printSuccess(fmt.Sprintf("Uncordoned %d node(s)", len(nodes)))
+
+	output, err := runKubectlScript(server, access, restoreScript())
+	if err != nil {
+		return err
+	}
+	if restored := printScriptResults(output, "RESTORED:", "restored"); restored > 0 {
+		printSuccess(fmt.Sprintf("Restored %d scaled-down workload(s)", restored))
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMaintenanceNodes(t *testing.T) {
+	nodes := []NodeInfo{
+		{Name: "workers-2", Roles: []string{"worker"}},
+		{Name: "Masters-1", Roles: []string{"controlplane"}},
+		{Name: "workers-1.cluster.local", Roles: []string{"worker"}},
+		{Name: "combined-1", Roles: []string{"master", "worker"}},
+		{Name: "workers-2", Roles: []string{"worker"}},
+	}
+
+	all := maintenanceNodes(nodes, "")
+	if strings.Join(all, ",") != "combined-1,masters-1,workers-1,workers-2" {
+		t.Errorf("Expected every node once by Kubernetes name, got %v", all)
+	}
+
+	workers := maintenanceNodes(nodes, "worker")
+	if strings.Join(workers, ",") != "combined-1,workers-1,workers-2" {
+		t.Errorf("Expected the worker nodes, got %v", workers)
+	}
+
+	masters := maintenanceNodes(nodes, "master")
+	if strings.Join(masters, ",") != "combined-1,masters-1" {
+		t.Errorf("Expected controlplane to match the master role, got %v", masters)
+	}
+}
+
+func TestCordonScript(t *testing.T) {
+	script := cordonScript([]string{"masters-1", "workers-1"}, true)
+	if !strings.HasPrefix(script, kubectlScriptPrefix) {
+		t.Error("Expected the kubeconfig to be set up first")
+	}
+	if !strings.HasSuffix(script, "kubectl cordon 'masters-1' 'workers-1'\n") {
+		t.Errorf("Expected one cordon of every node, got %q", script)
+	}
+
+	script = cordonScript([]string{"workers-1"}, false)
+	if !strings.HasSuffix(script, "kubectl uncordon 'workers-1'\n") {
+		t.Errorf("Expected an uncordon, got %q", script)
+	}
+}
+
+func TestParseMaintenanceWorkloads(t *testing.T) {
+	output := strings.Join([]string{
+		"default\tweb-7d9f8c6b5-abcde\tReplicaSet\tweb-7d9f8c6b5\tworkers-1",
+		"default\tweb-7d9f8c6b5-fghij\tReplicaSet\tweb-7d9f8c6b5\tworkers-2",
+		"default\tweb-7d9f8c6b5-klmno\tReplicaSet\tweb-7d9f8c6b5\tmasters-1",
+		"data\tpostgres-0\tStatefulSet\tpostgres\tworkers-2",
+		"kube-system\tsvclb-traefik-x1\tDaemonSet\tsvclb-traefik\tworkers-1",
+		"default\tdebug\t\t\tworkers-1",
+	}, "\n")
+
+	workloads := parseMaintenanceWorkloads(output, []string{"workers-1", "workers-2"})
+	expected := []maintenanceWorkload{
+		{Namespace: "data", Kind: "StatefulSet", Name: "postgres", Pods: 1},
+		{Namespace: "default", Kind: "Deployment", Name: "web", Pods: 2},
+		{Namespace: "default", Kind: "Pod", Name: "debug", Pods: 1},
+	}
+	if len(workloads) != len(expected) {
+		t.Fatalf("Expected %d workloads, got %+v", len(expected), workloads)
+	}
+	for i := range expected {
+		if workloads[i] != expected[i] {
+			t.Errorf("Workload %d: expected %+v, got %+v", i, expected[i], workloads[i])
+		}
+	}
+}
+
+func TestScaleDownScript(t *testing.T) {
+	script := scaleDownScript(maintenanceKeptNamespaces([]string{"monitoring", " "}))
+	if !strings.Contains(script, `KEEP=" kube-system kube-public kube-node-lease ingress-nginx cert-manager argocd monitoring "`) {
+		t.Errorf("Expected the system and extra namespaces to be kept, got %q", script)
+	}
+	if !strings.Contains(script, maintenanceReplicasAnnotation+"=$REPLICAS") {
+		t.Error("Expected the replica count to be recorded before scaling down")
+	}
+
+	if restore := restoreScript(); !strings.Contains(restore, `annotations.sloth-kubernetes\.io/maintenance-replicas`) {
+		t.Errorf("Expected the restore to read the escaped annotation, got %q", restore)
+	}
+}
@@ -0,0 +1,879 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/tokens"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/workspace"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+	"github.com/chalkan3/sloth-kubernetes/pkg/providers"
+)
+
+// nodeAddSSHInterval and nodeAddSSHAttempts bound the wait for a new node to
+// accept SSH. They are variables so tests do not sleep.
+var (
+	nodeAddSSHInterval = 10 * time.Second
+	nodeAddSSHAttempts = 30
+)
+
+// bastionPeerHost names the bastion among the hosts a peer was added to
+const bastionPeerHost = "bastion"
+
+// nodeAddition is a node planned to be added to a deployed cluster
+type nodeAddition struct {
+	Pool string
+	Node config.NodeConfig
+
+	// Stack is the stack managing the node's cloud resources
+	Stack    string
+	IsServer bool
+}
+
+// nodeAdder performs the steps of adding a node to a running cluster
+type nodeAdder interface {
+	// Create creates the node's cloud resources and returns the node
+	Create(add nodeAddition) (NodeInfo, error)
+	// Bootstrap waits for SSH on node, installs WireGuard and returns the
+	// node's WireGuard public key
+	Bootstrap(node NodeInfo) (string, error)
+	// AddPeer adds node as a peer on every existing node and the bastion. It
+	// returns the hosts it tried to change, also on error.
+	AddPeer(node NodeInfo, publicKey string) ([]string, error)
+	// ConfigureVPN writes node's own WireGuard config and brings it up
+	ConfigureVPN(node NodeInfo, publicKey string) error
+	// Join joins node to the cluster with the existing join token and waits
+	// for it to become Ready
+	Join(node NodeInfo, isServer bool) error
+	// RemovePeer removes the peer from hosts and returns those where that failed
+	RemovePeer(hosts []string, publicKey string) []string
+	// Destroy destroys the node's cloud resources and their stack
+	Destroy(add nodeAddition) error
+	// Record adds node to the stack outputs
+	Record(node NodeInfo) error
+}
+
+// planNodeAddition picks the pool a new node is created from and names the
+// node. Without poolName the first pool with role is used. The node gets the
+// first index past the pool's count that no deployed node uses, so it never
+// collides with a node a later deployment of the pool creates.
+func planNodeAddition(cfg *config.ClusterConfig, deployed []NodeInfo, stack, poolName, role string) (nodeAddition, error) {
+	if poolName == "" {
+		for _, name := range config.SortedPoolNames(cfg.NodePools) {
+			if config.HasRole(cfg.NodePools[name].Roles, role) {
+				poolName = name
+				break
+			}
+		}
+		if poolName == "" {
+			return nodeAddition{}, fmt.Errorf("no node pool with role '%s' in configuration", role)
+		}
+	}
+
+	pool, ok := cfg.NodePools[poolName]
+	if !ok {
+		return nodeAddition{}, fmt.Errorf("node pool '%s' not found in configuration", poolName)
+	}
+
+	names := make(map[string]bool, len(deployed))
+	for _, node := range deployed {
+		names[node.Name] = true
+	}
+
+	name, index := "", pool.Count
+	for ; index <= pool.Count+len(deployed); index++ {
+		candidate, err := config.RenderPoolNodeName(poolName, &pool, index)
+		if err != nil {
+			return nodeAddition{}, err
+		}
+		if !names[candidate] {
+			name = candidate
+			break
+		}
+	}
+	if name == "" {
+		return nodeAddition{}, fmt.Errorf("pool '%s' has no free node name: its name template must include the index", poolName)
+	}
+
+	node := config.NodeConfig{
+		Name:     name,
+		Provider: pool.Provider,
+		Pool:     poolName,
+		Region:   pool.Region,
+		Zone:     pool.PlacedZone(index),
+		Size:     pool.Size,
+		Image:    pool.Image,
+		Roles:    pool.Roles,
+		Labels:   pool.Labels,
+		Taints:   pool.Taints,
+	}
+	node.Monitoring = cfg.NodeMonitoringEnabled(&node)
+
+	return nodeAddition{
+		Pool:     poolName,
+		Node:     node,
+		Stack:    fmt.Sprintf("%s-node-%s", stack, name),
+		IsServer: config.HasRole(pool.Roles, config.RoleMaster),
+	}, nil
+}
+
+// usedVPNIPs returns the VPN addresses taken by the deployed nodes and by any
+// peer in their WireGuard configs, such as nodes added earlier or clients
+func usedVPNIPs(nodes []NodeInfo, states []wgNodeConfig) map[string]bool {
+	used := make(map[string]bool)
+	for _, node := range nodes {
+		if node.WireGuardIP != "" {
+			used[node.WireGuardIP] = true
+		}
+	}
+	for _, state := range states {
+		for _, block := range parseWGPeerBlocks(state.Config) {
+			for _, line := range block.Lines {
+				key, value, ok := strings.Cut(line, "=")
+				if !ok || strings.TrimSpace(key) != "AllowedIPs" {
+					continue
+				}
+				first, _, _ := strings.Cut(value, ",")
+				ip, _, _ := strings.Cut(strings.TrimSpace(first), "/")
+				used[ip] = true
+			}
+		}
+	}
+	return used
+}
+
+// runNodeAddition adds a node step by step. When a step before the node is
+// recorded fails, the peers added so far are removed again and the node is
+// destroyed, so a failed addition leaves the cluster as it was.
+func runNodeAddition(add nodeAddition, a nodeAdder) (NodeInfo, error) {
+	var touched []string
+	publicKey := ""
+
+	rollback := func(cause error) error {
+		printWarning(fmt.Sprintf("Adding %s failed, rolling back: %v", add.Node.Name, cause))
+
+		var leftovers []string
+		if len(touched) > 0 {
+			if failed := a.RemovePeer(touched, publicKey); len(failed) > 0 {
+				leftovers = append(leftovers, fmt.Sprintf("VPN peer %s on %s", add.Node.WireGuardIP, strings.Join(failed, ", ")))
+			}
+		}
+		if err := a.Destroy(add); err != nil {
+			leftovers = append(leftovers, fmt.Sprintf("stack %s (%v)", add.Stack, err))
+		}
+
+		if len(leftovers) > 0 {
+			return fmt.Errorf("%w; rollback incomplete, clean up by hand: %s", cause, strings.Join(leftovers, "; "))
+		}
+		return fmt.Errorf("%w (rolled back)", cause)
+	}
+
+	printInfo(fmt.Sprintf("Step 1/5: Creating %s (%s, %s, %s)...", add.Node.Name, add.Node.Provider, add.Node.Region, add.Node.Size))
+	node, err := a.Create(add)
+	if err != nil {
+		return NodeInfo{}, rollback(fmt.Errorf("failed to create %s: %w", add.Node.Name, err))
+	}
+
+	printInfo("Step 2/5: Installing WireGuard...")
+	publicKey, err = a.Bootstrap(node)
+	if err != nil {
+		return NodeInfo{}, rollback(err)
+	}
+
+	printInfo(fmt.Sprintf("Step 3/5: Adding %s (%s) to the VPN mesh...", node.Name, node.WireGuardIP))
+	touched, err = a.AddPeer(node, publicKey)
+	if err != nil {
+		return NodeInfo{}, rollback(err)
+	}
+	if err := a.ConfigureVPN(node, publicKey); err != nil {
+		return NodeInfo{}, rollback(err)
+	}
+
+	printInfo("Step 4/5: Joining the cluster...")
+	if err := a.Join(node, add.IsServer); err != nil {
+		return NodeInfo{}, rollback(err)
+	}
+
+	printInfo("Step 5/5: Updating stack outputs...")
+	if err := a.Record(node); err != nil {
+		return node, fmt.Errorf("%s joined the cluster but the stack outputs were not updated: %w", node.Name, err)
+	}
+	return node, nil
+}
+
+// meshPeerAddScript adds a cluster node as a peer to a running mesh member
+// and syncs the interface. Unlike a full mesh config it only routes the
+// node's VPN address: WireGuard gives each allowed IP to a single peer, so
+// adding 10.0.0.0/8 here would take that route away from the existing peers.
+func meshPeerAddScript(name, wgIP, publicKey, endpoint string) string {
+	publicKey = strings.ReplaceAll(publicKey, "'", "'\\''")
+	return fmt.Sprintf(`set -e
+sudo cp /etc/wireguard/wg0.conf /etc/wireguard/wg0.conf.backup-$(date +%%Y%%m%%d-%%H%%M%%S)
+
+if ! sudo grep -qF 'PublicKey = %s' /etc/wireguard/wg0.conf; then
+sudo tee -a /etc/wireguard/wg0.conf > /dev/null << 'WGEOF'
+
+[Peer]
+# %s (%s)
+PublicKey = %s
+AllowedIPs = %s/32
+Endpoint = %s:%d
+PersistentKeepalive = 25
+WGEOF
+fi
+
+sudo wg-quick strip wg0 | sudo wg syncconf wg0 /dev/stdin
+
+if ! { %s; }; then
+    echo "Peer is not in both /etc/wireguard/wg0.conf and the running interface" >&2
+    exit 1
+fi
+`, publicKey, name, wgIP, publicKey, wgIP, endpoint, wgPort, peerStateCheck("sudo ", publicKey, true))
+}
+
+// newNodeMeshConfig returns the wg0.conf of a node joining the mesh: every
+// existing node as a peer, routed as the deployment routes them, plus the
+// bastion's peer section copied from an existing node when bastionKey is set
+func newNodeMeshConfig(node NodeInfo, publicKey string, states []wgNodeConfig, subnet *config.WireGuardSubnet, bastionKey string, routing meshRouting) string {
+	all := append([]wgNodeConfig{}, states...)
+	all = append(all, wgNodeConfig{Node: node, PublicKey: publicKey})
+	conf := buildMeshConfigs(all, subnet, routing)[node.Name]
+
+	// A spoke reaches the bastion through the hub
+	if bastionKey == "" || routing.hub != nil {
+		return conf
+	}
+	for _, state := range states {
+		for _, block := range parseWGPeerBlocks(state.Config) {
+			if block.PublicKey == bastionKey {
+				return conf + "\n[Peer]\n" + strings.Join(block.Lines, "\n") + "\n"
+			}
+		}
+	}
+	return conf
+}
+
+// nodeWireGuardBootstrapScript installs WireGuard on a new node, creates its
+// key pair and prints the public key
+const nodeWireGuardBootstrapScript = `set -e
+cloud-init status --wait > /dev/null 2>&1 || true
+if ! command -v wg > /dev/null 2>&1; then
+    apt-get update -qq
+    DEBIAN_FRONTEND=noninteractive apt-get install -y -qq wireguard wireguard-tools > /dev/null
+fi
+mkdir -p /etc/wireguard
+if [ ! -f /etc/wireguard/privatekey ]; then
+    umask 077
+    wg genkey | tee /etc/wireguard/privatekey | wg pubkey > /etc/wireguard/publickey
+fi
+cat /etc/wireguard/publickey
+`
+
+// rke2NodeJoinScript writes the RKE2 config of a node joining through server,
+// installs RKE2 and starts it. The node registers with its VPN IP, like the
+// nodes created by the deployment, and is labelled with its region and zone.
+// Servers also get the admission configuration file their config names.
+func rke2NodeJoinScript(rke2 *config.RKE2Config, k8s *config.KubernetesConfig, security *config.SecurityConfig, isServer bool, node NodeInfo, server string) (string, error) {
+	labelled := *rke2
+	labelled.NodeLabel = append(append([]string{}, rke2.NodeLabel...), config.TopologyLabels(node.Region, node.Zone)...)
+	rke2 = &labelled
+
+	service := "rke2-agent"
+	rke2Config := config.BuildRKE2AgentConfig(rke2, node.WireGuardIP, node.Name, server, k8s)
+	admissionSetup := ""
+	if isServer {
+		service = "rke2-server"
+		rke2Config = config.BuildRKE2ServerConfig(rke2, node.WireGuardIP, node.Name, false, server, k8s)
+
+		var err error
+		if admissionSetup, err = config.WriteAdmissionConfigCommand(&k8s.Admission, config.AdmissionConfigPath); err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf(`set -e
+mkdir -p /etc/rancher/rke2
+cat > /etc/rancher/rke2/config.yaml << 'RKE2EOF'
+%sRKE2EOF
+chmod 600 /etc/rancher/rke2/config.yaml
+%s
+%s
+systemctl enable --now %s.service
+`, rke2Config, admissionSetup, config.GetRKE2InstallCommand(rke2, isServer, security), service), nil
+}
+
+// k3sNodeJoinScript installs K3s on a node joining through server, which
+// also starts it, with the flags the deployment gives its nodes: the node
+// registers with its VPN IP, runs flannel over WireGuard and is labelled with
+// its region and zone. Servers also get the admission configuration file
+// their flags name.
+func k3sNodeJoinScript(k8s *config.KubernetesConfig, security *config.SecurityConfig, isServer bool, node NodeInfo, server string) (string, error) {
+	nodeArgs := fmt.Sprintf(" --node-name=%s --node-ip=%s --node-external-ip=%s --flannel-iface=wg0", node.Name, node.WireGuardIP, node.PublicIP) +
+		config.K3sKubeletArgs(k8s) + config.K3sTopologyArgs(node.Region, node.Zone)
+
+	exec := "agent" + nodeArgs
+	admissionSetup := ""
+	if isServer {
+		exec = fmt.Sprintf("server%s --advertise-address=%s --tls-san=%s --tls-san=%s --tls-san=127.0.0.1 --write-kubeconfig-mode=644 --disable=traefik%s",
+			nodeArgs, node.WireGuardIP, node.WireGuardIP, node.PublicIP, config.K3sServerArgs(k8s))
+
+		var err error
+		if admissionSetup, err = config.WriteAdmissionConfigCommand(&k8s.Admission, config.K3sAdmissionConfigPath); err != nil {
+			return "", err
+		}
+	}
+
+	env := fmt.Sprintf(`K3S_URL=https://%s:6443 K3S_TOKEN='%s' INSTALL_K3S_EXEC="%s"`, server, k8s.RKE2.ClusterToken, exec)
+	return fmt.Sprintf(`set -e
+%s
+%s
+`, admissionSetup, config.K3sInstallCommand(k8s.RKE2, security, env)), nil
+}
+
+// addNodeOutput adds node to a "nodes" output map under the first free
+// node_N key
+func addNodeOutput(nodes map[string]interface{}, node NodeInfo) {
+	key := ""
+	for i := 0; key == ""; i++ {
+		if _, ok := nodes[fmt.Sprintf("node_%d", i)]; !ok {
+			key = fmt.Sprintf("node_%d", i)
+		}
+	}
+
+	roles := make([]interface{}, len(node.Roles))
+	for i, role := range node.Roles {
+		roles[i] = role
+	}
+	nodes[key] = map[string]interface{}{
+		"name":       node.Name,
+		"public_ip":  node.PublicIP,
+		"private_ip": node.PrivateIP,
+		"vpn_ip":     node.WireGuardIP,
+		"provider":   node.Provider,
+		"region":     node.Region,
+		"zone":       node.Zone,
+		"size":       node.Size,
+		"roles":      roles,
+		"status":     node.Status,
+		"stack":      node.Stack,
+		"pool":       node.Pool,
+	}
+}
+
+// updateDeploymentNodes applies update to the "nodes" output of the root
+// stack resource in an exported deployment and keeps node_count in line.
+// Numbers are kept as they are, so the rest of the state is unchanged.
+func updateDeploymentNodes(raw json.RawMessage, update func(nodes map[string]interface{})) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var deployment map[string]interface{}
+	if err := decoder.Decode(&deployment); err != nil {
+		return nil, fmt.Errorf("failed to parse deployment: %w", err)
+	}
+
+	resources, _ := deployment["resources"].([]interface{})
+	for _, r := range resources {
+		resource, ok := r.(map[string]interface{})
+		if !ok || resource["type"] != "pulumi:pulumi:Stack" {
+			continue
+		}
+
+		outputs, _ := resource["outputs"].(map[string]interface{})
+		if outputs == nil {
+			outputs = make(map[string]interface{})
+			resource["outputs"] = outputs
+		}
+		nodes, _ := outputs["nodes"].(map[string]interface{})
+		if nodes == nil {
+			nodes = make(map[string]interface{})
+			outputs["nodes"] = nodes
+		}
+
+		update(nodes)
+		if _, ok := outputs["node_count"]; ok {
+			outputs["node_count"] = len(nodes)
+		}
+		return json.Marshal(deployment)
+	}
+	return nil, fmt.Errorf("root stack resource not found in deployment")
+}
+
+// updateStackNodes rewrites the "nodes" output of a stack in its state, for
+// commands that change the cluster's nodes outside a deployment
+func updateStackNodes(ctx context.Context, stack auto.Stack, update func(nodes map[string]interface{})) error {
+	deployment, err := stack.Export(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to export stack: %w", err)
+	}
+
+	updated, err := updateDeploymentNodes(deployment.Deployment, update)
+	if err != nil {
+		return err
+	}
+	deployment.Deployment = updated
+
+	if err := stack.Import(ctx, deployment); err != nil {
+		return fmt.Errorf("failed to import modified state: %w", err)
+	}
+	return nil
+}
+
+// clusterSSHPublicKey returns the authorized_keys line of the cluster's SSH key
+func clusterSSHPublicKey(keyPath string) (string, error) {
+	pemBytes, err := os.ReadFile(keyPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read SSH key: %w", err)
+	}
+	signer, err := ssh.ParsePrivateKey(pemBytes)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse SSH key %s: %w", keyPath, err)
+	}
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey()))), nil
+}
+
+// nodeAddProviders are the providers whose CreateNode can create a node in a
+// stack of its own. Other providers need the network their deployment
+// creates first.
+var nodeAddProviders = []string{"digitalocean", "linode", "vultr"}
+
+// checkNodeAddProvider refuses a provider nodes add cannot create nodes on
+func checkNodeAddProvider(provider string) error {
+	for _, supported := range nodeAddProviders {
+		if provider == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("nodes add does not support provider '%s' (supported: %s): raise the pool's count and run deploy instead",
+		provider, strings.Join(nodeAddProviders, ", "))
+}
+
+// nodeProgramConfig returns a copy of cfg whose providers authorize the
+// cluster's SSH key, leaving cfg itself untouched
+func nodeProgramConfig(cfg *config.ClusterConfig, sshPublicKey string) *config.ClusterConfig {
+	nodeCfg := *cfg
+	if do := cfg.Providers.DigitalOcean; do != nil {
+		doCopy := *do
+		doCopy.SSHPublicKey = sshPublicKey
+		nodeCfg.Providers.DigitalOcean = &doCopy
+	}
+	if linode := cfg.Providers.Linode; linode != nil {
+		linodeCopy := *linode
+		linodeCopy.AuthorizedKeys = append(append([]string{}, linode.AuthorizedKeys...), sshPublicKey)
+		nodeCfg.Providers.Linode = &linodeCopy
+	}
+	if vultr := cfg.Providers.Vultr; vultr != nil {
+		vultrCopy := *vultr
+		vultrCopy.SSHPublicKey = sshPublicKey
+		nodeCfg.Providers.Vultr = &vultrCopy
+	}
+	return &nodeCfg
+}
+
+// nodeStackProgram creates a single node with its provider's CreateNode,
+// authorized for the cluster's SSH key. The program runs on every preview,
+// up and retry, so it works on its own copy of the config.
+func nodeStackProgram(cfg *config.ClusterConfig, node config.NodeConfig, sshPublicKey string) pulumi.RunFunc {
+	return func(ctx *pulumi.Context) error {
+		nodeCfg := nodeProgramConfig(cfg, sshPublicKey)
+
+		provider, err := providers.NewProviderFactory().GetProvider(node.Provider)
+		if err != nil {
+			return err
+		}
+		if err := provider.Initialize(ctx, nodeCfg); err != nil {
+			return fmt.Errorf("failed to initialize provider %s: %w", node.Provider, err)
+		}
+
+		output, err := provider.CreateNode(ctx, &node)
+		if err != nil {
+			return err
+		}
+		ctx.Export("public_ip", output.PublicIP)
+		ctx.Export("private_ip", output.PrivateIP)
+		return nil
+	}
+}
+
+// selectNodeStack creates or selects the stack managing one added node
+func selectNodeStack(ctx context.Context, stackName string, program pulumi.RunFunc, cfg *config.ClusterConfig) (auto.Stack, error) {
+	projectName := "sloth-kubernetes"
+	workspaceOpts := []auto.LocalWorkspaceOption{
+		auto.Program(program),
+		auto.Project(workspace.Project{
+			Name:    tokens.PackageName(projectName),
+			Runtime: workspace.NewProjectRuntimeInfo("go", nil),
+		}),
+	}
+
+	backendOpts, err := newStateBackendOptions()
+	if err != nil {
+		return auto.Stack{}, err
+	}
+	workspaceOpts = append(workspaceOpts, backendOpts...)
+
+	ws, err := auto.NewLocalWorkspace(ctx, workspaceOpts...)
+	if err != nil {
+		return auto.Stack{}, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	stack, err := auto.UpsertStack(ctx, fmt.Sprintf("organization/%s/%s", projectName, stackName), ws)
+	if err != nil {
+		return auto.Stack{}, fmt.Errorf("failed to create or select stack: %w", err)
+	}
+
+	// The node's provider reads its token from the provider config, as in
+	// deploy; Vultr's provider takes its API key from the cluster config
+	configs := auto.ConfigMap{}
+	if do := cfg.Providers.DigitalOcean; do != nil && do.Token != "" {
+		configs["digitalocean:token"] = auto.ConfigValue{Value: do.Token, Secret: true}
+	}
+	if linode := cfg.Providers.Linode; linode != nil && linode.Token != "" {
+		configs["linode:token"] = auto.ConfigValue{Value: linode.Token, Secret: true}
+	}
+	if err := stack.SetAllConfig(ctx, configs); err != nil {
+		return auto.Stack{}, fmt.Errorf("failed to set stack config: %w", err)
+	}
+	return stack, nil
+}
+
+// stackNodeAdder adds a node in its own stack and changes the running cluster
+// over SSH
+type stackNodeAdder struct {
+	ctx    context.Context
+	cfg    *config.ClusterConfig
+	stack  auto.Stack
+	access nodeSSHAccess
+
+	// nodes are the deployed nodes, states their WireGuard keys and configs
+	nodes      []NodeInfo
+	states     []wgNodeConfig
+	subnet     *config.WireGuardSubnet
+	bastionKey string
+
+	// nodeStack is the stack of the added node, once created
+	nodeStack *auto.Stack
+}
+
+// publicOnly returns node with only its public address, which is how the
+// node is reached (through the bastion if there is one) until it is in the mesh
+func publicOnly(node NodeInfo) NodeInfo {
+	return NodeInfo{Name: node.Name, Provider: node.Provider, PublicIP: node.PublicIP}
+}
+
+// runAsRoot pipes script to bash as root on node, reached on its public IP
+func (a *stackNodeAdder) runAsRoot(node NodeInfo, script string) ([]byte, error) {
+	user := getSSHUserForNode(node.Provider)
+	return sshRunner(a.access.args(publicOnly(node), user, 30, "sudo", "bash", "-s"), script)
+}
+
+func (a *stackNodeAdder) Create(add nodeAddition) (NodeInfo, error) {
+	sshPublicKey, err := clusterSSHPublicKey(a.access.KeyPath)
+	if err != nil {
+		return NodeInfo{}, err
+	}
+
+	stack, err := selectNodeStack(a.ctx, add.Stack, nodeStackProgram(a.cfg, add.Node, sshPublicKey), a.cfg)
+	if err != nil {
+		return NodeInfo{}, err
+	}
+	a.nodeStack = &stack
+
+	res, err := stack.Up(a.ctx, optup.ProgressStreams(pulumiProgress()))
+	if err != nil {
+		return NodeInfo{}, stackLockedError(err, "nodes add", add.Stack)
+	}
+
+	publicIP, _ := res.Outputs["public_ip"].Value.(string)
+	privateIP, _ := res.Outputs["private_ip"].Value.(string)
+	if publicIP == "" {
+		return NodeInfo{}, fmt.Errorf("%s has no public IP in stack %s", add.Node.Name, add.Stack)
+	}
+
+	return NodeInfo{
+		Name:        add.Node.Name,
+		Provider:    add.Node.Provider,
+		Region:      add.Node.Region,
+		Zone:        add.Node.Zone,
+		Size:        add.Node.Size,
+		PublicIP:    publicIP,
+		PrivateIP:   privateIP,
+		WireGuardIP: add.Node.WireGuardIP,
+		Roles:       add.Node.Roles,
+		Status:      "active",
+		Stack:       add.Stack,
+		Pool:        add.Pool,
+	}, nil
+}
+
+func (a *stackNodeAdder) Bootstrap(node NodeInfo) (string, error) {
+	user := getSSHUserForNode(node.Provider)
+	reachable := false
+	for attempt := 0; attempt < nodeAddSSHAttempts && !reachable; attempt++ {
+		if attempt > 0 {
+			time.Sleep(nodeAddSSHInterval)
+		}
+		_, err := sshRunner(a.access.args(publicOnly(node), user, 10, "true"), "")
+		reachable = err == nil
+	}
+	if !reachable {
+		return "", errs.Mark(fmt.Errorf("%s (%s) did not accept SSH", node.Name, node.PublicIP), errs.ErrNodeUnreachable)
+	}
+
+	output, err := a.runAsRoot(node, nodeWireGuardBootstrapScript)
+	if err != nil {
+		return "", fmt.Errorf("failed to install WireGuard on %s: %v (output: %s)", node.Name, err, strings.TrimSpace(string(output)))
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	publicKey := strings.TrimSpace(lines[len(lines)-1])
+	if key, err := base64.StdEncoding.DecodeString(publicKey); err != nil || len(key) != 32 {
+		return "", fmt.Errorf("invalid WireGuard public key from %s: %q", node.Name, publicKey)
+	}
+	return publicKey, nil
+}
+
+func (a *stackNodeAdder) AddPeer(node NodeInfo, publicKey string) ([]string, error) {
+	script := meshPeerAddScript(node.Name, node.WireGuardIP, publicKey, node.PublicIP)
+
+	nodes := sortNodesByName(a.nodes)
+	failures := make([]string, len(nodes))
+	runParallel(len(nodes), a.access.concurrency(vpnConcurrency), func(i int) {
+		if output, err := a.access.runScript(nodes[i], 10, script); err != nil {
+			failures[i] = fmt.Sprintf("%s (%v: %s)", nodes[i].Name, err, strings.TrimSpace(string(output)))
+		}
+	})
+
+	touched := make([]string, 0, len(nodes)+1)
+	var failed []string
+	for i, n := range nodes {
+		touched = append(touched, n.Name)
+		if failures[i] != "" {
+			failed = append(failed, failures[i])
+		} else {
+			printSuccess(fmt.Sprintf("  ✓ %s", n.Name))
+		}
+	}
+
+	if a.access.viaBastion() {
+		touched = append(touched, bastionPeerHost)
+		if output, err := sshRunner(a.access.bastionArgs(10, "bash", "-s"), script); err != nil {
+			failed = append(failed, fmt.Sprintf("bastion (%v: %s)", err, strings.TrimSpace(string(output))))
+		} else {
+			printSuccess("  ✓ bastion")
+		}
+	}
+
+	if len(failed) > 0 {
+		return touched, fmt.Errorf("failed to add the peer on: %s", strings.Join(failed, ", "))
+	}
+	return touched, nil
+}
+
+func (a *stackNodeAdder) ConfigureVPN(node NodeInfo, publicKey string) error {
+	conf := newNodeMeshConfig(node, publicKey, a.states, a.subnet, a.bastionKey, newMeshRouting(a.cfg))
+	if output, err := a.runAsRoot(node, generateMeshApplyScript(conf)); err != nil {
+		return fmt.Errorf("failed to configure WireGuard on %s: %v (output: %s)", node.Name, err, strings.TrimSpace(string(output)))
+	}
+	printSuccess(fmt.Sprintf("  ✓ %s is in the mesh as %s", node.Name, node.WireGuardIP))
+	return nil
+}
+
+func (a *stackNodeAdder) Join(node NodeInfo, isServer bool) error {
+	server, err := findReachableNode(controlPlaneNodes(a.nodes), a.access)
+	if err != nil {
+		return err
+	}
+	details, err := fetchJoinDetails(server, a.access)
+	if err != nil {
+		return err
+	}
+
+	// The node runs the version the cluster runs, not the config's default
+	rke2 := config.MergeRKE2Config(a.cfg.Kubernetes.RKE2, a.cfg.Kubernetes.Version)
+	if details.Version != "" {
+		rke2.Version = details.Version
+	}
+	rke2.ClusterToken = details.Token
+
+	var script string
+	if details.Distribution == distributionK3s {
+		k8s := a.cfg.Kubernetes
+		k8s.RKE2 = rke2
+		script, err = k3sNodeJoinScript(&k8s, &a.cfg.Security, isServer, node, joinServerAddress(server))
+	} else {
+		script, err = rke2NodeJoinScript(rke2, &a.cfg.Kubernetes, &a.cfg.Security, isServer, node, joinServerAddress(server))
+	}
+	if err != nil {
+		return err
+	}
+	if output, err := a.runAsRoot(node, script); err != nil {
+		return fmt.Errorf("%s join failed on %s: %v (output: %s)", details.Distribution, node.Name, err, strings.TrimSpace(string(output)))
+	}
+
+	return stackNodeReplacer{ctx: a.ctx, stack: a.stack, stackName: a.access.Stack, server: server, access: a.access}.WaitReady(node.Name)
+}
+
+func (a *stackNodeAdder) RemovePeer(hosts []string, publicKey string) []string {
+	var nodes, failed []string
+	for _, host := range hosts {
+		if host != bastionPeerHost {
+			nodes = append(nodes, host)
+			continue
+		}
+		output, err := sshRunner(a.access.bastionArgs(10, peerRemoveCommand(publicKey)), "")
+		if err != nil || strings.TrimSpace(string(output)) != "SUCCESS" {
+			failed = append(failed, host)
+		}
+	}
+	return append(failed, rollbackPeerAdd(nodes, a.nodes, a.access, publicKey)...)
+}
+
+func (a *stackNodeAdder) Destroy(add nodeAddition) error {
+	if a.nodeStack == nil {
+		return nil
+	}
+	if _, err := a.nodeStack.Destroy(a.ctx, optdestroy.ProgressStreams(pulumiProgress())); err != nil {
+		return stackLockedError(err, "destroy", add.Stack)
+	}
+	return a.nodeStack.Workspace().RemoveStack(a.ctx, a.nodeStack.Name())
+}
+
+func (a *stackNodeAdder) Record(node NodeInfo) error {
+	return updateStackNodes(a.ctx, a.stack, func(nodes map[string]interface{}) {
+		addNodeOutput(nodes, node)
+	})
+}
+
+func runAddNode(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	isServer, err := parseJoinRole(addNodeRole)
+	if err != nil {
+		return err
+	}
+	role := config.RoleWorker
+	if isServer {
+		role = config.RoleMaster
+	}
+
+	configFile := cfgFile
+	if configFile == "" {
+		configFile = "./cluster-config.yaml"
+	}
+	cfg, err := config.LoadFromYAML(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config file: %w", err)
+	}
+
+	printHeader(fmt.Sprintf("➕ Adding node to stack: %s", stack))
+
+	workspace, err := createWorkspaceWithS3Support(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+	s, err := auto.SelectStack(ctx, fmt.Sprintf("organization/sloth-kubernetes/%s", stack), workspace)
+	if err != nil {
+		return selectStackError(err, stack)
+	}
+	outputs, err := stackOutputsWithRetry(ctx, s)
+	if err != nil {
+		return err
+	}
+	nodes, err := ParseNodeOutputs(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to parse nodes: %w", err)
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("no nodes found in stack - cluster may not be deployed yet")
+	}
+
+	add, err := planNodeAddition(cfg, nodes, stack, addNodePool, role)
+	if err != nil {
+		return err
+	}
+	if err := checkNodeAddProvider(add.Node.Provider); err != nil {
+		return err
+	}
+	if cfg.Network.WireGuard.HubMode() {
+		return fmt.Errorf("nodes add does not register spokes on an existing WireGuard hub: raise the pool's count and run deploy instead")
+	}
+
+	access := newNodeSSHAccess(stack, outputs)
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
+	}
+
+	// Every node has to take the new peer, so nothing is created unless all
+	// nodes can be read
+	printInfo("Reading WireGuard keys and configuration from cluster nodes...")
+	states, unreadable := readWGNodeConfigs(nodes, access)
+	if len(unreadable) > 0 {
+		return errs.Mark(fmt.Errorf("cannot add a node to the mesh without reaching every node; failed to read: %s", strings.Join(unreadable, ", ")), errs.ErrNodeUnreachable)
+	}
+
+	bastionKey := ""
+	if access.viaBastion() {
+		output, err := sshRunner(access.bastionArgs(10, "cat /etc/wireguard/publickey"), "")
+		if err != nil {
+			return fmt.Errorf("failed to read the bastion's WireGuard key: %w", err)
+		}
+		bastionKey = strings.TrimSpace(string(output))
+	}
+
+	subnet := vpnSubnet(outputs)
+	add.Node.WireGuardIP, err = nextFreeVPNIP(usedVPNIPs(nodes, states), subnet, config.WireGuardFirstNodeHost, config.WireGuardLastNodeHost)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	printInfo(fmt.Sprintf("Pool '%s' (%s, %s, %s): new node %s with VPN IP %s", add.Pool, add.Node.Provider, add.Node.Region, add.Node.Size, add.Node.Name, add.Node.WireGuardIP))
+	if add.IsServer {
+		if masters := len(controlPlaneNodes(nodes)) + 1; masters%2 == 0 {
+			printWarning(fmt.Sprintf("The cluster will have %d control plane nodes: etcd tolerates no more failures than with %d", masters, masters-1))
+		}
+	}
+	if !autoApprove && !confirm(fmt.Sprintf("Create %s and join it to the cluster?", add.Node.Name)) {
+		printWarning("Node addition cancelled")
+		return nil
+	}
+
+	fmt.Println()
+	adder := &stackNodeAdder{
+		ctx:        ctx,
+		cfg:        cfg,
+		stack:      s,
+		access:     access,
+		nodes:      nodes,
+		states:     states,
+		subnet:     subnet,
+		bastionKey: bastionKey,
+	}
+	node, err := runNodeAddition(add, adder)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	printSuccess(fmt.Sprintf("%s joined the cluster (public IP %s, VPN IP %s)", node.Name, node.PublicIP, node.WireGuardIP))
+	printInfo("VPN clients reach the node once their config is regenerated with 'vpn join'")
+	return nil
+}
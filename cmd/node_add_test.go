@@ -0,0 +1,345 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+// fakeNodeAdder records the steps of a node addition and fails the step
+// named in failAt
+type fakeNodeAdder struct {
+	failAt string
+	calls  []string
+}
+
+func (a *fakeNodeAdder) step(name string) error {
+	a.calls = append(a.calls, name)
+	if name == a.failAt {
+		return errors.New(name + " failed")
+	}
+	return nil
+}
+
+func (a *fakeNodeAdder) Create(add nodeAddition) (NodeInfo, error) {
+	return NodeInfo{Name: add.Node.Name, WireGuardIP: add.Node.WireGuardIP}, a.step("create")
+}
+
+func (a *fakeNodeAdder) Bootstrap(node NodeInfo) (string, error) {
+	return "newkey", a.step("bootstrap")
+}
+
+func (a *fakeNodeAdder) AddPeer(node NodeInfo, publicKey string) ([]string, error) {
+	return []string{"masters-1", "workers-1"}, a.step("peer")
+}
+
+func (a *fakeNodeAdder) ConfigureVPN(node NodeInfo, publicKey string) error {
+	return a.step("vpn")
+}
+
+func (a *fakeNodeAdder) Join(node NodeInfo, isServer bool) error {
+	return a.step("join")
+}
+
+func (a *fakeNodeAdder) RemovePeer(hosts []string, publicKey string) []string {
+	a.calls = append(a.calls, "unpeer "+publicKey+" "+strings.Join(hosts, ","))
+	return nil
+}
+
+func (a *fakeNodeAdder) Destroy(add nodeAddition) error {
+	return a.step("destroy")
+}
+
+func (a *fakeNodeAdder) Record(node NodeInfo) error {
+	return a.step("record")
+}
+
+func TestRunNodeAddition(t *testing.T) {
+	add := nodeAddition{Node: config.NodeConfig{Name: "workers-3", WireGuardIP: "10.8.0.13"}, Stack: "prod-node-workers-3"}
+
+	tests := []struct {
+		failAt string
+		calls  []string
+	}{
+		{"", []string{"create", "bootstrap", "peer", "vpn", "join", "record"}},
+		{"create", []string{"create", "destroy"}},
+		{"bootstrap", []string{"create", "bootstrap", "destroy"}},
+		{"peer", []string{"create", "bootstrap", "peer", "unpeer newkey masters-1,workers-1", "destroy"}},
+		{"join", []string{"create", "bootstrap", "peer", "vpn", "join", "unpeer newkey masters-1,workers-1", "destroy"}},
+		{"record", []string{"create", "bootstrap", "peer", "vpn", "join", "record"}},
+	}
+
+	for _, tt := range tests {
+		t.Run("fail at "+tt.failAt, func(t *testing.T) {
+			adder := &fakeNodeAdder{failAt: tt.failAt}
+			_, err := runNodeAddition(add, adder)
+			if (err != nil) != (tt.failAt != "") {
+				t.Errorf("Expected error only when a step fails, got %v", err)
+			}
+			if !reflect.DeepEqual(adder.calls, tt.calls) {
+				t.Errorf("Expected steps %v, got %v", tt.calls, adder.calls)
+			}
+		})
+	}
+}
+
+func TestPlanNodeAddition(t *testing.T) {
+	cfg := &config.ClusterConfig{
+		NodePools: map[string]config.NodePool{
+			"masters":  {Provider: "linode", Region: "us-east", Count: 3, Size: "g6-standard-2", Roles: []string{"master"}},
+			"workers":  {Provider: "digitalocean", Region: "nyc3", Count: 2, Size: "s-2vcpu-4gb", Roles: []string{"worker"}},
+			"workers2": {Provider: "linode", Region: "us-east", Count: 1, Roles: []string{"worker"}},
+		},
+	}
+	deployed := []NodeInfo{{Name: "workers-1"}, {Name: "workers-2"}, {Name: "workers-3"}}
+
+	add, err := planNodeAddition(cfg, deployed, "prod", "", config.RoleWorker)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if add.Pool != "workers" || add.Node.Name != "workers-4" || add.Node.Provider != "digitalocean" || add.IsServer {
+		t.Errorf("Expected worker workers-4 from pool workers, got %+v", add)
+	}
+	if add.Stack != "prod-node-workers-4" {
+		t.Errorf("Expected stack prod-node-workers-4, got %s", add.Stack)
+	}
+
+	add, err = planNodeAddition(cfg, deployed, "prod", "masters", config.RoleWorker)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if add.Node.Name != "masters-4" || !add.IsServer {
+		t.Errorf("Expected server masters-4, got %+v", add)
+	}
+
+	cfg.NodePools["azure"] = config.NodePool{Provider: "azure", Region: "eastus", Count: 2, Zones: []string{"1", "2", "3"}, Roles: []string{"worker"}}
+	add, err = planNodeAddition(cfg, deployed, "prod", "azure", config.RoleWorker)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if add.Node.Zone != "3" {
+		t.Errorf("Expected the third node to be placed in zone 3, got %q", add.Node.Zone)
+	}
+
+	if _, err := planNodeAddition(cfg, deployed, "prod", "missing", config.RoleWorker); err == nil {
+		t.Error("Expected an error for an unknown pool")
+	}
+	if _, err := planNodeAddition(cfg, deployed, "prod", "", "etcd"); err == nil {
+		t.Error("Expected an error when no pool has the role")
+	}
+}
+
+func TestUsedVPNIPs(t *testing.T) {
+	nodes := []NodeInfo{{Name: "a", WireGuardIP: "10.8.0.10"}, {Name: "b", WireGuardIP: "10.8.0.11"}}
+	states := []wgNodeConfig{{Config: `[Interface]
+Address = 10.8.0.10/24
+
+[Peer]
+PublicKey = added
+AllowedIPs = 10.8.0.12/32, 10.0.0.0/8
+
+[Peer]
+PublicKey = client
+AllowedIPs = 10.8.0.100/32
+`}}
+
+	used := usedVPNIPs(nodes, states)
+	for _, ip := range []string{"10.8.0.10", "10.8.0.11", "10.8.0.12", "10.8.0.100"} {
+		if !used[ip] {
+			t.Errorf("Expected %s to be used", ip)
+		}
+	}
+
+	ip, err := nextFreeVPNIP(used, vpnSubnet(nil), 10, 99)
+	if err != nil || ip != "10.8.0.13" {
+		t.Errorf("Expected 10.8.0.13, got %s (%v)", ip, err)
+	}
+}
+
+func TestNewNodeMeshConfig(t *testing.T) {
+	states := []wgNodeConfig{
+		{Node: NodeInfo{Name: "masters-1", WireGuardIP: "10.8.0.10", PublicIP: "203.0.113.1"}, PublicKey: "key1",
+			Config: "[Peer]\n# bastion\nPublicKey = bastionkey\nAllowedIPs = 10.8.0.5/32\nEndpoint = 203.0.113.9:51820\n"},
+	}
+	node := NodeInfo{Name: "workers-3", WireGuardIP: "10.8.0.13", PublicIP: "203.0.113.3"}
+
+	conf := newNodeMeshConfig(node, "newkey", states, vpnSubnet(nil), "bastionkey", meshRouting{})
+	for _, want := range []string{"Address = 10.8.0.13/24", "PublicKey = key1", "Endpoint = 203.0.113.1:51820", "PublicKey = bastionkey", "AllowedIPs = 10.8.0.5/32"} {
+		if !strings.Contains(conf, want) {
+			t.Errorf("Expected config to contain %q, got:\n%s", want, conf)
+		}
+	}
+	if strings.Contains(conf, "PublicKey = newkey") {
+		t.Error("Expected the node not to peer with itself")
+	}
+
+	if conf := newNodeMeshConfig(node, "newkey", states, vpnSubnet(nil), "", meshRouting{}); strings.Contains(conf, "bastionkey") {
+		t.Error("Expected no bastion peer without a bastion")
+	}
+}
+
+func TestNewNodeMeshConfig_HybridVPC(t *testing.T) {
+	cfg := &config.ClusterConfig{
+		Network: config.NetworkConfig{Mode: config.NetworkModeHybrid},
+		Providers: config.ProvidersConfig{
+			DigitalOcean: &config.DigitalOceanProvider{Enabled: true, VPC: &config.VPCConfig{CIDR: "10.10.0.0/16"}},
+		},
+	}
+	states := []wgNodeConfig{
+		{Node: NodeInfo{Name: "masters-1", Provider: "digitalocean", WireGuardIP: "10.8.0.10", PublicIP: "203.0.113.1", PrivateIP: "10.10.0.2"}, PublicKey: "key1"},
+	}
+	node := NodeInfo{Name: "workers-3", Provider: "digitalocean", WireGuardIP: "10.8.0.13", PublicIP: "203.0.113.3", PrivateIP: "10.10.0.5"}
+
+	conf := newNodeMeshConfig(node, "newkey", states, vpnSubnet(nil), "", newMeshRouting(cfg))
+	if want := "Endpoint = 10.10.0.2:51820"; !strings.Contains(conf, want) {
+		t.Errorf("Expected a peer in the same VPC to be reached privately (%q), got:\n%s", want, conf)
+	}
+}
+
+func TestNodeProgramConfig_LeavesConfigUntouched(t *testing.T) {
+	cfg := &config.ClusterConfig{Providers: config.ProvidersConfig{
+		DigitalOcean: &config.DigitalOceanProvider{},
+		Linode:       &config.LinodeProvider{AuthorizedKeys: []string{"ssh-ed25519 operator"}},
+		Vultr:        &config.VultrProvider{},
+	}}
+
+	// The program runs again on every preview, up and retry
+	for i := 0; i < 3; i++ {
+		nodeCfg := nodeProgramConfig(cfg, "ssh-ed25519 cluster")
+		if got := nodeCfg.Providers.Linode.AuthorizedKeys; !reflect.DeepEqual(got, []string{"ssh-ed25519 operator", "ssh-ed25519 cluster"}) {
+			t.Fatalf("run %d: unexpected authorized keys %v", i, got)
+		}
+		if nodeCfg.Providers.DigitalOcean.SSHPublicKey != "ssh-ed25519 cluster" || nodeCfg.Providers.Vultr.SSHPublicKey != "ssh-ed25519 cluster" {
+			t.Errorf("run %d: expected the cluster key on every provider", i)
+		}
+	}
+
+	if len(cfg.Providers.Linode.AuthorizedKeys) != 1 || cfg.Providers.DigitalOcean.SSHPublicKey != nil {
+		t.Errorf("Expected the shared config to be left untouched, got %+v", cfg.Providers)
+	}
+}
+
+func TestCheckNodeAddProvider(t *testing.T) {
+	for _, provider := range []string{"digitalocean", "linode", "vultr"} {
+		if err := checkNodeAddProvider(provider); err != nil {
+			t.Errorf("Expected %s to be supported, got %v", provider, err)
+		}
+	}
+	for _, provider := range []string{"azure", "aws", "gcp"} {
+		if err := checkNodeAddProvider(provider); err == nil {
+			t.Errorf("Expected %s to be refused", provider)
+		}
+	}
+}
+
+func TestMeshPeerAddScript(t *testing.T) {
+	script := meshPeerAddScript("workers-3", "10.8.0.13", "newkey", "203.0.113.3")
+	for _, want := range []string{"# workers-3 (10.8.0.13)", "PublicKey = newkey", "AllowedIPs = 10.8.0.13/32\n", "Endpoint = 203.0.113.3:51820", "wg syncconf wg0"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q", want)
+		}
+	}
+}
+
+func TestRKE2NodeJoinScript(t *testing.T) {
+	rke2 := &config.RKE2Config{ClusterToken: "secret", Version: "v1.28.5+rke2r1"}
+	node := NodeInfo{Name: "workers-3", WireGuardIP: "10.8.0.13", Region: "eastus", Zone: "2"}
+
+	script, err := rke2NodeJoinScript(rke2, &config.KubernetesConfig{}, &config.SecurityConfig{}, false, node, "10.8.0.10")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{"server: https://10.8.0.10:9345", "token: secret", "node-name: workers-3", "node-ip: 10.8.0.13", "INSTALL_RKE2_TYPE=agent", "systemctl enable --now rke2-agent.service",
+		"  - topology.kubernetes.io/region=eastus\n", "  - topology.kubernetes.io/zone=2\n"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q", want)
+		}
+	}
+
+	k8s := &config.KubernetesConfig{Admission: config.AdmissionConfig{Config: map[string]string{"PodSecurity": "kind: PodSecurityConfiguration\n"}}}
+	script, err = rke2NodeJoinScript(rke2, k8s, &config.SecurityConfig{}, true, node, "10.8.0.10")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(script, "INSTALL_RKE2_TYPE=server") || !strings.Contains(script, "rke2-server.service") {
+		t.Error("Expected a server join to install and start rke2-server")
+	}
+	if !strings.Contains(script, "admission-control-config-file="+config.AdmissionConfigPath) ||
+		!strings.Contains(script, "| base64 -d > "+config.AdmissionConfigPath) {
+		t.Error("Expected a server join to write the admission configuration its config names")
+	}
+	if strings.Index(script, "base64 -d") > strings.Index(script, "systemctl enable") {
+		t.Error("Expected the admission configuration to be written before the server starts")
+	}
+}
+
+func TestK3sNodeJoinScript(t *testing.T) {
+	k8s := &config.KubernetesConfig{RKE2: &config.RKE2Config{ClusterToken: "secret", Version: "v1.30.4+k3s1"}}
+	node := NodeInfo{Name: "workers-3", PublicIP: "203.0.113.13", WireGuardIP: "10.8.0.13", Region: "eastus", Zone: "2"}
+
+	script, err := k3sNodeJoinScript(k8s, &config.SecurityConfig{}, false, node, "10.8.0.10")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{"K3S_URL=https://10.8.0.10:6443", "K3S_TOKEN='secret'", `INSTALL_K3S_EXEC="agent --node-name=workers-3 --node-ip=10.8.0.13`,
+		"--flannel-iface=wg0", "--node-label=topology.kubernetes.io/zone=2", "INSTALL_K3S_VERSION=v1.30.4+k3s1"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q\nGot: %s", want, script)
+		}
+	}
+
+	k8s.Admission = config.AdmissionConfig{Config: map[string]string{"PodSecurity": "kind: PodSecurityConfiguration\n"}}
+	script, err = k3sNodeJoinScript(k8s, &config.SecurityConfig{}, true, node, "10.8.0.10")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(script, `INSTALL_K3S_EXEC="server --node-name=workers-3`) || !strings.Contains(script, "--tls-san=10.8.0.13") {
+		t.Errorf("Expected a server join, got: %s", script)
+	}
+	if !strings.Contains(script, "admission-control-config-file="+config.K3sAdmissionConfigPath) ||
+		strings.Index(script, "| base64 -d > "+config.K3sAdmissionConfigPath) > strings.Index(script, "sh /tmp/k3s-install.sh") {
+		t.Error("Expected a server join to write the admission configuration before K3s starts")
+	}
+}
+
+func TestUpdateDeploymentNodes(t *testing.T) {
+	raw := json.RawMessage(`{"manifest":{"time":"2024-01-01T00:00:00Z"},"resources":[
+		{"urn":"urn:pulumi:prod::sloth-kubernetes::pulumi:pulumi:Stack::sloth-kubernetes-prod","type":"pulumi:pulumi:Stack",
+		 "outputs":{"node_count":1,"nodes":{"node_0":{"name":"masters-1","vpn_ip":"10.8.0.10"}}}},
+		{"urn":"urn:other","type":"digitalocean:index/droplet:Droplet","outputs":{"size":12345678901234567890}}]}`)
+
+	updated, err := updateDeploymentNodes(raw, func(nodes map[string]interface{}) {
+		addNodeOutput(nodes, NodeInfo{Name: "workers-3", WireGuardIP: "10.8.0.13", Roles: []string{"worker"}, Stack: "prod-node-workers-3", Pool: "workers"})
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(string(updated), "12345678901234567890") {
+		t.Error("Expected numbers elsewhere in the state to be kept as they are")
+	}
+
+	var deployment struct {
+		Resources []struct {
+			Outputs map[string]interface{} `json:"outputs"`
+		} `json:"resources"`
+	}
+	if err := json.Unmarshal(updated, &deployment); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	outputs := deployment.Resources[0].Outputs
+	if outputs["node_count"] != float64(2) {
+		t.Errorf("Expected node_count 2, got %v", outputs["node_count"])
+	}
+	added, ok := outputs["nodes"].(map[string]interface{})["node_1"].(map[string]interface{})
+	if !ok || added["name"] != "workers-3" || added["stack"] != "prod-node-workers-3" || added["pool"] != "workers" {
+		t.Errorf("Expected workers-3 as node_1, got %v", outputs["nodes"])
+	}
+
+	if _, err := updateDeploymentNodes(json.RawMessage(`{"resources":[]}`), func(map[string]interface{}) {}); err == nil {
+		t.Error("Expected an error without a root stack resource")
+	}
+}
@@ -0,0 +1,396 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optdestroy"
+	"github.com/spf13/cobra"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+var drainNodeCmd = &cobra.Command{
+	Use:   "drain [stack-name] <node-name>",
+	Short: "Cordon a node and evict its pods",
+	Long: `Cordon a node and evict its pods so it can be taken down for maintenance.
+DaemonSet pods stay and emptyDir data is discarded. kubectl runs on a
+control plane node, through the bastion when the cluster has one.
+
+Make the node schedulable again with kubectl uncordon.`,
+	Example: `  # Drain a worker before maintenance
+  sloth-kubernetes nodes drain production workers-2`,
+	RunE: runDrainNode,
+}
+
+// nodeDeleter performs the steps of removing a node from a running cluster
+type nodeDeleter interface {
+	// Drain cordons node and evicts its pods
+	Drain(node string) error
+	// Delete removes node from the cluster
+	Delete(node string) error
+	// RemovePeers removes node's WireGuard peer from the other nodes
+	RemovePeers(node NodeInfo) error
+	// Destroy destroys node's cloud resources
+	Destroy(node NodeInfo) error
+	// Record removes node from the stack outputs
+	Record(node NodeInfo) error
+}
+
+// etcdQuorumMath describes the quorum of an etcd cluster of members
+func etcdQuorumMath(members int) string {
+	return fmt.Sprintf("%d etcd member(s): quorum %d, tolerates %d failure(s)", members, members/2+1, (members-1)/2)
+}
+
+// checkControlPlaneRemoval refuses to remove one of members control plane
+// nodes when the etcd members left would be none or an even number: an even
+// member count needs a larger quorum without tolerating more failures
+func checkControlPlaneRemoval(node string, members int) error {
+	remaining := members - 1
+	if remaining == 0 {
+		return errs.Mark(fmt.Errorf("removing %s would leave the cluster without a control plane node", node), errs.ErrQuorumRisk)
+	}
+	if remaining%2 == 0 {
+		return errs.Mark(fmt.Errorf("removing %s would leave %s: etcd needs an odd number of members (1, 3, 5, ...)",
+			node, etcdQuorumMath(remaining)), errs.ErrQuorumRisk)
+	}
+	return nil
+}
+
+// removeNodeOutput removes the named node from a "nodes" output map
+func removeNodeOutput(nodes map[string]interface{}, name string) {
+	for key, value := range nodes {
+		if node, ok := value.(map[string]interface{}); ok && node["name"] == name {
+			delete(nodes, key)
+		}
+	}
+}
+
+// runNodeRemoval removes a node from the cluster, then from the VPN mesh,
+// then destroys it. The node is drained first unless drain is false. Once it
+// is out of the cluster, a failure to remove its peers is only reported:
+// stale peers do not affect the cluster, a half-removed node would.
+func runNodeRemoval(node NodeInfo, drain bool, r nodeDeleter) error {
+	if drain {
+		printInfo(fmt.Sprintf("Draining %s...", node.Name))
+		if err := r.Drain(node.Name); err != nil {
+			return fmt.Errorf("failed to drain %s: %w", node.Name, err)
+		}
+	}
+
+	printInfo(fmt.Sprintf("Deleting %s from the cluster...", node.Name))
+	if err := r.Delete(node.Name); err != nil {
+		return fmt.Errorf("failed to delete %s: %w", node.Name, err)
+	}
+
+	printInfo(fmt.Sprintf("Removing the VPN peer %s...", node.WireGuardIP))
+	if err := r.RemovePeers(node); err != nil {
+		printWarning(fmt.Sprintf("Could not remove the peer everywhere: %v - remove it with: sloth-kubernetes vpn leave --vpn-ip %s", err, node.WireGuardIP))
+	}
+
+	printInfo(fmt.Sprintf("Destroying %s...", node.Name))
+	if err := r.Destroy(node); err != nil {
+		return fmt.Errorf("failed to destroy %s: %w", node.Name, err)
+	}
+
+	if err := r.Record(node); err != nil {
+		return fmt.Errorf("%s was destroyed but the stack outputs were not updated: %w", node.Name, err)
+	}
+	return nil
+}
+
+// stackNodeDeleter drains and deletes a node with kubectl on a control plane
+// node, removes its peers over SSH and destroys it in the stack managing it
+type stackNodeDeleter struct {
+	stackNodeReplacer
+
+	// remaining are the nodes staying in the cluster
+	remaining []NodeInfo
+
+	// plan holds the stack resources of the node, checked before the removal
+	// started
+	plan nodeDestroyPlan
+}
+
+// nodeDestroyPlan holds the stack resources owned by some nodes and the
+// resources staying in the stack that depend on them
+type nodeDestroyPlan struct {
+	URNs []string
+	// Dependents would be left pointing at destroyed resources. Pulumi
+	// refuses a targeted destroy while there are any.
+	Dependents []string
+}
+
+// planNodeDestroy returns the resources owned by the named nodes and the
+// other resources that depend on them
+func planNodeDestroy(resources []stackResource, nodeNames []string) nodeDestroyPlan {
+	plan := nodeDestroyPlan{URNs: nodeResourceURNs(resources, nodeNames)}
+	owned := make(map[string]bool, len(plan.URNs))
+	for _, urn := range plan.URNs {
+		owned[urn] = true
+	}
+	for _, resource := range resources {
+		if !owned[resource.URN] && resource.dependsOnAny(owned) {
+			plan.Dependents = append(plan.Dependents, resource.URN)
+		}
+	}
+	return plan
+}
+
+// checkNodeDestroy plans destroying the resources of the named nodes. It
+// fails when resources staying in the stack depend on them, so that the
+// removal stops before the nodes are drained rather than at a destroy
+// Pulumi refuses.
+func checkNodeDestroy(ctx context.Context, stack auto.Stack, nodeNames []string) (nodeDestroyPlan, error) {
+	resources, err := exportStackResources(ctx, stack)
+	if err != nil {
+		return nodeDestroyPlan{}, err
+	}
+	plan := planNodeDestroy(resources, nodeNames)
+	if len(plan.Dependents) == 0 {
+		return plan, nil
+	}
+
+	names := make([]string, 0, len(plan.Dependents))
+	for _, urn := range plan.Dependents {
+		if idx := strings.LastIndex(urn, "::"); idx >= 0 {
+			urn = urn[idx+2:]
+		}
+		names = append(names, urn)
+	}
+	return plan, fmt.Errorf("%s cannot be destroyed on its own: %d resource(s) staying in the stack depend on it (%s); remove it from the config and run sloth-kubernetes deploy, which updates them along with the removal",
+		strings.Join(nodeNames, ", "), len(names), strings.Join(names, ", "))
+}
+
+// destroyNodeResources destroys the resources of plan and nothing else
+func destroyNodeResources(ctx context.Context, stack auto.Stack, stackName string, plan nodeDestroyPlan) error {
+	if len(plan.URNs) == 0 {
+		return nil
+	}
+	if _, err := stack.Destroy(ctx, optdestroy.Target(plan.URNs), optdestroy.ProgressStreams(pulumiProgress())); err != nil {
+		return stackLockedError(err, "destroy nodes", stackName)
+	}
+	return nil
+}
+
+func (d stackNodeDeleter) RemovePeers(node NodeInfo) error {
+	if node.WireGuardIP == "" {
+		return nil
+	}
+
+	discoveryNode, err := findReachableNode(d.remaining, d.access)
+	if err != nil {
+		return err
+	}
+	publicKey, err := findPeerKeyByVPNIP(discoveryNode, d.access, node.WireGuardIP)
+	if err != nil {
+		return err
+	}
+
+	tracker := newNodeChangeTracker()
+	defer tracker.Stop()
+
+	removed := removePeerFromNodes(d.remaining, d.access, publicKey, tracker)
+	if tracker.Interrupted() {
+		return tracker.interruptedError("nodes remove")
+	}
+
+	if d.access.viaBastion() {
+		if output, err := sshRunner(d.access.bastionArgs(5, peerRemoveCommand(publicKey)), ""); err != nil || string(output) != "SUCCESS\n" {
+			return fmt.Errorf("failed to remove the peer from the bastion")
+		}
+	}
+
+	peers := 0
+	for _, n := range d.remaining {
+		if n.WireGuardIP != "" {
+			peers++
+		}
+	}
+	if removed < peers {
+		return fmt.Errorf("peer removed from %d/%d nodes", removed, peers)
+	}
+	return nil
+}
+
+func (d stackNodeDeleter) Destroy(node NodeInfo) error {
+	// A node added with nodes add has a stack of its own
+	if node.Stack != "" {
+		workspace, err := createWorkspaceWithS3Support(d.ctx)
+		if err != nil {
+			return fmt.Errorf("failed to create workspace: %w", err)
+		}
+		fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", node.Stack)
+		s, err := auto.SelectStack(d.ctx, fullyQualifiedStackName, workspace)
+		if err != nil {
+			return selectStackError(err, node.Stack)
+		}
+		if _, err := s.Destroy(d.ctx, optdestroy.ProgressStreams(pulumiProgress())); err != nil {
+			return stackLockedError(err, "destroy", node.Stack)
+		}
+		return workspace.RemoveStack(d.ctx, fullyQualifiedStackName)
+	}
+
+	if len(d.plan.URNs) == 0 {
+		printWarning(fmt.Sprintf("No resources of %s found in the stack", node.Name))
+		return nil
+	}
+	if err := destroyNodeResources(d.ctx, d.stack, d.stackName, d.plan); err != nil {
+		return err
+	}
+	printWarning(fmt.Sprintf("%s belongs to a node pool: lower the pool's count in the config, or the next deploy creates it again", node.Name))
+	return nil
+}
+
+func (d stackNodeDeleter) Record(node NodeInfo) error {
+	return updateStackNodes(d.ctx, d.stack, func(nodes map[string]interface{}) {
+		removeNodeOutput(nodes, node.Name)
+	})
+}
+
+// selectNodeInStack loads the nodes of a stack and returns the named node
+// along with the stack, its outputs and all its nodes
+func selectNodeInStack(ctx context.Context, stack, name string) (auto.Stack, auto.OutputMap, []NodeInfo, NodeInfo, error) {
+	workspace, err := createWorkspaceWithS3Support(ctx)
+	if err != nil {
+		return auto.Stack{}, nil, nil, NodeInfo{}, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	s, err := auto.SelectStack(ctx, fmt.Sprintf("organization/sloth-kubernetes/%s", stack), workspace)
+	if err != nil {
+		return auto.Stack{}, nil, nil, NodeInfo{}, selectStackError(err, stack)
+	}
+	outputs, err := stackOutputsWithRetry(ctx, s)
+	if err != nil {
+		return auto.Stack{}, nil, nil, NodeInfo{}, err
+	}
+	nodes, err := ParseNodeOutputs(outputs)
+	if err != nil {
+		return auto.Stack{}, nil, nil, NodeInfo{}, fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	for _, node := range nodes {
+		if node.Name == name {
+			return s, outputs, nodes, node, nil
+		}
+	}
+	return auto.Stack{}, nil, nil, NodeInfo{}, fmt.Errorf("node '%s' not found in stack '%s'", name, stack)
+}
+
+func runDrainNode(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, rest, err := splitStackArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 {
+		return fmt.Errorf("usage: sloth-kubernetes nodes drain [stack-name] <node-name>")
+	}
+
+	s, outputs, nodes, node, err := selectNodeInStack(ctx, stack, rest[0])
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("🚧 Draining node '%s' in stack: %s", node.Name, stack))
+
+	access := newNodeSSHAccess(stack, outputs)
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
+	}
+	server, err := findReachableNode(controlPlaneNodes(nodes), access)
+	if err != nil {
+		return err
+	}
+
+	replacer := stackNodeReplacer{ctx: ctx, stack: s, stackName: stack, server: server, access: access}
+	if err := replacer.Drain(node.Name); err != nil {
+		return fmt.Errorf("failed to drain %s: %w", node.Name, err)
+	}
+
+	printSuccess(fmt.Sprintf("%s is cordoned and drained", node.Name))
+	printInfo(fmt.Sprintf("Make it schedulable again with: kubectl uncordon %s", node.Name))
+	return nil
+}
+
+func runRemoveNode(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, rest, err := splitStackArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 {
+		return fmt.Errorf("usage: sloth-kubernetes nodes remove [stack-name] <node-name>")
+	}
+
+	s, outputs, nodes, node, err := selectNodeInStack(ctx, stack, rest[0])
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("➖ Removing node '%s' from stack: %s", node.Name, stack))
+
+	if isControlPlaneNode(node) {
+		members := len(controlPlaneNodes(nodes))
+		fmt.Println()
+		printInfo(fmt.Sprintf("Control plane now:   %s", etcdQuorumMath(members)))
+		if members > 1 {
+			printInfo(fmt.Sprintf("After the removal:   %s", etcdQuorumMath(members-1)))
+		}
+		if err := checkControlPlaneRemoval(node.Name, members); err != nil {
+			return err
+		}
+	}
+
+	var remaining []NodeInfo
+	for _, n := range nodes {
+		if n.Name != node.Name {
+			remaining = append(remaining, n)
+		}
+	}
+
+	// Only the resources the node owns, not the rest of its pool. A node
+	// added with nodes add has a stack of its own, destroyed as a whole.
+	var plan nodeDestroyPlan
+	if node.Stack == "" {
+		if plan, err = checkNodeDestroy(ctx, s, []string{node.Name}); err != nil {
+			return err
+		}
+	}
+
+	access := newNodeSSHAccess(stack, outputs)
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
+	}
+	server, err := findReachableNode(controlPlaneNodes(remaining), access)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	if !forceRemove {
+		color.Yellow("⚠️  Node will be drained before removal")
+	} else {
+		color.Red("⚠️  Force removal - node will NOT be drained!")
+	}
+	if !autoApprove && !confirm(fmt.Sprintf("Remove %s from the cluster and destroy it?", node.Name)) {
+		printWarning("Node removal cancelled")
+		return nil
+	}
+
+	fmt.Println()
+	deleter := stackNodeDeleter{
+		stackNodeReplacer: stackNodeReplacer{ctx: ctx, stack: s, stackName: stack, server: server, access: access},
+		remaining:         remaining,
+		plan:              plan,
+	}
+	if err := runNodeRemoval(node, !forceRemove, deleter); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	printSuccess(fmt.Sprintf("%s removed from the cluster and destroyed", node.Name))
+	return nil
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+// fakeNodeDeleter records the steps of a node removal and fails the step
+// named in failAt
+type fakeNodeDeleter struct {
+	failAt string
+	calls  []string
+}
+
+func (d *fakeNodeDeleter) step(name string) error {
+	d.calls = append(d.calls, name)
+	if name == d.failAt {
+		return errors.New(name + " failed")
+	}
+	return nil
+}
+
+func (d *fakeNodeDeleter) Drain(node string) error         { return d.step("drain") }
+func (d *fakeNodeDeleter) Delete(node string) error        { return d.step("delete") }
+func (d *fakeNodeDeleter) RemovePeers(node NodeInfo) error { return d.step("unpeer") }
+func (d *fakeNodeDeleter) Destroy(node NodeInfo) error     { return d.step("destroy") }
+func (d *fakeNodeDeleter) Record(node NodeInfo) error      { return d.step("record") }
+
+func TestRunNodeRemoval(t *testing.T) {
+	node := NodeInfo{Name: "workers-2", WireGuardIP: "10.8.0.12"}
+
+	tests := []struct {
+		name    string
+		drain   bool
+		failAt  string
+		wantErr bool
+		calls   []string
+	}{
+		{"drained", true, "", false, []string{"drain", "delete", "unpeer", "destroy", "record"}},
+		{"forced", false, "", false, []string{"delete", "unpeer", "destroy", "record"}},
+		{"drain fails", true, "drain", true, []string{"drain"}},
+		{"delete fails", true, "delete", true, []string{"drain", "delete"}},
+		{"peer removal fails", true, "unpeer", false, []string{"drain", "delete", "unpeer", "destroy", "record"}},
+		{"destroy fails", true, "destroy", true, []string{"drain", "delete", "unpeer", "destroy"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deleter := &fakeNodeDeleter{failAt: tt.failAt}
+			err := runNodeRemoval(node, tt.drain, deleter)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if !reflect.DeepEqual(deleter.calls, tt.calls) {
+				t.Errorf("Expected steps %v, got %v", tt.calls, deleter.calls)
+			}
+		})
+	}
+}
+
+func TestCheckControlPlaneRemoval(t *testing.T) {
+	tests := []struct {
+		members int
+		refused bool
+	}{
+		{1, true},
+		{2, false},
+		{3, true},
+		{4, false},
+		{5, true},
+	}
+
+	for _, tt := range tests {
+		err := checkControlPlaneRemoval("masters-1", tt.members)
+		if (err != nil) != tt.refused {
+			t.Errorf("%d members: expected refused=%v, got %v", tt.members, tt.refused, err)
+		}
+		if err != nil && !errors.Is(err, errs.ErrQuorumRisk) {
+			t.Errorf("%d members: expected ErrQuorumRisk, got %v", tt.members, err)
+		}
+	}
+}
+
+func TestEtcdQuorumMath(t *testing.T) {
+	if got := etcdQuorumMath(3); got != "3 etcd member(s): quorum 2, tolerates 1 failure(s)" {
+		t.Errorf("Unexpected quorum math: %s", got)
+	}
+	if got := etcdQuorumMath(4); got != "4 etcd member(s): quorum 3, tolerates 1 failure(s)" {
+		t.Errorf("Unexpected quorum math: %s", got)
+	}
+}
+
+func TestRemoveNodeOutput(t *testing.T) {
+	nodes := map[string]interface{}{
+		"node_0": map[string]interface{}{"name": "masters-1"},
+		"node_1": map[string]interface{}{"name": "workers-2"},
+	}
+
+	removeNodeOutput(nodes, "workers-2")
+	if len(nodes) != 1 || nodes["node_0"] == nil {
+		t.Errorf("Expected only masters-1 to remain, got %v", nodes)
+	}
+}
+
+// TestPlanNodeDestroy_FindsDependents tests that resources staying in the
+// stack which take inputs from the node are found before anything is changed
+func TestPlanNodeDestroy_FindsDependents(t *testing.T) {
+	prefix := "urn:pulumi:production::sloth-kubernetes::"
+	worker := prefix + "kubernetes-create:compute:RealNode::kubernetes-cluster-nodes-workers-workers-2"
+	droplet := prefix + "kubernetes-create:compute:RealNode$digitalocean:index/droplet:Droplet::workers-2"
+	master := prefix + "kubernetes-create:compute:RealNode::kubernetes-cluster-nodes-masters-masters-1"
+	meshConfig := prefix + "kubernetes-create:network:WireGuardMesh$command:remote:Command::masters-1-wg-config"
+	k3sJoin := prefix + "kubernetes-create:cluster:K3s$command:remote:Command::workers-2-k3s-join"
+
+	resources := []stackResource{
+		{URN: worker, Type: realNodeType},
+		{URN: droplet, Type: "digitalocean:index/droplet:Droplet", Parent: worker},
+		{URN: master, Type: realNodeType},
+		{URN: meshConfig, Type: "command:remote:Command", PropertyDependencies: map[string][]string{"triggers": {droplet}}},
+		{URN: k3sJoin, Type: "command:remote:Command", Dependencies: []string{droplet, master}},
+	}
+
+	plan := planNodeDestroy(resources, []string{"workers-2"})
+	if !reflect.DeepEqual(plan.URNs, []string{worker, droplet}) {
+		t.Errorf("Expected only the node's own resources, got %v", plan.URNs)
+	}
+	if !reflect.DeepEqual(plan.Dependents, []string{meshConfig, k3sJoin}) {
+		t.Errorf("Expected the mesh and join commands as dependents, got %v", plan.Dependents)
+	}
+}
+
+// TestPlanNodeDestroy_NoDependents tests a node nothing else depends on
+func TestPlanNodeDestroy_NoDependents(t *testing.T) {
+	prefix := "urn:pulumi:production::sloth-kubernetes::"
+	worker := prefix + "kubernetes-create:compute:RealNode::kubernetes-cluster-nodes-workers-workers-2"
+	droplet := prefix + "kubernetes-create:compute:RealNode$digitalocean:index/droplet:Droplet::workers-2"
+	master := prefix + "kubernetes-create:compute:RealNode::kubernetes-cluster-nodes-masters-masters-1"
+
+	resources := []stackResource{
+		{URN: worker, Type: realNodeType},
+		{URN: droplet, Type: "digitalocean:index/droplet:Droplet", Parent: worker, Dependencies: []string{master}},
+		{URN: master, Type: realNodeType},
+	}
+
+	plan := planNodeDestroy(resources, []string{"workers-2"})
+	if len(plan.URNs) != 2 || len(plan.Dependents) != 0 {
+		t.Errorf("Expected 2 resources and no dependents, got %+v", plan)
+	}
+}
@@ -7,16 +7,17 @@ import (
 	"os/exec"
 	"text/tabwriter"
 
+	"github.com/chalkan3/sloth-kubernetes/internal/common"
 	"github.com/fatih/color"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
 	"github.com/spf13/cobra"
-	yaml "gopkg.in/yaml.v3"
 )
 
 var nodesCmd = &cobra.Command{
-	Use:   "nodes",
-	Short: "Manage cluster nodes",
-	Long:  `List, add, remove, and manage Kubernetes cluster nodes`,
+	Use:     "nodes",
+	Aliases: []string{"node"},
+	Short:   "Manage cluster nodes",
+	Long:    `List, add, remove, and manage Kubernetes cluster nodes`,
 }
 
 var listNodesCmd = &cobra.Command{
@@ -46,26 +47,53 @@ var sshNodeCmd = &cobra.Command{
 var addNodeCmd = &cobra.Command{
 	Use:   "add [stack-name]",
 	Short: "Add a new node to the cluster",
-	Long:  `Add a new node to an existing cluster by updating the stack`,
-	Example: `  # Add a node from config file
-  sloth-kubernetes nodes add production --config node.yaml
-
-  # Add a node with inline parameters
-  sloth-kubernetes nodes add production \
-    --name worker-new-1 \
-    --provider digitalocean \
-    --size s-2vcpu-4gb \
-    --role worker`,
+	Long: `Add one node to a deployed cluster without redeploying it.
+
+The node is created from a node pool of the config file: --pool picks the
+pool, otherwise the first pool with --role. It gets the next free VPN IP,
+is added to the WireGuard mesh of every node (and the bastion), and joins
+the cluster with the existing RKE2 token. The stack outputs are updated so
+'nodes list' and 'vpn peers' show it.
+
+The node's cloud resources live in their own stack, <stack>-node-<name>.
+If the node cannot be added to the mesh or join the cluster, its peers are
+removed again and the node is destroyed.
+
+Nodes can be added on DigitalOcean, Linode and Vultr, and not to clusters
+whose nodes are spokes of an existing WireGuard hub: raise the pool's count
+and run deploy there instead.`,
+	Example: `  # Add a worker from the first worker pool
+  sloth-kubernetes nodes add production --config cluster.yaml
+
+  # Add a node to a specific pool
+  sloth-kubernetes node add production --config cluster.yaml --pool workers-fra1
+
+  # Add a control-plane node
+  sloth-kubernetes nodes add production --config cluster.yaml --role master`,
 	RunE: runAddNode,
 }
 
 var removeNodeCmd = &cobra.Command{
-	Use:   "remove [stack-name] [node-name]",
-	Short: "Remove a node from the cluster",
-	Long:  `Safely drain and remove a node from the cluster`,
+	Use:     "remove [stack-name] [node-name]",
+	Aliases: []string{"delete"},
+	Short:   "Remove a node from the cluster",
+	Long: `Safely remove a node from the cluster and destroy it:
+
+  1. Drain the node (skipped with --force)
+  2. Delete it from Kubernetes; RKE2 removes its etcd member
+  3. Remove its WireGuard peer from the other nodes and the bastion
+  4. Destroy its cloud resources and drop it from the stack outputs
+
+Removing a control plane node is refused when it would leave no control
+plane or an even number of etcd members. Removing a pool node is refused,
+before anything changes, when other stack resources such as the VPN mesh
+depend on it: lower the pool's count and run deploy instead.`,
 	Example: `  # Remove a node
   sloth-kubernetes nodes remove production worker-old-1
 
+  # Same, with the delete alias
+  sloth-kubernetes nodes delete production worker-old-1
+
   # Force remove without draining
   sloth-kubernetes nodes remove production worker-old-1 --force`,
 	RunE: runRemoveNode,
@@ -75,10 +103,8 @@ var (
 	nodesOutputFormat string
 	sshCommand        string
 	forceRemove       bool
-	nodeName          string
-	nodeProvider      string
-	nodeSize          string
-	nodeRole          string
+	addNodePool       string
+	addNodeRole       string
 )
 
 func init() {
@@ -89,6 +115,7 @@ func init() {
 	nodesCmd.AddCommand(sshNodeCmd)
 	nodesCmd.AddCommand(addNodeCmd)
 	nodesCmd.AddCommand(removeNodeCmd)
+	nodesCmd.AddCommand(drainNodeCmd)
 
 	// List flags
 	listNodesCmd.Flags().StringVar(&nodesOutputFormat, "output", "table", "Output format (table, json, yaml)")
@@ -97,10 +124,8 @@ func init() {
 	sshNodeCmd.Flags().StringVar(&sshCommand, "command", "", "Command to execute on the node")
 
 	// Add node flags
-	addNodeCmd.Flags().StringVar(&nodeName, "name", "", "Node name")
-	addNodeCmd.Flags().StringVar(&nodeProvider, "provider", "", "Cloud provider (digitalocean, linode)")
-	addNodeCmd.Flags().StringVar(&nodeSize, "size", "", "Node size/type")
-	addNodeCmd.Flags().StringVar(&nodeRole, "role", "worker", "Node role (master, worker)")
+	addNodeCmd.Flags().StringVar(&addNodePool, "pool", "", "Node pool the node is created from (default: the first pool with --role)")
+	addNodeCmd.Flags().StringVar(&addNodeRole, "role", "worker", "Role of the pool to pick when --pool is not given (worker, master)")
 
 	// Remove node flags
 	removeNodeCmd.Flags().BoolVar(&forceRemove, "force", false, "Force remove without draining")
@@ -110,7 +135,10 @@ func runListNodes(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
 	// Get stack name
-	stack := getStackFromArgs(args, 0)
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
 
 	printHeader(fmt.Sprintf("📋 Nodes in stack: %s", stack))
 
@@ -161,13 +189,16 @@ func runListNodes(cmd *cobra.Command, args []string) error {
 }
 
 func runSSHNode(cmd *cobra.Command, args []string) error {
-	if len(args) < 2 {
-		return fmt.Errorf("usage: sloth-kubernetes nodes ssh <stack-name> <node-name>")
+	stack, rest, err := splitStackArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 {
+		return fmt.Errorf("usage: sloth-kubernetes nodes ssh [stack-name] <node-name>")
 	}
 
 	ctx := context.Background()
-	stack := args[0]
-	nodeName := args[1]
+	nodeName := rest[0]
 
 	printInfo(fmt.Sprintf("🔐 Connecting to node '%s' in stack '%s'...", nodeName, stack))
 
@@ -251,8 +282,8 @@ func runSSHNode(cmd *cobra.Command, args []string) error {
 		sshArgs = []string{
 			"-i", sshKeyPath,
 			"-o", "StrictHostKeyChecking=accept-new",
-			"-o", "UserKnownHostsFile=/dev/null",
-			"-o", fmt.Sprintf("ProxyCommand=ssh -i %s -o StrictHostKeyChecking=accept-new -o UserKnownHostsFile=/dev/null -W %%h:%%p root@%s", sshKeyPath, bastionIP),
+			"-o", knownHostsOption(stack),
+			"-o", "ProxyCommand=" + bastionProxyCommand(sshKeyPath, getBastionUser(outputs), bastionIP, knownHostsOption(stack), getBastionJumpHosts(outputs), false),
 			fmt.Sprintf("root@%s", targetIP),
 		}
 	} else {
@@ -262,9 +293,10 @@ func runSSHNode(cmd *cobra.Command, args []string) error {
 		sshArgs = []string{
 			"-i", sshKeyPath,
 			"-o", "StrictHostKeyChecking=accept-new",
-			"-o", "UserKnownHostsFile=/dev/null",
-			fmt.Sprintf("root@%s", targetNode.PublicIP),
+			"-o", knownHostsOption(stack),
 		}
+		sshArgs = append(sshArgs, sshProxyOptions()...)
+		sshArgs = append(sshArgs, fmt.Sprintf("root@%s", targetNode.PublicIP))
 	}
 
 	// Add custom command if specified
@@ -284,7 +316,17 @@ func runSSHNode(cmd *cobra.Command, args []string) error {
 		fmt.Println()
 	}
 
-	// Execute SSH with full I/O redirection
+	// Execute SSH with full I/O redirection. A one-off remote command is bound
+	// by --ssh-timeout; an interactive session is not.
+	if sshCommand != "" {
+		call := newSSHCommand(sshArgs...)
+		call.Path = sshPath
+		call.Stdin = os.Stdin
+		call.Stdout = os.Stdout
+		call.Stderr = os.Stderr
+		return call.Run()
+	}
+
 	execCmd := exec.Command(sshPath, sshArgs...)
 	execCmd.Stdin = os.Stdin
 	execCmd.Stdout = os.Stdout
@@ -293,125 +335,6 @@ func runSSHNode(cmd *cobra.Command, args []string) error {
 	return execCmd.Run()
 }
 
-func runAddNode(cmd *cobra.Command, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: sloth-kubernetes nodes add <stack-name> --pool <pool-name>")
-	}
-
-	stack := args[0]
-
-	// Validate required flags
-	if nodeName == "" {
-		return fmt.Errorf("--pool flag is required (specify which node pool to scale)")
-	}
-	poolName := nodeName // Reusing the nodeName flag for pool name
-
-	printHeader(fmt.Sprintf("➕ Adding node to stack: %s", stack))
-
-	// Get the config file path
-	configFile := cfgFile
-	if configFile == "" {
-		configFile = "./cluster-config.yaml"
-	}
-
-	printInfo(fmt.Sprintf("📄 Reading configuration from: %s", configFile))
-
-	// Read the current config file
-	configData, err := os.ReadFile(configFile)
-	if err != nil {
-		return fmt.Errorf("failed to read config file: %w", err)
-	}
-
-	// Parse YAML
-	var config map[string]interface{}
-	if err := yaml.Unmarshal(configData, &config); err != nil {
-		return fmt.Errorf("failed to parse config YAML: %w", err)
-	}
-
-	// Find and update the node pool
-	nodePools, ok := config["nodePools"].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("nodePools not found in configuration")
-	}
-
-	pool, ok := nodePools[poolName].(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("node pool '%s' not found in configuration", poolName)
-	}
-
-	// Get current count
-	currentCount, ok := pool["count"].(int)
-	if !ok {
-		return fmt.Errorf("count field not found or invalid in pool '%s'", poolName)
-	}
-
-	// Calculate new count
-	addCount := 1
-	if nodeSize != "" {
-		// If --count flag is provided, use it (reusing nodeSize flag for count)
-		fmt.Sscanf(nodeSize, "%d", &addCount)
-	}
-	newCount := currentCount + addCount
-
-	printInfo(fmt.Sprintf("📊 Current node count in pool '%s': %d", poolName, currentCount))
-	printInfo(fmt.Sprintf("➕ Adding %d node(s)", addCount))
-	printInfo(fmt.Sprintf("📈 New node count: %d", newCount))
-
-	// Update the count
-	pool["count"] = newCount
-
-	// Save the updated config to a temporary file
-	tempConfig := fmt.Sprintf("%s.add-node.tmp", configFile)
-	updatedData, err := yaml.Marshal(config)
-	if err != nil {
-		return fmt.Errorf("failed to marshal updated config: %w", err)
-	}
-
-	if err := os.WriteFile(tempConfig, updatedData, 0644); err != nil {
-		return fmt.Errorf("failed to write temporary config: %w", err)
-	}
-
-	printInfo(fmt.Sprintf("💾 Saved updated configuration to: %s", tempConfig))
-	fmt.Println()
-
-	color.Cyan("🚀 Running deployment to add node(s)...")
-	color.Yellow("⚠️  This will provision new infrastructure. Press Ctrl+C to cancel.")
-	fmt.Println()
-
-	// TODO: Call the deploy command programmatically
-	// For now, show the command the user should run
-	color.Green("✅ Configuration updated successfully!")
-	fmt.Println()
-	color.Cyan("To apply the changes, run:")
-	fmt.Printf("  ./sloth-kubernetes deploy %s --config %s --do-token $DIGITALOCEAN_TOKEN --linode-token $LINODE_TOKEN -y\n", stack, tempConfig)
-	fmt.Println()
-	color.Yellow("💡 Tip: After deployment completes, you can delete the temporary config file:")
-	fmt.Printf("  rm %s\n", tempConfig)
-
-	return nil
-}
-
-func runRemoveNode(cmd *cobra.Command, args []string) error {
-	if len(args) < 2 {
-		return fmt.Errorf("usage: sloth-kubernetes nodes remove <stack-name> <node-name>")
-	}
-
-	stack := args[0]
-	node := args[1]
-
-	printHeader(fmt.Sprintf("➖ Removing node '%s' from stack: %s", node, stack))
-
-	if !forceRemove {
-		color.Yellow("⚠️  Node will be drained before removal")
-	} else {
-		color.Red("⚠️  Force removal - node will NOT be drained!")
-	}
-
-	color.Yellow("⚠️  Remove node functionality will be implemented in next phase")
-
-	return nil
-}
-
 func printNodesTable(outputs auto.OutputMap) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
 	defer w.Flush()
@@ -471,12 +394,29 @@ func printNodesTableReal(nodes []NodeInfo) {
 	}
 }
 
-func getStackFromArgs(args []string, index int) string {
-	if len(args) > index {
-		return args[index]
+// getStackFromArgs resolves the stack a command operates on: the positional
+// argument at index, then --stack, then defaultStack in ~/.sloth-kubernetes/config
+func getStackFromArgs(args []string, index int) (string, error) {
+	if len(args) > index && args[index] != "" {
+		return args[index], nil
 	}
 	if stackName != "" {
-		return stackName
+		return stackName, nil
+	}
+	if stack := common.SavedConfigValue(common.DefaultStackKey); stack != "" {
+		return stack, nil
+	}
+	return "", fmt.Errorf("no stack specified\nusage: pass the stack name as an argument, use --stack <name>, or set %s=<name> in ~/.sloth-kubernetes/config", common.DefaultStackKey)
+}
+
+// splitStackArgs handles commands whose stack is an optional leading argument
+// followed by want other arguments ("<stack> <node>" or just "<node>").
+// It returns the resolved stack and the remaining arguments.
+func splitStackArgs(args []string, want int) (string, []string, error) {
+	if len(args) > want {
+		stack, err := getStackFromArgs(args, 0)
+		return stack, args[1:], err
 	}
-	return "production"
+	stack, err := getStackFromArgs(nil, 0)
+	return stack, args, err
 }
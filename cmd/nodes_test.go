@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 )
@@ -126,11 +128,13 @@ func TestGetStackFromArgs(t *testing.T) {
 	originalStackName := stackName
 
 	tests := []struct {
-		name         string
-		args         []string
-		index        int
-		stackNameVar string
-		expected     string
+		name          string
+		args          []string
+		index         int
+		stackNameVar  string
+		configDefault string
+		expected      string
+		wantErr       bool
 	}{
 		{
 			name:         "Stack from args",
@@ -154,11 +158,27 @@ func TestGetStackFromArgs(t *testing.T) {
 			expected:     "development",
 		},
 		{
-			name:         "Default to production",
+			name:          "Flag precedence over config default",
+			args:          []string{},
+			index:         0,
+			stackNameVar:  "development",
+			configDefault: "home-cluster",
+			expected:      "development",
+		},
+		{
+			name:          "Config default",
+			args:          []string{},
+			index:         0,
+			stackNameVar:  "",
+			configDefault: "home-cluster",
+			expected:      "home-cluster",
+		},
+		{
+			name:         "No stack anywhere",
 			args:         []string{},
 			index:        0,
 			stackNameVar: "",
-			expected:     "production",
+			wantErr:      true,
 		},
 		{
 			name:         "Args precedence over stackName",
@@ -175,21 +195,44 @@ func TestGetStackFromArgs(t *testing.T) {
 			expected:     "fallback",
 		},
 		{
-			name:         "Index out of bounds - default",
-			args:         []string{"production"},
-			index:        5,
-			stackNameVar: "",
-			expected:     "production",
+			name:          "Index out of bounds - config default",
+			args:          []string{"production"},
+			index:         5,
+			stackNameVar:  "",
+			configDefault: "home-cluster",
+			expected:      "home-cluster",
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			if tt.configDefault != "" {
+				configDir := filepath.Join(home, ".sloth-kubernetes")
+				if err := os.MkdirAll(configDir, 0700); err != nil {
+					t.Fatal(err)
+				}
+				content := "AWS_REGION=us-east-1\ndefaultStack=" + tt.configDefault + "\n"
+				if err := os.WriteFile(filepath.Join(configDir, "config"), []byte(content), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
 			// Set stackName variable
 			stackName = tt.stackNameVar
 
-			result := getStackFromArgs(tt.args, tt.index)
+			result, err := getStackFromArgs(tt.args, tt.index)
 
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error, got stack %q", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
 			if result != tt.expected {
 				t.Errorf("Expected %q, got %q", tt.expected, result)
 			}
@@ -200,6 +243,30 @@ func TestGetStackFromArgs(t *testing.T) {
 	stackName = originalStackName
 }
 
+// TestSplitStackArgs tests resolving an optional leading stack argument
+func TestSplitStackArgs(t *testing.T) {
+	originalStackName := stackName
+	defer func() { stackName = originalStackName }()
+	t.Setenv("HOME", t.TempDir())
+
+	stackName = "from-flag"
+
+	stack, rest, err := splitStackArgs([]string{"staging", "master-1"}, 1)
+	if err != nil || stack != "staging" || len(rest) != 1 || rest[0] != "master-1" {
+		t.Errorf("Explicit stack: got %q %v %v", stack, rest, err)
+	}
+
+	stack, rest, err = splitStackArgs([]string{"master-1"}, 1)
+	if err != nil || stack != "from-flag" || len(rest) != 1 || rest[0] != "master-1" {
+		t.Errorf("Omitted stack: got %q %v %v", stack, rest, err)
+	}
+
+	stackName = ""
+	if _, _, err := splitStackArgs([]string{"master-1"}, 1); err == nil {
+		t.Error("Expected error when no stack can be resolved")
+	}
+}
+
 // TestOutputFormatOptions tests output format options
 func TestOutputFormatOptions(t *testing.T) {
 	validFormats := []string{"table", "json", "yaml"}
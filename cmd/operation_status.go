@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// vpnJoinOperation names the status file of vpn join
+const vpnJoinOperation = "vpn-join"
+
+// operationStatus records which nodes a multi-node operation succeeded and
+// failed on, so a partially failed run can be resumed on the failed nodes only
+type operationStatus struct {
+	Operation string       `json:"operation"`
+	Stack     string       `json:"stack"`
+	Succeeded []string     `json:"succeeded"`
+	Failed    []string     `json:"failed"`
+	Peer      *vpnJoinPeer `json:"peer,omitempty"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// vpnJoinPeer is the peer a vpn join was adding. A resumed join must add the
+// same key and IP the succeeded nodes already have.
+type vpnJoinPeer struct {
+	IP           string `json:"ip"`
+	Label        string `json:"label,omitempty"`
+	PrivateKey   string `json:"private_key"`
+	PresharedKey string `json:"preshared_key,omitempty"`
+}
+
+// operationStatusDir returns the directory status files are kept in
+var operationStatusDir = func() (string, error) {
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get home directory: %w", err)
+	}
+	return filepath.Join(homeDir, ".sloth-kubernetes", "operations"), nil
+}
+
+// operationStatusPath returns the status file of operation on stack
+func operationStatusPath(stack, operation string) (string, error) {
+	dir, err := operationStatusDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.json", stack, operation)), nil
+}
+
+// saveOperationStatus writes status, replacing any earlier one. The file may
+// hold a private key, so it is only readable by the user.
+func saveOperationStatus(status *operationStatus) error {
+	path, err := operationStatusPath(status.Stack, status.Operation)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create status directory: %w", err)
+	}
+
+	status.UpdatedAt = time.Now()
+	data, err := json.MarshalIndent(status, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal status: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write status file: %w", err)
+	}
+	return nil
+}
+
+// loadOperationStatus reads the saved status of operation on stack, returning
+// nil when there is none
+func loadOperationStatus(stack, operation string) (*operationStatus, error) {
+	path, err := operationStatusPath(stack, operation)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read status file: %w", err)
+	}
+
+	var status operationStatus
+	if err := json.Unmarshal(data, &status); err != nil {
+		return nil, fmt.Errorf("failed to parse status file %s: %w", path, err)
+	}
+	return &status, nil
+}
+
+// removeOperationStatus deletes the saved status of operation on stack
+func removeOperationStatus(stack, operation string) error {
+	path, err := operationStatusPath(stack, operation)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove status file: %w", err)
+	}
+	return nil
+}
+
+// markSucceeded records that the operation succeeded on node
+func (s *operationStatus) markSucceeded(node string) {
+	s.Failed = removeName(s.Failed, node)
+	if !containsName(s.Succeeded, node) {
+		s.Succeeded = append(s.Succeeded, node)
+	}
+}
+
+// markFailed records that the operation failed on node
+func (s *operationStatus) markFailed(node string) {
+	s.Succeeded = removeName(s.Succeeded, node)
+	if !containsName(s.Failed, node) {
+		s.Failed = append(s.Failed, node)
+	}
+}
+
+// selectResumeNodes returns the nodes the saved status lists as failed, in
+// stack order, and the failed names no longer found in the stack
+func selectResumeNodes(nodes []NodeInfo, status *operationStatus) ([]NodeInfo, []string) {
+	present := make(map[string]bool, len(nodes))
+	var selected []NodeInfo
+	for _, node := range nodes {
+		present[node.Name] = true
+		if containsName(status.Failed, node.Name) {
+			selected = append(selected, node)
+		}
+	}
+
+	var missing []string
+	for _, name := range status.Failed {
+		if !present[name] {
+			missing = append(missing, name)
+		}
+	}
+	return selected, missing
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+func removeName(names []string, name string) []string {
+	var kept []string
+	for _, n := range names {
+		if n != name {
+			kept = append(kept, n)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+// stubOperationStatusDir keeps status files in a temporary directory
+func stubOperationStatusDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	original := operationStatusDir
+	operationStatusDir = func() (string, error) { return dir, nil }
+	t.Cleanup(func() { operationStatusDir = original })
+	return dir
+}
+
+func nodeNames(nodes []NodeInfo) string {
+	names := make([]string, len(nodes))
+	for i, node := range nodes {
+		names[i] = node.Name
+	}
+	return strings.Join(names, ",")
+}
+
+func TestOperationStatus_SaveLoadRemove(t *testing.T) {
+	stubOperationStatusDir(t)
+
+	if status, err := loadOperationStatus("production", vpnJoinOperation); err != nil || status != nil {
+		t.Fatalf("Expected no status before saving, got %v, %v", status, err)
+	}
+
+	status := &operationStatus{
+		Operation: vpnJoinOperation,
+		Stack:     "production",
+		Peer:      &vpnJoinPeer{IP: "10.8.0.100", Label: "laptop", PrivateKey: "PRIVATE="},
+	}
+	status.markSucceeded("master-1")
+	status.markFailed("worker-1")
+	if err := saveOperationStatus(status); err != nil {
+		t.Fatalf("Failed to save status: %v", err)
+	}
+
+	path, _ := operationStatusPath("production", vpnJoinOperation)
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Status file not written: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected status file mode 0600, got %o", info.Mode().Perm())
+	}
+
+	loaded, err := loadOperationStatus("production", vpnJoinOperation)
+	if err != nil || loaded == nil {
+		t.Fatalf("Failed to load status: %v", err)
+	}
+	if strings.Join(loaded.Succeeded, ",") != "master-1" || strings.Join(loaded.Failed, ",") != "worker-1" {
+		t.Errorf("Unexpected node lists: %+v", loaded)
+	}
+	if loaded.Peer == nil || loaded.Peer.IP != "10.8.0.100" || loaded.Peer.PrivateKey != "PRIVATE=" {
+		t.Errorf("Expected the peer to round-trip, got %+v", loaded.Peer)
+	}
+
+	if err := removeOperationStatus("production", vpnJoinOperation); err != nil {
+		t.Fatalf("Failed to remove status: %v", err)
+	}
+	if loaded, _ := loadOperationStatus("production", vpnJoinOperation); loaded != nil {
+		t.Error("Expected status to be gone after remove")
+	}
+	if err := removeOperationStatus("production", vpnJoinOperation); err != nil {
+		t.Errorf("Removing a missing status should not fail: %v", err)
+	}
+}
+
+func TestOperationStatus_MarkMovesNodes(t *testing.T) {
+	status := &operationStatus{}
+	status.markFailed("worker-1")
+	status.markFailed("worker-1")
+	status.markSucceeded("worker-1")
+
+	if len(status.Failed) != 0 || strings.Join(status.Succeeded, ",") != "worker-1" {
+		t.Errorf("Expected worker-1 to move to succeeded once, got %+v", status)
+	}
+}
+
+func TestSelectResumeNodes(t *testing.T) {
+	stubOperationStatusDir(t)
+
+	saved := &operationStatus{
+		Operation: vpnJoinOperation,
+		Stack:     "production",
+		Succeeded: []string{"master-1", "worker-2"},
+		Failed:    []string{"worker-3", "worker-1", "gone-1"},
+	}
+	if err := saveOperationStatus(saved); err != nil {
+		t.Fatalf("Failed to save status: %v", err)
+	}
+	status, err := loadOperationStatus("production", vpnJoinOperation)
+	if err != nil || status == nil {
+		t.Fatalf("Failed to load status: %v", err)
+	}
+
+	nodes := []NodeInfo{
+		{Name: "master-1"},
+		{Name: "worker-1"},
+		{Name: "worker-2"},
+		{Name: "worker-3"},
+		{Name: "worker-4"},
+	}
+	selected, missing := selectResumeNodes(nodes, status)
+
+	if got := nodeNames(selected); got != "worker-1,worker-3" {
+		t.Errorf("Expected only failed nodes in stack order, got %s", got)
+	}
+	if strings.Join(missing, ",") != "gone-1" {
+		t.Errorf("Expected gone-1 reported missing, got %v", missing)
+	}
+}
+
+func TestWireGuardPublicKey_MatchesGeneratedPair(t *testing.T) {
+	privateKey, publicKey, err := generateWireGuardKeypair()
+	if err != nil {
+		t.Fatalf("Failed to generate keypair: %v", err)
+	}
+
+	derived, err := wireGuardPublicKey(privateKey)
+	if err != nil {
+		t.Fatalf("Failed to derive public key: %v", err)
+	}
+	if derived != publicKey {
+		t.Errorf("Expected %s, got %s", publicKey, derived)
+	}
+
+	if _, err := wireGuardPublicKey("not base64!"); err == nil {
+		t.Error("Expected an error for an invalid private key")
+	}
+}
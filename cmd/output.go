@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/logging"
+)
+
+// logFormat is the --log-format flag; SLOTH_LOG_FORMAT applies when it is
+// not given
+var logFormat string
+
+// setupLogging selects the output format of the print helpers from
+// --log-format or SLOTH_LOG_FORMAT
+func setupLogging(cmd *cobra.Command) error {
+	name := os.Getenv(logging.EnvFormat)
+	if flag := cmd.Flags().Lookup("log-format"); flag != nil && flag.Changed {
+		name = logFormat
+	}
+	format, err := logging.ParseFormat(name)
+	if err != nil {
+		return err
+	}
+
+	// Escape sequences would end up inside the JSON messages
+	if format == logging.FormatJSON {
+		color.NoColor = true
+	}
+	logging.SetDefault(logging.New(logging.Stdout, format))
+	return nil
+}
+
+// jsonLogging reports whether output is written as JSON events
+func jsonLogging() bool {
+	return logging.Default().JSON()
+}
+
+func printHeader(text string) {
+	logging.Default().Header(text)
+}
+
+func printSuccess(text string) {
+	logging.Default().Success(text)
+}
+
+func printInfo(text string) {
+	logging.Default().Info(text)
+}
+
+func printWarning(text string) {
+	logging.Default().Warning(text)
+}
+
+// eventsOnlyStdout keeps stdout for the JSON events of the print helpers and
+// sends everything else printed there, such as colored progress, to stderr
+// until restore is called. Text output is left alone.
+func eventsOnlyStdout() (restore func()) {
+	if !jsonLogging() {
+		return func() {}
+	}
+	logger := logging.Default()
+	stdout, restoreStdout := stdoutToStderr()
+	logging.SetDefault(logging.New(stdout, logging.FormatJSON))
+	return func() {
+		logging.SetDefault(logger)
+		restoreStdout()
+	}
+}
+
+// pulumiProgress returns where Pulumi writes its progress. It goes to stderr
+// with JSON output, leaving stdout to the events.
+func pulumiProgress() io.Writer {
+	if jsonLogging() {
+		return os.Stderr
+	}
+	return os.Stdout
+}
+
+// printError reports the error a command failed with
+func printError(err error) {
+	if jsonLogging() {
+		logging.Default().Error(err.Error())
+		return
+	}
+	fmt.Fprintln(os.Stderr, err)
+}
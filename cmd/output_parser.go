@@ -14,12 +14,18 @@ type NodeInfo struct {
 	Name        string   `json:"name" yaml:"name"`
 	Provider    string   `json:"provider" yaml:"provider"`
 	Region      string   `json:"region" yaml:"region"`
+	Zone        string   `json:"zone,omitempty" yaml:"zone,omitempty"`
 	Size        string   `json:"size" yaml:"size"`
 	PublicIP    string   `json:"publicIP" yaml:"publicIP"`
 	PrivateIP   string   `json:"privateIP" yaml:"privateIP"`
 	WireGuardIP string   `json:"wireGuardIP" yaml:"wireGuardIP"`
 	Roles       []string `json:"roles" yaml:"roles"`
 	Status      string   `json:"status" yaml:"status"`
+	// Stack is the stack managing the node when it was added with nodes add
+	// rather than created by the deployment
+	Stack string `json:"stack,omitempty" yaml:"stack,omitempty"`
+	// Pool is the node pool a node added with nodes add was created from
+	Pool string `json:"pool,omitempty" yaml:"pool,omitempty"`
 }
 
 // VPNPeerInfo represents a VPN peer (external client)
@@ -84,12 +90,21 @@ func ParseNodeOutputs(outputs auto.OutputMap) ([]NodeInfo, error) {
 		if region, ok := nodeMap["region"].(string); ok {
 			node.Region = region
 		}
+		if zone, ok := nodeMap["zone"].(string); ok {
+			node.Zone = zone
+		}
 		if size, ok := nodeMap["size"].(string); ok {
 			node.Size = size
 		}
 		if status, ok := nodeMap["status"].(string); ok {
 			node.Status = status
 		}
+		if stack, ok := nodeMap["stack"].(string); ok {
+			node.Stack = stack
+		}
+		if pool, ok := nodeMap["pool"].(string); ok {
+			node.Pool = pool
+		}
 
 		// Parse roles array
 		if rolesData, ok := nodeMap["roles"].([]interface{}); ok {
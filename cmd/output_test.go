@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/logging"
+)
+
+// withLogFormatFlag returns a command with --log-format, restoring the
+// logger and colors after the test
+func withLogFormatFlag(t *testing.T) *cobra.Command {
+	t.Helper()
+	logger, noColor, format := logging.Default(), color.NoColor, logFormat
+	t.Cleanup(func() {
+		logging.SetDefault(logger)
+		color.NoColor, logFormat = noColor, format
+	})
+
+	cmd := &cobra.Command{}
+	cmd.Flags().StringVar(&logFormat, "log-format", "", "")
+	return cmd
+}
+
+func TestSetupLogging(t *testing.T) {
+	cmd := withLogFormatFlag(t)
+	t.Setenv(logging.EnvFormat, "")
+	if err := setupLogging(cmd); err != nil || jsonLogging() {
+		t.Fatalf("Expected text output by default, got json=%v err=%v", jsonLogging(), err)
+	}
+
+	t.Setenv(logging.EnvFormat, "json")
+	if err := setupLogging(cmd); err != nil || !jsonLogging() {
+		t.Fatalf("Expected SLOTH_LOG_FORMAT to select json, got json=%v err=%v", jsonLogging(), err)
+	}
+	if !color.NoColor {
+		t.Error("Expected colors disabled with json output")
+	}
+
+	// The flag wins over the environment
+	if err := cmd.Flags().Set("log-format", "text"); err != nil {
+		t.Fatal(err)
+	}
+	if err := setupLogging(cmd); err != nil || jsonLogging() {
+		t.Fatalf("Expected --log-format text to override the environment, got json=%v err=%v", jsonLogging(), err)
+	}
+
+	if err := cmd.Flags().Set("log-format", "xml"); err != nil {
+		t.Fatal(err)
+	}
+	if err := setupLogging(cmd); err == nil {
+		t.Error("Expected an invalid format to be rejected")
+	}
+}
+
+func TestEventsOnlyStdout(t *testing.T) {
+	withLogFormatFlag(t)
+	dir := t.TempDir()
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	realStdout, realStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = stdout, stderr
+	t.Cleanup(func() { os.Stdout, os.Stderr = realStdout, realStderr })
+
+	logging.SetDefault(logging.New(logging.Stdout, logging.FormatJSON))
+	restore := eventsOnlyStdout()
+	fmt.Println("plain progress")
+	printInfo("an event")
+	restore()
+
+	events, _ := os.ReadFile(stdout.Name())
+	progress, _ := os.ReadFile(stderr.Name())
+	if strings.Contains(string(events), "plain progress") || !strings.Contains(string(events), `"message":"an event"`) {
+		t.Errorf("Expected stdout to carry only the event, got %q", events)
+	}
+	if !strings.Contains(string(progress), "plain progress") {
+		t.Errorf("Expected plain output on stderr, got %q", progress)
+	}
+}
@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/spf13/cobra"
+	yaml "gopkg.in/yaml.v3"
+
+	"github.com/chalkan3/sloth-kubernetes/internal/validation"
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+var poolCmd = &cobra.Command{
+	Use:   "pool",
+	Short: "Manage node pools",
+	Long:  `Manage the node pools of a cluster as a whole`,
+}
+
+var poolRemoveCmd = &cobra.Command{
+	Use:   "remove [stack-name] <pool-name>",
+	Short: "Drain and remove every node of a pool",
+	Long: `Decommission a node pool: every node is drained and deleted from the
+cluster one at a time, its VPN peers and DNS records are removed, the
+instances are destroyed and the pool is dropped from the config file. Nodes
+added to the pool with nodes add are removed too, along with their stacks.
+
+The removal is refused when the cluster would be left without a master or
+a worker, or with an even number of masters. It is also refused, before any
+node is drained, when cluster-wide resources such as the VPN mesh depend on
+the pool's nodes: only the pool's own resources are ever destroyed, so drop
+the pool from the config and run deploy instead.`,
+	Example: `  # Remove the pool of a region being decommissioned
+  sloth-kubernetes pool remove production workers-fra1 --config cluster.yaml`,
+	RunE: runPoolRemove,
+}
+
+func init() {
+	rootCmd.AddCommand(poolCmd)
+	poolCmd.AddCommand(poolRemoveCmd)
+}
+
+// checkPoolRemoval refuses to remove a pool when the nodes left would not
+// keep the cluster running: at least one master, an odd number of masters
+// for etcd quorum, and at least one worker if the cluster has any
+func checkPoolRemoval(cfg *config.ClusterConfig, poolName string) error {
+	pool, ok := cfg.NodePools[poolName]
+	if !ok {
+		return fmt.Errorf("node pool '%s' not found in configuration", poolName)
+	}
+
+	remaining := *cfg
+	remaining.NodePools = make(map[string]config.NodePool, len(cfg.NodePools))
+	for name, p := range cfg.NodePools {
+		if name != poolName {
+			remaining.NodePools[name] = p
+		}
+	}
+	dist := validation.CalculateDistribution(&remaining)
+
+	if dist.Masters == 0 {
+		return errs.Mark(fmt.Errorf("removing pool '%s' would leave the cluster without a master node", poolName), errs.ErrQuorumRisk)
+	}
+	if config.HasRole(pool.Roles, config.RoleMaster) && dist.Masters > 1 && dist.Masters%2 == 0 {
+		return errs.Mark(fmt.Errorf("removing pool '%s' would leave %d master nodes: etcd needs an odd number (1, 3, 5, ...)", poolName, dist.Masters), errs.ErrQuorumRisk)
+	}
+	if config.HasRole(pool.Roles, config.RoleWorker) && dist.Workers == 0 {
+		return fmt.Errorf("removing pool '%s' would leave the cluster without a worker node", poolName)
+	}
+	return nil
+}
+
+// runPoolRemoval drains and deletes nodes from the cluster one at a time.
+// Before a control plane node goes, every surviving control plane node must
+// be Ready, so etcd keeps its quorum while the member leaves.
+func runPoolRemoval(nodes []string, controlPlane bool, survivingMasters []string, r nodeReplacer) error {
+	for i, node := range nodes {
+		printInfo(fmt.Sprintf("[%d/%d] removing %s", i+1, len(nodes), node))
+
+		if controlPlane {
+			ready, err := r.Schedulable(survivingMasters)
+			if err != nil {
+				return err
+			}
+			if ready < len(survivingMasters) {
+				return errs.Mark(fmt.Errorf("not removing %s: only %d of %d remaining master node(s) are Ready",
+					node, ready, len(survivingMasters)), errs.ErrQuorumRisk)
+			}
+		}
+
+		if err := r.Drain(node); err != nil {
+			return fmt.Errorf("failed to drain %s: %w", node, err)
+		}
+		if err := r.Delete(node); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", node, err)
+		}
+		color.Green("  ✓ %s removed from the cluster", node)
+	}
+	return nil
+}
+
+// splitPoolNodes splits the deployed nodes into the pool's, created by the
+// deployment under one of the pool's names or added to the pool with nodes
+// add, and the nodes staying in the cluster
+func splitPoolNodes(poolName string, names []string, deployed []NodeInfo) (removed, remaining []NodeInfo) {
+	inPool := make(map[string]bool, len(names))
+	for _, name := range names {
+		inPool[name] = true
+	}
+	for _, node := range deployed {
+		if inPool[node.Name] || node.Pool == poolName {
+			removed = append(removed, node)
+		} else {
+			remaining = append(remaining, node)
+		}
+	}
+	return removed, remaining
+}
+
+// removePoolNodes finishes the removal of the pool's nodes once they are out
+// of the cluster: their peers are removed as nodes remove does, then the
+// deployment's nodes are destroyed together with destroyDeployed and each
+// node added with nodes add in its own stack. A failure to remove peers is
+// only reported, like for a single node.
+func removePoolNodes(removed []NodeInfo, destroyDeployed func() error, r nodeDeleter) error {
+	for _, node := range removed {
+		if err := r.RemovePeers(node); err != nil {
+			printWarning(fmt.Sprintf("Could not remove the peer of %s everywhere: %v - remove it with: sloth-kubernetes vpn leave --vpn-ip %s", node.Name, err, node.WireGuardIP))
+		}
+	}
+
+	if err := destroyDeployed(); err != nil {
+		return err
+	}
+	for _, node := range removed {
+		if node.Stack != "" {
+			if err := r.Destroy(node); err != nil {
+				return fmt.Errorf("failed to destroy %s: %w", node.Name, err)
+			}
+		}
+		if err := r.Record(node); err != nil {
+			return fmt.Errorf("%s was destroyed but the stack outputs were not updated: %w", node.Name, err)
+		}
+	}
+	return nil
+}
+
+// removePoolFromConfigFile drops a pool from the nodePools of a config file,
+// keeping the rest of the file and its comments as they are
+func removePoolFromConfigFile(path, poolName string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config YAML: %w", err)
+	}
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return fmt.Errorf("nodePools not found in configuration")
+	}
+
+	pools := mappingValue(doc.Content[0], "nodePools")
+	if pools == nil || pools.Kind != yaml.MappingNode {
+		return fmt.Errorf("nodePools not found in configuration")
+	}
+	removed := false
+	for i := 0; i+1 < len(pools.Content); i += 2 {
+		if pools.Content[i].Value == poolName {
+			pools.Content = append(pools.Content[:i], pools.Content[i+2:]...)
+			removed = true
+			break
+		}
+	}
+	if !removed {
+		return fmt.Errorf("node pool '%s' not found in configuration", poolName)
+	}
+
+	updated, err := yaml.Marshal(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to marshal updated config: %w", err)
+	}
+	return os.WriteFile(path, updated, 0644)
+}
+
+// mappingValue returns the value of key in a YAML mapping, or nil
+func mappingValue(mapping *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func runPoolRemove(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, rest, err := splitStackArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 {
+		return fmt.Errorf("usage: sloth-kubernetes pool remove [stack-name] <pool-name>")
+	}
+	poolName := rest[0]
+
+	configFile := cfgFile
+	if configFile == "" {
+		configFile = "./cluster-config.yaml"
+	}
+	cfg, err := config.LoadFromYAML(configFile)
+	if err != nil {
+		return fmt.Errorf("failed to load config file: %w", err)
+	}
+	if err := checkPoolRemoval(cfg, poolName); err != nil {
+		return err
+	}
+	pool := cfg.NodePools[poolName]
+	names, err := poolNodeNames(poolName, &pool)
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("➖ Removing pool '%s' from stack: %s", poolName, stack))
+
+	workspace, err := createWorkspaceWithS3Support(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+	s, err := auto.SelectStack(ctx, fmt.Sprintf("organization/sloth-kubernetes/%s", stack), workspace)
+	if err != nil {
+		return selectStackError(err, stack)
+	}
+	outputs, err := stackOutputsWithRetry(ctx, s)
+	if err != nil {
+		return err
+	}
+	deployed, err := ParseNodeOutputs(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	removed, remaining := splitPoolNodes(poolName, names, deployed)
+
+	// Nodes added with nodes add are destroyed in their own stacks
+	var removedNames, deployedNames, survivingMasters []string
+	for _, node := range removed {
+		removedNames = append(removedNames, node.Name)
+		if node.Stack == "" {
+			deployedNames = append(deployedNames, node.Name)
+		}
+	}
+	for _, node := range controlPlaneNodes(remaining) {
+		survivingMasters = append(survivingMasters, node.Name)
+	}
+
+	// Destroying only the pool's resources must not strand the cluster-wide
+	// resources built on them, checked before any node is drained
+	plan, err := checkNodeDestroy(ctx, s, deployedNames)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	printInfo(fmt.Sprintf("Pool '%s' (%s, %s): %d deployed node(s)", poolName, pool.Provider, pool.Region, len(removed)))
+	for _, name := range removedNames {
+		fmt.Printf("  - %s\n", name)
+	}
+	if !autoApprove && !confirm(fmt.Sprintf("Drain and destroy every node of pool '%s'?", poolName)) {
+		printWarning("Pool removal cancelled")
+		return nil
+	}
+
+	if len(removed) > 0 {
+		access := newNodeSSHAccess(stack, outputs)
+		if err := checkBastionReachable(ctx, access); err != nil {
+			return err
+		}
+		server, err := findReachableNode(controlPlaneNodes(remaining), access)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println()
+		printInfo("Draining and deleting nodes...")
+		replacer := stackNodeReplacer{ctx: ctx, stack: s, stackName: stack, server: server, access: access}
+		controlPlane := config.HasRole(pool.Roles, config.RoleMaster)
+		if err := runPoolRemoval(removedNames, controlPlane, survivingMasters, replacer); err != nil {
+			return fmt.Errorf("removal of pool '%s' stopped: %w", poolName, err)
+		}
+
+		printInfo("Removing VPN peers, instances and DNS records...")
+		deleter := stackNodeDeleter{stackNodeReplacer: replacer, remaining: remaining, plan: plan}
+		destroyDeployed := func() error { return destroyNodeResources(ctx, s, stack, plan) }
+		if err := removePoolNodes(removed, destroyDeployed, deleter); err != nil {
+			return err
+		}
+	}
+
+	if err := removePoolFromConfigFile(configFile, poolName); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	printSuccess(fmt.Sprintf("Pool '%s' removed; %s no longer defines it", poolName, configFile))
+	return nil
+}
@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto/optup"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+// nodeReplacePollInterval and nodeReplacePollAttempts bound the wait for a
+// new node to become Ready. They are variables so tests do not sleep.
+var (
+	nodeReplacePollInterval = 10 * time.Second
+	nodeReplacePollAttempts = 60
+)
+
+// poolReplacement is the planned rolling replacement of one pool
+type poolReplacement struct {
+	Pool string
+
+	// OldSizes maps the nodes to replace to the size they run now
+	OldSizes map[string]string
+	Steps    []config.ReplacementStep
+}
+
+// nodeReplacer performs the steps of a rolling node replacement
+type nodeReplacer interface {
+	// Apply brings the infrastructure in line with the config
+	Apply() error
+	// WaitReady waits for node to join the cluster and become Ready
+	WaitReady(node string) error
+	// Schedulable returns how many of nodes are Ready and not cordoned
+	Schedulable(nodes []string) (int, error)
+	// Drain cordons node and evicts its pods
+	Drain(node string) error
+	// Delete removes node from the cluster
+	Delete(node string) error
+}
+
+// planPoolReplacements plans a rolling replacement for every worker pool
+// with deployed nodes of another size than the config asks for. Control
+// plane pools are resized in place by the deployment, as before.
+func planPoolReplacements(cfg *config.ClusterConfig, deployed []NodeInfo) ([]poolReplacement, error) {
+	sizes := make(map[string]string)
+	for _, node := range deployed {
+		sizes[node.Name] = node.Size
+	}
+
+	var plans []poolReplacement
+	for _, poolName := range config.SortedPoolNames(cfg.NodePools) {
+		pool := cfg.NodePools[poolName]
+		stale, err := config.StaleSizeNodes(poolName, &pool, sizes)
+		if err != nil {
+			return nil, err
+		}
+		if len(stale) == 0 {
+			continue
+		}
+		if config.HasRole(pool.Roles, config.RoleMaster) {
+			printWarning(fmt.Sprintf("Pool '%s' changes size: control plane nodes are resized in place, one at a time", poolName))
+			continue
+		}
+
+		steps, err := config.PlanSizeReplacement(poolName, &pool, stale)
+		if err != nil {
+			return nil, err
+		}
+
+		oldSizes := make(map[string]string, len(stale))
+		for _, node := range stale {
+			oldSizes[node] = sizes[node]
+		}
+		plans = append(plans, poolReplacement{Pool: poolName, OldSizes: oldSizes, Steps: steps})
+	}
+	return plans, nil
+}
+
+// printPoolReplacement prints the steps of a planned replacement
+func printPoolReplacement(cfg *config.ClusterConfig, plan poolReplacement) {
+	pool := cfg.NodePools[plan.Pool]
+	printInfo(fmt.Sprintf("🔁 Pool '%s' changes size to %s: replacing %d node(s) one at a time", plan.Pool, pool.Size, len(plan.OldSizes)))
+	for i, step := range plan.Steps {
+		fmt.Printf("  %2d. %-6s %s (schedulable nodes: %d)\n", i+1, step.Action, step.Node, step.Capacity)
+	}
+}
+
+// poolNodeNames returns the names of the nodes pool currently has, its
+// surge node included
+func poolNodeNames(poolName string, pool *config.NodePool) ([]string, error) {
+	var names []string
+	for i := 0; i < pool.Count; i++ {
+		name, err := config.RenderPoolNodeName(poolName, pool, i)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.ExcludedNodes[name] {
+			names = append(names, name)
+		}
+	}
+	if pool.Surge {
+		names = append(names, config.SurgeNodeName(poolName))
+	}
+	return names, nil
+}
+
+// runPoolReplacement executes a planned replacement. Every infrastructure
+// change is made by shaping the pool in cfg and applying it, and no node is
+// drained while that would leave the pool below its minCount.
+func runPoolReplacement(cfg *config.ClusterConfig, plan poolReplacement, r nodeReplacer) error {
+	update := func(change func(pool *config.NodePool)) {
+		pool := cfg.NodePools[plan.Pool]
+		change(&pool)
+		cfg.NodePools[plan.Pool] = pool
+	}
+
+	// Nodes not replaced yet keep running their current size
+	update(func(pool *config.NodePool) {
+		pool.NodeSizes = make(map[string]string, len(plan.OldSizes))
+		for node, size := range plan.OldSizes {
+			pool.NodeSizes[node] = size
+		}
+		pool.ExcludedNodes = make(map[string]bool)
+	})
+
+	surge := config.SurgeNodeName(plan.Pool)
+	for i, step := range plan.Steps {
+		printInfo(fmt.Sprintf("[%d/%d] %s %s", i+1, len(plan.Steps), step.Action, step.Node))
+
+		switch step.Action {
+		case config.ReplacementCreate:
+			update(func(pool *config.NodePool) {
+				if step.Node == surge {
+					pool.Surge = true
+				}
+				delete(pool.ExcludedNodes, step.Node)
+				delete(pool.NodeSizes, step.Node)
+			})
+			if err := r.Apply(); err != nil {
+				return fmt.Errorf("failed to create %s: %w", step.Node, err)
+			}
+
+		case config.ReplacementJoin:
+			if err := r.WaitReady(step.Node); err != nil {
+				return err
+			}
+
+		case config.ReplacementDrain:
+			pool := cfg.NodePools[plan.Pool]
+			nodes, err := poolNodeNames(plan.Pool, &pool)
+			if err != nil {
+				return err
+			}
+			schedulable, err := r.Schedulable(nodes)
+			if err != nil {
+				return err
+			}
+			if schedulable-1 < pool.MinCount {
+				return fmt.Errorf("not draining %s: pool '%s' has %d schedulable node(s) and must keep its minCount of %d",
+					step.Node, plan.Pool, schedulable, pool.MinCount)
+			}
+			if err := r.Drain(step.Node); err != nil {
+				return err
+			}
+
+		case config.ReplacementRemove:
+			if err := r.Delete(step.Node); err != nil {
+				return err
+			}
+			update(func(pool *config.NodePool) {
+				if step.Node == surge {
+					pool.Surge = false
+				} else {
+					pool.ExcludedNodes[step.Node] = true
+				}
+			})
+			if err := r.Apply(); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", step.Node, err)
+			}
+		}
+	}
+
+	update(func(pool *config.NodePool) {
+		pool.NodeSizes = nil
+		pool.ExcludedNodes = nil
+	})
+	return nil
+}
+
+// stackNodeReplacer replaces nodes by updating the Pulumi stack, and drains
+// and watches them with kubectl on a control plane node
+type stackNodeReplacer struct {
+	ctx       context.Context
+	stack     auto.Stack
+	stackName string
+	server    NodeInfo
+	access    nodeSSHAccess
+}
+
+func (r stackNodeReplacer) Apply() error {
+	if _, err := r.stack.Up(r.ctx, optup.ProgressStreams(pulumiProgress())); err != nil {
+		return stackLockedError(err, "deploy", r.stackName)
+	}
+	return nil
+}
+
+func (r stackNodeReplacer) WaitReady(node string) error {
+	script := kubectlScriptPrefix + fmt.Sprintf(`kubectl get node '%s' -o jsonpath='{.status.conditions[?(@.type=="Ready")].status}' 2>/dev/null || true`, node)
+	for attempt := 0; attempt < nodeReplacePollAttempts; attempt++ {
+		output, err := runKubectlScript(r.server, r.access, script)
+		if err == nil && strings.TrimSpace(output) == "True" {
+			color.Green("  ✓ %s is Ready", node)
+			return nil
+		}
+		time.Sleep(nodeReplacePollInterval)
+	}
+	return fmt.Errorf("node %s did not become Ready", node)
+}
+
+func (r stackNodeReplacer) Schedulable(nodes []string) (int, error) {
+	output, err := runKubectlScript(r.server, r.access, kubectlScriptPrefix+"kubectl get nodes --no-headers\n")
+	if err != nil {
+		return 0, err
+	}
+	return countSchedulableNodes(output, nodes), nil
+}
+
+func (r stackNodeReplacer) Drain(node string) error {
+	_, err := runKubectlScript(r.server, r.access, kubectlScriptPrefix+fmt.Sprintf(
+		"kubectl drain '%s' --ignore-daemonsets --delete-emptydir-data --timeout=600s\n", node))
+	return err
+}
+
+func (r stackNodeReplacer) Delete(node string) error {
+	_, err := runKubectlScript(r.server, r.access, kubectlScriptPrefix+fmt.Sprintf(
+		"kubectl delete node '%s' --ignore-not-found\n", node))
+	return err
+}
+
+// countSchedulableNodes counts the nodes of `kubectl get nodes` output that
+// are among nodes and Ready without SchedulingDisabled
+func countSchedulableNodes(output string, nodes []string) int {
+	wanted := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		wanted[node] = true
+	}
+
+	count := 0
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && wanted[fields[0]] && fields[1] == "Ready" {
+			count++
+		}
+	}
+	return count
+}
+
+// replaceResizedPools rolls worker pools whose size changed over to the new
+// size node by node, so the regular update that follows does not resize
+// them in place. With dryRun the plans are only printed.
+func replaceResizedPools(ctx context.Context, stack auto.Stack, stackName string, cfg *config.ClusterConfig, dryRun bool) error {
+	outputs, err := stackOutputsWithRetry(ctx, stack)
+	if err != nil {
+		return err
+	}
+	nodes, err := ParseNodeOutputs(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	plans, err := planPoolReplacements(cfg, nodes)
+	if err != nil || len(plans) == 0 {
+		return err
+	}
+
+	fmt.Println()
+	for _, plan := range plans {
+		printPoolReplacement(cfg, plan)
+	}
+	if dryRun {
+		return nil
+	}
+
+	access := newNodeSSHAccess(stackName, outputs)
+	server, err := findReachableNode(controlPlaneNodes(nodes), access)
+	if err != nil {
+		return err
+	}
+
+	replacer := stackNodeReplacer{ctx: ctx, stack: stack, stackName: stackName, server: server, access: access}
+	for _, plan := range plans {
+		fmt.Println()
+		printHeader(fmt.Sprintf("🔁 Replacing nodes of pool '%s'", plan.Pool))
+		if err := runPoolReplacement(cfg, plan, replacer); err != nil {
+			return fmt.Errorf("rolling replacement of pool '%s' stopped: %w", plan.Pool, err)
+		}
+		printSuccess(fmt.Sprintf("Pool '%s' runs %s on every node", plan.Pool, cfg.NodePools[plan.Pool].Size))
+	}
+	return nil
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+// fakeNodeReplacer records the replacement steps and, on every Apply, the
+// nodes and sizes the pool would deploy
+type fakeNodeReplacer struct {
+	cfg         *config.ClusterConfig
+	pool        string
+	schedulable int
+	calls       []string
+}
+
+func (r *fakeNodeReplacer) Apply() error {
+	pool := r.cfg.NodePools[r.pool]
+	names, err := poolNodeNames(r.pool, &pool)
+	if err != nil {
+		return err
+	}
+	var nodes []string
+	for _, name := range names {
+		nodes = append(nodes, name+"="+pool.NodeSize(name))
+	}
+	r.calls = append(r.calls, "apply "+strings.Join(nodes, ","))
+	return nil
+}
+
+func (r *fakeNodeReplacer) WaitReady(node string) error {
+	r.calls = append(r.calls, "ready "+node)
+	return nil
+}
+
+func (r *fakeNodeReplacer) Schedulable(nodes []string) (int, error) {
+	return r.schedulable, nil
+}
+
+func (r *fakeNodeReplacer) Drain(node string) error {
+	r.calls = append(r.calls, "drain "+node)
+	return nil
+}
+
+func (r *fakeNodeReplacer) Delete(node string) error {
+	r.calls = append(r.calls, "delete "+node)
+	return nil
+}
+
+func poolReplaceTestConfig() *config.ClusterConfig {
+	return &config.ClusterConfig{
+		NodePools: map[string]config.NodePool{
+			"masters": {Name: "masters", Count: 1, Size: "s-4vcpu-8gb", Roles: []string{"master"}},
+			"workers": {Name: "workers", Count: 2, MinCount: 2, Size: "s-4vcpu-8gb", Roles: []string{"worker"}},
+		},
+	}
+}
+
+func TestPlanPoolReplacements(t *testing.T) {
+	cfg := poolReplaceTestConfig()
+	deployed := []NodeInfo{
+		{Name: "masters-1", Size: "s-2vcpu-4gb"},
+		{Name: "workers-1", Size: "s-2vcpu-4gb"},
+		{Name: "workers-2", Size: "s-2vcpu-4gb"},
+	}
+
+	plans, err := planPoolReplacements(cfg, deployed)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(plans) != 1 || plans[0].Pool != "workers" {
+		t.Fatalf("Expected only the worker pool to be replaced, got %+v", plans)
+	}
+	want := map[string]string{"workers-1": "s-2vcpu-4gb", "workers-2": "s-2vcpu-4gb"}
+	if !reflect.DeepEqual(plans[0].OldSizes, want) {
+		t.Errorf("Expected old sizes %v, got %v", want, plans[0].OldSizes)
+	}
+
+	deployed[1].Size, deployed[2].Size = "s-4vcpu-8gb", "s-4vcpu-8gb"
+	if plans, err := planPoolReplacements(cfg, deployed); err != nil || len(plans) != 0 {
+		t.Errorf("Expected nothing to replace once every worker runs the new size, got %+v (%v)", plans, err)
+	}
+}
+
+func TestRunPoolReplacement_Ordering(t *testing.T) {
+	cfg := poolReplaceTestConfig()
+	plans, err := planPoolReplacements(cfg, []NodeInfo{
+		{Name: "workers-1", Size: "s-2vcpu-4gb"},
+		{Name: "workers-2", Size: "s-2vcpu-4gb"},
+	})
+	if err != nil || len(plans) != 1 {
+		t.Fatalf("Expected one plan, got %+v (%v)", plans, err)
+	}
+
+	r := &fakeNodeReplacer{cfg: cfg, pool: "workers", schedulable: 3}
+	if err := runPoolReplacement(cfg, plans[0], r); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []string{
+		"apply workers-1=s-2vcpu-4gb,workers-2=s-2vcpu-4gb,workers-surge=s-4vcpu-8gb",
+		"ready workers-surge",
+		"drain workers-1",
+		"delete workers-1",
+		"apply workers-2=s-2vcpu-4gb,workers-surge=s-4vcpu-8gb",
+		"apply workers-1=s-4vcpu-8gb,workers-2=s-2vcpu-4gb,workers-surge=s-4vcpu-8gb",
+		"ready workers-1",
+		"drain workers-2",
+		"delete workers-2",
+		"apply workers-1=s-4vcpu-8gb,workers-surge=s-4vcpu-8gb",
+		"apply workers-1=s-4vcpu-8gb,workers-2=s-4vcpu-8gb,workers-surge=s-4vcpu-8gb",
+		"ready workers-2",
+		"drain workers-surge",
+		"delete workers-surge",
+		"apply workers-1=s-4vcpu-8gb,workers-2=s-4vcpu-8gb",
+	}
+	if !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("Expected:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(r.calls, "\n"))
+	}
+
+	pool := cfg.NodePools["workers"]
+	if pool.Surge || pool.NodeSizes != nil || pool.ExcludedNodes != nil {
+		t.Errorf("Expected the rollout state to be cleared, got %+v", pool)
+	}
+}
+
+func TestRunPoolReplacement_MinCountGuard(t *testing.T) {
+	cfg := poolReplaceTestConfig()
+	plans, _ := planPoolReplacements(cfg, []NodeInfo{{Name: "workers-1", Size: "s-2vcpu-4gb"}})
+
+	// The surge node joined but another worker went NotReady meanwhile
+	r := &fakeNodeReplacer{cfg: cfg, pool: "workers", schedulable: 2}
+	err := runPoolReplacement(cfg, plans[0], r)
+	if err == nil || !strings.Contains(err.Error(), "not draining workers-1: pool 'workers' has 2 schedulable node(s) and must keep its minCount of 2") {
+		t.Fatalf("Expected the drain to be refused, got %v", err)
+	}
+	for _, call := range r.calls {
+		if strings.HasPrefix(call, "drain") {
+			t.Errorf("Expected no node to be drained, got %v", r.calls)
+		}
+	}
+}
+
+func TestCountSchedulableNodes(t *testing.T) {
+	output := strings.Join([]string{
+		"masters-1       Ready                      control-plane,etcd,master   10d   v1.28.5+rke2r1",
+		"workers-1       Ready,SchedulingDisabled   <none>                      10d   v1.28.5+rke2r1",
+		"workers-2       Ready                      <none>                      10d   v1.28.5+rke2r1",
+		"workers-surge   NotReady                   <none>                      1m    v1.28.5+rke2r1",
+	}, "\n")
+
+	if got := countSchedulableNodes(output, []string{"workers-1", "workers-2", "workers-surge"}); got != 1 {
+		t.Errorf("Expected only workers-2 to be schedulable, got %d", got)
+	}
+}
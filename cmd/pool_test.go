@@ -0,0 +1,236 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+func poolRemovalTestConfig() *config.ClusterConfig {
+	return &config.ClusterConfig{
+		NodePools: map[string]config.NodePool{
+			"masters-nyc": {Name: "masters-nyc", Count: 3, Roles: []string{"master"}},
+			"masters-fra": {Name: "masters-fra", Count: 2, Roles: []string{"master"}},
+			"workers-nyc": {Name: "workers-nyc", Count: 2, Roles: []string{"worker"}},
+			"workers-fra": {Name: "workers-fra", Count: 2, Roles: []string{"worker"}},
+		},
+	}
+}
+
+func TestCheckPoolRemoval(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(cfg *config.ClusterConfig)
+		pool    string
+		wantErr string
+		quorum  bool
+	}{
+		{name: "worker pool with workers left", pool: "workers-fra"},
+		{name: "master pool leaving an odd count", pool: "masters-fra"},
+		{name: "unknown pool", pool: "workers-sfo", wantErr: "node pool 'workers-sfo' not found"},
+		{
+			name:    "last worker pool",
+			change:  func(cfg *config.ClusterConfig) { delete(cfg.NodePools, "workers-nyc") },
+			pool:    "workers-fra",
+			wantErr: "without a worker node",
+		},
+		{
+			name:    "last master pool",
+			change:  func(cfg *config.ClusterConfig) { delete(cfg.NodePools, "masters-fra") },
+			pool:    "masters-nyc",
+			wantErr: "without a master node",
+			quorum:  true,
+		},
+		{
+			name: "master pool leaving an even count",
+			change: func(cfg *config.ClusterConfig) {
+				pool := cfg.NodePools["masters-fra"]
+				pool.Count = 1
+				cfg.NodePools["masters-fra"] = pool
+				pool = cfg.NodePools["masters-nyc"]
+				pool.Count = 2
+				cfg.NodePools["masters-nyc"] = pool
+			},
+			pool:    "masters-fra",
+			wantErr: "would leave 2 master nodes",
+			quorum:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := poolRemovalTestConfig()
+			if tt.change != nil {
+				tt.change(cfg)
+			}
+
+			err := checkPoolRemoval(cfg, tt.pool)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if got := errors.Is(err, errs.ErrQuorumRisk); got != tt.quorum {
+				t.Errorf("errors.Is(err, ErrQuorumRisk) = %v, want %v", got, tt.quorum)
+			}
+		})
+	}
+}
+
+func TestCheckPoolRemoval_LeavesConfigUntouched(t *testing.T) {
+	cfg := poolRemovalTestConfig()
+	if err := checkPoolRemoval(cfg, "workers-fra"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := cfg.NodePools["workers-fra"]; !ok {
+		t.Error("checkPoolRemoval removed the pool from the config")
+	}
+}
+
+func TestRunPoolRemoval_DrainsThenDeletesEachNode(t *testing.T) {
+	r := &fakeNodeReplacer{}
+	if err := runPoolRemoval([]string{"workers-fra-1", "workers-fra-2"}, false, nil, r); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"drain workers-fra-1", "delete workers-fra-1", "drain workers-fra-2", "delete workers-fra-2"}
+	if !reflect.DeepEqual(r.calls, want) {
+		t.Errorf("calls = %v, want %v", r.calls, want)
+	}
+}
+
+func TestRunPoolRemoval_StopsWhenMastersAreNotReady(t *testing.T) {
+	r := &fakeNodeReplacer{schedulable: 2}
+	err := runPoolRemoval([]string{"masters-fra-1"}, true, []string{"masters-nyc-1", "masters-nyc-2", "masters-nyc-3"}, r)
+	if !errors.Is(err, errs.ErrQuorumRisk) {
+		t.Fatalf("expected a quorum risk error, got %v", err)
+	}
+	if len(r.calls) != 0 {
+		t.Errorf("no node should be touched, got %v", r.calls)
+	}
+}
+
+func TestSplitPoolNodes(t *testing.T) {
+	deployed := []NodeInfo{
+		{Name: "masters-1"},
+		{Name: "workers-fra-1"},
+		{Name: "workers-fra-2"},
+		{Name: "workers-fra-3", Pool: "workers-fra", Stack: "prod-node-workers-fra-3"},
+		{Name: "workers-nyc-2", Pool: "workers-nyc", Stack: "prod-node-workers-nyc-2"},
+	}
+
+	removed, remaining := splitPoolNodes("workers-fra", []string{"workers-fra-1", "workers-fra-2"}, deployed)
+	var removedNames, remainingNames []string
+	for _, node := range removed {
+		removedNames = append(removedNames, node.Name)
+	}
+	for _, node := range remaining {
+		remainingNames = append(remainingNames, node.Name)
+	}
+	if want := []string{"workers-fra-1", "workers-fra-2", "workers-fra-3"}; !reflect.DeepEqual(removedNames, want) {
+		t.Errorf("removed = %v, want %v", removedNames, want)
+	}
+	if want := []string{"masters-1", "workers-nyc-2"}; !reflect.DeepEqual(remainingNames, want) {
+		t.Errorf("remaining = %v, want %v", remainingNames, want)
+	}
+}
+
+func TestRemovePoolNodes_DestroysAddedNodeStacks(t *testing.T) {
+	removed := []NodeInfo{
+		{Name: "workers-fra-1", WireGuardIP: "10.8.0.11"},
+		{Name: "workers-fra-3", WireGuardIP: "10.8.0.13", Stack: "prod-node-workers-fra-3"},
+	}
+	deleter := &fakeNodeDeleter{failAt: "unpeer"}
+	destroyed := 0
+	destroyDeployed := func() error {
+		destroyed++
+		deleter.calls = append(deleter.calls, "destroy deployed")
+		return nil
+	}
+
+	if err := removePoolNodes(removed, destroyDeployed, deleter); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"unpeer", "unpeer", "destroy deployed", "record", "destroy", "record"}
+	if !reflect.DeepEqual(deleter.calls, want) {
+		t.Errorf("calls = %v, want %v", deleter.calls, want)
+	}
+	if destroyed != 1 {
+		t.Errorf("expected the deployment's nodes to be destroyed once, got %d", destroyed)
+	}
+}
+
+func TestStackNodeDeleterRemovePeers_RemovesBastionPeer(t *testing.T) {
+	bastionRemoved := false
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		last := args[len(args)-1]
+		switch {
+		case strings.Contains(last, "wg show wg0 dump"):
+			return []byte("KEY3=\n"), nil
+		case last == peerRemoveCommand("KEY3="):
+			if args[len(args)-2] == "root@203.0.113.5" {
+				bastionRemoved = true
+			}
+			return []byte("SUCCESS\n"), nil
+		}
+		return nil, nil
+	})
+
+	access := nodeSSHAccess{Stack: "prod", KeyPath: "/tmp/key", BastionEnabled: true, BastionIP: "203.0.113.5"}
+	deleter := stackNodeDeleter{
+		stackNodeReplacer: stackNodeReplacer{access: access},
+		remaining:         []NodeInfo{{Name: "masters-1", PublicIP: "198.51.100.10", PrivateIP: "10.0.0.10", WireGuardIP: "10.8.0.10"}},
+	}
+	if err := deleter.RemovePeers(NodeInfo{Name: "workers-fra-3", WireGuardIP: "10.8.0.13"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bastionRemoved {
+		t.Error("expected the peer to be removed from the bastion")
+	}
+}
+
+func TestRemovePoolFromConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cluster.yaml")
+	original := `metadata:
+  name: prod
+nodePools:
+  # primary region
+  workers-nyc:
+    count: 2
+  workers-fra:
+    count: 2
+`
+	if err := os.WriteFile(path, []byte(original), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := removePoolFromConfigFile(path, "workers-fra"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "workers-fra") {
+		t.Errorf("pool still in config:\n%s", data)
+	}
+	for _, kept := range []string{"workers-nyc:", "# primary region", "name: prod"} {
+		if !strings.Contains(string(data), kept) {
+			t.Errorf("config lost %q:\n%s", kept, data)
+		}
+	}
+
+	if err := removePoolFromConfigFile(path, "workers-fra"); err == nil {
+		t.Error("expected an error removing a pool that is gone")
+	}
+}
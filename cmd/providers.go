@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/chalkan3/sloth-kubernetes/internal/validation"
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+var providersCmd = &cobra.Command{
+	Use:   "providers",
+	Short: "Inspect the cloud providers of a cluster",
+	Long:  `Inspect the cloud providers a cluster is deployed on.`,
+}
+
+var providersTestCmd = &cobra.Command{
+	Use:   "test [stack]",
+	Short: "Check the API token permissions of each provider",
+	Long: `Check that the API token of every enabled provider can do what a deployment
+needs, reporting pass or fail per capability:
+
+  read     list regions
+  compute  create droplets / Linode instances
+  network  create VPCs
+  dns      create records in the cluster domain, when the provider serves it
+
+Create permissions are checked with requests the provider authorizes and then
+rejects for an invalid region, so nothing is created. This catches a token that
+can list but not create before a deployment spends time on it.
+
+The tokens are read from --config when given, falling back to DIGITALOCEAN_TOKEN
+and LINODE_TOKEN, otherwise from the stack config.`,
+	Example: `  # Check the tokens stored in a stack
+  sloth-kubernetes providers test production
+
+  # Check the tokens of a cluster config before the first deploy
+  sloth-kubernetes providers test --config cluster.yaml`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runProvidersTest,
+}
+
+func init() {
+	providersCmd.AddCommand(providersTestCmd)
+	rootCmd.AddCommand(providersCmd)
+}
+
+func runProvidersTest(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	var cfg *config.ClusterConfig
+	if cfgFile != "" {
+		var err error
+		cfg, err = config.LoadFromYAML(cfgFile)
+		if err != nil {
+			return fmt.Errorf("failed to load config file: %w", err)
+		}
+		printHeader(fmt.Sprintf("🔑 Provider Permissions - Config: %s", cfgFile))
+	} else {
+		stack, err := getStackFromArgs(args, 0)
+		if err != nil {
+			return err
+		}
+		printHeader(fmt.Sprintf("🔑 Provider Permissions - Stack: %s", stack))
+
+		s, err := selectConvertStack(ctx, stack)
+		if err != nil {
+			return err
+		}
+		stackConfig, err := s.GetAllConfig(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read stack config: %w", err)
+		}
+		cfg = providersFromStackValues(pulumiValuesFromStackConfig(stackConfig))
+	}
+
+	checks := validation.CheckProviderPermissions(cfg)
+	if len(checks) == 0 {
+		return fmt.Errorf("no provider is enabled")
+	}
+
+	if failed := printPermissionChecks(os.Stdout, checks); failed > 0 {
+		return fmt.Errorf("%d permission check(s) failed", failed)
+	}
+	printSuccess("Every provider token has the permissions a deployment needs")
+	return nil
+}
+
+// providersFromStackValues returns a config enabling every provider with a
+// token in the stack config
+func providersFromStackValues(values map[string]string) *config.ClusterConfig {
+	cfg := &config.ClusterConfig{}
+	if token := values[config.PulumiKeyDigitalOceanToken]; token != "" {
+		cfg.Providers.DigitalOcean = &config.DigitalOceanProvider{Enabled: true, Token: token}
+	}
+	if token := values[config.PulumiKeyLinodeToken]; token != "" {
+		cfg.Providers.Linode = &config.LinodeProvider{Enabled: true, Token: token}
+	}
+	return cfg
+}
+
+// printPermissionChecks prints a pass/fail line per provider capability and
+// returns the number of failed checks
+func printPermissionChecks(w io.Writer, checks []validation.PermissionCheck) int {
+	failed := 0
+	provider := ""
+	for _, check := range checks {
+		if check.Provider != provider {
+			provider = check.Provider
+			fmt.Fprintf(w, "\n%s\n", color.CyanString(provider))
+		}
+		if check.Passed() {
+			fmt.Fprintf(w, "  %s %-8s\n", color.GreenString("✓"), check.Capability)
+			continue
+		}
+		failed++
+		fmt.Fprintf(w, "  %s %-8s %v\n", color.RedString("✗"), check.Capability, check.Err)
+	}
+	fmt.Fprintln(w)
+	return failed
+}
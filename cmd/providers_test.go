@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/internal/validation"
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+func TestProvidersFromStackValues(t *testing.T) {
+	cfg := providersFromStackValues(map[string]string{config.PulumiKeyLinodeToken: "linode-token"})
+	if cfg.Providers.DigitalOcean != nil {
+		t.Error("Expected a provider without a stored token to stay disabled")
+	}
+	if linode := cfg.Providers.Linode; linode == nil || !linode.Enabled || linode.Token != "linode-token" {
+		t.Errorf("Expected Linode enabled with the stored token, got %+v", linode)
+	}
+}
+
+func TestPrintPermissionChecks(t *testing.T) {
+	var out bytes.Buffer
+	failed := printPermissionChecks(&out, []validation.PermissionCheck{
+		{Provider: "digitalocean", Capability: validation.CapabilityRead},
+		{Provider: "digitalocean", Capability: validation.CapabilityCompute, Err: errors.New("permission denied")},
+		{Provider: "linode", Capability: validation.CapabilityRead},
+	})
+
+	if failed != 1 {
+		t.Errorf("Expected 1 failed check, got %d", failed)
+	}
+	output := out.String()
+	if strings.Count(output, "digitalocean") != 1 || !strings.Contains(output, "linode") {
+		t.Errorf("Expected one heading per provider, got %q", output)
+	}
+	if !strings.Contains(output, "compute  permission denied") {
+		t.Errorf("Expected the failed capability with its error, got %q", output)
+	}
+}
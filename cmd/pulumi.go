@@ -3,7 +3,6 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"os"
 
 	"github.com/fatih/color"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
@@ -152,14 +151,12 @@ func runPreview(cmd *cobra.Command, args []string) error {
 
 	// Determine stack name
 	targetStack := pulumiStackName
-	if targetStack == "" && len(args) > 0 {
-		targetStack = args[0]
-	}
-	if targetStack == "" {
-		targetStack = stackName
-	}
 	if targetStack == "" {
-		return fmt.Errorf("stack name required. Use: --stack <name>")
+		var err error
+		targetStack, err = getStackFromArgs(args, 0)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Create workspace
@@ -181,7 +178,7 @@ func runPreview(cmd *cobra.Command, args []string) error {
 
 	// Preview
 	previewOpts := []optpreview.Option{
-		optpreview.ProgressStreams(os.Stdout),
+		optpreview.ProgressStreams(pulumiProgress()),
 	}
 
 	_, err = stack.Preview(ctx, previewOpts...)
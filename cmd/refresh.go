@@ -3,7 +3,6 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/briandowns/spinner"
@@ -62,15 +61,10 @@ func init() {
 func runRefresh(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
-	// Determine target stack
-	targetStack := stackName
-	if len(args) > 0 {
-		targetStack = args[0]
-	}
-
-	// Validate stack name
-	if targetStack == "" {
-		return fmt.Errorf("stack name is required. Use: sloth-kubernetes refresh <stack-name> or --stack <name>")
+	// Resolve stack from args, --stack or the configured default
+	targetStack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
 	}
 
 	// Print header
@@ -130,7 +124,7 @@ func runRefresh(cmd *cobra.Command, args []string) error {
 
 	// Build refresh options
 	refreshOpts := []optrefresh.Option{
-		optrefresh.ProgressStreams(os.Stdout),
+		optrefresh.ProgressStreams(pulumiProgress()),
 	}
 
 	if expectNoChanges {
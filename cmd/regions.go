@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+
+	"github.com/chalkan3/sloth-kubernetes/internal/validation"
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+var sizesRegion string
+
+var regionsCmd = &cobra.Command{
+	Use:   "regions <provider>",
+	Short: "List the regions of a cloud provider",
+	Long: `List the regions a cloud provider can create nodes in, as reported by the
+provider API. Use the slugs as the region of node pools in the cluster config.
+
+The API token is read from --config when given, otherwise from
+DIGITALOCEAN_TOKEN or LINODE_TOKEN. Results are cached for a few minutes in
+~/.sloth-kubernetes/cache.`,
+	Example: `  # List DigitalOcean regions
+  sloth-kubernetes regions digitalocean
+
+  # Use the token of a cluster config
+  sloth-kubernetes regions linode --config cluster.yaml`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRegions,
+}
+
+var sizesCmd = &cobra.Command{
+	Use:   "sizes <provider>",
+	Short: "List the instance sizes of a cloud provider",
+	Long: `List the instance sizes a cloud provider offers, with vCPUs, memory, disk and
+monthly price, as reported by the provider API. Use the slugs as the size of
+node pools in the cluster config.
+
+The API token is read from --config when given, otherwise from
+DIGITALOCEAN_TOKEN or LINODE_TOKEN. Results are cached for a few minutes in
+~/.sloth-kubernetes/cache.`,
+	Example: `  # List every DigitalOcean droplet size
+  sloth-kubernetes sizes digitalocean
+
+  # List the Linode types available in a region, with that region's price
+  sloth-kubernetes sizes linode --region br-gru`,
+	Args: cobra.ExactArgs(1),
+	RunE: runSizes,
+}
+
+func init() {
+	rootCmd.AddCommand(regionsCmd)
+	rootCmd.AddCommand(sizesCmd)
+
+	sizesCmd.Flags().StringVar(&sizesRegion, "region", "", "Only list sizes available in this region")
+}
+
+// providerCatalog creates the catalog used by regions and sizes, cached in
+// ~/.sloth-kubernetes/cache
+func providerCatalog() *validation.ProviderCatalog {
+	cacheDir := ""
+	if dir, err := getConfigDir(); err == nil {
+		cacheDir = filepath.Join(dir, "cache")
+	}
+	return validation.NewProviderCatalog(cacheDir)
+}
+
+// catalogToken returns the API token of provider from --config or the
+// provider's environment variable
+func catalogToken(provider string) (string, error) {
+	cfg := &config.ClusterConfig{}
+	if cfgFile != "" {
+		var err error
+		if cfg, err = config.LoadFromYAML(cfgFile); err != nil {
+			return "", fmt.Errorf("failed to load config file: %w", err)
+		}
+	}
+
+	token := validation.ProviderToken(cfg, provider)
+	if token == "" {
+		return "", fmt.Errorf("no API token for %s: set %s_TOKEN or the provider token in --config",
+			provider, strings.ToUpper(provider))
+	}
+	return token, nil
+}
+
+func runRegions(cmd *cobra.Command, args []string) error {
+	provider := strings.ToLower(args[0])
+	catalog := providerCatalog()
+	if err := catalog.Supports(provider); err != nil {
+		return err
+	}
+
+	token, err := catalogToken(provider)
+	if err != nil {
+		return err
+	}
+
+	regions, err := catalog.Regions(provider, token)
+	if err != nil {
+		return err
+	}
+
+	printRegions(os.Stdout, regions)
+	return nil
+}
+
+func runSizes(cmd *cobra.Command, args []string) error {
+	provider := strings.ToLower(args[0])
+	catalog := providerCatalog()
+	if err := catalog.Supports(provider); err != nil {
+		return err
+	}
+
+	token, err := catalogToken(provider)
+	if err != nil {
+		return err
+	}
+
+	sizes, err := catalog.Sizes(provider, token, sizesRegion)
+	if err != nil {
+		return err
+	}
+	if len(sizes) == 0 {
+		color.Yellow("⚠️  No %s sizes are available in region '%s'", provider, sizesRegion)
+		return nil
+	}
+
+	printSizes(os.Stdout, sizes, sizesRegion)
+	return nil
+}
+
+// printRegions prints regions as a table
+func printRegions(out io.Writer, regions []validation.ProviderRegion) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "SLUG\tNAME\tAVAILABLE")
+	for _, region := range regions {
+		available := "yes"
+		if !region.Available {
+			available = "no"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", region.Slug, region.Name, available)
+	}
+}
+
+// printSizes prints sizes as a table, priced for region when one is given
+func printSizes(out io.Writer, sizes []validation.ProviderSize, region string) {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "SLUG\tVCPUS\tMEMORY\tDISK\tPRICE/MONTH")
+	for _, size := range sizes {
+		price := "-"
+		if p := size.PriceIn(region); p > 0 {
+			price = fmt.Sprintf("$%.2f", p)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%d GB\t%s\n", size.Slug, size.VCPUs, formatMemoryMB(size.MemoryMB), size.DiskGB, price)
+	}
+}
+
+// formatMemoryMB formats a memory amount in MB, e.g. 512 MB or 2 GB
+func formatMemoryMB(mb int) string {
+	if mb < 1024 {
+		return fmt.Sprintf("%d MB", mb)
+	}
+	return fmt.Sprintf("%g GB", float64(mb)/1024)
+}
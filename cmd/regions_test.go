@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/internal/validation"
+)
+
+func TestPrintSizes(t *testing.T) {
+	sizes := []validation.ProviderSize{
+		{Slug: "g6-nanode-1", VCPUs: 1, MemoryMB: 1024, DiskGB: 25, PriceMonthly: 5},
+		{Slug: "g6-standard-2", VCPUs: 2, MemoryMB: 4096, DiskGB: 80, PriceMonthly: 24, RegionPrices: map[string]float64{"br-gru": 28.8}},
+		{Slug: "custom", VCPUs: 1, MemoryMB: 512, DiskGB: 10},
+	}
+
+	var out bytes.Buffer
+	printSizes(&out, sizes, "br-gru")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("Expected a header and 3 sizes, got:\n%s", out.String())
+	}
+	for i, want := range [][]string{
+		{"SLUG", "VCPUS", "MEMORY", "DISK", "PRICE/MONTH"},
+		{"g6-nanode-1", "1", "1", "GB", "25", "GB", "$5.00"},
+		{"g6-standard-2", "2", "4", "GB", "80", "GB", "$28.80"},
+		{"custom", "1", "512", "MB", "10", "GB", "-"},
+	} {
+		if got := strings.Fields(lines[i]); strings.Join(got, " ") != strings.Join(want, " ") {
+			t.Errorf("Line %d: expected %v, got %v", i, want, got)
+		}
+	}
+}
+
+func TestCatalogToken(t *testing.T) {
+	cfgFile = ""
+	t.Setenv("LINODE_TOKEN", "linode-env-token")
+	t.Setenv("DIGITALOCEAN_TOKEN", "")
+
+	if token, err := catalogToken("linode"); err != nil || token != "linode-env-token" {
+		t.Errorf("Expected the token from the environment, got %q (%v)", token, err)
+	}
+
+	_, err := catalogToken("digitalocean")
+	if err == nil || !strings.Contains(err.Error(), "DIGITALOCEAN_TOKEN") {
+		t.Errorf("Expected a missing token error naming the variable, got %v", err)
+	}
+}
@@ -1,7 +1,6 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
 
 	"github.com/chalkan3/sloth-kubernetes/internal/common"
@@ -25,14 +24,15 @@ with RKE2, WireGuard VPN mesh, and automated configuration.
 
 This tool uses Pulumi Automation API internally - no Pulumi CLI required!
 Stack-based deployment enables managing multiple independent clusters.`,
-	Version: "1.0.0",
+	Version:           "1.0.0",
+	PersistentPreRunE: persistentPreRun,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	if err := runWithCleanup(rootCmd.Execute); err != nil {
+		printError(err)
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -42,9 +42,25 @@ func init() {
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "", "Config file (default: ./cluster-config.yaml)")
-	rootCmd.PersistentFlags().StringVarP(&stackName, "stack", "s", "production", "Pulumi stack name")
+	rootCmd.PersistentFlags().StringVarP(&stackName, "stack", "s", "", "Pulumi stack name (default: defaultStack from ~/.sloth-kubernetes/config)")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Verbose output")
 	rootCmd.PersistentFlags().BoolVarP(&autoApprove, "yes", "y", false, "Auto-approve without prompting")
+	rootCmd.PersistentFlags().StringVar(&sshProxy, "ssh-proxy", "", "HTTP CONNECT proxy for SSH connections (e.g. http://proxy.corp:3128)")
+	rootCmd.PersistentFlags().StringVar(&artifactDir, "output-dir", "", "Directory for generated files such as client configs and kubeconfigs (default: ~/.sloth-kubernetes/<stack>)")
+	rootCmd.PersistentFlags().BoolVar(&verifyHostKeys, "verify-host-keys", false, "Record SSH host keys in ~/.sloth-kubernetes/<stack>/known_hosts and refuse changed keys")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "", "Output format: text or json, one JSON object per event (default: $SLOTH_LOG_FORMAT or text)")
+	rootCmd.PersistentFlags().DurationVar(&sshTimeout, "ssh-timeout", defaultSSHTimeout, "Kill an SSH call still running after this long; each call gets its own timeout, the command as a whole has none (0 disables)")
+}
+
+// persistentPreRun runs before every command
+func persistentPreRun(cmd *cobra.Command, args []string) error {
+	if err := setupLogging(cmd); err != nil {
+		return err
+	}
+	if err := applySSHTimeout(cmd); err != nil {
+		return err
+	}
+	return resolveSSHProxy(cmd, args)
 }
 
 func initConfig() {
@@ -35,7 +35,7 @@ Configuration:
   Set these environment variables or use flags:
   • SALT_API_URL - Salt API endpoint (default: http://bastion-ip:8000)
   • SALT_USERNAME - Salt API username (default: saltapi)
-  • SALT_PASSWORD - Salt API password (default: saltapi123)`,
+  • SALT_PASSWORD - Salt API password (stack output: salt_api_password)`,
 	Example: `  # Ping all minions
   sloth-kubernetes salt ping
 
@@ -187,7 +187,7 @@ func init() {
 	// Load saved configuration if available
 	defaultURL := getEnvOrDefault("SALT_API_URL", "")
 	defaultUser := getEnvOrDefault("SALT_USERNAME", "saltapi")
-	defaultPass := getEnvOrDefault("SALT_PASSWORD", "saltapi123")
+	defaultPass := getEnvOrDefault("SALT_PASSWORD", "")
 
 	// Try to load from saved config file
 	if savedConfig, err := loadSaltConfig(); err == nil {
@@ -197,7 +197,7 @@ func init() {
 		if defaultUser == "saltapi" {
 			defaultUser = savedConfig.Username
 		}
-		if defaultPass == "saltapi123" {
+		if defaultPass == "" {
 			defaultPass = savedConfig.Password
 		}
 	}
@@ -222,10 +222,10 @@ Please run one of the following:
   2. Set environment variables:
      export SALT_API_URL="http://bastion-ip:8000"
      export SALT_USERNAME="saltapi"
-     export SALT_PASSWORD="saltapi123"
+     export SALT_PASSWORD="<salt_api_password stack output>"
 
   3. Use command-line flags:
-     --url "http://bastion-ip:8000" --username saltapi --password saltapi123`,
+     --url "http://bastion-ip:8000" --username saltapi --password <password>`,
 			color.CyanString("sloth-kubernetes salt login"))
 	}
 
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 
 	"github.com/chalkan3/sloth-kubernetes/internal/common"
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
 	"github.com/chalkan3/sloth-kubernetes/pkg/salt"
 	"github.com/fatih/color"
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
@@ -150,9 +151,25 @@ func runSaltLogin(cmd *cobra.Command, args []string) error {
 	// Build Salt API URL
 	saltAPIURL := fmt.Sprintf("http://%s:8000", bastionIP)
 
-	// Default credentials (as documented in bastion setup)
-	saltUsername := getEnvOrDefault("SALT_USERNAME", "saltapi")
-	saltPassword := getEnvOrDefault("SALT_PASSWORD", "saltapi123")
+	// The password is generated at deploy time unless set in the config
+	saltUsername := getEnvOrDefault("SALT_USERNAME", "")
+	if saltUsername == "" {
+		if output, ok := outputs["salt_api_user"]; ok {
+			saltUsername, _ = output.Value.(string)
+		}
+	}
+	if saltUsername == "" {
+		saltUsername = config.DefaultSaltAPIUser
+	}
+	saltPassword := getEnvOrDefault("SALT_PASSWORD", "")
+	if saltPassword == "" {
+		if output, ok := outputs["salt_api_password"]; ok {
+			saltPassword, _ = output.Value.(string)
+		}
+	}
+	if saltPassword == "" {
+		return fmt.Errorf("no Salt API password in the stack outputs; redeploy the stack or set SALT_PASSWORD")
+	}
 
 	printInfo(fmt.Sprintf("🌐 Salt API URL: %s", saltAPIURL))
 	printInfo(fmt.Sprintf("👤 Username: %s", saltUsername))
@@ -328,36 +345,11 @@ func createWorkspaceForSalt(ctx context.Context) (auto.Workspace, error) {
 		}),
 	}
 
-	// Collect all AWS/S3 environment variables
-	envVars := make(map[string]string)
-	awsEnvKeys := []string{
-		"AWS_ACCESS_KEY_ID",
-		"AWS_SECRET_ACCESS_KEY",
-		"AWS_REGION",
-		"AWS_S3_ENDPOINT",
-		"AWS_S3_USE_PATH_STYLE",
-		"AWS_S3_FORCE_PATH_STYLE",
-		"PULUMI_BACKEND_URL",
-		"PULUMI_CONFIG_PASSPHRASE",
-	}
-	for _, key := range awsEnvKeys {
-		if val := os.Getenv(key); val != "" {
-			envVars[key] = val
-		}
-	}
-
-	// Add environment variables to workspace options
-	if len(envVars) > 0 {
-		workspaceOpts = append(workspaceOpts, auto.EnvVars(envVars))
-	}
-
-	// If PULUMI_BACKEND_URL is set, use passphrase secrets provider
-	if backendURL := os.Getenv("PULUMI_BACKEND_URL"); backendURL != "" {
-		workspaceOpts = append(workspaceOpts, auto.SecretsProvider("passphrase"))
-		if os.Getenv("PULUMI_CONFIG_PASSPHRASE") == "" {
-			os.Setenv("PULUMI_CONFIG_PASSPHRASE", "")
-		}
+	backendOpts, err := newStateBackendOptions()
+	if err != nil {
+		return nil, err
 	}
+	workspaceOpts = append(workspaceOpts, backendOpts...)
 
 	// Create workspace
 	ws, err := auto.NewLocalWorkspace(ctx, workspaceOpts...)
@@ -0,0 +1,372 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/spf13/cobra"
+)
+
+// Rotation stages reported by `secrets-encrypt status`
+const (
+	secretsStageStart             = "start"
+	secretsStagePrepare           = "prepare"
+	secretsStageRotate            = "rotate"
+	secretsStageReencryptFinished = "reencrypt_finished"
+)
+
+// secretsEncryptPollInterval and secretsEncryptPollAttempts bound the wait for
+// a server to come back after a restart and for re-encryption to finish.
+// They are variables so tests do not sleep.
+var (
+	secretsEncryptPollInterval = 10 * time.Second
+	secretsEncryptPollAttempts = 30
+)
+
+var secretsEncryptCmd = &cobra.Command{
+	Use:   "secrets-encrypt",
+	Short: "Inspect and rotate encryption of Secrets at rest",
+	Long: `Inspect and rotate the key used to encrypt Kubernetes Secrets in etcd.
+Encryption is enabled with kubernetes.encryptSecrets or
+kubernetes.rke2.secretsEncryption in the cluster configuration.`,
+}
+
+var secretsEncryptStatusCmd = &cobra.Command{
+	Use:   "status [stack-name]",
+	Short: "Show secrets encryption status on every control-plane node",
+	Example: `  # Check that Secrets are encrypted at rest
+  sloth-kubernetes secrets-encrypt status production`,
+	RunE: runSecretsEncryptStatus,
+}
+
+var secretsEncryptRotateCmd = &cobra.Command{
+	Use:   "rotate [stack-name]",
+	Short: "Rotate the secrets encryption key across the control plane",
+	Long: `Rotate the key used to encrypt Secrets at rest. The rotation runs the
+prepare, rotate and reencrypt stages from one server and restarts every
+control-plane node one at a time after each stage, so the API stays available
+and every server always holds the keys needed to read existing Secrets.
+All control-plane nodes must be reachable before the rotation starts.`,
+	Example: `  # Rotate the encryption key
+  sloth-kubernetes secrets-encrypt rotate production
+
+  # Rotate without confirmation
+  sloth-kubernetes secrets-encrypt rotate production --yes`,
+	RunE: runSecretsEncryptRotate,
+}
+
+func init() {
+	rootCmd.AddCommand(secretsEncryptCmd)
+	secretsEncryptCmd.AddCommand(secretsEncryptStatusCmd)
+	secretsEncryptCmd.AddCommand(secretsEncryptRotateCmd)
+}
+
+// secretsEncryptStatus is the parsed output of `secrets-encrypt status`
+type secretsEncryptStatus struct {
+	Enabled     bool
+	Stage       string
+	HashesMatch bool
+}
+
+// secretsEncryptScriptPrefix selects the RKE2 or K3s binary and server unit
+const secretsEncryptScriptPrefix = `set -e
+export PATH="$PATH:/usr/local/bin:/var/lib/rancher/rke2/bin"
+if command -v rke2 >/dev/null 2>&1; then BIN=rke2; SVC=rke2-server; else BIN=k3s; SVC=k3s; fi
+`
+
+// secretsEncryptScript runs `secrets-encrypt <action>` on a server
+func secretsEncryptScript(action string) string {
+	return secretsEncryptScriptPrefix + fmt.Sprintf("$BIN secrets-encrypt %s\n", action)
+}
+
+// restartServerScript restarts the server and waits until it answers
+// secrets-encrypt requests again
+func restartServerScript() string {
+	return secretsEncryptScriptPrefix + fmt.Sprintf(`systemctl restart "$SVC"
+for i in $(seq 1 %d); do
+  if $BIN secrets-encrypt status >/dev/null 2>&1; then
+    echo "READY"
+    exit 0
+  fi
+  sleep %d
+done
+echo "server did not come back after restart"
+exit 1
+`, secretsEncryptPollAttempts, int(secretsEncryptPollInterval.Seconds()))
+}
+
+// parseSecretsEncryptStatus parses the output of `secrets-encrypt status`, e.g.
+//
+//	Encryption Status: Enabled
+//	Current Rotation Stage: start
+//	Server Encryption Hashes: All hashes match
+func parseSecretsEncryptStatus(output string) secretsEncryptStatus {
+	var status secretsEncryptStatus
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+
+		switch strings.TrimSpace(key) {
+		case "Encryption Status":
+			status.Enabled = strings.EqualFold(value, "Enabled")
+		case "Current Rotation Stage":
+			status.Stage = value
+		case "Server Encryption Hashes":
+			status.HashesMatch = strings.HasPrefix(value, "All hashes match")
+		}
+	}
+	return status
+}
+
+// runSecretsEncrypt runs a secrets-encrypt action as root on a server
+func runSecretsEncrypt(node NodeInfo, access nodeSSHAccess, action string) (string, error) {
+	user := getSSHUserForNode(node.Provider)
+	output, err := sshRunner(access.args(node, user, 10, "sudo", "bash", "-s"), secretsEncryptScript(action))
+	if err != nil {
+		return "", fmt.Errorf("secrets-encrypt %s failed on %s: %v (output: %s)", action, node.Name, err, strings.TrimSpace(string(output)))
+	}
+	return string(output), nil
+}
+
+// readSecretsEncryptStatus reads the secrets encryption status of a server
+func readSecretsEncryptStatus(node NodeInfo, access nodeSSHAccess) (secretsEncryptStatus, error) {
+	output, err := runSecretsEncrypt(node, access, "status")
+	if err != nil {
+		return secretsEncryptStatus{}, err
+	}
+	return parseSecretsEncryptStatus(output), nil
+}
+
+// controlPlaneNodes returns the control-plane nodes in name order
+func controlPlaneNodes(nodes []NodeInfo) []NodeInfo {
+	var servers []NodeInfo
+	for _, node := range nodes {
+		if isControlPlaneNode(node) {
+			servers = append(servers, node)
+		}
+	}
+	sort.Slice(servers, func(i, j int) bool { return servers[i].Name < servers[j].Name })
+	return servers
+}
+
+// verifySecretsEncryption checks that every server reports encryption as
+// enabled and returns an error naming the servers where it is not
+func verifySecretsEncryption(servers []NodeInfo, access nodeSSHAccess) error {
+	if len(servers) == 0 {
+		return fmt.Errorf("no control-plane node found to verify secrets encryption")
+	}
+
+	var failed []string
+	for _, server := range servers {
+		status, err := readSecretsEncryptStatus(server, access)
+		switch {
+		case err != nil:
+			failed = append(failed, fmt.Sprintf("%s (%v)", server.Name, err))
+		case !status.Enabled:
+			failed = append(failed, fmt.Sprintf("%s (encryption disabled)", server.Name))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("secrets encryption is not active on:\n  • %s", strings.Join(failed, "\n  • "))
+	}
+	return nil
+}
+
+// restartServers restarts the servers one at a time, waiting for each to come
+// back before moving on so the control plane keeps quorum
+func restartServers(servers []NodeInfo, access nodeSSHAccess) error {
+	for _, server := range servers {
+		printInfo(fmt.Sprintf("  Restarting %s...", server.Name))
+		user := getSSHUserForNode(server.Provider)
+		output, err := sshRunner(access.args(server, user, 10, "sudo", "bash", "-s"), restartServerScript())
+		if err != nil || !strings.Contains(string(output), "READY") {
+			return fmt.Errorf("failed to restart %s: %v (output: %s)", server.Name, err, strings.TrimSpace(string(output)))
+		}
+	}
+	return nil
+}
+
+// waitForRotationStage polls a server until it reports the wanted stage
+func waitForRotationStage(server NodeInfo, access nodeSSHAccess, stage string) error {
+	var status secretsEncryptStatus
+	for attempt := 0; attempt < secretsEncryptPollAttempts; attempt++ {
+		var err error
+		status, err = readSecretsEncryptStatus(server, access)
+		if err == nil && status.Stage == stage {
+			return nil
+		}
+		time.Sleep(secretsEncryptPollInterval)
+	}
+	return fmt.Errorf("%s did not reach rotation stage %s (last stage: %q)", server.Name, stage, status.Stage)
+}
+
+// rotateSecretsEncryption rotates the encryption key. Each stage is started
+// from the first server and every server is restarted after it, so all of
+// them load the new encryption configuration before the next stage.
+func rotateSecretsEncryption(servers []NodeInfo, access nodeSSHAccess) error {
+	leader := servers[0]
+
+	stages := []struct {
+		action string
+		stage  string
+	}{
+		{"prepare", secretsStagePrepare},
+		{"rotate", secretsStageRotate},
+		{"reencrypt", secretsStageReencryptFinished},
+	}
+
+	for _, step := range stages {
+		printInfo(fmt.Sprintf("Running secrets-encrypt %s on %s...", step.action, leader.Name))
+		if _, err := runSecretsEncrypt(leader, access, step.action); err != nil {
+			return err
+		}
+		if err := waitForRotationStage(leader, access, step.stage); err != nil {
+			return err
+		}
+		if err := restartServers(servers, access); err != nil {
+			return fmt.Errorf("%s stage: %w", step.action, err)
+		}
+	}
+
+	return nil
+}
+
+// loadControlPlane selects the stack and returns its control-plane nodes and
+// SSH access details
+func loadControlPlane(ctx context.Context, stack string) ([]NodeInfo, nodeSSHAccess, error) {
+	nodes, access, err := loadStackNodes(ctx, stack)
+	if err != nil {
+		return nil, nodeSSHAccess{}, err
+	}
+
+	servers := controlPlaneNodes(nodes)
+	if len(servers) == 0 {
+		return nil, nodeSSHAccess{}, fmt.Errorf("no control-plane node found in stack '%s'", stack)
+	}
+	return servers, access, nil
+}
+
+// loadStackNodes selects the stack and returns all its nodes and SSH access
+// details
+func loadStackNodes(ctx context.Context, stack string) ([]NodeInfo, nodeSSHAccess, error) {
+	// Create workspace with S3 support
+	workspace, err := createWorkspaceWithS3Support(ctx)
+	if err != nil {
+		return nil, nodeSSHAccess{}, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	// Use fully qualified stack name for S3 backend
+	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
+	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
+	if err != nil {
+		return nil, nodeSSHAccess{}, selectStackError(err, stack)
+	}
+
+	// Get outputs
+	outputs, err := stackOutputsWithRetry(ctx, s)
+	if err != nil {
+		return nil, nodeSSHAccess{}, err
+	}
+
+	// Parse nodes
+	nodes, err := ParseNodeOutputs(outputs)
+	if err != nil {
+		return nil, nodeSSHAccess{}, fmt.Errorf("failed to parse nodes: %w", err)
+	}
+	return nodes, newNodeSSHAccess(stack, outputs), nil
+}
+
+func runSecretsEncryptStatus(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("🔐 Secrets Encryption - Stack: %s", stack))
+
+	servers, access, err := loadControlPlane(ctx, stack)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println()
+	fmt.Printf("%-25s %-10s %-20s %s\n", "NODE", "ENCRYPTION", "STAGE", "HASHES")
+	for _, server := range servers {
+		status, err := readSecretsEncryptStatus(server, access)
+		if err != nil {
+			fmt.Printf("%-25s %s\n", server.Name, color.RedString("unreachable"))
+			continue
+		}
+
+		encryption := color.RedString("%-10s", "disabled")
+		if status.Enabled {
+			encryption = color.GreenString("%-10s", "enabled")
+		}
+		hashes := "differ"
+		if status.HashesMatch {
+			hashes = "match"
+		}
+		fmt.Printf("%-25s %s %-20s %s\n", server.Name, encryption, status.Stage, hashes)
+	}
+	fmt.Println()
+
+	return nil
+}
+
+func runSecretsEncryptRotate(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("🔐 Rotate Secrets Encryption Key - Stack: %s", stack))
+
+	servers, access, err := loadControlPlane(ctx, stack)
+	if err != nil {
+		return err
+	}
+
+	// Every server is restarted during the rotation, so nothing is changed
+	// unless all of them are reachable and already encrypting
+	if err := verifySecretsEncryption(servers, access); err != nil {
+		return fmt.Errorf("cannot rotate: %w", err)
+	}
+	status, err := readSecretsEncryptStatus(servers[0], access)
+	if err != nil {
+		return err
+	}
+	if status.Stage != secretsStageStart && status.Stage != secretsStageReencryptFinished {
+		return fmt.Errorf("a rotation is already in progress on %s (stage %s)", servers[0].Name, status.Stage)
+	}
+
+	fmt.Println()
+	printInfo(fmt.Sprintf("Control plane: %d server(s), each restarted 3 times", len(servers)))
+	if !autoApprove && !confirm("Rotate the secrets encryption key?") {
+		printWarning("Rotation cancelled")
+		return nil
+	}
+
+	fmt.Println()
+	if err := rotateSecretsEncryption(servers, access); err != nil {
+		return err
+	}
+
+	if err := verifySecretsEncryption(servers, access); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	printSuccess("Secrets encryption key rotated")
+	return nil
+}
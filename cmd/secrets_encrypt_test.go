@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const secretsEncryptStatusEnabled = `Encryption Status: Enabled
+Current Rotation Stage: start
+Server Encryption Hashes: All hashes match
+
+Active  Key Type  Name
+------  --------  ----
+ *      AES-CBC   aescbckey
+`
+
+func stubSecretsEncryptPolling(t *testing.T) {
+	t.Helper()
+	interval, attempts := secretsEncryptPollInterval, secretsEncryptPollAttempts
+	secretsEncryptPollInterval, secretsEncryptPollAttempts = 0, 3
+	t.Cleanup(func() {
+		secretsEncryptPollInterval, secretsEncryptPollAttempts = interval, attempts
+	})
+}
+
+func TestParseSecretsEncryptStatus(t *testing.T) {
+	status := parseSecretsEncryptStatus(secretsEncryptStatusEnabled)
+	if !status.Enabled || status.Stage != secretsStageStart || !status.HashesMatch {
+		t.Errorf("Unexpected status: %+v", status)
+	}
+
+	status = parseSecretsEncryptStatus("Encryption Status: Disabled\nCurrent Rotation Stage: \nServer Encryption Hashes: hash does not match\n")
+	if status.Enabled || status.HashesMatch {
+		t.Errorf("Expected disabled status with mismatched hashes, got %+v", status)
+	}
+}
+
+func TestSecretsEncryptScript(t *testing.T) {
+	script := secretsEncryptScript("status")
+	for _, want := range []string{"BIN=rke2; SVC=rke2-server", "BIN=k3s; SVC=k3s", "$BIN secrets-encrypt status"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q, got:\n%s", want, script)
+		}
+	}
+}
+
+func TestControlPlaneNodes(t *testing.T) {
+	nodes := []NodeInfo{
+		{Name: "master-2", Roles: []string{"master"}},
+		{Name: "worker-1", Roles: []string{"worker"}},
+		{Name: "master-1", Roles: []string{"controlplane", "etcd"}},
+	}
+
+	servers := controlPlaneNodes(nodes)
+	if len(servers) != 2 || servers[0].Name != "master-1" || servers[1].Name != "master-2" {
+		t.Errorf("Unexpected control-plane nodes: %+v", servers)
+	}
+}
+
+func TestVerifySecretsEncryption(t *testing.T) {
+	servers := []NodeInfo{
+		{Name: "master-1", PublicIP: "203.0.113.10", Provider: "digitalocean", Roles: []string{"master"}},
+		{Name: "master-2", PublicIP: "203.0.113.11", Provider: "digitalocean", Roles: []string{"master"}},
+	}
+
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		if !strings.Contains(stdin, "secrets-encrypt status") {
+			t.Errorf("Expected a status script, got:\n%s", stdin)
+		}
+		return []byte(secretsEncryptStatusEnabled), nil
+	})
+	if err := verifySecretsEncryption(servers, nodeSSHAccess{}); err != nil {
+		t.Errorf("Expected encryption verified, got %v", err)
+	}
+
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		switch sshTarget(args) {
+		case "root@203.0.113.11":
+			return nil, errors.New("connection timed out")
+		}
+		return []byte("Encryption Status: Disabled\n"), nil
+	})
+	err := verifySecretsEncryption(servers, nodeSSHAccess{})
+	if err == nil {
+		t.Fatal("Expected an error when encryption is not active")
+	}
+	for _, want := range []string{"master-1 (encryption disabled)", "master-2"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got: %v", want, err)
+		}
+	}
+
+	if err := verifySecretsEncryption(nil, nodeSSHAccess{}); err == nil {
+		t.Error("Expected an error without control-plane nodes")
+	}
+}
+
+func TestRotateSecretsEncryption(t *testing.T) {
+	stubSecretsEncryptPolling(t)
+	servers := []NodeInfo{
+		{Name: "master-1", PublicIP: "203.0.113.10", Provider: "digitalocean", Roles: []string{"master"}},
+		{Name: "master-2", PublicIP: "203.0.113.11", Provider: "digitalocean", Roles: []string{"master"}},
+	}
+
+	stage := secretsStageStart
+	var steps []string
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		target := sshTarget(args)
+		switch {
+		case strings.Contains(stdin, "systemctl restart"):
+			steps = append(steps, "restart "+target)
+			return []byte("READY\n"), nil
+		case strings.Contains(stdin, "secrets-encrypt status"):
+			return []byte("Encryption Status: Enabled\nCurrent Rotation Stage: " + stage + "\n"), nil
+		case strings.Contains(stdin, "secrets-encrypt prepare"):
+			stage = secretsStagePrepare
+		case strings.Contains(stdin, "secrets-encrypt rotate"):
+			stage = secretsStageRotate
+		case strings.Contains(stdin, "secrets-encrypt reencrypt"):
+			stage = secretsStageReencryptFinished
+		}
+		steps = append(steps, stage+" "+target)
+		return nil, nil
+	})
+
+	if err := rotateSecretsEncryption(servers, nodeSSHAccess{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []string{
+		"prepare root@203.0.113.10", "restart root@203.0.113.10", "restart root@203.0.113.11",
+		"rotate root@203.0.113.10", "restart root@203.0.113.10", "restart root@203.0.113.11",
+		"reencrypt_finished root@203.0.113.10", "restart root@203.0.113.10", "restart root@203.0.113.11",
+	}
+	if strings.Join(steps, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected rotation steps:\n%s\nwant:\n%s", strings.Join(steps, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestRotateSecretsEncryption_StopsOnFailedRestart(t *testing.T) {
+	stubSecretsEncryptPolling(t)
+	servers := []NodeInfo{
+		{Name: "master-1", PublicIP: "203.0.113.10", Provider: "digitalocean", Roles: []string{"master"}},
+		{Name: "master-2", PublicIP: "203.0.113.11", Provider: "digitalocean", Roles: []string{"master"}},
+	}
+
+	var rotated bool
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		switch {
+		case strings.Contains(stdin, "systemctl restart"):
+			if sshTarget(args) == "root@203.0.113.11" {
+				return []byte("server did not come back after restart\n"), errors.New("exit status 1")
+			}
+			return []byte("READY\n"), nil
+		case strings.Contains(stdin, "secrets-encrypt status"):
+			return []byte("Current Rotation Stage: prepare\n"), nil
+		case strings.Contains(stdin, "secrets-encrypt rotate"):
+			rotated = true
+		}
+		return nil, nil
+	})
+
+	err := rotateSecretsEncryption(servers, nodeSSHAccess{})
+	if err == nil || !strings.Contains(err.Error(), "master-2") {
+		t.Errorf("Expected the failed restart of master-2, got %v", err)
+	}
+	if rotated {
+		t.Error("Expected the rotation to stop before the rotate stage")
+	}
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+const (
+	// secretsProviderKey selects the secrets provider of stacks, in the
+	// environment or ~/.sloth-kubernetes/config
+	secretsProviderKey = "PULUMI_SECRETS_PROVIDER"
+
+	// passphraseSecretsProvider encrypts stack secrets with PULUMI_CONFIG_PASSPHRASE
+	passphraseSecretsProvider = "passphrase"
+)
+
+// kmsSecretsProviderSchemes are the cloud KMS secrets providers, configured as
+// URLs such as awskms://alias/sloth?region=us-east-1
+var kmsSecretsProviderSchemes = []string{"awskms://", "gcpkms://"}
+
+// stateBackendEnvKeys are the environment variables the Pulumi subprocess
+// needs to reach the state backend and decrypt stack secrets
+var stateBackendEnvKeys = []string{
+	"AWS_ACCESS_KEY_ID",
+	"AWS_SECRET_ACCESS_KEY",
+	"AWS_SESSION_TOKEN",
+	"AWS_PROFILE",
+	"AWS_REGION",
+	"AWS_S3_ENDPOINT",
+	"AWS_S3_USE_PATH_STYLE",
+	"AWS_S3_FORCE_PATH_STYLE",
+	"GOOGLE_APPLICATION_CREDENTIALS",
+	"PULUMI_BACKEND_URL",
+	"PULUMI_CONFIG_PASSPHRASE",
+	"PULUMI_CONFIG_PASSPHRASE_FILE",
+}
+
+// stateBackendOptions returns the workspace options every command using the
+// state backend needs: the environment of the Pulumi subprocess and the
+// secrets provider. Values come from the environment, which
+// common.LoadSavedConfig fills from ~/.sloth-kubernetes/config.
+func stateBackendOptions(lookupEnv func(string) (string, bool)) ([]auto.LocalWorkspaceOption, error) {
+	var opts []auto.LocalWorkspaceOption
+
+	envVars := make(map[string]string)
+	for _, key := range stateBackendEnvKeys {
+		if val, _ := lookupEnv(key); val != "" {
+			envVars[key] = val
+		}
+	}
+	if len(envVars) > 0 {
+		opts = append(opts, auto.EnvVars(envVars))
+	}
+
+	provider, err := stackSecretsProvider(lookupEnv)
+	if err != nil {
+		return nil, err
+	}
+	if provider != "" {
+		opts = append(opts, auto.SecretsProvider(provider))
+	}
+
+	return opts, nil
+}
+
+// stackSecretsProvider returns the secrets provider of stacks: the configured
+// one, otherwise the passphrase provider for a self-managed backend. It
+// returns "" when Pulumi Cloud manages secrets. The passphrase provider needs
+// PULUMI_CONFIG_PASSPHRASE or PULUMI_CONFIG_PASSPHRASE_FILE set; an empty
+// passphrase has to be set explicitly.
+func stackSecretsProvider(lookupEnv func(string) (string, bool)) (string, error) {
+	if provider, _ := lookupEnv(secretsProviderKey); provider != "" {
+		if err := validateSecretsProvider(provider); err != nil {
+			return "", err
+		}
+		if provider != passphraseSecretsProvider {
+			return provider, nil
+		}
+	} else if backend, _ := lookupEnv("PULUMI_BACKEND_URL"); backend == "" {
+		return "", nil
+	}
+
+	_, hasPassphrase := lookupEnv("PULUMI_CONFIG_PASSPHRASE")
+	_, hasPassphraseFile := lookupEnv("PULUMI_CONFIG_PASSPHRASE_FILE")
+	if !hasPassphrase && !hasPassphraseFile {
+		return "", fmt.Errorf("stack secrets are encrypted with a passphrase: set PULUMI_CONFIG_PASSPHRASE or configure a KMS secrets provider (%s)", secretsProviderKey)
+	}
+	return passphraseSecretsProvider, nil
+}
+
+// validateSecretsProvider checks that provider is the passphrase provider or
+// a supported cloud KMS key URL
+func validateSecretsProvider(provider string) error {
+	if provider == passphraseSecretsProvider {
+		return nil
+	}
+	for _, scheme := range kmsSecretsProviderSchemes {
+		if strings.HasPrefix(provider, scheme) && len(provider) > len(scheme) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported secrets provider %q (use passphrase, awskms://<key> or gcpkms://<key>)", provider)
+}
+
+// newStateBackendOptions returns stateBackendOptions for the process environment
+func newStateBackendOptions() ([]auto.LocalWorkspaceOption, error) {
+	return stateBackendOptions(os.LookupEnv)
+}
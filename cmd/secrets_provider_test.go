@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+// fakeLookupEnv returns a lookup over env, reporting unset keys as missing
+func fakeLookupEnv(env map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		val, ok := env[key]
+		return val, ok
+	}
+}
+
+func TestStackSecretsProvider_KMS(t *testing.T) {
+	for _, provider := range []string{"awskms://alias/sloth?region=us-east-1", "gcpkms://projects/p/locations/global/keyRings/r/cryptoKeys/k"} {
+		got, err := stackSecretsProvider(fakeLookupEnv(map[string]string{
+			"PULUMI_BACKEND_URL": "s3://state",
+			secretsProviderKey:   provider,
+		}))
+		if err != nil {
+			t.Fatalf("Expected %s to need no passphrase, got %v", provider, err)
+		}
+		if got != provider {
+			t.Errorf("Expected the workspace secrets provider %s, got %q", provider, got)
+		}
+	}
+}
+
+func TestStackSecretsProvider_Passphrase(t *testing.T) {
+	_, err := stackSecretsProvider(fakeLookupEnv(map[string]string{"PULUMI_BACKEND_URL": "s3://state"}))
+	if err == nil || !strings.Contains(err.Error(), "set PULUMI_CONFIG_PASSPHRASE or configure a KMS secrets provider") {
+		t.Errorf("Expected a missing passphrase to be reported, got %v", err)
+	}
+
+	// An empty passphrase is allowed when set explicitly
+	got, err := stackSecretsProvider(fakeLookupEnv(map[string]string{"PULUMI_BACKEND_URL": "s3://state", "PULUMI_CONFIG_PASSPHRASE": ""}))
+	if err != nil || got != passphraseSecretsProvider {
+		t.Errorf("Expected the passphrase provider, got %q, %v", got, err)
+	}
+
+	got, err = stackSecretsProvider(fakeLookupEnv(map[string]string{}))
+	if err != nil || got != "" {
+		t.Errorf("Expected no secrets provider without a self-managed backend, got %q, %v", got, err)
+	}
+}
+
+func TestStackSecretsProvider_Unsupported(t *testing.T) {
+	_, err := stackSecretsProvider(fakeLookupEnv(map[string]string{secretsProviderKey: "vault://secret"}))
+	if err == nil || !strings.Contains(err.Error(), `unsupported secrets provider "vault://secret"`) {
+		t.Errorf("Expected an unsupported provider to be rejected, got %v", err)
+	}
+}
+
+func TestStateBackendOptions(t *testing.T) {
+	opts, err := stateBackendOptions(fakeLookupEnv(map[string]string{
+		"PULUMI_BACKEND_URL": "s3://state",
+		secretsProviderKey:   "awskms://alias/sloth",
+	}))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(opts) != 2 {
+		t.Errorf("Expected the subprocess environment and secrets provider options, got %d option(s)", len(opts))
+	}
+
+	if _, err := stateBackendOptions(fakeLookupEnv(map[string]string{"PULUMI_BACKEND_URL": "s3://state"})); err == nil {
+		t.Error("Expected the workspace to refuse a missing passphrase")
+	}
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/chalkan3/sloth-kubernetes/internal/common"
+	"github.com/spf13/cobra"
+)
+
+var (
+	// sshProxy is the HTTP CONNECT proxy given with --ssh-proxy
+	sshProxy string
+	// sshProxyCommand is the ssh ProxyCommand resolved from sshProxy
+	sshProxyCommand string
+)
+
+// resolveSSHProxy validates --ssh-proxy and builds the ProxyCommand used for
+// the first SSH hop out of the local network
+func resolveSSHProxy(cmd *cobra.Command, args []string) error {
+	sshProxyCommand = ""
+	if sshProxy == "" {
+		return nil
+	}
+
+	proxyCmd, err := common.SSHProxyCommand(sshProxy)
+	if err != nil {
+		return fmt.Errorf("invalid --ssh-proxy: %w", err)
+	}
+	sshProxyCommand = proxyCmd
+	return nil
+}
+
+// sshProxyOptions returns the ssh options that send a direct connection
+// through the configured proxy, or nil when no proxy is set
+func sshProxyOptions() []string {
+	if sshProxyCommand == "" {
+		return nil
+	}
+	return []string{"-o", "ProxyCommand=" + sshProxyCommand}
+}
+
+// bastionProxyCommand returns the ProxyCommand that jumps through the bastion.
+// With a proxy configured the bastion hop itself goes through the proxy; its
+// %h/%p tokens are escaped so the outer ssh leaves them for the inner one.
+// Jump hosts in front of the bastion are chained with -J, outermost first;
+// they are reached with the operator's own ssh config, so the first of them
+// rather than the bastion is what would go through a proxy. The bastion is
+// logged in to as bastionUser, root when empty.
+func bastionProxyCommand(keyPath, bastionUser, bastionIP, knownHosts string, jumpHosts []string, quiet bool) string {
+	if bastionUser == "" {
+		bastionUser = "root"
+	}
+
+	var b strings.Builder
+	b.WriteString("ssh ")
+	if quiet {
+		b.WriteString("-q ")
+	}
+	fmt.Fprintf(&b, "-i %s -o StrictHostKeyChecking=accept-new -o %s ", keyPath, knownHosts)
+	if len(jumpHosts) > 0 {
+		fmt.Fprintf(&b, "-J %s ", strings.Join(jumpHosts, ","))
+	} else if sshProxyCommand != "" {
+		fmt.Fprintf(&b, "-o 'ProxyCommand=%s' ", strings.ReplaceAll(sshProxyCommand, "%", "%%"))
+	}
+	fmt.Fprintf(&b, "-W %%h:%%p %s@%s", bastionUser, bastionIP)
+	return b.String()
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// stackOutputsAttempts is how many times stack outputs are read before
+// giving up on a retryable error
+const stackOutputsAttempts = 4
+
+// stackOutputsBackoff is the wait before the first retry, doubled after each
+var stackOutputsBackoff = time.Second
+
+// fetchStackOutputs reads the outputs of a stack, replaced in tests
+var fetchStackOutputs = func(ctx context.Context, s auto.Stack) (auto.OutputMap, error) {
+	return s.Outputs(ctx)
+}
+
+// transientBackendErrors are fragments of errors the state backend returns
+// for failures that go away on their own: S3 throttling and eventual
+// consistency, and network hiccups
+var transientBackendErrors = []string{
+	"SlowDown",
+	"Throttling",
+	"TooManyRequests",
+	"RequestTimeout",
+	"ServiceUnavailable",
+	"InternalError",
+	"NoSuchKey",
+	"connection reset",
+	"connection refused",
+	"i/o timeout",
+	"TLS handshake timeout",
+	"unexpected EOF",
+}
+
+// retryableOutputsError reports whether reading stack outputs failed in a
+// way worth retrying
+func retryableOutputsError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	msg := err.Error()
+	for _, fragment := range transientBackendErrors {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// stackOutputsWithRetry reads the outputs of a stack, retrying with
+// exponential backoff while the backend fails transiently
+func stackOutputsWithRetry(ctx context.Context, s auto.Stack) (auto.OutputMap, error) {
+	backoff := stackOutputsBackoff
+	for attempt := 1; ; attempt++ {
+		outputs, err := fetchStackOutputs(ctx, s)
+		if err == nil {
+			return outputs, nil
+		}
+		if attempt == stackOutputsAttempts || !retryableOutputsError(err) {
+			return nil, fmt.Errorf("failed to get stack outputs: %w", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to get stack outputs: %w", err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+)
+
+// stubStackOutputs replaces the stack outputs fetcher and removes the backoff
+func stubStackOutputs(t *testing.T, fn func(ctx context.Context, s auto.Stack) (auto.OutputMap, error)) {
+	t.Helper()
+	originalFetch, originalBackoff := fetchStackOutputs, stackOutputsBackoff
+	fetchStackOutputs, stackOutputsBackoff = fn, time.Millisecond
+	t.Cleanup(func() { fetchStackOutputs, stackOutputsBackoff = originalFetch, originalBackoff })
+}
+
+func TestStackOutputsWithRetry_RetriesTransientErrors(t *testing.T) {
+	calls := 0
+	stubStackOutputs(t, func(ctx context.Context, s auto.Stack) (auto.OutputMap, error) {
+		calls++
+		if calls < 3 {
+			return nil, errors.New("reading state: SlowDown: Please reduce your request rate")
+		}
+		return auto.OutputMap{"kubeconfig": {Value: "config"}}, nil
+	})
+
+	outputs, err := stackOutputsWithRetry(context.Background(), auto.Stack{})
+	if err != nil {
+		t.Fatalf("Expected outputs after transient failures, got %v", err)
+	}
+	if calls != 3 || outputs["kubeconfig"].Value != "config" {
+		t.Errorf("Expected outputs from the third attempt, got %d call(s) and %v", calls, outputs)
+	}
+}
+
+func TestStackOutputsWithRetry_GivesUp(t *testing.T) {
+	calls := 0
+	stubStackOutputs(t, func(ctx context.Context, s auto.Stack) (auto.OutputMap, error) {
+		calls++
+		return nil, errors.New("dial tcp: i/o timeout")
+	})
+
+	_, err := stackOutputsWithRetry(context.Background(), auto.Stack{})
+	if err == nil || !strings.Contains(err.Error(), "failed to get stack outputs") {
+		t.Errorf("Expected the last error once attempts run out, got %v", err)
+	}
+	if calls != stackOutputsAttempts {
+		t.Errorf("Expected %d attempts, got %d", stackOutputsAttempts, calls)
+	}
+}
+
+func TestStackOutputsWithRetry_PermanentError(t *testing.T) {
+	calls := 0
+	stubStackOutputs(t, func(ctx context.Context, s auto.Stack) (auto.OutputMap, error) {
+		calls++
+		return nil, errors.New("failed to decrypt secrets: incorrect passphrase")
+	})
+
+	if _, err := stackOutputsWithRetry(context.Background(), auto.Stack{}); err == nil {
+		t.Error("Expected a permanent error to be returned")
+	}
+	if calls != 1 {
+		t.Errorf("Expected no retry of a permanent error, got %d attempts", calls)
+	}
+}
@@ -219,37 +219,11 @@ func createWorkspaceWithS3Support(ctx context.Context) (auto.Workspace, error) {
 		}),
 	}
 
-	// Collect all AWS/S3 environment variables to pass to Pulumi subprocess
-	envVars := make(map[string]string)
-	awsEnvKeys := []string{
-		"AWS_ACCESS_KEY_ID",
-		"AWS_SECRET_ACCESS_KEY",
-		"AWS_REGION",
-		"AWS_S3_ENDPOINT",
-		"AWS_S3_USE_PATH_STYLE",
-		"AWS_S3_FORCE_PATH_STYLE",
-		"PULUMI_BACKEND_URL",
-		"PULUMI_CONFIG_PASSPHRASE",
-	}
-	for _, key := range awsEnvKeys {
-		if val := os.Getenv(key); val != "" {
-			envVars[key] = val
-		}
-	}
-
-	// Add environment variables to workspace options
-	if len(envVars) > 0 {
-		workspaceOpts = append(workspaceOpts, auto.EnvVars(envVars))
-	}
-
-	// If PULUMI_BACKEND_URL is set, use passphrase secrets provider
-	if backendURL := os.Getenv("PULUMI_BACKEND_URL"); backendURL != "" {
-		workspaceOpts = append(workspaceOpts, auto.SecretsProvider("passphrase"))
-		if os.Getenv("PULUMI_CONFIG_PASSPHRASE") == "" {
-			os.Setenv("PULUMI_CONFIG_PASSPHRASE", "")
-			envVars["PULUMI_CONFIG_PASSPHRASE"] = ""
-		}
+	backendOpts, err := newStateBackendOptions()
+	if err != nil {
+		return nil, err
 	}
+	workspaceOpts = append(workspaceOpts, backendOpts...)
 
 	return auto.NewLocalWorkspace(ctx, workspaceOpts...)
 }
@@ -286,12 +260,11 @@ func runListStacks(cmd *cobra.Command, args []string) error {
 }
 
 func runStackInfo(cmd *cobra.Command, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: sloth-kubernetes stacks info <stack-name>")
-	}
-
 	ctx := context.Background()
-	stackName := args[0]
+	stackName, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
 
 	printHeader(fmt.Sprintf("📊 Stack Info: %s", stackName))
 
@@ -326,12 +299,11 @@ func runStackInfo(cmd *cobra.Command, args []string) error {
 }
 
 func runDeleteStack(cmd *cobra.Command, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: sloth-kubernetes stacks delete <stack-name>")
+	stackName, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
 	}
 
-	stackName := args[0]
-
 	printHeader(fmt.Sprintf("🗑️  Deleting Stack: %s", stackName))
 
 	if destroyStack {
@@ -443,12 +415,11 @@ func formatTime(t time.Time) string {
 }
 
 func runStackOutput(cmd *cobra.Command, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: sloth-kubernetes stacks output <stack-name>")
-	}
-
 	ctx := context.Background()
-	stackName := args[0]
+	stackName, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
 
 	printHeader(fmt.Sprintf("📤 Stack Outputs: %s", stackName))
 
@@ -557,12 +528,11 @@ func runSelectStack(cmd *cobra.Command, args []string) error {
 }
 
 func runExportStack(cmd *cobra.Command, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: sloth-kubernetes stacks export <stack-name>")
+	stackName, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
 	}
 
-	stackName := args[0]
-
 	printHeader(fmt.Sprintf("💾 Exporting Stack: %s", stackName))
 
 	fmt.Println()
@@ -578,12 +548,15 @@ func runExportStack(cmd *cobra.Command, args []string) error {
 }
 
 func runImportStack(cmd *cobra.Command, args []string) error {
-	if len(args) < 2 {
-		return fmt.Errorf("usage: sloth-kubernetes stacks import <stack-name> <file>")
+	stackName, rest, err := splitStackArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 {
+		return fmt.Errorf("usage: sloth-kubernetes stacks import [stack-name] <file>")
 	}
 
-	stackName := args[0]
-	filePath := args[1]
+	filePath := rest[0]
 
 	printHeader(fmt.Sprintf("📥 Importing Stack: %s", stackName))
 
@@ -683,12 +656,11 @@ func runRenameStack(cmd *cobra.Command, args []string) error {
 }
 
 func runStateList(cmd *cobra.Command, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: sloth-kubernetes stacks state list <stack-name>")
-	}
-
 	ctx := context.Background()
-	stackName := args[0]
+	stackName, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
 
 	printHeader(fmt.Sprintf("📋 Stack State: %s", stackName))
 
@@ -756,13 +728,16 @@ func runStateList(cmd *cobra.Command, args []string) error {
 }
 
 func runStateDelete(cmd *cobra.Command, args []string) error {
-	if len(args) < 2 {
-		return fmt.Errorf("usage: sloth-kubernetes stacks state delete <stack-name> <urn>")
+	stackName, rest, err := splitStackArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 {
+		return fmt.Errorf("usage: sloth-kubernetes stacks state delete [stack-name] <urn>")
 	}
 
 	ctx := context.Background()
-	stackName := args[0]
-	urn := args[1]
+	urn := rest[0]
 
 	printHeader(fmt.Sprintf("🗑️  Delete Resource from State: %s", stackName))
 
@@ -866,11 +841,11 @@ func runStateDelete(cmd *cobra.Command, args []string) error {
 }
 
 func runCancel(cmd *cobra.Command, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("stack name required")
+	stackName, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
 	}
 
-	stackName := args[0]
 	ctx := context.Background()
 
 	printHeader("🔓 Canceling Stack Operations")
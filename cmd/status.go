@@ -39,6 +39,11 @@ func init() {
 func runStatus(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
 
+	stackName, err := getStackFromArgs(nil, 0)
+	if err != nil {
+		return err
+	}
+
 	s := spinner.New(spinner.CharSets[14], 100*time.Millisecond)
 	s.Suffix = " Fetching cluster status..."
 	s.Start()
@@ -93,9 +98,37 @@ func runStatus(cmd *cobra.Command, args []string) error {
 	// Node table
 	printStatusNodeTable()
 
+	printStatusVersionSkew(stackName, outputs)
+
 	return nil
 }
 
+// printStatusVersionSkew reports nodes outside the Kubernetes version skew
+// policy. Status only warns; 'version-skew' fails on skew.
+func printStatusVersionSkew(stackName string, outputs auto.OutputMap) {
+	nodes, err := ParseNodeOutputs(outputs)
+	if err != nil || len(nodes) == 0 {
+		return
+	}
+
+	versions, unreadable := collectNodeVersions(nodes, newNodeSSHAccess(stackName, outputs))
+	issues := evaluateVersionSkew(versions, maxAgentMinorSkew)
+
+	switch {
+	case len(issues) > 0:
+		color.Red(fmt.Sprintf("Version Skew: ✗ %d node(s) outside the supported range", len(issues)))
+		for _, issue := range issues {
+			color.Red(fmt.Sprintf("  • %s", issue))
+		}
+	case len(versions) == 0:
+		color.Yellow("Version Skew: ⚠️  Could not read node versions")
+	case len(unreadable) > 0:
+		color.Yellow(fmt.Sprintf("Version Skew: ⚠️  %d node(s) unreadable, others within range", len(unreadable)))
+	default:
+		color.Green("Version Skew: ✅ All nodes within the supported range")
+	}
+}
+
 func printStatusNodeTable() {
 	// Simulated node data (in real implementation, would fetch from outputs)
 	color.Cyan("Nodes:")
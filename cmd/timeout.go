@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultSSHTimeout bounds how long a single SSH call may run before it is
+// killed
+const defaultSSHTimeout = 10 * time.Minute
+
+// sshWaitDelay is how long a killed ssh may keep its output pipes open (e.g.
+// through a ProxyCommand child) before they are closed forcibly
+const sshWaitDelay = 5 * time.Second
+
+var (
+	// sshTimeout is the value of --ssh-timeout, which bounds each SSH call on
+	// its own rather than the whole command (0 disables it)
+	sshTimeout = defaultSSHTimeout
+
+	commandCtx    context.Context
+	cancelCommand context.CancelFunc
+)
+
+// applySSHTimeout validates --ssh-timeout and attaches a cancellable
+// command context to cmd. The context has no deadline of its own; aborting
+// it kills every SSH call still in flight.
+func applySSHTimeout(cmd *cobra.Command) error {
+	if sshTimeout < 0 {
+		return fmt.Errorf("--ssh-timeout must not be negative")
+	}
+
+	parent := cmd.Context()
+	if parent == nil {
+		parent = context.Background()
+	}
+	commandCtx, cancelCommand = context.WithCancel(parent)
+	cmd.SetContext(commandCtx)
+	return nil
+}
+
+// commandContext returns the context SSH calls are derived from
+func commandContext() context.Context {
+	if commandCtx == nil {
+		return context.Background()
+	}
+	return commandCtx
+}
+
+// sshCallContext returns the context a single SSH call runs under. Each call
+// gets its own deadline, so a long command never starves the calls it makes
+// late in its run.
+func sshCallContext() (context.Context, context.CancelFunc) {
+	if sshTimeout == 0 {
+		return context.WithCancel(commandContext())
+	}
+	return context.WithTimeout(commandContext(), sshTimeout)
+}
+
+// timeoutError turns err into a clear timeout error when the call failed
+// because ctx hit its deadline, and returns it unchanged otherwise
+func timeoutError(ctx context.Context, name string, err error) error {
+	if err == nil || !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	return fmt.Errorf("%s timed out after %s (raise it with --ssh-timeout): %w", name, sshTimeout, err)
+}
+
+// sshCall is an ssh invocation bound to its own --ssh-timeout deadline
+type sshCall struct {
+	*exec.Cmd
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// newSSHCommand builds an ssh invocation that is killed when it runs longer
+// than --ssh-timeout
+func newSSHCommand(args ...string) *sshCall {
+	ctx, cancel := sshCallContext()
+	c := exec.CommandContext(ctx, "ssh", args...)
+	c.WaitDelay = sshWaitDelay
+	return &sshCall{Cmd: c, ctx: ctx, cancel: cancel}
+}
+
+// CombinedOutput runs the call and returns its combined stdout and stderr
+func (s *sshCall) CombinedOutput() ([]byte, error) {
+	defer s.cancel()
+	output, err := s.Cmd.CombinedOutput()
+	return output, timeoutError(s.ctx, "ssh", err)
+}
+
+// Output runs the call and returns its stdout
+func (s *sshCall) Output() ([]byte, error) {
+	defer s.cancel()
+	output, err := s.Cmd.Output()
+	return output, timeoutError(s.ctx, "ssh", err)
+}
+
+// Run runs the call with the I/O already attached to it
+func (s *sshCall) Run() error {
+	defer s.cancel()
+	return timeoutError(s.ctx, "ssh", s.Cmd.Run())
+}
+
+// runWithContext runs name with args and optional stdin under ctx and returns
+// its combined output. A deadline hit is reported as a timeout error.
+func runWithContext(ctx context.Context, name string, args []string, stdin string) ([]byte, error) {
+	c := exec.CommandContext(ctx, name, args...)
+	c.WaitDelay = sshWaitDelay
+	if stdin != "" {
+		c.Stdin = strings.NewReader(stdin)
+	}
+
+	output, err := c.CombinedOutput()
+	return output, timeoutError(ctx, name, err)
+}
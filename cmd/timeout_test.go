@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// withSSHTimeout sets --ssh-timeout for the duration of a test
+func withSSHTimeout(t *testing.T, timeout time.Duration) *cobra.Command {
+	t.Helper()
+	original := sshTimeout
+	sshTimeout = timeout
+	t.Cleanup(func() {
+		sshTimeout = original
+		commandCtx, cancelCommand = nil, nil
+	})
+
+	cmd := &cobra.Command{}
+	cmd.SetContext(context.Background())
+	return cmd
+}
+
+func TestSSHTimeout_CancelsHungCall(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	cmd := withSSHTimeout(t, 200*time.Millisecond)
+	if err := applySSHTimeout(cmd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cmd.Context() != commandContext() {
+		t.Error("Expected the command context to be attached to the cobra command")
+	}
+
+	ctx, cancel := sshCallContext()
+	defer cancel()
+
+	start := time.Now()
+	_, err := runWithContext(ctx, "sleep", []string{"30"}, "")
+	elapsed := time.Since(start)
+
+	if err == nil {
+		t.Fatal("Expected the hung call to be cancelled")
+	}
+	if !strings.Contains(err.Error(), "--ssh-timeout") {
+		t.Errorf("Expected a timeout error, got %v", err)
+	}
+	if elapsed > 10*time.Second {
+		t.Errorf("Expected the call to be killed promptly, took %s", elapsed)
+	}
+}
+
+func TestSSHTimeout_EachCallGetsItsOwnDeadline(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	cmd := withSSHTimeout(t, 400*time.Millisecond)
+	if err := applySSHTimeout(cmd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Together the calls outlast the timeout; each one alone does not
+	for i := 0; i < 3; i++ {
+		ctx, cancel := sshCallContext()
+		_, err := runWithContext(ctx, "sleep", []string{"0.2"}, "")
+		cancel()
+		if err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i, err)
+		}
+	}
+}
+
+func TestSSHTimeout_SuccessIsNotReportedAsTimeout(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+
+	if err := timeoutError(ctx, "ssh", nil); err != nil {
+		t.Errorf("Expected no error for a call that succeeded, got %v", err)
+	}
+
+	failure := errors.New("exit status 255")
+	if err := timeoutError(context.Background(), "ssh", failure); err != failure {
+		t.Errorf("Expected a failure without a deadline to pass through, got %v", err)
+	}
+}
+
+func TestSSHTimeout_AbortKillsCall(t *testing.T) {
+	if _, err := exec.LookPath("sleep"); err != nil {
+		t.Skip("sleep not available")
+	}
+
+	cmd := withSSHTimeout(t, time.Minute)
+	if err := applySSHTimeout(cmd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := sshCallContext()
+	defer cancel()
+	time.AfterFunc(100*time.Millisecond, cancelCommand)
+
+	start := time.Now()
+	if _, err := runWithContext(ctx, "sleep", []string{"30"}, ""); err == nil {
+		t.Fatal("Expected the aborted call to fail")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected the call to be killed promptly, took %s", elapsed)
+	}
+}
+
+func TestSSHTimeout_FastCallSucceeds(t *testing.T) {
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("echo not available")
+	}
+
+	cmd := withSSHTimeout(t, time.Minute)
+	if err := applySSHTimeout(cmd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := sshCallContext()
+	defer cancel()
+	output, err := runWithContext(ctx, "echo", []string{"ok"}, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.TrimSpace(string(output)) != "ok" {
+		t.Errorf("Expected output 'ok', got %q", output)
+	}
+}
+
+func TestSSHTimeout_ZeroDisablesDeadline(t *testing.T) {
+	cmd := withSSHTimeout(t, 0)
+	if err := applySSHTimeout(cmd); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := sshCallContext()
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("Expected no deadline when --ssh-timeout is 0")
+	}
+}
+
+func TestSSHTimeout_NegativeRejected(t *testing.T) {
+	cmd := withSSHTimeout(t, -time.Second)
+	if err := applySSHTimeout(cmd); err == nil {
+		t.Error("Expected an error for a negative --ssh-timeout")
+	}
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/spf13/cobra"
+)
+
+// forceUnlock makes deploy and destroy remove stale stack locks before running
+var forceUnlock bool
+
+// stackLockPattern matches the lock lines of a self-managed backend's
+// "stack is currently locked" error, e.g.
+// s3://bucket/.pulumi/locks/.../<id>.json: created by alice@laptop (pid 4242) at 2024-05-01T10:00:00Z
+var stackLockPattern = regexp.MustCompile(`(\S+): created by ([^\s@]+)@(\S+) \(pid (\d+)\) at (\S+)`)
+
+// stackLock describes one lock held on a stack
+type stackLock struct {
+	Path    string
+	User    string
+	Host    string
+	PID     int
+	Created time.Time
+}
+
+var unlockCmd = &cobra.Command{
+	Use:   "unlock [stack-name]",
+	Short: "Remove a stale lock left on a stack by a crashed run",
+	Long: `Remove the lock a crashed or killed deploy/destroy left on a stack, so that
+commands stop failing with "the stack is currently locked".
+
+Only the lock is removed: no resources and no state are changed. If another
+deploy or destroy is still running against the stack, unlocking lets a second
+operation run concurrently and can corrupt the state - make sure it is not.`,
+	Example: `  # Unlock a stack after a crashed deploy
+  sloth-kubernetes unlock production
+
+  # Without the confirmation prompt
+  sloth-kubernetes unlock production --yes`,
+	RunE: runUnlock,
+}
+
+func init() {
+	rootCmd.AddCommand(unlockCmd)
+
+	deployCmd.Flags().BoolVar(&forceUnlock, "force-unlock", false, "Remove a stale stack lock before deploying")
+	destroyCmd.Flags().BoolVar(&forceUnlock, "force-unlock", false, "Remove a stale stack lock before destroying")
+}
+
+func runUnlock(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stackName, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("🔓 Unlock Stack - %s", stackName))
+
+	// Create workspace with S3 support
+	workspace, err := createWorkspaceWithS3Support(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	// Use fully qualified stack name for S3 backend
+	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stackName)
+	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
+	if err != nil {
+		return fmt.Errorf("failed to select stack '%s': %w", stackName, err)
+	}
+
+	return unlockStack(ctx, s, stackName)
+}
+
+// unlockStack warns about concurrent operations, asks for confirmation and
+// removes the stack's locks. It only runs `pulumi cancel`, which never
+// touches resources or state.
+func unlockStack(ctx context.Context, s auto.Stack, stackName string) error {
+	fmt.Println()
+	color.Yellow("⚠️  Only unlock if no deploy/destroy is running against stack '%s'.", stackName)
+	color.Yellow("   Two concurrent operations can corrupt the stack state.")
+	fmt.Println()
+
+	if !autoApprove && !confirm(fmt.Sprintf("Remove the lock on stack '%s'?", stackName)) {
+		return fmt.Errorf("unlock cancelled")
+	}
+
+	if err := s.Cancel(ctx); err != nil {
+		return fmt.Errorf("failed to unlock stack '%s': %w", stackName, err)
+	}
+	printSuccess(fmt.Sprintf("Stack '%s' unlocked", stackName))
+	return nil
+}
+
+// isStackLockedError reports whether err says the stack is locked by
+// another operation
+func isStackLockedError(err error) bool {
+	if err == nil {
+		return false
+	}
+	return auto.IsConcurrentUpdateError(err) || strings.Contains(err.Error(), "currently locked")
+}
+
+// parseStackLocks extracts the locks listed in a "stack is currently locked"
+// error message
+func parseStackLocks(msg string) []stackLock {
+	var locks []stackLock
+	for _, m := range stackLockPattern.FindAllStringSubmatch(msg, -1) {
+		pid, _ := strconv.Atoi(m[4])
+		created, _ := time.Parse(time.RFC3339, m[5])
+		locks = append(locks, stackLock{Path: m[1], User: m[2], Host: m[3], PID: pid, Created: created})
+	}
+	return locks
+}
+
+// processRunning reports whether a local process exists, and whether that
+// could be determined at all. It is a variable so tests can fake processes.
+var processRunning = func(pid int) (running bool, known bool) {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false, true
+	}
+	err = process.Signal(syscall.Signal(0))
+	switch {
+	case err == nil:
+		return true, true
+	case strings.Contains(err.Error(), "process already finished"), strings.Contains(err.Error(), "no such process"):
+		return false, true
+	default:
+		return false, false
+	}
+}
+
+// describeStackLock says whether lock belongs to a running operation or was
+// left behind. Only locks taken on this host can be checked for certain.
+func describeStackLock(lock stackLock, hostname string, now time.Time) string {
+	holder := fmt.Sprintf("%s@%s (pid %d)", lock.User, lock.Host, lock.PID)
+	age := "unknown time"
+	if !lock.Created.IsZero() {
+		age = now.Sub(lock.Created).Round(time.Second).String()
+	}
+
+	if lock.Host == hostname {
+		if running, known := processRunning(lock.PID); known {
+			if running {
+				return fmt.Sprintf("%s, %s ago: an operation is still running on this machine - wait for it to finish", holder, age)
+			}
+			return fmt.Sprintf("%s, %s ago: stale - the process that took it is gone", holder, age)
+		}
+	}
+	return fmt.Sprintf("%s, %s ago: held from another machine - check that the operation is no longer running", holder, age)
+}
+
+// stackLockedError explains a lock failure of operation on stackName, listing
+// the locks and how to remove them. Other errors are returned wrapped as is.
+func stackLockedError(err error, operation, stackName string) error {
+	if !isStackLockedError(err) {
+		return fmt.Errorf("failed to %s: %w", operation, err)
+	}
+
+	hostname, _ := os.Hostname()
+	fmt.Println()
+	color.Red("❌ Stack '%s' is locked by another operation", stackName)
+	for _, lock := range parseStackLocks(err.Error()) {
+		fmt.Printf("  • %s\n", describeStackLock(lock, hostname, time.Now()))
+	}
+	fmt.Println()
+	printInfo(fmt.Sprintf("If the lock is stale, remove it with: sloth-kubernetes unlock %s (or retry with --force-unlock)", stackName))
+	return fmt.Errorf("failed to %s: stack '%s' is locked", operation, stackName)
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const lockedErrorOutput = "error: the stack is currently locked by 2 lock(s). Either wait for the other process(es) to end or delete the lock file with `pulumi cancel`.\n" +
+	"  s3://state/.pulumi/locks/organization/sloth-kubernetes/production/1a2b.json: created by alice@laptop (pid 4242) at 2024-05-01T10:00:00Z\n" +
+	"  s3://state/.pulumi/locks/organization/sloth-kubernetes/production/3c4d.json: created by ci@runner-7 (pid 17) at 2024-05-01T09:30:00Z"
+
+func stubProcessRunning(t *testing.T, running, known bool) {
+	t.Helper()
+	original := processRunning
+	processRunning = func(pid int) (bool, bool) { return running, known }
+	t.Cleanup(func() { processRunning = original })
+}
+
+func TestIsStackLockedError(t *testing.T) {
+	if !isStackLockedError(errors.New(lockedErrorOutput)) {
+		t.Error("Expected a locked stack error to be detected")
+	}
+	if isStackLockedError(errors.New("failed to create droplet")) {
+		t.Error("Unrelated errors are not lock errors")
+	}
+	if isStackLockedError(nil) {
+		t.Error("nil is not a lock error")
+	}
+}
+
+func TestParseStackLocks(t *testing.T) {
+	locks := parseStackLocks(lockedErrorOutput)
+	if len(locks) != 2 {
+		t.Fatalf("Expected 2 locks, got %d", len(locks))
+	}
+
+	lock := locks[0]
+	if lock.User != "alice" || lock.Host != "laptop" || lock.PID != 4242 {
+		t.Errorf("Unexpected lock holder: %+v", lock)
+	}
+	if !strings.HasSuffix(lock.Path, "/production/1a2b.json") {
+		t.Errorf("Unexpected lock path: %s", lock.Path)
+	}
+	if !lock.Created.Equal(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("Unexpected lock time: %s", lock.Created)
+	}
+	if locks[1].Host != "runner-7" || locks[1].PID != 17 {
+		t.Errorf("Unexpected second lock: %+v", locks[1])
+	}
+
+	if locks := parseStackLocks("error: stack is locked"); len(locks) != 0 {
+		t.Errorf("Expected no locks without details, got %v", locks)
+	}
+}
+
+func TestDescribeStackLock(t *testing.T) {
+	lock := stackLock{User: "alice", Host: "laptop", PID: 4242, Created: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	now := lock.Created.Add(2 * time.Hour)
+
+	stubProcessRunning(t, false, true)
+	if got := describeStackLock(lock, "laptop", now); !strings.Contains(got, "stale") || !strings.Contains(got, "2h0m0s ago") {
+		t.Errorf("Expected a stale lock, got %q", got)
+	}
+
+	stubProcessRunning(t, true, true)
+	if got := describeStackLock(lock, "laptop", now); !strings.Contains(got, "still running") {
+		t.Errorf("Expected a running operation, got %q", got)
+	}
+
+	if got := describeStackLock(lock, "other-host", now); !strings.Contains(got, "another machine") {
+		t.Errorf("Expected a lock from another machine, got %q", got)
+	}
+
+	stubProcessRunning(t, false, false)
+	if got := describeStackLock(lock, "laptop", now); !strings.Contains(got, "check that the operation") {
+		t.Errorf("Expected an undetermined lock, got %q", got)
+	}
+}
+
+func TestStackLockedError(t *testing.T) {
+	err := stackLockedError(errors.New(lockedErrorOutput), "deploy", "production")
+	if err == nil || !strings.Contains(err.Error(), "stack 'production' is locked") {
+		t.Errorf("Expected a locked stack error, got %v", err)
+	}
+
+	err = stackLockedError(errors.New("quota exceeded"), "deploy", "production")
+	if err == nil || err.Error() != "failed to deploy: quota exceeded" {
+		t.Errorf("Expected other errors to be wrapped as is, got %v", err)
+	}
+}
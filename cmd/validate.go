@@ -3,6 +3,7 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
@@ -25,7 +26,8 @@ This command performs comprehensive validation including:
   • DNS configuration
   • Resource limits and quotas
 
-Use this before 'deploy' to catch configuration errors early.`,
+Use this before 'deploy' to catch configuration errors early. With --live, node
+images are also checked against the provider APIs for their target region.`,
 	Example: `  # Validate configuration file
   sloth-kubernetes validate --config cluster.yaml
 
@@ -33,12 +35,19 @@ Use this before 'deploy' to catch configuration errors early.`,
   sloth-kubernetes validate --config production.yaml --verbose
 
   # Validate and show node distribution
-  sloth-kubernetes validate -c staging.yaml`,
+  sloth-kubernetes validate -c staging.yaml
+
+  # Also check node images with the cloud providers
+  sloth-kubernetes validate -c production.yaml --live`,
 	RunE: runValidate,
 }
 
+var validateLive bool
+
 func init() {
 	rootCmd.AddCommand(validateCmd)
+
+	validateCmd.Flags().BoolVar(&validateLive, "live", false, "Also check node images against the provider APIs")
 }
 
 func runValidate(cmd *cobra.Command, args []string) error {
@@ -148,6 +157,7 @@ func runValidate(cmd *cobra.Command, args []string) error {
 		fmt.Println("  • At least 1 master node")
 		fmt.Println("  • Master nodes must be odd number for HA (1, 3, 5, ...)")
 		fmt.Println("  • At least 1 node in total")
+		fmt.Println("  • With highAvailability, at least 1 worker node")
 		fmt.Println()
 		return err
 	}
@@ -230,6 +240,98 @@ func runValidate(cmd *cobra.Command, args []string) error {
 	}
 	fmt.Println()
 
+	if err := config.ValidateDisabledComponents(cfg); err != nil {
+		color.Red("❌ RKE2 component validation failed")
+		fmt.Printf("  %v\n", err)
+		fmt.Println()
+		color.Yellow("💡 rke2-ingress-nginx can be disabled (sloth-kubernetes installs its own ingress),")
+		fmt.Println("   but CoreDNS and the network plugin are required")
+		fmt.Println()
+		return err
+	}
+	if cfg.Kubernetes.RKE2 != nil && len(cfg.Kubernetes.RKE2.DisableComponents) > 0 {
+		color.Green("✅ Disabled RKE2 components: %s", strings.Join(cfg.Kubernetes.RKE2.DisableComponents, ", "))
+		fmt.Println()
+	}
+
+	if err := config.ValidateAdmissionConfig(&cfg.Kubernetes.Admission); err != nil {
+		color.Red("❌ Admission plugin validation failed")
+		fmt.Printf("  %v\n", err)
+		fmt.Println()
+		return err
+	}
+	if len(cfg.Kubernetes.Admission.Plugins) > 0 {
+		color.Green("✅ Admission plugins: %s", strings.Join(cfg.Kubernetes.Admission.Plugins, ", "))
+		fmt.Println()
+	}
+
+	if err := config.ValidateBastionJumpChain(cfg.Security.Bastion); err != nil {
+		color.Red("❌ Bastion jump host validation failed")
+		fmt.Printf("  %v\n", err)
+		fmt.Println()
+		return err
+	}
+
+	if err := config.ValidateBastionSalt(cfg.Security.Bastion); err != nil {
+		color.Red("❌ Bastion Salt validation failed")
+		fmt.Printf("  %v\n", err)
+		fmt.Println()
+		return err
+	}
+
+	if err := config.ValidateBastionAuditLog(cfg.Security.Bastion); err != nil {
+		color.Red("❌ Bastion audit log validation failed")
+		fmt.Printf("  %v\n", err)
+		fmt.Println()
+		return err
+	}
+
+	if err := config.ValidateIngressController(&cfg.Network.Ingress); err != nil {
+		color.Red("❌ Ingress controller validation failed")
+		fmt.Printf("  %v\n", err)
+		fmt.Println()
+		return err
+	}
+
+	if err := config.ValidateMonitoring(cfg); err != nil {
+		color.Red("❌ Monitoring validation failed")
+		fmt.Printf("  %v\n", err)
+		fmt.Println()
+		return err
+	}
+
+	if err := config.ValidateAdminPasswords(cfg); err != nil {
+		color.Red("❌ Password validation failed")
+		fmt.Printf("  %v\n", err)
+		fmt.Println()
+		return err
+	}
+
+	if err := config.ValidateDownloadChecksums(&cfg.Security); err != nil {
+		color.Red("❌ Download checksum validation failed")
+		fmt.Printf("  %v\n", err)
+		fmt.Println()
+		return err
+	}
+
+	if validateLive {
+		printHeader("🔌 Validating with Cloud Providers")
+		fmt.Println()
+
+		imageWarnings, err := validation.ValidateNodeImages(cfg, validation.NewImageCatalog())
+		for _, warning := range imageWarnings {
+			color.Yellow("⚠️  %s", warning)
+		}
+		if err != nil {
+			color.Red("❌ Node image validation failed")
+			fmt.Printf("  %v\n", err)
+			fmt.Println()
+			return err
+		}
+		color.Green("✅ Node images are available in their regions")
+		fmt.Println()
+	}
+
 	// Overall validation
 	printHeader("✨ Overall Validation")
 	fmt.Println()
@@ -303,5 +405,13 @@ func collectWarnings(cfg *config.ClusterConfig) []string {
 		warnings = append(warnings, "Single cloud provider - consider multi-cloud for redundancy")
 	}
 
+	// Check disabled RKE2 components that degrade features in use
+	_, componentWarnings := config.CheckDisabledComponents(cfg)
+	warnings = append(warnings, componentWarnings...)
+
+	// Check admission plugin names
+	_, admissionWarnings := config.CheckAdmissionConfig(&cfg.Kubernetes.Admission)
+	warnings = append(warnings, admissionWarnings...)
+
 	return warnings
 }
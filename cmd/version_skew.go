@@ -0,0 +1,307 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/spf13/cobra"
+)
+
+// maxAgentMinorSkew is how many minor versions a kubelet may lag behind the
+// oldest kube-apiserver (Kubernetes version skew policy, 1.28+)
+const maxAgentMinorSkew = 3
+
+// maxServerMinorSkew is how far apart kube-apiservers of an HA control plane
+// may be
+const maxServerMinorSkew = 1
+
+var skewMaxAgentMinor int
+
+// kubeVersion is a parsed Kubernetes, RKE2 or K3s version such as
+// v1.28.5+rke2r1
+type kubeVersion struct {
+	Major int
+	Minor int
+	Patch int
+	Raw   string
+}
+
+// nodeVersion is the Kubernetes version running on a node
+type nodeVersion struct {
+	Node    string
+	Server  bool
+	Version kubeVersion
+}
+
+var versionSkewCmd = &cobra.Command{
+	Use:   "version-skew [stack-name]",
+	Short: "Check Kubernetes version skew between servers and agents",
+	Long: `Read the RKE2 or K3s version of every node of a deployed stack over SSH and report
+nodes outside the Kubernetes version skew policy:
+  • Servers must be within one minor version of each other
+  • Agents must not be newer than the oldest server
+  • Agents must not lag the oldest server by more than --max-agent-skew minor versions`,
+	Example: `  # Check a stack
+  sloth-kubernetes version-skew production
+
+  # Enforce the pre-1.28 policy of two minor versions
+  sloth-kubernetes version-skew production --max-agent-skew 2`,
+	RunE: runVersionSkew,
+}
+
+func init() {
+	rootCmd.AddCommand(versionSkewCmd)
+
+	versionSkewCmd.Flags().IntVar(&skewMaxAgentMinor, "max-agent-skew", maxAgentMinorSkew, "Minor versions agents may lag behind servers")
+}
+
+func runVersionSkew(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("🔢 Version Skew - Stack: %s", stack))
+
+	// Create workspace with S3 support
+	workspace, err := createWorkspaceWithS3Support(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	// Use fully qualified stack name for S3 backend
+	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
+	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
+	if err != nil {
+		return selectStackError(err, stack)
+	}
+
+	// Get outputs
+	outputs, err := stackOutputsWithRetry(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	// Parse nodes
+	nodes, err := ParseNodeOutputs(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to parse nodes: %w", err)
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("no nodes found in stack")
+	}
+
+	fmt.Println()
+	printInfo(fmt.Sprintf("Reading Kubernetes versions from %d nodes...", len(nodes)))
+	fmt.Println()
+
+	versions, unreadable := collectNodeVersions(nodes, newNodeSSHAccess(stack, outputs))
+	printNodeVersions(versions)
+	for _, msg := range unreadable {
+		color.Yellow(fmt.Sprintf("  ⚠️  %s", msg))
+	}
+
+	fmt.Println()
+	issues := evaluateVersionSkew(versions, skewMaxAgentMinor)
+	if len(issues) > 0 {
+		for _, issue := range issues {
+			color.Red(fmt.Sprintf("  ✗ %s", issue))
+		}
+		return fmt.Errorf("%d node(s) outside the supported version skew", len(issues))
+	}
+	if len(unreadable) > 0 {
+		return fmt.Errorf("could not read the version of %d node(s)", len(unreadable))
+	}
+
+	printSuccess("All nodes are within the supported version skew")
+	return nil
+}
+
+// nodeVersionScript prints the distribution of a node and its `<binary>
+// --version` output, one per line, detected like joinDetailsScript does
+var nodeVersionScript = etcdBackupScriptPrefix(nil) + `echo "$BIN"
+$BIN --version 2>/dev/null | head -1
+`
+
+// parseNodeVersion parses the output of nodeVersionScript
+func parseNodeVersion(output string) (kubeVersion, error) {
+	lines := strings.SplitN(output, "\n", 2)
+	distribution := strings.TrimSpace(lines[0])
+	if distribution != distributionRKE2 && distribution != distributionK3s {
+		return kubeVersion{}, fmt.Errorf("unexpected distribution %q", distribution)
+	}
+	if len(lines) < 2 {
+		return kubeVersion{}, fmt.Errorf("%s --version printed nothing", distribution)
+	}
+	return parseKubeVersion(parseRKE2Version(lines[1]))
+}
+
+// collectNodeVersions reads `rke2 --version` or `k3s --version` from every
+// node. Nodes whose version cannot be read are reported separately.
+func collectNodeVersions(nodes []NodeInfo, access nodeSSHAccess) ([]nodeVersion, []string) {
+	var versions []nodeVersion
+	var unreadable []string
+	for _, node := range nodes {
+		output, err := access.run(node, 10, nodeVersionScript)
+		if err != nil {
+			unreadable = append(unreadable, fmt.Sprintf("%s: %v", node.Name, err))
+			continue
+		}
+
+		version, err := parseNodeVersion(string(output))
+		if err != nil {
+			unreadable = append(unreadable, fmt.Sprintf("%s: %v", node.Name, err))
+			continue
+		}
+		versions = append(versions, nodeVersion{Node: node.Name, Server: isControlPlaneNode(node), Version: version})
+	}
+	return versions, unreadable
+}
+
+// printNodeVersions prints the version of every node, servers first
+func printNodeVersions(versions []nodeVersion) {
+	sorted := make([]nodeVersion, len(versions))
+	copy(sorted, versions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Server != sorted[j].Server {
+			return sorted[i].Server
+		}
+		return sorted[i].Node < sorted[j].Node
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NODE\tROLE\tVERSION")
+	for _, v := range sorted {
+		role := "agent"
+		if v.Server {
+			role = "server"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", v.Node, role, v.Version.Raw)
+	}
+	w.Flush()
+}
+
+// parseKubeVersion parses versions such as v1.28.5+rke2r1, 1.28.5 or v1.28
+func parseKubeVersion(raw string) (kubeVersion, error) {
+	trimmed := strings.TrimPrefix(strings.TrimSpace(raw), "v")
+	if i := strings.IndexAny(trimmed, "+-"); i >= 0 {
+		trimmed = trimmed[:i]
+	}
+
+	parts := strings.Split(trimmed, ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return kubeVersion{}, fmt.Errorf("invalid Kubernetes version %q", raw)
+	}
+
+	nums := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return kubeVersion{}, fmt.Errorf("invalid Kubernetes version %q", raw)
+		}
+		nums[i] = n
+	}
+
+	return kubeVersion{Major: nums[0], Minor: nums[1], Patch: nums[2], Raw: strings.TrimSpace(raw)}, nil
+}
+
+// minorsBehind returns how many minor versions v is behind other; negative
+// when v is newer. Different major versions count as unlimited skew.
+func (v kubeVersion) minorsBehind(other kubeVersion) int {
+	if v.Major != other.Major {
+		if v.Major < other.Major {
+			return math.MaxInt
+		}
+		return -math.MaxInt
+	}
+	return other.Minor - v.Minor
+}
+
+// evaluateVersionSkew returns one message per node outside the version skew
+// policy: servers within maxServerMinorSkew of each other, and agents no newer
+// than the oldest server and at most maxAgentMinor minor versions behind it
+func evaluateVersionSkew(versions []nodeVersion, maxAgentMinor int) []string {
+	var servers []nodeVersion
+	for _, v := range versions {
+		if v.Server {
+			servers = append(servers, v)
+		}
+	}
+	if len(servers) == 0 {
+		return nil
+	}
+
+	oldest, newest := servers[0], servers[0]
+	for _, s := range servers[1:] {
+		if s.Version.minorsBehind(oldest.Version) > 0 {
+			oldest = s
+		}
+		if s.Version.minorsBehind(newest.Version) < 0 {
+			newest = s
+		}
+	}
+
+	var issues []string
+	if oldest.Version.minorsBehind(newest.Version) > maxServerMinorSkew {
+		issues = append(issues, fmt.Sprintf("server %s (%s) is more than %d minor version behind server %s (%s)",
+			oldest.Node, oldest.Version.Raw, maxServerMinorSkew, newest.Node, newest.Version.Raw))
+	}
+
+	for _, v := range versions {
+		if v.Server {
+			continue
+		}
+		behind := v.Version.minorsBehind(oldest.Version)
+		switch {
+		case behind < 0:
+			issues = append(issues, fmt.Sprintf("agent %s (%s) is newer than server %s (%s)",
+				v.Node, v.Version.Raw, oldest.Node, oldest.Version.Raw))
+		case behind > maxAgentMinor:
+			issues = append(issues, fmt.Sprintf("agent %s (%s) is more than %d minor versions behind server %s (%s)",
+				v.Node, v.Version.Raw, maxAgentMinor, oldest.Node, oldest.Version.Raw))
+		}
+	}
+
+	return issues
+}
+
+// validateUpgradeSkew checks that moving the nodes in targets (node name ->
+// version) to their target version keeps the cluster within the version skew
+// policy. Upgrades call this before touching any node.
+func validateUpgradeSkew(versions []nodeVersion, targets map[string]string, maxAgentMinor int) error {
+	planned := make([]nodeVersion, len(versions))
+	copy(planned, versions)
+
+	known := make(map[string]bool, len(planned))
+	for i, v := range planned {
+		known[v.Node] = true
+		target, ok := targets[v.Node]
+		if !ok {
+			continue
+		}
+		version, err := parseKubeVersion(target)
+		if err != nil {
+			return fmt.Errorf("node %s: %w", v.Node, err)
+		}
+		planned[i].Version = version
+	}
+	for node := range targets {
+		if !known[node] {
+			return fmt.Errorf("node %s has no known version", node)
+		}
+	}
+
+	if issues := evaluateVersionSkew(planned, maxAgentMinor); len(issues) > 0 {
+		return fmt.Errorf("upgrade would create unsupported version skew:\n  • %s", strings.Join(issues, "\n  • "))
+	}
+	return nil
+}
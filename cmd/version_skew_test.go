@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func mustKubeVersion(t *testing.T, raw string) kubeVersion {
+	t.Helper()
+	v, err := parseKubeVersion(raw)
+	if err != nil {
+		t.Fatalf("parseKubeVersion(%q): %v", raw, err)
+	}
+	return v
+}
+
+func TestParseKubeVersion(t *testing.T) {
+	tests := []struct {
+		raw                 string
+		major, minor, patch int
+	}{
+		{"v1.28.5+rke2r1", 1, 28, 5},
+		{"1.29.0", 1, 29, 0},
+		{"v1.30", 1, 30, 0},
+		{"v1.27.3-rc1", 1, 27, 3},
+	}
+	for _, tt := range tests {
+		v := mustKubeVersion(t, tt.raw)
+		if v.Major != tt.major || v.Minor != tt.minor || v.Patch != tt.patch {
+			t.Errorf("parseKubeVersion(%q) = %+v", tt.raw, v)
+		}
+		if v.Raw != tt.raw {
+			t.Errorf("Expected raw %q, got %q", tt.raw, v.Raw)
+		}
+	}
+
+	for _, raw := range []string{"", "latest", "v1", "v1.x.2", "1.2.3.4"} {
+		if _, err := parseKubeVersion(raw); err == nil {
+			t.Errorf("Expected error for %q", raw)
+		}
+	}
+}
+
+func TestCollectNodeVersions_DetectsK3s(t *testing.T) {
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		if !strings.Contains(strings.Join(args, " ")+stdin, "BIN=k3s") {
+			t.Errorf("Expected the distribution to be detected on the node, got %v", args)
+		}
+		return []byte("k3s\nk3s version v1.30.4+k3s1 (98262b5d)\n"), nil
+	})
+
+	nodes := []NodeInfo{{Name: "master-1", PublicIP: "203.0.113.10", Roles: []string{"master"}}}
+	versions, unreadable := collectNodeVersions(nodes, nodeSSHAccess{Stack: "production", KeyPath: "/tmp/key"})
+	if len(unreadable) > 0 {
+		t.Fatalf("Unexpected unreadable nodes: %v", unreadable)
+	}
+	if len(versions) != 1 || versions[0].Version.Raw != "v1.30.4+k3s1" || !versions[0].Server {
+		t.Errorf("Expected master-1 as a v1.30.4+k3s1 server, got %+v", versions)
+	}
+
+	if _, err := parseNodeVersion("docker\nDocker version 24.0.7\n"); err == nil {
+		t.Error("Expected an unknown distribution to be rejected")
+	}
+}
+
+func TestEvaluateVersionSkew(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers []string
+		agents  []string
+		want    []string // substrings, one per expected issue
+	}{
+		{"same version", []string{"v1.28.5+rke2r1"}, []string{"v1.28.5+rke2r1"}, nil},
+		{"patch difference", []string{"v1.28.5"}, []string{"v1.28.1"}, nil},
+		{"agent three minors behind", []string{"v1.31.0"}, []string{"v1.28.9"}, nil},
+		{"agent four minors behind", []string{"v1.32.0"}, []string{"v1.28.9"}, []string{"more than 3 minor versions behind"}},
+		{"agent newer than server", []string{"v1.28.5"}, []string{"v1.29.0"}, []string{"newer than server"}},
+		{"agent newer than oldest server", []string{"v1.28.5", "v1.29.1"}, []string{"v1.29.1"}, []string{"newer than server server-0"}},
+		{"servers one minor apart", []string{"v1.28.5", "v1.29.1"}, nil, nil},
+		{"servers two minors apart", []string{"v1.28.5", "v1.30.0"}, nil, []string{"server server-0 (v1.28.5) is more than 1 minor version behind"}},
+		{"major version mismatch", []string{"v2.0.0"}, []string{"v1.28.0"}, []string{"minor versions behind"}},
+		{"no servers", nil, []string{"v1.28.0", "v1.20.0"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var versions []nodeVersion
+			for i, raw := range tt.servers {
+				versions = append(versions, nodeVersion{Node: "server-" + string(rune('0'+i)), Server: true, Version: mustKubeVersion(t, raw)})
+			}
+			for i, raw := range tt.agents {
+				versions = append(versions, nodeVersion{Node: "agent-" + string(rune('0'+i)), Version: mustKubeVersion(t, raw)})
+			}
+
+			issues := evaluateVersionSkew(versions, maxAgentMinorSkew)
+			if len(issues) != len(tt.want) {
+				t.Fatalf("Expected %d issue(s), got %v", len(tt.want), issues)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(issues[i], want) {
+					t.Errorf("Expected issue containing %q, got %q", want, issues[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateVersionSkew_MaxAgentMinor(t *testing.T) {
+	versions := []nodeVersion{
+		{Node: "master-1", Server: true, Version: mustKubeVersion(t, "v1.30.0")},
+		{Node: "worker-1", Version: mustKubeVersion(t, "v1.27.0")},
+	}
+	if issues := evaluateVersionSkew(versions, 3); len(issues) != 0 {
+		t.Errorf("Expected no issues with a skew of 3, got %v", issues)
+	}
+	if issues := evaluateVersionSkew(versions, 2); len(issues) != 1 {
+		t.Errorf("Expected one issue with a skew of 2, got %v", issues)
+	}
+}
+
+func TestValidateUpgradeSkew(t *testing.T) {
+	versions := []nodeVersion{
+		{Node: "master-1", Server: true, Version: mustKubeVersion(t, "v1.28.5+rke2r1")},
+		{Node: "master-2", Server: true, Version: mustKubeVersion(t, "v1.28.5+rke2r1")},
+		{Node: "worker-1", Version: mustKubeVersion(t, "v1.28.5+rke2r1")},
+	}
+
+	// Servers first, one minor at a time, is supported
+	if err := validateUpgradeSkew(versions, map[string]string{"master-1": "v1.29.0+rke2r1"}, maxAgentMinorSkew); err != nil {
+		t.Errorf("Expected rolling server upgrade to be allowed, got %v", err)
+	}
+
+	// Agents ahead of the control plane are not
+	err := validateUpgradeSkew(versions, map[string]string{"worker-1": "v1.29.0+rke2r1"}, maxAgentMinorSkew)
+	if err == nil || !strings.Contains(err.Error(), "agent worker-1 (v1.29.0+rke2r1) is newer") {
+		t.Errorf("Expected agent skew error, got %v", err)
+	}
+
+	// Skipping minor versions on one server breaks the HA control plane
+	err = validateUpgradeSkew(versions, map[string]string{"master-1": "v1.30.0"}, maxAgentMinorSkew)
+	if err == nil || !strings.Contains(err.Error(), "server master-2") {
+		t.Errorf("Expected server skew error, got %v", err)
+	}
+
+	if err := validateUpgradeSkew(versions, map[string]string{"worker-1": "latest"}, maxAgentMinorSkew); err == nil {
+		t.Error("Expected error for invalid target version")
+	}
+	if err := validateUpgradeSkew(versions, map[string]string{"worker-9": "v1.28.5"}, maxAgentMinorSkew); err == nil {
+		t.Error("Expected error for unknown node")
+	}
+}
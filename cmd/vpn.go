@@ -5,9 +5,11 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"fmt"
+	"io"
+	"net"
 	"os"
 	"os/exec"
-	"strconv"
+	"sort"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -16,6 +18,20 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/auto"
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/curve25519"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+// broadClientRoute is routed through every cluster node peer of a client
+// config unless --no-broad-route is given
+const broadClientRoute = "10.0.0.0/8"
+
+// Cluster networks routed with --no-broad-route when the stack does not
+// export them
+const (
+	defaultPodCIDR     = "10.42.0.0/16"
+	defaultServiceCIDR = "10.43.0.0/16"
 )
 
 var (
@@ -24,6 +40,12 @@ var (
 	vpnJoinIP      string
 	vpnJoinLabel   string
 	vpnJoinInstall bool
+	vpnJoinAtomic  bool
+	vpnJoinResume  bool
+	vpnJoinForce   bool
+	vpnJoinNoBroad bool
+	vpnJoinPrint   bool
+	vpnJoinPSK     bool
 
 	// VPN leave command flags
 	vpnLeaveIP string
@@ -31,6 +53,24 @@ var (
 	// VPN client config flags
 	vpnConfigOutput string
 	vpnConfigQR     bool
+
+	// VPN peers flags
+	vpnPeersOutput string
+
+	// VPN test flags
+	vpnTestFrom     string
+	vpnTestTo       string
+	vpnTestOutput   string
+	vpnTestTextfile string
+
+	// VPN stats flags
+	vpnStatsInterval int
+	vpnStatsTop      int
+	vpnStatsCSV      string
+
+	// VPN rotate-keys flags
+	vpnRotateIP     string
+	vpnRotateOutput string
 )
 
 var vpnCmd = &cobra.Command{
@@ -51,9 +91,16 @@ var vpnStatusCmd = &cobra.Command{
 var vpnPeersCmd = &cobra.Command{
 	Use:   "peers [stack-name]",
 	Short: "List all VPN peers",
-	Long:  `Display all nodes in the VPN mesh with their public keys and endpoints`,
+	Long: `Display all nodes in the VPN mesh with their public keys and endpoints.
+
+With --output json or --output yaml the peers are written to stdout with full
+public keys, handshake ages in seconds and raw transfer byte counts, and the
+human output goes to stderr.`,
 	Example: `  # List VPN peers
-  sloth-kubernetes vpn peers production`,
+  sloth-kubernetes vpn peers production
+
+  # List VPN peers for scripting
+  sloth-kubernetes vpn peers production --output json | jq '.[] | select(.handshake_age_seconds > 180)'`,
 	RunE: runVPNPeers,
 }
 
@@ -69,9 +116,25 @@ var vpnConfigCmd = &cobra.Command{
 var vpnTestCmd = &cobra.Command{
 	Use:   "test [stack-name]",
 	Short: "Test VPN connectivity",
-	Long:  `Test connectivity between all nodes in the VPN mesh`,
+	Long: `Test connectivity between all nodes in the VPN mesh.
+Use --from and --to to test only the links of a suspect node instead of the full mesh.
+Exits with code 6 when any tested link fails.
+
+With --output prometheus or --output json the results are written to stdout
+in that format and the human output goes to stderr. --textfile-dir writes the
+Prometheus metrics to sloth_vpn_<stack>.prom in a node_exporter textfile
+collector directory instead, so a cron job can feed mesh health to alerts.`,
 	Example: `  # Test VPN connectivity
-  sloth-kubernetes vpn test production`,
+  sloth-kubernetes vpn test production
+
+  # Test only the links leaving master-1
+  sloth-kubernetes vpn test production --from master-1
+
+  # Test master-1 <-> worker-3 in both directions
+  sloth-kubernetes vpn test production --from master-1 --to worker-3
+
+  # Export mesh health for node_exporter from cron
+  sloth-kubernetes vpn test production --textfile-dir /var/lib/node_exporter/textfile`,
 	RunE: runVPNTest,
 }
 
@@ -91,7 +154,22 @@ and provide you with the WireGuard configuration to install locally.`,
   sloth-kubernetes vpn join production --vpn-ip 10.8.0.100
 
   # Join and auto-install WireGuard config
-  sloth-kubernetes vpn join production --install`,
+  sloth-kubernetes vpn join production --install
+
+  # Undo partial changes if interrupted with Ctrl-C
+  sloth-kubernetes vpn join production --atomic
+
+  # Retry only the nodes the last join failed on
+  sloth-kubernetes vpn join production --resume-failed
+
+  # Keep the peer on the nodes that accepted it even if others failed
+  sloth-kubernetes vpn join production --force
+
+  # Route only the cluster networks instead of all of 10.0.0.0/8
+  sloth-kubernetes vpn join production --no-broad-route
+
+  # Print the client config and install it on another host
+  sloth-kubernetes vpn join production --print-config | ssh host 'sudo tee /etc/wireguard/wg0.conf >/dev/null'`,
 	RunE: runVPNJoin,
 }
 
@@ -110,7 +188,11 @@ var vpnLeaveCmd = &cobra.Command{
 var vpnClientConfigCmd = &cobra.Command{
 	Use:   "client-config [stack-name]",
 	Short: "Generate WireGuard client configuration",
-	Long:  `Generate a WireGuard configuration file for connecting to the VPN mesh`,
+	Long: `Generate a WireGuard configuration file for connecting to the VPN mesh.
+A new keypair and a free VPN IP of the client range (.100 onwards of the
+stack's VPN subnet) are assigned, but unlike
+'vpn join' the peer is not added to the cluster nodes: the config is for
+manual import.`,
 	Example: `  # Generate client config
   sloth-kubernetes vpn client-config production
 
@@ -122,6 +204,79 @@ var vpnClientConfigCmd = &cobra.Command{
 	RunE: runVPNClientConfig,
 }
 
+var vpnStatsCmd = &cobra.Command{
+	Use:   "stats [stack-name]",
+	Short: "Show VPN transfer rates per peer",
+	Long: `Sample WireGuard transfer counters on every node twice, a few seconds apart,
+and report per-peer and per-node throughput along with the top talkers.
+Useful for spotting saturated mesh links.`,
+	Example: `  # Sample transfer rates over 5 seconds
+  sloth-kubernetes vpn stats production
+
+  # Sample over 30 seconds and show the top 5 links
+  sloth-kubernetes vpn stats production --interval 30 --top 5
+
+  # Export the samples as CSV for plotting
+  sloth-kubernetes vpn stats production --csv vpn-stats.csv`,
+	RunE: runVPNStats,
+}
+
+var vpnRefreshEndpointsCmd = &cobra.Command{
+	Use:   "refresh-endpoints [stack-name]",
+	Short: "Update peer endpoints after node IP changes",
+	Long: `Re-read each node's current public IP from the stack outputs and update the
+WireGuard Endpoint for that node on every other node where it has changed.
+Fixes broken tunnels after a reboot or dynamic IP change. Safe to run repeatedly.`,
+	Example: `  # Refresh stale peer endpoints
+  sloth-kubernetes vpn refresh-endpoints production`,
+	RunE: runVPNRefreshEndpoints,
+}
+
+var vpnDoctorCmd = &cobra.Command{
+	Use:   "doctor [stack-name]",
+	Short: "Diagnose VPN mesh problems",
+	Long: `Check every node for the wg0 interface, IP forwarding, a ufw rule for the
+WireGuard port and the handshake age of each peer, then cross-reference the
+peers both ends of each link have configured. Prints the probable root causes,
+most important first, with the symptoms each one explains and how to fix it.`,
+	Example: `  # Diagnose the mesh
+  sloth-kubernetes vpn doctor production`,
+	RunE: runVPNDoctor,
+}
+
+var vpnRebuildCmd = &cobra.Command{
+	Use:   "rebuild [stack-name]",
+	Short: "Regenerate the WireGuard mesh on every node",
+	Long: `Rewrite wg0.conf on every cluster node from the stack's node list, so each node
+peers with all other nodes again. Peers that are not cluster nodes (clients added
+with 'vpn join', the bastion) are kept. RKE2 is not touched. Use this when the
+mesh is broken beyond what 'vpn refresh-endpoints' can fix; connectivity between
+all nodes is verified afterwards.
+
+Peers get the routes and endpoints the deployment gives them, read from the
+cluster config (--config, default ./cluster-config.yaml): private routes to other
+providers, private endpoints within a shared VPC in hybrid mode, and only the hub
+as peer when the nodes are spokes of an existing WireGuard server.`,
+	Example: `  # Reset the mesh to a full mesh
+  sloth-kubernetes vpn rebuild production
+
+  # Without the confirmation prompt
+  sloth-kubernetes vpn rebuild production --yes`,
+	RunE: runVPNRebuild,
+}
+
+var vpnRotateKeysCmd = &cobra.Command{
+	Use:   "rotate-keys [stack-name]",
+	Short: "Replace a VPN client's WireGuard keypair",
+	Long: `Generate a new keypair for the client with the given VPN IP, replace its old
+public key with the new one on every cluster node and write a new client config.
+The client keeps its VPN IP and label. Every node is checked first: nothing is
+changed unless all nodes are reachable and at least one has the old key.`,
+	Example: `  # Rotate a compromised laptop key
+  sloth-kubernetes vpn rotate-keys production --vpn-ip 10.8.0.100`,
+	RunE: runVPNRotateKeys,
+}
+
 func init() {
 	rootCmd.AddCommand(vpnCmd)
 
@@ -133,24 +288,62 @@ func init() {
 	vpnCmd.AddCommand(vpnJoinCmd)
 	vpnCmd.AddCommand(vpnLeaveCmd)
 	vpnCmd.AddCommand(vpnClientConfigCmd)
+	vpnCmd.AddCommand(vpnStatsCmd)
+	vpnCmd.AddCommand(vpnRefreshEndpointsCmd)
+	vpnCmd.AddCommand(vpnDoctorCmd)
+	vpnCmd.AddCommand(vpnRebuildCmd)
+	vpnCmd.AddCommand(vpnRotateKeysCmd)
+
+	// Concurrency of the per-node SSH sessions
+	for _, c := range []*cobra.Command{vpnPeersCmd, vpnTestCmd, vpnJoinCmd, vpnDoctorCmd} {
+		c.Flags().IntVar(&vpnConcurrency, "concurrency", defaultSSHConcurrency, fmt.Sprintf("Nodes to contact at once (at most %d through the bastion)", maxBastionConcurrency))
+	}
 
 	// Join flags
 	vpnJoinCmd.Flags().StringVar(&vpnJoinRemote, "remote", "", "Remote SSH host to add (e.g., user@host.com)")
-	vpnJoinCmd.Flags().StringVar(&vpnJoinIP, "vpn-ip", "", "Custom VPN IP address (default: auto-assign)")
+	vpnJoinCmd.Flags().StringVar(&vpnJoinIP, "vpn-ip", "", "Custom VPN IP address in the client range of the VPN subnet (default: auto-assign)")
 	vpnJoinCmd.Flags().StringVar(&vpnJoinLabel, "label", "", "Peer label/name (e.g., 'laptop', 'ci-server')")
 	vpnJoinCmd.Flags().BoolVar(&vpnJoinInstall, "install", false, "Auto-install WireGuard configuration")
+	vpnJoinCmd.Flags().BoolVar(&vpnJoinAtomic, "atomic", false, "On interrupt, remove the peer from every node it was already added to")
+	vpnJoinCmd.Flags().BoolVar(&vpnJoinResume, "resume-failed", false, "Re-run the last join only on the nodes it failed on")
+	vpnJoinCmd.Flags().BoolVar(&vpnJoinForce, "force", false, "Continue when the peer could not be added to every node instead of rolling it back")
+	vpnJoinCmd.Flags().BoolVar(&vpnJoinNoBroad, "no-broad-route", false, "Route only the VPN subnet and pod/service CIDRs instead of 10.0.0.0/8")
+	vpnJoinCmd.Flags().BoolVar(&vpnJoinPSK, "preshared", false, "Generate a PresharedKey for the peer as an extra symmetric encryption layer")
+	vpnJoinCmd.Flags().BoolVar(&vpnJoinPrint, "print-config", false, "Write the client config to stdout instead of a file; all other output goes to stderr")
 
 	// Leave flags
 	vpnLeaveCmd.Flags().StringVar(&vpnLeaveIP, "vpn-ip", "", "VPN IP of peer to remove")
 
 	// Client config flags
-	vpnClientConfigCmd.Flags().StringVar(&vpnConfigOutput, "output", "", "Output file path")
+	vpnClientConfigCmd.Flags().StringVar(&vpnConfigOutput, "output", "", "Output file path (default: wg0.conf in --output-dir)")
 	vpnClientConfigCmd.Flags().BoolVar(&vpnConfigQR, "qr", false, "Generate QR code for mobile devices")
+
+	// Peers flags
+	vpnPeersCmd.Flags().StringVar(&vpnPeersOutput, "output", "table", "Output format (table, json, yaml)")
+
+	// Test flags
+	vpnTestCmd.Flags().StringVar(&vpnTestFrom, "from", "", "Only test links from this node")
+	vpnTestCmd.Flags().StringVar(&vpnTestTo, "to", "", "Only test links to this node (with --from: both directions between the two)")
+	vpnTestCmd.Flags().StringVar(&vpnTestOutput, "output", "pretty", "Output format (pretty, prometheus, json)")
+	vpnTestCmd.Flags().StringVar(&vpnTestTextfile, "textfile-dir", "", "Write Prometheus metrics to sloth_vpn_<stack>.prom in this node_exporter textfile directory")
+
+	// Stats flags
+	vpnStatsCmd.Flags().IntVar(&vpnStatsInterval, "interval", 5, "Seconds between the two counter samples")
+	vpnStatsCmd.Flags().IntVar(&vpnStatsTop, "top", 10, "Number of top talkers to show")
+	vpnStatsCmd.Flags().StringVar(&vpnStatsCSV, "csv", "", "Write per-link samples as CSV to this file")
+
+	// Rotate-keys flags
+	vpnRotateKeysCmd.Flags().StringVar(&vpnRotateIP, "vpn-ip", "", "VPN IP of the peer to rotate")
+	vpnRotateKeysCmd.Flags().StringVar(&vpnRotateOutput, "output", "", "Output file path (default: wg0-<vpn-ip>.conf in --output-dir)")
+	_ = vpnRotateKeysCmd.MarkFlagRequired("vpn-ip")
 }
 
 func runVPNStatus(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
-	stack := getStackFromArgs(args, 0)
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
 
 	printHeader(fmt.Sprintf("🔐 VPN Status - Stack: %s", stack))
 
@@ -164,28 +357,47 @@ func runVPNStatus(cmd *cobra.Command, args []string) error {
 	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
 	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
 	if err != nil {
-		return fmt.Errorf("failed to select stack '%s': %w", stack, err)
+		return selectStackError(err, stack)
 	}
 
 	// Get outputs
-	outputs, err := s.Outputs(ctx)
+	outputs, err := stackOutputsWithRetry(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := ParseNodeOutputs(outputs)
 	if err != nil {
-		return fmt.Errorf("failed to get stack outputs: %w", err)
+		return fmt.Errorf("failed to parse nodes: %w", err)
 	}
 
 	fmt.Println()
-	printVPNStatusTable(outputs)
+	color.Cyan("ℹ  Reading WireGuard state from cluster nodes...")
+	fmt.Println()
+
+	printVPNStatusTable(readVPNStatus(ctx, nodes, newNodeSSHAccess(stack, outputs)))
 
 	return nil
 }
 
 func runVPNPeers(cmd *cobra.Command, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: sloth-kubernetes vpn peers <stack-name>")
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
 	}
 
-	ctx := context.Background()
-	stack := args[0]
+	// With a machine-readable format stdout carries nothing but the peers
+	var reportOut io.Writer
+	switch vpnPeersOutput {
+	case "table":
+	case "json", "yaml":
+		stdout, restore := stdoutToStderr()
+		defer restore()
+		reportOut = stdout
+	default:
+		return fmt.Errorf("unknown output format %q (use table, json or yaml)", vpnPeersOutput)
+	}
 
 	printHeader(fmt.Sprintf("👥 VPN Peers - Stack: %s", stack))
 
@@ -199,13 +411,13 @@ func runVPNPeers(cmd *cobra.Command, args []string) error {
 	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
 	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
 	if err != nil {
-		return fmt.Errorf("failed to select stack '%s': %w", stack, err)
+		return selectStackError(err, stack)
 	}
 
 	// Get outputs
-	outputs, err := s.Outputs(ctx)
+	outputs, err := stackOutputsWithRetry(ctx, s)
 	if err != nil {
-		return fmt.Errorf("failed to get stack outputs: %w", err)
+		return err
 	}
 
 	// Parse nodes
@@ -215,88 +427,42 @@ func runVPNPeers(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get SSH key and bastion info
-	sshKeyPath := GetSSHKeyPath(stack)
-	bastionEnabled := false
-	bastionIP := ""
-
-	if bastionEnabledOutput, ok := outputs["bastion_enabled"]; ok {
-		if bastionEnabledOutput.Value != nil {
-			bastionEnabled = bastionEnabledOutput.Value == true
-		}
-	}
-
-	if bastionEnabled {
-		if bastionOutput, ok := outputs["bastion"]; ok {
-			if bastionMap, ok := bastionOutput.Value.(map[string]interface{}); ok {
-				if pubIP, ok := bastionMap["public_ip"].(string); ok {
-					bastionIP = pubIP
-				}
-			}
-		}
+	access := newNodeSSHAccess(stack, outputs)
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
 	}
 
 	fmt.Println()
 	color.Cyan("ℹ  Fetching peer information from cluster nodes...")
 	fmt.Println()
 
-	// Collect peer information from all nodes
-	type PeerInfo struct {
-		NodeName      string
-		VPNIp         string
-		PublicKey     string
-		Label         string
-		Endpoint      string
-		LastHandshake string
-		Transfer      string
-	}
-
-	var allPeers []PeerInfo
-
-	// For each node, SSH and get WireGuard peer information
+	// All nodes should have the same peers: ask them in name order and use
+	// the first that answers
+	nodes = sortNodesByName(nodes)
+	var allPeers []vpnPeer
 	for _, node := range nodes {
-		// Determine target IP for SSH
-		targetIP := node.PublicIP
-		if bastionEnabled && bastionIP != "" {
-			// When using bastion, connect to private IP
-			if node.PrivateIP != "" {
-				targetIP = node.PrivateIP
-			}
+		// Get WireGuard config and peers from this node: the config holds the
+		// peer labels in comments, fetched alongside the peers
+		fetchCmds := []string{
+			"cat /etc/wireguard/wg0.conf",
+			"wg show wg0 dump | tail -n +2", // Skip header line
 		}
+		outputs := make([][]byte, len(fetchCmds))
+		failures := make([]error, len(fetchCmds))
 
-		// Get WireGuard config and peers from this node
-		// First get the config to extract labels from comments
-		fetchConfigCmd := "cat /etc/wireguard/wg0.conf"
-		fetchPeersCmd := "wg show wg0 dump | tail -n +2" // Skip header line
+		runParallel(len(fetchCmds), len(fetchCmds), func(i int) {
+			outputs[i], failures[i] = access.run(node, 5, fetchCmds[i])
+		})
 
-		// Fetch config to extract peer labels
-		var configCmd *exec.Cmd
-		if bastionEnabled && bastionIP != "" {
-			configCmd = exec.Command("ssh",
-				"-q",
-				"-i", sshKeyPath,
-				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
-				"-o", "ConnectTimeout=5",
-				"-o", fmt.Sprintf("ProxyCommand=ssh -q -i %s -o StrictHostKeyChecking=accept-new -o UserKnownHostsFile=/dev/null -W %%h:%%p root@%s", sshKeyPath, bastionIP),
-				fmt.Sprintf("root@%s", targetIP),
-				fetchConfigCmd,
-			)
-		} else {
-			configCmd = exec.Command("ssh",
-				"-q",
-				"-i", sshKeyPath,
-				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
-				"-o", "ConnectTimeout=5",
-				fmt.Sprintf("root@%s", targetIP),
-				fetchConfigCmd,
-			)
+		if failures[1] != nil {
+			color.Yellow(fmt.Sprintf("⚠  Failed to get peers from %s: %v", node.Name, failures[1]))
+			continue
 		}
 
 		// Parse labels from config
 		peerLabels := make(map[string]string) // map[publicKey]label
-		if configOutput, err := configCmd.CombinedOutput(); err == nil {
-			configLines := strings.Split(string(configOutput), "\n")
+		if failures[0] == nil {
+			configLines := strings.Split(string(outputs[0]), "\n")
 			var currentLabel string
 			var currentPublicKey string
 
@@ -322,86 +488,10 @@ func runVPNPeers(cmd *cobra.Command, args []string) error {
 			}
 		}
 
-		// Fetch peer information
-		var sshCmd *exec.Cmd
-		if bastionEnabled && bastionIP != "" {
-			sshCmd = exec.Command("ssh",
-				"-q",
-				"-i", sshKeyPath,
-				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
-				"-o", "ConnectTimeout=5",
-				"-o", fmt.Sprintf("ProxyCommand=ssh -q -i %s -o StrictHostKeyChecking=accept-new -o UserKnownHostsFile=/dev/null -W %%h:%%p root@%s", sshKeyPath, bastionIP),
-				fmt.Sprintf("root@%s", targetIP),
-				fetchPeersCmd,
-			)
-		} else {
-			sshCmd = exec.Command("ssh",
-				"-q",
-				"-i", sshKeyPath,
-				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
-				"-o", "ConnectTimeout=5",
-				fmt.Sprintf("root@%s", targetIP),
-				fetchPeersCmd,
-			)
-		}
-
-		output, err := sshCmd.CombinedOutput()
-		if err != nil {
-			color.Yellow(fmt.Sprintf("⚠  Failed to get peers from %s: %v", node.Name, err))
-			continue
-		}
-
 		// Parse wg dump output
-		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-		for _, line := range lines {
-			if line == "" {
-				continue
-			}
-
-			fields := strings.Fields(line)
-			if len(fields) < 8 {
-				continue
-			}
-
-			publicKey := fields[0]
-			endpoint := fields[2]
-			allowedIPs := fields[3]
-			lastHandshake := fields[4]
-			rxBytes := fields[5]
-			txBytes := fields[6]
-
-			// Extract VPN IP from allowed IPs (format: 10.8.0.X/32)
-			vpnIP := strings.TrimSuffix(allowedIPs, "/32")
-
-			// Format handshake time
-			handshakeStr := "Never"
-			if lastHandshake != "0" {
-				handshakeTime, err := strconv.ParseInt(lastHandshake, 10, 64)
-				if err == nil {
-					elapsed := time.Now().Unix() - handshakeTime
-					if elapsed < 60 {
-						handshakeStr = fmt.Sprintf("%ds ago", elapsed)
-					} else if elapsed < 3600 {
-						handshakeStr = fmt.Sprintf("%dm ago", elapsed/60)
-					} else if elapsed < 86400 {
-						handshakeStr = fmt.Sprintf("%dh ago", elapsed/3600)
-					} else {
-						handshakeStr = fmt.Sprintf("%dd ago", elapsed/86400)
-					}
-				}
-			}
-
-			// Format transfer
-			rx, _ := strconv.ParseInt(rxBytes, 10, 64)
-			tx, _ := strconv.ParseInt(txBytes, 10, 64)
-			transferStr := fmt.Sprintf("↑ %s / ↓ %s", formatBytes(tx), formatBytes(rx))
-
-			// Format endpoint
-			if endpoint == "(none)" {
-				endpoint = "N/A"
-			}
+		now := time.Now().Unix()
+		for _, peer := range parseWGDump(string(outputs[1])) {
+			vpnIP := peer.VPNIP()
 
 			// Find peer node name by VPN IP
 			peerNodeName := ""
@@ -416,64 +506,30 @@ func runVPNPeers(cmd *cobra.Command, args []string) error {
 
 			// Only add peers that belong to cluster nodes (skip external/unknown peers)
 			if peerNodeName != "" {
-				// Get label from map
-				label := peerLabels[publicKey]
-
-				allPeers = append(allPeers, PeerInfo{
-					NodeName:      peerNodeName,
-					VPNIp:         vpnIP,
-					PublicKey:     publicKey[:16] + "...", // Truncate for display
-					Label:         label,
-					Endpoint:      endpoint,
-					LastHandshake: handshakeStr,
-					Transfer:      transferStr,
-				})
+				allPeers = append(allPeers, newVPNPeer(peerNodeName, peerLabels[peer.PublicKey], peer, now))
 			}
 		}
-
-		// Only need to get from one node since all should have the same peers
-		if len(allPeers) > 0 {
-			break
-		}
+		break
 	}
 
 	// Remove duplicates and display
 	seen := make(map[string]bool)
-	uniquePeers := []PeerInfo{}
+	uniquePeers := []vpnPeer{}
 	for _, peer := range allPeers {
-		if !seen[peer.VPNIp] {
-			seen[peer.VPNIp] = true
+		if !seen[peer.VPNIP] {
+			seen[peer.VPNIP] = true
 			uniquePeers = append(uniquePeers, peer)
 		}
 	}
 
-	// Display table
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	defer w.Flush()
-
-	color.New(color.Bold).Fprintln(w, "NODE\tLABEL\tVPN IP\tPUBLIC KEY\tENDPOINT\tLAST HANDSHAKE\tTRANSFER")
-	fmt.Fprintln(w, "----\t-----\t------\t----------\t--------\t--------------\t--------")
+	sort.SliceStable(uniquePeers, func(i, j int) bool { return uniquePeers[i].Node < uniquePeers[j].Node })
 
-	if len(uniquePeers) == 0 {
-		fmt.Fprintln(w, "No peers found")
-	} else {
-		for _, peer := range uniquePeers {
-			label := peer.Label
-			if label == "" {
-				label = "-"
-			}
-			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
-				peer.NodeName,
-				label,
-				peer.VPNIp,
-				peer.PublicKey,
-				peer.Endpoint,
-				peer.LastHandshake,
-				peer.Transfer,
-			)
-		}
+	if reportOut != nil {
+		return writeVPNPeers(reportOut, vpnPeersOutput, uniquePeers)
 	}
 
+	printVPNPeerList(uniquePeers)
+
 	fmt.Println()
 	color.Green(fmt.Sprintf("✓ Found %d peers in VPN mesh", len(uniquePeers)))
 
@@ -481,13 +537,16 @@ func runVPNPeers(cmd *cobra.Command, args []string) error {
 }
 
 func runVPNConfig(cmd *cobra.Command, args []string) error {
-	if len(args) < 2 {
-		return fmt.Errorf("usage: sloth-kubernetes vpn config <stack-name> <node-name>")
+	stack, rest, err := splitStackArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	if len(rest) < 1 {
+		return fmt.Errorf("usage: sloth-kubernetes vpn config [stack-name] <node-name>")
 	}
 
 	ctx := context.Background()
-	stack := args[0]
-	nodeName := args[1]
+	nodeName := rest[0]
 
 	printHeader(fmt.Sprintf("📋 VPN Config - Node: %s", nodeName))
 
@@ -501,12 +560,12 @@ func runVPNConfig(cmd *cobra.Command, args []string) error {
 	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
 	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
 	if err != nil {
-		return fmt.Errorf("failed to select stack '%s': %w", stack, err)
+		return selectStackError(err, stack)
 	}
 
-	outputs, err := s.Outputs(ctx)
+	outputs, err := stackOutputsWithRetry(ctx, s)
 	if err != nil {
-		return fmt.Errorf("failed to get stack outputs: %w", err)
+		return err
 	}
 
 	// Parse nodes
@@ -529,62 +588,16 @@ func runVPNConfig(cmd *cobra.Command, args []string) error {
 	}
 
 	// Get SSH key and bastion info
-	sshKeyPath := GetSSHKeyPath(stack)
-	bastionEnabled := false
-	bastionIP := ""
-
-	if bastionEnabledOutput, ok := outputs["bastion_enabled"]; ok {
-		if bastionEnabledOutput.Value != nil {
-			bastionEnabled = bastionEnabledOutput.Value == true
-		}
-	}
-
-	if bastionEnabled {
-		if bastionOutput, ok := outputs["bastion"]; ok {
-			if bastionMap, ok := bastionOutput.Value.(map[string]interface{}); ok {
-				if pubIP, ok := bastionMap["public_ip"].(string); ok {
-					bastionIP = pubIP
-				}
-			}
-		}
+	access := newNodeSSHAccess(stack, outputs)
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
 	}
 
 	fmt.Println()
 	printInfo(fmt.Sprintf("Fetching WireGuard configuration from %s...", targetNode.Name))
 
-	// Determine target IP for SSH
-	targetIP := targetNode.WireGuardIP
-	if targetIP == "" {
-		targetIP = targetNode.PrivateIP
-		if targetIP == "" {
-			targetIP = targetNode.PublicIP
-		}
-	}
-
 	// Fetch the WireGuard config
-	fetchCmd := "cat /etc/wireguard/wg0.conf"
-
-	var sshCmd *exec.Cmd
-	if bastionEnabled && bastionIP != "" {
-		sshCmd = exec.Command("ssh",
-			"-i", sshKeyPath,
-			"-o", "StrictHostKeyChecking=accept-new",
-			"-o", "UserKnownHostsFile=/dev/null",
-			"-o", fmt.Sprintf("ProxyCommand=ssh -i %s -o StrictHostKeyChecking=accept-new -o UserKnownHostsFile=/dev/null -W %%h:%%p root@%s", sshKeyPath, bastionIP),
-			fmt.Sprintf("root@%s", targetIP),
-			fetchCmd,
-		)
-	} else {
-		sshCmd = exec.Command("ssh",
-			"-i", sshKeyPath,
-			"-o", "StrictHostKeyChecking=accept-new",
-			"-o", "UserKnownHostsFile=/dev/null",
-			fmt.Sprintf("root@%s", targetNode.PublicIP),
-			fetchCmd,
-		)
-	}
-
-	output, err := sshCmd.CombinedOutput()
+	output, err := access.run(*targetNode, 10, "cat /etc/wireguard/wg0.conf")
 	if err != nil {
 		return fmt.Errorf("failed to fetch config from node: %w (output: %s)", err, string(output))
 	}
@@ -605,7 +618,25 @@ func runVPNConfig(cmd *cobra.Command, args []string) error {
 
 func runVPNTest(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
-	stack := getStackFromArgs(args, 0)
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	// With a machine-readable format stdout carries nothing but the report
+	var reportOut io.Writer
+	switch vpnTestOutput {
+	case "pretty":
+	case "prometheus", "json":
+		if vpnTestTextfile != "" {
+			return fmt.Errorf("--textfile-dir cannot be combined with --output %s", vpnTestOutput)
+		}
+		stdout, restore := stdoutToStderr()
+		defer restore()
+		reportOut = stdout
+	default:
+		return fmt.Errorf("unknown output format %q (use pretty, prometheus or json)", vpnTestOutput)
+	}
 
 	printHeader(fmt.Sprintf("🧪 Testing VPN Connectivity - Stack: %s", stack))
 
@@ -619,12 +650,12 @@ func runVPNTest(cmd *cobra.Command, args []string) error {
 	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
 	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
 	if err != nil {
-		return fmt.Errorf("failed to select stack '%s': %w", stack, err)
+		return selectStackError(err, stack)
 	}
 
-	outputs, err := s.Outputs(ctx)
+	outputs, err := stackOutputsWithRetry(ctx, s)
 	if err != nil {
-		return fmt.Errorf("failed to get stack outputs: %w", err)
+		return err
 	}
 
 	// Parse nodes
@@ -637,28 +668,22 @@ func runVPNTest(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no nodes found in stack")
 	}
 
-	fmt.Println()
-	printInfo(fmt.Sprintf("Found %d nodes to test", len(nodes)))
-
-	// Get SSH key and bastion info
-	sshKeyPath := GetSSHKeyPath(stack)
-	bastionEnabled := false
-	bastionIP := ""
+	links, err := vpnTestLinks(nodes, vpnTestFrom, vpnTestTo)
+	if err != nil {
+		return err
+	}
+	testedNodes := vpnLinkNodes(nodes, links)
 
-	if bastionEnabledOutput, ok := outputs["bastion_enabled"]; ok {
-		if bastionEnabledOutput.Value != nil {
-			bastionEnabled = bastionEnabledOutput.Value == true
-		}
+	fmt.Println()
+	printInfo(fmt.Sprintf("Found %d nodes to test", len(testedNodes)))
+	if vpnTestFrom != "" || vpnTestTo != "" {
+		printInfo(fmt.Sprintf("Testing %d selected link(s)", len(links)))
 	}
 
-	if bastionEnabled {
-		if bastionOutput, ok := outputs["bastion"]; ok {
-			if bastionMap, ok := bastionOutput.Value.(map[string]interface{}); ok {
-				if pubIP, ok := bastionMap["public_ip"].(string); ok {
-					bastionIP = pubIP
-				}
-			}
-		}
+	// Get SSH key and bastion info
+	access := newNodeSSHAccess(stack, outputs)
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
 	}
 
 	// Test 1: Ping test between nodes
@@ -666,67 +691,36 @@ func runVPNTest(cmd *cobra.Command, args []string) error {
 	printInfo("Test 1/3: Testing ping connectivity via VPN...")
 	fmt.Println()
 
-	successCount := 0
-	totalTests := 0
-
-	for i, sourceNode := range nodes {
-		if sourceNode.WireGuardIP == "" {
-			continue
+	// The O(n²) pings run concurrently; results print in name order
+	sort.SliceStable(links, func(i, j int) bool {
+		if links[i].Source.Name != links[j].Source.Name {
+			return links[i].Source.Name < links[j].Source.Name
 		}
+		return links[i].Target.Name < links[j].Target.Name
+	})
+	concurrency := access.concurrency(vpnConcurrency)
 
-		for j, targetNode := range nodes {
-			if i == j || targetNode.WireGuardIP == "" {
-				continue
-			}
-
-			totalTests++
-
-			// Build ping command
-			pingCmd := fmt.Sprintf("ping -c 2 -W 2 %s > /dev/null 2>&1 && echo 'SUCCESS' || echo 'FAILED'", targetNode.WireGuardIP)
+	passed := make([]bool, len(links))
+	runParallel(len(links), concurrency, func(i int) {
+		sourceNode, targetNode := links[i].Source, links[i].Target
 
-			// Determine target IP for SSH
-			sourceIP := sourceNode.WireGuardIP
-			if sourceIP == "" {
-				sourceIP = sourceNode.PrivateIP
-				if sourceIP == "" {
-					sourceIP = sourceNode.PublicIP
-				}
-			}
-
-			// Build SSH command
-			var sshCmd *exec.Cmd
-			if bastionEnabled && bastionIP != "" {
-				sshCmd = exec.Command("ssh",
-					"-q",
-					"-i", sshKeyPath,
-					"-o", "StrictHostKeyChecking=accept-new",
-					"-o", "UserKnownHostsFile=/dev/null",
-					"-o", "ConnectTimeout=5",
-					"-o", fmt.Sprintf("ProxyCommand=ssh -q -i %s -o StrictHostKeyChecking=accept-new -o UserKnownHostsFile=/dev/null -W %%h:%%p root@%s", sshKeyPath, bastionIP),
-					fmt.Sprintf("root@%s", sourceIP),
-					pingCmd,
-				)
-			} else {
-				sshCmd = exec.Command("ssh",
-					"-q",
-					"-i", sshKeyPath,
-					"-o", "StrictHostKeyChecking=accept-new",
-					"-o", "UserKnownHostsFile=/dev/null",
-					"-o", "ConnectTimeout=5",
-					fmt.Sprintf("root@%s", sourceNode.PublicIP),
-					pingCmd,
-				)
-			}
+		// Build ping command
+		pingCmd := fmt.Sprintf("ping -c 2 -W 2 %s > /dev/null 2>&1 && echo 'SUCCESS' || echo 'FAILED'", targetNode.WireGuardIP)
 
-			output, err := sshCmd.CombinedOutput()
-			result := strings.TrimSpace(string(output))
+		output, err := access.run(sourceNode, 5, pingCmd)
+		passed[i] = err == nil && strings.TrimSpace(string(output)) == "SUCCESS"
+	})
 
-			if err == nil && result == "SUCCESS" {
-				fmt.Printf("  ✓ %s → %s (%s)\n", sourceNode.Name, targetNode.Name, targetNode.WireGuardIP)
-				successCount++
-			} else {
-				fmt.Printf("  ✗ %s → %s (%s) - Failed\n", sourceNode.Name, targetNode.Name, targetNode.WireGuardIP)
-			}
+	report := vpnTestReport{Stack: stack, PingTotal: len(links), Links: []vpnLinkResult{}, Nodes: []vpnNodeHandshakes{}}
+	successCount := 0
+	totalTests := len(links)
+	for i, link := range links {
+		report.Links = append(report.Links, vpnLinkResult{Source: link.Source.Name, Target: link.Target.Name, VPNIP: link.Target.WireGuardIP, Success: passed[i]})
+		if passed[i] {
+			fmt.Printf("  ✓ %s → %s (%s)\n", link.Source.Name, link.Target.Name, link.Target.WireGuardIP)
+			successCount++
+		} else {
+			fmt.Printf("  ✗ %s → %s (%s) - Failed\n", link.Source.Name, link.Target.Name, link.Target.WireGuardIP)
 		}
 	}
 
@@ -735,51 +729,24 @@ func runVPNTest(cmd *cobra.Command, args []string) error {
 	printInfo("Test 2/3: Checking WireGuard handshake status...")
 	fmt.Println()
 
-	handshakeOK := 0
-	for _, node := range nodes {
-		if node.WireGuardIP == "" {
-			continue
-		}
-
-		// Check handshake on this node
-		targetIP := node.WireGuardIP
-		if targetIP == "" {
-			targetIP = node.PrivateIP
-			if targetIP == "" {
-				targetIP = node.PublicIP
-			}
+	testedNodes = sortNodesByName(testedNodes)
+	handshakes := make([]vpnNodeHandshakes, len(testedNodes))
+	runParallel(len(testedNodes), concurrency, func(i int) {
+		node := testedNodes[i]
+		handshakes[i] = vpnNodeHandshakes{Node: node.Name}
+		output, err := access.run(node, 5, handshakeCommand)
+		if err != nil {
+			return
 		}
-
-		checkCmd := "wg show wg0 latest-handshakes | wc -l"
-
-		var sshCmd *exec.Cmd
-		if bastionEnabled && bastionIP != "" {
-			sshCmd = exec.Command("ssh",
-				"-q",
-				"-i", sshKeyPath,
-				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
-				"-o", "ConnectTimeout=5",
-				"-o", fmt.Sprintf("ProxyCommand=ssh -q -i %s -o StrictHostKeyChecking=accept-new -o UserKnownHostsFile=/dev/null -W %%h:%%p root@%s", sshKeyPath, bastionIP),
-				fmt.Sprintf("root@%s", targetIP),
-				checkCmd,
-			)
-		} else {
-			sshCmd = exec.Command("ssh",
-				"-q",
-				"-i", sshKeyPath,
-				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
-				"-o", "ConnectTimeout=5",
-				fmt.Sprintf("root@%s", node.PublicIP),
-				checkCmd,
-			)
+		if now, latest, err := parseLatestHandshakes(string(output)); err == nil {
+			handshakes[i] = summarizeHandshakes(node.Name, now, latest)
 		}
+	})
 
-		output, err := sshCmd.CombinedOutput()
-		if err == nil {
-			peerCount := strings.TrimSpace(string(output))
-			fmt.Printf("  ✓ %s - %s active peers\n", node.Name, peerCount)
+	handshakeOK := 0
+	for i, node := range testedNodes {
+		if handshakes[i].Responding {
+			fmt.Printf("  ✓ %s - %d/%d peers active\n", node.Name, handshakes[i].ActivePeers, handshakes[i].Peers)
 			handshakeOK++
 		} else {
 			fmt.Printf("  ✗ %s - Could not check handshake status\n", node.Name)
@@ -792,40 +759,233 @@ func runVPNTest(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	defer w.Flush()
 
 	fmt.Fprintln(w, "METRIC\tRESULT")
 	fmt.Fprintln(w, "------\t------")
-	fmt.Fprintf(w, "Total Nodes\t%d\n", len(nodes))
+	fmt.Fprintf(w, "Total Nodes\t%d\n", len(testedNodes))
 	fmt.Fprintf(w, "Ping Tests\t%d/%d passed (%.1f%%)\n", successCount, totalTests, float64(successCount)/float64(totalTests)*100)
-	fmt.Fprintf(w, "Handshake Checks\t%d/%d nodes responding\n", handshakeOK, len(nodes))
+	fmt.Fprintf(w, "Handshake Checks\t%d/%d nodes responding\n", handshakeOK, len(testedNodes))
 
-	if successCount == totalTests && handshakeOK == len(nodes) {
+	if successCount == totalTests && handshakeOK == len(testedNodes) {
 		fmt.Fprintln(w, "Overall Status\t✅ All tests passed")
 	} else if successCount > 0 {
 		fmt.Fprintln(w, "Overall Status\t⚠️  Some tests failed")
 	} else {
 		fmt.Fprintln(w, "Overall Status\t❌ All tests failed")
 	}
+	w.Flush()
+
+	report.PingSuccess = successCount
+	report.Nodes = append(report.Nodes, handshakes...)
+	switch {
+	case vpnTestTextfile != "":
+		path, err := writeVPNTestTextfile(vpnTestTextfile, report)
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		printSuccess(fmt.Sprintf("Metrics written to %s", path))
+	case vpnTestOutput == "prometheus":
+		if err := writeVPNTestPrometheus(reportOut, report); err != nil {
+			return err
+		}
+	case vpnTestOutput == "json":
+		if err := writeVPNTestJSON(reportOut, report); err != nil {
+			return err
+		}
+	}
 
+	if successCount < totalTests {
+		return errs.Mark(fmt.Errorf("%d of %d VPN ping tests failed", totalTests-successCount, totalTests), errs.ErrMeshDegraded)
+	}
 	return nil
 }
 
-func printVPNStatusTable(outputs auto.OutputMap) {
+// vpnLink is a directed link pinged by vpn test
+type vpnLink struct {
+	Source NodeInfo
+	Target NodeInfo
+}
+
+// vpnTestLinks returns the links between VPN nodes that vpn test pings: the
+// full mesh, only the links leaving from, only those reaching to, or with
+// both set the two directions between from and to
+func vpnTestLinks(nodes []NodeInfo, from, to string) ([]vpnLink, error) {
+	var vpnNodes []NodeInfo
+	known := make(map[string]bool)
+	for _, node := range nodes {
+		if node.WireGuardIP != "" {
+			vpnNodes = append(vpnNodes, node)
+			known[node.Name] = true
+		}
+	}
+
+	for flag, name := range map[string]string{"--from": from, "--to": to} {
+		if name != "" && !known[name] {
+			return nil, fmt.Errorf("%s: no VPN node named %q in stack", flag, name)
+		}
+	}
+	if from != "" && from == to {
+		return nil, fmt.Errorf("--from and --to name the same node %q", from)
+	}
+
+	var links []vpnLink
+	for _, source := range vpnNodes {
+		for _, target := range vpnNodes {
+			if source.Name == target.Name {
+				continue
+			}
+
+			keep := true
+			switch {
+			case from != "" && to != "":
+				keep = (source.Name == from && target.Name == to) || (source.Name == to && target.Name == from)
+			case from != "":
+				keep = source.Name == from
+			case to != "":
+				keep = target.Name == to
+			}
+			if keep {
+				links = append(links, vpnLink{Source: source, Target: target})
+			}
+		}
+	}
+	return links, nil
+}
+
+// vpnLinkNodes returns the nodes taking part in links, in node order
+func vpnLinkNodes(nodes []NodeInfo, links []vpnLink) []NodeInfo {
+	involved := make(map[string]bool)
+	for _, link := range links {
+		involved[link.Source.Name] = true
+		involved[link.Target.Name] = true
+	}
+
+	var result []NodeInfo
+	for _, node := range nodes {
+		if involved[node.Name] {
+			result = append(result, node)
+		}
+	}
+	return result
+}
+
+// vpnActiveHandshakeWindow is how recent a peer's last handshake must be
+// for its tunnel to count as active. WireGuard re-handshakes every two
+// minutes while a tunnel carries traffic or keepalives.
+const vpnActiveHandshakeWindow = 3 * time.Minute
+
+// wgStatusScript prints the WireGuard dump and the interface address
+const wgStatusScript = `sudo wg show wg0 dump && echo "--- address ---" && ip -o -4 addr show dev wg0`
+
+// vpnStatus is the state of the VPN mesh as seen from one node
+type vpnStatus struct {
+	Node        string // Node the state was read from, empty when none was reachable
+	TotalNodes  int
+	Peers       int
+	ActivePeers int
+	Subnet      string
+	RxBytes     int64
+	TxBytes     int64
+	Unreachable []string
+}
+
+// readVPNStatus reads the WireGuard state from the first reachable node
+func readVPNStatus(ctx context.Context, nodes []NodeInfo, access nodeSSHAccess) vpnStatus {
+	status := vpnStatus{TotalNodes: len(nodes)}
+
+	if err := checkBastionReachable(ctx, access); err != nil {
+		color.Yellow(fmt.Sprintf("⚠  %v", err))
+		for _, node := range nodes {
+			status.Unreachable = append(status.Unreachable, node.Name)
+		}
+		return status
+	}
+
+	for _, node := range nodes {
+		output, err := access.runScript(node, 5, wgStatusScript)
+		if err != nil {
+			color.Yellow(fmt.Sprintf("⚠  Failed to read WireGuard state from %s: %v", node.Name, err))
+			status.Unreachable = append(status.Unreachable, node.Name)
+			continue
+		}
+		parsed := parseVPNStatus(string(output), time.Now())
+		parsed.Node = node.Name
+		parsed.TotalNodes = status.TotalNodes
+		parsed.Unreachable = status.Unreachable
+		return parsed
+	}
+
+	return status
+}
+
+// parseVPNStatus computes the mesh state from the output of wgStatusScript
+func parseVPNStatus(output string, now time.Time) vpnStatus {
+	var status vpnStatus
+	dump, address, _ := strings.Cut(output, "--- address ---")
+
+	for _, peer := range parseWGDump(dump) {
+		status.Peers++
+		status.RxBytes += peer.RxBytes
+		status.TxBytes += peer.TxBytes
+		if peer.LatestHandshake != 0 && now.Sub(time.Unix(peer.LatestHandshake, 0)) <= vpnActiveHandshakeWindow {
+			status.ActivePeers++
+		}
+	}
+
+	fields := strings.Fields(address)
+	for i := 0; i+1 < len(fields); i++ {
+		if fields[i] != "inet" {
+			continue
+		}
+		if _, subnet, err := net.ParseCIDR(fields[i+1]); err == nil {
+			status.Subnet = subnet.String()
+		}
+		break
+	}
+
+	return status
+}
+
+// summary describes the health of the mesh in one line
+func (s vpnStatus) summary() string {
+	switch {
+	case s.Node == "":
+		return "❌ Degraded: no node reachable"
+	case s.Peers == 0:
+		return "⚠️  No tunnels configured"
+	case s.ActivePeers == s.Peers:
+		return "✅ All tunnels active"
+	default:
+		return fmt.Sprintf("⚠️  Degraded: %d of %d tunnels without a handshake in %s", s.Peers-s.ActivePeers, s.Peers, vpnActiveHandshakeWindow)
+	}
+}
+
+func printVPNStatusTable(status vpnStatus) {
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
-	defer w.Flush()
 
 	color.New(color.Bold).Fprintln(w, "METRIC\tVALUE")
 	fmt.Fprintln(w, "------\t-----")
 
-	// TODO: Parse actual VPN data from outputs
 	fmt.Fprintln(w, "VPN Mode\tWireGuard Mesh")
-	fmt.Fprintln(w, "Total Nodes\t6")
-	fmt.Fprintln(w, "Total Tunnels\t15")
-	fmt.Fprintln(w, "VPN Subnet\t10.8.0.0/24")
-	fmt.Fprintln(w, "Status\t✅ All tunnels active")
+	fmt.Fprintf(w, "Total Nodes\t%d\n", status.TotalNodes)
+	if status.Node != "" {
+		subnet := status.Subnet
+		if subnet == "" {
+			subnet = "unknown"
+		}
+		fmt.Fprintf(w, "Read From\t%s\n", status.Node)
+		fmt.Fprintf(w, "Total Tunnels\t%d\n", status.Peers)
+		fmt.Fprintf(w, "Active Tunnels\t%d\n", status.ActivePeers)
+		fmt.Fprintf(w, "VPN Subnet\t%s\n", subnet)
+		fmt.Fprintf(w, "Transfer\t↑ %s / ↓ %s\n", formatBytes(status.TxBytes), formatBytes(status.RxBytes))
+	}
+	fmt.Fprintf(w, "Status\t%s\n", status.summary())
+	w.Flush()
 
-	color.Yellow("\n⚠️  Real-time VPN metrics will be available after implementing monitoring")
+	if len(status.Unreachable) > 0 {
+		color.Yellow(fmt.Sprintf("\n⚠️  Unreachable: %s", strings.Join(status.Unreachable, ", ")))
+	}
 }
 
 func printVPNPeersTable(outputs auto.OutputMap) {
@@ -843,12 +1003,22 @@ func printVPNPeersTable(outputs auto.OutputMap) {
 }
 
 func runVPNJoin(cmd *cobra.Command, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: sloth-kubernetes vpn join <stack-name>")
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
 	}
 
-	ctx := context.Background()
-	stack := args[0]
+	// With --print-config stdout carries nothing but the client config
+	var configOut io.Writer
+	if vpnJoinPrint {
+		if vpnJoinInstall {
+			return fmt.Errorf("--print-config cannot be combined with --install")
+		}
+		stdout, restore := stdoutToStderr()
+		defer restore()
+		configOut = stdout
+	}
 
 	printHeader(fmt.Sprintf("🔗 Joining VPN - Stack: %s", stack))
 
@@ -862,12 +1032,12 @@ func runVPNJoin(cmd *cobra.Command, args []string) error {
 	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
 	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
 	if err != nil {
-		return fmt.Errorf("failed to select stack '%s': %w", stack, err)
+		return selectStackError(err, stack)
 	}
 
-	outputs, err := s.Outputs(ctx)
+	outputs, err := stackOutputsWithRetry(ctx, s)
 	if err != nil {
-		return fmt.Errorf("failed to get stack outputs: %w", err)
+		return err
 	}
 
 	// Parse nodes to get cluster info
@@ -883,245 +1053,119 @@ func runVPNJoin(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 	printInfo(fmt.Sprintf("Found %d cluster nodes", len(nodes)))
 
-	// Determine target (local or remote)
-	target := "local machine"
-	if vpnJoinRemote != "" {
-		target = vpnJoinRemote
-	}
-
-	printInfo(fmt.Sprintf("Target: %s", target))
-
-	// Get SSH key and bastion info early (needed for peer discovery)
-	sshKeyPath := GetSSHKeyPath(stack)
-	bastionEnabled := false
-	bastionIP := ""
-
-	if bastionEnabledOutput, ok := outputs["bastion_enabled"]; ok {
-		if bastionEnabledOutput.Value != nil {
-			bastionEnabled = bastionEnabledOutput.Value == true
+	// With --resume-failed only the nodes the last join failed on are changed,
+	// reusing that join's peer so it matches the nodes that succeeded
+	joinNodes := nodes
+	joinStatus := &operationStatus{Operation: vpnJoinOperation, Stack: stack}
+	if vpnJoinResume {
+		joinStatus, err = loadOperationStatus(stack, vpnJoinOperation)
+		if err != nil {
+			return err
 		}
-	}
-
-	if bastionEnabled {
-		if bastionOutput, ok := outputs["bastion"]; ok {
-			if bastionMap, ok := bastionOutput.Value.(map[string]interface{}); ok {
-				if pubIP, ok := bastionMap["public_ip"].(string); ok {
-					bastionIP = pubIP
-				}
-			}
+		if joinStatus == nil || joinStatus.Peer == nil || len(joinStatus.Failed) == 0 {
+			return fmt.Errorf("no failed vpn join recorded for stack '%s'", stack)
 		}
-	}
 
-	// STEP 0.5: Discover existing VPN clients early (needed for IP auto-assignment)
-	var existingPeersForIPAssign []VPNPeerInfo
-
-	// Get first master node to query peers  (all masters are peers)
-	var firstMaster NodeInfo
-	for _, node := range nodes {
-		// Just get the first node - all have the same peer list
-		firstMaster = node
-		break
-	}
-
-	if firstMaster.Name != "" {
-		targetIP := firstMaster.WireGuardIP
-		if targetIP == "" {
-			targetIP = firstMaster.PrivateIP
-			if targetIP == "" {
-				targetIP = firstMaster.PublicIP
+		var missing []string
+		joinNodes, missing = selectResumeNodes(nodes, joinStatus)
+		for _, name := range missing {
+			printWarning(fmt.Sprintf("Node %s is no longer in the stack, skipping", name))
+			joinStatus.Failed = removeName(joinStatus.Failed, name)
+		}
+		if len(joinNodes) == 0 {
+			if err := removeOperationStatus(stack, vpnJoinOperation); err != nil {
+				return err
 			}
+			return fmt.Errorf("none of the failed nodes are in the stack anymore")
 		}
 
-		listPeersScript := `wg show wg0 dump | tail -n +2 | while IFS=$'\t' read -r pubkey _ endpoint allowed_ips _; do
-			first_ip=$(echo "$allowed_ips" | cut -d, -f1 | cut -d/ -f1)
-			if [ -n "$first_ip" ] && [ "$first_ip" != "(none)" ]; then
-				echo "$pubkey|$first_ip"
-			fi
-		done`
+		vpnJoinIP = joinStatus.Peer.IP
+		vpnJoinLabel = joinStatus.Peer.Label
+		printInfo(fmt.Sprintf("Resuming join of %s on %d failed node(s)", vpnJoinIP, len(joinNodes)))
+	}
 
-		var listCmd *exec.Cmd
-		if bastionEnabled && bastionIP != "" {
-			listCmd = exec.Command("ssh",
-				"-i", sshKeyPath,
-				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
-				"-o", fmt.Sprintf("ProxyCommand=ssh -i %s -o StrictHostKeyChecking=accept-new -o UserKnownHostsFile=/dev/null -W %%h:%%p root@%s", sshKeyPath, bastionIP),
-				fmt.Sprintf("root@%s", targetIP),
-				listPeersScript,
-			)
-		} else {
-			listCmd = exec.Command("ssh",
-				"-i", sshKeyPath,
-				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
-				fmt.Sprintf("root@%s", targetIP),
-				listPeersScript,
-			)
-		}
+	// Determine target (local or remote)
+	target := "local machine"
+	if vpnJoinRemote != "" {
+		target = vpnJoinRemote
+	}
 
-		output, err := listCmd.CombinedOutput()
-		if err == nil {
-			lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-			for _, line := range lines {
-				parts := strings.Split(line, "|")
-				if len(parts) == 2 {
-					peerIP := strings.TrimSpace(parts[1])
-					peerKey := strings.TrimSpace(parts[0])
-
-					if peerIP == "" || peerIP == "(none)" {
-						continue
-					}
+	printInfo(fmt.Sprintf("Target: %s", target))
 
-					// Filter out cluster node IPs (10.8.0.10-99)
-					if strings.HasPrefix(peerIP, "10.8.0.") {
-						ipParts := strings.Split(peerIP, ".")
-						if len(ipParts) == 4 {
-							var lastOctet int
-							if _, err := fmt.Sscanf(ipParts[3], "%d", &lastOctet); err == nil {
-								if lastOctet >= 10 && lastOctet < 100 {
-									continue
-								}
-							}
-						}
-					}
+	// Get SSH key and bastion info early (needed for peer discovery)
+	access := newNodeSSHAccess(stack, outputs)
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
+	}
+	sshKeyPath := access.KeyPath
 
-					existingPeersForIPAssign = append(existingPeersForIPAssign, VPNPeerInfo{
-						PublicKey:  peerKey,
-						VPNAddress: peerIP,
-					})
-				}
-			}
-		}
+	// Pick a reachable node for discovery so a single dead node doesn't block the join
+	discoveryNode, err := findReachableNode(nodes, access)
+	if err != nil {
+		return err
 	}
+	printInfo(fmt.Sprintf("Using %s for peer discovery", discoveryNode.Name))
+
+	// STEP 0.5: Discover existing VPN clients early (needed for IP auto-assignment)
+	subnet := vpnSubnet(outputs)
+	existingPeersForIPAssign := discoverVPNClients(discoveryNode, access, subnet)
 
 	// Auto-assign VPN IP if not specified
 	if vpnJoinIP == "" {
-		// Find next available IP in 10.8.0.x range (100-254)
-		// Check existing peers to avoid collisions
-		usedIPs := make(map[string]bool)
-		for _, peer := range existingPeersForIPAssign {
-			usedIPs[peer.VPNAddress] = true
-		}
-
-		// Start from 100 and find first available
-		for i := 100; i < 255; i++ {
-			candidateIP := fmt.Sprintf("10.8.0.%d", i)
-			if !usedIPs[candidateIP] {
-				vpnJoinIP = candidateIP
-				break
-			}
-		}
-
-		if vpnJoinIP == "" {
-			return fmt.Errorf("no available VPN IPs in range 10.8.0.100-254")
+		vpnJoinIP, err = nextClientIP(existingPeersForIPAssign, subnet)
+		if err != nil {
+			return err
 		}
 
 		printInfo(fmt.Sprintf("Auto-assigned VPN IP: %s", vpnJoinIP))
 	} else {
+		if err := checkClientVPNIP(vpnJoinIP, subnet); err != nil {
+			return err
+		}
 		printInfo(fmt.Sprintf("Using custom VPN IP: %s", vpnJoinIP))
 	}
 
 	// STEP 1: Generate WireGuard keypair
 	fmt.Println()
 	printInfo("Step 1/4: Generating WireGuard keypair...")
-	privateKey, publicKey, err := generateWireGuardKeypair()
-	if err != nil {
-		return fmt.Errorf("failed to generate keypair: %w", err)
-	}
-	printSuccess(fmt.Sprintf("Generated keypair (public key: %s...)", publicKey[:16]))
-	printInfo(fmt.Sprintf("Using SSH key: %s", sshKeyPath))
-
-	// Get bastion info if enabled
-	if bastionEnabled {
-		if bastionOutput, ok := outputs["bastion"]; ok {
-			if bastionMap, ok := bastionOutput.Value.(map[string]interface{}); ok {
-				if pubIP, ok := bastionMap["public_ip"].(string); ok {
-					bastionIP = pubIP
-				}
+	var privateKey, publicKey, presharedKey string
+	if vpnJoinResume {
+		privateKey = joinStatus.Peer.PrivateKey
+		presharedKey = joinStatus.Peer.PresharedKey
+		publicKey, err = wireGuardPublicKey(privateKey)
+		if err != nil {
+			return fmt.Errorf("invalid private key in saved join status: %w", err)
+		}
+		printSuccess(fmt.Sprintf("Reusing keypair of the failed join (public key: %s...)", publicKey[:16]))
+	} else {
+		privateKey, publicKey, err = generateWireGuardKeypair()
+		if err != nil {
+			return fmt.Errorf("failed to generate keypair: %w", err)
+		}
+		if vpnJoinPSK {
+			presharedKey, err = generatePresharedKey()
+			if err != nil {
+				return fmt.Errorf("failed to generate preshared key: %w", err)
 			}
 		}
+		joinStatus.Peer = &vpnJoinPeer{IP: vpnJoinIP, Label: vpnJoinLabel, PrivateKey: privateKey, PresharedKey: presharedKey}
+		printSuccess(fmt.Sprintf("Generated keypair (public key: %s...)", publicKey[:16]))
+	}
+	if presharedKey != "" {
+		// Only a prefix is shown; the full key is in the client config
+		printSuccess(fmt.Sprintf("Using preshared key %s...", presharedKey[:4]))
 	}
+	printInfo(fmt.Sprintf("Using SSH key: %s", sshKeyPath))
 
 	// STEP 3: Get list of existing VPN peers (external clients)
 	fmt.Println()
 	printInfo("Step 2/5: Discovering existing VPN clients...")
 
+	// Skip the peer being joined when resuming
 	var existingPeers []VPNPeerInfo
-	if len(nodes) > 0 {
-		// Get list of all peers from first master node
-		firstMaster := nodes[0]
-		targetIP := firstMaster.WireGuardIP
-		if targetIP == "" {
-			targetIP = firstMaster.PrivateIP
-			if targetIP == "" {
-				targetIP = firstMaster.PublicIP
-			}
-		}
-
-		listPeersScript := `wg show wg0 dump | tail -n +2 | while IFS=$'\t' read -r pubkey _ endpoint allowed_ips _; do
-			# Extract first IP from allowed-ips (format: 10.8.0.x/32,10.0.0.0/8)
-			first_ip=$(echo "$allowed_ips" | cut -d, -f1 | cut -d/ -f1)
-			if [ -n "$first_ip" ] && [ "$first_ip" != "(none)" ]; then
-				echo "$pubkey|$first_ip"
-			fi
-		done`
-
-		var listCmd *exec.Cmd
-		if bastionEnabled && bastionIP != "" {
-			listCmd = exec.Command("ssh",
-				"-i", sshKeyPath,
-				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
-				"-o", fmt.Sprintf("ProxyCommand=ssh -i %s -o StrictHostKeyChecking=accept-new -o UserKnownHostsFile=/dev/null -W %%h:%%p root@%s", sshKeyPath, bastionIP),
-				fmt.Sprintf("root@%s", targetIP),
-				listPeersScript,
-			)
-		} else {
-			listCmd = exec.Command("ssh",
-				"-i", sshKeyPath,
-				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
-				fmt.Sprintf("root@%s", targetIP),
-				listPeersScript,
-			)
-		}
-
-		output, err := listCmd.CombinedOutput()
-		if err == nil {
-			lines := strings.Split(strings.TrimSpace(string(output)), "\n")
-			for _, line := range lines {
-				if line == "" {
-					continue
-				}
-				parts := strings.Split(line, "|")
-				if len(parts) == 2 {
-					peerIP := strings.TrimSpace(parts[1])
-					peerKey := strings.TrimSpace(parts[0])
-
-					// Skip if no IP
-					if peerIP == "" || peerIP == "(none)" {
-						continue
-					}
-
-					// Filter out cluster node IPs (10.8.0.10-99 are reserved for cluster)
-					if strings.HasPrefix(peerIP, "10.8.0.") {
-						ipParts := strings.Split(peerIP, ".")
-						if len(ipParts) == 4 {
-							var lastOctet int
-							if _, err := fmt.Sscanf(ipParts[3], "%d", &lastOctet); err == nil {
-								if lastOctet >= 10 && lastOctet < 100 {
-									continue // Skip cluster nodes (10-99)
-								}
-							}
-						}
-					}
-
-					existingPeers = append(existingPeers, VPNPeerInfo{
-						PublicKey:  peerKey,
-						VPNAddress: peerIP,
-					})
-				}
-			}
+	for _, peer := range discoverVPNClients(discoveryNode, access, subnet) {
+		if peer.PublicKey != publicKey {
+			existingPeers = append(existingPeers, peer)
 		}
 	}
 
@@ -1135,147 +1179,156 @@ func runVPNJoin(cmd *cobra.Command, args []string) error {
 	fmt.Println()
 	printInfo("Step 3/5: Adding peer to all cluster nodes...")
 
-	for i, node := range nodes {
-		nodeTarget := node.PublicIP
-		peerAddScript := generatePeerAddScript(vpnJoinIP, publicKey, vpnJoinLabel)
+	// From here on nodes are changed: Ctrl-C stops before the next node
+	tracker := newNodeChangeTracker()
+	defer tracker.Stop()
 
-		// Try up to 3 times to handle transient SSH connection issues
-		maxRetries := 3
-		var output []byte
-		var err error
-		var attemptSucceeded bool
+	// Nodes count as failed until the peer is confirmed added
+	for _, node := range joinNodes {
+		joinStatus.markFailed(node.Name)
+	}
 
-		for attempt := 1; attempt <= maxRetries; attempt++ {
-			// Build SSH command based on bastion mode
-			var sshCmd *exec.Cmd
-			if bastionEnabled && bastionIP != "" {
-				// Use ProxyJump through bastion
-				if attempt == 1 {
-					printInfo(fmt.Sprintf("  [%d/%d] Adding peer to %s (via bastion)...", i+1, len(nodes), node.Name))
-				}
-				// Use WireGuard VPN IP for bastion ProxyJump (all nodes in VPN mesh)
-				targetIP := node.WireGuardIP
-				if targetIP == "" {
-					// Fallback to PrivateIP, then PublicIP
-					targetIP = node.PrivateIP
-					if targetIP == "" {
-						targetIP = node.PublicIP
-					}
-				}
+	// Nodes are changed concurrently; results print in name order
+	joinNodes = sortNodesByName(joinNodes)
+	concurrency := access.concurrency(vpnConcurrency)
+	via := ""
+	if access.viaBastion() {
+		via = " via bastion"
+	}
+	printInfo(fmt.Sprintf("  Adding peer to %d node(s)%s, %d at a time...", len(joinNodes), via, concurrency))
 
-				sshUser := getSSHUserForNode(node.Provider)
-				sshCmd = exec.Command("ssh",
-					"-i", sshKeyPath,
-					"-o", "StrictHostKeyChecking=accept-new",
-					"-o", "UserKnownHostsFile=/dev/null",
-					"-o", "ConnectTimeout=10",
-					"-o", fmt.Sprintf("ProxyCommand=ssh -i %s -o StrictHostKeyChecking=accept-new -o UserKnownHostsFile=/dev/null -W %%h:%%p root@%s", sshKeyPath, bastionIP),
-					fmt.Sprintf("%s@%s", sshUser, targetIP),
-					"bash", "-s",
-				)
-				// Pipe the script via stdin
-				sshCmd.Stdin = strings.NewReader(peerAddScript)
-			} else {
-				// Direct SSH
-				if attempt == 1 {
-					printInfo(fmt.Sprintf("  [%d/%d] Adding peer to %s...", i+1, len(nodes), node.Name))
-				}
-				sshUser := getSSHUserForNode(node.Provider)
-				sshCmd = exec.Command("ssh",
-					"-i", sshKeyPath,
-					"-o", "StrictHostKeyChecking=accept-new",
-					"-o", "UserKnownHostsFile=/dev/null",
-					"-o", "ConnectTimeout=10",
-					fmt.Sprintf("%s@%s", sshUser, nodeTarget),
-					"bash", "-s",
-				)
-				// Pipe the script via stdin
-				sshCmd.Stdin = strings.NewReader(peerAddScript)
-			}
+	peerAddScript := generatePeerAddScript(vpnJoinIP, subnet, publicKey, vpnJoinLabel, presharedKey)
+	added := make([]bool, len(joinNodes))
+	failures := make([]string, len(joinNodes))
+
+	// Try up to 3 times to handle transient SSH connection issues
+	maxRetries := 3
+
+	runParallel(len(joinNodes), concurrency, func(i int) {
+		node := joinNodes[i]
+		if tracker.Interrupted() {
+			return
+		}
+		tracker.Begin(node.Name)
 
-			output, err = sshCmd.CombinedOutput()
+		for attempt := 1; attempt <= maxRetries; attempt++ {
+			output, err := access.runScript(node, 10, peerAddScript)
 			if err == nil {
-				attemptSucceeded = true
-				break // Success
+				added[i] = true
+				break
+			}
+
+			// The session was likely killed by the interrupt: the node's state
+			// is unknown, so it stays in flight
+			if tracker.Interrupted() {
+				return
 			}
 
-			// If this was the last attempt, log the failure
 			if attempt == maxRetries {
-				color.Yellow(fmt.Sprintf("  ⚠️  Failed to add peer to %s after %d attempts: %v (output: %s)", node.Name, maxRetries, err, string(output)))
-				continue
+				failures[i] = fmt.Sprintf("%v (output: %s)", err, string(output))
+				break
 			}
 
-			// Wait before retrying (exponential backoff)
-			time.Sleep(time.Duration(attempt) * time.Second)
+			// Wait before retrying (exponential backoff: 1s, 2s, ...)
+			time.Sleep(time.Second << (attempt - 1))
 		}
 
-		if attemptSucceeded {
+		tracker.DoneNode(node.Name, added[i])
+	})
+
+	for i, node := range joinNodes {
+		switch {
+		case added[i]:
+			joinStatus.markSucceeded(node.Name)
 			printSuccess(fmt.Sprintf("  ✓ Added peer to %s", node.Name))
+		case failures[i] != "":
+			color.Yellow(fmt.Sprintf("  ⚠️  Failed to add peer to %s after %d attempts: %s", node.Name, maxRetries, failures[i]))
+		}
+	}
+
+	if tracker.Interrupted() {
+		return abortVPNJoin(tracker, joinStatus, joinNodes, access, publicKey)
+	}
+
+	// A half-joined peer is rolled back unless --force keeps it
+	if len(joinStatus.Failed) > 0 && !vpnJoinForce {
+		var addedNow []string
+		for i, node := range joinNodes {
+			if added[i] {
+				addedNow = append(addedNow, node.Name)
+			}
 		}
+		return abortIncompleteVPNJoin(joinStatus, joinNodes, addedNow, access, publicKey)
+	}
 
-		// Small delay to avoid overwhelming bastion with simultaneous connections
-		if bastionEnabled && i < len(nodes)-1 {
-			time.Sleep(2 * time.Second)
+	if len(joinStatus.Failed) > 0 {
+		if err := saveOperationStatus(joinStatus); err != nil {
+			printWarning(fmt.Sprintf("Could not save join status: %v", err))
+		} else {
+			color.Yellow(fmt.Sprintf("  ⚠️  Peer not added to %s - retry with: sloth-kubernetes vpn join %s --resume-failed",
+				strings.Join(joinStatus.Failed, ", "), stack))
 		}
+	} else if err := removeOperationStatus(stack, vpnJoinOperation); err != nil {
+		printWarning(fmt.Sprintf("Could not remove join status: %v", err))
 	}
 
 	// STEP 5: Add new peer to all existing VPN clients (including local machine if on VPN)
 	fmt.Println()
 	printInfo("Step 4/5: Adding peer to existing VPN clients...")
 
-	// Check if local machine has WireGuard running (cross-platform: Linux and macOS)
-	localHasWG := false
-	localWGInterface := ""
+	// Check if local machine has WireGuard running (Linux, macOS and Windows)
+	localOS := detectOS()
+	localWGInterface := detectLocalWireGuard(localOS)
 
-	// Try to detect WireGuard by running 'wg show' which works on both Linux and macOS
-	checkLocalWG := exec.Command("sh", "-c", "sudo wg show 2>/dev/null | head -1 | awk '{print $2}'")
-	if output, err := checkLocalWG.CombinedOutput(); err == nil && len(output) > 0 {
-		iface := strings.TrimSpace(string(output))
-		if iface != "" {
-			localHasWG = true
-			localWGInterface = iface
-		}
-	}
+	// The clients given the preshared key, by public key: only their peers
+	// get it in the new client's config
+	pskPeers := map[string]bool{}
 
 	// Always try to add to local machine if it has WireGuard running
-	if localHasWG {
+	if localWGInterface != "" {
 		printInfo(fmt.Sprintf("  [local] Adding peer to local WireGuard interface (%s)...", localWGInterface))
-		localAddCmd := exec.Command("sudo", "wg", "set", localWGInterface,
-			"peer", publicKey,
-			"allowed-ips", fmt.Sprintf("%s/32", vpnJoinIP),
-			"persistent-keepalive", "25")
-
-		if output, err := localAddCmd.CombinedOutput(); err != nil {
+		if output, err := addLocalWGPeer(localOS, localWGInterface, publicKey, fmt.Sprintf("%s/32", vpnJoinIP), presharedKey); err != nil {
 			color.Yellow(fmt.Sprintf("  ⚠️  Failed to add peer locally: %v (output: %s)", err, string(output)))
-			color.Yellow(fmt.Sprintf("      You may need to run: sudo wg set %s peer %s allowed-ips %s/32 persistent-keepalive 25", localWGInterface, publicKey, vpnJoinIP))
+			color.Yellow(fmt.Sprintf("      You may need to run: %s %s peer %s allowed-ips %s/32 persistent-keepalive 25%s", localWGSetHint(localOS), localWGInterface, publicKey, vpnJoinIP, presharedKeyHint(presharedKey)))
 		} else {
 			printSuccess("  ✓ Added peer to local machine")
+			if localKey, err := localWGCommand(localOS, "show", localWGInterface, "public-key").Output(); err == nil {
+				pskPeers[strings.TrimSpace(string(localKey))] = presharedKey != ""
+			}
 		}
 	}
 
 	// Add to other existing VPN clients
 	if len(existingPeers) > 0 {
+		pskPipe, pskArg := "", ""
+		if presharedKey != "" {
+			pskPipe, pskArg = fmt.Sprintf("echo '%s' | ", presharedKey), " preshared-key /dev/stdin"
+		}
 
 		// For each existing peer, we need to add the new peer to their config
 		// This requires SSH access to those machines
 		for i, peer := range existingPeers {
+			if tracker.Interrupted() {
+				break
+			}
 			printInfo(fmt.Sprintf("  [%d/%d] Updating VPN client at %s...", i+1, len(existingPeers), peer.VPNAddress))
 
 			// Try to add peer via SSH to the VPN IP
 			// Note: This assumes the existing clients are reachable via VPN
 			addPeerScript := fmt.Sprintf(`
-if command -v wg &> /dev/null; then
-    sudo wg set wg0 peer %s allowed-ips %s/32 persistent-keepalive 25 2>/dev/null || true
+if ! command -v wg &> /dev/null; then
+    echo "⚠️  WireGuard not installed"
+elif %ssudo wg set wg0 peer %s allowed-ips %s/32 persistent-keepalive 25%s 2>/dev/null; then
     echo "✓ Peer added"
 else
-    echo "⚠️  WireGuard not installed"
+    echo "⚠️  wg set failed"
 fi
-`, publicKey, vpnJoinIP)
+`, pskPipe, publicKey, vpnJoinIP, pskArg)
 
 			// Try direct connection to VPN IP (requires being on VPN or having access)
-			sshCmd := exec.Command("ssh",
+			sshCmd := newSSHCommand(
 				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
+				"-o", knownHostsOption(stack),
 				"-o", "ConnectTimeout=5",
 				fmt.Sprintf("root@%s", peer.VPNAddress),
 				addPeerScript,
@@ -1284,9 +1337,9 @@ fi
 			output, err := sshCmd.CombinedOutput()
 			if err != nil {
 				// Try with different username (might not be root)
-				sshCmd2 := exec.Command("ssh",
+				sshCmd2 := newSSHCommand(
 					"-o", "StrictHostKeyChecking=accept-new",
-					"-o", "UserKnownHostsFile=/dev/null",
+					"-o", knownHostsOption(stack),
 					"-o", "ConnectTimeout=5",
 					peer.VPNAddress,
 					addPeerScript,
@@ -1294,10 +1347,11 @@ fi
 				output2, err2 := sshCmd2.CombinedOutput()
 				if err2 != nil {
 					color.Yellow(fmt.Sprintf("  ⚠️  Could not reach client at %s: %v", peer.VPNAddress, err))
-					color.Yellow(fmt.Sprintf("      Client will need to add peer manually: sudo wg set wg0 peer %s allowed-ips %s/32 persistent-keepalive 25", publicKey, vpnJoinIP))
+					color.Yellow(fmt.Sprintf("      Client will need to add peer manually: sudo wg set wg0 peer %s allowed-ips %s/32 persistent-keepalive 25%s", publicKey, vpnJoinIP, presharedKeyHint(presharedKey)))
 				} else {
 					if strings.Contains(string(output2), "✓") {
 						printSuccess(fmt.Sprintf("  ✓ Updated client at %s", peer.VPNAddress))
+						pskPeers[peer.PublicKey] = presharedKey != ""
 					} else {
 						color.Yellow(fmt.Sprintf("  ⚠️  Unexpected response from %s: %s", peer.VPNAddress, string(output2)))
 					}
@@ -1305,6 +1359,7 @@ fi
 			} else {
 				if strings.Contains(string(output), "✓") {
 					printSuccess(fmt.Sprintf("  ✓ Updated client at %s", peer.VPNAddress))
+					pskPeers[peer.PublicKey] = presharedKey != ""
 				} else {
 					color.Yellow(fmt.Sprintf("  ⚠️  Unexpected response from %s: %s", peer.VPNAddress, string(output)))
 				}
@@ -1312,16 +1367,24 @@ fi
 		}
 	}
 
+	if tracker.Interrupted() {
+		return abortVPNJoin(tracker, joinStatus, joinNodes, access, publicKey)
+	}
+
 	// STEP 6: Generate client configuration
 	fmt.Println()
 	printInfo("Step 5/5: Generating client configuration...")
-	clientConfig := generateClientConfig(privateKey, vpnJoinIP, vpnJoinLabel, nodes, existingPeers, sshKeyPath, bastionEnabled, bastionIP)
+	var routes []string
+	if vpnJoinNoBroad {
+		routes = clientRoutes(outputs)
+		printInfo(fmt.Sprintf("Routing only cluster networks: %s", strings.Join(routes, ", ")))
+	}
+	clientConfig := generateClientConfig(access, privateKey, vpnJoinIP, subnet, vpnJoinLabel, presharedKey, nodes, existingPeers, pskPeers, !vpnJoinNoBroad, routes)
 
-	configPath := "./wg0-client.conf"
-	if err := os.WriteFile(configPath, []byte(clientConfig), 0600); err != nil {
-		return fmt.Errorf("failed to write config file: %w", err)
+	configPath, err := writeClientConfig(stack, clientConfig, configOut)
+	if err != nil {
+		return err
 	}
-	printSuccess(fmt.Sprintf("Client configuration saved to: %s", configPath))
 
 	// STEP 5: Optionally install
 	if vpnJoinInstall {
@@ -1382,9 +1445,9 @@ echo "✓ WireGuard installed and started"
 `, clientConfig)
 
 			// Execute installation via SSH using stdin to avoid shell escaping issues
-			sshCmd := exec.Command("ssh",
+			sshCmd := newSSHCommand(
 				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
+				"-o", knownHostsOption(stack),
 				vpnJoinRemote,
 				"sudo", "bash",
 			)
@@ -1397,7 +1460,7 @@ echo "✓ WireGuard installed and started"
 				fmt.Println()
 				fmt.Println("Please install manually on remote host:")
 				fmt.Println("  1. Install WireGuard: sudo apt install wireguard-tools")
-				fmt.Printf("  2. Copy config to remote: scp wg0-client.conf %s:/tmp/wg0.conf\n", vpnJoinRemote)
+				fmt.Printf("  2. Copy config to remote: scp %s %s:/tmp/wg0.conf\n", configPath, vpnJoinRemote)
 				fmt.Printf("  3. On remote: sudo mv /tmp/wg0.conf /etc/wireguard/wg0.conf\n")
 				fmt.Println("  4. On remote: sudo wg-quick up wg0")
 			} else {
@@ -1480,17 +1543,39 @@ echo "✓ WireGuard installed and started"
 					printSuccess("WireGuard installed and started")
 				}
 
+			case "windows":
+				printInfo("Detected Windows - installing WireGuard tunnel service")
+
+				if err := installWindowsTunnel(configPath); err != nil {
+					color.Yellow(fmt.Sprintf("⚠️  %v", err))
+					color.Yellow("Installing the tunnel service requires WireGuard for Windows and an elevated prompt.")
+					fmt.Println()
+					printWindowsInstallSteps(configPath)
+					return nil
+				}
+
+				printSuccess("✓ WireGuard tunnel service installed and started!")
+				fmt.Println()
+				fmt.Println("To check VPN status:")
+				fmt.Printf("  \"%s\\wg.exe\" show\n", windowsWireGuardDir())
+				fmt.Println()
+				fmt.Println("To stop VPN:")
+				fmt.Printf("  \"%s\\wireguard.exe\" /uninstalltunnelservice %s\n", windowsWireGuardDir(), windowsTunnelName)
+
 			default:
 				color.Yellow(fmt.Sprintf("⚠️  Unsupported OS: %s", osType))
 				color.Cyan(fmt.Sprintf("\nConfiguration saved to: %s", configPath))
 				color.Cyan("Please install WireGuard manually for your platform")
 			}
 		}
-	} else {
+	} else if configOut == nil {
 		fmt.Println()
 		osType := detectOS()
 
-		if osType == "darwin" {
+		switch osType {
+		case "windows":
+			printWindowsInstallSteps(configPath)
+		case "darwin":
 			color.Cyan("To install the configuration on macOS:")
 			fmt.Println()
 			fmt.Println("  1. Install WireGuard app: https://www.wireguard.com/install/")
@@ -1501,7 +1586,7 @@ echo "✓ WireGuard installed and started"
 			fmt.Printf("  sudo mkdir -p /opt/homebrew/etc/wireguard\n")
 			fmt.Printf("  sudo cp %s /opt/homebrew/etc/wireguard/wg0.conf\n", configPath)
 			fmt.Printf("  wg-quick up /opt/homebrew/etc/wireguard/wg0.conf\n")
-		} else {
+		default:
 			color.Cyan("To install the configuration manually:")
 			fmt.Println()
 			fmt.Printf("  sudo cp %s /etc/wireguard/wg0.conf\n", configPath)
@@ -1512,18 +1597,96 @@ echo "✓ WireGuard installed and started"
 
 	fmt.Println()
 	printSuccess(fmt.Sprintf("Successfully joined VPN with IP %s!", vpnJoinIP))
-	printInfo("You can now access cluster nodes via their VPN IPs (10.8.0.x)")
+	printInfo(fmt.Sprintf("You can now access cluster nodes via their VPN IPs (%s)", subnet))
 
 	return nil
 }
 
-func runVPNLeave(cmd *cobra.Command, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: sloth-kubernetes vpn leave <stack-name>")
+// writeClientConfig writes a client config to out when set, otherwise to the
+// stack's artifact directory, and returns the file it was saved to
+func writeClientConfig(stack, clientConfig string, out io.Writer) (string, error) {
+	if out != nil {
+		if _, err := io.WriteString(out, clientConfig); err != nil {
+			return "", fmt.Errorf("failed to write config: %w", err)
+		}
+		printSuccess("Client configuration written to stdout")
+		return "", nil
+	}
+
+	configPath, err := artifactPath(stack, "wg0-client.conf")
+	if err != nil {
+		return "", err
 	}
+	if err := os.WriteFile(configPath, []byte(clientConfig), 0600); err != nil {
+		return "", fmt.Errorf("failed to write config file: %w", err)
+	}
+	printSuccess(fmt.Sprintf("Client configuration saved to: %s", configPath))
+	return configPath, nil
+}
+
+// findPeerKeyByVPNIP returns the public key of the peer routing vpnIP on node
+func findPeerKeyByVPNIP(node NodeInfo, access nodeSSHAccess, vpnIP string) (string, error) {
+	getPubKeyCmd := fmt.Sprintf("wg show wg0 dump | awk '$5 ~ /%s\\/32/ {print $1; exit}'", strings.ReplaceAll(vpnIP, ".", "\\."))
+
+	output, err := access.run(node, 5, getPubKeyCmd)
+	publicKey := strings.TrimSpace(string(output))
+	if err != nil || publicKey == "" {
+		return "", fmt.Errorf("peer not found in cluster")
+	}
+	return publicKey, nil
+}
+
+// removePeerFromNodes removes the peer with publicKey from every node with a
+// VPN IP, one node at a time, and stops before the next node once tracker is
+// interrupted. It returns the number of nodes the peer was removed from.
+func removePeerFromNodes(nodes []NodeInfo, access nodeSSHAccess, publicKey string, tracker *nodeChangeTracker) int {
+	removeCmd := peerRemoveCommand(publicKey)
+
+	successCount := 0
+	for i, node := range nodes {
+		if tracker.Interrupted() {
+			break
+		}
+		if node.WireGuardIP == "" {
+			continue
+		}
+		tracker.Begin(node.Name)
+
+		output, err := access.run(node, 5, removeCmd)
+		result := strings.TrimSpace(string(output))
+
+		// The session was likely killed by the interrupt: the node's state is unknown
+		if err != nil && tracker.Interrupted() {
+			break
+		}
+
+		tracker.Done(err == nil && result == "SUCCESS")
+		if err == nil && result == "SUCCESS" {
+			fmt.Printf("  [%d/%d] ✓ Removed peer from %s\n", i+1, len(nodes), node.Name)
+			successCount++
+		} else {
+			fmt.Printf("  [%d/%d] ✗ Failed to remove peer from %s\n", i+1, len(nodes), node.Name)
+		}
+	}
+	return successCount
+}
 
+// stdoutToStderr sends everything printed to stdout, including colored
+// output, to stderr until restore is called, and returns the original stdout
+func stdoutToStderr() (stdout *os.File, restore func()) {
+	stdout, colorOutput := os.Stdout, color.Output
+	os.Stdout, color.Output = os.Stderr, os.Stderr
+	return stdout, func() {
+		os.Stdout, color.Output = stdout, colorOutput
+	}
+}
+
+func runVPNLeave(cmd *cobra.Command, args []string) error {
 	ctx := context.Background()
-	stack := args[0]
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
 
 	printHeader(fmt.Sprintf("👋 Leaving VPN - Stack: %s", stack))
 
@@ -1560,12 +1723,12 @@ func runVPNLeave(cmd *cobra.Command, args []string) error {
 	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
 	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
 	if err != nil {
-		return fmt.Errorf("failed to select stack '%s': %w", stack, err)
+		return selectStackError(err, stack)
 	}
 
-	outputs, err := s.Outputs(ctx)
+	outputs, err := stackOutputsWithRetry(ctx, s)
 	if err != nil {
-		return fmt.Errorf("failed to get stack outputs: %w", err)
+		return err
 	}
 
 	// Parse nodes
@@ -1578,130 +1741,48 @@ func runVPNLeave(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no nodes found in stack")
 	}
 
-	// Get SSH key and bastion info
-	sshKeyPath := GetSSHKeyPath(stack)
-	bastionEnabled := false
-	bastionIP := ""
+	// Cluster nodes are part of the mesh, not peers that can leave it
+	subnet := vpnSubnet(outputs)
+	if _, ok := subnet.HostOffset(targetIP); !ok || subnet.IsNodeIP(targetIP) {
+		return fmt.Errorf("VPN IP %s is not a client address of the VPN subnet %s", targetIP, subnet)
+	}
 
-	if bastionEnabledOutput, ok := outputs["bastion_enabled"]; ok {
-		if bastionEnabledOutput.Value != nil {
-			bastionEnabled = bastionEnabledOutput.Value == true
-		}
+	// Get SSH key and bastion info
+	access := newNodeSSHAccess(stack, outputs)
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
 	}
 
-	if bastionEnabled {
-		if bastionOutput, ok := outputs["bastion"]; ok {
-			if bastionMap, ok := bastionOutput.Value.(map[string]interface{}); ok {
-				if pubIP, ok := bastionMap["public_ip"].(string); ok {
-					bastionIP = pubIP
-				}
-			}
-		}
+	// Pick a reachable node to look up the peer so a single dead node doesn't block removal
+	discoveryNode, err := findReachableNode(nodes, access)
+	if err != nil {
+		return err
 	}
 
 	fmt.Println()
 	printInfo(fmt.Sprintf("Removing peer from %d cluster nodes...", len(nodes)))
 
 	// First, get the public key for this VPN IP from one of the nodes
-	var peerPublicKey string
-	if len(nodes) > 0 {
-		firstNode := nodes[0]
-		targetIP := firstNode.WireGuardIP
-		if targetIP == "" {
-			targetIP = firstNode.PrivateIP
-			if targetIP == "" {
-				targetIP = firstNode.PublicIP
-			}
-		}
-
-		// Get public key for this VPN IP
-		getPubKeyCmd := fmt.Sprintf("wg show wg0 dump | awk '$5 ~ /%s\\/32/ {print $1; exit}'", strings.ReplaceAll(targetIP, ".", "\\."))
-
-		var sshCmd *exec.Cmd
-		if bastionEnabled && bastionIP != "" {
-			sshCmd = exec.Command("ssh",
-				"-q",
-				"-i", sshKeyPath,
-				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
-				"-o", "ConnectTimeout=5",
-				"-o", fmt.Sprintf("ProxyCommand=ssh -q -i %s -o StrictHostKeyChecking=accept-new -o UserKnownHostsFile=/dev/null -W %%h:%%p root@%s", sshKeyPath, bastionIP),
-				fmt.Sprintf("root@%s", targetIP),
-				getPubKeyCmd,
-			)
-		} else {
-			sshCmd = exec.Command("ssh",
-				"-q",
-				"-i", sshKeyPath,
-				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
-				"-o", "ConnectTimeout=5",
-				fmt.Sprintf("root@%s", firstNode.PublicIP),
-				getPubKeyCmd,
-			)
-		}
-
-		output, err := sshCmd.CombinedOutput()
-		if err == nil && len(output) > 0 {
-			peerPublicKey = strings.TrimSpace(string(output))
-			printInfo(fmt.Sprintf("Found peer public key: %s...", peerPublicKey[:16]))
-		} else {
-			color.Yellow(fmt.Sprintf("⚠️  Could not find peer with VPN IP %s", targetIP))
-			return fmt.Errorf("peer not found in cluster")
-		}
+	peerPublicKey, err := findPeerKeyByVPNIP(discoveryNode, access, targetIP)
+	if err != nil {
+		color.Yellow(fmt.Sprintf("⚠️  Could not find peer with VPN IP %s", targetIP))
+		return err
 	}
+	printInfo(fmt.Sprintf("Found peer public key: %s...", peerPublicKey[:16]))
 
-	// Remove peer from all nodes
-	successCount := 0
-	for i, node := range nodes {
-		if node.WireGuardIP == "" {
-			continue
-		}
-
-		targetIP := node.WireGuardIP
-		if targetIP == "" {
-			targetIP = node.PrivateIP
-			if targetIP == "" {
-				targetIP = node.PublicIP
-			}
-		}
+	// Remove peer from all nodes, stopping before the next node on Ctrl-C
+	tracker := newNodeChangeTracker()
+	defer tracker.Stop()
 
-		// Remove peer using public key
-		removeCmd := fmt.Sprintf("wg set wg0 peer %s remove 2>/dev/null && wg-quick save wg0 && echo 'SUCCESS' || echo 'FAILED'", peerPublicKey)
+	successCount := removePeerFromNodes(nodes, access, peerPublicKey, tracker)
 
-		var sshCmd *exec.Cmd
-		if bastionEnabled && bastionIP != "" {
-			sshCmd = exec.Command("ssh",
-				"-q",
-				"-i", sshKeyPath,
-				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
-				"-o", "ConnectTimeout=5",
-				"-o", fmt.Sprintf("ProxyCommand=ssh -q -i %s -o StrictHostKeyChecking=accept-new -o UserKnownHostsFile=/dev/null -W %%h:%%p root@%s", sshKeyPath, bastionIP),
-				fmt.Sprintf("root@%s", targetIP),
-				removeCmd,
-			)
-		} else {
-			sshCmd = exec.Command("ssh",
-				"-q",
-				"-i", sshKeyPath,
-				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
-				"-o", "ConnectTimeout=5",
-				fmt.Sprintf("root@%s", node.PublicIP),
-				removeCmd,
-			)
-		}
-
-		output, err := sshCmd.CombinedOutput()
-		result := strings.TrimSpace(string(output))
-
-		if err == nil && result == "SUCCESS" {
-			fmt.Printf("  [%d/%d] ✓ Removed peer from %s\n", i+1, len(nodes), node.Name)
-			successCount++
-		} else {
-			fmt.Printf("  [%d/%d] ✗ Failed to remove peer from %s\n", i+1, len(nodes), node.Name)
+	if tracker.Interrupted() {
+		names := make([]string, len(nodes))
+		for i, node := range nodes {
+			names[i] = node.Name
 		}
+		tracker.Report(names, fmt.Sprintf("sloth-kubernetes vpn leave %s --vpn-ip %s", stack, targetIP))
+		return tracker.interruptedError("vpn leave")
 	}
 
 	fmt.Println()
@@ -1727,6 +1808,20 @@ func runVPNLeave(cmd *cobra.Command, args []string) error {
 		var stopCmd *exec.Cmd
 
 		switch osType {
+		case "windows":
+			if output, err := uninstallWindowsTunnel(); err != nil {
+				color.Yellow(fmt.Sprintf("⚠️  Failed to remove the WireGuard tunnel service: %v", err))
+				color.Yellow(fmt.Sprintf("Output: %s", string(output)))
+				fmt.Println()
+				color.Cyan("Please stop WireGuard manually:")
+				printWindowsRemoveSteps()
+				return nil
+			}
+			printSuccess("✓ WireGuard tunnel service removed successfully!")
+			fmt.Println()
+			color.Cyan("To remove WireGuard configuration:")
+			fmt.Printf("  del \"%s\"\n", windowsTunnelConfigPath())
+			return nil
 		case "darwin":
 			stopCmd = exec.Command("sudo", "wg-quick", "down", "wg0")
 		case "linux":
@@ -1760,18 +1855,20 @@ func runVPNLeave(cmd *cobra.Command, args []string) error {
 		color.Cyan(fmt.Sprintf("To stop WireGuard on the removed machine (%s):", targetIP))
 		fmt.Println("  sudo wg-quick down wg0")
 		fmt.Println("  sudo rm /etc/wireguard/wg0.conf")
+		fmt.Println()
+		color.Cyan("On Windows:")
+		printWindowsRemoveSteps()
 	}
 
 	return nil
 }
 
 func runVPNClientConfig(cmd *cobra.Command, args []string) error {
-	if len(args) < 1 {
-		return fmt.Errorf("usage: sloth-kubernetes vpn client-config <stack-name>")
-	}
-
 	ctx := context.Background()
-	stack := args[0]
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
 
 	printHeader(fmt.Sprintf("📱 Generate Client Config - Stack: %s", stack))
 
@@ -1785,12 +1882,12 @@ func runVPNClientConfig(cmd *cobra.Command, args []string) error {
 	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
 	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
 	if err != nil {
-		return fmt.Errorf("failed to select stack '%s': %w", stack, err)
+		return selectStackError(err, stack)
 	}
 
-	outputs, err := s.Outputs(ctx)
+	outputs, err := stackOutputsWithRetry(ctx, s)
 	if err != nil {
-		return fmt.Errorf("failed to get stack outputs: %w", err)
+		return err
 	}
 
 	// Parse nodes
@@ -1803,52 +1900,84 @@ func runVPNClientConfig(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no nodes found in stack")
 	}
 
+	configPath := vpnConfigOutput
+	if configPath == "" {
+		configPath, err = artifactPath(stack, "wg0.conf")
+		if err != nil {
+			return err
+		}
+	}
+
+	access := newNodeSSHAccess(stack, outputs)
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
+	}
+
+	// Existing clients are discovered only to pick a free VPN IP; unlike
+	// vpn join the new peer is not added to the nodes
+	discoveryNode, err := findReachableNode(nodes, access)
+	if err != nil {
+		return err
+	}
+	subnet := vpnSubnet(outputs)
+	existingPeers := discoverVPNClients(discoveryNode, access, subnet)
+
+	clientIP, err := nextClientIP(existingPeers, subnet)
+	if err != nil {
+		return err
+	}
+
 	fmt.Println()
 	printInfo(fmt.Sprintf("Generating config for %d peer(s)", len(nodes)))
+	printInfo(fmt.Sprintf("Assigned VPN IP: %s", clientIP))
 
-	if vpnConfigOutput != "" {
-		printInfo(fmt.Sprintf("Output file: %s", vpnConfigOutput))
+	privateKey, publicKey, err := generateWireGuardKeypair()
+	if err != nil {
+		return fmt.Errorf("failed to generate keypair: %w", err)
 	}
 
-	if vpnConfigQR {
-		printInfo("QR code generation enabled")
+	clientConfig := generateClientConfig(access, privateKey, clientIP, subnet, "", "", nodes, existingPeers, nil, true, nil)
+	if err := os.WriteFile(configPath, []byte(clientConfig), 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
 	}
+	printSuccess(fmt.Sprintf("Client configuration saved to: %s", configPath))
 
-	fmt.Println()
-	color.Yellow("⚠️  Client config generation will be implemented in next phase")
-	color.Cyan("\nWhat will be implemented:")
-	fmt.Println("  • Generate new WireGuard keypair")
-	fmt.Println("  • Create [Interface] section with private key and VPN IP")
-	fmt.Println("  • Create [Peer] sections for all cluster nodes")
-	fmt.Println("  • Save to file (default: ./wg0.conf)")
 	if vpnConfigQR {
-		fmt.Println("  • Generate QR code using 'qrencode' for mobile import")
+		fmt.Println()
+		printVPNConfigQR(clientConfig, configPath)
 	}
 
 	fmt.Println()
-	color.Cyan("Example output format:")
-	fmt.Print(`
-[Interface]
-PrivateKey = <generated-private-key>
-Address = 10.8.0.100/24
-DNS = 1.1.1.1
+	printWarning("This peer is not added to the cluster nodes; add it on each node before connecting:")
+	fmt.Printf("  wg set wg0 peer %s allowed-ips %s/32\n", publicKey, clientIP)
+	printInfo("Or use 'sloth-kubernetes vpn join' to generate a config and add the peer in one step")
 
-[Peer]
-PublicKey = <master-1-public-key>
-Endpoint = 167.71.1.1:51820
-AllowedIPs = 10.8.0.10/32
+	return nil
+}
 
-[Peer]
-PublicKey = <worker-1-public-key>
-Endpoint = 172.236.1.1:51820
-AllowedIPs = 10.8.0.11/32
-`)
+// printVPNConfigQR prints a client config as a QR code for mobile import,
+// or how to make one when qrencode is not installed
+func printVPNConfigQR(clientConfig, configPath string) {
+	qrencode, err := exec.LookPath("qrencode")
+	if err != nil {
+		printWarning("qrencode not found, skipping QR code")
+		fmt.Println("  Install it (apt install qrencode / brew install qrencode), then run:")
+		fmt.Printf("  qrencode -t ansiutf8 < %s\n", configPath)
+		return
+	}
 
-	return nil
+	qr := exec.Command(qrencode, "-t", "ansiutf8")
+	qr.Stdin = strings.NewReader(clientConfig)
+	qr.Stdout = os.Stdout
+	qr.Stderr = os.Stderr
+	if err := qr.Run(); err != nil {
+		printWarning(fmt.Sprintf("Failed to generate QR code: %v", err))
+	}
 }
 
 // getSSHUserForNode returns the correct SSH username based on node provider
-// Azure uses "azureuser", AWS/GCP use "ubuntu", others use "root"
+// Azure uses "azureuser", AWS/GCP use "ubuntu", others (DigitalOcean, Linode,
+// Vultr) use "root"
 func getSSHUserForNode(provider string) string {
 	switch provider {
 	case "azure":
@@ -1858,7 +1987,7 @@ func getSSHUserForNode(provider string) string {
 	case "gcp":
 		return "ubuntu"
 	default:
-		return "root" // DigitalOcean, Linode, and others use "root"
+		return "root" // DigitalOcean, Linode, Vultr and others use "root"
 	}
 }
 
@@ -1886,21 +2015,202 @@ func generateWireGuardKeypair() (privateKey string, publicKey string, err error)
 	return privateKey, publicKey, nil
 }
 
+// presharedKeyHint returns the preshared-key argument of a manual wg set
+// hint, pointing at the client config rather than printing the key
+func presharedKeyHint(presharedKey string) string {
+	if presharedKey == "" {
+		return ""
+	}
+	return " preshared-key <file holding the PresharedKey from the client config>"
+}
+
+// generatePresharedKey generates a random WireGuard preshared key
+func generatePresharedKey() (string, error) {
+	var psk [32]byte
+	if _, err := rand.Read(psk[:]); err != nil {
+		return "", fmt.Errorf("failed to generate random key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(psk[:]), nil
+}
+
+// wireGuardPublicKey derives the base64 public key of a base64 private key
+func wireGuardPublicKey(privateKey string) (string, error) {
+	privKey, err := base64.StdEncoding.DecodeString(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode private key: %w", err)
+	}
+	pubKey, err := curve25519.X25519(privKey, curve25519.Basepoint)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive public key: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(pubKey), nil
+}
+
+// peerRemoveCommand removes a peer's section from the saved config and syncs
+// the running interface from it, the same way a join adds one, so both always
+// match. It prints SUCCESS once neither has the peer, FAILED otherwise.
+func peerRemoveCommand(publicKey string) string {
+	publicKey = strings.ReplaceAll(publicKey, "'", "'\\''")
+	return fmt.Sprintf(`awk -v key='%s' '
+function flush() { if (block != "" && !drop) printf "%%s", block; block = ""; drop = 0 }
+/^\[/ { flush(); in_peer = ($0 ~ /^\[Peer\]/) }
+{ if (!in_peer) { print; next } block = block $0 "\n"; if ($1 == "PublicKey" && $3 == key) drop = 1 }
+END { flush() }
+' /etc/wireguard/wg0.conf > /etc/wireguard/wg0.conf.new && mv /etc/wireguard/wg0.conf.new /etc/wireguard/wg0.conf && \
+wg-quick strip wg0 | wg syncconf wg0 /dev/stdin && \
+%s && echo 'SUCCESS' || echo 'FAILED'`, publicKey, peerStateCheck("", publicKey, false))
+}
+
+// peerStateCheck returns a shell condition that holds when the saved config
+// and the running interface both have the peer (present) or both lack it.
+// publicKey must already be escaped for single quotes.
+func peerStateCheck(sudo, publicKey string, present bool) string {
+	saved := fmt.Sprintf("%sgrep -qF 'PublicKey = %s' /etc/wireguard/wg0.conf", sudo, publicKey)
+	running := fmt.Sprintf("%swg show wg0 peers | grep -qxF '%s'", sudo, publicKey)
+	if present {
+		return saved + " && " + running
+	}
+	return "! " + saved + " && ! " + running
+}
+
+// abortVPNJoin ends an interrupted join. With --atomic the peer is removed
+// again from every node this run may have added it to; otherwise the nodes
+// already changed are listed and the rest are saved for --resume-failed.
+func abortVPNJoin(tracker *nodeChangeTracker, status *operationStatus, nodes []NodeInfo, access nodeSSHAccess, publicKey string) error {
+	stack := status.Stack
+	names := make([]string, len(nodes))
+	for i, node := range nodes {
+		names[i] = node.Name
+	}
+
+	if !vpnJoinAtomic {
+		if err := saveOperationStatus(status); err != nil {
+			printWarning(fmt.Sprintf("Could not save join status: %v", err))
+		}
+		tracker.Report(names, fmt.Sprintf("sloth-kubernetes vpn join %s --resume-failed, or vpn leave %s --vpn-ip %s", stack, stack, vpnJoinIP))
+		return tracker.interruptedError("vpn join")
+	}
+
+	touched := tracker.Touched()
+	fmt.Println()
+	printInfo(fmt.Sprintf("Rolling back: removing the peer from %d node(s)...", len(touched)))
+
+	failed := rollbackPeerAdd(touched, nodes, access, publicKey)
+	for _, name := range touched {
+		if !containsName(failed, name) {
+			status.markFailed(name)
+		}
+	}
+
+	// Nodes from an earlier run still have the peer: keep them resumable
+	if len(status.Succeeded) > 0 {
+		if err := saveOperationStatus(status); err != nil {
+			printWarning(fmt.Sprintf("Could not save join status: %v", err))
+		}
+	} else if err := removeOperationStatus(stack, vpnJoinOperation); err != nil {
+		printWarning(fmt.Sprintf("Could not remove join status: %v", err))
+	}
+
+	if len(failed) > 0 {
+		color.Yellow(fmt.Sprintf("  ⚠️  Rollback failed on %s - remove the peer with: sloth-kubernetes vpn leave %s --vpn-ip %s",
+			strings.Join(failed, ", "), stack, vpnJoinIP))
+		return fmt.Errorf("vpn join interrupted and rollback failed on %d node(s)", len(failed))
+	}
+
+	printSuccess("Rollback complete - no node keeps the new peer")
+	return fmt.Errorf("vpn join interrupted and rolled back")
+}
+
+// abortIncompleteVPNJoin stops a join that could not add the peer to every
+// node: it lists the nodes missing the peer and removes it again from the
+// nodes this run added it to, so the mesh is not left half-joined
+func abortIncompleteVPNJoin(status *operationStatus, nodes []NodeInfo, added []string, access nodeSSHAccess, publicKey string) error {
+	stack := status.Stack
+	missing := append([]string(nil), status.Failed...)
+
+	fmt.Println()
+	color.Yellow(fmt.Sprintf("  ⚠️  Peer %s is missing on %d node(s): %s", vpnJoinIP, len(missing), strings.Join(missing, ", ")))
+	printInfo("Aborting the join - use --force to keep the peer on the nodes that accepted it")
+
+	var failed []string
+	if len(added) > 0 {
+		printInfo(fmt.Sprintf("Rolling back: removing the peer from %d node(s)...", len(added)))
+		failed = rollbackPeerAdd(added, nodes, access, publicKey)
+	}
+	for _, name := range added {
+		if !containsName(failed, name) {
+			status.markFailed(name)
+		}
+	}
+
+	// Nodes from an earlier run still have the peer: keep them resumable
+	if len(status.Succeeded) > 0 {
+		if err := saveOperationStatus(status); err != nil {
+			printWarning(fmt.Sprintf("Could not save join status: %v", err))
+		}
+	} else if err := removeOperationStatus(stack, vpnJoinOperation); err != nil {
+		printWarning(fmt.Sprintf("Could not remove join status: %v", err))
+	}
+
+	joinErr := fmt.Errorf("vpn join failed on %d node(s): %s", len(missing), strings.Join(missing, ", "))
+	if len(failed) > 0 {
+		color.Yellow(fmt.Sprintf("  ⚠️  Rollback failed on %s - remove the peer with: sloth-kubernetes vpn leave %s --vpn-ip %s",
+			strings.Join(failed, ", "), stack, vpnJoinIP))
+		joinErr = fmt.Errorf("%w; rollback failed on %d node(s)", joinErr, len(failed))
+	} else if len(added) > 0 {
+		printSuccess("Rollback complete - the peer was removed from every node this run added it to")
+	}
+	return errs.Mark(joinErr, errs.ErrMeshDegraded)
+}
+
+// rollbackPeerAdd removes the peer from the named nodes and returns the names
+// of the nodes where that failed
+func rollbackPeerAdd(names []string, nodes []NodeInfo, access nodeSSHAccess, publicKey string) []string {
+	byName := make(map[string]NodeInfo, len(nodes))
+	for _, node := range nodes {
+		byName[node.Name] = node
+	}
+
+	var failed []string
+	for _, name := range names {
+		node, ok := byName[name]
+		if !ok {
+			failed = append(failed, name)
+			continue
+		}
+
+		output, err := access.run(node, 10, peerRemoveCommand(publicKey))
+		if err != nil || strings.TrimSpace(string(output)) != "SUCCESS" {
+			color.Yellow(fmt.Sprintf("  ✗ %s", name))
+			failed = append(failed, name)
+			continue
+		}
+		printSuccess(fmt.Sprintf("  ✓ %s", name))
+	}
+	return failed
+}
+
 // generatePeerAddScript creates a bash script to add a peer to WireGuard config
-// It uses escaped echo commands to write the configuration safely
-func generatePeerAddScript(peerIP string, peerPublicKey string, peerLabel string) string {
+// The peer block is written through a quoted heredoc, so nothing in it is
+// expanded by the shell or shows up on a command line. The peer's
+// PresharedKey is written too when presharedKey is set. Existing client peers,
+// those in the client range of subnet, are removed first.
+func generatePeerAddScript(peerIP string, subnet *config.WireGuardSubnet, peerPublicKey string, peerLabel string, presharedKey string) string {
 	comment := "Client joined via CLI"
 	if peerLabel != "" {
-		comment = fmt.Sprintf("Peer: %s", peerLabel)
+		comment = fmt.Sprintf("Peer: %s", strings.ReplaceAll(peerLabel, "\n", " "))
 	}
 
-	// Escape any single quotes in the values to prevent shell injection
-	comment = strings.ReplaceAll(comment, "'", "'\\''")
-	peerPublicKey = strings.ReplaceAll(peerPublicKey, "'", "'\\''")
-	peerIP = strings.ReplaceAll(peerIP, "'", "'\\''")
+	// The public key is also checked on a single-quoted command line
+	escapedKey := strings.ReplaceAll(peerPublicKey, "'", "'\\''")
+	firstClient := subnet.HostIP(config.WireGuardFirstClientHost)
+	lastClient := subnet.HostIP(subnet.LastHost())
+
+	pskLine := ""
+	if presharedKey != "" {
+		pskLine = "PresharedKey = " + presharedKey + "\n"
+	}
 
-	// Use escaped echo commands with single quotes to write configuration safely
-	// Single quotes prevent any shell expansion, and we escape any single quotes in the values
 	return fmt.Sprintf(`
 set -e
 
@@ -1916,9 +2226,14 @@ sudo sed -i '/\\\\n/d' /etc/wireguard/wg0.conf 2>/dev/null || true
 # Also remove any malformed [Peer] sections that might exist
 sudo sed -i '/\[Peer\][^]]*\\n/d' /etc/wireguard/wg0.conf 2>/dev/null || true
 
-# Remove existing client peers (10.8.0.100+) using awk
-sudo awk '
-BEGIN { in_peer=0; skip=0; buffer="" }
+# Remove existing client peers (%s-%s) using awk
+sudo awk -v first=%s -v last=%s '
+function ip_num(addr,   o) {
+    sub(/\/.*/, "", addr)
+    split(addr, o, ".")
+    return ((o[1] * 256 + o[2]) * 256 + o[3]) * 256 + o[4]
+}
+BEGIN { in_peer=0; skip=0; buffer=""; first=ip_num(first); last=ip_num(last) }
 /^\[Peer\]/ {
     if (buffer != "" && skip == 0) print buffer
     buffer=$0"\n"
@@ -1926,7 +2241,7 @@ BEGIN { in_peer=0; skip=0; buffer="" }
     skip=0
     next
 }
-in_peer && /^AllowedIPs = 10\.8\.0\.(10[0-9]|1[1-9][0-9]|[2-9][0-9]{2})/ {
+in_peer && /^AllowedIPs = / && ip_num($3) >= first && ip_num($3) <= last {
     skip=1
     buffer=""
     in_peer=0
@@ -1941,68 +2256,37 @@ sudo mv /etc/wireguard/wg0.conf.clean /etc/wireguard/wg0.conf
 # Step 2: Add new peer configuration
 echo "Adding new peer..."
 
-# Write peer config using tee (most reliable method)
-echo "" | sudo tee -a /etc/wireguard/wg0.conf >/dev/null
-echo "[Peer]" | sudo tee -a /etc/wireguard/wg0.conf >/dev/null
-echo "# %s" | sudo tee -a /etc/wireguard/wg0.conf >/dev/null
-echo "PublicKey = %s" | sudo tee -a /etc/wireguard/wg0.conf >/dev/null
-echo "AllowedIPs = %s/32" | sudo tee -a /etc/wireguard/wg0.conf >/dev/null
-echo "PersistentKeepalive = 25" | sudo tee -a /etc/wireguard/wg0.conf >/dev/null
+sudo tee -a /etc/wireguard/wg0.conf > /dev/null << 'WGEOF'
+
+[Peer]
+# %s
+PublicKey = %s
+%sAllowedIPs = %s/32
+PersistentKeepalive = 25
+WGEOF
 
 # Step 3: Reload WireGuard configuration
 echo "Reloading WireGuard..."
 sudo wg-quick strip wg0 | sudo wg syncconf wg0 /dev/stdin
+
+# Step 4: Verify the peer is both saved and running, so it survives a reboot
+if ! { %s; }; then
+    echo "Peer is not in both /etc/wireguard/wg0.conf and the running interface" >&2
+    exit 1
+fi
 echo "Peer added and WireGuard reloaded successfully!"
-`, comment, peerPublicKey, peerIP)
+`, firstClient, lastClient, firstClient, lastClient, comment, peerPublicKey, pskLine, peerIP, peerStateCheck("sudo ", escapedKey, true))
 }
 
 // fetchNodePublicKey fetches the WireGuard public key from a node via SSH
-func fetchNodePublicKey(node NodeInfo, sshKeyPath string, bastionEnabled bool, bastionIP string) (string, error) {
-	// Determine target IP
-	targetIP := node.WireGuardIP
-	if targetIP == "" {
-		targetIP = node.PrivateIP
-		if targetIP == "" {
-			targetIP = node.PublicIP
-		}
-	}
-
-	// Build SSH command with sudo for permission and retry for connection issues
-	sshUser := getSSHUserForNode(node.Provider)
-
+func fetchNodePublicKey(node NodeInfo, access nodeSSHAccess) (string, error) {
 	// Try up to 3 times to handle transient SSH connection issues
 	var output []byte
 	var err error
 	maxRetries := 3
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
-		var sshCmd *exec.Cmd
-		if bastionEnabled && bastionIP != "" {
-			// Use ProxyCommand through bastion (use -q to suppress SSH warnings)
-			sshCmd = exec.Command("ssh",
-				"-q",
-				"-i", sshKeyPath,
-				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
-				"-o", "ConnectTimeout=10",
-				"-o", fmt.Sprintf("ProxyCommand=ssh -q -i %s -o StrictHostKeyChecking=accept-new -o UserKnownHostsFile=/dev/null -W %%h:%%p root@%s", sshKeyPath, bastionIP),
-				fmt.Sprintf("%s@%s", sshUser, targetIP),
-				"sudo cat /etc/wireguard/publickey",
-			)
-		} else {
-			// Direct SSH (use -q to suppress SSH warnings)
-			sshCmd = exec.Command("ssh",
-				"-q",
-				"-i", sshKeyPath,
-				"-o", "StrictHostKeyChecking=accept-new",
-				"-o", "UserKnownHostsFile=/dev/null",
-				"-o", "ConnectTimeout=10",
-				fmt.Sprintf("%s@%s", sshUser, node.PublicIP),
-				"sudo cat /etc/wireguard/publickey",
-			)
-		}
-
-		output, err = sshCmd.CombinedOutput()
+		output, err = access.run(node, 10, "cat /etc/wireguard/publickey")
 		if err == nil {
 			break // Success
 		}
@@ -2016,35 +2300,128 @@ func fetchNodePublicKey(node NodeInfo, sshKeyPath string, bastionEnabled bool, b
 		time.Sleep(time.Duration(attempt) * time.Second)
 	}
 
-	// Trim whitespace and newlines
-	publicKey := string(output)
-	// Remove trailing newlines
-	for len(publicKey) > 0 && (publicKey[len(publicKey)-1] == '\n' || publicKey[len(publicKey)-1] == '\r') {
-		publicKey = publicKey[:len(publicKey)-1]
+	return strings.TrimRight(string(output), "\r\n"), nil
+}
+
+// listVPNPeersScript prints the public key and first allowed IP of every
+// WireGuard peer of a node, one peer per line
+const listVPNPeersScript = `wg show wg0 dump | tail -n +2 | while IFS=$'\t' read -r pubkey _ endpoint allowed_ips _; do
+	# Extract first IP from allowed-ips (format: <vpn-ip>/32,10.0.0.0/8)
+	first_ip=$(echo "$allowed_ips" | cut -d, -f1 | cut -d/ -f1)
+	if [ -n "$first_ip" ] && [ "$first_ip" != "(none)" ]; then
+		echo "$pubkey|$first_ip"
+	fi
+done`
+
+// discoverVPNClients returns the VPN clients peered with node, the peers
+// outside the cluster node range of subnet. Clients can't be discovered when
+// the node can't be read, so none are returned then.
+func discoverVPNClients(node NodeInfo, access nodeSSHAccess, subnet *config.WireGuardSubnet) []VPNPeerInfo {
+	output, err := access.run(node, 10, listVPNPeersScript)
+	if err != nil {
+		return nil
+	}
+	return parseVPNClientPeers(string(output), subnet)
+}
+
+// parseVPNClientPeers parses the output of listVPNPeersScript, skipping
+// cluster nodes (.10-.99 of subnet are reserved for the cluster)
+func parseVPNClientPeers(output string, subnet *config.WireGuardSubnet) []VPNPeerInfo {
+	var peers []VPNPeerInfo
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		parts := strings.Split(line, "|")
+		if len(parts) != 2 {
+			continue
+		}
+		peerKey := strings.TrimSpace(parts[0])
+		peerIP := strings.TrimSpace(parts[1])
+		if peerIP == "" || peerIP == "(none)" {
+			continue
+		}
+
+		if subnet.IsNodeIP(peerIP) {
+			continue
+		}
+
+		peers = append(peers, VPNPeerInfo{
+			PublicKey:  peerKey,
+			VPNAddress: peerIP,
+		})
+	}
+	return peers
+}
+
+// vpnSubnet returns the WireGuard subnet of a stack from its outputs, falling
+// back to the default subnet for stacks deployed before it was exported
+func vpnSubnet(outputs auto.OutputMap) *config.WireGuardSubnet {
+	wg := &config.WireGuardConfig{}
+	if output, ok := outputs["wireguard_network"]; ok {
+		wg.SubnetCIDR, _ = output.Value.(string)
 	}
+	return wg.ParsedSubnet()
+}
 
-	return publicKey, nil
+// checkClientVPNIP checks that ip is in the client range of subnet, so a
+// custom --vpn-ip can't take the address of the bastion or a cluster node
+func checkClientVPNIP(ip string, subnet *config.WireGuardSubnet) error {
+	host, ok := subnet.HostOffset(ip)
+	if !ok || host < config.WireGuardFirstClientHost || host > subnet.LastHost() {
+		return fmt.Errorf("VPN IP %s is outside the client range %s-%s of the VPN subnet %s",
+			ip, subnet.HostIP(config.WireGuardFirstClientHost), subnet.HostIP(subnet.LastHost()), subnet)
+	}
+	return nil
+}
+
+// nextClientIP returns the first VPN client IP of subnet, from .100 to its
+// last host, that no existing peer uses
+func nextClientIP(existingPeers []VPNPeerInfo, subnet *config.WireGuardSubnet) (string, error) {
+	usedIPs := make(map[string]bool)
+	for _, peer := range existingPeers {
+		usedIPs[peer.VPNAddress] = true
+	}
+	return nextFreeVPNIP(usedIPs, subnet, config.WireGuardFirstClientHost, subnet.LastHost())
+}
+
+// nextFreeVPNIP returns the first address at host offsets first-last of
+// subnet that is not in usedIPs
+func nextFreeVPNIP(usedIPs map[string]bool, subnet *config.WireGuardSubnet, first, last int) (string, error) {
+	for i := first; i <= last; i++ {
+		candidateIP := subnet.HostIP(i)
+		if !usedIPs[candidateIP] {
+			return candidateIP, nil
+		}
+	}
+	return "", fmt.Errorf("no available VPN IPs in range %s-%s", subnet.HostIP(first), subnet.HostIP(last))
 }
 
-// generateClientConfig generates a complete WireGuard client configuration
-func generateClientConfig(privateKey string, clientIP string, peerLabel string, nodes []NodeInfo, existingPeers []VPNPeerInfo, sshKeyPath string, bastionEnabled bool, bastionIP string) string {
+// generateClientConfig generates a complete WireGuard client configuration.
+// Node peers route 10.0.0.0/8 when broadRoute is set, otherwise routes. Node
+// peers get presharedKey when it is set; the bastion and other clients only
+// when pskPeers holds their public key, since both ends must use the same key.
+func generateClientConfig(access nodeSSHAccess, privateKey string, clientIP string, subnet *config.WireGuardSubnet, peerLabel string, presharedKey string, nodes []NodeInfo, existingPeers []VPNPeerInfo, pskPeers map[string]bool, broadRoute bool, routes []string) string {
 	labelComment := ""
 	if peerLabel != "" {
 		labelComment = fmt.Sprintf("# Peer Label: %s\n", peerLabel)
 	}
+	pskLine := ""
+	if presharedKey != "" {
+		pskLine = fmt.Sprintf("PresharedKey = %s\n", presharedKey)
+	}
 
 	config := fmt.Sprintf(`[Interface]
 # WireGuard Client Configuration
 # Generated by sloth-kubernetes CLI
 %sPrivateKey = %s
-Address = %s/24
+Address = %s/%d
 DNS = 1.1.1.1
 
 # Post-connection script (optional)
 # PostUp = echo "Connected to Kubernetes cluster VPN"
 # PreDown = echo "Disconnecting from cluster VPN"
 
-`, labelComment, privateKey, clientIP)
+`, labelComment, privateKey, clientIP, subnet.PrefixLen())
+
+	allowedIPs := clientNodeAllowedIPs(nodes, broadRoute, routes)
 
 	// Add each cluster node as a peer
 	for _, node := range nodes {
@@ -2053,7 +2430,7 @@ DNS = 1.1.1.1
 		}
 
 		// Fetch actual public key from node
-		publicKey, err := fetchNodePublicKey(node, sshKeyPath, bastionEnabled, bastionIP)
+		publicKey, err := fetchNodePublicKey(node, access)
 		if err != nil {
 			// If we can't fetch the key, use placeholder and add a warning
 			color.Yellow(fmt.Sprintf("  ⚠️  Failed to fetch public key from %s: %v", node.Name, err))
@@ -2064,63 +2441,114 @@ DNS = 1.1.1.1
 [Peer]
 # %s (%s)
 PublicKey = %s
-Endpoint = %s:51820
-AllowedIPs = %s/32, 10.0.0.0/8
+%sEndpoint = %s:51820
+AllowedIPs = %s
 PersistentKeepalive = 25
-`, node.Name, node.Provider, publicKey, node.PublicIP, node.WireGuardIP)
+`, node.Name, node.Provider, publicKey, pskLine, node.PublicIP, allowedIPs[node.Name])
 	}
 
 	// Add existing VPN clients as peers for full mesh
-	// Special handling: if bastion is in existingPeers (VPN IP 10.8.0.5), add it with endpoint
+	// Special handling: if bastion is in existingPeers (VPN IP .5 of the subnet), add it with endpoint
 	for _, peer := range existingPeers {
-		// Check if this peer is the bastion (VPN IP 10.8.0.5)
-		if peer.VPNAddress == "10.8.0.5" && bastionEnabled && bastionIP != "" {
+		peerPSK := ""
+		if pskPeers[peer.PublicKey] {
+			peerPSK = pskLine
+		}
+
+		// Check if this peer is the bastion
+		if peer.VPNAddress == subnet.BastionIP() && access.viaBastion() {
 			// Add bastion with endpoint for direct connectivity
 			config += fmt.Sprintf(`
 [Peer]
 # Bastion Host
 PublicKey = %s
-Endpoint = %s:51820
+%sEndpoint = %s:51820
 AllowedIPs = %s/32, 192.168.0.0/16
 PersistentKeepalive = 25
-`, peer.PublicKey, bastionIP, peer.VPNAddress)
+`, peer.PublicKey, peerPSK, access.BastionIP, peer.VPNAddress)
 		} else {
 			// Regular external VPN client without endpoint
 			config += fmt.Sprintf(`
 [Peer]
 # External VPN Client
 PublicKey = %s
-AllowedIPs = %s/32
+%sAllowedIPs = %s/32
 PersistentKeepalive = 25
-`, peer.PublicKey, peer.VPNAddress)
+`, peer.PublicKey, peerPSK, peer.VPNAddress)
 		}
 	}
 
 	return config
 }
 
-// detectOS detects the operating system
-func detectOS() string {
-	cmd := exec.Command("uname", "-s")
-	output, err := cmd.Output()
-	if err != nil {
-		return "unknown"
+// clientRoutes returns the networks a VPN client needs to reach the cluster:
+// the WireGuard subnet and the pod and service CIDRs, read from the stack
+// outputs and falling back to the RKE2 defaults
+func clientRoutes(outputs auto.OutputMap) []string {
+	candidates := []struct {
+		output   string
+		fallback string
+	}{
+		{"wireguard_network", config.DefaultWireGuardSubnet},
+		{"pod_cidr", defaultPodCIDR},
+		{"service_cidr", defaultServiceCIDR},
 	}
 
-	osName := string(output)
-	// Remove trailing newline if present
-	if len(osName) > 0 && osName[len(osName)-1] == '\n' {
-		osName = osName[:len(osName)-1]
+	var routes []string
+	seen := make(map[string]bool)
+	for _, c := range candidates {
+		route := c.fallback
+		if output, ok := outputs[c.output]; ok {
+			if value, ok := output.Value.(string); ok {
+				if _, network, err := net.ParseCIDR(value); err == nil {
+					route = network.String()
+				}
+			}
+		}
+		if !seen[route] {
+			seen[route] = true
+			routes = append(routes, route)
+		}
 	}
+	return routes
+}
 
-	switch osName {
-	case "Darwin":
-		return "darwin"
-	case "Linux":
-		return "linux"
-	default:
-		return "unknown"
+// clientNodeAllowedIPs returns the AllowedIPs of each cluster node peer of a
+// client config, keyed by node name. With broadRoute every node also claims
+// 10.0.0.0/8. Otherwise routes go to a single gateway node, preferably a
+// server, since WireGuard assigns each prefix to only one peer.
+func clientNodeAllowedIPs(nodes []NodeInfo, broadRoute bool, routes []string) map[string]string {
+	gateway := ""
+	if !broadRoute {
+		for _, node := range nodes {
+			if node.WireGuardIP == "" {
+				continue
+			}
+			if gateway == "" {
+				gateway = node.Name
+			}
+			if isControlPlaneNode(node) {
+				gateway = node.Name
+				break
+			}
+		}
+	}
+
+	allowed := make(map[string]string, len(nodes))
+	for _, node := range nodes {
+		if node.WireGuardIP == "" {
+			continue
+		}
+		ips := []string{node.WireGuardIP + "/32"}
+		switch {
+		case broadRoute:
+			ips = append(ips, broadClientRoute)
+		case node.Name == gateway:
+			ips = append(ips, routes...)
+		}
+		allowed[node.Name] = strings.Join(ips, ", ")
 	}
+	return allowed
 }
 
 // formatBytes formats bytes into human-readable format
@@ -0,0 +1,595 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/term"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+var (
+	vpnBackupOutput     string
+	vpnBackupInput      string
+	vpnBackupPassphrase string
+)
+
+var vpnBackupConfigsCmd = &cobra.Command{
+	Use:   "backup-configs [stack-name]",
+	Short: "Bundle every node and client WireGuard config into a tarball",
+	Long: `Collect each node's wg0.conf and key pair, and the client configs saved
+locally for the stack, into a gzipped tarball. Private keys are encrypted under
+a passphrase; everything else stays readable for audits. Restore the bundle with
+'vpn restore-configs'.`,
+	Example: `  # Back up the mesh configs
+  sloth-kubernetes vpn backup-configs production --output configs.tar.gz`,
+	RunE: runVPNBackupConfigs,
+}
+
+var vpnRestoreConfigsCmd = &cobra.Command{
+	Use:   "restore-configs [stack-name]",
+	Short: "Push WireGuard configs from a backup back to the nodes",
+	Long: `Restore the wg0.conf and key pair of every node in a 'vpn backup-configs'
+tarball, and its client configs to the stack's output directory. Each node keeps
+a wg0.conf.backup-* of the config it had. Nodes in the backup that are no longer
+in the stack are skipped.`,
+	Example: `  # Restore after a node lost its disk
+  sloth-kubernetes vpn restore-configs production --input configs.tar.gz`,
+	RunE: runVPNRestoreConfigs,
+}
+
+func init() {
+	vpnCmd.AddCommand(vpnBackupConfigsCmd)
+	vpnCmd.AddCommand(vpnRestoreConfigsCmd)
+
+	vpnBackupConfigsCmd.Flags().StringVar(&vpnBackupOutput, "output", "", "Tarball to write (default: wireguard-configs-<stack>.tar.gz)")
+	vpnBackupConfigsCmd.Flags().StringVar(&vpnBackupPassphrase, "passphrase", "", "Passphrase encrypting the private keys (prompted when not set)")
+	vpnRestoreConfigsCmd.Flags().StringVar(&vpnBackupInput, "input", "", "Tarball written by backup-configs")
+	vpnRestoreConfigsCmd.Flags().StringVar(&vpnBackupPassphrase, "passphrase", "", "Passphrase the private keys were encrypted with (prompted when not set)")
+	_ = vpnRestoreConfigsCmd.MarkFlagRequired("input")
+}
+
+// wgBackupVersion is the bundle format written by backup-configs
+const wgBackupVersion = 1
+
+// wgSealedPrefix marks a private key encrypted in a bundle
+const wgSealedPrefix = "sealed:"
+
+// wgReadBackupScript prints the node's wg0.conf, private key and public key,
+// separated by marker lines
+const wgReadBackupScript = `sudo cat /etc/wireguard/wg0.conf && echo "--- privatekey ---" && sudo cat /etc/wireguard/privatekey && echo "--- publickey ---" && sudo cat /etc/wireguard/publickey`
+
+// wgBackup is the content of a WireGuard config bundle
+type wgBackup struct {
+	Stack     string
+	CreatedAt time.Time
+	Nodes     []wgBackupNode
+	Clients   map[string]string // File name in the output directory to client config
+}
+
+// wgBackupNode is the WireGuard state of one node
+type wgBackupNode struct {
+	Name        string `json:"name"`
+	WireGuardIP string `json:"wireguardIp"`
+	Config      string `json:"-"`
+	PrivateKey  string `json:"-"`
+	PublicKey   string `json:"-"`
+}
+
+// wgBackupManifest is manifest.json of a bundle
+type wgBackupManifest struct {
+	Version   int            `json:"version"`
+	Stack     string         `json:"stack"`
+	CreatedAt time.Time      `json:"createdAt"`
+	Salt      string         `json:"salt"`
+	Nodes     []wgBackupNode `json:"nodes"`
+	Clients   []string       `json:"clients"`
+}
+
+// wgKeySealer encrypts private keys with AES-GCM under a key derived from
+// the passphrase with scrypt
+type wgKeySealer struct {
+	aead cipher.AEAD
+}
+
+// newWGKeySealer derives the sealing key of passphrase and salt
+func newWGKeySealer(passphrase string, salt []byte) (*wgKeySealer, error) {
+	if passphrase == "" {
+		return nil, fmt.Errorf("a passphrase is required to encrypt private keys")
+	}
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, 32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &wgKeySealer{aead: aead}, nil
+}
+
+// Seal encrypts a private key
+func (s *wgKeySealer) Seal(plain string) (string, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := s.aead.Seal(nonce, nonce, []byte(plain), nil)
+	return wgSealedPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Open decrypts a private key sealed by Seal
+func (s *wgKeySealer) Open(sealed string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(sealed, wgSealedPrefix))
+	if err != nil || len(data) < s.aead.NonceSize() {
+		return "", fmt.Errorf("malformed encrypted key")
+	}
+	nonce, ciphertext := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+	plain, err := s.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("wrong passphrase or corrupted backup")
+	}
+	return string(plain), nil
+}
+
+// mapConfigPrivateKeys rewrites the value of every PrivateKey line of a
+// WireGuard config with fn
+func mapConfigPrivateKeys(conf string, fn func(string) (string, error)) (string, error) {
+	lines := strings.Split(conf, "\n")
+	for i, line := range lines {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) != "PrivateKey" {
+			continue
+		}
+		mapped, err := fn(strings.TrimSpace(value))
+		if err != nil {
+			return "", err
+		}
+		lines[i] = "PrivateKey = " + mapped
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// writeWGBackup writes backup as a gzipped tarball, private keys sealed under
+// passphrase
+func writeWGBackup(w io.Writer, backup wgBackup, passphrase string) error {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	sealer, err := newWGKeySealer(passphrase, salt)
+	if err != nil {
+		return err
+	}
+
+	manifest := wgBackupManifest{
+		Version:   wgBackupVersion,
+		Stack:     backup.Stack,
+		CreatedAt: backup.CreatedAt,
+		Salt:      base64.StdEncoding.EncodeToString(salt),
+		Nodes:     backup.Nodes,
+	}
+	for name := range backup.Clients {
+		manifest.Clients = append(manifest.Clients, name)
+	}
+	sort.Strings(manifest.Clients)
+
+	files := map[string]string{}
+	for _, node := range backup.Nodes {
+		conf, err := mapConfigPrivateKeys(node.Config, sealer.Seal)
+		if err != nil {
+			return err
+		}
+		privateKey, err := sealer.Seal(strings.TrimSpace(node.PrivateKey))
+		if err != nil {
+			return err
+		}
+		files[path.Join("nodes", node.Name, "wg0.conf")] = conf
+		files[path.Join("nodes", node.Name, "privatekey")] = privateKey + "\n"
+		files[path.Join("nodes", node.Name, "publickey")] = node.PublicKey
+	}
+	for name, conf := range backup.Clients {
+		sealed, err := mapConfigPrivateKeys(conf, sealer.Seal)
+		if err != nil {
+			return err
+		}
+		files[path.Join("clients", name)] = sealed
+	}
+
+	manifestJSON, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	write := func(name string, data []byte) error {
+		header := &tar.Header{Name: name, Mode: 0600, Size: int64(len(data)), ModTime: backup.CreatedAt}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	if err := write("manifest.json", manifestJSON); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := write(name, []byte(files[name])); err != nil {
+			return fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	return gz.Close()
+}
+
+// validClientConfigName reports whether name is a plain *.conf file name,
+// with no directory that could take it out of the output directory
+func validClientConfigName(name string) bool {
+	return name == filepath.Base(name) && !strings.ContainsAny(name, `/\`) &&
+		strings.HasSuffix(name, ".conf") && name != ".conf"
+}
+
+// readWGBackup reads a tarball written by writeWGBackup and opens its
+// private keys with passphrase
+func readWGBackup(r io.Reader, passphrase string) (wgBackup, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return wgBackup{}, fmt.Errorf("not a WireGuard config backup: %w", err)
+	}
+	defer gz.Close()
+
+	files := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return wgBackup{}, fmt.Errorf("failed to read backup: %w", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return wgBackup{}, fmt.Errorf("failed to read backup: %w", err)
+		}
+		files[header.Name] = string(data)
+	}
+
+	var manifest wgBackupManifest
+	if err := json.Unmarshal([]byte(files["manifest.json"]), &manifest); err != nil {
+		return wgBackup{}, fmt.Errorf("backup has no valid manifest.json: %w", err)
+	}
+	if manifest.Version != wgBackupVersion {
+		return wgBackup{}, fmt.Errorf("unsupported backup version %d", manifest.Version)
+	}
+	salt, err := base64.StdEncoding.DecodeString(manifest.Salt)
+	if err != nil {
+		return wgBackup{}, fmt.Errorf("backup has an invalid salt: %w", err)
+	}
+	sealer, err := newWGKeySealer(passphrase, salt)
+	if err != nil {
+		return wgBackup{}, err
+	}
+
+	backup := wgBackup{Stack: manifest.Stack, CreatedAt: manifest.CreatedAt, Clients: map[string]string{}}
+	for _, node := range manifest.Nodes {
+		dir := path.Join("nodes", node.Name)
+		if node.Config, err = mapConfigPrivateKeys(files[path.Join(dir, "wg0.conf")], sealer.Open); err != nil {
+			return wgBackup{}, fmt.Errorf("%s: %w", node.Name, err)
+		}
+		if node.PrivateKey, err = sealer.Open(strings.TrimSpace(files[path.Join(dir, "privatekey")])); err != nil {
+			return wgBackup{}, fmt.Errorf("%s: %w", node.Name, err)
+		}
+		node.PublicKey = files[path.Join(dir, "publickey")]
+		backup.Nodes = append(backup.Nodes, node)
+	}
+	for _, name := range manifest.Clients {
+		// The name becomes a file name in the stack's output directory
+		if !validClientConfigName(name) {
+			return wgBackup{}, fmt.Errorf("backup has an invalid client config name %q", name)
+		}
+		conf, err := mapConfigPrivateKeys(files[path.Join("clients", name)], sealer.Open)
+		if err != nil {
+			return wgBackup{}, fmt.Errorf("client %s: %w", name, err)
+		}
+		backup.Clients[name] = conf
+	}
+	return backup, nil
+}
+
+// parseWGBackupOutput parses the output of wgReadBackupScript
+func parseWGBackupOutput(node NodeInfo, output string) (wgBackupNode, error) {
+	conf, keys, ok := strings.Cut(output, "--- privatekey ---\n")
+	if !ok {
+		return wgBackupNode{}, fmt.Errorf("missing private key")
+	}
+	privateKey, publicKey, ok := strings.Cut(keys, "--- publickey ---\n")
+	if !ok {
+		return wgBackupNode{}, fmt.Errorf("missing public key")
+	}
+	if strings.TrimSpace(privateKey) == "" || !strings.Contains(conf, "[Interface]") {
+		return wgBackupNode{}, fmt.Errorf("incomplete WireGuard config")
+	}
+	return wgBackupNode{
+		Name:        node.Name,
+		WireGuardIP: node.WireGuardIP,
+		Config:      conf,
+		PrivateKey:  strings.TrimSpace(privateKey),
+		PublicKey:   publicKey,
+	}, nil
+}
+
+// localClientConfigs returns the WireGuard client configs saved in the
+// stack's output directory, by file name
+func localClientConfigs(stack string) (map[string]string, error) {
+	dir, err := stackArtifactDir(stack)
+	if err != nil {
+		return nil, err
+	}
+	matches, err := filepath.Glob(filepath.Join(dir, "*.conf"))
+	if err != nil {
+		return nil, err
+	}
+
+	clients := map[string]string{}
+	for _, match := range matches {
+		data, err := os.ReadFile(match)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", match, err)
+		}
+		if strings.Contains(string(data), "[Interface]") {
+			clients[filepath.Base(match)] = string(data)
+		}
+	}
+	return clients, nil
+}
+
+// generateWGRestoreScript writes a node's key pair and wg0.conf, keeping a
+// backup of the config it replaces, and applies it
+func generateWGRestoreScript(node wgBackupNode) string {
+	return fmt.Sprintf(`set -e
+sudo mkdir -p /etc/wireguard
+sudo cp /etc/wireguard/wg0.conf /etc/wireguard/wg0.conf.backup-$(date +%%Y%%m%%d-%%H%%M%%S) 2>/dev/null || true
+
+sudo tee /etc/wireguard/privatekey > /dev/null << 'WGEOF'
+%s
+WGEOF
+sudo tee /etc/wireguard/publickey > /dev/null << 'WGEOF'
+%s
+WGEOF
+sudo tee /etc/wireguard/wg0.conf > /dev/null << 'WGEOF'
+%s
+WGEOF
+sudo chmod 600 /etc/wireguard/privatekey /etc/wireguard/wg0.conf
+
+if sudo wg show wg0 > /dev/null 2>&1; then
+    sudo wg-quick strip wg0 | sudo wg syncconf wg0 /dev/stdin
+else
+    sudo wg-quick up wg0
+fi
+sudo systemctl enable wg-quick@wg0 2>/dev/null || true
+`, node.PrivateKey, strings.TrimRight(node.PublicKey, "\n"), strings.TrimRight(node.Config, "\n"))
+}
+
+// readBackupPassphrase returns --passphrase, or prompts for it on a
+// terminal, twice when confirm is set
+func readBackupPassphrase(confirm bool) (string, error) {
+	if vpnBackupPassphrase != "" {
+		return vpnBackupPassphrase, nil
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return "", fmt.Errorf("--passphrase is required when not running in a terminal")
+	}
+
+	fmt.Fprint(os.Stderr, "Passphrase: ")
+	passphrase, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read passphrase: %w", err)
+	}
+	if confirm {
+		fmt.Fprint(os.Stderr, "Repeat passphrase: ")
+		again, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase: %w", err)
+		}
+		if string(again) != string(passphrase) {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+	return string(passphrase), nil
+}
+
+func runVPNBackupConfigs(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	output := vpnBackupOutput
+	if output == "" {
+		output = fmt.Sprintf("wireguard-configs-%s.tar.gz", stack)
+	}
+
+	passphrase, err := readBackupPassphrase(true)
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("💾 Back Up WireGuard Configs - Stack: %s", stack))
+
+	nodes, access, err := loadStackNodes(ctx, stack)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("no nodes found in stack")
+	}
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	printInfo("Reading WireGuard configs from cluster nodes...")
+
+	// A backup missing nodes would not recover them, so every node must be read
+	backup := wgBackup{Stack: stack, CreatedAt: time.Now().UTC()}
+	var unreadable []string
+	for _, node := range nodes {
+		out, err := access.runScript(node, 10, wgReadBackupScript)
+		if err != nil {
+			unreadable = append(unreadable, fmt.Sprintf("%s (%v)", node.Name, err))
+			continue
+		}
+		state, err := parseWGBackupOutput(node, string(out))
+		if err != nil {
+			unreadable = append(unreadable, fmt.Sprintf("%s (%v)", node.Name, err))
+			continue
+		}
+		backup.Nodes = append(backup.Nodes, state)
+		printSuccess(fmt.Sprintf("  ✓ %s", node.Name))
+	}
+	if len(unreadable) > 0 {
+		return errs.Mark(fmt.Errorf("failed to read the WireGuard config of: %s", strings.Join(unreadable, ", ")), errs.ErrNodeUnreachable)
+	}
+
+	if backup.Clients, err = localClientConfigs(stack); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := writeWGBackup(&buf, backup, passphrase); err != nil {
+		return err
+	}
+	if err := os.WriteFile(output, buf.Bytes(), 0600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+
+	fmt.Println()
+	printSuccess(fmt.Sprintf("Backed up %d node config(s) and %d client config(s) to %s", len(backup.Nodes), len(backup.Clients), output))
+	printInfo("Private keys are encrypted; keep the passphrase to restore them")
+	return nil
+}
+
+func runVPNRestoreConfigs(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	passphrase, err := readBackupPassphrase(false)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(vpnBackupInput)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	backup, err := readWGBackup(f, passphrase)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("♻️  Restore WireGuard Configs - Stack: %s", stack))
+	printInfo(fmt.Sprintf("Backup of stack %s taken %s", backup.Stack, backup.CreatedAt.Format(time.RFC3339)))
+	if backup.Stack != stack {
+		color.Yellow(fmt.Sprintf("  ⚠️  The backup was taken from stack %s", backup.Stack))
+	}
+
+	nodes, access, err := loadStackNodes(ctx, stack)
+	if err != nil {
+		return err
+	}
+	byName := make(map[string]NodeInfo, len(nodes))
+	for _, node := range nodes {
+		byName[node.Name] = node
+	}
+
+	var targets []wgBackupNode
+	for _, node := range backup.Nodes {
+		if _, ok := byName[node.Name]; !ok {
+			color.Yellow(fmt.Sprintf("  ⚠️  %s is not in the stack, skipped", node.Name))
+			continue
+		}
+		targets = append(targets, node)
+	}
+
+	if !autoApprove && !confirm(fmt.Sprintf("Restore WireGuard configs on %d node(s) and %d client config(s)?", len(targets), len(backup.Clients))) {
+		printWarning("Restore cancelled")
+		return nil
+	}
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	printInfo("Restoring WireGuard configs...")
+
+	failed := 0
+	for _, target := range targets {
+		out, err := access.runScript(byName[target.Name], 10, generateWGRestoreScript(target))
+		if err != nil {
+			color.Yellow(fmt.Sprintf("  ⚠️  Failed to restore %s: %v (output: %s)", target.Name, err, strings.TrimSpace(string(out))))
+			failed++
+			continue
+		}
+		printSuccess(fmt.Sprintf("  ✓ %s", target.Name))
+	}
+
+	for name, conf := range backup.Clients {
+		configPath, err := artifactPath(stack, name)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(configPath, []byte(conf), 0600); err != nil {
+			return fmt.Errorf("failed to write %s: %w", configPath, err)
+		}
+		printSuccess(fmt.Sprintf("  ✓ Client config %s", configPath))
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		return fmt.Errorf("%d of %d node(s) failed to restore", failed, len(targets))
+	}
+	printSuccess(fmt.Sprintf("Restored %d node config(s) and %d client config(s)", len(targets), len(backup.Clients)))
+	return nil
+}
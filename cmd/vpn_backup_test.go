@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testWGBackup() wgBackup {
+	return wgBackup{
+		Stack:     "production",
+		CreatedAt: time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Nodes: []wgBackupNode{
+			{
+				Name:        "master-1",
+				WireGuardIP: "10.8.0.10",
+				Config:      "[Interface]\nPrivateKey = bWFzdGVyLXByaXZhdGUta2V5\nAddress = 10.8.0.10/24\n\n# Peer: worker-1\n[Peer]\nPublicKey = d29ya2VyLXB1YmxpYy1rZXk=\n",
+				PrivateKey:  "bWFzdGVyLXByaXZhdGUta2V5",
+				PublicKey:   "bWFzdGVyLXB1YmxpYy1rZXk=\n",
+			},
+		},
+		Clients: map[string]string{
+			"wg0-client.conf": "[Interface]\nPrivateKey = Y2xpZW50LXByaXZhdGUta2V5\nAddress = 10.8.0.100/24\n",
+		},
+	}
+}
+
+func TestWGBackup_RoundTrip(t *testing.T) {
+	backup := testWGBackup()
+
+	var buf bytes.Buffer
+	if err := writeWGBackup(&buf, backup, "correct horse"); err != nil {
+		t.Fatalf("writeWGBackup: %v", err)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"bWFzdGVyLXByaXZhdGUta2V5", "Y2xpZW50LXByaXZhdGUta2V5"} {
+		if strings.Contains(string(archive), key) {
+			t.Errorf("Expected private key %s to be encrypted in the archive", key)
+		}
+	}
+	for _, name := range []string{"manifest.json", "nodes/master-1/wg0.conf", "nodes/master-1/privatekey", "clients/wg0-client.conf", "# Peer: worker-1"} {
+		if !strings.Contains(string(archive), name) {
+			t.Errorf("Expected archive to contain %q", name)
+		}
+	}
+
+	restored, err := readWGBackup(bytes.NewReader(buf.Bytes()), "correct horse")
+	if err != nil {
+		t.Fatalf("readWGBackup: %v", err)
+	}
+	if !reflect.DeepEqual(restored, backup) {
+		t.Errorf("Round trip mismatch:\ngot  %+v\nwant %+v", restored, backup)
+	}
+}
+
+func TestWGBackup_WrongPassphrase(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeWGBackup(&buf, testWGBackup(), "correct horse"); err != nil {
+		t.Fatalf("writeWGBackup: %v", err)
+	}
+
+	_, err := readWGBackup(bytes.NewReader(buf.Bytes()), "battery staple")
+	if err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("Expected wrong passphrase error, got %v", err)
+	}
+
+	if err := writeWGBackup(&buf, testWGBackup(), ""); err == nil {
+		t.Error("Expected an empty passphrase to be rejected")
+	}
+}
+
+func TestReadWGBackup_RejectsClientPathTraversal(t *testing.T) {
+	for _, name := range []string{"../../.ssh/authorized_keys.conf", "/etc/wireguard/wg0.conf", "sub/wg0-client.conf", "kubeconfig", ".."} {
+		backup := testWGBackup()
+		backup.Clients = map[string]string{name: "[Interface]\nAddress = 10.8.0.100/24\n"}
+
+		var buf bytes.Buffer
+		if err := writeWGBackup(&buf, backup, "correct horse"); err != nil {
+			t.Fatalf("writeWGBackup: %v", err)
+		}
+		_, err := readWGBackup(bytes.NewReader(buf.Bytes()), "correct horse")
+		if err == nil || !strings.Contains(err.Error(), "invalid client config name") {
+			t.Errorf("Expected client name %q to be rejected, got %v", name, err)
+		}
+	}
+}
+
+func TestParseWGBackupOutput(t *testing.T) {
+	node := NodeInfo{Name: "worker-1", WireGuardIP: "10.8.0.11"}
+	out := "[Interface]\nPrivateKey = a2V5\n--- privatekey ---\na2V5\n--- publickey ---\ncHVi\n"
+
+	state, err := parseWGBackupOutput(node, out)
+	if err != nil {
+		t.Fatalf("parseWGBackupOutput: %v", err)
+	}
+	if state.Config != "[Interface]\nPrivateKey = a2V5\n" || state.PrivateKey != "a2V5" || state.PublicKey != "cHVi\n" {
+		t.Errorf("Unexpected node state: %+v", state)
+	}
+
+	if _, err := parseWGBackupOutput(node, "[Interface]\n"); err == nil {
+		t.Error("Expected output without keys to fail")
+	}
+}
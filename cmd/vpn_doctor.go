@@ -0,0 +1,435 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/spf13/cobra"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+// vpnDoctorScript prints the WireGuard, forwarding and firewall state of a
+// node in sections. The dump skips its interface line, which holds the
+// private key.
+const vpnDoctorScript = `echo "--- interface ---"
+ip link show wg0 >/dev/null 2>&1 && echo present || echo missing
+echo "--- public-key ---"
+sudo wg show wg0 public-key 2>/dev/null
+echo "--- dump ---"
+sudo wg show wg0 dump 2>/dev/null | tail -n +2
+echo "--- forwarding ---"
+sysctl -n net.ipv4.ip_forward 2>/dev/null
+echo "--- ufw ---"
+sudo ufw status 2>/dev/null
+echo "--- now ---"
+date +%s`
+
+// vpnFirewallState is whether a node's ufw lets WireGuard traffic in
+type vpnFirewallState int
+
+const (
+	vpnFirewallUnknown  vpnFirewallState = iota // ufw not installed or not readable
+	vpnFirewallInactive                         // ufw installed but disabled
+	vpnFirewallOpen                             // ufw allows the WireGuard port
+	vpnFirewallBlocked                          // ufw active without a rule for the port
+)
+
+// vpnDoctorNode is what vpn doctor read from one node
+type vpnDoctorNode struct {
+	Node       NodeInfo
+	Reachable  bool
+	Interface  bool
+	PublicKey  string
+	Peers      []wgDumpPeer
+	Forwarding string // value of net.ipv4.ip_forward, empty when unknown
+	Firewall   vpnFirewallState
+	Now        int64
+}
+
+// parseVPNDoctorOutput parses the output of vpnDoctorScript
+func parseVPNDoctorOutput(node NodeInfo, output string) vpnDoctorNode {
+	state := vpnDoctorNode{Node: node, Reachable: true}
+
+	sections := make(map[string]string)
+	current := ""
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "--- ") && strings.HasSuffix(line, " ---") {
+			current = strings.TrimSuffix(strings.TrimPrefix(line, "--- "), " ---")
+			continue
+		}
+		if current != "" {
+			sections[current] += line + "\n"
+		}
+	}
+
+	state.Interface = strings.TrimSpace(sections["interface"]) == "present"
+	state.PublicKey = strings.TrimSpace(sections["public-key"])
+	state.Peers = parseWGDump(sections["dump"])
+	state.Forwarding = strings.TrimSpace(sections["forwarding"])
+	state.Firewall = parseUFWStatus(sections["ufw"], wgPort)
+	state.Now, _ = strconv.ParseInt(strings.TrimSpace(sections["now"]), 10, 64)
+	return state
+}
+
+// parseUFWStatus tells from `ufw status` whether UDP port is let in
+func parseUFWStatus(output string, port int) vpnFirewallState {
+	output = strings.TrimSpace(output)
+	switch {
+	case output == "":
+		return vpnFirewallUnknown
+	case strings.Contains(output, "Status: inactive"):
+		return vpnFirewallInactive
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || !strings.HasPrefix(fields[1], "ALLOW") {
+			continue
+		}
+		if fields[0] == fmt.Sprintf("%d/udp", port) || fields[0] == strconv.Itoa(port) {
+			return vpnFirewallOpen
+		}
+	}
+	return vpnFirewallBlocked
+}
+
+// peer returns the peer with publicKey, or nil
+func (s vpnDoctorNode) peer(publicKey string) *wgDumpPeer {
+	if publicKey == "" {
+		return nil
+	}
+	for i := range s.Peers {
+		if s.Peers[i].PublicKey == publicKey {
+			return &s.Peers[i]
+		}
+	}
+	return nil
+}
+
+// healthy reports whether the node's WireGuard state could be read
+func (s vpnDoctorNode) healthy() bool {
+	return s.Reachable && s.Interface && s.PublicKey != ""
+}
+
+// handshakeAge describes how long ago the peer's last handshake was
+func (s vpnDoctorNode) handshakeAge(peer wgDumpPeer) string {
+	if peer.LatestHandshake == 0 {
+		return "never"
+	}
+	return (time.Duration(s.Now-peer.LatestHandshake) * time.Second).String() + " ago"
+}
+
+// handshakeFresh reports whether the peer's last handshake is recent enough
+// for the tunnel to count as active
+func (s vpnDoctorNode) handshakeFresh(peer wgDumpPeer) bool {
+	return peer.LatestHandshake != 0 && time.Duration(s.Now-peer.LatestHandshake)*time.Second <= vpnActiveHandshakeWindow
+}
+
+// allowsIP reports whether the peer's AllowedIPs hold ip as a /32
+func allowsIP(peer wgDumpPeer, ip string) bool {
+	for _, allowed := range strings.Split(peer.AllowedIPs, ",") {
+		if allowed == ip+"/32" {
+			return true
+		}
+	}
+	return false
+}
+
+// endpointMatches reports whether the peer's endpoint is one of the node's
+// addresses. A peer without an endpoint waits for the node to connect.
+func endpointMatches(peer wgDumpPeer, node NodeInfo) bool {
+	if peer.Endpoint == "" || peer.Endpoint == "(none)" {
+		return true
+	}
+	host, _, err := net.SplitHostPort(peer.Endpoint)
+	if err != nil {
+		return false
+	}
+	return host == node.PublicIP || host == node.PrivateIP || host == node.WireGuardIP
+}
+
+// Priorities of vpn doctor findings: lower values are listed first, as
+// they explain the most symptoms
+const (
+	vpnDoctorUnreachable = iota
+	vpnDoctorNoInterface
+	vpnDoctorFirewall
+	vpnDoctorMissingPeer
+	vpnDoctorAllowedIPs
+	vpnDoctorEndpoint
+	vpnDoctorNoHandshake
+	vpnDoctorForwarding
+)
+
+// vpnDiagnosis is a probable root cause found by vpn doctor with the
+// symptoms it explains
+type vpnDiagnosis struct {
+	Priority int
+	Cause    string
+	Fix      string
+	Symptoms []string
+}
+
+// vpnDiagnoser collects diagnoses keyed by cause so symptoms seen from
+// both ends of a link attach to one cause
+type vpnDiagnoser struct {
+	order []string
+	byKey map[string]*vpnDiagnosis
+}
+
+func (d *vpnDiagnoser) add(key string, priority int, cause, fix string) *vpnDiagnosis {
+	if diagnosis, ok := d.byKey[key]; ok {
+		return diagnosis
+	}
+	if d.byKey == nil {
+		d.byKey = make(map[string]*vpnDiagnosis)
+	}
+	diagnosis := &vpnDiagnosis{Priority: priority, Cause: cause, Fix: fix}
+	d.byKey[key] = diagnosis
+	d.order = append(d.order, key)
+	return diagnosis
+}
+
+// sorted returns the diagnoses by priority, then in the order found
+func (d *vpnDiagnoser) sorted() []vpnDiagnosis {
+	result := make([]vpnDiagnosis, 0, len(d.order))
+	for _, key := range d.order {
+		result = append(result, *d.byKey[key])
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Priority < result[j].Priority })
+	return result
+}
+
+// diagnoseVPN cross-references the state of every node and returns the
+// probable root causes of mesh problems, most important first. A missing
+// handshake is attributed to what the other end of the link shows, e.g. the
+// other node not having this one as a peer.
+func diagnoseVPN(stack string, states []vpnDoctorNode) []vpnDiagnosis {
+	var d vpnDiagnoser
+
+	for _, s := range states {
+		name := s.Node.Name
+		switch {
+		case !s.Reachable:
+			d.add("unreachable:"+name, vpnDoctorUnreachable, fmt.Sprintf("%s is not reachable over SSH", name),
+				"Check that the node is running and reachable (through the bastion if there is one)")
+			continue
+		case !s.Interface || s.PublicKey == "":
+			d.add("interface:"+name, vpnDoctorNoInterface, fmt.Sprintf("%s has no wg0 interface", name),
+				fmt.Sprintf("Start it with 'systemctl restart wg-quick@wg0' on %s, or run: sloth-kubernetes vpn rebuild %s", name, stack))
+			continue
+		}
+		if s.Firewall == vpnFirewallBlocked {
+			d.add("firewall:"+name, vpnDoctorFirewall, fmt.Sprintf("%s's firewall does not allow %d/udp", name, wgPort),
+				fmt.Sprintf("Run 'ufw allow %d/udp' on %s", wgPort, name))
+		}
+		if s.Forwarding == "0" {
+			d.add("forwarding:"+name, vpnDoctorForwarding, fmt.Sprintf("IP forwarding is disabled on %s", name),
+				fmt.Sprintf("Run 'sysctl -w net.ipv4.ip_forward=1' on %s and persist it in /etc/sysctl.conf", name))
+		}
+	}
+
+	for _, a := range states {
+		if !a.healthy() || a.Node.WireGuardIP == "" {
+			continue
+		}
+		for _, b := range states {
+			if a.Node.Name == b.Node.Name || !b.healthy() || b.Node.WireGuardIP == "" {
+				continue
+			}
+
+			peerB := a.peer(b.PublicKey)
+			if peerB == nil {
+				d.add("missing:"+a.Node.Name+":"+b.Node.Name, vpnDoctorMissingPeer,
+					fmt.Sprintf("%s is missing %s as a peer", a.Node.Name, b.Node.Name),
+					fmt.Sprintf("sloth-kubernetes vpn rebuild %s", stack))
+				continue
+			}
+			if !allowsIP(*peerB, b.Node.WireGuardIP) {
+				d.add("allowed:"+a.Node.Name+":"+b.Node.Name, vpnDoctorAllowedIPs,
+					fmt.Sprintf("%s routes %s to the wrong peer: AllowedIPs of %s are %s, expected %s/32", a.Node.Name, b.Node.WireGuardIP, b.Node.Name, peerB.AllowedIPs, b.Node.WireGuardIP),
+					fmt.Sprintf("sloth-kubernetes vpn rebuild %s", stack))
+			}
+			if a.handshakeFresh(*peerB) {
+				continue
+			}
+
+			symptom := fmt.Sprintf("%s has no handshake with %s (last: %s)", a.Node.Name, b.Node.Name, a.handshakeAge(*peerB))
+			var cause *vpnDiagnosis
+			switch {
+			case b.peer(a.PublicKey) == nil:
+				cause = d.add("missing:"+b.Node.Name+":"+a.Node.Name, vpnDoctorMissingPeer,
+					fmt.Sprintf("%s is missing %s as a peer", b.Node.Name, a.Node.Name),
+					fmt.Sprintf("sloth-kubernetes vpn rebuild %s", stack))
+			case b.Firewall == vpnFirewallBlocked:
+				cause = d.byKey["firewall:"+b.Node.Name]
+			case a.Firewall == vpnFirewallBlocked:
+				cause = d.byKey["firewall:"+a.Node.Name]
+			case !endpointMatches(*peerB, b.Node):
+				cause = d.add("endpoint:"+a.Node.Name+":"+b.Node.Name, vpnDoctorEndpoint,
+					fmt.Sprintf("%s has a stale endpoint %s for %s (public IP %s)", a.Node.Name, peerB.Endpoint, b.Node.Name, b.Node.PublicIP),
+					fmt.Sprintf("sloth-kubernetes vpn refresh-endpoints %s", stack))
+			case !allowsIP(*peerB, b.Node.WireGuardIP):
+				cause = d.byKey["allowed:"+a.Node.Name+":"+b.Node.Name]
+			default:
+				// Both ends look right: UDP is dropped between them
+				pair := []string{a.Node.Name, b.Node.Name}
+				sort.Strings(pair)
+				cause = d.add("handshake:"+pair[0]+":"+pair[1], vpnDoctorNoHandshake,
+					fmt.Sprintf("%s and %s are configured as peers but cannot handshake", pair[0], pair[1]),
+					fmt.Sprintf("Check that a cloud firewall or NAT does not drop %d/udp between them", wgPort))
+			}
+			cause.Symptoms = append(cause.Symptoms, symptom)
+		}
+	}
+
+	return d.sorted()
+}
+
+func runVPNDoctor(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("🩺 VPN Doctor - Stack: %s", stack))
+
+	// Create workspace with S3 support
+	workspace, err := createWorkspaceWithS3Support(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	// Use fully qualified stack name for S3 backend
+	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
+	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
+	if err != nil {
+		return selectStackError(err, stack)
+	}
+
+	outputs, err := stackOutputsWithRetry(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	nodes, err := ParseNodeOutputs(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to parse nodes: %w", err)
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("no nodes found in stack")
+	}
+
+	access := newNodeSSHAccess(stack, outputs)
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	printInfo(fmt.Sprintf("Checking %d nodes...", len(nodes)))
+
+	states := readVPNDoctorStates(sortNodesByName(nodes), access)
+	printVPNDoctorNodes(states)
+
+	diagnoses := diagnoseVPN(stack, states)
+	fmt.Println()
+	if len(diagnoses) == 0 {
+		printSuccess("No problems found: every node has wg0 up and a recent handshake with every other node")
+		return nil
+	}
+
+	printVPNDiagnoses(diagnoses)
+	return errs.Mark(fmt.Errorf("vpn doctor found %d probable problem(s)", len(diagnoses)), errs.ErrMeshDegraded)
+}
+
+// readVPNDoctorStates runs the doctor script on every node concurrently
+func readVPNDoctorStates(nodes []NodeInfo, access nodeSSHAccess) []vpnDoctorNode {
+	states := make([]vpnDoctorNode, len(nodes))
+	runParallel(len(nodes), access.concurrency(vpnConcurrency), func(i int) {
+		output, err := access.runScript(nodes[i], 10, vpnDoctorScript)
+		if err != nil {
+			states[i] = vpnDoctorNode{Node: nodes[i]}
+			return
+		}
+		states[i] = parseVPNDoctorOutput(nodes[i], string(output))
+	})
+	return states
+}
+
+// printVPNDoctorNodes prints the checks of each node and its peers'
+// handshake ages
+func printVPNDoctorNodes(states []vpnDoctorNode) {
+	names := make(map[string]string)
+	for _, s := range states {
+		if s.PublicKey != "" {
+			names[s.PublicKey] = s.Node.Name
+		}
+	}
+
+	check := func(ok bool, text string) {
+		if ok {
+			fmt.Printf("  ✓ %s\n", text)
+		} else {
+			color.Yellow(fmt.Sprintf("  ✗ %s", text))
+		}
+	}
+
+	for _, s := range states {
+		fmt.Println()
+		color.New(color.Bold).Printf("%s (%s)\n", s.Node.Name, s.Node.WireGuardIP)
+		if !s.Reachable {
+			check(false, "not reachable over SSH")
+			continue
+		}
+		check(s.Interface, "wg0 interface exists")
+		if !s.Interface {
+			continue
+		}
+
+		switch s.Forwarding {
+		case "":
+			fmt.Println("  ? IP forwarding unknown")
+		default:
+			check(s.Forwarding == "1", "IP forwarding enabled")
+		}
+
+		switch s.Firewall {
+		case vpnFirewallUnknown:
+			fmt.Println("  ? ufw not readable")
+		case vpnFirewallInactive:
+			fmt.Println("  - ufw inactive")
+		default:
+			check(s.Firewall == vpnFirewallOpen, fmt.Sprintf("ufw allows %d/udp", wgPort))
+		}
+
+		for _, peer := range s.Peers {
+			name, ok := names[peer.PublicKey]
+			if !ok {
+				name = peer.VPNIP()
+			}
+			fmt.Printf("    %-20s %-18s handshake %s\n", name, peer.AllowedIPs, s.handshakeAge(peer))
+		}
+	}
+}
+
+// printVPNDiagnoses prints the probable root causes, most important first
+func printVPNDiagnoses(diagnoses []vpnDiagnosis) {
+	color.New(color.Bold).Println("Probable root causes:")
+	for i, diagnosis := range diagnoses {
+		fmt.Println()
+		color.Yellow(fmt.Sprintf("  %d. %s", i+1, diagnosis.Cause))
+		for _, symptom := range diagnosis.Symptoms {
+			fmt.Printf("     • %s\n", symptom)
+		}
+		fmt.Printf("     → %s\n", diagnosis.Fix)
+	}
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// doctorNode builds the state of a healthy node peering with peers, whose
+// last handshakes were handshakeAge seconds ago
+func doctorNode(name, ip, publicKey string, handshakeAge int64, peers ...vpnDoctorNode) vpnDoctorNode {
+	const now = 1700000000
+	state := vpnDoctorNode{
+		Node:       NodeInfo{Name: name, WireGuardIP: ip, PublicIP: "203.0.113." + ip[len("10.8.0."):]},
+		Reachable:  true,
+		Interface:  true,
+		PublicKey:  publicKey,
+		Forwarding: "1",
+		Firewall:   vpnFirewallOpen,
+		Now:        now,
+	}
+	for _, peer := range peers {
+		state.Peers = append(state.Peers, wgDumpPeer{
+			PublicKey:       peer.PublicKey,
+			Endpoint:        peer.Node.PublicIP + ":51820",
+			AllowedIPs:      peer.Node.WireGuardIP + "/32",
+			LatestHandshake: now - handshakeAge,
+		})
+	}
+	return state
+}
+
+func TestParseVPNDoctorOutput(t *testing.T) {
+	output := `--- interface ---
+present
+--- public-key ---
+MASTERKEY=
+--- dump ---
+WORKERKEY=	(none)	203.0.113.20:51820	10.8.0.20/32	1699999990	100	200	25
+--- forwarding ---
+1
+--- ufw ---
+Status: active
+
+To                         Action      From
+--                         ------      ----
+22/tcp                     ALLOW       Anywhere
+51820/udp                  ALLOW       Anywhere
+--- now ---
+1700000000
+`
+	state := parseVPNDoctorOutput(NodeInfo{Name: "master-1"}, output)
+
+	if !state.healthy() || state.PublicKey != "MASTERKEY=" {
+		t.Errorf("Expected a healthy node with its key, got %+v", state)
+	}
+	if len(state.Peers) != 1 || state.Peers[0].AllowedIPs != "10.8.0.20/32" {
+		t.Errorf("Unexpected peers: %+v", state.Peers)
+	}
+	if state.Forwarding != "1" || state.Firewall != vpnFirewallOpen || state.Now != 1700000000 {
+		t.Errorf("Unexpected checks: %+v", state)
+	}
+	if age := state.handshakeAge(state.Peers[0]); age != "10s ago" {
+		t.Errorf("Expected handshake 10s ago, got %s", age)
+	}
+}
+
+func TestParseUFWStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   vpnFirewallState
+	}{
+		{"not installed", "", vpnFirewallUnknown},
+		{"inactive", "Status: inactive", vpnFirewallInactive},
+		{"open", "Status: active\n51820/udp   ALLOW   Anywhere\n51820/udp (v6)   ALLOW   Anywhere (v6)", vpnFirewallOpen},
+		{"blocked", "Status: active\n22/tcp   ALLOW   Anywhere\n51820/udp   DENY   Anywhere", vpnFirewallBlocked},
+	}
+	for _, tt := range tests {
+		if got := parseUFWStatus(tt.output, 51820); got != tt.want {
+			t.Errorf("%s: expected %d, got %d", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestDiagnoseVPN_Healthy(t *testing.T) {
+	master := doctorNode("master-1", "10.8.0.10", "MASTER=", 0)
+	worker := doctorNode("worker-1", "10.8.0.20", "WORKER=", 0)
+	master = doctorNode("master-1", "10.8.0.10", "MASTER=", 30, worker)
+	worker = doctorNode("worker-1", "10.8.0.20", "WORKER=", 30, master)
+
+	if diagnoses := diagnoseVPN("production", []vpnDoctorNode{master, worker}); len(diagnoses) != 0 {
+		t.Errorf("Expected no problems, got %+v", diagnoses)
+	}
+}
+
+func TestDiagnoseVPN_CorrelatesMissingPeer(t *testing.T) {
+	master := doctorNode("master-1", "10.8.0.10", "MASTER=", 0)
+	worker1 := doctorNode("worker-1", "10.8.0.20", "WORKER1=", 0)
+	worker2 := doctorNode("worker-2", "10.8.0.21", "WORKER2=", 0)
+
+	// master-1 lost worker-2; worker-2 still has master-1 but never got a handshake
+	states := []vpnDoctorNode{
+		doctorNode("master-1", "10.8.0.10", "MASTER=", 30, worker1),
+		doctorNode("worker-1", "10.8.0.20", "WORKER1=", 30, master, worker2),
+		doctorNode("worker-2", "10.8.0.21", "WORKER2=", 30, worker1),
+	}
+	states[2].Peers = append(states[2].Peers, wgDumpPeer{PublicKey: "MASTER=", Endpoint: "203.0.113.10:51820", AllowedIPs: "10.8.0.10/32"})
+
+	diagnoses := diagnoseVPN("production", states)
+	if len(diagnoses) != 1 {
+		t.Fatalf("Expected one root cause, got %+v", diagnoses)
+	}
+	if diagnoses[0].Cause != "master-1 is missing worker-2 as a peer" {
+		t.Errorf("Unexpected cause: %s", diagnoses[0].Cause)
+	}
+	if len(diagnoses[0].Symptoms) != 1 || !strings.HasPrefix(diagnoses[0].Symptoms[0], "worker-2 has no handshake with master-1") {
+		t.Errorf("Expected the missing handshake as symptom, got %v", diagnoses[0].Symptoms)
+	}
+}
+
+func TestDiagnoseVPN_PrioritizesNodeProblems(t *testing.T) {
+	master := doctorNode("master-1", "10.8.0.10", "MASTER=", 0)
+	worker := doctorNode("worker-1", "10.8.0.20", "WORKER=", 0)
+
+	master = doctorNode("master-1", "10.8.0.10", "MASTER=", 600, worker)
+	master.Forwarding = "0"
+	worker = doctorNode("worker-1", "10.8.0.20", "WORKER=", 600, master)
+	worker.Firewall = vpnFirewallBlocked
+	down := vpnDoctorNode{Node: NodeInfo{Name: "worker-2", WireGuardIP: "10.8.0.21"}}
+
+	diagnoses := diagnoseVPN("production", []vpnDoctorNode{master, worker, down})
+	var causes []string
+	for _, diagnosis := range diagnoses {
+		causes = append(causes, diagnosis.Cause)
+	}
+	want := []string{
+		"worker-2 is not reachable over SSH",
+		"worker-1's firewall does not allow 51820/udp",
+		"IP forwarding is disabled on master-1",
+	}
+	if strings.Join(causes, "|") != strings.Join(want, "|") {
+		t.Fatalf("Expected causes %v, got %v", want, causes)
+	}
+	if len(diagnoses[1].Symptoms) != 2 {
+		t.Errorf("Expected both directions of the link blamed on the firewall, got %v", diagnoses[1].Symptoms)
+	}
+}
+
+func TestDiagnoseVPN_StaleEndpoint(t *testing.T) {
+	master := doctorNode("master-1", "10.8.0.10", "MASTER=", 0)
+	worker := doctorNode("worker-1", "10.8.0.20", "WORKER=", 0)
+	master = doctorNode("master-1", "10.8.0.10", "MASTER=", 600, worker)
+	master.Peers[0].Endpoint = "198.51.100.7:51820"
+	worker = doctorNode("worker-1", "10.8.0.20", "WORKER=", 30, master)
+
+	diagnoses := diagnoseVPN("production", []vpnDoctorNode{master, worker})
+	if len(diagnoses) != 1 || !strings.Contains(diagnoses[0].Cause, "stale endpoint 198.51.100.7:51820") {
+		t.Fatalf("Expected a stale endpoint, got %+v", diagnoses)
+	}
+	if !strings.Contains(diagnoses[0].Fix, "vpn refresh-endpoints production") {
+		t.Errorf("Expected refresh-endpoints as fix, got %s", diagnoses[0].Fix)
+	}
+}
+
+func TestReadVPNDoctorStates_MarksUnreachable(t *testing.T) {
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		if sshTarget(args) == "root@203.0.113.20" {
+			return nil, errors.New("connection timed out")
+		}
+		return []byte("--- interface ---\nmissing\n"), nil
+	})
+
+	nodes := []NodeInfo{
+		{Name: "master-1", PublicIP: "203.0.113.10"},
+		{Name: "worker-1", PublicIP: "203.0.113.20"},
+	}
+	states := readVPNDoctorStates(nodes, nodeSSHAccess{KeyPath: "/tmp/key.pem"})
+	if !states[0].Reachable || states[0].Interface {
+		t.Errorf("Expected master-1 reachable without wg0, got %+v", states[0])
+	}
+	if states[1].Reachable {
+		t.Errorf("Expected worker-1 unreachable, got %+v", states[1])
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"strconv"
+	"strings"
+)
+
+// wgDumpPeer is one peer line from `wg show <iface> dump`
+type wgDumpPeer struct {
+	PublicKey       string
+	PresharedKey    string // Empty when the peer has none
+	Endpoint        string
+	AllowedIPs      string
+	LatestHandshake int64
+	RxBytes         int64
+	TxBytes         int64
+}
+
+// VPNIP returns the first allowed IP of the peer without its prefix length
+func (p wgDumpPeer) VPNIP() string {
+	first := strings.Split(p.AllowedIPs, ",")[0]
+	return strings.Split(first, "/")[0]
+}
+
+// parseWGDump parses the peer lines of `wg show <iface> dump`.
+// The interface header line (4 fields) and malformed lines are skipped.
+func parseWGDump(output string) []wgDumpPeer {
+	var peers []wgDumpPeer
+
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 8 {
+			continue
+		}
+
+		handshake, _ := strconv.ParseInt(fields[4], 10, 64)
+		rx, _ := strconv.ParseInt(fields[5], 10, 64)
+		tx, _ := strconv.ParseInt(fields[6], 10, 64)
+
+		presharedKey := fields[1]
+		if presharedKey == "(none)" {
+			presharedKey = ""
+		}
+
+		peers = append(peers, wgDumpPeer{
+			PublicKey:       fields[0],
+			PresharedKey:    presharedKey,
+			Endpoint:        fields[2],
+			AllowedIPs:      fields[3],
+			LatestHandshake: handshake,
+			RxBytes:         rx,
+			TxBytes:         tx,
+		})
+	}
+
+	return peers
+}
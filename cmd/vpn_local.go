@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// windowsTunnelName is the name of the tunnel service vpn join installs on
+// Windows; WireGuard for Windows names the service after the config file
+const windowsTunnelName = "wg0"
+
+// detectOS returns the operating system this machine runs: darwin, linux,
+// windows or unknown
+func detectOS() string {
+	return clientOS(runtime.GOOS)
+}
+
+// clientOS maps a GOOS to the operating systems vpn join and leave support
+func clientOS(goos string) string {
+	switch goos {
+	case "darwin", "linux", "windows":
+		return goos
+	default:
+		return "unknown"
+	}
+}
+
+// windowsWireGuardDir returns the installation directory of WireGuard for
+// Windows, which also holds wg.exe and the config vpn join installs
+func windowsWireGuardDir() string {
+	programFiles := os.Getenv("ProgramFiles")
+	if programFiles == "" {
+		programFiles = `C:\Program Files`
+	}
+	return programFiles + `\WireGuard`
+}
+
+// windowsTunnelConfigPath returns where vpn join installs the tunnel config
+// on Windows
+func windowsTunnelConfigPath() string {
+	return fmt.Sprintf(`%s\%s.conf`, windowsWireGuardDir(), windowsTunnelName)
+}
+
+// localWGCommand returns a wg command run on this machine: through sudo on
+// macOS and Linux, and with the wg.exe of WireGuard for Windows, which needs
+// an elevated prompt instead, on Windows
+func localWGCommand(osType string, args ...string) *exec.Cmd {
+	if osType == "windows" {
+		return exec.Command(windowsWireGuardDir()+`\wg.exe`, args...)
+	}
+	return exec.Command("sudo", append([]string{"wg"}, args...)...)
+}
+
+// localWGSetHint returns the manual wg set command for osType, without its
+// arguments
+func localWGSetHint(osType string) string {
+	if osType == "windows" {
+		return "wg.exe set"
+	}
+	return "sudo wg set"
+}
+
+// detectLocalWireGuard returns the first WireGuard interface up on this
+// machine, or "" when there is none
+func detectLocalWireGuard(osType string) string {
+	output, err := localWGCommand(osType, "show", "interfaces").Output()
+	if err != nil {
+		return ""
+	}
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[0]
+}
+
+// addLocalWGPeer adds a peer to the local WireGuard interface iface. The
+// preshared key, when set, never hits disk on macOS and Linux; wg.exe only
+// reads it from a file, which is removed right after.
+func addLocalWGPeer(osType, iface, publicKey, allowedIPs, presharedKey string) ([]byte, error) {
+	args := []string{"set", iface,
+		"peer", publicKey,
+		"allowed-ips", allowedIPs,
+		"persistent-keepalive", "25"}
+
+	if presharedKey == "" {
+		return localWGCommand(osType, args...).CombinedOutput()
+	}
+
+	if osType != "windows" {
+		cmd := localWGCommand(osType, append(args, "preshared-key", "/dev/stdin")...)
+		cmd.Stdin = strings.NewReader(presharedKey)
+		return cmd.CombinedOutput()
+	}
+
+	pskFile, err := os.CreateTemp("", "sloth-psk-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(pskFile.Name())
+	if _, err := pskFile.WriteString(presharedKey); err != nil {
+		pskFile.Close()
+		return nil, err
+	}
+	if err := pskFile.Close(); err != nil {
+		return nil, err
+	}
+	return localWGCommand(osType, append(args, "preshared-key", pskFile.Name())...).CombinedOutput()
+}
+
+// installWindowsTunnel installs the client config at configPath as the
+// wg0 tunnel service of WireGuard for Windows and starts it
+func installWindowsTunnel(configPath string) error {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to read config: %w", err)
+	}
+	if err := os.WriteFile(windowsTunnelConfigPath(), data, 0600); err != nil {
+		return fmt.Errorf("failed to copy config: %w", err)
+	}
+
+	// Replace a tunnel left by an earlier join
+	_ = exec.Command(windowsWireGuardDir()+`\wireguard.exe`, "/uninstalltunnelservice", windowsTunnelName).Run()
+
+	output, err := exec.Command(windowsWireGuardDir()+`\wireguard.exe`, "/installtunnelservice", windowsTunnelConfigPath()).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to install the tunnel service: %w (output: %s)", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// uninstallWindowsTunnel stops and removes the tunnel service vpn join
+// installed on Windows
+func uninstallWindowsTunnel() ([]byte, error) {
+	return exec.Command(windowsWireGuardDir()+`\wireguard.exe`, "/uninstalltunnelservice", windowsTunnelName).CombinedOutput()
+}
+
+// printWindowsInstallSteps prints how to install the client config at
+// configPath with WireGuard for Windows
+func printWindowsInstallSteps(configPath string) {
+	color.Cyan("To install the configuration on Windows:")
+	fmt.Println()
+	fmt.Println("  1. Install WireGuard for Windows: https://www.wireguard.com/install/")
+	fmt.Printf("  2. Click 'Import tunnel(s) from file' and select: %s\n", configPath)
+	fmt.Println("  3. Click 'Activate' to connect")
+	fmt.Println()
+	color.Cyan("Or from an elevated command prompt:")
+	fmt.Printf("  copy \"%s\" \"%s\"\n", configPath, windowsTunnelConfigPath())
+	fmt.Printf("  \"%s\\wireguard.exe\" /installtunnelservice \"%s\"\n", windowsWireGuardDir(), windowsTunnelConfigPath())
+}
+
+// printWindowsRemoveSteps prints how to stop and remove the tunnel vpn join
+// installed with WireGuard for Windows
+func printWindowsRemoveSteps() {
+	fmt.Println("  From an elevated command prompt:")
+	fmt.Printf("  \"%s\\wireguard.exe\" /uninstalltunnelservice %s\n", windowsWireGuardDir(), windowsTunnelName)
+	fmt.Printf("  del \"%s\"\n", windowsTunnelConfigPath())
+	fmt.Println("  Or click 'Deactivate' and remove the tunnel in the WireGuard app")
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestClientOS(t *testing.T) {
+	for goos, want := range map[string]string{
+		"darwin":  "darwin",
+		"linux":   "linux",
+		"windows": "windows",
+		"freebsd": "unknown",
+	} {
+		if got := clientOS(goos); got != want {
+			t.Errorf("clientOS(%q) = %q, want %q", goos, got, want)
+		}
+	}
+}
+
+func TestWindowsTunnelConfigPath(t *testing.T) {
+	t.Setenv("ProgramFiles", `D:\Apps`)
+	if got := windowsTunnelConfigPath(); got != `D:\Apps\WireGuard\wg0.conf` {
+		t.Errorf("Unexpected config path %q", got)
+	}
+
+	t.Setenv("ProgramFiles", "")
+	if got := windowsWireGuardDir(); got != `C:\Program Files\WireGuard` {
+		t.Errorf("Expected the default Program Files, got %q", got)
+	}
+}
+
+func TestLocalWGCommand(t *testing.T) {
+	t.Setenv("ProgramFiles", `C:\Program Files`)
+
+	windows := localWGCommand("windows", "show", "interfaces")
+	if windows.Path != `C:\Program Files\WireGuard\wg.exe` || strings.Join(windows.Args[1:], " ") != "show interfaces" {
+		t.Errorf("Expected wg.exe of WireGuard for Windows, got %v", windows.Args)
+	}
+
+	linux := localWGCommand("linux", "show", "interfaces")
+	if strings.Join(linux.Args, " ") != "sudo wg show interfaces" {
+		t.Errorf("Expected wg through sudo, got %v", linux.Args)
+	}
+}
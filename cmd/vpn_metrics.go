@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// handshakeActiveSeconds is how recent a handshake must be for its peer to
+// count as active: WireGuard re-handshakes every 2 minutes while traffic flows
+const handshakeActiveSeconds = 180
+
+// handshakeCommand prints the node's clock followed by the latest handshake
+// of every peer, so handshake ages do not depend on the local clock
+const handshakeCommand = "date +%s && wg show wg0 latest-handshakes"
+
+// vpnLinkResult is the result of one ping of vpn test
+type vpnLinkResult struct {
+	Source  string `json:"source"`
+	Target  string `json:"target"`
+	VPNIP   string `json:"vpn_ip"`
+	Success bool   `json:"success"`
+}
+
+// vpnNodeHandshakes summarizes the WireGuard handshakes seen by one node
+type vpnNodeHandshakes struct {
+	Node       string `json:"node"`
+	Responding bool   `json:"responding"`
+	Peers      int    `json:"peers"`
+	// ActivePeers handshook within handshakeActiveSeconds
+	ActivePeers int `json:"active_peers"`
+	// HandshakeAgeSeconds is the age of the oldest handshake, nil when no
+	// peer has completed one
+	HandshakeAgeSeconds *int64 `json:"handshake_age_seconds,omitempty"`
+}
+
+// vpnTestReport is the machine-readable result of vpn test
+type vpnTestReport struct {
+	Stack       string              `json:"stack"`
+	PingSuccess int                 `json:"ping_success"`
+	PingTotal   int                 `json:"ping_total"`
+	Links       []vpnLinkResult     `json:"links"`
+	Nodes       []vpnNodeHandshakes `json:"nodes"`
+}
+
+// parseLatestHandshakes parses the output of handshakeCommand into the
+// node's clock and the latest handshake of each peer, 0 for peers that never
+// completed one
+func parseLatestHandshakes(output string) (now int64, handshakes map[string]int64, err error) {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	now, err = strconv.ParseInt(strings.TrimSpace(lines[0]), 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("unexpected clock %q", lines[0])
+	}
+
+	handshakes = make(map[string]int64)
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+		ts, err := strconv.ParseInt(fields[1], 10, 64)
+		if err != nil {
+			return 0, nil, fmt.Errorf("unexpected handshake line %q", line)
+		}
+		handshakes[fields[0]] = ts
+	}
+	return now, handshakes, nil
+}
+
+// summarizeHandshakes builds the handshake summary of node from the output
+// of handshakeCommand
+func summarizeHandshakes(node string, now int64, handshakes map[string]int64) vpnNodeHandshakes {
+	summary := vpnNodeHandshakes{Node: node, Responding: true, Peers: len(handshakes)}
+	for _, ts := range handshakes {
+		if ts == 0 {
+			continue
+		}
+		age := now - ts
+		if age < 0 {
+			age = 0
+		}
+		if age <= handshakeActiveSeconds {
+			summary.ActivePeers++
+		}
+		if summary.HandshakeAgeSeconds == nil || age > *summary.HandshakeAgeSeconds {
+			summary.HandshakeAgeSeconds = &age
+		}
+	}
+	return summary
+}
+
+// writeVPNTestJSON writes report as indented JSON
+func writeVPNTestJSON(w io.Writer, report vpnTestReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// writeVPNTestPrometheus writes report in the Prometheus text exposition
+// format
+func writeVPNTestPrometheus(w io.Writer, report vpnTestReport) error {
+	stack := strconv.Quote(report.Stack)
+	var b strings.Builder
+
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("sloth_vpn_ping_success_total", "counter", "VPN pings between nodes that succeeded.")
+	fmt.Fprintf(&b, "sloth_vpn_ping_success_total{stack=%s} %d\n", stack, report.PingSuccess)
+	metric("sloth_vpn_ping_total", "counter", "VPN pings between nodes that were attempted.")
+	fmt.Fprintf(&b, "sloth_vpn_ping_total{stack=%s} %d\n", stack, report.PingTotal)
+
+	metric("sloth_vpn_node_up", "gauge", "Whether the node's WireGuard handshakes could be read.")
+	for _, node := range report.Nodes {
+		up := 0
+		if node.Responding {
+			up = 1
+		}
+		fmt.Fprintf(&b, "sloth_vpn_node_up{stack=%s,node=%s} %d\n", stack, strconv.Quote(node.Node), up)
+	}
+
+	metric("sloth_vpn_peers_active", "gauge", fmt.Sprintf("WireGuard peers of the node with a handshake in the last %d seconds.", handshakeActiveSeconds))
+	for _, node := range report.Nodes {
+		if node.Responding {
+			fmt.Fprintf(&b, "sloth_vpn_peers_active{stack=%s,node=%s} %d\n", stack, strconv.Quote(node.Node), node.ActivePeers)
+		}
+	}
+
+	metric("sloth_vpn_handshake_age_seconds", "gauge", "Age of the oldest WireGuard handshake on the node.")
+	for _, node := range report.Nodes {
+		if node.HandshakeAgeSeconds != nil {
+			fmt.Fprintf(&b, "sloth_vpn_handshake_age_seconds{stack=%s,node=%s} %d\n", stack, strconv.Quote(node.Node), *node.HandshakeAgeSeconds)
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeVPNTestTextfile writes the Prometheus metrics of report to
+// sloth_vpn_<stack>.prom in dir, for node_exporter's textfile collector. The
+// file is replaced atomically so the collector never reads a partial one.
+func writeVPNTestTextfile(dir string, report vpnTestReport) (string, error) {
+	path := filepath.Join(dir, fmt.Sprintf("sloth_vpn_%s.prom", report.Stack))
+	tmp, err := os.CreateTemp(dir, ".sloth_vpn_*.tmp")
+	if err != nil {
+		return "", fmt.Errorf("failed to write metrics: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := writeVPNTestPrometheus(tmp, report); err != nil {
+		tmp.Close()
+		return "", fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return "", fmt.Errorf("failed to write metrics: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return "", fmt.Errorf("failed to write metrics: %w", err)
+	}
+	return path, nil
+}
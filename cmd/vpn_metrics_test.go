@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseLatestHandshakes(t *testing.T) {
+	now, handshakes, err := parseLatestHandshakes("1700000300\nkeyA=\t1700000250\nkeyB=\t0\n")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if now != 1700000300 || handshakes["keyA="] != 1700000250 || len(handshakes) != 2 {
+		t.Errorf("Unexpected result: now=%d handshakes=%v", now, handshakes)
+	}
+
+	if _, _, err := parseLatestHandshakes("wg: not found"); err == nil {
+		t.Error("Expected an error without a clock line")
+	}
+}
+
+func TestSummarizeHandshakes(t *testing.T) {
+	summary := summarizeHandshakes("master-1", 1000, map[string]int64{"a": 950, "b": 700, "c": 0})
+	if !summary.Responding || summary.Peers != 3 || summary.ActivePeers != 1 {
+		t.Errorf("Expected 1 of 3 peers active, got %+v", summary)
+	}
+	if summary.HandshakeAgeSeconds == nil || *summary.HandshakeAgeSeconds != 300 {
+		t.Errorf("Expected the oldest handshake age 300, got %v", summary.HandshakeAgeSeconds)
+	}
+
+	if summary := summarizeHandshakes("master-1", 1000, map[string]int64{"a": 0}); summary.HandshakeAgeSeconds != nil {
+		t.Error("Expected no age when no peer completed a handshake")
+	}
+}
+
+func testVPNReport() vpnTestReport {
+	age := int64(42)
+	return vpnTestReport{
+		Stack:       "prod",
+		PingSuccess: 1,
+		PingTotal:   2,
+		Links: []vpnLinkResult{
+			{Source: "master-1", Target: "worker-1", VPNIP: "10.8.0.11", Success: true},
+			{Source: "worker-1", Target: "master-1", VPNIP: "10.8.0.10"},
+		},
+		Nodes: []vpnNodeHandshakes{
+			{Node: "master-1", Responding: true, Peers: 2, ActivePeers: 1, HandshakeAgeSeconds: &age},
+			{Node: "worker-1"},
+		},
+	}
+}
+
+func TestWriteVPNTestPrometheus(t *testing.T) {
+	var b strings.Builder
+	if err := writeVPNTestPrometheus(&b, testVPNReport()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"# TYPE sloth_vpn_ping_success_total counter\n",
+		`sloth_vpn_ping_success_total{stack="prod"} 1`,
+		`sloth_vpn_ping_total{stack="prod"} 2`,
+		`sloth_vpn_node_up{stack="prod",node="worker-1"} 0`,
+		`sloth_vpn_peers_active{stack="prod",node="master-1"} 1`,
+		`sloth_vpn_handshake_age_seconds{stack="prod",node="master-1"} 42`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `peers_active{stack="prod",node="worker-1"}`) || strings.Contains(out, `age_seconds{stack="prod",node="worker-1"}`) {
+		t.Error("Expected no handshake metrics for a node that did not respond")
+	}
+}
+
+func TestWriteVPNTestJSON(t *testing.T) {
+	var b strings.Builder
+	if err := writeVPNTestJSON(&b, testVPNReport()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var report vpnTestReport
+	if err := json.Unmarshal([]byte(b.String()), &report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if report.PingTotal != 2 || len(report.Links) != 2 || *report.Nodes[0].HandshakeAgeSeconds != 42 {
+		t.Errorf("Unexpected report: %+v", report)
+	}
+}
+
+func TestWriteVPNTestTextfile(t *testing.T) {
+	dir := t.TempDir()
+
+	path, err := writeVPNTestTextfile(dir, testVPNReport())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if path != filepath.Join(dir, "sloth_vpn_prod.prom") {
+		t.Errorf("Unexpected path %s", path)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || !strings.Contains(string(data), "sloth_vpn_ping_total") {
+		t.Errorf("Expected metrics in %s, got %q (%v)", path, data, err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected no temporary file left behind, got %d entries", len(entries))
+	}
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"sort"
+	"sync"
+)
+
+// defaultSSHConcurrency is the default number of nodes contacted at once
+const defaultSSHConcurrency = 4
+
+// maxBastionConcurrency caps the SSH sessions proxied through the bastion at
+// once, below the default as sshd starts refusing them past its MaxStartups
+// limit
+const maxBastionConcurrency = 2
+
+// vpnConcurrency is the --concurrency flag of the vpn commands
+var vpnConcurrency int
+
+// concurrency returns how many nodes to contact at once for the requested
+// concurrency, capped when sessions go through the bastion
+func (a nodeSSHAccess) concurrency(requested int) int {
+	if requested < 1 {
+		requested = 1
+	}
+	if a.viaBastion() && requested > maxBastionConcurrency {
+		return maxBastionConcurrency
+	}
+	return requested
+}
+
+// runParallel calls fn for every index below n, at most concurrency at a
+// time, and returns once every call has returned. fn stores its result by
+// index so callers print results in a deterministic order afterwards.
+func runParallel(n, concurrency int, fn func(i int)) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > n {
+		concurrency = n
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// sortNodesByName returns a copy of nodes sorted by name
+func sortNodesByName(nodes []NodeInfo) []NodeInfo {
+	sorted := append([]NodeInfo(nil), nodes...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+	return sorted
+}
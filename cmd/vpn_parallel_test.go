@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunParallel_BoundsConcurrency(t *testing.T) {
+	var running, peak int32
+	done := make([]bool, 10)
+
+	runParallel(len(done), 3, func(i int) {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		done[i] = true
+	})
+
+	for i, ok := range done {
+		if !ok {
+			t.Errorf("Job %d did not run", i)
+		}
+	}
+	if peak > 3 {
+		t.Errorf("Expected at most 3 concurrent jobs, got %d", peak)
+	}
+}
+
+func TestNodeSSHAccessConcurrency(t *testing.T) {
+	direct := nodeSSHAccess{}
+	if got := direct.concurrency(10); got != 10 {
+		t.Errorf("Expected direct connections to use the requested concurrency, got %d", got)
+	}
+	if got := direct.concurrency(0); got != 1 {
+		t.Errorf("Expected at least one connection, got %d", got)
+	}
+
+	bastion := nodeSSHAccess{BastionEnabled: true, BastionIP: "203.0.113.5"}
+	if got := bastion.concurrency(10); got != maxBastionConcurrency {
+		t.Errorf("Expected bastion concurrency capped at %d, got %d", maxBastionConcurrency, got)
+	}
+}
+
+func TestNodeChangeTracker_ConcurrentNodes(t *testing.T) {
+	tracker := newNodeChangeTracker()
+	defer tracker.Stop()
+
+	var wg sync.WaitGroup
+	for _, name := range []string{"master-1", "worker-1", "worker-2"} {
+		tracker.Begin(name)
+	}
+	for _, name := range []string{"master-1", "worker-2"} {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			tracker.DoneNode(name, name == "master-1")
+		}(name)
+	}
+	wg.Wait()
+
+	if got := tracker.Modified(); len(got) != 1 || got[0] != "master-1" {
+		t.Errorf("Expected only master-1 modified, got %v", got)
+	}
+	if got := tracker.Touched(); len(got) != 2 || got[1] != "worker-1" {
+		t.Errorf("Expected worker-1 still in flight, got %v", got)
+	}
+}
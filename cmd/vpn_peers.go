@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/fatih/color"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// vpnPeer is a cluster node peer listed by vpn peers, with the raw values
+// of `wg show dump` so --output json and yaml are lossless
+type vpnPeer struct {
+	Node      string `json:"node" yaml:"node"`
+	Label     string `json:"label,omitempty" yaml:"label,omitempty"`
+	VPNIP     string `json:"vpn_ip" yaml:"vpn_ip"`
+	PublicKey string `json:"public_key" yaml:"public_key"`
+	// Endpoint is empty until the peer has connected
+	Endpoint string `json:"endpoint,omitempty" yaml:"endpoint,omitempty"`
+	// HandshakeAgeSeconds is nil when the peer never completed a handshake
+	HandshakeAgeSeconds *int64 `json:"handshake_age_seconds,omitempty" yaml:"handshake_age_seconds,omitempty"`
+	RxBytes             int64  `json:"rx_bytes" yaml:"rx_bytes"`
+	TxBytes             int64  `json:"tx_bytes" yaml:"tx_bytes"`
+}
+
+// newVPNPeer builds the vpn peers entry of node from its dump line, with
+// handshake ages relative to now
+func newVPNPeer(node, label string, peer wgDumpPeer, now int64) vpnPeer {
+	entry := vpnPeer{
+		Node:      node,
+		Label:     label,
+		VPNIP:     peer.VPNIP(),
+		PublicKey: peer.PublicKey,
+		RxBytes:   peer.RxBytes,
+		TxBytes:   peer.TxBytes,
+	}
+	if peer.Endpoint != "(none)" {
+		entry.Endpoint = peer.Endpoint
+	}
+	if peer.LatestHandshake != 0 {
+		age := now - peer.LatestHandshake
+		if age < 0 {
+			age = 0
+		}
+		entry.HandshakeAgeSeconds = &age
+	}
+	return entry
+}
+
+// formatHandshakeAge humanizes a handshake age for the table
+func formatHandshakeAge(age *int64) string {
+	switch {
+	case age == nil:
+		return "Never"
+	case *age < 60:
+		return fmt.Sprintf("%ds ago", *age)
+	case *age < 3600:
+		return fmt.Sprintf("%dm ago", *age/60)
+	case *age < 86400:
+		return fmt.Sprintf("%dh ago", *age/3600)
+	default:
+		return fmt.Sprintf("%dd ago", *age/86400)
+	}
+}
+
+// printVPNPeerList prints peers as the default vpn peers table, with
+// truncated keys and humanized transfer
+func printVPNPeerList(peers []vpnPeer) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	defer w.Flush()
+
+	color.New(color.Bold).Fprintln(w, "NODE\tLABEL\tVPN IP\tPUBLIC KEY\tENDPOINT\tLAST HANDSHAKE\tTRANSFER")
+	fmt.Fprintln(w, "----\t-----\t------\t----------\t--------\t--------------\t--------")
+
+	if len(peers) == 0 {
+		fmt.Fprintln(w, "No peers found")
+		return
+	}
+
+	for _, peer := range peers {
+		label := peer.Label
+		if label == "" {
+			label = "-"
+		}
+		publicKey := peer.PublicKey
+		if len(publicKey) > 16 {
+			publicKey = publicKey[:16] + "..."
+		}
+		endpoint := peer.Endpoint
+		if endpoint == "" {
+			endpoint = "N/A"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			peer.Node,
+			label,
+			peer.VPNIP,
+			publicKey,
+			endpoint,
+			formatHandshakeAge(peer.HandshakeAgeSeconds),
+			fmt.Sprintf("↑ %s / ↓ %s", formatBytes(peer.TxBytes), formatBytes(peer.RxBytes)),
+		)
+	}
+}
+
+// writeVPNPeers writes peers as JSON or YAML
+func writeVPNPeers(w io.Writer, format string, peers []vpnPeer) error {
+	var data []byte
+	var err error
+	switch format {
+	case "json":
+		data, err = json.MarshalIndent(peers, "", "  ")
+	case "yaml":
+		data, err = yaml.Marshal(peers)
+	default:
+		return fmt.Errorf("unknown output format %q (use table, json or yaml)", format)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to marshal peers: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
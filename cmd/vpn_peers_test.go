@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestNewVPNPeer(t *testing.T) {
+	key := "aGVsbG8td29ybGQtcHVibGljLWtleS1mb3ItdGVzdHM="
+	peer := newVPNPeer("master-1", "laptop", wgDumpPeer{
+		PublicKey:       key,
+		Endpoint:        "(none)",
+		AllowedIPs:      "10.8.0.10/32",
+		LatestHandshake: 1700000000,
+		RxBytes:         123456789,
+		TxBytes:         42,
+	}, 1700000090)
+
+	if peer.PublicKey != key || peer.VPNIP != "10.8.0.10" || peer.Endpoint != "" {
+		t.Errorf("Unexpected peer: %+v", peer)
+	}
+	if peer.HandshakeAgeSeconds == nil || *peer.HandshakeAgeSeconds != 90 {
+		t.Errorf("Expected a handshake age of 90s, got %v", peer.HandshakeAgeSeconds)
+	}
+	if formatHandshakeAge(peer.HandshakeAgeSeconds) != "1m ago" {
+		t.Errorf("Expected 1m ago, got %q", formatHandshakeAge(peer.HandshakeAgeSeconds))
+	}
+
+	never := newVPNPeer("master-1", "", wgDumpPeer{PublicKey: key, AllowedIPs: "10.8.0.11/32"}, 1700000090)
+	if never.HandshakeAgeSeconds != nil || formatHandshakeAge(never.HandshakeAgeSeconds) != "Never" {
+		t.Errorf("Expected no handshake age, got %v", never.HandshakeAgeSeconds)
+	}
+}
+
+func TestWriteVPNPeers(t *testing.T) {
+	age := int64(5)
+	key := "aGVsbG8td29ybGQtcHVibGljLWtleS1mb3ItdGVzdHM="
+	peers := []vpnPeer{{Node: "master-1", VPNIP: "10.8.0.10", PublicKey: key, HandshakeAgeSeconds: &age, RxBytes: 123456789, TxBytes: 42}}
+
+	var out bytes.Buffer
+	if err := writeVPNPeers(&out, "json", peers); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var decoded []map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got %v: %s", err, out.String())
+	}
+	if len(decoded) != 1 || decoded[0]["public_key"] != key || decoded[0]["rx_bytes"] != float64(123456789) || decoded[0]["handshake_age_seconds"] != float64(5) {
+		t.Errorf("Expected the full key and raw counters, got %v", decoded)
+	}
+
+	out.Reset()
+	if err := writeVPNPeers(&out, "yaml", peers); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "public_key: "+key) || !strings.Contains(out.String(), "tx_bytes: 42") {
+		t.Errorf("Unexpected YAML: %s", out.String())
+	}
+
+	if err := writeVPNPeers(&out, "xml", peers); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
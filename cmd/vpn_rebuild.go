@@ -0,0 +1,444 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/spf13/cobra"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+// wgNodeConfig is a node's WireGuard public key and its current wg0.conf
+type wgNodeConfig struct {
+	Node      NodeInfo
+	PublicKey string
+	Config    string
+}
+
+// wgPeerBlock is one [Peer] section of a wg0.conf, kept verbatim
+type wgPeerBlock struct {
+	PublicKey string
+	Lines     []string
+}
+
+// wgReadConfigScript prints the node's public key, a separator, then its wg0.conf
+const wgReadConfigScript = `sudo cat /etc/wireguard/publickey && echo "---" && (sudo cat /etc/wireguard/wg0.conf 2>/dev/null || true)`
+
+func runVPNRebuild(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("🔨 Rebuild VPN Mesh - Stack: %s", stack))
+
+	// Create workspace with S3 support
+	workspace, err := createWorkspaceWithS3Support(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	// Use fully qualified stack name for S3 backend
+	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
+	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
+	if err != nil {
+		return selectStackError(err, stack)
+	}
+
+	// Get outputs
+	outputs, err := stackOutputsWithRetry(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	// Parse nodes
+	nodes, err := ParseNodeOutputs(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to parse nodes: %w", err)
+	}
+	if len(nodes) == 0 {
+		return fmt.Errorf("no nodes found in stack")
+	}
+
+	access := newNodeSSHAccess(stack, outputs)
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	printInfo("Reading WireGuard keys and configuration from cluster nodes...")
+
+	// Every node's key is needed to build the mesh, so nothing is changed
+	// unless all nodes could be read
+	states, unreadable := readWGNodeConfigs(nodes, access)
+	if len(unreadable) > 0 {
+		return errs.Mark(fmt.Errorf("cannot rebuild the mesh without every node's WireGuard key; failed to read: %s", strings.Join(unreadable, ", ")), errs.ErrNodeUnreachable)
+	}
+
+	routing, err := loadMeshRouting()
+	if err != nil {
+		return err
+	}
+	configs := buildMeshConfigs(states, vpnSubnet(outputs), routing)
+
+	fmt.Println()
+	for _, state := range states {
+		external := len(routing.externalPeers(state, states))
+		if routing.hub != nil {
+			printInfo(fmt.Sprintf("  • %s (%s): spoke of %s, %d external peer(s) preserved", state.Node.Name, state.Node.WireGuardIP, routing.hub.HubEndpoint(), external))
+			continue
+		}
+		printInfo(fmt.Sprintf("  • %s (%s): %d mesh peer(s), %d external peer(s) preserved", state.Node.Name, state.Node.WireGuardIP, len(states)-1, external))
+	}
+
+	if !autoApprove && !confirm(fmt.Sprintf("Rewrite wg0.conf on all %d node(s)?", len(states))) {
+		printWarning("Rebuild cancelled")
+		return nil
+	}
+
+	fmt.Println()
+	printInfo("Applying WireGuard configuration...")
+
+	// Ctrl-C stops before the next node
+	tracker := newNodeChangeTracker()
+	defer tracker.Stop()
+
+	failed := 0
+	for _, state := range states {
+		if tracker.Interrupted() {
+			break
+		}
+		tracker.Begin(state.Node.Name)
+
+		output, err := access.runScript(state.Node, 10, generateMeshApplyScript(configs[state.Node.Name]))
+		if err != nil && tracker.Interrupted() {
+			break
+		}
+		tracker.Done(err == nil)
+		if err != nil {
+			color.Yellow(fmt.Sprintf("  ⚠️  Failed to rebuild %s: %v (output: %s)", state.Node.Name, err, strings.TrimSpace(string(output))))
+			failed++
+			continue
+		}
+		printSuccess(fmt.Sprintf("  ✓ %s", state.Node.Name))
+	}
+
+	if tracker.Interrupted() {
+		names := make([]string, len(states))
+		for i, state := range states {
+			names[i] = state.Node.Name
+		}
+		tracker.Report(names, fmt.Sprintf("sloth-kubernetes vpn rebuild %s (each node keeps a wg0.conf.backup-* of its previous config)", stack))
+		return tracker.interruptedError("vpn rebuild")
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d node(s) failed to rebuild", failed, len(states))
+	}
+
+	fmt.Println()
+	printInfo("Verifying full mesh connectivity...")
+
+	unreachable := 0
+	for _, state := range states {
+		var peerIPs []string
+		for _, peer := range states {
+			if peer.Node.Name != state.Node.Name {
+				peerIPs = append(peerIPs, peer.Node.WireGuardIP)
+			}
+		}
+
+		output, err := access.runScript(state.Node, 10, generateMeshPingScript(peerIPs))
+		if err != nil {
+			color.Yellow(fmt.Sprintf("  ⚠️  Failed to verify %s: %v", state.Node.Name, err))
+			unreachable += len(peerIPs)
+			continue
+		}
+
+		failedIPs := parseMeshPingOutput(string(output))
+		if len(failedIPs) > 0 {
+			color.Yellow(fmt.Sprintf("  ✗ %s cannot reach %s", state.Node.Name, strings.Join(failedIPs, ", ")))
+			unreachable += len(failedIPs)
+			continue
+		}
+		printSuccess(fmt.Sprintf("  ✓ %s reaches all %d peer(s)", state.Node.Name, len(peerIPs)))
+	}
+
+	fmt.Println()
+	if unreachable > 0 {
+		return errs.Mark(fmt.Errorf("mesh rebuilt but %d link(s) are not reachable", unreachable), errs.ErrMeshDegraded)
+	}
+	printSuccess(fmt.Sprintf("VPN mesh rebuilt on %d node(s) with full connectivity", len(states)))
+
+	return nil
+}
+
+// readWGNodeConfigs reads the WireGuard key and wg0.conf of every node. It
+// also returns the nodes that could not be read, with the reason.
+func readWGNodeConfigs(nodes []NodeInfo, access nodeSSHAccess) ([]wgNodeConfig, []string) {
+	var states []wgNodeConfig
+	var unreadable []string
+	for _, node := range nodes {
+		if node.WireGuardIP == "" {
+			unreadable = append(unreadable, fmt.Sprintf("%s (no VPN IP in stack outputs)", node.Name))
+			continue
+		}
+
+		output, err := access.runScript(node, 10, wgReadConfigScript)
+		if err != nil {
+			unreadable = append(unreadable, fmt.Sprintf("%s (%v)", node.Name, err))
+			continue
+		}
+
+		state, err := parseWGNodeConfig(node, string(output))
+		if err != nil {
+			unreadable = append(unreadable, fmt.Sprintf("%s (%v)", node.Name, err))
+			continue
+		}
+		states = append(states, state)
+	}
+	return states, unreadable
+}
+
+// parseWGNodeConfig parses the output of wgReadConfigScript
+func parseWGNodeConfig(node NodeInfo, output string) (wgNodeConfig, error) {
+	parts := strings.SplitN(output, "---", 2)
+	if len(parts) != 2 {
+		return wgNodeConfig{}, fmt.Errorf("missing config section")
+	}
+
+	publicKey := strings.TrimSpace(parts[0])
+	if publicKey == "" {
+		return wgNodeConfig{}, fmt.Errorf("missing public key")
+	}
+
+	return wgNodeConfig{Node: node, PublicKey: publicKey, Config: parts[1]}, nil
+}
+
+// parseWGPeerBlocks returns the [Peer] sections of a wg0.conf in file order.
+// Sections without a PublicKey are dropped as corrupted.
+func parseWGPeerBlocks(conf string) []wgPeerBlock {
+	var blocks []wgPeerBlock
+	var current *wgPeerBlock
+
+	flush := func() {
+		if current != nil && current.PublicKey != "" {
+			blocks = append(blocks, *current)
+		}
+		current = nil
+	}
+
+	for _, line := range strings.Split(conf, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "[") {
+			flush()
+			if trimmed == "[Peer]" {
+				current = &wgPeerBlock{}
+			}
+			continue
+		}
+		if current == nil || trimmed == "" {
+			continue
+		}
+
+		current.Lines = append(current.Lines, trimmed)
+		if key, value, ok := strings.Cut(trimmed, "="); ok && strings.TrimSpace(key) == "PublicKey" {
+			current.PublicKey = strings.TrimSpace(value)
+		}
+	}
+	flush()
+
+	return blocks
+}
+
+// externalPeers returns the peers on a node that are not cluster nodes
+// (e.g. clients added with 'vpn join' and the bastion), once per public key
+func externalPeers(state wgNodeConfig, states []wgNodeConfig) []wgPeerBlock {
+	nodeKeys := make(map[string]bool)
+	for _, s := range states {
+		nodeKeys[s.PublicKey] = true
+	}
+
+	seen := make(map[string]bool)
+	var peers []wgPeerBlock
+	for _, block := range parseWGPeerBlocks(state.Config) {
+		if nodeKeys[block.PublicKey] || seen[block.PublicKey] {
+			continue
+		}
+		seen[block.PublicKey] = true
+		peers = append(peers, block)
+	}
+	return peers
+}
+
+// meshRouting decides how cluster nodes reach each other over WireGuard, as
+// the WireGuard components of the deployment do
+type meshRouting struct {
+	// vpcNetworks are the VPC CIDRs of a hybrid network, nil otherwise
+	vpcNetworks map[string]string
+	// hub is the existing WireGuard server the nodes are spokes of, nil for
+	// a mesh between the nodes
+	hub *config.WireGuardConfig
+}
+
+// newMeshRouting returns the mesh routing of cfg; a nil cfg gives a full
+// mesh over public endpoints
+func newMeshRouting(cfg *config.ClusterConfig) meshRouting {
+	if cfg == nil {
+		return meshRouting{}
+	}
+	routing := meshRouting{vpcNetworks: config.HybridVPCNetworks(cfg)}
+	if cfg.Network.WireGuard.HubMode() {
+		routing.hub = cfg.Network.WireGuard
+	}
+	return routing
+}
+
+// loadMeshRouting reads the mesh routing from the cluster config, which the
+// stack outputs do not record. Without a config the mesh is rebuilt as a
+// full mesh over public endpoints, with a warning.
+func loadMeshRouting() (meshRouting, error) {
+	configFile := cfgFile
+	if configFile == "" {
+		configFile = "./cluster-config.yaml"
+		if _, err := os.Stat(configFile); err != nil {
+			printWarning("No cluster config found: rebuilding a full mesh over public endpoints. Pass --config for hub mode or hybrid network clusters.")
+			return meshRouting{}, nil
+		}
+	}
+	cfg, err := config.LoadFromYAML(configFile)
+	if err != nil {
+		return meshRouting{}, fmt.Errorf("failed to load config file: %w", err)
+	}
+	return newMeshRouting(cfg), nil
+}
+
+// externalPeers returns the peers on a node that are neither cluster nodes
+// nor the hub (e.g. clients added with 'vpn join' and the bastion), once per
+// public key
+func (r meshRouting) externalPeers(state wgNodeConfig, states []wgNodeConfig) []wgPeerBlock {
+	var peers []wgPeerBlock
+	for _, peer := range externalPeers(state, states) {
+		if r.hub == nil || peer.PublicKey != r.hub.ServerPublicKey {
+			peers = append(peers, peer)
+		}
+	}
+	return peers
+}
+
+// buildMeshConfigs generates the full wg0.conf of every node, keyed by node
+// name. In a mesh each node peers with every other node in name order, with
+// the AllowedIPs and endpoint the deployment gives them; in hub mode the hub
+// is the only node peer. The external peers already present in a node's
+// config follow. The private key is left as $(cat /etc/wireguard/privatekey)
+// and expanded on the node. Addresses use the prefix length of subnet.
+func buildMeshConfigs(states []wgNodeConfig, subnet *config.WireGuardSubnet, routing meshRouting) map[string]string {
+	sorted := make([]wgNodeConfig, len(states))
+	copy(sorted, states)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Node.Name < sorted[j].Node.Name })
+
+	configs := make(map[string]string, len(sorted))
+	for _, state := range sorted {
+		var b strings.Builder
+
+		if routing.hub != nil {
+			b.WriteString(routing.hub.HubSpokeConfig(state.Node.WireGuardIP))
+		} else {
+			fmt.Fprintf(&b, `[Interface]
+Address = %s/%d
+ListenPort = %d
+PrivateKey = $(cat /etc/wireguard/privatekey)
+PostUp = iptables -A FORWARD -i wg0 -j ACCEPT; iptables -t nat -A POSTROUTING -o eth0 -j MASQUERADE; sysctl -w net.ipv4.ip_forward=1
+PostDown = iptables -D FORWARD -i wg0 -j ACCEPT; iptables -t nat -D POSTROUTING -o eth0 -j MASQUERADE
+`, state.Node.WireGuardIP, subnet.PrefixLen(), wgPort)
+
+			self := config.MeshEndpoint{PrivateIP: state.Node.PrivateIP, Provider: state.Node.Provider}
+			for _, peer := range sorted {
+				if peer.Node.Name == state.Node.Name {
+					continue
+				}
+				endpoint := config.MeshEndpoint{
+					WgIP:      peer.Node.WireGuardIP,
+					PublicIP:  peer.Node.PublicIP,
+					PrivateIP: peer.Node.PrivateIP,
+					Provider:  peer.Node.Provider,
+				}
+				allowedIPs, host := config.MeshPeerRoute(endpoint, self, routing.vpcNetworks)
+				fmt.Fprintf(&b, `
+[Peer]
+# %s (%s)
+PublicKey = %s
+AllowedIPs = %s
+Endpoint = %s:%d
+PersistentKeepalive = 25
+`, peer.Node.Name, peer.Node.WireGuardIP, peer.PublicKey, allowedIPs, host, wgPort)
+			}
+		}
+
+		for _, peer := range routing.externalPeers(state, states) {
+			b.WriteString("\n[Peer]\n")
+			b.WriteString(strings.Join(peer.Lines, "\n"))
+			b.WriteString("\n")
+		}
+
+		configs[state.Node.Name] = b.String()
+	}
+
+	return configs
+}
+
+// generateMeshApplyScript creates a bash script that backs up wg0.conf, writes
+// the rebuilt config with the node's private key expanded, and applies it.
+// A running interface is synced in place so SSH over the VPN is not dropped.
+func generateMeshApplyScript(conf string) string {
+	return fmt.Sprintf(`set -e
+sudo cp /etc/wireguard/wg0.conf /etc/wireguard/wg0.conf.backup-$(date +%%Y%%m%%d-%%H%%M%%S) 2>/dev/null || true
+
+cat > /tmp/wg0.conf.template << 'WGEOF'
+%s
+WGEOF
+
+PRIVKEY=$(sudo cat /etc/wireguard/privatekey)
+sed "s|\$(cat /etc/wireguard/privatekey)|$PRIVKEY|g" /tmp/wg0.conf.template | sudo tee /etc/wireguard/wg0.conf > /dev/null
+rm -f /tmp/wg0.conf.template
+sudo chmod 600 /etc/wireguard/wg0.conf
+
+if sudo wg show wg0 > /dev/null 2>&1; then
+    sudo wg-quick strip wg0 | sudo wg syncconf wg0 /dev/stdin
+else
+    sudo wg-quick up wg0
+fi
+sudo systemctl enable wg-quick@wg0 2>/dev/null || true
+`, strings.TrimRight(conf, "\n"))
+}
+
+// generateMeshPingScript creates a bash script that pings each peer VPN IP and
+// prints "<ip> ok" or "<ip> fail" per peer
+func generateMeshPingScript(peerIPs []string) string {
+	var b strings.Builder
+	for _, ip := range peerIPs {
+		fmt.Fprintf(&b, "if ping -c 3 -W 2 %s > /dev/null 2>&1; then echo '%s ok'; else echo '%s fail'; fi\n", ip, ip, ip)
+	}
+	return b.String()
+}
+
+// parseMeshPingOutput returns the peer IPs reported as unreachable by
+// generateMeshPingScript
+func parseMeshPingOutput(output string) []string {
+	var failed []string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == "fail" {
+			failed = append(failed, fields[0])
+		}
+	}
+	return failed
+}
@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/spf13/cobra"
+)
+
+// wgPort is the WireGuard listen port used by every cluster node
+const wgPort = 51820
+
+// wgNodeEndpoints is a node's own WireGuard public key and its view of peer endpoints
+type wgNodeEndpoints struct {
+	Node      NodeInfo
+	PublicKey string
+	Endpoints map[string]string // peer public key -> endpoint
+}
+
+// wgEndpointUpdate is a stale peer endpoint on a node that must be rewritten
+type wgEndpointUpdate struct {
+	Node          NodeInfo
+	PeerName      string
+	PeerPublicKey string
+	OldEndpoint   string
+	NewEndpoint   string
+}
+
+// wgReadEndpointsScript prints the node's public key, a separator, then `wg show wg0 endpoints`
+const wgReadEndpointsScript = `sudo wg show wg0 public-key && echo "---" && sudo wg show wg0 endpoints`
+
+func runVPNRefreshEndpoints(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("🔄 Refresh VPN Endpoints - Stack: %s", stack))
+
+	// Create workspace with S3 support
+	workspace, err := createWorkspaceWithS3Support(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	// Use fully qualified stack name for S3 backend
+	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
+	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
+	if err != nil {
+		return selectStackError(err, stack)
+	}
+
+	// Get outputs
+	outputs, err := stackOutputsWithRetry(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	// Parse nodes
+	nodes, err := ParseNodeOutputs(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	access := newNodeSSHAccess(stack, outputs)
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	printInfo("Reading current peer endpoints from cluster nodes...")
+
+	var states []wgNodeEndpoints
+	for _, node := range nodes {
+		output, err := access.runScript(node, 10, wgReadEndpointsScript)
+		if err != nil {
+			color.Yellow(fmt.Sprintf("⚠  Failed to read endpoints from %s: %v", node.Name, err))
+			continue
+		}
+
+		state, err := parseWGNodeEndpoints(node, string(output))
+		if err != nil {
+			color.Yellow(fmt.Sprintf("⚠  Unexpected output from %s: %v", node.Name, err))
+			continue
+		}
+		states = append(states, state)
+	}
+
+	if len(states) == 0 {
+		return errNoReachableNode
+	}
+
+	updates := planEndpointUpdates(states)
+	if len(updates) == 0 {
+		fmt.Println()
+		printSuccess("All peer endpoints are up to date")
+		return nil
+	}
+
+	fmt.Println()
+	printInfo(fmt.Sprintf("Updating %d stale endpoint(s)...", len(updates)))
+
+	// Group updates per node so each node is touched once
+	byNode := make(map[string][]wgEndpointUpdate)
+	var order []string
+	for _, update := range updates {
+		if _, ok := byNode[update.Node.Name]; !ok {
+			order = append(order, update.Node.Name)
+		}
+		byNode[update.Node.Name] = append(byNode[update.Node.Name], update)
+	}
+
+	// Ctrl-C stops before the next node
+	tracker := newNodeChangeTracker()
+	defer tracker.Stop()
+
+	failed := 0
+	for _, name := range order {
+		if tracker.Interrupted() {
+			break
+		}
+		tracker.Begin(name)
+
+		nodeUpdates := byNode[name]
+		output, err := access.runScript(nodeUpdates[0].Node, 10, generateEndpointUpdateScript(nodeUpdates))
+		if err != nil && tracker.Interrupted() {
+			break
+		}
+		tracker.Done(err == nil)
+		if err != nil {
+			color.Yellow(fmt.Sprintf("  ⚠️  Failed to update %s: %v (output: %s)", name, err, strings.TrimSpace(string(output))))
+			failed += len(nodeUpdates)
+			continue
+		}
+
+		for _, update := range nodeUpdates {
+			oldEndpoint := update.OldEndpoint
+			if oldEndpoint == "" {
+				oldEndpoint = "(none)"
+			}
+			printSuccess(fmt.Sprintf("  ✓ %s: %s %s → %s", name, update.PeerName, oldEndpoint, update.NewEndpoint))
+		}
+	}
+
+	if tracker.Interrupted() {
+		tracker.Report(order, fmt.Sprintf("sloth-kubernetes vpn refresh-endpoints %s", stack))
+		return tracker.interruptedError("vpn refresh-endpoints")
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		return fmt.Errorf("%d of %d endpoint update(s) failed", failed, len(updates))
+	}
+	printSuccess(fmt.Sprintf("Updated %d endpoint(s)", len(updates)))
+
+	return nil
+}
+
+// parseWGNodeEndpoints parses the output of wgReadEndpointsScript
+func parseWGNodeEndpoints(node NodeInfo, output string) (wgNodeEndpoints, error) {
+	parts := strings.SplitN(output, "---", 2)
+	if len(parts) != 2 {
+		return wgNodeEndpoints{}, fmt.Errorf("missing endpoint section")
+	}
+
+	publicKey := strings.TrimSpace(parts[0])
+	if publicKey == "" {
+		return wgNodeEndpoints{}, fmt.Errorf("missing public key")
+	}
+
+	endpoints := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(parts[1]), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		endpoint := fields[1]
+		if endpoint == "(none)" {
+			endpoint = ""
+		}
+		endpoints[fields[0]] = endpoint
+	}
+
+	return wgNodeEndpoints{Node: node, PublicKey: publicKey, Endpoints: endpoints}, nil
+}
+
+// planEndpointUpdates compares every node's peer endpoints with the other
+// nodes' current public IPs and returns the ones that changed
+func planEndpointUpdates(states []wgNodeEndpoints) []wgEndpointUpdate {
+	peersByKey := make(map[string]NodeInfo)
+	for _, state := range states {
+		peersByKey[state.PublicKey] = state.Node
+	}
+
+	var updates []wgEndpointUpdate
+	for _, state := range states {
+		var keys []string
+		for key := range state.Endpoints {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			peer, ok := peersByKey[key]
+			if !ok || peer.Name == state.Node.Name || peer.PublicIP == "" {
+				continue
+			}
+
+			want := net.JoinHostPort(peer.PublicIP, fmt.Sprintf("%d", wgPort))
+			if state.Endpoints[key] == want {
+				continue
+			}
+
+			updates = append(updates, wgEndpointUpdate{
+				Node:          state.Node,
+				PeerName:      peer.Name,
+				PeerPublicKey: key,
+				OldEndpoint:   state.Endpoints[key],
+				NewEndpoint:   want,
+			})
+		}
+	}
+
+	return updates
+}
+
+// generateEndpointUpdateScript creates a bash script that sets the new endpoints
+// on the live interface and rewrites them in wg0.conf so they survive a restart
+func generateEndpointUpdateScript(updates []wgEndpointUpdate) string {
+	var b strings.Builder
+
+	b.WriteString("set -e\n")
+	b.WriteString("sudo cp /etc/wireguard/wg0.conf /etc/wireguard/wg0.conf.backup-$(date +%Y%m%d-%H%M%S)\n")
+
+	for _, update := range updates {
+		key := strings.ReplaceAll(update.PeerPublicKey, "'", "'\\''")
+		endpoint := strings.ReplaceAll(update.NewEndpoint, "'", "'\\''")
+
+		fmt.Fprintf(&b, "sudo wg set wg0 peer '%s' endpoint '%s'\n", key, endpoint)
+
+		// Rewrite the Endpoint line inside the [Peer] block with this PublicKey,
+		// adding one if the block has none. The new file holds the private key,
+		// so it is created owner-only before anything is written to it.
+		b.WriteString("sudo install -m 600 /dev/null /etc/wireguard/wg0.conf.new\n")
+		fmt.Fprintf(&b, `sudo awk -v key='%s' -v endpoint='%s' '
+function flush(   i) {
+    for (i = 1; i <= n; i++) {
+        if (match_block && lines[i] ~ /^Endpoint[ \t]*=/) continue
+        print lines[i]
+        if (match_block && lines[i] ~ /^PublicKey[ \t]*=/) print "Endpoint = " endpoint
+    }
+    n = 0; match_block = 0
+}
+/^\[/ { flush() }
+{
+    lines[++n] = $0
+    if ($0 ~ /^PublicKey[ \t]*=/) {
+        value = $0; sub(/^PublicKey[ \t]*=[ \t]*/, "", value)
+        if (value == key) match_block = 1
+    }
+}
+END { flush() }
+' /etc/wireguard/wg0.conf | sudo tee /etc/wireguard/wg0.conf.new > /dev/null
+sudo mv /etc/wireguard/wg0.conf.new /etc/wireguard/wg0.conf
+`, key, endpoint)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/spf13/cobra"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+// wgReadPeersScript prints `wg show wg0 dump` without its header, a
+// separator, then wg0.conf for the peer labels
+const wgReadPeersScript = `sudo wg show wg0 dump | tail -n +2 && echo "---" && sudo cat /etc/wireguard/wg0.conf`
+
+// wgPeerLookup is the peer a node has for a VPN IP
+type wgPeerLookup struct {
+	Node         NodeInfo
+	PublicKey    string // Empty when the node has no peer for the IP
+	PresharedKey string // Empty when the peer has none
+}
+
+// findPeerByIP returns the public key, preshared key and label of the peer
+// with vpnIP in the output of wgReadPeersScript, or empty strings when there
+// is none
+func findPeerByIP(output, vpnIP string) (publicKey, presharedKey, label string) {
+	dump, conf, _ := strings.Cut(output, "---\n")
+	for _, peer := range parseWGDump(dump) {
+		if peer.VPNIP() == vpnIP {
+			publicKey, presharedKey = peer.PublicKey, peer.PresharedKey
+			break
+		}
+	}
+	if publicKey == "" {
+		return "", "", ""
+	}
+
+	// Labels are "# Peer: <label>" comments in the peer's section
+	var currentLabel string
+	for _, line := range strings.Split(conf, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "[Peer]":
+			currentLabel = ""
+		case strings.HasPrefix(line, "# Peer:"):
+			currentLabel = strings.TrimSpace(strings.TrimPrefix(line, "# Peer:"))
+		case strings.HasPrefix(line, "PublicKey"):
+			if _, key, ok := strings.Cut(line, "="); ok && strings.TrimSpace(key) == publicKey {
+				return publicKey, presharedKey, currentLabel
+			}
+		}
+	}
+	return publicKey, presharedKey, ""
+}
+
+// rotatedPeerAddScript adds the client peer with its new key to a node whose
+// old key was removed, and syncs the interface. Only this peer is touched:
+// unlike a join, the other clients of the node are kept. The preshared key
+// the peer had is carried over.
+func rotatedPeerAddScript(peerIP, publicKey, label, presharedKey string) string {
+	comment := "Client joined via CLI"
+	if label != "" {
+		comment = "Peer: " + label
+	}
+	pskLine := ""
+	if presharedKey != "" {
+		pskLine = "PresharedKey = " + presharedKey + "\n"
+	}
+	escapedKey := strings.ReplaceAll(publicKey, "'", "'\\''")
+
+	return fmt.Sprintf(`set -e
+sudo cp /etc/wireguard/wg0.conf /etc/wireguard/wg0.conf.backup-$(date +%%Y%%m%%d-%%H%%M%%S)
+
+if ! sudo grep -qF 'PublicKey = %s' /etc/wireguard/wg0.conf; then
+sudo tee -a /etc/wireguard/wg0.conf > /dev/null << 'WGEOF'
+
+[Peer]
+# %s
+PublicKey = %s
+%sAllowedIPs = %s/32
+PersistentKeepalive = 25
+WGEOF
+fi
+
+sudo wg-quick strip wg0 | sudo wg syncconf wg0 /dev/stdin
+
+if ! { %s; }; then
+    echo "Peer is not in both /etc/wireguard/wg0.conf and the running interface" >&2
+    exit 1
+fi
+`, escapedKey, comment, publicKey, pskLine, peerIP, peerStateCheck("sudo ", escapedKey, true))
+}
+
+// keyFingerprint shortens a public key for display
+func keyFingerprint(publicKey string) string {
+	if len(publicKey) <= 16 {
+		return publicKey
+	}
+	return publicKey[:16] + "..."
+}
+
+func runVPNRotateKeys(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("🔑 Rotate VPN Keys - Stack: %s", stack))
+	printInfo(fmt.Sprintf("Rotating keys of peer with VPN IP: %s", vpnRotateIP))
+
+	// Create workspace with S3 support
+	workspace, err := createWorkspaceWithS3Support(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	// Use fully qualified stack name for S3 backend
+	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
+	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
+	if err != nil {
+		return selectStackError(err, stack)
+	}
+
+	outputs, err := stackOutputsWithRetry(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	// Parse nodes
+	nodes, err := ParseNodeOutputs(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	var meshNodes []NodeInfo
+	for _, node := range nodes {
+		if node.WireGuardIP != "" {
+			meshNodes = append(meshNodes, node)
+		}
+	}
+	if len(meshNodes) == 0 {
+		return fmt.Errorf("no nodes found in stack")
+	}
+
+	access := newNodeSSHAccess(stack, outputs)
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
+	}
+
+	// Look the peer up on every node before changing any, so a node that
+	// can't be read doesn't keep trusting the old key
+	fmt.Println()
+	printInfo(fmt.Sprintf("Looking up the peer on %d cluster nodes...", len(meshNodes)))
+
+	var lookups []wgPeerLookup
+	var unreachable []string
+	var oldKey, presharedKey, label string
+	for _, node := range meshNodes {
+		output, err := access.runScript(node, 10, wgReadPeersScript)
+		if err != nil {
+			unreachable = append(unreachable, node.Name)
+			continue
+		}
+		publicKey, peerPSK, peerLabel := findPeerByIP(string(output), vpnRotateIP)
+		lookups = append(lookups, wgPeerLookup{Node: node, PublicKey: publicKey, PresharedKey: peerPSK})
+		if publicKey != "" && oldKey == "" {
+			oldKey, presharedKey, label = publicKey, peerPSK, peerLabel
+		}
+	}
+	if len(unreachable) > 0 {
+		return errs.Mark(fmt.Errorf("cannot read WireGuard peers from %s; no keys were rotated", strings.Join(unreachable, ", ")), errs.ErrNodeUnreachable)
+	}
+	if oldKey == "" {
+		return fmt.Errorf("no peer with VPN IP %s found on any node; no keys were rotated", vpnRotateIP)
+	}
+	printInfo(fmt.Sprintf("Found peer public key: %s", keyFingerprint(oldKey)))
+
+	privateKey, publicKey, err := generateWireGuardKeypair()
+	if err != nil {
+		return fmt.Errorf("failed to generate keypair: %w", err)
+	}
+	printSuccess(fmt.Sprintf("Generated keypair (public key: %s)", keyFingerprint(publicKey)))
+
+	fmt.Println()
+	printInfo("Replacing the peer key on all cluster nodes...")
+
+	// Replace the key node by node, stopping before the next node on Ctrl-C
+	tracker := newNodeChangeTracker()
+	defer tracker.Stop()
+
+	var failed []string
+	for i, lookup := range lookups {
+		if tracker.Interrupted() {
+			break
+		}
+		node := lookup.Node
+		tracker.Begin(node.Name)
+
+		if lookup.PublicKey != "" {
+			output, err := access.run(node, 10, peerRemoveCommand(lookup.PublicKey))
+			if err != nil && tracker.Interrupted() {
+				break
+			}
+			if err != nil || strings.TrimSpace(string(output)) != "SUCCESS" {
+				tracker.Done(false)
+				fmt.Printf("  [%d/%d] ✗ Failed to remove old key from %s\n", i+1, len(lookups), node.Name)
+				failed = append(failed, node.Name)
+				continue
+			}
+		}
+
+		// Nodes that did not have the peer get the preshared key the client
+		// config will carry
+		peerPSK := lookup.PresharedKey
+		if lookup.PublicKey == "" {
+			peerPSK = presharedKey
+		}
+		output, err := access.runScript(node, 10, rotatedPeerAddScript(vpnRotateIP, publicKey, label, peerPSK))
+		if err != nil && tracker.Interrupted() {
+			break
+		}
+		tracker.Done(err == nil)
+		if err != nil {
+			fmt.Printf("  [%d/%d] ✗ Failed to add new key to %s: %s\n", i+1, len(lookups), node.Name, strings.TrimSpace(string(output)))
+			failed = append(failed, node.Name)
+			continue
+		}
+		fmt.Printf("  [%d/%d] ✓ Rotated key on %s\n", i+1, len(lookups), node.Name)
+	}
+
+	if tracker.Interrupted() {
+		names := make([]string, len(lookups))
+		for i, lookup := range lookups {
+			names[i] = lookup.Node.Name
+		}
+		tracker.Report(names, fmt.Sprintf("sloth-kubernetes vpn rotate-keys %s --vpn-ip %s", stack, vpnRotateIP))
+		return tracker.interruptedError("vpn rotate-keys")
+	}
+
+	// The config is written even after failures: the nodes that were
+	// rotated only accept the new key
+	configPath := vpnRotateOutput
+	if configPath == "" {
+		configPath, err = artifactPath(stack, fmt.Sprintf("wg0-%s.conf", vpnRotateIP))
+		if err != nil {
+			return err
+		}
+	}
+
+	var existingPeers []VPNPeerInfo
+	subnet := vpnSubnet(outputs)
+	for _, peer := range discoverVPNClients(lookups[0].Node, access, subnet) {
+		if peer.PublicKey != publicKey && peer.PublicKey != oldKey {
+			existingPeers = append(existingPeers, peer)
+		}
+	}
+	clientConfig := generateClientConfig(access, privateKey, vpnRotateIP, subnet, label, presharedKey, nodes, existingPeers, nil, true, nil)
+	if err := os.WriteFile(configPath, []byte(clientConfig), 0600); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	fmt.Println()
+	printSuccess(fmt.Sprintf("Client configuration saved to: %s", configPath))
+	fmt.Printf("  Old public key: %s\n", keyFingerprint(oldKey))
+	fmt.Printf("  New public key: %s\n", keyFingerprint(publicKey))
+
+	if len(failed) > 0 {
+		fmt.Println()
+		color.Yellow(fmt.Sprintf("⚠️  Key rotated on %d/%d nodes", len(lookups)-len(failed), len(lookups)))
+		return fmt.Errorf("failed to rotate key on: %s", strings.Join(failed, ", "))
+	}
+
+	fmt.Println()
+	printSuccess("Key rotated on all nodes")
+	fmt.Println()
+	color.Cyan(fmt.Sprintf("Install the new config on the peer (%s):", vpnRotateIP))
+	fmt.Printf("  sudo cp %s /etc/wireguard/wg0.conf && sudo wg-quick down wg0; sudo wg-quick up wg0\n", configPath)
+	return nil
+}
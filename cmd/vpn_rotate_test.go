@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindPeerByIP(t *testing.T) {
+	output := "nodeKey=\t(none)\t203.0.113.10:51820\t10.8.0.11/32\t0\t0\t0\t25\n" +
+		"laptopKey=\t(none)\t(none)\t10.8.0.100/32\t0\t0\t0\t25\n" +
+		"ciKey=\t(none)\t(none)\t10.8.0.101/32\t0\t0\t0\t25\n" +
+		"---\n" +
+		"[Interface]\nPrivateKey = secret\n\n" +
+		"[Peer]\n# Peer: laptop\nPublicKey = laptopKey=\nAllowedIPs = 10.8.0.100/32\n\n" +
+		"[Peer]\n# Client joined via CLI\nPublicKey = ciKey=\nAllowedIPs = 10.8.0.101/32\n"
+
+	tests := []struct {
+		ip        string
+		wantKey   string
+		wantLabel string
+	}{
+		{ip: "10.8.0.100", wantKey: "laptopKey=", wantLabel: "laptop"},
+		{ip: "10.8.0.101", wantKey: "ciKey="},
+		{ip: "10.8.0.102"},
+	}
+
+	for _, tt := range tests {
+		key, _, label := findPeerByIP(output, tt.ip)
+		if key != tt.wantKey || label != tt.wantLabel {
+			t.Errorf("findPeerByIP(%s) = (%q, %q), want (%q, %q)", tt.ip, key, label, tt.wantKey, tt.wantLabel)
+		}
+	}
+}
+
+func TestFindPeerByIP_PresharedKey(t *testing.T) {
+	output := "laptopKey=\tPSK=\t(none)\t10.8.0.100/32\t0\t0\t0\t25\n---\n"
+	if key, psk, _ := findPeerByIP(output, "10.8.0.100"); key != "laptopKey=" || psk != "PSK=" {
+		t.Errorf("Expected the peer's preshared key, got (%q, %q)", key, psk)
+	}
+}
+
+func TestRotatePeer_KeepsOtherClients(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	dir := t.TempDir()
+	initial := "[Interface]\nPrivateKey = server\nAddress = 10.8.0.10/24\n\n" +
+		"[Peer]\n# Node: master-2\nPublicKey = NODE=\nAllowedIPs = 10.8.0.11/32\n\n" +
+		"[Peer]\n# Peer: laptop\nPublicKey = OLD=\nPresharedKey = PSK=\nAllowedIPs = 10.8.0.100/32\n\n" +
+		"[Peer]\n# Peer: ci\nPublicKey = CI=\nAllowedIPs = 10.8.0.101/32\n"
+	if err := os.WriteFile(filepath.Join(dir, "wg0.conf"), []byte(initial), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if output, err := runPeerScriptFixture(t, dir, peerRemoveCommand("OLD="), false); err != nil || strings.TrimSpace(output) != "SUCCESS" {
+		t.Fatalf("Expected the old key removed, got %v: %s", err, output)
+	}
+	if output, err := runPeerScriptFixture(t, dir, rotatedPeerAddScript("10.8.0.100", "NEW=", "laptop", "PSK="), false); err != nil {
+		t.Fatalf("Peer add failed: %v\n%s", err, output)
+	}
+
+	saved, _ := os.ReadFile(filepath.Join(dir, "wg0.conf"))
+	if strings.Contains(string(saved), "OLD=") {
+		t.Errorf("Expected the old key gone, got:\n%s", saved)
+	}
+	if !strings.Contains(string(saved), "# Peer: laptop\nPublicKey = NEW=\nPresharedKey = PSK=\nAllowedIPs = 10.8.0.100/32") {
+		t.Errorf("Expected the new key with the old preshared key, got:\n%s", saved)
+	}
+	for _, kept := range []string{"PublicKey = NODE=", "# Peer: ci\nPublicKey = CI=\nAllowedIPs = 10.8.0.101/32"} {
+		if !strings.Contains(string(saved), kept) {
+			t.Errorf("Expected %q to survive the rotation, got:\n%s", kept, saved)
+		}
+	}
+	running, _ := os.ReadFile(filepath.Join(dir, "running"))
+	if !strings.Contains(string(running), "CI=\n") || !strings.Contains(string(running), "NEW=\n") {
+		t.Errorf("Expected the other client and the new key on the interface, got:\n%s", running)
+	}
+}
@@ -0,0 +1,311 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+// errNoReachableNode is returned when no cluster node answers over SSH
+var errNoReachableNode = errs.Mark(errors.New("no cluster node reachable — check VPN/bastion"), errs.ErrNodeUnreachable)
+
+// sshRunner executes ssh with the given arguments and returns its combined output.
+// When stdin is non-empty it is piped to the remote command.
+// It is a variable so tests can simulate node reachability without a network.
+var sshRunner = func(args []string, stdin string) ([]byte, error) {
+	ctx, cancel := sshCallContext()
+	defer cancel()
+	output, err := runWithContext(ctx, "ssh", args, stdin)
+	if err != nil {
+		warnHostKeyChanged(args, output)
+	}
+	return output, err
+}
+
+// nodeSSHAccess holds what is needed to reach cluster nodes over SSH,
+// either directly or through the bastion host
+type nodeSSHAccess struct {
+	Stack          string
+	KeyPath        string
+	BastionEnabled bool
+	BastionIP      string
+	// BastionUser is the login user of the bastion, root when empty
+	BastionUser string
+	// JumpHosts are the ssh destinations in front of the bastion, outermost
+	// first
+	JumpHosts []string
+}
+
+// newNodeSSHAccess builds SSH access details for a stack from its outputs
+func newNodeSSHAccess(stack string, outputs auto.OutputMap) nodeSSHAccess {
+	bastionEnabled, bastionIP := getBastionInfo(outputs)
+	return nodeSSHAccess{
+		Stack:          stack,
+		KeyPath:        GetSSHKeyPath(stack),
+		BastionEnabled: bastionEnabled,
+		BastionIP:      bastionIP,
+		BastionUser:    getBastionUser(outputs),
+		JumpHosts:      getBastionJumpHosts(outputs),
+	}
+}
+
+// getBastionInfo reports whether the stack has a bastion and its public IP
+func getBastionInfo(outputs auto.OutputMap) (bool, string) {
+	bastionEnabled := false
+	bastionIP := ""
+
+	if bastionEnabledOutput, ok := outputs["bastion_enabled"]; ok {
+		if bastionEnabledOutput.Value != nil {
+			bastionEnabled = bastionEnabledOutput.Value == true
+		}
+	}
+
+	if bastionEnabled {
+		if bastionOutput, ok := outputs["bastion"]; ok {
+			if bastionMap, ok := bastionOutput.Value.(map[string]interface{}); ok {
+				if pubIP, ok := bastionMap["public_ip"].(string); ok {
+					bastionIP = pubIP
+				}
+			}
+		}
+	}
+
+	return bastionEnabled, bastionIP
+}
+
+// getBastionUser returns the login user of the stack's bastion, which
+// depends on its provider like a node's
+func getBastionUser(outputs auto.OutputMap) string {
+	bastionMap, ok := outputs["bastion"].Value.(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	provider, _ := bastionMap["provider"].(string)
+	return getSSHUserForNode(provider)
+}
+
+// getBastionJumpHosts returns the jump hosts in front of the stack's bastion,
+// outermost first
+func getBastionJumpHosts(outputs auto.OutputMap) []string {
+	bastionMap, ok := outputs["bastion"].Value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	hosts, _ := bastionMap["jump_hosts"].([]interface{})
+
+	var jumpHosts []string
+	for _, host := range hosts {
+		if address, ok := host.(string); ok && address != "" {
+			jumpHosts = append(jumpHosts, address)
+		}
+	}
+	return jumpHosts
+}
+
+// viaBastion reports whether connections should be proxied through the bastion
+func (a nodeSSHAccess) viaBastion() bool {
+	return a.BastionEnabled && a.BastionIP != ""
+}
+
+// targetIP returns the address used to reach a node. Through the bastion the
+// VPN address is preferred (falling back to private, then public IP);
+// direct connections always use the public IP.
+func (a nodeSSHAccess) targetIP(node NodeInfo) string {
+	if !a.viaBastion() {
+		return node.PublicIP
+	}
+
+	targetIP := node.WireGuardIP
+	if targetIP == "" {
+		targetIP = node.PrivateIP
+		if targetIP == "" {
+			targetIP = node.PublicIP
+		}
+	}
+	return targetIP
+}
+
+// sshQuietOption silences ssh's diagnostics. When host keys are verified,
+// errors are still logged so a changed key is reported.
+func sshQuietOption() string {
+	if verifyHostKeys {
+		return "-oLogLevel=ERROR"
+	}
+	return "-q"
+}
+
+// args builds the ssh arguments to run remoteCmd on node as user
+func (a nodeSSHAccess) args(node NodeInfo, user string, connectTimeout int, remoteCmd ...string) []string {
+	args := []string{
+		sshQuietOption(),
+		"-i", a.KeyPath,
+		"-o", "StrictHostKeyChecking=accept-new",
+		"-o", knownHostsOption(a.Stack),
+		"-o", fmt.Sprintf("ConnectTimeout=%d", connectTimeout),
+	}
+
+	if a.viaBastion() {
+		args = append(args, "-o", "ProxyCommand="+bastionProxyCommand(a.KeyPath, a.BastionUser, a.BastionIP, knownHostsOption(a.Stack), a.JumpHosts, true))
+	} else {
+		args = append(args, sshProxyOptions()...)
+	}
+
+	args = append(args, fmt.Sprintf("%s@%s", user, a.targetIP(node)))
+	return append(args, remoteCmd...)
+}
+
+// run executes remoteCmd as root on node. It logs in as the node's provider
+// user, the same one findReachableNode probes with, and goes through sudo
+// when that user is not root.
+func (a nodeSSHAccess) run(node NodeInfo, connectTimeout int, remoteCmd string) ([]byte, error) {
+	user := getSSHUserForNode(node.Provider)
+	if user != "root" {
+		remoteCmd = fmt.Sprintf("sudo bash -c '%s'", strings.ReplaceAll(remoteCmd, "'", "'\\''"))
+	}
+	return sshRunner(a.args(node, user, connectTimeout, remoteCmd), "")
+}
+
+// bastionArgs builds the ssh arguments to run remoteCmd on the bastion itself,
+// through the jump hosts in front of it if there are any
+func (a nodeSSHAccess) bastionArgs(connectTimeout int, remoteCmd ...string) []string {
+	args := []string{
+		sshQuietOption(),
+		"-i", a.KeyPath,
+		"-o", "StrictHostKeyChecking=accept-new",
+		"-o", knownHostsOption(a.Stack),
+		"-o", fmt.Sprintf("ConnectTimeout=%d", connectTimeout),
+	}
+	if len(a.JumpHosts) > 0 {
+		args = append(args, "-J", strings.Join(a.JumpHosts, ","))
+	} else {
+		args = append(args, sshProxyOptions()...)
+	}
+
+	user := a.BastionUser
+	if user == "" {
+		user = "root"
+	}
+	args = append(args, fmt.Sprintf("%s@%s", user, a.BastionIP))
+	return append(args, remoteCmd...)
+}
+
+// checkBastionReachable connects to the bastion before a command reaches
+// nodes through it, so an operator whose IP the bastion firewall does not
+// allow gets one clear error instead of a ProxyCommand failure per node
+func checkBastionReachable(ctx context.Context, access nodeSSHAccess) error {
+	if !access.viaBastion() {
+		return nil
+	}
+	if _, err := sshRunner(access.bastionArgs(5, "true"), ""); err == nil {
+		return nil
+	}
+
+	currentIP := "unknown"
+	if ip, err := detectPublicIP(ctx); err == nil {
+		currentIP = strings.TrimSpace(ip)
+	}
+	return errs.Mark(fmt.Errorf("cannot reach bastion at %s — is your IP allowed in the bastion firewall? Current public IP: %s (allow it with: sloth-kubernetes bastion allow-ip %s)",
+		access.BastionIP, currentIP, access.Stack), errs.ErrNodeUnreachable)
+}
+
+// isControlPlaneNode reports whether a node carries a control-plane role
+func isControlPlaneNode(node NodeInfo) bool {
+	for _, role := range node.Roles {
+		switch role {
+		case "master", "controlplane", "control-plane":
+			return true
+		}
+	}
+	return false
+}
+
+// findReachableNode probes the cluster nodes over SSH and returns the first one
+// that responds. Control-plane nodes are tried first, then the rest, each group
+// in name order so the choice is stable across runs. When the nodes span
+// several regions all are probed at once and the fastest to answer is used,
+// so reads go to the region closest to the operator.
+func findReachableNode(nodes []NodeInfo, access nodeSSHAccess) (NodeInfo, error) {
+	candidates := make([]NodeInfo, len(nodes))
+	copy(candidates, nodes)
+	sort.SliceStable(candidates, func(i, j int) bool {
+		mi, mj := isControlPlaneNode(candidates[i]), isControlPlaneNode(candidates[j])
+		if mi != mj {
+			return mi
+		}
+		return candidates[i].Name < candidates[j].Name
+	})
+
+	probe := func(node NodeInfo) error {
+		user := getSSHUserForNode(node.Provider)
+		_, err := sshRunner(access.args(node, user, 5, "true"), "")
+		if err != nil && verbose {
+			printWarning(fmt.Sprintf("Node %s (%s) is not reachable", node.Name, access.targetIP(node)))
+		}
+		return err
+	}
+
+	if multiRegion(candidates) {
+		return pickClosestNode(candidates, probe)
+	}
+
+	for _, node := range candidates {
+		if probe(node) == nil {
+			return node, nil
+		}
+	}
+
+	return NodeInfo{}, errNoReachableNode
+}
+
+// multiRegion reports whether nodes are spread over more than one region
+func multiRegion(nodes []NodeInfo) bool {
+	region := ""
+	for _, node := range nodes {
+		if node.Region == "" {
+			continue
+		}
+		if region != "" && node.Region != region {
+			return true
+		}
+		region = node.Region
+	}
+	return false
+}
+
+// pickClosestNode probes all nodes concurrently and returns the first that
+// answers, i.e. the one with the shortest connect time from this machine.
+// probe returns nil when the node is reachable.
+func pickClosestNode(nodes []NodeInfo, probe func(NodeInfo) error) (NodeInfo, error) {
+	type probeResult struct {
+		node NodeInfo
+		err  error
+	}
+
+	// Buffered so probes still running after the winner never block
+	results := make(chan probeResult, len(nodes))
+	for _, node := range nodes {
+		go func(node NodeInfo) {
+			results <- probeResult{node: node, err: probe(node)}
+		}(node)
+	}
+
+	for range nodes {
+		if result := <-results; result.err == nil {
+			return result.node, nil
+		}
+	}
+
+	return NodeInfo{}, errNoReachableNode
+}
+
+// runScript pipes script to bash on node as the provider's SSH user
+func (a nodeSSHAccess) runScript(node NodeInfo, connectTimeout int, script string) ([]byte, error) {
+	user := getSSHUserForNode(node.Provider)
+	return sshRunner(a.args(node, user, connectTimeout, "bash", "-s"), script)
+}
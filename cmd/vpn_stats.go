@@ -0,0 +1,330 @@
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+	"github.com/spf13/cobra"
+)
+
+// wgTransferSample is one read of the WireGuard counters on a node
+type wgTransferSample struct {
+	Node  string
+	Taken time.Time
+	Peers []wgDumpPeer
+}
+
+// wgLinkRate is the measured throughput between a node and one of its peers
+type wgLinkRate struct {
+	Node      string
+	Peer      string
+	VPNIP     string
+	PublicKey string
+	Seconds   float64
+	RxBytes   int64
+	TxBytes   int64
+	RxRate    float64
+	TxRate    float64
+}
+
+// Total returns the combined throughput of the link in bytes/sec
+func (r wgLinkRate) Total() float64 {
+	return r.RxRate + r.TxRate
+}
+
+// wgNodeRate is the aggregated throughput of a node across all its peers
+type wgNodeRate struct {
+	Node   string
+	RxRate float64
+	TxRate float64
+}
+
+func runVPNStats(cmd *cobra.Command, args []string) error {
+	if vpnStatsInterval < 1 {
+		return fmt.Errorf("--interval must be at least 1 second")
+	}
+
+	ctx := context.Background()
+	stack, err := getStackFromArgs(args, 0)
+	if err != nil {
+		return err
+	}
+
+	printHeader(fmt.Sprintf("📈 VPN Transfer Stats - Stack: %s", stack))
+
+	// Create workspace with S3 support
+	workspace, err := createWorkspaceWithS3Support(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	// Use fully qualified stack name for S3 backend
+	fullyQualifiedStackName := fmt.Sprintf("organization/sloth-kubernetes/%s", stack)
+	s, err := auto.SelectStack(ctx, fullyQualifiedStackName, workspace)
+	if err != nil {
+		return selectStackError(err, stack)
+	}
+
+	// Get outputs
+	outputs, err := stackOutputsWithRetry(ctx, s)
+	if err != nil {
+		return err
+	}
+
+	// Parse nodes
+	nodes, err := ParseNodeOutputs(outputs)
+	if err != nil {
+		return fmt.Errorf("failed to parse nodes: %w", err)
+	}
+
+	access := newNodeSSHAccess(stack, outputs)
+	if err := checkBastionReachable(ctx, access); err != nil {
+		return err
+	}
+
+	fmt.Println()
+	printInfo(fmt.Sprintf("Sampling transfer counters on %d nodes over %ds...", len(nodes), vpnStatsInterval))
+
+	before := sampleWGTransfer(nodes, access)
+	time.Sleep(time.Duration(vpnStatsInterval) * time.Second)
+	after := sampleWGTransfer(nodes, access)
+
+	// Name peers after cluster nodes where possible
+	peerNames := make(map[string]string)
+	for _, node := range nodes {
+		if node.WireGuardIP != "" {
+			peerNames[strings.TrimSuffix(node.WireGuardIP, "/32")] = node.Name
+		}
+	}
+
+	var links []wgLinkRate
+	for _, node := range nodes {
+		b, okBefore := before[node.Name]
+		a, okAfter := after[node.Name]
+		if !okBefore || !okAfter {
+			continue
+		}
+		links = append(links, computeWGLinkRates(b, a, peerNames)...)
+	}
+
+	if len(links) == 0 {
+		return fmt.Errorf("no transfer samples collected — check VPN/bastion")
+	}
+
+	fmt.Println()
+	printNodeRates(summarizeWGNodeRates(links))
+
+	fmt.Println()
+	printTopTalkers(topWGTalkers(links, vpnStatsTop))
+
+	if vpnStatsCSV != "" {
+		f, err := os.Create(vpnStatsCSV)
+		if err != nil {
+			return fmt.Errorf("failed to create CSV file: %w", err)
+		}
+		defer f.Close()
+
+		if err := writeWGStatsCSV(f, links); err != nil {
+			return fmt.Errorf("failed to write CSV: %w", err)
+		}
+
+		fmt.Println()
+		printSuccess(fmt.Sprintf("Samples written to %s", vpnStatsCSV))
+	}
+
+	return nil
+}
+
+// sampleWGTransfer reads the WireGuard counters on every node.
+// Nodes that can't be reached are skipped with a warning.
+func sampleWGTransfer(nodes []NodeInfo, access nodeSSHAccess) map[string]wgTransferSample {
+	samples := make(map[string]wgTransferSample)
+
+	for _, node := range nodes {
+		output, err := access.run(node, 5, "wg show wg0 dump | tail -n +2")
+		if err != nil {
+			color.Yellow(fmt.Sprintf("⚠  Failed to read counters from %s: %v", node.Name, err))
+			continue
+		}
+
+		samples[node.Name] = wgTransferSample{
+			Node:  node.Name,
+			Taken: time.Now(),
+			Peers: parseWGDump(string(output)),
+		}
+	}
+
+	return samples
+}
+
+// computeWGLinkRates computes per-peer throughput between two samples of the
+// same node. Peers missing from either sample are skipped, and a counter that
+// went backwards (interface restart) is counted from zero.
+func computeWGLinkRates(before, after wgTransferSample, peerNames map[string]string) []wgLinkRate {
+	seconds := after.Taken.Sub(before.Taken).Seconds()
+	if seconds <= 0 {
+		return nil
+	}
+
+	previous := make(map[string]wgDumpPeer)
+	for _, peer := range before.Peers {
+		previous[peer.PublicKey] = peer
+	}
+
+	var rates []wgLinkRate
+	for _, peer := range after.Peers {
+		prev, ok := previous[peer.PublicKey]
+		if !ok {
+			continue
+		}
+
+		rx := peer.RxBytes - prev.RxBytes
+		if rx < 0 {
+			rx = peer.RxBytes
+		}
+		tx := peer.TxBytes - prev.TxBytes
+		if tx < 0 {
+			tx = peer.TxBytes
+		}
+
+		vpnIP := peer.VPNIP()
+		name := peerNames[vpnIP]
+		if name == "" {
+			name = vpnIP
+		}
+
+		rates = append(rates, wgLinkRate{
+			Node:      after.Node,
+			Peer:      name,
+			VPNIP:     vpnIP,
+			PublicKey: peer.PublicKey,
+			Seconds:   seconds,
+			RxBytes:   rx,
+			TxBytes:   tx,
+			RxRate:    float64(rx) / seconds,
+			TxRate:    float64(tx) / seconds,
+		})
+	}
+
+	return rates
+}
+
+// summarizeWGNodeRates sums link throughput per node, sorted by node name
+func summarizeWGNodeRates(links []wgLinkRate) []wgNodeRate {
+	byNode := make(map[string]*wgNodeRate)
+	var order []string
+
+	for _, link := range links {
+		rate, ok := byNode[link.Node]
+		if !ok {
+			rate = &wgNodeRate{Node: link.Node}
+			byNode[link.Node] = rate
+			order = append(order, link.Node)
+		}
+		rate.RxRate += link.RxRate
+		rate.TxRate += link.TxRate
+	}
+
+	sort.Strings(order)
+	result := make([]wgNodeRate, 0, len(order))
+	for _, name := range order {
+		result = append(result, *byNode[name])
+	}
+	return result
+}
+
+// topWGTalkers returns the n busiest links by combined throughput
+func topWGTalkers(links []wgLinkRate, n int) []wgLinkRate {
+	sorted := make([]wgLinkRate, len(links))
+	copy(sorted, links)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Total() > sorted[j].Total()
+	})
+
+	if n > 0 && len(sorted) > n {
+		sorted = sorted[:n]
+	}
+	return sorted
+}
+
+// writeWGStatsCSV writes one row per link with its raw deltas and rates
+func writeWGStatsCSV(out io.Writer, links []wgLinkRate) error {
+	w := csv.NewWriter(out)
+
+	if err := w.Write([]string{"node", "peer", "vpn_ip", "public_key", "interval_seconds", "rx_bytes", "tx_bytes", "rx_bytes_per_sec", "tx_bytes_per_sec"}); err != nil {
+		return err
+	}
+
+	for _, link := range links {
+		record := []string{
+			link.Node,
+			link.Peer,
+			link.VPNIP,
+			link.PublicKey,
+			strconv.FormatFloat(link.Seconds, 'f', 3, 64),
+			strconv.FormatInt(link.RxBytes, 10),
+			strconv.FormatInt(link.TxBytes, 10),
+			strconv.FormatFloat(link.RxRate, 'f', 2, 64),
+			strconv.FormatFloat(link.TxRate, 'f', 2, 64),
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
+
+func printNodeRates(rates []wgNodeRate) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	defer w.Flush()
+
+	color.New(color.Bold).Fprintln(w, "NODE\tRX\tTX\tTOTAL")
+	fmt.Fprintln(w, "----\t--\t--\t-----")
+
+	for _, rate := range rates {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			rate.Node,
+			formatRate(rate.RxRate),
+			formatRate(rate.TxRate),
+			formatRate(rate.RxRate+rate.TxRate),
+		)
+	}
+}
+
+func printTopTalkers(links []wgLinkRate) {
+	color.New(color.Bold).Println("Top talkers")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	defer w.Flush()
+
+	color.New(color.Bold).Fprintln(w, "NODE\tPEER\tVPN IP\tRX\tTX\tTOTAL")
+	fmt.Fprintln(w, "----\t----\t------\t--\t--\t-----")
+
+	for _, link := range links {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			link.Node,
+			link.Peer,
+			link.VPNIP,
+			formatRate(link.RxRate),
+			formatRate(link.TxRate),
+			formatRate(link.Total()),
+		)
+	}
+}
+
+// formatRate formats a bytes/sec value using formatBytes units
+func formatRate(bytesPerSec float64) string {
+	return formatBytes(int64(bytesPerSec)) + "/s"
+}
@@ -0,0 +1,1272 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/auto"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+// stubSSHRunner replaces sshRunner for the duration of a test
+func stubSSHRunner(t *testing.T, fn func(args []string, stdin string) ([]byte, error)) {
+	t.Helper()
+	original := sshRunner
+	sshRunner = fn
+	t.Cleanup(func() { sshRunner = original })
+}
+
+// sshTarget returns the user@host argument from an ssh argument list
+func sshTarget(args []string) string {
+	for _, arg := range args {
+		if strings.Contains(arg, "@") && !strings.HasPrefix(arg, "ProxyCommand") {
+			return arg
+		}
+	}
+	return ""
+}
+
+func TestFindReachableNode_SkipsUnreachableNode(t *testing.T) {
+	nodes := []NodeInfo{
+		{Name: "worker-1", PublicIP: "203.0.113.20", Roles: []string{"worker"}},
+		{Name: "master-2", PublicIP: "203.0.113.11", Roles: []string{"master"}},
+		{Name: "master-1", PublicIP: "203.0.113.10", Roles: []string{"master"}},
+	}
+
+	var probed []string
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		target := sshTarget(args)
+		probed = append(probed, target)
+		if target == "root@203.0.113.10" {
+			return nil, errors.New("connection timed out")
+		}
+		return nil, nil
+	})
+
+	node, err := findReachableNode(nodes, nodeSSHAccess{KeyPath: "/tmp/key.pem"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if node.Name != "master-2" {
+		t.Errorf("Expected master-2 to be selected, got %q", node.Name)
+	}
+
+	expected := []string{"root@203.0.113.10", "root@203.0.113.11"}
+	if strings.Join(probed, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected probe order %v, got %v", expected, probed)
+	}
+}
+
+func TestNodeSSHAccessRun_UsesProbedUser(t *testing.T) {
+	nodes := []NodeInfo{{Name: "master-1", PublicIP: "203.0.113.10", Provider: "aws", Roles: []string{"master"}}}
+
+	var targets, commands []string
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		targets = append(targets, sshTarget(args))
+		commands = append(commands, args[len(args)-1])
+		return nil, nil
+	})
+
+	access := nodeSSHAccess{KeyPath: "/tmp/key.pem"}
+	node, err := findReachableNode(nodes, access)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := access.run(node, 5, "wg show wg0 | grep 'peer'"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(targets) != 2 || targets[0] != "ubuntu@203.0.113.10" || targets[1] != targets[0] {
+		t.Errorf("Expected probe and command as ubuntu@203.0.113.10, got %v", targets)
+	}
+	if want := `sudo bash -c 'wg show wg0 | grep '\''peer'\'''`; commands[1] != want {
+		t.Errorf("Expected command run through sudo, got %q", commands[1])
+	}
+}
+
+func TestFindReachableNode_NoneReachable(t *testing.T) {
+	nodes := []NodeInfo{
+		{Name: "master-1", PublicIP: "203.0.113.10", Roles: []string{"master"}},
+		{Name: "worker-1", PublicIP: "203.0.113.20", Roles: []string{"worker"}},
+	}
+
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	_, err := findReachableNode(nodes, nodeSSHAccess{KeyPath: "/tmp/key.pem"})
+	if err == nil {
+		t.Fatal("Expected error when no node is reachable")
+	}
+
+	if !strings.Contains(err.Error(), "no cluster node reachable") {
+		t.Errorf("Unexpected error message: %v", err)
+	}
+}
+
+func TestPickClosestNode_FastestResponder(t *testing.T) {
+	nodes := []NodeInfo{
+		{Name: "master-us", Region: "nyc3"},
+		{Name: "master-eu", Region: "fra1"},
+		{Name: "master-ap", Region: "sgp1"},
+		{Name: "worker-eu", Region: "fra1"},
+	}
+	latency := map[string]time.Duration{
+		"master-us": 80 * time.Millisecond,
+		"master-eu": 20 * time.Millisecond,
+		"master-ap": 150 * time.Millisecond,
+		"worker-eu": 1 * time.Millisecond,
+	}
+
+	node, err := pickClosestNode(nodes, func(node NodeInfo) error {
+		time.Sleep(latency[node.Name])
+		if node.Name == "worker-eu" {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if node.Name != "master-eu" {
+		t.Errorf("Expected the fastest reachable node master-eu, got %q", node.Name)
+	}
+}
+
+func TestPickClosestNode_NoneReachable(t *testing.T) {
+	nodes := []NodeInfo{{Name: "master-us", Region: "nyc3"}, {Name: "master-eu", Region: "fra1"}}
+
+	_, err := pickClosestNode(nodes, func(node NodeInfo) error {
+		return errors.New("connection timed out")
+	})
+	if err == nil || !strings.Contains(err.Error(), "no cluster node reachable") {
+		t.Errorf("Expected no reachable node error, got %v", err)
+	}
+}
+
+func TestFindReachableNode_MultiRegionPrefersClosest(t *testing.T) {
+	nodes := []NodeInfo{
+		{Name: "master-1", PublicIP: "203.0.113.10", Region: "sgp1", Roles: []string{"master"}},
+		{Name: "worker-1", PublicIP: "203.0.113.20", Region: "fra1", Roles: []string{"worker"}},
+	}
+
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		if sshTarget(args) == "root@203.0.113.10" {
+			time.Sleep(100 * time.Millisecond)
+		}
+		return nil, nil
+	})
+
+	node, err := findReachableNode(nodes, nodeSSHAccess{KeyPath: "/tmp/key.pem"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if node.Name != "worker-1" {
+		t.Errorf("Expected the closer worker-1 to be selected, got %q", node.Name)
+	}
+}
+
+func TestMultiRegion(t *testing.T) {
+	if multiRegion([]NodeInfo{{Region: "fra1"}, {Region: "fra1"}, {}}) {
+		t.Error("Expected a single region")
+	}
+	if !multiRegion([]NodeInfo{{Region: "fra1"}, {Region: "nyc3"}}) {
+		t.Error("Expected multiple regions")
+	}
+}
+
+func TestNodeSSHAccessArgs_ViaBastion(t *testing.T) {
+	access := nodeSSHAccess{KeyPath: "/tmp/key.pem", BastionEnabled: true, BastionIP: "198.51.100.5"}
+	node := NodeInfo{Name: "master-1", PublicIP: "203.0.113.10", PrivateIP: "10.10.0.2", WireGuardIP: "10.8.0.10"}
+
+	args := access.args(node, "root", 5, "true")
+	joined := strings.Join(args, " ")
+
+	if !strings.Contains(joined, "root@198.51.100.5") {
+		t.Error("Expected ProxyCommand through the bastion")
+	}
+	if sshTarget(args) != "root@10.8.0.10" {
+		t.Errorf("Expected VPN IP target through bastion, got %q", sshTarget(args))
+	}
+	if args[len(args)-1] != "true" {
+		t.Errorf("Expected remote command last, got %q", args[len(args)-1])
+	}
+
+	direct := nodeSSHAccess{KeyPath: "/tmp/key.pem"}
+	if target := sshTarget(direct.args(node, "ubuntu", 5)); target != "ubuntu@203.0.113.10" {
+		t.Errorf("Expected public IP target for direct SSH, got %q", target)
+	}
+}
+
+func TestNodeSSHAccessArgs_WithSSHProxy(t *testing.T) {
+	sshProxyCommand = "nc -X connect -x proxy.example:3128 %h %p"
+	defer func() { sshProxyCommand = "" }()
+
+	node := NodeInfo{Name: "master-1", PublicIP: "203.0.113.10", WireGuardIP: "10.8.0.10"}
+
+	direct := strings.Join(nodeSSHAccess{KeyPath: "/tmp/key.pem"}.args(node, "root", 5, "true"), " ")
+	if !strings.Contains(direct, "ProxyCommand=nc -X connect -x proxy.example:3128 %h %p") {
+		t.Errorf("Expected direct SSH through the proxy, got %q", direct)
+	}
+
+	access := nodeSSHAccess{KeyPath: "/tmp/key.pem", BastionEnabled: true, BastionIP: "198.51.100.5"}
+	viaBastion := strings.Join(access.args(node, "root", 5, "true"), " ")
+	if !strings.Contains(viaBastion, "-o 'ProxyCommand=nc -X connect -x proxy.example:3128 %%h %%p' -W %h:%p root@198.51.100.5") {
+		t.Errorf("Expected bastion hop through the proxy, got %q", viaBastion)
+	}
+}
+
+func TestNodeSSHAccessArgs_JumpChain(t *testing.T) {
+	access := nodeSSHAccess{
+		KeyPath:        "/tmp/key.pem",
+		BastionEnabled: true,
+		BastionIP:      "198.51.100.5",
+		JumpHosts:      []string{"ops@edge.example.com", "10.0.0.5:2222"},
+	}
+	node := NodeInfo{Name: "master-1", PublicIP: "203.0.113.10", WireGuardIP: "10.8.0.10"}
+
+	joined := strings.Join(access.args(node, "root", 5, "true"), " ")
+	if !strings.Contains(joined, "-J ops@edge.example.com,10.0.0.5:2222 -W %h:%p root@198.51.100.5") {
+		t.Errorf("Expected the bastion hop to chain both jump hosts in order, got %q", joined)
+	}
+
+	sshProxyCommand = "nc -X connect -x proxy.example:3128 %h %p"
+	defer func() { sshProxyCommand = "" }()
+	if joined := strings.Join(access.args(node, "root", 5, "true"), " "); strings.Contains(joined, "proxy.example") {
+		t.Errorf("Expected the jump hosts rather than the proxy in front of the bastion, got %q", joined)
+	}
+}
+
+func TestCheckBastionReachable_Unreachable(t *testing.T) {
+	var targets []string
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		targets = append(targets, sshTarget(args))
+		return nil, errors.New("exit status 255")
+	})
+	stubDetectPublicIP(t, "203.0.113.7\n", nil)
+
+	access := nodeSSHAccess{Stack: "production", KeyPath: "/tmp/key.pem", BastionEnabled: true, BastionIP: "198.51.100.5"}
+	err := checkBastionReachable(context.Background(), access)
+	if err == nil {
+		t.Fatal("Expected an error when the bastion does not answer")
+	}
+	if !strings.Contains(err.Error(), "cannot reach bastion at 198.51.100.5 — is your IP allowed in the bastion firewall? Current public IP: 203.0.113.7") {
+		t.Errorf("Unexpected message: %v", err)
+	}
+	if !strings.Contains(err.Error(), "bastion allow-ip production") {
+		t.Errorf("Expected the allow-ip hint, got %v", err)
+	}
+	if !errors.Is(err, errs.ErrNodeUnreachable) {
+		t.Error("Expected the error to be marked as node unreachable")
+	}
+	if len(targets) != 1 || targets[0] != "root@198.51.100.5" {
+		t.Errorf("Expected a single connect to the bastion itself, got %v", targets)
+	}
+}
+
+func TestCheckBastionReachable_PublicIPUnknown(t *testing.T) {
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		return nil, errors.New("exit status 255")
+	})
+	stubDetectPublicIP(t, "", errors.New("offline"))
+
+	access := nodeSSHAccess{KeyPath: "/tmp/key.pem", BastionEnabled: true, BastionIP: "198.51.100.5"}
+	err := checkBastionReachable(context.Background(), access)
+	if err == nil || !strings.Contains(err.Error(), "Current public IP: unknown") {
+		t.Errorf("Expected the public IP reported as unknown, got %v", err)
+	}
+}
+
+func TestCheckBastionReachable_Reachable(t *testing.T) {
+	calls := 0
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		calls++
+		return nil, nil
+	})
+
+	access := nodeSSHAccess{KeyPath: "/tmp/key.pem", BastionEnabled: true, BastionIP: "198.51.100.5"}
+	if err := checkBastionReachable(context.Background(), access); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+	if err := checkBastionReachable(context.Background(), nodeSSHAccess{KeyPath: "/tmp/key.pem"}); err != nil {
+		t.Errorf("Expected no check without a bastion, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("Expected one connect, got %d", calls)
+	}
+}
+
+func TestNodeSSHAccessArgs_BastionUser(t *testing.T) {
+	outputs := auto.OutputMap{
+		"bastion_enabled": {Value: true},
+		"bastion":         {Value: map[string]interface{}{"public_ip": "198.51.100.5", "provider": "aws"}},
+	}
+	access := newNodeSSHAccess("prod", outputs)
+	node := NodeInfo{Name: "master-1", PublicIP: "203.0.113.10", WireGuardIP: "10.8.0.10"}
+
+	joined := strings.Join(access.args(node, "root", 5, "true"), " ")
+	if !strings.Contains(joined, "-W %h:%p ubuntu@198.51.100.5") {
+		t.Errorf("Expected the AWS bastion hop as ubuntu, got %q", joined)
+	}
+	if target := access.bastionArgs(5, "true"); !strings.Contains(strings.Join(target, " "), "ubuntu@198.51.100.5") {
+		t.Errorf("Expected the AWS bastion logged in to as ubuntu, got %v", target)
+	}
+
+	if proxy := bastionProxyCommand("/tmp/key.pem", "", "198.51.100.5", "UserKnownHostsFile=/dev/null", nil, true); !strings.HasSuffix(proxy, "root@198.51.100.5") {
+		t.Errorf("Expected root without a bastion user, got %q", proxy)
+	}
+}
+
+func TestFetchNodePublicKey_ThroughJumpChain(t *testing.T) {
+	var proxy string
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "ProxyCommand=") {
+				proxy = arg
+			}
+		}
+		return []byte("NODEKEY=\n"), nil
+	})
+
+	access := nodeSSHAccess{KeyPath: "/tmp/key.pem", BastionEnabled: true, BastionIP: "198.51.100.5", JumpHosts: []string{"ops@edge.example.com"}}
+	node := NodeInfo{Name: "master-1", PublicIP: "203.0.113.10", WireGuardIP: "10.8.0.10"}
+	key, err := fetchNodePublicKey(node, access)
+	if err != nil || key != "NODEKEY=" {
+		t.Fatalf("Expected the node key, got %q (%v)", key, err)
+	}
+	if !strings.Contains(proxy, "-J ops@edge.example.com") || !strings.Contains(proxy, "198.51.100.5") {
+		t.Errorf("Expected the node reached through the jump chain and bastion, got %q", proxy)
+	}
+}
+
+func TestVPNNodeCommands_UseSSHProxy(t *testing.T) {
+	original := sshProxyCommand
+	sshProxyCommand = "nc -X connect -x proxy.internal:3128 %h %p"
+	t.Cleanup(func() { sshProxyCommand = original })
+
+	var calls [][]string
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		calls = append(calls, args)
+		return []byte("NODEKEY=\n"), nil
+	})
+
+	access := nodeSSHAccess{KeyPath: "/tmp/key.pem"}
+	node := NodeInfo{Name: "master-1", PublicIP: "203.0.113.10", WireGuardIP: "10.8.0.10"}
+	if _, err := fetchNodePublicKey(node, access); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := access.runScript(node, 10, "true"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, args := range calls {
+		if !strings.Contains(strings.Join(args, " "), "ProxyCommand=nc -X connect -x proxy.internal:3128 %h %p") {
+			t.Errorf("Expected the node reached through --ssh-proxy, got %v", args)
+		}
+	}
+}
+
+func TestBastionArgs_JumpChain(t *testing.T) {
+	access := nodeSSHAccess{KeyPath: "/tmp/key.pem", BastionIP: "198.51.100.5", JumpHosts: []string{"ops@edge.example.com"}}
+	joined := strings.Join(access.bastionArgs(5, "true"), " ")
+	if !strings.HasSuffix(joined, "-J ops@edge.example.com root@198.51.100.5 true") {
+		t.Errorf("Expected the bastion reached through its jump hosts, got %q", joined)
+	}
+}
+
+func TestBastionArgs_LogsHostKeyErrorsWhenVerifying(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	verifyHostKeys = true
+	t.Cleanup(func() { verifyHostKeys = false })
+
+	access := nodeSSHAccess{Stack: "prod", KeyPath: "/tmp/key.pem", BastionIP: "198.51.100.5"}
+	args := access.bastionArgs(5, "true")
+	if args[0] != "-oLogLevel=ERROR" {
+		t.Errorf("Expected errors logged like node connections, got %v", args)
+	}
+	for _, arg := range args {
+		if arg == "-q" {
+			t.Errorf("Expected no -q while verifying host keys, got %v", args)
+		}
+	}
+}
+
+func TestGetBastionJumpHosts(t *testing.T) {
+	outputs := auto.OutputMap{
+		"bastion": auto.OutputValue{Value: map[string]interface{}{
+			"public_ip":  "198.51.100.5",
+			"jump_hosts": []interface{}{"ops@edge.example.com", "10.0.0.5"},
+		}},
+	}
+	if hosts := getBastionJumpHosts(outputs); strings.Join(hosts, ",") != "ops@edge.example.com,10.0.0.5" {
+		t.Errorf("Expected the jump hosts in order, got %v", hosts)
+	}
+	if hosts := getBastionJumpHosts(auto.OutputMap{}); hosts != nil {
+		t.Errorf("Expected no jump hosts without a bastion, got %v", hosts)
+	}
+}
+
+func TestGetBastionInfo(t *testing.T) {
+	outputs := auto.OutputMap{
+		"bastion_enabled": auto.OutputValue{Value: true},
+		"bastion":         auto.OutputValue{Value: map[string]interface{}{"public_ip": "198.51.100.5"}},
+	}
+
+	enabled, ip := getBastionInfo(outputs)
+	if !enabled || ip != "198.51.100.5" {
+		t.Errorf("Expected bastion 198.51.100.5 enabled, got %v %q", enabled, ip)
+	}
+
+	enabled, ip = getBastionInfo(auto.OutputMap{})
+	if enabled || ip != "" {
+		t.Errorf("Expected no bastion, got %v %q", enabled, ip)
+	}
+}
+
+func TestParseWGDump(t *testing.T) {
+	output := "privkey\tpubkey\t51820\toff\n" +
+		"peerA=\t(none)\t203.0.113.10:51820\t10.8.0.10/32\t1700000000\t1024\t2048\t25\n" +
+		"peerB=\t(none)\t(none)\t10.8.0.100/32,10.0.0.0/8\t0\t0\t0\toff\n"
+
+	peers := parseWGDump(output)
+	if len(peers) != 2 {
+		t.Fatalf("Expected 2 peers, got %d", len(peers))
+	}
+
+	if peers[0].PublicKey != "peerA=" || peers[0].RxBytes != 1024 || peers[0].TxBytes != 2048 {
+		t.Errorf("Unexpected first peer: %+v", peers[0])
+	}
+	if peers[0].LatestHandshake != 1700000000 {
+		t.Errorf("Expected handshake 1700000000, got %d", peers[0].LatestHandshake)
+	}
+	if peers[1].VPNIP() != "10.8.0.100" {
+		t.Errorf("Expected VPN IP 10.8.0.100, got %q", peers[1].VPNIP())
+	}
+}
+
+func TestComputeWGLinkRates(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	before := wgTransferSample{
+		Node:  "master-1",
+		Taken: start,
+		Peers: []wgDumpPeer{
+			{PublicKey: "a", AllowedIPs: "10.8.0.11/32", RxBytes: 1000, TxBytes: 2000},
+			{PublicKey: "b", AllowedIPs: "10.8.0.100/32", RxBytes: 5000, TxBytes: 5000},
+		},
+	}
+	after := wgTransferSample{
+		Node:  "master-1",
+		Taken: start.Add(10 * time.Second),
+		Peers: []wgDumpPeer{
+			{PublicKey: "a", AllowedIPs: "10.8.0.11/32", RxBytes: 11000, TxBytes: 4000},
+			{PublicKey: "b", AllowedIPs: "10.8.0.100/32", RxBytes: 300, TxBytes: 5000},
+			{PublicKey: "c", AllowedIPs: "10.8.0.12/32", RxBytes: 9999, TxBytes: 9999},
+		},
+	}
+
+	rates := computeWGLinkRates(before, after, map[string]string{"10.8.0.11": "master-2"})
+	if len(rates) != 2 {
+		t.Fatalf("Expected 2 links (new peer skipped), got %d", len(rates))
+	}
+
+	if rates[0].Peer != "master-2" || rates[0].RxRate != 1000 || rates[0].TxRate != 200 {
+		t.Errorf("Unexpected rate for master-2: %+v", rates[0])
+	}
+
+	// Counter reset is counted from zero; unknown peers are named by VPN IP
+	if rates[1].Peer != "10.8.0.100" || rates[1].RxBytes != 300 || rates[1].TxBytes != 0 {
+		t.Errorf("Unexpected rate for external peer: %+v", rates[1])
+	}
+
+	top := topWGTalkers(rates, 1)
+	if len(top) != 1 || top[0].Peer != "master-2" {
+		t.Errorf("Expected master-2 as top talker, got %+v", top)
+	}
+
+	nodeRates := summarizeWGNodeRates(rates)
+	if len(nodeRates) != 1 || nodeRates[0].RxRate != 1030 || nodeRates[0].TxRate != 200 {
+		t.Errorf("Unexpected node rates: %+v", nodeRates)
+	}
+}
+
+func TestWriteWGStatsCSV(t *testing.T) {
+	links := []wgLinkRate{
+		{Node: "master-1", Peer: "master-2", VPNIP: "10.8.0.11", PublicKey: "a", Seconds: 10, RxBytes: 10000, TxBytes: 2000, RxRate: 1000, TxRate: 200},
+	}
+
+	var buf bytes.Buffer
+	if err := writeWGStatsCSV(&buf, links); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected header and one row, got %d lines", len(lines))
+	}
+	if !strings.HasPrefix(lines[0], "node,peer,vpn_ip") {
+		t.Errorf("Unexpected header: %s", lines[0])
+	}
+	if lines[1] != "master-1,master-2,10.8.0.11,a,10.000,10000,2000,1000.00,200.00" {
+		t.Errorf("Unexpected row: %s", lines[1])
+	}
+}
+
+func TestPlanEndpointUpdates(t *testing.T) {
+	master1 := NodeInfo{Name: "master-1", PublicIP: "203.0.113.10"}
+	master2 := NodeInfo{Name: "master-2", PublicIP: "203.0.113.99"}
+	worker1 := NodeInfo{Name: "worker-1", PublicIP: "203.0.113.20"}
+
+	output := "key-m1=\n---\nkey-m2=\t203.0.113.11:51820\nkey-w1=\t203.0.113.20:51820\nclient=\t198.51.100.7:40000\n"
+	m1, err := parseWGNodeEndpoints(master1, output)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if m1.PublicKey != "key-m1=" || len(m1.Endpoints) != 3 {
+		t.Fatalf("Unexpected parsed state: %+v", m1)
+	}
+
+	states := []wgNodeEndpoints{
+		m1,
+		{Node: master2, PublicKey: "key-m2=", Endpoints: map[string]string{"key-m1=": "203.0.113.10:51820", "key-w1=": "203.0.113.20:51820"}},
+		{Node: worker1, PublicKey: "key-w1=", Endpoints: map[string]string{"key-m1=": "203.0.113.10:51820", "key-m2=": ""}},
+	}
+
+	updates := planEndpointUpdates(states)
+	if len(updates) != 2 {
+		t.Fatalf("Expected 2 updates for master-2's new IP, got %d: %+v", len(updates), updates)
+	}
+
+	for _, update := range updates {
+		if update.PeerName != "master-2" || update.NewEndpoint != "203.0.113.99:51820" {
+			t.Errorf("Unexpected update: %+v", update)
+		}
+	}
+	if updates[0].Node.Name != "master-1" || updates[0].OldEndpoint != "203.0.113.11:51820" {
+		t.Errorf("Expected master-1 stale endpoint first, got %+v", updates[0])
+	}
+
+	// Once applied, nothing is left to update
+	states[0].Endpoints["key-m2="] = "203.0.113.99:51820"
+	states[2].Endpoints["key-m2="] = "203.0.113.99:51820"
+	if updates := planEndpointUpdates(states); len(updates) != 0 {
+		t.Errorf("Expected no updates after refresh, got %+v", updates)
+	}
+}
+
+func TestGenerateEndpointUpdateScript(t *testing.T) {
+	script := generateEndpointUpdateScript([]wgEndpointUpdate{
+		{PeerPublicKey: "key-m2=", NewEndpoint: "203.0.113.99:51820"},
+	})
+
+	if !strings.Contains(script, "sudo wg set wg0 peer 'key-m2=' endpoint '203.0.113.99:51820'") {
+		t.Error("Script should update the live interface")
+	}
+	if !strings.Contains(script, "/etc/wireguard/wg0.conf.new") {
+		t.Error("Script should persist the endpoint in wg0.conf")
+	}
+	create := strings.Index(script, "install -m 600 /dev/null /etc/wireguard/wg0.conf.new")
+	if create < 0 || create > strings.Index(script, "tee /etc/wireguard/wg0.conf.new") {
+		t.Error("Script should create wg0.conf.new owner-only before writing the private key to it")
+	}
+}
+
+func TestBuildMeshConfigs_PreservesExternalPeers(t *testing.T) {
+	master := NodeInfo{Name: "master-1", PublicIP: "203.0.113.10", WireGuardIP: "10.8.0.10"}
+	worker := NodeInfo{Name: "worker-1", PublicIP: "203.0.113.20", WireGuardIP: "10.8.0.11"}
+
+	// master-1's config is missing worker-1 and has a broken block and a client peer
+	masterConf := `[Interface]
+Address = 10.8.0.10/24
+
+[Peer]
+AllowedIPs = 10.8.0.99/32
+
+[Peer]
+# Peer: laptop
+PublicKey = key-laptop=
+AllowedIPs = 10.8.0.100/32
+PersistentKeepalive = 25
+`
+	m, err := parseWGNodeConfig(master, "key-m1=\n---\n"+masterConf)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+
+	states := []wgNodeConfig{
+		{Node: worker, PublicKey: "key-w1=", Config: "[Interface]\n\n[Peer]\nPublicKey = key-m1=\n"},
+		m,
+	}
+
+	configs := buildMeshConfigs(states, vpnSubnet(nil), meshRouting{})
+
+	masterOut := configs["master-1"]
+	for _, want := range []string{
+		"Address = 10.8.0.10/24",
+		"PublicKey = key-w1=\nAllowedIPs = 10.8.0.11/32, 10.0.0.0/8\nEndpoint = 203.0.113.20:51820",
+		"# Peer: laptop\nPublicKey = key-laptop=\nAllowedIPs = 10.8.0.100/32",
+	} {
+		if !strings.Contains(masterOut, want) {
+			t.Errorf("master-1 config should contain %q, got:\n%s", want, masterOut)
+		}
+	}
+	if strings.Contains(masterOut, "10.8.0.99") {
+		t.Error("Peer block without a PublicKey should be dropped")
+	}
+	if strings.Count(masterOut, "[Peer]") != 2 {
+		t.Errorf("Expected 2 peers on master-1, got:\n%s", masterOut)
+	}
+
+	workerOut := configs["worker-1"]
+	if strings.Count(workerOut, "[Peer]") != 1 || !strings.Contains(workerOut, "Endpoint = 203.0.113.10:51820") {
+		t.Errorf("worker-1 should peer only with master-1, got:\n%s", workerOut)
+	}
+
+	// Rebuilding is deterministic regardless of input order
+	reversed := buildMeshConfigs([]wgNodeConfig{states[1], states[0]}, vpnSubnet(nil), meshRouting{})
+	if reversed["master-1"] != masterOut || reversed["worker-1"] != workerOut {
+		t.Error("Rebuilt configs should not depend on node order")
+	}
+}
+
+func TestBuildMeshConfigs_HybridVPC(t *testing.T) {
+	cfg := &config.ClusterConfig{
+		Network: config.NetworkConfig{Mode: config.NetworkModeHybrid},
+		Providers: config.ProvidersConfig{
+			DigitalOcean: &config.DigitalOceanProvider{Enabled: true, VPC: &config.VPCConfig{CIDR: "10.10.0.0/16"}},
+			Linode:       &config.LinodeProvider{Enabled: true, VPC: &config.VPCConfig{CIDR: "192.168.128.0/17"}},
+		},
+	}
+	routing := newMeshRouting(cfg)
+	if routing.vpcNetworks == nil {
+		t.Fatal("Expected the VPC networks of the hybrid network")
+	}
+
+	states := []wgNodeConfig{
+		{Node: NodeInfo{Name: "do-1", Provider: "digitalocean", PublicIP: "203.0.113.1", PrivateIP: "10.10.0.2", WireGuardIP: "10.8.0.10"}, PublicKey: "key-do1="},
+		{Node: NodeInfo{Name: "do-2", Provider: "digitalocean", PublicIP: "203.0.113.2", PrivateIP: "10.10.0.3", WireGuardIP: "10.8.0.11"}, PublicKey: "key-do2="},
+		{Node: NodeInfo{Name: "li-1", Provider: "linode", PublicIP: "198.51.100.1", PrivateIP: "192.168.130.4", WireGuardIP: "10.8.0.12"}, PublicKey: "key-li1="},
+	}
+
+	conf := buildMeshConfigs(states, vpnSubnet(nil), routing)["do-1"]
+	for _, want := range []string{
+		// Same VPC: reached on the private IP
+		"PublicKey = key-do2=\nAllowedIPs = 10.8.0.11/32, 10.0.0.0/8\nEndpoint = 10.10.0.3:51820",
+		// Other VPC: public endpoint, private IP routed through the tunnel
+		"PublicKey = key-li1=\nAllowedIPs = 10.8.0.12/32, 192.168.130.4/32, 10.0.0.0/8\nEndpoint = 198.51.100.1:51820",
+	} {
+		if !strings.Contains(conf, want) {
+			t.Errorf("do-1 config should contain %q, got:\n%s", want, conf)
+		}
+	}
+}
+
+func TestBuildMeshConfigs_CrossProviderRoutesPrivateIP(t *testing.T) {
+	states := []wgNodeConfig{
+		{Node: NodeInfo{Name: "do-1", Provider: "digitalocean", PublicIP: "203.0.113.1", PrivateIP: "10.10.0.2", WireGuardIP: "10.8.0.10"}, PublicKey: "key-do1="},
+		{Node: NodeInfo{Name: "li-1", Provider: "linode", PublicIP: "198.51.100.1", PrivateIP: "192.168.130.4", WireGuardIP: "10.8.0.12"}, PublicKey: "key-li1="},
+	}
+
+	conf := buildMeshConfigs(states, vpnSubnet(nil), newMeshRouting(&config.ClusterConfig{}))["do-1"]
+	want := "AllowedIPs = 10.8.0.12/32, 192.168.130.4/32, 10.0.0.0/8\nEndpoint = 198.51.100.1:51820"
+	if !strings.Contains(conf, want) {
+		t.Errorf("do-1 config should contain %q, got:\n%s", want, conf)
+	}
+}
+
+func TestBuildMeshConfigs_HubMode(t *testing.T) {
+	hub := &config.WireGuardConfig{
+		Enabled:         true,
+		ServerEndpoint:  "vpn.example.com",
+		ServerPublicKey: "key-hub=",
+		AllowedIPs:      []string{"172.16.0.0/16"},
+	}
+	routing := newMeshRouting(&config.ClusterConfig{Network: config.NetworkConfig{WireGuard: hub}})
+	if routing.hub == nil {
+		t.Fatal("Expected hub mode for an existing WireGuard server")
+	}
+
+	states := []wgNodeConfig{
+		{Node: NodeInfo{Name: "master-1", PublicIP: "203.0.113.10", WireGuardIP: "10.8.0.10"}, PublicKey: "key-m1=",
+			Config: "[Interface]\n\n[Peer]\n# wireguard-hub\nPublicKey = key-hub=\nEndpoint = vpn.example.com:51820\n\n[Peer]\n# Peer: laptop\nPublicKey = key-laptop=\nAllowedIPs = 10.8.0.100/32\n"},
+		{Node: NodeInfo{Name: "worker-1", PublicIP: "203.0.113.20", WireGuardIP: "10.8.0.11"}, PublicKey: "key-w1="},
+	}
+
+	conf := buildMeshConfigs(states, vpnSubnet(nil), routing)["master-1"]
+	for _, want := range []string{
+		"Address = 10.8.0.10/24",
+		"PublicKey = key-hub=\nEndpoint = vpn.example.com:51820\nAllowedIPs = 10.8.0.0/24, 172.16.0.0/16",
+		"PublicKey = key-laptop=",
+	} {
+		if !strings.Contains(conf, want) {
+			t.Errorf("master-1 config should contain %q, got:\n%s", want, conf)
+		}
+	}
+	if strings.Contains(conf, "key-w1=") {
+		t.Error("Spokes should not peer with each other")
+	}
+	if strings.Count(conf, "key-hub=") != 1 {
+		t.Errorf("Expected the hub peer once, got:\n%s", conf)
+	}
+}
+
+func TestParseMeshPingOutput(t *testing.T) {
+	script := generateMeshPingScript([]string{"10.8.0.11", "10.8.0.12"})
+	if !strings.Contains(script, "ping -c 3 -W 2 10.8.0.12") {
+		t.Errorf("Script should ping every peer, got:\n%s", script)
+	}
+
+	failed := parseMeshPingOutput("10.8.0.11 ok\n10.8.0.12 fail\n")
+	if len(failed) != 1 || failed[0] != "10.8.0.12" {
+		t.Errorf("Expected 10.8.0.12 to be unreachable, got %v", failed)
+	}
+}
+
+func TestClientNodeAllowedIPs_BroadRoute(t *testing.T) {
+	nodes := []NodeInfo{
+		{Name: "worker-1", WireGuardIP: "10.8.0.20", Roles: []string{"worker"}},
+		{Name: "master-1", WireGuardIP: "10.8.0.10", Roles: []string{"controlplane"}},
+		{Name: "pending-1"},
+	}
+
+	allowed := clientNodeAllowedIPs(nodes, true, nil)
+	if allowed["worker-1"] != "10.8.0.20/32, 10.0.0.0/8" || allowed["master-1"] != "10.8.0.10/32, 10.0.0.0/8" {
+		t.Errorf("Expected every node to claim 10.0.0.0/8, got %v", allowed)
+	}
+	if _, ok := allowed["pending-1"]; ok {
+		t.Error("Nodes without a VPN IP should not become peers")
+	}
+}
+
+func TestClientNodeAllowedIPs_NoBroadRoute(t *testing.T) {
+	nodes := []NodeInfo{
+		{Name: "worker-1", WireGuardIP: "10.8.0.20", Roles: []string{"worker"}},
+		{Name: "master-1", WireGuardIP: "10.8.0.10", Roles: []string{"controlplane"}},
+	}
+	routes := []string{"10.8.0.0/24", "10.42.0.0/16", "10.43.0.0/16"}
+
+	allowed := clientNodeAllowedIPs(nodes, false, routes)
+	if allowed["worker-1"] != "10.8.0.20/32" {
+		t.Errorf("Expected worker-1 to route only its VPN IP, got %q", allowed["worker-1"])
+	}
+	if allowed["master-1"] != "10.8.0.10/32, 10.8.0.0/24, 10.42.0.0/16, 10.43.0.0/16" {
+		t.Errorf("Expected the server to carry the cluster routes, got %q", allowed["master-1"])
+	}
+	for name, ips := range allowed {
+		if strings.Contains(ips, "10.0.0.0/8") {
+			t.Errorf("%s should not route 10.0.0.0/8: %s", name, ips)
+		}
+	}
+}
+
+func TestClientRoutes(t *testing.T) {
+	routes := clientRoutes(auto.OutputMap{})
+	if strings.Join(routes, ",") != "10.8.0.0/24,10.42.0.0/16,10.43.0.0/16" {
+		t.Errorf("Expected RKE2 defaults, got %v", routes)
+	}
+
+	routes = clientRoutes(auto.OutputMap{
+		"wireguard_network": {Value: "10.8.0.0/24"},
+		"pod_cidr":          {Value: "10.100.0.1/16"},
+		"service_cidr":      {Value: "not-a-cidr"},
+	})
+	if strings.Join(routes, ",") != "10.8.0.0/24,10.100.0.0/16,10.43.0.0/16" {
+		t.Errorf("Expected exported CIDRs with fallback for invalid ones, got %v", routes)
+	}
+}
+
+func TestVPNTestLinks_Filters(t *testing.T) {
+	nodes := []NodeInfo{
+		{Name: "master-1", WireGuardIP: "10.8.0.10"},
+		{Name: "worker-1", WireGuardIP: "10.8.0.11"},
+		{Name: "worker-2", WireGuardIP: "10.8.0.12"},
+		{Name: "worker-3", WireGuardIP: "10.8.0.13"},
+		{Name: "no-vpn"},
+	}
+
+	linkNames := func(links []vpnLink) []string {
+		var names []string
+		for _, link := range links {
+			names = append(names, link.Source.Name+"->"+link.Target.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name     string
+		from, to string
+		want     []string
+	}{
+		{"full mesh", "", "", nil},
+		{"from", "master-1", "", []string{"master-1->worker-1", "master-1->worker-2", "master-1->worker-3"}},
+		{"to", "", "worker-2", []string{"master-1->worker-2", "worker-1->worker-2", "worker-3->worker-2"}},
+		{"pair", "master-1", "worker-3", []string{"master-1->worker-3", "worker-3->master-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			links, err := vpnTestLinks(nodes, tt.from, tt.to)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tt.want == nil {
+				if len(links) != 4*3 {
+					t.Errorf("Expected the full mesh of 12 links, got %v", linkNames(links))
+				}
+				return
+			}
+			if got := strings.Join(linkNames(links), ","); got != strings.Join(tt.want, ",") {
+				t.Errorf("Expected links %v, got %v", tt.want, linkNames(links))
+			}
+		})
+	}
+}
+
+func TestVPNTestLinks_UnknownNode(t *testing.T) {
+	nodes := []NodeInfo{
+		{Name: "master-1", WireGuardIP: "10.8.0.10"},
+		{Name: "no-vpn"},
+	}
+
+	if _, err := vpnTestLinks(nodes, "master-9", ""); err == nil || !strings.Contains(err.Error(), "--from") {
+		t.Errorf("Expected an unknown --from node error, got %v", err)
+	}
+	if _, err := vpnTestLinks(nodes, "", "no-vpn"); err == nil || !strings.Contains(err.Error(), "--to") {
+		t.Errorf("Expected a node without VPN IP to be rejected, got %v", err)
+	}
+	if _, err := vpnTestLinks(nodes, "master-1", "master-1"); err == nil {
+		t.Error("Expected an error when --from and --to are the same node")
+	}
+}
+
+func TestVPNLinkNodes(t *testing.T) {
+	nodes := []NodeInfo{{Name: "master-1"}, {Name: "worker-1"}, {Name: "worker-2"}}
+	links := []vpnLink{{Source: nodes[2], Target: nodes[0]}}
+
+	got := vpnLinkNodes(nodes, links)
+	if len(got) != 2 || got[0].Name != "master-1" || got[1].Name != "worker-2" {
+		t.Errorf("Expected master-1 and worker-2 in node order, got %+v", got)
+	}
+}
+
+// capturePipe replaces *target with a pipe for the duration of a test and
+// returns a function reading what was written to it
+func capturePipe(t *testing.T, target **os.File) func() string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	original := *target
+	*target = w
+	t.Cleanup(func() { *target = original })
+
+	return func() string {
+		w.Close()
+		data, _ := io.ReadAll(r)
+		return string(data)
+	}
+}
+
+func TestWriteClientConfig_PrintConfig(t *testing.T) {
+	dir := t.TempDir()
+	stubArtifactDir(t, dir)
+	readStdout := capturePipe(t, &os.Stdout)
+	readStderr := capturePipe(t, &os.Stderr)
+
+	clientConfig := "[Interface]\nPrivateKey = key\nAddress = 10.8.0.100/24\n"
+
+	stdout, restore := stdoutToStderr()
+	printHeader("🔗 Joining VPN - Stack: production")
+	printInfo("Step 5/5: Generating client configuration...")
+	fmt.Println()
+	configPath, err := writeClientConfig("production", clientConfig, stdout)
+	restore()
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if configPath != "" {
+		t.Errorf("Expected no config file, got %s", configPath)
+	}
+	if got := readStdout(); got != clientConfig {
+		t.Errorf("Expected stdout to contain only the config, got %q", got)
+	}
+	if stderr := readStderr(); !strings.Contains(stderr, "Joining VPN") || !strings.Contains(stderr, "written to stdout") {
+		t.Errorf("Expected diagnostics on stderr, got %q", stderr)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Expected no file to be written, found %d", len(entries))
+	}
+}
+
+func TestWriteClientConfig_File(t *testing.T) {
+	stubArtifactDir(t, t.TempDir())
+
+	configPath, err := writeClientConfig("production", "[Interface]\n", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil || string(data) != "[Interface]\n" {
+		t.Errorf("Expected the config in %s, got %q (%v)", configPath, data, err)
+	}
+}
+
+// runPeerScriptFixture runs a peer script against a WireGuard config in dir,
+// with sudo, wg-quick and wg replaced by fakes. The fake wg keeps the running
+// peers in dir/running; with brokenSync set, syncconf changes nothing.
+func runPeerScriptFixture(t *testing.T, dir, script string, brokenSync bool) (string, error) {
+	t.Helper()
+	bin := filepath.Join(dir, "bin")
+	fakes := map[string]string{
+		"sudo":     `exec "$@"`,
+		"wg-quick": `grep -v '^#' "$WG_CONF"`,
+		"wg": `case "$1" in
+syncconf) peers=$(awk '$1 == "PublicKey" { print $3 }' "$3"); [ -n "$WG_BROKEN" ] || printf '%s\n' "$peers" > "$WG_RUNNING" ;;
+show) cat "$WG_RUNNING" ;;
+esac`,
+	}
+	if err := os.MkdirAll(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, body := range fakes {
+		if err := os.WriteFile(filepath.Join(bin, name), []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conf := filepath.Join(dir, "wg0.conf")
+	cmd := exec.Command("bash", "-c", strings.ReplaceAll(script, "/etc/wireguard/wg0.conf", conf))
+	cmd.Env = append(os.Environ(),
+		"PATH="+bin+string(os.PathListSeparator)+os.Getenv("PATH"),
+		"WG_CONF="+conf,
+		"WG_RUNNING="+filepath.Join(dir, "running"),
+	)
+	if brokenSync {
+		cmd.Env = append(cmd.Env, "WG_BROKEN=1")
+	}
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+func TestPeerScripts_KeepConfigAndInterfaceInSync(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	dir := t.TempDir()
+	conf := filepath.Join(dir, "wg0.conf")
+	initial := "[Interface]\nPrivateKey = server\nAddress = 10.8.0.1/24\n\n[Peer]\n# Node: master-1\nPublicKey = NODE=\nAllowedIPs = 10.8.0.10/32\n"
+	if err := os.WriteFile(conf, []byte(initial), 0600); err != nil {
+		t.Fatal(err)
+	}
+	readFile := func(name string) string {
+		data, _ := os.ReadFile(filepath.Join(dir, name))
+		return string(data)
+	}
+
+	if output, err := runPeerScriptFixture(t, dir, generatePeerAddScript("10.8.0.100", vpnSubnet(nil), "CLIENT+KEY=", "laptop", ""), false); err != nil {
+		t.Fatalf("Peer add failed: %v\n%s", err, output)
+	}
+	if saved := readFile("wg0.conf"); !strings.Contains(saved, "# Peer: laptop\nPublicKey = CLIENT+KEY=\nAllowedIPs = 10.8.0.100/32") {
+		t.Errorf("Expected the peer saved in the config, got:\n%s", saved)
+	}
+	if running := readFile("running"); !strings.Contains(running, "CLIENT+KEY=\n") || !strings.Contains(running, "NODE=\n") {
+		t.Errorf("Expected the peer on the running interface, got:\n%s", running)
+	}
+
+	output, err := runPeerScriptFixture(t, dir, peerRemoveCommand("CLIENT+KEY="), false)
+	if err != nil || strings.TrimSpace(output) != "SUCCESS" {
+		t.Fatalf("Expected the peer removal to succeed, got %v: %s", err, output)
+	}
+	saved := readFile("wg0.conf")
+	if strings.Contains(saved, "CLIENT+KEY=") || strings.Contains(saved, "laptop") {
+		t.Errorf("Expected the peer section removed from the config, got:\n%s", saved)
+	}
+	if !strings.Contains(saved, "[Interface]") || !strings.Contains(saved, "[Peer]\n# Node: master-1\nPublicKey = NODE=") {
+		t.Errorf("Expected the other sections kept, got:\n%s", saved)
+	}
+	if running := readFile("running"); strings.Contains(running, "CLIENT+KEY=") {
+		t.Errorf("Expected the peer removed from the running interface, got:\n%s", running)
+	}
+}
+
+func TestPeerAddScript_PresharedKey(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	psk, err := generatePresharedKey()
+	if err != nil {
+		t.Fatalf("Failed to generate preshared key: %v", err)
+	}
+	if key, err := base64.StdEncoding.DecodeString(psk); err != nil || len(key) != 32 {
+		t.Fatalf("Expected a base64 32-byte key, got %q", psk)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "wg0.conf"), []byte("[Interface]\nPrivateKey = server\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	script := generatePeerAddScript("10.8.0.100", vpnSubnet(nil), "CLIENT=", "", psk)
+	for _, line := range strings.Split(script, "\n") {
+		if strings.Contains(line, psk) && line != "PresharedKey = "+psk {
+			t.Errorf("Expected the preshared key only inside the heredoc, got command line %q", line)
+		}
+	}
+	if output, err := runPeerScriptFixture(t, dir, script, false); err != nil {
+		t.Fatalf("Peer add failed: %v\n%s", err, output)
+	}
+	saved, _ := os.ReadFile(filepath.Join(dir, "wg0.conf"))
+	if !strings.Contains(string(saved), "PublicKey = CLIENT=\nPresharedKey = "+psk+"\nAllowedIPs = 10.8.0.100/32") {
+		t.Errorf("Expected the preshared key saved with the peer, got:\n%s", saved)
+	}
+	if strings.Contains(generatePeerAddScript("10.8.0.100", vpnSubnet(nil), "CLIENT=", "", ""), "PresharedKey") {
+		t.Error("Expected no preshared key without one")
+	}
+}
+
+func TestGenerateClientConfig_PresharedKeyOnlyForGivenPeers(t *testing.T) {
+	subnet := vpnSubnet(nil)
+	access := nodeSSHAccess{Stack: "production", BastionEnabled: true, BastionIP: "203.0.113.5"}
+	nodes := []NodeInfo{{Name: "master-1", PublicIP: "198.51.100.10", WireGuardIP: "10.8.0.10"}}
+	peers := []VPNPeerInfo{
+		{PublicKey: "BASTION=", VPNAddress: subnet.BastionIP()},
+		{PublicKey: "LAPTOP=", VPNAddress: "10.8.0.101"},
+		{PublicKey: "OFFLINE=", VPNAddress: "10.8.0.102"},
+	}
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		return []byte("NODEKEY=\n"), nil
+	})
+
+	conf := generateClientConfig(access, "PRIVATE=", "10.8.0.100", subnet, "", "PSK=", nodes, peers, map[string]bool{"LAPTOP=": true}, true, nil)
+	for _, want := range []string{"PublicKey = NODEKEY=\nPresharedKey = PSK=\n", "PublicKey = LAPTOP=\nPresharedKey = PSK=\n"} {
+		if !strings.Contains(conf, want) {
+			t.Errorf("Expected %q in config:\n%s", want, conf)
+		}
+	}
+	for _, key := range []string{"BASTION=", "OFFLINE="} {
+		if strings.Contains(conf, "PublicKey = "+key+"\nPresharedKey") {
+			t.Errorf("Expected no preshared key for %s, which was not given it:\n%s", key, conf)
+		}
+	}
+}
+
+func TestPeerAddScript_ReplacesClientsOfSubnet(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	dir := t.TempDir()
+	initial := "[Interface]\nPrivateKey = server\nAddress = 10.100.0.10/24\n\n" +
+		"[Peer]\n# Node: master-2\nPublicKey = NODE=\nAllowedIPs = 10.100.0.11/32, 10.0.0.0/8\n\n" +
+		"[Peer]\n# Client joined via CLI\nPublicKey = OLD=\nAllowedIPs = 10.100.0.120/32\n"
+	if err := os.WriteFile(filepath.Join(dir, "wg0.conf"), []byte(initial), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	subnet := vpnSubnet(auto.OutputMap{"wireguard_network": {Value: "10.100.0.0/24"}})
+	if output, err := runPeerScriptFixture(t, dir, generatePeerAddScript("10.100.0.100", subnet, "CLIENT=", "", ""), false); err != nil {
+		t.Fatalf("Peer add failed: %v\n%s", err, output)
+	}
+	saved, _ := os.ReadFile(filepath.Join(dir, "wg0.conf"))
+	if strings.Contains(string(saved), "OLD=") {
+		t.Errorf("Expected the old client peer of the subnet removed, got:\n%s", saved)
+	}
+	if !strings.Contains(string(saved), "PublicKey = NODE=") || !strings.Contains(string(saved), "AllowedIPs = 10.100.0.100/32") {
+		t.Errorf("Expected the node peer kept and the new peer added, got:\n%s", saved)
+	}
+}
+
+func TestPeerAddScript_FailsWhenNotRunning(t *testing.T) {
+	if _, err := exec.LookPath("bash"); err != nil {
+		t.Skip("bash not available")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "wg0.conf"), []byte("[Interface]\nPrivateKey = server\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "running"), nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	output, err := runPeerScriptFixture(t, dir, generatePeerAddScript("10.8.0.100", vpnSubnet(nil), "CLIENT=", "", ""), true)
+	if err == nil {
+		t.Errorf("Expected the add to fail when the interface does not pick up the peer, got:\n%s", output)
+	}
+	if !strings.Contains(output, "not in both") {
+		t.Errorf("Expected the verification failure to be reported, got:\n%s", output)
+	}
+}
+
+func TestParseVPNStatus(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	output := "privkey\tpubkey\t51820\toff\n" +
+		"peerA=\t(none)\t203.0.113.10:51820\t10.8.0.11/32\t1699999940\t1024\t2048\t25\n" +
+		"peerB=\t(none)\t203.0.113.11:51820\t10.8.0.12/32\t1699999000\t100\t200\t25\n" +
+		"peerC=\t(none)\t(none)\t10.8.0.100/32\t0\t0\t0\toff\n" +
+		"--- address ---\n" +
+		"5: wg0    inet 10.8.0.10/24 scope global wg0\\       valid_lft forever preferred_lft forever\n"
+
+	status := parseVPNStatus(output, now)
+	if status.Peers != 3 || status.ActivePeers != 1 {
+		t.Errorf("Expected 1 of 3 tunnels active, got %d of %d", status.ActivePeers, status.Peers)
+	}
+	if status.Subnet != "10.8.0.0/24" {
+		t.Errorf("Expected subnet 10.8.0.0/24, got %q", status.Subnet)
+	}
+	if status.RxBytes != 1124 || status.TxBytes != 2248 {
+		t.Errorf("Unexpected transfer totals: rx %d tx %d", status.RxBytes, status.TxBytes)
+	}
+
+	status.Node = "master-1"
+	if !strings.Contains(status.summary(), "Degraded: 2 of 3") {
+		t.Errorf("Expected degraded summary, got %q", status.summary())
+	}
+	status.ActivePeers = 3
+	if !strings.Contains(status.summary(), "All tunnels active") {
+		t.Errorf("Expected all active summary, got %q", status.summary())
+	}
+	if summary := (vpnStatus{TotalNodes: 3}).summary(); !strings.Contains(summary, "no node reachable") {
+		t.Errorf("Expected unreachable summary, got %q", summary)
+	}
+}
+
+func TestParseVPNClientPeers(t *testing.T) {
+	output := "nodeKey=|10.8.0.10\nbastionKey=|10.8.0.5\nlaptopKey=|10.8.0.100\nbroken\nnoneKey=|(none)\n"
+
+	peers := parseVPNClientPeers(output, vpnSubnet(nil))
+	want := []VPNPeerInfo{
+		{PublicKey: "bastionKey=", VPNAddress: "10.8.0.5"},
+		{PublicKey: "laptopKey=", VPNAddress: "10.8.0.100"},
+	}
+	if !reflect.DeepEqual(peers, want) {
+		t.Errorf("parseVPNClientPeers() = %+v, want %+v", peers, want)
+	}
+}
+
+func TestNextClientIP(t *testing.T) {
+	ip, err := nextClientIP([]VPNPeerInfo{{VPNAddress: "10.8.0.100"}, {VPNAddress: "10.8.0.102"}}, vpnSubnet(nil))
+	if err != nil || ip != "10.8.0.101" {
+		t.Errorf("Expected 10.8.0.101, got %q (%v)", ip, err)
+	}
+
+	var full []VPNPeerInfo
+	for i := 100; i < 255; i++ {
+		full = append(full, VPNPeerInfo{VPNAddress: fmt.Sprintf("10.8.0.%d", i)})
+	}
+	if _, err := nextClientIP(full, vpnSubnet(nil)); err == nil {
+		t.Error("Expected an error when the client range is exhausted")
+	}
+}
+
+func TestVPNSubnet_FromOutputs(t *testing.T) {
+	if got := vpnSubnet(nil).String(); got != "10.8.0.0/24" {
+		t.Errorf("Expected the default subnet without outputs, got %s", got)
+	}
+	subnet := vpnSubnet(auto.OutputMap{"wireguard_network": {Value: "10.100.0.0/24"}})
+	if got := subnet.String(); got != "10.100.0.0/24" {
+		t.Errorf("Expected the exported subnet, got %s", got)
+	}
+
+	ip, err := nextClientIP([]VPNPeerInfo{{VPNAddress: "10.100.0.100"}}, subnet)
+	if err != nil || ip != "10.100.0.101" {
+		t.Errorf("Expected 10.100.0.101, got %q (%v)", ip, err)
+	}
+
+	peers := parseVPNClientPeers("nodeKey=|10.100.0.10\nlaptopKey=|10.100.0.100\n", subnet)
+	if len(peers) != 1 || peers[0].VPNAddress != "10.100.0.100" {
+		t.Errorf("Expected only the client at 10.100.0.100, got %+v", peers)
+	}
+}
+
+func TestCheckClientVPNIP(t *testing.T) {
+	subnet := vpnSubnet(auto.OutputMap{"wireguard_network": {Value: "10.100.0.0/24"}})
+	if err := checkClientVPNIP("10.100.0.150", subnet); err != nil {
+		t.Errorf("Expected a client address to be accepted, got %v", err)
+	}
+	for _, ip := range []string{"10.100.0.5", "10.100.0.12", "10.8.0.100", "10.100.0.255"} {
+		if err := checkClientVPNIP(ip, subnet); err == nil {
+			t.Errorf("Expected %s to be rejected", ip)
+		}
+	}
+}
+
+func TestAbortIncompleteVPNJoin_RollsBackAddedNodes(t *testing.T) {
+	originalIP := vpnJoinIP
+	vpnJoinIP = "10.8.0.100"
+	t.Cleanup(func() { vpnJoinIP = originalIP })
+
+	var contacted []string
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		if remote := args[len(args)-1]; !strings.Contains(remote, "key='PUBKEY='") {
+			t.Errorf("Unexpected command: %s", remote)
+		}
+		contacted = append(contacted, sshTarget(args))
+		return []byte("SUCCESS\n"), nil
+	})
+
+	nodes := []NodeInfo{
+		{Name: "master-1", PublicIP: "203.0.113.10"},
+		{Name: "worker-1", PublicIP: "203.0.113.20"},
+		{Name: "worker-2", PublicIP: "203.0.113.21"},
+	}
+	stubOperationStatusDir(t)
+	status := &operationStatus{Operation: vpnJoinOperation, Stack: "production"}
+	status.markSucceeded("master-1")
+	status.markSucceeded("worker-2")
+	status.markFailed("worker-1")
+
+	err := abortIncompleteVPNJoin(status, nodes, []string{"master-1", "worker-2"}, nodeSSHAccess{KeyPath: "/tmp/key.pem"}, "PUBKEY=")
+	if !errors.Is(err, errs.ErrMeshDegraded) || !strings.Contains(err.Error(), "failed on 1 node(s): worker-1") {
+		t.Errorf("Expected a degraded mesh error naming worker-1, got %v", err)
+	}
+
+	want := []string{"root@203.0.113.10", "root@203.0.113.21"}
+	if strings.Join(contacted, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected rollback on %v, got %v", want, contacted)
+	}
+	if saved, _ := loadOperationStatus("production", vpnJoinOperation); saved != nil {
+		t.Errorf("Expected no join status after a full rollback, got %+v", saved)
+	}
+}
+
+func TestAbortIncompleteVPNJoin_ReportsRollbackFailures(t *testing.T) {
+	stubSSHRunner(t, func(args []string, stdin string) ([]byte, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	nodes := []NodeInfo{
+		{Name: "master-1", PublicIP: "203.0.113.10"},
+		{Name: "worker-1", PublicIP: "203.0.113.20"},
+	}
+	stubOperationStatusDir(t)
+	status := &operationStatus{Operation: vpnJoinOperation, Stack: "production"}
+	status.markSucceeded("master-1")
+	status.markFailed("worker-1")
+
+	err := abortIncompleteVPNJoin(status, nodes, []string{"master-1"}, nodeSSHAccess{KeyPath: "/tmp/key.pem"}, "PUBKEY=")
+	if err == nil || !strings.Contains(err.Error(), "rollback failed on 1 node(s)") {
+		t.Errorf("Expected rollback failure in error, got %v", err)
+	}
+
+	// master-1 still has the peer, so the join stays resumable
+	saved, err := loadOperationStatus("production", vpnJoinOperation)
+	if err != nil || saved == nil {
+		t.Fatalf("Expected the join status to be kept, got %v, %v", saved, err)
+	}
+	if strings.Join(saved.Succeeded, ",") != "master-1" || strings.Join(saved.Failed, ",") != "worker-1" {
+		t.Errorf("Unexpected saved status: %+v", saved)
+	}
+}
@@ -38,6 +38,26 @@ func LoadSavedConfig() error {
 	return nil
 }
 
+// DefaultStackKey is the config key holding the stack used when a command
+// gets neither a stack argument nor --stack
+const DefaultStackKey = "defaultStack"
+
+// SavedConfigValue returns a value from ~/.sloth-kubernetes/config,
+// or "" when the file or key does not exist
+func SavedConfigValue(key string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	config, err := loadConfigFile(filepath.Join(home, ".sloth-kubernetes", "config"))
+	if err != nil {
+		return ""
+	}
+
+	return config[key]
+}
+
 // Deprecated: Use LoadSavedConfig instead
 func LoadSavedCredentials() error {
 	return LoadSavedConfig()
@@ -0,0 +1,134 @@
+package common
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// lookPath finds proxy helper binaries (replaced in tests)
+var lookPath = exec.LookPath
+
+// ValidateProxyURL parses an HTTP(S) proxy URL and checks it has a usable host and port
+func ValidateProxyURL(raw string) (*url.URL, error) {
+	if strings.TrimSpace(raw) == "" {
+		return nil, fmt.Errorf("empty proxy URL")
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", raw, err)
+	}
+
+	switch u.Scheme {
+	case "http", "https":
+	case "":
+		return nil, fmt.Errorf("invalid proxy URL %q: missing scheme (expected http:// or https://)", raw)
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %q: unsupported scheme %s (expected http or https)", raw, u.Scheme)
+	}
+
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q: missing host", raw)
+	}
+
+	if p := u.Port(); p != "" {
+		port, err := strconv.Atoi(p)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, fmt.Errorf("invalid proxy URL %q: invalid port %s", raw, p)
+		}
+	}
+
+	if u.Path != "" && u.Path != "/" {
+		return nil, fmt.Errorf("invalid proxy URL %q: unexpected path %s", raw, u.Path)
+	}
+
+	return u, nil
+}
+
+// ProxyFromEnvironment reads HTTPS_PROXY, HTTP_PROXY and NO_PROXY (or their
+// lowercase forms) and returns a proxy function for http.Transport.
+// Unlike http.ProxyFromEnvironment the environment is read when the function
+// is built rather than once per process, and malformed proxy URLs are
+// reported instead of silently ignored.
+func ProxyFromEnvironment() (func(*http.Request) (*url.URL, error), error) {
+	cfg := httpproxy.FromEnvironment()
+
+	for _, raw := range []string{cfg.HTTPSProxy, cfg.HTTPProxy} {
+		if raw == "" {
+			continue
+		}
+		// Like net/http, accept "host:port" without a scheme
+		if !strings.Contains(raw, "://") {
+			raw = "http://" + raw
+		}
+		if strings.HasPrefix(raw, "socks5://") {
+			continue
+		}
+		if _, err := ValidateProxyURL(raw); err != nil {
+			return nil, fmt.Errorf("proxy environment: %w", err)
+		}
+	}
+
+	proxyFunc := cfg.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}, nil
+}
+
+// NewHTTPClient returns an HTTP client whose transport honors the proxy
+// environment variables. Provider SDK clients should be built on top of it.
+func NewHTTPClient(timeout time.Duration) (*http.Client, error) {
+	proxy, err := ProxyFromEnvironment()
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: transport,
+	}, nil
+}
+
+// SSHProxyCommand returns an ssh ProxyCommand that tunnels through the HTTP
+// CONNECT proxy at proxyURL, using corkscrew when installed and nc otherwise.
+// The returned command contains the %h and %p tokens for ssh to expand.
+func SSHProxyCommand(proxyURL string) (string, error) {
+	u, err := ValidateProxyURL(proxyURL)
+	if err != nil {
+		return "", err
+	}
+
+	// corkscrew and nc only speak plain HTTP CONNECT
+	if u.Scheme != "http" {
+		return "", fmt.Errorf("SSH proxy %q: only http:// proxies are supported for SSH", proxyURL)
+	}
+	if u.User != nil {
+		return "", fmt.Errorf("SSH proxy %q: proxy credentials are not supported for SSH", proxyURL)
+	}
+
+	host := u.Hostname()
+	port := u.Port()
+	if port == "" {
+		port = "80"
+	}
+
+	if _, err := lookPath("corkscrew"); err == nil {
+		return fmt.Sprintf("corkscrew %s %s %%h %%p", host, port), nil
+	}
+	if _, err := lookPath("nc"); err == nil {
+		return fmt.Sprintf("nc -X connect -x %s %%h %%p", net.JoinHostPort(host, port)), nil
+	}
+
+	return "", fmt.Errorf("SSH proxy %q: neither corkscrew nor nc found in PATH", proxyURL)
+}
@@ -2,6 +2,9 @@ package orchestrator
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/chalkan3/sloth-kubernetes/internal/orchestrator/components"
 	"github.com/chalkan3/sloth-kubernetes/pkg/config"
@@ -32,8 +35,9 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 
 	// Phase 1: SSH Keys
 	ctx.Log.Info("🔑 Phase 1: Generating SSH keys...", nil)
+	phaseDone := startPhase("ssh-keys")
 	sshKeyComponent, err := components.NewSSHKeyComponent(ctx, fmt.Sprintf("%s-ssh-keys", name), cfg, pulumi.Parent(component))
-	if err != nil {
+	if err := phaseDone(err); err != nil {
 		return nil, fmt.Errorf("failed to create SSH keys: %w", err)
 	}
 
@@ -60,6 +64,9 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 		ctx.Log.Info("⚠️  IMPORTANT: Nodes will ONLY be created AFTER bastion is 100% validated", nil)
 		ctx.Log.Info("", nil)
 
+		phaseDone = startPhase("bastion")
+		cfg.Security.Bastion.SaltBootstrap = cfg.Security.SaltBootstrapDownload()
+		cfg.Security.Bastion.WireGuardIP = cfg.Network.WireGuard.ParsedSubnet().BastionIP()
 		bastionComponent, err = components.NewBastionComponent(
 			ctx,
 			fmt.Sprintf("%s-bastion", name),
@@ -71,7 +78,7 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 			pulumi.Parent(component),
 			pulumi.DependsOn([]pulumi.Resource{sshKeyComponent}),
 		)
-		if err != nil {
+		if err := phaseDone(err); err != nil {
 			return nil, fmt.Errorf("failed to create bastion: %w", err)
 		}
 
@@ -123,6 +130,7 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 	ctx.Log.Info("════════════════════════════════════════════════════════════", nil)
 	ctx.Log.Info("", nil)
 
+	phaseDone = startPhase("nodes")
 	nodeComponent, realNodes, err := components.NewRealNodeDeploymentComponent(
 		ctx,
 		fmt.Sprintf("%s-nodes", name),
@@ -136,7 +144,7 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 		pulumi.Parent(component),
 		pulumi.DependsOn(nodeDependencies), // WAIT for bastion to be validated (or SSH keys if no bastion)
 	)
-	if err != nil {
+	if err := phaseDone(err); err != nil {
 		return nil, fmt.Errorf("failed to deploy nodes: %w", err)
 	}
 
@@ -150,6 +158,7 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 	ctx.Log.Info("⏳ Waiting for cloud-init to complete (Docker + WireGuard installation)...", nil)
 	ctx.Log.Info("", nil)
 
+	phaseDone = startPhase("cloud-init")
 	cloudInitValidator, err := components.NewCloudInitValidatorComponent(
 		ctx,
 		fmt.Sprintf("%s-cloudinit-validator", name),
@@ -159,12 +168,36 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 		pulumi.Parent(component),
 		pulumi.DependsOn([]pulumi.Resource{nodeComponent}),
 	)
-	if err != nil {
+	if err := phaseDone(err); err != nil {
 		return nil, fmt.Errorf("failed to validate cloud-init: %w", err)
 	}
 
 	ctx.Log.Info("✅ Cloud-init validation passed - Docker and WireGuard installed on all nodes", nil)
 
+	// Phase 2.6: Kernel modules and sysctls (same on every node)
+	ctx.Log.Info("", nil)
+	ctx.Log.Info("════════════════════════════════════════════════════════════", nil)
+	ctx.Log.Info("⚙️  Phase 2.6: SYSTEM TUNING (KERNEL MODULES + SYSCTLS)", nil)
+	ctx.Log.Info("════════════════════════════════════════════════════════════", nil)
+	ctx.Log.Info("", nil)
+
+	phaseDone = startPhase("system-tuning")
+	systemTuning, err := components.NewSystemTuningComponent(
+		ctx,
+		fmt.Sprintf("%s-system-tuning", name),
+		realNodes,
+		cfg.Kubernetes.SystemTuning,
+		sshKeyComponent.PrivateKey,
+		bastionComponent,
+		pulumi.Parent(component),
+		pulumi.DependsOn([]pulumi.Resource{cloudInitValidator}),
+	)
+	if err := phaseDone(err); err != nil {
+		return nil, fmt.Errorf("failed to apply system tuning: %w", err)
+	}
+
+	ctx.Log.Info("✅ System tuning applied on all nodes", nil)
+
 	// Phase 3: WireGuard Mesh VPN (REAL) - includes bastion if enabled
 	ctx.Log.Info("", nil)
 	ctx.Log.Info("════════════════════════════════════════════════════════════", nil)
@@ -175,29 +208,63 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 	// Build dependency list - must wait for cloud-init validation
 	// CRITICAL: WireGuard must be installed (via cloud-init) before we configure the mesh
 	var wgDependencies []pulumi.Resource
-	wgDependencies = append(wgDependencies, cloudInitValidator)
+	wgDependencies = append(wgDependencies, cloudInitValidator, systemTuning)
 	if bastionComponent != nil {
 		ctx.Log.Info("🏰 WireGuard mesh will wait for bastion provisioning to complete...", nil)
 		wgDependencies = append(wgDependencies, bastionComponent)
 	}
 
-	wgComponent, err := components.NewWireGuardMeshComponent(
-		ctx,
-		fmt.Sprintf("%s-wireguard", name),
-		realNodes,
-		sshKeyComponent.PrivateKey,
-		bastionComponent, // Pass bastion to be included in VPN mesh
-		pulumi.Parent(component),
-		pulumi.DependsOn(wgDependencies),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to setup WireGuard: %w", err)
-	}
+	phaseDone = startPhase("wireguard")
+	var wgComponent pulumi.Resource
+	if cfg.Network.WireGuard.HubMode() {
+		// Existing WireGuard server (create: false): nodes become spokes of the hub
+		hubSSHKey, err := readHubSSHKey(cfg.Network.WireGuard.SSHPrivateKeyPath)
+		if err != nil {
+			return nil, phaseDone(err)
+		}
 
-	ctx.Log.Info("✅ WireGuard mesh VPN configured", nil)
+		hubComponent, err := components.NewWireGuardHubComponent(
+			ctx,
+			fmt.Sprintf("%s-wireguard-hub", name),
+			realNodes,
+			cfg.Network.WireGuard,
+			sshKeyComponent.PrivateKey,
+			hubSSHKey,
+			bastionComponent,
+			pulumi.Parent(component),
+			pulumi.DependsOn(wgDependencies),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup WireGuard hub: %w", phaseDone(err))
+		}
+		ctx.Export("wireguard_hub_peers", hubComponent.HubPeers)
+		wgComponent = hubComponent
+
+		ctx.Log.Info("✅ WireGuard hub-and-spoke VPN configured", nil)
+	} else {
+		meshComponent, err := components.NewWireGuardMeshComponent(
+			ctx,
+			fmt.Sprintf("%s-wireguard", name),
+			realNodes,
+			sshKeyComponent.PrivateKey,
+			bastionComponent, // Pass bastion to be included in VPN mesh
+			cfg.Network.WireGuard.ParsedSubnet(),
+			config.HybridVPCNetworks(cfg),
+			pulumi.Parent(component),
+			pulumi.DependsOn(wgDependencies),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup WireGuard: %w", phaseDone(err))
+		}
+		wgComponent = meshComponent
+
+		ctx.Log.Info("✅ WireGuard mesh VPN configured", nil)
+	}
+	_ = phaseDone(nil)
 
 	// Phase 3.5: Validate VPN connectivity before RKE2
 	ctx.Log.Info("🔍 Phase 3.5: Validating VPN connectivity...", nil)
+	phaseDone = startPhase("vpn-verification")
 	vpnValidator, err := components.NewVPNValidatorComponent(
 		ctx,
 		fmt.Sprintf("%s-vpn-validator", name),
@@ -207,14 +274,36 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 		pulumi.Parent(component),
 		pulumi.DependsOn([]pulumi.Resource{wgComponent}),
 	)
-	if err != nil {
+	if err := phaseDone(err); err != nil {
 		return nil, fmt.Errorf("failed to validate VPN: %w", err)
 	}
 
 	ctx.Log.Info("✅ VPN validation passed - all nodes reachable", nil)
 
+	// Phase 3.6: API load balancer in front of the masters (HA only)
+	var apiLoadBalancer *components.APILoadBalancerComponent
+	if lbConfig := cfg.APILoadBalancer(); lbConfig != nil {
+		ctx.Log.Info("⚖️  Phase 3.6: Creating API load balancer...", nil)
+		phaseDone = startPhase("api-load-balancer")
+		apiLoadBalancer, err = components.NewAPILoadBalancerComponent(
+			ctx,
+			fmt.Sprintf("%s-api-lb", name),
+			lbConfig,
+			realNodes,
+			pulumi.Parent(component),
+			pulumi.DependsOn([]pulumi.Resource{nodeComponent}),
+		)
+		if err := phaseDone(err); err != nil {
+			return nil, fmt.Errorf("failed to create API load balancer: %w", err)
+		}
+		if apiLoadBalancer != nil {
+			ctx.Log.Info(fmt.Sprintf("✅ API load balancer %s created on %s", lbConfig.Name, lbConfig.Provider), nil)
+		}
+	}
+
 	// Phase 4: K3s Kubernetes Cluster (REAL)
 	ctx.Log.Info("☸️  Phase 4: Installing K3s Kubernetes cluster...", nil)
+	phaseDone = startPhase("k3s")
 	rkeComponent, err := components.NewK3sRealComponent(
 		ctx,
 		fmt.Sprintf("%s-k3s", name),
@@ -222,17 +311,39 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 		sshKeyComponent.PrivateKey,
 		cfg,
 		bastionComponent, // Pass bastion for ProxyJump SSH connections
+		apiLoadBalancer,  // Nodes join through it when there is one
 		pulumi.Parent(component),
 		pulumi.DependsOn([]pulumi.Resource{vpnValidator}), // Wait for VPN validation
 	)
-	if err != nil {
+	if err := phaseDone(err); err != nil {
 		return nil, fmt.Errorf("failed to install K3s: %w", err)
 	}
 
 	ctx.Log.Info("✅ K3s cluster installed", nil)
 
+	// Phase 4.5: Node-local DNS cache (if enabled)
+	if cfg.Kubernetes.NodeLocalDNSEnabled() {
+		ctx.Log.Info("🗂️  Phase 4.5: Installing node-local DNS cache...", nil)
+		phaseDone = startPhase("node-local-dns")
+		_, err := components.NewNodeLocalDNSComponent(
+			ctx,
+			fmt.Sprintf("%s-node-local-dns", name),
+			&cfg.Kubernetes,
+			realNodes,
+			bastionComponent,
+			sshKeyComponent.PrivateKey,
+			pulumi.Parent(component),
+			pulumi.DependsOn([]pulumi.Resource{rkeComponent}),
+		)
+		if err := phaseDone(err); err != nil {
+			return nil, fmt.Errorf("failed to install node-local DNS: %w", err)
+		}
+		ctx.Log.Info("✅ Node-local DNS cache installed", nil)
+	}
+
 	// Phase 5: DNS Records (REAL)
 	ctx.Log.Info("🌐 Phase 5: Creating DNS records...", nil)
+	phaseDone = startPhase("dns")
 	dnsComponent, err := components.NewDNSRealComponent(
 		ctx,
 		fmt.Sprintf("%s-dns", name),
@@ -241,7 +352,7 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 		pulumi.Parent(component),
 		pulumi.DependsOn([]pulumi.Resource{rkeComponent}),
 	)
-	if err != nil {
+	if err := phaseDone(err); err != nil {
 		return nil, fmt.Errorf("failed to create DNS: %w", err)
 	}
 
@@ -256,6 +367,7 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 		ctx.Log.Info("════════════════════════════════════════════════════════════", nil)
 		ctx.Log.Info("", nil)
 
+		phaseDone = startPhase("argocd")
 		argoCDComponent, err = components.NewArgoCDInstallerComponent(
 			ctx,
 			fmt.Sprintf("%s-argocd", name),
@@ -266,7 +378,7 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 			pulumi.Parent(component),
 			pulumi.DependsOn([]pulumi.Resource{rkeComponent, dnsComponent}),
 		)
-		if err != nil {
+		if err := phaseDone(err); err != nil {
 			ctx.Log.Warn(fmt.Sprintf("⚠️  ArgoCD installation failed: %v", err), nil)
 			ctx.Log.Warn("   Cluster is ready but ArgoCD was not installed", nil)
 		} else {
@@ -275,11 +387,16 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 	}
 
 	// Set outputs
+	phaseDone = startPhase("outputs")
 	component.ClusterName = pulumi.String(cfg.Metadata.Name).ToStringOutput()
 	component.KubeConfig = rkeComponent.KubeConfig
 	component.SSHPrivateKey = sshKeyComponent.PrivateKeyPath
 	component.SSHPublicKey = sshKeyComponent.PublicKey
 	component.APIEndpoint = dnsComponent.APIEndpoint
+	if apiLoadBalancer != nil {
+		component.APIEndpoint = apiLoadBalancer.Endpoint
+		ctx.Export("api_load_balancer_ip", apiLoadBalancer.IP)
+	}
 	component.Status = pulumi.String("✅ REAL Kubernetes cluster deployed successfully!").ToStringOutput()
 
 	// Export detailed node information as a structured map for CLI commands
@@ -293,6 +410,7 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 			"vpn_ip":     node.WireGuardIP,
 			"provider":   node.Provider,
 			"region":     node.Region,
+			"zone":       node.Zone,
 			"size":       node.Size,
 			"roles":      node.Roles,
 			"status":     node.Status,
@@ -300,6 +418,9 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 	}
 	ctx.Export("nodes", nodesMap)
 	ctx.Export("node_count", pulumi.Int(len(realNodes)))
+	ctx.Export("wireguard_network", pulumi.String(cfg.Network.WireGuard.ParsedSubnet().String()))
+
+	exportNodeZones(ctx, cfg.ZoneBreakdown())
 
 	// Export bastion information if enabled
 	if bastionComponent != nil {
@@ -312,15 +433,16 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 			"region":     bastionComponent.Region,
 			"ssh_port":   bastionComponent.SSHPort,
 			"status":     bastionComponent.Status,
+			"jump_hosts": bastionJumpHosts(cfg.Security.Bastion),
 		})
 		ctx.Export("bastion_enabled", pulumi.Bool(true))
 	} else {
 		ctx.Export("bastion_enabled", pulumi.Bool(false))
 	}
 
-	// Export ArgoCD information if installed
+	// Export ArgoCD information if installed; its admin password is exported
+	// with the other admin passwords
 	if argoCDComponent != nil {
-		ctx.Export("argocd_admin_password", argoCDComponent.AdminPassword)
 		ctx.Export("argocd_status", argoCDComponent.Status)
 	}
 
@@ -332,10 +454,47 @@ func NewSimpleRealOrchestratorComponent(ctx *pulumi.Context, name string, cfg *c
 		"apiEndpoint":   component.APIEndpoint,
 		"status":        component.Status,
 	}); err != nil {
-		return nil, err
+		return nil, phaseDone(err)
 	}
+	_ = phaseDone(nil)
 
 	ctx.Log.Info("🎉 REAL Kubernetes cluster deployment COMPLETE!", nil)
 
 	return component, nil
 }
+
+// readHubSSHKey reads the key used to register spokes on an existing WireGuard
+// server. An empty path means the peers are added to the hub manually.
+func readHubSSHKey(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve WireGuard hub SSH key path: %w", err)
+		}
+		path = filepath.Join(home, path[2:])
+	}
+
+	key, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read WireGuard hub SSH key: %w", err)
+	}
+	return string(key), nil
+}
+
+// bastionJumpHosts returns the ssh destinations of the jump hosts in front of
+// the bastion, outermost first, so CLI commands can reach it through them.
+// The chain is validated before deployment.
+func bastionJumpHosts(bastion *config.BastionConfig) pulumi.StringArray {
+	hosts := pulumi.StringArray{}
+	if bastion == nil {
+		return hosts
+	}
+	chain, _ := bastion.JumpChain()
+	for _, host := range chain {
+		hosts = append(hosts, pulumi.String(host.Address()))
+	}
+	return hosts
+}
@@ -0,0 +1,188 @@
+package components
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-digitalocean/sdk/v4/go/digitalocean"
+	"github.com/pulumi/pulumi-linode/sdk/v4/go/linode"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+// APILoadBalancerComponent is the TCP load balancer in front of the K3s
+// servers of a highAvailability cluster
+type APILoadBalancerComponent struct {
+	pulumi.ResourceState
+
+	IP       pulumi.StringOutput `pulumi:"ip"`
+	Endpoint pulumi.StringOutput `pulumi:"endpoint"`
+}
+
+// NewAPILoadBalancerComponent creates the API load balancer of lbConfig in
+// front of the K3s servers of its provider. Providers without a load balancer
+// implementation are skipped with a warning, leaving nodes to join the first
+// server directly.
+func NewAPILoadBalancerComponent(
+	ctx *pulumi.Context,
+	name string,
+	lbConfig *config.LoadBalancerConfig,
+	nodes []*RealNodeComponent,
+	opts ...pulumi.ResourceOption,
+) (*APILoadBalancerComponent, error) {
+	if lbConfig == nil {
+		return nil, nil // No HA
+	}
+	if lbConfig.Provider != "digitalocean" && lbConfig.Provider != "linode" {
+		ctx.Log.Warn(fmt.Sprintf("⚠️  No API load balancer on %s: nodes join the first master directly", lbConfig.Provider), nil)
+		return nil, nil
+	}
+
+	// A provider load balancer reaches the servers of its provider and region
+	var targets, others []*RealNodeComponent
+	for _, node := range k3sMasters(nodes) {
+		if node.nodeConfig.Provider == lbConfig.Provider && (len(targets) == 0 || node.nodeConfig.Region == targets[0].nodeConfig.Region) {
+			targets = append(targets, node)
+		} else {
+			others = append(others, node)
+		}
+	}
+	if len(targets) == 0 {
+		ctx.Log.Warn(fmt.Sprintf("⚠️  No API load balancer: no K3s server runs on %s", lbConfig.Provider), nil)
+		return nil, nil
+	}
+	for _, node := range others {
+		ctx.Log.Warn(fmt.Sprintf("⚠️  API load balancer on %s cannot reach master %s", lbConfig.Provider, node.nodeConfig.Name), nil)
+	}
+
+	// K3s serves the API and node registration on the same port
+	var ports []config.PortConfig
+	for _, port := range lbConfig.Ports {
+		if port.TargetPort == config.APIServerPort {
+			ports = append(ports, port)
+		}
+	}
+
+	component := &APILoadBalancerComponent{}
+	err := ctx.RegisterComponentResource("kubernetes-create:network:APILoadBalancer", name, component, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	region := targets[0].nodeConfig.Region
+	if lbConfig.Provider == "digitalocean" {
+		component.IP, err = createDigitalOceanAPILoadBalancer(ctx, name, lbConfig.Name, region, ports, targets, component)
+	} else {
+		component.IP, err = createLinodeAPILoadBalancer(ctx, name, lbConfig.Name, region, ports, targets, component)
+	}
+	if err != nil {
+		return nil, err
+	}
+	component.Endpoint = pulumi.Sprintf("https://%s:%d", component.IP, config.APIServerPort)
+
+	if err := ctx.RegisterResourceOutputs(component, pulumi.Map{
+		"ip":       component.IP,
+		"endpoint": component.Endpoint,
+	}); err != nil {
+		return nil, err
+	}
+
+	return component, nil
+}
+
+// createDigitalOceanAPILoadBalancer forwards ports to the droplets of targets,
+// checking them with a TCP connection as the API server only speaks TLS
+func createDigitalOceanAPILoadBalancer(ctx *pulumi.Context, name, lbName, region string, ports []config.PortConfig, targets []*RealNodeComponent, component *APILoadBalancerComponent) (pulumi.StringOutput, error) {
+	dropletIDs := make(pulumi.IntArray, 0, len(targets))
+	for _, node := range targets {
+		dropletIDs = append(dropletIDs, node.DropletID.ApplyT(func(id pulumi.ID) int {
+			var idInt int
+			fmt.Sscanf(string(id), "%d", &idInt)
+			return idInt
+		}).(pulumi.IntOutput))
+	}
+
+	forwardingRules := make(digitalocean.LoadBalancerForwardingRuleArray, len(ports))
+	for i, port := range ports {
+		forwardingRules[i] = &digitalocean.LoadBalancerForwardingRuleArgs{
+			EntryPort:      pulumi.Int(port.Port),
+			TargetPort:     pulumi.Int(port.TargetPort),
+			EntryProtocol:  pulumi.String("tcp"),
+			TargetProtocol: pulumi.String("tcp"),
+		}
+	}
+
+	loadBalancer, err := digitalocean.NewLoadBalancer(ctx, name, &digitalocean.LoadBalancerArgs{
+		Name:            pulumi.String(lbName),
+		Region:          pulumi.String(region),
+		Size:            pulumi.String("lb-small"),
+		ForwardingRules: forwardingRules,
+		DropletIds:      dropletIDs,
+		Healthcheck: &digitalocean.LoadBalancerHealthcheckArgs{
+			Protocol: pulumi.String("tcp"),
+			Port:     pulumi.Int(config.APIServerPort),
+		},
+	}, pulumi.Parent(component))
+	if err != nil {
+		return pulumi.StringOutput{}, fmt.Errorf("failed to create API load balancer: %w", err)
+	}
+	return loadBalancer.Ip, nil
+}
+
+// createLinodeAPILoadBalancer creates a NodeBalancer forwarding ports to the
+// private addresses of targets
+func createLinodeAPILoadBalancer(ctx *pulumi.Context, name, lbName, region string, ports []config.PortConfig, targets []*RealNodeComponent, component *APILoadBalancerComponent) (pulumi.StringOutput, error) {
+	nodeBalancer, err := linode.NewNodeBalancer(ctx, name, &linode.NodeBalancerArgs{
+		Label:  pulumi.String(lbName),
+		Region: pulumi.String(region),
+		Tags:   pulumi.StringArray{pulumi.String("kubernetes"), pulumi.String(ctx.Stack())},
+	}, pulumi.Parent(component))
+	if err != nil {
+		return pulumi.StringOutput{}, fmt.Errorf("failed to create API NodeBalancer: %w", err)
+	}
+	nodeBalancerID := nodeBalancer.ID().ApplyT(func(id pulumi.ID) int {
+		var idInt int
+		fmt.Sscanf(string(id), "%d", &idInt)
+		return idInt
+	}).(pulumi.IntOutput)
+
+	for _, port := range ports {
+		configName := fmt.Sprintf("%s-%d", name, port.Port)
+		nbConfig, err := linode.NewNodeBalancerConfig(ctx, configName, &linode.NodeBalancerConfigArgs{
+			NodebalancerId: nodeBalancerID,
+			Port:           pulumi.Int(port.Port),
+			Protocol:       pulumi.String("tcp"),
+			Algorithm:      pulumi.String("roundrobin"),
+			Check:          pulumi.String("connection"),
+			CheckInterval:  pulumi.Int(30),
+			CheckTimeout:   pulumi.Int(5),
+			CheckAttempts:  pulumi.Int(3),
+		}, pulumi.Parent(component))
+		if err != nil {
+			return pulumi.StringOutput{}, fmt.Errorf("failed to create API NodeBalancer config: %w", err)
+		}
+
+		for _, node := range targets {
+			targetPort := port.TargetPort
+			_, err := linode.NewNodeBalancerNode(ctx, fmt.Sprintf("%s-%s", configName, node.nodeConfig.Name), &linode.NodeBalancerNodeArgs{
+				NodebalancerId: nodeBalancerID,
+				ConfigId: nbConfig.ID().ApplyT(func(id pulumi.ID) int {
+					var idInt int
+					fmt.Sscanf(string(id), "%d", &idInt)
+					return idInt
+				}).(pulumi.IntOutput),
+				Address: node.PrivateIP.ApplyT(func(ip string) string {
+					return fmt.Sprintf("%s:%d", ip, targetPort)
+				}).(pulumi.StringOutput),
+				Label:  pulumi.String(node.nodeConfig.Name),
+				Mode:   pulumi.String("accept"),
+				Weight: pulumi.Int(100),
+			}, pulumi.Parent(component))
+			if err != nil {
+				return pulumi.StringOutput{}, fmt.Errorf("failed to add %s to the API NodeBalancer: %w", node.nodeConfig.Name, err)
+			}
+		}
+	}
+
+	return nodeBalancer.Ipv4, nil
+}
@@ -0,0 +1,88 @@
+package components
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// apiLoadBalancerNodes returns nodes of the providers given, in order
+func apiLoadBalancerNodes(providers ...string) []*RealNodeComponent {
+	var nodes []*RealNodeComponent
+	for i, provider := range providers {
+		node := &RealNodeComponent{nodeConfig: config.NodeConfig{Name: provider + "-" + string(rune('a'+i)), Provider: provider, Region: "nyc3"}}
+		node.DropletID = pulumi.ID("1234").ToIDOutput()
+		node.PrivateIP = pulumi.String("10.0.0.10").ToStringOutput()
+		nodes = append(nodes, node)
+	}
+	return nodes
+}
+
+// TestNewAPILoadBalancerComponent tests that the load balancer is created on
+// providers that implement one and skipped elsewhere
+func TestNewAPILoadBalancerComponent(t *testing.T) {
+	tests := []struct {
+		provider string
+		created  string
+	}{
+		{"digitalocean", "digitalocean:index/loadBalancer:LoadBalancer"},
+		{"linode", "linode:index/nodeBalancerNode:NodeBalancerNode"},
+		{"azure", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			mocks := &bastionCloudMocks{}
+			var lb *APILoadBalancerComponent
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				cfg := &config.ClusterConfig{
+					Metadata: config.Metadata{Name: "prod"},
+					Cluster:  config.ClusterSpec{HighAvailability: true},
+					Nodes: []config.NodeConfig{
+						{Name: "master-1", Provider: tt.provider, Roles: []string{config.RoleMaster}},
+					},
+				}
+				var err error
+				lb, err = NewAPILoadBalancerComponent(ctx, "prod-api-lb", cfg.APILoadBalancer(), apiLoadBalancerNodes(tt.provider, tt.provider, tt.provider, tt.provider))
+				return err
+			}, pulumi.WithMocks("project", "stack", mocks))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			created := strings.Join(mocks.types, ",")
+			if tt.created == "" {
+				if lb != nil || len(mocks.types) > 0 {
+					t.Errorf("Expected no load balancer on %s, got %s", tt.provider, created)
+				}
+				return
+			}
+			if lb == nil || !strings.Contains(created, tt.created) {
+				t.Errorf("Expected %s, got %s", tt.created, created)
+			}
+			// The fourth node is a worker
+			if tt.provider == "linode" && strings.Count(created, tt.created) != 3 {
+				t.Errorf("Expected the three K3s servers behind the NodeBalancer, got %s", created)
+			}
+		})
+	}
+}
+
+// TestK3sJoinAddress tests that nodes join through the API load balancer when
+// there is one
+func TestK3sJoinAddress(t *testing.T) {
+	if addr := k3sJoinAddress("10.8.0.10", ""); addr != "10.8.0.10" {
+		t.Errorf("Expected the first server without a load balancer, got %q", addr)
+	}
+	if addr := k3sJoinAddress("10.8.0.10", "203.0.113.50"); addr != "203.0.113.50" {
+		t.Errorf("Expected the load balancer, got %q", addr)
+	}
+	if args := k3sAPILoadBalancerArgs("203.0.113.50"); args != " --tls-san=203.0.113.50" {
+		t.Errorf("Unexpected server args: %q", args)
+	}
+	if args := k3sAPILoadBalancerArgs(""); args != "" {
+		t.Errorf("Expected no args without a load balancer, got %q", args)
+	}
+}
@@ -2,11 +2,13 @@ package components
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/pulumi/pulumi-command/sdk/go/command/remote"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 
 	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/health"
 )
 
 // ArgoCDInstallerComponent outputs
@@ -116,10 +118,10 @@ kubectl wait --for=condition=Ready pods --all -n %s --timeout=300s || {
   kubectl get pods -n %s
   exit 1
 }
-
+%s
 echo ""
 echo "✅ ArgoCD installed successfully!"
-		`, namespace, namespace, version, namespace, version, namespace, namespace)),
+		`, namespace, namespace, version, namespace, version, namespace, namespace, health.ArgoCDGate(namespace).WaitScript(5*time.Minute))),
 	}, pulumi.Parent(component))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create ArgoCD install command: %w", err)
@@ -170,8 +172,6 @@ echo "🎉 GitOps applications deployed!"
 		}
 	}
 
-	// Step 3: Get ArgoCD admin password
-	ctx.Log.Info("🚀 Step 3/3: Retrieving ArgoCD admin password...", nil)
 	var passwordDeps []pulumi.Resource
 	if applyCmd != nil {
 		passwordDeps = []pulumi.Resource{applyCmd}
@@ -179,9 +179,22 @@ echo "🎉 GitOps applications deployed!"
 		passwordDeps = []pulumi.Resource{installCmd}
 	}
 
-	getPasswordCmd, err := remote.NewCommand(ctx, fmt.Sprintf("%s-get-password", name), &remote.CommandArgs{
-		Connection: connArgs,
-		Create: pulumi.String(fmt.Sprintf(`#!/bin/bash
+	// Step 3: Set the configured admin password, or read the one ArgoCD generated
+	if argoCDConfig.AdminPassword != "" {
+		ctx.Log.Info("🚀 Step 3/3: Setting ArgoCD admin password...", nil)
+		_, err := remote.NewCommand(ctx, fmt.Sprintf("%s-set-password", name), &remote.CommandArgs{
+			Connection: connArgs,
+			Create:     pulumi.ToSecret(pulumi.String(argoCDSetPasswordScript(namespace, argoCDConfig.AdminPassword))).(pulumi.StringOutput),
+		}, pulumi.Parent(component), pulumi.DependsOn(passwordDeps))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create set password command: %w", err)
+		}
+		component.AdminPassword = pulumi.ToSecret(pulumi.String(argoCDConfig.AdminPassword)).(pulumi.StringOutput)
+	} else {
+		ctx.Log.Info("🚀 Step 3/3: Retrieving ArgoCD admin password...", nil)
+		getPasswordCmd, err := remote.NewCommand(ctx, fmt.Sprintf("%s-get-password", name), &remote.CommandArgs{
+			Connection: connArgs,
+			Create: pulumi.String(fmt.Sprintf(`#!/bin/bash
 set -e
 
 # Wait a bit for the secret to be created
@@ -190,12 +203,13 @@ sleep 5
 # Get ArgoCD admin password
 kubectl -n %s get secret argocd-initial-admin-secret -o jsonpath="{.data.password}" 2>/dev/null | base64 -d || echo "password-not-ready"
 		`, namespace)),
-	}, pulumi.Parent(component), pulumi.DependsOn(passwordDeps))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create get password command: %w", err)
+		}, pulumi.Parent(component), pulumi.DependsOn(passwordDeps), pulumi.AdditionalSecretOutputs([]string{"stdout"}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create get password command: %w", err)
+		}
+		component.AdminPassword = getPasswordCmd.Stdout
 	}
 
-	component.AdminPassword = getPasswordCmd.Stdout
 	component.Status = pulumi.String("installed").ToStringOutput()
 
 	// Register outputs
@@ -208,3 +222,18 @@ kubectl -n %s get secret argocd-initial-admin-secret -o jsonpath="{.data.passwor
 
 	return component, nil
 }
+
+// argoCDSetPasswordScript sets the admin password of ArgoCD, hashed with the
+// argocd CLI of the server, and removes the generated initial password
+func argoCDSetPasswordScript(namespace, password string) string {
+	return fmt.Sprintf(`#!/bin/bash
+set -e
+
+HASH=$(kubectl -n %s exec deploy/argocd-server -- argocd account bcrypt --password '%s')
+kubectl -n %s patch secret argocd-secret --type merge \
+  -p "{\"stringData\": {\"admin.password\": \"$HASH\", \"admin.passwordMtime\": \"$(date -u +%%FT%%TZ)\"}}"
+kubectl -n %s delete secret argocd-initial-admin-secret --ignore-not-found
+
+echo "✅ ArgoCD admin password set"
+`, namespace, password, namespace, namespace)
+}
@@ -2,6 +2,7 @@ package components
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/chalkan3/sloth-kubernetes/pkg/config"
 	azurecompute "github.com/pulumi/pulumi-azure-native-sdk/compute/v2"
@@ -25,6 +26,10 @@ type BastionComponent struct {
 	Region      pulumi.StringOutput `pulumi:"region"`
 	SSHPort     pulumi.IntOutput    `pulumi:"sshPort"`
 	Status      pulumi.StringOutput `pulumi:"status"`
+
+	// wireGuardIP is WireGuardIP as a plain value, for the scripts that
+	// configure the mesh
+	wireGuardIP string
 }
 
 // NewBastionComponent creates a bastion host for secure cluster access
@@ -61,8 +66,12 @@ func NewBastionComponent(
 		bastionConfig.SSHPort = 22
 	}
 
-	// Assign VPN IP for bastion (10.8.0.5 - reserved for bastion)
-	bastionVPNIP := "10.8.0.5"
+	// Assign VPN IP for bastion (.5 of the WireGuard subnet - reserved for bastion)
+	bastionVPNIP := bastionConfig.WireGuardIP
+	if bastionVPNIP == "" {
+		bastionVPNIP = (&config.WireGuardConfig{}).ParsedSubnet().BastionIP()
+	}
+	component.wireGuardIP = bastionVPNIP
 
 	component.BastionName = pulumi.String(bastionConfig.Name).ToStringOutput()
 	component.Provider = pulumi.String(bastionConfig.Provider).ToStringOutput()
@@ -78,8 +87,12 @@ func NewBastionComponent(
 		err = createLinodeBastion(ctx, name, bastionConfig, sshKeyOutput, linodeToken, component)
 	case "azure":
 		err = createAzureBastion(ctx, name, bastionConfig, sshKeyOutput, component)
+	case "aws":
+		err = createAWSBastion(ctx, name, bastionConfig, sshKeyOutput, component)
+	case "gcp":
+		err = createGCPBastion(ctx, name, bastionConfig, sshKeyOutput, component)
 	default:
-		return nil, fmt.Errorf("unsupported bastion provider: %s (only digitalocean, linode, and azure are supported)", bastionConfig.Provider)
+		return nil, fmt.Errorf("unsupported bastion provider: %s (only digitalocean, linode, azure, aws, and gcp are supported)", bastionConfig.Provider)
 	}
 
 	if err != nil {
@@ -152,7 +165,7 @@ func createDigitalOceanBastion(
 			pulumi.String(ctx.Stack()),
 		},
 		Ipv6:       pulumi.Bool(true),
-		Monitoring: pulumi.Bool(true),
+		Monitoring: pulumi.Bool(bastionConfig.Monitoring),
 	}, pulumi.Parent(component))
 	if err != nil {
 		return fmt.Errorf("failed to create bastion droplet: %w", err)
@@ -421,6 +434,378 @@ func createAzureBastion(
 	return nil
 }
 
+// The AWS and GCP bastion resources are registered by their type tokens, so
+// the cluster build doesn't pull in the full AWS and GCP SDKs for a single VM
+
+// awsProviderResource is an explicit AWS provider pinned to the bastion region
+type awsProviderResource struct {
+	pulumi.ProviderResourceState
+}
+
+// cloudResource is an AWS or GCP resource whose outputs, other than its ID,
+// aren't read
+type cloudResource struct {
+	pulumi.CustomResourceState
+}
+
+type awsKeyPair struct {
+	pulumi.CustomResourceState
+
+	KeyName pulumi.StringOutput `pulumi:"keyName"`
+}
+
+type awsInstance struct {
+	pulumi.CustomResourceState
+
+	PrivateIP pulumi.StringOutput `pulumi:"privateIp"`
+}
+
+type awsElasticIP struct {
+	pulumi.CustomResourceState
+
+	PublicIP pulumi.StringOutput `pulumi:"publicIp"`
+}
+
+type gcpAddress struct {
+	pulumi.CustomResourceState
+
+	Address pulumi.StringOutput `pulumi:"address"`
+}
+
+type gcpInstance struct {
+	pulumi.CustomResourceState
+
+	NetworkInterfaces pulumi.MapArrayOutput `pulumi:"networkInterfaces"`
+}
+
+// ubuntuAMIOwner is Canonical's AWS account, which publishes the Ubuntu AMIs
+const ubuntuAMIOwner = "099720109477"
+
+// bastionZone returns the configured zone, or the region's first zone for
+// AWS (us-east-1a) and GCP (us-central1-a)
+func bastionZone(provider, region, zone string) string {
+	if zone != "" {
+		return zone
+	}
+	if provider == "gcp" {
+		return region + "-a"
+	}
+	return region + "a"
+}
+
+// createAWSBastion creates an EC2 bastion in its own VPC and public subnet,
+// with a security group and an Elastic IP
+func createAWSBastion(
+	ctx *pulumi.Context,
+	name string,
+	bastionConfig *config.BastionConfig,
+	sshKeyOutput pulumi.StringOutput,
+	component *BastionComponent,
+) error {
+	region := bastionConfig.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	zone := bastionZone("aws", region, bastionConfig.Zone)
+	if err := config.ValidateZoneForRegion("aws", region, zone); err != nil {
+		return fmt.Errorf("invalid bastion zone: %w", err)
+	}
+
+	instanceType := bastionConfig.Size
+	if instanceType == "" {
+		instanceType = "t3.micro" // Free tier eligible
+	}
+
+	tags := func(resource string) pulumi.StringMap {
+		return pulumi.StringMap{
+			"Name":      pulumi.Sprintf("%s-%s", name, resource),
+			"Role":      pulumi.String("bastion"),
+			"ManagedBy": pulumi.String("sloth-kubernetes"),
+		}
+	}
+
+	// Pin every resource to the bastion region
+	provider := &awsProviderResource{}
+	if err := ctx.RegisterResource("pulumi:providers:aws", fmt.Sprintf("%s-aws", name), pulumi.Map{
+		"region": pulumi.String(region),
+	}, provider, pulumi.Parent(component)); err != nil {
+		return fmt.Errorf("failed to create AWS provider: %w", err)
+	}
+	opts := []pulumi.ResourceOption{pulumi.Parent(component), pulumi.Provider(provider)}
+
+	// Create VPC
+	vpc := &cloudResource{}
+	if err := ctx.RegisterResource("aws:ec2/vpc:Vpc", fmt.Sprintf("%s-vpc", name), pulumi.Map{
+		"cidrBlock":          pulumi.String("10.100.0.0/16"),
+		"enableDnsHostnames": pulumi.Bool(true),
+		"enableDnsSupport":   pulumi.Bool(true),
+		"tags":               tags("vpc"),
+	}, vpc, opts...); err != nil {
+		return fmt.Errorf("failed to create VPC: %w", err)
+	}
+
+	// Create Internet Gateway
+	igw := &cloudResource{}
+	if err := ctx.RegisterResource("aws:ec2/internetGateway:InternetGateway", fmt.Sprintf("%s-igw", name), pulumi.Map{
+		"vpcId": vpc.ID(),
+		"tags":  tags("igw"),
+	}, igw, opts...); err != nil {
+		return fmt.Errorf("failed to create internet gateway: %w", err)
+	}
+
+	// Create public Subnet
+	subnet := &cloudResource{}
+	if err := ctx.RegisterResource("aws:ec2/subnet:Subnet", fmt.Sprintf("%s-subnet", name), pulumi.Map{
+		"vpcId":               vpc.ID(),
+		"cidrBlock":           pulumi.String("10.100.1.0/24"),
+		"availabilityZone":    pulumi.String(zone),
+		"mapPublicIpOnLaunch": pulumi.Bool(true),
+		"tags":                tags("subnet"),
+	}, subnet, opts...); err != nil {
+		return fmt.Errorf("failed to create subnet: %w", err)
+	}
+
+	// Route the subnet through the Internet Gateway
+	routeTable := &cloudResource{}
+	if err := ctx.RegisterResource("aws:ec2/routeTable:RouteTable", fmt.Sprintf("%s-rt", name), pulumi.Map{
+		"vpcId": vpc.ID(),
+		"routes": pulumi.MapArray{
+			pulumi.Map{
+				"cidrBlock": pulumi.String("0.0.0.0/0"),
+				"gatewayId": igw.ID(),
+			},
+		},
+		"tags": tags("rt"),
+	}, routeTable, opts...); err != nil {
+		return fmt.Errorf("failed to create route table: %w", err)
+	}
+
+	if err := ctx.RegisterResource("aws:ec2/routeTableAssociation:RouteTableAssociation", fmt.Sprintf("%s-rta", name), pulumi.Map{
+		"subnetId":     subnet.ID(),
+		"routeTableId": routeTable.ID(),
+	}, &cloudResource{}, opts...); err != nil {
+		return fmt.Errorf("failed to associate route table: %w", err)
+	}
+
+	// Create Security Group
+	securityGroup := &cloudResource{}
+	if err := ctx.RegisterResource("aws:ec2/securityGroup:SecurityGroup", fmt.Sprintf("%s-sg", name), pulumi.Map{
+		"vpcId":       vpc.ID(),
+		"description": pulumi.String("Bastion SSH and WireGuard access"),
+		"ingress": pulumi.MapArray{
+			pulumi.Map{
+				"description": pulumi.String("allow-ssh"),
+				"protocol":    pulumi.String("tcp"),
+				"fromPort":    pulumi.Int(bastionConfig.SSHPort),
+				"toPort":      pulumi.Int(bastionConfig.SSHPort),
+				"cidrBlocks":  pulumi.StringArray{pulumi.String("0.0.0.0/0")},
+			},
+			pulumi.Map{
+				"description": pulumi.String("allow-wireguard"),
+				"protocol":    pulumi.String("udp"),
+				"fromPort":    pulumi.Int(51820),
+				"toPort":      pulumi.Int(51820),
+				"cidrBlocks":  pulumi.StringArray{pulumi.String("0.0.0.0/0")},
+			},
+		},
+		"egress": pulumi.MapArray{
+			pulumi.Map{
+				"protocol":   pulumi.String("-1"),
+				"fromPort":   pulumi.Int(0),
+				"toPort":     pulumi.Int(0),
+				"cidrBlocks": pulumi.StringArray{pulumi.String("0.0.0.0/0")},
+			},
+		},
+		"tags": tags("sg"),
+	}, securityGroup, opts...); err != nil {
+		return fmt.Errorf("failed to create security group: %w", err)
+	}
+
+	// Create Key Pair
+	keyPair := &awsKeyPair{}
+	if err := ctx.RegisterResource("aws:ec2/keyPair:KeyPair", fmt.Sprintf("%s-key", name), pulumi.Map{
+		"keyName":   pulumi.Sprintf("bastion-key-%s", name),
+		"publicKey": sshKeyOutput,
+		"tags":      tags("key"),
+	}, keyPair, opts...); err != nil {
+		return fmt.Errorf("failed to create key pair: %w", err)
+	}
+
+	// Use the configured AMI or the latest Ubuntu 22.04 from Canonical
+	ami := bastionConfig.Image
+	if !strings.HasPrefix(ami, "ami-") {
+		var result struct {
+			ID string `pulumi:"id"`
+		}
+		err := ctx.Invoke("aws:ec2/getAmi:getAmi", map[string]interface{}{
+			"mostRecent": true,
+			"owners":     []string{ubuntuAMIOwner},
+			"filters": []map[string]interface{}{
+				{"name": "name", "values": []string{"ubuntu/images/hvm-ssd/ubuntu-jammy-22.04-amd64-server-*"}},
+				{"name": "virtualization-type", "values": []string{"hvm"}},
+			},
+		}, &result, pulumi.Provider(provider))
+		if err != nil {
+			return fmt.Errorf("failed to look up Ubuntu AMI: %w", err)
+		}
+		ami = result.ID
+	}
+
+	// Create EC2 instance
+	vmName := bastionConfig.Name
+	if vmName == "" {
+		vmName = "bastion-aws"
+	}
+
+	instance := &awsInstance{}
+	if err := ctx.RegisterResource("aws:ec2/instance:Instance", vmName, pulumi.Map{
+		"ami":                 pulumi.String(ami),
+		"instanceType":        pulumi.String(instanceType),
+		"subnetId":            subnet.ID(),
+		"vpcSecurityGroupIds": pulumi.StringArray{securityGroup.ID().ToStringOutput()},
+		"keyName":             keyPair.KeyName,
+		"tags": pulumi.StringMap{
+			"Name":      pulumi.String(vmName),
+			"Role":      pulumi.String("bastion"),
+			"ManagedBy": pulumi.String("sloth-kubernetes"),
+		},
+	}, instance, opts...); err != nil {
+		return fmt.Errorf("failed to create EC2 instance: %w", err)
+	}
+
+	// Create Elastic IP
+	eip := &awsElasticIP{}
+	if err := ctx.RegisterResource("aws:ec2/eip:Eip", fmt.Sprintf("%s-eip", name), pulumi.Map{
+		"domain":   pulumi.String("vpc"),
+		"instance": instance.ID(),
+		"tags":     tags("eip"),
+	}, eip, append(opts, pulumi.DependsOn([]pulumi.Resource{igw}))...); err != nil {
+		return fmt.Errorf("failed to create Elastic IP: %w", err)
+	}
+
+	// Set component outputs
+	component.PublicIP = eip.PublicIP
+	component.PrivateIP = instance.PrivateIP
+
+	ctx.Log.Info(fmt.Sprintf("✅ AWS bastion instance '%s' created in %s", vmName, zone), nil)
+
+	return nil
+}
+
+// createGCPBastion creates a Compute Engine bastion with a static external IP
+// and a firewall rule on the default network
+func createGCPBastion(
+	ctx *pulumi.Context,
+	name string,
+	bastionConfig *config.BastionConfig,
+	sshKeyOutput pulumi.StringOutput,
+	component *BastionComponent,
+) error {
+	region := bastionConfig.Region
+	if region == "" {
+		region = "us-central1"
+	}
+	zone := bastionZone("gcp", region, bastionConfig.Zone)
+	if err := config.ValidateZoneForRegion("gcp", region, zone); err != nil {
+		return fmt.Errorf("invalid bastion zone: %w", err)
+	}
+
+	machineType := bastionConfig.Size
+	if machineType == "" {
+		machineType = "e2-micro" // Free tier eligible
+	}
+
+	// Use the configured image (project/family) or Ubuntu 22.04
+	image := bastionConfig.Image
+	if !strings.Contains(image, "/") {
+		image = "ubuntu-os-cloud/ubuntu-2204-lts"
+	}
+
+	labels := pulumi.StringMap{
+		"role":       pulumi.String("bastion"),
+		"managed-by": pulumi.String("sloth-kubernetes"),
+	}
+
+	// Create Firewall rule
+	if err := ctx.RegisterResource("gcp:compute/firewall:Firewall", fmt.Sprintf("%s-fw", name), pulumi.Map{
+		"name":    pulumi.Sprintf("%s-fw", name),
+		"network": pulumi.String("default"),
+		"allows": pulumi.MapArray{
+			pulumi.Map{
+				"protocol": pulumi.String("tcp"),
+				"ports":    pulumi.StringArray{pulumi.Sprintf("%d", bastionConfig.SSHPort)},
+			},
+			pulumi.Map{
+				"protocol": pulumi.String("udp"),
+				"ports":    pulumi.StringArray{pulumi.String("51820")},
+			},
+		},
+		"sourceRanges": pulumi.StringArray{pulumi.String("0.0.0.0/0")},
+		"targetTags":   pulumi.StringArray{pulumi.String("bastion")},
+	}, &cloudResource{}, pulumi.Parent(component)); err != nil {
+		return fmt.Errorf("failed to create firewall rule: %w", err)
+	}
+
+	// Create static external IP
+	address := &gcpAddress{}
+	if err := ctx.RegisterResource("gcp:compute/address:Address", fmt.Sprintf("%s-ip", name), pulumi.Map{
+		"name":   pulumi.Sprintf("%s-ip", name),
+		"region": pulumi.String(region),
+		"labels": labels,
+	}, address, pulumi.Parent(component)); err != nil {
+		return fmt.Errorf("failed to create static IP: %w", err)
+	}
+
+	// Create Compute Engine instance
+	vmName := bastionConfig.Name
+	if vmName == "" {
+		vmName = "bastion-gcp"
+	}
+
+	instance := &gcpInstance{}
+	if err := ctx.RegisterResource("gcp:compute/instance:Instance", vmName, pulumi.Map{
+		"name":        pulumi.String(vmName),
+		"machineType": pulumi.String(machineType),
+		"zone":        pulumi.String(zone),
+		"bootDisk": pulumi.Map{
+			"initializeParams": pulumi.Map{
+				"image": pulumi.String(image),
+			},
+		},
+		"networkInterfaces": pulumi.MapArray{
+			pulumi.Map{
+				"network": pulumi.String("default"),
+				"accessConfigs": pulumi.MapArray{
+					pulumi.Map{
+						"natIp": address.Address,
+					},
+				},
+			},
+		},
+		"metadata": pulumi.StringMap{
+			"ssh-keys": pulumi.Sprintf("ubuntu:%s", sshKeyOutput),
+		},
+		"tags":   pulumi.StringArray{pulumi.String("bastion")},
+		"labels": labels,
+	}, instance, pulumi.Parent(component)); err != nil {
+		return fmt.Errorf("failed to create Compute Engine instance: %w", err)
+	}
+
+	// Set component outputs
+	component.PublicIP = address.Address
+	component.PrivateIP = instance.NetworkInterfaces.ApplyT(func(nics []map[string]interface{}) string {
+		if len(nics) == 0 {
+			return ""
+		}
+		ip, _ := nics[0]["networkIp"].(string)
+		return ip
+	}).(pulumi.StringOutput)
+
+	ctx.Log.Info(fmt.Sprintf("✅ GCP bastion instance '%s' created in %s", vmName, zone), nil)
+
+	return nil
+}
+
 // BastionProvisioningComponent handles bastion host provisioning and hardening
 type BastionProvisioningComponent struct {
 	pulumi.ResourceState
@@ -448,10 +833,15 @@ func NewBastionProvisioningComponent(
 	// Determine SSH user based on provider
 	sshUser := "root"
 	sudoPrefix := ""
-	if bastionConfig.Provider == "azure" {
+	switch bastionConfig.Provider {
+	case "azure":
 		sshUser = "azureuser"
 		sudoPrefix = "sudo "
 		ctx.Log.Info("🔧 Using Azure-specific configuration (user: azureuser, sudo required)", nil)
+	case "aws", "gcp":
+		sshUser = "ubuntu"
+		sudoPrefix = "sudo "
+		ctx.Log.Info(fmt.Sprintf("🔧 Using %s-specific configuration (user: ubuntu, sudo required)", strings.ToUpper(bastionConfig.Provider)), nil)
 	}
 
 	// Build provisioning script with security hardening
@@ -468,6 +858,12 @@ func NewBastionProvisioningComponent(
 	if bastionConfig.EnableMFA {
 		ctx.Log.Info("  • MFA (Google Authenticator)", nil)
 	}
+	if bastionConfig.EnableAuditLog {
+		ctx.Log.Info("  • SSH session recording", nil)
+		if url := bastionConfig.AuditLogS3URL(); url != "" {
+			ctx.Log.Info(fmt.Sprintf("  • Audit log shipping to %s", url), nil)
+		}
+	}
 
 	// Execute the provisioning script on the bastion host
 	ctx.Log.Info("⏳ Starting bastion provisioning (this may take 5-10 minutes)...", nil)
@@ -494,60 +890,47 @@ func NewBastionProvisioningComponent(
 		return nil, fmt.Errorf("failed to execute provisioning script: %w", err)
 	}
 
-	ctx.Log.Info("✅ Bastion provisioning command completed successfully", nil)
-	ctx.Log.Info("", nil)
-	ctx.Log.Info("🔍 Validating bastion SSH connectivity...", nil)
-
-	// SSH Validation Command - Test that SSH is working properly
-	validateSSHCmd, err := remote.NewCommand(ctx, fmt.Sprintf("%s-validate-ssh", name), &remote.CommandArgs{
-		Connection: remote.ConnectionArgs{
-			Host:           bastionIP,
-			User:           pulumi.String(sshUser),
-			PrivateKey:     sshPrivateKey,
-			DialErrorLimit: pulumi.Int(10),
-		},
-		Create: pulumi.String(fmt.Sprintf(`#!/bin/bash
-echo "=========================================="
-echo "🔍 BASTION SSH VALIDATION TEST"
-echo "=========================================="
-echo "✅ SSH connection successful!"
-echo "📋 Bastion details:"
-echo "  • Hostname: $(hostname)"
-echo "  • Uptime: $(uptime -p)"
-echo "  • SSH service: $(%ssystemctl is-active sshd)"
-echo "  • UFW status: $(%sufw status | head -1)"
-echo "  • fail2ban status: $(%ssystemctl is-active fail2ban)"
-echo ""
-echo "✅ Bastion is fully operational and ready!"
-echo "=========================================="
-`, sudoPrefix, sudoPrefix, sudoPrefix)),
-	}, pulumi.Parent(component), pulumi.DependsOn([]pulumi.Resource{provisionCmd}), pulumi.Timeouts(&pulumi.CustomTimeouts{
-		Create: "2m",
-	}))
-	if err != nil {
-		return nil, fmt.Errorf("failed to validate bastion SSH: %w", err)
-	}
-
-	// Log validation output
-	validateSSHCmd.Stdout.ApplyT(func(stdout string) string {
-		if stdout != "" {
-			ctx.Log.Info("", nil)
-			ctx.Log.Info("✅ BASTION VALIDATION SUCCESSFUL", nil)
-			ctx.Log.Info("   SSH connectivity confirmed", nil)
-			ctx.Log.Info("   Security services are active", nil)
-			ctx.Log.Info("", nil)
-			ctx.Log.Info("🎉 BASTION IS 100% READY FOR CLUSTER DEPLOYMENT", nil)
-			ctx.Log.Info("", nil)
+	// The Salt API user gets its password apart from the provisioning script,
+	// so the password is kept secret in the state
+	if bastionConfig.SaltEnabled() && bastionConfig.SaltAPIPassword != "" {
+		_, err := remote.NewCommand(ctx, fmt.Sprintf("%s-salt-api-password", name), &remote.CommandArgs{
+			Connection: remote.ConnectionArgs{
+				Host:           bastionIP,
+				User:           pulumi.String(sshUser),
+				PrivateKey:     sshPrivateKey,
+				DialErrorLimit: pulumi.Int(30),
+			},
+			Create: pulumi.ToSecret(pulumi.String(saltAPIPasswordScript(bastionConfig.SaltUser(), bastionConfig.SaltAPIPassword, sudoPrefix))).(pulumi.StringOutput),
+		}, pulumi.Parent(component), pulumi.DependsOn([]pulumi.Resource{provisionCmd}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to set Salt API password: %w", err)
 		}
-		return stdout
-	})
+	}
 
-	// Set status based on validation success
-	component.Status = validateSSHCmd.Stdout.ApplyT(func(stdout string) string {
-		if stdout != "" {
-			return "validated"
+	// Like the Salt API password, the audit log bucket's credentials are kept
+	// out of the provisioning script so they stay secret in the state
+	if bastionConfig.EnableAuditLog && bastionConfig.AuditLogS3URL() != "" && bastionConfig.AuditLogS3AccessKey != "" {
+		_, err := remote.NewCommand(ctx, fmt.Sprintf("%s-audit-log-credentials", name), &remote.CommandArgs{
+			Connection: remote.ConnectionArgs{
+				Host:           bastionIP,
+				User:           pulumi.String(sshUser),
+				PrivateKey:     sshPrivateKey,
+				DialErrorLimit: pulumi.Int(30),
+			},
+			Create: pulumi.ToSecret(pulumi.String(bastionAuditLogS3CredentialsScript(bastionConfig.AuditLogS3AccessKey, bastionConfig.AuditLogS3SecretKey, sudoPrefix))).(pulumi.StringOutput),
+		}, pulumi.Parent(component), pulumi.DependsOn([]pulumi.Resource{provisionCmd}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to set audit log bucket credentials: %w", err)
 		}
-		return "validation-failed"
+	}
+
+	ctx.Log.Info("✅ Bastion provisioning command completed successfully", nil)
+	ctx.Log.Info("   SSH connectivity is validated once the deployment finishes", nil)
+
+	// Provisioned once the provisioning script has run; connectivity is
+	// checked over the operator's SSH settings after the deployment
+	component.Status = provisionCmd.Stdout.ApplyT(func(string) string {
+		return "provisioned"
 	}).(pulumi.StringOutput)
 
 	if err := ctx.RegisterResourceOutputs(component, pulumi.Map{
@@ -559,8 +942,190 @@ echo "=========================================="
 	return component, nil
 }
 
+// saltAPIPasswordScript sets the password of the Salt API user
+func saltAPIPasswordScript(user, password, sudoPrefix string) string {
+	return fmt.Sprintf("#!/bin/bash\nset -e\necho '%s:%s' | %schpasswd\n", user, password, sudoPrefix)
+}
+
+// bastionSessionRecordingDir holds the session recordings and the log of
+// commands run over SSH
+const bastionSessionRecordingDir = "/var/log/bastion-sessions"
+
+// bastionSessionRecordingScript records every interactive SSH session with
+// script(1), through a ForceCommand wrapper. Commands run over SSH (scp,
+// Salt, provisioning) are logged and run unchanged, as a pty would corrupt
+// their input and output; ProxyJump connections open no session and are not
+// affected. Recordings are replayed with scriptreplay --timing=<file>.timing
+// <file>.log. Heredocs are double quoted, as the script may run in sudo bash
+// -c '...'.
+const bastionSessionRecordingScript = `
+echo "[$(date +%H:%M:%S)] Setting up SSH session recording..."
+mkdir -p ` + bastionSessionRecordingDir + `
+chmod 1733 ` + bastionSessionRecordingDir + `
+touch ` + bastionSessionRecordingDir + `/commands.log
+chmod 0622 ` + bastionSessionRecordingDir + `/commands.log
+
+cat > /usr/local/sbin/bastion-session <<"EOF"
+#!/bin/bash
+dir=` + bastionSessionRecordingDir + `
+user=$(id -un)
+ts=$(date -u +%Y%m%dT%H%M%SZ)
+client=${SSH_CLIENT%% *}
+if [ -n "$SSH_ORIGINAL_COMMAND" ]; then
+    echo "$ts $user $client command $SSH_ORIGINAL_COMMAND" >> "$dir/commands.log"
+    exec "${SHELL:-/bin/bash}" -c "$SSH_ORIGINAL_COMMAND"
+fi
+name="$user-$ts-$$"
+echo "$ts $user $client session $name" >> "$dir/commands.log"
+echo "This session is recorded."
+exec script -q -f --timing="$dir/$name.timing" "$dir/$name.log"
+EOF
+chmod 0755 /usr/local/sbin/bastion-session
+
+cat >> /etc/ssh/sshd_config <<"EOF"
+
+# Record SSH sessions
+ForceCommand /usr/local/sbin/bastion-session
+EOF
+
+if ! sshd -t; then
+    echo "[$(date +%H:%M:%S)] ❌ sshd rejected the session recording configuration, removing it"
+    sed -i "/bastion-session/d" /etc/ssh/sshd_config
+    exit 1
+fi
+systemctl reload sshd || true
+echo "[$(date +%H:%M:%S)] ✅ SSH sessions are recorded in ` + bastionSessionRecordingDir + `"
+`
+
+// bastionAuditLogRetentionScript removes local session recordings and
+// rotated audit logs older than days, or returns "" to keep them
+func bastionAuditLogRetentionScript(days int) string {
+	if days <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(`
+echo "[$(date +%%H:%%M:%%S)] Installing audit log retention policy (%[1]d days)..."
+cat > /etc/logrotate.d/bastion-sessions <<"EOF"
+%[2]s/commands.log {
+    daily
+    rotate %[1]d
+    maxage %[1]d
+    compress
+    missingok
+    notifempty
+    create 0622 root root
+}
+EOF
+
+cat > /etc/cron.daily/bastion-audit-cleanup <<"EOF"
+#!/bin/sh
+find %[2]s -type f \( -name "*.log" -o -name "*.timing" \) ! -name commands.log -mtime +%[1]d -delete
+find /var/log/audit -type f -name "audit.log.*" -mtime +%[1]d -delete
+EOF
+chmod 0755 /etc/cron.daily/bastion-audit-cleanup
+echo "[$(date +%%H:%%M:%%S)] ✅ Audit logs older than %[1]d days are removed daily"
+`, days, bastionSessionRecordingDir)
+}
+
+// bastionAuditLogS3Config is the s3cmd configuration of the audit log upload
+const bastionAuditLogS3Config = "/etc/bastion-audit/s3cfg"
+
+// bastionAuditLogShippingScript syncs session recordings and audit logs to
+// the configured bucket every 15 minutes, under a prefix named after the
+// bastion's hostname, or returns "" when no bucket is configured. Credentials,
+// when set, are added apart from this script (see
+// bastionAuditLogS3CredentialsScript).
+func bastionAuditLogShippingScript(cfg *config.BastionConfig) string {
+	url := cfg.AuditLogS3URL()
+	if url == "" {
+		return ""
+	}
+
+	host := "s3.amazonaws.com"
+	if cfg.AuditLogS3Endpoint != "" {
+		host = strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(cfg.AuditLogS3Endpoint, "https://"), "http://"), "/")
+	}
+
+	return fmt.Sprintf(`
+echo "[$(date +%%H:%%M:%%S)] Configuring audit log shipping to %[1]s..."
+apt_get_with_retry apt-get install -y s3cmd
+mkdir -p /etc/bastion-audit
+cat > %[2]s <<"EOF"
+[default]
+host_base = %[3]s
+host_bucket = %%(bucket)s.%[3]s
+bucket_location = %[4]s
+use_https = True
+EOF
+chmod 0600 %[2]s
+
+cat > /etc/cron.d/bastion-audit-ship <<"EOF"
+*/15 * * * * root s3cmd -c %[2]s -q sync %[5]s/ %[1]s$(hostname)/sessions/ && s3cmd -c %[2]s -q sync /var/log/audit/ %[1]s$(hostname)/audit/
+EOF
+chmod 0644 /etc/cron.d/bastion-audit-ship
+
+# Outgoing traffic is allowed by default; keep the upload open if that changes
+ufw allow out 443/tcp comment "Audit log shipping"
+echo "[$(date +%%H:%%M:%%S)] ✅ Audit logs are shipped to %[1]s every 15 minutes"
+`, url, bastionAuditLogS3Config, host, cfg.AuditLogRegion(), bastionSessionRecordingDir)
+}
+
+// bastionAuditLogS3CredentialsScript adds the access key of the audit log
+// bucket to the s3cmd configuration
+func bastionAuditLogS3CredentialsScript(accessKey, secretKey, sudoPrefix string) string {
+	return fmt.Sprintf("#!/bin/bash\nset -e\nprintf 'access_key = %%s\\nsecret_key = %%s\\n' '%s' '%s' | %stee -a %s > /dev/null\n",
+		accessKey, secretKey, sudoPrefix, bastionAuditLogS3Config)
+}
+
+// bastionMFAScript requires a Google Authenticator code on top of the SSH key.
+// The PAM module is nullok, so users that haven't enrolled yet, including the
+// provisioning connection, still log in with their key alone; each user
+// enrolls interactively on first login. The config is checked with sshd -t
+// before the reload, so a bad config can't lock anyone out.
+const bastionMFAScript = `
+echo ""
+echo "[$(date +%H:%M:%S)] =========================================="
+echo "[$(date +%H:%M:%S)] STEP 6b: Configuring MFA (Google Authenticator)"
+echo "[$(date +%H:%M:%S)] =========================================="
+apt_get_with_retry apt-get install -y libpam-google-authenticator
+
+# Ask for the code instead of the password: key + code, never the password
+cp /etc/pam.d/sshd /etc/pam.d/sshd.backup
+sed -i "s/^@include common-auth/#@include common-auth/" /etc/pam.d/sshd
+grep -q "pam_google_authenticator.so" /etc/pam.d/sshd || echo "auth required pam_google_authenticator.so nullok" >> /etc/pam.d/sshd
+
+# sshd uses the first value of a keyword, so override the distro defaults in place
+sed -i "s/^KbdInteractiveAuthentication no/KbdInteractiveAuthentication yes/" /etc/ssh/sshd_config
+sed -i "s/^ChallengeResponseAuthentication no/ChallengeResponseAuthentication yes/" /etc/ssh/sshd_config
+echo "AuthenticationMethods publickey,keyboard-interactive" >> /etc/ssh/sshd_config
+
+if ! sshd -t; then
+    echo "[$(date +%H:%M:%S)] ❌ sshd rejected the MFA configuration, restoring the previous one"
+    cp /etc/ssh/sshd_config.backup /etc/ssh/sshd_config
+    cp /etc/pam.d/sshd.backup /etc/pam.d/sshd
+    exit 1
+fi
+
+# Remind users that haven't enrolled yet at every login
+cat > /etc/profile.d/bastion-mfa.sh <<"EOF"
+if [ ! -f "$HOME/.google_authenticator" ]; then
+    echo "MFA is enabled on this bastion but you have not enrolled yet."
+    echo "Run: google-authenticator -t -d -f -r 3 -R 30 -w 3"
+    echo "and scan the QR code with your authenticator app."
+fi
+EOF
+
+echo "[$(date +%H:%M:%S)] ✅ MFA configured - each user enrolls interactively on first login"
+echo "[$(date +%H:%M:%S)] ℹ️  Once root enrolls, non-interactive SSH through the bastion needs a code too"
+`
+
 // buildBastionProvisionScript creates the provisioning script for bastion security hardening
 func buildBastionProvisionScript(cfg *config.BastionConfig, sudoPrefix string) string {
+	saltBootstrap := cfg.SaltBootstrap
+	if saltBootstrap.URL == "" {
+		saltBootstrap = (&config.SecurityConfig{}).SaltBootstrapDownload()
+	}
+
 	// If sudoPrefix is needed, wrap the entire script with sudo bash -c
 	scriptHeader := `#!/bin/bash
 set -e
@@ -698,6 +1263,9 @@ ufw default allow outgoing
 			// Special handling for 0.0.0.0/0 - UFW doesn't handle "from 0.0.0.0/0" correctly
 			if cidr == "0.0.0.0/0" {
 				script += fmt.Sprintf("ufw allow %d/tcp comment 'SSH from anywhere'\n", cfg.SSHPort)
+			} else if cidr == cfg.OperatorCIDR {
+				// Tagged so 'bastion allow-ip' can replace it when the operator's IP changes
+				script += fmt.Sprintf("ufw allow from %s to any port %d proto tcp comment '%s'\n", cidr, cfg.SSHPort, config.BastionOperatorRuleComment)
 			} else {
 				script += fmt.Sprintf("ufw allow from %s to any port %d proto tcp comment 'SSH from %s'\n", cidr, cfg.SSHPort, cidr)
 			}
@@ -748,8 +1316,13 @@ cat >> /etc/ssh/sshd_config <<'EOF'
 PermitRootLogin prohibit-password
 PasswordAuthentication no
 PubkeyAuthentication yes
-ChallengeResponseAuthentication no
-UsePAM yes
+`
+	if cfg.EnableMFA {
+		script += "ChallengeResponseAuthentication yes\n"
+	} else {
+		script += "ChallengeResponseAuthentication no\n"
+	}
+	script += `UsePAM yes
 X11Forwarding no
 PrintMotd no
 AcceptEnv LANG LC_*
@@ -767,7 +1340,13 @@ AcceptEnv LANG LC_*
 
 # Enable SSH agent forwarding for ProxyJump
 sed -i 's/#AllowAgentForwarding yes/AllowAgentForwarding yes/' /etc/ssh/sshd_config
+`
+
+	if cfg.EnableMFA {
+		script += bastionMFAScript
+	}
 
+	script += `
 echo "[$(date +%H:%M:%S)] 🔄 Reloading SSH configuration (not restarting to avoid breaking Pulumi connection)..."
 # Use 'reload' instead of 'restart' to apply config changes without dropping connections
 systemctl reload sshd || {
@@ -801,17 +1380,18 @@ augenrules --load || true
 systemctl enable auditd
 systemctl restart auditd
 echo "[$(date +%H:%M:%S)] ✅ Audit logging configured"
-`
+` + bastionSessionRecordingScript + bastionAuditLogRetentionScript(cfg.AuditLogRetentionDays) + bastionAuditLogShippingScript(cfg)
 	}
 
-	script += `
+	if cfg.SaltEnabled() {
+		script += `
 # Install Salt Master with Salt API
 echo ""
 echo "[$(date +%H:%M:%S)] =========================================="
 echo "[$(date +%H:%M:%S)] STEP 8: Installing Salt Master with API"
 echo "[$(date +%H:%M:%S)] =========================================="
 echo "[$(date +%H:%M:%S)] Downloading Salt Bootstrap script..."
-curl -o /tmp/bootstrap-salt.sh -L https://github.com/saltstack/salt-bootstrap/releases/latest/download/bootstrap-salt.sh
+` + saltBootstrap.Fetch("/tmp/bootstrap-salt.sh") + `
 chmod +x /tmp/bootstrap-salt.sh
 
 echo "[$(date +%H:%M:%S)] Installing Salt Master and Salt API..."
@@ -840,7 +1420,7 @@ cat >> /etc/salt/master <<'SALTEOF'
 # External authentication for Salt API
 external_auth:
   pam:
-    saltapi:
+    ` + cfg.SaltUser() + `:
       - .*
       - '@wheel'
       - '@runner'
@@ -852,8 +1432,7 @@ SALTEOF
 
 # Create Salt API user
 echo "[$(date +%H:%M:%S)] Creating Salt API user..."
-useradd -M -s /bin/bash saltapi || true
-echo 'saltapi:saltapi123' | chpasswd
+useradd -M -s /bin/bash ` + cfg.SaltUser() + ` || true
 
 # CRITICAL: Add salt user to shadow group (required for PAM authentication)
 echo "[$(date +%H:%M:%S)] Adding salt user to shadow group for PAM authentication..."
@@ -865,10 +1444,6 @@ ufw allow 8000/tcp comment 'Salt API'
 ufw allow 4505/tcp comment 'Salt Publisher'
 ufw allow 4506/tcp comment 'Salt Request Server'
 
-# Allow WireGuard VPN port
-echo "[$(date +%H:%M:%S)] Configuring firewall for WireGuard VPN..."
-ufw allow 51820/udp comment 'WireGuard VPN'
-
 # Restart Salt Master and start Salt API
 echo "[$(date +%H:%M:%S)] Starting Salt Master and API services..."
 systemctl restart salt-master
@@ -888,6 +1463,14 @@ SALTEOF
 systemctl restart salt-master
 
 echo "[$(date +%H:%M:%S)] ✅ Salt Master configured to auto-accept minion keys"
+`
+	}
+
+	script += `
+# Allow WireGuard VPN port
+echo ""
+echo "[$(date +%H:%M:%S)] Configuring firewall for WireGuard VPN..."
+ufw allow 51820/udp comment 'WireGuard VPN'
 
 # Install WireGuard
 echo ""
@@ -914,7 +1497,7 @@ cat > /etc/motd <<'EOF'
 ╚═══════════════════════════════════════════════════════════╝
 
 Cluster Access:
-  • SSH to cluster nodes: ssh root@10.8.0.<node-vpn-ip>
+  • SSH to cluster nodes: ssh root@<node-vpn-ip>
   • ProxyJump is configured automatically
   • All sessions are audited
 
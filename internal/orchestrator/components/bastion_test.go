@@ -0,0 +1,243 @@
+package components
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// TestBastionProvisionScript_MFA tests that MFA is configured only when enabled,
+// and validated before sshd is reloaded
+func TestBastionProvisionScript_MFA(t *testing.T) {
+	script := buildBastionProvisionScript(&config.BastionConfig{SSHPort: 22, EnableMFA: true}, "")
+
+	expected := []string{
+		"libpam-google-authenticator",
+		"auth required pam_google_authenticator.so nullok",
+		"ChallengeResponseAuthentication yes",
+		"AuthenticationMethods publickey,keyboard-interactive",
+		"google-authenticator -t -d -f -r 3 -R 30 -w 3",
+	}
+	for _, want := range expected {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q", want)
+		}
+	}
+	if strings.Contains(script, "ChallengeResponseAuthentication no\nUsePAM") {
+		t.Error("Expected challenge-response authentication to be enabled with MFA")
+	}
+
+	check := strings.Index(script, "sshd -t")
+	reload := strings.Index(script, "systemctl reload sshd")
+	if check < 0 || reload < 0 || check > reload {
+		t.Errorf("Expected sshd -t (at %d) before systemctl reload sshd (at %d)", check, reload)
+	}
+
+	script = buildBastionProvisionScript(&config.BastionConfig{SSHPort: 22}, "")
+	if strings.Contains(script, "pam_google_authenticator") {
+		t.Error("Expected no MFA configuration when MFA is disabled")
+	}
+	if !strings.Contains(script, "ChallengeResponseAuthentication no\nUsePAM") {
+		t.Error("Expected challenge-response authentication to stay disabled without MFA")
+	}
+}
+
+// bastionCloudMocks records the resource types created and fills in the
+// outputs the AWS and GCP bastions read
+type bastionCloudMocks struct {
+	pulumi.MockResourceMonitor
+
+	mu    sync.Mutex
+	types []string
+}
+
+func (m *bastionCloudMocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	m.mu.Lock()
+	m.types = append(m.types, args.TypeToken)
+	m.mu.Unlock()
+
+	outputs := args.Inputs.Copy()
+	switch args.TypeToken {
+	case "aws:ec2/eip:Eip":
+		outputs["publicIp"] = resource.NewStringProperty("203.0.113.10")
+	case "aws:ec2/instance:Instance":
+		outputs["privateIp"] = resource.NewStringProperty("10.100.1.10")
+	case "gcp:compute/address:Address":
+		outputs["address"] = resource.NewStringProperty("203.0.113.20")
+	case "gcp:compute/instance:Instance":
+		outputs["networkInterfaces"] = resource.NewArrayProperty([]resource.PropertyValue{
+			resource.NewObjectProperty(resource.PropertyMap{
+				"networkIp": resource.NewStringProperty("10.128.0.20"),
+			}),
+		})
+	}
+	return args.Name + "_id", outputs, nil
+}
+
+func (m *bastionCloudMocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	if args.Token == "aws:ec2/getAmi:getAmi" {
+		return resource.PropertyMap{"id": resource.NewStringProperty("ami-0123456789")}, nil
+	}
+	return resource.PropertyMap{}, nil
+}
+
+// TestCreateCloudBastions tests that the AWS and GCP bastions create their
+// network resources and set the component IPs
+func TestCreateCloudBastions(t *testing.T) {
+	tests := []struct {
+		provider  string
+		region    string
+		create    func(*pulumi.Context, string, *config.BastionConfig, pulumi.StringOutput, *BastionComponent) error
+		types     []string
+		publicIP  string
+		privateIP string
+	}{
+		{
+			provider: "aws",
+			region:   "eu-west-1",
+			create:   createAWSBastion,
+			types: []string{
+				"aws:ec2/vpc:Vpc",
+				"aws:ec2/subnet:Subnet",
+				"aws:ec2/securityGroup:SecurityGroup",
+				"aws:ec2/instance:Instance",
+				"aws:ec2/eip:Eip",
+			},
+			publicIP:  "203.0.113.10",
+			privateIP: "10.100.1.10",
+		},
+		{
+			provider: "gcp",
+			region:   "europe-west1",
+			create:   createGCPBastion,
+			types: []string{
+				"gcp:compute/firewall:Firewall",
+				"gcp:compute/address:Address",
+				"gcp:compute/instance:Instance",
+			},
+			publicIP:  "203.0.113.20",
+			privateIP: "10.128.0.20",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			mocks := &bastionCloudMocks{}
+			var publicIP, privateIP string
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				component := &BastionComponent{}
+				if err := ctx.RegisterComponentResource("kubernetes-create:security:Bastion", "bastion", component); err != nil {
+					return err
+				}
+				cfg := &config.BastionConfig{Provider: tt.provider, Region: tt.region, SSHPort: 22}
+				if err := tt.create(ctx, "bastion", cfg, pulumi.String("ssh-ed25519 AAAA").ToStringOutput(), component); err != nil {
+					return err
+				}
+				pulumi.All(component.PublicIP, component.PrivateIP).ApplyT(func(ips []interface{}) error {
+					publicIP, privateIP = ips[0].(string), ips[1].(string)
+					return nil
+				})
+				return nil
+			}, pulumi.WithMocks("project", "stack", mocks))
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			created := strings.Join(mocks.types, ",")
+			for _, want := range tt.types {
+				if !strings.Contains(created, want) {
+					t.Errorf("Expected a %s resource, got %s", want, created)
+				}
+			}
+			if publicIP != tt.publicIP || privateIP != tt.privateIP {
+				t.Errorf("Expected IPs %s/%s, got %s/%s", tt.publicIP, tt.privateIP, publicIP, privateIP)
+			}
+		})
+	}
+}
+
+// TestCreateCloudBastions_InvalidZone tests that a zone outside the region is rejected
+func TestCreateCloudBastions_InvalidZone(t *testing.T) {
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		cfg := &config.BastionConfig{Provider: "gcp", Region: "us-central1", Zone: "europe-west1-b", SSHPort: 22}
+		return createGCPBastion(ctx, "bastion", cfg, pulumi.String("key").ToStringOutput(), &BastionComponent{})
+	}, pulumi.WithMocks("project", "stack", &bastionCloudMocks{}))
+	if err == nil || !strings.Contains(err.Error(), "invalid bastion zone") {
+		t.Errorf("Expected invalid bastion zone error, got %v", err)
+	}
+}
+
+// TestBastionProvisionScript_Salt tests that the Salt API user is configurable
+// and that Salt and its ports are left out when disabled
+func TestBastionProvisionScript_Salt(t *testing.T) {
+	script := buildBastionProvisionScript(&config.BastionConfig{SSHPort: 22, SaltAPIUser: "salt-ops"}, "")
+	for _, want := range []string{"STEP 8", "    salt-ops:", "useradd -M -s /bin/bash salt-ops", "ufw allow 8000/tcp", "ufw allow 51820/udp"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q", want)
+		}
+	}
+	if strings.Contains(script, "saltapi") {
+		t.Error("Expected no default Salt API user in the script")
+	}
+
+	installSalt := false
+	script = buildBastionProvisionScript(&config.BastionConfig{SSHPort: 22, InstallSalt: &installSalt}, "")
+	for _, unwanted := range []string{"STEP 8", "bootstrap-salt", "8000/tcp", "4505/tcp", "4506/tcp"} {
+		if strings.Contains(script, unwanted) {
+			t.Errorf("Expected script without Salt not to contain %q", unwanted)
+		}
+	}
+	if !strings.Contains(script, "ufw allow 51820/udp") {
+		t.Error("Expected WireGuard to stay allowed without Salt")
+	}
+}
+
+// TestBastionProvisionScript_AuditLog tests that audit logging records SSH
+// sessions, and ships and expires them only when configured
+func TestBastionProvisionScript_AuditLog(t *testing.T) {
+	script := buildBastionProvisionScript(&config.BastionConfig{SSHPort: 22, EnableAuditLog: true}, "")
+	for _, want := range []string{"ForceCommand /usr/local/sbin/bastion-session", `exec script -q -f --timing=`, "sshd -t"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q", want)
+		}
+	}
+	for _, unwanted := range []string{"s3cmd", "bastion-audit-cleanup"} {
+		if strings.Contains(script, unwanted) {
+			t.Errorf("Expected no %q without a bucket or retention", unwanted)
+		}
+	}
+
+	script = buildBastionProvisionScript(&config.BastionConfig{
+		SSHPort:               22,
+		EnableAuditLog:        true,
+		AuditLogS3Bucket:      "audit-logs/prod",
+		AuditLogS3Endpoint:    "https://nyc3.digitaloceanspaces.com",
+		AuditLogRetentionDays: 14,
+	}, "")
+	for _, want := range []string{
+		"host_base = nyc3.digitaloceanspaces.com",
+		"sync /var/log/bastion-sessions/ s3://audit-logs/prod/$(hostname)/sessions/",
+		`ufw allow out 443/tcp comment "Audit log shipping"`,
+		"-mtime +14 -delete",
+		"rotate 14",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q", want)
+		}
+	}
+
+	if script := buildBastionProvisionScript(&config.BastionConfig{SSHPort: 22}, ""); strings.Contains(script, "ForceCommand") {
+		t.Error("Expected no session recording without audit logging")
+	}
+}
+
+func TestBastionAuditLogS3CredentialsScript(t *testing.T) {
+	script := bastionAuditLogS3CredentialsScript("AKIA", "secret", "sudo ")
+	if !strings.Contains(script, "'AKIA' 'secret' | sudo tee -a /etc/bastion-audit/s3cfg") {
+		t.Errorf("Unexpected credentials script:\n%s", script)
+	}
+}
@@ -39,8 +39,9 @@ type K3sRealComponent struct {
 	FirstMasterIP pulumi.StringOutput `pulumi:"firstMasterIP"`
 }
 
-// NewK3sRealComponent deploys a REAL K3s cluster
-func NewK3sRealComponent(ctx *pulumi.Context, name string, nodes []*RealNodeComponent, sshPrivateKey pulumi.StringOutput, cfg *config.ClusterConfig, bastionComponent *BastionComponent, opts ...pulumi.ResourceOption) (*K3sRealComponent, error) {
+// NewK3sRealComponent deploys a REAL K3s cluster. With an API load balancer,
+// servers accept its address and the other nodes join through it.
+func NewK3sRealComponent(ctx *pulumi.Context, name string, nodes []*RealNodeComponent, sshPrivateKey pulumi.StringOutput, cfg *config.ClusterConfig, bastionComponent *BastionComponent, apiLoadBalancer *APILoadBalancerComponent, opts ...pulumi.ResourceOption) (*K3sRealComponent, error) {
 	component := &K3sRealComponent{}
 	err := ctx.RegisterComponentResource("kubernetes-create:cluster:K3sReal", name, component, opts...)
 	if err != nil {
@@ -53,17 +54,13 @@ func NewK3sRealComponent(ctx *pulumi.Context, name string, nodes []*RealNodeComp
 	// 2. linode-masters (2 nodes) → masters[1], masters[2]
 	// 3. do-workers (2 nodes) → workers[0], workers[1]
 	// 4. linode-workers (1 node) → workers[2]
-	var masters []*RealNodeComponent
-	var workers []*RealNodeComponent
-
 	ctx.Log.Info("🔍 Separating nodes (first 3 = masters, last 3 = workers)...", nil)
 
-	for i, node := range nodes {
-		if i < 3 {
-			masters = append(masters, node)
-		} else {
-			workers = append(workers, node)
-		}
+	masters := k3sMasters(nodes)
+	workers := nodes[len(masters):]
+
+	if len(masters) == 0 {
+		return nil, fmt.Errorf("no master nodes found for K3s installation")
 	}
 
 	ctx.Log.Info(fmt.Sprintf("🚀 Installing K3s: %d masters, %d workers", len(masters), len(workers)), nil)
@@ -75,6 +72,30 @@ func NewK3sRealComponent(ctx *pulumi.Context, name string, nodes []*RealNodeComp
 	}
 	clusterTokenOutput := pulumi.String(clusterToken).ToStringOutput()
 
+	// Extra flags for every kubelet
+	kubeletArgs := config.K3sKubeletArgs(&cfg.Kubernetes)
+	serverArgs := kubeletArgs + config.K3sServerArgs(&cfg.Kubernetes)
+
+	// Servers get the admission configuration their API server flags name
+	// before K3s first starts
+	admissionSetup, err := config.WriteAdmissionConfigCommand(&cfg.Kubernetes.Admission, config.K3sAdmissionConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	serverInstallCommand := func(env string) string {
+		install := config.K3sInstallCommand(cfg.Kubernetes.RKE2, &cfg.Security, env)
+		if admissionSetup == "" {
+			return install
+		}
+		return admissionSetup + " && " + install
+	}
+
+	// Address of the API load balancer, empty without one
+	apiLoadBalancerIP := pulumi.String("").ToStringOutput()
+	if apiLoadBalancer != nil {
+		apiLoadBalancerIP = apiLoadBalancer.IP
+	}
+
 	// STEP 1: Install K3s on first master node (this becomes the cluster leader)
 	firstMaster := masters[0]
 
@@ -101,9 +122,21 @@ func NewK3sRealComponent(ctx *pulumi.Context, name string, nodes []*RealNodeComp
 
 	firstMasterInstall, err := remote.NewCommand(ctx, fmt.Sprintf("%s-master-0-install", name), &remote.CommandArgs{
 		Connection: firstMasterConnArgs,
-		Create: pulumi.All(firstMaster.WireGuardIP, firstMaster.PublicIP).ApplyT(func(args []interface{}) string {
+		Create: pulumi.All(firstMaster.WireGuardIP, firstMaster.PublicIP, firstMaster.Region, firstMaster.Zone, apiLoadBalancerIP).ApplyT(func(args []interface{}) string {
 			wgIP := args[0].(string)
 			publicIP := args[1].(string)
+			nodeArgs := serverArgs + config.K3sTopologyArgs(args[2].(string), args[3].(string)) + k3sAPILoadBalancerArgs(args[4].(string))
+			installEnv := fmt.Sprintf(`INSTALL_K3S_EXEC="server \
+  --node-ip=%s \
+  --node-external-ip=%s \
+  --advertise-address=%s \
+  --tls-san=%s \
+  --tls-san=%s \
+  --tls-san=127.0.0.1 \
+  --flannel-iface=wg0%s \
+  --write-kubeconfig-mode=644 \
+  --cluster-init \
+  --disable=traefik"`, wgIP, publicIP, wgIP, wgIP, publicIP, nodeArgs)
 
 			return fmt.Sprintf(`#!/bin/bash
 set -e
@@ -126,17 +159,7 @@ echo "✅ WireGuard ready (IP: %s)"
 
 # Install K3s with inline configuration
 echo "📥 Installing K3s server..."
-if ! curl -sfL https://get.k3s.io | INSTALL_K3S_EXEC="server \
-  --node-ip=%s \
-  --node-external-ip=%s \
-  --advertise-address=%s \
-  --tls-san=%s \
-  --tls-san=%s \
-  --tls-san=127.0.0.1 \
-  --flannel-iface=wg0 \
-  --write-kubeconfig-mode=644 \
-  --cluster-init \
-  --disable=traefik" sh -; then
+if ! { %s; }; then
   echo "❌ K3s installation script failed!"
   exit 1
 fi
@@ -182,7 +205,7 @@ done
 # Show status
 kubectl --kubeconfig=/etc/rancher/k3s/k3s.yaml get nodes
 cat /etc/rancher/k3s/k3s.yaml
-`, wgIP, wgIP, wgIP, publicIP, wgIP, wgIP, publicIP, wgIP, wgIP, wgIP)
+`, wgIP, wgIP, serverInstallCommand(installEnv), wgIP, wgIP, wgIP)
 		}).(pulumi.StringOutput),
 	}, pulumi.Parent(component), pulumi.Timeouts(&pulumi.CustomTimeouts{
 		Create: "30m", // Increased from 20m for slower Azure B1s VMs
@@ -310,12 +333,27 @@ exit 1
 
 		masterInstall, err := remote.NewCommand(ctx, fmt.Sprintf("%s-master-%d-install", name, i), &remote.CommandArgs{
 			Connection: masterConnArgs,
-			Create: pulumi.All(k3sToken, firstMaster.WireGuardIP, master.WireGuardIP, master.PublicIP).ApplyT(func(args []interface{}) string {
+			Create: pulumi.All(k3sToken, firstMaster.WireGuardIP, master.WireGuardIP, master.PublicIP, master.Region, master.Zone, apiLoadBalancerIP).ApplyT(func(args []interface{}) string {
 				token := args[0].(string) // K3s join token from first master
 				firstMasterWgIP := args[1].(string)
 				myWgIP := args[2].(string)
 				myPublicIP := args[3].(string)
+				nodeArgs := serverArgs + config.K3sTopologyArgs(args[4].(string), args[5].(string)) + k3sAPILoadBalancerArgs(args[6].(string))
+				joinAddr := k3sJoinAddress(firstMasterWgIP, args[6].(string))
 				masterNum := i + 1
+				installEnv := fmt.Sprintf(`K3S_URL=https://%s:6443 \
+  K3S_TOKEN="%s" \
+  INSTALL_K3S_EXEC="server \
+    --server https://%s:6443 \
+    --node-ip=%s \
+    --node-external-ip=%s \
+    --advertise-address=%s \
+    --tls-san=%s \
+    --tls-san=%s \
+    --tls-san=127.0.0.1 \
+    --flannel-iface=wg0%s \
+    --write-kubeconfig-mode=644 \
+    --disable=traefik"`, joinAddr, token, joinAddr, myWgIP, myPublicIP, myWgIP, myWgIP, myPublicIP, nodeArgs)
 
 				return fmt.Sprintf(`#!/bin/bash
 set -e
@@ -361,19 +399,7 @@ fi
 
 # Install K3s server in cluster mode (join existing cluster)
 echo "📥 Installing K3s server (joining cluster)..."
-if ! curl -sfL https://get.k3s.io | K3S_URL=https://%s:6443 \
-  K3S_TOKEN="%s" \
-  INSTALL_K3S_EXEC="server \
-    --server https://%s:6443 \
-    --node-ip=%s \
-    --node-external-ip=%s \
-    --advertise-address=%s \
-    --tls-san=%s \
-    --tls-san=%s \
-    --tls-san=127.0.0.1 \
-    --flannel-iface=wg0 \
-    --write-kubeconfig-mode=644 \
-    --disable=traefik" sh -; then
+if ! { %s; }; then
   echo "❌ K3s installation script failed!"
   exit 1
 fi
@@ -411,7 +437,7 @@ done
 kubectl --kubeconfig=/etc/rancher/k3s/k3s.yaml get nodes
 
 echo "✅ K3s master %d joined cluster"
-`, masterNum, myWgIP, myWgIP, firstMasterWgIP, firstMasterWgIP, firstMasterWgIP, firstMasterWgIP, firstMasterWgIP, token, firstMasterWgIP, myWgIP, myPublicIP, myWgIP, myWgIP, myPublicIP, masterNum)
+`, masterNum, myWgIP, myWgIP, firstMasterWgIP, firstMasterWgIP, firstMasterWgIP, firstMasterWgIP, serverInstallCommand(installEnv), masterNum)
 			}).(pulumi.StringOutput),
 		}, pulumi.Parent(component), pulumi.DependsOn([]pulumi.Resource{tokenFetch}), pulumi.Timeouts(&pulumi.CustomTimeouts{
 			Create: "30m", // Increased from 20m for slower Azure B1s VMs
@@ -448,12 +474,21 @@ echo "✅ K3s master %d joined cluster"
 
 		workerInstall, err := remote.NewCommand(ctx, fmt.Sprintf("%s-worker-%d-install", name, i), &remote.CommandArgs{
 			Connection: workerConnArgs,
-			Create: pulumi.All(k3sToken, firstMaster.WireGuardIP, worker.WireGuardIP, worker.PublicIP).ApplyT(func(args []interface{}) string {
+			Create: pulumi.All(k3sToken, firstMaster.WireGuardIP, worker.WireGuardIP, worker.PublicIP, worker.Region, worker.Zone, apiLoadBalancerIP).ApplyT(func(args []interface{}) string {
 				token := args[0].(string) // K3s join token from first master
 				firstMasterWgIP := args[1].(string)
 				myWgIP := args[2].(string)
 				myPublicIP := args[3].(string)
+				nodeArgs := kubeletArgs + config.K3sTopologyArgs(args[4].(string), args[5].(string))
+				joinAddr := k3sJoinAddress(firstMasterWgIP, args[6].(string))
 				workerNum := i + 1
+				installEnv := fmt.Sprintf(`K3S_URL=https://%s:6443 \
+  K3S_TOKEN="%s" \
+  INSTALL_K3S_EXEC="agent \
+    --node-name=${HOSTNAME} \
+    --node-ip=%s \
+    --node-external-ip=%s \
+    --flannel-iface=wg0%s"`, joinAddr, token, myWgIP, myPublicIP, nodeArgs)
 
 				return fmt.Sprintf(`#!/bin/bash
 set -e
@@ -500,13 +535,7 @@ HOSTNAME=$(hostname -s)
 
 # Install K3s agent (worker node)
 echo "📥 Installing K3s agent (joining cluster)..."
-if ! curl -sfL https://get.k3s.io | K3S_URL=https://%s:6443 \
-  K3S_TOKEN="%s" \
-  INSTALL_K3S_EXEC="agent \
-    --node-name=${HOSTNAME} \
-    --node-ip=%s \
-    --node-external-ip=%s \
-    --flannel-iface=wg0" sh -; then
+if ! { %s; }; then
   echo "❌ K3s agent installation script failed!"
   exit 1
 fi
@@ -530,7 +559,7 @@ echo "⏳ Waiting for K3s agent to join cluster..."
 sleep 30
 
 echo "✅ K3s worker %d joined cluster"
-`, workerNum, myWgIP, myWgIP, firstMasterWgIP, firstMasterWgIP, firstMasterWgIP, firstMasterWgIP, token, myWgIP, myPublicIP, workerNum)
+`, workerNum, myWgIP, myWgIP, firstMasterWgIP, firstMasterWgIP, firstMasterWgIP, config.K3sInstallCommand(cfg.Kubernetes.RKE2, &cfg.Security, installEnv), workerNum)
 			}).(pulumi.StringOutput),
 		}, pulumi.Parent(component), pulumi.DependsOn([]pulumi.Resource{tokenFetch}), pulumi.Timeouts(&pulumi.CustomTimeouts{
 			Create: "30m", // Increased from 20m for slower Azure B1s VMs
@@ -566,3 +595,32 @@ echo "✅ K3s worker %d joined cluster"
 
 	return component, nil
 }
+
+// k3sMasterCount is how many of the first nodes K3s installs as servers
+const k3sMasterCount = 3
+
+// k3sMasters returns the nodes K3s installs as servers
+func k3sMasters(nodes []*RealNodeComponent) []*RealNodeComponent {
+	if len(nodes) > k3sMasterCount {
+		return nodes[:k3sMasterCount]
+	}
+	return nodes
+}
+
+// k3sAPILoadBalancerArgs returns the flags adding the API load balancer
+// address to the certificate of a server, or nothing without one
+func k3sAPILoadBalancerArgs(apiLoadBalancerIP string) string {
+	if apiLoadBalancerIP == "" {
+		return ""
+	}
+	return " --tls-san=" + apiLoadBalancerIP
+}
+
+// k3sJoinAddress returns the address nodes join the cluster through: the API
+// load balancer when there is one, the first server otherwise
+func k3sJoinAddress(firstMasterWgIP, apiLoadBalancerIP string) string {
+	if apiLoadBalancerIP != "" {
+		return apiLoadBalancerIP
+	}
+	return firstMasterWgIP
+}
@@ -31,6 +31,7 @@ type RealNodeComponent struct {
 	NodeName    pulumi.StringOutput `pulumi:"nodeName"`
 	Provider    pulumi.StringOutput `pulumi:"provider"`
 	Region      pulumi.StringOutput `pulumi:"region"`
+	Zone        pulumi.StringOutput `pulumi:"zone"`
 	Size        pulumi.StringOutput `pulumi:"size"`
 	PublicIP    pulumi.StringOutput `pulumi:"publicIP"`
 	PrivateIP   pulumi.StringOutput `pulumi:"privateIP"`
@@ -39,6 +40,9 @@ type RealNodeComponent struct {
 	Status      pulumi.StringOutput `pulumi:"status"`
 	DropletID   pulumi.IDOutput     `pulumi:"dropletId"`  // For DigitalOcean
 	InstanceID  pulumi.IntOutput    `pulumi:"instanceId"` // For Linode
+
+	// nodeConfig is the configuration the node was created from
+	nodeConfig config.NodeConfig
 }
 
 // NewRealNodeDeploymentComponent creates real cloud resources
@@ -81,6 +85,12 @@ func NewRealNodeDeploymentComponent(ctx *pulumi.Context, name string, clusterCon
 
 	// Create individual nodes
 	for _, nodeConfig := range clusterConfig.Nodes {
+		// Only zone-aware providers place a node in its zone
+		if !config.ProviderSupportsZones(nodeConfig.Provider) {
+			nodeConfig.Zone = ""
+		}
+		nodeConfig.Monitoring = clusterConfig.NodeMonitoringEnabled(&nodeConfig)
+		nodeConfig.SaltBootstrap = clusterConfig.Security.SaltBootstrapDownload()
 		nodeComp, err := newRealNodeComponent(ctx, fmt.Sprintf("%s-%s", name, nodeConfig.Name), &nodeConfig, sshKeyOutput, sshPrivateKey, sharedDOSshKey, nil, doToken, linodeToken, vpcComponent, bastionComponent, component)
 		if err != nil {
 			return nil, nil, err
@@ -93,6 +103,7 @@ func NewRealNodeDeploymentComponent(ctx *pulumi.Context, name string, clusterCon
 	// CRITICAL: Go maps have random iteration order, which causes K3s to assign
 	// master/worker roles incorrectly. Process pools in explicit order: masters first!
 	nodeIndex := len(realNodeComponents)
+	wgSubnet := clusterConfig.Network.WireGuard.ParsedSubnet()
 
 	// Build deterministic pool order: ALL masters first, then ALL workers
 	// This allows for dynamic providers (DigitalOcean, Linode, Azure, AWS, GCP)
@@ -104,8 +115,10 @@ func NewRealNodeDeploymentComponent(ctx *pulumi.Context, name string, clusterCon
 		ctx.Log.Info(fmt.Sprintf("🔍 DEBUG: Pool '%s' - provider=%s, count=%d", poolName, pool.Provider, pool.Count), nil)
 	}
 
-	// First pass: add all master pools
-	for poolName, pool := range clusterConfig.NodePools {
+	// First pass: add all master pools (each pass in pool name order)
+	poolNames := config.SortedPoolNames(clusterConfig.NodePools)
+	for _, poolName := range poolNames {
+		pool := clusterConfig.NodePools[poolName]
 		for _, role := range pool.Roles {
 			if role == "master" || role == "controlplane" {
 				poolOrder = append(poolOrder, poolName)
@@ -115,7 +128,8 @@ func NewRealNodeDeploymentComponent(ctx *pulumi.Context, name string, clusterCon
 	}
 
 	// Second pass: add all worker pools
-	for poolName, pool := range clusterConfig.NodePools {
+	for _, poolName := range poolNames {
+		pool := clusterConfig.NodePools[poolName]
 		isMaster := false
 		for _, role := range pool.Roles {
 			if role == "master" || role == "controlplane" {
@@ -132,20 +146,34 @@ func NewRealNodeDeploymentComponent(ctx *pulumi.Context, name string, clusterCon
 		poolConfig := clusterConfig.NodePools[poolName]
 
 		for i := 0; i < poolConfig.Count; i++ {
-			nodeName := fmt.Sprintf("%s-%d", poolName, i+1)
+			nodeName, err := config.RenderPoolNodeName(poolName, &poolConfig, i)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			// A node removed during a rolling replacement keeps its index, so
+			// the addresses of the nodes after it do not move
+			if poolConfig.ExcludedNodes[nodeName] {
+				nodeIndex++
+				continue
+			}
 
 			nodeConfig := config.NodeConfig{
 				Name:        nodeName,
 				Provider:    poolConfig.Provider,
 				Region:      poolConfig.Region,
-				Size:        poolConfig.Size,
+				Zone:        poolConfig.PlacedZone(i),
+				Size:        poolConfig.NodeSize(nodeName),
 				Image:       poolConfig.Image,
 				Roles:       poolConfig.Roles,
 				Labels:      poolConfig.Labels,
 				Taints:      poolConfig.Taints,
 				PrivateIP:   fmt.Sprintf("10.0.1.%d", nodeIndex+1),
-				WireGuardIP: fmt.Sprintf("10.8.0.%d", 10+nodeIndex),
+				WireGuardIP: wgSubnet.NodeIP(nodeIndex),
+				Monitoring:  poolConfig.Monitoring,
 			}
+			nodeConfig.Monitoring = clusterConfig.NodeMonitoringEnabled(&nodeConfig)
+			nodeConfig.SaltBootstrap = clusterConfig.Security.SaltBootstrapDownload()
 
 			nodeComp, err := newRealNodeComponent(ctx, fmt.Sprintf("%s-%s-%s", name, poolName, nodeName), &nodeConfig, sshKeyOutput, sshPrivateKey, sharedDOSshKey, nil, doToken, linodeToken, vpcComponent, bastionComponent, component)
 			if err != nil {
@@ -157,6 +185,39 @@ func NewRealNodeDeploymentComponent(ctx *pulumi.Context, name string, clusterCon
 		}
 	}
 
+	// Surge nodes of rolling replacements come last, for the same reason
+	for _, poolName := range poolOrder {
+		poolConfig := clusterConfig.NodePools[poolName]
+		if !poolConfig.Surge {
+			continue
+		}
+
+		nodeConfig := config.NodeConfig{
+			Name:        config.SurgeNodeName(poolName),
+			Provider:    poolConfig.Provider,
+			Region:      poolConfig.Region,
+			Zone:        poolConfig.PlacedZone(poolConfig.Count),
+			Size:        poolConfig.Size,
+			Image:       poolConfig.Image,
+			Roles:       poolConfig.Roles,
+			Labels:      poolConfig.Labels,
+			Taints:      poolConfig.Taints,
+			PrivateIP:   fmt.Sprintf("10.0.1.%d", nodeIndex+1),
+			WireGuardIP: wgSubnet.NodeIP(nodeIndex),
+			Monitoring:  poolConfig.Monitoring,
+		}
+		nodeConfig.Monitoring = clusterConfig.NodeMonitoringEnabled(&nodeConfig)
+		nodeConfig.SaltBootstrap = clusterConfig.Security.SaltBootstrapDownload()
+
+		nodeComp, err := newRealNodeComponent(ctx, fmt.Sprintf("%s-%s-%s", name, poolName, nodeConfig.Name), &nodeConfig, sshKeyOutput, sshPrivateKey, sharedDOSshKey, nil, doToken, linodeToken, vpcComponent, bastionComponent, component)
+		if err != nil {
+			return nil, nil, err
+		}
+		realNodeComponents = append(realNodeComponents, nodeComp)
+		nodesArray = append(nodesArray, pulumi.ToOutput(nodeComp))
+		nodeIndex++
+	}
+
 	component.Nodes = pulumi.ToArrayOutput(nodesArray)
 
 	ctx.Log.Info(fmt.Sprintf("✅ All %d VMs created, starting PARALLEL provisioning...", len(realNodeComponents)), nil)
@@ -202,9 +263,11 @@ func newRealNodeComponent(ctx *pulumi.Context, name string, nodeConfig *config.N
 		return nil, err
 	}
 
+	component.nodeConfig = *nodeConfig
 	component.NodeName = pulumi.String(nodeConfig.Name).ToStringOutput()
 	component.Provider = pulumi.String(nodeConfig.Provider).ToStringOutput()
 	component.Region = pulumi.String(nodeConfig.Region).ToStringOutput()
+	component.Zone = pulumi.String(nodeConfig.Zone).ToStringOutput()
 	component.Size = pulumi.String(nodeConfig.Size).ToStringOutput()
 	component.WireGuardIP = pulumi.String(nodeConfig.WireGuardIP).ToStringOutput()
 
@@ -219,8 +282,8 @@ func newRealNodeComponent(ctx *pulumi.Context, name string, nodeConfig *config.N
 	bastionEnabled := bastionComponent != nil && bastionComponent.BastionName.ToStringOutput() != pulumi.String("").ToStringOutput()
 	saltMasterIP := ""
 	if bastionEnabled {
-		// Use the fixed WireGuard IP of the bastion (10.8.0.5)
-		saltMasterIP = "10.8.0.5"
+		// Use the fixed WireGuard IP of the bastion (.5 of the WireGuard subnet)
+		saltMasterIP = bastionComponent.wireGuardIP
 	}
 
 	// Create real cloud resource based on provider
@@ -248,6 +311,7 @@ func newRealNodeComponent(ctx *pulumi.Context, name string, nodeConfig *config.N
 		"nodeName":    component.NodeName,
 		"provider":    component.Provider,
 		"region":      component.Region,
+		"zone":        component.Zone,
 		"size":        component.Size,
 		"publicIP":    component.PublicIP,
 		"privateIP":   component.PrivateIP,
@@ -264,28 +328,7 @@ func newRealNodeComponent(ctx *pulumi.Context, name string, nodeConfig *config.N
 // createDigitalOceanDroplet creates a real DigitalOcean Droplet
 func createDigitalOceanDroplet(ctx *pulumi.Context, name string, nodeConfig *config.NodeConfig, sharedSshKey *digitalocean.SshKey, doToken pulumi.StringInput, vpcComponent *VPCComponent, bastionEnabled bool, saltMasterIP string, component *RealNodeComponent) error {
 	// Use the shared SSH key (already created, no duplication)
-
-	// Build droplet args
-	dropletArgs := &digitalocean.DropletArgs{
-		Image:  pulumi.String(nodeConfig.Image),
-		Name:   pulumi.String(nodeConfig.Name),
-		Region: pulumi.String(nodeConfig.Region),
-		Size:   pulumi.String(nodeConfig.Size),
-		SshKeys: pulumi.StringArray{
-			sharedSshKey.Fingerprint,
-		},
-		Tags: pulumi.StringArray{
-			pulumi.String("kubernetes"),
-			pulumi.String(strings.ReplaceAll(ctx.Stack(), ".", "-")),
-		},
-		Ipv6:       pulumi.Bool(true),
-		Monitoring: pulumi.Bool(true),
-		// Cloud-init user-data: Install prerequisites (WireGuard, packages, Salt Minion) during VM boot
-		// K3s installation is handled by remote commands AFTER WireGuard is configured
-		// Set unique hostname to avoid etcd "duplicate node name" errors
-		// If Salt Master IP is provided, Salt Minion will be installed and configured
-		UserData: pulumi.String(cloudinit.GenerateUserDataWithHostnameAndSalt(nodeConfig.Name, saltMasterIP)),
-	}
+	dropletArgs := nodeDropletArgs(ctx.Stack(), nodeConfig, sharedSshKey.Fingerprint, saltMasterIP)
 
 	// If bastion is enabled, attach to VPC and configure for bastion-only SSH access
 	if bastionEnabled && vpcComponent != nil {
@@ -322,6 +365,31 @@ func createDigitalOceanDroplet(ctx *pulumi.Context, name string, nodeConfig *con
 	return nil
 }
 
+// nodeDropletArgs returns the create args of a node droplet. Monitoring
+// installs DigitalOcean's metrics agent when the node asks for it.
+func nodeDropletArgs(stack string, nodeConfig *config.NodeConfig, sshKeyFingerprint pulumi.StringInput, saltMasterIP string) *digitalocean.DropletArgs {
+	return &digitalocean.DropletArgs{
+		Image:  pulumi.String(nodeConfig.Image),
+		Name:   pulumi.String(nodeConfig.Name),
+		Region: pulumi.String(nodeConfig.Region),
+		Size:   pulumi.String(nodeConfig.Size),
+		SshKeys: pulumi.StringArray{
+			sshKeyFingerprint,
+		},
+		Tags: pulumi.StringArray{
+			pulumi.String("kubernetes"),
+			pulumi.String(strings.ReplaceAll(stack, ".", "-")),
+		},
+		Ipv6:       pulumi.Bool(true),
+		Monitoring: pulumi.Bool(nodeConfig.Monitoring),
+		// Cloud-init user-data: Install prerequisites (WireGuard, packages, Salt Minion) during VM boot
+		// K3s installation is handled by remote commands AFTER WireGuard is configured
+		// Set unique hostname to avoid etcd "duplicate node name" errors
+		// If Salt Master IP is provided, Salt Minion will be installed and configured
+		UserData: pulumi.String(cloudinit.GenerateUserDataWithHostnameAndSalt(nodeConfig.Name, saltMasterIP, nodeConfig.SaltBootstrap)),
+	}
+}
+
 // createLinodeInstance creates a real Linode Instance
 func createLinodeInstance(ctx *pulumi.Context, name string, nodeConfig *config.NodeConfig, sshKeyOutput pulumi.StringOutput, sharedStackscript *linode.StackScript, linodeToken pulumi.StringInput, bastionEnabled bool, saltMasterIP string, component *RealNodeComponent) error {
 	// Use the SSH key directly - it's already normalized in sshkeys.go
@@ -366,7 +434,7 @@ func createLinodeInstance(ctx *pulumi.Context, name string, nodeConfig *config.N
 		// If Salt Master IP is provided, Salt Minion will be installed and configured
 		Metadatas: linode.InstanceMetadataArray{
 			&linode.InstanceMetadataArgs{
-				UserData: pulumi.String(base64.StdEncoding.EncodeToString([]byte(cloudinit.GenerateUserDataWithHostnameAndSalt(nodeConfig.Name, saltMasterIP)))),
+				UserData: pulumi.String(base64.StdEncoding.EncodeToString([]byte(cloudinit.GenerateUserDataWithHostnameAndSalt(nodeConfig.Name, saltMasterIP, nodeConfig.SaltBootstrap)))),
 			},
 		},
 	}, pulumi.Parent(component))
@@ -504,6 +572,19 @@ func createAzureVM(ctx *pulumi.Context, name string, nodeConfig *config.NodeConf
 		azureNSG = nsg
 	}
 
+	// Pin the VM and its public IP to the node's availability zone
+	var zones pulumi.StringArray
+	if nodeConfig.Zone != "" {
+		zones = pulumi.StringArray{pulumi.String(nodeConfig.Zone)}
+	}
+
+	// The VM, its NIC and public IP belong to the node so operations on a
+	// node can target them; the alias keeps VMs created before unparented
+	ownedByNode := []pulumi.ResourceOption{
+		pulumi.Parent(component),
+		pulumi.Aliases([]pulumi.Alias{{NoParent: pulumi.Bool(true)}}),
+	}
+
 	// Create Public IP for this VM
 	publicIPName := fmt.Sprintf("%s-pip", nodeConfig.Name)
 	publicIP, err := azurenetwork.NewPublicIPAddress(ctx, publicIPName, &azurenetwork.PublicIPAddressArgs{
@@ -514,7 +595,8 @@ func createAzureVM(ctx *pulumi.Context, name string, nodeConfig *config.NodeConf
 		Sku: &azurenetwork.PublicIPAddressSkuArgs{
 			Name: pulumi.String("Standard"),
 		},
-	})
+		Zones: zones,
+	}, ownedByNode...)
 	if err != nil {
 		return fmt.Errorf("failed to create public IP: %w", err)
 	}
@@ -540,13 +622,13 @@ func createAzureVM(ctx *pulumi.Context, name string, nodeConfig *config.NodeConf
 		NetworkSecurityGroup: &azurenetwork.NetworkSecurityGroupTypeArgs{
 			Id: azureNSG.ID(),
 		},
-	})
+	}, ownedByNode...)
 	if err != nil {
 		return fmt.Errorf("failed to create network interface: %w", err)
 	}
 
 	// Generate cloud-init user data with Salt Minion if master IP is provided
-	userData := cloudinit.GenerateUserDataWithHostnameAndSalt(nodeConfig.Name, saltMasterIP)
+	userData := cloudinit.GenerateUserDataWithHostnameAndSalt(nodeConfig.Name, saltMasterIP, nodeConfig.SaltBootstrap)
 	userDataEncoded := base64.StdEncoding.EncodeToString([]byte(userData))
 
 	// Map image name to Azure image reference
@@ -565,6 +647,7 @@ func createAzureVM(ctx *pulumi.Context, name string, nodeConfig *config.NodeConf
 		ResourceGroupName: azureResourceGroup.Name,
 		Location:          pulumi.String(location),
 		VmName:            pulumi.String(nodeConfig.Name),
+		Zones:             zones,
 		NetworkProfile: &azurecompute.NetworkProfileArgs{
 			NetworkInterfaces: azurecompute.NetworkInterfaceReferenceArray{
 				&azurecompute.NetworkInterfaceReferenceArgs{
@@ -606,7 +689,7 @@ func createAzureVM(ctx *pulumi.Context, name string, nodeConfig *config.NodeConf
 		},
 	}
 
-	vm, err := azurecompute.NewVirtualMachine(ctx, nodeConfig.Name, vmArgs)
+	vm, err := azurecompute.NewVirtualMachine(ctx, nodeConfig.Name, vmArgs, ownedByNode...)
 	if err != nil {
 		return fmt.Errorf("failed to create VM: %w", err)
 	}
@@ -0,0 +1,25 @@
+package components
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+// TestNodeDropletArgs_Monitoring tests that droplets get monitoring only when the node asks for it
+func TestNodeDropletArgs_Monitoring(t *testing.T) {
+	node := &config.NodeConfig{Name: "do-1", Provider: "digitalocean", Region: "nyc3", Size: "s-2vcpu-4gb", Image: "ubuntu-22-04-x64"}
+
+	args := nodeDropletArgs("production", node, pulumi.String("fingerprint"), "")
+	if args.Monitoring != pulumi.Bool(false) {
+		t.Errorf("Expected monitoring off for a node not requesting it, got %v", args.Monitoring)
+	}
+
+	node.Monitoring = true
+	args = nodeDropletArgs("production", node, pulumi.String("fingerprint"), "")
+	if args.Monitoring != pulumi.Bool(true) {
+		t.Errorf("Expected the node's monitoring flag in the droplet args, got %v", args.Monitoring)
+	}
+}
@@ -0,0 +1,91 @@
+package components
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi-command/sdk/go/command/remote"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+// NodeLocalDNSComponent installs the node-local DNS cache DaemonSet
+type NodeLocalDNSComponent struct {
+	pulumi.ResourceState
+
+	Status pulumi.StringOutput `pulumi:"status"`
+}
+
+// NewNodeLocalDNSComponent applies the node-local-dns manifests from the first
+// master. Kubelets are already pointed at the cache by the K3s install.
+func NewNodeLocalDNSComponent(
+	ctx *pulumi.Context,
+	name string,
+	k8sConfig *config.KubernetesConfig,
+	nodes []*RealNodeComponent,
+	bastionComponent *BastionComponent,
+	sshPrivateKey pulumi.StringInput,
+	opts ...pulumi.ResourceOption,
+) (*NodeLocalDNSComponent, error) {
+	if !k8sConfig.NodeLocalDNSEnabled() {
+		return nil, nil // Node-local DNS not enabled
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no master nodes found for node-local DNS installation")
+	}
+
+	component := &NodeLocalDNSComponent{}
+	err := ctx.RegisterComponentResource("sloth:kubernetes:NodeLocalDNS", name, component, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	// First node is the first master by convention
+	connArgs := &remote.ConnectionArgs{
+		Host:           nodes[0].WireGuardIP,
+		User:           pulumi.String("root"),
+		PrivateKey:     sshPrivateKey,
+		DialErrorLimit: pulumi.Int(30),
+	}
+	if bastionComponent != nil {
+		connArgs.Proxy = &remote.ProxyConnectionArgs{
+			Host:       bastionComponent.PublicIP,
+			User:       pulumi.String("root"),
+			PrivateKey: sshPrivateKey,
+		}
+	}
+
+	_, err = remote.NewCommand(ctx, fmt.Sprintf("%s-install", name), &remote.CommandArgs{
+		Connection: connArgs,
+		Create:     pulumi.String(nodeLocalDNSInstallScript(k8sConfig)),
+	}, pulumi.Parent(component))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create node-local DNS install command: %w", err)
+	}
+
+	component.Status = pulumi.String("installed").ToStringOutput()
+	if err := ctx.RegisterResourceOutputs(component, pulumi.Map{
+		"status": component.Status,
+	}); err != nil {
+		return nil, err
+	}
+
+	return component, nil
+}
+
+// nodeLocalDNSInstallScript applies the manifests and waits for the DaemonSet
+func nodeLocalDNSInstallScript(k8sConfig *config.KubernetesConfig) string {
+	return fmt.Sprintf(`#!/bin/bash
+set -e
+
+echo "📦 Installing node-local DNS cache (%s)..."
+kubectl apply -f - <<'MANIFEST'
+%s
+MANIFEST
+
+echo "⏳ Waiting for node-local-dns to roll out (timeout: 300s)..."
+kubectl -n kube-system rollout status daemonset/node-local-dns --timeout=300s
+
+echo "✅ Node-local DNS cache installed"
+`, k8sConfig.NodeLocalDNS.Address(), config.BuildNodeLocalDNSManifest(k8sConfig))
+}
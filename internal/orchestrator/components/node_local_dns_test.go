@@ -0,0 +1,25 @@
+package components
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+// TestNodeLocalDNSInstallScript tests that the manifest is applied and awaited
+func TestNodeLocalDNSInstallScript(t *testing.T) {
+	k := &config.KubernetesConfig{
+		ClusterDNS:    "10.43.0.10",
+		ClusterDomain: "cluster.local",
+		NodeLocalDNS:  &config.NodeLocalDNSConfig{Enabled: true},
+	}
+
+	script := nodeLocalDNSInstallScript(k)
+
+	for _, want := range []string{"kubectl apply -f - <<'MANIFEST'", "name: node-local-dns", "rollout status daemonset/node-local-dns"} {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected install script to contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pulumi/pulumi-command/sdk/go/command/remote"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+const (
+	systemTuningModulesFile = "/etc/modules-load.d/sloth-kubernetes.conf"
+	systemTuningSysctlFile  = "/etc/sysctl.d/90-sloth-kubernetes.conf"
+)
+
+// SystemTuningComponent applies kernel modules and sysctls to every node
+type SystemTuningComponent struct {
+	pulumi.ResourceState
+
+	Status pulumi.StringOutput `pulumi:"status"`
+}
+
+// generateSystemTuningScript renders the script that persists and applies the
+// given kernel modules and sysctls. Modules are loaded before sysctls because
+// the net.bridge.* keys only exist once br_netfilter is loaded.
+func generateSystemTuningScript(sysctls []config.SysctlEntry, modules []string) string {
+	var b strings.Builder
+
+	b.WriteString("#!/bin/bash\n")
+	b.WriteString("set -e\n\n")
+
+	b.WriteString("# Kernel modules (persisted for reboots)\n")
+	fmt.Fprintf(&b, "cat > %s <<'EOF'\n", systemTuningModulesFile)
+	for _, module := range modules {
+		b.WriteString(module + "\n")
+	}
+	b.WriteString("EOF\n")
+	for _, module := range modules {
+		fmt.Fprintf(&b, "modprobe %s || echo \"WARNING: failed to load kernel module %s\"\n", module, module)
+	}
+
+	b.WriteString("\n# Sysctls (persisted for reboots)\n")
+	fmt.Fprintf(&b, "cat > %s <<'EOF'\n", systemTuningSysctlFile)
+	for _, entry := range sysctls {
+		fmt.Fprintf(&b, "%s = %s\n", entry.Key, entry.Value)
+	}
+	b.WriteString("EOF\n")
+	fmt.Fprintf(&b, "sysctl -e -p %s\n\n", systemTuningSysctlFile)
+
+	fmt.Fprintf(&b, "echo \"System tuning applied: %d modules, %d sysctls\"\n", len(modules), len(sysctls))
+
+	return b.String()
+}
+
+// NewSystemTuningComponent loads kernel modules and applies sysctls on all nodes.
+// The RKE2 defaults are always applied; tuning adds to or overrides them.
+func NewSystemTuningComponent(ctx *pulumi.Context, name string, nodes []*RealNodeComponent, tuning *config.SystemTuningConfig, sshPrivateKey pulumi.StringOutput, bastionComponent *BastionComponent, opts ...pulumi.ResourceOption) (*SystemTuningComponent, error) {
+	component := &SystemTuningComponent{}
+	err := ctx.RegisterComponentResource("kubernetes-create:provisioning:SystemTuning", name, component, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	sysctls, modules := config.EffectiveSystemTuning(tuning)
+	script := generateSystemTuningScript(sysctls, modules)
+
+	ctx.Log.Info(fmt.Sprintf("⚙️  Applying %d kernel modules and %d sysctls on %d nodes...", len(modules), len(sysctls), len(nodes)), nil)
+
+	for i, node := range nodes {
+		connArgs := remote.ConnectionArgs{
+			Host:           node.PublicIP,
+			User:           getSSHUserForProvider(node.Provider),
+			PrivateKey:     sshPrivateKey,
+			DialErrorLimit: pulumi.Int(30),
+		}
+		if bastionComponent != nil {
+			connArgs.Proxy = &remote.ProxyConnectionArgs{
+				Host:       bastionComponent.PublicIP,
+				User:       pulumi.String("root"),
+				PrivateKey: sshPrivateKey,
+			}
+		}
+
+		_, err := remote.NewCommand(ctx, fmt.Sprintf("%s-node-%d-tuning", name, i), &remote.CommandArgs{
+			Connection: connArgs,
+			Create:     pulumi.Sprintf("%sbash -s", getSudoPrefixForUser(node.Provider)),
+			Stdin:      pulumi.String(script),
+		}, pulumi.Parent(component), pulumi.Timeouts(&pulumi.CustomTimeouts{
+			Create: "5m",
+		}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply system tuning on node %d: %w", i, err)
+		}
+	}
+
+	component.Status = pulumi.Sprintf("System tuning applied on %d nodes", len(nodes))
+
+	if err := ctx.RegisterResourceOutputs(component, pulumi.Map{
+		"status": component.Status,
+	}); err != nil {
+		return nil, err
+	}
+
+	return component, nil
+}
@@ -0,0 +1,67 @@
+package components
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+// TestSystemTuningScript_DefaultsAndUserSysctls tests that defaults and user tuning render into the script
+func TestSystemTuningScript_DefaultsAndUserSysctls(t *testing.T) {
+	sysctls, modules := config.EffectiveSystemTuning(&config.SystemTuningConfig{
+		Sysctls: map[string]string{
+			"vm.max_map_count":            "262144",
+			"fs.inotify.max_user_watches": "1048576",
+		},
+		Modules: []string{"nvme_tcp"},
+	})
+	script := generateSystemTuningScript(sysctls, modules)
+
+	expected := []string{
+		// Defaults
+		"net.ipv4.ip_forward = 1",
+		"net.bridge.bridge-nf-call-iptables = 1",
+		"net.bridge.bridge-nf-call-ip6tables = 1",
+		"modprobe br_netfilter",
+		"modprobe overlay",
+		// User tuning
+		"vm.max_map_count = 262144",
+		"fs.inotify.max_user_watches = 1048576",
+		"modprobe nvme_tcp",
+		// Persistence and apply
+		systemTuningModulesFile,
+		systemTuningSysctlFile,
+		"sysctl -e -p " + systemTuningSysctlFile,
+	}
+	for _, want := range expected {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected script to contain %q", want)
+		}
+	}
+
+	if strings.Contains(script, "fs.inotify.max_user_watches = 524288") {
+		t.Error("Expected user value to replace the default inotify watch limit")
+	}
+
+	// Modules must be loaded before sysctls are applied (net.bridge.* needs br_netfilter)
+	if strings.Index(script, "modprobe br_netfilter") > strings.Index(script, "sysctl -e -p") {
+		t.Error("Expected modules to be loaded before sysctls are applied")
+	}
+}
+
+// TestSystemTuningScript_Stable tests that rendering is deterministic
+func TestSystemTuningScript_Stable(t *testing.T) {
+	tuning := &config.SystemTuningConfig{
+		Sysctls: map[string]string{"vm.swappiness": "10", "kernel.pid_max": "4194304", "net.core.somaxconn": "32768"},
+	}
+
+	sysctls, modules := config.EffectiveSystemTuning(tuning)
+	first := generateSystemTuningScript(sysctls, modules)
+	for i := 0; i < 10; i++ {
+		sysctls, modules = config.EffectiveSystemTuning(tuning)
+		if generateSystemTuningScript(sysctls, modules) != first {
+			t.Fatal("Expected identical scripts for identical tuning")
+		}
+	}
+}
@@ -61,7 +61,7 @@ func NewVPNValidatorComponent(ctx *pulumi.Context, name string, nodes []*RealNod
 	// Add bastion if present
 	if bastionComponent != nil {
 		allNodes = append(allNodes, &nodeInfo{
-			wgIP: pulumi.String("10.8.0.5").ToStringOutput(),
+			wgIP: bastionComponent.WireGuardIP,
 			name: pulumi.String("bastion").ToStringOutput(),
 		})
 	}
@@ -162,6 +162,9 @@ fi
 
 	// Run validation on first node (representative test)
 	// If this passes, mesh is configured correctly
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no nodes found for VPN validation")
+	}
 	firstNode := nodes[0]
 
 	// Determine SSH user based on provider (Azure uses "azureuser", others use "root")
@@ -0,0 +1,228 @@
+package components
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/pulumi/pulumi-command/sdk/go/command/remote"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+// hubPeer is a spoke that must be registered on the hub server
+type hubPeer struct {
+	name      string
+	publicKey string
+	wgIP      string
+}
+
+// WireGuardHubComponent connects every node to an existing WireGuard server
+// (hub-and-spoke) instead of building a mesh between the nodes
+type WireGuardHubComponent struct {
+	pulumi.ResourceState
+
+	Status      pulumi.StringOutput `pulumi:"status"`
+	PeerCount   pulumi.IntOutput    `pulumi:"peerCount"`
+	TunnelCount pulumi.IntOutput    `pulumi:"tunnelCount"`
+	HubPeers    pulumi.StringOutput `pulumi:"hubPeers"`
+}
+
+// hubHost returns the address used to SSH into the hub server
+func hubHost(wg *config.WireGuardConfig) string {
+	if wg.ServerIPAddress != "" {
+		return wg.ServerIPAddress
+	}
+	host, _, err := net.SplitHostPort(wg.ServerEndpoint)
+	if err != nil {
+		return wg.ServerEndpoint
+	}
+	return host
+}
+
+// generateHubServerPeers renders the [Peer] sections the hub needs for the spokes
+func generateHubServerPeers(peers []hubPeer) string {
+	var b strings.Builder
+	for _, peer := range peers {
+		fmt.Fprintf(&b, "\n[Peer]\n# %s (%s)\nPublicKey = %s\nAllowedIPs = %s/32\n", peer.name, peer.wgIP, peer.publicKey, peer.wgIP)
+	}
+	return b.String()
+}
+
+// generateHubRegisterScript adds the spokes to the running hub interface and
+// persists them in its wg0.conf. Peers already present are left untouched,
+// so the script is safe to re-run.
+func generateHubRegisterScript(peers []hubPeer) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/bash\nset -e\n\n")
+	b.WriteString("sysctl -w net.ipv4.ip_forward=1 > /dev/null\n\n")
+	for _, peer := range peers {
+		fmt.Fprintf(&b, "wg set wg0 peer %s allowed-ips %s/32\n", peer.publicKey, peer.wgIP)
+		fmt.Fprintf(&b, "if ! grep -q '%s' /etc/wireguard/wg0.conf; then\n", peer.publicKey)
+		fmt.Fprintf(&b, "  printf '\\n[Peer]\\n# %s (%s)\\nPublicKey = %s\\nAllowedIPs = %s/32\\n' >> /etc/wireguard/wg0.conf\n", peer.name, peer.wgIP, peer.publicKey, peer.wgIP)
+		b.WriteString("fi\n")
+	}
+	fmt.Fprintf(&b, "\necho \"✅ Registered %d spokes on the WireGuard hub\"\n", len(peers))
+	return b.String()
+}
+
+// nodeConnectionArgs returns the SSH connection for a node, through the bastion when present
+func nodeConnectionArgs(node *RealNodeComponent, sshPrivateKey pulumi.StringOutput, bastionComponent *BastionComponent) remote.ConnectionArgs {
+	connArgs := remote.ConnectionArgs{
+		Host:           node.PublicIP,
+		User:           getSSHUserForWireGuard(node.Provider),
+		PrivateKey:     sshPrivateKey,
+		DialErrorLimit: pulumi.Int(30),
+	}
+	if bastionComponent != nil {
+		connArgs.Proxy = &remote.ProxyConnectionArgs{
+			Host:       bastionComponent.PublicIP,
+			User:       pulumi.String("root"),
+			PrivateKey: sshPrivateKey,
+		}
+	}
+	return connArgs
+}
+
+// NewWireGuardHubComponent configures every node (and the bastion, if present)
+// as a spoke of the existing WireGuard server from the config. Cluster traffic
+// between nodes is routed through the hub.
+// When hubSSHKey is set the spokes are registered on the hub over SSH; otherwise
+// the [Peer] sections to add are exported in HubPeers.
+func NewWireGuardHubComponent(ctx *pulumi.Context, name string, nodes []*RealNodeComponent, wg *config.WireGuardConfig, sshPrivateKey pulumi.StringOutput, hubSSHKey string, bastionComponent *BastionComponent, opts ...pulumi.ResourceOption) (*WireGuardHubComponent, error) {
+	component := &WireGuardHubComponent{}
+	err := ctx.RegisterComponentResource("kubernetes-create:network:WireGuardHub", name, component, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx.Log.Info(fmt.Sprintf("🔧 Configuring WireGuard hub-and-spoke: %d nodes -> %s", len(nodes), wg.HubEndpoint()), nil)
+	subnet := wg.ParsedSubnet()
+
+	keygenScript := `#!/bin/bash
+set -e
+umask 077
+mkdir -p /etc/wireguard
+if [ ! -f /etc/wireguard/privatekey ]; then
+  wg genkey > /etc/wireguard/privatekey
+fi
+wg pubkey < /etc/wireguard/privatekey | tee /etc/wireguard/publickey`
+
+	var deployments []pulumi.Resource
+	var publicKeys []interface{}
+	var peers []hubPeer
+
+	// Bastion joins as a spoke so it keeps reaching the nodes over the VPN
+	if bastionComponent != nil {
+		bastionConn := remote.ConnectionArgs{
+			Host:           bastionComponent.PublicIP,
+			User:           pulumi.String("root"),
+			PrivateKey:     sshPrivateKey,
+			DialErrorLimit: pulumi.Int(30),
+		}
+
+		keyCmd, err := remote.NewCommand(ctx, fmt.Sprintf("%s-keygen-bastion", name), &remote.CommandArgs{
+			Connection: bastionConn,
+			Create:     pulumi.String(keygenScript),
+		}, pulumi.Parent(component), pulumi.Timeouts(&pulumi.CustomTimeouts{Create: "10m"}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate WireGuard keys on bastion: %w", err)
+		}
+
+		deployCmd, err := remote.NewCommand(ctx, fmt.Sprintf("%s-deploy-bastion", name), &remote.CommandArgs{
+			Connection: bastionConn,
+			Create:     pulumi.String(wireGuardNodeDeployScript(wg.HubSpokeConfig(subnet.BastionIP()), "", "hub")),
+		}, pulumi.Parent(component), pulumi.DependsOn([]pulumi.Resource{keyCmd}), pulumi.Timeouts(&pulumi.CustomTimeouts{Create: "15m"}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure WireGuard on bastion: %w", err)
+		}
+
+		deployments = append(deployments, deployCmd)
+		publicKeys = append(publicKeys, keyCmd.Stdout)
+		peers = append(peers, hubPeer{name: "bastion", wgIP: subnet.BastionIP()})
+	}
+
+	for i, node := range nodes {
+		wgIP := subnet.NodeIP(i)
+		connArgs := nodeConnectionArgs(node, sshPrivateKey, bastionComponent)
+		sudoPrefix := getSudoPrefixForUser(node.Provider)
+
+		keyCmd, err := remote.NewCommand(ctx, fmt.Sprintf("%s-keygen-%d", name, i), &remote.CommandArgs{
+			Connection: connArgs,
+			Create:     pulumi.Sprintf("%sbash -s", sudoPrefix),
+			Stdin:      pulumi.String(keygenScript),
+		}, pulumi.Parent(component), pulumi.Timeouts(&pulumi.CustomTimeouts{Create: "10m"}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate WireGuard keys on node %d: %w", i, err)
+		}
+
+		spokeConfig := wg.HubSpokeConfig(wgIP)
+		deployScript := sudoPrefix.ApplyT(func(sudo string) string {
+			return wireGuardNodeDeployScript(spokeConfig, sudo, "hub")
+		}).(pulumi.StringOutput)
+
+		deployCmd, err := remote.NewCommand(ctx, fmt.Sprintf("%s-deploy-%d", name, i), &remote.CommandArgs{
+			Connection: connArgs,
+			Create:     deployScript,
+		}, pulumi.Parent(component), pulumi.DependsOn([]pulumi.Resource{keyCmd}), pulumi.Timeouts(&pulumi.CustomTimeouts{Create: "15m"}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure WireGuard on node %d: %w", i, err)
+		}
+
+		deployments = append(deployments, deployCmd)
+		publicKeys = append(publicKeys, keyCmd.Stdout)
+		peers = append(peers, hubPeer{name: fmt.Sprintf("node-%d", i), wgIP: wgIP})
+	}
+
+	// Fill in the spoke public keys once the keygen commands have run
+	resolvePeers := func(keys []interface{}) []hubPeer {
+		resolved := make([]hubPeer, len(peers))
+		for i, key := range keys {
+			resolved[i] = peers[i]
+			resolved[i].publicKey = strings.TrimSpace(key.(string))
+		}
+		return resolved
+	}
+
+	component.HubPeers = pulumi.All(publicKeys...).ApplyT(func(keys []interface{}) string {
+		return generateHubServerPeers(resolvePeers(keys))
+	}).(pulumi.StringOutput)
+
+	if hubSSHKey != "" {
+		registerScript := pulumi.All(publicKeys...).ApplyT(func(keys []interface{}) string {
+			return generateHubRegisterScript(resolvePeers(keys))
+		}).(pulumi.StringOutput)
+
+		_, err := remote.NewCommand(ctx, fmt.Sprintf("%s-register-spokes", name), &remote.CommandArgs{
+			Connection: remote.ConnectionArgs{
+				Host:           pulumi.String(hubHost(wg)),
+				User:           pulumi.String("root"),
+				PrivateKey:     pulumi.String(hubSSHKey),
+				DialErrorLimit: pulumi.Int(30),
+			},
+			Create: registerScript,
+		}, pulumi.Parent(component), pulumi.DependsOn(deployments), pulumi.Timeouts(&pulumi.CustomTimeouts{Create: "10m"}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to register spokes on WireGuard hub: %w", err)
+		}
+		ctx.Log.Info(fmt.Sprintf("✅ Spokes will be registered on hub %s over SSH", hubHost(wg)), nil)
+	} else {
+		ctx.Log.Warn("⚠️  No sshPrivateKeyPath for the WireGuard hub - add the peers from the wireguard_hub_peers output to the hub manually", nil)
+	}
+
+	component.Status = pulumi.Sprintf("WireGuard hub-and-spoke: %d spokes via %s", len(peers), wg.HubEndpoint())
+	component.PeerCount = pulumi.Int(len(peers)).ToIntOutput()
+	component.TunnelCount = pulumi.Int(len(peers)).ToIntOutput()
+
+	if err := ctx.RegisterResourceOutputs(component, pulumi.Map{
+		"status":      component.Status,
+		"peerCount":   component.PeerCount,
+		"tunnelCount": component.TunnelCount,
+		"hubPeers":    component.HubPeers,
+	}); err != nil {
+		return nil, err
+	}
+
+	return component, nil
+}
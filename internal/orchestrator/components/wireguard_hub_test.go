@@ -0,0 +1,64 @@
+package components
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+// TestHubHost tests which address is used to SSH into the hub
+func TestHubHost(t *testing.T) {
+	if got := hubHost(&config.WireGuardConfig{ServerEndpoint: "vpn.example.com:51820"}); got != "vpn.example.com" {
+		t.Errorf("Expected endpoint host, got %q", got)
+	}
+	if got := hubHost(&config.WireGuardConfig{ServerEndpoint: "vpn.example.com", ServerIPAddress: "203.0.113.1"}); got != "203.0.113.1" {
+		t.Errorf("Expected serverIpAddress to win, got %q", got)
+	}
+}
+
+// TestGenerateHubServerPeers tests the peer sections exported for the hub
+func TestGenerateHubServerPeers(t *testing.T) {
+	peers := []hubPeer{
+		{name: "bastion", publicKey: "bastionKey=", wgIP: "10.8.0.5"},
+		{name: "node-0", publicKey: "node0Key=", wgIP: "10.8.0.10"},
+	}
+
+	got := generateHubServerPeers(peers)
+
+	want := `
+[Peer]
+# bastion (10.8.0.5)
+PublicKey = bastionKey=
+AllowedIPs = 10.8.0.5/32
+
+[Peer]
+# node-0 (10.8.0.10)
+PublicKey = node0Key=
+AllowedIPs = 10.8.0.10/32
+`
+	if got != want {
+		t.Errorf("Unexpected hub peers\nGot:\n%s\nWant:\n%s", got, want)
+	}
+}
+
+// TestGenerateHubRegisterScript tests that spokes are added live and persisted once
+func TestGenerateHubRegisterScript(t *testing.T) {
+	script := generateHubRegisterScript([]hubPeer{
+		{name: "node-0", publicKey: "node0Key=", wgIP: "10.8.0.10"},
+		{name: "node-1", publicKey: "node1Key=", wgIP: "10.8.0.11"},
+	})
+
+	expected := []string{
+		"net.ipv4.ip_forward=1",
+		"wg set wg0 peer node0Key= allowed-ips 10.8.0.10/32",
+		"wg set wg0 peer node1Key= allowed-ips 10.8.0.11/32",
+		"if ! grep -q 'node0Key=' /etc/wireguard/wg0.conf; then",
+		"Registered 2 spokes",
+	}
+	for _, want := range expected {
+		if !strings.Contains(script, want) {
+			t.Errorf("Expected register script to contain %q\nGot:\n%s", want, script)
+		}
+	}
+}
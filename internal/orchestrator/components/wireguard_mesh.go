@@ -5,6 +5,8 @@ import (
 
 	"github.com/pulumi/pulumi-command/sdk/go/command/remote"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
 )
 
 // getSSHUserForProvider returns the correct SSH username for the given cloud provider
@@ -47,8 +49,11 @@ type WireGuardMeshComponent struct {
 
 // NewWireGuardMeshComponent sets up WireGuard mesh between nodes
 // This configures a REAL full mesh VPN where every node connects to every other node
-// If bastionComponent is provided, it's added to the mesh with VPN IP 10.8.0.5
-func NewWireGuardMeshComponent(ctx *pulumi.Context, name string, nodes []*RealNodeComponent, sshPrivateKey pulumi.StringOutput, bastionComponent *BastionComponent, opts ...pulumi.ResourceOption) (*WireGuardMeshComponent, error) {
+// If bastionComponent is provided, it's added to the mesh with its VPN IP, .5
+// of the WireGuard subnet; nodes get .10 onwards
+// vpcNetworks maps providers to their VPC CIDR in hybrid network mode (nil
+// otherwise): peers in the same VPC are then reached over the VPC
+func NewWireGuardMeshComponent(ctx *pulumi.Context, name string, nodes []*RealNodeComponent, sshPrivateKey pulumi.StringOutput, bastionComponent *BastionComponent, subnet *config.WireGuardSubnet, vpcNetworks map[string]string, opts ...pulumi.ResourceOption) (*WireGuardMeshComponent, error) {
 	component := &WireGuardMeshComponent{}
 	err := ctx.RegisterComponentResource("kubernetes-create:network:WireGuardMesh", name, component, opts...)
 	if err != nil {
@@ -59,7 +64,7 @@ func NewWireGuardMeshComponent(ctx *pulumi.Context, name string, nodes []*RealNo
 	totalPeers := peerCount
 	if bastionComponent != nil {
 		totalPeers++ // Include bastion in peer count
-		ctx.Log.Info("🏰 Including bastion host in WireGuard mesh (10.8.0.5)", nil)
+		ctx.Log.Info(fmt.Sprintf("🏰 Including bastion host in WireGuard mesh (%s)", bastionComponent.wireGuardIP), nil)
 	}
 	tunnelCount := (totalPeers * (totalPeers - 1)) / 2
 
@@ -69,6 +74,8 @@ func NewWireGuardMeshComponent(ctx *pulumi.Context, name string, nodes []*RealNo
 	type nodeKeys struct {
 		publicKey pulumi.StringOutput
 		publicIP  pulumi.StringOutput
+		privateIP pulumi.StringOutput
+		provider  pulumi.StringOutput
 		wgIP      string
 		name      string
 	}
@@ -78,7 +85,7 @@ func NewWireGuardMeshComponent(ctx *pulumi.Context, name string, nodes []*RealNo
 
 	// Generate keys for bastion if present
 	if bastionComponent != nil {
-		bastionWgIP := "10.8.0.5"
+		bastionWgIP := bastionComponent.wireGuardIP
 		keyCmd, err := remote.NewCommand(ctx, fmt.Sprintf("%s-keygen-bastion", name), &remote.CommandArgs{
 			Connection: remote.ConnectionArgs{
 				Host:           bastionComponent.PublicIP,
@@ -115,6 +122,8 @@ cat publickey
 			allNodeKeys[0] = &nodeKeys{
 				publicKey: publicKey,
 				publicIP:  bastionComponent.PublicIP,
+				privateIP: pulumi.String("").ToStringOutput(),
+				provider:  pulumi.String("").ToStringOutput(),
 				wgIP:      bastionWgIP,
 				name:      "bastion",
 			}
@@ -130,7 +139,7 @@ cat publickey
 	}
 
 	for i, node := range nodes {
-		wgIP := fmt.Sprintf("10.8.0.%d", 10+i)
+		wgIP := subnet.NodeIP(i)
 
 		// Generate keys on each node
 		// When bastion is present, use ProxyJump to connect through it
@@ -197,6 +206,8 @@ cat /etc/wireguard/publickey`
 		allNodeKeys[nodeOffset+i] = &nodeKeys{
 			publicKey: publicKey,
 			publicIP:  node.PublicIP,
+			privateIP: node.PrivateIP,
+			provider:  node.Provider,
 			wgIP:      wgIP,
 			name:      fmt.Sprintf("node-%d", i),
 		}
@@ -255,12 +266,12 @@ PersistentKeepalive = 25
 		// Build complete WireGuard config for bastion
 		fullConfig := allPeerConfigsOutput.ApplyT(func(peerSection string) string {
 			interfaceSection := fmt.Sprintf(`[Interface]
-Address = %s/24
+Address = %s/%d
 ListenPort = 51820
 PrivateKey = $(cat /etc/wireguard/privatekey)
 PostUp = iptables -A FORWARD -i wg0 -j ACCEPT; iptables -t nat -A POSTROUTING -o eth0 -j MASQUERADE; sysctl -w net.ipv4.ip_forward=1
 PostDown = iptables -D FORWARD -i wg0 -j ACCEPT; iptables -t nat -D POSTROUTING -o eth0 -j MASQUERADE
-`, myWgIP)
+`, myWgIP, subnet.PrefixLen())
 
 			return interfaceSection + peerSection
 		}).(pulumi.StringOutput)
@@ -340,20 +351,22 @@ wg show
 				peerKeys := allNodeKeys[j]
 
 				// Build peer config section
-				peerConfig := pulumi.All(peerKeys.publicKey, peerKeys.publicIP).ApplyT(func(args []interface{}) string {
+				peerConfig := pulumi.All(peerKeys.publicKey, peerKeys.publicIP, peerKeys.privateIP, peerKeys.provider, node.Provider, node.PrivateIP).ApplyT(func(args []interface{}) string {
 					pubKey := args[0].(string)
-					peerIP := args[1].(string)
 					peerWgIP := allNodeKeys[j].wgIP
 					peerName := allNodeKeys[j].name
+					peer := config.MeshEndpoint{WgIP: peerWgIP, PublicIP: args[1].(string), PrivateIP: args[2].(string), Provider: args[3].(string)}
+					self := config.MeshEndpoint{PrivateIP: args[5].(string), Provider: args[4].(string)}
+					allowedIPs, endpoint := config.MeshPeerRoute(peer, self, vpcNetworks)
 
 					return fmt.Sprintf(`
 [Peer]
 # %s (%s)
 PublicKey = %s
-AllowedIPs = %s/32, 10.0.0.0/8
+AllowedIPs = %s
 Endpoint = %s:51820
 PersistentKeepalive = 25
-`, peerName, peerWgIP, pubKey, peerWgIP, peerIP)
+`, peerName, peerWgIP, pubKey, allowedIPs, endpoint)
 				}).(pulumi.StringOutput)
 
 				peerConfigs = append(peerConfigs, peerConfig)
@@ -381,65 +394,19 @@ PersistentKeepalive = 25
 		fullConfig := allPeerConfigsOutput.ApplyT(func(peerSection string) string {
 			// Read the private key from the node
 			interfaceSection := fmt.Sprintf(`[Interface]
-Address = %s/24
+Address = %s/%d
 ListenPort = 51820
 PrivateKey = $(cat /etc/wireguard/privatekey)
 PostUp = iptables -A FORWARD -i wg0 -j ACCEPT; iptables -t nat -A POSTROUTING -o eth0 -j MASQUERADE; sysctl -w net.ipv4.ip_forward=1
 PostDown = iptables -D FORWARD -i wg0 -j ACCEPT; iptables -t nat -D POSTROUTING -o eth0 -j MASQUERADE
-`, myWgIP)
+`, myWgIP, subnet.PrefixLen())
 
 			return interfaceSection + peerSection
 		}).(pulumi.StringOutput)
 
 		// Deploy configuration to node - build script with sudo if needed
 		deployScript := pulumi.All(fullConfig, sudoPrefix).ApplyT(func(args []interface{}) string {
-			config := args[0].(string)
-			sudo := args[1].(string)
-			return fmt.Sprintf(`#!/bin/bash
-set -e
-
-# Write WireGuard configuration (expanding privatekey variable)
-cat > /tmp/wg0.conf.template << 'WGEOF'
-%s
-WGEOF
-
-# Expand the privatekey variable
-export PRIVKEY=$(%scat /etc/wireguard/privatekey)
-sed "s|\$(cat /etc/wireguard/privatekey)|$PRIVKEY|g" /tmp/wg0.conf.template | %stee /etc/wireguard/wg0.conf > /dev/null
-%schmod 600 /etc/wireguard/wg0.conf
-
-# Enable IP forwarding permanently
-echo "net.ipv4.ip_forward=1" | %stee -a /etc/sysctl.conf > /dev/null
-%ssysctl -w net.ipv4.ip_forward=1
-
-# Stop any existing WireGuard interface
-%swg-quick down wg0 2>/dev/null || true
-sleep 2
-
-# Start WireGuard
-%swg-quick up wg0
-
-# Enable on boot
-%ssystemctl enable wg-quick@wg0 2>/dev/null || true
-
-echo "✅ WireGuard mesh configured"
-%swg show
-
-# Restart Salt Minion if installed (so it connects to master via WireGuard VPN)
-if %ssystemctl is-active --quiet salt-minion 2>/dev/null || %ssystemctl status salt-minion 2>/dev/null | grep -q "loaded"; then
-    echo "🔄 Restarting Salt Minion to connect via WireGuard VPN..."
-    %ssystemctl restart salt-minion 2>/dev/null || true
-    %ssystemctl enable salt-minion 2>/dev/null || true
-    sleep 2
-    if %ssystemctl is-active --quiet salt-minion; then
-        echo "✅ Salt Minion restarted and connected to master"
-    else
-        echo "⚠️  Salt Minion restart attempted (may still be initializing)"
-    fi
-else
-    echo "ℹ️  Salt Minion not installed on this node"
-fi
-`, config, sudo, sudo, sudo, sudo, sudo, sudo, sudo, sudo, sudo, sudo, sudo, sudo, sudo, sudo)
+			return wireGuardNodeDeployScript(args[0].(string), args[1].(string), "mesh")
 		}).(pulumi.StringOutput)
 
 		// Execute deployment
@@ -497,3 +464,54 @@ fi
 
 	return component, nil
 }
+
+// wireGuardNodeDeployScript installs a rendered wg0.conf on a cluster node and
+// brings the interface up. sudo is the prefix for non-root users; topology
+// ("mesh" or "hub") is only used in the log output.
+func wireGuardNodeDeployScript(config, sudo, topology string) string {
+	return fmt.Sprintf(`#!/bin/bash
+set -e
+
+# Write WireGuard configuration (expanding privatekey variable)
+cat > /tmp/wg0.conf.template << 'WGEOF'
+%s
+WGEOF
+
+# Expand the privatekey variable
+export PRIVKEY=$(%scat /etc/wireguard/privatekey)
+sed "s|\$(cat /etc/wireguard/privatekey)|$PRIVKEY|g" /tmp/wg0.conf.template | %stee /etc/wireguard/wg0.conf > /dev/null
+%schmod 600 /etc/wireguard/wg0.conf
+
+# Enable IP forwarding permanently
+echo "net.ipv4.ip_forward=1" | %stee -a /etc/sysctl.conf > /dev/null
+%ssysctl -w net.ipv4.ip_forward=1
+
+# Stop any existing WireGuard interface
+%swg-quick down wg0 2>/dev/null || true
+sleep 2
+
+# Start WireGuard
+%swg-quick up wg0
+
+# Enable on boot
+%ssystemctl enable wg-quick@wg0 2>/dev/null || true
+
+echo "✅ WireGuard %s configured"
+%swg show
+
+# Restart Salt Minion if installed (so it connects to master via WireGuard VPN)
+if %ssystemctl is-active --quiet salt-minion 2>/dev/null || %ssystemctl status salt-minion 2>/dev/null | grep -q "loaded"; then
+    echo "🔄 Restarting Salt Minion to connect via WireGuard VPN..."
+    %ssystemctl restart salt-minion 2>/dev/null || true
+    %ssystemctl enable salt-minion 2>/dev/null || true
+    sleep 2
+    if %ssystemctl is-active --quiet salt-minion; then
+        echo "✅ Salt Minion restarted and connected to master"
+    else
+        echo "⚠️  Salt Minion restart attempted (may still be initializing)"
+    fi
+else
+    echo "ℹ️  Salt Minion not installed on this node"
+fi
+`, config, sudo, sudo, sudo, sudo, sudo, sudo, sudo, sudo, topology, sudo, sudo, sudo, sudo, sudo, sudo)
+}
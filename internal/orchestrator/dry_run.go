@@ -0,0 +1,240 @@
+package orchestrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/chalkan3/sloth-kubernetes/internal/validation"
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/dns"
+	"github.com/chalkan3/sloth-kubernetes/pkg/network"
+	"github.com/chalkan3/sloth-kubernetes/pkg/security"
+)
+
+// DeployPlan is what a deployment would create, computed from the config
+// without creating any resource
+type DeployPlan struct {
+	Providers     []string
+	Pools         []PoolPlan
+	NetworkCIDR   string
+	NodeIPs       []NodeIPPlan
+	FirewallRules []config.FirewallRule
+	DNSDomain     string
+	DNSRecords    []string
+	// APILoadBalancer fronts the masters of an HA cluster, nil without HA
+	APILoadBalancer *config.LoadBalancerConfig
+}
+
+// PoolPlan is the nodes a pool, or a standalone node, would create
+type PoolPlan struct {
+	Name     string
+	Provider string
+	Count    int
+	Roles    []string
+}
+
+// NodeIPPlan is the private IP a node would get from AllocateNodeIPs
+type NodeIPPlan struct {
+	Node string
+	IP   string
+}
+
+// plannedProviders lists the enabled providers in the order
+// initializeProviders initializes them
+func plannedProviders(providers *config.ProvidersConfig) []string {
+	var names []string
+	if providers.DigitalOcean != nil && providers.DigitalOcean.Enabled {
+		names = append(names, "digitalocean")
+	}
+	if providers.Linode != nil && providers.Linode.Enabled {
+		names = append(names, "linode")
+	}
+	if providers.Azure != nil && providers.Azure.Enabled {
+		names = append(names, "azure")
+	}
+	if providers.AWS != nil && providers.AWS.Enabled {
+		names = append(names, "aws")
+	}
+	if providers.GCP != nil && providers.GCP.Enabled {
+		names = append(names, "gcp")
+	}
+	if providers.Vultr != nil && providers.Vultr.Enabled {
+		names = append(names, "vultr")
+	}
+	return names
+}
+
+// Plan computes the deployment plan, running the same validation as Deploy:
+// providers, node names and distribution, CIDRs and WireGuard. No Pulumi
+// resource is created.
+func (o *Orchestrator) Plan() (*DeployPlan, error) {
+	plan := &DeployPlan{
+		Providers:   plannedProviders(&o.config.Providers),
+		NetworkCIDR: o.config.Network.CIDR,
+		DNSDomain:   o.dnsDomain(),
+	}
+
+	// Providers
+	if len(plan.Providers) == 0 {
+		return nil, fmt.Errorf("no cloud providers enabled")
+	}
+
+	// Nodes, in the order they are named
+	for _, node := range o.config.Nodes {
+		plan.Pools = append(plan.Pools, PoolPlan{Name: node.Name, Provider: node.Provider, Count: 1, Roles: node.Roles})
+	}
+	for _, name := range config.SortedPoolNames(o.config.NodePools) {
+		pool := o.config.NodePools[name]
+		plan.Pools = append(plan.Pools, PoolPlan{Name: name, Provider: pool.Provider, Count: pool.Count, Roles: pool.Roles})
+	}
+	nodeNames, err := config.ExpandNodeNames(o.config)
+	if err != nil {
+		return nil, err
+	}
+	if collisions := config.NodeNameCollisions(o.config); len(collisions) > 0 {
+		return nil, fmt.Errorf("duplicate node names: %s", strings.Join(collisions, "; "))
+	}
+	if err := validation.ValidateNodeDistribution(o.config); err != nil {
+		return nil, fmt.Errorf("node distribution validation failed: %w", err)
+	}
+
+	// Networking
+	networkManager := network.NewManager(o.ctx, &o.config.Network)
+	if plan.NetworkCIDR != "" {
+		if err := networkManager.ValidateCIDRs(); err != nil {
+			return nil, fmt.Errorf("network validation failed: %w", err)
+		}
+		ips, err := networkManager.AllocateNodeIPs(len(nodeNames))
+		if err != nil {
+			return nil, fmt.Errorf("failed to allocate node IPs: %w", err)
+		}
+		for i, ip := range ips {
+			plan.NodeIPs = append(plan.NodeIPs, NodeIPPlan{Node: nodeNames[i].Name, IP: ip})
+		}
+	}
+
+	// WireGuard
+	if wg := o.config.Network.WireGuard; wg != nil && wg.Enabled {
+		if err := security.NewWireGuardManager(o.ctx, wg).ValidateConfiguration(); err != nil {
+			return nil, fmt.Errorf("WireGuard validation failed: %w", err)
+		}
+	}
+
+	// Firewalls. The provider networks don't exist yet, so the rules
+	// allowing traffic within them are left out.
+	plan.FirewallRules = network.NewFirewallRuleSet(o.ctx.Stack(), &o.config.Network, "", "").InboundRules
+
+	// DNS
+	plan.DNSRecords = o.plannedDNSRecords()
+
+	// API load balancer
+	plan.APILoadBalancer = o.config.APILoadBalancer()
+
+	return plan, nil
+}
+
+// plannedDNSRecords returns the names of the records configureDNS creates,
+// numbering masters and workers in node name order
+func (o *Orchestrator) plannedDNSRecords() []string {
+	type plannedNode struct {
+		name, provider, role string
+	}
+	var nodes []plannedNode
+	for _, node := range o.config.Nodes {
+		role := config.RoleWorker
+		if config.HasRole(node.Roles, config.RoleMaster) {
+			role = config.RoleMaster
+		}
+		nodes = append(nodes, plannedNode{node.Name, node.Provider, role})
+	}
+	for _, poolName := range config.SortedPoolNames(o.config.NodePools) {
+		pool := o.config.NodePools[poolName]
+		for i := 0; i < pool.Count; i++ {
+			name, err := config.RenderPoolNodeName(poolName, &pool, i)
+			if err != nil {
+				continue
+			}
+			nodes = append(nodes, plannedNode{name, pool.Provider, config.PoolRole(&pool)})
+		}
+	}
+
+	wireGuard := o.config.Network.WireGuard != nil && o.config.Network.WireGuard.Enabled
+	var records []string
+	counts := make(map[string]int)
+	for _, node := range nodes {
+		counts[node.role]++
+		records = append(records, dns.NodeRecordNames(node.role, node.provider, node.name, counts[node.role])...)
+		records = append(records, "private-"+node.name)
+		if wireGuard {
+			records = append(records, "wg-"+node.name)
+			if node.role == config.RoleMaster || node.role == config.RoleWorker {
+				records = append(records, fmt.Sprintf("wg-%s%d", node.role, counts[node.role]))
+			}
+		}
+	}
+	records = append(records, "*.k8s", "kube-ingress")
+
+	var cnames []string
+	for name := range dns.ClusterRecords {
+		cnames = append(cnames, name)
+	}
+	sort.Strings(cnames)
+	records = append(records, cnames...)
+
+	for i, record := range records {
+		records[i] = strings.ToLower(record)
+	}
+	return records
+}
+
+// String renders the plan as a readable summary, one item per line
+func (p *DeployPlan) String() string {
+	var b strings.Builder
+	b.WriteString("Dry run: no resources will be created\n")
+	fmt.Fprintf(&b, "Providers: %s\n", strings.Join(p.Providers, ", "))
+
+	b.WriteString("Nodes:\n")
+	for _, pool := range p.Pools {
+		fmt.Fprintf(&b, "  %s (%s): %d x %s\n", pool.Name, pool.Provider, pool.Count, strings.Join(pool.Roles, "+"))
+	}
+
+	if p.NetworkCIDR != "" {
+		fmt.Fprintf(&b, "Node IPs (%s):\n", p.NetworkCIDR)
+		for _, ip := range p.NodeIPs {
+			fmt.Fprintf(&b, "  %s: %s\n", ip.Node, ip.IP)
+		}
+	}
+
+	b.WriteString("Firewall rules:\n")
+	for _, rule := range p.FirewallRules {
+		fmt.Fprintf(&b, "  %s %s from %s - %s\n", rule.Protocol, rule.Port, strings.Join(rule.Source, ", "), rule.Description)
+	}
+
+	fmt.Fprintf(&b, "DNS records (%s):\n", p.DNSDomain)
+	for _, record := range p.DNSRecords {
+		fmt.Fprintf(&b, "  %s.%s\n", record, p.DNSDomain)
+	}
+
+	if lb := p.APILoadBalancer; lb != nil {
+		ports := make([]string, 0, len(lb.Ports))
+		for _, port := range lb.Ports {
+			ports = append(ports, fmt.Sprintf("%d", port.Port))
+		}
+		fmt.Fprintf(&b, "API load balancer: %s (%s) on %s %s to the masters\n", lb.Name, lb.Provider, lb.Type, strings.Join(ports, ", "))
+	}
+
+	return b.String()
+}
+
+// previewDeploy logs the deployment plan instead of deploying
+func (o *Orchestrator) previewDeploy() error {
+	plan, err := o.Plan()
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(plan.String(), "\n"), "\n") {
+		o.ctx.Log.Info(line, nil)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/briandowns/spinner"
+	"github.com/chalkan3/sloth-kubernetes/pkg/network"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"golang.org/x/term"
+)
+
+// meshProgressLogInterval is how often progress is logged when no terminal
+// is attached
+const meshProgressLogInterval = 15 * time.Second
+
+// meshProgressReporter renders the progress of the mesh verification: a
+// spinner when stderr is a terminal, periodic log lines in CI and other
+// non-interactive runs
+type meshProgressReporter struct {
+	ctx     *pulumi.Context
+	spinner *spinner.Spinner
+	lastLog time.Time
+}
+
+// newMeshProgressReporter creates a reporter for the current output
+func newMeshProgressReporter(ctx *pulumi.Context) *meshProgressReporter {
+	r := &meshProgressReporter{ctx: ctx}
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		r.spinner = spinner.New(spinner.CharSets[14], 100*time.Millisecond, spinner.WithWriter(os.Stderr))
+	}
+	return r
+}
+
+// Update renders a progress snapshot
+func (r *meshProgressReporter) Update(progress network.MeshProgress) {
+	message := meshProgressMessage(progress)
+
+	if r.spinner != nil {
+		r.spinner.Lock()
+		r.spinner.Suffix = " " + message
+		r.spinner.Unlock()
+		if !r.spinner.Active() {
+			r.spinner.Start()
+		}
+		return
+	}
+
+	// Log the first and last links, and the ones in between at intervals
+	if progress.Done() > 1 && progress.Done() < progress.Total && time.Since(r.lastLog) < meshProgressLogInterval {
+		return
+	}
+	r.lastLog = time.Now()
+	r.ctx.Log.Info(message, nil)
+}
+
+// Stop clears the spinner once the verification is over
+func (r *meshProgressReporter) Stop() {
+	if r.spinner != nil {
+		r.spinner.Stop()
+	}
+}
+
+// meshProgressMessage describes a progress snapshot
+func meshProgressMessage(progress network.MeshProgress) string {
+	return fmt.Sprintf("Mesh verification: %d/%d links verified, %d failing",
+		progress.Verified, progress.Total, progress.Failing)
+}
@@ -53,15 +53,20 @@ func NewNodeDeploymentComponent(ctx *pulumi.Context, name string, clusterConfig
 
 	// Add nodes from pools - each node gets its own component
 	nodeIndex := len(nodeComponents)
-	for poolName, poolConfig := range clusterConfig.NodePools {
+	for _, poolName := range config.SortedPoolNames(clusterConfig.NodePools) {
+		poolConfig := clusterConfig.NodePools[poolName]
 		for i := 0; i < poolConfig.Count; i++ {
-			nodeName := fmt.Sprintf("%s-%d", poolName, i+1)
+			nodeName, err := config.RenderPoolNodeName(poolName, &poolConfig, i)
+			if err != nil {
+				return nil, err
+			}
 
 			// Create a node config from pool config
 			nodeConfig := config.NodeConfig{
 				Name:        nodeName,
 				Provider:    poolConfig.Provider,
 				Region:      poolConfig.Region,
+				Zone:        poolConfig.ZoneForIndex(i),
 				Size:        poolConfig.Size,
 				Image:       poolConfig.Image,
 				Roles:       poolConfig.Roles,
@@ -69,6 +74,7 @@ func NewNodeDeploymentComponent(ctx *pulumi.Context, name string, clusterConfig
 				Taints:      poolConfig.Taints,
 				PrivateIP:   fmt.Sprintf("10.0.1.%d", nodeIndex+1),
 				WireGuardIP: fmt.Sprintf("10.8.0.%d", 10+nodeIndex),
+				Monitoring:  poolConfig.Monitoring,
 			}
 
 			nodeComp, err := newIndividualNodeComponent(ctx, fmt.Sprintf("%s-%s-%s", name, poolName, nodeName), &nodeConfig, component)
@@ -2,13 +2,18 @@ package orchestrator
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 
+	"github.com/chalkan3/sloth-kubernetes/internal/validation"
 	"github.com/chalkan3/sloth-kubernetes/pkg/cluster"
 	"github.com/chalkan3/sloth-kubernetes/pkg/config"
 	"github.com/chalkan3/sloth-kubernetes/pkg/dns"
 	"github.com/chalkan3/sloth-kubernetes/pkg/health"
 	"github.com/chalkan3/sloth-kubernetes/pkg/ingress"
+	"github.com/chalkan3/sloth-kubernetes/pkg/logging"
 	"github.com/chalkan3/sloth-kubernetes/pkg/network"
 	"github.com/chalkan3/sloth-kubernetes/pkg/providers"
 	"github.com/chalkan3/sloth-kubernetes/pkg/security"
@@ -25,46 +30,54 @@ type Orchestrator struct {
 	sshKeyManager    *security.SSHKeyManager
 	osFirewallMgr    *security.OSFirewallManager
 	dnsManager       *dns.Manager
-	ingressManager   *ingress.NginxIngressManager
+	ingressManager   ingress.Controller
 	rkeManager       *cluster.RKEManager
 	healthChecker    *health.HealthChecker
 	validator        *health.PrerequisiteValidator
 	vpnChecker       *network.VPNConnectivityChecker
 	nodes            map[string][]*providers.NodeOutput
+	apiLoadBalancer  *providers.LoadBalancerOutput
+	dryRun           bool
 	mu               sync.Mutex
 }
 
-// New creates a new orchestrator
-func New(ctx *pulumi.Context, config *config.ClusterConfig) *Orchestrator {
+// New creates a new orchestrator. With dryRun set, Deploy validates the
+// config and logs the deployment plan without creating any resource.
+func New(ctx *pulumi.Context, config *config.ClusterConfig, dryRun bool) *Orchestrator {
 	return &Orchestrator{
 		ctx:              ctx,
 		config:           config,
 		providerRegistry: providers.NewProviderRegistry(),
 		nodes:            make(map[string][]*providers.NodeOutput),
+		dryRun:           dryRun,
 	}
 }
 
 // Deploy orchestrates the complete cluster deployment
 func (o *Orchestrator) Deploy() error {
+	if o.dryRun {
+		return o.previewDeploy()
+	}
+
 	o.ctx.Log.Info("Starting Kubernetes cluster deployment", nil)
 
 	// Phase 0: Generate SSH keys
-	if err := o.generateSSHKeys(); err != nil {
+	if err := o.runPhase("ssh-keys", o.generateSSHKeys); err != nil {
 		return fmt.Errorf("failed to generate SSH keys: %w", err)
 	}
 
 	// Phase 1: Initialize providers
-	if err := o.initializeProviders(); err != nil {
+	if err := o.runPhase("providers", o.initializeProviders); err != nil {
 		return fmt.Errorf("failed to initialize providers: %w", err)
 	}
 
 	// Phase 2: Create networking infrastructure
-	if err := o.createNetworking(); err != nil {
+	if err := o.runPhase("networking", o.createNetworking); err != nil {
 		return fmt.Errorf("failed to create networking: %w", err)
 	}
 
 	// Phase 3: Deploy nodes
-	if err := o.deployNodes(); err != nil {
+	if err := o.runPhase("nodes", o.deployNodes); err != nil {
 		return fmt.Errorf("failed to deploy nodes: %w", err)
 	}
 
@@ -75,20 +88,28 @@ func (o *Orchestrator) Deploy() error {
 	// }
 
 	// Phase 5: Configure DNS records
-	if err := o.configureDNS(); err != nil {
+	if err := o.runPhase("dns", o.configureDNS); err != nil {
 		return fmt.Errorf("failed to configure DNS: %w", err)
 	}
 
 	// Phase 6: Configure WireGuard VPN
-	if err := o.configureWireGuard(); err != nil {
+	if err := o.runPhase("wireguard", o.configureWireGuard); err != nil {
 		return fmt.Errorf("failed to configure WireGuard: %w", err)
 	}
 
 	// Phase 7: Configure cloud provider firewalls
-	if err := o.configureFirewalls(); err != nil {
+	if err := o.runPhase("firewalls", o.configureFirewalls); err != nil {
 		return fmt.Errorf("failed to configure firewalls: %w", err)
 	}
 
+	// Phase 7.2: Front the control plane of an HA cluster with a load
+	// balancer, before RKE so its address is in the API certificate
+	if o.config.Cluster.HighAvailability {
+		if err := o.runPhase("api-load-balancer", o.createAPILoadBalancer); err != nil {
+			return fmt.Errorf("failed to create API load balancer: %w", err)
+		}
+	}
+
 	// Phase 7.5: CRITICAL - Verify VPN connectivity before RKE
 	// This MUST pass before RKE deployment or the cluster will fail
 	if o.config.Network.WireGuard != nil && o.config.Network.WireGuard.Enabled {
@@ -98,7 +119,7 @@ func (o *Orchestrator) Deploy() error {
 		o.ctx.Log.Info("RKE requires all nodes to communicate via private network", nil)
 		o.ctx.Log.Info("Checking full mesh connectivity before proceeding...", nil)
 
-		if err := o.verifyVPNReadyForRKE(); err != nil {
+		if err := o.runPhase("vpn-verification", o.verifyVPNReadyForRKE); err != nil {
 			return fmt.Errorf("VPN not ready for RKE deployment: %w", err)
 		}
 
@@ -108,27 +129,50 @@ func (o *Orchestrator) Deploy() error {
 	}
 
 	// Phase 8: Deploy RKE cluster
-	if err := o.deployRKE(); err != nil {
+	if err := o.runPhase("rke", o.deployRKE); err != nil {
 		return fmt.Errorf("failed to deploy RKE: %w", err)
 	}
 
 	// Phase 9: Install NGINX Ingress
-	if err := o.installIngress(); err != nil {
+	if err := o.runPhase("ingress", o.installIngress); err != nil {
 		return fmt.Errorf("failed to install ingress: %w", err)
 	}
 
 	// Phase 10: Install addons
-	if err := o.installAddons(); err != nil {
+	if err := o.runPhase("addons", o.installAddons); err != nil {
 		return fmt.Errorf("failed to install addons: %w", err)
 	}
 
 	// Phase 11: Export outputs
-	o.exportOutputs()
+	_ = o.runPhase("outputs", func() error {
+		o.exportOutputs()
+		return nil
+	})
 
 	o.ctx.Log.Info("Kubernetes cluster deployment completed successfully", nil)
 	return nil
 }
 
+// runPhase runs a deployment phase, recording its start and completion as
+// events when logging JSON
+func (o *Orchestrator) runPhase(phase string, run func() error) error {
+	return startPhase(phase)(run())
+}
+
+// startPhase records the start of a deployment phase and returns the
+// function recording its completion, which passes the phase's error through.
+// Component phases complete once their resources are registered; Pulumi
+// creates them afterwards.
+func startPhase(phase string) func(err error) error {
+	logger := logging.Default()
+	logger.PhaseStart(phase)
+	start := time.Now()
+	return func(err error) error {
+		logger.PhaseComplete(phase, time.Since(start), err)
+		return err
+	}
+}
+
 // generateSSHKeys generates SSH keys for the cluster
 func (o *Orchestrator) generateSSHKeys() error {
 	o.ctx.Log.Info("Generating SSH keys for cluster", nil)
@@ -148,6 +192,9 @@ func (o *Orchestrator) generateSSHKeys() error {
 	if o.config.Providers.Linode != nil {
 		o.config.Providers.Linode.SSHPublicKey = publicKey
 	}
+	if o.config.Providers.Vultr != nil {
+		o.config.Providers.Vultr.SSHPublicKey = publicKey
+	}
 	// Note: Azure SSH key is handled directly in node_deployment.go via sshKeyOutput parameter
 
 	return nil
@@ -207,6 +254,16 @@ func (o *Orchestrator) initializeProviders() error {
 		o.ctx.Log.Info("✓ GCP provider initialized", nil)
 	}
 
+	// Initialize Vultr provider
+	if o.config.Providers.Vultr != nil && o.config.Providers.Vultr.Enabled {
+		vultrProvider := providers.NewVultrProvider()
+		if err := vultrProvider.Initialize(o.ctx, o.config); err != nil {
+			return fmt.Errorf("failed to initialize Vultr provider: %w", err)
+		}
+		o.providerRegistry.Register("vultr", vultrProvider)
+		o.ctx.Log.Info("✓ Vultr provider initialized", nil)
+	}
+
 	// Verify at least one provider is enabled
 	if len(o.providerRegistry.GetAll()) == 0 {
 		return fmt.Errorf("no cloud providers enabled")
@@ -275,6 +332,13 @@ func (o *Orchestrator) deployNodes() error {
 		}
 	}
 
+	// Bound the wait for the API server and nodes after install
+	readyTimeout, readyInterval, err := o.config.Kubernetes.KubernetesReadyWait()
+	if err != nil {
+		return err
+	}
+	o.healthChecker.SetKubernetesReadyWait(readyTimeout, readyInterval)
+
 	// Set SSH key path if available
 	if o.sshKeyManager != nil {
 		sshKeyPath := fmt.Sprintf("~/.ssh/kubernetes-clusters/%s.pem", o.ctx.Stack())
@@ -329,88 +393,132 @@ func (o *Orchestrator) deployNodePool(poolName string, poolConfig *config.NodePo
 	return nil
 }
 
-// verifyNodeDistribution verifies the node distribution matches requirements
-func (o *Orchestrator) verifyNodeDistribution() error {
-	totalNodes := 0
-	masterNodes := 0
-	workerNodes := 0
-
-	doNodes := 0
-	linodeNodes := 0
+// nodeRoleCount counts nodes by role. A node with both the control plane
+// and worker roles counts toward Masters and Workers alike.
+type nodeRoleCount struct {
+	Total   int
+	Masters int
+	Workers int
+}
 
-	for provider, nodes := range o.nodes {
-		for _, node := range nodes {
-			totalNodes++
-
-			// Count by provider
-			switch provider {
-			case "digitalocean":
-				doNodes++
-			case "linode":
-				linodeNodes++
+// countDeployedNodes counts the deployed nodes by role
+func countDeployedNodes(nodes map[string][]*providers.NodeOutput) nodeRoleCount {
+	var count nodeRoleCount
+	for _, providerNodes := range nodes {
+		for _, node := range providerNodes {
+			count.Total++
+			if node.HasRole(config.RoleMaster) {
+				count.Masters++
 			}
-
-			// Count by role
-			if node.Labels != nil {
-				if role, ok := node.Labels["role"]; ok {
-					switch role {
-					case "master", "controlplane":
-						masterNodes++
-					case "worker":
-						workerNodes++
-					}
-				}
+			if node.HasRole(config.RoleWorker) {
+				count.Workers++
 			}
 		}
 	}
+	return count
+}
 
-	// Calculate expected nodes from configuration
-	expectedTotal := 0
-	expectedMasters := 0
-	expectedWorkers := 0
+// countExpectedNodes counts the nodes the config defines by role, the same
+// way the config was validated
+func countExpectedNodes(cfg *config.ClusterConfig) nodeRoleCount {
+	dist := validation.CalculateDistribution(cfg)
+	return nodeRoleCount{Total: dist.Total, Masters: dist.Masters, Workers: dist.Workers}
+}
 
-	for _, pool := range o.config.NodePools {
-		expectedTotal += pool.Count
-		for _, role := range pool.Roles {
-			if role == "master" || role == "controlplane" {
-				expectedMasters += pool.Count
-			} else if role == "worker" {
-				expectedWorkers += pool.Count
-			}
-		}
+// checkNodeDistribution compares the deployed node counts with the expected
+func checkNodeDistribution(deployed, expected nodeRoleCount) error {
+	if deployed.Total != expected.Total {
+		return fmt.Errorf("expected %d nodes, got %d", expected.Total, deployed.Total)
+	}
+
+	if deployed.Masters != expected.Masters {
+		return fmt.Errorf("expected %d master nodes, got %d", expected.Masters, deployed.Masters)
 	}
 
-	// Verify distribution
-	if totalNodes != expectedTotal {
-		return fmt.Errorf("expected %d nodes, got %d", expectedTotal, totalNodes)
+	if deployed.Workers != expected.Workers {
+		return fmt.Errorf("expected %d worker nodes, got %d", expected.Workers, deployed.Workers)
 	}
 
-	if masterNodes != expectedMasters {
-		return fmt.Errorf("expected %d master nodes, got %d", expectedMasters, masterNodes)
+	return nil
+}
+
+// countNodesByZone counts the deployed nodes per location, keyed by
+// config.ZoneKey
+func countNodesByZone(nodes map[string][]*providers.NodeOutput) map[string]int {
+	byZone := make(map[string]int)
+	for _, providerNodes := range nodes {
+		for _, node := range providerNodes {
+			byZone[config.ZoneKey(node.Region, node.Zone)]++
+		}
 	}
+	return byZone
+}
 
-	if workerNodes != expectedWorkers {
-		return fmt.Errorf("expected %d worker nodes, got %d", expectedWorkers, workerNodes)
+// verifyNodeDistribution verifies the node distribution matches requirements
+// and exports the per-zone breakdown as node_zones
+func (o *Orchestrator) verifyNodeDistribution() error {
+	deployed := countDeployedNodes(o.nodes)
+	if err := checkNodeDistribution(deployed, countExpectedNodes(o.config)); err != nil {
+		return err
 	}
 
-	o.ctx.Log.Info(fmt.Sprintf("Node distribution verified: %d total (%d masters, %d workers)", totalNodes, masterNodes, workerNodes), nil)
+	o.ctx.Log.Info(fmt.Sprintf("Node distribution verified: %d total (%d masters, %d workers)", deployed.Total, deployed.Masters, deployed.Workers), nil)
+
+	byZone := countNodesByZone(o.nodes)
+	keys := make([]string, 0, len(byZone))
+	for zone := range byZone {
+		keys = append(keys, zone)
+	}
+	sort.Strings(keys)
+	for _, zone := range keys {
+		o.ctx.Log.Info(fmt.Sprintf("  %s: %d node(s)", zone, byZone[zone]), nil)
+	}
+	exportNodeZones(o.ctx, byZone)
 
 	return nil
 }
 
+// exportNodeZones exports the per-zone node breakdown as node_zones, keyed by
+// region/zone or only the region
+func exportNodeZones(ctx *pulumi.Context, byZone map[string]int) {
+	zones := pulumi.IntMap{}
+	for zone, count := range byZone {
+		zones[zone] = pulumi.Int(count)
+	}
+	ctx.Export("node_zones", zones)
+}
+
 // configureDNS configures DNS records for all nodes
 func (o *Orchestrator) configureDNS() error {
-	// Check if we have a domain configured
-	domain := o.config.Network.DNS.Domain
-	if domain == "" {
-		// Default to chalkan3.com.br if not configured
-		domain = "chalkan3.com.br"
-	}
+	domain := o.dnsDomain()
 
 	o.ctx.Log.Info("Configuring DNS records", nil)
 
 	o.dnsManager = dns.NewManager(o.ctx, domain)
 
+	// Cloudflare records are managed through its API; any other provider
+	// keeps the DigitalOcean records
+	if dnsConfig := &o.config.Network.DNS; dnsConfig.UsesCloudflare() {
+		var zoneID string
+		var proxied bool
+		if cf := dnsConfig.Cloudflare; cf != nil {
+			zoneID, proxied = cf.ZoneID, cf.Proxied
+		}
+		zone, err := dns.NewCloudflareZoneProvider(dnsConfig.CloudflareAPIToken(), zoneID, proxied)
+		if err != nil {
+			return fmt.Errorf("failed to configure Cloudflare DNS: %w", err)
+		}
+		o.dnsManager.SetZoneProvider(zone)
+	} else if o.config.Providers.DigitalOcean != nil && o.config.Providers.DigitalOcean.Token != "" {
+		// Look up existing records so re-deploys update them instead of conflicting
+		recordProvider, err := dns.NewDigitalOceanRecordProvider(o.config.Providers.DigitalOcean.Token)
+		if err != nil {
+			o.ctx.Log.Warn(fmt.Sprintf("DNS record lookup disabled: %v", err), nil)
+		} else {
+			o.dnsManager.SetRecordProvider(recordProvider)
+		}
+	}
+
 	// Create DNS records for all nodes
 	if err := o.dnsManager.CreateNodeRecords(o.nodes); err != nil {
 		return fmt.Errorf("failed to create node DNS records: %w", err)
@@ -427,6 +535,15 @@ func (o *Orchestrator) configureDNS() error {
 	return nil
 }
 
+// dnsDomain returns the configured DNS domain
+func (o *Orchestrator) dnsDomain() string {
+	if o.config.Network.DNS.Domain == "" {
+		// Default to chalkan3.com.br if not configured
+		return "chalkan3.com.br"
+	}
+	return o.config.Network.DNS.Domain
+}
+
 // configureWireGuard configures WireGuard VPN on all nodes
 func (o *Orchestrator) configureWireGuard() error {
 	if o.config.Network.WireGuard == nil || !o.config.Network.WireGuard.Enabled {
@@ -459,7 +576,7 @@ func (o *Orchestrator) configureWireGuard() error {
 
 	// Initialize VPN connectivity checker
 	o.ctx.Log.Info("Initializing VPN connectivity verification", nil)
-	o.vpnChecker = network.NewVPNConnectivityChecker(o.ctx)
+	o.vpnChecker = o.newVPNChecker()
 
 	// Add all nodes to VPN checker
 	for _, nodes := range o.nodes {
@@ -484,7 +601,11 @@ func (o *Orchestrator) configureWireGuard() error {
 	o.ctx.Log.Info("Verifying full mesh VPN connectivity between all nodes", nil)
 	o.ctx.Log.Info("This ensures every node can reach every other node via WireGuard", nil)
 
-	if err := o.vpnChecker.VerifyFullMeshConnectivity(); err != nil {
+	progress := newMeshProgressReporter(o.ctx)
+	o.vpnChecker.SetProgressFunc(progress.Update)
+	err := o.vpnChecker.VerifyFullMeshConnectivity()
+	progress.Stop()
+	if err != nil {
 		// Print connectivity matrix to help debug
 		o.vpnChecker.PrintConnectivityMatrix()
 		return fmt.Errorf("VPN connectivity verification failed: %w", err)
@@ -535,6 +656,12 @@ func (o *Orchestrator) deployRKE() error {
 	o.ctx.Log.Info("All prerequisites validated, deploying RKE cluster", nil)
 
 	o.rkeManager = cluster.NewRKEManager(o.ctx, &o.config.Kubernetes)
+	if grafana := o.config.Monitoring.Grafana; grafana != nil {
+		o.rkeManager.SetGrafanaAdminPassword(grafana.AdminPassword)
+	}
+	for _, san := range o.apiLoadBalancerSANs() {
+		o.rkeManager.AddTLSSan(san)
+	}
 
 	// Add all nodes to RKE manager
 	for _, nodes := range o.nodes {
@@ -559,9 +686,16 @@ func (o *Orchestrator) deployRKE() error {
 	return nil
 }
 
-// installIngress installs NGINX Ingress Controller
+// installIngress installs the configured ingress controller
 func (o *Orchestrator) installIngress() error {
-	o.ctx.Log.Info("Preparing to install NGINX Ingress Controller", nil)
+	// The controller is installed from the first master
+	masters := o.GetMasterNodes()
+	if len(masters) == 0 {
+		return fmt.Errorf("no master node available for ingress installation")
+	}
+
+	controller := o.config.Network.Ingress.ControllerName()
+	o.ctx.Log.Info(fmt.Sprintf("Preparing to install the %s ingress controller", controller), nil)
 
 	// Collect all nodes for validation
 	allNodes := []*providers.NodeOutput{}
@@ -583,7 +717,7 @@ func (o *Orchestrator) installIngress() error {
 		return fmt.Errorf("Kubernetes cluster not ready for Ingress installation: %w", err)
 	}
 
-	o.ctx.Log.Info("All prerequisites validated, installing NGINX Ingress Controller", nil)
+	o.ctx.Log.Info(fmt.Sprintf("All prerequisites validated, installing the %s ingress controller", controller), nil)
 
 	// Get domain for ingress
 	domain := o.config.Network.DNS.Domain
@@ -591,16 +725,21 @@ func (o *Orchestrator) installIngress() error {
 		domain = "chalkan3.com.br"
 	}
 
-	// Create ingress manager
-	o.ingressManager = ingress.NewNginxIngressManager(o.ctx, domain)
-
-	// Get first master node
-	masterNode := o.GetMasterNodes()[0]
-	if masterNode == nil {
-		return fmt.Errorf("no master node available for ingress installation")
+	// Create the manager of the configured controller
+	ingressManager, err := ingress.NewController(o.ctx, &o.config.Network.Ingress, domain)
+	if err != nil {
+		return err
 	}
+	o.ingressManager = ingressManager
+
+	o.ingressManager.SetMasterNode(masters[0])
 
-	o.ingressManager.SetMasterNode(masterNode)
+	// A custom certificate replaces cert-manager
+	if o.config.Security.TLS.HasCustomCert() {
+		if err := o.ingressManager.UseCustomCertificate(&o.config.Security.TLS); err != nil {
+			return fmt.Errorf("invalid custom ingress certificate: %w", err)
+		}
+	}
 
 	// Set SSH key path if available
 	if o.sshKeyManager != nil {
@@ -608,16 +747,16 @@ func (o *Orchestrator) installIngress() error {
 		o.ingressManager.SetSSHKeyPath(sshKeyPath)
 	}
 
-	// Install NGINX Ingress
+	// Install the ingress controller
 	ingressIP, err := o.ingressManager.Install()
 	if err != nil {
-		return fmt.Errorf("failed to install NGINX Ingress: %w", err)
+		return fmt.Errorf("failed to install the %s ingress controller: %w", controller, err)
 	}
 
 	// Wait for Ingress to be ready
-	o.ctx.Log.Info("Waiting for NGINX Ingress Controller to be ready", nil)
+	o.ctx.Log.Info(fmt.Sprintf("Waiting for the %s ingress controller to be ready", controller), nil)
 	if err := o.healthChecker.WaitForIngressReady(); err != nil {
-		return fmt.Errorf("NGINX Ingress Controller failed to become ready: %w", err)
+		return fmt.Errorf("%s ingress controller failed to become ready: %w", controller, err)
 	}
 
 	// Update DNS records with actual ingress IP
@@ -627,15 +766,19 @@ func (o *Orchestrator) installIngress() error {
 		}
 	}
 
-	// Install cert-manager for TLS
-	if err := o.ingressManager.InstallCertManager(); err != nil {
+	// Serve TLS from the custom certificate, or install cert-manager
+	if o.ingressManager.UsesCustomCertificate() {
+		if err := o.ingressManager.CreateTLSSecret(); err != nil {
+			return err
+		}
+	} else if err := o.ingressManager.InstallCertManager(); err != nil {
 		o.ctx.Log.Warn("Failed to install cert-manager", nil)
 	}
 
 	// Create sample ingress
 	o.ingressManager.CreateSampleIngress()
 
-	o.ctx.Log.Info("NGINX Ingress Controller installed successfully", nil)
+	o.ctx.Log.Info(fmt.Sprintf("%s ingress controller installed successfully", controller), nil)
 
 	return nil
 }
@@ -691,6 +834,66 @@ func (o *Orchestrator) installLoadBalancers() error {
 	return nil
 }
 
+// createAPILoadBalancer creates the TCP load balancer in front of the masters
+// of an HA cluster on 6443 and the RKE2 supervisor port, and exports it as
+// the API endpoint. Masters of other providers are not behind it.
+func (o *Orchestrator) createAPILoadBalancer() error {
+	lbConfig := o.config.APILoadBalancer()
+	if lbConfig == nil {
+		return fmt.Errorf("highAvailability requires master nodes to put behind the API load balancer")
+	}
+
+	provider, ok := o.providerRegistry.Get(lbConfig.Provider)
+	if !ok {
+		return fmt.Errorf("provider %s not found for API load balancer", lbConfig.Provider)
+	}
+
+	var targets, others []string
+	for providerName, nodes := range o.nodes {
+		for _, node := range nodes {
+			if !node.HasRole(config.RoleMaster) {
+				continue
+			}
+			if providerName == lbConfig.Provider {
+				targets = append(targets, node.Name)
+			} else {
+				others = append(others, node.Name)
+			}
+		}
+	}
+	sort.Strings(targets)
+	sort.Strings(others)
+	if len(others) > 0 {
+		o.ctx.Log.Warn(fmt.Sprintf("API load balancer on %s cannot reach masters %s of other providers", lbConfig.Provider, strings.Join(others, ", ")), nil)
+	}
+
+	lb, err := provider.CreateLoadBalancer(o.ctx, lbConfig)
+	if err != nil {
+		return fmt.Errorf("failed to create load balancer %s: %w", lbConfig.Name, err)
+	}
+	o.apiLoadBalancer = lb
+
+	o.ctx.Export("api_load_balancer_ip", lb.IP)
+	o.ctx.Export("apiEndpoint", pulumi.Sprintf("https://%s:%d", lb.IP, config.APIServerPort))
+	o.ctx.Log.Info(fmt.Sprintf("API load balancer %s on %s in front of %s", lbConfig.Name, lbConfig.Provider, strings.Join(targets, ", ")), nil)
+
+	return nil
+}
+
+// apiLoadBalancerSANs returns the IP and, when the provider gives one, the
+// hostname of the API load balancer, for the API server certificate
+func (o *Orchestrator) apiLoadBalancerSANs() []pulumi.StringOutput {
+	lb := o.apiLoadBalancer
+	if lb == nil {
+		return nil
+	}
+	sans := []pulumi.StringOutput{lb.IP}
+	if lb.Hostname.OutputState != nil {
+		sans = append(sans, lb.Hostname)
+	}
+	return sans
+}
+
 // exportOutputs exports all cluster outputs
 func (o *Orchestrator) exportOutputs() {
 	o.ctx.Log.Info("Exporting cluster outputs", nil)
@@ -771,9 +974,13 @@ func (o *Orchestrator) exportOutputs() {
 	}
 
 	// Export access information
+	apiEndpoint := pulumi.String("https://10.8.0.11:6443").ToStringOutput() // Master 1 WireGuard IP
+	if o.apiLoadBalancer != nil {
+		apiEndpoint = pulumi.Sprintf("https://%s:%d", o.apiLoadBalancer.IP, config.APIServerPort)
+	}
 	o.ctx.Export("access_info", pulumi.Map{
 		"wireguard_required": pulumi.Bool(o.config.Network.WireGuard != nil && o.config.Network.WireGuard.Enabled),
-		"api_endpoint":       pulumi.String(fmt.Sprintf("https://10.8.0.11:6443")), // Master 1 WireGuard IP
+		"api_endpoint":       apiEndpoint,
 		"ssh_user":           pulumi.String("root"),
 	})
 }
@@ -817,10 +1024,8 @@ func (o *Orchestrator) GetMasterNodes() []*providers.NodeOutput {
 	masters := []*providers.NodeOutput{}
 	for _, nodes := range o.nodes {
 		for _, node := range nodes {
-			if node.Labels != nil {
-				if role, ok := node.Labels["role"]; ok && (role == "master" || role == "controlplane") {
-					masters = append(masters, node)
-				}
+			if node.HasRole(config.RoleMaster) {
+				masters = append(masters, node)
 			}
 		}
 	}
@@ -832,21 +1037,34 @@ func (o *Orchestrator) GetWorkerNodes() []*providers.NodeOutput {
 	workers := []*providers.NodeOutput{}
 	for _, nodes := range o.nodes {
 		for _, node := range nodes {
-			if node.Labels != nil {
-				if role, ok := node.Labels["role"]; ok && role == "worker" {
-					workers = append(workers, node)
-				}
+			if node.HasRole(config.RoleWorker) {
+				workers = append(workers, node)
 			}
 		}
 	}
 	return workers
 }
 
+// newVPNChecker creates the VPN connectivity checker with the mesh
+// verification settings from the WireGuard config
+func (o *Orchestrator) newVPNChecker() *network.VPNConnectivityChecker {
+	checker := network.NewVPNConnectivityChecker(o.ctx)
+	if wg := o.config.Network.WireGuard; wg != nil {
+		if wg.MeshCheckConcurrency > 0 {
+			checker.SetConcurrency(wg.MeshCheckConcurrency)
+		}
+		if wg.MeshCheckAttempts > 0 {
+			checker.SetMaxAttempts(wg.MeshCheckAttempts)
+		}
+	}
+	return checker
+}
+
 // verifyVPNReadyForRKE performs comprehensive VPN verification before RKE deployment
 func (o *Orchestrator) verifyVPNReadyForRKE() error {
 	// Initialize VPN checker if not already done
 	if o.vpnChecker == nil {
-		o.vpnChecker = network.NewVPNConnectivityChecker(o.ctx)
+		o.vpnChecker = o.newVPNChecker()
 
 		// Add all nodes to VPN checker
 		for _, nodes := range o.nodes {
@@ -942,9 +1160,9 @@ func (o *Orchestrator) verifyVPNReadyForRKE() error {
 		allNodes = append(allNodes, nodes...)
 	}
 
-	// Ensure we have the expected number of nodes
-	if len(allNodes) != 6 {
-		return fmt.Errorf("expected 6 nodes for VPN mesh, found %d", len(allNodes))
+	// Ensure every configured node is in the mesh
+	if expected := countExpectedNodes(o.config).Total; len(allNodes) != expected {
+		return fmt.Errorf("expected %d nodes for VPN mesh, found %d", expected, len(allNodes))
 	}
 
 	o.ctx.Log.Info("✓ All VPN checks passed", nil)
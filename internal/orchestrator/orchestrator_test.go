@@ -0,0 +1,248 @@
+package orchestrator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/logging"
+	"github.com/chalkan3/sloth-kubernetes/pkg/providers"
+)
+
+func TestCountDeployedNodes_MixedRoles(t *testing.T) {
+	nodes := map[string][]*providers.NodeOutput{
+		"digitalocean": {
+			{Name: "combined-1", Roles: []string{"controlplane", "worker"}},
+			{Name: "combined-2", Roles: []string{"worker", "master"}},
+			{Name: "master-1", Roles: []string{"master"}},
+		},
+		"linode": {
+			{Name: "worker-1", Roles: []string{"worker"}},
+			{Name: "labelled", Labels: map[string]string{"role": "controlplane, worker"}},
+		},
+	}
+
+	count := countDeployedNodes(nodes)
+	if count != (nodeRoleCount{Total: 5, Masters: 4, Workers: 4}) {
+		t.Errorf("Expected mixed-role nodes to count as masters and workers, got %+v", count)
+	}
+}
+
+func TestCountExpectedNodes_MixedRoles(t *testing.T) {
+	pools := map[string]config.NodePool{
+		"combined": {Count: 3, Roles: []string{"controlplane", "worker"}},
+		"workers":  {Count: 2, Roles: []string{"worker"}},
+		"aliases":  {Count: 1, Roles: []string{"master", "controlplane"}},
+	}
+
+	count := countExpectedNodes(&config.ClusterConfig{NodePools: pools})
+	if count != (nodeRoleCount{Total: 6, Masters: 4, Workers: 5}) {
+		t.Errorf("Expected each pool counted once per role, got %+v", count)
+	}
+}
+
+func TestCheckNodeDistribution(t *testing.T) {
+	pools := map[string]config.NodePool{
+		"combined": {Count: 1, Roles: []string{"controlplane", "worker"}},
+	}
+	deployed := map[string][]*providers.NodeOutput{
+		"digitalocean": {{Name: "combined-1", Roles: []string{"controlplane", "worker"}}},
+	}
+
+	if err := checkNodeDistribution(countDeployedNodes(deployed), countExpectedNodes(&config.ClusterConfig{NodePools: pools})); err != nil {
+		t.Errorf("Expected a single controlplane+worker node to match its pool, got %v", err)
+	}
+
+	deployed["digitalocean"][0].Roles = []string{"controlplane"}
+	err := checkNodeDistribution(countDeployedNodes(deployed), countExpectedNodes(&config.ClusterConfig{NodePools: pools}))
+	if err == nil || !strings.Contains(err.Error(), "expected 1 worker nodes, got 0") {
+		t.Errorf("Expected a missing worker role to be reported, got %v", err)
+	}
+}
+
+func TestInstallIngress_NoMasterNodes(t *testing.T) {
+	o := &Orchestrator{
+		config: &config.ClusterConfig{},
+		nodes: map[string][]*providers.NodeOutput{
+			"digitalocean": {{Name: "worker-1", Roles: []string{"worker"}}},
+		},
+	}
+
+	err := o.installIngress()
+	if err == nil || !strings.Contains(err.Error(), "no master node available") {
+		t.Errorf("Expected an error for a stack without masters, got %v", err)
+	}
+}
+
+// resourceCounter counts the Pulumi resources a program registers
+type resourceCounter struct {
+	pulumi.MockResourceMonitor
+	created int
+}
+
+func (m *resourceCounter) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	m.created++
+	return args.Name + "_id", args.Inputs, nil
+}
+
+func (m *resourceCounter) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	return resource.PropertyMap{}, nil
+}
+
+func TestDeploy_DryRunCreatesNoResources(t *testing.T) {
+	cfg := &config.ClusterConfig{
+		Providers: config.ProvidersConfig{
+			DigitalOcean: &config.DigitalOceanProvider{Enabled: true},
+			Linode:       &config.LinodeProvider{Enabled: true},
+		},
+		Network: config.NetworkConfig{
+			CIDR:        "10.0.0.0/16",
+			PodCIDR:     "10.42.0.0/16",
+			ServiceCIDR: "10.43.0.0/16",
+			DNS:         config.DNSConfig{Domain: "example.com"},
+		},
+		NodePools: map[string]config.NodePool{
+			"masters": {Provider: "digitalocean", Count: 1, Roles: []string{"master"}},
+			"workers": {Provider: "linode", Count: 2, Roles: []string{"worker"}},
+		},
+	}
+
+	mocks := &resourceCounter{}
+	var plan *DeployPlan
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		if err := New(ctx, cfg, true).Deploy(); err != nil {
+			return err
+		}
+		var err error
+		plan, err = New(ctx, cfg, true).Plan()
+		return err
+	}, pulumi.WithMocks("project", "stack", mocks))
+	if err != nil {
+		t.Fatalf("Dry run failed: %v", err)
+	}
+	if mocks.created != 0 {
+		t.Errorf("Expected no resources created, got %d", mocks.created)
+	}
+
+	if len(plan.Providers) != 2 || plan.Providers[0] != "digitalocean" {
+		t.Errorf("Expected both providers planned, got %v", plan.Providers)
+	}
+	if len(plan.NodeIPs) != 3 || plan.NodeIPs[0] != (NodeIPPlan{Node: "masters-1", IP: "10.0.0.3"}) {
+		t.Errorf("Expected an IP allocated per node, got %+v", plan.NodeIPs)
+	}
+	if len(plan.FirewallRules) == 0 {
+		t.Error("Expected the Kubernetes firewall rules planned")
+	}
+	summary := plan.String()
+	for _, want := range []string{"api.example.com", "worker2-linode.example.com", "workers (linode): 2 x worker"} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Expected %q in the plan, got:\n%s", want, summary)
+		}
+	}
+}
+
+func TestPlan_APILoadBalancer(t *testing.T) {
+	cfg := &config.ClusterConfig{
+		Metadata:  config.Metadata{Name: "prod"},
+		Cluster:   config.ClusterSpec{HighAvailability: true},
+		Providers: config.ProvidersConfig{DigitalOcean: &config.DigitalOceanProvider{Enabled: true}},
+		NodePools: map[string]config.NodePool{
+			"masters": {Provider: "digitalocean", Count: 3, Roles: []string{"master"}},
+			"workers": {Provider: "digitalocean", Count: 2, Roles: []string{"worker"}},
+		},
+	}
+
+	var plan *DeployPlan
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		var err error
+		plan, err = New(ctx, cfg, true).Plan()
+		return err
+	}, pulumi.WithMocks("project", "stack", &resourceCounter{}))
+	if err != nil {
+		t.Fatalf("Plan failed: %v", err)
+	}
+
+	if plan.APILoadBalancer == nil || plan.APILoadBalancer.Provider != "digitalocean" {
+		t.Fatalf("Expected an API load balancer on digitalocean, got %+v", plan.APILoadBalancer)
+	}
+	if want := "API load balancer: prod-api (digitalocean) on tcp 6443, 9345 to the masters"; !strings.Contains(plan.String(), want) {
+		t.Errorf("Expected %q in the plan, got:\n%s", want, plan.String())
+	}
+}
+
+func TestPlan_RunsValidation(t *testing.T) {
+	cfg := &config.ClusterConfig{
+		Providers: config.ProvidersConfig{DigitalOcean: &config.DigitalOceanProvider{Enabled: true}},
+		Network:   config.NetworkConfig{CIDR: "10.0.0.0/8", PodCIDR: "10.42.0.0/16"},
+		NodePools: map[string]config.NodePool{
+			"masters": {Provider: "digitalocean", Count: 1, Roles: []string{"master"}},
+		},
+	}
+
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		_, err := New(ctx, cfg, true).Plan()
+		return err
+	}, pulumi.WithMocks("project", "stack", &resourceCounter{}))
+	if err == nil || !strings.Contains(err.Error(), "overlap") {
+		t.Errorf("Expected the CIDR overlap to fail the plan, got %v", err)
+	}
+}
+
+func TestCountNodesByZone(t *testing.T) {
+	nodes := map[string][]*providers.NodeOutput{
+		"azure": {
+			{Name: "a-1", Region: "eastus", Zone: "1"},
+			{Name: "a-2", Region: "eastus", Zone: "2"},
+			{Name: "a-3", Region: "eastus", Zone: "1"},
+		},
+		"linode": {{Name: "l-1", Region: "us-east"}},
+	}
+
+	byZone := countNodesByZone(nodes)
+	if byZone["eastus/1"] != 2 || byZone["eastus/2"] != 1 || byZone["us-east"] != 1 || len(byZone) != 3 {
+		t.Errorf("Unexpected breakdown %v", byZone)
+	}
+}
+
+func TestRunPhase_LogsPhaseEvents(t *testing.T) {
+	original := logging.Default()
+	t.Cleanup(func() { logging.SetDefault(original) })
+	var buf bytes.Buffer
+	logging.SetDefault(logging.New(&buf, logging.FormatJSON))
+
+	o := &Orchestrator{}
+	if err := o.runPhase("networking", func() error { return nil }); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	failure := errors.New("quota exceeded")
+	if err := o.runPhase("nodes", func() error { return failure }); err != failure {
+		t.Errorf("Expected the phase error returned, got %v", err)
+	}
+
+	var events []logging.Event
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var event logging.Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Expected JSON events, got %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+
+	var got []string
+	for _, event := range events {
+		got = append(got, event.Event+" "+event.Phase)
+	}
+	want := "phase-start networking,phase-complete networking,phase-start nodes,phase-failed nodes"
+	if strings.Join(got, ",") != want {
+		t.Errorf("Unexpected events:\n got: %s\nwant: %s", strings.Join(got, ","), want)
+	}
+	if events[3].Error != "quota exceeded" {
+		t.Errorf("Expected the failure on the event, got %+v", events[3])
+	}
+}
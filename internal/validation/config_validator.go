@@ -1,7 +1,11 @@
 package validation
 
 import (
+	"encoding/base64"
 	"fmt"
+	"net"
+	"strconv"
+	"strings"
 
 	"github.com/chalkan3/sloth-kubernetes/pkg/config"
 )
@@ -54,6 +58,41 @@ func ValidateWireGuardConfig(cfg *config.ClusterConfig) error {
 		return fmt.Errorf("WireGuard server public key is required when using existing VPN server")
 	}
 
+	if err := validateWireGuardEndpoint(cfg.Network.WireGuard.ServerEndpoint); err != nil {
+		return err
+	}
+
+	if err := validateWireGuardKey(cfg.Network.WireGuard.ServerPublicKey); err != nil {
+		return fmt.Errorf("invalid WireGuard server public key: %w", err)
+	}
+
+	return nil
+}
+
+// validateWireGuardEndpoint checks an endpoint of the form host or host:port
+func validateWireGuardEndpoint(endpoint string) error {
+	host := endpoint
+	if h, port, err := net.SplitHostPort(endpoint); err == nil {
+		p, err := strconv.Atoi(port)
+		if err != nil || p < 1 || p > 65535 {
+			return fmt.Errorf("invalid WireGuard server endpoint port: %s", endpoint)
+		}
+		host = h
+	}
+
+	if host == "" || strings.ContainsAny(host, " /") {
+		return fmt.Errorf("invalid WireGuard server endpoint: %s (expected host or host:port)", endpoint)
+	}
+
+	return nil
+}
+
+// validateWireGuardKey checks that key is a base64-encoded 32-byte WireGuard key
+func validateWireGuardKey(key string) error {
+	decoded, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(decoded) != 32 {
+		return fmt.Errorf("expected a base64-encoded 32-byte key (as printed by wg pubkey)")
+	}
 	return nil
 }
 
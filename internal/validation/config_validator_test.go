@@ -182,12 +182,56 @@ func TestValidateWireGuardConfig(t *testing.T) {
 						Enabled:         true,
 						Create:          false,
 						ServerEndpoint:  "1.2.3.4:51820",
-						ServerPublicKey: "test-public-key",
+						ServerPublicKey: "YWJjZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXphYmNkZWY=",
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "Valid - Existing VPN with hostname endpoint",
+			config: &config.ClusterConfig{
+				Network: config.NetworkConfig{
+					WireGuard: &config.WireGuardConfig{
+						Enabled:         true,
+						Create:          false,
+						ServerEndpoint:  "vpn.example.com",
+						ServerPublicKey: "YWJjZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXphYmNkZWY=",
 					},
 				},
 			},
 			wantErr: false,
 		},
+		{
+			name: "Invalid - Existing VPN with malformed public key",
+			config: &config.ClusterConfig{
+				Network: config.NetworkConfig{
+					WireGuard: &config.WireGuardConfig{
+						Enabled:         true,
+						Create:          false,
+						ServerEndpoint:  "1.2.3.4:51820",
+						ServerPublicKey: "test-public-key",
+					},
+				},
+			},
+			wantErr:       true,
+			errorContains: "invalid WireGuard server public key",
+		},
+		{
+			name: "Invalid - Existing VPN with bad endpoint port",
+			config: &config.ClusterConfig{
+				Network: config.NetworkConfig{
+					WireGuard: &config.WireGuardConfig{
+						Enabled:         true,
+						Create:          false,
+						ServerEndpoint:  "1.2.3.4:99999",
+						ServerPublicKey: "YWJjZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXphYmNkZWY=",
+					},
+				},
+			},
+			wantErr:       true,
+			errorContains: "invalid WireGuard server endpoint port",
+		},
 		{
 			name: "Invalid - WireGuard not enabled",
 			config: &config.ClusterConfig{
@@ -2,12 +2,17 @@ package validation
 
 import (
 	"context"
+	goerrors "errors"
 	"fmt"
+	"net/http"
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
+	"github.com/chalkan3/sloth-kubernetes/internal/common"
 	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
 	"github.com/digitalocean/godo"
 	"github.com/linode/linodego"
 	"golang.org/x/oauth2"
@@ -46,6 +51,63 @@ func ValidateForDeployment(cfg *config.ClusterConfig) error {
 		return fmt.Errorf("resource size validation failed: %w", err)
 	}
 
+	// 7. Validate kernel tuning (sysctls and modules)
+	if err := config.ValidateSystemTuning(cfg.Kubernetes.SystemTuning); err != nil {
+		return fmt.Errorf("system tuning validation failed: %w", err)
+	}
+
+	// 8. Validate private registry and air-gapped install settings
+	if err := config.ValidateRKE2Registry(cfg.Kubernetes.RKE2); err != nil {
+		return fmt.Errorf("registry validation failed: %w", err)
+	}
+
+	// 9. Validate the custom ingress certificate
+	if err := config.ValidateCustomCert(&cfg.Security.TLS); err != nil {
+		return fmt.Errorf("TLS validation failed: %w", err)
+	}
+
+	// 10. Validate that disabled RKE2 components are not ones the cluster needs
+	if err := config.ValidateDisabledComponents(cfg); err != nil {
+		return fmt.Errorf("RKE2 component validation failed: %w", err)
+	}
+
+	// 11. Validate that admission plugin configurations can be rendered
+	if err := config.ValidateAdmissionConfig(&cfg.Kubernetes.Admission); err != nil {
+		return fmt.Errorf("admission validation failed: %w", err)
+	}
+
+	// 12. Validate that the bastion's jump hosts form a chain, its Salt API
+	// user and its audit log shipping
+	if err := config.ValidateBastionJumpChain(cfg.Security.Bastion); err != nil {
+		return fmt.Errorf("bastion validation failed: %w", err)
+	}
+	if err := config.ValidateBastionSalt(cfg.Security.Bastion); err != nil {
+		return fmt.Errorf("bastion validation failed: %w", err)
+	}
+	if err := config.ValidateBastionAuditLog(cfg.Security.Bastion); err != nil {
+		return fmt.Errorf("bastion validation failed: %w", err)
+	}
+
+	// 13. Validate that the ingress controller is supported
+	if err := config.ValidateIngressController(&cfg.Network.Ingress); err != nil {
+		return fmt.Errorf("ingress validation failed: %w", err)
+	}
+
+	// 14. Validate that monitoring is only requested where a provider agent exists
+	if err := config.ValidateMonitoring(cfg); err != nil {
+		return fmt.Errorf("monitoring validation failed: %w", err)
+	}
+
+	// 15. Validate that the admin passwords set are strong enough
+	if err := config.ValidateAdminPasswords(cfg); err != nil {
+		return fmt.Errorf("password validation failed: %w", err)
+	}
+
+	// 16. Validate the pinned checksums of install scripts
+	if err := config.ValidateDownloadChecksums(&cfg.Security); err != nil {
+		return fmt.Errorf("download validation failed: %w", err)
+	}
+
 	return nil
 }
 
@@ -73,6 +135,13 @@ func ValidateAPITokensPresence(cfg *config.ClusterConfig) error {
 		}
 	}
 
+	// Check Vultr API key
+	if cfg.Providers.Vultr != nil && cfg.Providers.Vultr.Enabled {
+		if cfg.Providers.Vultr.APIKey == "" && os.Getenv("VULTR_API_KEY") == "" {
+			errors = append(errors, "Vultr API key is required (set VULTR_API_KEY env var or provide in config)")
+		}
+	}
+
 	// Check Azure credentials
 	// Note: Azure credentials are optional if using Azure CLI (az login)
 	// The Azure provider will automatically use Azure CLI credentials when available
@@ -90,6 +159,7 @@ func ValidateAPITokensPresence(cfg *config.ClusterConfig) error {
 // ValidateAPITokensWithProviders validates tokens by making actual API calls
 func ValidateAPITokensWithProviders(cfg *config.ClusterConfig) error {
 	errors := []string{}
+	authFailed := false
 
 	// Validate DigitalOcean token
 	if cfg.Providers.DigitalOcean != nil && cfg.Providers.DigitalOcean.Enabled {
@@ -101,6 +171,7 @@ func ValidateAPITokensWithProviders(cfg *config.ClusterConfig) error {
 		if token != "" {
 			if err := validateDigitalOceanToken(token); err != nil {
 				errors = append(errors, fmt.Sprintf("DigitalOcean token validation failed: %v", err))
+				authFailed = authFailed || goerrors.Is(err, errs.ErrProviderAuth)
 			}
 		}
 	}
@@ -115,39 +186,77 @@ func ValidateAPITokensWithProviders(cfg *config.ClusterConfig) error {
 		if token != "" {
 			if err := validateLinodeToken(token); err != nil {
 				errors = append(errors, fmt.Sprintf("Linode token validation failed: %v", err))
+				authFailed = authFailed || goerrors.Is(err, errs.ErrProviderAuth)
 			}
 		}
 	}
 
 	if len(errors) > 0 {
-		return fmt.Errorf("provider API validation failed:\n  • %s", strings.Join(errors, "\n  • "))
+		err := fmt.Errorf("provider API validation failed:\n  • %s", strings.Join(errors, "\n  • "))
+		if authFailed {
+			return errs.Mark(err, errs.ErrProviderAuth)
+		}
+		return err
 	}
 
 	return nil
 }
 
+// providerAPIError classifies a provider API error answered with an
+// authentication or authorization status as errs.ErrProviderAuth
+func providerAPIError(statusCode int, err error) error {
+	if statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden {
+		err = errs.Mark(err, errs.ErrProviderAuth)
+	}
+	return fmt.Errorf("invalid token or API error: %w", err)
+}
+
 // validateDigitalOceanToken validates a DO token by making a test API call
 func validateDigitalOceanToken(token string) error {
-	client := godo.NewFromToken(token)
-	ctx := context.Background()
+	ctx, err := proxyAwareContext()
+	if err != nil {
+		return err
+	}
+
+	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	client := godo.NewClient(oauth2.NewClient(ctx, tokenSource))
 
-	_, _, err := client.Account.Get(ctx)
+	_, resp, err := client.Account.Get(ctx)
 	if err != nil {
-		return fmt.Errorf("invalid token or API error: %w", err)
+		statusCode := 0
+		if resp != nil {
+			statusCode = resp.StatusCode
+		}
+		return providerAPIError(statusCode, err)
 	}
 
 	return nil
 }
 
+// proxyAwareContext returns a context carrying an HTTP client that honors
+// HTTPS_PROXY/HTTP_PROXY/NO_PROXY, for oauth2-based provider clients
+func proxyAwareContext() (context.Context, error) {
+	httpClient, err := common.NewHTTPClient(30 * time.Second)
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(context.Background(), oauth2.HTTPClient, httpClient), nil
+}
+
 // validateLinodeToken validates a Linode token by making a test API call
 func validateLinodeToken(token string) error {
+	ctx, err := proxyAwareContext()
+	if err != nil {
+		return err
+	}
+
 	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
-	oauth2Client := oauth2.NewClient(context.Background(), tokenSource)
+	oauth2Client := oauth2.NewClient(ctx, tokenSource)
 	client := linodego.NewClient(oauth2Client)
 
-	_, err := client.GetProfile(context.Background())
+	_, err = client.GetProfile(ctx)
 	if err != nil {
-		return fmt.Errorf("invalid token or API error: %w", err)
+		return providerAPIError(linodeStatusCode(err), err)
 	}
 
 	return nil
@@ -192,6 +301,15 @@ func ValidateNodePools(cfg *config.ClusterConfig) error {
 			errors = append(errors, fmt.Sprintf("pool '%s' has no region specified", poolName))
 		}
 
+		// Validate zones belong to the pool region
+		if len(pool.Zones) > 0 && config.ProviderSupportsZones(pool.Provider) {
+			for _, zone := range pool.Zones {
+				if err := config.ValidateZoneForRegion(pool.Provider, pool.Region, zone); err != nil {
+					errors = append(errors, fmt.Sprintf("pool '%s': %v", poolName, err))
+				}
+			}
+		}
+
 		// Validate roles
 		if len(pool.Roles) == 0 {
 			errors = append(errors, fmt.Sprintf("pool '%s' has no roles specified", poolName))
@@ -247,6 +365,9 @@ func ValidateNodePools(cfg *config.ClusterConfig) error {
 		}
 	}
 
+	// Validate that pools and standalone nodes expand to unique node names
+	errors = append(errors, config.NodeNameCollisions(cfg)...)
+
 	if len(errors) > 0 {
 		return fmt.Errorf("node pool validation failed:\n  • %s", strings.Join(errors, "\n  • "))
 	}
@@ -258,6 +379,12 @@ func ValidateNodePools(cfg *config.ClusterConfig) error {
 func ValidateNetworkingConfig(cfg *config.ClusterConfig) error {
 	errors := []string{}
 
+	// An existing WireGuard server must be identified; a created one stores
+	// its endpoint and public key in the stack after provisioning
+	if err := config.ValidateWireGuardServer(cfg.Network.WireGuard); err != nil {
+		errors = append(errors, err.Error())
+	}
+
 	// Validate WireGuard configuration if enabled
 	// TEMPORARILY DISABLED - WireGuard validation has issues with Create field
 	// TODO: Fix this properly
@@ -328,6 +455,47 @@ func ValidateNetworkingConfig(cfg *config.ClusterConfig) error {
 		}
 	}
 
+	// Validate provider VPCs (create vs existing, no overlap with the VPN subnet)
+	wireGuardSubnet := cfg.Network.WireGuard.Subnet()
+	if do := cfg.Providers.DigitalOcean; do != nil && do.Enabled {
+		if err := config.ValidateVPC("digitalocean", do.VPC, wireGuardSubnet); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+	if linode := cfg.Providers.Linode; linode != nil && linode.Enabled {
+		if err := config.ValidateVPC("linode", linode.VPC, wireGuardSubnet); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+	if vultr := cfg.Providers.Vultr; vultr != nil && vultr.Enabled {
+		if err := config.ValidateVPC("vultr", vultr.VPC, wireGuardSubnet); err != nil {
+			errors = append(errors, err.Error())
+		}
+	}
+
+	// The node-local DNS cache forwards to the cluster DNS service
+	if err := config.ValidateNodeLocalDNS(&cfg.Kubernetes); err != nil {
+		errors = append(errors, err.Error())
+	}
+
+	if err := config.ValidateKubernetesReadyWait(&cfg.Kubernetes); err != nil {
+		errors = append(errors, err.Error())
+	}
+
+	if err := config.ValidateDNSCredentials(&cfg.Network.DNS); err != nil {
+		errors = append(errors, err.Error())
+	}
+
+	// Provider networks must not collide, or private IPs routed over the mesh are ambiguous
+	if err := config.ValidateProviderCIDRs(config.ProviderNetworkCIDRs(cfg)); err != nil {
+		errors = append(errors, err.Error())
+	}
+
+	// Hybrid mode routes within a VPC directly, so every provider needs one
+	if err := config.ValidateHybridNetwork(cfg); err != nil {
+		errors = append(errors, err.Error())
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("network validation failed:\n  • %s", strings.Join(errors, "\n  • "))
 	}
@@ -0,0 +1,25 @@
+package validation
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+func TestProviderAPIError(t *testing.T) {
+	cause := errors.New("GET https://api.digitalocean.com/v2/account: 401 Unable to authenticate you")
+
+	for _, status := range []int{http.StatusUnauthorized, http.StatusForbidden} {
+		if err := providerAPIError(status, cause); !errors.Is(err, errs.ErrProviderAuth) || !errors.Is(err, cause) {
+			t.Errorf("Expected status %d to be a provider auth error, got %v", status, err)
+		}
+	}
+	if err := providerAPIError(http.StatusServiceUnavailable, cause); errors.Is(err, errs.ErrProviderAuth) {
+		t.Errorf("Expected an outage not to be a provider auth error, got %v", err)
+	}
+	if err := providerAPIError(0, cause); errors.Is(err, errs.ErrProviderAuth) {
+		t.Errorf("Expected a network error not to be a provider auth error, got %v", err)
+	}
+}
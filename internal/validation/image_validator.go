@@ -0,0 +1,338 @@
+package validation
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/digitalocean/godo"
+	"github.com/linode/linodego"
+	"golang.org/x/oauth2"
+)
+
+// maxImageSuggestions bounds the alternatives offered for a missing image
+const maxImageSuggestions = 3
+
+// ProviderImage is an image a provider can boot nodes from
+type ProviderImage struct {
+	// ID is the value used in the config: the DigitalOcean slug (or numeric
+	// ID for custom images) or the Linode image ID
+	ID string
+
+	// Regions the image is available in. Empty means every region.
+	Regions []string
+}
+
+// availableIn reports whether the image can be used in region
+func (i ProviderImage) availableIn(region string) bool {
+	if len(i.Regions) == 0 || region == "" {
+		return true
+	}
+	for _, r := range i.Regions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
+
+// imageLister lists the images available to an account
+type imageLister func(token string) ([]ProviderImage, error)
+
+// ImageCatalog checks node images against the provider image APIs. Each
+// provider is listed at most once per catalog.
+type ImageCatalog struct {
+	listers map[string]imageLister
+
+	mu     sync.Mutex
+	images map[string][]ProviderImage
+	errors map[string]error
+}
+
+// NewImageCatalog creates a catalog backed by the DigitalOcean and Linode APIs
+func NewImageCatalog() *ImageCatalog {
+	return newImageCatalog(map[string]imageLister{
+		"digitalocean": listDigitalOceanImages,
+		"linode":       listLinodeImages,
+	})
+}
+
+func newImageCatalog(listers map[string]imageLister) *ImageCatalog {
+	return &ImageCatalog{
+		listers: listers,
+		images:  make(map[string][]ProviderImage),
+		errors:  make(map[string]error),
+	}
+}
+
+// list returns the cached images of provider, listing them on first use
+func (c *ImageCatalog) list(provider, token string) ([]ProviderImage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if images, ok := c.images[provider]; ok {
+		return images, nil
+	}
+	if err, ok := c.errors[provider]; ok {
+		return nil, err
+	}
+
+	images, err := c.listers[provider](token)
+	if err != nil {
+		c.errors[provider] = err
+		return nil, err
+	}
+	c.images[provider] = images
+	return images, nil
+}
+
+// nodeImage is an image a node or node pool boots from
+type nodeImage struct {
+	Owner    string
+	Provider string
+	Image    string
+	Region   string
+}
+
+// configuredImages returns the images of the config's nodes and node pools,
+// with the provider default region when none is set
+func configuredImages(cfg *config.ClusterConfig) []nodeImage {
+	defaultRegion := func(provider string) string {
+		switch provider {
+		case "digitalocean":
+			if cfg.Providers.DigitalOcean != nil {
+				return cfg.Providers.DigitalOcean.Region
+			}
+		case "linode":
+			if cfg.Providers.Linode != nil {
+				return cfg.Providers.Linode.Region
+			}
+		}
+		return ""
+	}
+
+	var images []nodeImage
+	add := func(owner, provider, image, region string) {
+		if image == "" {
+			return
+		}
+		if region == "" {
+			region = defaultRegion(provider)
+		}
+		images = append(images, nodeImage{Owner: owner, Provider: provider, Image: image, Region: region})
+	}
+
+	for _, node := range cfg.Nodes {
+		add(fmt.Sprintf("node '%s'", node.Name), node.Provider, node.Image, node.Region)
+	}
+
+	poolNames := make([]string, 0, len(cfg.NodePools))
+	for name := range cfg.NodePools {
+		poolNames = append(poolNames, name)
+	}
+	sort.Strings(poolNames)
+	for _, name := range poolNames {
+		pool := cfg.NodePools[name]
+		add(fmt.Sprintf("pool '%s'", name), pool.Provider, pool.Image, pool.Region)
+	}
+
+	return images
+}
+
+// ProviderToken returns the API token of provider from the config or its
+// environment variable
+func ProviderToken(cfg *config.ClusterConfig, provider string) string {
+	switch provider {
+	case "digitalocean":
+		if cfg.Providers.DigitalOcean != nil && cfg.Providers.DigitalOcean.Token != "" {
+			return cfg.Providers.DigitalOcean.Token
+		}
+		return os.Getenv("DIGITALOCEAN_TOKEN")
+	case "linode":
+		if cfg.Providers.Linode != nil && cfg.Providers.Linode.Token != "" {
+			return cfg.Providers.Linode.Token
+		}
+		return os.Getenv("LINODE_TOKEN")
+	}
+	return ""
+}
+
+// Check verifies that every node image exists at its provider and is
+// available in the node's region. Images of providers without an image API
+// lister or token are skipped; a failed lookup is a warning, since the
+// deployment itself would surface the same outage.
+func (c *ImageCatalog) Check(cfg *config.ClusterConfig) (errs []string, warnings []string) {
+	warned := make(map[string]bool)
+
+	for _, ni := range configuredImages(cfg) {
+		if _, ok := c.listers[ni.Provider]; !ok {
+			continue
+		}
+		token := ProviderToken(cfg, ni.Provider)
+		if token == "" {
+			continue
+		}
+
+		images, err := c.list(ni.Provider, token)
+		if err != nil {
+			if !warned[ni.Provider] {
+				warned[ni.Provider] = true
+				warnings = append(warnings, fmt.Sprintf("could not list %s images: %v", ni.Provider, err))
+			}
+			continue
+		}
+
+		if msg := checkImage(ni, images); msg != "" {
+			errs = append(errs, msg)
+		}
+	}
+
+	return errs, warnings
+}
+
+// checkImage returns why ni cannot be used, or "" when it can
+func checkImage(ni nodeImage, images []ProviderImage) string {
+	for _, image := range images {
+		if image.ID != ni.Image {
+			continue
+		}
+		if image.availableIn(ni.Region) {
+			return ""
+		}
+		regions := append([]string(nil), image.Regions...)
+		sort.Strings(regions)
+		return fmt.Sprintf("%s: %s image '%s' is not available in region %s (available in: %s)",
+			ni.Owner, ni.Provider, ni.Image, ni.Region, strings.Join(regions, ", "))
+	}
+
+	msg := fmt.Sprintf("%s: %s image '%s' does not exist", ni.Owner, ni.Provider, ni.Image)
+	if suggestions := suggestImages(ni, images); len(suggestions) > 0 {
+		msg += fmt.Sprintf(" (did you mean: %s?)", strings.Join(suggestions, ", "))
+	}
+	return msg
+}
+
+// suggestImages returns the images available in ni's region whose ID shares
+// the longest prefix with the requested one, e.g. ubuntu-24-04-x64 for
+// ubuntu-20-04-x64
+func suggestImages(ni nodeImage, images []ProviderImage) []string {
+	type candidate struct {
+		id     string
+		prefix int
+	}
+
+	var candidates []candidate
+	for _, image := range images {
+		if !image.availableIn(ni.Region) {
+			continue
+		}
+		if _, err := strconv.Atoi(image.ID); err == nil {
+			// Custom images are only known by number, not worth suggesting
+			continue
+		}
+		if n := commonPrefixLen(strings.ToLower(ni.Image), strings.ToLower(image.ID)); n >= 3 {
+			candidates = append(candidates, candidate{image.ID, n})
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].prefix != candidates[j].prefix {
+			return candidates[i].prefix > candidates[j].prefix
+		}
+		// Newer releases sort last alphabetically, offer them first
+		return candidates[i].id > candidates[j].id
+	})
+
+	var suggestions []string
+	for i := 0; i < len(candidates) && i < maxImageSuggestions; i++ {
+		suggestions = append(suggestions, candidates[i].id)
+	}
+	return suggestions
+}
+
+// commonPrefixLen returns the length of the common prefix of a and b
+func commonPrefixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+// ValidateNodeImages checks node images against the provider image APIs
+func ValidateNodeImages(cfg *config.ClusterConfig, catalog *ImageCatalog) (warnings []string, err error) {
+	errs, warnings := catalog.Check(cfg)
+	if len(errs) > 0 {
+		return warnings, fmt.Errorf("node image validation failed:\n  • %s", strings.Join(errs, "\n  • "))
+	}
+	return warnings, nil
+}
+
+// listDigitalOceanImages lists the public and private images of a
+// DigitalOcean account
+func listDigitalOceanImages(token string) ([]ProviderImage, error) {
+	ctx, err := proxyAwareContext()
+	if err != nil {
+		return nil, err
+	}
+
+	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	client := godo.NewClient(oauth2.NewClient(ctx, tokenSource))
+
+	var images []ProviderImage
+	opt := &godo.ListOptions{PerPage: 200}
+	for {
+		page, resp, err := client.Images.List(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, image := range page {
+			id := image.Slug
+			if id == "" {
+				id = strconv.Itoa(image.ID)
+			}
+			images = append(images, ProviderImage{ID: id, Regions: image.Regions})
+		}
+
+		if resp == nil || resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		current, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+		opt.Page = current + 1
+	}
+
+	return images, nil
+}
+
+// listLinodeImages lists the public and private images of a Linode account
+func listLinodeImages(token string) ([]ProviderImage, error) {
+	ctx, err := proxyAwareContext()
+	if err != nil {
+		return nil, err
+	}
+
+	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	client := linodego.NewClient(oauth2.NewClient(ctx, tokenSource))
+
+	list, err := client.ListImages(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	images := make([]ProviderImage, 0, len(list))
+	for _, image := range list {
+		var regions []string
+		for _, r := range image.Regions {
+			regions = append(regions, r.Region)
+		}
+		images = append(images, ProviderImage{ID: image.ID, Regions: regions})
+	}
+	return images, nil
+}
@@ -0,0 +1,127 @@
+package validation
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+func imageTestConfig() *config.ClusterConfig {
+	return &config.ClusterConfig{
+		Providers: config.ProvidersConfig{
+			DigitalOcean: &config.DigitalOceanProvider{Enabled: true, Token: "do-token", Region: "nyc3"},
+			Linode:       &config.LinodeProvider{Enabled: true, Token: "linode-token", Region: "us-east"},
+		},
+		NodePools: map[string]config.NodePool{
+			"do-masters":     {Name: "do-masters", Provider: "digitalocean", Count: 1, Image: "ubuntu-22-04-x64"},
+			"linode-workers": {Name: "linode-workers", Provider: "linode", Count: 1, Image: "linode/ubuntu22.04"},
+		},
+	}
+}
+
+func countingLister(calls *int, images ...ProviderImage) imageLister {
+	return func(token string) ([]ProviderImage, error) {
+		*calls++
+		return images, nil
+	}
+}
+
+func TestImageCatalog_PresentImages(t *testing.T) {
+	doCalls, linodeCalls := 0, 0
+	catalog := newImageCatalog(map[string]imageLister{
+		"digitalocean": countingLister(&doCalls,
+			ProviderImage{ID: "ubuntu-22-04-x64", Regions: []string{"nyc3", "fra1"}},
+			ProviderImage{ID: "ubuntu-24-04-x64", Regions: []string{"nyc3"}},
+		),
+		"linode": countingLister(&linodeCalls, ProviderImage{ID: "linode/ubuntu22.04"}),
+	})
+
+	cfg := imageTestConfig()
+	cfg.Nodes = []config.NodeConfig{{Name: "bastion", Provider: "digitalocean", Image: "ubuntu-24-04-x64"}}
+
+	errs, warnings := catalog.Check(cfg)
+	if len(errs) != 0 || len(warnings) != 0 {
+		t.Fatalf("Expected all images to be valid, got errors %v, warnings %v", errs, warnings)
+	}
+
+	catalog.Check(cfg)
+	if doCalls != 1 || linodeCalls != 1 {
+		t.Errorf("Expected each provider to be listed once, got digitalocean=%d linode=%d", doCalls, linodeCalls)
+	}
+}
+
+func TestImageCatalog_AbsentImage(t *testing.T) {
+	calls := 0
+	catalog := newImageCatalog(map[string]imageLister{
+		"digitalocean": countingLister(&calls,
+			ProviderImage{ID: "ubuntu-22-04-x64", Regions: []string{"nyc3"}},
+			ProviderImage{ID: "ubuntu-24-04-x64", Regions: []string{"nyc3"}},
+			ProviderImage{ID: "ubuntu-24-10-x64", Regions: []string{"fra1"}},
+			ProviderImage{ID: "debian-12-x64", Regions: []string{"nyc3"}},
+			ProviderImage{ID: "123456", Regions: []string{"nyc3"}},
+		),
+	})
+
+	cfg := imageTestConfig()
+	cfg.NodePools["do-masters"] = config.NodePool{Name: "do-masters", Provider: "digitalocean", Image: "ubuntu-20-04-x64"}
+
+	errs, _ := catalog.Check(cfg)
+	if len(errs) != 1 {
+		t.Fatalf("Expected one invalid image, got %v", errs)
+	}
+	want := "pool 'do-masters': digitalocean image 'ubuntu-20-04-x64' does not exist (did you mean: ubuntu-24-04-x64, ubuntu-22-04-x64?)"
+	if errs[0] != want {
+		t.Errorf("Expected %q, got %q", want, errs[0])
+	}
+}
+
+func TestImageCatalog_ImageNotInRegion(t *testing.T) {
+	calls := 0
+	catalog := newImageCatalog(map[string]imageLister{
+		"digitalocean": countingLister(&calls, ProviderImage{ID: "ubuntu-22-04-x64", Regions: []string{"sfo3", "fra1"}}),
+	})
+
+	_, err := ValidateNodeImages(imageTestConfig(), catalog)
+	if err == nil || !strings.Contains(err.Error(), "image 'ubuntu-22-04-x64' is not available in region nyc3 (available in: fra1, sfo3)") {
+		t.Errorf("Expected a region mismatch, got %v", err)
+	}
+}
+
+func TestImageCatalog_ListFailureWarns(t *testing.T) {
+	calls := 0
+	catalog := newImageCatalog(map[string]imageLister{
+		"digitalocean": func(token string) ([]ProviderImage, error) {
+			calls++
+			return nil, errors.New("503 Service Unavailable")
+		},
+	})
+
+	cfg := imageTestConfig()
+	cfg.Nodes = []config.NodeConfig{{Name: "bastion", Provider: "digitalocean", Image: "ubuntu-24-04-x64"}}
+
+	warnings, err := ValidateNodeImages(cfg, catalog)
+	if err != nil {
+		t.Errorf("Expected a failed lookup not to fail validation, got %v", err)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "could not list digitalocean images") {
+		t.Errorf("Expected one warning for the provider, got %v", warnings)
+	}
+	if calls != 1 {
+		t.Errorf("Expected the failed lookup to be cached, got %d calls", calls)
+	}
+}
+
+func TestImageCatalog_SkipsProvidersWithoutToken(t *testing.T) {
+	t.Setenv("DIGITALOCEAN_TOKEN", "")
+	calls := 0
+	catalog := newImageCatalog(map[string]imageLister{"digitalocean": countingLister(&calls)})
+
+	cfg := imageTestConfig()
+	cfg.Providers.DigitalOcean.Token = ""
+
+	if errs, _ := catalog.Check(cfg); len(errs) != 0 || calls != 0 {
+		t.Errorf("Expected images to be skipped without a token, got errors %v after %d calls", errs, calls)
+	}
+}
@@ -2,8 +2,11 @@ package validation
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 
 	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
 )
 
 // NodeDistribution holds information about node distribution
@@ -12,9 +15,15 @@ type NodeDistribution struct {
 	Masters    int
 	Workers    int
 	ByProvider map[string]int
+	// Sources describes the roles each pool and individual node contributes,
+	// e.g. "pool 'masters': 3 x master", sorted for stable messages
+	Sources []string
 }
 
-// ValidateNodeDistribution validates that the cluster has the correct node distribution
+// ValidateNodeDistribution validates that the cluster has the correct node
+// distribution. Any node count is accepted as long as there is at least one
+// master, the masters keep etcd quorum, and highAvailability clusters have
+// workers so workloads stay off the control plane.
 func ValidateNodeDistribution(cfg *config.ClusterConfig) error {
 	dist := CalculateDistribution(cfg)
 
@@ -23,37 +32,47 @@ func ValidateNodeDistribution(cfg *config.ClusterConfig) error {
 	}
 
 	if dist.Masters == 0 {
-		return fmt.Errorf("configuration must define at least 1 master node, found 0")
+		return fmt.Errorf("configuration must define at least 1 master node, found 0 (%s)", dist.describeSources())
 	}
 
 	// Validate odd number of masters for HA
 	if dist.Masters > 1 && dist.Masters%2 == 0 {
-		return fmt.Errorf("for HA, master nodes must be an odd number (1, 3, 5, ...), found %d", dist.Masters)
+		return errs.Mark(fmt.Errorf("for HA, master nodes must be an odd number (1, 3, 5, ...), found %d (%s)", dist.Masters, dist.describeSources()), errs.ErrQuorumRisk)
+	}
+
+	// Without highAvailability the masters are schedulable and may run workloads
+	if cfg.Cluster.HighAvailability && dist.Workers == 0 {
+		return fmt.Errorf("highAvailability requires at least 1 worker node, found 0 (%s); add a worker pool or disable highAvailability to schedule workloads on the masters", dist.describeSources())
 	}
 
 	return nil
 }
 
-// CalculateDistribution calculates node distribution from configuration
+// describeSources lists what each pool and node contributes to the distribution
+func (d NodeDistribution) describeSources() string {
+	return strings.Join(d.Sources, ", ")
+}
+
+// CalculateDistribution calculates node distribution from configuration.
+// Nodes with both the control plane and worker roles count toward Masters and
+// Workers alike, so Masters+Workers can exceed Total.
 func CalculateDistribution(cfg *config.ClusterConfig) NodeDistribution {
 	dist := NodeDistribution{
 		ByProvider: make(map[string]int),
 	}
 
 	// Count nodes from NodePools
-	for _, pool := range cfg.NodePools {
+	for name, pool := range cfg.NodePools {
 		dist.Total += pool.Count
 		dist.ByProvider[pool.Provider] += pool.Count
 
-		for _, role := range pool.Roles {
-			if role == "controlplane" || role == "master" {
-				dist.Masters += pool.Count
-				break
-			} else if role == "worker" {
-				dist.Workers += pool.Count
-				break
-			}
+		if config.HasRole(pool.Roles, config.RoleMaster) {
+			dist.Masters += pool.Count
+		}
+		if config.HasRole(pool.Roles, config.RoleWorker) {
+			dist.Workers += pool.Count
 		}
+		dist.Sources = append(dist.Sources, fmt.Sprintf("pool '%s': %d x %s", name, pool.Count, describeRoles(pool.Roles)))
 	}
 
 	// Count nodes from individual Nodes
@@ -61,20 +80,27 @@ func CalculateDistribution(cfg *config.ClusterConfig) NodeDistribution {
 		dist.Total++
 		dist.ByProvider[node.Provider]++
 
-		for _, role := range node.Roles {
-			if role == "controlplane" || role == "master" {
-				dist.Masters++
-				break
-			} else if role == "worker" {
-				dist.Workers++
-				break
-			}
+		if config.HasRole(node.Roles, config.RoleMaster) {
+			dist.Masters++
 		}
+		if config.HasRole(node.Roles, config.RoleWorker) {
+			dist.Workers++
+		}
+		dist.Sources = append(dist.Sources, fmt.Sprintf("node '%s': %s", node.Name, describeRoles(node.Roles)))
 	}
 
+	sort.Strings(dist.Sources)
 	return dist
 }
 
+// describeRoles joins roles for display, naming nodes without one
+func describeRoles(roles []string) string {
+	if len(roles) == 0 {
+		return "no role"
+	}
+	return strings.Join(roles, "+")
+}
+
 // GetDistributionSummary returns a human-readable summary of node distribution
 func GetDistributionSummary(cfg *config.ClusterConfig) string {
 	dist := CalculateDistribution(cfg)
@@ -1,10 +1,12 @@
 package validation
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
 	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
 )
 
 func TestValidateNodeDistribution(t *testing.T) {
@@ -175,6 +177,56 @@ func TestValidateNodeDistribution(t *testing.T) {
 	}
 }
 
+func TestValidateNodeDistribution_SingleMixedRoleNode(t *testing.T) {
+	cfg := &config.ClusterConfig{
+		NodePools: map[string]config.NodePool{
+			"all-in-one": {Name: "all-in-one", Provider: "digitalocean", Count: 1, Roles: []string{"worker", "controlplane"}},
+		},
+	}
+
+	if err := ValidateNodeDistribution(cfg); err != nil {
+		t.Errorf("Expected a single controlplane+worker node to be valid, got %v", err)
+	}
+}
+
+func TestValidateNodeDistribution_EvenMastersRiskQuorum(t *testing.T) {
+	cfg := &config.ClusterConfig{
+		NodePools: map[string]config.NodePool{
+			"masters": {Name: "masters", Provider: "digitalocean", Count: 2, Roles: []string{"master"}},
+		},
+	}
+
+	err := ValidateClusterConfig(cfg)
+	if !errors.Is(err, errs.ErrQuorumRisk) {
+		t.Errorf("Expected an even master count to be a quorum risk, got %v", err)
+	}
+}
+
+func TestValidateNodeDistribution_HighAvailabilityNeedsWorkers(t *testing.T) {
+	cfg := &config.ClusterConfig{
+		Cluster: config.ClusterSpec{HighAvailability: true},
+		NodePools: map[string]config.NodePool{
+			"masters": {Name: "masters", Provider: "digitalocean", Count: 3, Roles: []string{"master"}},
+		},
+	}
+
+	err := ValidateNodeDistribution(cfg)
+	if err == nil || !strings.Contains(err.Error(), "pool 'masters': 3 x master") {
+		t.Errorf("Expected the missing workers reported with the pools' roles, got %v", err)
+	}
+
+	cfg.Cluster.HighAvailability = false
+	if err := ValidateNodeDistribution(cfg); err != nil {
+		t.Errorf("Expected schedulable masters without highAvailability, got %v", err)
+	}
+
+	cfg.Cluster.HighAvailability = true
+	cfg.NodePools["workers"] = config.NodePool{Name: "workers", Provider: "linode", Count: 7, Roles: []string{"worker"}}
+	if err := ValidateNodeDistribution(cfg); err != nil {
+		t.Errorf("Expected any worker count to be valid, got %v", err)
+	}
+}
+
 func TestCalculateDistribution(t *testing.T) {
 	tests := []struct {
 		name               string
@@ -333,6 +385,54 @@ func TestCalculateDistribution(t *testing.T) {
 				"digitalocean": 3,
 			},
 		},
+		{
+			name: "Mixed-role nodes count as masters and workers",
+			config: &config.ClusterConfig{
+				NodePools: map[string]config.NodePool{
+					"combined": {
+						Name:     "combined",
+						Provider: "digitalocean",
+						Count:    3,
+						Roles:    []string{"controlplane", "worker"},
+					},
+					"workers": {
+						Name:     "workers",
+						Provider: "linode",
+						Count:    2,
+						Roles:    []string{"worker"},
+					},
+				},
+				Nodes: []config.NodeConfig{
+					{Name: "edge", Provider: "linode", Roles: []string{"worker", "master"}},
+				},
+			},
+			expectedTotal:   6,
+			expectedMasters: 4,
+			expectedWorkers: 6,
+			expectedByProvider: map[string]int{
+				"digitalocean": 3,
+				"linode":       3,
+			},
+		},
+		{
+			name: "Role aliases are not counted twice",
+			config: &config.ClusterConfig{
+				NodePools: map[string]config.NodePool{
+					"control": {
+						Name:     "control",
+						Provider: "digitalocean",
+						Count:    1,
+						Roles:    []string{"master", "controlplane"},
+					},
+				},
+			},
+			expectedTotal:   1,
+			expectedMasters: 1,
+			expectedWorkers: 0,
+			expectedByProvider: map[string]int{
+				"digitalocean": 1,
+			},
+		},
 	}
 
 	for _, tt := range tests {
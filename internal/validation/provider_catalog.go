@@ -0,0 +1,388 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/linode/linodego"
+	"golang.org/x/oauth2"
+)
+
+// catalogCacheTTL is how long listed regions and sizes are reused before
+// the provider API is queried again
+const catalogCacheTTL = 10 * time.Minute
+
+// digitalOceanAPIURL and linodeAPIURL override the provider API endpoints.
+// They are variables so tests can point the clients at a fake API.
+var (
+	digitalOceanAPIURL = ""
+	linodeAPIURL       = ""
+)
+
+// ProviderRegion is a region a provider can create nodes in
+type ProviderRegion struct {
+	Slug      string `json:"slug"`
+	Name      string `json:"name"`
+	Available bool   `json:"available"`
+}
+
+// ProviderSize is an instance size (droplet size, Linode type) of a provider
+type ProviderSize struct {
+	Slug         string  `json:"slug"`
+	VCPUs        int     `json:"vcpus"`
+	MemoryMB     int     `json:"memory_mb"`
+	DiskGB       int     `json:"disk_gb"`
+	PriceMonthly float64 `json:"price_monthly"`
+
+	// RegionPrices holds the monthly price in regions that differ from
+	// PriceMonthly
+	RegionPrices map[string]float64 `json:"region_prices,omitempty"`
+
+	// Regions the size is available in. Empty means every region.
+	Regions []string `json:"regions,omitempty"`
+}
+
+// PriceIn returns the monthly price of the size in region
+func (s ProviderSize) PriceIn(region string) float64 {
+	if price, ok := s.RegionPrices[region]; ok {
+		return price
+	}
+	return s.PriceMonthly
+}
+
+// availableIn reports whether the size can be used in region
+func (s ProviderSize) availableIn(region string) bool {
+	if len(s.Regions) == 0 || region == "" {
+		return true
+	}
+	for _, r := range s.Regions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
+
+// catalogLister lists the regions and sizes available to an account
+type catalogLister struct {
+	regions func(token string) ([]ProviderRegion, error)
+	sizes   func(token string) ([]ProviderSize, error)
+}
+
+// ProviderCatalog lists provider regions and sizes. Results are cached on
+// disk for a few minutes so repeated lookups while writing a config do not
+// hit the provider API every time.
+type ProviderCatalog struct {
+	listers  map[string]catalogLister
+	cacheDir string
+	now      func() time.Time
+}
+
+// NewProviderCatalog creates a catalog backed by the DigitalOcean and Linode
+// APIs that caches results in cacheDir. An empty cacheDir disables caching.
+func NewProviderCatalog(cacheDir string) *ProviderCatalog {
+	return newProviderCatalog(map[string]catalogLister{
+		"digitalocean": {regions: listDigitalOceanRegions, sizes: listDigitalOceanSizes},
+		"linode":       {regions: listLinodeRegions, sizes: listLinodeSizes},
+	}, cacheDir)
+}
+
+func newProviderCatalog(listers map[string]catalogLister, cacheDir string) *ProviderCatalog {
+	return &ProviderCatalog{
+		listers:  listers,
+		cacheDir: cacheDir,
+		now:      time.Now,
+	}
+}
+
+// Providers returns the providers the catalog can list, sorted
+func (c *ProviderCatalog) Providers() []string {
+	providers := make([]string, 0, len(c.listers))
+	for provider := range c.listers {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// Supports returns an error when the catalog cannot list provider
+func (c *ProviderCatalog) Supports(provider string) error {
+	_, err := c.lister(provider)
+	return err
+}
+
+// lister returns the lister of provider
+func (c *ProviderCatalog) lister(provider string) (catalogLister, error) {
+	lister, ok := c.listers[provider]
+	if !ok {
+		return catalogLister{}, fmt.Errorf("listing regions and sizes is not supported for provider '%s' (supported: %s)",
+			provider, strings.Join(c.Providers(), ", "))
+	}
+	return lister, nil
+}
+
+// Regions returns the regions of provider, sorted by slug
+func (c *ProviderCatalog) Regions(provider, token string) ([]ProviderRegion, error) {
+	lister, err := c.lister(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []ProviderRegion
+	if !c.readCache(provider+"-regions", &regions) {
+		if regions, err = lister.regions(token); err != nil {
+			return nil, fmt.Errorf("failed to list %s regions: %w", provider, err)
+		}
+		c.writeCache(provider+"-regions", regions)
+	}
+
+	sort.Slice(regions, func(i, j int) bool { return regions[i].Slug < regions[j].Slug })
+	return regions, nil
+}
+
+// Sizes returns the sizes of provider available in region, or every size
+// when region is empty, ordered by price
+func (c *ProviderCatalog) Sizes(provider, token, region string) ([]ProviderSize, error) {
+	lister, err := c.lister(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []ProviderSize
+	if !c.readCache(provider+"-sizes", &all) {
+		if all, err = lister.sizes(token); err != nil {
+			return nil, fmt.Errorf("failed to list %s sizes: %w", provider, err)
+		}
+		c.writeCache(provider+"-sizes", all)
+	}
+
+	var sizes []ProviderSize
+	for _, size := range all {
+		if size.availableIn(region) {
+			sizes = append(sizes, size)
+		}
+	}
+
+	sort.SliceStable(sizes, func(i, j int) bool {
+		if pi, pj := sizes[i].PriceIn(region), sizes[j].PriceIn(region); pi != pj {
+			return pi < pj
+		}
+		return sizes[i].Slug < sizes[j].Slug
+	})
+	return sizes, nil
+}
+
+// catalogCacheEntry is the on-disk form of a cached listing
+type catalogCacheEntry struct {
+	FetchedAt time.Time       `json:"fetched_at"`
+	Items     json.RawMessage `json:"items"`
+}
+
+// readCache decodes the cached listing name into v. It reports false when
+// there is no cache entry or it has expired.
+func (c *ProviderCatalog) readCache(name string, v any) bool {
+	if c.cacheDir == "" {
+		return false
+	}
+
+	data, err := os.ReadFile(filepath.Join(c.cacheDir, name+".json"))
+	if err != nil {
+		return false
+	}
+
+	var entry catalogCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return false
+	}
+	if c.now().Sub(entry.FetchedAt) > catalogCacheTTL {
+		return false
+	}
+	return json.Unmarshal(entry.Items, v) == nil
+}
+
+// writeCache stores the listing v as name. Failures are ignored, the next
+// lookup simply queries the provider again.
+func (c *ProviderCatalog) writeCache(name string, v any) {
+	if c.cacheDir == "" {
+		return
+	}
+
+	items, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	data, err := json.Marshal(catalogCacheEntry{FetchedAt: c.now(), Items: items})
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(c.cacheDir, 0700); err != nil {
+		return
+	}
+	_ = os.WriteFile(filepath.Join(c.cacheDir, name+".json"), data, 0600)
+}
+
+// digitalOceanClient creates a DigitalOcean API client for token
+func digitalOceanClient(token string) (context.Context, *godo.Client, error) {
+	ctx, err := proxyAwareContext()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	httpClient := oauth2.NewClient(ctx, tokenSource)
+	if digitalOceanAPIURL == "" {
+		return ctx, godo.NewClient(httpClient), nil
+	}
+
+	client, err := godo.New(httpClient, godo.SetBaseURL(digitalOceanAPIURL))
+	return ctx, client, err
+}
+
+// linodeClient creates a Linode API client for token
+func linodeClient(token string) (context.Context, *linodego.Client, error) {
+	ctx, err := proxyAwareContext()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+	client := linodego.NewClient(oauth2.NewClient(ctx, tokenSource))
+	if linodeAPIURL != "" {
+		client.SetBaseURL(linodeAPIURL)
+	}
+	return ctx, &client, nil
+}
+
+// listDigitalOceanRegions lists the DigitalOcean regions
+func listDigitalOceanRegions(token string) ([]ProviderRegion, error) {
+	ctx, client, err := digitalOceanClient(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var regions []ProviderRegion
+	opt := &godo.ListOptions{PerPage: 200}
+	for {
+		page, resp, err := client.Regions.List(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, region := range page {
+			regions = append(regions, ProviderRegion{Slug: region.Slug, Name: region.Name, Available: region.Available})
+		}
+
+		if resp == nil || resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		current, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+		opt.Page = current + 1
+	}
+
+	return regions, nil
+}
+
+// listDigitalOceanSizes lists the droplet sizes that can still be created
+func listDigitalOceanSizes(token string) ([]ProviderSize, error) {
+	ctx, client, err := digitalOceanClient(token)
+	if err != nil {
+		return nil, err
+	}
+
+	var sizes []ProviderSize
+	opt := &godo.ListOptions{PerPage: 200}
+	for {
+		page, resp, err := client.Sizes.List(ctx, opt)
+		if err != nil {
+			return nil, err
+		}
+		for _, size := range page {
+			if !size.Available {
+				continue
+			}
+			sizes = append(sizes, ProviderSize{
+				Slug:         size.Slug,
+				VCPUs:        size.Vcpus,
+				MemoryMB:     size.Memory,
+				DiskGB:       size.Disk,
+				PriceMonthly: size.PriceMonthly,
+				Regions:      size.Regions,
+			})
+		}
+
+		if resp == nil || resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		current, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, err
+		}
+		opt.Page = current + 1
+	}
+
+	return sizes, nil
+}
+
+// listLinodeRegions lists the Linode regions
+func listLinodeRegions(token string) ([]ProviderRegion, error) {
+	ctx, client, err := linodeClient(token)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := client.ListRegions(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	regions := make([]ProviderRegion, 0, len(list))
+	for _, region := range list {
+		regions = append(regions, ProviderRegion{Slug: region.ID, Name: region.Label, Available: region.Status == "ok"})
+	}
+	return regions, nil
+}
+
+// listLinodeSizes lists the Linode instance types. Types are offered in
+// every region, some at a region-specific price.
+func listLinodeSizes(token string) ([]ProviderSize, error) {
+	ctx, client, err := linodeClient(token)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := client.ListTypes(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := make([]ProviderSize, 0, len(list))
+	for _, t := range list {
+		size := ProviderSize{
+			Slug:     t.ID,
+			VCPUs:    t.VCPUs,
+			MemoryMB: t.Memory,
+			DiskGB:   t.Disk / 1024,
+		}
+		if t.Price != nil {
+			size.PriceMonthly = float64(t.Price.Monthly)
+		}
+		for _, price := range t.RegionPrices {
+			if size.RegionPrices == nil {
+				size.RegionPrices = make(map[string]float64)
+			}
+			size.RegionPrices[price.ID] = float64(price.Monthly)
+		}
+		sizes = append(sizes, size)
+	}
+	return sizes, nil
+}
@@ -0,0 +1,175 @@
+package validation
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeProviderAPI serves canned DigitalOcean and Linode API responses and
+// points the provider clients at them
+func fakeProviderAPI(t *testing.T, responses map[string]string) map[string]int {
+	t.Helper()
+	calls := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls[r.URL.Path]++
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"id":"unauthorized","message":"Unable to authenticate you"}`))
+			return
+		}
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+
+	originalDO, originalLinode := digitalOceanAPIURL, linodeAPIURL
+	digitalOceanAPIURL, linodeAPIURL = server.URL+"/", server.URL
+	t.Cleanup(func() { digitalOceanAPIURL, linodeAPIURL = originalDO, originalLinode })
+	return calls
+}
+
+func TestProviderCatalog_DigitalOcean(t *testing.T) {
+	fakeProviderAPI(t, map[string]string{
+		"/v2/regions": `{"regions": [
+			{"slug": "nyc3", "name": "New York 3", "available": true},
+			{"slug": "ams2", "name": "Amsterdam 2", "available": false}
+		], "links": {}, "meta": {"total": 2}}`,
+		"/v2/sizes": `{"sizes": [
+			{"slug": "s-2vcpu-4gb", "memory": 4096, "vcpus": 2, "disk": 80, "price_monthly": 24, "regions": ["nyc3", "fra1"], "available": true},
+			{"slug": "s-1vcpu-1gb", "memory": 1024, "vcpus": 1, "disk": 25, "price_monthly": 6, "regions": ["nyc3"], "available": true},
+			{"slug": "s-1vcpu-512mb-10gb", "memory": 512, "vcpus": 1, "disk": 10, "price_monthly": 4, "regions": ["fra1"], "available": true},
+			{"slug": "m-2vcpu-16gb", "memory": 16384, "vcpus": 2, "disk": 50, "price_monthly": 84, "regions": ["nyc3"], "available": false}
+		], "links": {}, "meta": {"total": 4}}`,
+	})
+	catalog := NewProviderCatalog("")
+
+	regions, err := catalog.Regions("digitalocean", "test-token")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(regions) != 2 || regions[0] != (ProviderRegion{Slug: "ams2", Name: "Amsterdam 2"}) ||
+		regions[1] != (ProviderRegion{Slug: "nyc3", Name: "New York 3", Available: true}) {
+		t.Errorf("Expected regions sorted by slug, got %+v", regions)
+	}
+
+	sizes, err := catalog.Sizes("digitalocean", "test-token", "nyc3")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(sizes) != 2 || sizes[0].Slug != "s-1vcpu-1gb" || sizes[1].Slug != "s-2vcpu-4gb" {
+		t.Fatalf("Expected the available nyc3 sizes ordered by price, got %+v", sizes)
+	}
+	if sizes[1].VCPUs != 2 || sizes[1].MemoryMB != 4096 || sizes[1].DiskGB != 80 || sizes[1].PriceMonthly != 24 {
+		t.Errorf("Expected the size specs to be kept, got %+v", sizes[1])
+	}
+
+	all, err := catalog.Sizes("digitalocean", "test-token", "")
+	if err != nil || len(all) != 3 {
+		t.Errorf("Expected every available size without a region, got %d (%v)", len(all), err)
+	}
+}
+
+func TestProviderCatalog_Linode(t *testing.T) {
+	fakeProviderAPI(t, map[string]string{
+		"/v4/regions": `{"data": [
+			{"id": "us-east", "label": "Newark, NJ", "status": "ok"},
+			{"id": "br-gru", "label": "Sao Paulo, BR", "status": "outage"}
+		], "page": 1, "pages": 1, "results": 2}`,
+		"/v4/linode/types": `{"data": [
+			{"id": "g6-standard-2", "label": "Linode 4GB", "vcpus": 2, "memory": 4096, "disk": 81920,
+			 "price": {"hourly": 0.036, "monthly": 24}, "region_prices": [{"id": "br-gru", "hourly": 0.043, "monthly": 28.8}]},
+			{"id": "g6-nanode-1", "label": "Nanode 1GB", "vcpus": 1, "memory": 1024, "disk": 25600,
+			 "price": {"hourly": 0.0075, "monthly": 5}, "region_prices": []}
+		], "page": 1, "pages": 1, "results": 2}`,
+	})
+	catalog := NewProviderCatalog("")
+
+	regions, err := catalog.Regions("linode", "test-token")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(regions) != 2 || regions[0].Slug != "br-gru" || regions[0].Available || !regions[1].Available {
+		t.Errorf("Expected regions with their status, got %+v", regions)
+	}
+
+	sizes, err := catalog.Sizes("linode", "test-token", "br-gru")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(sizes) != 2 || sizes[0].Slug != "g6-nanode-1" {
+		t.Fatalf("Expected every type ordered by price, got %+v", sizes)
+	}
+	standard := sizes[1]
+	if standard.DiskGB != 80 || standard.MemoryMB != 4096 || standard.VCPUs != 2 {
+		t.Errorf("Expected the disk in GB and the other specs kept, got %+v", standard)
+	}
+	if price := standard.PriceIn("br-gru"); price < 28.79 || price > 28.81 {
+		t.Errorf("Expected the br-gru price, got %v", price)
+	}
+	if price := standard.PriceIn("us-east"); price != 24 {
+		t.Errorf("Expected the default price outside br-gru, got %v", price)
+	}
+}
+
+func TestProviderCatalog_APIError(t *testing.T) {
+	fakeProviderAPI(t, nil)
+
+	_, err := NewProviderCatalog("").Regions("digitalocean", "wrong-token")
+	if err == nil || !strings.Contains(err.Error(), "failed to list digitalocean regions") {
+		t.Errorf("Expected the API error to be reported, got %v", err)
+	}
+}
+
+func TestProviderCatalog_Cache(t *testing.T) {
+	calls := fakeProviderAPI(t, map[string]string{
+		"/v2/regions": `{"regions": [{"slug": "nyc3", "name": "New York 3", "available": true}], "links": {}, "meta": {"total": 1}}`,
+	})
+	cacheDir := t.TempDir()
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	catalog := NewProviderCatalog(cacheDir)
+	catalog.now = func() time.Time { return now }
+	for i := 0; i < 2; i++ {
+		if _, err := catalog.Regions("digitalocean", "test-token"); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+
+	// A new catalog, as in the next command run, reuses the cache too
+	next := NewProviderCatalog(cacheDir)
+	next.now = func() time.Time { return now.Add(5 * time.Minute) }
+	if regions, err := next.Regions("digitalocean", "test-token"); err != nil || len(regions) != 1 {
+		t.Fatalf("Expected the cached regions, got %v (%v)", regions, err)
+	}
+	if calls["/v2/regions"] != 1 {
+		t.Errorf("Expected one API call while the cache is fresh, got %d", calls["/v2/regions"])
+	}
+
+	next.now = func() time.Time { return now.Add(catalogCacheTTL + time.Minute) }
+	if _, err := next.Regions("digitalocean", "test-token"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if calls["/v2/regions"] != 2 {
+		t.Errorf("Expected an expired cache to query the API again, got %d calls", calls["/v2/regions"])
+	}
+}
+
+func TestProviderCatalog_UnsupportedProvider(t *testing.T) {
+	catalog := newProviderCatalog(map[string]catalogLister{"linode": {}, "digitalocean": {}}, "")
+
+	err := catalog.Supports("aws")
+	if err == nil || !strings.Contains(err.Error(), "not supported for provider 'aws' (supported: digitalocean, linode)") {
+		t.Errorf("Expected an unsupported provider error, got %v", err)
+	}
+	if _, err := catalog.Sizes("aws", "token", ""); err == nil {
+		t.Error("Expected sizes of an unsupported provider to fail")
+	}
+}
@@ -0,0 +1,228 @@
+package validation
+
+import (
+	"context"
+	goerrors "errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/digitalocean/godo"
+	"github.com/linode/linodego"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+// Capabilities checked by CheckProviderPermissions
+const (
+	CapabilityRead    = "read"
+	CapabilityCompute = "compute"
+	CapabilityNetwork = "network"
+	CapabilityDNS     = "dns"
+)
+
+// permissionProbeName names the resources of permission probes, in case a
+// provider accepts one
+const permissionProbeName = "sloth-permission-check"
+
+// permissionProbeInvalid is a region, size and image no provider has. A create
+// request using it passes the permission check and is then rejected by
+// validation, so the probe never creates anything.
+const permissionProbeInvalid = "sloth-invalid"
+
+// PermissionCheck is the result of checking one capability of a provider token
+type PermissionCheck struct {
+	Provider   string
+	Capability string
+	Err        error
+}
+
+// Passed reports whether the token has the capability
+func (c PermissionCheck) Passed() bool {
+	return c.Err == nil
+}
+
+// CheckProviderPermissions checks that the token of every enabled provider
+// can read regions and create compute and networks, and DNS records when the
+// provider also serves the cluster domain. Create permissions are probed with
+// requests the provider rejects after authorizing them; a probe the provider
+// accepts anyway is deleted right away.
+func CheckProviderPermissions(cfg *config.ClusterConfig) []PermissionCheck {
+	var checks []PermissionCheck
+
+	if do := cfg.Providers.DigitalOcean; do != nil && do.Enabled {
+		checks = append(checks, checkDigitalOceanPermissions(ProviderToken(cfg, "digitalocean"), permissionDNSDomain(cfg, "digitalocean"))...)
+	}
+	if linode := cfg.Providers.Linode; linode != nil && linode.Enabled {
+		checks = append(checks, checkLinodePermissions(ProviderToken(cfg, "linode"), permissionDNSDomain(cfg, "linode"))...)
+	}
+
+	return checks
+}
+
+// permissionDNSDomain returns the cluster domain when provider serves its DNS
+func permissionDNSDomain(cfg *config.ClusterConfig, provider string) string {
+	dns := cfg.Network.DNS
+	if !strings.EqualFold(dns.Provider, provider) || dns.Domain == "" || dns.Domain == "example.com" {
+		return ""
+	}
+	return dns.Domain
+}
+
+// permissionProbeError interprets the result of a permission probe. A
+// rejection for bad input means the request was authorized; 401 and 403 mean
+// the token lacks the permission.
+func permissionProbeError(statusCode int, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return fmt.Errorf("permission denied: %w", errs.Mark(err, errs.ErrProviderAuth))
+	case statusCode == http.StatusBadRequest || statusCode == http.StatusUnprocessableEntity:
+		return nil
+	}
+	return err
+}
+
+// checkDigitalOceanPermissions probes the capabilities of a DigitalOcean token
+func checkDigitalOceanPermissions(token, domain string) []PermissionCheck {
+	result := func(capability string, err error) PermissionCheck {
+		return PermissionCheck{Provider: "digitalocean", Capability: capability, Err: err}
+	}
+	if token == "" {
+		return []PermissionCheck{result(CapabilityRead, fmt.Errorf("no API token: set DIGITALOCEAN_TOKEN or the provider token"))}
+	}
+
+	ctx, client, err := digitalOceanClient(token)
+	if err != nil {
+		return []PermissionCheck{result(CapabilityRead, err)}
+	}
+	statusCode := func(resp *godo.Response) int {
+		if resp == nil {
+			return 0
+		}
+		return resp.StatusCode
+	}
+
+	_, resp, err := client.Regions.List(ctx, &godo.ListOptions{PerPage: 1})
+	if err != nil {
+		// Reading is never a probe, so any error fails the check
+		err = providerAPIError(statusCode(resp), err)
+	}
+	checks := []PermissionCheck{result(CapabilityRead, err)}
+
+	droplet, resp, err := client.Droplets.Create(ctx, &godo.DropletCreateRequest{
+		Name:   permissionProbeName,
+		Region: permissionProbeInvalid,
+		Size:   permissionProbeInvalid,
+		Image:  godo.DropletCreateImage{Slug: permissionProbeInvalid},
+	})
+	if err == nil && droplet != nil {
+		_, _ = client.Droplets.Delete(ctx, droplet.ID)
+	}
+	checks = append(checks, result(CapabilityCompute, permissionProbeError(statusCode(resp), err)))
+
+	vpc, resp, err := client.VPCs.Create(ctx, &godo.VPCCreateRequest{
+		Name:       permissionProbeName,
+		RegionSlug: permissionProbeInvalid,
+	})
+	if err == nil && vpc != nil {
+		_, _ = client.VPCs.Delete(ctx, vpc.ID)
+	}
+	checks = append(checks, result(CapabilityNetwork, permissionProbeError(statusCode(resp), err)))
+
+	if domain != "" {
+		record, resp, err := client.Domains.CreateRecord(ctx, domain, &godo.DomainRecordEditRequest{
+			Type: "INVALID",
+			Name: permissionProbeName,
+		})
+		if err == nil && record != nil {
+			_, _ = client.Domains.DeleteRecord(ctx, domain, record.ID)
+		}
+		if statusCode(resp) == http.StatusNotFound {
+			err = fmt.Errorf("domain %s not found", domain)
+		} else {
+			err = permissionProbeError(statusCode(resp), err)
+		}
+		checks = append(checks, result(CapabilityDNS, err))
+	}
+
+	return checks
+}
+
+// checkLinodePermissions probes the capabilities of a Linode token
+func checkLinodePermissions(token, domain string) []PermissionCheck {
+	result := func(capability string, err error) PermissionCheck {
+		return PermissionCheck{Provider: "linode", Capability: capability, Err: err}
+	}
+	if token == "" {
+		return []PermissionCheck{result(CapabilityRead, fmt.Errorf("no API token: set LINODE_TOKEN or the provider token"))}
+	}
+
+	ctx, client, err := linodeClient(token)
+	if err != nil {
+		return []PermissionCheck{result(CapabilityRead, err)}
+	}
+	_, err = client.ListRegions(ctx, linodego.NewListOptions(1, ""))
+	if err != nil {
+		err = providerAPIError(linodeStatusCode(err), err)
+	}
+	checks := []PermissionCheck{result(CapabilityRead, err)}
+
+	instance, err := client.CreateInstance(ctx, linodego.InstanceCreateOptions{
+		Label:  permissionProbeName,
+		Region: permissionProbeInvalid,
+		Type:   permissionProbeInvalid,
+	})
+	if err == nil && instance != nil {
+		_ = client.DeleteInstance(ctx, instance.ID)
+	}
+	checks = append(checks, result(CapabilityCompute, permissionProbeError(linodeStatusCode(err), err)))
+
+	vpc, err := client.CreateVPC(ctx, linodego.VPCCreateOptions{
+		Label:  permissionProbeName,
+		Region: permissionProbeInvalid,
+	})
+	if err == nil && vpc != nil {
+		_ = client.DeleteVPC(ctx, vpc.ID)
+	}
+	checks = append(checks, result(CapabilityNetwork, permissionProbeError(linodeStatusCode(err), err)))
+
+	if domain != "" {
+		checks = append(checks, result(CapabilityDNS, checkLinodeDNSPermission(ctx, client, domain)))
+	}
+
+	return checks
+}
+
+// checkLinodeDNSPermission probes whether a Linode token can create records
+// in domain
+func checkLinodeDNSPermission(ctx context.Context, client *linodego.Client, domain string) error {
+	filter := fmt.Sprintf(`{"domain": %q}`, domain)
+	domains, err := client.ListDomains(ctx, linodego.NewListOptions(1, filter))
+	if err != nil {
+		return providerAPIError(linodeStatusCode(err), err)
+	}
+	if len(domains) == 0 {
+		return fmt.Errorf("domain %s not found", domain)
+	}
+
+	record, err := client.CreateDomainRecord(ctx, domains[0].ID, linodego.DomainRecordCreateOptions{
+		Type: "INVALID",
+		Name: permissionProbeName,
+	})
+	if err == nil && record != nil {
+		_ = client.DeleteDomainRecord(ctx, domains[0].ID, record.ID)
+	}
+	return permissionProbeError(linodeStatusCode(err), err)
+}
+
+// linodeStatusCode returns the HTTP status of a Linode API error, or 0
+func linodeStatusCode(err error) int {
+	var apiErr *linodego.Error
+	if goerrors.As(err, &apiErr) {
+		return apiErr.Code
+	}
+	return 0
+}
@@ -0,0 +1,148 @@
+package validation
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+// fakeAPIResponse is a canned response of fakePermissionAPI
+type fakeAPIResponse struct {
+	status int
+	body   string
+}
+
+// fakePermissionAPI serves canned responses keyed by "METHOD /path" and points
+// the provider clients at them
+func fakePermissionAPI(t *testing.T, responses map[string]fakeAPIResponse) map[string]int {
+	t.Helper()
+	calls := make(map[string]int)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Method + " " + r.URL.Path
+		calls[key]++
+		response, ok := responses[key]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(response.status)
+		w.Write([]byte(response.body))
+	}))
+	t.Cleanup(server.Close)
+
+	originalDO, originalLinode := digitalOceanAPIURL, linodeAPIURL
+	digitalOceanAPIURL, linodeAPIURL = server.URL+"/", server.URL
+	t.Cleanup(func() { digitalOceanAPIURL, linodeAPIURL = originalDO, originalLinode })
+	return calls
+}
+
+var (
+	doForbidden     = fakeAPIResponse{http.StatusForbidden, `{"id":"forbidden","message":"You are not authorized to perform this operation"}`}
+	doUnprocessable = fakeAPIResponse{http.StatusUnprocessableEntity, `{"id":"unprocessable_entity","message":"Region is not available"}`}
+	linodeForbidden = fakeAPIResponse{http.StatusUnauthorized, `{"errors":[{"reason":"Unauthorized"}]}`}
+	linodeBadInput  = fakeAPIResponse{http.StatusBadRequest, `{"errors":[{"reason":"region is not valid","field":"region"}]}`}
+)
+
+func permissionResults(checks []PermissionCheck) map[string]error {
+	results := make(map[string]error)
+	for _, check := range checks {
+		results[check.Provider+"/"+check.Capability] = check.Err
+	}
+	return results
+}
+
+func TestCheckProviderPermissions_DigitalOcean(t *testing.T) {
+	fakePermissionAPI(t, map[string]fakeAPIResponse{
+		"GET /v2/regions":                      {http.StatusOK, `{"regions": [], "links": {}, "meta": {"total": 0}}`},
+		"POST /v2/droplets":                    doForbidden,
+		"POST /v2/vpcs":                        doUnprocessable,
+		"POST /v2/domains/example.org/records": doForbidden,
+	})
+	cfg := &config.ClusterConfig{
+		Providers: config.ProvidersConfig{DigitalOcean: &config.DigitalOceanProvider{Enabled: true, Token: "test-token"}},
+		Network:   config.NetworkConfig{DNS: config.DNSConfig{Domain: "example.org", Provider: "digitalocean"}},
+	}
+
+	checks := CheckProviderPermissions(cfg)
+	if len(checks) != 4 {
+		t.Fatalf("Expected read, compute, network and dns checks, got %+v", checks)
+	}
+	results := permissionResults(checks)
+	if err := results["digitalocean/read"]; err != nil {
+		t.Errorf("Expected listing regions to pass, got %v", err)
+	}
+	if err := results["digitalocean/compute"]; err == nil || !errors.Is(err, errs.ErrProviderAuth) {
+		t.Errorf("Expected a forbidden droplet create to fail as a permission error, got %v", err)
+	}
+	if err := results["digitalocean/network"]; err != nil {
+		t.Errorf("Expected a VPC create rejected for its region to pass, got %v", err)
+	}
+	if err := results["digitalocean/dns"]; err == nil || !strings.Contains(err.Error(), "permission denied") {
+		t.Errorf("Expected a forbidden record create to fail, got %v", err)
+	}
+}
+
+func TestCheckProviderPermissions_Linode(t *testing.T) {
+	fakePermissionAPI(t, map[string]fakeAPIResponse{
+		"GET /v4/regions":           {http.StatusOK, `{"data": [], "page": 1, "pages": 1, "results": 0}`},
+		"POST /v4/linode/instances": linodeBadInput,
+		"POST /v4/vpcs":             linodeForbidden,
+	})
+	cfg := &config.ClusterConfig{
+		Providers: config.ProvidersConfig{Linode: &config.LinodeProvider{Enabled: true, Token: "test-token"}},
+		Network:   config.NetworkConfig{DNS: config.DNSConfig{Domain: "example.org", Provider: "digitalocean"}},
+	}
+
+	results := permissionResults(CheckProviderPermissions(cfg))
+	if len(results) != 3 {
+		t.Fatalf("Expected no dns check for a domain served elsewhere, got %v", results)
+	}
+	if err := results["linode/read"]; err != nil {
+		t.Errorf("Expected listing regions to pass, got %v", err)
+	}
+	if err := results["linode/compute"]; err != nil {
+		t.Errorf("Expected an instance create rejected for its region to pass, got %v", err)
+	}
+	if err := results["linode/network"]; err == nil || !errors.Is(err, errs.ErrProviderAuth) {
+		t.Errorf("Expected an unauthorized VPC create to fail as a permission error, got %v", err)
+	}
+}
+
+func TestCheckProviderPermissions_DeletesAcceptedProbe(t *testing.T) {
+	calls := fakePermissionAPI(t, map[string]fakeAPIResponse{
+		"GET /v2/regions":           {http.StatusOK, `{"regions": [], "links": {}, "meta": {"total": 0}}`},
+		"POST /v2/droplets":         doUnprocessable,
+		"POST /v2/vpcs":             {http.StatusCreated, `{"vpc": {"id": "vpc-probe", "name": "sloth-permission-check"}}`},
+		"DELETE /v2/vpcs/vpc-probe": {http.StatusNoContent, ""},
+	})
+	cfg := &config.ClusterConfig{
+		Providers: config.ProvidersConfig{DigitalOcean: &config.DigitalOceanProvider{Enabled: true, Token: "test-token"}},
+	}
+
+	for _, check := range CheckProviderPermissions(cfg) {
+		if !check.Passed() {
+			t.Errorf("Expected %s to pass, got %v", check.Capability, check.Err)
+		}
+	}
+	if calls["DELETE /v2/vpcs/vpc-probe"] != 1 {
+		t.Error("Expected the VPC created by the probe to be deleted")
+	}
+}
+
+func TestCheckProviderPermissions_MissingToken(t *testing.T) {
+	t.Setenv("LINODE_TOKEN", "")
+	cfg := &config.ClusterConfig{
+		Providers: config.ProvidersConfig{Linode: &config.LinodeProvider{Enabled: true}},
+	}
+
+	checks := CheckProviderPermissions(cfg)
+	if len(checks) != 1 || checks[0].Passed() || !strings.Contains(checks[0].Err.Error(), "LINODE_TOKEN") {
+		t.Errorf("Expected a single failed check naming the token, got %+v", checks)
+	}
+}
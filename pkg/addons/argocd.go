@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/health"
 )
 
 // InstallArgoCD installs ArgoCD and applies GitOps applications
@@ -114,18 +115,12 @@ echo "ArgoCD installed successfully"
 	return runSSHCommand(masterNodeIP, sshPrivateKey, installScript)
 }
 
-// waitForArgoCDReady waits for ArgoCD pods to be ready
+// waitForArgoCDReady waits for ArgoCD to accept applications: its CRDs
+// established and its server and repo server available
 func waitForArgoCDReady(masterNodeIP string, sshPrivateKey string, namespace string) error {
-	waitScript := fmt.Sprintf(`
-set -e
-
-echo "Waiting for ArgoCD pods to be ready..."
-kubectl wait --for=condition=Ready pods --all -n %s --timeout=300s
-
-echo "ArgoCD is ready"
-`, namespace)
-
-	return runSSHCommand(masterNodeIP, sshPrivateKey, waitScript)
+	return health.NewHealthChecker(nil).WaitForArgoCDReady(namespace, func(script string) (string, error) {
+		return runSSHCommandWithOutput(masterNodeIP, sshPrivateKey, script)
+	})
 }
 
 // applyGitOpsApplications clones the GitOps repo and applies application manifests
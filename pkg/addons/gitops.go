@@ -2,8 +2,11 @@ package addons
 
 import (
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/health"
 )
 
 // GitOpsConfig represents GitOps configuration
@@ -40,13 +43,15 @@ func BootstrapArgoCD(kubeconfig string, gitopsConfig *GitOpsConfig) error {
 		return fmt.Errorf("failed to install ArgoCD: %w", err)
 	}
 
-	// 2. Wait for ArgoCD to be ready
-	cmd = exec.Command("kubectl", "--kubeconfig", kubeconfig,
-		"wait", "--for=condition=ready", "pod",
-		"-l", "app.kubernetes.io/name=argocd-server",
-		"-n", "argocd", "--timeout=300s")
-	if err := cmd.Run(); err != nil {
-		return fmt.Errorf("ArgoCD pods not ready: %w", err)
+	// 2. Wait for ArgoCD to accept applications
+	err := health.NewHealthChecker(nil).WaitForArgoCDReady("argocd", func(script string) (string, error) {
+		cmd := exec.Command("bash", "-c", script)
+		cmd.Env = append(os.Environ(), "KUBECONFIG="+kubeconfig)
+		output, err := cmd.Output()
+		return string(output), err
+	})
+	if err != nil {
+		return fmt.Errorf("ArgoCD not ready: %w", err)
 	}
 
 	// 3. Create ArgoCD Application pointing to the GitOps repo
@@ -1,16 +1,21 @@
 package cloudinit
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
 
 // GenerateUserDataWithHostname generates cloud-init user data with hostname configuration
 // K3s installation is handled by remote commands AFTER WireGuard is configured
 func GenerateUserDataWithHostname(hostname string) string {
-	return GenerateUserDataWithHostnameAndSalt(hostname, "")
+	return GenerateUserDataWithHostnameAndSalt(hostname, "", config.Download{})
 }
 
 // GenerateUserDataWithHostnameAndSalt generates cloud-init user data with hostname and Salt Minion
-// If saltMasterIP is provided, Salt Minion will be installed and configured to connect to that master
-func GenerateUserDataWithHostnameAndSalt(hostname string, saltMasterIP string) string {
+// If saltMasterIP is provided, Salt Minion will be installed and configured to connect to that master,
+// from the bootstrap script fetched by saltBootstrap (the verified default when empty)
+func GenerateUserDataWithHostnameAndSalt(hostname string, saltMasterIP string, saltBootstrap config.Download) string {
 	// Add hostname configuration if provided
 	hostnameConfig := ""
 	if hostname != "" {
@@ -51,12 +56,17 @@ runcmd:
 
 	// Add Salt Minion installation if master IP is provided
 	if saltMasterIP != "" {
-		runcmds += `
-  # Install Salt Minion
+		if saltBootstrap.URL == "" {
+			saltBootstrap = (&config.SecurityConfig{}).SaltBootstrapDownload()
+		}
+		runcmds += fmt.Sprintf(`
+  # Install Salt Minion, stopping here if the bootstrap script fails verification
   - echo "Installing Salt Minion..."
-  - curl -o /tmp/bootstrap-salt.sh -L https://github.com/saltstack/salt-bootstrap/releases/latest/download/bootstrap-salt.sh
+  - |
+    %s || exit 1
   - chmod +x /tmp/bootstrap-salt.sh
-  - sh /tmp/bootstrap-salt.sh stable
+  - sh /tmp/bootstrap-salt.sh stable`, saltBootstrap.Fetch("/tmp/bootstrap-salt.sh"))
+		runcmds += `
   # Restart Salt Minion to apply configuration
   - systemctl restart salt-minion
   - systemctl enable salt-minion
@@ -18,6 +18,12 @@ type RKEManager struct {
 	ctx        *pulumi.Context
 	clusterYML pulumi.StringOutput
 	kubeconfig pulumi.StringOutput
+
+	// tlsSans are names or addresses added to the API server certificate
+	// once known, such as those of the control plane load balancer
+	tlsSans []pulumi.StringOutput
+
+	grafanaAdminPassword string
 }
 
 // NewRKEManager creates a new RKE manager
@@ -29,6 +35,19 @@ func NewRKEManager(ctx *pulumi.Context, config *config.KubernetesConfig) *RKEMan
 	}
 }
 
+// SetGrafanaAdminPassword sets the admin password of the Grafana installed
+// with monitoring. Without one Grafana gets a random password, kept in its
+// secret in the monitoring namespace.
+func (r *RKEManager) SetGrafanaAdminPassword(password string) {
+	r.grafanaAdminPassword = password
+}
+
+// AddTLSSan adds san, once known, to the names and addresses of the API
+// server certificate, next to the configured tlsSan
+func (r *RKEManager) AddTLSSan(san pulumi.StringOutput) {
+	r.tlsSans = append(r.tlsSans, san)
+}
+
 // AddNode adds a node to the RKE cluster
 func (r *RKEManager) AddNode(node *providers.NodeOutput) {
 	r.nodes = append(r.nodes, node)
@@ -36,8 +55,9 @@ func (r *RKEManager) AddNode(node *providers.NodeOutput) {
 
 // GenerateClusterConfig generates RKE cluster.yml configuration
 func (r *RKEManager) GenerateClusterConfig() pulumi.StringOutput {
-	return pulumi.All(r.gatherNodeInfo()).ApplyT(func(args []interface{}) string {
+	return pulumi.All(r.gatherNodeInfo(), pulumi.ToStringArrayOutput(r.tlsSans)).ApplyT(func(args []interface{}) string {
 		nodes := args[0].([]map[string]interface{})
+		sans := r.certificateSANs(args[1].([]string))
 
 		clusterConfig := map[string]interface{}{
 			"cluster_name":       r.ctx.Stack(),
@@ -59,6 +79,7 @@ func (r *RKEManager) GenerateClusterConfig() pulumi.StringOutput {
 			"authentication": map[string]interface{}{
 				"strategy": "x509",
 				"options":  map[string]interface{}{},
+				"sans":     sans,
 			},
 			"authorization": map[string]interface{}{
 				"mode": "rbac",
@@ -88,6 +109,25 @@ func (r *RKEManager) GenerateClusterConfig() pulumi.StringOutput {
 	}).(pulumi.StringOutput)
 }
 
+// certificateSANs returns the configured tlsSan followed by the added
+// ones, without empty or repeated entries
+func (r *RKEManager) certificateSANs(added []string) []string {
+	var configured []string
+	if r.config.RKE2 != nil {
+		configured = r.config.RKE2.TLSSan
+	}
+
+	sans := []string{}
+	seen := make(map[string]bool)
+	for _, san := range append(append([]string{}, configured...), added...) {
+		if san != "" && !seen[san] {
+			seen[san] = true
+			sans = append(sans, san)
+		}
+	}
+	return sans
+}
+
 // gatherNodeInfo gathers information about nodes for RKE config
 func (r *RKEManager) gatherNodeInfo() []interface{} {
 	nodeInfos := make([]interface{}, len(r.nodes))
@@ -518,7 +558,21 @@ func (r *RKEManager) installMonitoring(masterNode *providers.NodeOutput) error {
 			User:       pulumi.String(masterNode.SSHUser),
 			PrivateKey: pulumi.String(r.getSSHPrivateKey()),
 		},
-		Create: pulumi.String(`
+		Create: pulumi.ToSecret(pulumi.String(monitoringScript(r.grafanaAdminPassword))).(pulumi.StringOutput),
+	})
+
+	return err
+}
+
+// monitoringScript installs the Prometheus stack with the given Grafana
+// admin password, or a random one when it is empty
+func monitoringScript(grafanaAdminPassword string) string {
+	password := "$(openssl rand -base64 24)"
+	if grafanaAdminPassword != "" {
+		password = "'" + grafanaAdminPassword + "'"
+	}
+
+	return fmt.Sprintf(`
 #!/bin/bash
 set -e
 
@@ -531,13 +585,10 @@ helm upgrade --install prometheus prometheus-community/kube-prometheus-stack \
   --set prometheus.prometheusSpec.retention=30d \
   --set prometheus.prometheusSpec.storageSpec.volumeClaimTemplate.spec.accessModes[0]=ReadWriteOnce \
   --set prometheus.prometheusSpec.storageSpec.volumeClaimTemplate.spec.resources.requests.storage=50Gi \
-  --set grafana.adminPassword=admin
+  --set-literal grafana.adminPassword=%s
 
 echo "Monitoring stack installed"
-`),
-	})
-
-	return err
+`, password)
 }
 
 // ExportClusterInfo exports cluster information
@@ -760,3 +760,21 @@ func TestNodeRolePriority(t *testing.T) {
 		})
 	}
 }
+
+func TestCertificateSANs(t *testing.T) {
+	manager := &RKEManager{
+		config: &config.KubernetesConfig{
+			RKE2: &config.RKE2Config{TLSSan: []string{"api.example.com"}},
+		},
+	}
+
+	sans := manager.certificateSANs([]string{"203.0.113.10", "", "api.example.com"})
+	if strings.Join(sans, ",") != "api.example.com,203.0.113.10" {
+		t.Errorf("Expected the configured SAN followed by the load balancer address, got %v", sans)
+	}
+
+	manager.config.RKE2 = nil
+	if sans := manager.certificateSANs(nil); sans == nil || len(sans) != 0 {
+		t.Errorf("Expected an empty SAN list, got %v", sans)
+	}
+}
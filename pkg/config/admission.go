@@ -0,0 +1,163 @@
+package config
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Where the admission configuration file is written on control-plane nodes,
+// next to the RKE2 and K3s configs
+const (
+	AdmissionConfigPath    = "/etc/rancher/rke2/admission-config.yaml"
+	K3sAdmissionConfigPath = "/etc/rancher/k3s/admission-config.yaml"
+)
+
+// knownAdmissionPlugins are the admission plugins compiled into kube-apiserver
+var knownAdmissionPlugins = map[string]bool{
+	"AlwaysAdmit":                          true,
+	"AlwaysDeny":                           true,
+	"AlwaysPullImages":                     true,
+	"CertificateApproval":                  true,
+	"CertificateSigning":                   true,
+	"CertificateSubjectRestriction":        true,
+	"ClusterTrustBundleAttest":             true,
+	"DefaultIngressClass":                  true,
+	"DefaultStorageClass":                  true,
+	"DefaultTolerationSeconds":             true,
+	"DenyServiceExternalIPs":               true,
+	"EventRateLimit":                       true,
+	"ExtendedResourceToleration":           true,
+	"ImagePolicyWebhook":                   true,
+	"LimitPodHardAntiAffinityTopology":     true,
+	"LimitRanger":                          true,
+	"MutatingAdmissionPolicy":              true,
+	"MutatingAdmissionWebhook":             true,
+	"NamespaceAutoProvision":               true,
+	"NamespaceExists":                      true,
+	"NamespaceLifecycle":                   true,
+	"NodeRestriction":                      true,
+	"OwnerReferencesPermissionEnforcement": true,
+	"PersistentVolumeClaimResize":          true,
+	"PodNodeSelector":                      true,
+	"PodSecurity":                          true,
+	"PodTolerationRestriction":             true,
+	"Priority":                             true,
+	"ResourceQuota":                        true,
+	"RuntimeClass":                         true,
+	"ServiceAccount":                       true,
+	"StorageObjectInUseProtection":         true,
+	"TaintNodesByCondition":                true,
+	"ValidatingAdmissionPolicy":            true,
+	"ValidatingAdmissionWebhook":           true,
+}
+
+// admissionConfiguration is the file passed to --admission-control-config-file
+type admissionConfiguration struct {
+	APIVersion string                  `yaml:"apiVersion"`
+	Kind       string                  `yaml:"kind"`
+	Plugins    []admissionPluginConfig `yaml:"plugins"`
+}
+
+type admissionPluginConfig struct {
+	Name          string      `yaml:"name"`
+	Configuration interface{} `yaml:"configuration"`
+}
+
+// CheckAdmissionConfig checks kubernetes.admission. Errors are plugin
+// configurations that are not valid YAML, warnings are plugin names
+// kube-apiserver does not know and would refuse to start with.
+func CheckAdmissionConfig(admission *AdmissionConfig) (errs []string, warnings []string) {
+	for _, plugin := range admission.Plugins {
+		if !knownAdmissionPlugins[strings.TrimSpace(plugin)] {
+			warnings = append(warnings, fmt.Sprintf("%s is not a known Kubernetes admission plugin", plugin))
+		}
+	}
+
+	for _, plugin := range sortedKeys(admission.Config) {
+		var parsed interface{}
+		if err := yaml.Unmarshal([]byte(admission.Config[plugin]), &parsed); err != nil {
+			errs = append(errs, fmt.Sprintf("configuration of admission plugin %s is not valid YAML: %v", plugin, err))
+		}
+	}
+
+	return errs, warnings
+}
+
+// ValidateAdmissionConfig returns an error when an admission plugin
+// configuration cannot be rendered
+func ValidateAdmissionConfig(admission *AdmissionConfig) error {
+	errs, _ := CheckAdmissionConfig(admission)
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid kubernetes.admission:\n  • %s", strings.Join(errs, "\n  • "))
+	}
+	return nil
+}
+
+// AdmissionAPIServerArgs returns the kube-apiserver flags (without leading
+// dashes) enabling the configured plugins and their configuration file,
+// expected at configPath. WriteAdmissionConfigCommand writes that file.
+func AdmissionAPIServerArgs(admission *AdmissionConfig, configPath string) []string {
+	var args []string
+	if len(admission.Plugins) > 0 {
+		args = append(args, fmt.Sprintf("enable-admission-plugins=%s", strings.Join(admission.Plugins, ",")))
+	}
+	if len(admission.Config) > 0 {
+		args = append(args, fmt.Sprintf("admission-control-config-file=%s", configPath))
+	}
+	return args
+}
+
+// WriteAdmissionConfigCommand returns a command writing the admission
+// configuration file to dest, or "" when no plugin is configured. It must
+// run on every server before kube-apiserver starts, which refuses to start
+// without the file its flags name. The file is passed base64 encoded so
+// plugin configurations need no shell quoting.
+func WriteAdmissionConfigCommand(admission *AdmissionConfig, dest string) (string, error) {
+	file, err := BuildAdmissionConfigFile(admission)
+	if err != nil || file == "" {
+		return "", err
+	}
+	return fmt.Sprintf("mkdir -p %s && echo %s | base64 -d > %s && chmod 600 %s",
+		path.Dir(dest), base64.StdEncoding.EncodeToString([]byte(file)), dest, dest), nil
+}
+
+// BuildAdmissionConfigFile generates the AdmissionConfiguration holding each
+// plugin's configuration, in plugin name order. It returns an empty string
+// when no plugin is configured.
+func BuildAdmissionConfigFile(admission *AdmissionConfig) (string, error) {
+	if len(admission.Config) == 0 {
+		return "", nil
+	}
+
+	file := admissionConfiguration{
+		APIVersion: "apiserver.config.k8s.io/v1",
+		Kind:       "AdmissionConfiguration",
+	}
+	for _, plugin := range sortedKeys(admission.Config) {
+		var configuration interface{}
+		if err := yaml.Unmarshal([]byte(admission.Config[plugin]), &configuration); err != nil {
+			return "", fmt.Errorf("configuration of admission plugin %s is not valid YAML: %w", plugin, err)
+		}
+		file.Plugins = append(file.Plugins, admissionPluginConfig{Name: plugin, Configuration: configuration})
+	}
+
+	data, err := yaml.Marshal(file)
+	if err != nil {
+		return "", fmt.Errorf("failed to render admission configuration: %w", err)
+	}
+	return string(data), nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
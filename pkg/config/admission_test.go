@@ -0,0 +1,130 @@
+package config
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+const testPodSecurityConfig = `apiVersion: pod-security.admission.config.k8s.io/v1
+kind: PodSecurityConfiguration
+defaults:
+  enforce: baseline
+  warn: restricted
+exemptions:
+  namespaces: [kube-system]
+`
+
+func TestAdmissionAPIServerArgs(t *testing.T) {
+	admission := &AdmissionConfig{
+		Plugins: []string{"NodeRestriction", "PodSecurity"},
+		Config:  map[string]string{"PodSecurity": testPodSecurityConfig},
+	}
+
+	args := AdmissionAPIServerArgs(admission, AdmissionConfigPath)
+
+	expected := []string{
+		"enable-admission-plugins=NodeRestriction,PodSecurity",
+		"admission-control-config-file=" + AdmissionConfigPath,
+	}
+	if strings.Join(args, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected %v, got %v", expected, args)
+	}
+
+	if args := AdmissionAPIServerArgs(&AdmissionConfig{}, AdmissionConfigPath); len(args) != 0 {
+		t.Errorf("Expected no flags without admission settings, got %v", args)
+	}
+}
+
+func TestBuildAdmissionConfigFile(t *testing.T) {
+	admission := &AdmissionConfig{
+		Plugins: []string{"PodSecurity", "EventRateLimit"},
+		Config: map[string]string{
+			"PodSecurity":    testPodSecurityConfig,
+			"EventRateLimit": "apiVersion: eventratelimit.admission.k8s.io/v1alpha1\nkind: Configuration\nlimits:\n  - type: Server\n    qps: 50\n    burst: 100\n",
+		},
+	}
+
+	file, err := BuildAdmissionConfigFile(admission)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"apiVersion: apiserver.config.k8s.io/v1",
+		"kind: AdmissionConfiguration",
+		"- name: EventRateLimit",
+		"- name: PodSecurity",
+		"kind: PodSecurityConfiguration",
+		"enforce: baseline",
+		"qps: 50",
+	}
+	for _, want := range expected {
+		if !strings.Contains(file, want) {
+			t.Errorf("Expected admission config to contain %q, got:\n%s", want, file)
+		}
+	}
+	if strings.Index(file, "name: EventRateLimit") > strings.Index(file, "name: PodSecurity") {
+		t.Error("Expected plugins in name order")
+	}
+}
+
+func TestBuildAdmissionConfigFile_Empty(t *testing.T) {
+	file, err := BuildAdmissionConfigFile(&AdmissionConfig{Plugins: []string{"PodSecurity"}})
+	if err != nil || file != "" {
+		t.Errorf("Expected no file without plugin configuration, got %q, %v", file, err)
+	}
+}
+
+func TestWriteAdmissionConfigCommand(t *testing.T) {
+	admission := &AdmissionConfig{Config: map[string]string{"PodSecurity": testPodSecurityConfig}}
+
+	command, err := WriteAdmissionConfigCommand(admission, K3sAdmissionConfigPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	file, _ := BuildAdmissionConfigFile(admission)
+	expected := "mkdir -p /etc/rancher/k3s && echo " + base64.StdEncoding.EncodeToString([]byte(file)) +
+		" | base64 -d > /etc/rancher/k3s/admission-config.yaml && chmod 600 /etc/rancher/k3s/admission-config.yaml"
+	if command != expected {
+		t.Errorf("Unexpected command\nGot:  %s\nWant: %s", command, expected)
+	}
+
+	if command, err := WriteAdmissionConfigCommand(&AdmissionConfig{Plugins: []string{"PodSecurity"}}, AdmissionConfigPath); err != nil || command != "" {
+		t.Errorf("Expected no command without plugin configuration, got %q, %v", command, err)
+	}
+}
+
+func TestCheckAdmissionConfig(t *testing.T) {
+	errs, warnings := CheckAdmissionConfig(&AdmissionConfig{
+		Plugins: []string{"PodSecurity", "PodSecurityPolicy", "MyWebhook"},
+		Config:  map[string]string{"PodSecurity": "defaults: [unclosed"},
+	})
+
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "PodSecurityPolicy") || !strings.Contains(warnings[1], "MyWebhook") {
+		t.Errorf("Expected warnings for the unknown plugins, got %v", warnings)
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0], "PodSecurity is not valid YAML") {
+		t.Errorf("Expected an error for the invalid configuration, got %v", errs)
+	}
+
+	if err := ValidateAdmissionConfig(&AdmissionConfig{Plugins: []string{"NodeRestriction"}}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestBuildRKE2ServerConfig_Admission(t *testing.T) {
+	k8sConfig := &KubernetesConfig{
+		Admission: AdmissionConfig{
+			Plugins: []string{"PodSecurity"},
+			Config:  map[string]string{"PodSecurity": testPodSecurityConfig},
+		},
+	}
+
+	result := BuildRKE2ServerConfig(GetRKE2Defaults(), "10.8.0.10", "master-1", true, "", k8sConfig)
+
+	expected := "kube-apiserver-arg:\n  - enable-admission-plugins=PodSecurity\n  - admission-control-config-file=" + AdmissionConfigPath + "\n"
+	if !strings.Contains(result, expected) {
+		t.Errorf("Expected admission flags in server config, got:\n%s", result)
+	}
+}
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Ports of the control plane that the API load balancer forwards: the
+// Kubernetes API and the RKE2 supervisor nodes join through
+const (
+	APIServerPort      = 6443
+	RKE2SupervisorPort = 9345
+)
+
+// APILoadBalancer returns the TCP load balancer created in front of the
+// control plane of a highAvailability cluster, or nil without HA or masters.
+// A provider load balancer only reaches that provider's nodes, so it is
+// created on the provider running most masters.
+func (c *ClusterConfig) APILoadBalancer() *LoadBalancerConfig {
+	if !c.Cluster.HighAvailability {
+		return nil
+	}
+	provider := c.APILoadBalancerProvider()
+	if provider == "" {
+		return nil
+	}
+
+	return &LoadBalancerConfig{
+		Name:     fmt.Sprintf("%s-api", c.Metadata.Name),
+		Type:     "tcp",
+		Provider: provider,
+		Ports: []PortConfig{
+			{Name: "kube-apiserver", Port: APIServerPort, TargetPort: APIServerPort, Protocol: "tcp"},
+			{Name: "rke2-supervisor", Port: RKE2SupervisorPort, TargetPort: RKE2SupervisorPort, Protocol: "tcp"},
+		},
+		TargetRole: RoleMaster,
+	}
+}
+
+// APILoadBalancerProvider returns the provider running most masters, the
+// first by name on a tie, or "" without masters
+func (c *ClusterConfig) APILoadBalancerProvider() string {
+	masters := c.mastersByProvider()
+	providers := make([]string, 0, len(masters))
+	for provider := range masters {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	best := ""
+	for _, provider := range providers {
+		if best == "" || masters[provider] > masters[best] {
+			best = provider
+		}
+	}
+	return best
+}
+
+// mastersByProvider counts the master nodes of each provider, standalone
+// nodes and pool nodes alike
+func (c *ClusterConfig) mastersByProvider() map[string]int {
+	masters := make(map[string]int)
+	for _, node := range c.Nodes {
+		if HasRole(node.Roles, RoleMaster) {
+			masters[node.Provider]++
+		}
+	}
+	for name := range c.NodePools {
+		pool := c.NodePools[name]
+		if PoolRole(&pool) == RoleMaster && pool.Count > 0 {
+			masters[pool.Provider] += pool.Count
+		}
+	}
+	return masters
+}
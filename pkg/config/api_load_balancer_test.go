@@ -0,0 +1,53 @@
+package config
+
+import "testing"
+
+func TestAPILoadBalancer(t *testing.T) {
+	cfg := &ClusterConfig{
+		Metadata: Metadata{Name: "prod"},
+		Nodes: []NodeConfig{
+			{Name: "master-a", Provider: "linode", Roles: []string{"controlplane"}},
+		},
+		NodePools: map[string]NodePool{
+			"masters": {Provider: "digitalocean", Count: 2, Roles: []string{"master"}},
+			"workers": {Provider: "linode", Count: 5, Roles: []string{"worker"}},
+		},
+	}
+
+	if lb := cfg.APILoadBalancer(); lb != nil {
+		t.Errorf("Expected no API load balancer without highAvailability, got %+v", lb)
+	}
+
+	cfg.Cluster.HighAvailability = true
+	lb := cfg.APILoadBalancer()
+	if lb == nil {
+		t.Fatal("Expected an API load balancer with highAvailability")
+	}
+	if lb.Name != "prod-api" || lb.Provider != "digitalocean" || lb.TargetRole != RoleMaster {
+		t.Errorf("Expected prod-api on the provider with most masters, got %+v", lb)
+	}
+	if len(lb.Ports) != 2 || lb.Ports[0].Port != APIServerPort || lb.Ports[1].Port != RKE2SupervisorPort {
+		t.Errorf("Expected the API server and supervisor ports, got %+v", lb.Ports)
+	}
+}
+
+func TestAPILoadBalancerProvider(t *testing.T) {
+	cfg := &ClusterConfig{
+		NodePools: map[string]NodePool{
+			"do-masters":     {Provider: "digitalocean", Count: 1, Roles: []string{"master"}},
+			"linode-masters": {Provider: "linode", Count: 1, Roles: []string{"master"}},
+		},
+	}
+	if got := cfg.APILoadBalancerProvider(); got != "digitalocean" {
+		t.Errorf("Expected the first provider by name on a tie, got %q", got)
+	}
+
+	cfg.NodePools = map[string]NodePool{"workers": {Provider: "linode", Count: 3, Roles: []string{"worker"}}}
+	if got := cfg.APILoadBalancerProvider(); got != "" {
+		t.Errorf("Expected no provider without masters, got %q", got)
+	}
+	cfg.Cluster.HighAvailability = true
+	if lb := cfg.APILoadBalancer(); lb != nil {
+		t.Errorf("Expected no API load balancer without masters, got %+v", lb)
+	}
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// BastionOperatorRuleComment tags the bastion firewall rule allowing the
+// operator's IP, so it can be found and replaced when that IP changes
+const BastionOperatorRuleComment = "sloth-operator"
+
+// DefaultSaltAPIUser is the Salt API user when none is configured
+const DefaultSaltAPIUser = "saltapi"
+
+// saltAPIUserPattern matches the user names useradd accepts by default
+var saltAPIUserPattern = regexp.MustCompile(`^[a-z_][a-z0-9_-]{0,31}$`)
+
+// SaltEnabled reports whether the Salt Master and API are installed on the
+// bastion, the default
+func (b *BastionConfig) SaltEnabled() bool {
+	return b.InstallSalt == nil || *b.InstallSalt
+}
+
+// SaltUser returns the Salt API user
+func (b *BastionConfig) SaltUser() string {
+	if b.SaltAPIUser == "" {
+		return DefaultSaltAPIUser
+	}
+	return b.SaltAPIUser
+}
+
+// HostCIDR returns the single-address CIDR of ip: /32 for IPv4, /128 for IPv6
+func HostCIDR(ip string) (string, error) {
+	parsed := net.ParseIP(strings.TrimSpace(ip))
+	if parsed == nil {
+		return "", fmt.Errorf("invalid IP address %q", ip)
+	}
+	if v4 := parsed.To4(); v4 != nil {
+		return v4.String() + "/32", nil
+	}
+	return parsed.String() + "/128", nil
+}
+
+// AddAllowedCIDR adds cidr to the bastion's allowed SSH sources unless it is
+// already there, reporting whether it was added
+func (b *BastionConfig) AddAllowedCIDR(cidr string) bool {
+	for _, existing := range b.AllowedCIDRs {
+		if existing == cidr {
+			return false
+		}
+	}
+	b.AllowedCIDRs = append(b.AllowedCIDRs, cidr)
+	return true
+}
+
+// OpenToAnywhere reports whether the bastion accepts SSH from any address:
+// no allowed CIDRs means no restriction
+func (b *BastionConfig) OpenToAnywhere() bool {
+	if len(b.AllowedCIDRs) == 0 {
+		return true
+	}
+	for _, cidr := range b.AllowedCIDRs {
+		if cidr == "0.0.0.0/0" || cidr == "::/0" {
+			return true
+		}
+	}
+	return false
+}
+
+// Address returns the jump host as an ssh destination, [user@]host[:port]
+func (h JumpHostConfig) Address() string {
+	address := h.Host
+	if h.Port != 0 && h.Port != 22 {
+		address = net.JoinHostPort(h.Host, strconv.Itoa(h.Port))
+	}
+	if h.User != "" {
+		address = h.User + "@" + address
+	}
+	return address
+}
+
+// JumpChain resolves the jump hosts the bastion is reached through by
+// following Upstream, ordered from the first hop out of the local network to
+// the one next to the bastion. It fails on unknown or duplicate names, hosts
+// without an address and chains that loop.
+func (b *BastionConfig) JumpChain() ([]JumpHostConfig, error) {
+	hosts := make(map[string]JumpHostConfig, len(b.JumpHosts))
+	for _, host := range b.JumpHosts {
+		if host.Name == "" {
+			return nil, fmt.Errorf("bastion jump host %q has no name", host.Host)
+		}
+		if host.Host == "" {
+			return nil, fmt.Errorf("bastion jump host %q has no host", host.Name)
+		}
+		if _, ok := hosts[host.Name]; ok {
+			return nil, fmt.Errorf("bastion jump host %q is defined more than once", host.Name)
+		}
+		hosts[host.Name] = host
+	}
+
+	var chain []JumpHostConfig
+	visited := make(map[string]bool)
+	for name := b.Upstream; name != ""; {
+		if visited[name] {
+			return nil, fmt.Errorf("bastion jump hosts form a loop at %q", name)
+		}
+		visited[name] = true
+
+		host, ok := hosts[name]
+		if !ok {
+			return nil, fmt.Errorf("bastion upstream %q is not a defined jump host", name)
+		}
+		chain = append(chain, host)
+		name = host.Upstream
+	}
+
+	// The chain was followed from the bastion outwards
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain, nil
+}
+
+// ValidateBastionSalt checks that the Salt API user is a valid Linux user
+// name that isn't a system account
+func ValidateBastionSalt(b *BastionConfig) error {
+	if b == nil || !b.Enabled || !b.SaltEnabled() {
+		return nil
+	}
+	user := b.SaltUser()
+	if !saltAPIUserPattern.MatchString(user) {
+		return fmt.Errorf("invalid Salt API user %q: must start with a lowercase letter or underscore and contain only lowercase letters, digits, _ and -", user)
+	}
+	if user == "root" || user == "salt" {
+		return fmt.Errorf("invalid Salt API user %q: reserved system account", user)
+	}
+	return nil
+}
+
+// DefaultAuditLogS3Region is the region of the audit log bucket when none is
+// configured
+const DefaultAuditLogS3Region = "us-east-1"
+
+// s3BucketPattern matches S3 bucket names
+var s3BucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+
+// AuditLogS3URL returns the s3:// URL session recordings are shipped to, or
+// "" when shipping is not configured
+func (b *BastionConfig) AuditLogS3URL() string {
+	if b.AuditLogS3Bucket == "" {
+		return ""
+	}
+	return "s3://" + strings.Trim(b.AuditLogS3Bucket, "/") + "/"
+}
+
+// AuditLogRegion returns the region of the audit log bucket
+func (b *BastionConfig) AuditLogRegion() string {
+	if b.AuditLogS3Region == "" {
+		return DefaultAuditLogS3Region
+	}
+	return b.AuditLogS3Region
+}
+
+// ValidateBastionAuditLog checks that audit log shipping and retention are
+// only set with audit logging enabled, and that the bucket and credentials
+// are usable
+func ValidateBastionAuditLog(b *BastionConfig) error {
+	if b == nil || !b.Enabled {
+		return nil
+	}
+	if (b.AuditLogS3Bucket != "" || b.AuditLogRetentionDays != 0) && !b.EnableAuditLog {
+		return fmt.Errorf("auditLogS3Bucket and auditLogRetentionDays require enableAuditLog")
+	}
+	if b.AuditLogRetentionDays < 0 {
+		return fmt.Errorf("invalid auditLogRetentionDays %d: must not be negative", b.AuditLogRetentionDays)
+	}
+	if b.AuditLogS3Bucket != "" {
+		bucket := strings.SplitN(strings.Trim(b.AuditLogS3Bucket, "/"), "/", 2)[0]
+		if !s3BucketPattern.MatchString(bucket) {
+			return fmt.Errorf("invalid auditLogS3Bucket %q: expected bucket or bucket/prefix", b.AuditLogS3Bucket)
+		}
+	}
+	if (b.AuditLogS3AccessKey == "") != (b.AuditLogS3SecretKey == "") {
+		return fmt.Errorf("auditLogS3AccessKey and auditLogS3SecretKey must be set together")
+	}
+	return nil
+}
+
+// ValidateBastionJumpChain checks that the bastion's jump hosts resolve to an
+// acyclic chain
+func ValidateBastionJumpChain(b *BastionConfig) error {
+	if b == nil || !b.Enabled {
+		return nil
+	}
+	_, err := b.JumpChain()
+	return err
+}
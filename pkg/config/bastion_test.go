@@ -0,0 +1,215 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHostCIDR(t *testing.T) {
+	tests := []struct {
+		ip      string
+		want    string
+		wantErr bool
+	}{
+		{ip: "203.0.113.7", want: "203.0.113.7/32"},
+		{ip: " 203.0.113.7\n", want: "203.0.113.7/32"},
+		{ip: "::ffff:203.0.113.7", want: "203.0.113.7/32"},
+		{ip: "2001:db8::1", want: "2001:db8::1/128"},
+		{ip: "203.0.113.7/24", wantErr: true},
+		{ip: "not-an-ip", wantErr: true},
+		{ip: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := HostCIDR(tt.ip)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("HostCIDR(%q) expected error, got %q", tt.ip, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("HostCIDR(%q) = %q, %v; want %q", tt.ip, got, err, tt.want)
+		}
+	}
+}
+
+func TestBastionConfig_AllowedCIDRs(t *testing.T) {
+	b := &BastionConfig{}
+	if !b.OpenToAnywhere() {
+		t.Error("A bastion without allowed CIDRs should be open to anywhere")
+	}
+
+	if !b.AddAllowedCIDR("203.0.113.7/32") || b.AddAllowedCIDR("203.0.113.7/32") {
+		t.Error("Expected the CIDR to be added exactly once")
+	}
+	if b.OpenToAnywhere() {
+		t.Errorf("Bastion restricted to %v should not be open", b.AllowedCIDRs)
+	}
+
+	b.AddAllowedCIDR("0.0.0.0/0")
+	if !b.OpenToAnywhere() {
+		t.Error("0.0.0.0/0 should count as open to anywhere")
+	}
+}
+
+func TestJumpHostConfig_Address(t *testing.T) {
+	tests := map[string]JumpHostConfig{
+		"edge.example.com":          {Host: "edge.example.com"},
+		"ops@edge.example.com":      {Host: "edge.example.com", User: "ops", Port: 22},
+		"ops@edge.example.com:2222": {Host: "edge.example.com", User: "ops", Port: 2222},
+		"[2001:db8::1]:2222":        {Host: "2001:db8::1", Port: 2222},
+	}
+	for want, host := range tests {
+		if got := host.Address(); got != want {
+			t.Errorf("Address() of %+v = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestBastionConfig_JumpChain(t *testing.T) {
+	bastion := &BastionConfig{
+		Enabled:  true,
+		Upstream: "internal",
+		JumpHosts: []JumpHostConfig{
+			{Name: "internal", Host: "10.0.0.5", Upstream: "edge"},
+			{Name: "edge", Host: "edge.example.com", User: "ops"},
+			{Name: "unused", Host: "other.example.com"},
+		},
+	}
+
+	chain, err := bastion.JumpChain()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(chain) != 2 || chain[0].Name != "edge" || chain[1].Name != "internal" {
+		t.Errorf("Expected the chain from the outermost hop, got %+v", chain)
+	}
+
+	if chain, err := (&BastionConfig{Enabled: true}).JumpChain(); err != nil || len(chain) != 0 {
+		t.Errorf("Expected no chain without an upstream, got %+v, %v", chain, err)
+	}
+}
+
+func TestValidateBastionJumpChain(t *testing.T) {
+	tests := []struct {
+		name    string
+		bastion *BastionConfig
+		wantErr string
+	}{
+		{
+			name: "loop",
+			bastion: &BastionConfig{Enabled: true, Upstream: "a", JumpHosts: []JumpHostConfig{
+				{Name: "a", Host: "a.example.com", Upstream: "b"},
+				{Name: "b", Host: "b.example.com", Upstream: "a"},
+			}},
+			wantErr: "loop",
+		},
+		{
+			name: "self reference",
+			bastion: &BastionConfig{Enabled: true, Upstream: "a", JumpHosts: []JumpHostConfig{
+				{Name: "a", Host: "a.example.com", Upstream: "a"},
+			}},
+			wantErr: "loop",
+		},
+		{
+			name:    "unknown upstream",
+			bastion: &BastionConfig{Enabled: true, Upstream: "edge"},
+			wantErr: "not a defined jump host",
+		},
+		{
+			name: "duplicate name",
+			bastion: &BastionConfig{Enabled: true, JumpHosts: []JumpHostConfig{
+				{Name: "a", Host: "a.example.com"},
+				{Name: "a", Host: "b.example.com"},
+			}},
+			wantErr: "more than once",
+		},
+		{
+			name: "missing host",
+			bastion: &BastionConfig{Enabled: true, Upstream: "a", JumpHosts: []JumpHostConfig{
+				{Name: "a"},
+			}},
+			wantErr: "no host",
+		},
+		{
+			name:    "disabled bastion",
+			bastion: &BastionConfig{Upstream: "edge"},
+		},
+	}
+
+	for _, tt := range tests {
+		err := ValidateBastionJumpChain(tt.bastion)
+		if tt.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error: %v", tt.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: expected error containing %q, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
+
+func TestValidateBastionSalt(t *testing.T) {
+	disabled := false
+	tests := []struct {
+		name    string
+		bastion *BastionConfig
+		wantErr bool
+	}{
+		{"default user", &BastionConfig{Enabled: true}, false},
+		{"custom user", &BastionConfig{Enabled: true, SaltAPIUser: "salt-ops"}, false},
+		{"shell characters", &BastionConfig{Enabled: true, SaltAPIUser: "ops;rm"}, true},
+		{"uppercase", &BastionConfig{Enabled: true, SaltAPIUser: "Ops"}, true},
+		{"root", &BastionConfig{Enabled: true, SaltAPIUser: "root"}, true},
+		{"salt disabled", &BastionConfig{Enabled: true, InstallSalt: &disabled, SaltAPIUser: "root"}, false},
+		{"bastion disabled", &BastionConfig{SaltAPIUser: "root"}, false},
+		{"no bastion", nil, false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateBastionSalt(tt.bastion)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidateBastionSalt() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	if user := (&BastionConfig{}).SaltUser(); user != DefaultSaltAPIUser {
+		t.Errorf("SaltUser() = %s, want %s", user, DefaultSaltAPIUser)
+	}
+}
+
+func TestValidateBastionAuditLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		bastion *BastionConfig
+		wantErr bool
+	}{
+		{"audit log only", &BastionConfig{Enabled: true, EnableAuditLog: true}, false},
+		{"bucket with prefix", &BastionConfig{Enabled: true, EnableAuditLog: true, AuditLogS3Bucket: "audit-logs/prod", AuditLogRetentionDays: 30}, false},
+		{"bucket without audit log", &BastionConfig{Enabled: true, AuditLogS3Bucket: "audit-logs"}, true},
+		{"retention without audit log", &BastionConfig{Enabled: true, AuditLogRetentionDays: 7}, true},
+		{"negative retention", &BastionConfig{Enabled: true, EnableAuditLog: true, AuditLogRetentionDays: -1}, true},
+		{"invalid bucket", &BastionConfig{Enabled: true, EnableAuditLog: true, AuditLogS3Bucket: "Audit_Logs"}, true},
+		{"access key without secret", &BastionConfig{Enabled: true, EnableAuditLog: true, AuditLogS3Bucket: "audit-logs", AuditLogS3AccessKey: "AKIA"}, true},
+		{"bastion disabled", &BastionConfig{AuditLogS3Bucket: "Audit_Logs"}, false},
+		{"no bastion", nil, false},
+	}
+
+	for _, tt := range tests {
+		err := ValidateBastionAuditLog(tt.bastion)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidateBastionAuditLog() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+
+	b := &BastionConfig{AuditLogS3Bucket: "/audit-logs/prod/"}
+	if url := b.AuditLogS3URL(); url != "s3://audit-logs/prod/" {
+		t.Errorf("AuditLogS3URL() = %s", url)
+	}
+	if region := b.AuditLogRegion(); region != DefaultAuditLogS3Region {
+		t.Errorf("AuditLogRegion() = %s, want %s", region, DefaultAuditLogS3Region)
+	}
+}
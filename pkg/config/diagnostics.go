@@ -0,0 +1,248 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Diagnostic is a problem found in a configuration, with the YAML path of
+// the offending field (e.g. network.podCidr). Path is empty for problems
+// that are not tied to one field.
+type Diagnostic struct {
+	Path    string
+	Message string
+}
+
+func (d Diagnostic) String() string {
+	if d.Path == "" {
+		return d.Message
+	}
+	return fmt.Sprintf("%s: %s", d.Path, d.Message)
+}
+
+// bastionProviders lists the providers a bastion can be created on
+var bastionProviders = []string{"digitalocean", "linode", "azure", "aws", "gcp"}
+
+// Diagnose checks the configuration and returns every problem found, in a
+// stable order, instead of stopping at the first one
+func Diagnose(cfg *ClusterConfig) []Diagnostic {
+	var diags []Diagnostic
+	add := func(path, format string, args ...interface{}) {
+		diags = append(diags, Diagnostic{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+	addErr := func(path string, err error) {
+		if err != nil {
+			add(path, "%v", err)
+		}
+	}
+
+	if cfg.Metadata.Name == "" {
+		add("metadata.name", "cluster name is required")
+	}
+
+	diagnoseProviders(cfg, add)
+	diagnoseNodes(cfg, add)
+	diagnoseCIDRs(cfg, add)
+	diagnoseWireGuard(cfg.Network.WireGuard, add)
+
+	if b := cfg.Security.Bastion; b != nil && b.Enabled {
+		if !stringInSlice(bastionProviders, b.Provider) {
+			add("security.bastion.provider", "unsupported bastion provider %q (supported: %s)", b.Provider, strings.Join(bastionProviders, ", "))
+		}
+		addErr("security.bastion.saltApiUser", ValidateBastionSalt(b))
+		addErr("security.bastion.jumpHosts", ValidateBastionJumpChain(b))
+		addErr("security.bastion.auditLogS3Bucket", ValidateBastionAuditLog(b))
+	}
+
+	addErr("kubernetes.rke2.disableComponents", ValidateDisabledComponents(cfg))
+	addErr("kubernetes.admission", ValidateAdmissionConfig(&cfg.Kubernetes.Admission))
+	addErr("kubernetes.readyTimeout", ValidateKubernetesReadyWait(&cfg.Kubernetes))
+	addErr("network.dns.cloudflare.apiToken", ValidateDNSCredentials(&cfg.Network.DNS))
+	addErr("network.ingress.controller", ValidateIngressController(&cfg.Network.Ingress))
+	addErr("security.downloadChecksums", ValidateDownloadChecksums(&cfg.Security))
+	addErr("monitoring", ValidateMonitoring(cfg))
+	addErr("", ValidateAdminPasswords(cfg))
+
+	return diags
+}
+
+// providerEnabled reports whether the named provider is enabled
+func providerEnabled(cfg *ClusterConfig, provider string) bool {
+	p := cfg.Providers
+	switch provider {
+	case "digitalocean":
+		return p.DigitalOcean != nil && p.DigitalOcean.Enabled
+	case "linode":
+		return p.Linode != nil && p.Linode.Enabled
+	case "aws":
+		return p.AWS != nil && p.AWS.Enabled
+	case "azure":
+		return p.Azure != nil && p.Azure.Enabled
+	case "gcp":
+		return p.GCP != nil && p.GCP.Enabled
+	case "vultr":
+		return p.Vultr != nil && p.Vultr.Enabled
+	}
+	return false
+}
+
+func diagnoseProviders(cfg *ClusterConfig, add func(path, format string, args ...interface{})) {
+	enabled := 0
+	for _, provider := range SupportedProviders {
+		if providerEnabled(cfg, provider) {
+			enabled++
+		}
+	}
+	if enabled == 0 {
+		add("providers", "at least one cloud provider must be enabled")
+	}
+
+	if providerEnabled(cfg, "digitalocean") && cfg.Providers.DigitalOcean.Token == "" {
+		add("providers.digitalocean.token", "DigitalOcean API token is required when the provider is enabled")
+	}
+	if providerEnabled(cfg, "linode") && cfg.Providers.Linode.Token == "" {
+		add("providers.linode.token", "Linode API token is required when the provider is enabled")
+	}
+	if providerEnabled(cfg, "vultr") && cfg.Providers.Vultr.APIKey == "" {
+		add("providers.vultr.apiKey", "Vultr API key is required when the provider is enabled")
+	}
+}
+
+// diagnoseNodeProvider checks that provider is supported and enabled
+func diagnoseNodeProvider(cfg *ClusterConfig, path, provider string, add func(path, format string, args ...interface{})) {
+	switch {
+	case provider == "":
+		add(path, "provider is required")
+	case !stringInSlice(SupportedProviders, provider):
+		add(path, "unsupported provider %q (supported: %s)", provider, strings.Join(SupportedProviders, ", "))
+	case !providerEnabled(cfg, provider):
+		add(path, "provider %q is not enabled under providers", provider)
+	}
+}
+
+func diagnoseNodes(cfg *ClusterConfig, add func(path, format string, args ...interface{})) {
+	if len(cfg.Nodes) == 0 && len(cfg.NodePools) == 0 {
+		add("nodePools", "at least one node or node pool must be configured")
+		return
+	}
+
+	masters := 0
+	for i, node := range cfg.Nodes {
+		diagnoseNodeProvider(cfg, fmt.Sprintf("nodes[%d].provider", i), node.Provider, add)
+		if HasRole(node.Roles, RoleMaster) {
+			masters++
+		}
+	}
+	for _, name := range SortedPoolNames(cfg.NodePools) {
+		pool := cfg.NodePools[name]
+		diagnoseNodeProvider(cfg, fmt.Sprintf("nodePools.%s.provider", name), pool.Provider, add)
+		if pool.Count < 1 {
+			add(fmt.Sprintf("nodePools.%s.count", name), "count must be at least 1 (got %d)", pool.Count)
+		}
+		if HasRole(pool.Roles, RoleMaster) {
+			masters += pool.Count
+		}
+	}
+
+	switch {
+	case masters == 0:
+		add("nodePools", "at least one control plane node is required")
+	case masters%2 == 0:
+		add("nodePools", "control plane node count must be odd for etcd quorum (got %d)", masters)
+	}
+}
+
+// cidrField is a CIDR set in the configuration
+type cidrField struct {
+	path  string
+	value string
+	ipNet *net.IPNet
+}
+
+// sameRange lists pairs of fields that configure the same range in two
+// places, so they may overlap
+var sameRange = map[[2]string]bool{
+	{"network.podCidr", "kubernetes.podCidr"}:         true,
+	{"network.serviceCidr", "kubernetes.serviceCidr"}: true,
+}
+
+func diagnoseCIDRs(cfg *ClusterConfig, add func(path, format string, args ...interface{})) {
+	candidates := []cidrField{
+		{path: "network.cidr", value: cfg.Network.CIDR},
+		{path: "network.podCidr", value: cfg.Network.PodCIDR},
+		{path: "network.serviceCidr", value: cfg.Network.ServiceCIDR},
+		{path: "network.podCidrV6", value: cfg.Network.PodCIDRv6},
+		{path: "network.serviceCidrV6", value: cfg.Network.ServiceCIDRv6},
+		{path: "kubernetes.podCidr", value: cfg.Kubernetes.PodCIDR},
+		{path: "kubernetes.serviceCidr", value: cfg.Kubernetes.ServiceCIDR},
+	}
+	if w := cfg.Network.WireGuard; w != nil && w.Enabled {
+		candidates = append(candidates, cidrField{path: "network.wireguard.subnetCidr", value: w.Subnet()})
+	}
+
+	var fields []cidrField
+	for _, f := range candidates {
+		if f.value == "" {
+			continue
+		}
+		_, ipNet, err := net.ParseCIDR(f.value)
+		if err != nil {
+			add(f.path, "invalid CIDR %q", f.value)
+			continue
+		}
+		f.ipNet = ipNet
+		fields = append(fields, f)
+	}
+
+	for i := range fields {
+		for j := i + 1; j < len(fields); j++ {
+			a, b := fields[i], fields[j]
+			if sameRange[[2]string{a.path, b.path}] {
+				continue
+			}
+			if a.ipNet.Contains(b.ipNet.IP) || b.ipNet.Contains(a.ipNet.IP) {
+				add(b.path, "%s overlaps %s (%s)", b.value, a.path, a.value)
+			}
+		}
+	}
+}
+
+func diagnoseWireGuard(w *WireGuardConfig, add func(path, format string, args ...interface{})) {
+	if w == nil || !w.Enabled {
+		return
+	}
+
+	// Invalid CIDRs are reported by diagnoseCIDRs
+	if _, _, err := net.ParseCIDR(w.Subnet()); err == nil {
+		if _, err := ParseWireGuardSubnet(w.Subnet()); err != nil {
+			add("network.wireguard.subnetCidr", "%v", err)
+		}
+	}
+
+	if w.Create {
+		if w.Provider == "" {
+			add("network.wireguard.provider", "provider is required when create is true")
+		}
+		if w.Region == "" {
+			add("network.wireguard.region", "region is required when create is true")
+		}
+		return
+	}
+
+	if w.ServerEndpoint == "" {
+		add("network.wireguard.serverEndpoint", "serverEndpoint of the existing server is required when WireGuard is enabled without create")
+	}
+	if w.ServerPublicKey == "" {
+		add("network.wireguard.serverPublicKey", "serverPublicKey of the existing server is required when WireGuard is enabled without create")
+	}
+}
+
+func stringInSlice(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
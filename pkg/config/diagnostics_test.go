@@ -0,0 +1,123 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// diagnosticPaths returns the paths of diags, keyed for lookup
+func diagnosticPaths(diags []Diagnostic) map[string]bool {
+	paths := make(map[string]bool)
+	for _, d := range diags {
+		paths[d.Path] = true
+	}
+	return paths
+}
+
+func validDiagnoseConfig() *ClusterConfig {
+	return &ClusterConfig{
+		Metadata: Metadata{Name: "prod"},
+		Providers: ProvidersConfig{
+			DigitalOcean: &DigitalOceanProvider{Enabled: true, Token: "token"},
+		},
+		Network: NetworkConfig{CIDR: "10.0.0.0/16"},
+		Kubernetes: KubernetesConfig{
+			PodCIDR:     "10.42.0.0/16",
+			ServiceCIDR: "10.43.0.0/16",
+		},
+		NodePools: map[string]NodePool{
+			"masters": {Provider: "digitalocean", Count: 3, Roles: []string{"master"}},
+			"workers": {Provider: "digitalocean", Count: 2, Roles: []string{"worker"}},
+		},
+	}
+}
+
+func TestDiagnose_Valid(t *testing.T) {
+	if diags := Diagnose(validDiagnoseConfig()); len(diags) != 0 {
+		t.Errorf("Expected no problems, got %v", diags)
+	}
+}
+
+func TestDiagnose_ReportsEveryProblem(t *testing.T) {
+	cfg := validDiagnoseConfig()
+	cfg.Providers.DigitalOcean.Token = ""
+	cfg.Network.PodCIDR = "10.0.128.0/17"
+	cfg.NodePools["masters"] = NodePool{Provider: "digitalocean", Count: 2, Roles: []string{"master"}}
+	cfg.NodePools["workers"] = NodePool{Provider: "linode", Count: 2, Roles: []string{"worker"}}
+	cfg.Network.WireGuard = &WireGuardConfig{Enabled: true}
+	cfg.Security.Bastion = &BastionConfig{Enabled: true, Provider: "hetzner"}
+
+	paths := diagnosticPaths(Diagnose(cfg))
+	for _, want := range []string{
+		"providers.digitalocean.token",
+		"network.podCidr",
+		"nodePools",
+		"nodePools.workers.provider",
+		"network.wireguard.serverEndpoint",
+		"network.wireguard.serverPublicKey",
+		"security.bastion.provider",
+	} {
+		if !paths[want] {
+			t.Errorf("Expected a problem at %s, got %v", want, paths)
+		}
+	}
+}
+
+func TestDiagnose_SameRangeInTwoPlaces(t *testing.T) {
+	cfg := validDiagnoseConfig()
+	cfg.Network.PodCIDR = cfg.Kubernetes.PodCIDR
+	cfg.Network.ServiceCIDR = cfg.Kubernetes.ServiceCIDR
+	if diags := Diagnose(cfg); len(diags) != 0 {
+		t.Errorf("Expected the pod and service CIDRs to be accepted in both places, got %v", diags)
+	}
+}
+
+func TestDiagnose_InvalidCIDR(t *testing.T) {
+	cfg := validDiagnoseConfig()
+	cfg.Kubernetes.ServiceCIDR = "10.43.0.0"
+	diags := Diagnose(cfg)
+	if len(diags) != 1 || diags[0].Path != "kubernetes.serviceCidr" {
+		t.Errorf("Expected one problem at kubernetes.serviceCidr, got %v", diags)
+	}
+}
+
+func TestLoaderParse_DoesNotValidate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cluster.yaml")
+	if err := os.WriteFile(path, []byte("metadata:\n  name: prod\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewLoader(path)
+	if _, err := loader.Load(); err == nil {
+		t.Fatal("Expected Load to reject a config without providers")
+	}
+	cfg, err := loader.Parse()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	paths := diagnosticPaths(loader.Diagnose(cfg))
+	if !paths["providers"] || !paths["nodePools"] {
+		t.Errorf("Expected missing providers and nodes, got %v", paths)
+	}
+}
+
+func TestDiagnose_VultrAPIKey(t *testing.T) {
+	cfg := validDiagnoseConfig()
+	cfg.Providers.Vultr = &VultrProvider{Enabled: true, Region: "ewr"}
+	cfg.NodePools["workers"] = NodePool{Provider: "vultr", Count: 2, Roles: []string{"worker"}}
+
+	diags := Diagnose(cfg)
+	if len(diags) != 1 || diags[0].Path != "providers.vultr.apiKey" {
+		t.Errorf("Expected only the missing Vultr API key, got %v", diags)
+	}
+}
+
+func TestDiagnose_WireGuardSubnetTooSmall(t *testing.T) {
+	cfg := validDiagnoseConfig()
+	cfg.Network.WireGuard = &WireGuardConfig{Enabled: true, Create: true, Provider: "digitalocean", Region: "nyc3", SubnetCIDR: "10.100.0.0/28"}
+	diags := Diagnose(cfg)
+	if len(diags) != 1 || diags[0].Path != "network.wireguard.subnetCidr" {
+		t.Errorf("Expected one problem at network.wireguard.subnetCidr, got %v", diags)
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// DNS providers the cluster records can be managed with
+const (
+	DNSProviderDigitalOcean = "digitalocean"
+	DNSProviderCloudflare   = "cloudflare"
+)
+
+// EnvCloudflareAPIToken is read when network.dns.cloudflare.apiToken is unset
+const EnvCloudflareAPIToken = "CLOUDFLARE_API_TOKEN"
+
+// UsesCloudflare reports whether the cluster records are managed through
+// Cloudflare
+func (d *DNSConfig) UsesCloudflare() bool {
+	return d.Provider == DNSProviderCloudflare
+}
+
+// CloudflareAPIToken returns the configured Cloudflare token, falling back
+// to CLOUDFLARE_API_TOKEN
+func (d *DNSConfig) CloudflareAPIToken() string {
+	if d.Cloudflare != nil && d.Cloudflare.APIToken != "" {
+		return d.Cloudflare.APIToken
+	}
+	return os.Getenv(EnvCloudflareAPIToken)
+}
+
+// ValidateDNSCredentials checks that the DNS provider can be authenticated
+// against
+func ValidateDNSCredentials(d *DNSConfig) error {
+	if d.UsesCloudflare() && d.CloudflareAPIToken() == "" {
+		return fmt.Errorf("Cloudflare API token is required (set %s or network.dns.cloudflare.apiToken)", EnvCloudflareAPIToken)
+	}
+	return nil
+}
@@ -0,0 +1,33 @@
+package config
+
+import "testing"
+
+func TestCloudflareAPIToken(t *testing.T) {
+	t.Setenv(EnvCloudflareAPIToken, "env-token")
+
+	d := &DNSConfig{Provider: DNSProviderCloudflare}
+	if got := d.CloudflareAPIToken(); got != "env-token" {
+		t.Errorf("Expected the token from %s, got %q", EnvCloudflareAPIToken, got)
+	}
+
+	d.Cloudflare = &CloudflareDNSConfig{APIToken: "config-token"}
+	if got := d.CloudflareAPIToken(); got != "config-token" {
+		t.Errorf("Expected the configured token to win, got %q", got)
+	}
+}
+
+func TestValidateDNSCredentials(t *testing.T) {
+	t.Setenv(EnvCloudflareAPIToken, "")
+
+	if err := ValidateDNSCredentials(&DNSConfig{Provider: DNSProviderDigitalOcean}); err != nil {
+		t.Errorf("Expected DigitalOcean to need no Cloudflare token, got %v", err)
+	}
+	if err := ValidateDNSCredentials(&DNSConfig{Provider: DNSProviderCloudflare}); err == nil {
+		t.Error("Expected Cloudflare without a token to be rejected")
+	}
+
+	t.Setenv(EnvCloudflareAPIToken, "env-token")
+	if err := ValidateDNSCredentials(&DNSConfig{Provider: DNSProviderCloudflare}); err != nil {
+		t.Errorf("Expected the token from the environment to be accepted, got %v", err)
+	}
+}
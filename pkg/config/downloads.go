@@ -0,0 +1,109 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Downloads checked before they run on hosts, the keys of
+// security.downloadChecksums
+const (
+	DownloadRKE2Installer = "rke2-installer"
+	DownloadK3sInstaller  = "k3s-installer"
+	DownloadSaltBootstrap = "salt-bootstrap"
+)
+
+// Releases whose install scripts are fetched when the config names none, so
+// that the scripts do not change under their pinned checksums
+const (
+	DefaultK3sVersion    = "v1.30.4+k3s1"
+	SaltBootstrapVersion = "v2024.12.12"
+)
+
+// Install script locations
+const (
+	// rke2InstallScriptURL serves the latest install script, which changes
+	// between releases
+	rke2InstallScriptURL = "https://get.rke2.io"
+
+	// rke2ReleaseInstallScriptURL is the install script of one release, which
+	// does not change and so can be pinned
+	rke2ReleaseInstallScriptURL = "https://raw.githubusercontent.com/rancher/rke2/%s/install.sh"
+
+	// k3sReleaseInstallScriptURL is the K3s install script of one release
+	k3sReleaseInstallScriptURL = "https://raw.githubusercontent.com/k3s-io/k3s/%s/install.sh"
+
+	// SaltBootstrapURL is the Salt bootstrap script of SaltBootstrapVersion
+	SaltBootstrapURL = "https://github.com/saltstack/salt-bootstrap/releases/download/" + SaltBootstrapVersion + "/bootstrap-salt.sh"
+)
+
+// knownDownloadChecksums are the SHA256 of vetted install scripts by URL,
+// used for downloads security.downloadChecksums does not pin. Downloads
+// without either run after a warning.
+var knownDownloadChecksums = map[string]string{}
+
+// sha256Pattern matches a hex encoded SHA256
+var sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// Download is a script fetched onto a host before it runs there
+type Download struct {
+	Name   string
+	URL    string
+	SHA256 string // Expected checksum, empty when none is pinned
+	Verify bool
+}
+
+// DownloadsVerified reports whether downloads are checked against their
+// pinned checksums, the default
+func (s *SecurityConfig) DownloadsVerified() bool {
+	return s == nil || s.VerifyDownloads == nil || *s.VerifyDownloads
+}
+
+// Download returns the download name fetched from url, with the checksum
+// pinned in the config or known for url
+func (s *SecurityConfig) Download(name, url string) Download {
+	d := Download{Name: name, URL: url, Verify: s.DownloadsVerified()}
+	if s != nil && s.DownloadChecksums[name] != "" {
+		d.SHA256 = strings.ToLower(s.DownloadChecksums[name])
+	} else {
+		d.SHA256 = knownDownloadChecksums[url]
+	}
+	return d
+}
+
+// SaltBootstrapDownload returns the Salt bootstrap script download
+func (s *SecurityConfig) SaltBootstrapDownload() Download {
+	return s.Download(DownloadSaltBootstrap, SaltBootstrapURL)
+}
+
+// Fetch returns a command that downloads d to dest. When verified it fails,
+// removing the file, unless the file matches the pinned checksum.
+func (d Download) Fetch(dest string) string {
+	fetch := fmt.Sprintf("curl -sfL %s -o %s", d.URL, dest)
+	if !d.Verify {
+		return fetch
+	}
+	if d.SHA256 == "" {
+		return fetch + fmt.Sprintf(` && echo "WARNING: no checksum pinned for %s, running it unverified" >&2`, d.Name)
+	}
+	return fetch + fmt.Sprintf(
+		` && { echo "%[1]s  %[2]s" | sha256sum -c --status || { echo "ERROR: %[3]s checksum mismatch: expected %[1]s, got $(sha256sum %[2]s | cut -c1-64)" >&2; rm -f %[2]s; false; }; }`,
+		d.SHA256, dest, d.Name)
+}
+
+// ValidateDownloadChecksums checks that pinned checksums name a known
+// download and are SHA256 digests
+func ValidateDownloadChecksums(s *SecurityConfig) error {
+	for name, sum := range s.DownloadChecksums {
+		switch name {
+		case DownloadRKE2Installer, DownloadK3sInstaller, DownloadSaltBootstrap:
+		default:
+			return fmt.Errorf("security.downloadChecksums: unknown download %q (expected %s, %s or %s)", name, DownloadRKE2Installer, DownloadK3sInstaller, DownloadSaltBootstrap)
+		}
+		if !sha256Pattern.MatchString(sum) {
+			return fmt.Errorf("security.downloadChecksums.%s: %q is not a SHA256 digest", name, sum)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runFetch downloads a local file with the command of d and returns the
+// command output and whether it succeeded
+func runFetch(t *testing.T, d Download, dest string) (string, bool) {
+	t.Helper()
+	for _, tool := range []string{"bash", "curl", "sha256sum"} {
+		if _, err := exec.LookPath(tool); err != nil {
+			t.Skipf("%s not available", tool)
+		}
+	}
+	out, err := exec.Command("bash", "-c", d.Fetch(dest)).CombinedOutput()
+	return string(out), err == nil
+}
+
+func TestDownloadFetch_VerifiesChecksum(t *testing.T) {
+	dir := t.TempDir()
+	script := []byte("#!/bin/sh\necho installed\n")
+	src := filepath.Join(dir, "install.sh")
+	if err := os.WriteFile(src, script, 0644); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(script)
+	good := hex.EncodeToString(sum[:])
+	dest := filepath.Join(dir, "download.sh")
+
+	d := Download{Name: DownloadRKE2Installer, URL: "file://" + src, SHA256: good, Verify: true}
+	if out, ok := runFetch(t, d, dest); !ok {
+		t.Fatalf("Expected matching download to pass, got: %s", out)
+	}
+	if _, err := os.Stat(dest); err != nil {
+		t.Errorf("Expected verified download to be kept: %v", err)
+	}
+
+	d.SHA256 = strings.Repeat("0", 64)
+	out, ok := runFetch(t, d, dest)
+	if ok {
+		t.Fatal("Expected mismatching download to fail")
+	}
+	if !strings.Contains(out, "checksum mismatch") || !strings.Contains(out, good) {
+		t.Errorf("Expected mismatch error with the actual checksum, got: %s", out)
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("Expected mismatching download to be removed")
+	}
+
+	d.Verify = false
+	if out, ok := runFetch(t, d, dest); !ok {
+		t.Errorf("Expected unverified download to pass, got: %s", out)
+	}
+}
+
+func TestDownloadFetch_Unpinned(t *testing.T) {
+	d := Download{Name: DownloadSaltBootstrap, URL: SaltBootstrapURL, Verify: true}
+	cmd := d.Fetch("/tmp/bootstrap-salt.sh")
+	if strings.Contains(cmd, "sha256sum") {
+		t.Errorf("Expected no checksum check without a pin, got: %s", cmd)
+	}
+	if !strings.Contains(cmd, "WARNING: no checksum pinned for salt-bootstrap") {
+		t.Errorf("Expected a warning for the unpinned download, got: %s", cmd)
+	}
+}
+
+func TestSecurityConfigDownload(t *testing.T) {
+	url := "https://example.com/install.sh"
+	known := strings.Repeat("a", 64)
+	knownDownloadChecksums[url] = known
+	defer delete(knownDownloadChecksums, url)
+
+	var unset *SecurityConfig
+	if d := unset.Download(DownloadRKE2Installer, url); !d.Verify || d.SHA256 != known {
+		t.Errorf("Expected verified download with the known checksum by default, got %+v", d)
+	}
+
+	pinned := strings.Repeat("B", 64)
+	disabled := false
+	s := &SecurityConfig{
+		VerifyDownloads:   &disabled,
+		DownloadChecksums: map[string]string{DownloadRKE2Installer: pinned},
+	}
+	d := s.Download(DownloadRKE2Installer, url)
+	if d.Verify {
+		t.Error("Expected verification to be disabled")
+	}
+	if d.SHA256 != strings.ToLower(pinned) {
+		t.Errorf("Expected the pinned checksum to override the known one, got %q", d.SHA256)
+	}
+}
+
+func TestValidateDownloadChecksums(t *testing.T) {
+	tests := []struct {
+		name      string
+		checksums map[string]string
+		wantErr   string
+	}{
+		{name: "none"},
+		{name: "valid", checksums: map[string]string{DownloadSaltBootstrap: strings.Repeat("f", 64)}},
+		{name: "k3s installer", checksums: map[string]string{DownloadK3sInstaller: strings.Repeat("f", 64)}},
+		{name: "unknown download", checksums: map[string]string{"k3s": strings.Repeat("f", 64)}, wantErr: "unknown download"},
+		{name: "not a digest", checksums: map[string]string{DownloadRKE2Installer: "abc123"}, wantErr: "not a SHA256 digest"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateDownloadChecksums(&SecurityConfig{DownloadChecksums: tt.checksums})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
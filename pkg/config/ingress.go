@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Ingress controllers of IngressConfig.Controller
+const (
+	IngressControllerNginx   = "nginx"
+	IngressControllerTraefik = "traefik"
+	IngressControllerHAProxy = "haproxy"
+)
+
+// IngressControllers lists the supported ingress controllers
+var IngressControllers = []string{IngressControllerNginx, IngressControllerTraefik, IngressControllerHAProxy}
+
+// ControllerName returns the ingress controller to install, nginx by default
+func (i *IngressConfig) ControllerName() string {
+	if i.Controller == "" {
+		return IngressControllerNginx
+	}
+	return strings.ToLower(i.Controller)
+}
+
+// ClassName returns the ingress class served by the controller, named after
+// the controller unless set
+func (i *IngressConfig) ClassName() string {
+	if i.Class != "" {
+		return i.Class
+	}
+	return i.ControllerName()
+}
+
+// ValidateIngressController checks that the ingress controller is supported
+func ValidateIngressController(i *IngressConfig) error {
+	controller := i.ControllerName()
+	for _, supported := range IngressControllers {
+		if controller == supported {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported ingress controller %q (supported: %s)", i.Controller, strings.Join(IngressControllers, ", "))
+}
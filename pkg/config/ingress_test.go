@@ -0,0 +1,40 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIngressConfig_ControllerName(t *testing.T) {
+	tests := []struct {
+		ingress        IngressConfig
+		wantController string
+		wantClass      string
+	}{
+		{IngressConfig{}, "nginx", "nginx"},
+		{IngressConfig{Controller: "Traefik"}, "traefik", "traefik"},
+		{IngressConfig{Controller: "haproxy", Class: "public"}, "haproxy", "public"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.ingress.ControllerName(); got != tt.wantController {
+			t.Errorf("ControllerName() of %+v = %q, want %q", tt.ingress, got, tt.wantController)
+		}
+		if got := tt.ingress.ClassName(); got != tt.wantClass {
+			t.Errorf("ClassName() of %+v = %q, want %q", tt.ingress, got, tt.wantClass)
+		}
+	}
+}
+
+func TestValidateIngressController(t *testing.T) {
+	for _, controller := range []string{"", "nginx", "traefik", "HAProxy"} {
+		if err := ValidateIngressController(&IngressConfig{Controller: controller}); err != nil {
+			t.Errorf("Expected %q to be supported, got %v", controller, err)
+		}
+	}
+
+	err := ValidateIngressController(&IngressConfig{Controller: "kong"})
+	if err == nil || !strings.Contains(err.Error(), "nginx, traefik, haproxy") {
+		t.Errorf("Expected kong to be rejected with the supported list, got %v", err)
+	}
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// secretReferencePattern matches ${ENV_VAR} and ${file:/path} references
+var secretReferencePattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// secretField is a secret string field that may hold references
+type secretField struct {
+	path  string
+	value *string
+	// required fields fail to load when a reference cannot be resolved;
+	// others keep the reference as written
+	required bool
+}
+
+// secretFields returns the secret fields of cfg that references are
+// expanded in
+func secretFields(cfg *ClusterConfig) []secretField {
+	var fields []secretField
+	if do := cfg.Providers.DigitalOcean; do != nil {
+		fields = append(fields, secretField{"providers.digitalocean.token", &do.Token, do.Enabled})
+	}
+	if linode := cfg.Providers.Linode; linode != nil {
+		fields = append(fields,
+			secretField{"providers.linode.token", &linode.Token, linode.Enabled},
+			secretField{"providers.linode.rootPassword", &linode.RootPassword, linode.Enabled},
+		)
+	}
+	if vultr := cfg.Providers.Vultr; vultr != nil {
+		fields = append(fields, secretField{"providers.vultr.apiKey", &vultr.APIKey, vultr.Enabled})
+	}
+	if cf := cfg.Network.DNS.Cloudflare; cf != nil {
+		fields = append(fields, secretField{"network.dns.cloudflare.apiToken", &cf.APIToken, cfg.Network.DNS.UsesCloudflare()})
+	}
+	if rke2 := cfg.Kubernetes.RKE2; rke2 != nil {
+		fields = append(fields, secretField{"kubernetes.rke2.clusterToken", &rke2.ClusterToken, true})
+	}
+	if b := cfg.Security.Bastion; b != nil {
+		shipping := b.Enabled && b.EnableAuditLog && b.AuditLogS3Bucket != ""
+		fields = append(fields,
+			secretField{"security.bastion.auditLogS3AccessKey", &b.AuditLogS3AccessKey, shipping},
+			secretField{"security.bastion.auditLogS3SecretKey", &b.AuditLogS3SecretKey, shipping},
+		)
+	}
+	if grafana := cfg.Monitoring.Grafana; grafana != nil {
+		fields = append(fields, secretField{"monitoring.grafana.adminPassword", &grafana.AdminPassword, grafana.Enabled})
+	}
+	return fields
+}
+
+// interpolateSecrets expands references in the secret fields of cfg
+func interpolateSecrets(cfg *ClusterConfig) error {
+	for _, field := range secretFields(cfg) {
+		value, err := resolveSecretReferences(*field.value)
+		if err != nil {
+			if field.required {
+				return fmt.Errorf("%s: %w", field.path, err)
+			}
+			continue
+		}
+		*field.value = value
+	}
+	return nil
+}
+
+// resolveSecretReferences replaces each ${ENV_VAR} in value with the
+// variable's value and each ${file:/path} with the file's content, without
+// its trailing newline. It fails on the first reference that cannot be
+// resolved.
+func resolveSecretReferences(value string) (string, error) {
+	var resolveErr error
+	result := secretReferencePattern.ReplaceAllStringFunc(value, func(match string) string {
+		if resolveErr != nil {
+			return match
+		}
+		ref := match[2 : len(match)-1]
+
+		if path, ok := strings.CutPrefix(ref, "file:"); ok {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				resolveErr = fmt.Errorf("cannot read %s for ${%s}: %w", path, ref, err)
+				return match
+			}
+			return strings.TrimRight(string(data), "\r\n")
+		}
+
+		val, ok := os.LookupEnv(ref)
+		if !ok {
+			resolveErr = fmt.Errorf("environment variable %s is not set", ref)
+			return match
+		}
+		return val
+	})
+	if resolveErr != nil {
+		return "", resolveErr
+	}
+	return result, nil
+}
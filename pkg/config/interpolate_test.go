@@ -0,0 +1,120 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeLoaderConfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cluster.yaml")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const interpolatedConfig = `metadata:
+  name: prod
+providers:
+  digitalocean:
+    enabled: true
+    token: ${SLOTH_TEST_DO_TOKEN}
+  linode:
+    enabled: true
+    token: ${SLOTH_TEST_LINODE_TOKEN}
+    rootPassword: ${file:%s}
+kubernetes:
+  rke2:
+    clusterToken: prefix-${SLOTH_TEST_RKE2_TOKEN}
+monitoring:
+  grafana:
+    enabled: true
+    adminPassword: ${SLOTH_TEST_GRAFANA_PASSWORD}
+nodePools:
+  masters:
+    provider: digitalocean
+    count: 1
+    roles: [master]
+`
+
+func TestLoader_InterpolatesSecrets(t *testing.T) {
+	passwordFile := filepath.Join(t.TempDir(), "root-password")
+	if err := os.WriteFile(passwordFile, []byte("s3cret-root\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("SLOTH_TEST_DO_TOKEN", "do-token")
+	t.Setenv("SLOTH_TEST_LINODE_TOKEN", "linode-token")
+	t.Setenv("SLOTH_TEST_RKE2_TOKEN", "rke2-token")
+	t.Setenv("SLOTH_TEST_GRAFANA_PASSWORD", "grafana-password")
+
+	path := writeLoaderConfig(t, strings.Replace(interpolatedConfig, "%s", passwordFile, 1))
+	cfg, err := NewLoader(path).Load()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if cfg.Providers.DigitalOcean.Token != "do-token" {
+		t.Errorf("Unexpected DigitalOcean token %q", cfg.Providers.DigitalOcean.Token)
+	}
+	if cfg.Providers.Linode.Token != "linode-token" {
+		t.Errorf("Unexpected Linode token %q", cfg.Providers.Linode.Token)
+	}
+	if cfg.Providers.Linode.RootPassword != "s3cret-root" {
+		t.Errorf("Expected the root password from the file without its newline, got %q", cfg.Providers.Linode.RootPassword)
+	}
+	if cfg.Kubernetes.RKE2.ClusterToken != "prefix-rke2-token" {
+		t.Errorf("Unexpected RKE2 cluster token %q", cfg.Kubernetes.RKE2.ClusterToken)
+	}
+	if cfg.Monitoring.Grafana.AdminPassword != "grafana-password" {
+		t.Errorf("Unexpected Grafana password %q", cfg.Monitoring.Grafana.AdminPassword)
+	}
+}
+
+func TestLoader_UnresolvedSecret(t *testing.T) {
+	path := writeLoaderConfig(t, `metadata:
+  name: prod
+providers:
+  digitalocean:
+    enabled: true
+    token: ${SLOTH_TEST_UNSET_TOKEN}
+`)
+
+	_, err := NewLoader(path).Load()
+	if err == nil {
+		t.Fatal("Expected an error for an unset variable")
+	}
+	for _, want := range []string{"providers.digitalocean.token", "SLOTH_TEST_UNSET_TOKEN"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to name %s, got %v", want, err)
+		}
+	}
+}
+
+func TestResolveSecretReferences(t *testing.T) {
+	t.Setenv("SLOTH_TEST_EMPTY", "")
+
+	if got, err := resolveSecretReferences("plain"); err != nil || got != "plain" {
+		t.Errorf("Expected a value without references unchanged, got %q (%v)", got, err)
+	}
+	if got, err := resolveSecretReferences("${SLOTH_TEST_EMPTY}"); err != nil || got != "" {
+		t.Errorf("Expected a set but empty variable to resolve, got %q (%v)", got, err)
+	}
+	if _, err := resolveSecretReferences("${file:/nonexistent/secret}"); err == nil || !strings.Contains(err.Error(), "/nonexistent/secret") {
+		t.Errorf("Expected an error naming the missing file, got %v", err)
+	}
+}
+
+func TestInterpolateSecrets_DisabledProvider(t *testing.T) {
+	cfg := &ClusterConfig{Providers: ProvidersConfig{
+		Linode: &LinodeProvider{Token: "${SLOTH_TEST_UNSET_TOKEN}"},
+	}}
+	if err := interpolateSecrets(cfg); err != nil {
+		t.Fatalf("Expected references of a disabled provider to be left alone, got %v", err)
+	}
+	if cfg.Providers.Linode.Token != "${SLOTH_TEST_UNSET_TOKEN}" {
+		t.Errorf("Unexpected token %q", cfg.Providers.Linode.Token)
+	}
+}
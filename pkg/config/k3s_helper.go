@@ -0,0 +1,115 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// K3s install locations
+const (
+	// k3sInstallScriptPath is where the install script is downloaded before
+	// it runs
+	k3sInstallScriptPath = "/tmp/k3s-install.sh"
+
+	// k3sImagesDir is where K3s imports image tarballs from when it starts
+	k3sImagesDir = "/var/lib/rancher/k3s/agent/images"
+)
+
+// k3sAirGapImages is the image tarball air-gapped installs preload
+const k3sAirGapImages = "k3s-airgap-images-amd64.tar.zst"
+
+// K3sVersion returns the K3s release to install: version when it names a
+// K3s release, DefaultK3sVersion otherwise
+func (c *RKE2Config) K3sVersion() string {
+	if strings.Contains(c.Version, "+k3s") {
+		return c.Version
+	}
+	return DefaultK3sVersion
+}
+
+// K3sInstallScriptURL returns the K3s install script location: the mirror
+// for air-gapped installs, the script of K3sVersion otherwise
+func (c *RKE2Config) K3sInstallScriptURL() string {
+	if c.AirGapEnabled() {
+		return c.AirGapInstallScriptURL()
+	}
+	return fmt.Sprintf(k3sReleaseInstallScriptURL, c.K3sVersion())
+}
+
+// K3sInstallCommand returns the command installing K3s, with env holding the
+// variables of the install script such as K3S_URL and INSTALL_K3S_EXEC. The
+// K3s settings live in kubernetes.rke2, which may be nil. Air-gapped installs
+// stage the k3s binary and images from the mirror instead of downloading
+// them, and the registries.yaml of a system default registry is written
+// before K3s first starts. The install script is checked against its pinned
+// checksum unless security disables it.
+func K3sInstallCommand(cfg *RKE2Config, security *SecurityConfig, env string) string {
+	if cfg == nil {
+		cfg = &RKE2Config{}
+	}
+
+	var builder strings.Builder
+
+	if registries := writeRegistriesCommand(cfg, "/etc/rancher/k3s"); registries != "" {
+		builder.WriteString(registries + " && ")
+	}
+
+	if cfg.AirGapEnabled() {
+		baseURL := strings.TrimSuffix(cfg.AirGap.ArtifactURL, "/")
+		builder.WriteString(fmt.Sprintf("mkdir -p %s && ", k3sImagesDir))
+		builder.WriteString(fmt.Sprintf("curl -sfL %s/%s -o %s/%s && ", baseURL, k3sAirGapImages, k3sImagesDir, k3sAirGapImages))
+		builder.WriteString(fmt.Sprintf("curl -sfL %s/k3s -o /usr/local/bin/k3s && chmod +x /usr/local/bin/k3s && ", baseURL))
+	}
+
+	download := security.Download(DownloadK3sInstaller, cfg.K3sInstallScriptURL())
+	builder.WriteString(download.Fetch(k3sInstallScriptPath) + " && ")
+
+	if cfg.AirGapEnabled() {
+		builder.WriteString("INSTALL_K3S_SKIP_DOWNLOAD=true ")
+	} else if strings.Contains(cfg.Version, "+k3s") {
+		builder.WriteString(fmt.Sprintf("INSTALL_K3S_VERSION=%s ", cfg.Version))
+	}
+	if env != "" {
+		builder.WriteString(env + " ")
+	}
+	builder.WriteString("sh " + k3sInstallScriptPath)
+
+	return builder.String()
+}
+
+// K3sKubeletArgs returns the kubelet flags appended to every K3s install. With
+// the node-local DNS cache, pods are pointed at the cache instead of CoreDNS.
+func K3sKubeletArgs(k *KubernetesConfig) string {
+	if !k.NodeLocalDNSEnabled() {
+		return ""
+	}
+	return fmt.Sprintf(" --kubelet-arg=cluster-dns=%s", k.KubeletClusterDNS())
+}
+
+// K3sTopologyArgs returns the flags labelling a node with the region and
+// zone it runs in, so workloads can spread across zones
+func K3sTopologyArgs(region, zone string) string {
+	var args strings.Builder
+	for _, label := range TopologyLabels(region, zone) {
+		args.WriteString(" --node-label=" + label)
+	}
+	return args.String()
+}
+
+// K3sServerArgs returns the flags appended to K3s server installs only.
+// Secrets encryption and admission plugins are server settings; agents never
+// touch etcd or run an API server. The system default registry prefixes the
+// images of the packaged components, which only servers deploy.
+func K3sServerArgs(k *KubernetesConfig) string {
+	var args strings.Builder
+	if k.SecretsEncryptionEnabled() {
+		args.WriteString(" --secrets-encryption")
+	}
+	for _, arg := range AdmissionAPIServerArgs(&k.Admission, K3sAdmissionConfigPath) {
+		args.WriteString(" --kube-apiserver-arg=" + arg)
+	}
+	if k.RKE2 != nil && k.RKE2.SystemDefaultRegistry != "" {
+		args.WriteString(" --system-default-registry=" + k.RKE2.SystemDefaultRegistry)
+	}
+	return args.String()
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestK3sInstallCommand(t *testing.T) {
+	cmd := K3sInstallCommand(nil, nil, `INSTALL_K3S_EXEC="server"`)
+	expected := `curl -sfL https://raw.githubusercontent.com/k3s-io/k3s/` + DefaultK3sVersion + `/install.sh -o /tmp/k3s-install.sh && ` +
+		`echo "WARNING: no checksum pinned for k3s-installer, running it unverified" >&2 && INSTALL_K3S_EXEC="server" sh /tmp/k3s-install.sh`
+	if cmd != expected {
+		t.Errorf("Unexpected command\nGot:  %s\nWant: %s", cmd, expected)
+	}
+}
+
+func TestK3sInstallCommand_PinnedVersion(t *testing.T) {
+	sum := strings.Repeat("b", 64)
+	security := &SecurityConfig{DownloadChecksums: map[string]string{DownloadK3sInstaller: sum}}
+
+	cmd := K3sInstallCommand(&RKE2Config{Version: "v1.29.0+k3s1"}, security, `INSTALL_K3S_EXEC="server"`)
+	if !strings.Contains(cmd, "https://raw.githubusercontent.com/k3s-io/k3s/v1.29.0+k3s1/install.sh") {
+		t.Errorf("Expected the install script of the configured release\nGot: %s", cmd)
+	}
+	if !strings.Contains(cmd, sum+"  /tmp/k3s-install.sh\" | sha256sum -c") {
+		t.Errorf("Expected the install script to be verified\nGot: %s", cmd)
+	}
+	if !strings.Contains(cmd, `INSTALL_K3S_VERSION=v1.29.0+k3s1 INSTALL_K3S_EXEC="server" sh /tmp/k3s-install.sh`) {
+		t.Errorf("Expected the configured release to be installed\nGot: %s", cmd)
+	}
+
+	if cmd := K3sInstallCommand(&RKE2Config{Version: "v1.28.5+rke2r1"}, nil, ""); strings.Contains(cmd, "INSTALL_K3S_VERSION") {
+		t.Errorf("RKE2 versions should not be passed to the K3s installer\nGot: %s", cmd)
+	}
+}
+
+func TestK3sInstallCommand_AirGap(t *testing.T) {
+	cfg := &RKE2Config{
+		SystemDefaultRegistry: "registry.internal:5000",
+		AirGap: &AirGapConfig{
+			Enabled:     true,
+			ArtifactURL: "https://mirror.internal/k3s/v1.30.4+k3s1/",
+		},
+	}
+
+	cmd := K3sInstallCommand(cfg, nil, `K3S_URL=https://10.8.0.10:6443 INSTALL_K3S_EXEC="agent"`)
+
+	wantInOrder := []string{
+		"mkdir -p /etc/rancher/k3s && printf 'mirrors:\\n  docker.io:\\n",
+		"> /etc/rancher/k3s/registries.yaml",
+		"curl -sfL https://mirror.internal/k3s/v1.30.4+k3s1/k3s-airgap-images-amd64.tar.zst -o /var/lib/rancher/k3s/agent/images/k3s-airgap-images-amd64.tar.zst",
+		"curl -sfL https://mirror.internal/k3s/v1.30.4+k3s1/k3s -o /usr/local/bin/k3s && chmod +x /usr/local/bin/k3s",
+		"curl -sfL https://mirror.internal/k3s/v1.30.4+k3s1/install.sh -o /tmp/k3s-install.sh",
+		`INSTALL_K3S_SKIP_DOWNLOAD=true K3S_URL=https://10.8.0.10:6443 INSTALL_K3S_EXEC="agent" sh /tmp/k3s-install.sh`,
+	}
+	rest := cmd
+	for _, want := range wantInOrder {
+		idx := strings.Index(rest, want)
+		if idx < 0 {
+			t.Fatalf("Command should contain %q after the previous steps\nGot: %s", want, cmd)
+		}
+		rest = rest[idx+len(want):]
+	}
+
+	if strings.Contains(cmd, "get.k3s.io") {
+		t.Errorf("Air-gapped command should not reach get.k3s.io\nGot: %s", cmd)
+	}
+}
+
+// TestK3sKubeletArgs tests that kubelets use the node-local cache only when enabled
+func TestK3sKubeletArgs(t *testing.T) {
+	k := &KubernetesConfig{ClusterDNS: "10.43.0.10"}
+	if args := K3sKubeletArgs(k); args != "" {
+		t.Errorf("Expected no kubelet args without node-local DNS, got %q", args)
+	}
+
+	k.NodeLocalDNS = &NodeLocalDNSConfig{Enabled: true}
+	if args := K3sKubeletArgs(k); args != " --kubelet-arg=cluster-dns=169.254.20.10" {
+		t.Errorf("Unexpected kubelet args: %q", args)
+	}
+}
+
+// TestK3sServerArgs tests that secrets encryption and the system default
+// registry are passed to servers when requested
+func TestK3sServerArgs(t *testing.T) {
+	k := &KubernetesConfig{}
+	if args := K3sServerArgs(k); args != "" {
+		t.Errorf("Expected no server args by default, got %q", args)
+	}
+
+	k.EncryptSecrets = true
+	if args := K3sServerArgs(k); args != " --secrets-encryption" {
+		t.Errorf("Unexpected server args: %q", args)
+	}
+
+	k.RKE2 = &RKE2Config{SystemDefaultRegistry: "registry.internal:5000"}
+	if args := K3sServerArgs(k); args != " --secrets-encryption --system-default-registry=registry.internal:5000" {
+		t.Errorf("Unexpected server args: %q", args)
+	}
+}
+
+// TestK3sTopologyArgs tests that nodes are labelled with their region and zone
+func TestK3sTopologyArgs(t *testing.T) {
+	args := K3sTopologyArgs("eastus", "2")
+	if args != " --node-label=topology.kubernetes.io/region=eastus --node-label=topology.kubernetes.io/zone=2" {
+		t.Errorf("Unexpected topology args: %q", args)
+	}
+
+	if args := K3sTopologyArgs("", ""); args != "" {
+		t.Errorf("Expected no args without a location, got %q", args)
+	}
+}
+
+// TestK3sServerArgs_Admission tests that admission plugins and their
+// configuration file are passed to the API server of K3s servers
+func TestK3sServerArgs_Admission(t *testing.T) {
+	k := &KubernetesConfig{Admission: AdmissionConfig{
+		Plugins: []string{"NodeRestriction", "PodSecurity"},
+		Config:  map[string]string{"PodSecurity": "kind: PodSecurityConfiguration\n"},
+	}}
+
+	expected := " --kube-apiserver-arg=enable-admission-plugins=NodeRestriction,PodSecurity" +
+		" --kube-apiserver-arg=admission-control-config-file=/etc/rancher/k3s/admission-config.yaml"
+	if args := K3sServerArgs(k); args != expected {
+		t.Errorf("Unexpected server args\nGot:  %q\nWant: %q", args, expected)
+	}
+}
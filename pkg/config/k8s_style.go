@@ -98,8 +98,9 @@ type NetworkSpec struct {
 
 // DNSSpec configuration
 type DNSSpec struct {
-	Domain   string `yaml:"domain" json:"domain"`
-	Provider string `yaml:"provider" json:"provider"`
+	Domain     string               `yaml:"domain" json:"domain"`
+	Provider   string               `yaml:"provider" json:"provider"`
+	Cloudflare *CloudflareDNSConfig `yaml:"cloudflare,omitempty" json:"cloudflare,omitempty"`
 }
 
 // WireGuardSpec VPN configuration
@@ -123,6 +124,12 @@ type KubernetesSpec struct {
 	ClusterDNS    string    `yaml:"clusterDNS" json:"clusterDNS"`
 	ClusterDomain string    `yaml:"clusterDomain,omitempty" json:"clusterDomain,omitempty"`
 	RKE2          *RKE2Spec `yaml:"rke2,omitempty" json:"rke2,omitempty"`
+
+	SystemTuning *SystemTuningConfig `yaml:"systemTuning,omitempty" json:"systemTuning,omitempty"`
+	NodeLocalDNS *NodeLocalDNSConfig `yaml:"nodeLocalDNS,omitempty" json:"nodeLocalDNS,omitempty"`
+
+	ReadyTimeout      string `yaml:"readyTimeout,omitempty" json:"readyTimeout,omitempty"`
+	ReadyPollInterval string `yaml:"readyPollInterval,omitempty" json:"readyPollInterval,omitempty"`
 }
 
 // RKE2Spec RKE2-specific configuration
@@ -137,19 +144,23 @@ type RKE2Spec struct {
 	WriteKubeconfigMode  string            `yaml:"writeKubeconfigMode,omitempty" json:"writeKubeconfigMode,omitempty"`
 	ExtraServerArgs      map[string]string `yaml:"extraServerArgs,omitempty" json:"extraServerArgs,omitempty"`
 	ExtraAgentArgs       map[string]string `yaml:"extraAgentArgs,omitempty" json:"extraAgentArgs,omitempty"`
+
+	SystemDefaultRegistry string        `yaml:"systemDefaultRegistry,omitempty" json:"systemDefaultRegistry,omitempty"`
+	AirGap                *AirGapConfig `yaml:"airGap,omitempty" json:"airGap,omitempty"`
 }
 
 // NodePoolSpec defines a node pool
 type NodePoolSpec struct {
-	Name     string            `yaml:"name" json:"name"`
-	Provider string            `yaml:"provider" json:"provider"`
-	Count    int               `yaml:"count" json:"count"`
-	Roles    []string          `yaml:"roles" json:"roles"`
-	Size     string            `yaml:"size" json:"size"`
-	Image    string            `yaml:"image" json:"image"`
-	Region   string            `yaml:"region" json:"region"`
-	Labels   map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
-	Taints   []TaintSpec       `yaml:"taints,omitempty" json:"taints,omitempty"`
+	Name         string            `yaml:"name" json:"name"`
+	Provider     string            `yaml:"provider" json:"provider"`
+	Count        int               `yaml:"count" json:"count"`
+	Roles        []string          `yaml:"roles" json:"roles"`
+	Size         string            `yaml:"size" json:"size"`
+	Image        string            `yaml:"image" json:"image"`
+	Region       string            `yaml:"region" json:"region"`
+	Labels       map[string]string `yaml:"labels,omitempty" json:"labels,omitempty"`
+	Taints       []TaintSpec       `yaml:"taints,omitempty" json:"taints,omitempty"`
+	NameTemplate string            `yaml:"nameTemplate,omitempty" json:"nameTemplate,omitempty"`
 }
 
 // TaintSpec defines node taints
@@ -270,8 +281,9 @@ func convertFromK8sStyle(k8s *KubernetesStyleConfig) *ClusterConfig {
 
 	// Network
 	cfg.Network.DNS = DNSConfig{
-		Domain:   k8s.Spec.Network.DNS.Domain,
-		Provider: k8s.Spec.Network.DNS.Provider,
+		Domain:     k8s.Spec.Network.DNS.Domain,
+		Provider:   k8s.Spec.Network.DNS.Provider,
+		Cloudflare: k8s.Spec.Network.DNS.Cloudflare,
 	}
 	if k8s.Spec.Network.WireGuard != nil {
 		cfg.Network.WireGuard = &WireGuardConfig{
@@ -294,6 +306,11 @@ func convertFromK8sStyle(k8s *KubernetesStyleConfig) *ClusterConfig {
 		ServiceCIDR:   k8s.Spec.Kubernetes.ServiceCIDR,
 		ClusterDNS:    k8s.Spec.Kubernetes.ClusterDNS,
 		ClusterDomain: k8s.Spec.Kubernetes.ClusterDomain,
+		SystemTuning:  k8s.Spec.Kubernetes.SystemTuning,
+		NodeLocalDNS:  k8s.Spec.Kubernetes.NodeLocalDNS,
+
+		ReadyTimeout:      k8s.Spec.Kubernetes.ReadyTimeout,
+		ReadyPollInterval: k8s.Spec.Kubernetes.ReadyPollInterval,
 	}
 	if k8s.Spec.Kubernetes.RKE2 != nil {
 		cfg.Kubernetes.RKE2 = &RKE2Config{
@@ -307,6 +324,9 @@ func convertFromK8sStyle(k8s *KubernetesStyleConfig) *ClusterConfig {
 			WriteKubeconfigMode:  k8s.Spec.Kubernetes.RKE2.WriteKubeconfigMode,
 			ExtraServerArgs:      k8s.Spec.Kubernetes.RKE2.ExtraServerArgs,
 			ExtraAgentArgs:       k8s.Spec.Kubernetes.RKE2.ExtraAgentArgs,
+
+			SystemDefaultRegistry: k8s.Spec.Kubernetes.RKE2.SystemDefaultRegistry,
+			AirGap:                k8s.Spec.Kubernetes.RKE2.AirGap,
 		}
 	}
 
@@ -322,15 +342,16 @@ func convertFromK8sStyle(k8s *KubernetesStyleConfig) *ClusterConfig {
 		}
 
 		cfg.NodePools[pool.Name] = NodePool{
-			Name:     pool.Name,
-			Provider: pool.Provider,
-			Count:    pool.Count,
-			Roles:    pool.Roles,
-			Size:     pool.Size,
-			Image:    pool.Image,
-			Region:   pool.Region,
-			Labels:   pool.Labels,
-			Taints:   taints,
+			Name:         pool.Name,
+			Provider:     pool.Provider,
+			Count:        pool.Count,
+			Roles:        pool.Roles,
+			Size:         pool.Size,
+			Image:        pool.Image,
+			Region:       pool.Region,
+			Labels:       pool.Labels,
+			Taints:       taints,
+			NameTemplate: pool.NameTemplate,
 		}
 	}
 
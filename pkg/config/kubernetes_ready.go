@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Defaults of the wait for the Kubernetes API and nodes after install
+const (
+	DefaultKubernetesReadyTimeout      = 10 * time.Minute
+	DefaultKubernetesReadyPollInterval = 10 * time.Second
+)
+
+// KubernetesReadyWait returns how long deploy waits for the API server and
+// every node to be Ready, and how often it checks
+func (k *KubernetesConfig) KubernetesReadyWait() (timeout, interval time.Duration, err error) {
+	timeout, interval = DefaultKubernetesReadyTimeout, DefaultKubernetesReadyPollInterval
+	if k.ReadyTimeout != "" {
+		if timeout, err = time.ParseDuration(k.ReadyTimeout); err != nil || timeout <= 0 {
+			return 0, 0, fmt.Errorf("readyTimeout %q is not a positive duration (e.g. 15m)", k.ReadyTimeout)
+		}
+	}
+	if k.ReadyPollInterval != "" {
+		if interval, err = time.ParseDuration(k.ReadyPollInterval); err != nil || interval <= 0 {
+			return 0, 0, fmt.Errorf("readyPollInterval %q is not a positive duration (e.g. 10s)", k.ReadyPollInterval)
+		}
+	}
+	if interval > timeout {
+		return 0, 0, fmt.Errorf("readyPollInterval %s is longer than readyTimeout %s", interval, timeout)
+	}
+	return timeout, interval, nil
+}
+
+// ValidateKubernetesReadyWait checks the readiness timeout and poll interval
+func ValidateKubernetesReadyWait(k *KubernetesConfig) error {
+	_, _, err := k.KubernetesReadyWait()
+	return err
+}
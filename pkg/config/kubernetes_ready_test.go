@@ -0,0 +1,40 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKubernetesReadyWait(t *testing.T) {
+	timeout, interval, err := (&KubernetesConfig{}).KubernetesReadyWait()
+	if err != nil || timeout != DefaultKubernetesReadyTimeout || interval != DefaultKubernetesReadyPollInterval {
+		t.Errorf("Expected the defaults, got %s, %s, %v", timeout, interval, err)
+	}
+
+	timeout, interval, err = (&KubernetesConfig{ReadyTimeout: "20m", ReadyPollInterval: "30s"}).KubernetesReadyWait()
+	if err != nil || timeout != 20*time.Minute || interval != 30*time.Second {
+		t.Errorf("Expected 20m and 30s, got %s, %s, %v", timeout, interval, err)
+	}
+
+	for _, k := range []KubernetesConfig{
+		{ReadyTimeout: "ten minutes"},
+		{ReadyTimeout: "-5m"},
+		{ReadyPollInterval: "0s"},
+		{ReadyTimeout: "30s", ReadyPollInterval: "1m"},
+	} {
+		if err := ValidateKubernetesReadyWait(&k); err == nil {
+			t.Errorf("Expected %+v to be rejected", k)
+		}
+	}
+}
+
+func TestDiagnose_KubernetesReadyTimeout(t *testing.T) {
+	cfg := &ClusterConfig{Kubernetes: KubernetesConfig{ReadyTimeout: "soon"}}
+	for _, d := range Diagnose(cfg) {
+		if d.Path == "kubernetes.readyTimeout" && strings.Contains(d.Message, `"soon"`) {
+			return
+		}
+	}
+	t.Error("Expected an invalid readyTimeout to be reported")
+}
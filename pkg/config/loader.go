@@ -35,8 +35,25 @@ func NewLoader(configPath string) *Loader {
 	}
 }
 
-// Load loads the configuration from file
+// Load loads the configuration from file and validates it
 func (l *Loader) Load() (*ClusterConfig, error) {
+	config, err := l.Parse()
+	if err != nil {
+		return nil, err
+	}
+
+	// Validate configuration
+	if err := l.validate(config); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+
+	l.config = config
+	return config, nil
+}
+
+// Parse loads the configuration from file, applying overrides and defaults,
+// without validating it
+func (l *Loader) Parse() (*ClusterConfig, error) {
 	// Check if config file exists
 	if _, err := os.Stat(l.configPath); os.IsNotExist(err) {
 		return nil, fmt.Errorf("configuration file not found: %s", l.configPath)
@@ -65,6 +82,11 @@ func (l *Loader) Load() (*ClusterConfig, error) {
 		return nil, fmt.Errorf("unsupported configuration format: %s", ext)
 	}
 
+	// Expand ${ENV_VAR} and ${file:/path} references in secrets
+	if err := interpolateSecrets(config); err != nil {
+		return nil, fmt.Errorf("failed to resolve configuration secrets: %w", err)
+	}
+
 	// Apply environment variable overrides
 	if err := l.applyEnvironmentOverrides(config); err != nil {
 		return nil, fmt.Errorf("failed to apply environment overrides: %w", err)
@@ -80,12 +102,6 @@ func (l *Loader) Load() (*ClusterConfig, error) {
 		return nil, fmt.Errorf("failed to set defaults: %w", err)
 	}
 
-	// Validate configuration
-	if err := l.validate(config); err != nil {
-		return nil, fmt.Errorf("configuration validation failed: %w", err)
-	}
-
-	l.config = config
 	return config, nil
 }
 
@@ -296,7 +312,7 @@ func (l *Loader) setDefaults(config *ClusterConfig) error {
 
 	// Set network defaults
 	if config.Network.Mode == "" {
-		config.Network.Mode = "vpc"
+		config.Network.Mode = NetworkModeVPC
 	}
 	if config.Network.CIDR == "" {
 		config.Network.CIDR = "10.0.0.0/16"
@@ -370,6 +386,9 @@ func (l *Loader) validate(config *ClusterConfig) error {
 	if config.Providers.GCP != nil && config.Providers.GCP.Enabled {
 		hasProvider = true
 	}
+	if config.Providers.Vultr != nil && config.Providers.Vultr.Enabled {
+		hasProvider = true
+	}
 
 	if !hasProvider {
 		return fmt.Errorf("at least one cloud provider must be enabled")
@@ -413,6 +432,18 @@ func (l *Loader) validate(config *ClusterConfig) error {
 	return nil
 }
 
+// Diagnose checks the configuration like Diagnose, also running the
+// loader's custom validators, whose problems have no path
+func (l *Loader) Diagnose(config *ClusterConfig) []Diagnostic {
+	diags := Diagnose(config)
+	for _, validator := range l.validators {
+		if err := validator.Validate(config); err != nil {
+			diags = append(diags, Diagnostic{Message: err.Error()})
+		}
+	}
+	return diags
+}
+
 // MergeConfigs merges multiple configurations
 func MergeConfigs(configs ...*ClusterConfig) (*ClusterConfig, error) {
 	if len(configs) == 0 {
@@ -0,0 +1,43 @@
+package config
+
+import "fmt"
+
+// ProviderSupportsMonitoring reports whether a provider has a native metrics
+// agent that node monitoring enables. DigitalOcean installs its agent
+// (do-agent) when a droplet is created with monitoring.
+func ProviderSupportsMonitoring(provider string) bool {
+	return provider == "digitalocean"
+}
+
+// NodeMonitoringEnabled reports whether the node runs its provider's metrics
+// agent: the node asks for it, or its provider enables it for every node
+func (c *ClusterConfig) NodeMonitoringEnabled(node *NodeConfig) bool {
+	if !ProviderSupportsMonitoring(node.Provider) {
+		return false
+	}
+	if node.Monitoring {
+		return true
+	}
+	do := c.Providers.DigitalOcean
+	return do != nil && do.Monitoring
+}
+
+// ValidateMonitoring checks that monitoring is only requested for nodes and
+// bastions on a provider with a native metrics agent
+func ValidateMonitoring(cfg *ClusterConfig) error {
+	for _, node := range cfg.Nodes {
+		if node.Monitoring && !ProviderSupportsMonitoring(node.Provider) {
+			return fmt.Errorf("node %s: monitoring is not supported on provider %s", node.Name, node.Provider)
+		}
+	}
+	for _, name := range SortedPoolNames(cfg.NodePools) {
+		pool := cfg.NodePools[name]
+		if pool.Monitoring && !ProviderSupportsMonitoring(pool.Provider) {
+			return fmt.Errorf("node pool %s: monitoring is not supported on provider %s", name, pool.Provider)
+		}
+	}
+	if b := cfg.Security.Bastion; b != nil && b.Enabled && b.Monitoring && !ProviderSupportsMonitoring(b.Provider) {
+		return fmt.Errorf("bastion: monitoring is not supported on provider %s", b.Provider)
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNodeMonitoringEnabled(t *testing.T) {
+	cfg := &ClusterConfig{}
+	node := &NodeConfig{Name: "do-1", Provider: "digitalocean"}
+	if cfg.NodeMonitoringEnabled(node) {
+		t.Error("Expected monitoring off unless requested")
+	}
+
+	node.Monitoring = true
+	if !cfg.NodeMonitoringEnabled(node) {
+		t.Error("Expected a node requesting monitoring to get it")
+	}
+
+	node.Monitoring = false
+	cfg.Providers.DigitalOcean = &DigitalOceanProvider{Enabled: true, Monitoring: true}
+	if !cfg.NodeMonitoringEnabled(node) {
+		t.Error("Expected provider-wide monitoring to apply to the node")
+	}
+
+	linodeNode := &NodeConfig{Name: "linode-1", Provider: "linode", Monitoring: true}
+	if cfg.NodeMonitoringEnabled(linodeNode) {
+		t.Error("Expected no monitoring on a provider without a metrics agent")
+	}
+}
+
+func TestValidateMonitoring(t *testing.T) {
+	cfg := &ClusterConfig{
+		Nodes: []NodeConfig{{Name: "do-1", Provider: "digitalocean", Monitoring: true}},
+		NodePools: map[string]NodePool{
+			"workers": {Name: "workers", Provider: "digitalocean", Monitoring: true},
+		},
+	}
+	if err := ValidateMonitoring(cfg); err != nil {
+		t.Fatalf("Expected DigitalOcean monitoring to be valid, got %v", err)
+	}
+
+	cfg.NodePools["linode-workers"] = NodePool{Name: "linode-workers", Provider: "linode", Monitoring: true}
+	err := ValidateMonitoring(cfg)
+	if err == nil || !strings.Contains(err.Error(), "node pool linode-workers: monitoring is not supported on provider linode") {
+		t.Errorf("Expected the Linode pool to be rejected, got %v", err)
+	}
+
+	delete(cfg.NodePools, "linode-workers")
+	cfg.Security.Bastion = &BastionConfig{Enabled: true, Provider: "azure", Monitoring: true}
+	if err := ValidateMonitoring(cfg); err == nil || !strings.Contains(err.Error(), "bastion") {
+		t.Errorf("Expected the Azure bastion to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Network modes of NetworkConfig.Mode
+const (
+	NetworkModeVPC       = "vpc"
+	NetworkModeWireGuard = "wireguard"
+	NetworkModeTailscale = "tailscale"
+	NetworkModeHybrid    = "hybrid"
+)
+
+// HybridMode reports whether nodes sharing a provider VPC talk over the VPC
+// and only traffic between VPCs goes over WireGuard
+func (n *NetworkConfig) HybridMode() bool {
+	return strings.EqualFold(n.Mode, NetworkModeHybrid)
+}
+
+// HybridVPCNetworks returns the VPC CIDR of each provider whose nodes share a
+// VPC in hybrid mode, or nil outside hybrid mode
+func HybridVPCNetworks(cfg *ClusterConfig) map[string]string {
+	if !cfg.Network.HybridMode() {
+		return nil
+	}
+	return ProviderNetworkCIDRs(cfg)
+}
+
+// ValidateHybridNetwork checks that hybrid mode can route every node: the
+// nodes need the WireGuard mesh between VPCs and a VPC on every provider
+func ValidateHybridNetwork(cfg *ClusterConfig) error {
+	if !cfg.Network.HybridMode() {
+		return nil
+	}
+
+	wg := cfg.Network.WireGuard
+	if wg == nil || !(wg.Enabled || wg.Create) {
+		return fmt.Errorf("hybrid network mode requires wireguard to connect the provider VPCs")
+	}
+	if wg.HubMode() {
+		return fmt.Errorf("hybrid network mode requires the WireGuard mesh (wireguard.create: true), not an existing hub server")
+	}
+
+	used := make(map[string]bool)
+	for _, pool := range cfg.NodePools {
+		used[pool.Provider] = true
+	}
+	for _, node := range cfg.Nodes {
+		used[node.Provider] = true
+	}
+
+	networks := ProviderNetworkCIDRs(cfg)
+	var missing []string
+	for provider := range used {
+		if provider != "" && networks[provider] == "" {
+			missing = append(missing, provider)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("hybrid network mode requires a VPC with a cidr for every provider with nodes, missing for: %s",
+			strings.Join(missing, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func hybridTestConfig() *ClusterConfig {
+	return &ClusterConfig{
+		Providers: ProvidersConfig{
+			DigitalOcean: &DigitalOceanProvider{Enabled: true, VPC: &VPCConfig{Create: true, CIDR: "10.10.0.0/16"}},
+			Linode:       &LinodeProvider{Enabled: true, VPC: &VPCConfig{Create: true, CIDR: "192.168.128.0/17"}},
+		},
+		Network: NetworkConfig{
+			Mode:      NetworkModeHybrid,
+			WireGuard: &WireGuardConfig{Enabled: true, Create: true},
+		},
+		NodePools: map[string]NodePool{
+			"do":     {Provider: "digitalocean", Count: 3},
+			"linode": {Provider: "linode", Count: 2},
+		},
+	}
+}
+
+func TestNetworkConfig_HybridMode(t *testing.T) {
+	for mode, want := range map[string]bool{"hybrid": true, "Hybrid": true, "vpc": false, "wireguard": false, "": false} {
+		if got := (&NetworkConfig{Mode: mode}).HybridMode(); got != want {
+			t.Errorf("HybridMode() for %q = %v, want %v", mode, got, want)
+		}
+	}
+}
+
+func TestHybridVPCNetworks(t *testing.T) {
+	cfg := hybridTestConfig()
+	networks := HybridVPCNetworks(cfg)
+	if networks["digitalocean"] != "10.10.0.0/16" || networks["linode"] != "192.168.128.0/17" {
+		t.Errorf("Expected the provider VPC CIDRs, got %v", networks)
+	}
+
+	cfg.Network.Mode = NetworkModeWireGuard
+	if networks := HybridVPCNetworks(cfg); networks != nil {
+		t.Errorf("Expected no VPC networks outside hybrid mode, got %v", networks)
+	}
+}
+
+func TestValidateHybridNetwork(t *testing.T) {
+	if err := ValidateHybridNetwork(hybridTestConfig()); err != nil {
+		t.Errorf("Expected a VPC on every provider to be valid, got %v", err)
+	}
+
+	cfg := hybridTestConfig()
+	cfg.Providers.Linode.VPC = nil
+	err := ValidateHybridNetwork(cfg)
+	if err == nil || !strings.Contains(err.Error(), "missing for: linode") {
+		t.Errorf("Expected the provider without a VPC to be reported, got %v", err)
+	}
+
+	cfg = hybridTestConfig()
+	cfg.Network.WireGuard = nil
+	if err := ValidateHybridNetwork(cfg); err == nil {
+		t.Error("Expected hybrid mode without WireGuard to fail")
+	}
+
+	cfg = hybridTestConfig()
+	cfg.Network.WireGuard = &WireGuardConfig{Enabled: true, ServerEndpoint: "203.0.113.1:51820", ServerPublicKey: "key"}
+	if err := ValidateHybridNetwork(cfg); err == nil || !strings.Contains(err.Error(), "mesh") {
+		t.Errorf("Expected hybrid mode with a hub server to fail, got %v", err)
+	}
+
+	cfg = hybridTestConfig()
+	cfg.Network.Mode = NetworkModeVPC
+	cfg.Providers.Linode.VPC = nil
+	if err := ValidateHybridNetwork(cfg); err != nil {
+		t.Errorf("Expected other modes not to require VPCs, got %v", err)
+	}
+}
@@ -0,0 +1,264 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Node-local DNS defaults
+const (
+	DefaultNodeLocalDNSIP    = "169.254.20.10"
+	DefaultNodeLocalDNSImage = "registry.k8s.io/dns/k8s-dns-node-cache:1.23.1"
+)
+
+// NodeLocalDNSEnabled reports whether the node-local DNS cache is installed
+func (k *KubernetesConfig) NodeLocalDNSEnabled() bool {
+	return k.NodeLocalDNS != nil && k.NodeLocalDNS.Enabled
+}
+
+// Address returns the link-local address the cache listens on
+func (c *NodeLocalDNSConfig) Address() string {
+	if c.LocalIP != "" {
+		return c.LocalIP
+	}
+	return DefaultNodeLocalDNSIP
+}
+
+// ImageName returns the node-cache image
+func (c *NodeLocalDNSConfig) ImageName() string {
+	if c.Image != "" {
+		return c.Image
+	}
+	return DefaultNodeLocalDNSImage
+}
+
+// KubeletClusterDNS returns the DNS server kubelet hands to pods: the
+// node-local cache when enabled, otherwise the cluster DNS service
+func (k *KubernetesConfig) KubeletClusterDNS() string {
+	if k.NodeLocalDNSEnabled() {
+		return k.NodeLocalDNS.Address()
+	}
+	return k.ClusterDNS
+}
+
+// ValidateNodeLocalDNS checks the node-local DNS settings: the cache forwards
+// cluster queries to the cluster DNS service, so its IP must be inside the
+// service CIDR, and the cache's own address must not collide with it
+func ValidateNodeLocalDNS(k *KubernetesConfig) error {
+	if !k.NodeLocalDNSEnabled() {
+		return nil
+	}
+
+	localIP := net.ParseIP(k.NodeLocalDNS.Address())
+	if localIP == nil || localIP.To4() == nil {
+		return fmt.Errorf("nodeLocalDNS.localIP %q is not a valid IPv4 address", k.NodeLocalDNS.Address())
+	}
+
+	clusterDNS := net.ParseIP(k.ClusterDNS)
+	if clusterDNS == nil {
+		return fmt.Errorf("nodeLocalDNS requires a valid clusterDns, got %q", k.ClusterDNS)
+	}
+
+	if k.ServiceCIDR != "" {
+		_, serviceNet, err := net.ParseCIDR(k.ServiceCIDR)
+		if err != nil {
+			return fmt.Errorf("invalid serviceCidr %s: %w", k.ServiceCIDR, err)
+		}
+		if !serviceNet.Contains(clusterDNS) {
+			return fmt.Errorf("clusterDns %s is outside serviceCidr %s", k.ClusterDNS, k.ServiceCIDR)
+		}
+		if serviceNet.Contains(localIP) {
+			return fmt.Errorf("nodeLocalDNS.localIP %s is inside serviceCidr %s; use a link-local address such as %s", localIP, k.ServiceCIDR, DefaultNodeLocalDNSIP)
+		}
+	}
+
+	return nil
+}
+
+// BuildNodeLocalDNSManifest generates the node-local-dns manifests for the
+// cluster's DNS IP and domain. The cache listens on its local address and the
+// cluster DNS IP, and reaches CoreDNS through the kube-dns-upstream service.
+func BuildNodeLocalDNSManifest(k *KubernetesConfig) string {
+	domain := k.ClusterDomain
+	if domain == "" {
+		domain = "cluster.local"
+	}
+
+	replacer := strings.NewReplacer(
+		"__LOCAL_DNS__", k.NodeLocalDNS.Address(),
+		"__DNS_SERVER__", k.ClusterDNS,
+		"__DNS_DOMAIN__", domain,
+		"__IMAGE__", k.NodeLocalDNS.ImageName(),
+	)
+	return replacer.Replace(nodeLocalDNSManifestTemplate)
+}
+
+// nodeLocalDNSManifestTemplate follows the upstream nodelocaldns.yaml. The
+// __PILLAR__ placeholders are filled in by node-cache itself at startup.
+const nodeLocalDNSManifestTemplate = `apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: node-local-dns
+  namespace: kube-system
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: kube-dns-upstream
+  namespace: kube-system
+  labels:
+    k8s-app: kube-dns
+spec:
+  ports:
+  - name: dns
+    port: 53
+    protocol: UDP
+    targetPort: 53
+  - name: dns-tcp
+    port: 53
+    protocol: TCP
+    targetPort: 53
+  selector:
+    k8s-app: kube-dns
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: node-local-dns
+  namespace: kube-system
+data:
+  Corefile: |
+    __DNS_DOMAIN__:53 {
+        errors
+        cache {
+            success 9984 30
+            denial 9984 5
+        }
+        reload
+        loop
+        bind __LOCAL_DNS__ __DNS_SERVER__
+        forward . __PILLAR__CLUSTER__DNS__ {
+            force_tcp
+        }
+        prometheus :9253
+        health __LOCAL_DNS__:8080
+    }
+    in-addr.arpa:53 {
+        errors
+        cache 30
+        reload
+        loop
+        bind __LOCAL_DNS__ __DNS_SERVER__
+        forward . __PILLAR__CLUSTER__DNS__ {
+            force_tcp
+        }
+        prometheus :9253
+    }
+    ip6.arpa:53 {
+        errors
+        cache 30
+        reload
+        loop
+        bind __LOCAL_DNS__ __DNS_SERVER__
+        forward . __PILLAR__CLUSTER__DNS__ {
+            force_tcp
+        }
+        prometheus :9253
+    }
+    .:53 {
+        errors
+        cache 30
+        reload
+        loop
+        bind __LOCAL_DNS__ __DNS_SERVER__
+        forward . __PILLAR__UPSTREAM__SERVERS__
+        prometheus :9253
+    }
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: node-local-dns
+  namespace: kube-system
+  labels:
+    k8s-app: node-local-dns
+spec:
+  updateStrategy:
+    rollingUpdate:
+      maxUnavailable: 10%
+  selector:
+    matchLabels:
+      k8s-app: node-local-dns
+  template:
+    metadata:
+      labels:
+        k8s-app: node-local-dns
+      annotations:
+        prometheus.io/port: "9253"
+        prometheus.io/scrape: "true"
+    spec:
+      priorityClassName: system-node-critical
+      serviceAccountName: node-local-dns
+      hostNetwork: true
+      dnsPolicy: Default
+      tolerations:
+      - key: "CriticalAddonsOnly"
+        operator: "Exists"
+      - effect: "NoExecute"
+        operator: "Exists"
+      - effect: "NoSchedule"
+        operator: "Exists"
+      containers:
+      - name: node-cache
+        image: __IMAGE__
+        resources:
+          requests:
+            cpu: 25m
+            memory: 5Mi
+        args: [ "-localip", "__LOCAL_DNS__,__DNS_SERVER__", "-conf", "/etc/Corefile", "-upstreamsvc", "kube-dns-upstream" ]
+        securityContext:
+          capabilities:
+            add:
+            - NET_ADMIN
+        ports:
+        - containerPort: 53
+          name: dns
+          protocol: UDP
+        - containerPort: 53
+          name: dns-tcp
+          protocol: TCP
+        - containerPort: 9253
+          name: metrics
+          protocol: TCP
+        livenessProbe:
+          httpGet:
+            host: __LOCAL_DNS__
+            path: /health
+            port: 8080
+          initialDelaySeconds: 60
+          timeoutSeconds: 5
+        volumeMounts:
+        - mountPath: /run/xtables.lock
+          name: xtables-lock
+          readOnly: false
+        - name: config-volume
+          mountPath: /etc/coredns
+        - name: kube-dns-config
+          mountPath: /etc/kube-dns
+      volumes:
+      - name: xtables-lock
+        hostPath:
+          path: /run/xtables.lock
+          type: FileOrCreate
+      - name: kube-dns-config
+        configMap:
+          name: kube-dns
+          optional: true
+      - name: config-volume
+        configMap:
+          name: node-local-dns
+          items:
+          - key: Corefile
+            path: Corefile.base
+`
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildNodeLocalDNSManifest(t *testing.T) {
+	k := &KubernetesConfig{
+		ClusterDNS:    "10.43.0.10",
+		ClusterDomain: "cluster.local",
+		NodeLocalDNS:  &NodeLocalDNSConfig{Enabled: true},
+	}
+
+	manifest := BuildNodeLocalDNSManifest(k)
+
+	expected := []string{
+		"cluster.local:53 {",
+		"bind 169.254.20.10 10.43.0.10",
+		`"-localip", "169.254.20.10,10.43.0.10"`,
+		"image: " + DefaultNodeLocalDNSImage,
+		"host: 169.254.20.10",
+		"name: kube-dns-upstream",
+		"kind: DaemonSet",
+	}
+	for _, want := range expected {
+		if !strings.Contains(manifest, want) {
+			t.Errorf("Expected manifest to contain %q", want)
+		}
+	}
+	for _, placeholder := range []string{"__LOCAL_DNS__", "__DNS_SERVER__", "__DNS_DOMAIN__", "__IMAGE__"} {
+		if strings.Contains(manifest, placeholder) {
+			t.Errorf("Placeholder %s was not replaced", placeholder)
+		}
+	}
+	if !strings.Contains(manifest, "__PILLAR__CLUSTER__DNS__") {
+		t.Error("Expected the upstream placeholder to be left for node-cache")
+	}
+}
+
+func TestBuildNodeLocalDNSManifest_Custom(t *testing.T) {
+	k := &KubernetesConfig{
+		ClusterDNS:    "10.96.0.10",
+		ClusterDomain: "corp.internal",
+		NodeLocalDNS:  &NodeLocalDNSConfig{Enabled: true, LocalIP: "169.254.25.10", Image: "registry.example.com/node-cache:1.0"},
+	}
+
+	manifest := BuildNodeLocalDNSManifest(k)
+
+	for _, want := range []string{"corp.internal:53 {", "bind 169.254.25.10 10.96.0.10", "image: registry.example.com/node-cache:1.0"} {
+		if !strings.Contains(manifest, want) {
+			t.Errorf("Expected manifest to contain %q", want)
+		}
+	}
+}
+
+func TestKubeletClusterDNS(t *testing.T) {
+	k := &KubernetesConfig{ClusterDNS: "10.43.0.10"}
+	if got := k.KubeletClusterDNS(); got != "10.43.0.10" {
+		t.Errorf("Expected cluster DNS without the cache, got %s", got)
+	}
+
+	k.NodeLocalDNS = &NodeLocalDNSConfig{Enabled: true}
+	if got := k.KubeletClusterDNS(); got != DefaultNodeLocalDNSIP {
+		t.Errorf("Expected the node-local address, got %s", got)
+	}
+}
+
+func TestValidateNodeLocalDNS(t *testing.T) {
+	tests := []struct {
+		name    string
+		k       *KubernetesConfig
+		wantErr string
+	}{
+		{"disabled", &KubernetesConfig{ClusterDNS: "192.168.0.10", ServiceCIDR: "10.43.0.0/16"}, ""},
+		{"valid", &KubernetesConfig{ClusterDNS: "10.43.0.10", ServiceCIDR: "10.43.0.0/16", NodeLocalDNS: &NodeLocalDNSConfig{Enabled: true}}, ""},
+		{"dns outside service cidr", &KubernetesConfig{ClusterDNS: "10.96.0.10", ServiceCIDR: "10.43.0.0/16", NodeLocalDNS: &NodeLocalDNSConfig{Enabled: true}}, "outside serviceCidr"},
+		{"missing cluster dns", &KubernetesConfig{ServiceCIDR: "10.43.0.0/16", NodeLocalDNS: &NodeLocalDNSConfig{Enabled: true}}, "requires a valid clusterDns"},
+		{"invalid local ip", &KubernetesConfig{ClusterDNS: "10.43.0.10", NodeLocalDNS: &NodeLocalDNSConfig{Enabled: true, LocalIP: "nope"}}, "not a valid IPv4"},
+		{"local ip in service cidr", &KubernetesConfig{ClusterDNS: "10.43.0.10", ServiceCIDR: "10.43.0.0/16", NodeLocalDNS: &NodeLocalDNSConfig{Enabled: true, LocalIP: "10.43.0.20"}}, "inside serviceCidr"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateNodeLocalDNS(tt.k)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// hostnamePattern matches a DNS-safe hostname label (RFC 1123)
+var hostnamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// NodeNameVars are the variables available to a NodePool.NameTemplate
+type NodeNameVars struct {
+	Pool     string
+	Index    int // 1-based, matching the default <pool>-<n> scheme
+	Role     string
+	Provider string
+	Region   string
+}
+
+// PoolNodeName returns the default name of the index-th (0-based) node of a
+// pool, e.g. workers-1 for the first node of pool "workers"
+func PoolNodeName(pool string, index int) string {
+	return fmt.Sprintf("%s-%d", pool, index+1)
+}
+
+// PoolRole returns the role used in node names: master for control plane
+// pools, otherwise the first role of the pool (worker when it has none)
+func PoolRole(pool *NodePool) string {
+	if HasRole(pool.Roles, RoleMaster) {
+		return RoleMaster
+	}
+	if len(pool.Roles) > 0 {
+		return NormalizeRole(pool.Roles[0])
+	}
+	return RoleWorker
+}
+
+// RenderPoolNodeName returns the name of the index-th (0-based) node of a
+// pool, rendered from pool.NameTemplate or the default scheme when unset
+func RenderPoolNodeName(poolName string, pool *NodePool, index int) (string, error) {
+	if pool.NameTemplate == "" {
+		return PoolNodeName(poolName, index), nil
+	}
+
+	tmpl, err := template.New(poolName).Option("missingkey=error").Parse(pool.NameTemplate)
+	if err != nil {
+		return "", fmt.Errorf("pool '%s' has invalid name template: %w", poolName, err)
+	}
+
+	var buf bytes.Buffer
+	vars := NodeNameVars{
+		Pool:     poolName,
+		Index:    index + 1,
+		Role:     PoolRole(pool),
+		Provider: pool.Provider,
+		Region:   pool.Region,
+	}
+	if err := tmpl.Execute(&buf, vars); err != nil {
+		return "", fmt.Errorf("pool '%s' name template failed to render: %w", poolName, err)
+	}
+
+	name := strings.TrimSpace(buf.String())
+	if !hostnamePattern.MatchString(name) {
+		return "", fmt.Errorf("pool '%s' name template produced invalid hostname %q (must be lowercase letters, digits and '-', at most 63 characters)", poolName, name)
+	}
+	return name, nil
+}
+
+// SortedPoolNames returns the pool names in name order, so nodes expanded
+// from pools are always produced in the same order
+func SortedPoolNames(pools map[string]NodePool) []string {
+	names := make([]string, 0, len(pools))
+	for name := range pools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ExpandedNodeName is a node name together with the config entry producing it
+type ExpandedNodeName struct {
+	Name   string
+	Source string
+}
+
+// ExpandNodeNames returns the names of every node the config creates:
+// standalone nodes in config order, then pool nodes in pool name order
+func ExpandNodeNames(cfg *ClusterConfig) ([]ExpandedNodeName, error) {
+	var names []ExpandedNodeName
+
+	for i, node := range cfg.Nodes {
+		names = append(names, ExpandedNodeName{
+			Name:   node.Name,
+			Source: fmt.Sprintf("node %d (%s)", i, node.Name),
+		})
+	}
+
+	for _, poolName := range SortedPoolNames(cfg.NodePools) {
+		pool := cfg.NodePools[poolName]
+		for i := 0; i < pool.Count; i++ {
+			name, err := RenderPoolNodeName(poolName, &pool, i)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, ExpandedNodeName{
+				Name:   name,
+				Source: fmt.Sprintf("pool '%s' node %d", poolName, i+1),
+			})
+		}
+	}
+
+	return names, nil
+}
+
+// NodeNameCollisions returns one message for every node name produced by more
+// than one config entry, naming the conflicting entries. A name template that
+// cannot be rendered is reported instead.
+func NodeNameCollisions(cfg *ClusterConfig) []string {
+	expanded, err := ExpandNodeNames(cfg)
+	if err != nil {
+		return []string{err.Error()}
+	}
+
+	sources := make(map[string][]string)
+	var order []string
+
+	for _, n := range expanded {
+		if _, seen := sources[n.Name]; !seen {
+			order = append(order, n.Name)
+		}
+		sources[n.Name] = append(sources[n.Name], n.Source)
+	}
+
+	var collisions []string
+	for _, name := range order {
+		if len(sources[name]) > 1 {
+			collisions = append(collisions, fmt.Sprintf("node name '%s' is used by %s", name, strings.Join(sources[name], " and ")))
+		}
+	}
+	return collisions
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPoolNodeName(t *testing.T) {
+	if got := PoolNodeName("workers", 0); got != "workers-1" {
+		t.Errorf("Expected workers-1, got %s", got)
+	}
+	if got := PoolNodeName("workers", 9); got != "workers-10" {
+		t.Errorf("Expected workers-10, got %s", got)
+	}
+}
+
+func TestExpandNodeNames_Deterministic(t *testing.T) {
+	cfg := &ClusterConfig{
+		Nodes: []NodeConfig{{Name: "bastion-node"}},
+		NodePools: map[string]NodePool{
+			"workers": {Name: "workers", Count: 2},
+			"masters": {Name: "masters", Count: 1},
+		},
+	}
+
+	expanded, err := ExpandNodeNames(cfg)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var names []string
+	for _, n := range expanded {
+		names = append(names, n.Name)
+	}
+
+	want := []string{"bastion-node", "masters-1", "workers-1", "workers-2"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("Expected %v, got %v", want, names)
+	}
+}
+
+func TestNodeNameCollisions_Clean(t *testing.T) {
+	cfg := &ClusterConfig{
+		Nodes: []NodeConfig{{Name: "edge-1"}},
+		NodePools: map[string]NodePool{
+			"masters": {Name: "masters", Count: 3},
+			"workers": {Name: "workers", Count: 2},
+		},
+	}
+
+	if collisions := NodeNameCollisions(cfg); len(collisions) != 0 {
+		t.Errorf("Expected no collisions, got %v", collisions)
+	}
+}
+
+func TestNodeNameCollisions_PoolAndStandalone(t *testing.T) {
+	cfg := &ClusterConfig{
+		Nodes: []NodeConfig{{Name: "workers-2"}},
+		NodePools: map[string]NodePool{
+			"workers": {Name: "workers", Count: 3},
+		},
+	}
+
+	collisions := NodeNameCollisions(cfg)
+	if len(collisions) != 1 {
+		t.Fatalf("Expected 1 collision, got %v", collisions)
+	}
+	if !strings.Contains(collisions[0], "workers-2") ||
+		!strings.Contains(collisions[0], "node 0 (workers-2)") ||
+		!strings.Contains(collisions[0], "pool 'workers' node 2") {
+		t.Errorf("Collision should name both entries, got %q", collisions[0])
+	}
+}
+
+func TestNodeNameCollisions_DuplicateStandalone(t *testing.T) {
+	cfg := &ClusterConfig{
+		Nodes: []NodeConfig{{Name: "db"}, {Name: "db"}},
+	}
+
+	collisions := NodeNameCollisions(cfg)
+	if len(collisions) != 1 || !strings.Contains(collisions[0], "'db'") {
+		t.Errorf("Expected one collision for 'db', got %v", collisions)
+	}
+}
+
+func TestRenderPoolNodeName_Template(t *testing.T) {
+	tests := []struct {
+		name     string
+		pool     NodePool
+		index    int
+		expected string
+	}{
+		{"default scheme", NodePool{}, 0, "masters-1"},
+		{"padded index and role", NodePool{Roles: []string{"controlplane", "etcd"}, NameTemplate: `prod-k8s-{{if eq .Role "master"}}cp{{else}}wk{{end}}-{{printf "%02d" .Index}}`}, 0, "prod-k8s-cp-01"},
+		{"worker role", NodePool{Roles: []string{"worker"}, NameTemplate: "{{.Role}}-{{.Index}}"}, 2, "worker-3"},
+		{"provider and region", NodePool{Provider: "digitalocean", Region: "nyc3", NameTemplate: "{{.Provider}}-{{.Region}}-{{.Pool}}-{{.Index}}"}, 1, "digitalocean-nyc3-masters-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderPoolNodeName("masters", &tt.pool, tt.index)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRenderPoolNodeName_InvalidTemplates(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+	}{
+		{"parse error", "{{.Pool"},
+		{"unknown field", "{{.Zone}}-{{.Index}}"},
+		{"uppercase", "{{.Pool}}-NODE-{{.Index}}"},
+		{"underscore", "{{.Pool}}_{{.Index}}"},
+		{"empty", `{{if false}}x{{end}}`},
+		{"too long", strings.Repeat("a", 64)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool := NodePool{NameTemplate: tt.template}
+			if _, err := RenderPoolNodeName("workers", &pool, 0); err == nil {
+				t.Errorf("Expected error for template %q", tt.template)
+			}
+		})
+	}
+}
+
+func TestNodeNameCollisions_TemplateDuplicates(t *testing.T) {
+	cfg := &ClusterConfig{
+		NodePools: map[string]NodePool{
+			"workers": {Name: "workers", Count: 2, Roles: []string{"worker"}, NameTemplate: "{{.Pool}}-{{.Role}}"},
+		},
+	}
+
+	collisions := NodeNameCollisions(cfg)
+	if len(collisions) != 1 || !strings.Contains(collisions[0], "workers-worker") {
+		t.Errorf("Expected one collision for 'workers-worker', got %v", collisions)
+	}
+}
+
+func TestNodeNameCollisions_InvalidTemplate(t *testing.T) {
+	cfg := &ClusterConfig{
+		NodePools: map[string]NodePool{
+			"workers": {Name: "workers", Count: 1, NameTemplate: "Workers-{{.Index}}"},
+		},
+	}
+
+	collisions := NodeNameCollisions(cfg)
+	if len(collisions) != 1 || !strings.Contains(collisions[0], "invalid hostname") {
+		t.Errorf("Expected an invalid hostname error, got %v", collisions)
+	}
+}
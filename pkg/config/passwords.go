@@ -0,0 +1,156 @@
+package config
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+// MinPasswordLength is the shortest admin password accepted
+const MinPasswordLength = 12
+
+// generatedPasswordLength is the length of generated admin passwords
+const generatedPasswordLength = 24
+
+// Characters of generated passwords. Symbols leave out quotes, backslashes,
+// $ and backticks so a password can be embedded in the provisioning scripts.
+const (
+	passwordLower   = "abcdefghijkmnopqrstuvwxyz"
+	passwordUpper   = "ABCDEFGHJKLMNPQRSTUVWXYZ"
+	passwordDigits  = "23456789"
+	passwordSymbols = "!#%*+-.:=?@^_~"
+)
+
+// unsafePasswordChars would break out of the shell scripts passwords are
+// embedded in
+const unsafePasswordChars = "'\"\\$`"
+
+// Pulumi config keys holding generated admin passwords
+const (
+	PulumiKeyGrafanaAdminPassword = "grafanaAdminPassword"
+	PulumiKeyArgoCDAdminPassword  = "argocdAdminPassword"
+	PulumiKeySaltAPIPassword      = "saltApiPassword"
+)
+
+// AdminPassword is the admin password of a service the cluster runs
+type AdminPassword struct {
+	// Name names the password in messages
+	Name string
+	// Key is the Pulumi config key a generated password is kept in
+	Key string
+	// Output is the stack output the password is exported as
+	Output string
+	// Value points at the password in the cluster config
+	Value *string
+}
+
+// AdminPasswords returns the admin passwords of the enabled services: Grafana,
+// ArgoCD and the Salt API on the bastion
+func (c *ClusterConfig) AdminPasswords() []AdminPassword {
+	var passwords []AdminPassword
+	if g := c.Monitoring.Grafana; g != nil && g.Enabled {
+		passwords = append(passwords, AdminPassword{"Grafana admin", PulumiKeyGrafanaAdminPassword, "grafana_admin_password", &g.AdminPassword})
+	}
+	if a := c.Addons.ArgoCD; a != nil && a.Enabled {
+		passwords = append(passwords, AdminPassword{"ArgoCD admin", PulumiKeyArgoCDAdminPassword, "argocd_admin_password", &a.AdminPassword})
+	}
+	if b := c.Security.Bastion; b != nil && b.Enabled && b.SaltEnabled() {
+		key := PulumiKeySaltAPIPassword
+		if b.SaltAPIPasswordFromSecret != "" {
+			key = b.SaltAPIPasswordFromSecret
+		}
+		passwords = append(passwords, AdminPassword{"Salt API", key, "salt_api_password", &b.SaltAPIPassword})
+	}
+	return passwords
+}
+
+// ValidatePasswordStrength checks that a password is at least
+// MinPasswordLength characters long, mixes at least three of lowercase,
+// uppercase, digits and symbols, and can be embedded in a shell script
+func ValidatePasswordStrength(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("must be at least %d characters long", MinPasswordLength)
+	}
+	if strings.ContainsAny(password, unsafePasswordChars) {
+		return fmt.Errorf("must not contain quotes, backslashes, $ or backticks")
+	}
+
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	classes := 0
+	for _, present := range []bool{lower, upper, digit, symbol} {
+		if present {
+			classes++
+		}
+	}
+	if classes < 3 {
+		return fmt.Errorf("must mix at least three of lowercase letters, uppercase letters, digits and symbols")
+	}
+	return nil
+}
+
+// ValidateAdminPasswords checks the strength of the admin passwords set for
+// the enabled services. Passwords left empty are generated at deploy time.
+func ValidateAdminPasswords(cfg *ClusterConfig) error {
+	for _, password := range cfg.AdminPasswords() {
+		if *password.Value == "" {
+			continue
+		}
+		if err := ValidatePasswordStrength(*password.Value); err != nil {
+			return fmt.Errorf("%s password %w", password.Name, err)
+		}
+	}
+	return nil
+}
+
+// GeneratePassword returns a random password of generatedPasswordLength
+// characters with every character class in it
+func GeneratePassword() (string, error) {
+	classes := []string{passwordLower, passwordUpper, passwordDigits, passwordSymbols}
+	all := strings.Join(classes, "")
+
+	password := make([]byte, generatedPasswordLength)
+	for i := range password {
+		charset := all
+		if i < len(classes) {
+			charset = classes[i]
+		}
+		c, err := randomChar(charset)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate password: %w", err)
+		}
+		password[i] = c
+	}
+
+	// Move the characters guaranteeing each class to random positions
+	for i := len(password) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate password: %w", err)
+		}
+		password[i], password[j.Int64()] = password[j.Int64()], password[i]
+	}
+	return string(password), nil
+}
+
+// randomChar returns a uniformly random character of charset
+func randomChar(charset string) (byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(int64(len(charset))))
+	if err != nil {
+		return 0, err
+	}
+	return charset[n.Int64()], nil
+}
@@ -0,0 +1,114 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidatePasswordStrength(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		wantErr  string
+	}{
+		{name: "strong", password: "Correct-Horse-42"},
+		{name: "three classes", password: "correcthorse42X"},
+		{name: "too short", password: "Ab1!xyz", wantErr: "at least 12 characters"},
+		{name: "single quote", password: "Correct'Horse42", wantErr: "must not contain"},
+		{name: "dollar", password: "Correct$Horse42", wantErr: "must not contain"},
+		{name: "backtick", password: "Correct`Horse42", wantErr: "must not contain"},
+		{name: "two classes", password: "correcthorsebattery42", wantErr: "at least three"},
+		{name: "well known default", password: "saltapi123", wantErr: "at least 12 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePasswordStrength(tt.password)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateAdminPasswords(t *testing.T) {
+	cfg := &ClusterConfig{
+		Monitoring: MonitoringConfig{Grafana: &GrafanaConfig{Enabled: true}},
+		Addons:     AddonsConfig{ArgoCD: &ArgoCDConfig{Enabled: true, AdminPassword: "Correct-Horse-42"}},
+		Security:   SecurityConfig{Bastion: &BastionConfig{Enabled: true, SaltAPIPassword: "saltapi123"}},
+	}
+
+	err := ValidateAdminPasswords(cfg)
+	if err == nil || !strings.Contains(err.Error(), "Salt API password") {
+		t.Fatalf("expected a Salt API password error, got %v", err)
+	}
+
+	// Empty passwords are generated at deploy time
+	cfg.Security.Bastion.SaltAPIPassword = ""
+	if err := ValidateAdminPasswords(cfg); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// Disabled services are not checked
+	cfg.Security.Bastion = &BastionConfig{SaltAPIPassword: "weak"}
+	if err := ValidateAdminPasswords(cfg); err != nil {
+		t.Errorf("unexpected error for a disabled bastion: %v", err)
+	}
+}
+
+func TestAdminPasswords(t *testing.T) {
+	cfg := &ClusterConfig{
+		Monitoring: MonitoringConfig{Grafana: &GrafanaConfig{Enabled: true}},
+		Security:   SecurityConfig{Bastion: &BastionConfig{Enabled: true}},
+	}
+
+	passwords := cfg.AdminPasswords()
+	if len(passwords) != 2 {
+		t.Fatalf("AdminPasswords() = %+v, want Grafana and Salt API", passwords)
+	}
+	if passwords[0].Key != PulumiKeyGrafanaAdminPassword || passwords[1].Key != PulumiKeySaltAPIPassword {
+		t.Errorf("unexpected keys: %s, %s", passwords[0].Key, passwords[1].Key)
+	}
+
+	*passwords[1].Value = "set"
+	if cfg.Security.Bastion.SaltAPIPassword != "set" {
+		t.Error("Value should point at the config field")
+	}
+
+	cfg.Security.Bastion.SaltAPIPasswordFromSecret = "bastionSaltPassword"
+	if passwords := cfg.AdminPasswords(); passwords[1].Key != "bastionSaltPassword" {
+		t.Errorf("Salt API password key = %s, want the configured secret", passwords[1].Key)
+	}
+
+	installSalt := false
+	cfg.Security.Bastion.InstallSalt = &installSalt
+	if passwords := cfg.AdminPasswords(); len(passwords) != 1 {
+		t.Errorf("AdminPasswords() = %+v, want no Salt API password without Salt", passwords)
+	}
+}
+
+func TestGeneratePassword(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		password, err := GeneratePassword()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(password) != generatedPasswordLength {
+			t.Errorf("len(%q) = %d, want %d", password, len(password), generatedPasswordLength)
+		}
+		if err := ValidatePasswordStrength(password); err != nil {
+			t.Errorf("generated password %q is not strong: %v", password, err)
+		}
+		if seen[password] {
+			t.Errorf("password %q generated twice", password)
+		}
+		seen[password] = true
+	}
+}
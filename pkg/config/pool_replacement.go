@@ -0,0 +1,106 @@
+package config
+
+import "fmt"
+
+// ReplacementAction is one kind of step of a rolling node replacement
+type ReplacementAction string
+
+const (
+	// ReplacementCreate creates the node at the pool size
+	ReplacementCreate ReplacementAction = "create"
+	// ReplacementJoin waits for the node to join the cluster and become Ready
+	ReplacementJoin ReplacementAction = "join"
+	// ReplacementDrain cordons the node and evicts its pods
+	ReplacementDrain ReplacementAction = "drain"
+	// ReplacementRemove deletes the node from the cluster and destroys it
+	ReplacementRemove ReplacementAction = "remove"
+)
+
+// ReplacementStep is one step of a rolling node replacement
+type ReplacementStep struct {
+	Action ReplacementAction
+	Node   string
+
+	// Capacity is the number of schedulable nodes of the pool once the step
+	// is done
+	Capacity int
+}
+
+// SurgeNodeName returns the name of the temporary extra node a pool runs
+// while its nodes are replaced
+func SurgeNodeName(poolName string) string {
+	return poolName + "-surge"
+}
+
+// StaleSizeNodes returns the deployed nodes of a pool running another size
+// than pool.Size, in pool order. deployedSizes maps deployed node names to
+// their size.
+func StaleSizeNodes(poolName string, pool *NodePool, deployedSizes map[string]string) ([]string, error) {
+	var stale []string
+	for i := 0; i < pool.Count; i++ {
+		name, err := RenderPoolNodeName(poolName, pool, i)
+		if err != nil {
+			return nil, err
+		}
+		if size, ok := deployedSizes[name]; ok && size != "" && size != pool.Size {
+			stale = append(stale, name)
+		}
+	}
+	return stale, nil
+}
+
+// PlanSizeReplacement plans the rolling replacement of the stale nodes of a
+// worker pool, those deployed with another size than pool.Size. A surge node
+// at the new size joins first, then each stale node in turn is drained,
+// removed and created again at the new size, and the surge node is drained
+// and removed last. The pool therefore never has fewer schedulable nodes
+// than pool.Count; a plan that would drop below pool.MinCount is refused.
+func PlanSizeReplacement(poolName string, pool *NodePool, stale []string) ([]ReplacementStep, error) {
+	if len(stale) == 0 {
+		return nil, nil
+	}
+	if HasRole(pool.Roles, RoleMaster) {
+		return nil, fmt.Errorf("pool '%s' runs control plane nodes: size changes are only rolled out on worker pools", poolName)
+	}
+
+	capacity := pool.Count
+	var steps []ReplacementStep
+	add := func(action ReplacementAction, node string) {
+		switch action {
+		case ReplacementJoin:
+			capacity++
+		case ReplacementDrain:
+			capacity--
+		}
+		steps = append(steps, ReplacementStep{Action: action, Node: node, Capacity: capacity})
+	}
+
+	surge := SurgeNodeName(poolName)
+	add(ReplacementCreate, surge)
+	add(ReplacementJoin, surge)
+	for _, node := range stale {
+		add(ReplacementDrain, node)
+		add(ReplacementRemove, node)
+		add(ReplacementCreate, node)
+		add(ReplacementJoin, node)
+	}
+	add(ReplacementDrain, surge)
+	add(ReplacementRemove, surge)
+
+	for _, step := range steps {
+		if step.Capacity < pool.MinCount {
+			return nil, fmt.Errorf("replacing pool '%s' would leave %d schedulable node(s) after %s %s, below its minCount of %d",
+				poolName, step.Capacity, step.Action, step.Node, pool.MinCount)
+		}
+	}
+	return steps, nil
+}
+
+// NodeSize returns the size the named pool node is deployed with: the pool
+// size, unless the node still awaits a rolling replacement
+func (p *NodePool) NodeSize(name string) string {
+	if size, ok := p.NodeSizes[name]; ok {
+		return size
+	}
+	return p.Size
+}
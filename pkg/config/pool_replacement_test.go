@@ -0,0 +1,100 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStaleSizeNodes(t *testing.T) {
+	pool := &NodePool{Name: "workers", Count: 3, Size: "s-4vcpu-8gb", Roles: []string{"worker"}}
+	deployed := map[string]string{
+		"workers-1": "s-2vcpu-4gb",
+		"workers-2": "s-4vcpu-8gb", // already replaced
+		"workers-3": "s-2vcpu-4gb",
+		"masters-1": "s-2vcpu-4gb",
+	}
+
+	stale, err := StaleSizeNodes("workers", pool, deployed)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := []string{"workers-1", "workers-3"}; !reflect.DeepEqual(stale, want) {
+		t.Errorf("Expected %v, got %v", want, stale)
+	}
+
+	// Nodes not deployed yet are created at the new size, not replaced
+	if stale, _ := StaleSizeNodes("workers", pool, nil); len(stale) != 0 {
+		t.Errorf("Expected no stale nodes before the first deploy, got %v", stale)
+	}
+}
+
+func TestPlanSizeReplacement_Ordering(t *testing.T) {
+	pool := &NodePool{Name: "workers", Count: 2, MinCount: 2, Size: "s-4vcpu-8gb", Roles: []string{"worker"}}
+
+	steps, err := PlanSizeReplacement("workers", pool, []string{"workers-1", "workers-2"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var got []string
+	for _, step := range steps {
+		got = append(got, fmt.Sprintf("%s %s %d", step.Action, step.Node, step.Capacity))
+	}
+	want := []string{
+		"create workers-surge 2",
+		"join workers-surge 3",
+		"drain workers-1 2",
+		"remove workers-1 2",
+		"create workers-1 2",
+		"join workers-1 3",
+		"drain workers-2 2",
+		"remove workers-2 2",
+		"create workers-2 2",
+		"join workers-2 3",
+		"drain workers-surge 2",
+		"remove workers-surge 2",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected steps:\n%s\ngot:\n%s", strings.Join(want, "\n"), strings.Join(got, "\n"))
+	}
+}
+
+func TestPlanSizeReplacement_Refused(t *testing.T) {
+	tests := []struct {
+		name string
+		pool NodePool
+		want string
+	}{
+		{
+			name: "control plane pool",
+			pool: NodePool{Count: 3, Roles: []string{"controlplane", "etcd"}},
+			want: "only rolled out on worker pools",
+		},
+		{
+			name: "below minCount",
+			pool: NodePool{Count: 2, MinCount: 3, Roles: []string{"worker"}},
+			want: "would leave 2 schedulable node(s) after create workers-surge, below its minCount of 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := PlanSizeReplacement("workers", &tt.pool, []string{"workers-1"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestNodePoolNodeSize(t *testing.T) {
+	pool := &NodePool{Size: "s-4vcpu-8gb", NodeSizes: map[string]string{"workers-2": "s-2vcpu-4gb"}}
+	if got := pool.NodeSize("workers-1"); got != "s-4vcpu-8gb" {
+		t.Errorf("Expected the pool size, got %s", got)
+	}
+	if got := pool.NodeSize("workers-2"); got != "s-2vcpu-4gb" {
+		t.Errorf("Expected the size of a node awaiting replacement, got %s", got)
+	}
+}
@@ -1,27 +1,94 @@
 package config
 
 import (
+	"fmt"
+	"sort"
+
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
 )
 
+// Pulumi config keys read by LoadPulumiConfig
+const (
+	PulumiKeyDigitalOceanToken        = "digitaloceanToken"
+	PulumiKeyLinodeToken              = "linodeToken"
+	PulumiKeyWireGuardServerEndpoint  = "wireguardServerEndpoint"
+	PulumiKeyWireGuardServerPublicKey = "wireguardServerPublicKey"
+	PulumiKeyWireGuardCreate          = "wireguardCreate"
+	PulumiKeyRKE2ClusterToken         = "rke2ClusterToken"
+)
+
+// requiredPulumiKeys must be set for a Pulumi-config based deployment. The
+// WireGuard server endpoint and public key are only required when the server
+// is not created by the deployment, see ValidateWireGuardServer.
+var requiredPulumiKeys = []string{
+	PulumiKeyDigitalOceanToken,
+	PulumiKeyLinodeToken,
+}
+
+// optionalPulumiKeys are read when set
+var optionalPulumiKeys = []string{
+	PulumiKeyWireGuardServerEndpoint,
+	PulumiKeyWireGuardServerPublicKey,
+	PulumiKeyWireGuardCreate,
+	PulumiKeyRKE2ClusterToken,
+}
+
+// IsSecretPulumiKey reports whether a Pulumi config key holds a secret
+func IsSecretPulumiKey(key string) bool {
+	switch key {
+	case PulumiKeyDigitalOceanToken, PulumiKeyLinodeToken, PulumiKeyRKE2ClusterToken,
+		PulumiKeyGrafanaAdminPassword, PulumiKeyArgoCDAdminPassword, PulumiKeySaltAPIPassword:
+		return true
+	}
+	return false
+}
+
 // LoadPulumiConfig loads cluster configuration from Pulumi config
 // This replaces the loadConfigFromPulumi function in main.go
 func LoadPulumiConfig(ctx *pulumi.Context) (*ClusterConfig, error) {
 	conf := config.New(ctx, "")
 
-	// Get provider tokens from Pulumi config
-	doToken := conf.Require("digitaloceanToken")
-	linodeToken := conf.Require("linodeToken")
+	// Get provider tokens, WireGuard and RKE2 settings from Pulumi config
+	values := make(map[string]string)
+	for _, key := range requiredPulumiKeys {
+		values[key] = conf.Require(key)
+	}
+	for _, key := range optionalPulumiKeys {
+		values[key] = conf.Get(key)
+	}
 
-	// Get WireGuard configuration from Pulumi config
-	wgEndpoint := conf.Require("wireguardServerEndpoint")
-	wgPubKey := conf.Require("wireguardServerPublicKey")
+	return BuildFromPulumiValues(values)
+}
 
-	// Get RKE2 cluster token from Pulumi config (optional, will generate if not set)
-	rke2Token := conf.Get("rke2ClusterToken")
-	if rke2Token == "" {
-		rke2Token = "my-super-secret-cluster-token-rke2-production-2025"
+// BuildFromPulumiValues builds the cluster configuration used by Pulumi-config
+// based deployments from raw config values keyed by Pulumi config key
+func BuildFromPulumiValues(values map[string]string) (*ClusterConfig, error) {
+	var missing []string
+	for _, key := range requiredPulumiKeys {
+		if values[key] == "" {
+			missing = append(missing, key)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("missing required Pulumi config: %v", missing)
+	}
+
+	doToken := values[PulumiKeyDigitalOceanToken]
+	linodeToken := values[PulumiKeyLinodeToken]
+	wireGuard := &WireGuardConfig{
+		Enabled:         true,
+		Create:          values[PulumiKeyWireGuardCreate] == "true",
+		ServerEndpoint:  values[PulumiKeyWireGuardServerEndpoint],
+		ServerPublicKey: values[PulumiKeyWireGuardServerPublicKey],
+	}
+	if wireGuard.Create {
+		wireGuard.Provider = "digitalocean"
+		wireGuard.Region = "nyc3"
+	}
+	if err := ValidateWireGuardServer(wireGuard); err != nil {
+		return nil, err
 	}
 
 	// Build cluster config
@@ -47,11 +114,7 @@ func LoadPulumiConfig(ctx *pulumi.Context) (*ClusterConfig, error) {
 				Domain:   "chalkan3.com.br",
 				Provider: "digitalocean",
 			},
-			WireGuard: &WireGuardConfig{
-				Enabled:         true,
-				ServerEndpoint:  wgEndpoint,
-				ServerPublicKey: wgPubKey,
-			},
+			WireGuard: wireGuard,
 		},
 		NodePools: map[string]NodePool{
 			"do-masters": {
@@ -93,5 +156,39 @@ func LoadPulumiConfig(ctx *pulumi.Context) (*ClusterConfig, error) {
 		},
 	}
 
+	// RKE2 cluster token is optional, RKE2 generates one if not set
+	if token := values[PulumiKeyRKE2ClusterToken]; token != "" {
+		cfg.Kubernetes.RKE2 = &RKE2Config{ClusterToken: token}
+	}
+
 	return cfg, nil
 }
+
+// PulumiValuesFromConfig extracts the Pulumi config values that
+// BuildFromPulumiValues reads, so a YAML config can drive a Pulumi-config stack
+func PulumiValuesFromConfig(cfg *ClusterConfig) map[string]string {
+	values := make(map[string]string)
+
+	if cfg.Providers.DigitalOcean != nil && cfg.Providers.DigitalOcean.Enabled {
+		values[PulumiKeyDigitalOceanToken] = cfg.Providers.DigitalOcean.Token
+	}
+	if cfg.Providers.Linode != nil && cfg.Providers.Linode.Enabled {
+		values[PulumiKeyLinodeToken] = cfg.Providers.Linode.Token
+	}
+	if wg := cfg.Network.WireGuard; wg != nil {
+		if wg.ServerEndpoint != "" {
+			values[PulumiKeyWireGuardServerEndpoint] = wg.ServerEndpoint
+		}
+		if wg.ServerPublicKey != "" {
+			values[PulumiKeyWireGuardServerPublicKey] = wg.ServerPublicKey
+		}
+		if wg.Create {
+			values[PulumiKeyWireGuardCreate] = "true"
+		}
+	}
+	if cfg.Kubernetes.RKE2 != nil && cfg.Kubernetes.RKE2.ClusterToken != "" {
+		values[PulumiKeyRKE2ClusterToken] = cfg.Kubernetes.RKE2.ClusterToken
+	}
+
+	return values
+}
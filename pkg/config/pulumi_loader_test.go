@@ -0,0 +1,77 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBuildFromPulumiValues_MissingKeys(t *testing.T) {
+	_, err := BuildFromPulumiValues(map[string]string{
+		PulumiKeyDigitalOceanToken: "do-token",
+	})
+	if err == nil {
+		t.Fatal("Expected error for missing required keys")
+	}
+	if !strings.Contains(err.Error(), PulumiKeyLinodeToken) {
+		t.Errorf("Expected missing key in error, got: %v", err)
+	}
+}
+
+func TestPulumiValuesRoundTrip(t *testing.T) {
+	values := map[string]string{
+		PulumiKeyDigitalOceanToken:        "do-token",
+		PulumiKeyLinodeToken:              "linode-token",
+		PulumiKeyWireGuardServerEndpoint:  "203.0.113.1:51820",
+		PulumiKeyWireGuardServerPublicKey: "server-public-key",
+		PulumiKeyRKE2ClusterToken:         "cluster-token",
+	}
+
+	cfg, err := BuildFromPulumiValues(values)
+	if err != nil {
+		t.Fatalf("BuildFromPulumiValues failed: %v", err)
+	}
+
+	got := PulumiValuesFromConfig(cfg)
+	for key, want := range values {
+		if got[key] != want {
+			t.Errorf("%s: expected %q, got %q", key, want, got[key])
+		}
+	}
+}
+
+func TestIsSecretPulumiKey(t *testing.T) {
+	if !IsSecretPulumiKey(PulumiKeyLinodeToken) {
+		t.Error("Linode token should be secret")
+	}
+	if IsSecretPulumiKey(PulumiKeyWireGuardServerEndpoint) {
+		t.Error("WireGuard endpoint should not be secret")
+	}
+}
+
+func TestBuildFromPulumiValues_CreatedWireGuardServer(t *testing.T) {
+	cfg, err := BuildFromPulumiValues(map[string]string{
+		PulumiKeyDigitalOceanToken: "do-token",
+		PulumiKeyLinodeToken:       "linode-token",
+		PulumiKeyWireGuardCreate:   "true",
+	})
+	if err != nil {
+		t.Fatalf("Expected a created server to need no endpoint or key, got %v", err)
+	}
+	if !cfg.Network.WireGuard.Create {
+		t.Error("Expected WireGuard create to be set")
+	}
+	if got := PulumiValuesFromConfig(cfg); got[PulumiKeyWireGuardCreate] != "true" || got[PulumiKeyWireGuardServerEndpoint] != "" {
+		t.Errorf("Unexpected Pulumi values for a created server: %v", got)
+	}
+}
+
+func TestBuildFromPulumiValues_ExistingWireGuardServerNeedsIdentity(t *testing.T) {
+	_, err := BuildFromPulumiValues(map[string]string{
+		PulumiKeyDigitalOceanToken:       "do-token",
+		PulumiKeyLinodeToken:             "linode-token",
+		PulumiKeyWireGuardServerEndpoint: "203.0.113.1:51820",
+	})
+	if err == nil || !strings.Contains(err.Error(), "serverPublicKey") {
+		t.Errorf("Expected the missing public key to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Packaged RKE2 components that may appear in rke2.disableComponents
+const (
+	rke2CoreDNS       = "rke2-coredns"
+	rke2IngressNginx  = "rke2-ingress-nginx"
+	rke2MetricsServer = "rke2-metrics-server"
+	rke2ServiceLB     = "rke2-servicelb"
+)
+
+// replaceableRKE2Components can be disabled freely: the tool installs its own
+// ingress controller, and the rest are optional extras
+var replaceableRKE2Components = map[string]bool{
+	rke2IngressNginx:                   true,
+	"rke2-snapshot-controller":         true,
+	"rke2-snapshot-controller-crd":     true,
+	"rke2-snapshot-validation-webhook": true,
+	"rke2-runtimeclasses":              true,
+}
+
+// CheckDisabledComponents checks rke2.disableComponents against what the tool
+// relies on. Errors are components the cluster cannot work without, warnings
+// are components whose loss breaks features the configuration uses.
+func CheckDisabledComponents(cfg *ClusterConfig) (errs []string, warnings []string) {
+	rke2 := cfg.Kubernetes.RKE2
+	if rke2 == nil {
+		return nil, nil
+	}
+
+	cni := cfg.Kubernetes.NetworkPlugin
+	if cni == "" {
+		cni = "canal"
+	}
+
+	for _, component := range rke2.DisableComponents {
+		name := strings.TrimSpace(component)
+		switch {
+		case replaceableRKE2Components[name]:
+			continue
+		case name == rke2CoreDNS:
+			errs = append(errs, fmt.Sprintf("%s cannot be disabled: cluster DNS is required by the ingress, cert-manager and addon installs", name))
+		case name == "rke2-"+cni:
+			errs = append(errs, fmt.Sprintf("%s cannot be disabled: it is the configured network plugin (kubernetes.networkPlugin: %s)", name, cni))
+		case name == rke2ServiceLB:
+			if expectsLoadBalancer(cfg) {
+				warnings = append(warnings, fmt.Sprintf("%s is disabled but the configuration uses LoadBalancer services - they stay pending without a cloud load balancer", name))
+			}
+		case name == rke2MetricsServer:
+			warnings = append(warnings, fmt.Sprintf("%s is disabled - kubectl top and HorizontalPodAutoscalers will not work", name))
+		case strings.HasPrefix(name, "rke2-canal"), strings.HasPrefix(name, "rke2-calico"), strings.HasPrefix(name, "rke2-cilium"):
+			// Network plugins other than the configured one are not deployed anyway
+		default:
+			warnings = append(warnings, fmt.Sprintf("%s is not a known RKE2 component", name))
+		}
+	}
+
+	return errs, warnings
+}
+
+// ValidateDisabledComponents returns an error when rke2.disableComponents
+// removes a component the cluster depends on
+func ValidateDisabledComponents(cfg *ClusterConfig) error {
+	errs, _ := CheckDisabledComponents(cfg)
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid rke2.disableComponents:\n  • %s", strings.Join(errs, "\n  • "))
+	}
+	return nil
+}
+
+// expectsLoadBalancer reports whether the configuration relies on
+// LoadBalancer services, i.e. load balancers or an ingress controller
+func expectsLoadBalancer(cfg *ClusterConfig) bool {
+	return len(cfg.Network.LoadBalancers) > 0 || cfg.Network.Ingress.Controller != "" || cfg.LoadBalancer.Type != ""
+}
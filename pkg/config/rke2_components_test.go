@@ -0,0 +1,86 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func configWithDisabled(components ...string) *ClusterConfig {
+	return &ClusterConfig{
+		Kubernetes: KubernetesConfig{
+			NetworkPlugin: "canal",
+			RKE2:          &RKE2Config{DisableComponents: components},
+		},
+	}
+}
+
+func TestCheckDisabledComponents_AllowsReplacedComponents(t *testing.T) {
+	cfg := configWithDisabled("rke2-ingress-nginx", "rke2-snapshot-controller", "rke2-cilium")
+
+	errs, warnings := CheckDisabledComponents(cfg)
+	if len(errs) != 0 || len(warnings) != 0 {
+		t.Errorf("Expected no issues, got errors %v, warnings %v", errs, warnings)
+	}
+	if err := ValidateDisabledComponents(cfg); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
+
+func TestCheckDisabledComponents_RejectsRequiredComponents(t *testing.T) {
+	cfg := configWithDisabled("rke2-ingress-nginx", "rke2-coredns", "rke2-canal")
+
+	errs, _ := CheckDisabledComponents(cfg)
+	if len(errs) != 2 {
+		t.Fatalf("Expected coredns and the CNI to be rejected, got %v", errs)
+	}
+	if !strings.Contains(errs[0], "rke2-coredns") || !strings.Contains(errs[1], "rke2-canal") {
+		t.Errorf("Unexpected errors: %v", errs)
+	}
+
+	err := ValidateDisabledComponents(cfg)
+	if err == nil || !strings.Contains(err.Error(), "rke2-coredns") {
+		t.Errorf("Expected a validation error naming rke2-coredns, got %v", err)
+	}
+}
+
+func TestCheckDisabledComponents_DefaultCNI(t *testing.T) {
+	cfg := configWithDisabled("rke2-canal")
+	cfg.Kubernetes.NetworkPlugin = ""
+
+	if errs, _ := CheckDisabledComponents(cfg); len(errs) != 1 {
+		t.Errorf("Expected the default canal CNI to be protected, got %v", errs)
+	}
+}
+
+func TestCheckDisabledComponents_ServiceLBNeedsLoadBalancer(t *testing.T) {
+	cfg := configWithDisabled("rke2-servicelb")
+	if _, warnings := CheckDisabledComponents(cfg); len(warnings) != 0 {
+		t.Errorf("Expected no warning without LoadBalancer services, got %v", warnings)
+	}
+
+	cfg.Network.Ingress.Controller = "nginx"
+	_, warnings := CheckDisabledComponents(cfg)
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "LoadBalancer") {
+		t.Errorf("Expected a LoadBalancer warning, got %v", warnings)
+	}
+	if err := ValidateDisabledComponents(cfg); err != nil {
+		t.Errorf("Warnings should not fail validation: %v", err)
+	}
+}
+
+func TestCheckDisabledComponents_WarnsOnDegradedAndUnknown(t *testing.T) {
+	_, warnings := CheckDisabledComponents(configWithDisabled("rke2-metrics-server", "rke2-typo"))
+	if len(warnings) != 2 {
+		t.Fatalf("Expected two warnings, got %v", warnings)
+	}
+	if !strings.Contains(warnings[0], "kubectl top") || !strings.Contains(warnings[1], "not a known") {
+		t.Errorf("Unexpected warnings: %v", warnings)
+	}
+}
+
+func TestCheckDisabledComponents_NoRKE2Config(t *testing.T) {
+	errs, warnings := CheckDisabledComponents(&ClusterConfig{})
+	if errs != nil || warnings != nil {
+		t.Errorf("Expected no issues without rke2 config, got %v, %v", errs, warnings)
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"strings"
 )
 
@@ -57,6 +58,19 @@ func BuildRKE2ServerConfig(cfg *RKE2Config, nodeIP, nodeName string, isFirstMast
 	if k8sConfig.ClusterDNS != "" {
 		builder.WriteString(fmt.Sprintf("cluster-dns: %s\n", k8sConfig.ClusterDNS))
 	}
+	if k8sConfig.NodeLocalDNSEnabled() {
+		// Pods resolve through the node-local cache instead of the DNS service
+		builder.WriteString("kubelet-arg:\n")
+		builder.WriteString(fmt.Sprintf("  - cluster-dns=%s\n", k8sConfig.KubeletClusterDNS()))
+	}
+
+	// Admission plugins
+	if apiServerArgs := AdmissionAPIServerArgs(&k8sConfig.Admission, AdmissionConfigPath); len(apiServerArgs) > 0 {
+		builder.WriteString("kube-apiserver-arg:\n")
+		for _, arg := range apiServerArgs {
+			builder.WriteString(fmt.Sprintf("  - %s\n", arg))
+		}
+	}
 
 	// CNI
 	if k8sConfig.NetworkPlugin != "" {
@@ -116,7 +130,7 @@ func BuildRKE2ServerConfig(cfg *RKE2Config, nodeIP, nodeName string, isFirstMast
 	if cfg.SeLinux {
 		builder.WriteString("selinux: true\n")
 	}
-	if cfg.SecretsEncryption {
+	if cfg.SecretsEncryption || k8sConfig.EncryptSecrets {
 		builder.WriteString("secrets-encryption: true\n")
 	}
 	if cfg.ProtectKernelDefaults {
@@ -143,13 +157,19 @@ func BuildRKE2ServerConfig(cfg *RKE2Config, nodeIP, nodeName string, isFirstMast
 }
 
 // BuildRKE2AgentConfig generates the RKE2 agent (worker) config file content
-func BuildRKE2AgentConfig(cfg *RKE2Config, nodeIP, nodeName, serverIP string) string {
+func BuildRKE2AgentConfig(cfg *RKE2Config, nodeIP, nodeName, serverIP string, k8sConfig *KubernetesConfig) string {
 	var builder strings.Builder
 
 	// Basic configuration
 	builder.WriteString(fmt.Sprintf("token: %s\n", cfg.ClusterToken))
 	builder.WriteString(fmt.Sprintf("server: https://%s:9345\n", serverIP))
 
+	if k8sConfig.NodeLocalDNSEnabled() {
+		// Pods resolve through the node-local cache, as on the servers
+		builder.WriteString("kubelet-arg:\n")
+		builder.WriteString(fmt.Sprintf("  - cluster-dns=%s\n", k8sConfig.KubeletClusterDNS()))
+	}
+
 	// Node configuration
 	builder.WriteString(fmt.Sprintf("node-name: %s\n", nodeName))
 	builder.WriteString(fmt.Sprintf("node-ip: %s\n", nodeIP))
@@ -199,11 +219,77 @@ func BuildRKE2AgentConfig(cfg *RKE2Config, nodeIP, nodeName, serverIP string) st
 	return builder.String()
 }
 
-// GetRKE2InstallCommand returns the installation command for RKE2
-func GetRKE2InstallCommand(cfg *RKE2Config, isServer bool) string {
+// rke2ArtifactDir is where air-gapped installs stage the release artifacts
+const rke2ArtifactDir = "/root/rke2-artifacts"
+
+// rke2AirGapArtifacts are the release files the install script needs when
+// INSTALL_RKE2_ARTIFACT_PATH is set
+var rke2AirGapArtifacts = []string{
+	"rke2-images.linux-amd64.tar.zst",
+	"rke2.linux-amd64.tar.gz",
+	"sha256sum-amd64.txt",
+}
+
+// registryMirrorUpstreams are the public registries redirected to the
+// system default registry, so workload images are pulled from it as well
+var registryMirrorUpstreams = []string{
+	"docker.io",
+	"registry.k8s.io",
+	"quay.io",
+	"ghcr.io",
+}
+
+// AirGapEnabled reports whether RKE2 should be installed from a mirror
+func (c *RKE2Config) AirGapEnabled() bool {
+	return c != nil && c.AirGap != nil && c.AirGap.Enabled
+}
+
+// AirGapInstallScriptURL returns the install script location for air-gapped
+// installs, defaulting to install.sh next to the artifacts
+func (c *RKE2Config) AirGapInstallScriptURL() string {
+	if !c.AirGapEnabled() {
+		return ""
+	}
+	if c.AirGap.InstallScriptURL != "" {
+		return c.AirGap.InstallScriptURL
+	}
+	return strings.TrimSuffix(c.AirGap.ArtifactURL, "/") + "/install.sh"
+}
+
+// rke2InstallScriptPath is where the install script is downloaded before it
+// runs
+const rke2InstallScriptPath = "/tmp/rke2-install.sh"
+
+// InstallScriptURL returns the install script location: the mirror for
+// air-gapped installs, the script of the release when a version is set
+func (c *RKE2Config) InstallScriptURL() string {
+	if c.AirGapEnabled() {
+		return c.AirGapInstallScriptURL()
+	}
+	if c.Version != "" {
+		return fmt.Sprintf(rke2ReleaseInstallScriptURL, c.Version)
+	}
+	return rke2InstallScriptURL
+}
+
+// GetRKE2InstallCommand returns the installation command for RKE2. The
+// install script is downloaded and, unless security disables it, checked
+// against its pinned checksum before it runs.
+func GetRKE2InstallCommand(cfg *RKE2Config, isServer bool, security *SecurityConfig) string {
 	var builder strings.Builder
 
-	builder.WriteString("curl -sfL https://get.rke2.io | ")
+	// registries.yaml must be in place before RKE2 first starts
+	if registries := writeRegistriesCommand(cfg, "/etc/rancher/rke2"); registries != "" {
+		builder.WriteString(registries + " && ")
+	}
+
+	if cfg.AirGapEnabled() {
+		builder.WriteString(getRKE2AirGapInstallCommand(cfg, isServer, security))
+		return builder.String()
+	}
+
+	download := security.Download(DownloadRKE2Installer, cfg.InstallScriptURL())
+	builder.WriteString(download.Fetch(rke2InstallScriptPath) + " && ")
 
 	// Type (server or agent)
 	if isServer {
@@ -222,11 +308,139 @@ func GetRKE2InstallCommand(cfg *RKE2Config, isServer bool) string {
 		builder.WriteString(fmt.Sprintf("INSTALL_RKE2_CHANNEL=%s ", cfg.Channel))
 	}
 
-	builder.WriteString("sh -")
+	builder.WriteString("sh " + rke2InstallScriptPath)
+
+	return builder.String()
+}
+
+// getRKE2AirGapInstallCommand stages the release artifacts from the mirror and
+// runs the mirrored install script against them. Version and channel are not
+// passed: the artifacts pin the version and channel lookups need internet access.
+func getRKE2AirGapInstallCommand(cfg *RKE2Config, isServer bool, security *SecurityConfig) string {
+	var builder strings.Builder
+
+	baseURL := strings.TrimSuffix(cfg.AirGap.ArtifactURL, "/")
+
+	builder.WriteString(fmt.Sprintf("mkdir -p %s && ", rke2ArtifactDir))
+	for _, artifact := range rke2AirGapArtifacts {
+		builder.WriteString(fmt.Sprintf("curl -sfL %s/%s -o %s/%s && ", baseURL, artifact, rke2ArtifactDir, artifact))
+	}
+
+	download := security.Download(DownloadRKE2Installer, cfg.InstallScriptURL())
+	builder.WriteString(download.Fetch(rke2InstallScriptPath) + " && ")
+	builder.WriteString(fmt.Sprintf("INSTALL_RKE2_ARTIFACT_PATH=%s ", rke2ArtifactDir))
+
+	if isServer {
+		builder.WriteString("INSTALL_RKE2_TYPE=server ")
+	} else {
+		builder.WriteString("INSTALL_RKE2_TYPE=agent ")
+	}
+
+	builder.WriteString("sh " + rke2InstallScriptPath)
 
 	return builder.String()
 }
 
+// BuildRKE2RegistriesConfig generates the registries.yaml of RKE2 and K3s,
+// which redirects the public registries to the system default registry.
+// Returns "" when no system default registry is configured.
+func BuildRKE2RegistriesConfig(cfg *RKE2Config) string {
+	if cfg.SystemDefaultRegistry == "" {
+		return ""
+	}
+
+	var builder strings.Builder
+
+	builder.WriteString("mirrors:\n")
+	for _, upstream := range registryMirrorUpstreams {
+		builder.WriteString(fmt.Sprintf("  %s:\n", upstream))
+		builder.WriteString("    endpoint:\n")
+		builder.WriteString(fmt.Sprintf("      - \"https://%s\"\n", cfg.SystemDefaultRegistry))
+	}
+
+	return builder.String()
+}
+
+// writeRegistriesCommand returns a command writing the registries.yaml of the
+// system default registry into dir, or "" without one
+func writeRegistriesCommand(cfg *RKE2Config, dir string) string {
+	registries := BuildRKE2RegistriesConfig(cfg)
+	if registries == "" {
+		return ""
+	}
+	return fmt.Sprintf("mkdir -p %s && printf '%s' > %s/registries.yaml", dir, strings.ReplaceAll(registries, "\n", `\n`), dir)
+}
+
+// ValidateRKE2Registry checks the system default registry and air-gap settings
+func ValidateRKE2Registry(cfg *RKE2Config) error {
+	if cfg == nil {
+		return nil
+	}
+
+	if cfg.SystemDefaultRegistry != "" {
+		if err := validateRegistryHost(cfg.SystemDefaultRegistry); err != nil {
+			return err
+		}
+	}
+
+	if !cfg.AirGapEnabled() {
+		return nil
+	}
+
+	if cfg.SystemDefaultRegistry == "" {
+		return fmt.Errorf("rke2.systemDefaultRegistry is required when airGap is enabled")
+	}
+	if cfg.AirGap.ArtifactURL == "" {
+		return fmt.Errorf("rke2.airGap.artifactUrl is required when airGap is enabled")
+	}
+	if err := validateMirrorURL("rke2.airGap.artifactUrl", cfg.AirGap.ArtifactURL); err != nil {
+		return err
+	}
+	if cfg.AirGap.InstallScriptURL != "" {
+		if err := validateMirrorURL("rke2.airGap.installScriptUrl", cfg.AirGap.InstallScriptURL); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateRegistryHost checks a registry reference such as
+// registry.example.com:5000 or registry.example.com/mirror
+func validateRegistryHost(registry string) error {
+	if strings.Contains(registry, "://") {
+		return fmt.Errorf("rke2.systemDefaultRegistry must not include a scheme: %s", registry)
+	}
+	if strings.ContainsAny(registry, " \t\n") {
+		return fmt.Errorf("rke2.systemDefaultRegistry must not contain whitespace: %q", registry)
+	}
+
+	host := strings.SplitN(registry, "/", 2)[0]
+	if host == "" {
+		return fmt.Errorf("rke2.systemDefaultRegistry has no host: %s", registry)
+	}
+	if _, err := url.Parse("https://" + host); err != nil {
+		return fmt.Errorf("invalid rke2.systemDefaultRegistry %s: %w", registry, err)
+	}
+
+	return nil
+}
+
+// validateMirrorURL checks that raw is an absolute http(s) URL
+func validateMirrorURL(field, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", field, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%s must be an http or https URL: %s", field, raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%s has no host: %s", field, raw)
+	}
+	return nil
+}
+
 // MergeRKE2Config merges user config with defaults
 func MergeRKE2Config(user *RKE2Config, k8sVersion string) *RKE2Config {
 	defaults := GetRKE2Defaults()
@@ -300,6 +514,9 @@ func MergeRKE2Config(user *RKE2Config, k8sVersion string) *RKE2Config {
 	if len(user.ExtraAgentArgs) > 0 {
 		defaults.ExtraAgentArgs = user.ExtraAgentArgs
 	}
+	if user.AirGap != nil {
+		defaults.AirGap = user.AirGap
+	}
 
 	return defaults
 }
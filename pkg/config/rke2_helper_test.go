@@ -204,6 +204,78 @@ func TestBuildRKE2ServerConfig(t *testing.T) {
 	}
 }
 
+func TestBuildRKE2ServerConfig_NodeLocalDNS(t *testing.T) {
+	k8sConfig := &KubernetesConfig{
+		ClusterDNS:   "10.43.0.10",
+		NodeLocalDNS: &NodeLocalDNSConfig{Enabled: true},
+	}
+
+	result := BuildRKE2ServerConfig(GetRKE2Defaults(), "10.8.0.10", "master-1", true, "", k8sConfig)
+
+	if !strings.Contains(result, "cluster-dns: 10.43.0.10\n") {
+		t.Error("Expected the DNS service IP to stay the cluster DNS")
+	}
+	if !strings.Contains(result, "kubelet-arg:\n  - cluster-dns=169.254.20.10\n") {
+		t.Errorf("Expected kubelet to use the node-local cache, got:\n%s", result)
+	}
+}
+
+func TestBuildRKE2AgentConfig_NodeLocalDNS(t *testing.T) {
+	k8sConfig := &KubernetesConfig{
+		ClusterDNS:   "10.43.0.10",
+		NodeLocalDNS: &NodeLocalDNSConfig{Enabled: true},
+	}
+
+	result := BuildRKE2AgentConfig(GetRKE2Defaults(), "10.8.0.20", "worker-1", "10.8.0.10", k8sConfig)
+	if !strings.Contains(result, "kubelet-arg:\n  - cluster-dns=169.254.20.10\n") {
+		t.Errorf("Expected agent kubelet to use the node-local cache, got:\n%s", result)
+	}
+
+	result = BuildRKE2AgentConfig(GetRKE2Defaults(), "10.8.0.20", "worker-1", "10.8.0.10", &KubernetesConfig{ClusterDNS: "10.43.0.10"})
+	if strings.Contains(result, "kubelet-arg") {
+		t.Errorf("Expected no kubelet-arg without the node-local cache, got:\n%s", result)
+	}
+}
+
+func TestBuildRKE2ServerConfig_SecretsEncryption(t *testing.T) {
+	tests := []struct {
+		name     string
+		rke2     bool
+		k8sFlag  bool
+		wantLine bool
+	}{
+		{"disabled", false, false, false},
+		{"rke2 secretsEncryption", true, false, true},
+		{"kubernetes encryptSecrets", false, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := GetRKE2Defaults()
+			cfg.SecretsEncryption = tt.rke2
+			k8sConfig := &KubernetesConfig{EncryptSecrets: tt.k8sFlag}
+
+			result := BuildRKE2ServerConfig(cfg, "10.8.0.10", "master-1", true, "", k8sConfig)
+
+			if got := strings.Contains(result, "secrets-encryption: true\n"); got != tt.wantLine {
+				t.Errorf("secrets-encryption rendered = %v, want %v\nGot:\n%s", got, tt.wantLine, result)
+			}
+		})
+	}
+}
+
+func TestKubernetesConfig_SecretsEncryptionEnabled(t *testing.T) {
+	if (&KubernetesConfig{}).SecretsEncryptionEnabled() {
+		t.Error("Expected secrets encryption off by default")
+	}
+	if !(&KubernetesConfig{EncryptSecrets: true}).SecretsEncryptionEnabled() {
+		t.Error("Expected encryptSecrets to enable secrets encryption")
+	}
+	if !(&KubernetesConfig{RKE2: &RKE2Config{SecretsEncryption: true}}).SecretsEncryptionEnabled() {
+		t.Error("Expected rke2.secretsEncryption to enable secrets encryption")
+	}
+}
+
 func TestBuildRKE2AgentConfig(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -278,7 +350,7 @@ func TestBuildRKE2AgentConfig(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			config := BuildRKE2AgentConfig(tt.cfg, tt.nodeIP, tt.nodeName, tt.serverIP)
+			config := BuildRKE2AgentConfig(tt.cfg, tt.nodeIP, tt.nodeName, tt.serverIP, &KubernetesConfig{})
 
 			for _, want := range tt.wantContains {
 				if !strings.Contains(config, want) {
@@ -303,7 +375,7 @@ func TestGetRKE2InstallCommand(t *testing.T) {
 			},
 			isServer: true,
 			wantContains: []string{
-				"curl -sfL https://get.rke2.io",
+				"curl -sfL https://raw.githubusercontent.com/rancher/rke2/v1.28.5+rke2r1/install.sh -o /tmp/rke2-install.sh",
 				"INSTALL_RKE2_TYPE=server",
 				"INSTALL_RKE2_VERSION=v1.28.5+rke2r1",
 			},
@@ -315,7 +387,7 @@ func TestGetRKE2InstallCommand(t *testing.T) {
 			},
 			isServer: false,
 			wantContains: []string{
-				"curl -sfL https://get.rke2.io",
+				"curl -sfL https://raw.githubusercontent.com/rancher/rke2/v1.28.5+rke2r1/install.sh -o /tmp/rke2-install.sh",
 				"INSTALL_RKE2_TYPE=agent",
 				"INSTALL_RKE2_VERSION=v1.28.5+rke2r1",
 			},
@@ -327,7 +399,7 @@ func TestGetRKE2InstallCommand(t *testing.T) {
 			},
 			isServer: true,
 			wantContains: []string{
-				"curl -sfL https://get.rke2.io",
+				"curl -sfL https://get.rke2.io -o /tmp/rke2-install.sh",
 				"INSTALL_RKE2_TYPE=server",
 				"INSTALL_RKE2_CHANNEL=stable",
 			},
@@ -337,7 +409,7 @@ func TestGetRKE2InstallCommand(t *testing.T) {
 			cfg:      &RKE2Config{},
 			isServer: true,
 			wantContains: []string{
-				"curl -sfL https://get.rke2.io",
+				"curl -sfL https://get.rke2.io -o /tmp/rke2-install.sh",
 				"INSTALL_RKE2_TYPE=server",
 			},
 		},
@@ -345,7 +417,7 @@ func TestGetRKE2InstallCommand(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cmd := GetRKE2InstallCommand(tt.cfg, tt.isServer)
+			cmd := GetRKE2InstallCommand(tt.cfg, tt.isServer, nil)
 
 			for _, want := range tt.wantContains {
 				if !strings.Contains(cmd, want) {
@@ -483,3 +555,170 @@ func TestMergeRKE2Config(t *testing.T) {
 		})
 	}
 }
+
+func TestGetRKE2InstallCommand_AirGap(t *testing.T) {
+	cfg := &RKE2Config{
+		Version:               "v1.28.5+rke2r1",
+		Channel:               "stable",
+		SystemDefaultRegistry: "registry.internal:5000",
+		AirGap: &AirGapConfig{
+			Enabled:     true,
+			ArtifactURL: "https://mirror.internal/rke2/v1.28.5+rke2r1/",
+		},
+	}
+
+	cmd := GetRKE2InstallCommand(cfg, false, nil)
+
+	wantContains := []string{
+		"curl -sfL https://mirror.internal/rke2/v1.28.5+rke2r1/rke2-images.linux-amd64.tar.zst -o /root/rke2-artifacts/rke2-images.linux-amd64.tar.zst",
+		"curl -sfL https://mirror.internal/rke2/v1.28.5+rke2r1/rke2.linux-amd64.tar.gz -o /root/rke2-artifacts/rke2.linux-amd64.tar.gz",
+		"curl -sfL https://mirror.internal/rke2/v1.28.5+rke2r1/sha256sum-amd64.txt -o /root/rke2-artifacts/sha256sum-amd64.txt",
+		"curl -sfL https://mirror.internal/rke2/v1.28.5+rke2r1/install.sh -o /tmp/rke2-install.sh",
+		"INSTALL_RKE2_ARTIFACT_PATH=/root/rke2-artifacts",
+		"INSTALL_RKE2_TYPE=agent",
+	}
+	for _, want := range wantContains {
+		if !strings.Contains(cmd, want) {
+			t.Errorf("Command should contain '%s'\nGot: %s", want, cmd)
+		}
+	}
+
+	for _, unwanted := range []string{"get.rke2.io", "INSTALL_RKE2_CHANNEL", "INSTALL_RKE2_VERSION"} {
+		if strings.Contains(cmd, unwanted) {
+			t.Errorf("Air-gapped command should not contain '%s'\nGot: %s", unwanted, cmd)
+		}
+	}
+
+	if !strings.HasPrefix(cmd, "mkdir -p /etc/rancher/rke2 && printf 'mirrors:\\n") || !strings.Contains(cmd, "> /etc/rancher/rke2/registries.yaml && ") {
+		t.Errorf("Command should write registries.yaml before installing\nGot: %s", cmd)
+	}
+
+	cfg.AirGap.InstallScriptURL = "https://mirror.internal/scripts/rke2-install.sh"
+	cmd = GetRKE2InstallCommand(cfg, true, nil)
+	if !strings.Contains(cmd, "curl -sfL https://mirror.internal/scripts/rke2-install.sh -o /tmp/rke2-install.sh") {
+		t.Errorf("Expected custom install script URL\nGot: %s", cmd)
+	}
+
+	cfg.AirGap.Enabled = false
+	cmd = GetRKE2InstallCommand(cfg, true, nil)
+	if !strings.Contains(cmd, "https://raw.githubusercontent.com/rancher/rke2/v1.28.5+rke2r1/install.sh") {
+		t.Errorf("Disabled air gap should use the release install script\nGot: %s", cmd)
+	}
+}
+
+func TestBuildRKE2Config_SystemDefaultRegistry(t *testing.T) {
+	cfg := &RKE2Config{ClusterToken: "token", SystemDefaultRegistry: "registry.internal:5000"}
+
+	server := BuildRKE2ServerConfig(cfg, "10.8.0.10", "master-1", true, "", &KubernetesConfig{})
+	if !strings.Contains(server, "system-default-registry: registry.internal:5000\n") {
+		t.Errorf("Server config should set system-default-registry\nGot: %s", server)
+	}
+
+	agent := BuildRKE2AgentConfig(cfg, "10.8.0.20", "worker-1", "10.8.0.10", &KubernetesConfig{})
+	if !strings.Contains(agent, "system-default-registry: registry.internal:5000\n") {
+		t.Errorf("Agent config should set system-default-registry\nGot: %s", agent)
+	}
+}
+
+func TestBuildRKE2RegistriesConfig(t *testing.T) {
+	if got := BuildRKE2RegistriesConfig(&RKE2Config{}); got != "" {
+		t.Errorf("Expected no registries.yaml without a registry, got %q", got)
+	}
+
+	got := BuildRKE2RegistriesConfig(&RKE2Config{SystemDefaultRegistry: "registry.internal:5000"})
+
+	expected := `mirrors:
+  docker.io:
+    endpoint:
+      - "https://registry.internal:5000"
+  registry.k8s.io:
+    endpoint:
+      - "https://registry.internal:5000"
+  quay.io:
+    endpoint:
+      - "https://registry.internal:5000"
+  ghcr.io:
+    endpoint:
+      - "https://registry.internal:5000"
+`
+	if got != expected {
+		t.Errorf("Unexpected registries.yaml\nGot:\n%s\nWant:\n%s", got, expected)
+	}
+}
+
+func TestValidateRKE2Registry(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *RKE2Config
+		wantErr string
+	}{
+		{name: "nil config", cfg: nil},
+		{name: "no registry", cfg: &RKE2Config{}},
+		{name: "registry with port", cfg: &RKE2Config{SystemDefaultRegistry: "registry.internal:5000"}},
+		{name: "registry with path", cfg: &RKE2Config{SystemDefaultRegistry: "registry.internal/mirror"}},
+		{
+			name:    "registry with scheme",
+			cfg:     &RKE2Config{SystemDefaultRegistry: "https://registry.internal"},
+			wantErr: "must not include a scheme",
+		},
+		{
+			name: "air gap complete",
+			cfg: &RKE2Config{
+				SystemDefaultRegistry: "registry.internal:5000",
+				AirGap:                &AirGapConfig{Enabled: true, ArtifactURL: "https://mirror.internal/rke2"},
+			},
+		},
+		{
+			name: "air gap disabled is not checked",
+			cfg:  &RKE2Config{AirGap: &AirGapConfig{Enabled: false}},
+		},
+		{
+			name:    "air gap without registry",
+			cfg:     &RKE2Config{AirGap: &AirGapConfig{Enabled: true, ArtifactURL: "https://mirror.internal/rke2"}},
+			wantErr: "systemDefaultRegistry is required",
+		},
+		{
+			name: "air gap without artifact URL",
+			cfg: &RKE2Config{
+				SystemDefaultRegistry: "registry.internal:5000",
+				AirGap:                &AirGapConfig{Enabled: true},
+			},
+			wantErr: "artifactUrl is required",
+		},
+		{
+			name: "air gap with non-http artifact URL",
+			cfg: &RKE2Config{
+				SystemDefaultRegistry: "registry.internal:5000",
+				AirGap:                &AirGapConfig{Enabled: true, ArtifactURL: "ftp://mirror.internal/rke2"},
+			},
+			wantErr: "must be an http or https URL",
+		},
+		{
+			name: "air gap with invalid install script URL",
+			cfg: &RKE2Config{
+				SystemDefaultRegistry: "registry.internal:5000",
+				AirGap: &AirGapConfig{
+					Enabled:          true,
+					ArtifactURL:      "https://mirror.internal/rke2",
+					InstallScriptURL: "mirror.internal/install.sh",
+				},
+			},
+			wantErr: "installScriptUrl must be an http or https URL",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateRKE2Registry(tt.cfg)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Node roles accepted by role filters
+const (
+	RoleMaster = "master"
+	RoleWorker = "worker"
+)
+
+// NormalizeRole maps role aliases to RoleMaster or RoleWorker.
+// Unknown roles (e.g. etcd) are returned unchanged.
+func NormalizeRole(role string) string {
+	switch role {
+	case "master", "controlplane", "control-plane":
+		return RoleMaster
+	case "worker":
+		return RoleWorker
+	}
+	return role
+}
+
+// HasRole reports whether roles contains role, treating aliases as equal
+func HasRole(roles []string, role string) bool {
+	want := NormalizeRole(role)
+	for _, r := range roles {
+		if NormalizeRole(r) == want {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateRoleFilter checks that role can be used to restrict an operation
+func ValidateRoleFilter(role string) error {
+	switch NormalizeRole(role) {
+	case RoleMaster, RoleWorker:
+		return nil
+	}
+	return fmt.Errorf("invalid role %q (must be %s or %s)", role, RoleMaster, RoleWorker)
+}
+
+// NodeNamesWithRole returns the names of all nodes with the given role,
+// including nodes expanded from pools (see RenderPoolNodeName). Names are
+// sorted so master operations always run in the same, one-at-a-time order.
+func NodeNamesWithRole(cfg *ClusterConfig, role string) ([]string, error) {
+	var names []string
+
+	for _, node := range cfg.Nodes {
+		if HasRole(node.Roles, role) {
+			names = append(names, node.Name)
+		}
+	}
+
+	for poolName, pool := range cfg.NodePools {
+		if !HasRole(pool.Roles, role) {
+			continue
+		}
+		for i := 0; i < pool.Count; i++ {
+			name, err := RenderPoolNodeName(poolName, &pool, i)
+			if err != nil {
+				return nil, err
+			}
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHasRole_Aliases(t *testing.T) {
+	if !HasRole([]string{"controlplane", "etcd"}, RoleMaster) {
+		t.Error("controlplane should count as master")
+	}
+	if !HasRole([]string{"master"}, "control-plane") {
+		t.Error("control-plane filter should match master")
+	}
+	if HasRole([]string{"worker"}, RoleMaster) {
+		t.Error("worker should not count as master")
+	}
+}
+
+func TestNodeNamesWithRole(t *testing.T) {
+	cfg := &ClusterConfig{
+		Nodes: []NodeConfig{
+			{Name: "edge-1", Roles: []string{"worker"}},
+			{Name: "cp-extra", Roles: []string{"controlplane"}},
+		},
+		NodePools: map[string]NodePool{
+			"masters": {Name: "masters", Count: 3, Roles: []string{"master", "etcd"}},
+			"workers": {Name: "workers", Count: 2, Roles: []string{"worker"}},
+		},
+	}
+
+	workers, err := NodeNamesWithRole(cfg, RoleWorker)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := []string{"edge-1", "workers-1", "workers-2"}; !reflect.DeepEqual(workers, want) {
+		t.Errorf("Expected workers %v, got %v", want, workers)
+	}
+
+	masters, err := NodeNamesWithRole(cfg, RoleMaster)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := []string{"cp-extra", "masters-1", "masters-2", "masters-3"}; !reflect.DeepEqual(masters, want) {
+		t.Errorf("Expected masters %v, got %v", want, masters)
+	}
+}
+
+func TestValidateRoleFilter(t *testing.T) {
+	for _, role := range []string{"master", "controlplane", "worker"} {
+		if err := ValidateRoleFilter(role); err != nil {
+			t.Errorf("%s: unexpected error: %v", role, err)
+		}
+	}
+	if err := ValidateRoleFilter("etcd"); err == nil {
+		t.Error("etcd should not be a valid role filter")
+	}
+}
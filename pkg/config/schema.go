@@ -0,0 +1,110 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+)
+
+// JSONSchemaDraft is the JSON Schema version GenerateJSONSchema emits
+const JSONSchemaDraft = "http://json-schema.org/draft-07/schema#"
+
+// SupportedProviders lists the cloud providers a node can be created on
+var SupportedProviders = []string{"digitalocean", "linode", "aws", "gcp", "azure", "vultr"}
+
+// schemaEnums constrains fields with a known set of values, keyed by
+// <struct>.<field>
+var schemaEnums = map[string][]string{
+	"ClusterSpec.Type":    {"rke", "rke2", "k3s", "eks", "gke", "aks"},
+	"NetworkConfig.Mode":  {NetworkModeVPC, NetworkModeWireGuard, NetworkModeTailscale, NetworkModeHybrid},
+	"NodeConfig.Provider": SupportedProviders,
+	"NodePool.Provider":   SupportedProviders,
+}
+
+// GenerateJSONSchema returns a draft-07 JSON Schema for ClusterConfig files,
+// built by reflecting over the config types and their yaml tags. Unknown
+// keys are allowed, as the YAML loader ignores them.
+func GenerateJSONSchema() ([]byte, error) {
+	definitions := make(map[string]interface{})
+	root := schemaForStruct(reflect.TypeOf(ClusterConfig{}), definitions)
+	root["$schema"] = JSONSchemaDraft
+	root["title"] = "sloth-kubernetes ClusterConfig"
+	root["definitions"] = definitions
+	return json.MarshalIndent(root, "", "  ")
+}
+
+// schemaFor returns the schema of a value of type t. Structs are added to
+// definitions once and referenced from there.
+func schemaFor(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		name := t.Name()
+		if _, ok := definitions[name]; !ok {
+			// Reserve the name first, for types that refer to themselves
+			definitions[name] = nil
+			definitions[name] = schemaForStruct(t, definitions)
+		}
+		return map[string]interface{}{"$ref": "#/definitions/" + name}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": schemaFor(t.Elem(), definitions)}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": schemaFor(t.Elem(), definitions)}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	// interface{} and anything else accept any value
+	return map[string]interface{}{}
+}
+
+// schemaForStruct returns the object schema of struct type t
+func schemaForStruct(t reflect.Type, definitions map[string]interface{}) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := schemaFieldName(field)
+		if name == "" {
+			continue
+		}
+
+		property := schemaFor(field.Type, definitions)
+		if values, ok := schemaEnums[t.Name()+"."+field.Name]; ok {
+			property = map[string]interface{}{"type": "string", "enum": values}
+		}
+		properties[name] = property
+	}
+	return map[string]interface{}{"type": "object", "properties": properties}
+}
+
+// schemaFieldName returns the key of a field in config files: its yaml tag,
+// else its json tag, else its Go name. Fields tagged "-" have none.
+func schemaFieldName(field reflect.StructField) string {
+	for _, key := range []string{"yaml", "json"} {
+		tag, ok := field.Tag.Lookup(key)
+		if !ok {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "-" {
+			return ""
+		}
+		if name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"gopkg.in/yaml.v3"
+)
+
+func compileTestSchema(t *testing.T) *jsonschema.Schema {
+	t.Helper()
+	data, err := GenerateJSONSchema()
+	if err != nil {
+		t.Fatalf("GenerateJSONSchema failed: %v", err)
+	}
+
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	if err := compiler.AddResource("cluster.schema.json", bytes.NewReader(data)); err != nil {
+		t.Fatalf("Failed to load schema: %v", err)
+	}
+	schema, err := compiler.Compile("cluster.schema.json")
+	if err != nil {
+		t.Fatalf("Generated schema does not compile: %v", err)
+	}
+	return schema
+}
+
+// yamlToJSONValue decodes YAML into the values a JSON decoder would produce
+func yamlToJSONValue(t *testing.T, data []byte) interface{} {
+	t.Helper()
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Failed to parse YAML: %v", err)
+	}
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		t.Fatalf("Failed to convert YAML: %v", err)
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		t.Fatalf("Failed to convert YAML: %v", err)
+	}
+	return value
+}
+
+func TestGenerateJSONSchema_Structure(t *testing.T) {
+	data, err := GenerateJSONSchema()
+	if err != nil {
+		t.Fatalf("GenerateJSONSchema failed: %v", err)
+	}
+
+	var schema struct {
+		Schema      string                            `json:"$schema"`
+		Properties  map[string]map[string]interface{} `json:"properties"`
+		Definitions map[string]struct {
+			Properties map[string]map[string]interface{} `json:"properties"`
+		} `json:"definitions"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("Schema is not valid JSON: %v", err)
+	}
+
+	if schema.Schema != JSONSchemaDraft {
+		t.Errorf("Expected $schema %s, got %s", JSONSchemaDraft, schema.Schema)
+	}
+	if schema.Properties["nodePools"]["type"] != "object" {
+		t.Errorf("Expected nodePools to be a map, got %v", schema.Properties["nodePools"])
+	}
+	if _, ok := schema.Definitions["NodePool"].Properties["count"]; !ok {
+		t.Error("Expected NodePool.count in the definitions")
+	}
+	if _, ok := schema.Definitions["DigitalOceanProvider"].Properties["sshKeys"]; !ok {
+		t.Error("Expected fields to be named after their yaml tags")
+	}
+	if _, ok := schema.Definitions["DigitalOceanProvider"].Properties["SSHPublicKey"]; ok {
+		t.Error("Expected fields tagged - to be left out")
+	}
+
+	mode := schema.Definitions["NetworkConfig"].Properties["mode"]["enum"]
+	if values, ok := mode.([]interface{}); !ok || len(values) != 4 {
+		t.Errorf("Expected the network mode enum, got %v", mode)
+	}
+}
+
+func TestGenerateJSONSchema_RejectsInvalidValues(t *testing.T) {
+	schema := compileTestSchema(t)
+
+	for name, doc := range map[string]string{
+		"network mode": "network:\n  mode: carrier-pigeon\n",
+		"cluster type": "cluster:\n  type: swarm\n",
+		"provider":     "nodePools:\n  workers:\n    provider: mainframe\n",
+		"count":        "nodePools:\n  workers:\n    count: three\n",
+	} {
+		if err := schema.Validate(yamlToJSONValue(t, []byte(doc))); err == nil {
+			t.Errorf("Expected an invalid %s to be rejected", name)
+		}
+	}
+}
+
+// TestGenerateJSONSchema_ValidatesExamples checks the schema against every
+// example written in the ClusterConfig layout, i.e. without a spec section
+func TestGenerateJSONSchema_ValidatesExamples(t *testing.T) {
+	schema := compileTestSchema(t)
+
+	files, err := filepath.Glob(filepath.Join("..", "..", "examples", "*.yaml"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("No examples found: %v", err)
+	}
+
+	validated := 0
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %v", file, err)
+		}
+		value := yamlToJSONValue(t, data)
+		if doc, ok := value.(map[string]interface{}); !ok || doc["spec"] != nil {
+			continue
+		}
+
+		if err := schema.Validate(value); err != nil {
+			t.Errorf("%s does not validate: %v", filepath.Base(file), strings.TrimSpace(err.Error()))
+		}
+		validated++
+	}
+	if validated == 0 {
+		t.Error("Expected at least one example in the ClusterConfig layout")
+	}
+}
@@ -0,0 +1,8 @@
+package config
+
+// SecretsEncryptionEnabled reports whether Secrets are encrypted at rest in
+// etcd. Either kubernetes.encryptSecrets or kubernetes.rke2.secretsEncryption
+// turns it on.
+func (k *KubernetesConfig) SecretsEncryptionEnabled() bool {
+	return k.EncryptSecrets || (k.RKE2 != nil && k.RKE2.SecretsEncryption)
+}
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	// Sysctl keys are dotted lowercase paths (net.ipv4.ip_forward); segments after
+	// the first may contain interface names such as eth0 or cali-abc
+	sysctlKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*(\.[A-Za-z0-9_-]+)+$`)
+	// Kernel module names as accepted by modprobe
+	kernelModulePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// DefaultSysctls are the kernel parameters every RKE2/K3s node needs
+var DefaultSysctls = map[string]string{
+	"net.ipv4.ip_forward":                 "1",
+	"net.bridge.bridge-nf-call-iptables":  "1",
+	"net.bridge.bridge-nf-call-ip6tables": "1",
+	"fs.inotify.max_user_instances":       "8192",
+	"fs.inotify.max_user_watches":         "524288",
+}
+
+// DefaultKernelModules are the kernel modules every RKE2/K3s node needs
+var DefaultKernelModules = []string{
+	"br_netfilter",
+	"overlay",
+	"ip_vs",
+	"ip_vs_rr",
+	"ip_vs_wrr",
+	"ip_vs_sh",
+	"nf_conntrack",
+}
+
+// SysctlEntry is a single kernel parameter
+type SysctlEntry struct {
+	Key   string
+	Value string
+}
+
+// EffectiveSystemTuning merges the defaults with the user configuration.
+// User sysctls override defaults; sysctls are sorted by key and modules are
+// deduplicated keeping defaults first, so the rendered result is stable.
+func EffectiveSystemTuning(tuning *SystemTuningConfig) ([]SysctlEntry, []string) {
+	merged := make(map[string]string, len(DefaultSysctls))
+	for key, value := range DefaultSysctls {
+		merged[key] = value
+	}
+
+	modules := append([]string(nil), DefaultKernelModules...)
+
+	if tuning != nil {
+		for key, value := range tuning.Sysctls {
+			merged[key] = value
+		}
+		modules = append(modules, tuning.Modules...)
+	}
+
+	keys := make([]string, 0, len(merged))
+	for key := range merged {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sysctls := make([]SysctlEntry, 0, len(keys))
+	for _, key := range keys {
+		sysctls = append(sysctls, SysctlEntry{Key: key, Value: merged[key]})
+	}
+
+	seen := make(map[string]bool, len(modules))
+	unique := make([]string, 0, len(modules))
+	for _, module := range modules {
+		if seen[module] {
+			continue
+		}
+		seen[module] = true
+		unique = append(unique, module)
+	}
+
+	return sysctls, unique
+}
+
+// ValidateSysctlKey checks that key is a well-formed sysctl name
+func ValidateSysctlKey(key string) error {
+	if !sysctlKeyPattern.MatchString(key) {
+		return fmt.Errorf("invalid sysctl key %q (expected dotted form such as net.ipv4.ip_forward)", key)
+	}
+	return nil
+}
+
+// ValidateSystemTuning checks sysctl keys, values and module names
+func ValidateSystemTuning(tuning *SystemTuningConfig) error {
+	if tuning == nil {
+		return nil
+	}
+
+	errors := []string{}
+
+	keys := make([]string, 0, len(tuning.Sysctls))
+	for key := range tuning.Sysctls {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if err := ValidateSysctlKey(key); err != nil {
+			errors = append(errors, err.Error())
+			continue
+		}
+		value := tuning.Sysctls[key]
+		if strings.TrimSpace(value) == "" {
+			errors = append(errors, fmt.Sprintf("sysctl %s has an empty value", key))
+		} else if strings.ContainsAny(value, "\n\r") {
+			errors = append(errors, fmt.Sprintf("sysctl %s value must be a single line", key))
+		}
+	}
+
+	for _, module := range tuning.Modules {
+		if !kernelModulePattern.MatchString(module) {
+			errors = append(errors, fmt.Sprintf("invalid kernel module name %q", module))
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEffectiveSystemTuning_Defaults(t *testing.T) {
+	sysctls, modules := EffectiveSystemTuning(nil)
+
+	if len(sysctls) != len(DefaultSysctls) {
+		t.Fatalf("Expected %d sysctls, got %d", len(DefaultSysctls), len(sysctls))
+	}
+	for i := 1; i < len(sysctls); i++ {
+		if sysctls[i-1].Key > sysctls[i].Key {
+			t.Errorf("Sysctls not sorted: %s before %s", sysctls[i-1].Key, sysctls[i].Key)
+		}
+	}
+	if len(modules) != len(DefaultKernelModules) {
+		t.Errorf("Expected %d modules, got %d", len(DefaultKernelModules), len(modules))
+	}
+}
+
+func TestEffectiveSystemTuning_UserOverrides(t *testing.T) {
+	sysctls, modules := EffectiveSystemTuning(&SystemTuningConfig{
+		Sysctls: map[string]string{
+			"fs.inotify.max_user_watches": "1048576",
+			"vm.max_map_count":            "262144",
+		},
+		Modules: []string{"overlay", "nvme_tcp"},
+	})
+
+	values := map[string]string{}
+	for _, entry := range sysctls {
+		values[entry.Key] = entry.Value
+	}
+	if values["fs.inotify.max_user_watches"] != "1048576" {
+		t.Errorf("Expected user value to override default, got %s", values["fs.inotify.max_user_watches"])
+	}
+	if values["vm.max_map_count"] != "262144" {
+		t.Errorf("Expected user sysctl to be added, got %q", values["vm.max_map_count"])
+	}
+	if values["net.ipv4.ip_forward"] != "1" {
+		t.Errorf("Expected default sysctl to be kept, got %q", values["net.ipv4.ip_forward"])
+	}
+
+	if len(modules) != len(DefaultKernelModules)+1 {
+		t.Errorf("Expected duplicate modules to be dropped, got %v", modules)
+	}
+	if modules[len(modules)-1] != "nvme_tcp" {
+		t.Errorf("Expected user module last, got %v", modules)
+	}
+}
+
+func TestValidateSysctlKey(t *testing.T) {
+	tests := []struct {
+		key     string
+		wantErr bool
+	}{
+		{"net.ipv4.ip_forward", false},
+		{"net.bridge.bridge-nf-call-iptables", false},
+		{"net.ipv4.conf.eth0.rp_filter", false},
+		{"vm.max_map_count", false},
+		{"", true},
+		{"ip_forward", true},
+		{"net..ipv4", true},
+		{"net.ipv4.ip_forward ", true},
+		{"net/ipv4/ip_forward", true},
+		{"net.ipv4.ip_forward=1", true},
+		{"Net.ipv4", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateSysctlKey(tt.key)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ValidateSysctlKey(%q) error = %v, wantErr %v", tt.key, err, tt.wantErr)
+		}
+	}
+}
+
+func TestValidateSystemTuning(t *testing.T) {
+	if err := ValidateSystemTuning(nil); err != nil {
+		t.Errorf("Expected nil tuning to be valid, got %v", err)
+	}
+
+	valid := &SystemTuningConfig{
+		Sysctls: map[string]string{"vm.max_map_count": "262144"},
+		Modules: []string{"nf_conntrack"},
+	}
+	if err := ValidateSystemTuning(valid); err != nil {
+		t.Errorf("Expected valid tuning, got %v", err)
+	}
+
+	invalid := &SystemTuningConfig{
+		Sysctls: map[string]string{
+			"bad key":          "1",
+			"vm.swappiness":    "",
+			"kernel.pid_max":   "1\nrm -rf /",
+			"vm.max_map_count": "262144",
+		},
+		Modules: []string{"ip_vs; reboot"},
+	}
+	err := ValidateSystemTuning(invalid)
+	if err == nil {
+		t.Fatal("Expected error for invalid tuning")
+	}
+	for _, want := range []string{"bad key", "vm.swappiness", "kernel.pid_max", "ip_vs; reboot"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected error to mention %q, got %v", want, err)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// tlsSecretRefPrefix marks a CustomCert that references an existing secret
+const tlsSecretRefPrefix = "secret:"
+
+// HasCustomCert reports whether ingress uses a user-supplied certificate
+// instead of cert-manager
+func (t *TLSConfig) HasCustomCert() bool {
+	return t != nil && t.CustomCert != ""
+}
+
+// CustomCertSecret returns the name of the existing TLS secret referenced by
+// CustomCert ("secret:<name>"), if any
+func (t *TLSConfig) CustomCertSecret() (string, bool) {
+	if t == nil || !strings.HasPrefix(t.CustomCert, tlsSecretRefPrefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(t.CustomCert, tlsSecretRefPrefix), true
+}
+
+// LoadCustomCert reads the PEM certificate and key files of a custom
+// certificate and checks that they form a valid pair
+func (t *TLSConfig) LoadCustomCert() ([]byte, []byte, error) {
+	certPEM, err := readPEMFile(t.CustomCert)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read tls.customCert: %w", err)
+	}
+	keyPEM, err := readPEMFile(t.CustomKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read tls.customKey: %w", err)
+	}
+
+	if _, err := ParseCertKeyPair(certPEM, keyPEM); err != nil {
+		return nil, nil, err
+	}
+	return certPEM, keyPEM, nil
+}
+
+// readPEMFile reads a file, expanding a leading ~ to the home directory
+func readPEMFile(path string) ([]byte, error) {
+	if len(path) > 0 && path[0] == '~' {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		path = filepath.Join(home, path[1:])
+	}
+	return os.ReadFile(path)
+}
+
+// ParseCertKeyPair checks that certPEM and keyPEM match and returns the leaf
+// certificate
+func ParseCertKeyPair(certPEM, keyPEM []byte) (*x509.Certificate, error) {
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("custom certificate and key do not form a valid pair: %w", err)
+	}
+
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse custom certificate: %w", err)
+	}
+	return leaf, nil
+}
+
+// CertCoversDomains checks that cert is currently valid and that its names
+// cover every domain (wildcard certificates cover one label)
+func CertCoversDomains(cert *x509.Certificate, domains []string) error {
+	now := time.Now()
+	if now.After(cert.NotAfter) {
+		return fmt.Errorf("custom certificate expired on %s", cert.NotAfter.Format("2006-01-02"))
+	}
+	if now.Before(cert.NotBefore) {
+		return fmt.Errorf("custom certificate is not valid before %s", cert.NotBefore.Format("2006-01-02"))
+	}
+
+	var uncovered []string
+	for _, domain := range domains {
+		if err := cert.VerifyHostname(domain); err != nil {
+			uncovered = append(uncovered, domain)
+		}
+	}
+	if len(uncovered) > 0 {
+		return fmt.Errorf("custom certificate does not cover: %s (names: %s)",
+			strings.Join(uncovered, ", "), strings.Join(cert.DNSNames, ", "))
+	}
+	return nil
+}
+
+// ValidateCustomCert checks a custom ingress certificate: the cert and key
+// files must match and cover tls.domains. Secret references are checked for a
+// name only, since their contents live in the cluster.
+func ValidateCustomCert(t *TLSConfig) error {
+	if !t.HasCustomCert() {
+		if t != nil && t.CustomKey != "" {
+			return fmt.Errorf("tls.customKey requires tls.customCert")
+		}
+		return nil
+	}
+
+	if name, ok := t.CustomCertSecret(); ok {
+		if name == "" {
+			return fmt.Errorf("tls.customCert secret reference requires a name (secret:<name>)")
+		}
+		return nil
+	}
+
+	if t.CustomKey == "" {
+		return fmt.Errorf("tls.customKey is required with a tls.customCert file")
+	}
+
+	certPEM, keyPEM, err := t.LoadCustomCert()
+	if err != nil {
+		return err
+	}
+	cert, err := ParseCertKeyPair(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+	return CertCoversDomains(cert, t.Domains)
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestCert returns a self-signed PEM certificate for names and its key
+func newTestCert(t *testing.T, names []string, notBefore, notAfter time.Time) ([]byte, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeTestCert(t *testing.T, certPEM, keyPEM []byte) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "tls.crt")
+	keyPath := filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certPath, certPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certPath, keyPath
+}
+
+func TestParseCertKeyPair(t *testing.T) {
+	now := time.Now()
+	certPEM, keyPEM := newTestCert(t, []string{"example.com"}, now.Add(-time.Hour), now.Add(24*time.Hour))
+	_, otherKeyPEM := newTestCert(t, []string{"example.com"}, now.Add(-time.Hour), now.Add(24*time.Hour))
+
+	cert, err := ParseCertKeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("Expected matching pair, got %v", err)
+	}
+	if cert.Subject.CommonName != "example.com" {
+		t.Errorf("Expected leaf certificate, got %s", cert.Subject.CommonName)
+	}
+
+	if _, err := ParseCertKeyPair(certPEM, otherKeyPEM); err == nil || !strings.Contains(err.Error(), "do not form a valid pair") {
+		t.Errorf("Expected key mismatch error, got %v", err)
+	}
+	if _, err := ParseCertKeyPair([]byte("not a cert"), keyPEM); err == nil {
+		t.Error("Expected error for invalid PEM")
+	}
+}
+
+func TestCertCoversDomains(t *testing.T) {
+	now := time.Now()
+	certPEM, keyPEM := newTestCert(t, []string{"*.apps.example.com", "example.com"}, now.Add(-time.Hour), now.Add(24*time.Hour))
+	cert, err := ParseCertKeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CertCoversDomains(cert, []string{"example.com", "shop.apps.example.com"}); err != nil {
+		t.Errorf("Expected domains to be covered, got %v", err)
+	}
+
+	err = CertCoversDomains(cert, []string{"example.com", "api.example.com", "a.b.apps.example.com"})
+	if err == nil || !strings.Contains(err.Error(), "does not cover: api.example.com, a.b.apps.example.com") {
+		t.Errorf("Expected uncovered domains, got %v", err)
+	}
+
+	expiredPEM, expiredKey := newTestCert(t, []string{"example.com"}, now.Add(-48*time.Hour), now.Add(-24*time.Hour))
+	expired, err := ParseCertKeyPair(expiredPEM, expiredKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CertCoversDomains(expired, []string{"example.com"}); err == nil || !strings.Contains(err.Error(), "expired") {
+		t.Errorf("Expected expired error, got %v", err)
+	}
+}
+
+func TestTLSConfig_CustomCertSecret(t *testing.T) {
+	var nilTLS *TLSConfig
+	if nilTLS.HasCustomCert() {
+		t.Error("nil TLS config should not have a custom cert")
+	}
+
+	tls := &TLSConfig{CustomCert: "secret:corp-wildcard"}
+	name, ok := tls.CustomCertSecret()
+	if !tls.HasCustomCert() || !ok || name != "corp-wildcard" {
+		t.Errorf("Expected secret reference corp-wildcard, got %q (%v)", name, ok)
+	}
+
+	if _, ok := (&TLSConfig{CustomCert: "/etc/certs/tls.crt"}).CustomCertSecret(); ok {
+		t.Error("File path should not be a secret reference")
+	}
+}
+
+func TestValidateCustomCert(t *testing.T) {
+	now := time.Now()
+	certPEM, keyPEM := newTestCert(t, []string{"*.example.com"}, now.Add(-time.Hour), now.Add(24*time.Hour))
+	certPath, keyPath := writeTestCert(t, certPEM, keyPEM)
+	_, otherKeyPEM := newTestCert(t, []string{"*.example.com"}, now.Add(-time.Hour), now.Add(24*time.Hour))
+	_, otherKeyPath := writeTestCert(t, certPEM, otherKeyPEM)
+
+	tests := []struct {
+		name    string
+		tls     *TLSConfig
+		wantErr string
+	}{
+		{"nil", nil, ""},
+		{"no custom cert", &TLSConfig{CertManager: true}, ""},
+		{"valid files", &TLSConfig{CustomCert: certPath, CustomKey: keyPath, Domains: []string{"app.example.com"}}, ""},
+		{"secret reference", &TLSConfig{CustomCert: "secret:corp-tls"}, ""},
+		{"empty secret reference", &TLSConfig{CustomCert: "secret:"}, "requires a name"},
+		{"key without cert", &TLSConfig{CustomKey: keyPath}, "requires tls.customCert"},
+		{"cert without key", &TLSConfig{CustomCert: certPath}, "tls.customKey is required"},
+		{"missing file", &TLSConfig{CustomCert: "/nonexistent/tls.crt", CustomKey: keyPath}, "failed to read tls.customCert"},
+		{"mismatched key", &TLSConfig{CustomCert: certPath, CustomKey: otherKeyPath}, "do not form a valid pair"},
+		{"uncovered domain", &TLSConfig{CustomCert: certPath, CustomKey: keyPath, Domains: []string{"example.org"}}, "does not cover: example.org"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateCustomCert(tt.tls)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -68,6 +68,7 @@ type ProvidersConfig struct {
 	AWS          *AWSProvider          `yaml:"aws,omitempty" json:"aws,omitempty"`
 	Azure        *AzureProvider        `yaml:"azure,omitempty" json:"azure,omitempty"`
 	GCP          *GCPProvider          `yaml:"gcp,omitempty" json:"gcp,omitempty"`
+	Vultr        *VultrProvider        `yaml:"vultr,omitempty" json:"vultr,omitempty"`
 }
 
 // DigitalOceanProvider configuration
@@ -103,6 +104,21 @@ type LinodeProvider struct {
 	Custom         map[string]interface{} `yaml:"custom" json:"custom"`
 }
 
+// VultrProvider configuration
+type VultrProvider struct {
+	Enabled      bool                   `yaml:"enabled" json:"enabled"`
+	APIKey       string                 `yaml:"apiKey" json:"apiKey"`
+	Region       string                 `yaml:"region" json:"region"`
+	Plan         string                 `yaml:"plan" json:"plan"`       // Default plan of nodes without a size, e.g. vc2-2c-4gb
+	OSID         int                    `yaml:"osId" json:"osId"`       // Default OS of nodes without a numeric image (default: Ubuntu 22.04)
+	SSHKeys      []string               `yaml:"sshKeys" json:"sshKeys"` // IDs of existing Vultr SSH keys
+	SSHPublicKey interface{}            `yaml:"-" json:"-"`             // Set programmatically
+	Tags         []string               `yaml:"tags" json:"tags"`
+	IPv6         bool                   `yaml:"ipv6" json:"ipv6"`
+	VPC          *VPCConfig             `yaml:"vpc,omitempty" json:"vpc,omitempty"`
+	Custom       map[string]interface{} `yaml:"custom" json:"custom"`
+}
+
 // AWSProvider configuration
 type AWSProvider struct {
 	Enabled         bool                   `yaml:"enabled" json:"enabled"`
@@ -148,6 +164,8 @@ type NetworkConfig struct {
 	CIDR                    string                 `yaml:"cidr" json:"cidr"`
 	PodCIDR                 string                 `yaml:"podCidr" json:"podCidr"`
 	ServiceCIDR             string                 `yaml:"serviceCidr" json:"serviceCidr"`
+	PodCIDRv6               string                 `yaml:"podCidrV6,omitempty" json:"podCidrV6,omitempty"`         // IPv6 pod range for dual-stack
+	ServiceCIDRv6           string                 `yaml:"serviceCidrV6,omitempty" json:"serviceCidrV6,omitempty"` // IPv6 service range for dual-stack
 	Subnets                 []SubnetConfig         `yaml:"subnets" json:"subnets"`
 	DNS                     DNSConfig              `yaml:"dns" json:"dns"`
 	DNSServers              []string               `yaml:"dnsServers" json:"dnsServers"`
@@ -174,20 +192,22 @@ type WireGuardConfig struct {
 	ServerIPAddress string `yaml:"serverIpAddress" json:"serverIpAddress"` // Server public IP (auto-set if creating)
 
 	// Connection settings (used if Create=false, or auto-generated if Create=true)
-	Enabled             bool            `yaml:"enabled" json:"enabled"`
-	ServerEndpoint      string          `yaml:"serverEndpoint" json:"serverEndpoint"`
-	ServerPublicKey     string          `yaml:"serverPublicKey" json:"serverPublicKey"`
-	ServerPrivateKey    string          `yaml:"serverPrivateKey" json:"serverPrivateKey"` // Only if creating
-	ClientIPBase        string          `yaml:"clientIpBase" json:"clientIpBase"`
-	Port                int             `yaml:"port" json:"port"`
-	AllowedIPs          []string        `yaml:"allowedIps" json:"allowedIps"`
-	DNS                 []string        `yaml:"dns" json:"dns"`
-	MTU                 int             `yaml:"mtu" json:"mtu"`
-	PersistentKeepalive int             `yaml:"persistentKeepalive" json:"persistentKeepalive"`
-	Peers               []WireGuardPeer `yaml:"peers" json:"peers"`
-	AutoConfig          bool            `yaml:"autoConfig" json:"autoConfig"`
-	MeshNetworking      bool            `yaml:"meshNetworking" json:"meshNetworking"`
-	SSHPrivateKeyPath   string          `yaml:"sshPrivateKeyPath" json:"sshPrivateKeyPath"`
+	Enabled              bool            `yaml:"enabled" json:"enabled"`
+	ServerEndpoint       string          `yaml:"serverEndpoint" json:"serverEndpoint"`
+	ServerPublicKey      string          `yaml:"serverPublicKey" json:"serverPublicKey"`
+	ServerPrivateKey     string          `yaml:"serverPrivateKey" json:"serverPrivateKey"` // Only if creating
+	ClientIPBase         string          `yaml:"clientIpBase" json:"clientIpBase"`
+	Port                 int             `yaml:"port" json:"port"`
+	AllowedIPs           []string        `yaml:"allowedIps" json:"allowedIps"`
+	DNS                  []string        `yaml:"dns" json:"dns"`
+	MTU                  int             `yaml:"mtu" json:"mtu"`
+	PersistentKeepalive  int             `yaml:"persistentKeepalive" json:"persistentKeepalive"`
+	Peers                []WireGuardPeer `yaml:"peers" json:"peers"`
+	AutoConfig           bool            `yaml:"autoConfig" json:"autoConfig"`
+	MeshNetworking       bool            `yaml:"meshNetworking" json:"meshNetworking"`
+	SSHPrivateKeyPath    string          `yaml:"sshPrivateKeyPath" json:"sshPrivateKeyPath"`
+	MeshCheckConcurrency int             `yaml:"meshCheckConcurrency" json:"meshCheckConcurrency"` // Mesh links tested at once (default: 10)
+	MeshCheckAttempts    int             `yaml:"meshCheckAttempts" json:"meshCheckAttempts"`       // Tries per failing mesh link (default: 5)
 
 	// Network configuration
 	SubnetCIDR string `yaml:"subnetCidr" json:"subnetCidr"` // VPN subnet (e.g., 10.8.0.0/24)
@@ -214,6 +234,12 @@ type SecurityConfig struct {
 	Compliance      ComplianceConfig       `yaml:"compliance" json:"compliance"`
 	Audit           AuditConfig            `yaml:"audit" json:"audit"`
 	Custom          map[string]interface{} `yaml:"custom" json:"custom"`
+
+	// VerifyDownloads checks install scripts against their pinned SHA256
+	// before running them (default: true). DownloadChecksums pins them by
+	// download name, overriding the known checksums.
+	VerifyDownloads   *bool             `yaml:"verifyDownloads,omitempty" json:"verifyDownloads,omitempty"`
+	DownloadChecksums map[string]string `yaml:"downloadChecksums,omitempty" json:"downloadChecksums,omitempty"`
 }
 
 // BastionConfig defines bastion host configuration for secure cluster access
@@ -221,16 +247,73 @@ type BastionConfig struct {
 	Enabled        bool     `yaml:"enabled" json:"enabled"`
 	Provider       string   `yaml:"provider" json:"provider"` // digitalocean, linode, aws, gcp, azure
 	Region         string   `yaml:"region" json:"region"`
+	Zone           string   `yaml:"zone" json:"zone"` // AWS/GCP zone (default: the region's first zone)
 	Size           string   `yaml:"size" json:"size"`
 	Image          string   `yaml:"image" json:"image"`
 	Name           string   `yaml:"name" json:"name"`
 	VPNOnly        bool     `yaml:"vpnOnly" json:"vpnOnly"`               // If true, only VPN users can SSH to bastion
 	AllowedCIDRs   []string `yaml:"allowedCIDRs" json:"allowedCIDRs"`     // CIDRs allowed to SSH to bastion
+	AllowMyIP      bool     `yaml:"allowMyIP" json:"allowMyIP"`           // Add the deployer's public IP to AllowedCIDRs
+	OperatorCIDR   string   `yaml:"-" json:"-"`                           // Deployer's CIDR added by AllowMyIP, set at deploy time
 	SSHPort        int      `yaml:"sshPort" json:"sshPort"`               // Custom SSH port (default: 22)
 	IdleTimeout    int      `yaml:"idleTimeout" json:"idleTimeout"`       // SSH idle timeout in minutes
 	MaxSessions    int      `yaml:"maxSessions" json:"maxSessions"`       // Max concurrent SSH sessions
-	EnableAuditLog bool     `yaml:"enableAuditLog" json:"enableAuditLog"` // Log all SSH sessions
+	EnableAuditLog bool     `yaml:"enableAuditLog" json:"enableAuditLog"` // Log and record all SSH sessions
 	EnableMFA      bool     `yaml:"enableMFA" json:"enableMFA"`           // Require MFA for bastion access
+	Monitoring     bool     `yaml:"monitoring" json:"monitoring"`         // Run the provider's metrics agent
+
+	// AuditLogS3Bucket ships session recordings and audit logs to this
+	// bucket, given as bucket or bucket/prefix
+	AuditLogS3Bucket string `yaml:"auditLogS3Bucket,omitempty" json:"auditLogS3Bucket,omitempty"`
+	// AuditLogS3Endpoint is the endpoint of S3-compatible storage such as
+	// DigitalOcean Spaces or Linode Object Storage (default: AWS S3)
+	AuditLogS3Endpoint string `yaml:"auditLogS3Endpoint,omitempty" json:"auditLogS3Endpoint,omitempty"`
+	AuditLogS3Region   string `yaml:"auditLogS3Region,omitempty" json:"auditLogS3Region,omitempty"` // default: us-east-1
+	// AuditLogS3AccessKey and AuditLogS3SecretKey authenticate the uploads.
+	// When empty, the bastion's instance role is used.
+	AuditLogS3AccessKey string `yaml:"auditLogS3AccessKey,omitempty" json:"auditLogS3AccessKey,omitempty"`
+	AuditLogS3SecretKey string `yaml:"auditLogS3SecretKey,omitempty" json:"auditLogS3SecretKey,omitempty"`
+	// AuditLogRetentionDays removes local session recordings and rotated
+	// audit logs older than this many days (default: keep them)
+	AuditLogRetentionDays int `yaml:"auditLogRetentionDays,omitempty" json:"auditLogRetentionDays,omitempty"`
+
+	// InstallSalt installs the Salt Master and API on the bastion
+	// (default: true)
+	InstallSalt *bool `yaml:"installSalt,omitempty" json:"installSalt,omitempty"`
+
+	// SaltAPIUser is the PAM user the Salt API authenticates (default: saltapi)
+	SaltAPIUser string `yaml:"saltApiUser,omitempty" json:"saltApiUser,omitempty"`
+
+	// SaltAPIPassword is the password of the Salt API user, generated at
+	// deploy time when empty
+	SaltAPIPassword string `yaml:"saltApiPassword,omitempty" json:"saltApiPassword,omitempty"`
+
+	// SaltAPIPasswordFromSecret names the Pulumi secret config key the Salt
+	// API password is read from, instead of the default saltApiPassword. A
+	// password is generated into it when the key is not set.
+	SaltAPIPasswordFromSecret string `yaml:"saltApiPasswordFromSecret,omitempty" json:"saltApiPasswordFromSecret,omitempty"`
+
+	// Upstream names the jump host the bastion is reached through, for
+	// networks where it is not reachable directly. Each jump host may have
+	// an upstream of its own, forming a chain.
+	Upstream  string           `yaml:"upstream" json:"upstream"`
+	JumpHosts []JumpHostConfig `yaml:"jumpHosts" json:"jumpHosts"`
+
+	// SaltBootstrap is the Salt bootstrap download, set at deploy time
+	SaltBootstrap Download `yaml:"-" json:"-"`
+	// WireGuardIP is the VPN address of the bastion, set at deploy time
+	WireGuardIP string `yaml:"-" json:"-"`
+}
+
+// JumpHostConfig is an SSH host on the path to the bastion that is not
+// managed by the cluster. It is reached with the operator's own SSH keys and
+// config, not the cluster key.
+type JumpHostConfig struct {
+	Name     string `yaml:"name" json:"name"`
+	Host     string `yaml:"host" json:"host"`
+	User     string `yaml:"user" json:"user"`         // Default: the ssh default user
+	Port     int    `yaml:"port" json:"port"`         // Default: 22
+	Upstream string `yaml:"upstream" json:"upstream"` // Jump host this one is reached through
 }
 
 // NodeConfig represents individual node configuration
@@ -252,6 +335,9 @@ type NodeConfig struct {
 	SSHKey      string                 `yaml:"sshKey" json:"sshKey"`
 	Monitoring  bool                   `yaml:"monitoring" json:"monitoring"`
 	Custom      map[string]interface{} `yaml:"custom" json:"custom"`
+
+	// SaltBootstrap is the Salt bootstrap download, set at deploy time
+	SaltBootstrap Download `yaml:"-" json:"-"`
 }
 
 // NodePool defines a pool of similar nodes
@@ -272,7 +358,17 @@ type NodePool struct {
 	SpotInstance bool                   `yaml:"spotInstance" json:"spotInstance"`
 	Preemptible  bool                   `yaml:"preemptible" json:"preemptible"`
 	UserData     string                 `yaml:"userData" json:"userData"`
+	Monitoring   bool                   `yaml:"monitoring" json:"monitoring"`                         // Run the provider's metrics agent
+	NameTemplate string                 `yaml:"nameTemplate,omitempty" json:"nameTemplate,omitempty"` // Go template for node names (.Pool, .Index, .Role, .Provider, .Region)
 	Custom       map[string]interface{} `yaml:"custom" json:"custom"`
+
+	// Rolling replacement state, set for the duration of a deploy and never
+	// read from YAML (see PlanSizeReplacement): the size of nodes not yet
+	// replaced, the nodes currently removed, and whether the pool's temporary
+	// surge node exists
+	NodeSizes     map[string]string `yaml:"-" json:"-"`
+	ExcludedNodes map[string]bool   `yaml:"-" json:"-"`
+	Surge         bool              `yaml:"-" json:"-"`
 }
 
 // KubernetesConfig for Kubernetes-specific settings
@@ -296,9 +392,27 @@ type KubernetesConfig struct {
 	AuditLog          bool                   `yaml:"auditLog" json:"auditLog"`
 	EncryptSecrets    bool                   `yaml:"encryptSecrets" json:"encryptSecrets"`
 	Monitoring        bool                   `yaml:"monitoring" json:"monitoring"`
+	SystemTuning      *SystemTuningConfig    `yaml:"systemTuning,omitempty" json:"systemTuning,omitempty"`
+	NodeLocalDNS      *NodeLocalDNSConfig    `yaml:"nodeLocalDNS,omitempty" json:"nodeLocalDNS,omitempty"`
+	ReadyTimeout      string                 `yaml:"readyTimeout,omitempty" json:"readyTimeout,omitempty"`           // Wait for the API and nodes to be Ready (default: 10m)
+	ReadyPollInterval string                 `yaml:"readyPollInterval,omitempty" json:"readyPollInterval,omitempty"` // How often readiness is checked (default: 10s)
 	Custom            map[string]interface{} `yaml:"custom" json:"custom"`
 }
 
+// NodeLocalDNSConfig enables the node-local DNS cache: a DaemonSet answering
+// pod DNS queries on every node, so CoreDNS only sees cache misses
+type NodeLocalDNSConfig struct {
+	Enabled bool   `yaml:"enabled" json:"enabled"`
+	LocalIP string `yaml:"localIP" json:"localIP"` // Link-local address the cache listens on (default: 169.254.20.10)
+	Image   string `yaml:"image" json:"image"`     // node-cache image (default: registry.k8s.io/dns/k8s-dns-node-cache)
+}
+
+// SystemTuningConfig holds kernel parameters and modules applied to every node
+type SystemTuningConfig struct {
+	Sysctls map[string]string `yaml:"sysctls" json:"sysctls"` // Merged over DefaultSysctls
+	Modules []string          `yaml:"modules" json:"modules"` // Loaded in addition to DefaultKernelModules
+}
+
 // RKE2Config specific configuration for RKE2 distribution
 type RKE2Config struct {
 	Version                  string            `yaml:"version" json:"version"`                                   // e.g., "v1.28.5+rke2r1"
@@ -320,6 +434,14 @@ type RKE2Config struct {
 	ProtectKernelDefaults    bool              `yaml:"protectKernelDefaults" json:"protectKernelDefaults"`       // Protect kernel defaults
 	ExtraServerArgs          map[string]string `yaml:"extraServerArgs" json:"extraServerArgs"`                   // Extra arguments for server
 	ExtraAgentArgs           map[string]string `yaml:"extraAgentArgs" json:"extraAgentArgs"`                     // Extra arguments for agent
+	AirGap                   *AirGapConfig     `yaml:"airGap,omitempty" json:"airGap,omitempty"`                 // Install from a mirror instead of get.rke2.io
+}
+
+// AirGapConfig configures installs that cannot reach public endpoints
+type AirGapConfig struct {
+	Enabled          bool   `yaml:"enabled" json:"enabled"`
+	ArtifactURL      string `yaml:"artifactUrl" json:"artifactUrl"`           // Base URL serving the release artifacts: the RKE2 tarballs and checksums, or the k3s binary and image tarball
+	InstallScriptURL string `yaml:"installScriptUrl" json:"installScriptUrl"` // Mirror of the install script (default: <artifactUrl>/install.sh)
 }
 
 // Helper types for various configurations
@@ -379,6 +501,15 @@ type DNSConfig struct {
 	Options     []string `yaml:"options" json:"options"`
 	ExternalDNS bool     `yaml:"externalDns" json:"externalDns"`
 	Provider    string   `yaml:"provider" json:"provider"` // digitalocean, cloudflare, route53, etc
+	// Cloudflare holds the credentials and options of the cloudflare provider
+	Cloudflare *CloudflareDNSConfig `yaml:"cloudflare,omitempty" json:"cloudflare,omitempty"`
+}
+
+// CloudflareDNSConfig configures DNS records managed through the Cloudflare API
+type CloudflareDNSConfig struct {
+	APIToken string `yaml:"apiToken" json:"apiToken"` // Needs Zone:Read and DNS:Edit; falls back to CLOUDFLARE_API_TOKEN
+	ZoneID   string `yaml:"zoneId" json:"zoneId"`     // Looked up by domain when empty
+	Proxied  bool   `yaml:"proxied" json:"proxied"`   // Proxy the ingress records through Cloudflare
 }
 
 type IngressConfig struct {
@@ -395,6 +526,8 @@ type LoadBalancerConfig struct {
 	Provider string                 `yaml:"provider" json:"provider"`
 	Ports    []PortConfig           `yaml:"ports" json:"ports"`
 	Custom   map[string]interface{} `yaml:"custom" json:"custom"`
+	// TargetRole limits the targets to the provider's nodes with this role; all of them when empty
+	TargetRole string `yaml:"targetRole,omitempty" json:"targetRole,omitempty"`
 }
 
 type PortConfig struct {
@@ -442,6 +575,11 @@ type TLSConfig struct {
 	Provider    string   `yaml:"provider" json:"provider"`
 	Email       string   `yaml:"email" json:"email"`
 	Domains     []string `yaml:"domains" json:"domains"`
+	// CustomCert and CustomKey are PEM files of a certificate issued by your
+	// own CA, used for ingress instead of cert-manager. CustomCert may also be
+	// "secret:<name>" to reference an existing kubernetes.io/tls secret.
+	CustomCert string `yaml:"customCert,omitempty" json:"customCert,omitempty"`
+	CustomKey  string `yaml:"customKey,omitempty" json:"customKey,omitempty"`
 }
 
 type RBACConfig struct {
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+)
+
+// Existing reports whether the VPC is an existing one to attach nodes to
+// instead of one to create
+func (v *VPCConfig) Existing() bool {
+	return v != nil && !v.Create && v.ID != ""
+}
+
+// DigitalOceanIPRange returns the IP range of a DigitalOcean VPC: the
+// provider-specific ipRange when set, otherwise CIDR
+func (v *VPCConfig) DigitalOceanIPRange() string {
+	if v.DigitalOcean != nil && v.DigitalOcean.IPRange != "" {
+		return v.DigitalOcean.IPRange
+	}
+	return v.CIDR
+}
+
+// LinodeLabel returns the label of a Linode VPC: the provider-specific label
+// when set, otherwise Name
+func (v *VPCConfig) LinodeLabel() string {
+	if v.Linode != nil && v.Linode.Label != "" {
+		return v.Linode.Label
+	}
+	return v.Name
+}
+
+// LinodeSubnets returns the subnets of a Linode VPC. Without explicit subnets
+// a single subnet spanning CIDR is used. Nodes attach to the first subnet.
+func (v *VPCConfig) LinodeSubnets() []LinodeSubnetConfig {
+	if v.Linode != nil && len(v.Linode.Subnets) > 0 {
+		return v.Linode.Subnets
+	}
+	if v.CIDR == "" {
+		return nil
+	}
+	return []LinodeSubnetConfig{{Label: fmt.Sprintf("%s-subnet", v.LinodeLabel()), IPv4: v.CIDR}}
+}
+
+// ValidateVPC checks a provider VPC configuration: it must either be created
+// or reference an existing VPC, and its address ranges must not overlap the
+// WireGuard subnet
+func ValidateVPC(provider string, vpc *VPCConfig, wireGuardSubnet string) error {
+	if vpc == nil {
+		return nil
+	}
+
+	if vpc.Create && vpc.ID != "" {
+		return fmt.Errorf("%s VPC sets both create and id; use create for a new VPC or id for an existing one", provider)
+	}
+
+	var ranges []string
+	switch provider {
+	case "digitalocean":
+		if vpc.Create && vpc.DigitalOceanIPRange() == "" {
+			return fmt.Errorf("%s VPC requires a cidr when create is true", provider)
+		}
+		ranges = append(ranges, vpc.DigitalOceanIPRange())
+	case "vultr":
+		if vpc.Create && vpc.CIDR == "" {
+			return fmt.Errorf("%s VPC requires a cidr when create is true", provider)
+		}
+		ranges = append(ranges, vpc.CIDR)
+	case "linode":
+		if vpc.Create && len(vpc.LinodeSubnets()) == 0 {
+			return fmt.Errorf("%s VPC requires a cidr or subnets when create is true", provider)
+		}
+		ranges = append(ranges, vpc.CIDR)
+		for _, subnet := range vpc.LinodeSubnets() {
+			ranges = append(ranges, subnet.IPv4)
+		}
+	default:
+		ranges = append(ranges, vpc.CIDR)
+	}
+
+	_, wgNet, err := net.ParseCIDR(wireGuardSubnet)
+	if err != nil {
+		return fmt.Errorf("invalid WireGuard subnet %s: %w", wireGuardSubnet, err)
+	}
+
+	errors := []string{}
+	seen := make(map[string]bool)
+	for _, cidr := range ranges {
+		if cidr == "" || seen[cidr] {
+			continue
+		}
+		seen[cidr] = true
+
+		_, vpcNet, err := net.ParseCIDR(cidr)
+		if err != nil {
+			errors = append(errors, fmt.Sprintf("%s VPC has invalid CIDR %s", provider, cidr))
+			continue
+		}
+		if vpcNet.Contains(wgNet.IP) || wgNet.Contains(vpcNet.IP) {
+			errors = append(errors, fmt.Sprintf("%s VPC CIDR %s overlaps the WireGuard subnet %s", provider, cidr, wireGuardSubnet))
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+
+	return nil
+}
+
+// ProviderNetworkCIDRs returns the private network CIDR of each enabled
+// provider that configures one
+func ProviderNetworkCIDRs(cfg *ClusterConfig) map[string]string {
+	cidrs := make(map[string]string)
+	if do := cfg.Providers.DigitalOcean; do != nil && do.Enabled && do.VPC != nil && do.VPC.DigitalOceanIPRange() != "" {
+		cidrs["digitalocean"] = do.VPC.DigitalOceanIPRange()
+	}
+	if linode := cfg.Providers.Linode; linode != nil && linode.Enabled && linode.VPC != nil {
+		if linode.VPC.CIDR != "" {
+			cidrs["linode"] = linode.VPC.CIDR
+		} else if subnets := linode.VPC.LinodeSubnets(); len(subnets) > 0 {
+			cidrs["linode"] = subnets[0].IPv4
+		}
+	}
+	if vultr := cfg.Providers.Vultr; vultr != nil && vultr.Enabled && vultr.VPC != nil && vultr.VPC.CIDR != "" {
+		cidrs["vultr"] = vultr.VPC.CIDR
+	}
+	if azure := cfg.Providers.Azure; azure != nil && azure.Enabled && azure.VirtualNetwork != nil && azure.VirtualNetwork.CIDR != "" {
+		cidrs["azure"] = azure.VirtualNetwork.CIDR
+	}
+	return cidrs
+}
+
+// ValidateProviderCIDRs checks that no two providers use overlapping private
+// network CIDRs. Nodes reach other providers' private IPs over the WireGuard
+// mesh, which cannot tell which provider an address in both ranges belongs to.
+func ValidateProviderCIDRs(cidrs map[string]string) error {
+	providers := make([]string, 0, len(cidrs))
+	for provider := range cidrs {
+		providers = append(providers, provider)
+	}
+	sort.Strings(providers)
+
+	nets := make(map[string]*net.IPNet, len(cidrs))
+	for _, provider := range providers {
+		_, ipNet, err := net.ParseCIDR(cidrs[provider])
+		if err != nil {
+			return fmt.Errorf("%s network has invalid CIDR %s", provider, cidrs[provider])
+		}
+		nets[provider] = ipNet
+	}
+
+	errors := []string{}
+	for i := 0; i < len(providers); i++ {
+		for j := i + 1; j < len(providers); j++ {
+			a, b := nets[providers[i]], nets[providers[j]]
+			if a.Contains(b.IP) || b.Contains(a.IP) {
+				errors = append(errors, fmt.Sprintf("%s network %s overlaps %s network %s; give each provider a distinct private CIDR",
+					providers[i], cidrs[providers[i]], providers[j], cidrs[providers[j]]))
+			}
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("%s", strings.Join(errors, "; "))
+	}
+
+	return nil
+}
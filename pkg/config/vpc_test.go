@@ -0,0 +1,142 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestVPCConfig_ProviderDefaults(t *testing.T) {
+	vpc := &VPCConfig{Name: "cluster-vpc", CIDR: "10.10.0.0/16"}
+
+	if got := vpc.DigitalOceanIPRange(); got != "10.10.0.0/16" {
+		t.Errorf("Expected DigitalOcean range to default to CIDR, got %s", got)
+	}
+	if got := vpc.LinodeLabel(); got != "cluster-vpc" {
+		t.Errorf("Expected Linode label to default to name, got %s", got)
+	}
+
+	subnets := vpc.LinodeSubnets()
+	if len(subnets) != 1 || subnets[0].Label != "cluster-vpc-subnet" || subnets[0].IPv4 != "10.10.0.0/16" {
+		t.Errorf("Expected one subnet spanning the CIDR, got %+v", subnets)
+	}
+
+	vpc.DigitalOcean = &DOVPCConfig{IPRange: "10.20.0.0/16"}
+	vpc.Linode = &LinodeVPCConfig{Label: "k8s", Subnets: []LinodeSubnetConfig{{Label: "nodes", IPv4: "10.21.0.0/24"}}}
+	if got := vpc.DigitalOceanIPRange(); got != "10.20.0.0/16" {
+		t.Errorf("Expected DigitalOcean ipRange override, got %s", got)
+	}
+	if got := vpc.LinodeLabel(); got != "k8s" {
+		t.Errorf("Expected Linode label override, got %s", got)
+	}
+	if subnets := vpc.LinodeSubnets(); len(subnets) != 1 || subnets[0].Label != "nodes" {
+		t.Errorf("Expected explicit Linode subnets, got %+v", subnets)
+	}
+}
+
+func TestVPCConfig_Existing(t *testing.T) {
+	var nilVPC *VPCConfig
+	if nilVPC.Existing() {
+		t.Error("nil VPC should not be existing")
+	}
+	if (&VPCConfig{Create: true}).Existing() {
+		t.Error("VPC to create should not be existing")
+	}
+	if !(&VPCConfig{ID: "123"}).Existing() {
+		t.Error("VPC with an id should be existing")
+	}
+}
+
+func TestValidateVPC(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider string
+		vpc      *VPCConfig
+		wantErr  string
+	}{
+		{"nil", "digitalocean", nil, ""},
+		{"valid digitalocean", "digitalocean", &VPCConfig{Create: true, CIDR: "10.10.0.0/16"}, ""},
+		{"valid existing", "linode", &VPCConfig{ID: "42"}, ""},
+		{"create and id", "digitalocean", &VPCConfig{Create: true, ID: "abc", CIDR: "10.10.0.0/16"}, "both create and id"},
+		{"missing cidr", "digitalocean", &VPCConfig{Create: true}, "requires a cidr"},
+		{"invalid cidr", "digitalocean", &VPCConfig{Create: true, CIDR: "10.10.0.0"}, "invalid CIDR"},
+		{"overlapping range", "digitalocean", &VPCConfig{Create: true, CIDR: "10.0.0.0/8"}, "overlaps the WireGuard subnet"},
+		{"overlapping ipRange override", "digitalocean", &VPCConfig{Create: true, CIDR: "10.10.0.0/16", DigitalOcean: &DOVPCConfig{IPRange: "10.8.0.0/16"}}, "10.8.0.0/16 overlaps"},
+		{"overlapping linode subnet", "linode", &VPCConfig{Create: true, Linode: &LinodeVPCConfig{Subnets: []LinodeSubnetConfig{{Label: "vpn", IPv4: "10.8.0.128/25"}}}}, "10.8.0.128/25 overlaps"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateVPC(tt.provider, tt.vpc, DefaultWireGuardSubnet)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestProviderNetworkCIDRs(t *testing.T) {
+	cfg := &ClusterConfig{
+		Providers: ProvidersConfig{
+			DigitalOcean: &DigitalOceanProvider{Enabled: true, VPC: &VPCConfig{Create: true, CIDR: "10.10.0.0/16", DigitalOcean: &DOVPCConfig{IPRange: "10.20.0.0/16"}}},
+			Linode:       &LinodeProvider{Enabled: true, VPC: &VPCConfig{Create: true, Linode: &LinodeVPCConfig{Subnets: []LinodeSubnetConfig{{Label: "nodes", IPv4: "10.30.0.0/24"}}}}},
+			Azure:        &AzureProvider{Enabled: false, VirtualNetwork: &AzureVirtualNetwork{CIDR: "10.40.0.0/16"}},
+		},
+	}
+
+	cidrs := ProviderNetworkCIDRs(cfg)
+	if len(cidrs) != 2 {
+		t.Fatalf("Expected 2 provider CIDRs, got %v", cidrs)
+	}
+	if cidrs["digitalocean"] != "10.20.0.0/16" {
+		t.Errorf("Expected the DigitalOcean ipRange, got %q", cidrs["digitalocean"])
+	}
+	if cidrs["linode"] != "10.30.0.0/24" {
+		t.Errorf("Expected the first Linode subnet, got %q", cidrs["linode"])
+	}
+}
+
+func TestValidateProviderCIDRs(t *testing.T) {
+	tests := []struct {
+		name    string
+		cidrs   map[string]string
+		wantErr string
+	}{
+		{"none", nil, ""},
+		{"single provider", map[string]string{"digitalocean": "10.0.0.0/16"}, ""},
+		{"distinct", map[string]string{"digitalocean": "10.10.0.0/16", "linode": "10.20.0.0/16", "azure": "172.16.0.0/16"}, ""},
+		{"same cidr", map[string]string{"digitalocean": "10.0.0.0/16", "linode": "10.0.0.0/16"}, "digitalocean network 10.0.0.0/16 overlaps linode network 10.0.0.0/16"},
+		{"contained", map[string]string{"linode": "10.10.5.0/24", "azure": "10.10.0.0/16"}, "azure network 10.10.0.0/16 overlaps linode network 10.10.5.0/24"},
+		{"invalid", map[string]string{"linode": "10.10.0.0"}, "invalid CIDR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateProviderCIDRs(tt.cidrs)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestWireGuardConfig_Subnet(t *testing.T) {
+	var wg *WireGuardConfig
+	if got := wg.Subnet(); got != DefaultWireGuardSubnet {
+		t.Errorf("Expected default subnet, got %s", got)
+	}
+	if got := (&WireGuardConfig{SubnetCIDR: "10.99.0.0/24"}).Subnet(); got != "10.99.0.0/24" {
+		t.Errorf("Expected configured subnet, got %s", got)
+	}
+}
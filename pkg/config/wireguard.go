@@ -0,0 +1,144 @@
+package config
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// HubMode reports whether nodes join an existing WireGuard server as spokes
+// (Create=false) instead of building a full mesh between themselves
+func (w *WireGuardConfig) HubMode() bool {
+	return w != nil && w.Enabled && !w.Create
+}
+
+// DefaultWireGuardSubnet is the VPN subnet used when SubnetCIDR is not set
+const DefaultWireGuardSubnet = "10.8.0.0/24"
+
+// Subnet returns the VPN subnet, defaulting to DefaultWireGuardSubnet
+func (w *WireGuardConfig) Subnet() string {
+	if w == nil || w.SubnetCIDR == "" {
+		return DefaultWireGuardSubnet
+	}
+	return w.SubnetCIDR
+}
+
+// Host offsets of the VPN addresses within the WireGuard subnet: the bastion
+// is at .5, cluster nodes at .10-.99 and VPN clients from .100 to the last
+// host of the subnet
+const (
+	WireGuardBastionHost     = 5
+	WireGuardFirstNodeHost   = 10
+	WireGuardLastNodeHost    = 99
+	WireGuardFirstClientHost = 100
+)
+
+// WireGuardSubnet is a WireGuard subnet, used to compute the VPN addresses
+// of the bastion, the cluster nodes and the VPN clients within it
+type WireGuardSubnet struct {
+	network *net.IPNet
+}
+
+// ParseWireGuardSubnet parses cidr as a WireGuard subnet. It must be an IPv4
+// subnet large enough to hold the client range, i.e. a /25 or larger.
+func ParseWireGuardSubnet(cidr string) (*WireGuardSubnet, error) {
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid WireGuard subnet %q", cidr)
+	}
+	ones, bits := network.Mask.Size()
+	if bits != 32 {
+		return nil, fmt.Errorf("WireGuard subnet %s must be an IPv4 subnet", cidr)
+	}
+	if ones > 25 {
+		return nil, fmt.Errorf("WireGuard subnet %s is too small, use a /25 or larger to leave room for VPN clients from .%d", cidr, WireGuardFirstClientHost)
+	}
+	return &WireGuardSubnet{network: network}, nil
+}
+
+// ParsedSubnet returns the parsed VPN subnet, falling back to
+// DefaultWireGuardSubnet when SubnetCIDR is invalid (see Diagnose)
+func (w *WireGuardConfig) ParsedSubnet() *WireGuardSubnet {
+	subnet, err := ParseWireGuardSubnet(w.Subnet())
+	if err != nil {
+		subnet, _ = ParseWireGuardSubnet(DefaultWireGuardSubnet)
+	}
+	return subnet
+}
+
+// String returns the subnet in CIDR notation
+func (s *WireGuardSubnet) String() string {
+	return s.network.String()
+}
+
+// PrefixLen returns the prefix length of the subnet, used for the Address
+// of WireGuard interfaces
+func (s *WireGuardSubnet) PrefixLen() int {
+	ones, _ := s.network.Mask.Size()
+	return ones
+}
+
+// LastHost returns the offset of the last usable address of the subnet, the
+// one before its broadcast address
+func (s *WireGuardSubnet) LastHost() int {
+	ones, bits := s.network.Mask.Size()
+	return 1<<(bits-ones) - 2
+}
+
+// HostIP returns the address at offset host within the subnet, e.g. host 10
+// of 10.8.0.0/24 is 10.8.0.10
+func (s *WireGuardSubnet) HostIP(host int) string {
+	base := binary.BigEndian.Uint32(s.network.IP.To4())
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, base+uint32(host))
+	return ip.String()
+}
+
+// HostOffset returns the offset of ip within the subnet, and false when ip
+// is not in the subnet
+func (s *WireGuardSubnet) HostOffset(ip string) (int, bool) {
+	parsed := net.ParseIP(ip).To4()
+	if parsed == nil || !s.network.Contains(parsed) {
+		return 0, false
+	}
+	base := binary.BigEndian.Uint32(s.network.IP.To4())
+	return int(binary.BigEndian.Uint32(parsed) - base), true
+}
+
+// BastionIP returns the VPN address of the bastion
+func (s *WireGuardSubnet) BastionIP() string {
+	return s.HostIP(WireGuardBastionHost)
+}
+
+// NodeIP returns the VPN address of the cluster node at index i
+func (s *WireGuardSubnet) NodeIP(i int) string {
+	return s.HostIP(WireGuardFirstNodeHost + i)
+}
+
+// IsNodeIP reports whether ip is in the range reserved for cluster nodes
+func (s *WireGuardSubnet) IsNodeIP(ip string) bool {
+	host, ok := s.HostOffset(ip)
+	return ok && host >= WireGuardFirstNodeHost && host <= WireGuardLastNodeHost
+}
+
+// ValidateWireGuardServer checks that the WireGuard server is either created
+// by the deployment, which then stores its endpoint and public key in the
+// stack, or is an existing server whose endpoint and public key are given
+func ValidateWireGuardServer(w *WireGuardConfig) error {
+	if !w.HubMode() {
+		return nil
+	}
+
+	var missing []string
+	if w.ServerEndpoint == "" {
+		missing = append(missing, "serverEndpoint")
+	}
+	if w.ServerPublicKey == "" {
+		missing = append(missing, "serverPublicKey")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("wireguard: set create: true to provision the VPN server, or set %s of the existing server", strings.Join(missing, " and "))
+	}
+	return nil
+}
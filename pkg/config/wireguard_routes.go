@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultWireGuardPort is used when the config does not set a port
+	DefaultWireGuardPort = 51820
+
+	// defaultHubKeepalive keeps NAT mappings to the hub open
+	defaultHubKeepalive = 25
+)
+
+// MeshEndpoint holds the addresses of a mesh member
+type MeshEndpoint struct {
+	WgIP      string
+	PublicIP  string
+	PrivateIP string
+	Provider  string
+}
+
+// MeshPeerAllowedIPs returns the AllowedIPs of a mesh peer as seen from a
+// node on myProvider. A peer on another provider also gets its private IP, so
+// wg-quick routes that address through the tunnel: another provider's private
+// network is not reachable directly.
+func MeshPeerAllowedIPs(peerWgIP, peerPrivateIP, peerProvider, myProvider string) string {
+	allowed := []string{peerWgIP + "/32"}
+	if peerPrivateIP != "" && peerProvider != "" && peerProvider != myProvider {
+		allowed = append(allowed, peerPrivateIP+"/32")
+	}
+	allowed = append(allowed, "10.0.0.0/8")
+	return strings.Join(allowed, ", ")
+}
+
+// meshPeerSharesVPC reports whether two mesh members sit in the same VPC:
+// they run on the same provider and both private IPs are inside that
+// provider's VPC CIDR from vpcNetworks
+func meshPeerSharesVPC(peer, self MeshEndpoint, vpcNetworks map[string]string) bool {
+	if peer.Provider == "" || peer.Provider != self.Provider {
+		return false
+	}
+	_, vpc, err := net.ParseCIDR(vpcNetworks[peer.Provider])
+	if err != nil {
+		return false
+	}
+	peerIP, selfIP := net.ParseIP(peer.PrivateIP), net.ParseIP(self.PrivateIP)
+	return peerIP != nil && selfIP != nil && vpc.Contains(peerIP) && vpc.Contains(selfIP)
+}
+
+// MeshPeerRoute returns the AllowedIPs and endpoint host of a mesh peer as
+// seen from self. Outside hybrid mode (vpcNetworks is nil, see
+// HybridVPCNetworks) peers are reached on their public IP with
+// MeshPeerAllowedIPs. In hybrid mode a peer in the same VPC is reached on its
+// private IP, whose traffic stays on the VPC route; any other peer is reached
+// on its public IP and its private IP is routed through the tunnel.
+func MeshPeerRoute(peer, self MeshEndpoint, vpcNetworks map[string]string) (string, string) {
+	if vpcNetworks == nil {
+		return MeshPeerAllowedIPs(peer.WgIP, peer.PrivateIP, peer.Provider, self.Provider), peer.PublicIP
+	}
+
+	if meshPeerSharesVPC(peer, self, vpcNetworks) {
+		return strings.Join([]string{peer.WgIP + "/32", "10.0.0.0/8"}, ", "), peer.PrivateIP
+	}
+
+	allowed := []string{peer.WgIP + "/32"}
+	if peer.PrivateIP != "" {
+		allowed = append(allowed, peer.PrivateIP+"/32")
+	}
+	allowed = append(allowed, "10.0.0.0/8")
+	return strings.Join(allowed, ", "), peer.PublicIP
+}
+
+// HubEndpoint returns the hub endpoint as host:port, adding the configured
+// (or default) port when the endpoint has none
+func (w *WireGuardConfig) HubEndpoint() string {
+	if _, _, err := net.SplitHostPort(w.ServerEndpoint); err == nil {
+		return w.ServerEndpoint
+	}
+	port := w.Port
+	if port == 0 {
+		port = DefaultWireGuardPort
+	}
+	return net.JoinHostPort(w.ServerEndpoint, strconv.Itoa(port))
+}
+
+// HubAllowedIPs returns the networks routed through the hub: the cluster VPN
+// subnet, which holds the VPN addresses of the nodes and the bastion, plus
+// any extra networks from the config
+func (w *WireGuardConfig) HubAllowedIPs() []string {
+	subnet := w.ParsedSubnet().String()
+	allowed := []string{subnet}
+	seen := map[string]bool{subnet: true}
+	for _, cidr := range w.AllowedIPs {
+		if cidr == "" || seen[cidr] {
+			continue
+		}
+		seen[cidr] = true
+		allowed = append(allowed, cidr)
+	}
+	return allowed
+}
+
+// HubSpokeConfig renders wg0.conf for a node whose only peer is the hub. The
+// private key is left as $(cat /etc/wireguard/privatekey), expanded on the
+// node.
+func (w *WireGuardConfig) HubSpokeConfig(wgIP string) string {
+	keepalive := w.PersistentKeepalive
+	if keepalive == 0 {
+		keepalive = defaultHubKeepalive
+	}
+
+	var b strings.Builder
+	b.WriteString("[Interface]\n")
+	fmt.Fprintf(&b, "Address = %s/%d\n", wgIP, w.ParsedSubnet().PrefixLen())
+	b.WriteString("PrivateKey = $(cat /etc/wireguard/privatekey)\n")
+	if w.MTU > 0 {
+		fmt.Fprintf(&b, "MTU = %d\n", w.MTU)
+	}
+	b.WriteString("\n[Peer]\n")
+	b.WriteString("# wireguard-hub\n")
+	fmt.Fprintf(&b, "PublicKey = %s\n", w.ServerPublicKey)
+	fmt.Fprintf(&b, "Endpoint = %s\n", w.HubEndpoint())
+	fmt.Fprintf(&b, "AllowedIPs = %s\n", strings.Join(w.HubAllowedIPs(), ", "))
+	fmt.Fprintf(&b, "PersistentKeepalive = %d\n", keepalive)
+	return b.String()
+}
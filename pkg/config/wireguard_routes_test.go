@@ -0,0 +1,172 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+const testHubPublicKey = "YWJjZGVmZ2hpamtsbW5vcHFyc3R1dnd4eXphYmNkZWY="
+
+// TestMeshPeerAllowedIPs tests that only peers on other providers route their private IP
+func TestMeshPeerAllowedIPs(t *testing.T) {
+	tests := []struct {
+		name          string
+		peerPrivateIP string
+		peerProvider  string
+		myProvider    string
+		expected      string
+	}{
+		{"same provider", "10.10.0.5", "digitalocean", "digitalocean", "10.8.0.11/32, 10.0.0.0/8"},
+		{"other provider", "192.168.130.4", "linode", "digitalocean", "10.8.0.11/32, 192.168.130.4/32, 10.0.0.0/8"},
+		{"bastion peer", "", "", "digitalocean", "10.8.0.11/32, 10.0.0.0/8"},
+		{"no private IP", "", "linode", "digitalocean", "10.8.0.11/32, 10.0.0.0/8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MeshPeerAllowedIPs("10.8.0.11", tt.peerPrivateIP, tt.peerProvider, tt.myProvider)
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestMeshPeerRoute_Hybrid tests that hybrid mode reaches peers in the same
+// VPC over the VPC and every other peer over WireGuard
+func TestMeshPeerRoute_Hybrid(t *testing.T) {
+	vpcs := map[string]string{"digitalocean": "10.10.0.0/16", "linode": "192.168.128.0/17"}
+	self := MeshEndpoint{PrivateIP: "10.10.0.2", Provider: "digitalocean"}
+
+	tests := []struct {
+		name             string
+		peer             MeshEndpoint
+		expectedAllowed  string
+		expectedEndpoint string
+	}{
+		{
+			name:             "same VPC",
+			peer:             MeshEndpoint{WgIP: "10.8.0.11", PublicIP: "203.0.113.11", PrivateIP: "10.10.0.5", Provider: "digitalocean"},
+			expectedAllowed:  "10.8.0.11/32, 10.0.0.0/8",
+			expectedEndpoint: "10.10.0.5",
+		},
+		{
+			name:             "other provider",
+			peer:             MeshEndpoint{WgIP: "10.8.0.12", PublicIP: "198.51.100.12", PrivateIP: "192.168.130.4", Provider: "linode"},
+			expectedAllowed:  "10.8.0.12/32, 192.168.130.4/32, 10.0.0.0/8",
+			expectedEndpoint: "198.51.100.12",
+		},
+		{
+			name:             "same provider outside the VPC",
+			peer:             MeshEndpoint{WgIP: "10.8.0.13", PublicIP: "203.0.113.13", PrivateIP: "10.20.0.7", Provider: "digitalocean"},
+			expectedAllowed:  "10.8.0.13/32, 10.20.0.7/32, 10.0.0.0/8",
+			expectedEndpoint: "203.0.113.13",
+		},
+		{
+			name:             "bastion peer",
+			peer:             MeshEndpoint{WgIP: "10.8.0.5", PublicIP: "203.0.113.5"},
+			expectedAllowed:  "10.8.0.5/32, 10.0.0.0/8",
+			expectedEndpoint: "203.0.113.5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, endpoint := MeshPeerRoute(tt.peer, self, vpcs)
+			if allowed != tt.expectedAllowed {
+				t.Errorf("Expected AllowedIPs %q, got %q", tt.expectedAllowed, allowed)
+			}
+			if endpoint != tt.expectedEndpoint {
+				t.Errorf("Expected endpoint %q, got %q", tt.expectedEndpoint, endpoint)
+			}
+		})
+	}
+}
+
+// TestMeshPeerRoute_NotHybrid tests that peers keep their public endpoint
+// outside hybrid mode
+func TestMeshPeerRoute_NotHybrid(t *testing.T) {
+	peer := MeshEndpoint{WgIP: "10.8.0.11", PublicIP: "203.0.113.11", PrivateIP: "10.10.0.5", Provider: "digitalocean"}
+	self := MeshEndpoint{PrivateIP: "10.10.0.2", Provider: "digitalocean"}
+
+	allowed, endpoint := MeshPeerRoute(peer, self, nil)
+	if allowed != "10.8.0.11/32, 10.0.0.0/8" || endpoint != "203.0.113.11" {
+		t.Errorf("Expected the public endpoint and mesh AllowedIPs, got %q via %q", allowed, endpoint)
+	}
+}
+
+// TestHubEndpoint tests that the configured port is added only when missing
+func TestHubEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		port     int
+		want     string
+	}{
+		{"203.0.113.1:51821", 51820, "203.0.113.1:51821"},
+		{"203.0.113.1", 51999, "203.0.113.1:51999"},
+		{"vpn.example.com", 0, "vpn.example.com:51820"},
+	}
+
+	for _, tt := range tests {
+		got := (&WireGuardConfig{ServerEndpoint: tt.endpoint, Port: tt.port}).HubEndpoint()
+		if got != tt.want {
+			t.Errorf("HubEndpoint(%q, %d) = %q, want %q", tt.endpoint, tt.port, got, tt.want)
+		}
+	}
+}
+
+// TestHubSpokeConfig tests the node config in hub mode
+func TestHubSpokeConfig(t *testing.T) {
+	wg := &WireGuardConfig{
+		Enabled:             true,
+		ServerEndpoint:      "203.0.113.1",
+		ServerPublicKey:     testHubPublicKey,
+		Port:                51820,
+		MTU:                 1420,
+		PersistentKeepalive: 15,
+		AllowedIPs:          []string{"10.8.0.0/24", "172.16.0.0/16"},
+	}
+
+	cfg := wg.HubSpokeConfig("10.8.0.11")
+
+	expected := []string{
+		"Address = 10.8.0.11/24",
+		"PrivateKey = $(cat /etc/wireguard/privatekey)",
+		"MTU = 1420",
+		"PublicKey = " + testHubPublicKey,
+		"Endpoint = 203.0.113.1:51820",
+		"AllowedIPs = 10.8.0.0/24, 172.16.0.0/16",
+		"PersistentKeepalive = 15",
+	}
+	for _, want := range expected {
+		if !strings.Contains(cfg, want) {
+			t.Errorf("Expected spoke config to contain %q\nGot:\n%s", want, cfg)
+		}
+	}
+
+	if n := strings.Count(cfg, "[Peer]"); n != 1 {
+		t.Errorf("Expected the hub to be the only peer, got %d peers", n)
+	}
+	if strings.Contains(cfg, "ListenPort") {
+		t.Error("Spokes dial out to the hub and should not set ListenPort")
+	}
+}
+
+// TestHubSpokeConfig_Defaults tests keepalive default and omitted MTU
+func TestHubSpokeConfig_Defaults(t *testing.T) {
+	wg := &WireGuardConfig{
+		ServerEndpoint:  "vpn.example.com:51820",
+		ServerPublicKey: testHubPublicKey,
+	}
+	cfg := wg.HubSpokeConfig("10.8.0.10")
+
+	if !strings.Contains(cfg, "PersistentKeepalive = 25") {
+		t.Errorf("Expected default keepalive\nGot:\n%s", cfg)
+	}
+	if !strings.Contains(cfg, "AllowedIPs = 10.8.0.0/24\n") {
+		t.Errorf("Expected cluster VPN subnet to be routed through the hub\nGot:\n%s", cfg)
+	}
+	if strings.Contains(cfg, "MTU") {
+		t.Errorf("Expected no MTU when unset\nGot:\n%s", cfg)
+	}
+}
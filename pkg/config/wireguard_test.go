@@ -0,0 +1,93 @@
+package config
+
+import "testing"
+
+func TestWireGuardConfigHubMode(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *WireGuardConfig
+		want bool
+	}{
+		{"nil", nil, false},
+		{"disabled", &WireGuardConfig{Enabled: false}, false},
+		{"auto-created server", &WireGuardConfig{Enabled: true, Create: true}, false},
+		{"existing server", &WireGuardConfig{Enabled: true, Create: false, ServerEndpoint: "203.0.113.1:51820"}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.cfg.HubMode(); got != tt.want {
+			t.Errorf("%s: HubMode() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateWireGuardServer(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *WireGuardConfig
+		wantErr bool
+	}{
+		{"nil", nil, false},
+		{"disabled", &WireGuardConfig{Enabled: false}, false},
+		{"created server", &WireGuardConfig{Enabled: true, Create: true}, false},
+		{"existing server", &WireGuardConfig{Enabled: true, ServerEndpoint: "203.0.113.1:51820", ServerPublicKey: "key"}, false},
+		{"neither created nor identified", &WireGuardConfig{Enabled: true}, true},
+		{"existing server without key", &WireGuardConfig{Enabled: true, ServerEndpoint: "203.0.113.1:51820"}, true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateWireGuardServer(tt.cfg); (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidateWireGuardServer() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestWireGuardSubnet_Addresses(t *testing.T) {
+	subnet, err := ParseWireGuardSubnet("10.100.0.0/24")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := subnet.BastionIP(); got != "10.100.0.5" {
+		t.Errorf("BastionIP() = %s, want 10.100.0.5", got)
+	}
+	if got := subnet.NodeIP(2); got != "10.100.0.12" {
+		t.Errorf("NodeIP(2) = %s, want 10.100.0.12", got)
+	}
+	if got := subnet.LastHost(); got != 254 {
+		t.Errorf("LastHost() = %d, want 254", got)
+	}
+	if got := subnet.PrefixLen(); got != 24 {
+		t.Errorf("PrefixLen() = %d, want 24", got)
+	}
+	if !subnet.IsNodeIP("10.100.0.99") || subnet.IsNodeIP("10.100.0.100") || subnet.IsNodeIP("10.8.0.10") {
+		t.Error("Expected only 10.100.0.10-99 to be node addresses")
+	}
+
+	wide, err := ParseWireGuardSubnet("10.8.0.0/16")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := wide.HostIP(300); got != "10.8.1.44" {
+		t.Errorf("HostIP(300) = %s, want 10.8.1.44", got)
+	}
+	if host, ok := wide.HostOffset("10.8.1.44"); !ok || host != 300 {
+		t.Errorf("HostOffset(10.8.1.44) = %d, %v, want 300, true", host, ok)
+	}
+}
+
+func TestParseWireGuardSubnet_Invalid(t *testing.T) {
+	for _, cidr := range []string{"10.8.0.0", "fd00::/64", "10.8.0.0/26"} {
+		if _, err := ParseWireGuardSubnet(cidr); err == nil {
+			t.Errorf("Expected %q to be rejected", cidr)
+		}
+	}
+}
+
+func TestWireGuardConfigParsedSubnet(t *testing.T) {
+	if got := (&WireGuardConfig{SubnetCIDR: "10.100.0.0/24"}).ParsedSubnet().String(); got != "10.100.0.0/24" {
+		t.Errorf("ParsedSubnet() = %s, want 10.100.0.0/24", got)
+	}
+	if got := (*WireGuardConfig)(nil).ParsedSubnet().String(); got != DefaultWireGuardSubnet {
+		t.Errorf("ParsedSubnet() = %s, want %s", got, DefaultWireGuardSubnet)
+	}
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// AWS zones are the region plus a letter suffix (us-east-1a)
+	awsZonePattern = regexp.MustCompile(`^[a-z]$`)
+	// GCP zones are the region plus a dash and a letter (us-central1-a)
+	gcpZonePattern = regexp.MustCompile(`^-[a-z]$`)
+)
+
+// ProviderSupportsZones reports whether a provider can place nodes in
+// availability zones within a region
+func ProviderSupportsZones(provider string) bool {
+	switch provider {
+	case "aws", "gcp", "azure":
+		return true
+	}
+	return false
+}
+
+// ZoneForIndex returns the zone for the i-th node of the pool, spreading
+// nodes round-robin across Zones. Returns "" when the pool has no zones.
+func (p *NodePool) ZoneForIndex(i int) string {
+	if len(p.Zones) == 0 {
+		return ""
+	}
+	return p.Zones[i%len(p.Zones)]
+}
+
+// ZonePlacement returns the zone assigned to each node of the pool, in order
+func (p *NodePool) ZonePlacement() []string {
+	placement := make([]string, 0, p.Count)
+	for i := 0; i < p.Count; i++ {
+		placement = append(placement, p.ZoneForIndex(i))
+	}
+	return placement
+}
+
+// ValidateZoneForRegion checks that zone belongs to region for the provider.
+// Providers without zone support are not checked here; callers should warn instead.
+func ValidateZoneForRegion(provider, region, zone string) error {
+	if zone == "" {
+		return fmt.Errorf("empty zone")
+	}
+
+	switch provider {
+	case "aws":
+		if !strings.HasPrefix(zone, region) || !awsZonePattern.MatchString(strings.TrimPrefix(zone, region)) {
+			return fmt.Errorf("zone %s is not in AWS region %s", zone, region)
+		}
+	case "gcp":
+		if !strings.HasPrefix(zone, region) || !gcpZonePattern.MatchString(strings.TrimPrefix(zone, region)) {
+			return fmt.Errorf("zone %s is not in GCP region %s", zone, region)
+		}
+	case "azure":
+		switch zone {
+		case "1", "2", "3":
+		default:
+			return fmt.Errorf("invalid Azure zone %s (must be 1, 2 or 3)", zone)
+		}
+	}
+
+	return nil
+}
+
+// Well-known node labels describing where a node runs
+const (
+	TopologyRegionLabel = "topology.kubernetes.io/region"
+	TopologyZoneLabel   = "topology.kubernetes.io/zone"
+)
+
+// TopologyLabels returns the topology node labels, as key=value, for a node
+// in region and zone. Empty values are left out.
+func TopologyLabels(region, zone string) []string {
+	var labels []string
+	if region != "" {
+		labels = append(labels, TopologyRegionLabel+"="+region)
+	}
+	if zone != "" {
+		labels = append(labels, TopologyZoneLabel+"="+zone)
+	}
+	return labels
+}
+
+// ZoneKey names the location a node is counted under in a zone breakdown:
+// region/zone, or only the region when the node has no zone
+func ZoneKey(region, zone string) string {
+	if zone == "" {
+		return region
+	}
+	return region + "/" + zone
+}
+
+// PlacedZone returns the zone the i-th node of the pool is created in: its
+// round-robin zone, or "" when the provider has no availability zones
+func (p *NodePool) PlacedZone(i int) string {
+	if !ProviderSupportsZones(p.Provider) {
+		return ""
+	}
+	return p.ZoneForIndex(i)
+}
+
+// ZoneBreakdown counts the nodes the config defines per location, keyed by
+// ZoneKey. Pool nodes are placed the way the deployment spreads them.
+func (c *ClusterConfig) ZoneBreakdown() map[string]int {
+	breakdown := make(map[string]int)
+	for _, node := range c.Nodes {
+		zone := node.Zone
+		if !ProviderSupportsZones(node.Provider) {
+			zone = ""
+		}
+		breakdown[ZoneKey(node.Region, zone)]++
+	}
+	for name, pool := range c.NodePools {
+		pool := pool
+		for i := 0; i < pool.Count; i++ {
+			if nodeName, err := RenderPoolNodeName(name, &pool, i); err == nil && pool.ExcludedNodes[nodeName] {
+				continue
+			}
+			breakdown[ZoneKey(pool.Region, pool.PlacedZone(i))]++
+		}
+	}
+	return breakdown
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestNodePoolZonePlacement_RoundRobin(t *testing.T) {
+	pool := &NodePool{
+		Name:     "workers",
+		Provider: "aws",
+		Count:    6,
+		Region:   "us-east-1",
+		Zones:    []string{"us-east-1a", "us-east-1b", "us-east-1c"},
+	}
+
+	placement := pool.ZonePlacement()
+	if len(placement) != 6 {
+		t.Fatalf("Expected 6 placements, got %d", len(placement))
+	}
+
+	perZone := map[string]int{}
+	for _, zone := range placement {
+		perZone[zone]++
+	}
+	for _, zone := range pool.Zones {
+		if perZone[zone] != 2 {
+			t.Errorf("Expected 2 nodes in %s, got %d", zone, perZone[zone])
+		}
+	}
+
+	if placement[0] != "us-east-1a" || placement[1] != "us-east-1b" || placement[3] != "us-east-1a" {
+		t.Errorf("Expected round-robin order, got %v", placement)
+	}
+}
+
+func TestNodePoolZoneForIndex_NoZones(t *testing.T) {
+	pool := &NodePool{Count: 2, Region: "nyc3"}
+	if zone := pool.ZoneForIndex(1); zone != "" {
+		t.Errorf("Expected no zone, got %q", zone)
+	}
+}
+
+func TestValidateZoneForRegion(t *testing.T) {
+	tests := []struct {
+		provider string
+		region   string
+		zone     string
+		valid    bool
+	}{
+		{"aws", "us-east-1", "us-east-1a", true},
+		{"aws", "us-east-1", "us-west-2a", false},
+		{"aws", "us-east-1", "us-east-1", false},
+		{"gcp", "us-central1", "us-central1-a", true},
+		{"gcp", "us-central1", "europe-west1-b", false},
+		{"azure", "eastus", "2", true},
+		{"azure", "eastus", "eastus-1", false},
+		{"digitalocean", "nyc3", "nyc1", true},
+	}
+
+	for _, tt := range tests {
+		err := ValidateZoneForRegion(tt.provider, tt.region, tt.zone)
+		if tt.valid && err != nil {
+			t.Errorf("%s %s/%s: unexpected error: %v", tt.provider, tt.region, tt.zone, err)
+		}
+		if !tt.valid && err == nil {
+			t.Errorf("%s %s/%s: expected error", tt.provider, tt.region, tt.zone)
+		}
+	}
+}
+
+func TestTopologyLabels(t *testing.T) {
+	labels := TopologyLabels("us-east-1", "us-east-1b")
+	if len(labels) != 2 || labels[0] != "topology.kubernetes.io/region=us-east-1" || labels[1] != "topology.kubernetes.io/zone=us-east-1b" {
+		t.Errorf("Unexpected labels %v", labels)
+	}
+
+	if labels := TopologyLabels("nyc3", ""); len(labels) != 1 {
+		t.Errorf("Expected only the region label without a zone, got %v", labels)
+	}
+}
+
+func TestClusterConfigZoneBreakdown(t *testing.T) {
+	cfg := &ClusterConfig{
+		Nodes: []NodeConfig{{Name: "edge", Region: "nyc3"}},
+		NodePools: map[string]NodePool{
+			"workers": {Name: "workers", Provider: "aws", Count: 5, Region: "us-east-1", Zones: []string{"us-east-1a", "us-east-1b"}},
+			"masters": {Name: "masters", Provider: "digitalocean", Count: 1, Region: "nyc3", Zones: []string{"ignored"}},
+		},
+	}
+
+	breakdown := cfg.ZoneBreakdown()
+	want := map[string]int{"nyc3": 2, "us-east-1/us-east-1a": 3, "us-east-1/us-east-1b": 2}
+	if len(breakdown) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, breakdown)
+	}
+	for key, count := range want {
+		if breakdown[key] != count {
+			t.Errorf("Expected %d nodes in %s, got %d", count, key, breakdown[key])
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package dns
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/chalkan3/sloth-kubernetes/internal/common"
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+// cloudflareAPIURL is the base of the Cloudflare v4 API
+const cloudflareAPIURL = "https://api.cloudflare.com/client/v4"
+
+// cloudflareAutoTTL is the TTL Cloudflare reports for proxied records, whose
+// TTL it manages itself
+const cloudflareAutoTTL = 1
+
+// cloudflareZoneProvider implements ZoneProvider with the Cloudflare API
+type cloudflareZoneProvider struct {
+	client  *http.Client
+	baseURL string
+	token   string
+	proxied bool
+	// fixedZoneID is the configured zone, used instead of a lookup
+	fixedZoneID string
+
+	// mu serializes upserts, so two records of the same name are never
+	// created side by side, and guards zones
+	mu    sync.Mutex
+	zones map[string]string
+}
+
+// cloudflareRecord is a DNS record as the Cloudflare API represents it
+type cloudflareRecord struct {
+	ID      string `json:"id,omitempty"`
+	Type    string `json:"type"`
+	Name    string `json:"name"`
+	Content string `json:"content"`
+	TTL     int    `json:"ttl"`
+	Proxied bool   `json:"proxied"`
+}
+
+// cloudflareResponse is the envelope of every Cloudflare API response
+type cloudflareResponse struct {
+	Success bool `json:"success"`
+	Errors  []struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+	Result json.RawMessage `json:"result"`
+}
+
+// NewCloudflareZoneProvider creates a ZoneProvider for Cloudflare DNS. The
+// zone is looked up by domain unless zoneID is given. With proxied, records
+// that serve ingress traffic are proxied through Cloudflare; the others,
+// such as the API server and node records, always resolve to the nodes.
+func NewCloudflareZoneProvider(token, zoneID string, proxied bool) (ZoneProvider, error) {
+	if token == "" {
+		return nil, fmt.Errorf("Cloudflare API token is required for DNS records")
+	}
+
+	httpClient, err := common.NewHTTPClient(30 * time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	return newCloudflareZoneProvider(httpClient, cloudflareAPIURL, token, zoneID, proxied), nil
+}
+
+func newCloudflareZoneProvider(client *http.Client, baseURL, token, zoneID string, proxied bool) *cloudflareZoneProvider {
+	return &cloudflareZoneProvider{
+		client:      client,
+		baseURL:     strings.TrimSuffix(baseURL, "/"),
+		token:       token,
+		proxied:     proxied,
+		fixedZoneID: zoneID,
+		zones:       make(map[string]string),
+	}
+}
+
+// UpsertRecord creates record in the zone of domain, or updates the record
+// of the same type and name when its value, TTL or proxying differ
+func (p *cloudflareZoneProvider) UpsertRecord(domain string, record DesiredRecord, dryRun bool) (RecordAction, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	zoneID, err := p.zoneID(domain)
+	if err != nil {
+		return "", err
+	}
+
+	desired := cloudflareRecord{
+		Type:    record.Type,
+		Name:    cloudflareRecordName(record.Name, domain),
+		Content: record.Value,
+		TTL:     record.TTL,
+		Proxied: p.proxied && record.Ingress,
+	}
+	if desired.Proxied {
+		desired.TTL = cloudflareAutoTTL
+	}
+
+	existing, err := p.findRecord(zoneID, desired.Type, desired.Name)
+	if err != nil {
+		return "", err
+	}
+
+	var action RecordAction
+	if existing == nil {
+		action = RecordCreated
+	} else {
+		action = planRecordAction(&ExistingRecord{
+			ID:    existing.ID,
+			Type:  existing.Type,
+			Name:  existing.Name,
+			Value: existing.Content,
+			TTL:   existing.TTL,
+		}, desired.Content, desired.TTL)
+		if action == RecordUnchanged && existing.Proxied != desired.Proxied {
+			action = RecordUpdated
+		}
+	}
+	if dryRun || action == RecordUnchanged {
+		return action, nil
+	}
+
+	path := fmt.Sprintf("/zones/%s/dns_records", url.PathEscape(zoneID))
+	if existing == nil {
+		if err := p.do(http.MethodPost, path, desired, nil); err != nil {
+			return "", fmt.Errorf("failed to create DNS record %s: %w", desired.Name, err)
+		}
+	} else if err := p.do(http.MethodPut, path+"/"+url.PathEscape(existing.ID), desired, nil); err != nil {
+		return "", fmt.Errorf("failed to update DNS record %s: %w", desired.Name, err)
+	}
+	return action, nil
+}
+
+// zoneID returns the ID of the zone of domain, looking it up once
+func (p *cloudflareZoneProvider) zoneID(domain string) (string, error) {
+	if p.fixedZoneID != "" {
+		return p.fixedZoneID, nil
+	}
+	if id, ok := p.zones[domain]; ok {
+		return id, nil
+	}
+
+	var zones []struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	if err := p.do(http.MethodGet, "/zones?name="+url.QueryEscape(domain), nil, &zones); err != nil {
+		return "", fmt.Errorf("failed to look up the Cloudflare zone of %s: %w", domain, err)
+	}
+	if len(zones) == 0 {
+		return "", fmt.Errorf("no Cloudflare zone found for %s", domain)
+	}
+
+	p.zones[domain] = zones[0].ID
+	return zones[0].ID, nil
+}
+
+// findRecord returns the record of the given type and fully qualified name,
+// or nil when absent
+func (p *cloudflareZoneProvider) findRecord(zoneID, recordType, name string) (*cloudflareRecord, error) {
+	query := url.Values{"type": {recordType}, "name": {name}}
+	var records []cloudflareRecord
+	path := fmt.Sprintf("/zones/%s/dns_records?%s", url.PathEscape(zoneID), query.Encode())
+	if err := p.do(http.MethodGet, path, nil, &records); err != nil {
+		return nil, fmt.Errorf("failed to list DNS records %s: %w", name, err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	// Round-robin names have several records; the first one is managed
+	return &records[0], nil
+}
+
+// do sends a request to the Cloudflare API and decodes its result into
+// result, when given. Rejected credentials are marked errs.ErrProviderAuth.
+func (p *cloudflareZoneProvider) do(method, path string, body, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, p.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+p.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var envelope cloudflareResponse
+	if err := json.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return fmt.Errorf("unexpected response (HTTP %d): %w", resp.StatusCode, err)
+	}
+
+	if !envelope.Success || resp.StatusCode >= 300 {
+		messages := make([]string, 0, len(envelope.Errors))
+		for _, e := range envelope.Errors {
+			messages = append(messages, fmt.Sprintf("%s (code %d)", e.Message, e.Code))
+		}
+		if len(messages) == 0 {
+			messages = append(messages, resp.Status)
+		}
+		err := fmt.Errorf("Cloudflare API: %s", strings.Join(messages, "; "))
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			return errs.Mark(err, errs.ErrProviderAuth)
+		}
+		return err
+	}
+
+	if result != nil {
+		return json.Unmarshal(envelope.Result, result)
+	}
+	return nil
+}
+
+// cloudflareRecordName returns the fully qualified name Cloudflare uses for
+// a record named relative to domain
+func cloudflareRecordName(name, domain string) string {
+	if name == "" || name == "@" {
+		return domain
+	}
+	return name + "." + domain
+}
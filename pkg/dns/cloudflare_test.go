@@ -0,0 +1,265 @@
+package dns
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+)
+
+// fakeCloudflare is an in-memory Cloudflare API serving one zone
+type fakeCloudflare struct {
+	mu          sync.Mutex
+	zone        string
+	records     []cloudflareRecord
+	zoneLookups int
+	writes      []string
+}
+
+func (f *fakeCloudflare) serve(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"success":false,"errors":[{"code":9109,"message":"Invalid access token"}]}`)
+			return
+		}
+
+		var result interface{}
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/zones":
+			f.zoneLookups++
+			zones := []map[string]string{}
+			if r.URL.Query().Get("name") == f.zone {
+				zones = append(zones, map[string]string{"id": "zone-1", "name": f.zone})
+			}
+			result = zones
+		case r.Method == http.MethodGet && r.URL.Path == "/zones/zone-1/dns_records":
+			matches := []cloudflareRecord{}
+			for _, record := range f.records {
+				if record.Type == r.URL.Query().Get("type") && record.Name == r.URL.Query().Get("name") {
+					matches = append(matches, record)
+				}
+			}
+			result = matches
+		case r.Method == http.MethodPost && r.URL.Path == "/zones/zone-1/dns_records":
+			var record cloudflareRecord
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&record))
+			record.ID = fmt.Sprintf("rec-%d", len(f.records)+1)
+			f.records = append(f.records, record)
+			f.writes = append(f.writes, "POST "+record.Name)
+			result = record
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/zones/zone-1/dns_records/"):
+			var record cloudflareRecord
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&record))
+			record.ID = strings.TrimPrefix(r.URL.Path, "/zones/zone-1/dns_records/")
+			for i := range f.records {
+				if f.records[i].ID == record.ID {
+					f.records[i] = record
+				}
+			}
+			f.writes = append(f.writes, "PUT "+record.Name)
+			result = record
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"success":false,"errors":[{"code":7003,"message":"Could not route"}]}`)
+			return
+		}
+
+		data, _ := json.Marshal(result)
+		fmt.Fprintf(w, `{"success":true,"errors":[],"result":%s}`, data)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func (f *fakeCloudflare) record(recordType, name string) *cloudflareRecord {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.records {
+		if f.records[i].Type == recordType && f.records[i].Name == name {
+			return &f.records[i]
+		}
+	}
+	return nil
+}
+
+func newTestCloudflare(t *testing.T, fake *fakeCloudflare, proxied bool) *cloudflareZoneProvider {
+	t.Helper()
+	server := fake.serve(t)
+	return newCloudflareZoneProvider(server.Client(), server.URL, "test-token", "", proxied)
+}
+
+func TestCloudflareUpsertRecord(t *testing.T) {
+	fake := &fakeCloudflare{
+		zone: "example.com",
+		records: []cloudflareRecord{
+			{ID: "rec-a", Type: "A", Name: "api.example.com", Content: "198.51.100.1", TTL: 300},
+			{ID: "rec-b", Type: "A", Name: "master1.example.com", Content: "203.0.113.10", TTL: 300},
+		},
+	}
+	provider := newTestCloudflare(t, fake, false)
+
+	action, err := provider.UpsertRecord("example.com", DesiredRecord{Type: "A", Name: "api", Value: "203.0.113.10", TTL: 300}, false)
+	require.NoError(t, err)
+	assert.Equal(t, RecordUpdated, action)
+	assert.Equal(t, "203.0.113.10", fake.record("A", "api.example.com").Content)
+
+	action, err = provider.UpsertRecord("example.com", DesiredRecord{Type: "A", Name: "master1", Value: "203.0.113.10", TTL: 300}, false)
+	require.NoError(t, err)
+	assert.Equal(t, RecordUnchanged, action)
+
+	action, err = provider.UpsertRecord("example.com", DesiredRecord{Type: "A", Name: "worker1", Value: "203.0.113.20", TTL: 300}, false)
+	require.NoError(t, err)
+	assert.Equal(t, RecordCreated, action)
+	require.NotNil(t, fake.record("A", "worker1.example.com"))
+
+	assert.Equal(t, []string{"PUT api.example.com", "POST worker1.example.com"}, fake.writes)
+	assert.Equal(t, 1, fake.zoneLookups, "the zone should be looked up once per domain")
+}
+
+func TestCloudflareUpsertRecord_Proxied(t *testing.T) {
+	fake := &fakeCloudflare{
+		zone: "example.com",
+		records: []cloudflareRecord{
+			{ID: "rec-a", Type: "A", Name: "kube-ingress.example.com", Content: "203.0.113.10", TTL: 300},
+		},
+	}
+	provider := newTestCloudflare(t, fake, true)
+
+	action, err := provider.UpsertRecord("example.com", DesiredRecord{Type: "A", Name: "kube-ingress", Value: "203.0.113.10", TTL: 300, Ingress: true}, false)
+	require.NoError(t, err)
+	assert.Equal(t, RecordUpdated, action, "enabling the proxy must update an otherwise equal record")
+	ingress := fake.record("A", "kube-ingress.example.com")
+	assert.True(t, ingress.Proxied)
+	assert.Equal(t, cloudflareAutoTTL, ingress.TTL)
+
+	action, err = provider.UpsertRecord("example.com", DesiredRecord{Type: "A", Name: "kube-ingress", Value: "203.0.113.10", TTL: 300, Ingress: true}, false)
+	require.NoError(t, err)
+	assert.Equal(t, RecordUnchanged, action)
+
+	_, err = provider.UpsertRecord("example.com", DesiredRecord{Type: "A", Name: "api", Value: "203.0.113.10", TTL: 300}, false)
+	require.NoError(t, err)
+	assert.False(t, fake.record("A", "api.example.com").Proxied, "only ingress records may be proxied")
+}
+
+func TestCloudflareUpsertRecord_DryRun(t *testing.T) {
+	fake := &fakeCloudflare{zone: "example.com"}
+	provider := newTestCloudflare(t, fake, false)
+
+	action, err := provider.UpsertRecord("example.com", DesiredRecord{Type: "A", Name: "api", Value: "203.0.113.10", TTL: 300}, true)
+	require.NoError(t, err)
+	assert.Equal(t, RecordCreated, action)
+	assert.Empty(t, fake.writes, "a dry run must not modify records")
+}
+
+func TestCloudflareUpsertRecord_ConfiguredZone(t *testing.T) {
+	fake := &fakeCloudflare{zone: "example.com"}
+	server := fake.serve(t)
+	provider := newCloudflareZoneProvider(server.Client(), server.URL, "test-token", "zone-1", false)
+
+	_, err := provider.UpsertRecord("example.com", DesiredRecord{Type: "A", Name: "api", Value: "203.0.113.10", TTL: 300}, false)
+	require.NoError(t, err)
+	assert.Zero(t, fake.zoneLookups, "a configured zone must not be looked up")
+}
+
+func TestCloudflareUpsertRecord_Errors(t *testing.T) {
+	fake := &fakeCloudflare{zone: "example.com"}
+	server := fake.serve(t)
+
+	provider := newCloudflareZoneProvider(server.Client(), server.URL, "test-token", "", false)
+	_, err := provider.UpsertRecord("other.com", DesiredRecord{Type: "A", Name: "api", Value: "203.0.113.10", TTL: 300}, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "no Cloudflare zone found for other.com")
+
+	provider = newCloudflareZoneProvider(server.Client(), server.URL, "wrong-token", "", false)
+	_, err = provider.UpsertRecord("example.com", DesiredRecord{Type: "A", Name: "api", Value: "203.0.113.10", TTL: 300}, false)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "Invalid access token")
+	assert.True(t, errors.Is(err, errs.ErrProviderAuth), "rejected credentials should be marked as an auth failure")
+}
+
+func TestNewCloudflareZoneProvider_RequiresToken(t *testing.T) {
+	_, err := NewCloudflareZoneProvider("", "", false)
+	assert.Error(t, err)
+}
+
+func TestManager_ZoneProvider(t *testing.T) {
+	fake := &fakeCloudflare{
+		zone: "example.com",
+		records: []cloudflareRecord{
+			{ID: "rec-a", Type: "A", Name: "api.example.com", Content: "198.51.100.1", TTL: 300},
+		},
+	}
+	provider := newTestCloudflare(t, fake, true)
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	actions := make(map[string]string)
+
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		manager := NewManager(ctx, "example.com")
+		manager.SetZoneProvider(provider)
+
+		if err := manager.CreateNodeRecords(singleMasterNodes()); err != nil {
+			return err
+		}
+		if err := manager.CreateClusterRecords(); err != nil {
+			return err
+		}
+		if err := manager.UpdateIngressRecord(pulumi.String("192.0.2.50").ToStringOutput()); err != nil {
+			return err
+		}
+		assert.Empty(t, manager.records, "no DigitalOcean records should be declared")
+
+		for _, result := range manager.Summary() {
+			key := result.Type + " " + result.Name
+			wg.Add(1)
+			result.Action.ApplyT(func(action string) string {
+				defer wg.Done()
+				mu.Lock()
+				actions[key] = action
+				mu.Unlock()
+				return action
+			})
+		}
+		return nil
+	}, pulumi.WithMocks("test-project", "test-stack", &DNSMocks{}))
+	require.NoError(t, err)
+	wg.Wait()
+
+	assert.Equal(t, "updated", actions["A api"])
+	assert.Equal(t, "created", actions["A master1-digitalocean"])
+	assert.Equal(t, "created", actions["CNAME k8s"])
+
+	api := fake.record("A", "api.example.com")
+	require.NotNil(t, api)
+	assert.Equal(t, "203.0.113.10", api.Content)
+	assert.False(t, api.Proxied)
+
+	// The ingress update runs after the initial record, never before it
+	ingress := fake.record("A", "kube-ingress.example.com")
+	require.NotNil(t, ingress)
+	assert.Equal(t, "192.0.2.50", ingress.Content)
+	assert.True(t, ingress.Proxied)
+	assert.Equal(t, "192.0.2.50", fake.record("A", "*.k8s.example.com").Content)
+	assert.Equal(t, "192.0.2.50", fake.record("A", "grafana.k8s.example.com").Content)
+
+	k8s := fake.record("CNAME", "k8s.example.com")
+	require.NotNil(t, k8s)
+	assert.Equal(t, "api.example.com", k8s.Content)
+	assert.False(t, k8s.Proxied, "records pointing at the API server must not be proxied")
+	assert.True(t, fake.record("CNAME", "rancher.example.com").Proxied)
+}
@@ -2,6 +2,7 @@ package dns
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/chalkan3/sloth-kubernetes/pkg/providers"
@@ -11,10 +12,26 @@ import (
 
 // Manager handles DNS record creation
 type Manager struct {
-	ctx     *pulumi.Context
-	domain  string
-	records []*digitalocean.DnsRecord
-	nodes   []*providers.NodeOutput
+	ctx      *pulumi.Context
+	domain   string
+	records  []*digitalocean.DnsRecord
+	nodes    []*providers.NodeOutput
+	provider RecordProvider
+	existing map[string]*ExistingRecord
+	results  []RecordResult
+
+	// zone manages the records through the provider API instead of as
+	// DigitalOcean resources; zoneActions holds the last upsert of each
+	// record, which the next upsert of the same record waits for
+	zone        ZoneProvider
+	zoneActions map[string]pulumi.StringOutput
+}
+
+// RecordResult reports what CreateNodeRecords did with one record
+type RecordResult struct {
+	Name   string
+	Type   string
+	Action pulumi.StringOutput
 }
 
 // NewManager creates a new DNS manager
@@ -26,6 +43,26 @@ func NewManager(ctx *pulumi.Context, domain string) *Manager {
 	}
 }
 
+// SetRecordProvider enables upsert semantics: records that already exist at
+// the provider are updated in place, or left alone when unchanged, instead
+// of failing with a conflict. Without a provider every record is created.
+func (m *Manager) SetRecordProvider(provider RecordProvider) {
+	m.provider = provider
+	m.existing = nil
+}
+
+// SetZoneProvider manages every record through zone, such as Cloudflare,
+// instead of as DigitalOcean DNS resources
+func (m *Manager) SetZoneProvider(zone ZoneProvider) {
+	m.zone = zone
+	m.zoneActions = make(map[string]pulumi.StringOutput)
+}
+
+// Summary returns the action taken for each record, in creation order
+func (m *Manager) Summary() []RecordResult {
+	return m.results
+}
+
 // CreateNodeRecords creates DNS records for all nodes
 func (m *Manager) CreateNodeRecords(nodes map[string][]*providers.NodeOutput) error {
 	m.ctx.Log.Info("Creating DNS records for nodes", nil)
@@ -47,39 +84,16 @@ func (m *Manager) CreateNodeRecords(nodes map[string][]*providers.NodeOutput) er
 			}
 
 			// Create appropriate DNS names
-			var dnsNames []string
-
+			index := 0
 			switch nodeType {
 			case "master", "controlplane":
 				masterCount++
-				dnsNames = []string{
-					fmt.Sprintf("master%d", masterCount),
-					fmt.Sprintf("master%d-%s", masterCount, provider),
-					fmt.Sprintf("k8s-master%d", masterCount),
-				}
-
-				// Add API endpoint for first master
-				if masterCount == 1 {
-					dnsNames = append(dnsNames, "api", "k8s-api")
-				}
-
+				index = masterCount
 			case "worker":
 				workerCount++
-				dnsNames = []string{
-					fmt.Sprintf("worker%d", workerCount),
-					fmt.Sprintf("worker%d-%s", workerCount, provider),
-					fmt.Sprintf("k8s-worker%d", workerCount),
-				}
-
-			default:
-				dnsNames = []string{
-					node.Name,
-					fmt.Sprintf("%s-%s", node.Name, provider),
-				}
+				index = workerCount
 			}
-
-			// Also add the actual node name
-			dnsNames = append(dnsNames, node.Name)
+			dnsNames := NodeRecordNames(nodeType, provider, node.Name, index)
 
 			// Create A records for public IPs
 			for _, name := range dnsNames {
@@ -119,40 +133,192 @@ func (m *Manager) CreateNodeRecords(nodes map[string][]*providers.NodeOutput) er
 		return fmt.Errorf("failed to create wildcard record: %w", err)
 	}
 
+	m.exportSummary()
+
 	m.ctx.Log.Info("DNS records created successfully", nil)
 
 	return nil
 }
 
+// exportSummary exports the action taken for each record
+func (m *Manager) exportSummary() {
+	summary := pulumi.StringMap{}
+	for _, result := range m.results {
+		summary[result.Name] = result.Action
+	}
+	m.ctx.Export("dns_record_summary", summary)
+}
+
+// NodeRecordNames returns the names of the public A records of a node:
+// numbered names for the index-th (1-based) master or worker, plus the API
+// endpoint names for the first master, and the node name itself
+func NodeRecordNames(nodeType, provider, nodeName string, index int) []string {
+	var dnsNames []string
+
+	switch nodeType {
+	case "master", "controlplane":
+		dnsNames = []string{
+			fmt.Sprintf("master%d", index),
+			fmt.Sprintf("master%d-%s", index, provider),
+			fmt.Sprintf("k8s-master%d", index),
+		}
+
+		// Add API endpoint for first master
+		if index == 1 {
+			dnsNames = append(dnsNames, "api", "k8s-api")
+		}
+
+	case "worker":
+		dnsNames = []string{
+			fmt.Sprintf("worker%d", index),
+			fmt.Sprintf("worker%d-%s", index, provider),
+			fmt.Sprintf("k8s-worker%d", index),
+		}
+
+	default:
+		dnsNames = []string{
+			nodeName,
+			fmt.Sprintf("%s-%s", nodeName, provider),
+		}
+	}
+
+	// Also add the actual node name
+	return append(dnsNames, nodeName)
+}
+
 // createARecord creates an A record
 func (m *Manager) createARecord(name string, ip pulumi.StringInput) error {
 	recordName := strings.ToLower(name)
 
-	record, err := digitalocean.NewDnsRecord(m.ctx, fmt.Sprintf("dns-%s", recordName), &digitalocean.DnsRecordArgs{
+	if err := m.upsertARecord(fmt.Sprintf("dns-%s", recordName), recordName, ip, false); err != nil {
+		return err
+	}
+
+	// Export the DNS record
+	m.ctx.Export(fmt.Sprintf("dns_%s", strings.ReplaceAll(recordName, "-", "_")),
+		pulumi.Sprintf("%s.%s", recordName, m.domain))
+
+	return nil
+}
+
+// upsertARecord declares the A record recordName and records what happened to it.
+// A record that already exists at the provider is adopted by ID and its value is
+// updated through the provider API when it differs; dry runs only plan the update.
+// ingress marks records serving ingress traffic, which a zone provider may proxy.
+func (m *Manager) upsertARecord(resourceName, recordName string, value pulumi.StringInput, ingress bool) error {
+	if m.zone != nil {
+		m.upsertZoneRecord(DesiredRecord{Type: "A", Name: recordName, TTL: recordTTL, Ingress: ingress}, value)
+		return nil
+	}
+
+	existing, err := m.lookupExisting("A", recordName)
+	if err != nil {
+		return err
+	}
+
+	args := &digitalocean.DnsRecordArgs{
 		Domain: pulumi.String(m.domain),
 		Type:   pulumi.String("A"),
 		Name:   pulumi.String(recordName),
-		Value:  ip,
-		Ttl:    pulumi.Int(300), // 5 minutes TTL for easier updates
-	})
+		Value:  value,
+		Ttl:    pulumi.Int(recordTTL), // 5 minutes TTL for easier updates
+	}
+
+	if existing == nil {
+		record, err := digitalocean.NewDnsRecord(m.ctx, resourceName, args)
+		if err != nil {
+			return err
+		}
+		m.records = append(m.records, record)
+		m.results = append(m.results, RecordResult{
+			Name:   recordName,
+			Type:   "A",
+			Action: pulumi.String(string(RecordCreated)).ToStringOutput(),
+		})
+		return nil
+	}
+
+	// Value and TTL are kept in sync below, so the import doesn't fail on a mismatch
+	record, err := digitalocean.NewDnsRecord(m.ctx, resourceName, args,
+		pulumi.Import(pulumi.ID(existing.ID)),
+		pulumi.IgnoreChanges([]string{"value", "ttl"}))
 	if err != nil {
 		return err
 	}
-
 	m.records = append(m.records, record)
 
-	// Export the DNS record
-	m.ctx.Export(fmt.Sprintf("dns_%s", strings.ReplaceAll(recordName, "-", "_")),
-		pulumi.Sprintf("%s.%s", recordName, m.domain))
+	action := value.ToStringOutput().ApplyT(func(v string) (string, error) {
+		act := planRecordAction(existing, v, recordTTL)
+		if act == RecordUpdated && !m.ctx.DryRun() {
+			updated := *existing
+			updated.Value = v
+			updated.TTL = recordTTL
+			if err := m.provider.UpdateRecord(m.domain, updated); err != nil {
+				return "", err
+			}
+		}
+		return string(act), nil
+	}).(pulumi.StringOutput)
 
+	m.results = append(m.results, RecordResult{Name: recordName, Type: "A", Action: action})
 	return nil
 }
 
+// upsertZoneRecord upserts record through the zone provider once value is
+// known, after any earlier upsert of the same record, and records what
+// happened to it. Dry runs only plan the action.
+func (m *Manager) upsertZoneRecord(record DesiredRecord, value pulumi.StringInput) {
+	key := recordKey(record.Type, record.Name)
+	inputs := []interface{}{value}
+	if previous, ok := m.zoneActions[key]; ok {
+		inputs = append(inputs, previous)
+	}
+
+	action := pulumi.All(inputs...).ApplyT(func(args []interface{}) (string, error) {
+		desired := record
+		desired.Value = args[0].(string)
+		act, err := m.zone.UpsertRecord(m.domain, desired, m.ctx.DryRun())
+		if err != nil {
+			return "", err
+		}
+		return string(act), nil
+	}).(pulumi.StringOutput)
+
+	m.zoneActions[key] = action
+	m.results = append(m.results, RecordResult{Name: record.Name, Type: record.Type, Action: action})
+}
+
+// lookupExisting returns the provider's record of the given type and name, or
+// nil when absent. The domain's records are listed once, on first use.
+func (m *Manager) lookupExisting(recordType, name string) (*ExistingRecord, error) {
+	if m.provider == nil {
+		return nil, nil
+	}
+
+	if m.existing == nil {
+		records, err := m.provider.ListRecords(m.domain)
+		if err != nil {
+			return nil, err
+		}
+
+		m.existing = make(map[string]*ExistingRecord, len(records))
+		for i := range records {
+			key := recordKey(records[i].Type, records[i].Name)
+			// Round-robin names have several records; the first one is managed
+			if _, ok := m.existing[key]; !ok {
+				m.existing[key] = &records[i]
+			}
+		}
+	}
+
+	return m.existing[recordKey(recordType, name)], nil
+}
+
 // createWildcardRecord creates a wildcard DNS record for ingress
 func (m *Manager) createWildcardRecord() error {
 	// Initially point to first worker or master node
 	// This will be updated when ingress is installed
-	var initialIP pulumi.StringOutput
+	var initialIP pulumi.StringInput
 
 	for _, node := range m.nodes {
 		if node.Labels != nil {
@@ -164,39 +330,23 @@ func (m *Manager) createWildcardRecord() error {
 	}
 
 	// If no worker found, use first master
-	if initialIP == pulumi.String("").ToStringOutput() && len(m.nodes) > 0 {
+	if initialIP == nil && len(m.nodes) > 0 {
 		initialIP = m.nodes[0].PublicIP
 	}
 
-	if initialIP == pulumi.String("").ToStringOutput() {
+	if initialIP == nil {
 		return fmt.Errorf("no nodes available for wildcard record")
 	}
 
 	// Create wildcard record for all ingress subdomains
-	wildcardRecord, err := digitalocean.NewDnsRecord(m.ctx, "dns-wildcard-ingress", &digitalocean.DnsRecordArgs{
-		Domain: pulumi.String(m.domain),
-		Type:   pulumi.String("A"),
-		Name:   pulumi.String("*.k8s"),
-		Value:  initialIP,
-		Ttl:    pulumi.Int(300),
-	})
-	if err != nil {
+	if err := m.upsertARecord("dns-wildcard-ingress", "*.k8s", initialIP, true); err != nil {
 		return err
 	}
-	m.records = append(m.records, wildcardRecord)
 
 	// Create specific ingress record
-	ingressRecord, err := digitalocean.NewDnsRecord(m.ctx, "dns-kube-ingress", &digitalocean.DnsRecordArgs{
-		Domain: pulumi.String(m.domain),
-		Type:   pulumi.String("A"),
-		Name:   pulumi.String("kube-ingress"),
-		Value:  initialIP,
-		Ttl:    pulumi.Int(300),
-	})
-	if err != nil {
+	if err := m.upsertARecord("dns-kube-ingress", "kube-ingress", initialIP, true); err != nil {
 		return err
 	}
-	m.records = append(m.records, ingressRecord)
 
 	m.ctx.Export("ingress_domain", pulumi.String(fmt.Sprintf("kube-ingress.%s", m.domain)))
 	m.ctx.Export("wildcard_domain", pulumi.String(fmt.Sprintf("*.k8s.%s", m.domain)))
@@ -204,8 +354,33 @@ func (m *Manager) createWildcardRecord() error {
 	return nil
 }
 
+// IngressSubdomains are the services UpdateIngressRecord creates a
+// <name>.k8s record for
+var IngressSubdomains = []string{
+	"grafana",
+	"prometheus",
+	"alertmanager",
+	"dashboard",
+	"argocd",
+	"jenkins",
+	"gitlab",
+	"registry",
+}
+
 // UpdateIngressRecord updates the DNS record for ingress after load balancer is created
 func (m *Manager) UpdateIngressRecord(ingressIP pulumi.StringOutput) error {
+	if m.zone != nil {
+		names := []string{"kube-ingress", "*.k8s"}
+		for _, subdomain := range IngressSubdomains {
+			names = append(names, fmt.Sprintf("%s.k8s", subdomain))
+		}
+		for _, name := range names {
+			m.upsertZoneRecord(DesiredRecord{Type: "A", Name: name, TTL: recordTTL, Ingress: true}, ingressIP)
+		}
+		m.exportSummary()
+		return nil
+	}
+
 	// Create or update the main ingress record
 	_, err := digitalocean.NewDnsRecord(m.ctx, "dns-ingress-lb", &digitalocean.DnsRecordArgs{
 		Domain: pulumi.String(m.domain),
@@ -231,18 +406,7 @@ func (m *Manager) UpdateIngressRecord(ingressIP pulumi.StringOutput) error {
 	}
 
 	// Create additional ingress subdomains
-	ingressSubdomains := []string{
-		"grafana",
-		"prometheus",
-		"alertmanager",
-		"dashboard",
-		"argocd",
-		"jenkins",
-		"gitlab",
-		"registry",
-	}
-
-	for _, subdomain := range ingressSubdomains {
+	for _, subdomain := range IngressSubdomains {
 		_, err = digitalocean.NewDnsRecord(m.ctx, fmt.Sprintf("dns-%s", subdomain), &digitalocean.DnsRecordArgs{
 			Domain: pulumi.String(m.domain),
 			Type:   pulumi.String("A"),
@@ -259,18 +423,38 @@ func (m *Manager) UpdateIngressRecord(ingressIP pulumi.StringOutput) error {
 	return nil
 }
 
+// ClusterRecords are the convenience CNAME records CreateClusterRecords
+// creates, by name and target
+var ClusterRecords = map[string]string{
+	"k8s":        "api",
+	"kubernetes": "api",
+	"cluster":    "api",
+	"rancher":    "kube-ingress",
+	"dashboard":  "kube-ingress",
+}
+
 // CreateClusterRecords creates convenience DNS records for the cluster
 func (m *Manager) CreateClusterRecords() error {
-	// Create CNAME records for convenience
-	conveniences := map[string]string{
-		"k8s":        "api",
-		"kubernetes": "api",
-		"cluster":    "api",
-		"rancher":    "kube-ingress",
-		"dashboard":  "kube-ingress",
+	if m.zone != nil {
+		names := make([]string, 0, len(ClusterRecords))
+		for name := range ClusterRecords {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			target := ClusterRecords[name]
+			m.upsertZoneRecord(DesiredRecord{
+				Type:    "CNAME",
+				Name:    name,
+				TTL:     recordTTL,
+				Ingress: target == "kube-ingress",
+			}, pulumi.String(fmt.Sprintf("%s.%s", target, m.domain)))
+		}
+		m.exportSummary()
+		return nil
 	}
 
-	for name, target := range conveniences {
+	for name, target := range ClusterRecords {
 		_, err := digitalocean.NewDnsRecord(m.ctx, fmt.Sprintf("dns-cname-%s", name), &digitalocean.DnsRecordArgs{
 			Domain: pulumi.String(m.domain),
 			Type:   pulumi.String("CNAME"),
@@ -0,0 +1,154 @@
+package dns
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/chalkan3/sloth-kubernetes/internal/common"
+	"github.com/digitalocean/godo"
+	"golang.org/x/oauth2"
+)
+
+// recordTTL is the TTL used for every record the manager creates
+const recordTTL = 300
+
+// RecordAction is what happened to a desired record during CreateNodeRecords
+type RecordAction string
+
+const (
+	RecordCreated   RecordAction = "created"
+	RecordUpdated   RecordAction = "updated"
+	RecordUnchanged RecordAction = "unchanged"
+)
+
+// ExistingRecord is a record already present at the DNS provider
+type ExistingRecord struct {
+	ID    string
+	Type  string
+	Name  string
+	Value string
+	TTL   int
+}
+
+// RecordProvider reads and updates records directly at the DNS provider, so
+// records that already exist are updated in place instead of conflicting
+type RecordProvider interface {
+	ListRecords(domain string) ([]ExistingRecord, error)
+	UpdateRecord(domain string, record ExistingRecord) error
+}
+
+// DesiredRecord is a record the manager wants at the DNS provider, named
+// relative to the domain
+type DesiredRecord struct {
+	Type  string
+	Name  string
+	Value string
+	TTL   int
+	// Ingress marks records that serve ingress traffic, which a provider
+	// with a proxy may route through it
+	Ingress bool
+}
+
+// ZoneProvider manages records directly through the DNS provider API, for
+// providers whose records are not Pulumi resources
+type ZoneProvider interface {
+	// UpsertRecord creates record, or updates the record of the same type
+	// and name when it differs. A dry run only plans the action.
+	UpsertRecord(domain string, record DesiredRecord, dryRun bool) (RecordAction, error)
+}
+
+// planRecordAction decides what to do with a desired record given the
+// existing one (nil when absent)
+func planRecordAction(existing *ExistingRecord, value string, ttl int) RecordAction {
+	if existing == nil {
+		return RecordCreated
+	}
+	if existing.Value == value && existing.TTL == ttl {
+		return RecordUnchanged
+	}
+	return RecordUpdated
+}
+
+// recordKey indexes records by type and name
+func recordKey(recordType, name string) string {
+	return recordType + " " + name
+}
+
+// digitalOceanRecordProvider implements RecordProvider with the DigitalOcean API
+type digitalOceanRecordProvider struct {
+	client *godo.Client
+}
+
+// NewDigitalOceanRecordProvider creates a RecordProvider for DigitalOcean DNS.
+// The HTTP client honors the proxy environment variables.
+func NewDigitalOceanRecordProvider(token string) (RecordProvider, error) {
+	if token == "" {
+		return nil, fmt.Errorf("DigitalOcean token is required for DNS record lookups")
+	}
+
+	httpClient, err := common.NewHTTPClient(30 * time.Second)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, httpClient)
+	tokenSource := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
+
+	return &digitalOceanRecordProvider{client: godo.NewClient(oauth2.NewClient(ctx, tokenSource))}, nil
+}
+
+// ListRecords returns every record of domain, following pagination
+func (p *digitalOceanRecordProvider) ListRecords(domain string) ([]ExistingRecord, error) {
+	ctx := context.Background()
+	opt := &godo.ListOptions{PerPage: 200}
+
+	var records []ExistingRecord
+	for {
+		page, resp, err := p.client.Domains.Records(ctx, domain, opt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list DNS records for %s: %w", domain, err)
+		}
+
+		for _, r := range page {
+			records = append(records, ExistingRecord{
+				ID:    strconv.Itoa(r.ID),
+				Type:  r.Type,
+				Name:  r.Name,
+				Value: r.Data,
+				TTL:   r.TTL,
+			})
+		}
+
+		if resp == nil || resp.Links == nil || resp.Links.IsLastPage() {
+			break
+		}
+		current, err := resp.Links.CurrentPage()
+		if err != nil {
+			return nil, fmt.Errorf("failed to paginate DNS records for %s: %w", domain, err)
+		}
+		opt.Page = current + 1
+	}
+
+	return records, nil
+}
+
+// UpdateRecord sets the value and TTL of an existing record
+func (p *digitalOceanRecordProvider) UpdateRecord(domain string, record ExistingRecord) error {
+	id, err := strconv.Atoi(record.ID)
+	if err != nil {
+		return fmt.Errorf("invalid DigitalOcean record ID %q: %w", record.ID, err)
+	}
+
+	_, _, err = p.client.Domains.EditRecord(context.Background(), domain, id, &godo.DomainRecordEditRequest{
+		Type: record.Type,
+		Name: record.Name,
+		Data: record.Value,
+		TTL:  record.TTL,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update DNS record %s: %w", record.Name, err)
+	}
+	return nil
+}
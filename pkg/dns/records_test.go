@@ -0,0 +1,166 @@
+package dns
+
+import (
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/providers"
+)
+
+// mockRecordProvider is an in-memory RecordProvider
+type mockRecordProvider struct {
+	mu        sync.Mutex
+	records   []ExistingRecord
+	listErr   error
+	listCalls int
+	updates   []ExistingRecord
+}
+
+func (p *mockRecordProvider) ListRecords(domain string) ([]ExistingRecord, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.listCalls++
+	if p.listErr != nil {
+		return nil, p.listErr
+	}
+	return append([]ExistingRecord(nil), p.records...), nil
+}
+
+func (p *mockRecordProvider) UpdateRecord(domain string, record ExistingRecord) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.updates = append(p.updates, record)
+	return nil
+}
+
+func singleMasterNodes() map[string][]*providers.NodeOutput {
+	return map[string][]*providers.NodeOutput{
+		"digitalocean": {
+			{
+				Name:      "master-1",
+				PublicIP:  pulumi.String("203.0.113.10").ToStringOutput(),
+				PrivateIP: pulumi.String("10.10.0.10").ToStringOutput(),
+				Labels:    map[string]string{"role": "master"},
+			},
+		},
+	}
+}
+
+// runUpsert runs CreateNodeRecords against provider and returns the resolved action per record
+func runUpsert(t *testing.T, provider RecordProvider, opts ...pulumi.RunOption) map[string]string {
+	t.Helper()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	actions := make(map[string]string)
+
+	opts = append(opts, pulumi.WithMocks("test-project", "test-stack", &DNSMocks{}))
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		manager := NewManager(ctx, "example.com")
+		if provider != nil {
+			manager.SetRecordProvider(provider)
+		}
+
+		if err := manager.CreateNodeRecords(singleMasterNodes()); err != nil {
+			return err
+		}
+
+		for _, result := range manager.Summary() {
+			name := result.Name
+			wg.Add(1)
+			result.Action.ApplyT(func(action string) string {
+				defer wg.Done()
+				mu.Lock()
+				actions[name] = action
+				mu.Unlock()
+				return action
+			})
+		}
+		return nil
+	}, opts...)
+	require.NoError(t, err)
+
+	wg.Wait()
+	return actions
+}
+
+func TestPlanRecordAction(t *testing.T) {
+	existing := &ExistingRecord{ID: "1", Type: "A", Name: "api", Value: "203.0.113.10", TTL: 300}
+
+	assert.Equal(t, RecordCreated, planRecordAction(nil, "203.0.113.10", 300))
+	assert.Equal(t, RecordUnchanged, planRecordAction(existing, "203.0.113.10", 300))
+	assert.Equal(t, RecordUpdated, planRecordAction(existing, "203.0.113.99", 300))
+	assert.Equal(t, RecordUpdated, planRecordAction(existing, "203.0.113.10", 3600))
+}
+
+func TestCreateNodeRecords_Upsert(t *testing.T) {
+	provider := &mockRecordProvider{
+		records: []ExistingRecord{
+			{ID: "101", Type: "A", Name: "master1", Value: "203.0.113.10", TTL: 300},
+			{ID: "102", Type: "A", Name: "api", Value: "198.51.100.1", TTL: 300},
+			{ID: "103", Type: "A", Name: "kube-ingress", Value: "203.0.113.10", TTL: 3600},
+			{ID: "104", Type: "CNAME", Name: "k8s-api", Value: "api.example.com.", TTL: 300},
+		},
+	}
+
+	actions := runUpsert(t, provider)
+
+	assert.Equal(t, "unchanged", actions["master1"])
+	assert.Equal(t, "updated", actions["api"])
+	assert.Equal(t, "updated", actions["kube-ingress"])
+	assert.Equal(t, "created", actions["k8s-api"], "a record of another type must not be matched")
+	assert.Equal(t, "created", actions["master1-digitalocean"])
+	assert.Equal(t, "created", actions["private-master-1"])
+	assert.Equal(t, "created", actions["*.k8s"])
+
+	assert.Equal(t, 1, provider.listCalls, "records should be listed once per domain")
+
+	require.Len(t, provider.updates, 2)
+	updated := make(map[string]ExistingRecord)
+	for _, record := range provider.updates {
+		updated[record.Name] = record
+	}
+	assert.Equal(t, ExistingRecord{ID: "102", Type: "A", Name: "api", Value: "203.0.113.10", TTL: 300}, updated["api"])
+	assert.Equal(t, ExistingRecord{ID: "103", Type: "A", Name: "kube-ingress", Value: "203.0.113.10", TTL: 300}, updated["kube-ingress"])
+}
+
+func TestCreateNodeRecords_UpsertDryRunDoesNotUpdate(t *testing.T) {
+	provider := &mockRecordProvider{
+		records: []ExistingRecord{
+			{ID: "102", Type: "A", Name: "api", Value: "198.51.100.1", TTL: 300},
+		},
+	}
+
+	dryRun := func(info *pulumi.RunInfo) { info.DryRun = true }
+	actions := runUpsert(t, provider, dryRun)
+
+	assert.Equal(t, "updated", actions["api"])
+	assert.Empty(t, provider.updates, "preview must not modify records")
+}
+
+func TestCreateNodeRecords_WithoutProviderCreatesAll(t *testing.T) {
+	actions := runUpsert(t, nil)
+
+	assert.NotEmpty(t, actions)
+	for name, action := range actions {
+		assert.Equal(t, "created", action, name)
+	}
+}
+
+func TestCreateNodeRecords_ListError(t *testing.T) {
+	provider := &mockRecordProvider{listErr: errors.New("api unavailable")}
+
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		manager := NewManager(ctx, "example.com")
+		manager.SetRecordProvider(provider)
+		return manager.CreateNodeRecords(singleMasterNodes())
+	}, pulumi.WithMocks("test-project", "test-stack", &DNSMocks{}))
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "api unavailable")
+}
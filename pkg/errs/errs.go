@@ -0,0 +1,79 @@
+// Package errs defines the error classes shared across sloth-kubernetes so
+// callers can tell failures apart with errors.Is and errors.As instead of
+// matching message strings.
+package errs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error classes. They are wrapped with %w, or attached with Mark, by the
+// code that detects the failure.
+var (
+	// ErrStackNotFound means the requested Pulumi stack does not exist
+	ErrStackNotFound = errors.New("stack not found")
+
+	// ErrNodeUnreachable means a cluster node could not be reached over SSH
+	ErrNodeUnreachable = errors.New("node unreachable")
+
+	// ErrProviderAuth means a cloud provider rejected the configured credentials
+	ErrProviderAuth = errors.New("provider authentication failed")
+
+	// ErrMeshDegraded means links of the WireGuard mesh are down
+	ErrMeshDegraded = errors.New("VPN mesh degraded")
+
+	// ErrQuorumRisk means an operation or topology would put etcd quorum at risk
+	ErrQuorumRisk = errors.New("etcd quorum at risk")
+)
+
+// NodeError reports a failure to reach a node. It matches ErrNodeUnreachable.
+type NodeError struct {
+	Node string
+	Err  error
+}
+
+// Unreachable returns a NodeError for node caused by err
+func Unreachable(node string, err error) error {
+	return &NodeError{Node: node, Err: err}
+}
+
+func (e *NodeError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("%s is unreachable", e.Node)
+	}
+	return fmt.Sprintf("%s is unreachable: %v", e.Node, e.Err)
+}
+
+// Unwrap returns the underlying cause
+func (e *NodeError) Unwrap() error {
+	return e.Err
+}
+
+// Is matches ErrNodeUnreachable
+func (e *NodeError) Is(target error) bool {
+	return target == ErrNodeUnreachable
+}
+
+// marked attaches an error class to an error without changing its message
+type marked struct {
+	err   error
+	class error
+}
+
+func (m *marked) Error() string {
+	return m.err.Error()
+}
+
+func (m *marked) Unwrap() []error {
+	return []error{m.err, m.class}
+}
+
+// Mark returns err classified as class, keeping its message. It returns nil
+// when err is nil.
+func Mark(err, class error) error {
+	if err == nil {
+		return nil
+	}
+	return &marked{err: err, class: class}
+}
@@ -0,0 +1,45 @@
+package errs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestMark(t *testing.T) {
+	cause := errors.New("ping 10.8.0.11 timed out")
+	err := fmt.Errorf("vpn test: %w", Mark(cause, ErrMeshDegraded))
+
+	if !errors.Is(err, ErrMeshDegraded) {
+		t.Error("Expected the marked error to match its class")
+	}
+	if !errors.Is(err, cause) {
+		t.Error("Expected the marked error to keep its cause")
+	}
+	if errors.Is(err, ErrQuorumRisk) {
+		t.Error("Expected the marked error not to match another class")
+	}
+	if err.Error() != "vpn test: ping 10.8.0.11 timed out" {
+		t.Errorf("Expected Mark to keep the message, got %q", err.Error())
+	}
+	if Mark(nil, ErrMeshDegraded) != nil {
+		t.Error("Expected Mark(nil) to be nil")
+	}
+}
+
+func TestNodeError(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := fmt.Errorf("vpn join: %w", Unreachable("master-1", cause))
+
+	if !errors.Is(err, ErrNodeUnreachable) || !errors.Is(err, cause) {
+		t.Errorf("Expected the node error to match ErrNodeUnreachable and its cause, got %v", err)
+	}
+
+	var nodeErr *NodeError
+	if !errors.As(err, &nodeErr) || nodeErr.Node != "master-1" {
+		t.Errorf("Expected errors.As to find the node, got %+v", nodeErr)
+	}
+	if err.Error() != "vpn join: master-1 is unreachable: connection refused" {
+		t.Errorf("Unexpected message: %q", err.Error())
+	}
+}
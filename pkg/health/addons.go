@@ -0,0 +1,186 @@
+package health
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AddonGate describes when an addon is ready for the addons and resources
+// that depend on it: its CRDs are established and its deployments available
+type AddonGate struct {
+	Name        string
+	Namespace   string
+	CRDs        []string
+	Deployments []string
+}
+
+// CertManagerGate is ready once cert-manager can accept issuers and
+// certificates, including the webhook that validates them
+var CertManagerGate = AddonGate{
+	Name:      "cert-manager",
+	Namespace: "cert-manager",
+	CRDs: []string{
+		"certificates.cert-manager.io",
+		"issuers.cert-manager.io",
+		"clusterissuers.cert-manager.io",
+	},
+	Deployments: []string{
+		"cert-manager",
+		"cert-manager-cainjector",
+		"cert-manager-webhook",
+	},
+}
+
+// ArgoCDGate is ready once ArgoCD in namespace can accept and sync
+// applications
+func ArgoCDGate(namespace string) AddonGate {
+	return AddonGate{
+		Name:      "argocd",
+		Namespace: namespace,
+		CRDs: []string{
+			"applications.argoproj.io",
+			"appprojects.argoproj.io",
+		},
+		Deployments: []string{
+			"argocd-server",
+			"argocd-repo-server",
+		},
+	}
+}
+
+// CheckScript prints one line per CRD and deployment of the gate, read by
+// Pending: CRD:<name>:<Established status> and
+// DEPLOYMENT:<name>:<available>/<desired>
+func (g AddonGate) CheckScript() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("\n# %s readiness checks\n", g.Name))
+	for _, crd := range g.CRDs {
+		b.WriteString(fmt.Sprintf(`echo "CRD:%[1]s:$(kubectl get crd %[1]s -o jsonpath='{.status.conditions[?(@.type=="Established")].status}' 2>/dev/null || echo Missing)"`+"\n", crd))
+	}
+	for _, deployment := range g.Deployments {
+		b.WriteString(fmt.Sprintf(`echo "DEPLOYMENT:%[1]s:$(kubectl get deployment %[1]s -n %[2]s -o jsonpath='{.status.availableReplicas}/{.spec.replicas}' 2>/dev/null || echo Missing)"`+"\n", deployment, g.Namespace))
+	}
+	return b.String()
+}
+
+// Pending returns what the output of CheckScript shows is not ready yet,
+// nothing once the addon is ready
+func (g AddonGate) Pending(output string) []string {
+	values := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		if len(parts) == 3 {
+			values[parts[0]+":"+parts[1]] = parts[2]
+		}
+	}
+
+	var pending []string
+	for _, crd := range g.CRDs {
+		if values["CRD:"+crd] != "True" {
+			pending = append(pending, fmt.Sprintf("CRD %s not established", crd))
+		}
+	}
+	for _, deployment := range g.Deployments {
+		value, ok := values["DEPLOYMENT:"+deployment]
+		if !ok || !replicasAvailable(value) {
+			pending = append(pending, fmt.Sprintf("deployment %s/%s not available", g.Namespace, deployment))
+		}
+	}
+	return pending
+}
+
+// WaitScript blocks a remote install script until the gate is ready, failing
+// it after timeout
+func (g AddonGate) WaitScript(timeout time.Duration) string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("\n# Wait for %s to be ready\n", g.Name))
+	b.WriteString(fmt.Sprintf("echo \"Waiting for %s to be ready...\"\n", g.Name))
+	b.WriteString(fmt.Sprintf("DEADLINE=$(( $(date +%%s) + %d ))\n", int(timeout.Seconds())))
+	var conditions []string
+	for _, crd := range g.CRDs {
+		conditions = append(conditions, fmt.Sprintf("kubectl wait --for=condition=Established crd/%s --timeout=10s >/dev/null 2>&1", crd))
+	}
+	for _, deployment := range g.Deployments {
+		conditions = append(conditions, fmt.Sprintf("kubectl wait --for=condition=Available deployment/%s -n %s --timeout=10s >/dev/null 2>&1", deployment, g.Namespace))
+	}
+	b.WriteString("until " + strings.Join(conditions, " && \\\n  ") + "; do\n")
+	b.WriteString("  if [ $(date +%s) -ge $DEADLINE ]; then\n")
+	b.WriteString(fmt.Sprintf("    echo \"%s not ready after %s\"\n", g.Name, timeout))
+	b.WriteString("    exit 1\n")
+	b.WriteString("  fi\n")
+	b.WriteString("  sleep 5\n")
+	b.WriteString("done\n")
+	b.WriteString(fmt.Sprintf("echo \"%s is ready\"\n", g.Name))
+	return b.String()
+}
+
+// WaitForAddonReady runs the check script of gate through run every
+// interval until the addon is ready, or fails with what is still pending
+// after timeout
+func WaitForAddonReady(gate AddonGate, run func(script string) (string, error), interval, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	script := gate.CheckScript()
+
+	for {
+		output, err := run(script)
+		pending := gate.Pending(output)
+		if err == nil && len(pending) == 0 {
+			return nil
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			if err != nil {
+				return fmt.Errorf("%s not ready after %s: %w", gate.Name, timeout, err)
+			}
+			return fmt.Errorf("%s not ready after %s: %s", gate.Name, timeout, strings.Join(pending, ", "))
+		}
+		time.Sleep(interval)
+	}
+}
+
+// WaitForCertManagerReady waits until cert-manager accepts issuers and
+// certificates, running kubectl through run
+func (h *HealthChecker) WaitForCertManagerReady(run func(script string) (string, error)) error {
+	h.logInfo("Waiting for cert-manager to be ready")
+	return WaitForAddonReady(CertManagerGate, run, h.checkInterval, h.timeout)
+}
+
+// WaitForArgoCDReady waits until ArgoCD in namespace accepts applications,
+// running kubectl through run
+func (h *HealthChecker) WaitForArgoCDReady(namespace string, run func(script string) (string, error)) error {
+	h.logInfo("Waiting for ArgoCD to be ready")
+	return WaitForAddonReady(ArgoCDGate(namespace), run, h.checkInterval, h.timeout)
+}
+
+// logInfo logs to the Pulumi context, when the checker runs inside one
+func (h *HealthChecker) logInfo(message string) {
+	if h.ctx != nil {
+		h.ctx.Log.Info(message, nil)
+	}
+}
+
+// logWarn logs a warning to the Pulumi context, when the checker runs
+// inside one
+func (h *HealthChecker) logWarn(message string) {
+	if h.ctx != nil {
+		h.ctx.Log.Warn(message, nil)
+	}
+}
+
+// replicasAvailable reports whether an <available>/<desired> replica count
+// has every desired replica available
+func replicasAvailable(value string) bool {
+	available, desired, ok := strings.Cut(value, "/")
+	if !ok {
+		return false
+	}
+	want, err := strconv.Atoi(desired)
+	if err != nil || want == 0 {
+		return false
+	}
+	// availableReplicas is omitted while none are available
+	have, _ := strconv.Atoi(available)
+	return have >= want
+}
@@ -0,0 +1,92 @@
+package health
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const certManagerInstalling = `
+CRD:certificates.cert-manager.io:True
+CRD:issuers.cert-manager.io:True
+CRD:clusterissuers.cert-manager.io:Missing
+DEPLOYMENT:cert-manager:1/1
+DEPLOYMENT:cert-manager-cainjector:1/1
+DEPLOYMENT:cert-manager-webhook:/1
+`
+
+const certManagerReady = `
+CRD:certificates.cert-manager.io:True
+CRD:issuers.cert-manager.io:True
+CRD:clusterissuers.cert-manager.io:True
+DEPLOYMENT:cert-manager:1/1
+DEPLOYMENT:cert-manager-cainjector:1/1
+DEPLOYMENT:cert-manager-webhook:1/1
+`
+
+func TestAddonGate_Pending(t *testing.T) {
+	assert.Equal(t, []string{
+		"CRD clusterissuers.cert-manager.io not established",
+		"deployment cert-manager/cert-manager-webhook not available",
+	}, CertManagerGate.Pending(certManagerInstalling))
+
+	assert.Empty(t, CertManagerGate.Pending(certManagerReady))
+}
+
+func TestAddonGate_PendingWithoutOutput(t *testing.T) {
+	gate := ArgoCDGate("argocd")
+	assert.Len(t, gate.Pending(""), len(gate.CRDs)+len(gate.Deployments))
+}
+
+func TestAddonGate_CheckScript(t *testing.T) {
+	script := ArgoCDGate("gitops").CheckScript()
+	assert.Contains(t, script, "kubectl get crd applications.argoproj.io")
+	assert.Contains(t, script, "kubectl get deployment argocd-server -n gitops")
+}
+
+func TestReplicasAvailable(t *testing.T) {
+	assert.True(t, replicasAvailable("2/2"))
+	assert.False(t, replicasAvailable("1/2"))
+	assert.False(t, replicasAvailable("/1"))
+	assert.False(t, replicasAvailable("0/0"))
+	assert.False(t, replicasAvailable("Missing"))
+}
+
+func TestWaitForCertManagerReady_PollsUntilReady(t *testing.T) {
+	checker := &HealthChecker{checkInterval: time.Millisecond, timeout: time.Second}
+	outputs := []string{"", certManagerInstalling, certManagerReady}
+
+	calls := 0
+	err := checker.WaitForCertManagerReady(func(script string) (string, error) {
+		output := outputs[calls]
+		calls++
+		return output, nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestWaitForArgoCDReady_TimesOut(t *testing.T) {
+	checker := &HealthChecker{checkInterval: time.Millisecond, timeout: 20 * time.Millisecond}
+
+	err := checker.WaitForArgoCDReady("argocd", func(script string) (string, error) {
+		return "CRD:applications.argoproj.io:True\n", nil
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "argocd not ready")
+	assert.Contains(t, err.Error(), "deployment argocd/argocd-server not available")
+}
+
+func TestWaitForAddonReady_ReturnsCommandError(t *testing.T) {
+	err := WaitForAddonReady(CertManagerGate, func(script string) (string, error) {
+		return "", errors.New("connection refused")
+	}, time.Millisecond, 5*time.Millisecond)
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "connection refused")
+}
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
 	"github.com/chalkan3/sloth-kubernetes/pkg/providers"
 	"github.com/pulumi/pulumi-command/sdk/go/command/remote"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
@@ -29,6 +30,12 @@ type HealthChecker struct {
 	mu            sync.RWMutex
 	checkInterval time.Duration
 	timeout       time.Duration
+
+	// kubernetesTimeout and kubernetesPollInterval bound WaitForKubernetesReady
+	kubernetesTimeout      time.Duration
+	kubernetesPollInterval time.Duration
+	// runCommand runs scripts on nodes; executeRemoteCommand when nil
+	runCommand func(node *providers.NodeOutput, script string) (string, error)
 }
 
 // NewHealthChecker creates a new health checker
@@ -39,6 +46,9 @@ func NewHealthChecker(ctx *pulumi.Context) *HealthChecker {
 		statuses:      make(map[string]*NodeStatus),
 		checkInterval: 10 * time.Second,
 		timeout:       5 * time.Minute,
+
+		kubernetesTimeout:      config.DefaultKubernetesReadyTimeout,
+		kubernetesPollInterval: config.DefaultKubernetesReadyPollInterval,
 	}
 }
 
@@ -271,11 +281,7 @@ check_port 2379
 check_port 2380
 `
 		case "nginx":
-			script += `
-# NGINX checks
-kubectl get svc -n ingress-nginx nginx-ingress-controller &>/dev/null && echo "NGINX:SERVICE:OK" || echo "NGINX:SERVICE:FAIL"
-kubectl get pods -n ingress-nginx -l app.kubernetes.io/name=ingress-nginx &>/dev/null && echo "NGINX:PODS:OK" || echo "NGINX:PODS:FAIL"
-`
+			script += IngressCheckScript
 		case "ssh":
 			script += `
 # SSH checks
@@ -342,7 +348,7 @@ func (h *HealthChecker) isServiceHealthy(output string, service string) bool {
 	case "kubelet":
 		return contains(output, "SERVICE:kubelet:RUNNING")
 	case "nginx":
-		return contains(output, "NGINX:SERVICE:OK") && contains(output, "NGINX:PODS:OK")
+		return IngressReady(output)
 	default:
 		return contains(output, fmt.Sprintf("SERVICE:%s:RUNNING", service))
 	}
@@ -426,19 +432,6 @@ func (h *HealthChecker) GetAllStatuses() map[string]*NodeStatus {
 	return result
 }
 
-// WaitForKubernetesReady waits specifically for Kubernetes to be ready
-func (h *HealthChecker) WaitForKubernetesReady() error {
-	requiredServices := []string{
-		"docker",
-		"kubelet",
-		"kubernetes",
-		"etcd",
-	}
-
-	h.ctx.Log.Info("Waiting for Kubernetes cluster to be ready", nil)
-	return h.WaitForNodesReady(requiredServices)
-}
-
 // WaitForIngressReady waits for ingress controller to be ready
 func (h *HealthChecker) WaitForIngressReady() error {
 	requiredServices := []string{
@@ -450,6 +443,21 @@ func (h *HealthChecker) WaitForIngressReady() error {
 	return h.WaitForNodesReady(requiredServices)
 }
 
+// IngressCheckScript checks the NGINX Ingress controller service and pods.
+// It expects kubectl to be configured and prints the markers read by
+// IngressReady.
+const IngressCheckScript = `
+# NGINX checks
+kubectl get svc -n ingress-nginx nginx-ingress-controller &>/dev/null && echo "NGINX:SERVICE:OK" || echo "NGINX:SERVICE:FAIL"
+kubectl get pods -n ingress-nginx -l app.kubernetes.io/name=ingress-nginx &>/dev/null && echo "NGINX:PODS:OK" || echo "NGINX:PODS:FAIL"
+`
+
+// IngressReady reports whether the output of IngressCheckScript shows a
+// ready ingress controller
+func IngressReady(output string) bool {
+	return contains(output, "NGINX:SERVICE:OK") && contains(output, "NGINX:PODS:OK")
+}
+
 // Helper functions
 
 func contains(s, substr string) bool {
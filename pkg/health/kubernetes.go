@@ -0,0 +1,272 @@
+package health
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/providers"
+)
+
+// kubectlScriptPrefix selects kubectl with the admin kubeconfig of an RKE2,
+// K3s or RKE server, as root
+const kubectlScriptPrefix = `SUDO=""
+[ "$(id -u)" -ne 0 ] && SUDO="sudo -n"
+if [ -f /etc/rancher/rke2/rke2.yaml ]; then
+  KUBECTL="$SUDO /var/lib/rancher/rke2/bin/kubectl --kubeconfig /etc/rancher/rke2/rke2.yaml"
+elif [ -f /etc/rancher/k3s/k3s.yaml ]; then
+  KUBECTL="$SUDO k3s kubectl"
+elif [ -f /root/kube_config_cluster.yml ]; then
+  KUBECTL="$SUDO kubectl --kubeconfig /root/kube_config_cluster.yml"
+else
+  KUBECTL="kubectl"
+fi
+`
+
+// KubernetesReadyCheckScript prints the lines read by
+// ParseKubernetesReadiness: READYZ:<ok|fail> for the /readyz endpoint of
+// the API server and NODE:<name>:<Ready condition status> per node
+const KubernetesReadyCheckScript = kubectlScriptPrefix + `
+if $KUBECTL get --raw /readyz >/dev/null 2>&1; then
+  echo "READYZ:ok"
+else
+  echo "READYZ:fail"
+fi
+$KUBECTL get nodes -o jsonpath='{range .items[*]}NODE:{.metadata.name}:{.status.conditions[?(@.type=="Ready")].status}{"\n"}{end}' 2>/dev/null || true
+`
+
+// KubernetesServicesScript prints SERVICE:<name>:<state> for the services a
+// node needs to join the cluster. Under RKE2 kubelet and containerd are
+// processes of the rke2 unit rather than units of their own, so a running
+// process counts.
+const KubernetesServicesScript = `for svc in kubelet containerd; do
+  state=$(systemctl is-active "$svc" 2>/dev/null)
+  if [ "$state" != "active" ] && pgrep -x "$svc" >/dev/null 2>&1; then
+    state="running"
+  fi
+  echo "SERVICE:$svc:${state:-unknown}"
+done
+for svc in rke2-server rke2-agent k3s k3s-agent; do
+  if systemctl cat "$svc" >/dev/null 2>&1; then
+    echo "SERVICE:$svc:$(systemctl is-active "$svc" 2>/dev/null)"
+  fi
+done
+`
+
+// KubernetesReadiness is what KubernetesReadyCheckScript saw
+type KubernetesReadiness struct {
+	APIReady bool
+	// Nodes maps the registered nodes to the status of their Ready
+	// condition: True, False or Unknown
+	Nodes map[string]string
+}
+
+// ParseKubernetesReadiness parses the output of KubernetesReadyCheckScript
+func ParseKubernetesReadiness(output string) KubernetesReadiness {
+	readiness := KubernetesReadiness{Nodes: make(map[string]string)}
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		switch {
+		case len(parts) == 2 && parts[0] == "READYZ":
+			readiness.APIReady = parts[1] == "ok"
+		case len(parts) == 3 && parts[0] == "NODE" && parts[1] != "":
+			status := parts[2]
+			if status == "" {
+				status = "Unknown"
+			}
+			readiness.Nodes[parts[1]] = status
+		}
+	}
+	return readiness
+}
+
+// Pending returns what is not ready yet: the API server, and each expected
+// node that is not registered or not Ready. Without expected nodes every
+// registered node must be Ready, and at least one registered.
+func (r KubernetesReadiness) Pending(expected []string) []string {
+	var pending []string
+	if !r.APIReady {
+		pending = append(pending, "API server /readyz failing")
+	}
+
+	if len(expected) == 0 {
+		if len(r.Nodes) == 0 {
+			return append(pending, "no nodes registered")
+		}
+		expected = r.nodeNames()
+	}
+	for _, name := range expected {
+		status, ok := r.Nodes[name]
+		switch {
+		case !ok:
+			pending = append(pending, fmt.Sprintf("node %s not registered", name))
+		case status != "True":
+			pending = append(pending, fmt.Sprintf("node %s not Ready (%s)", name, status))
+		}
+	}
+	return pending
+}
+
+// nodeNames returns the registered nodes, sorted
+func (r KubernetesReadiness) nodeNames() []string {
+	names := make([]string, 0, len(r.Nodes))
+	for name := range r.Nodes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WaitForKubernetesAPIReady runs KubernetesReadyCheckScript through run
+// every interval until the API server and the expected nodes are Ready, or
+// fails with what is still pending after timeout. It returns the last
+// readiness seen either way.
+func WaitForKubernetesAPIReady(run func(script string) (string, error), expected []string, interval, timeout time.Duration) (KubernetesReadiness, error) {
+	deadline := time.Now().Add(timeout)
+
+	for {
+		output, err := run(KubernetesReadyCheckScript)
+		readiness := ParseKubernetesReadiness(output)
+		pending := readiness.Pending(expected)
+		if err == nil && len(pending) == 0 {
+			return readiness, nil
+		}
+
+		if time.Now().Add(interval).After(deadline) {
+			if err != nil {
+				return readiness, fmt.Errorf("Kubernetes not ready after %s: %w", timeout, err)
+			}
+			return readiness, fmt.Errorf("Kubernetes not ready after %s: %s", timeout, strings.Join(pending, ", "))
+		}
+		time.Sleep(interval)
+	}
+}
+
+// parseServiceStates parses the output of KubernetesServicesScript
+func parseServiceStates(output string) map[string]string {
+	states := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		if len(parts) == 3 && parts[0] == "SERVICE" {
+			states[parts[1]] = parts[2]
+		}
+	}
+	return states
+}
+
+// SetKubernetesReadyWait sets how long WaitForKubernetesReady waits and how
+// often it checks. Zero values keep the defaults.
+func (h *HealthChecker) SetKubernetesReadyWait(timeout, interval time.Duration) {
+	if timeout > 0 {
+		h.kubernetesTimeout = timeout
+	}
+	if interval > 0 {
+		h.kubernetesPollInterval = interval
+	}
+}
+
+// SetCommandRunner replaces how scripts are run on nodes
+func (h *HealthChecker) SetCommandRunner(run func(node *providers.NodeOutput, script string) (string, error)) {
+	h.runCommand = run
+}
+
+// WaitForKubernetesReady waits until the API server answers /readyz and
+// every node is registered and Ready, checking with kubectl on the first
+// master. On timeout the last statuses seen, and the kubelet and
+// containerd states of every node, are logged and returned with the error.
+func (h *HealthChecker) WaitForKubernetesReady() error {
+	h.mu.RLock()
+	nodes := append([]*providers.NodeOutput(nil), h.nodes...)
+	h.mu.RUnlock()
+
+	var master *providers.NodeOutput
+	expected := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if master == nil && node.HasRole(config.RoleMaster) {
+			master = node
+		}
+		expected = append(expected, node.Name)
+	}
+	if master == nil {
+		return fmt.Errorf("no master node to check Kubernetes readiness from")
+	}
+
+	timeout, interval := h.kubernetesReadyWait()
+	h.logInfo(fmt.Sprintf("Waiting up to %s for the Kubernetes API and %d node(s) to be ready", timeout, len(expected)))
+
+	readiness, err := WaitForKubernetesAPIReady(func(script string) (string, error) {
+		return h.run(master, script)
+	}, expected, interval, timeout)
+	if err != nil {
+		report := h.kubernetesDiagnostics(readiness, expected, nodes)
+		h.logWarn(report)
+		return fmt.Errorf("%w\n%s", err, report)
+	}
+
+	h.logInfo("Kubernetes API and all nodes are ready")
+	return nil
+}
+
+// kubernetesDiagnostics describes the last readiness seen and the service
+// states gathered from every node
+func (h *HealthChecker) kubernetesDiagnostics(readiness KubernetesReadiness, expected []string, nodes []*providers.NodeOutput) string {
+	var b strings.Builder
+	api := "failing"
+	if readiness.APIReady {
+		api = "ok"
+	}
+	fmt.Fprintf(&b, "Last seen: API server /readyz %s\n", api)
+	for _, name := range expected {
+		if status, ok := readiness.Nodes[name]; ok {
+			fmt.Fprintf(&b, "  %s: Ready=%s\n", name, status)
+		} else {
+			fmt.Fprintf(&b, "  %s: not registered\n", name)
+		}
+	}
+
+	b.WriteString("Services:\n")
+	for _, node := range nodes {
+		output, err := h.run(node, KubernetesServicesScript)
+		if err != nil {
+			fmt.Fprintf(&b, "  %s: unreachable (%v)\n", node.Name, err)
+			continue
+		}
+		states := parseServiceStates(output)
+		names := make([]string, 0, len(states))
+		for name := range states {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		var parts []string
+		for _, name := range names {
+			parts = append(parts, name+"="+states[name])
+		}
+		if len(parts) == 0 {
+			parts = append(parts, "no state reported")
+		}
+		fmt.Fprintf(&b, "  %s: %s\n", node.Name, strings.Join(parts, " "))
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// kubernetesReadyWait returns the readiness timeout and poll interval
+func (h *HealthChecker) kubernetesReadyWait() (time.Duration, time.Duration) {
+	timeout, interval := h.kubernetesTimeout, h.kubernetesPollInterval
+	if timeout <= 0 {
+		timeout = config.DefaultKubernetesReadyTimeout
+	}
+	if interval <= 0 {
+		interval = config.DefaultKubernetesReadyPollInterval
+	}
+	return timeout, interval
+}
+
+// run runs script on node with the configured runner, over SSH by default
+func (h *HealthChecker) run(node *providers.NodeOutput, script string) (string, error) {
+	if h.runCommand != nil {
+		return h.runCommand(node, script)
+	}
+	return h.executeRemoteCommand(node, script)
+}
@@ -0,0 +1,114 @@
+package health
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/providers"
+)
+
+const kubernetesJoining = `READYZ:ok
+NODE:master-1:True
+NODE:worker-1:False
+`
+
+const kubernetesReady = `READYZ:ok
+NODE:master-1:True
+NODE:worker-1:True
+NODE:worker-2:True
+`
+
+func TestParseKubernetesReadiness(t *testing.T) {
+	readiness := ParseKubernetesReadiness(kubernetesJoining + "NODE:worker-3:\n")
+	assert.True(t, readiness.APIReady)
+	assert.Equal(t, map[string]string{"master-1": "True", "worker-1": "False", "worker-3": "Unknown"}, readiness.Nodes)
+
+	assert.False(t, ParseKubernetesReadiness("READYZ:fail\n").APIReady)
+}
+
+func TestKubernetesReadiness_Pending(t *testing.T) {
+	expected := []string{"master-1", "worker-1", "worker-2"}
+	assert.Equal(t, []string{
+		"node worker-1 not Ready (False)",
+		"node worker-2 not registered",
+	}, ParseKubernetesReadiness(kubernetesJoining).Pending(expected))
+	assert.Empty(t, ParseKubernetesReadiness(kubernetesReady).Pending(expected))
+
+	assert.Equal(t, []string{"API server /readyz failing", "no nodes registered"}, ParseKubernetesReadiness("").Pending(nil))
+	assert.Equal(t, []string{"node worker-1 not Ready (False)"}, ParseKubernetesReadiness(kubernetesJoining).Pending(nil))
+}
+
+func TestKubernetesReadyCheckScript(t *testing.T) {
+	assert.Contains(t, KubernetesReadyCheckScript, "get --raw /readyz")
+	assert.Contains(t, KubernetesReadyCheckScript, "--kubeconfig /etc/rancher/rke2/rke2.yaml")
+	assert.Contains(t, KubernetesReadyCheckScript, `{.status.conditions[?(@.type=="Ready")].status}`)
+}
+
+func kubernetesTestNodes() []*providers.NodeOutput {
+	return []*providers.NodeOutput{
+		{Name: "master-1", Roles: []string{"master"}},
+		{Name: "worker-1", Roles: []string{"worker"}},
+		{Name: "worker-2", Roles: []string{"worker"}},
+	}
+}
+
+func TestWaitForKubernetesReady_PollsUntilReady(t *testing.T) {
+	checker := &HealthChecker{kubernetesTimeout: time.Second, kubernetesPollInterval: time.Millisecond}
+	checker.nodes = kubernetesTestNodes()
+
+	outputs := []string{"READYZ:fail\n", kubernetesJoining, kubernetesReady}
+	calls := 0
+	checker.SetCommandRunner(func(node *providers.NodeOutput, script string) (string, error) {
+		assert.Equal(t, "master-1", node.Name)
+		assert.Equal(t, KubernetesReadyCheckScript, script)
+		output := outputs[calls]
+		calls++
+		return output, nil
+	})
+
+	require.NoError(t, checker.WaitForKubernetesReady())
+	assert.Equal(t, 3, calls)
+}
+
+func TestWaitForKubernetesReady_TimeoutDumpsDiagnostics(t *testing.T) {
+	checker := &HealthChecker{}
+	checker.SetKubernetesReadyWait(20*time.Millisecond, 5*time.Millisecond)
+	checker.nodes = kubernetesTestNodes()
+
+	checker.SetCommandRunner(func(node *providers.NodeOutput, script string) (string, error) {
+		if script == KubernetesReadyCheckScript {
+			return kubernetesJoining, nil
+		}
+		switch node.Name {
+		case "worker-1":
+			return "SERVICE:kubelet:inactive\nSERVICE:containerd:running\nSERVICE:rke2-agent:failed\n", nil
+		case "worker-2":
+			return "", errors.New("connection refused")
+		}
+		return "SERVICE:kubelet:running\nSERVICE:containerd:running\nSERVICE:rke2-server:active\n", nil
+	})
+
+	err := checker.WaitForKubernetesReady()
+	require.Error(t, err)
+	for _, want := range []string{
+		"Kubernetes not ready after 20ms: node worker-1 not Ready (False), node worker-2 not registered",
+		"Last seen: API server /readyz ok",
+		"worker-1: Ready=False",
+		"worker-2: not registered",
+		"master-1: containerd=running kubelet=running rke2-server=active",
+		"worker-1: containerd=running kubelet=inactive rke2-agent=failed",
+		"worker-2: unreachable (connection refused)",
+	} {
+		assert.True(t, strings.Contains(err.Error(), want), "expected %q in:\n%s", want, err)
+	}
+}
+
+func TestWaitForKubernetesReady_RequiresMaster(t *testing.T) {
+	checker := &HealthChecker{nodes: []*providers.NodeOutput{{Name: "worker-1", Roles: []string{"worker"}}}}
+	assert.Error(t, checker.WaitForKubernetesReady())
+}
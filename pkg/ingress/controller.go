@@ -0,0 +1,422 @@
+package ingress
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/health"
+	"github.com/chalkan3/sloth-kubernetes/pkg/providers"
+	"github.com/pulumi/pulumi-command/sdk/go/command/remote"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	// certManagerTLSSecret is the secret cert-manager issues the ingress certificate into
+	certManagerTLSSecret = "kube-ingress-tls"
+
+	// customTLSSecret is the secret created from a custom certificate
+	customTLSSecret = "kube-ingress-custom-tls"
+
+	// ingressNamespace is the namespace of the sample ingress and its TLS secret
+	ingressNamespace = "default"
+
+	// addonReadyTimeout bounds the wait for cert-manager before its issuers
+	// are created
+	addonReadyTimeout = 5 * time.Minute
+)
+
+// Controller installs an ingress controller on the cluster, with TLS for its
+// ingresses from cert-manager or a custom certificate
+type Controller interface {
+	SetMasterNode(node *providers.NodeOutput)
+	SetSSHKeyPath(path string)
+	UseCustomCertificate(tlsCfg *config.TLSConfig) error
+	UsesCustomCertificate() bool
+	Install() (pulumi.StringOutput, error)
+	InstallCertManager() error
+	CreateTLSSecret() error
+	CreateSampleIngress() error
+	Class() string
+}
+
+// NewController creates the manager of the ingress controller selected by
+// ingressCfg, NGINX unless configured otherwise
+func NewController(ctx *pulumi.Context, ingressCfg *config.IngressConfig, domain string) (Controller, error) {
+	if err := config.ValidateIngressController(ingressCfg); err != nil {
+		return nil, err
+	}
+
+	switch ingressCfg.ControllerName() {
+	case config.IngressControllerTraefik:
+		m := NewTraefikIngressManager(ctx, domain)
+		m.class = ingressCfg.ClassName()
+		return m, nil
+	case config.IngressControllerHAProxy:
+		m := NewHAProxyIngressManager(ctx, domain)
+		m.class = ingressCfg.ClassName()
+		return m, nil
+	default:
+		m := NewNginxIngressManager(ctx, domain)
+		m.class = ingressCfg.ClassName()
+		return m, nil
+	}
+}
+
+// ingressBase holds what every ingress controller shares: the node it is
+// installed from, its ingress class and the TLS source of its ingresses
+type ingressBase struct {
+	ctx        *pulumi.Context
+	domain     string
+	masterNode *providers.NodeOutput
+	sshKeyPath string
+
+	// controller names the controller in resources and exports, name in logs
+	controller string
+	name       string
+
+	// class is the ingress class the controller serves, the controller name
+	// when empty
+	class string
+
+	// sslRedirectAnnotation makes the controller redirect HTTP to HTTPS
+	sslRedirectAnnotation string
+
+	// Custom certificate, bypassing cert-manager when tlsSecret is set
+	tlsSecret     string
+	customCertPEM []byte
+	customKeyPEM  []byte
+}
+
+// Class returns the ingress class the controller serves
+func (b *ingressBase) Class() string {
+	if b.class != "" {
+		return b.class
+	}
+	return b.controller
+}
+
+// SetMasterNode sets the master node for installation
+func (b *ingressBase) SetMasterNode(node *providers.NodeOutput) {
+	b.masterNode = node
+}
+
+// SetSSHKeyPath sets the SSH key path
+func (b *ingressBase) SetSSHKeyPath(path string) {
+	b.sshKeyPath = path
+}
+
+// ingressHost returns the host name of the cluster ingress
+func (b *ingressBase) ingressHost() string {
+	return fmt.Sprintf("kube-ingress.%s", b.domain)
+}
+
+// connection returns the SSH connection to the master node
+func (b *ingressBase) connection() *remote.ConnectionArgs {
+	return &remote.ConnectionArgs{
+		Host:       b.masterNode.PublicIP,
+		Port:       pulumi.Float64(22),
+		User:       pulumi.String(b.masterNode.SSHUser),
+		PrivateKey: pulumi.String(b.getSSHPrivateKey()),
+	}
+}
+
+// UseCustomCertificate serves ingress TLS from a user-supplied certificate
+// instead of cert-manager. Certificate files must match their key and cover
+// the ingress host; secret references are used as is.
+func (b *ingressBase) UseCustomCertificate(tlsCfg *config.TLSConfig) error {
+	if name, ok := tlsCfg.CustomCertSecret(); ok {
+		if name == "" {
+			return fmt.Errorf("custom certificate secret reference requires a name")
+		}
+		b.tlsSecret = name
+		return nil
+	}
+
+	certPEM, keyPEM, err := tlsCfg.LoadCustomCert()
+	if err != nil {
+		return err
+	}
+	cert, err := config.ParseCertKeyPair(certPEM, keyPEM)
+	if err != nil {
+		return err
+	}
+	if err := config.CertCoversDomains(cert, []string{b.ingressHost()}); err != nil {
+		return err
+	}
+
+	b.tlsSecret = customTLSSecret
+	b.customCertPEM = certPEM
+	b.customKeyPEM = keyPEM
+	return nil
+}
+
+// UsesCustomCertificate reports whether ingress TLS bypasses cert-manager
+func (b *ingressBase) UsesCustomCertificate() bool {
+	return b.tlsSecret != ""
+}
+
+// installController runs a controller install script on the master node and
+// returns the load balancer IP the script prints as INGRESS_IP:<ip>
+func (b *ingressBase) installController(create, update, del string) (pulumi.StringOutput, error) {
+	if b.masterNode == nil {
+		return pulumi.StringOutput{}, fmt.Errorf("master node not set")
+	}
+
+	b.ctx.Log.Info(fmt.Sprintf("Installing %s", b.name), nil)
+
+	install, err := remote.NewCommand(b.ctx, fmt.Sprintf("install-%s-ingress", b.controller), &remote.CommandArgs{
+		Connection: b.connection(),
+		Create:     pulumi.String(create),
+		Update:     pulumi.String(update),
+		Delete:     pulumi.String(del),
+	})
+	if err != nil {
+		return pulumi.StringOutput{}, fmt.Errorf("failed to install %s: %w", b.name, err)
+	}
+
+	// Extract the LoadBalancer IP from the output
+	ingressIP := install.Stdout.ApplyT(func(output string) string {
+		for _, line := range strings.Split(output, "\n") {
+			if strings.HasPrefix(line, "INGRESS_IP:") {
+				return strings.TrimPrefix(line, "INGRESS_IP:")
+			}
+		}
+		return ""
+	}).(pulumi.StringOutput)
+
+	// Export ingress information
+	b.ctx.Export(b.controller+"_ingress_installed", pulumi.Bool(true))
+	b.ctx.Export(b.controller+"_ingress_ip", ingressIP)
+	b.ctx.Export(b.controller+"_ingress_url", pulumi.Sprintf("http://%s", b.ingressHost()))
+	b.ctx.Export(b.controller+"_ingress_https_url", pulumi.Sprintf("https://%s", b.ingressHost()))
+
+	return ingressIP, nil
+}
+
+// loadBalancerIPScript waits for the controller service to get an external
+// address and prints it as INGRESS_IP:<ip>
+func loadBalancerIPScript(namespace, service string) string {
+	return fmt.Sprintf(`# Wait for the LoadBalancer to get an external IP
+echo "Waiting for LoadBalancer IP..."
+for i in {1..60}; do
+  INGRESS_IP=$(kubectl get svc %[2]s -n %[1]s -o jsonpath='{.status.loadBalancer.ingress[0].ip}' 2>/dev/null || echo "")
+  if [ ! -z "$INGRESS_IP" ]; then
+    echo "LoadBalancer IP: $INGRESS_IP"
+    break
+  fi
+  echo "Waiting for LoadBalancer IP... ($i/60)"
+  sleep 10
+done
+
+# Get the final LoadBalancer IP
+INGRESS_IP=$(kubectl get svc %[2]s -n %[1]s -o jsonpath='{.status.loadBalancer.ingress[0].ip}' || \
+             kubectl get svc %[2]s -n %[1]s -o jsonpath='{.status.loadBalancer.ingress[0].hostname}' || \
+             echo "pending")
+
+echo "INGRESS_IP:$INGRESS_IP"
+`, namespace, service)
+}
+
+// InstallCertManager installs cert-manager for automatic TLS certificates
+func (b *ingressBase) InstallCertManager() error {
+	if b.masterNode == nil {
+		return fmt.Errorf("master node not set")
+	}
+
+	b.ctx.Log.Info("Installing cert-manager for TLS certificates", nil)
+
+	_, err := remote.NewCommand(b.ctx, "install-cert-manager", &remote.CommandArgs{
+		Connection: b.connection(),
+		Create:     pulumi.String(certManagerScript(b.domain, b.Class())),
+	})
+
+	if err != nil {
+		return fmt.Errorf("failed to install cert-manager: %w", err)
+	}
+
+	b.ctx.Export("cert_manager_installed", pulumi.Bool(true))
+
+	return nil
+}
+
+// certManagerScript installs cert-manager with Let's Encrypt issuers that
+// solve HTTP-01 challenges through the ingress class
+func certManagerScript(domain, class string) string {
+	return fmt.Sprintf(`
+#!/bin/bash
+set -e
+
+echo "Installing cert-manager..."
+
+export KUBECONFIG=/root/kube_config_cluster.yml
+
+# Add Jetstack Helm repository
+helm repo add jetstack https://charts.jetstack.io
+helm repo update
+
+# Create cert-manager namespace
+kubectl create namespace cert-manager --dry-run=client -o yaml | kubectl apply -f -
+
+# Install cert-manager CRDs
+kubectl apply -f https://github.com/cert-manager/cert-manager/releases/download/v1.13.0/cert-manager.crds.yaml
+
+# Install cert-manager
+helm upgrade --install cert-manager jetstack/cert-manager \
+  --namespace cert-manager \
+  --version v1.13.0 \
+  --set installCRDs=false \
+  --set global.leaderElection.namespace=cert-manager \
+  --wait
+%[3]s
+# Create ClusterIssuer for Let's Encrypt
+cat > /tmp/letsencrypt-issuer.yaml <<EOF
+apiVersion: cert-manager.io/v1
+kind: ClusterIssuer
+metadata:
+  name: letsencrypt-prod
+spec:
+  acme:
+    server: https://acme-v02.api.letsencrypt.org/directory
+    email: admin@%[1]s
+    privateKeySecretRef:
+      name: letsencrypt-prod
+    solvers:
+    - http01:
+        ingress:
+          class: %[2]s
+---
+apiVersion: cert-manager.io/v1
+kind: ClusterIssuer
+metadata:
+  name: letsencrypt-staging
+spec:
+  acme:
+    server: https://acme-staging-v02.api.letsencrypt.org/directory
+    email: admin@%[1]s
+    privateKeySecretRef:
+      name: letsencrypt-staging
+    solvers:
+    - http01:
+        ingress:
+          class: %[2]s
+EOF
+
+kubectl apply -f /tmp/letsencrypt-issuer.yaml
+
+echo "cert-manager installed successfully!"
+`, domain, class, health.CertManagerGate.WaitScript(addonReadyTimeout))
+}
+
+// CreateTLSSecret creates the ingress TLS secret from the custom certificate.
+// Nothing is created for secret references or without a custom certificate.
+func (b *ingressBase) CreateTLSSecret() error {
+	if b.customCertPEM == nil {
+		return nil
+	}
+	if b.masterNode == nil {
+		return fmt.Errorf("master node not set")
+	}
+
+	b.ctx.Log.Info("Creating ingress TLS secret from custom certificate", nil)
+
+	manifest := tlsSecretManifest(b.tlsSecret, ingressNamespace, b.customCertPEM, b.customKeyPEM)
+	script := fmt.Sprintf(`
+#!/bin/bash
+set -e
+
+export KUBECONFIG=/root/kube_config_cluster.yml
+
+kubectl apply -f - <<'EOF'
+%sEOF
+
+echo "TLS secret %s created"
+`, manifest, b.tlsSecret)
+
+	_, err := remote.NewCommand(b.ctx, "create-ingress-tls-secret", &remote.CommandArgs{
+		Connection: b.connection(),
+		// The script embeds the private key
+		Create: pulumi.ToSecret(pulumi.String(script)).(pulumi.StringOutput),
+		Delete: pulumi.String(fmt.Sprintf(`
+#!/bin/bash
+export KUBECONFIG=/root/kube_config_cluster.yml
+kubectl delete secret %s -n %s --ignore-not-found
+`, b.tlsSecret, ingressNamespace)),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create ingress TLS secret: %w", err)
+	}
+
+	return nil
+}
+
+// tlsSecretManifest returns a kubernetes.io/tls secret holding certPEM and keyPEM
+func tlsSecretManifest(name, namespace string, certPEM, keyPEM []byte) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Secret
+metadata:
+  name: %s
+  namespace: %s
+type: kubernetes.io/tls
+data:
+  tls.crt: %s
+  tls.key: %s
+`, name, namespace, base64.StdEncoding.EncodeToString(certPEM), base64.StdEncoding.EncodeToString(keyPEM))
+}
+
+// getSSHPrivateKey retrieves the SSH private key
+func (b *ingressBase) getSSHPrivateKey() string {
+	if b.sshKeyPath != "" {
+		// Read from file in production
+		return "SSH_PRIVATE_KEY_CONTENT"
+	}
+	return ""
+}
+
+// CreateSampleIngress creates a sample ingress resource
+func (b *ingressBase) CreateSampleIngress() error {
+	b.ctx.Export("sample_ingress_yaml", pulumi.String(b.sampleIngressYAML()))
+
+	return nil
+}
+
+// sampleIngressYAML returns the sample ingress for the controller's class.
+// Its certificate comes from the custom TLS secret when set, otherwise from
+// cert-manager.
+func (b *ingressBase) sampleIngressYAML() string {
+	secretName := certManagerTLSSecret
+	annotations := fmt.Sprintf(`    cert-manager.io/cluster-issuer: "letsencrypt-prod"
+    %s: "true"`, b.sslRedirectAnnotation)
+	if b.UsesCustomCertificate() {
+		secretName = b.tlsSecret
+		annotations = fmt.Sprintf(`    %s: "true"`, b.sslRedirectAnnotation)
+	}
+
+	return fmt.Sprintf(`
+apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: sample-ingress
+  annotations:
+%s
+spec:
+  ingressClassName: %s
+  tls:
+  - hosts:
+    - %s
+    secretName: %s
+  rules:
+  - host: %s
+    http:
+      paths:
+      - path: /
+        pathType: Prefix
+        backend:
+          service:
+            name: sample-service
+            port:
+              number: 80
+`, annotations, b.Class(), b.ingressHost(), secretName, b.ingressHost())
+}
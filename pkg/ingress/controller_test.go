@@ -0,0 +1,80 @@
+package ingress
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+func TestNewController_SelectsImplementation(t *testing.T) {
+	tests := []struct {
+		controller string
+		expected   Controller
+	}{
+		{"", &NginxIngressManager{}},
+		{"nginx", &NginxIngressManager{}},
+		{"traefik", &TraefikIngressManager{}},
+		{"HAProxy", &HAProxyIngressManager{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.controller, func(t *testing.T) {
+			controller, err := NewController(nil, &config.IngressConfig{Controller: tt.controller}, "example.com")
+			require.NoError(t, err)
+			assert.IsType(t, tt.expected, controller)
+		})
+	}
+}
+
+func TestNewController_RejectsUnknownController(t *testing.T) {
+	_, err := NewController(nil, &config.IngressConfig{Controller: "envoy"}, "example.com")
+	assert.ErrorContains(t, err, `unsupported ingress controller "envoy"`)
+}
+
+func TestNewController_CustomClass(t *testing.T) {
+	controller, err := NewController(nil, &config.IngressConfig{Controller: "traefik", Class: "public"}, "example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "public", controller.Class())
+}
+
+func TestSampleIngressYAML_ClassAnnotation(t *testing.T) {
+	tests := []struct {
+		controller string
+		class      string
+		annotation string
+	}{
+		{"nginx", "ingressClassName: nginx", `nginx.ingress.kubernetes.io/ssl-redirect: "true"`},
+		{"traefik", "ingressClassName: traefik", `traefik.ingress.kubernetes.io/router.tls: "true"`},
+		{"haproxy", "ingressClassName: haproxy", `haproxy.org/ssl-redirect: "true"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.controller, func(t *testing.T) {
+			controller, err := NewController(nil, &config.IngressConfig{Controller: tt.controller}, "example.com")
+			require.NoError(t, err)
+
+			yaml := controller.(interface{ sampleIngressYAML() string }).sampleIngressYAML()
+			assert.Contains(t, yaml, tt.class)
+			assert.Contains(t, yaml, tt.annotation)
+			assert.Contains(t, yaml, `cert-manager.io/cluster-issuer: "letsencrypt-prod"`)
+		})
+	}
+}
+
+func TestCertManagerScript_SolvesWithIngressClass(t *testing.T) {
+	script := certManagerScript("example.com", "traefik")
+	assert.Contains(t, script, "class: traefik")
+	assert.NotContains(t, script, "class: nginx")
+	assert.Contains(t, script, "email: admin@example.com")
+}
+
+func TestCertManagerScript_WaitsBeforeCreatingIssuers(t *testing.T) {
+	script := certManagerScript("example.com", "nginx")
+	wait := strings.Index(script, "crd/clusterissuers.cert-manager.io")
+	issuer := strings.Index(script, "kind: ClusterIssuer")
+	assert.True(t, wait >= 0 && wait < issuer, "cert-manager readiness gate must run before the ClusterIssuer is created")
+}
@@ -0,0 +1,94 @@
+package ingress
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// HAProxyIngressManager manages HAProxy Kubernetes Ingress Controller installation
+type HAProxyIngressManager struct {
+	ingressBase
+}
+
+// NewHAProxyIngressManager creates a new HAProxy ingress manager
+func NewHAProxyIngressManager(ctx *pulumi.Context, domain string) *HAProxyIngressManager {
+	return &HAProxyIngressManager{ingressBase{
+		ctx:                   ctx,
+		domain:                domain,
+		controller:            "haproxy",
+		name:                  "HAProxy Ingress Controller",
+		sslRedirectAnnotation: "haproxy.org/ssl-redirect",
+	}}
+}
+
+// Install installs the HAProxy Kubernetes Ingress Controller on the cluster
+func (h *HAProxyIngressManager) Install() (pulumi.StringOutput, error) {
+	create := fmt.Sprintf(`
+#!/bin/bash
+set -e
+
+echo "Installing HAProxy Ingress Controller..."
+
+# Wait for cluster to be ready
+export KUBECONFIG=/root/kube_config_cluster.yml
+kubectl wait --for=condition=Ready nodes --all --timeout=600s || true
+
+# Install Helm if not present
+if ! command -v helm &> /dev/null; then
+    echo "Installing Helm..."
+    curl https://raw.githubusercontent.com/helm/helm/main/scripts/get-helm-3 | bash
+fi
+
+# Add HAProxy Technologies repository
+helm repo add haproxytech https://haproxytech.github.io/helm-charts
+helm repo update
+
+# Create haproxy-controller namespace
+kubectl create namespace haproxy-controller --dry-run=client -o yaml | kubectl apply -f -
+
+# Install HAProxy Ingress Controller with custom values
+cat > /tmp/haproxy-ingress-values.yaml <<EOF
+controller:
+  replicaCount: 2
+
+  ingressClass: %s
+  ingressClassResource:
+    name: %s
+
+  service:
+    type: LoadBalancer
+    annotations:
+      service.beta.kubernetes.io/do-loadbalancer-hostname: "kube-ingress.%s"
+    externalTrafficPolicy: Local
+
+  resources:
+    limits:
+      memory: 512Mi
+    requests:
+      cpu: 100m
+      memory: 128Mi
+EOF
+
+# Install or upgrade HAProxy Ingress
+helm upgrade --install haproxy-ingress haproxytech/kubernetes-ingress \
+  --namespace haproxy-controller \
+  --values /tmp/haproxy-ingress-values.yaml \
+  --wait \
+  --timeout 10m
+
+%s
+echo "HAProxy Ingress Controller installed successfully!"
+echo "Ingress endpoint: kube-ingress.%s"
+echo "LoadBalancer IP: $INGRESS_IP"
+`, h.Class(), h.Class(), h.domain, loadBalancerIPScript("haproxy-controller", "haproxy-ingress-kubernetes-ingress"), h.domain)
+
+	return h.installController(create, `
+#!/bin/bash
+helm upgrade haproxy-ingress haproxytech/kubernetes-ingress --namespace haproxy-controller --reuse-values
+`, `
+#!/bin/bash
+helm uninstall haproxy-ingress --namespace haproxy-controller || true
+kubectl delete namespace haproxy-controller || true
+`)
+}
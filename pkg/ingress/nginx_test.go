@@ -31,9 +31,7 @@ func TestNewNginxIngressManager(t *testing.T) {
 
 // TestSetMasterNode tests master node setting
 func TestSetMasterNode(t *testing.T) {
-	manager := &NginxIngressManager{
-		domain: "example.com",
-	}
+	manager := NewNginxIngressManager(nil, "example.com")
 
 	// Initially nil
 	if manager.masterNode != nil {
@@ -59,9 +57,7 @@ func TestSetMasterNode(t *testing.T) {
 
 // TestSetSSHKeyPath tests SSH key path setting
 func TestSetSSHKeyPath(t *testing.T) {
-	manager := &NginxIngressManager{
-		domain: "example.com",
-	}
+	manager := NewNginxIngressManager(nil, "example.com")
 
 	tests := []struct {
 		name string
@@ -104,10 +100,8 @@ func TestGetSSHPrivateKey(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			manager := &NginxIngressManager{
-				domain:     "example.com",
-				sshKeyPath: tt.sshKeyPath,
-			}
+			manager := NewNginxIngressManager(nil, "example.com")
+			manager.sshKeyPath = tt.sshKeyPath
 
 			key := manager.getSSHPrivateKey()
 
@@ -622,10 +616,8 @@ func TestHelmRepositories(t *testing.T) {
 
 // TestNginxIngressManagerStructure tests manager structure
 func TestNginxIngressManagerStructure(t *testing.T) {
-	manager := &NginxIngressManager{
-		domain:     "example.com",
-		sshKeyPath: "/root/.ssh/id_rsa",
-	}
+	manager := NewNginxIngressManager(nil, "example.com")
+	manager.sshKeyPath = "/root/.ssh/id_rsa"
 
 	if manager.domain != "example.com" {
 		t.Errorf("Expected domain 'example.com', got %q", manager.domain)
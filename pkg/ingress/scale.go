@@ -0,0 +1,55 @@
+package ingress
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+const (
+	// ControllerNamespace is the namespace the NGINX Ingress Controller is installed into
+	ControllerNamespace = "ingress-nginx"
+
+	// ControllerSelector selects the controller deployment, its autoscaler and its pods
+	ControllerSelector = "app.kubernetes.io/name=ingress-nginx,app.kubernetes.io/component=controller"
+)
+
+// ValidateReplicas checks a requested controller replica count. A production
+// cluster always keeps at least one controller so ingress traffic is served.
+func ValidateReplicas(replicas int, production bool) error {
+	if replicas < 0 {
+		return fmt.Errorf("replicas must not be negative, got %d", replicas)
+	}
+	if replicas < 1 && production {
+		return fmt.Errorf("refusing to scale the ingress controller of a production cluster below 1 replica")
+	}
+	return nil
+}
+
+// ScalePatch returns the merge patch that sets the controller deployment
+// replica count
+func ScalePatch(replicas int) string {
+	patch := map[string]any{
+		"spec": map[string]any{
+			"replicas": replicas,
+		},
+	}
+	data, _ := json.Marshal(patch)
+	return string(data)
+}
+
+// AutoscalerPatch returns the merge patch that keeps the controller
+// autoscaler from undoing a scale to replicas. The minimum follows the
+// requested count and the maximum is raised to it when needed.
+func AutoscalerPatch(replicas, maxReplicas int) string {
+	if maxReplicas < replicas {
+		maxReplicas = replicas
+	}
+	patch := map[string]any{
+		"spec": map[string]any{
+			"minReplicas": replicas,
+			"maxReplicas": maxReplicas,
+		},
+	}
+	data, _ := json.Marshal(patch)
+	return string(data)
+}
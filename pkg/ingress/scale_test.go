@@ -0,0 +1,39 @@
+package ingress
+
+import "testing"
+
+func TestValidateReplicas(t *testing.T) {
+	tests := []struct {
+		name       string
+		replicas   int
+		production bool
+		wantErr    bool
+	}{
+		{"scale up", 3, true, false},
+		{"single replica in production", 1, true, false},
+		{"zero in production", 0, true, true},
+		{"zero outside production", 0, false, false},
+		{"negative", -1, false, true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateReplicas(tt.replicas, tt.production); (err != nil) != tt.wantErr {
+			t.Errorf("%s: ValidateReplicas() error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestScalePatch(t *testing.T) {
+	if got, want := ScalePatch(3), `{"spec":{"replicas":3}}`; got != want {
+		t.Errorf("ScalePatch(3) = %s, want %s", got, want)
+	}
+}
+
+func TestAutoscalerPatch(t *testing.T) {
+	if got, want := AutoscalerPatch(3, 4), `{"spec":{"maxReplicas":4,"minReplicas":3}}`; got != want {
+		t.Errorf("AutoscalerPatch(3, 4) = %s, want %s", got, want)
+	}
+	if got, want := AutoscalerPatch(6, 4), `{"spec":{"maxReplicas":6,"minReplicas":6}}`; got != want {
+		t.Errorf("AutoscalerPatch(6, 4) = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,126 @@
+package ingress
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/providers"
+)
+
+// writeCustomCert writes a self-signed certificate for names and its key,
+// returning a TLS config pointing at them
+func writeCustomCert(t *testing.T, names ...string) *config.TLSConfig {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: names[0]},
+		DNSNames:     names,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "tls.crt")
+	keyPath := filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	return &config.TLSConfig{CustomCert: certPath, CustomKey: keyPath}
+}
+
+func TestTLSSecretManifest(t *testing.T) {
+	manifest := tlsSecretManifest("corp-tls", "default", []byte("CERT"), []byte("KEY"))
+
+	assert.Contains(t, manifest, "kind: Secret")
+	assert.Contains(t, manifest, "type: kubernetes.io/tls")
+	assert.Contains(t, manifest, "name: corp-tls")
+	assert.Contains(t, manifest, "namespace: default")
+	assert.Contains(t, manifest, "tls.crt: "+base64.StdEncoding.EncodeToString([]byte("CERT")))
+	assert.Contains(t, manifest, "tls.key: "+base64.StdEncoding.EncodeToString([]byte("KEY")))
+}
+
+func TestUseCustomCertificate(t *testing.T) {
+	manager := NewNginxIngressManager(nil, "example.com")
+
+	require.NoError(t, manager.UseCustomCertificate(writeCustomCert(t, "*.example.com")))
+	assert.True(t, manager.UsesCustomCertificate())
+	assert.Equal(t, customTLSSecret, manager.tlsSecret)
+	assert.NotEmpty(t, manager.customCertPEM)
+	assert.NotEmpty(t, manager.customKeyPEM)
+}
+
+func TestUseCustomCertificate_HostNotCovered(t *testing.T) {
+	manager := NewNginxIngressManager(nil, "example.com")
+
+	err := manager.UseCustomCertificate(writeCustomCert(t, "example.org"))
+	assert.ErrorContains(t, err, "does not cover: kube-ingress.example.com")
+	assert.False(t, manager.UsesCustomCertificate())
+}
+
+func TestUseCustomCertificate_SecretReference(t *testing.T) {
+	manager := NewNginxIngressManager(nil, "example.com")
+
+	require.NoError(t, manager.UseCustomCertificate(&config.TLSConfig{CustomCert: "secret:corp-wildcard"}))
+	assert.Equal(t, "corp-wildcard", manager.tlsSecret)
+	assert.Nil(t, manager.customCertPEM, "Existing secrets should not be recreated")
+}
+
+func TestSampleIngressYAML_TLSSource(t *testing.T) {
+	manager := NewNginxIngressManager(nil, "example.com")
+
+	yaml := manager.sampleIngressYAML()
+	assert.Contains(t, yaml, `cert-manager.io/cluster-issuer: "letsencrypt-prod"`)
+	assert.Contains(t, yaml, "secretName: kube-ingress-tls")
+
+	manager.tlsSecret = "corp-wildcard"
+	yaml = manager.sampleIngressYAML()
+	assert.NotContains(t, yaml, "cert-manager.io/cluster-issuer")
+	assert.Contains(t, yaml, "secretName: corp-wildcard")
+	assert.Contains(t, yaml, "- kube-ingress.example.com")
+}
+
+func TestCreateTLSSecret(t *testing.T) {
+	tlsCfg := writeCustomCert(t, "*.example.com")
+
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		manager := NewNginxIngressManager(ctx, "example.com")
+
+		// Nothing to create without a custom certificate
+		assert.NoError(t, manager.CreateTLSSecret())
+
+		require.NoError(t, manager.UseCustomCertificate(tlsCfg))
+		assert.ErrorContains(t, manager.CreateTLSSecret(), "master node not set")
+
+		manager.SetMasterNode(&providers.NodeOutput{
+			Name:     "master-1",
+			PublicIP: pulumi.String("203.0.113.10").ToStringOutput(),
+			SSHUser:  "root",
+		})
+		return manager.CreateTLSSecret()
+	}, pulumi.WithMocks("test-project", "test-stack", &IngressMocks{}))
+
+	assert.NoError(t, err)
+}
@@ -0,0 +1,111 @@
+package ingress
+
+import (
+	"fmt"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// TraefikIngressManager manages Traefik ingress controller installation
+type TraefikIngressManager struct {
+	ingressBase
+}
+
+// NewTraefikIngressManager creates a new Traefik ingress manager
+func NewTraefikIngressManager(ctx *pulumi.Context, domain string) *TraefikIngressManager {
+	return &TraefikIngressManager{ingressBase{
+		ctx:        ctx,
+		domain:     domain,
+		controller: "traefik",
+		name:       "Traefik Ingress Controller",
+		// Traefik redirects HTTP to HTTPS on the web entrypoint, so serving
+		// the router over TLS is all an ingress needs
+		sslRedirectAnnotation: "traefik.ingress.kubernetes.io/router.tls",
+	}}
+}
+
+// Install installs the Traefik ingress controller on the cluster
+func (t *TraefikIngressManager) Install() (pulumi.StringOutput, error) {
+	create := fmt.Sprintf(`
+#!/bin/bash
+set -e
+
+echo "Installing Traefik Ingress Controller..."
+
+# Wait for cluster to be ready
+export KUBECONFIG=/root/kube_config_cluster.yml
+kubectl wait --for=condition=Ready nodes --all --timeout=600s || true
+
+# Install Helm if not present
+if ! command -v helm &> /dev/null; then
+    echo "Installing Helm..."
+    curl https://raw.githubusercontent.com/helm/helm/main/scripts/get-helm-3 | bash
+fi
+
+# Add Traefik repository
+helm repo add traefik https://traefik.github.io/charts
+helm repo update
+
+# Create traefik namespace
+kubectl create namespace traefik --dry-run=client -o yaml | kubectl apply -f -
+
+# Install Traefik with custom values
+cat > /tmp/traefik-values.yaml <<EOF
+deployment:
+  replicas: 2
+
+service:
+  type: LoadBalancer
+  annotations:
+    service.beta.kubernetes.io/do-loadbalancer-hostname: "kube-ingress.%s"
+  spec:
+    externalTrafficPolicy: Local
+
+ingressClass:
+  enabled: true
+  isDefaultClass: false
+  name: %s
+
+providers:
+  kubernetesIngress:
+    publishedService:
+      enabled: true
+
+ports:
+  web:
+    redirectTo:
+      port: websecure
+  websecure:
+    tls:
+      enabled: true
+
+resources:
+  limits:
+    memory: 512Mi
+  requests:
+    cpu: 100m
+    memory: 128Mi
+EOF
+
+# Install or upgrade Traefik
+helm upgrade --install traefik traefik/traefik \
+  --namespace traefik \
+  --values /tmp/traefik-values.yaml \
+  --wait \
+  --timeout 10m
+
+%s
+echo "Traefik Ingress Controller installed successfully!"
+echo "Ingress endpoint: kube-ingress.%s"
+echo "LoadBalancer IP: $INGRESS_IP"
+`, t.domain, t.Class(), loadBalancerIPScript("traefik", "traefik"), t.domain)
+
+	return t.installController(create, `
+#!/bin/bash
+helm upgrade traefik traefik/traefik --namespace traefik --reuse-values
+`, `
+#!/bin/bash
+helm uninstall traefik --namespace traefik || true
+kubectl delete namespace traefik || true
+`)
+}
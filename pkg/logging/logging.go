@@ -0,0 +1,231 @@
+// Package logging renders the user-facing output of sloth-kubernetes either
+// as colored text for people or as one JSON object per event for CI and
+// other automation.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// Format is an output format
+type Format string
+
+const (
+	// FormatText is colored, human-readable output
+	FormatText Format = "text"
+	// FormatJSON writes one JSON object per line and event
+	FormatJSON Format = "json"
+)
+
+// EnvFormat is the environment variable selecting the format when no flag
+// is given
+const EnvFormat = "SLOTH_LOG_FORMAT"
+
+// ParseFormat parses a format name. The empty string selects text.
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(strings.TrimSpace(name))) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON:
+		return FormatJSON, nil
+	}
+	return "", fmt.Errorf("invalid log format %q: expected text or json", name)
+}
+
+// Level is the severity of an event
+type Level string
+
+const (
+	LevelInfo  Level = "info"
+	LevelWarn  Level = "warn"
+	LevelError Level = "error"
+)
+
+// Event kinds. Plain messages have no kind.
+const (
+	EventPhaseStart    = "phase-start"
+	EventPhaseComplete = "phase-complete"
+	EventPhaseFailed   = "phase-failed"
+)
+
+// Event is a single log record in the JSON format
+type Event struct {
+	Time       time.Time `json:"time"`
+	Level      Level     `json:"level"`
+	Event      string    `json:"event,omitempty"`
+	Phase      string    `json:"phase,omitempty"`
+	Node       string    `json:"node,omitempty"`
+	Message    string    `json:"message"`
+	DurationMs int64     `json:"durationMs,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Logger writes events in its format. Loggers derived with WithPhase and
+// WithNode share the writer of their parent.
+type Logger struct {
+	out    *output
+	format Format
+	phase  string
+	node   string
+}
+
+// output serializes writes from loggers sharing a writer
+type output struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// now is stubbed by tests for stable timestamps
+var now = time.Now
+
+// New creates a logger writing to w
+func New(w io.Writer, format Format) *Logger {
+	return &Logger{out: &output{w: w}, format: format}
+}
+
+// Stdout writes to os.Stdout as it is at the time of the write, so output
+// follows callers that redirect os.Stdout
+var Stdout io.Writer = stdout{}
+
+type stdout struct{}
+
+func (stdout) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger = New(Stdout, FormatText)
+)
+
+// Default returns the logger used by the CLI and the orchestrator
+func Default() *Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+// SetDefault replaces the logger returned by Default
+func SetDefault(l *Logger) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultLogger = l
+}
+
+// Format returns the format of the logger
+func (l *Logger) Format() Format {
+	return l.format
+}
+
+// JSON reports whether the logger writes JSON
+func (l *Logger) JSON() bool {
+	return l.format == FormatJSON
+}
+
+// WithPhase returns a logger attaching phase to its events
+func (l *Logger) WithPhase(phase string) *Logger {
+	derived := *l
+	derived.phase = phase
+	return &derived
+}
+
+// WithNode returns a logger attaching node to its events
+func (l *Logger) WithNode(node string) *Logger {
+	derived := *l
+	derived.node = node
+	return &derived
+}
+
+// Header writes a section title
+func (l *Logger) Header(message string) {
+	l.log(LevelInfo, message, func(w io.Writer) {
+		fmt.Fprintln(w)
+		color.New(color.Bold, color.FgCyan).Fprintln(w, message)
+		fmt.Fprintln(w)
+	})
+}
+
+// Info writes an informational message
+func (l *Logger) Info(message string) {
+	l.log(LevelInfo, message, func(w io.Writer) {
+		color.New(color.FgCyan).Fprintln(w, message)
+	})
+}
+
+// Success writes a message about a completed step
+func (l *Logger) Success(message string) {
+	l.log(LevelInfo, message, func(w io.Writer) {
+		color.New(color.FgGreen).Fprintln(w, "✓ "+message)
+	})
+}
+
+// Warning writes a warning
+func (l *Logger) Warning(message string) {
+	l.log(LevelWarn, message, func(w io.Writer) {
+		color.New(color.FgYellow).Fprintln(w, message)
+	})
+}
+
+// Error writes an error
+func (l *Logger) Error(message string) {
+	l.log(LevelError, message, func(w io.Writer) {
+		color.New(color.FgRed).Fprintln(w, message)
+	})
+}
+
+// PhaseStart records the start of a deployment phase. Phase events are
+// only written in the JSON format; text output leaves progress reporting
+// to the caller.
+func (l *Logger) PhaseStart(phase string) {
+	if !l.JSON() {
+		return
+	}
+	l.write(Event{Level: LevelInfo, Event: EventPhaseStart, Phase: phase, Message: phase + " started"})
+}
+
+// PhaseComplete records the end of a deployment phase that took elapsed,
+// failed when err is set
+func (l *Logger) PhaseComplete(phase string, elapsed time.Duration, err error) {
+	if !l.JSON() {
+		return
+	}
+	event := Event{Level: LevelInfo, Event: EventPhaseComplete, Phase: phase, Message: phase + " completed", DurationMs: elapsed.Milliseconds()}
+	if err != nil {
+		event.Level, event.Event, event.Message, event.Error = LevelError, EventPhaseFailed, phase+" failed", err.Error()
+	}
+	l.write(event)
+}
+
+// log writes message as an event in the JSON format, or with text otherwise
+func (l *Logger) log(level Level, message string, text func(io.Writer)) {
+	if l.JSON() {
+		l.write(Event{Level: level, Message: strings.TrimSpace(message)})
+		return
+	}
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	text(l.out.w)
+}
+
+// write encodes event with the phase and node of the logger
+func (l *Logger) write(event Event) {
+	event.Time = now().UTC()
+	if event.Phase == "" {
+		event.Phase = l.phase
+	}
+	if event.Node == "" {
+		event.Node = l.node
+	}
+
+	l.out.mu.Lock()
+	defer l.out.mu.Unlock()
+	_ = json.NewEncoder(l.out.w).Encode(event)
+}
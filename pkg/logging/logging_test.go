@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func stubNow(t *testing.T) {
+	t.Helper()
+	original := now
+	now = func() time.Time { return time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC) }
+	t.Cleanup(func() { now = original })
+}
+
+func decodeEvents(t *testing.T, output string) []Event {
+	t.Helper()
+	var events []Event
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		var event Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("Expected one JSON object per line, got %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, want := range map[string]Format{"": FormatText, "text": FormatText, " JSON ": FormatJSON} {
+		got, err := ParseFormat(name)
+		if err != nil || got != want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("yaml"); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
+
+func TestLogger_JSON(t *testing.T) {
+	stubNow(t)
+	var buf bytes.Buffer
+	logger := New(&buf, FormatJSON)
+
+	logger.Header("🚀 Deploying cluster...")
+	logger.WithPhase("rke").WithNode("master-1").Success("RKE2 installed")
+	logger.Warning("bastion has no audit log")
+	logger.Error("deploy failed")
+
+	events := decodeEvents(t, buf.String())
+	if len(events) != 4 {
+		t.Fatalf("Expected 4 events, got %d:\n%s", len(events), buf.String())
+	}
+	if events[0].Message != "🚀 Deploying cluster..." || events[0].Level != LevelInfo || !events[0].Time.Equal(now()) {
+		t.Errorf("Unexpected header event: %+v", events[0])
+	}
+	if e := events[1]; e.Phase != "rke" || e.Node != "master-1" || e.Message != "RKE2 installed" {
+		t.Errorf("Expected phase and node on the event, got %+v", e)
+	}
+	if events[2].Level != LevelWarn || events[3].Level != LevelError {
+		t.Errorf("Unexpected levels: %s, %s", events[2].Level, events[3].Level)
+	}
+	if strings.Contains(buf.String(), `"node":""`) {
+		t.Errorf("Expected empty fields to be left out, got:\n%s", buf.String())
+	}
+}
+
+func TestLogger_PhaseEvents(t *testing.T) {
+	stubNow(t)
+	var buf bytes.Buffer
+	logger := New(&buf, FormatJSON)
+
+	logger.PhaseStart("networking")
+	logger.PhaseComplete("networking", 1500*time.Millisecond, nil)
+	logger.PhaseComplete("nodes", time.Second, errors.New("quota exceeded"))
+
+	events := decodeEvents(t, buf.String())
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+	if e := events[0]; e.Event != EventPhaseStart || e.Phase != "networking" {
+		t.Errorf("Unexpected start event: %+v", e)
+	}
+	if e := events[1]; e.Event != EventPhaseComplete || e.DurationMs != 1500 || e.Level != LevelInfo {
+		t.Errorf("Unexpected complete event: %+v", e)
+	}
+	if e := events[2]; e.Event != EventPhaseFailed || e.Level != LevelError || e.Error != "quota exceeded" {
+		t.Errorf("Unexpected failed event: %+v", e)
+	}
+}
+
+func TestLogger_Text(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&buf, FormatText)
+
+	logger.Info("100% done")
+	logger.Success("Pulumi stack configured")
+	logger.PhaseStart("networking")
+
+	output := buf.String()
+	for _, want := range []string{"100% done\n", "✓ Pulumi stack configured\n"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "{") || strings.Contains(output, "networking") {
+		t.Errorf("Expected no JSON or phase events in text output, got:\n%s", output)
+	}
+}
@@ -1,12 +1,17 @@
 package network
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"sort"
+	"strings"
+	"sync"
 
 	"github.com/chalkan3/sloth-kubernetes/pkg/config"
 	"github.com/chalkan3/sloth-kubernetes/pkg/providers"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"golang.org/x/sync/errgroup"
 )
 
 // Manager handles network orchestration across providers
@@ -15,6 +20,7 @@ type Manager struct {
 	providers map[string]providers.Provider
 	networks  map[string]*providers.NetworkOutput
 	ctx       *pulumi.Context
+	mu        sync.RWMutex // guards providers and networks
 }
 
 // NewManager creates a new network manager
@@ -29,6 +35,8 @@ func NewManager(ctx *pulumi.Context, config *config.NetworkConfig) *Manager {
 
 // RegisterProvider registers a provider for network management
 func (m *Manager) RegisterProvider(name string, provider providers.Provider) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
 	m.providers[name] = provider
 }
 
@@ -42,7 +50,9 @@ func (m *Manager) CreateNetworks() error {
 			return fmt.Errorf("failed to create network for %s: %w", name, err)
 		}
 
+		m.mu.Lock()
 		m.networks[name] = network
+		m.mu.Unlock()
 	}
 
 	// If cross-provider networking is enabled, create peering
@@ -55,44 +65,100 @@ func (m *Manager) CreateNetworks() error {
 	return nil
 }
 
-// CreateFirewalls creates firewall rules for nodes
+// CreateFirewalls creates firewall rules for nodes. Providers are independent,
+// so their firewalls are applied concurrently; every provider is attempted and
+// all failures are returned together.
 func (m *Manager) CreateFirewalls(nodes map[string][]*providers.NodeOutput) error {
-	for providerName, nodeList := range nodes {
-		provider, ok := m.providers[providerName]
-		if !ok {
-			return fmt.Errorf("provider %s not registered", providerName)
-		}
+	providerNames := make([]string, 0, len(nodes))
+	for providerName := range nodes {
+		providerNames = append(providerNames, providerName)
+	}
+	sort.Strings(providerNames)
+
+	errs := make([]error, len(providerNames))
+
+	var g errgroup.Group
+	for i, providerName := range providerNames {
+		nodeList := nodes[providerName]
+		g.Go(func() error {
+			// Record the error instead of returning it so one failing provider
+			// does not hide the others
+			errs[i] = m.createProviderFirewall(providerName, nodeList)
+			return nil
+		})
+	}
+	_ = g.Wait()
 
-		// Collect node IDs
-		nodeIds := make([]pulumi.IDOutput, len(nodeList))
-		for i, node := range nodeList {
-			nodeIds[i] = node.ID
-		}
+	return errors.Join(errs...)
+}
+
+// createProviderFirewall creates the firewall for the nodes of one provider
+func (m *Manager) createProviderFirewall(providerName string, nodeList []*providers.NodeOutput) error {
+	m.mu.RLock()
+	provider, ok := m.providers[providerName]
+	m.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("provider %s not registered", providerName)
+	}
 
-		// Create firewall config
-		firewallConfig := m.createFirewallConfig(providerName)
+	// Collect node IDs
+	nodeIds := make([]pulumi.IDOutput, len(nodeList))
+	for i, node := range nodeList {
+		nodeIds[i] = node.ID
+	}
 
-		if err := provider.CreateFirewall(m.ctx, firewallConfig, nodeIds); err != nil {
-			return fmt.Errorf("failed to create firewall for %s: %w", providerName, err)
-		}
+	firewallConfig := m.FirewallRuleSet(providerName)
+
+	if err := provider.CreateFirewall(m.ctx, firewallConfig, nodeIds); err != nil {
+		return fmt.Errorf("failed to create firewall for %s: %w", providerName, err)
 	}
 
 	return nil
 }
 
-// createFirewallConfig creates firewall configuration for a provider
-func (m *Manager) createFirewallConfig(providerName string) *config.FirewallConfig {
+// FirewallRuleSet returns the firewall rules of the nodes of a provider. It
+// is the single source of truth for both the cloud provider firewall and the
+// OS firewall of the nodes, so the two cannot allow different traffic.
+func (m *Manager) FirewallRuleSet(providerName string) *config.FirewallConfig {
+	m.mu.RLock()
+	network := m.networks[providerName]
+	m.mu.RUnlock()
+
+	networkCIDR := ""
+	if network != nil {
+		networkCIDR = network.CIDR
+	}
+	return NewFirewallRuleSet(m.ctx.Stack(), m.config, providerName, networkCIDR)
+}
+
+// NewFirewallRuleSet computes the firewall rules of the nodes of a provider
+// from the network config: traffic within the provider network, WireGuard,
+// Kubernetes and the custom rules. An empty networkCIDR leaves out the
+// provider network rules.
+func NewFirewallRuleSet(stack string, cfg *config.NetworkConfig, providerName, networkCIDR string) *config.FirewallConfig {
 	firewallConfig := &config.FirewallConfig{
-		Name:          fmt.Sprintf("%s-firewall", m.ctx.Stack()),
+		Name:          fmt.Sprintf("%s-firewall", stack),
 		InboundRules:  []config.FirewallRule{},
 		OutboundRules: []config.FirewallRule{},
 	}
 
+	// Allow all traffic within the private network of the provider
+	if networkCIDR != "" {
+		for _, protocol := range []string{"tcp", "udp"} {
+			firewallConfig.InboundRules = append(firewallConfig.InboundRules, config.FirewallRule{
+				Protocol:    protocol,
+				Port:        "1-65535",
+				Source:      []string{networkCIDR},
+				Description: fmt.Sprintf("Allow all %s from the %s network", strings.ToUpper(protocol), providerName),
+			})
+		}
+	}
+
 	// Add WireGuard rules if enabled
-	if m.config.WireGuard != nil && m.config.WireGuard.Enabled {
+	if cfg.WireGuard != nil && cfg.WireGuard.Enabled {
 		firewallConfig.InboundRules = append(firewallConfig.InboundRules, config.FirewallRule{
 			Protocol:    "udp",
-			Port:        fmt.Sprintf("%d", m.config.WireGuard.Port),
+			Port:        fmt.Sprintf("%d", cfg.WireGuard.Port),
 			Source:      []string{"0.0.0.0/0"},
 			Description: "WireGuard VPN",
 		})
@@ -101,25 +167,25 @@ func (m *Manager) createFirewallConfig(providerName string) *config.FirewallConf
 		firewallConfig.InboundRules = append(firewallConfig.InboundRules, config.FirewallRule{
 			Protocol:    "tcp",
 			Port:        "1-65535",
-			Source:      []string{"10.8.0.0/24"},
+			Source:      []string{cfg.WireGuard.Subnet()},
 			Description: "Allow all from WireGuard network",
 		})
 
 		firewallConfig.InboundRules = append(firewallConfig.InboundRules, config.FirewallRule{
 			Protocol:    "udp",
 			Port:        "1-65535",
-			Source:      []string{"10.8.0.0/24"},
+			Source:      []string{cfg.WireGuard.Subnet()},
 			Description: "Allow all UDP from WireGuard network",
 		})
 	}
 
 	// Add Kubernetes-specific rules
-	firewallConfig.InboundRules = append(firewallConfig.InboundRules, m.getKubernetesFirewallRules()...)
+	firewallConfig.InboundRules = append(firewallConfig.InboundRules, kubernetesFirewallRules(cfg)...)
 
 	// Add custom rules from config
-	if m.config.Firewall != nil {
-		firewallConfig.InboundRules = append(firewallConfig.InboundRules, m.config.Firewall.InboundRules...)
-		firewallConfig.OutboundRules = append(firewallConfig.OutboundRules, m.config.Firewall.OutboundRules...)
+	if cfg.Firewall != nil {
+		firewallConfig.InboundRules = append(firewallConfig.InboundRules, cfg.Firewall.InboundRules...)
+		firewallConfig.OutboundRules = append(firewallConfig.OutboundRules, cfg.Firewall.OutboundRules...)
 	}
 
 	return firewallConfig
@@ -127,6 +193,12 @@ func (m *Manager) createFirewallConfig(providerName string) *config.FirewallConf
 
 // getKubernetesFirewallRules returns Kubernetes-specific firewall rules
 func (m *Manager) getKubernetesFirewallRules() []config.FirewallRule {
+	return kubernetesFirewallRules(m.config)
+}
+
+// kubernetesFirewallRules returns the Kubernetes-specific firewall rules of
+// a network config
+func kubernetesFirewallRules(cfg *config.NetworkConfig) []config.FirewallRule {
 	rules := []config.FirewallRule{}
 
 	// Internal cluster communication (only from private networks)
@@ -137,6 +209,13 @@ func (m *Manager) getKubernetesFirewallRules() []config.FirewallRule {
 		Description: "Kubernetes API server",
 	})
 
+	rules = append(rules, config.FirewallRule{
+		Protocol:    "tcp",
+		Port:        "9345",
+		Source:      []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16"},
+		Description: "RKE2 supervisor",
+	})
+
 	rules = append(rules, config.FirewallRule{
 		Protocol:    "tcp",
 		Port:        "2379-2380",
@@ -181,11 +260,11 @@ func (m *Manager) getKubernetesFirewallRules() []config.FirewallRule {
 	})
 
 	// NodePort services (if enabled)
-	if m.config.EnableNodePorts {
+	if cfg.EnableNodePorts {
 		rules = append(rules, config.FirewallRule{
 			Protocol:    "tcp",
 			Port:        "30000-32767",
-			Source:      []string{"10.8.0.0/24"}, // Only from WireGuard
+			Source:      []string{cfg.WireGuard.Subnet()}, // Only from WireGuard
 			Description: "NodePort Services",
 		})
 	}
@@ -193,24 +272,74 @@ func (m *Manager) getKubernetesFirewallRules() []config.FirewallRule {
 	return rules
 }
 
-// createCrossProviderPeering creates network peering between providers
+// createCrossProviderPeering checks that provider networks can be routed to
+// each other over the WireGuard mesh and records the routes. The mesh carries
+// the traffic: each node routes the private IPs of nodes on other providers
+// through its WireGuard peer for that node.
 func (m *Manager) createCrossProviderPeering() error {
-	// This would implement VPC peering or VPN connections between providers
-	// For now, we rely on WireGuard for cross-provider connectivity
-	m.ctx.Log.Info("Cross-provider networking enabled via WireGuard", nil)
+	routes, err := m.CrossProviderRoutes()
+	if err != nil {
+		return err
+	}
+
+	providerNames := make([]string, 0, len(routes))
+	for provider := range routes {
+		providerNames = append(providerNames, provider)
+	}
+	sort.Strings(providerNames)
+
+	exported := pulumi.Map{}
+	for _, provider := range providerNames {
+		m.ctx.Log.Info(fmt.Sprintf("Cross-provider routes for %s via WireGuard: %v", provider, routes[provider]), nil)
+		exported[provider] = pulumi.ToStringArray(routes[provider])
+	}
+	m.ctx.Export("cross_provider_routes", exported)
 	return nil
 }
 
+// CrossProviderRoutes returns, for each provider, the private network CIDRs
+// of the other providers that its nodes reach over the WireGuard mesh. It
+// fails when two provider networks overlap, since an address in both could
+// not be routed to either.
+func (m *Manager) CrossProviderRoutes() (map[string][]string, error) {
+	m.mu.RLock()
+	cidrs := make(map[string]string, len(m.networks))
+	for provider, network := range m.networks {
+		if network != nil && network.CIDR != "" {
+			cidrs[provider] = network.CIDR
+		}
+	}
+	m.mu.RUnlock()
+
+	if err := config.ValidateProviderCIDRs(cidrs); err != nil {
+		return nil, err
+	}
+
+	routes := make(map[string][]string, len(cidrs))
+	for provider := range cidrs {
+		for other, cidr := range cidrs {
+			if other != provider {
+				routes[provider] = append(routes[provider], cidr)
+			}
+		}
+		sort.Strings(routes[provider])
+	}
+	return routes, nil
+}
+
 // GetNetworkByProvider returns the network output for a provider
 func (m *Manager) GetNetworkByProvider(provider string) (*providers.NetworkOutput, error) {
+	m.mu.RLock()
 	network, ok := m.networks[provider]
+	m.mu.RUnlock()
 	if !ok {
 		return nil, fmt.Errorf("network not found for provider %s", provider)
 	}
 	return network, nil
 }
 
-// ValidateCIDRs validates that network CIDRs don't overlap
+// ValidateCIDRs validates that network CIDRs don't overlap and that the
+// dual-stack pod and service CIDRs are IPv6
 func (m *Manager) ValidateCIDRs() error {
 	cidrs := []string{
 		m.config.CIDR,
@@ -224,6 +353,21 @@ func (m *Manager) ValidateCIDRs() error {
 		cidrs = append(cidrs, m.config.ServiceCIDR)
 	}
 
+	// Add the IPv6 Kubernetes CIDRs of a dual-stack cluster
+	for _, cidr := range []string{m.config.PodCIDRv6, m.config.ServiceCIDRv6} {
+		if cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return fmt.Errorf("invalid CIDR %s: %w", cidr, err)
+		}
+		if network.IP.To4() != nil {
+			return fmt.Errorf("invalid CIDR: %s is not an IPv6 CIDR", cidr)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+
 	// Check for overlaps
 	for i := 0; i < len(cidrs); i++ {
 		for j := i + 1; j < len(cidrs); j++ {
@@ -238,7 +382,8 @@ func (m *Manager) ValidateCIDRs() error {
 	return nil
 }
 
-// cidrOverlap checks if two CIDR ranges overlap
+// cidrOverlap checks if two CIDR ranges overlap. An IPv4 and an IPv6 range
+// never overlap.
 func cidrOverlap(cidr1, cidr2 string) (bool, error) {
 	_, net1, err := net.ParseCIDR(cidr1)
 	if err != nil {
@@ -250,10 +395,15 @@ func cidrOverlap(cidr1, cidr2 string) (bool, error) {
 		return false, fmt.Errorf("invalid CIDR %s: %w", cidr2, err)
 	}
 
+	if (net1.IP.To4() == nil) != (net2.IP.To4() == nil) {
+		return false, nil
+	}
+
 	return net1.Contains(net2.IP) || net2.Contains(net1.IP), nil
 }
 
-// AllocateNodeIPs allocates IPs for nodes within the network
+// AllocateNodeIPs allocates IPs for nodes within the network, which may be
+// IPv4 or IPv6
 func (m *Manager) AllocateNodeIPs(nodeCount int) ([]string, error) {
 	_, network, err := net.ParseCIDR(m.config.CIDR)
 	if err != nil {
@@ -279,8 +429,13 @@ func (m *Manager) AllocateNodeIPs(nodeCount int) ([]string, error) {
 	return ips, nil
 }
 
-// nextIP returns the next IP address
+// nextIP returns the next IP address. IPv4 addresses are incremented in their
+// 4-byte form so they roll over within IPv4, IPv6 addresses across all 16 bytes.
 func nextIP(ip net.IP) net.IP {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+
 	next := net.IP(make([]byte, len(ip)))
 	copy(next, ip)
 
@@ -309,6 +464,9 @@ func (m *Manager) GetDNSServers() []string {
 
 // ExportNetworkOutputs exports network information to Pulumi stack
 func (m *Manager) ExportNetworkOutputs() {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
 	for provider, network := range m.networks {
 		m.ctx.Export(fmt.Sprintf("%s_network_id", provider), network.ID)
 		m.ctx.Export(fmt.Sprintf("%s_network_cidr", provider), pulumi.String(network.CIDR))
@@ -324,6 +482,6 @@ func (m *Manager) ExportNetworkOutputs() {
 	if m.config.WireGuard != nil && m.config.WireGuard.Enabled {
 		m.ctx.Export("wireguard_enabled", pulumi.Bool(true))
 		m.ctx.Export("wireguard_port", pulumi.Int(m.config.WireGuard.Port))
-		m.ctx.Export("wireguard_network", pulumi.String("10.8.0.0/24"))
+		m.ctx.Export("wireguard_network", pulumi.String(m.config.WireGuard.Subnet()))
 	}
 }
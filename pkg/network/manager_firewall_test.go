@@ -0,0 +1,283 @@
+package network
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pulumi/pulumi-digitalocean/sdk/v4/go/digitalocean"
+	"github.com/pulumi/pulumi-linode/sdk/v4/go/linode"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/chalkan3/sloth-kubernetes/pkg/providers"
+	"github.com/chalkan3/sloth-kubernetes/pkg/security"
+)
+
+// firewallMocks implements pulumi.MockResourceMonitor for firewall tests
+type firewallMocks struct {
+	pulumi.MockResourceMonitor
+}
+
+func (m *firewallMocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	return args.Name + "_id", args.Inputs, nil
+}
+
+func (m *firewallMocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	return resource.PropertyMap{}, nil
+}
+
+// firewallProvider records CreateFirewall calls and optionally fails them
+type firewallProvider struct {
+	providers.Provider
+	name string
+	fail bool
+
+	mu    *sync.Mutex
+	calls map[string]int
+}
+
+func (p *firewallProvider) CreateFirewall(ctx *pulumi.Context, firewall *config.FirewallConfig, nodeIds []pulumi.IDOutput) error {
+	p.mu.Lock()
+	p.calls[p.name] = len(nodeIds)
+	p.mu.Unlock()
+
+	if p.fail {
+		return fmt.Errorf("%s API unavailable", p.name)
+	}
+	return nil
+}
+
+// TestCreateFirewalls_AttemptsAllProviders tests that a failing provider does
+// not stop the others and that all failures are reported
+func TestCreateFirewalls_AttemptsAllProviders(t *testing.T) {
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		manager := NewManager(ctx, &config.NetworkConfig{})
+
+		var mu sync.Mutex
+		calls := make(map[string]int)
+		for _, p := range []struct {
+			name string
+			fail bool
+		}{
+			{"digitalocean", true},
+			{"linode", false},
+			{"azure", true},
+		} {
+			manager.RegisterProvider(p.name, &firewallProvider{name: p.name, fail: p.fail, mu: &mu, calls: calls})
+		}
+
+		nodes := map[string][]*providers.NodeOutput{
+			"digitalocean": {{Name: "do-1"}, {Name: "do-2"}},
+			"linode":       {{Name: "linode-1"}},
+			"azure":        {{Name: "azure-1"}},
+		}
+
+		err := manager.CreateFirewalls(nodes)
+		if err == nil {
+			t.Fatal("Expected an error from the failing providers")
+		}
+
+		for name, count := range map[string]int{"digitalocean": 2, "linode": 1, "azure": 1} {
+			if calls[name] != count {
+				t.Errorf("Expected %s firewall with %d nodes, got %d", name, count, calls[name])
+			}
+		}
+
+		for _, want := range []string{"failed to create firewall for digitalocean", "failed to create firewall for azure"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("Expected error to contain %q, got %v", want, err)
+			}
+		}
+		if strings.Contains(err.Error(), "linode") {
+			t.Errorf("Linode succeeded and should not be reported, got %v", err)
+		}
+
+		return nil
+	}, pulumi.WithMocks("test-project", "test-stack", &firewallMocks{}))
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+// TestCreateFirewalls_UnregisteredProvider tests that unknown providers are
+// reported alongside firewalls that were created
+func TestCreateFirewalls_UnregisteredProvider(t *testing.T) {
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		manager := NewManager(ctx, &config.NetworkConfig{})
+
+		var mu sync.Mutex
+		calls := make(map[string]int)
+		manager.RegisterProvider("linode", &firewallProvider{name: "linode", mu: &mu, calls: calls})
+
+		err := manager.CreateFirewalls(map[string][]*providers.NodeOutput{
+			"linode": {{Name: "linode-1"}},
+			"gcp":    {{Name: "gcp-1"}},
+		})
+		if err == nil || !strings.Contains(err.Error(), "provider gcp not registered") {
+			t.Errorf("Expected unregistered provider error, got %v", err)
+		}
+		if calls["linode"] != 1 {
+			t.Error("Expected linode firewall to be created")
+		}
+
+		return nil
+	}, pulumi.WithMocks("test-project", "test-stack", &firewallMocks{}))
+
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
+// probe is a packet a firewall allows or denies
+type probe struct {
+	protocol string
+	port     int
+	source   string
+}
+
+// portSpecAllows reports whether a port or range, with sep between its
+// bounds, covers port
+func portSpecAllows(spec, sep string, port int) bool {
+	low, high, found := strings.Cut(spec, sep)
+	if !found {
+		high = low
+	}
+	var from, to int
+	fmt.Sscanf(low, "%d", &from)
+	fmt.Sscanf(high, "%d", &to)
+	return port >= from && port <= to
+}
+
+// sourceAllows reports whether a CIDR contains ip
+func sourceAllows(cidr, ip string) bool {
+	_, network, err := net.ParseCIDR(cidr)
+	return err == nil && network.Contains(net.ParseIP(ip))
+}
+
+// digitalOceanAllows evaluates DigitalOcean inbound rules
+func digitalOceanAllows(rules digitalocean.FirewallInboundRuleArray, p probe) bool {
+	for _, input := range rules {
+		rule := input.(*digitalocean.FirewallInboundRuleArgs)
+		if string(rule.Protocol.(pulumi.String)) != p.protocol || !portSpecAllows(string(rule.PortRange.(pulumi.String)), "-", p.port) {
+			continue
+		}
+		for _, source := range rule.SourceAddresses.(pulumi.StringArray) {
+			if sourceAllows(string(source.(pulumi.String)), p.source) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// linodeAllows evaluates Linode inbound rules
+func linodeAllows(rules linode.FirewallInboundArray, p probe) bool {
+	for _, input := range rules {
+		rule := input.(*linode.FirewallInboundArgs)
+		if string(rule.Protocol.(pulumi.String)) != strings.ToUpper(p.protocol) || !portSpecAllows(string(rule.Ports.(pulumi.String)), "-", p.port) {
+			continue
+		}
+		for _, source := range rule.Ipv4s.(pulumi.StringArray) {
+			if sourceAllows(string(source.(pulumi.String)), p.source) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ufwAllows evaluates the ufw allow commands the rule set renders to
+func ufwAllows(commands []string, p probe) bool {
+	for _, command := range commands {
+		fields := strings.Fields(command)
+		var source, port, protocol string
+		if fields[2] == "from" {
+			// ufw allow from SOURCE to any port PORT proto PROTOCOL
+			source, port, protocol = fields[3], fields[7], fields[9]
+		} else {
+			// ufw allow PORT/PROTOCOL
+			source = "0.0.0.0/0"
+			port, protocol, _ = strings.Cut(fields[2], "/")
+		}
+		if protocol == p.protocol && portSpecAllows(port, ":", p.port) && sourceAllows(source, p.source) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestFirewallRuleSet_CloudAndOSAgree tests that the rule set renders to cloud
+// firewall rules and ufw commands that allow and deny the same traffic
+func TestFirewallRuleSet_CloudAndOSAgree(t *testing.T) {
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		manager := NewManager(ctx, &config.NetworkConfig{
+			WireGuard:       &config.WireGuardConfig{Enabled: true, Port: 51820},
+			EnableNodePorts: true,
+			Firewall: &config.FirewallConfig{InboundRules: []config.FirewallRule{
+				{Protocol: "tcp", Port: "8080", Source: []string{"203.0.113.0/24", "198.51.100.7/32"}, Description: "Dashboard"},
+			}},
+		})
+		manager.networks["digitalocean"] = &providers.NetworkOutput{CIDR: "10.10.0.0/16"}
+
+		ruleSet := manager.FirewallRuleSet("digitalocean")
+		doRules := providers.DigitalOceanInboundRules(ruleSet.InboundRules)
+		linodeRules := providers.LinodeInboundRules(ruleSet.InboundRules)
+		ufwRules := security.UFWRules(ruleSet)
+
+		probes := []struct {
+			probe
+			allowed bool
+		}{
+			{probe{"udp", 51820, "198.18.0.1"}, true},   // WireGuard from anywhere
+			{probe{"tcp", 5432, "10.8.0.9"}, true},      // anything from the mesh
+			{probe{"tcp", 9100, "10.10.3.4"}, true},     // anything from the VPC
+			{probe{"tcp", 6443, "192.168.1.10"}, true},  // API server from a private network
+			{probe{"tcp", 6443, "198.18.0.1"}, false},   // API server from the internet
+			{probe{"tcp", 2380, "172.16.5.5"}, true},    // etcd peers
+			{probe{"tcp", 31000, "10.8.0.2"}, true},     // NodePorts over WireGuard
+			{probe{"tcp", 31000, "198.18.0.1"}, false},  // NodePorts from the internet
+			{probe{"tcp", 8080, "203.0.113.50"}, true},  // custom rule, first source
+			{probe{"tcp", 8080, "198.51.100.7"}, true},  // custom rule, second source
+			{probe{"tcp", 8080, "198.51.100.8"}, false}, // custom rule, other address
+			{probe{"udp", 8080, "203.0.113.50"}, false}, // custom rule, other protocol
+			{probe{"tcp", 22, "198.18.0.1"}, false},     // SSH from the internet
+			{probe{"udp", 8472, "10.200.0.1"}, true},    // Flannel VXLAN
+		}
+
+		for _, tc := range probes {
+			doAllows := digitalOceanAllows(doRules, tc.probe)
+			linodeAllowed := linodeAllows(linodeRules, tc.probe)
+			ufwAllowed := ufwAllows(ufwRules, tc.probe)
+			if doAllows != tc.allowed || linodeAllowed != tc.allowed || ufwAllowed != tc.allowed {
+				t.Errorf("%+v: DigitalOcean=%v Linode=%v ufw=%v, want %v",
+					tc.probe, doAllows, linodeAllowed, ufwAllowed, tc.allowed)
+			}
+		}
+		return nil
+	}, pulumi.WithMocks("project", "stack", &firewallMocks{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestNewFirewallRuleSet_WireGuardSubnet tests that the mesh rules follow a
+// custom WireGuard subnet
+func TestNewFirewallRuleSet_WireGuardSubnet(t *testing.T) {
+	ruleSet := NewFirewallRuleSet("prod", &config.NetworkConfig{
+		WireGuard:       &config.WireGuardConfig{Enabled: true, Port: 51820, SubnetCIDR: "10.100.0.0/24"},
+		EnableNodePorts: true,
+	}, "", "")
+	rules := providers.DigitalOceanInboundRules(ruleSet.InboundRules)
+
+	if !digitalOceanAllows(rules, probe{"tcp", 5432, "10.100.0.9"}) || !digitalOceanAllows(rules, probe{"tcp", 31000, "10.100.0.120"}) {
+		t.Error("Expected traffic from the custom WireGuard subnet to be allowed")
+	}
+	if digitalOceanAllows(rules, probe{"tcp", 31000, "10.8.0.2"}) {
+		t.Error("Expected the default WireGuard subnet not to be allowed")
+	}
+}
@@ -3,6 +3,7 @@ package network
 import (
 	"fmt"
 	"net"
+	"reflect"
 	"strings"
 	"testing"
 
@@ -161,6 +162,27 @@ func TestCIDROverlap(t *testing.T) {
 			wantOverlap: true,
 			wantError:   false,
 		},
+		{
+			name:        "Overlap - IPv6 one contains other",
+			cidr1:       "fd00:10:42::/48",
+			cidr2:       "fd00:10:42:1::/64",
+			wantOverlap: true,
+			wantError:   false,
+		},
+		{
+			name:        "No overlap - IPv6 different ranges",
+			cidr1:       "fd00:10:42::/56",
+			cidr2:       "fd00:10:43::/112",
+			wantOverlap: false,
+			wantError:   false,
+		},
+		{
+			name:        "No overlap - IPv4 and IPv6",
+			cidr1:       "0.0.0.0/0",
+			cidr2:       "::/0",
+			wantOverlap: false,
+			wantError:   false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -230,6 +252,54 @@ func TestValidateCIDRs(t *testing.T) {
 			wantError:     true,
 			errorContains: "invalid",
 		},
+		{
+			name: "Dual-stack without overlap",
+			config: &config.NetworkConfig{
+				CIDR:          "10.0.0.0/16",
+				PodCIDR:       "10.42.0.0/16",
+				ServiceCIDR:   "10.43.0.0/16",
+				PodCIDRv6:     "fd00:10:42::/56",
+				ServiceCIDRv6: "fd00:10:43::/112",
+			},
+			wantError: false,
+		},
+		{
+			name: "IPv6 pod and service CIDRs overlap",
+			config: &config.NetworkConfig{
+				CIDR:          "10.0.0.0/16",
+				PodCIDRv6:     "fd00:10::/32",
+				ServiceCIDRv6: "fd00:10:43::/112",
+			},
+			wantError:     true,
+			errorContains: "overlap",
+		},
+		{
+			name: "IPv6 network overlaps with IPv6 pod CIDR",
+			config: &config.NetworkConfig{
+				CIDR:      "fd00::/8",
+				PodCIDRv6: "fd00:10:42::/56",
+			},
+			wantError:     true,
+			errorContains: "overlap",
+		},
+		{
+			name: "IPv4 range in an IPv6 field",
+			config: &config.NetworkConfig{
+				CIDR:      "10.0.0.0/16",
+				PodCIDRv6: "10.42.0.0/16",
+			},
+			wantError:     true,
+			errorContains: "not an IPv6 CIDR",
+		},
+		{
+			name: "Invalid IPv6 CIDR",
+			config: &config.NetworkConfig{
+				CIDR:          "10.0.0.0/16",
+				ServiceCIDRv6: "fd00::zz/112",
+			},
+			wantError:     true,
+			errorContains: "invalid",
+		},
 	}
 
 	for _, tt := range tests {
@@ -287,6 +357,31 @@ func TestNextIP(t *testing.T) {
 			ip:   "10.0.0.0",
 			want: "10.0.0.1",
 		},
+		{
+			name: "IPv6 increment last group",
+			ip:   "fe80::1",
+			want: "fe80::2",
+		},
+		{
+			name: "IPv6 rollover last group",
+			ip:   "fe80::ffff",
+			want: "fe80::1:0",
+		},
+		{
+			name: "IPv6 rollover interface identifier in fe80::/10",
+			ip:   "fe80::ffff:ffff:ffff:ffff",
+			want: "fe80:0:0:1::",
+		},
+		{
+			name: "IPv6 rollover out of fe80::/10",
+			ip:   "febf:ffff:ffff:ffff:ffff:ffff:ffff:ffff",
+			want: "fec0::",
+		},
+		{
+			name: "IPv4 end of range stays IPv4",
+			ip:   "255.255.255.255",
+			want: "0.0.0.0",
+		},
 	}
 
 	for _, tt := range tests {
@@ -348,6 +443,20 @@ func TestAllocateNodeIPs(t *testing.T) {
 			wantError: false,
 			wantCount: 0,
 		},
+		{
+			name:      "Allocate 3 IPv6 IPs",
+			cidr:      "fd00:10::/64",
+			nodeCount: 3,
+			wantError: false,
+			wantCount: 3,
+		},
+		{
+			name:      "Too many IPs for /126 IPv6 network",
+			cidr:      "fd00:10::/126",
+			nodeCount: 2,
+			wantError: true,
+			wantCount: 0,
+		},
 	}
 
 	for _, tt := range tests {
@@ -853,14 +962,14 @@ func TestCreateFirewallConfig_WithWireGuard(t *testing.T) {
 	// Skip context-dependent parts by testing the logic directly
 	// We'll test the WireGuard rules creation
 
-	// Manually create firewall config similar to createFirewallConfig but without context
+	// Manually create firewall config similar to FirewallRuleSet but without context
 	firewallConfig := &config.FirewallConfig{
 		Name:          "test-firewall",
 		InboundRules:  []config.FirewallRule{},
 		OutboundRules: []config.FirewallRule{},
 	}
 
-	// Add WireGuard rules (same logic as in createFirewallConfig)
+	// Add WireGuard rules (same logic as in FirewallRuleSet)
 	if manager.config.WireGuard != nil && manager.config.WireGuard.Enabled {
 		firewallConfig.InboundRules = append(firewallConfig.InboundRules, config.FirewallRule{
 			Protocol:    "udp",
@@ -938,7 +1047,7 @@ func TestCreateFirewallConfig_WithoutWireGuard(t *testing.T) {
 		},
 	}
 
-	// Create firewall config manually (similar logic to createFirewallConfig)
+	// Create firewall config manually (similar logic to FirewallRuleSet)
 	firewallConfig := &config.FirewallConfig{
 		Name:          "test-firewall",
 		InboundRules:  []config.FirewallRule{},
@@ -1116,30 +1225,90 @@ func TestGetKubernetesFirewallRules_WithoutNodePorts(t *testing.T) {
 	}
 }
 
-// TestCreateCrossProviderPeering tests cross-provider peering config
-func TestCreateCrossProviderPeering(t *testing.T) {
+// TestCrossProviderRoutes tests the routes between distinct provider networks
+func TestCrossProviderRoutes(t *testing.T) {
 	manager := &Manager{
-		config: &config.NetworkConfig{
-			CrossProviderNetworking: true,
+		config: &config.NetworkConfig{CrossProviderNetworking: true},
+		networks: map[string]*providers.NetworkOutput{
+			"digitalocean": {CIDR: "10.10.0.0/16"},
+			"linode":       {CIDR: "10.20.0.0/16"},
+			"azure":        {CIDR: "172.16.0.0/16"},
 		},
 	}
 
-	// Test that the configuration is set correctly
-	// The actual createCrossProviderPeering method just logs and returns nil
-	// but requires Pulumi context, so we test the config instead
-	if !manager.config.CrossProviderNetworking {
-		t.Error("CrossProviderNetworking should be enabled")
+	routes, err := manager.CrossProviderRoutes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
 	}
 
-	// Test with cross-provider disabled
-	manager2 := &Manager{
-		config: &config.NetworkConfig{
-			CrossProviderNetworking: false,
+	expected := map[string][]string{
+		"digitalocean": {"10.20.0.0/16", "172.16.0.0/16"},
+		"linode":       {"10.10.0.0/16", "172.16.0.0/16"},
+		"azure":        {"10.10.0.0/16", "10.20.0.0/16"},
+	}
+	if !reflect.DeepEqual(routes, expected) {
+		t.Errorf("Expected routes %v, got %v", expected, routes)
+	}
+}
+
+// TestCrossProviderRoutes_Collision tests that overlapping provider networks are rejected
+func TestCrossProviderRoutes_Collision(t *testing.T) {
+	tests := []struct {
+		name     string
+		networks map[string]*providers.NetworkOutput
+		wantErr  string
+	}{
+		{
+			name: "same CIDR",
+			networks: map[string]*providers.NetworkOutput{
+				"digitalocean": {CIDR: "10.0.0.0/16"},
+				"linode":       {CIDR: "10.0.0.0/16"},
+			},
+			wantErr: "digitalocean network 10.0.0.0/16 overlaps linode network 10.0.0.0/16",
+		},
+		{
+			name: "nested CIDR",
+			networks: map[string]*providers.NetworkOutput{
+				"digitalocean": {CIDR: "10.0.0.0/8"},
+				"linode":       {CIDR: "10.20.0.0/16"},
+			},
+			wantErr: "overlaps",
+		},
+		{
+			name: "invalid CIDR",
+			networks: map[string]*providers.NetworkOutput{
+				"digitalocean": {CIDR: "not-a-cidr"},
+				"linode":       {CIDR: "10.20.0.0/16"},
+			},
+			wantErr: "invalid CIDR",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manager := &Manager{networks: tt.networks}
+			_, err := manager.CrossProviderRoutes()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestCrossProviderRoutes_SingleProvider tests that one provider needs no routes
+func TestCrossProviderRoutes_SingleProvider(t *testing.T) {
+	manager := &Manager{
+		networks: map[string]*providers.NetworkOutput{
+			"digitalocean": {CIDR: "10.10.0.0/16"},
 		},
 	}
 
-	if manager2.config.CrossProviderNetworking {
-		t.Error("CrossProviderNetworking should be disabled")
+	routes, err := manager.CrossProviderRoutes()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(routes["digitalocean"]) != 0 {
+		t.Errorf("Expected no routes for a single provider, got %v", routes)
 	}
 }
 
@@ -1514,8 +1683,8 @@ func TestFirewallRules_ComplexConfigurations(t *testing.T) {
 			},
 			validate: func(t *testing.T, rules []config.FirewallRule) {
 				// Should only have K8s default rules
-				if len(rules) != 7 {
-					t.Errorf("Expected 7 default K8s rules, got %d", len(rules))
+				if len(rules) != 8 {
+					t.Errorf("Expected 8 default K8s rules, got %d", len(rules))
 				}
 			},
 		},
@@ -1527,7 +1696,7 @@ func TestFirewallRules_ComplexConfigurations(t *testing.T) {
 				config: tt.config,
 			}
 
-			// Build firewall config manually (same logic as createFirewallConfig)
+			// Build firewall config manually (same logic as FirewallRuleSet)
 			firewallConfig := &config.FirewallConfig{
 				Name:          "test-firewall",
 				InboundRules:  []config.FirewallRule{},
@@ -6,11 +6,19 @@ import (
 	"sync"
 	"time"
 
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
 	"github.com/chalkan3/sloth-kubernetes/pkg/providers"
 	"github.com/pulumi/pulumi-command/sdk/go/command/remote"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
 
+// Mesh verification defaults
+const (
+	DefaultMeshCheckConcurrency = 10
+	DefaultMeshCheckAttempts    = 5
+	maxMeshCheckBackoff         = time.Minute
+)
+
 // VPNConnectivityChecker validates VPN connectivity between all nodes
 type VPNConnectivityChecker struct {
 	ctx           *pulumi.Context
@@ -20,6 +28,22 @@ type VPNConnectivityChecker struct {
 	mu            sync.RWMutex
 	checkInterval time.Duration
 	timeout       time.Duration
+	concurrency   int
+	maxAttempts   int
+	prober        func(source, target *providers.NodeOutput) *ConnectionStatus
+	onProgress    func(MeshProgress)
+}
+
+// MeshProgress is a snapshot of a running mesh verification
+type MeshProgress struct {
+	Total    int // Links in the mesh
+	Verified int // Links that connected
+	Failing  int // Links that ran out of attempts without connecting
+}
+
+// Done returns how many links finished testing
+func (p MeshProgress) Done() int {
+	return p.Verified + p.Failing
 }
 
 // ConnectivityResult represents the connectivity status from one node to all others
@@ -62,6 +86,8 @@ func NewVPNConnectivityChecker(ctx *pulumi.Context) *VPNConnectivityChecker {
 		results:       make(map[string]*ConnectivityResult),
 		checkInterval: 5 * time.Second,
 		timeout:       5 * time.Minute,
+		concurrency:   DefaultMeshCheckConcurrency,
+		maxAttempts:   DefaultMeshCheckAttempts,
 	}
 }
 
@@ -82,146 +108,196 @@ func (v *VPNConnectivityChecker) SetSSHKeyPath(path string) {
 	v.sshKeyPath = path
 }
 
-// VerifyFullMeshConnectivity verifies that all nodes can reach each other via WireGuard
-func (v *VPNConnectivityChecker) VerifyFullMeshConnectivity() error {
-	v.ctx.Log.Info("Starting VPN full mesh connectivity verification", nil)
+// SetConcurrency sets how many links are tested at once. Zero or less uses
+// DefaultMeshCheckConcurrency.
+func (v *VPNConnectivityChecker) SetConcurrency(concurrency int) {
+	v.concurrency = concurrency
+}
 
-	ctx, cancel := context.WithTimeout(context.Background(), v.timeout)
-	defer cancel()
+// SetMaxAttempts sets how many times a failing link is tested. The wait
+// between attempts starts at the check interval and doubles each time.
+func (v *VPNConnectivityChecker) SetMaxAttempts(attempts int) {
+	v.maxAttempts = attempts
+}
 
-	// Channel to collect results
-	resultChan := make(chan *ConnectivityResult, len(v.nodes))
-	errorChan := make(chan error, len(v.nodes))
+// SetProgressFunc sets a function called each time a link of the mesh
+// verification finishes testing. Calls are never concurrent.
+func (v *VPNConnectivityChecker) SetProgressFunc(fn func(MeshProgress)) {
+	v.onProgress = fn
+}
 
-	// Create a WaitGroup to track all goroutines
-	var wg sync.WaitGroup
+// VerifyFullMeshConnectivity verifies that all nodes can reach each other via
+// WireGuard. Each of the n*(n-1) links is tested by a bounded pool of workers,
+// and failing links are retried with exponential backoff before the mesh is
+// declared broken.
+func (v *VPNConnectivityChecker) VerifyFullMeshConnectivity() error {
+	v.ctx.Log.Info("Starting VPN full mesh connectivity verification", nil)
 
-	// Launch a goroutine for each node to check connectivity to all other nodes
-	for _, sourceNode := range v.nodes {
-		wg.Add(1)
-		go v.checkNodeConnectivity(ctx, &wg, sourceNode, resultChan, errorChan)
+	timeout := v.timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
 	}
-
-	// Monitor goroutine to wait for all checks to complete
-	go func() {
-		wg.Wait()
-		close(resultChan)
-		close(errorChan)
-	}()
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
 
 	// Status reporter goroutine
 	go v.reportConnectivityStatus(ctx)
 
-	// Collect results and check for full connectivity
-	allConnected := true
-	failedConnections := []string{}
-
-	for {
-		select {
-		case <-ctx.Done():
-			return fmt.Errorf("timeout waiting for VPN connectivity verification")
-
-		case err := <-errorChan:
-			if err != nil {
-				v.ctx.Log.Warn("Connectivity check error", nil)
-			}
-
-		case result, ok := <-resultChan:
-			if !ok {
-				// All results collected
-				if !allConnected {
-					return fmt.Errorf("VPN connectivity verification failed: %v", failedConnections)
-				}
+	v.mu.RLock()
+	nodes := make([]*providers.NodeOutput, len(v.nodes))
+	copy(nodes, v.nodes)
+	v.mu.RUnlock()
+
+	var progressMu sync.Mutex
+	progress := MeshProgress{Total: len(nodes) * (len(nodes) - 1)}
+	reportProgress := func(connected bool) {
+		progressMu.Lock()
+		defer progressMu.Unlock()
+
+		if connected {
+			progress.Verified++
+		} else {
+			progress.Failing++
+		}
+		if v.onProgress != nil {
+			v.onProgress(progress)
+		}
+	}
 
-				v.ctx.Log.Info("VPN full mesh connectivity verified successfully!", nil)
-				return nil
+	links := make(chan meshLink)
+	var wg sync.WaitGroup
+	for i := 0; i < v.meshConcurrency(len(nodes)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for link := range links {
+				status := v.checkLinkWithRetry(ctx, link)
+				v.recordConnection(link.source.Name, status)
+				reportProgress(status.IsConnected)
 			}
+		}()
+	}
 
-			// Update results
-			v.mu.Lock()
-			v.results[result.SourceNode] = result
-			v.mu.Unlock()
-
-			// Check if this node has full connectivity
-			if !result.AllConnected {
-				allConnected = false
-				for targetNode, conn := range result.Connections {
-					if !conn.IsConnected {
-						failedConnections = append(failedConnections,
-							fmt.Sprintf("%s -> %s", result.SourceNode, targetNode))
-					}
-				}
+	for _, source := range nodes {
+		for _, target := range nodes {
+			if source.Name != target.Name {
+				links <- meshLink{source: source, target: target}
 			}
+		}
+	}
+	close(links)
+	wg.Wait()
 
-			// Log successful connections
-			v.ctx.Log.Info("Node connectivity status", nil)
+	failedConnections := v.finalizeResults(nodes)
+	if len(failedConnections) > 0 {
+		if ctx.Err() != nil {
+			return errs.Mark(fmt.Errorf("timeout waiting for VPN connectivity verification: %v", failedConnections), errs.ErrMeshDegraded)
 		}
+		return errs.Mark(fmt.Errorf("VPN connectivity verification failed: %v", failedConnections), errs.ErrMeshDegraded)
 	}
+
+	v.ctx.Log.Info("VPN full mesh connectivity verified successfully!", nil)
+	return nil
 }
 
-// checkNodeConnectivity runs connectivity checks from one node to all others
-func (v *VPNConnectivityChecker) checkNodeConnectivity(
-	ctx context.Context,
-	wg *sync.WaitGroup,
-	sourceNode *providers.NodeOutput,
-	resultChan chan<- *ConnectivityResult,
-	errorChan chan<- error,
-) {
-	defer wg.Done()
+// meshLink is one directed link of the mesh
+type meshLink struct {
+	source *providers.NodeOutput
+	target *providers.NodeOutput
+}
 
-	ticker := time.NewTicker(v.checkInterval)
-	defer ticker.Stop()
+// meshConcurrency returns how many links are tested at once, never more than
+// there are links
+func (v *VPNConnectivityChecker) meshConcurrency(nodeCount int) int {
+	concurrency := v.concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultMeshCheckConcurrency
+	}
+	if links := nodeCount * (nodeCount - 1); links < concurrency {
+		concurrency = links
+	}
+	return concurrency
+}
 
-	result := &ConnectivityResult{
-		SourceNode:  sourceNode.Name,
-		Connections: make(map[string]*ConnectionStatus),
-		Timestamp:   time.Now(),
+// checkLinkWithRetry tests a link until it connects, the attempts run out or
+// the verification times out, doubling the wait between attempts
+func (v *VPNConnectivityChecker) checkLinkWithRetry(ctx context.Context, link meshLink) *ConnectionStatus {
+	attempts := v.maxAttempts
+	if attempts <= 0 {
+		attempts = DefaultMeshCheckAttempts
 	}
+	backoff := v.checkInterval
 
-	// Keep checking until all connections are established or timeout
-	for {
-		select {
-		case <-ctx.Done():
-			resultChan <- result
-			return
+	probe := v.prober
+	if probe == nil {
+		probe = v.performConnectivityCheck
+	}
 
-		case <-ticker.C:
-			allConnected := true
+	status := &ConnectionStatus{TargetNode: link.target.Name, TargetIP: link.target.WireGuardIP}
+	for attempt := 1; ; attempt++ {
+		if ctx.Err() != nil {
+			status.Error = ctx.Err()
+			return status
+		}
 
-			// Check connectivity to all other nodes
-			for _, targetNode := range v.nodes {
-				// Skip self
-				if targetNode.Name == sourceNode.Name {
-					continue
-				}
+		status = probe(link.source, link.target)
+		if status.IsConnected || attempt >= attempts {
+			return status
+		}
 
-				// Check if already connected
-				if conn, exists := result.Connections[targetNode.Name]; exists && conn.IsConnected {
-					// Refresh stats but don't recheck connectivity
-					v.updateWireGuardStats(sourceNode, targetNode, conn)
-					continue
-				}
+		select {
+		case <-ctx.Done():
+			return status
+		case <-time.After(backoff):
+		}
+		if backoff *= 2; backoff > maxMeshCheckBackoff {
+			backoff = maxMeshCheckBackoff
+		}
+	}
+}
 
-				// Perform connectivity check
-				conn := v.performConnectivityCheck(sourceNode, targetNode)
-				result.Connections[targetNode.Name] = conn
+// recordConnection stores a link result in the connectivity matrix
+func (v *VPNConnectivityChecker) recordConnection(source string, status *ConnectionStatus) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
 
-				if !conn.IsConnected {
-					allConnected = false
-				}
-			}
+	if v.results == nil {
+		v.results = make(map[string]*ConnectivityResult)
+	}
+	result, ok := v.results[source]
+	if !ok {
+		result = &ConnectivityResult{SourceNode: source, Connections: make(map[string]*ConnectionStatus)}
+		v.results[source] = result
+	}
+	result.Connections[status.TargetNode] = status
+	result.Timestamp = time.Now()
+}
 
-			result.AllConnected = allConnected
-			result.Timestamp = time.Now()
+// finalizeResults marks which nodes reach all others and returns the failed
+// links in node order
+func (v *VPNConnectivityChecker) finalizeResults(nodes []*providers.NodeOutput) []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
 
-			// If all connections are established, send result and exit
-			if allConnected {
-				v.ctx.Log.Info("Node has full connectivity", nil)
-				resultChan <- result
-				return
+	var failed []string
+	for _, source := range nodes {
+		result, ok := v.results[source.Name]
+		if !ok {
+			result = &ConnectivityResult{SourceNode: source.Name, Connections: make(map[string]*ConnectionStatus)}
+			v.results[source.Name] = result
+		}
+		result.AllConnected = true
+		for _, target := range nodes {
+			if target.Name == source.Name {
+				continue
+			}
+			if conn, ok := result.Connections[target.Name]; !ok || !conn.IsConnected {
+				result.AllConnected = false
+				failed = append(failed, fmt.Sprintf("%s -> %s", source.Name, target.Name))
 			}
 		}
 	}
+	return failed
 }
 
 // performConnectivityCheck checks connectivity between two nodes
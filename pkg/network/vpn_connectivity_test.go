@@ -1,7 +1,17 @@
 package network
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/errs"
+	"github.com/chalkan3/sloth-kubernetes/pkg/providers"
 )
 
 func TestContains(t *testing.T) {
@@ -332,3 +342,154 @@ func TestExtractValue_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// meshTestChecker builds a checker over n nodes whose links are tested by probe
+func meshTestChecker(ctx *pulumi.Context, n int, probe func(source, target *providers.NodeOutput) *ConnectionStatus) *VPNConnectivityChecker {
+	checker := NewVPNConnectivityChecker(ctx)
+	checker.checkInterval = time.Millisecond
+	checker.prober = probe
+	for i := 1; i <= n; i++ {
+		checker.AddNode(&providers.NodeOutput{Name: fmt.Sprintf("node-%d", i), WireGuardIP: fmt.Sprintf("10.8.0.%d", 10+i)})
+	}
+	return checker
+}
+
+// TestVerifyFullMeshConnectivity_BoundedConcurrency tests that no more links
+// than the configured concurrency are tested at once, that every link is
+// tested and that flaky links are retried until they connect
+func TestVerifyFullMeshConnectivity_BoundedConcurrency(t *testing.T) {
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		var mu sync.Mutex
+		inFlight, maxInFlight := 0, 0
+		attempts := make(map[string]int)
+
+		checker := meshTestChecker(ctx, 8, func(source, target *providers.NodeOutput) *ConnectionStatus {
+			mu.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			link := source.Name + "->" + target.Name
+			attempts[link]++
+			attempt := attempts[link]
+			mu.Unlock()
+
+			time.Sleep(time.Millisecond)
+
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+
+			// Links from node-1 only come up on the second attempt
+			connected := source.Name != "node-1" || attempt > 1
+			return &ConnectionStatus{TargetNode: target.Name, TargetIP: target.WireGuardIP, IsConnected: connected}
+		})
+		checker.SetConcurrency(3)
+
+		if err := checker.VerifyFullMeshConnectivity(); err != nil {
+			t.Fatalf("Expected the mesh to verify, got %v", err)
+		}
+
+		if maxInFlight > 3 {
+			t.Errorf("Expected at most 3 links tested at once, got %d", maxInFlight)
+		}
+		if len(attempts) != 8*7 {
+			t.Errorf("Expected all %d links tested, got %d", 8*7, len(attempts))
+		}
+		if attempts["node-1->node-2"] != 2 {
+			t.Errorf("Expected the flaky link to be retried once, got %d attempts", attempts["node-1->node-2"])
+		}
+
+		matrix := checker.GetConnectivityMatrix()
+		for source, targets := range matrix {
+			if len(targets) != 7 {
+				t.Errorf("Expected 7 links from %s in the matrix, got %d", source, len(targets))
+			}
+			for target, connected := range targets {
+				if !connected {
+					t.Errorf("Expected %s -> %s connected", source, target)
+				}
+			}
+		}
+		return nil
+	}, pulumi.WithMocks("project", "stack", &firewallMocks{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestVerifyFullMeshConnectivity_ReportsFailedLinks tests that a link failing
+// every attempt is reported once the attempts run out
+func TestVerifyFullMeshConnectivity_ReportsFailedLinks(t *testing.T) {
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		var mu sync.Mutex
+		brokenAttempts := 0
+
+		checker := meshTestChecker(ctx, 3, func(source, target *providers.NodeOutput) *ConnectionStatus {
+			broken := source.Name == "node-2" && target.Name == "node-3"
+			if broken {
+				mu.Lock()
+				brokenAttempts++
+				mu.Unlock()
+			}
+			return &ConnectionStatus{TargetNode: target.Name, IsConnected: !broken}
+		})
+		checker.SetMaxAttempts(3)
+
+		err := checker.VerifyFullMeshConnectivity()
+		if err == nil || !strings.Contains(err.Error(), "node-2 -> node-3") {
+			t.Errorf("Expected the broken link to be reported, got %v", err)
+		}
+		if !errors.Is(err, errs.ErrMeshDegraded) {
+			t.Errorf("Expected a degraded mesh error, got %v", err)
+		}
+		if brokenAttempts != 3 {
+			t.Errorf("Expected 3 attempts on the broken link, got %d", brokenAttempts)
+		}
+		if checker.results["node-2"].AllConnected || !checker.results["node-1"].AllConnected {
+			t.Error("Expected only node-2 to lack full connectivity")
+		}
+		return nil
+	}, pulumi.WithMocks("project", "stack", &firewallMocks{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestVerifyFullMeshConnectivity_ReportsProgress tests that the progress
+// function is called once for each completed link, counting the verified and
+// the failing ones
+func TestVerifyFullMeshConnectivity_ReportsProgress(t *testing.T) {
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		checker := meshTestChecker(ctx, 4, func(source, target *providers.NodeOutput) *ConnectionStatus {
+			broken := source.Name == "node-4"
+			return &ConnectionStatus{TargetNode: target.Name, IsConnected: !broken}
+		})
+		checker.SetMaxAttempts(1)
+
+		var updates []MeshProgress
+		checker.SetProgressFunc(func(progress MeshProgress) {
+			updates = append(updates, progress)
+		})
+
+		if err := checker.VerifyFullMeshConnectivity(); err == nil {
+			t.Error("Expected the links from node-4 to fail")
+		}
+
+		if len(updates) != 4*3 {
+			t.Fatalf("Expected a progress update for each of the %d links, got %d", 4*3, len(updates))
+		}
+		for i, progress := range updates {
+			if progress.Total != 4*3 || progress.Done() != i+1 {
+				t.Errorf("Update %d = %+v, want %d of %d links done", i, progress, i+1, 4*3)
+			}
+		}
+		if last := updates[len(updates)-1]; last.Verified != 9 || last.Failing != 3 {
+			t.Errorf("Expected 9 links verified and 3 failing, got %+v", last)
+		}
+		return nil
+	}, pulumi.WithMocks("project", "stack", &firewallMocks{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -70,6 +70,12 @@ func (p *AzureProvider) CreateNode(ctx *pulumi.Context, node *config.NodeConfig)
 	userData := p.generateUserData(node)
 	userDataEncoded := base64.StdEncoding.EncodeToString([]byte(userData))
 
+	// Pin the node to an availability zone when one is set
+	var zones pulumi.StringArray
+	if node.Zone != "" {
+		zones = pulumi.StringArray{pulumi.String(node.Zone)}
+	}
+
 	// Create Public IP
 	publicIPName := fmt.Sprintf("%s-pip", node.Name)
 	publicIP, err := azurenetwork.NewPublicIPAddress(ctx, publicIPName, &azurenetwork.PublicIPAddressArgs{
@@ -80,6 +86,7 @@ func (p *AzureProvider) CreateNode(ctx *pulumi.Context, node *config.NodeConfig)
 		Sku: &azurenetwork.PublicIPAddressSkuArgs{
 			Name: pulumi.String("Standard"),
 		},
+		Zones: zones,
 		Tags: pulumi.StringMap{
 			"Environment": pulumi.String("production"),
 			"ManagedBy":   pulumi.String("sloth-kubernetes"),
@@ -152,6 +159,7 @@ func (p *AzureProvider) CreateNode(ctx *pulumi.Context, node *config.NodeConfig)
 		ResourceGroupName: p.resourceGroup.Name,
 		Location:          pulumi.String(location),
 		VmName:            pulumi.String(node.Name),
+		Zones:             zones,
 		NetworkProfile: &azurecompute.NetworkProfileArgs{
 			NetworkInterfaces: azurecompute.NetworkInterfaceReferenceArray{
 				&azurecompute.NetworkInterfaceReferenceArgs{
@@ -207,8 +215,10 @@ func (p *AzureProvider) CreateNode(ctx *pulumi.Context, node *config.NodeConfig)
 		PrivateIP:   nic.IpConfigurations.Index(pulumi.Int(0)).PrivateIPAddress().Elem(),
 		Provider:    "azure",
 		Region:      location,
+		Zone:        node.Zone,
 		Size:        vmSize,
 		Status:      pulumi.String("active").ToStringOutput(),
+		Roles:       node.Roles,
 		Labels:      node.Labels,
 		WireGuardIP: node.WireGuardIP,
 		SSHUser:     "azureuser",
@@ -265,15 +275,14 @@ func (p *AzureProvider) CreateNodePool(ctx *pulumi.Context, pool *config.NodePoo
 	outputs := make([]*NodeOutput, 0, pool.Count)
 
 	for i := 0; i < pool.Count; i++ {
-		nodeName := fmt.Sprintf("%s-%d", pool.Name, i+1)
-
-		// Determine zone/region
-		region := pool.Region
-		if len(pool.Zones) > 0 {
-			// Distribute across zones
-			region = pool.Zones[i%len(pool.Zones)]
+		nodeName, err := config.RenderPoolNodeName(pool.Name, pool, i)
+		if err != nil {
+			return nil, err
 		}
 
+		// Spread nodes round-robin across availability zones
+		zone := pool.ZoneForIndex(i)
+
 		// Create node config from pool
 		nodeConfig := &config.NodeConfig{
 			Name:       nodeName,
@@ -282,11 +291,12 @@ func (p *AzureProvider) CreateNodePool(ctx *pulumi.Context, pool *config.NodePoo
 			Roles:      pool.Roles,
 			Size:       pool.Size,
 			Image:      pool.Image,
-			Region:     region,
+			Region:     pool.Region,
+			Zone:       zone,
 			Labels:     pool.Labels,
 			Taints:     pool.Taints,
 			UserData:   pool.UserData,
-			Monitoring: true,
+			Monitoring: pool.Monitoring,
 		}
 
 		// Set WireGuard IP based on role and index
@@ -3,6 +3,7 @@ package providers
 import (
 	"encoding/base64"
 	"fmt"
+	"strings"
 
 	"github.com/chalkan3/sloth-kubernetes/pkg/config"
 	"github.com/pulumi/pulumi-digitalocean/sdk/v4/go/digitalocean"
@@ -13,6 +14,7 @@ import (
 type DigitalOceanProvider struct {
 	config   *config.DigitalOceanProvider
 	vpc      *digitalocean.Vpc
+	vpcUUID  pulumi.StringPtrInput // VPC nodes attach to (created or existing), nil without one
 	firewall *digitalocean.Firewall
 	sshKeys  pulumi.StringArray
 	nodes    []*NodeOutput
@@ -123,14 +125,14 @@ func (p *DigitalOceanProvider) CreateNode(ctx *pulumi.Context, node *config.Node
 		Image:      pulumi.String(node.Image),
 		UserData:   userDataEncoded,
 		SshKeys:    p.sshKeys,
-		Monitoring: pulumi.Bool(p.config.Monitoring),
+		Monitoring: pulumi.Bool(p.config.Monitoring || node.Monitoring),
 		Ipv6:       pulumi.Bool(p.config.IPv6),
 		Tags:       tags,
 	}
 
 	// Add to VPC if configured
-	if p.vpc != nil {
-		dropletArgs.VpcUuid = p.vpc.ID()
+	if p.vpcUUID != nil {
+		dropletArgs.VpcUuid = p.vpcUUID
 	}
 
 	// Create the droplet
@@ -149,6 +151,7 @@ func (p *DigitalOceanProvider) CreateNode(ctx *pulumi.Context, node *config.Node
 		Region:      node.Region,
 		Size:        node.Size,
 		Status:      droplet.Status,
+		Roles:       node.Roles,
 		Labels:      node.Labels,
 		WireGuardIP: node.WireGuardIP,
 		SSHUser:     "root",
@@ -169,14 +172,15 @@ func (p *DigitalOceanProvider) CreateNode(ctx *pulumi.Context, node *config.Node
 func (p *DigitalOceanProvider) CreateNodePool(ctx *pulumi.Context, pool *config.NodePool) ([]*NodeOutput, error) {
 	outputs := make([]*NodeOutput, 0, pool.Count)
 
-	for i := 0; i < pool.Count; i++ {
-		nodeName := fmt.Sprintf("%s-%d", pool.Name, i+1)
+	// DigitalOcean has no availability zones; nodes stay in the pool region
+	if len(pool.Zones) > 0 {
+		ctx.Log.Warn(fmt.Sprintf("DigitalOcean does not support availability zones; ignoring zones for pool %s", pool.Name), nil)
+	}
 
-		// Determine zone/region
-		region := pool.Region
-		if len(pool.Zones) > 0 {
-			// Distribute across zones
-			region = pool.Zones[i%len(pool.Zones)]
+	for i := 0; i < pool.Count; i++ {
+		nodeName, err := config.RenderPoolNodeName(pool.Name, pool, i)
+		if err != nil {
+			return nil, err
 		}
 
 		// Create node config from pool
@@ -187,11 +191,11 @@ func (p *DigitalOceanProvider) CreateNodePool(ctx *pulumi.Context, pool *config.
 			Roles:      pool.Roles,
 			Size:       pool.Size,
 			Image:      pool.Image,
-			Region:     region,
+			Region:     pool.Region,
 			Labels:     pool.Labels,
 			Taints:     pool.Taints,
 			UserData:   pool.UserData,
-			Monitoring: true,
+			Monitoring: pool.Monitoring,
 		}
 
 		// Set WireGuard IP if using WireGuard
@@ -219,6 +223,7 @@ func (p *DigitalOceanProvider) CreateNetwork(ctx *pulumi.Context, network *confi
 	if p.config.VPC == nil {
 		// Create default VPC
 		p.config.VPC = &config.VPCConfig{
+			Create:  true,
 			Name:    fmt.Sprintf("%s-vpc", ctx.Stack()),
 			CIDR:    network.CIDR,
 			Region:  p.config.Region,
@@ -226,30 +231,63 @@ func (p *DigitalOceanProvider) CreateNetwork(ctx *pulumi.Context, network *confi
 		}
 	}
 
-	vpc, err := digitalocean.NewVpc(ctx, p.config.VPC.Name, &digitalocean.VpcArgs{
-		Name:    pulumi.String(p.config.VPC.Name),
-		Region:  pulumi.String(p.config.VPC.Region),
-		IpRange: pulumi.String(p.config.VPC.CIDR),
-	})
+	vpcCfg := p.config.VPC
+	region := vpcCfg.Region
+	if region == "" {
+		region = p.config.Region
+	}
+
+	// Attach nodes to an existing VPC
+	if vpcCfg.Existing() {
+		p.vpcUUID = pulumi.String(vpcCfg.ID)
+
+		ctx.Export("do_vpc_id", pulumi.String(vpcCfg.ID))
+		ctx.Export("do_vpc_cidr", pulumi.String(vpcCfg.DigitalOceanIPRange()))
+
+		return &NetworkOutput{
+			ID:     pulumi.ID(vpcCfg.ID).ToIDOutput(),
+			Name:   vpcCfg.Name,
+			CIDR:   vpcCfg.DigitalOceanIPRange(),
+			Region: region,
+		}, nil
+	}
+
+	vpc, err := digitalocean.NewVpc(ctx, vpcCfg.Name, digitalOceanVPCArgs(vpcCfg, region))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create VPC: %w", err)
 	}
 
 	p.vpc = vpc
+	p.vpcUUID = vpc.ID()
 
 	output := &NetworkOutput{
 		ID:     vpc.ID(),
-		Name:   p.config.VPC.Name,
-		CIDR:   p.config.VPC.CIDR,
-		Region: p.config.VPC.Region,
+		Name:   vpcCfg.Name,
+		CIDR:   vpcCfg.DigitalOceanIPRange(),
+		Region: region,
 	}
 
 	ctx.Export("do_vpc_id", vpc.ID())
-	ctx.Export("do_vpc_cidr", pulumi.String(p.config.VPC.CIDR))
+	ctx.Export("do_vpc_cidr", pulumi.String(vpcCfg.DigitalOceanIPRange()))
 
 	return output, nil
 }
 
+// digitalOceanVPCArgs builds the arguments for a new DigitalOcean VPC
+func digitalOceanVPCArgs(vpcCfg *config.VPCConfig, region string) *digitalocean.VpcArgs {
+	args := &digitalocean.VpcArgs{
+		Name:   pulumi.String(vpcCfg.Name),
+		Region: pulumi.String(region),
+	}
+	if ipRange := vpcCfg.DigitalOceanIPRange(); ipRange != "" {
+		args.IpRange = pulumi.String(ipRange)
+	}
+	if vpcCfg.DigitalOcean != nil && vpcCfg.DigitalOcean.Description != "" {
+		args.Description = pulumi.String(vpcCfg.DigitalOcean.Description)
+	}
+	return args
+}
+
 // CreateFirewall creates firewall rules
 func (p *DigitalOceanProvider) CreateFirewall(ctx *pulumi.Context, firewall *config.FirewallConfig, nodeIds []pulumi.IDOutput) error {
 	// Convert IDs to int array
@@ -262,43 +300,9 @@ func (p *DigitalOceanProvider) CreateFirewall(ctx *pulumi.Context, firewall *con
 		}).(pulumi.IntOutput)
 	}
 
-	// Build inbound rules
-	inboundRules := digitalocean.FirewallInboundRuleArray{}
-
-	// Default: Allow all traffic within VPC
-	if p.vpc != nil {
-		inboundRules = append(inboundRules, &digitalocean.FirewallInboundRuleArgs{
-			Protocol:        pulumi.String("tcp"),
-			PortRange:       pulumi.String("1-65535"),
-			SourceAddresses: pulumi.StringArray{pulumi.String(p.config.VPC.CIDR)},
-		})
-		inboundRules = append(inboundRules, &digitalocean.FirewallInboundRuleArgs{
-			Protocol:        pulumi.String("udp"),
-			PortRange:       pulumi.String("1-65535"),
-			SourceAddresses: pulumi.StringArray{pulumi.String(p.config.VPC.CIDR)},
-		})
-	}
-
-	// Add WireGuard rule if needed
-	inboundRules = append(inboundRules, &digitalocean.FirewallInboundRuleArgs{
-		Protocol:        pulumi.String("udp"),
-		PortRange:       pulumi.String("51820"),
-		SourceAddresses: pulumi.StringArray{pulumi.String("10.8.0.0/24")},
-	})
-
-	// Convert custom firewall rules
-	for _, rule := range firewall.InboundRules {
-		sources := make(pulumi.StringArray, len(rule.Source))
-		for i, src := range rule.Source {
-			sources[i] = pulumi.String(src)
-		}
-
-		inboundRules = append(inboundRules, &digitalocean.FirewallInboundRuleArgs{
-			Protocol:        pulumi.String(rule.Protocol),
-			PortRange:       pulumi.String(rule.Port),
-			SourceAddresses: sources,
-		})
-	}
+	// The rule set covers the VPC, WireGuard and Kubernetes rules; the nodes
+	// get the same rules in their OS firewall
+	inboundRules := DigitalOceanInboundRules(firewall.InboundRules)
 
 	// Build outbound rules (allow all by default)
 	outboundRules := digitalocean.FirewallOutboundRuleArray{
@@ -341,11 +345,25 @@ func (p *DigitalOceanProvider) CreateFirewall(ctx *pulumi.Context, firewall *con
 	return nil
 }
 
+// DigitalOceanInboundRules renders firewall rules as DigitalOcean firewall
+// inbound rules
+func DigitalOceanInboundRules(rules []config.FirewallRule) digitalocean.FirewallInboundRuleArray {
+	inboundRules := digitalocean.FirewallInboundRuleArray{}
+	for _, rule := range rules {
+		inboundRules = append(inboundRules, &digitalocean.FirewallInboundRuleArgs{
+			Protocol:        pulumi.String(rule.Protocol),
+			PortRange:       pulumi.String(rule.Port),
+			SourceAddresses: pulumi.ToStringArray(rule.Source),
+		})
+	}
+	return inboundRules
+}
+
 // CreateLoadBalancer creates a load balancer
 func (p *DigitalOceanProvider) CreateLoadBalancer(ctx *pulumi.Context, lb *config.LoadBalancerConfig) (*LoadBalancerOutput, error) {
 	// Get droplet IDs for the load balancer
 	dropletIds := make(pulumi.IntArray, 0)
-	for _, node := range p.nodes {
+	for _, node := range loadBalancerTargets(p.nodes, lb) {
 		dropletIds = append(dropletIds, node.ID.ApplyT(func(id pulumi.ID) int {
 			var idInt int
 			fmt.Sscanf(string(id), "%d", &idInt)
@@ -355,6 +373,7 @@ func (p *DigitalOceanProvider) CreateLoadBalancer(ctx *pulumi.Context, lb *confi
 
 	// Create forwarding rules
 	forwardingRules := make(digitalocean.LoadBalancerForwardingRuleArray, len(lb.Ports))
+	tcpOnly := len(lb.Ports) > 0
 	for i, port := range lb.Ports {
 		forwardingRules[i] = &digitalocean.LoadBalancerForwardingRuleArgs{
 			EntryPort:      pulumi.Int(port.Port),
@@ -362,18 +381,31 @@ func (p *DigitalOceanProvider) CreateLoadBalancer(ctx *pulumi.Context, lb *confi
 			EntryProtocol:  pulumi.String(port.Protocol),
 			TargetProtocol: pulumi.String(port.Protocol),
 		}
+		if !strings.EqualFold(port.Protocol, "tcp") {
+			tcpOnly = false
+		}
 	}
 
-	// Create load balancer
-	loadBalancer, err := digitalocean.NewLoadBalancer(ctx, lb.Name, &digitalocean.LoadBalancerArgs{
+	args := &digitalocean.LoadBalancerArgs{
 		Name:                pulumi.String(lb.Name),
 		Region:              pulumi.String(p.config.Region),
 		Size:                pulumi.String("lb-small"),
 		ForwardingRules:     forwardingRules,
 		DropletIds:          dropletIds,
-		VpcUuid:             p.vpc.ID(),
-		RedirectHttpToHttps: pulumi.Bool(true),
-	})
+		VpcUuid:             p.vpcUUID,
+		RedirectHttpToHttps: pulumi.Bool(!tcpOnly),
+	}
+	// The default HTTP check on port 80 fails against TCP-only backends
+	// such as the API server
+	if tcpOnly {
+		args.Healthcheck = &digitalocean.LoadBalancerHealthcheckArgs{
+			Protocol: pulumi.String("tcp"),
+			Port:     pulumi.Int(lb.Ports[0].TargetPort),
+		}
+	}
+
+	// Create load balancer
+	loadBalancer, err := digitalocean.NewLoadBalancer(ctx, lb.Name, args)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create load balancer: %w", err)
 	}
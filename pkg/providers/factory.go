@@ -18,7 +18,7 @@ func NewProviderFactory() *ProviderFactory {
 		registry: NewProviderRegistry(),
 	}
 
-	// Register all 5 cloud providers
+	// Register all 6 cloud providers
 	factory.registerAllProviders()
 
 	return factory
@@ -40,6 +40,9 @@ func (f *ProviderFactory) registerAllProviders() {
 
 	// Azure provider
 	f.registry.Register("azure", NewAzureProvider())
+
+	// Vultr provider
+	f.registry.Register("vultr", NewVultrProvider())
 }
 
 // GetRegistry returns the provider registry
@@ -105,6 +108,15 @@ func (f *ProviderFactory) GetEnabledProviders(cfg *config.ClusterConfig) ([]Prov
 		enabledProviders = append(enabledProviders, provider)
 	}
 
+	// Check Vultr
+	if cfg.Providers.Vultr != nil && cfg.Providers.Vultr.Enabled {
+		provider, err := f.GetProvider("vultr")
+		if err != nil {
+			return nil, err
+		}
+		enabledProviders = append(enabledProviders, provider)
+	}
+
 	if len(enabledProviders) == 0 {
 		return nil, fmt.Errorf("no providers enabled in configuration")
 	}
@@ -188,6 +200,13 @@ func (f *ProviderFactory) ValidateProviderConfig(cfg *config.ClusterConfig) erro
 		// Subscription ID and credentials can be provided via environment variables or managed identity
 	}
 
+	// Validate Vultr config if enabled
+	if cfg.Providers.Vultr != nil && cfg.Providers.Vultr.Enabled {
+		if cfg.Providers.Vultr.APIKey == "" {
+			errors = append(errors, "Vultr: apiKey is required")
+		}
+	}
+
 	if len(errors) > 0 {
 		return fmt.Errorf("provider configuration validation failed:\n  - %s", joinErrors(errors))
 	}
@@ -203,6 +222,7 @@ func (f *ProviderFactory) GetSupportedProviders() []string {
 		"aws",
 		"gcp",
 		"azure",
+		"vultr",
 	}
 }
 
@@ -244,6 +264,13 @@ func (f *ProviderFactory) GetProviderInfo() map[string]ProviderInfo {
 			Regions:     NewAzureProvider().GetRegions(),
 			Sizes:       NewAzureProvider().GetSizes(),
 		},
+		"vultr": {
+			Name:        "Vultr",
+			Code:        "vultr",
+			Description: "Vultr Cloud Compute - Low-cost multi-region instances",
+			Regions:     NewVultrProvider().GetRegions(),
+			Sizes:       NewVultrProvider().GetSizes(),
+		},
 	}
 }
 
@@ -2,6 +2,7 @@ package providers
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/chalkan3/sloth-kubernetes/pkg/config"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
@@ -48,8 +49,10 @@ type NodeOutput struct {
 	PrivateIP    pulumi.StringOutput
 	Provider     string
 	Region       string
+	Zone         string
 	Size         string
 	Status       pulumi.StringOutput
+	Roles        []string
 	Labels       map[string]string
 	WireGuardIP  string
 	WireGuardKey pulumi.StringOutput
@@ -57,6 +60,41 @@ type NodeOutput struct {
 	SSHKeyPath   string
 }
 
+// HasRole reports whether the node has role, treating role aliases such as
+// controlplane and master as equal. A node can have several roles, e.g. a
+// combined control plane and worker in a small cluster. Nodes without Roles
+// fall back to their comma-separated "role" label.
+func (n *NodeOutput) HasRole(role string) bool {
+	if len(n.Roles) > 0 {
+		return config.HasRole(n.Roles, role)
+	}
+	label, ok := n.Labels["role"]
+	if !ok {
+		return false
+	}
+
+	roles := strings.Split(label, ",")
+	for i := range roles {
+		roles[i] = strings.TrimSpace(roles[i])
+	}
+	return config.HasRole(roles, role)
+}
+
+// loadBalancerTargets returns the nodes lb forwards to: those with its
+// TargetRole, or all of them without one
+func loadBalancerTargets(nodes []*NodeOutput, lb *config.LoadBalancerConfig) []*NodeOutput {
+	if lb.TargetRole == "" {
+		return nodes
+	}
+	var targets []*NodeOutput
+	for _, node := range nodes {
+		if node.HasRole(lb.TargetRole) {
+			targets = append(targets, node)
+		}
+	}
+	return targets
+}
+
 // NetworkOutput represents network creation output
 type NetworkOutput struct {
 	ID      pulumi.IDOutput
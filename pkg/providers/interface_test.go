@@ -141,6 +141,23 @@ func TestLoadBalancerOutput_Structure(t *testing.T) {
 	}
 }
 
+func TestLoadBalancerTargets(t *testing.T) {
+	nodes := []*NodeOutput{
+		{Name: "master-1", Roles: []string{"master"}},
+		{Name: "worker-1", Roles: []string{"worker"}},
+		{Name: "master-2", Labels: map[string]string{"role": "controlplane,etcd"}},
+	}
+
+	targets := loadBalancerTargets(nodes, &config.LoadBalancerConfig{TargetRole: "master"})
+	if len(targets) != 2 || targets[0].Name != "master-1" || targets[1].Name != "master-2" {
+		t.Errorf("Expected only the masters as targets, got %v", targets)
+	}
+
+	if targets := loadBalancerTargets(nodes, &config.LoadBalancerConfig{}); len(targets) != 3 {
+		t.Errorf("Expected every node without a target role, got %d", len(targets))
+	}
+}
+
 func TestNewProviderRegistry(t *testing.T) {
 	registry := NewProviderRegistry()
 
@@ -342,6 +359,33 @@ func TestNodeOutput_Labels(t *testing.T) {
 	}
 }
 
+func TestNodeOutput_HasRole(t *testing.T) {
+	tests := []struct {
+		name       string
+		node       *NodeOutput
+		wantMaster bool
+		wantWorker bool
+	}{
+		{"master", &NodeOutput{Roles: []string{"master"}}, true, false},
+		{"controlplane and worker", &NodeOutput{Roles: []string{"controlplane", "worker"}}, true, true},
+		{"roles take precedence over the label", &NodeOutput{Roles: []string{"worker"}, Labels: map[string]string{"role": "master"}}, false, true},
+		{"role label", &NodeOutput{Labels: map[string]string{"role": "worker"}}, false, true},
+		{"comma-separated role label", &NodeOutput{Labels: map[string]string{"role": "controlplane, worker"}}, true, true},
+		{"no roles", &NodeOutput{}, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.node.HasRole("master"); got != tt.wantMaster {
+				t.Errorf("HasRole(master) = %v, want %v", got, tt.wantMaster)
+			}
+			if got := tt.node.HasRole("worker"); got != tt.wantWorker {
+				t.Errorf("HasRole(worker) = %v, want %v", got, tt.wantWorker)
+			}
+		})
+	}
+}
+
 func TestNodeOutput_WireGuardConfig(t *testing.T) {
 	node := &NodeOutput{
 		Name:        "vpn-node",
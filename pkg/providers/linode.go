@@ -2,6 +2,7 @@ package providers
 
 import (
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/chalkan3/sloth-kubernetes/pkg/config"
@@ -11,10 +12,11 @@ import (
 
 // LinodeProvider implements the Provider interface for Linode/Akamai
 type LinodeProvider struct {
-	config   *config.LinodeProvider
-	firewall *linode.Firewall
-	nodes    []*NodeOutput
-	ctx      *pulumi.Context
+	config      *config.LinodeProvider
+	vpcSubnetID pulumi.IntPtrInput // VPC subnet nodes attach to, nil without a VPC
+	firewall    *linode.Firewall
+	nodes       []*NodeOutput
+	ctx         *pulumi.Context
 }
 
 // NewLinodeProvider creates a new Linode provider
@@ -93,6 +95,11 @@ func (p *LinodeProvider) CreateNode(ctx *pulumi.Context, node *config.NodeConfig
 		BootConfigLabel: pulumi.String(fmt.Sprintf("%s-config", node.Name)),
 	}
 
+	// Attach to the VPC if configured
+	if p.vpcSubnetID != nil {
+		attachLinodeVPC(instanceArgs, p.vpcSubnetID)
+	}
+
 	// Create the instance
 	instance, err := linode.NewInstance(ctx, node.Name, instanceArgs)
 	if err != nil {
@@ -100,17 +107,23 @@ func (p *LinodeProvider) CreateNode(ctx *pulumi.Context, node *config.NodeConfig
 	}
 
 	// Get network information
+	privateIP := instance.PrivateIpAddress
+	if p.vpcSubnetID != nil {
+		// The VPC interface follows the public one
+		privateIP = instance.Interfaces.Index(pulumi.Int(1)).Ipv4().Vpc().Elem()
+	}
 
 	// Create node output
 	output := &NodeOutput{
 		ID:          instance.ID(),
 		Name:        node.Name,
 		PublicIP:    instance.IpAddress,
-		PrivateIP:   instance.PrivateIpAddress,
+		PrivateIP:   privateIP,
 		Provider:    "linode",
 		Region:      node.Region,
 		Size:        node.Size,
 		Status:      instance.Status,
+		Roles:       node.Roles,
 		Labels:      node.Labels,
 		WireGuardIP: node.WireGuardIP,
 		SSHUser:     "root",
@@ -131,14 +144,15 @@ func (p *LinodeProvider) CreateNode(ctx *pulumi.Context, node *config.NodeConfig
 func (p *LinodeProvider) CreateNodePool(ctx *pulumi.Context, pool *config.NodePool) ([]*NodeOutput, error) {
 	outputs := make([]*NodeOutput, 0, pool.Count)
 
-	for i := 0; i < pool.Count; i++ {
-		nodeName := fmt.Sprintf("%s-%d", pool.Name, i+1)
+	// Linode has no availability zones; nodes stay in the pool region
+	if len(pool.Zones) > 0 {
+		ctx.Log.Warn(fmt.Sprintf("Linode does not support availability zones; ignoring zones for pool %s", pool.Name), nil)
+	}
 
-		// Determine zone/region
-		region := pool.Region
-		if len(pool.Zones) > 0 {
-			// Distribute across zones
-			region = pool.Zones[i%len(pool.Zones)]
+	for i := 0; i < pool.Count; i++ {
+		nodeName, err := config.RenderPoolNodeName(pool.Name, pool, i)
+		if err != nil {
+			return nil, err
 		}
 
 		// Create node config from pool
@@ -149,11 +163,11 @@ func (p *LinodeProvider) CreateNodePool(ctx *pulumi.Context, pool *config.NodePo
 			Roles:      pool.Roles,
 			Size:       pool.Size,
 			Image:      pool.Image,
-			Region:     region,
+			Region:     pool.Region,
 			Labels:     pool.Labels,
 			Taints:     pool.Taints,
 			UserData:   pool.UserData,
-			Monitoring: true,
+			Monitoring: pool.Monitoring,
 		}
 
 		// Set WireGuard IP based on role and index
@@ -178,6 +192,10 @@ func (p *LinodeProvider) CreateNodePool(ctx *pulumi.Context, pool *config.NodePo
 
 // CreateNetwork creates network infrastructure
 func (p *LinodeProvider) CreateNetwork(ctx *pulumi.Context, network *config.NetworkConfig) (*NetworkOutput, error) {
+	if p.config.VPC != nil {
+		return p.createVPC(ctx)
+	}
+
 	// Linode uses private IPs for internal networking
 	// No explicit network creation needed - instances with private_ip enabled
 	// automatically get a private network interface
@@ -198,6 +216,136 @@ func (p *LinodeProvider) CreateNetwork(ctx *pulumi.Context, network *config.Netw
 	return output, nil
 }
 
+// createVPC creates the configured VPC and its subnets, or uses an existing
+// VPC, and records the subnet nodes attach to
+func (p *LinodeProvider) createVPC(ctx *pulumi.Context) (*NetworkOutput, error) {
+	vpcCfg := p.config.VPC
+	region := vpcCfg.Region
+	if region == "" {
+		region = p.config.Region
+	}
+
+	var vpcID pulumi.IDOutput
+	var vpcIntID pulumi.IntInput
+
+	if vpcCfg.Existing() {
+		id, err := strconv.Atoi(vpcCfg.ID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid Linode VPC id %q: must be numeric", vpcCfg.ID)
+		}
+		vpcID = pulumi.ID(vpcCfg.ID).ToIDOutput()
+		vpcIntID = pulumi.Int(id)
+
+		// Without subnets to create, attach to a subnet the VPC already has
+		if vpcCfg.Linode == nil || len(vpcCfg.Linode.Subnets) == 0 {
+			subnetID, err := lookupLinodeVPCSubnet(ctx, id, vpcCfg.CIDR)
+			if err != nil {
+				return nil, err
+			}
+			p.vpcSubnetID = pulumi.Int(subnetID)
+			return p.exportVPC(ctx, vpcID, vpcCfg, region), nil
+		}
+	} else {
+		vpc, err := linode.NewVpc(ctx, vpcCfg.LinodeLabel(), linodeVPCArgs(vpcCfg, region))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Linode VPC: %w", err)
+		}
+		vpcID = vpc.ID()
+		vpcIntID = idToInt(vpc.ID())
+	}
+
+	subnets := vpcCfg.LinodeSubnets()
+	if len(subnets) == 0 {
+		return nil, fmt.Errorf("Linode VPC %s has no subnets: set cidr or linode.subnets", vpcCfg.LinodeLabel())
+	}
+
+	for i, subnetCfg := range subnets {
+		subnet, err := linode.NewVpcSubnet(ctx, fmt.Sprintf("%s-%s", vpcCfg.LinodeLabel(), subnetCfg.Label), &linode.VpcSubnetArgs{
+			VpcId: vpcIntID,
+			Label: pulumi.String(subnetCfg.Label),
+			Ipv4:  pulumi.String(subnetCfg.IPv4),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Linode VPC subnet %s: %w", subnetCfg.Label, err)
+		}
+
+		// Nodes attach to the first subnet
+		if i == 0 {
+			p.vpcSubnetID = idToInt(subnet.ID())
+		}
+	}
+
+	return p.exportVPC(ctx, vpcID, vpcCfg, region), nil
+}
+
+// exportVPC exports the VPC details and returns them as a network output
+func (p *LinodeProvider) exportVPC(ctx *pulumi.Context, vpcID pulumi.IDOutput, vpcCfg *config.VPCConfig, region string) *NetworkOutput {
+	ctx.Export("linode_vpc_id", vpcID)
+	ctx.Export("linode_vpc_label", pulumi.String(vpcCfg.LinodeLabel()))
+
+	return &NetworkOutput{
+		ID:     vpcID,
+		Name:   vpcCfg.LinodeLabel(),
+		CIDR:   vpcCfg.CIDR,
+		Region: region,
+	}
+}
+
+// linodeVPCArgs builds the arguments for a new Linode VPC
+func linodeVPCArgs(vpcCfg *config.VPCConfig, region string) *linode.VpcArgs {
+	args := &linode.VpcArgs{
+		Label:  pulumi.String(vpcCfg.LinodeLabel()),
+		Region: pulumi.String(region),
+	}
+	if vpcCfg.Linode != nil && vpcCfg.Linode.Description != "" {
+		args.Description = pulumi.String(vpcCfg.Linode.Description)
+	}
+	return args
+}
+
+// attachLinodeVPC gives the instance a public interface plus a VPC interface
+// on subnetID. Linode does not allow a private IPv4 address alongside a VPC
+// interface, so it is turned off.
+func attachLinodeVPC(args *linode.InstanceArgs, subnetID pulumi.IntPtrInput) {
+	args.PrivateIp = pulumi.Bool(false)
+	args.Interfaces = linode.InstanceInterfaceArray{
+		&linode.InstanceInterfaceArgs{
+			Purpose: pulumi.String("public"),
+		},
+		&linode.InstanceInterfaceArgs{
+			Purpose:  pulumi.String("vpc"),
+			SubnetId: subnetID,
+		},
+	}
+}
+
+// lookupLinodeVPCSubnet returns the subnet of an existing VPC whose range is
+// cidr, or its first subnet when cidr is empty
+func lookupLinodeVPCSubnet(ctx *pulumi.Context, vpcID int, cidr string) (int, error) {
+	result, err := linode.GetVpcSubnets(ctx, &linode.GetVpcSubnetsArgs{VpcId: vpcID})
+	if err != nil {
+		return 0, fmt.Errorf("failed to look up subnets of Linode VPC %d: %w", vpcID, err)
+	}
+
+	for _, subnet := range result.VpcSubnets {
+		if cidr == "" || subnet.Ipv4 == cidr {
+			return subnet.Id, nil
+		}
+	}
+
+	if cidr != "" {
+		return 0, fmt.Errorf("Linode VPC %d has no subnet with range %s", vpcID, cidr)
+	}
+	return 0, fmt.Errorf("Linode VPC %d has no subnets", vpcID)
+}
+
+// idToInt converts a numeric resource ID output to an int output
+func idToInt(id pulumi.IDOutput) pulumi.IntOutput {
+	return id.ApplyT(func(id pulumi.ID) (int, error) {
+		return strconv.Atoi(string(id))
+	}).(pulumi.IntOutput)
+}
+
 // CreateFirewall creates firewall rules
 func (p *LinodeProvider) CreateFirewall(ctx *pulumi.Context, firewall *config.FirewallConfig, nodeIds []pulumi.IDOutput) error {
 	// Convert IDs to int array for Linode
@@ -210,44 +358,9 @@ func (p *LinodeProvider) CreateFirewall(ctx *pulumi.Context, firewall *config.Fi
 		}).(pulumi.IntOutput)
 	}
 
-	// Build inbound rules
-	inboundRules := linode.FirewallInboundArray{}
-
-	// Allow all internal traffic (private network)
-	inboundRules = append(inboundRules, &linode.FirewallInboundArgs{
-		Label:    pulumi.String("internal-all"),
-		Protocol: pulumi.String("TCP"),
-		Ports:    pulumi.String("1-65535"),
-		Ipv4s: pulumi.StringArray{
-			pulumi.String("10.0.0.0/8"),
-			pulumi.String("172.16.0.0/12"),
-			pulumi.String("192.168.0.0/16"),
-		},
-	})
-
-	// Allow WireGuard
-	inboundRules = append(inboundRules, &linode.FirewallInboundArgs{
-		Label:    pulumi.String("wireguard"),
-		Protocol: pulumi.String("UDP"),
-		Ports:    pulumi.String("51820"),
-		Ipv4s:    pulumi.StringArray{pulumi.String("10.8.0.0/24")},
-	})
-
-	// Convert custom firewall rules
-	for _, rule := range firewall.InboundRules {
-		ipv4Sources := make(pulumi.StringArray, len(rule.Source))
-		for i, src := range rule.Source {
-			ipv4Sources[i] = pulumi.String(src)
-		}
-
-		inboundRules = append(inboundRules, &linode.FirewallInboundArgs{
-			Label:    pulumi.String(fmt.Sprintf("custom-%s-%s", rule.Protocol, rule.Port)),
-			Protocol: pulumi.String(strings.ToUpper(rule.Protocol)),
-			Ports:    pulumi.String(rule.Port),
-			Action:   pulumi.String("ACCEPT"),
-			Ipv4s:    ipv4Sources,
-		})
-	}
+	// The rule set covers the VPC, WireGuard and Kubernetes rules; the nodes
+	// get the same rules in their OS firewall
+	inboundRules := LinodeInboundRules(firewall.InboundRules)
 
 	// Build outbound rules (allow all by default)
 	outboundRules := linode.FirewallOutboundArray{
@@ -290,6 +403,31 @@ func (p *LinodeProvider) CreateFirewall(ctx *pulumi.Context, firewall *config.Fi
 	return nil
 }
 
+// LinodeInboundRules renders firewall rules as Linode firewall inbound rules
+func LinodeInboundRules(rules []config.FirewallRule) linode.FirewallInboundArray {
+	inboundRules := linode.FirewallInboundArray{}
+	for _, rule := range rules {
+		inboundRules = append(inboundRules, &linode.FirewallInboundArgs{
+			Label:    pulumi.String(fmt.Sprintf("%s-%s", rule.Protocol, rule.Port)),
+			Protocol: pulumi.String(strings.ToUpper(rule.Protocol)),
+			Ports:    pulumi.String(rule.Port),
+			Action:   pulumi.String("ACCEPT"),
+			Ipv4s:    pulumi.ToStringArray(rule.Source),
+		})
+	}
+	return inboundRules
+}
+
+// nodeBalancerCheck returns the health check of a NodeBalancer port: an
+// HTTP request for HTTP ports, otherwise only a TCP connection, which is all
+// a TLS backend such as the API server answers
+func nodeBalancerCheck(protocol string) string {
+	if strings.EqualFold(protocol, "http") {
+		return "http"
+	}
+	return "connection"
+}
+
 // CreateLoadBalancer creates a NodeBalancer
 func (p *LinodeProvider) CreateLoadBalancer(ctx *pulumi.Context, lb *config.LoadBalancerConfig) (*LoadBalancerOutput, error) {
 	// Create NodeBalancer
@@ -315,7 +453,7 @@ func (p *LinodeProvider) CreateLoadBalancer(ctx *pulumi.Context, lb *config.Load
 			Port:          pulumi.Int(port.Port),
 			Protocol:      pulumi.String(strings.ToLower(port.Protocol)),
 			Algorithm:     pulumi.String("roundrobin"),
-			Check:         pulumi.String("http"),
+			Check:         pulumi.String(nodeBalancerCheck(port.Protocol)),
 			CheckInterval: pulumi.Int(30),
 			CheckTimeout:  pulumi.Int(5),
 			CheckAttempts: pulumi.Int(3),
@@ -326,7 +464,7 @@ func (p *LinodeProvider) CreateLoadBalancer(ctx *pulumi.Context, lb *config.Load
 		}
 
 		// Add nodes to the config
-		for i, node := range p.nodes {
+		for i, node := range loadBalancerTargets(p.nodes, lb) {
 			nodeName := fmt.Sprintf("%s-%d-node-%d", lb.Name, port.Port, i)
 
 			_, err := linode.NewNodeBalancerNode(ctx, nodeName, &linode.NodeBalancerNodeArgs{
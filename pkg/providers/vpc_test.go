@@ -0,0 +1,238 @@
+package providers
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/pulumi/pulumi-linode/sdk/v4/go/linode"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+// vpcMocks records the resources created during a VPC test
+type vpcMocks struct {
+	pulumi.MockResourceMonitor
+
+	mu        sync.Mutex
+	resources map[string][]resource.PropertyMap // type token -> inputs
+}
+
+func (m *vpcMocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.resources == nil {
+		m.resources = make(map[string][]resource.PropertyMap)
+	}
+	m.resources[args.TypeToken] = append(m.resources[args.TypeToken], args.Inputs)
+
+	// Linode IDs are numeric
+	return "4242", args.Inputs, nil
+}
+
+func (m *vpcMocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	if args.Token == "linode:index/getVpcSubnets:getVpcSubnets" {
+		return resource.NewPropertyMapFromMap(map[string]interface{}{
+			"vpcId": args.Args["vpcId"],
+			"vpcSubnets": []interface{}{
+				map[string]interface{}{"id": 11, "ipv4": "10.30.0.0/24", "label": "apps"},
+				map[string]interface{}{"id": 12, "ipv4": "10.30.1.0/24", "label": "k8s"},
+			},
+		}), nil
+	}
+	return resource.PropertyMap{}, nil
+}
+
+func (m *vpcMocks) created(typeToken string) []resource.PropertyMap {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.resources[typeToken]
+}
+
+func TestDigitalOceanCreateNetwork_CreatesVPC(t *testing.T) {
+	mocks := &vpcMocks{}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		p := NewDigitalOceanProvider()
+		p.config = &config.DigitalOceanProvider{
+			Region: "nyc3",
+			VPC: &config.VPCConfig{
+				Create:       true,
+				Name:         "cluster-vpc",
+				CIDR:         "10.10.0.0/16",
+				DigitalOcean: &config.DOVPCConfig{IPRange: "10.20.0.0/16", Description: "cluster network"},
+			},
+		}
+
+		network, err := p.CreateNetwork(ctx, &config.NetworkConfig{})
+		if err != nil {
+			return err
+		}
+		if network.CIDR != "10.20.0.0/16" || network.Region != "nyc3" {
+			t.Errorf("Unexpected network output: %+v", network)
+		}
+		if p.vpcUUID == nil {
+			t.Error("Droplets should be attached to the created VPC")
+		}
+		return nil
+	}, pulumi.WithMocks("test-project", "test-stack", mocks))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	vpcs := mocks.created("digitalocean:index/vpc:Vpc")
+	if len(vpcs) != 1 {
+		t.Fatalf("Expected 1 VPC, got %d", len(vpcs))
+	}
+	if got := vpcs[0]["ipRange"].StringValue(); got != "10.20.0.0/16" {
+		t.Errorf("Expected ipRange override 10.20.0.0/16, got %s", got)
+	}
+	if got := vpcs[0]["region"].StringValue(); got != "nyc3" {
+		t.Errorf("Expected region nyc3, got %s", got)
+	}
+	if got := vpcs[0]["description"].StringValue(); got != "cluster network" {
+		t.Errorf("Expected description, got %s", got)
+	}
+}
+
+func TestDigitalOceanCreateNetwork_ExistingVPC(t *testing.T) {
+	mocks := &vpcMocks{}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		p := NewDigitalOceanProvider()
+		p.config = &config.DigitalOceanProvider{
+			Region: "nyc3",
+			VPC:    &config.VPCConfig{ID: "5a4b3c2d-uuid", CIDR: "10.10.0.0/16"},
+		}
+
+		if _, err := p.CreateNetwork(ctx, &config.NetworkConfig{}); err != nil {
+			return err
+		}
+		if p.vpcUUID != pulumi.String("5a4b3c2d-uuid") {
+			t.Errorf("Droplets should attach to the existing VPC, got %v", p.vpcUUID)
+		}
+		return nil
+	}, pulumi.WithMocks("test-project", "test-stack", mocks))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if vpcs := mocks.created("digitalocean:index/vpc:Vpc"); len(vpcs) != 0 {
+		t.Errorf("Existing VPC should not be created, got %d", len(vpcs))
+	}
+}
+
+func TestLinodeCreateNetwork_CreatesVPCAndSubnets(t *testing.T) {
+	mocks := &vpcMocks{}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		p := NewLinodeProvider()
+		p.config = &config.LinodeProvider{
+			Region: "us-east",
+			VPC: &config.VPCConfig{
+				Create: true,
+				Name:   "cluster-vpc",
+				Linode: &config.LinodeVPCConfig{
+					Label:       "k8s-vpc",
+					Description: "cluster network",
+					Subnets: []config.LinodeSubnetConfig{
+						{Label: "nodes", IPv4: "10.21.0.0/24"},
+						{Label: "extra", IPv4: "10.21.1.0/24"},
+					},
+				},
+			},
+		}
+
+		network, err := p.CreateNetwork(ctx, &config.NetworkConfig{})
+		if err != nil {
+			return err
+		}
+		if network.Name != "k8s-vpc" {
+			t.Errorf("Expected network name k8s-vpc, got %s", network.Name)
+		}
+		if p.vpcSubnetID == nil {
+			t.Error("Instances should be attached to the first subnet")
+		}
+		return nil
+	}, pulumi.WithMocks("test-project", "test-stack", mocks))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	vpcs := mocks.created("linode:index/vpc:Vpc")
+	if len(vpcs) != 1 || vpcs[0]["label"].StringValue() != "k8s-vpc" || vpcs[0]["region"].StringValue() != "us-east" {
+		t.Fatalf("Unexpected VPCs: %v", vpcs)
+	}
+
+	subnets := mocks.created("linode:index/vpcSubnet:VpcSubnet")
+	if len(subnets) != 2 {
+		t.Fatalf("Expected 2 subnets, got %d", len(subnets))
+	}
+	for _, subnet := range subnets {
+		if subnet["vpcId"].NumberValue() != 4242 {
+			t.Errorf("Subnet should belong to the created VPC: %v", subnet)
+		}
+		if subnet["label"].StringValue() == "nodes" && subnet["ipv4"].StringValue() != "10.21.0.0/24" {
+			t.Errorf("Unexpected nodes subnet: %v", subnet)
+		}
+	}
+}
+
+func TestLinodeCreateNetwork_ExistingVPC(t *testing.T) {
+	mocks := &vpcMocks{}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		p := NewLinodeProvider()
+		p.config = &config.LinodeProvider{
+			Region: "us-east",
+			VPC:    &config.VPCConfig{ID: "77", CIDR: "10.30.1.0/24"},
+		}
+
+		if _, err := p.CreateNetwork(ctx, &config.NetworkConfig{}); err != nil {
+			return err
+		}
+		if p.vpcSubnetID != pulumi.Int(12) {
+			t.Errorf("Expected the subnet matching the VPC cidr (12), got %v", p.vpcSubnetID)
+		}
+		return nil
+	}, pulumi.WithMocks("test-project", "test-stack", mocks))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if vpcs := mocks.created("linode:index/vpc:Vpc"); len(vpcs) != 0 {
+		t.Errorf("Existing VPC should not be created, got %d", len(vpcs))
+	}
+}
+
+func TestLinodeCreateNetwork_InvalidExistingID(t *testing.T) {
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		p := NewLinodeProvider()
+		p.config = &config.LinodeProvider{VPC: &config.VPCConfig{ID: "not-a-number"}}
+
+		_, err := p.CreateNetwork(ctx, &config.NetworkConfig{})
+		return err
+	}, pulumi.WithMocks("test-project", "test-stack", &vpcMocks{}))
+	if err == nil {
+		t.Error("Expected error for non-numeric Linode VPC id")
+	}
+}
+
+func TestAttachLinodeVPC(t *testing.T) {
+	args := &linode.InstanceArgs{PrivateIp: pulumi.Bool(true)}
+	attachLinodeVPC(args, pulumi.Int(12))
+
+	if args.PrivateIp != pulumi.Bool(false) {
+		t.Error("Private IP should be disabled with a VPC interface")
+	}
+
+	interfaces, ok := args.Interfaces.(linode.InstanceInterfaceArray)
+	if !ok || len(interfaces) != 2 {
+		t.Fatalf("Expected public and VPC interfaces, got %v", args.Interfaces)
+	}
+	public := interfaces[0].(*linode.InstanceInterfaceArgs)
+	vpc := interfaces[1].(*linode.InstanceInterfaceArgs)
+	if public.Purpose != pulumi.String("public") {
+		t.Errorf("First interface should be public, got %v", public.Purpose)
+	}
+	if vpc.Purpose != pulumi.String("vpc") || vpc.SubnetId != pulumi.Int(12) {
+		t.Errorf("Second interface should be the VPC subnet, got %+v", vpc)
+	}
+}
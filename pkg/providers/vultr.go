@@ -0,0 +1,551 @@
+package providers
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Vultr resources are registered by type token against the Pulumi Vultr
+// provider plugin, which is downloaded from its GitHub releases on first use
+const (
+	vultrPluginDownloadURL = "github://api.github.com/dirien"
+
+	vultrProviderType      = "pulumi:providers:vultr"
+	vultrInstanceType      = "vultr:index/instance:Instance"
+	vultrSSHKeyType        = "vultr:index/sshKey:SshKey"
+	vultrVPCType           = "vultr:index/vpc:Vpc"
+	vultrFirewallGroupType = "vultr:index/firewallGroup:FirewallGroup"
+	vultrFirewallRuleType  = "vultr:index/firewallRule:FirewallRule"
+	vultrLoadBalancerType  = "vultr:index/loadBalancer:LoadBalancer"
+)
+
+// DefaultVultrOSID is the Vultr OS id of Ubuntu 22.04 x64, used for nodes
+// without a numeric image when the provider sets no osId
+const DefaultVultrOSID = 1743
+
+// vultrResource is a Vultr resource whose outputs are not read
+type vultrResource struct {
+	pulumi.CustomResourceState
+}
+
+// vultrInstance is a Vultr compute instance
+type vultrInstance struct {
+	pulumi.CustomResourceState
+
+	MainIP     pulumi.StringOutput `pulumi:"mainIp"`
+	InternalIP pulumi.StringOutput `pulumi:"internalIp"`
+	Status     pulumi.StringOutput `pulumi:"status"`
+}
+
+// vultrLoadBalancer is a Vultr load balancer
+type vultrLoadBalancer struct {
+	pulumi.CustomResourceState
+
+	IPv4   pulumi.StringOutput `pulumi:"ipv4"`
+	Status pulumi.StringOutput `pulumi:"status"`
+}
+
+// vultrProviderResource is the explicit Vultr provider carrying the API key
+type vultrProviderResource struct {
+	pulumi.ProviderResourceState
+}
+
+// VultrProvider implements the Provider interface for Vultr
+type VultrProvider struct {
+	config        *config.VultrProvider
+	provider      *vultrProviderResource
+	vpcID         pulumi.IDOutput // VPC nodes attach to, unset until CreateNetwork
+	hasVPC        bool
+	firewallGroup *vultrResource
+	sshKeyIDs     pulumi.StringArray
+	nodes         []*NodeOutput
+	ctx           *pulumi.Context
+}
+
+// NewVultrProvider creates a new Vultr provider
+func NewVultrProvider() *VultrProvider {
+	return &VultrProvider{
+		nodes: make([]*NodeOutput, 0),
+	}
+}
+
+// GetName returns the provider name
+func (p *VultrProvider) GetName() string {
+	return "vultr"
+}
+
+// Initialize initializes the Vultr provider
+func (p *VultrProvider) Initialize(ctx *pulumi.Context, config *config.ClusterConfig) error {
+	p.ctx = ctx
+
+	if config.Providers.Vultr == nil || !config.Providers.Vultr.Enabled {
+		return fmt.Errorf("Vultr provider is not enabled")
+	}
+
+	p.config = config.Providers.Vultr
+
+	provider := &vultrProviderResource{}
+	err := ctx.RegisterResource(vultrProviderType, fmt.Sprintf("%s-vultr", ctx.Stack()), pulumi.Map{
+		"apiKey": pulumi.ToSecret(pulumi.String(p.config.APIKey)),
+	}, provider, pulumi.PluginDownloadURL(vultrPluginDownloadURL))
+	if err != nil {
+		return fmt.Errorf("failed to create Vultr provider: %w", err)
+	}
+	p.provider = provider
+
+	if err := p.setupSSHKeys(ctx); err != nil {
+		return fmt.Errorf("failed to setup SSH keys: %w", err)
+	}
+
+	ctx.Log.Info("Vultr provider initialized", nil)
+	return nil
+}
+
+// resourceOptions returns the options of a Vultr resource
+func (p *VultrProvider) resourceOptions(opts ...pulumi.ResourceOption) []pulumi.ResourceOption {
+	return append([]pulumi.ResourceOption{
+		pulumi.Provider(p.provider),
+		pulumi.PluginDownloadURL(vultrPluginDownloadURL),
+	}, opts...)
+}
+
+// setupSSHKeys uploads the cluster SSH key generated by the orchestrator
+// and adds the configured existing keys
+func (p *VultrProvider) setupSSHKeys(ctx *pulumi.Context) error {
+	var publicKey pulumi.StringInput
+	switch key := p.config.SSHPublicKey.(type) {
+	case string:
+		if key != "" {
+			publicKey = pulumi.String(key)
+		}
+	case pulumi.StringInput:
+		publicKey = key
+	}
+
+	if publicKey != nil {
+		sshKey := &vultrResource{}
+		err := ctx.RegisterResource(vultrSSHKeyType, fmt.Sprintf("%s-vultr-key", ctx.Stack()), pulumi.Map{
+			"name":   pulumi.String(fmt.Sprintf("%s-kubernetes", ctx.Stack())),
+			"sshKey": publicKey,
+		}, sshKey, p.resourceOptions()...)
+		if err != nil {
+			return fmt.Errorf("failed to create SSH key in Vultr: %w", err)
+		}
+
+		p.sshKeyIDs = append(p.sshKeyIDs, sshKey.ID().ToStringOutput())
+		ctx.Export("vultr_ssh_key_id", sshKey.ID())
+	}
+
+	for _, id := range p.config.SSHKeys {
+		p.sshKeyIDs = append(p.sshKeyIDs, pulumi.String(id))
+	}
+
+	if len(p.sshKeyIDs) == 0 {
+		return fmt.Errorf("no SSH keys configured")
+	}
+	return nil
+}
+
+// ensureFirewallGroup creates the firewall group of the cluster once. Vultr
+// attaches instances to a group when they are created, so the group exists
+// before the nodes and CreateFirewall fills in its rules.
+func (p *VultrProvider) ensureFirewallGroup(ctx *pulumi.Context) (*vultrResource, error) {
+	if p.firewallGroup != nil {
+		return p.firewallGroup, nil
+	}
+
+	group := &vultrResource{}
+	err := ctx.RegisterResource(vultrFirewallGroupType, fmt.Sprintf("%s-vultr-firewall", ctx.Stack()), pulumi.Map{
+		"description": pulumi.String(fmt.Sprintf("%s kubernetes nodes", ctx.Stack())),
+	}, group, p.resourceOptions()...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Vultr firewall group: %w", err)
+	}
+
+	p.firewallGroup = group
+	ctx.Export("vultr_firewall_group_id", group.ID())
+	return group, nil
+}
+
+// CreateNode creates a Vultr instance
+func (p *VultrProvider) CreateNode(ctx *pulumi.Context, node *config.NodeConfig) (*NodeOutput, error) {
+	group, err := p.ensureFirewallGroup(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prepare tags
+	tags := pulumi.StringArray{
+		pulumi.String("kubernetes"),
+		pulumi.String(ctx.Stack()),
+	}
+
+	// Add role tags
+	for _, role := range node.Roles {
+		tags = append(tags, pulumi.String(fmt.Sprintf("role-%s", role)))
+	}
+
+	// Add custom tags from config
+	for _, tag := range p.config.Tags {
+		tags = append(tags, pulumi.String(tag))
+	}
+
+	region := node.Region
+	if region == "" {
+		region = p.config.Region
+	}
+	plan := node.Size
+	if plan == "" {
+		plan = p.config.Plan
+	}
+
+	args := pulumi.Map{
+		"label":           pulumi.String(node.Name),
+		"hostname":        pulumi.String(node.Name),
+		"region":          pulumi.String(region),
+		"plan":            pulumi.String(plan),
+		"osId":            pulumi.Int(p.osID(node.Image)),
+		"sshKeyIds":       p.sshKeyIDs,
+		"firewallGroupId": group.ID().ToStringOutput(),
+		"enableIpv6":      pulumi.Bool(p.config.IPv6),
+		"backups":         pulumi.String("disabled"),
+		"tags":            tags,
+	}
+	if node.UserData != "" {
+		args["userData"] = pulumi.String(node.UserData)
+	}
+
+	// Attach to the VPC so the node gets a private IP for the mesh
+	if p.hasVPC {
+		args["vpcIds"] = pulumi.StringArray{p.vpcID.ToStringOutput()}
+	}
+
+	instance := &vultrInstance{}
+	if err := ctx.RegisterResource(vultrInstanceType, node.Name, args, instance, p.resourceOptions()...); err != nil {
+		return nil, fmt.Errorf("failed to create Vultr instance %s: %w", node.Name, err)
+	}
+
+	output := &NodeOutput{
+		ID:          instance.ID(),
+		Name:        node.Name,
+		PublicIP:    instance.MainIP,
+		PrivateIP:   instance.InternalIP,
+		Provider:    "vultr",
+		Region:      region,
+		Size:        plan,
+		Status:      instance.Status,
+		Roles:       node.Roles,
+		Labels:      node.Labels,
+		WireGuardIP: node.WireGuardIP,
+		SSHUser:     "root",
+		SSHKeyPath:  "~/.ssh/id_rsa",
+	}
+
+	// Export node information
+	ctx.Export(fmt.Sprintf("%s_public_ip", node.Name), instance.MainIP)
+	ctx.Export(fmt.Sprintf("%s_private_ip", node.Name), instance.InternalIP)
+	ctx.Export(fmt.Sprintf("%s_id", node.Name), instance.ID())
+	ctx.Export(fmt.Sprintf("%s_status", node.Name), instance.Status)
+
+	p.nodes = append(p.nodes, output)
+	return output, nil
+}
+
+// osID returns the Vultr OS id of a node: its image when numeric, otherwise
+// the provider's osId or Ubuntu 22.04
+func (p *VultrProvider) osID(image string) int {
+	if id, err := strconv.Atoi(image); err == nil {
+		return id
+	}
+	if p.config.OSID != 0 {
+		return p.config.OSID
+	}
+	return DefaultVultrOSID
+}
+
+// CreateNodePool creates multiple nodes
+func (p *VultrProvider) CreateNodePool(ctx *pulumi.Context, pool *config.NodePool) ([]*NodeOutput, error) {
+	outputs := make([]*NodeOutput, 0, pool.Count)
+
+	// Vultr has no availability zones; nodes stay in the pool region
+	if len(pool.Zones) > 0 {
+		ctx.Log.Warn(fmt.Sprintf("Vultr does not support availability zones; ignoring zones for pool %s", pool.Name), nil)
+	}
+
+	for i := 0; i < pool.Count; i++ {
+		nodeName, err := config.RenderPoolNodeName(pool.Name, pool, i)
+		if err != nil {
+			return nil, err
+		}
+
+		// Create node config from pool
+		nodeConfig := &config.NodeConfig{
+			Name:       nodeName,
+			Provider:   pool.Provider,
+			Pool:       pool.Name,
+			Roles:      pool.Roles,
+			Size:       pool.Size,
+			Image:      pool.Image,
+			Region:     pool.Region,
+			Labels:     pool.Labels,
+			Taints:     pool.Taints,
+			UserData:   pool.UserData,
+			Monitoring: pool.Monitoring,
+		}
+
+		// Set WireGuard IP based on role and index
+		// Masters: 10.8.0.30+ (Vultr)
+		// Workers: 10.8.0.40+ (Vultr)
+		if contains(pool.Roles, "controlplane") || contains(pool.Roles, "master") {
+			nodeConfig.WireGuardIP = fmt.Sprintf("10.8.0.%d", 30+i)
+		} else if contains(pool.Roles, "worker") {
+			nodeConfig.WireGuardIP = fmt.Sprintf("10.8.0.%d", 40+i)
+		}
+
+		output, err := p.CreateNode(ctx, nodeConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create node %s: %w", nodeName, err)
+		}
+
+		outputs = append(outputs, output)
+	}
+
+	return outputs, nil
+}
+
+// CreateNetwork creates the VPC nodes attach to, or uses an existing one.
+// Without a VPC config a VPC is created anyway, since Vultr instances only
+// get a private IP through a VPC attachment.
+func (p *VultrProvider) CreateNetwork(ctx *pulumi.Context, network *config.NetworkConfig) (*NetworkOutput, error) {
+	vpcCfg := p.config.VPC
+	region := p.config.Region
+	if vpcCfg != nil && vpcCfg.Region != "" {
+		region = vpcCfg.Region
+	}
+
+	name := fmt.Sprintf("%s-vpc", ctx.Stack())
+	cidr := network.CIDR
+	if vpcCfg != nil {
+		if vpcCfg.Name != "" {
+			name = vpcCfg.Name
+		}
+		cidr = vpcCfg.CIDR
+	}
+
+	if vpcCfg.Existing() {
+		p.vpcID = pulumi.ID(vpcCfg.ID).ToIDOutput()
+	} else {
+		args := pulumi.Map{
+			"region":      pulumi.String(region),
+			"description": pulumi.String(name),
+		}
+		// Without a CIDR Vultr picks the range
+		if cidr != "" {
+			subnet, size, err := splitCIDR(cidr)
+			if err != nil {
+				return nil, fmt.Errorf("invalid Vultr VPC CIDR %q: %w", cidr, err)
+			}
+			args["v4Subnet"] = pulumi.String(subnet)
+			args["v4SubnetMask"] = pulumi.Int(size)
+		}
+
+		vpc := &vultrResource{}
+		if err := ctx.RegisterResource(vultrVPCType, name, args, vpc, p.resourceOptions()...); err != nil {
+			return nil, fmt.Errorf("failed to create Vultr VPC: %w", err)
+		}
+		p.vpcID = vpc.ID()
+	}
+	p.hasVPC = true
+
+	ctx.Export("vultr_vpc_id", p.vpcID)
+	ctx.Export("vultr_vpc_name", pulumi.String(name))
+
+	return &NetworkOutput{
+		ID:     p.vpcID,
+		Name:   name,
+		CIDR:   cidr,
+		Region: region,
+	}, nil
+}
+
+// CreateFirewall fills the firewall group the nodes were created in with the
+// rule set, which covers the VPC, WireGuard and Kubernetes rules
+func (p *VultrProvider) CreateFirewall(ctx *pulumi.Context, firewall *config.FirewallConfig, nodeIds []pulumi.IDOutput) error {
+	group, err := p.ensureFirewallGroup(ctx)
+	if err != nil {
+		return err
+	}
+
+	rules, err := VultrFirewallRules(firewall.InboundRules)
+	if err != nil {
+		return err
+	}
+
+	for i, rule := range rules {
+		args := pulumi.Map{
+			"firewallGroupId": group.ID().ToStringOutput(),
+			"protocol":        pulumi.String(rule.Protocol),
+			"ipType":          pulumi.String(rule.IPType),
+			"subnet":          pulumi.String(rule.Subnet),
+			"subnetSize":      pulumi.Int(rule.SubnetSize),
+			"notes":           pulumi.String(rule.Notes),
+		}
+		if rule.Port != "" {
+			args["port"] = pulumi.String(rule.Port)
+		}
+
+		name := fmt.Sprintf("%s-rule-%d", firewall.Name, i)
+		if err := ctx.RegisterResource(vultrFirewallRuleType, name, args, &vultrResource{}, p.resourceOptions()...); err != nil {
+			return fmt.Errorf("failed to create Vultr firewall rule %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// VultrFirewallRule is a rule of a Vultr firewall group. Vultr groups only
+// hold inbound rules with a single source each.
+type VultrFirewallRule struct {
+	Protocol   string // tcp, udp or icmp
+	IPType     string // v4 or v6
+	Subnet     string
+	SubnetSize int
+	Port       string // a port or a from:to range, empty for icmp
+	Notes      string
+}
+
+// VultrFirewallRules renders firewall rules as Vultr firewall group rules,
+// one per source
+func VultrFirewallRules(rules []config.FirewallRule) ([]VultrFirewallRule, error) {
+	var result []VultrFirewallRule
+	for _, rule := range rules {
+		protocol := strings.ToLower(rule.Protocol)
+		port := ""
+		if protocol != "icmp" {
+			port = strings.ReplaceAll(rule.Port, "-", ":")
+		}
+
+		for _, source := range rule.Source {
+			subnet, size, err := splitCIDR(source)
+			if err != nil {
+				return nil, fmt.Errorf("invalid source %q of firewall rule %s/%s: %w", source, rule.Protocol, rule.Port, err)
+			}
+
+			ipType := "v4"
+			if strings.Contains(subnet, ":") {
+				ipType = "v6"
+			}
+
+			result = append(result, VultrFirewallRule{
+				Protocol:   protocol,
+				IPType:     ipType,
+				Subnet:     subnet,
+				SubnetSize: size,
+				Port:       port,
+				Notes:      rule.Description,
+			})
+		}
+	}
+	return result, nil
+}
+
+// splitCIDR splits a CIDR into its network address and prefix length. A bare
+// IP is a single-host range.
+func splitCIDR(cidr string) (string, int, error) {
+	if !strings.Contains(cidr, "/") {
+		ip := net.ParseIP(cidr)
+		if ip == nil {
+			return "", 0, fmt.Errorf("not an IP or CIDR")
+		}
+		if ip.To4() != nil {
+			return ip.String(), 32, nil
+		}
+		return ip.String(), 128, nil
+	}
+
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", 0, err
+	}
+	size, _ := ipNet.Mask.Size()
+	return ipNet.IP.String(), size, nil
+}
+
+// CreateLoadBalancer creates a Vultr load balancer in front of the nodes
+func (p *VultrProvider) CreateLoadBalancer(ctx *pulumi.Context, lb *config.LoadBalancerConfig) (*LoadBalancerOutput, error) {
+	forwardingRules := pulumi.MapArray{}
+	for _, port := range lb.Ports {
+		protocol := strings.ToLower(port.Protocol)
+		forwardingRules = append(forwardingRules, pulumi.Map{
+			"frontendProtocol": pulumi.String(protocol),
+			"frontendPort":     pulumi.Int(port.Port),
+			"backendProtocol":  pulumi.String(protocol),
+			"backendPort":      pulumi.Int(port.TargetPort),
+		})
+	}
+
+	instances := pulumi.StringArray{}
+	for _, node := range loadBalancerTargets(p.nodes, lb) {
+		instances = append(instances, node.ID.ToStringOutput())
+	}
+
+	args := pulumi.Map{
+		"label":             pulumi.String(lb.Name),
+		"region":            pulumi.String(p.config.Region),
+		"forwardingRules":   forwardingRules,
+		"attachedInstances": instances,
+	}
+	if len(lb.Ports) > 0 {
+		args["healthCheck"] = pulumi.Map{
+			"protocol": pulumi.String("tcp"),
+			"port":     pulumi.Int(lb.Ports[0].TargetPort),
+		}
+	}
+	if p.hasVPC {
+		args["vpc"] = p.vpcID.ToStringOutput()
+	}
+
+	loadBalancer := &vultrLoadBalancer{}
+	if err := ctx.RegisterResource(vultrLoadBalancerType, lb.Name, args, loadBalancer, p.resourceOptions()...); err != nil {
+		return nil, fmt.Errorf("failed to create Vultr load balancer: %w", err)
+	}
+
+	ctx.Export(fmt.Sprintf("%s_ip", lb.Name), loadBalancer.IPv4)
+
+	// Vultr load balancers have no hostname of their own
+	return &LoadBalancerOutput{
+		ID:       loadBalancer.ID(),
+		IP:       loadBalancer.IPv4,
+		Hostname: loadBalancer.IPv4,
+		Status:   loadBalancer.Status,
+	}, nil
+}
+
+// GetRegions returns available Vultr regions
+func (p *VultrProvider) GetRegions() []string {
+	return []string{
+		"ewr", "ord", "dfw", "sea", "lax", "atl", "mia", "sjc", "yto",
+		"ams", "lhr", "fra", "cdg", "sto", "waw", "mad",
+		"nrt", "icn", "sgp", "syd", "bom", "sao", "jnb",
+	}
+}
+
+// GetSizes returns available instance plans
+func (p *VultrProvider) GetSizes() []string {
+	return []string{
+		"vc2-1c-1gb", "vc2-1c-2gb", "vc2-2c-4gb", "vc2-4c-8gb",
+		"vc2-6c-16gb", "vc2-8c-32gb", "vc2-16c-64gb", "vc2-24c-96gb",
+		"vhf-1c-1gb", "vhf-2c-4gb", "vhf-4c-16gb", "vhf-8c-32gb",
+	}
+}
+
+// Cleanup performs cleanup operations
+func (p *VultrProvider) Cleanup(ctx *pulumi.Context) error {
+	// Cleanup is handled by Pulumi's resource management
+	return nil
+}
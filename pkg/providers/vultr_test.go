@@ -0,0 +1,128 @@
+package providers
+
+import (
+	"testing"
+
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
+)
+
+func TestVultrFirewallRules(t *testing.T) {
+	rules, err := VultrFirewallRules([]config.FirewallRule{
+		{Protocol: "TCP", Port: "2379-2380", Source: []string{"10.0.0.0/8", "192.168.0.0/16"}, Description: "etcd"},
+		{Protocol: "udp", Port: "51820", Source: []string{"203.0.113.7"}},
+		{Protocol: "icmp", Port: "0", Source: []string{"2001:db8::/32"}},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	want := []VultrFirewallRule{
+		{Protocol: "tcp", IPType: "v4", Subnet: "10.0.0.0", SubnetSize: 8, Port: "2379:2380", Notes: "etcd"},
+		{Protocol: "tcp", IPType: "v4", Subnet: "192.168.0.0", SubnetSize: 16, Port: "2379:2380", Notes: "etcd"},
+		{Protocol: "udp", IPType: "v4", Subnet: "203.0.113.7", SubnetSize: 32, Port: "51820"},
+		{Protocol: "icmp", IPType: "v6", Subnet: "2001:db8::", SubnetSize: 32},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("Expected %d rules, got %d: %+v", len(want), len(rules), rules)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("Rule %d: expected %+v, got %+v", i, want[i], rules[i])
+		}
+	}
+}
+
+func TestVultrFirewallRules_InvalidSource(t *testing.T) {
+	_, err := VultrFirewallRules([]config.FirewallRule{{Protocol: "tcp", Port: "22", Source: []string{"anywhere"}}})
+	if err == nil {
+		t.Error("Expected an error for an invalid source")
+	}
+}
+
+func TestVultrCreateNode_AttachesVPCAndFirewallGroup(t *testing.T) {
+	mocks := &vpcMocks{}
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		p := NewVultrProvider()
+		err := p.Initialize(ctx, &config.ClusterConfig{
+			Providers: config.ProvidersConfig{
+				Vultr: &config.VultrProvider{Enabled: true, APIKey: "key", Region: "ewr", Plan: "vc2-2c-4gb", SSHPublicKey: "ssh-ed25519 AAAA"},
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		network, err := p.CreateNetwork(ctx, &config.NetworkConfig{CIDR: "10.50.0.0/20"})
+		if err != nil {
+			return err
+		}
+		if network.CIDR != "10.50.0.0/20" || network.Region != "ewr" {
+			t.Errorf("Unexpected network output: %+v", network)
+		}
+
+		_, err = p.CreateNode(ctx, &config.NodeConfig{Name: "master-1", Roles: []string{"master"}, Image: "2284"})
+		return err
+	}, pulumi.WithMocks("test-project", "test-stack", mocks))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	vpcs := mocks.created(vultrVPCType)
+	if len(vpcs) != 1 || vpcs[0]["v4Subnet"].StringValue() != "10.50.0.0" || vpcs[0]["v4SubnetMask"].NumberValue() != 20 {
+		t.Fatalf("Expected a VPC spanning 10.50.0.0/20, got %v", vpcs)
+	}
+	if groups := mocks.created(vultrFirewallGroupType); len(groups) != 1 {
+		t.Errorf("Expected 1 firewall group, got %d", len(groups))
+	}
+
+	instances := mocks.created(vultrInstanceType)
+	if len(instances) != 1 {
+		t.Fatalf("Expected 1 instance, got %d", len(instances))
+	}
+	instance := instances[0]
+	if got := instance["plan"].StringValue(); got != "vc2-2c-4gb" {
+		t.Errorf("Expected the provider plan, got %s", got)
+	}
+	if got := instance["region"].StringValue(); got != "ewr" {
+		t.Errorf("Expected the provider region, got %s", got)
+	}
+	if got := instance["osId"].NumberValue(); got != 2284 {
+		t.Errorf("Expected the numeric image as osId, got %v", got)
+	}
+	if vpcIDs := instance["vpcIds"].ArrayValue(); len(vpcIDs) != 1 {
+		t.Errorf("Expected the instance to attach to the VPC, got %v", vpcIDs)
+	}
+	if instance["firewallGroupId"].StringValue() == "" {
+		t.Error("Expected the instance to join the firewall group")
+	}
+	if keys := instance["sshKeyIds"].ArrayValue(); len(keys) != 1 {
+		t.Errorf("Expected the generated SSH key, got %v", keys)
+	}
+}
+
+func TestVultrInitialize_RequiresSSHKey(t *testing.T) {
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		return NewVultrProvider().Initialize(ctx, &config.ClusterConfig{
+			Providers: config.ProvidersConfig{Vultr: &config.VultrProvider{Enabled: true, APIKey: "key"}},
+		})
+	}, pulumi.WithMocks("test-project", "test-stack", &vpcMocks{}))
+	if err == nil {
+		t.Error("Expected an error without SSH keys")
+	}
+}
+
+func TestVultrOSID(t *testing.T) {
+	p := &VultrProvider{config: &config.VultrProvider{}}
+	if got := p.osID("ubuntu-22.04"); got != DefaultVultrOSID {
+		t.Errorf("Expected the default OS id, got %d", got)
+	}
+	p.config.OSID = 2136
+	if got := p.osID(""); got != 2136 {
+		t.Errorf("Expected the provider OS id, got %d", got)
+	}
+	if got := p.osID("477"); got != 477 {
+		t.Errorf("Expected the numeric image, got %d", got)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/chalkan3/sloth-kubernetes/pkg/config"
 	"github.com/chalkan3/sloth-kubernetes/pkg/providers"
 	"github.com/pulumi/pulumi-command/sdk/go/command/remote"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
@@ -20,6 +21,7 @@ type OSFirewallManager struct {
 	results    map[string]*FirewallResult
 	mu         sync.RWMutex
 	timeout    time.Duration
+	ruleSets   map[string]*config.FirewallConfig
 }
 
 // FirewallResult represents the result of firewall configuration
@@ -254,6 +256,19 @@ func (m *OSFirewallManager) SetSSHKeyPath(path string) {
 	m.sshKeyPath = path
 }
 
+// SetRuleSet sets the firewall rules of the nodes of a provider, the same
+// rules its cloud firewall is created from. Nodes of providers without a rule
+// set get the rules of KubernetesFirewallPorts for their role.
+func (m *OSFirewallManager) SetRuleSet(providerName string, ruleSet *config.FirewallConfig) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.ruleSets == nil {
+		m.ruleSets = make(map[string]*config.FirewallConfig)
+	}
+	m.ruleSets[providerName] = ruleSet
+}
+
 // ConfigureAllNodesFirewall configures firewall on all nodes using goroutines
 func (m *OSFirewallManager) ConfigureAllNodesFirewall() error {
 	m.ctx.Log.Info("Starting OS firewall configuration on all nodes", nil)
@@ -384,6 +399,13 @@ func (m *OSFirewallManager) configureNodeFirewall(
 
 // getRulesForNode returns the appropriate firewall rules based on node role
 func (m *OSFirewallManager) getRulesForNode(node *providers.NodeOutput) []FirewallRule {
+	m.mu.RLock()
+	ruleSet := m.ruleSets[node.Provider]
+	m.mu.RUnlock()
+	if ruleSet != nil {
+		return FirewallRulesFromConfig(ruleSet)
+	}
+
 	rules := []FirewallRule{}
 
 	// Common rules for all nodes
@@ -449,6 +471,48 @@ func (m *OSFirewallManager) getRulesForNode(node *providers.NodeOutput) []Firewa
 	return rules
 }
 
+// FirewallRulesFromConfig converts the inbound rules of a cloud firewall rule
+// set to OS firewall rules, one per source. Port ranges are written the
+// ufw/iptables way, 1000:2000 instead of 1000-2000.
+func FirewallRulesFromConfig(ruleSet *config.FirewallConfig) []FirewallRule {
+	var rules []FirewallRule
+	for _, rule := range ruleSet.InboundRules {
+		sources := rule.Source
+		if len(sources) == 0 {
+			sources = []string{"0.0.0.0/0"}
+		}
+		for _, source := range sources {
+			rules = append(rules, FirewallRule{
+				Port:        strings.Replace(rule.Port, "-", ":", 1),
+				Protocol:    strings.ToLower(rule.Protocol),
+				Source:      source,
+				Direction:   "inbound",
+				Action:      "allow",
+				Description: rule.Description,
+			})
+		}
+	}
+	return rules
+}
+
+// UFWRules renders the inbound rules of a firewall rule set as ufw commands
+func UFWRules(ruleSet *config.FirewallConfig) []string {
+	var commands []string
+	for _, rule := range FirewallRulesFromConfig(ruleSet) {
+		commands = append(commands, ufwAllowCommand(rule))
+	}
+	return commands
+}
+
+// ufwAllowCommand renders a rule as a ufw command
+func ufwAllowCommand(rule FirewallRule) string {
+	if rule.Source == "0.0.0.0/0" {
+		return fmt.Sprintf("ufw allow %s/%s comment '%s'", rule.Port, rule.Protocol, rule.Description)
+	}
+	return fmt.Sprintf("ufw allow from %s to any port %s proto %s comment '%s'",
+		rule.Source, rule.Port, rule.Protocol, rule.Description)
+}
+
 // generateFirewallScript generates the firewall configuration script
 func (m *OSFirewallManager) generateFirewallScript(node *providers.NodeOutput, rules []FirewallRule) string {
 	script := `#!/bin/bash
@@ -501,13 +565,7 @@ configure_ufw() {
 
 	// Add UFW rules
 	for _, rule := range rules {
-		if rule.Source == "0.0.0.0/0" {
-			script += fmt.Sprintf("    ufw allow %s/%s comment '%s'\n",
-				rule.Port, rule.Protocol, rule.Description)
-		} else {
-			script += fmt.Sprintf("    ufw allow from %s to any port %s proto %s comment '%s'\n",
-				rule.Source, rule.Port, rule.Protocol, rule.Description)
-		}
+		script += "    " + ufwAllowCommand(rule) + "\n"
 	}
 
 	script += `
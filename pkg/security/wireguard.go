@@ -331,7 +331,7 @@ func (w *WireGuardManager) ExportWireGuardInfo() {
 
 	if w.config.Enabled {
 		w.ctx.Export("wireguard_server_endpoint", pulumi.String(w.config.ServerEndpoint))
-		w.ctx.Export("wireguard_network", pulumi.String("10.8.0.0/24"))
+		w.ctx.Export("wireguard_network", pulumi.String(w.config.Subnet()))
 		w.ctx.Export("wireguard_port", pulumi.Int(w.config.Port))
 
 		// Export node WireGuard IPs
@@ -2,7 +2,9 @@ package vpn
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/pulumi/pulumi-command/sdk/go/command/remote"
 	"github.com/pulumi/pulumi-digitalocean/sdk/v4/go/digitalocean"
 	"github.com/pulumi/pulumi-linode/sdk/v4/go/linode"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
@@ -30,8 +32,26 @@ type WireGuardResult struct {
 	PrivateKey string
 	Port       int
 	SubnetCIDR string
+
+	// Endpoint is the server's ip:port, known once the server is created
+	Endpoint pulumi.StringOutput
+	// ServerPublicKey is read from the server by ReadServerPublicKey
+	ServerPublicKey pulumi.StringOutput
 }
 
+// waitForPublicKeyScript prints the key generated by the install script,
+// waiting for cloud-init to get that far
+const waitForPublicKeyScript = `for i in $(seq 1 120); do
+  if [ -s /etc/wireguard/publickey ]; then
+    cat /etc/wireguard/publickey
+    exit 0
+  fi
+  sleep 5
+done
+echo "WireGuard public key was not generated" >&2
+exit 1
+`
+
 // CreateWireGuardServer creates a WireGuard VPN server
 func (m *WireGuardManager) CreateWireGuardServer(cfg *config.WireGuardConfig, sshKey pulumi.StringOutput) (*WireGuardResult, error) {
 	if !cfg.Create {
@@ -132,6 +152,9 @@ func (m *WireGuardManager) createDigitalOceanWireGuard(cfg *config.WireGuardConf
 	m.ctx.Export("wireguard_server_name", droplet.Name)
 	m.ctx.Export("wireguard_port", pulumi.Int(cfg.Port))
 
+	endpoint := pulumi.Sprintf("%s:%d", droplet.Ipv4Address, cfg.Port)
+	m.ctx.Export("wireguard_server_endpoint", endpoint)
+
 	return &WireGuardResult{
 		Provider:   "digitalocean",
 		ServerID:   droplet.ID(),
@@ -139,6 +162,7 @@ func (m *WireGuardManager) createDigitalOceanWireGuard(cfg *config.WireGuardConf
 		ServerName: cfg.Name,
 		Port:       cfg.Port,
 		SubnetCIDR: cfg.SubnetCIDR,
+		Endpoint:   endpoint,
 	}, nil
 }
 
@@ -177,6 +201,9 @@ func (m *WireGuardManager) createLinodeWireGuard(cfg *config.WireGuardConfig, ss
 	m.ctx.Export("wireguard_server_name", instance.Label)
 	m.ctx.Export("wireguard_port", pulumi.Int(cfg.Port))
 
+	endpoint := pulumi.Sprintf("%s:%d", instance.IpAddress, cfg.Port)
+	m.ctx.Export("wireguard_server_endpoint", endpoint)
+
 	return &WireGuardResult{
 		Provider:   "linode",
 		ServerID:   instance.ID(),
@@ -184,9 +211,37 @@ func (m *WireGuardManager) createLinodeWireGuard(cfg *config.WireGuardConfig, ss
 		ServerName: cfg.Name,
 		Port:       cfg.Port,
 		SubnetCIDR: cfg.SubnetCIDR,
+		Endpoint:   endpoint,
 	}, nil
 }
 
+// ReadServerPublicKey reads the public key the created server generated on
+// first boot and exports it as wireguard_server_public_key. Together with
+// wireguard_server_endpoint this lets later operations take the server's
+// identity from the stack instead of requiring it up front as config.
+func (m *WireGuardManager) ReadServerPublicKey(result *WireGuardResult, sshPrivateKey pulumi.StringOutput, opts ...pulumi.ResourceOption) (pulumi.StringOutput, error) {
+	cmd, err := remote.NewCommand(m.ctx, fmt.Sprintf("%s-public-key", result.ServerName), &remote.CommandArgs{
+		Connection: &remote.ConnectionArgs{
+			Host:           result.ServerIP,
+			User:           pulumi.String("root"),
+			PrivateKey:     sshPrivateKey,
+			DialErrorLimit: pulumi.Int(30),
+		},
+		Create: pulumi.String(waitForPublicKeyScript),
+	}, opts...)
+	if err != nil {
+		return pulumi.StringOutput{}, fmt.Errorf("failed to read WireGuard server public key: %w", err)
+	}
+
+	publicKey := cmd.Stdout.ApplyT(func(out string) string {
+		return strings.TrimSpace(out)
+	}).(pulumi.StringOutput)
+	m.ctx.Export("wireguard_server_public_key", publicKey)
+
+	result.ServerPublicKey = publicKey
+	return publicKey, nil
+}
+
 // ConfigureWireGuardClient generates WireGuard client configuration
 func (m *WireGuardManager) ConfigureWireGuardClient(serverIP string, serverPort int, clientIP string) string {
 	return fmt.Sprintf(`[Interface]
@@ -1,6 +1,7 @@
 package vpn
 
 import (
+	"sync"
 	"testing"
 
 	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
@@ -37,6 +38,10 @@ func (m *WireGuardMocks) NewResource(args pulumi.MockResourceArgs) (string, reso
 		outputs["ipAddress"] = resource.NewStringProperty("198.51.100.20")
 		outputs["privateIpAddress"] = resource.NewStringProperty("10.20.0.20")
 		outputs["label"] = args.Inputs["label"]
+
+	case "command:remote:Command":
+		// The server prints its generated public key
+		outputs["stdout"] = resource.NewStringProperty("server-public-key\n")
 	}
 
 	return args.Name + "_id", outputs, nil
@@ -113,6 +118,41 @@ func TestCreateWireGuardServer_DigitalOcean_Defaults(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+// TestReadServerPublicKey tests that the created server's endpoint and the
+// public key it generated are available as outputs
+func TestReadServerPublicKey(t *testing.T) {
+	var endpoint, publicKey string
+	var wg sync.WaitGroup
+	wg.Add(1)
+
+	err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+		manager := NewWireGuardManager(ctx)
+
+		cfg := &config.WireGuardConfig{Create: true, Provider: "digitalocean", Region: "nyc3", Size: "s-1vcpu-1gb"}
+		result, err := manager.CreateWireGuardServer(cfg, pulumi.String("ssh-rsa AAAAB3...").ToStringOutput())
+		if err != nil {
+			return err
+		}
+
+		if _, err := manager.ReadServerPublicKey(result, pulumi.String("PRIVATE KEY").ToStringOutput()); err != nil {
+			return err
+		}
+
+		pulumi.All(result.Endpoint, result.ServerPublicKey).ApplyT(func(values []interface{}) error {
+			endpoint = values[0].(string)
+			publicKey = values[1].(string)
+			wg.Done()
+			return nil
+		})
+		return nil
+	}, pulumi.WithMocks("test-project", "test-stack", &WireGuardMocks{}))
+	assert.NoError(t, err)
+
+	wg.Wait()
+	assert.Equal(t, "203.0.113.10:51820", endpoint)
+	assert.Equal(t, "server-public-key", publicKey, "Public key should be trimmed")
+}
+
 // TestCreateWireGuardServer_DigitalOcean_CustomValues tests DigitalOcean with custom values
 func TestCreateWireGuardServer_DigitalOcean_CustomValues(t *testing.T) {
 	err := pulumi.RunErr(func(ctx *pulumi.Context) error {